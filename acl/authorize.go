@@ -0,0 +1,61 @@
+package acl
+
+import (
+	"fmt"
+)
+
+// Allowed checks whether the authorizer grants the access to a resource, for
+// callers which get the resource and access as values instead of calling the
+// methods of the Authorizer. The resources are named like in the rules, with
+// the segment being the name of the node, service, key and so on, which is
+// ignored for the resources without segments. The access is "read" or "write",
+// and "list" for keys. Writes are checked without a Sentinel scope.
+func Allowed(authz Authorizer, resource, segment, access string) (bool, error) {
+	var read, write func() bool
+	switch resource {
+	case "acl":
+		read, write = authz.ACLRead, authz.ACLWrite
+	case "agent":
+		read = func() bool { return authz.AgentRead(segment) }
+		write = func() bool { return authz.AgentWrite(segment) }
+	case "event":
+		read = func() bool { return authz.EventRead(segment) }
+		write = func() bool { return authz.EventWrite(segment) }
+	case "intention":
+		read = func() bool { return authz.IntentionRead(segment) }
+		write = func() bool { return authz.IntentionWrite(segment) }
+	case "key":
+		if access == PolicyList {
+			return authz.KeyList(segment), nil
+		}
+		read = func() bool { return authz.KeyRead(segment) }
+		write = func() bool { return authz.KeyWrite(segment, nil) }
+	case "keyring":
+		read, write = authz.KeyringRead, authz.KeyringWrite
+	case "node":
+		read = func() bool { return authz.NodeRead(segment) }
+		write = func() bool { return authz.NodeWrite(segment, nil) }
+	case "operator":
+		read, write = authz.OperatorRead, authz.OperatorWrite
+	case "query":
+		read = func() bool { return authz.PreparedQueryRead(segment) }
+		write = func() bool { return authz.PreparedQueryWrite(segment) }
+	case "service":
+		read = func() bool { return authz.ServiceRead(segment) }
+		write = func() bool { return authz.ServiceWrite(segment, nil) }
+	case "session":
+		read = func() bool { return authz.SessionRead(segment) }
+		write = func() bool { return authz.SessionWrite(segment) }
+	default:
+		return false, fmt.Errorf("Unknown resource %q", resource)
+	}
+
+	switch access {
+	case PolicyRead:
+		return read(), nil
+	case PolicyWrite:
+		return write(), nil
+	default:
+		return false, fmt.Errorf("Invalid access %q for resource %q", access, resource)
+	}
+}
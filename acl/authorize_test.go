@@ -0,0 +1,58 @@
+package acl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+	policy, err := NewPolicyFromSource("", 0, `
+		acl = "read"
+		service "web" { policy = "write" }
+		node_prefix "" { policy = "read" }
+		key_prefix "app/" { policy = "list" }
+		session_prefix "" { policy = "deny" }
+	`, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	authz, err := NewPolicyAuthorizer(DenyAll(), []*Policy{policy}, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		Resource string
+		Segment  string
+		Access   string
+		Allow    bool
+		Error    string
+	}{
+		{"acl", "", "read", true, ""},
+		{"acl", "", "write", false, ""},
+		{"service", "web", "read", true, ""},
+		{"service", "web", "write", true, ""},
+		{"service", "db", "read", false, ""},
+		{"node", "foo", "read", true, ""},
+		{"node", "foo", "write", false, ""},
+		{"key", "app/config", "list", true, ""},
+		{"key", "app/config", "read", true, ""},
+		{"key", "app/config", "write", false, ""},
+		{"key", "other", "list", false, ""},
+		{"session", "foo", "read", false, ""},
+		{"operator", "", "read", false, ""},
+		{"service", "web", "list", false, "Invalid access"},
+		{"services", "web", "read", false, "Unknown resource"},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s %s %s", tc.Resource, tc.Segment, tc.Access), func(t *testing.T) {
+			allow, err := Allowed(authz, tc.Resource, tc.Segment, tc.Access)
+			if tc.Error != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Allow, allow)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package acl
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -62,11 +64,71 @@ func IsErrPermissionDenied(err error) bool {
 
 type PermissionDeniedError struct {
 	Cause string
+
+	// Resource, Segment and AccessLevel name the permission which the
+	// token lacks, such as service "web" with write access. They are only
+	// set by the checks which know the permission they require.
+	Resource    string
+	Segment     string
+	AccessLevel string
+}
+
+// PermissionDenied returns a PermissionDeniedError for a token lacking the
+// given access level to a resource. The segment is the name or prefix of the
+// resource and is ignored for the resources without segments, such as
+// "operator".
+func PermissionDenied(resource, segment, accessLevel string) PermissionDeniedError {
+	return PermissionDeniedError{
+		Resource:    resource,
+		Segment:     segment,
+		AccessLevel: accessLevel,
+	}
+}
+
+// Permission returns the permission which the token lacks in the form
+// <resource>:<segment>:<access level>, or <resource>:<access level> for the
+// resources without segments. It is empty if the permission isn't known.
+func (e PermissionDeniedError) Permission() string {
+	switch {
+	case e.Resource == "":
+		return ""
+	case e.Resource == "acl" || e.Resource == "keyring" || e.Resource == "operator":
+		return e.Resource + ":" + e.AccessLevel
+	default:
+		return e.Resource + ":" + e.Segment + ":" + e.AccessLevel
+	}
 }
 
 func (e PermissionDeniedError) Error() string {
+	msg := errPermissionDenied
 	if e.Cause != "" {
-		return errPermissionDenied + ": " + e.Cause
+		msg += ": " + e.Cause
+	}
+	if permission := e.Permission(); permission != "" {
+		msg += fmt.Sprintf(" (requires %s)", permission)
+	}
+	return msg
+}
+
+// deniedPermissionRE matches the permission at the end of the message of a
+// PermissionDeniedError.
+var deniedPermissionRE = regexp.MustCompile(` \(requires (.+)\)$`)
+
+// DeniedPermission returns the permission named by a permission denied error,
+// which may have been received as a plain message over RPC, and the message
+// of the error without it. The permission is empty if the error doesn't name
+// one.
+func DeniedPermission(err error) (permission string, msg string) {
+	if err == nil {
+		return "", ""
+	}
+	msg = err.Error()
+	if !IsErrPermissionDenied(err) {
+		return "", msg
+	}
+	m := deniedPermissionRE.FindStringSubmatchIndex(msg)
+	if m == nil {
+		return "", msg
 	}
-	return errPermissionDenied
+	return msg[m[2]:m[3]], msg[:m[0]]
 }
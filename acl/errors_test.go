@@ -0,0 +1,61 @@
+package acl
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionDeniedError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err        error
+		msg        string
+		permission string
+		stripped   string
+	}{
+		{ErrPermissionDenied, "Permission denied", "", "Permission denied"},
+		{
+			PermissionDeniedError{Cause: "Cannot modify root ACL"},
+			"Permission denied: Cannot modify root ACL", "",
+			"Permission denied: Cannot modify root ACL",
+		},
+		{
+			PermissionDenied("service", "web", "write"),
+			"Permission denied (requires service:web:write)", "service:web:write",
+			"Permission denied",
+		},
+		{
+			PermissionDenied("key", "app/(config)", "read"),
+			"Permission denied (requires key:app/(config):read)", "key:app/(config):read",
+			"Permission denied",
+		},
+		{
+			PermissionDenied("operator", "ignored", "read"),
+			"Permission denied (requires operator:read)", "operator:read",
+			"Permission denied",
+		},
+		{
+			// The message of errors received over RPC.
+			errors.New("rpc error: Permission denied (requires node:foo:write)"),
+			"rpc error: Permission denied (requires node:foo:write)", "node:foo:write",
+			"rpc error: Permission denied",
+		},
+		{
+			errors.New("something else (requires node:foo:write)"),
+			"something else (requires node:foo:write)", "",
+			"something else (requires node:foo:write)",
+		},
+	}
+
+	for _, tc := range cases {
+		require.Equal(t, tc.msg, tc.err.Error())
+		require.True(t, IsErrPermissionDenied(tc.err) || tc.permission == "")
+
+		permission, stripped := DeniedPermission(tc.err)
+		require.Equal(t, tc.permission, permission, tc.msg)
+		require.Equal(t, tc.stripped, stripped, tc.msg)
+	}
+}
@@ -183,130 +183,139 @@ func parseCurrent(rules string, sentinel sentinel.Evaluator) (*Policy, error) {
 		return nil, fmt.Errorf("Failed to parse ACL rules: %v", err)
 	}
 
+	if err := p.validate(sentinel); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// validate checks the values of the policies decoded from rules in the
+// current syntax.
+func (p *Policy) validate(sentinel sentinel.Evaluator) error {
 	// Validate the acl policy
 	if p.ACL != "" && !isPolicyValid(p.ACL) {
-		return nil, fmt.Errorf("Invalid acl policy: %#v", p.ACL)
+		return fmt.Errorf("Invalid acl policy: %#v", p.ACL)
 	}
 
 	// Validate the agent policy
 	for _, ap := range p.Agents {
 		if !isPolicyValid(ap.Policy) {
-			return nil, fmt.Errorf("Invalid agent policy: %#v", ap)
+			return fmt.Errorf("Invalid agent policy: %#v", ap)
 		}
 	}
 	for _, ap := range p.AgentPrefixes {
 		if !isPolicyValid(ap.Policy) {
-			return nil, fmt.Errorf("Invalid agent_prefix policy: %#v", ap)
+			return fmt.Errorf("Invalid agent_prefix policy: %#v", ap)
 		}
 	}
 
 	// Validate the key policy
 	for _, kp := range p.Keys {
 		if kp.Policy != PolicyList && !isPolicyValid(kp.Policy) {
-			return nil, fmt.Errorf("Invalid key policy: %#v", kp)
+			return fmt.Errorf("Invalid key policy: %#v", kp)
 		}
 		if err := isSentinelValid(sentinel, kp.Policy, kp.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid key Sentinel policy: %#v, got error:%v", kp, err)
+			return fmt.Errorf("Invalid key Sentinel policy: %#v, got error:%v", kp, err)
 		}
 	}
 	for _, kp := range p.KeyPrefixes {
 		if kp.Policy != PolicyList && !isPolicyValid(kp.Policy) {
-			return nil, fmt.Errorf("Invalid key_prefix policy: %#v", kp)
+			return fmt.Errorf("Invalid key_prefix policy: %#v", kp)
 		}
 		if err := isSentinelValid(sentinel, kp.Policy, kp.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid key_prefix Sentinel policy: %#v, got error:%v", kp, err)
+			return fmt.Errorf("Invalid key_prefix Sentinel policy: %#v, got error:%v", kp, err)
 		}
 	}
 
 	// Validate the node policies
 	for _, np := range p.Nodes {
 		if !isPolicyValid(np.Policy) {
-			return nil, fmt.Errorf("Invalid node policy: %#v", np)
+			return fmt.Errorf("Invalid node policy: %#v", np)
 		}
 		if err := isSentinelValid(sentinel, np.Policy, np.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid node Sentinel policy: %#v, got error:%v", np, err)
+			return fmt.Errorf("Invalid node Sentinel policy: %#v, got error:%v", np, err)
 		}
 	}
 	for _, np := range p.NodePrefixes {
 		if !isPolicyValid(np.Policy) {
-			return nil, fmt.Errorf("Invalid node_prefix policy: %#v", np)
+			return fmt.Errorf("Invalid node_prefix policy: %#v", np)
 		}
 		if err := isSentinelValid(sentinel, np.Policy, np.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid node_prefix Sentinel policy: %#v, got error:%v", np, err)
+			return fmt.Errorf("Invalid node_prefix Sentinel policy: %#v, got error:%v", np, err)
 		}
 	}
 
 	// Validate the service policies
 	for _, sp := range p.Services {
 		if !isPolicyValid(sp.Policy) {
-			return nil, fmt.Errorf("Invalid service policy: %#v", sp)
+			return fmt.Errorf("Invalid service policy: %#v", sp)
 		}
 		if sp.Intentions != "" && !isPolicyValid(sp.Intentions) {
-			return nil, fmt.Errorf("Invalid service intentions policy: %#v", sp)
+			return fmt.Errorf("Invalid service intentions policy: %#v", sp)
 		}
 		if err := isSentinelValid(sentinel, sp.Policy, sp.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid service Sentinel policy: %#v, got error:%v", sp, err)
+			return fmt.Errorf("Invalid service Sentinel policy: %#v, got error:%v", sp, err)
 		}
 	}
 	for _, sp := range p.ServicePrefixes {
 		if !isPolicyValid(sp.Policy) {
-			return nil, fmt.Errorf("Invalid service_prefix policy: %#v", sp)
+			return fmt.Errorf("Invalid service_prefix policy: %#v", sp)
 		}
 		if sp.Intentions != "" && !isPolicyValid(sp.Intentions) {
-			return nil, fmt.Errorf("Invalid service_prefix intentions policy: %#v", sp)
+			return fmt.Errorf("Invalid service_prefix intentions policy: %#v", sp)
 		}
 		if err := isSentinelValid(sentinel, sp.Policy, sp.Sentinel); err != nil {
-			return nil, fmt.Errorf("Invalid service_prefix Sentinel policy: %#v, got error:%v", sp, err)
+			return fmt.Errorf("Invalid service_prefix Sentinel policy: %#v, got error:%v", sp, err)
 		}
 	}
 
 	// Validate the session policies
 	for _, sp := range p.Sessions {
 		if !isPolicyValid(sp.Policy) {
-			return nil, fmt.Errorf("Invalid session policy: %#v", sp)
+			return fmt.Errorf("Invalid session policy: %#v", sp)
 		}
 	}
 	for _, sp := range p.SessionPrefixes {
 		if !isPolicyValid(sp.Policy) {
-			return nil, fmt.Errorf("Invalid session_prefix policy: %#v", sp)
+			return fmt.Errorf("Invalid session_prefix policy: %#v", sp)
 		}
 	}
 
 	// Validate the user event policies
 	for _, ep := range p.Events {
 		if !isPolicyValid(ep.Policy) {
-			return nil, fmt.Errorf("Invalid event policy: %#v", ep)
+			return fmt.Errorf("Invalid event policy: %#v", ep)
 		}
 	}
 	for _, ep := range p.EventPrefixes {
 		if !isPolicyValid(ep.Policy) {
-			return nil, fmt.Errorf("Invalid event_prefix policy: %#v", ep)
+			return fmt.Errorf("Invalid event_prefix policy: %#v", ep)
 		}
 	}
 
 	// Validate the prepared query policies
 	for _, pq := range p.PreparedQueries {
 		if !isPolicyValid(pq.Policy) {
-			return nil, fmt.Errorf("Invalid query policy: %#v", pq)
+			return fmt.Errorf("Invalid query policy: %#v", pq)
 		}
 	}
 	for _, pq := range p.PreparedQueryPrefixes {
 		if !isPolicyValid(pq.Policy) {
-			return nil, fmt.Errorf("Invalid query_prefix policy: %#v", pq)
+			return fmt.Errorf("Invalid query_prefix policy: %#v", pq)
 		}
 	}
 
 	// Validate the keyring policy - this one is allowed to be empty
 	if p.Keyring != "" && !isPolicyValid(p.Keyring) {
-		return nil, fmt.Errorf("Invalid keyring policy: %#v", p.Keyring)
+		return fmt.Errorf("Invalid keyring policy: %#v", p.Keyring)
 	}
 
 	// Validate the operator policy - this one is allowed to be empty
 	if p.Operator != "" && !isPolicyValid(p.Operator) {
-		return nil, fmt.Errorf("Invalid operator policy: %#v", p.Operator)
+		return fmt.Errorf("Invalid operator policy: %#v", p.Operator)
 	}
 
-	return p, nil
+	return nil
 }
 
 func parseLegacy(rules string, sentinel sentinel.Evaluator) (*Policy, error) {
@@ -0,0 +1,158 @@
+package acl
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hashicorp/consul/sentinel"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	hclprinter "github.com/hashicorp/hcl/hcl/printer"
+)
+
+// RulesError is an error in ACL rules. The line and column of the error are
+// zero if the position isn't known.
+type RulesError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *RulesError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("At %d:%d: %v", e.Line, e.Column, e.Err)
+}
+
+// newRulesError returns the RulesError for an error of the HCL parser or
+// decoder, with the position if the error has one.
+func newRulesError(err error) *RulesError {
+	if perr, ok := err.(*parser.PosError); ok {
+		return &RulesError{Line: perr.Pos.Line, Column: perr.Pos.Column, Err: perr.Err}
+	}
+	return &RulesError{Err: err}
+}
+
+// ValidateRules parses rules in the current syntax like NewPolicyFromSource,
+// but returns a *RulesError with the position of the error. Invalid values of
+// policies are reported at the position of the rule they belong to, as the
+// decoded policies don't keep their positions.
+func ValidateRules(rules string, sentinel sentinel.Evaluator) (*Policy, error) {
+	file, err := hcl.Parse(rules)
+	if err != nil {
+		return nil, newRulesError(err)
+	}
+
+	policy := &Policy{}
+	if err := hcl.DecodeObject(policy, file); err != nil {
+		return nil, newRulesError(err)
+	}
+
+	err = policy.validate(sentinel)
+	if err == nil {
+		return policy, nil
+	}
+
+	// Validate the rules one at a time to find the one with the error.
+	if list, ok := file.Node.(*ast.ObjectList); ok {
+		for _, item := range list.Items {
+			single := &Policy{}
+			if err := hcl.DecodeObject(single, &ast.ObjectList{Items: []*ast.ObjectItem{item}}); err != nil {
+				continue
+			}
+			if err := single.validate(sentinel); err != nil {
+				pos := item.Pos()
+				return nil, &RulesError{Line: pos.Line, Column: pos.Column, Err: err}
+			}
+		}
+	}
+	return nil, &RulesError{Err: err}
+}
+
+// CanonicalRules returns the rules of the policy in the current syntax. The
+// rules are grouped by resource in a fixed order, keep their order within a
+// resource since later rules for the same segment take precedence, and are
+// formatted like by hclfmt.
+func (policy *Policy) CanonicalRules() (string, error) {
+	var b bytes.Buffer
+	if policy.ACL != "" {
+		fmt.Fprintf(&b, "acl = %q\n", policy.ACL)
+	}
+	for _, ap := range policy.Agents {
+		writeRule(&b, "agent", ap.Node, ap.Policy, "", Sentinel{})
+	}
+	for _, ap := range policy.AgentPrefixes {
+		writeRule(&b, "agent_prefix", ap.Node, ap.Policy, "", Sentinel{})
+	}
+	for _, kp := range policy.Keys {
+		writeRule(&b, "key", kp.Prefix, kp.Policy, "", kp.Sentinel)
+	}
+	for _, kp := range policy.KeyPrefixes {
+		writeRule(&b, "key_prefix", kp.Prefix, kp.Policy, "", kp.Sentinel)
+	}
+	for _, np := range policy.Nodes {
+		writeRule(&b, "node", np.Name, np.Policy, "", np.Sentinel)
+	}
+	for _, np := range policy.NodePrefixes {
+		writeRule(&b, "node_prefix", np.Name, np.Policy, "", np.Sentinel)
+	}
+	for _, sp := range policy.Services {
+		writeRule(&b, "service", sp.Name, sp.Policy, sp.Intentions, sp.Sentinel)
+	}
+	for _, sp := range policy.ServicePrefixes {
+		writeRule(&b, "service_prefix", sp.Name, sp.Policy, sp.Intentions, sp.Sentinel)
+	}
+	for _, sp := range policy.Sessions {
+		writeRule(&b, "session", sp.Node, sp.Policy, "", Sentinel{})
+	}
+	for _, sp := range policy.SessionPrefixes {
+		writeRule(&b, "session_prefix", sp.Node, sp.Policy, "", Sentinel{})
+	}
+	for _, ep := range policy.Events {
+		writeRule(&b, "event", ep.Event, ep.Policy, "", Sentinel{})
+	}
+	for _, ep := range policy.EventPrefixes {
+		writeRule(&b, "event_prefix", ep.Event, ep.Policy, "", Sentinel{})
+	}
+	for _, pq := range policy.PreparedQueries {
+		writeRule(&b, "query", pq.Prefix, pq.Policy, "", Sentinel{})
+	}
+	for _, pq := range policy.PreparedQueryPrefixes {
+		writeRule(&b, "query_prefix", pq.Prefix, pq.Policy, "", Sentinel{})
+	}
+	if policy.Keyring != "" {
+		fmt.Fprintf(&b, "keyring = %q\n", policy.Keyring)
+	}
+	if policy.Operator != "" {
+		fmt.Fprintf(&b, "operator = %q\n", policy.Operator)
+	}
+	if b.Len() == 0 {
+		return "", nil
+	}
+
+	formatted, err := hclprinter.Format(b.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("Failed to format rules: %v", err)
+	}
+	return string(formatted), nil
+}
+
+// writeRule writes a rule for a segment of a resource.
+func writeRule(b *bytes.Buffer, resource, segment, policy, intentions string, s Sentinel) {
+	fmt.Fprintf(b, "%s %q {\n", resource, segment)
+	fmt.Fprintf(b, "policy = %q\n", policy)
+	if intentions != "" {
+		fmt.Fprintf(b, "intentions = %q\n", intentions)
+	}
+	if s.Code != "" {
+		b.WriteString("sentinel {\n")
+		fmt.Fprintf(b, "code = %q\n", s.Code)
+		if s.EnforcementLevel != "" {
+			fmt.Fprintf(b, "enforcementlevel = %q\n", s.EnforcementLevel)
+		}
+		b.WriteString("}\n")
+	}
+	b.WriteString("}\n")
+}
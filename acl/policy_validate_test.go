@@ -0,0 +1,165 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRules(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Rules  string
+		Line   int
+		Column int
+		Error  string
+	}{
+		{
+			Name:  "valid",
+			Rules: `service "web" { policy = "read" }`,
+		},
+		{
+			Name:  "empty",
+			Rules: ``,
+		},
+		{
+			Name: "syntax error",
+			Rules: `service "web" {
+  policy = "read"
+}
+
+key_prefix "" {
+  policy = write
+}
+`,
+			Line:   6,
+			Column: 12,
+			Error:  "Unknown token",
+		},
+		{
+			Name: "decode error",
+			Rules: `node_prefix "" {
+  policy = "read"
+}
+operator = ["read"]
+`,
+			Line:   4,
+			Column: 12,
+			Error:  "unknown type for string",
+		},
+		{
+			Name: "invalid policy",
+			Rules: `service "web" {
+  policy = "read"
+}
+
+node "foo" {
+  policy = "admin"
+}
+`,
+			Line:   5,
+			Column: 1,
+			Error:  "Invalid node policy",
+		},
+		{
+			Name:   "invalid top level policy",
+			Rules:  "acl = \"read\"\n  keyring = \"lots\"\n",
+			Line:   2,
+			Column: 3,
+			Error:  "Invalid keyring policy",
+		},
+		{
+			Name:  "json",
+			Rules: `{"service": {"web": {"policy": "read"}}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			policy, err := ValidateRules(tc.Rules, nil)
+			if tc.Error == "" {
+				require.NoError(t, err)
+				require.NotNil(t, policy)
+
+				// The result is the same as the one of the regular parser.
+				expected, err := NewPolicyFromSource("", 0, tc.Rules, SyntaxCurrent, nil)
+				require.NoError(t, err)
+				require.Equal(t, expected, policy)
+				return
+			}
+
+			require.Error(t, err)
+			rerr, ok := err.(*RulesError)
+			require.True(t, ok, "error %#v", err)
+			require.Equal(t, tc.Line, rerr.Line)
+			require.Equal(t, tc.Column, rerr.Column)
+			require.Contains(t, rerr.Err.Error(), tc.Error)
+
+			// The regular parser rejects the rules as well.
+			_, err = NewPolicyFromSource("", 0, tc.Rules, SyntaxCurrent, nil)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPolicy_CanonicalRules(t *testing.T) {
+	rules := `
+operator = "read"
+service_prefix "" { policy = "read" }
+service "web" {
+	intentions = "write"
+	policy = "write"
+}
+key "foo" {
+	policy = "write"
+	sentinel {
+		code = "import \"strings\"\nmain = rule { strings.has_prefix(value, \"bar\") }"
+		enforcementlevel = "soft-mandatory"
+	}
+}
+key "foo" { policy = "deny" }
+acl = "read"
+`
+	policy, err := NewPolicyFromSource("", 0, rules, SyntaxCurrent, nil)
+	require.NoError(t, err)
+
+	canonical, err := policy.CanonicalRules()
+	require.NoError(t, err)
+	expected := `acl = "read"
+
+key "foo" {
+  policy = "write"
+
+  sentinel {
+    code             = "import \"strings\"\nmain = rule { strings.has_prefix(value, \"bar\") }"
+    enforcementlevel = "soft-mandatory"
+  }
+}
+
+key "foo" {
+  policy = "deny"
+}
+
+service "web" {
+  policy     = "write"
+  intentions = "write"
+}
+
+service_prefix "" {
+  policy = "read"
+}
+
+operator = "read"
+`
+	require.Equal(t, expected, canonical)
+
+	// The canonical rules are equivalent to the original ones.
+	parsed, err := NewPolicyFromSource("", 0, canonical, SyntaxCurrent, nil)
+	require.NoError(t, err)
+	require.Equal(t, policy, parsed)
+
+	// Empty rules stay empty.
+	canonical, err = (&Policy{}).CanonicalRules()
+	require.NoError(t, err)
+	require.Equal(t, "", canonical)
+}
@@ -35,6 +35,15 @@ func (a *Agent) resolveToken(id string) (acl.Authorizer, error) {
 	return a.delegate.ResolveToken(id)
 }
 
+// tokenAccessorID returns the accessor ID of the given token for logging, or
+// an empty string if ACLs are disabled or the token isn't known.
+func (a *Agent) tokenAccessorID(id string) string {
+	if !a.delegate.ACLsEnabled() {
+		return ""
+	}
+	return a.delegate.TokenAccessorID(id)
+}
+
 func (a *Agent) initializeACLs() error {
 	// Build a policy for the agent master token.
 	// The builtin agent master policy allows reading any node information
@@ -89,14 +98,14 @@ func (a *Agent) vetServiceRegister(token string, service *structs.NodeService) e
 
 	// Vet the service itself.
 	if !rule.ServiceWrite(service.Service, nil) {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("service", service.Service, "write")
 	}
 
 	// Vet any service that might be getting overwritten.
 	services := a.State.Services()
 	if existing, ok := services[service.ID]; ok {
 		if !rule.ServiceWrite(existing.Service, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", existing.Service, "write")
 		}
 	}
 
@@ -104,7 +113,7 @@ func (a *Agent) vetServiceRegister(token string, service *structs.NodeService) e
 	// since it can be discovered as an instance of that service.
 	if service.Kind == structs.ServiceKindConnectProxy {
 		if !rule.ServiceWrite(service.Proxy.DestinationServiceName, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", service.Proxy.DestinationServiceName, "write")
 		}
 	}
 
@@ -127,7 +136,7 @@ func (a *Agent) vetServiceUpdate(token string, serviceID string) error {
 	services := a.State.Services()
 	if existing, ok := services[serviceID]; ok {
 		if !rule.ServiceWrite(existing.Service, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", existing.Service, "write")
 		}
 	} else {
 		return fmt.Errorf("Unknown service %q", serviceID)
@@ -151,11 +160,11 @@ func (a *Agent) vetCheckRegister(token string, check *structs.HealthCheck) error
 	// Vet the check itself.
 	if len(check.ServiceName) > 0 {
 		if !rule.ServiceWrite(check.ServiceName, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", check.ServiceName, "write")
 		}
 	} else {
 		if !rule.NodeWrite(a.config.NodeName, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("node", a.config.NodeName, "write")
 		}
 	}
 
@@ -164,11 +173,11 @@ func (a *Agent) vetCheckRegister(token string, check *structs.HealthCheck) error
 	if existing, ok := checks[check.CheckID]; ok {
 		if len(existing.ServiceName) > 0 {
 			if !rule.ServiceWrite(existing.ServiceName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", existing.ServiceName, "write")
 			}
 		} else {
 			if !rule.NodeWrite(a.config.NodeName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("node", a.config.NodeName, "write")
 			}
 		}
 	}
@@ -192,11 +201,11 @@ func (a *Agent) vetCheckUpdate(token string, checkID types.CheckID) error {
 	if existing, ok := checks[checkID]; ok {
 		if len(existing.ServiceName) > 0 {
 			if !rule.ServiceWrite(existing.ServiceName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", existing.ServiceName, "write")
 			}
 		} else {
 			if !rule.NodeWrite(a.config.NodeName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("node", a.config.NodeName, "write")
 			}
 		}
 	} else {
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// aclBootstrapLimiterMaxSources is the number of sources after which the
+// limiters of idle sources are dropped.
+const aclBootstrapLimiterMaxSources = 1024
+
+// aclBootstrapReportInterval is how often the rate limited attempts of a
+// source are reported at most.
+const aclBootstrapReportInterval = time.Minute
+
+// aclBootstrapLimiter rate limits the ACL bootstrap attempts per source IP.
+// Bootstrapping is only allowed once, so repeated attempts usually mean that
+// somebody is probing the endpoint.
+type aclBootstrapLimiter struct {
+	limit rate.Limit
+	burst int
+
+	lock    sync.Mutex
+	sources map[string]*aclBootstrapSource
+}
+
+// aclBootstrapSource is the limiter of a single source.
+type aclBootstrapSource struct {
+	limiter *rate.Limiter
+	last    time.Time
+
+	// limited counts the rate limited attempts since reported, when they
+	// were last reported.
+	limited  int
+	reported time.Time
+}
+
+// newACLBootstrapLimiter returns a limiter which allows limit attempts per
+// second with bursts of the given size for every source. It returns nil if
+// the limit isn't positive, which allows all attempts.
+func newACLBootstrapLimiter(limit rate.Limit, burst int) *aclBootstrapLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &aclBootstrapLimiter{
+		limit:   limit,
+		burst:   burst,
+		sources: make(map[string]*aclBootstrapSource),
+	}
+}
+
+// Allow returns true if an attempt from the source is allowed now. Otherwise
+// it returns the number of rate limited attempts of the source which are to
+// be reported, which is zero if they were reported less than
+// aclBootstrapReportInterval ago, so that a flood of attempts doesn't flood
+// the log as well.
+func (l *aclBootstrapLimiter) Allow(source string) (bool, int) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	s, ok := l.sources[source]
+	if !ok {
+		if len(l.sources) >= aclBootstrapLimiterMaxSources {
+			l.prune(now)
+		}
+		s = &aclBootstrapSource{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.sources[source] = s
+	}
+	s.last = now
+	if s.limiter.AllowN(now, 1) {
+		return true, 0
+	}
+
+	s.limited++
+	if now.Sub(s.reported) < aclBootstrapReportInterval {
+		return false, 0
+	}
+	limited := s.limited
+	s.limited, s.reported = 0, now
+	return false, limited
+}
+
+// prune drops the limiters of the sources which have been idle long enough
+// to be allowed a full burst again.
+func (l *aclBootstrapLimiter) prune(now time.Time) {
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	for source, s := range l.sources {
+		if now.Sub(s.last) > refill {
+			delete(l.sources, source)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestACLBootstrapLimiter(t *testing.T) {
+	t.Parallel()
+
+	// A limiter without a positive rate allows everything.
+	l := newACLBootstrapLimiter(0, 1)
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow("1.2.3.4"); !ok {
+			t.Fatalf("attempt %d should be allowed", i)
+		}
+	}
+
+	// Every source gets its own burst.
+	l = newACLBootstrapLimiter(rate.Every(time.Hour), 2)
+	for _, source := range []string{"1.2.3.4", "5.6.7.8"} {
+		for i := 0; i < 2; i++ {
+			if ok, _ := l.Allow(source); !ok {
+				t.Fatalf("%s: attempt %d should be allowed", source, i)
+			}
+		}
+		if ok, _ := l.Allow(source); ok {
+			t.Fatalf("%s: attempt should be limited", source)
+		}
+	}
+}
+
+func TestACLBootstrapLimiter_report(t *testing.T) {
+	t.Parallel()
+
+	l := newACLBootstrapLimiter(rate.Every(time.Hour), 1)
+	l.Allow("1.2.3.4")
+
+	// The first limited attempt is reported right away, the following ones
+	// once the report interval passed.
+	for i, want := range []int{1, 0, 0} {
+		if ok, limited := l.Allow("1.2.3.4"); ok || limited != want {
+			t.Fatalf("attempt %d: got %v %d want false %d", i, ok, limited, want)
+		}
+	}
+	l.sources["1.2.3.4"].reported = time.Now().Add(-aclBootstrapReportInterval)
+	if _, limited := l.Allow("1.2.3.4"); limited != 3 {
+		t.Fatalf("got %d want 3", limited)
+	}
+	if _, limited := l.Allow("1.2.3.4"); limited != 0 {
+		t.Fatalf("got %d want 0", limited)
+	}
+
+	// Other sources are reported separately.
+	l.Allow("5.6.7.8")
+	if _, limited := l.Allow("5.6.7.8"); limited != 1 {
+		t.Fatalf("got %d want 1", limited)
+	}
+}
+
+func TestACLBootstrapLimiter_prune(t *testing.T) {
+	t.Parallel()
+
+	l := newACLBootstrapLimiter(rate.Every(time.Millisecond), 1)
+	l.Allow("1.2.3.4")
+	time.Sleep(10 * time.Millisecond)
+	l.Allow("5.6.7.8")
+	l.prune(time.Now())
+	if _, ok := l.sources["1.2.3.4"]; ok {
+		t.Fatalf("idle source should be pruned")
+	}
+	if _, ok := l.sources["5.6.7.8"]; !ok {
+		t.Fatalf("active source should be kept")
+	}
+}
@@ -3,13 +3,25 @@ package agent
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib/filter"
+)
+
+// The results of ACL bootstrap attempts for the audit log, metrics and hooks.
+const (
+	aclBootstrapSuccess     = "success"
+	aclBootstrapDenied      = "denied"
+	aclBootstrapRateLimited = "rate_limited"
+	aclBootstrapError       = "error"
 )
 
 // aclCreateResponse is used to wrap the ACL ID
@@ -37,6 +49,31 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 		return nil, nil
 	}
 
+	// Every attempt is audited since repeated attempts usually mean that
+	// somebody is probing the endpoint. Rate limited attempts are only
+	// reported once in a while per source.
+	source := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(source); err == nil {
+		source = host
+	}
+
+	allowed, limited := s.agent.aclBootstrapLimiter.Allow(source)
+	result, attempts := aclBootstrapError, 1
+	defer func() {
+		metrics.IncrCounterWithLabels([]string{"acl", "bootstrap", "attempt"}, 1,
+			[]metrics.Label{{Name: "result", Value: result}})
+		if attempts > 0 {
+			s.auditACLBootstrap(source, result, attempts)
+		}
+	}()
+
+	if !allowed {
+		result, attempts = aclBootstrapRateLimited, limited
+		resp.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(resp, "Too many ACL bootstrap attempts")
+		return nil, nil
+	}
+
 	args := structs.DCSpecificRequest{
 		Datacenter: s.agent.config.Datacenter,
 	}
@@ -52,6 +89,7 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 		err := s.agent.RPC("ACL.Bootstrap", &args, &out)
 		if err != nil {
 			if strings.Contains(err.Error(), structs.ACLBootstrapNotAllowedErr.Error()) {
+				result = aclBootstrapDenied
 				resp.WriteHeader(http.StatusForbidden)
 				fmt.Fprint(resp, acl.PermissionDeniedError{Cause: err.Error()}.Error())
 				return nil, nil
@@ -59,12 +97,14 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 				return nil, err
 			}
 		}
+		result = aclBootstrapSuccess
 		return &aclBootstrapResponse{ID: out.ID}, nil
 	} else {
 		var out structs.ACLToken
 		err := s.agent.RPC("ACL.BootstrapTokens", &args, &out)
 		if err != nil {
 			if strings.Contains(err.Error(), structs.ACLBootstrapNotAllowedErr.Error()) {
+				result = aclBootstrapDenied
 				resp.WriteHeader(http.StatusForbidden)
 				fmt.Fprint(resp, acl.PermissionDeniedError{Cause: err.Error()}.Error())
 				return nil, nil
@@ -72,10 +112,33 @@ func (s *HTTPServer) ACLBootstrap(resp http.ResponseWriter, req *http.Request) (
 				return nil, err
 			}
 		}
+		result = aclBootstrapSuccess
 		return &aclBootstrapResponse{ID: out.SecretID, ACLToken: out}, nil
 	}
 }
 
+// auditACLBootstrap logs ACL bootstrap attempts with the same result from a
+// source and runs the acl_bootstrap_attempt hooks for them. Only rate limited
+// attempts are reported in batches.
+func (s *HTTPServer) auditACLBootstrap(source, result string, attempts int) {
+	level := "WARN"
+	if result == aclBootstrapSuccess {
+		level = "INFO"
+	}
+	if result == aclBootstrapRateLimited {
+		s.agent.logger.Printf("[%s] http: ACL bootstrap attempt result=%s from=%s attempts=%d",
+			level, result, source, attempts)
+	} else {
+		s.agent.logger.Printf("[%s] http: ACL bootstrap attempt result=%s from=%s", level, result, source)
+	}
+	s.agent.hooks.Fire(&HookEvent{
+		Event:    config.HookEventACLBootstrap,
+		Source:   source,
+		Result:   result,
+		Attempts: attempts,
+	})
+}
+
 func (s *HTTPServer) ACLReplicationStatus(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.checkACLDisabled(resp, req) {
 		return nil, nil
@@ -165,6 +228,23 @@ func (s *HTTPServer) ACLRulesTranslateLegacyToken(resp http.ResponseWriter, req
 	return nil, nil
 }
 
+// validateACLListFilter returns a bad request error if the filter expression
+// of a list request is invalid for the listed objects. The servers evaluate
+// the filter.
+func validateACLListFilter(expression string, fields filter.Fields) error {
+	if expression == "" {
+		return nil
+	}
+	expr, err := filter.Parse(expression)
+	if err == nil {
+		err = expr.Validate(fields)
+	}
+	if err != nil {
+		return BadRequestError{Reason: fmt.Sprintf("Invalid filter: %v", err)}
+	}
+	return nil
+}
+
 func (s *HTTPServer) ACLPolicyList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.checkACLDisabled(resp, req) {
 		return nil, nil
@@ -179,6 +259,11 @@ func (s *HTTPServer) ACLPolicyList(resp http.ResponseWriter, req *http.Request)
 		args.Datacenter = s.agent.config.Datacenter
 	}
 
+	args.Filter = req.URL.Query().Get("filter")
+	if err := validateACLListFilter(args.Filter, &structs.ACLPolicy{}); err != nil {
+		return nil, err
+	}
+
 	var out structs.ACLPolicyListResponse
 	defer setMeta(resp, &out.QueryMeta)
 	if err := s.agent.RPC("ACL.PolicyList", &args, &out); err != nil {
@@ -256,22 +341,35 @@ func (s *HTTPServer) ACLPolicyCreate(resp http.ResponseWriter, req *http.Request
 	return s.ACLPolicyWrite(resp, req, "")
 }
 
-// fixCreateTimeAndHash is used to help in decoding the CreateTime and Hash
-// attributes from the ACL Token create/update requests. It is needed
-// to help mapstructure decode things properly when decodeBody is used.
-func fixCreateTimeAndHash(raw interface{}) error {
+// fixTimeAndHashFields is used to help in decoding the CreateTime,
+// ExpirationTime, ExpirationTTL and Hash attributes from the ACL Token
+// create/update requests. It is needed to help mapstructure decode things
+// properly when decodeBody is used.
+func fixTimeAndHashFields(raw interface{}) error {
 	rawMap, ok := raw.(map[string]interface{})
 	if !ok {
 		return nil
 	}
 
-	if val, ok := rawMap["CreateTime"]; ok {
+	for _, field := range []string{"CreateTime", "ExpirationTime"} {
+		if val, ok := rawMap[field]; ok {
+			if sval, ok := val.(string); ok {
+				t, err := time.Parse(time.RFC3339, sval)
+				if err != nil {
+					return err
+				}
+				rawMap[field] = t
+			}
+		}
+	}
+
+	if val, ok := rawMap["ExpirationTTL"]; ok {
 		if sval, ok := val.(string); ok {
-			t, err := time.Parse(time.RFC3339, sval)
+			d, err := time.ParseDuration(sval)
 			if err != nil {
 				return err
 			}
-			rawMap["CreateTime"] = t
+			rawMap["ExpirationTTL"] = d
 		}
 	}
 
@@ -324,6 +422,88 @@ func (s *HTTPServer) ACLPolicyDelete(resp http.ResponseWriter, req *http.Request
 	return true, nil
 }
 
+// PUT /v1/acl/policy/impact
+func (s *HTTPServer) ACLPolicyImpact(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	// The proposed policy is the update of an existing policy with its ID, or
+	// the deletion of it with the delete parameter.
+	args := structs.ACLPolicyImpactRequest{
+		Datacenter: s.agent.config.Datacenter,
+	}
+	s.parseToken(req, &args.Token)
+
+	if err := decodeBody(req, &args.Policy, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Policy decoding failed: %v", err)}
+	}
+	if args.Policy.ID == "" {
+		return nil, BadRequestError{Reason: "Missing policy ID"}
+	}
+
+	args.Policy.Syntax = acl.SyntaxCurrent
+	_, args.Delete = req.URL.Query()["delete"]
+
+	var out structs.ACLPolicyImpactResponse
+	if err := s.agent.RPC("ACL.PolicyImpact", &args, &out); err != nil {
+		return nil, err
+	}
+
+	if out.Tokens == nil {
+		out.Tokens = make([]*structs.ACLTokenImpact, 0)
+	}
+
+	return out, nil
+}
+
+// PUT /v1/acl/policy/validate
+func (s *HTTPServer) ACLPolicyValidate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	// Only the rules are checked, they are parsed locally in the current
+	// syntax like the servers parse them when a policy is stored.
+	var policy structs.ACLPolicy
+	if err := decodeBody(req, &policy, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Policy decoding failed: %v", err)}
+	}
+
+	parsed, err := acl.ValidateRules(policy.Rules, nil)
+	if err != nil {
+		rerr := err.(*acl.RulesError)
+		return structs.ACLPolicyValidateResponse{
+			Error: &structs.ACLRulesError{
+				Message: rerr.Err.Error(),
+				Line:    rerr.Line,
+				Column:  rerr.Column,
+			},
+		}, nil
+	}
+
+	out := structs.ACLPolicyValidateResponse{Valid: true}
+	if _, ok := req.URL.Query()["canonical"]; ok {
+		if out.CanonicalRules, err = parsed.CanonicalRules(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *HTTPServer) ACLTokens(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		return s.ACLTokenList(resp, req)
+
+	case "DELETE":
+		return s.ACLTokenFilterDelete(resp, req)
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "DELETE"}}
+	}
+}
+
 func (s *HTTPServer) ACLTokenList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.checkACLDisabled(resp, req) {
 		return nil, nil
@@ -341,7 +521,23 @@ func (s *HTTPServer) ACLTokenList(resp http.ResponseWriter, req *http.Request) (
 		args.Datacenter = s.agent.config.Datacenter
 	}
 
-	args.Policy = req.URL.Query().Get("policy")
+	query := req.URL.Query()
+	args.Policy = query.Get("policy")
+	args.AccessorPrefix = query.Get("prefix")
+	if len(args.AccessorPrefix) > 36 || strings.Trim(args.AccessorPrefix, "0123456789abcdefABCDEF-") != "" {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Invalid accessor ID prefix: %q", args.AccessorPrefix)}
+	}
+	args.Next = query.Get("next")
+	args.Filter = query.Get("filter")
+	if err := validateACLListFilter(args.Filter, &structs.ACLToken{}); err != nil {
+		return nil, err
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if args.Limit, err = strconv.Atoi(limit); err != nil || args.Limit < 0 {
+			return nil, BadRequestError{Reason: fmt.Sprintf("Invalid limit: %q", limit)}
+		}
+	}
 
 	var out structs.ACLTokenListResponse
 	defer setMeta(resp, &out.QueryMeta)
@@ -349,6 +545,34 @@ func (s *HTTPServer) ACLTokenList(resp http.ResponseWriter, req *http.Request) (
 		return nil, err
 	}
 
+	// The accessor ID which starts the next page is passed as the next
+	// parameter of the following request.
+	if out.Next != "" {
+		resp.Header().Set("X-Consul-Next", out.Next)
+	}
+
+	return out.Tokens, nil
+}
+
+func (s *HTTPServer) ACLTokenFilterDelete(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	args := structs.ACLTokenFilterDeleteRequest{
+		Filter: req.URL.Query().Get("filter"),
+	}
+	if args.Filter == "" {
+		return nil, BadRequestError{Reason: "Missing filter parameter"}
+	}
+	_, args.DryRun = req.URL.Query()["dry-run"]
+	s.parseDC(req, &args.Datacenter)
+	s.parseToken(req, &args.Token)
+
+	var out structs.ACLTokenFilterDeleteResponse
+	if err := s.agent.RPC("ACL.TokenFilterDelete", &args, &out); err != nil {
+		return nil, err
+	}
 	return out.Tokens, nil
 }
 
@@ -460,7 +684,7 @@ func (s *HTTPServer) ACLTokenWrite(resp http.ResponseWriter, req *http.Request,
 	}
 	s.parseToken(req, &args.Token)
 
-	if err := decodeBody(req, &args.ACLToken, fixCreateTimeAndHash); err != nil {
+	if err := decodeBody(req, &args.ACLToken, fixTimeAndHashFields); err != nil {
 		return nil, BadRequestError{Reason: fmt.Sprintf("Token decoding failed: %v", err)}
 	}
 
@@ -501,7 +725,7 @@ func (s *HTTPServer) ACLTokenClone(resp http.ResponseWriter, req *http.Request,
 		Datacenter: s.agent.config.Datacenter,
 	}
 
-	if err := decodeBody(req, &args.ACLToken, fixCreateTimeAndHash); err != nil && err.Error() != "EOF" {
+	if err := decodeBody(req, &args.ACLToken, fixTimeAndHashFields); err != nil && err.Error() != "EOF" {
 		return nil, BadRequestError{Reason: fmt.Sprintf("Token decoding failed: %v", err)}
 	}
 	s.parseToken(req, &args.Token)
@@ -516,3 +740,416 @@ func (s *HTTPServer) ACLTokenClone(resp http.ResponseWriter, req *http.Request,
 
 	return &out, nil
 }
+
+func (s *HTTPServer) ACLAuthMethodList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	var args structs.ACLAuthMethodListRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	if args.Datacenter == "" {
+		args.Datacenter = s.agent.config.Datacenter
+	}
+
+	var out structs.ACLAuthMethodListResponse
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ACL.AuthMethodList", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// make sure we return an array and not nil
+	if out.AuthMethods == nil {
+		out.AuthMethods = make(structs.ACLAuthMethodListStubs, 0)
+	}
+
+	return out.AuthMethods, nil
+}
+
+func (s *HTTPServer) ACLAuthMethodCRUD(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	var fn func(resp http.ResponseWriter, req *http.Request, methodName string) (interface{}, error)
+
+	switch req.Method {
+	case "GET":
+		fn = s.ACLAuthMethodRead
+
+	case "PUT":
+		fn = s.ACLAuthMethodWrite
+
+	case "DELETE":
+		fn = s.ACLAuthMethodDelete
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+
+	methodName := strings.TrimPrefix(req.URL.Path, "/v1/acl/auth-method/")
+	if methodName == "" && req.Method != "PUT" {
+		return nil, BadRequestError{Reason: "Missing auth method name"}
+	}
+
+	return fn(resp, req, methodName)
+}
+
+func (s *HTTPServer) ACLAuthMethodRead(resp http.ResponseWriter, req *http.Request, methodName string) (interface{}, error) {
+	args := structs.ACLAuthMethodReadRequest{
+		Datacenter:     s.agent.config.Datacenter,
+		AuthMethodName: methodName,
+	}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	if args.Datacenter == "" {
+		args.Datacenter = s.agent.config.Datacenter
+	}
+
+	var out structs.ACLAuthMethodResponse
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ACL.AuthMethodRead", &args, &out); err != nil {
+		return nil, err
+	}
+
+	if out.AuthMethod == nil {
+		return nil, acl.ErrNotFound
+	}
+
+	fixupAuthMethodConfig(out.AuthMethod)
+	return out.AuthMethod, nil
+}
+
+func (s *HTTPServer) ACLAuthMethodCreate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	return s.ACLAuthMethodWrite(resp, req, "")
+}
+
+// fixAuthMethodFields is used to help in decoding the MaxTokenTTL attribute
+// of the auth method create/update requests, which is given as a duration
+// string.
+func fixAuthMethodFields(raw interface{}) error {
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if val, ok := rawMap["MaxTokenTTL"]; ok {
+		if sval, ok := val.(string); ok {
+			d, err := time.ParseDuration(sval)
+			if err != nil {
+				return err
+			}
+			rawMap["MaxTokenTTL"] = d
+		}
+	}
+	return nil
+}
+
+func (s *HTTPServer) ACLAuthMethodWrite(resp http.ResponseWriter, req *http.Request, methodName string) (interface{}, error) {
+	args := structs.ACLAuthMethodUpsertRequest{
+		Datacenter: s.agent.config.Datacenter,
+	}
+	s.parseToken(req, &args.Token)
+
+	if err := decodeBody(req, &args.AuthMethod, fixAuthMethodFields); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Auth method decoding failed: %v", err)}
+	}
+
+	if methodName != "" {
+		if args.AuthMethod.Name != "" && args.AuthMethod.Name != methodName {
+			return nil, BadRequestError{Reason: "Auth method Name in URL and payload do not match"}
+		} else if args.AuthMethod.Name == "" {
+			args.AuthMethod.Name = methodName
+		}
+	}
+
+	var out structs.ACLAuthMethod
+	if err := s.agent.RPC("ACL.AuthMethodUpsert", args, &out); err != nil {
+		return nil, err
+	}
+
+	fixupAuthMethodConfig(&out)
+	return &out, nil
+}
+
+func (s *HTTPServer) ACLAuthMethodDelete(resp http.ResponseWriter, req *http.Request, methodName string) (interface{}, error) {
+	args := structs.ACLAuthMethodDeleteRequest{
+		Datacenter:     s.agent.config.Datacenter,
+		AuthMethodName: methodName,
+	}
+	s.parseToken(req, &args.Token)
+
+	var out string
+	if err := s.agent.RPC("ACL.AuthMethodDelete", args, &out); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+// fixupAuthMethodConfig converts the string values of the auth method config,
+// which come back as []uint8 from the RPC, so they are not base64 encoded in
+// the JSON response.
+func fixupAuthMethodConfig(method *structs.ACLAuthMethod) {
+	for k, v := range method.Config {
+		method.Config[k] = fixupAuthMethodConfigValue(v)
+	}
+}
+
+// fixupAuthMethodConfigValue converts the strings of a config value, which
+// msgpack decodes as []uint8, back to strings, including those nested in
+// lists and maps, whose keys it also decodes as interface{}.
+func fixupAuthMethodConfigValue(v interface{}) interface{} {
+	switch raw := v.(type) {
+	case []uint8:
+		return structs.Uint8ToString(raw)
+	case []interface{}:
+		for i, item := range raw {
+			raw[i] = fixupAuthMethodConfigValue(item)
+		}
+	case map[string]interface{}:
+		for k, item := range raw {
+			raw[k] = fixupAuthMethodConfigValue(item)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(raw))
+		for k, item := range raw {
+			m[fmt.Sprintf("%v", fixupAuthMethodConfigValue(k))] = fixupAuthMethodConfigValue(item)
+		}
+		return m
+	}
+	return v
+}
+
+func (s *HTTPServer) ACLBindingRuleList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	var args structs.ACLBindingRuleListRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	if args.Datacenter == "" {
+		args.Datacenter = s.agent.config.Datacenter
+	}
+
+	args.AuthMethod = req.URL.Query().Get("authmethod")
+
+	var out structs.ACLBindingRuleListResponse
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ACL.BindingRuleList", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// make sure we return an array and not nil
+	if out.BindingRules == nil {
+		out.BindingRules = make(structs.ACLBindingRules, 0)
+	}
+
+	return out.BindingRules, nil
+}
+
+func (s *HTTPServer) ACLBindingRuleCRUD(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	var fn func(resp http.ResponseWriter, req *http.Request, bindingRuleID string) (interface{}, error)
+
+	switch req.Method {
+	case "GET":
+		fn = s.ACLBindingRuleRead
+
+	case "PUT":
+		fn = s.ACLBindingRuleWrite
+
+	case "DELETE":
+		fn = s.ACLBindingRuleDelete
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+
+	bindingRuleID := strings.TrimPrefix(req.URL.Path, "/v1/acl/binding-rule/")
+	if bindingRuleID == "" && req.Method != "PUT" {
+		return nil, BadRequestError{Reason: "Missing binding rule ID"}
+	}
+
+	return fn(resp, req, bindingRuleID)
+}
+
+func (s *HTTPServer) ACLBindingRuleRead(resp http.ResponseWriter, req *http.Request, bindingRuleID string) (interface{}, error) {
+	args := structs.ACLBindingRuleReadRequest{
+		Datacenter:    s.agent.config.Datacenter,
+		BindingRuleID: bindingRuleID,
+	}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	if args.Datacenter == "" {
+		args.Datacenter = s.agent.config.Datacenter
+	}
+
+	var out structs.ACLBindingRuleResponse
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("ACL.BindingRuleRead", &args, &out); err != nil {
+		return nil, err
+	}
+
+	if out.BindingRule == nil {
+		return nil, acl.ErrNotFound
+	}
+
+	return out.BindingRule, nil
+}
+
+func (s *HTTPServer) ACLBindingRuleCreate(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	return s.ACLBindingRuleWrite(resp, req, "")
+}
+
+func (s *HTTPServer) ACLBindingRuleWrite(resp http.ResponseWriter, req *http.Request, bindingRuleID string) (interface{}, error) {
+	args := structs.ACLBindingRuleUpsertRequest{
+		Datacenter: s.agent.config.Datacenter,
+	}
+	s.parseToken(req, &args.Token)
+
+	if err := decodeBody(req, &args.BindingRule, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("BindingRule decoding failed: %v", err)}
+	}
+
+	if bindingRuleID != "" {
+		if args.BindingRule.ID != "" && args.BindingRule.ID != bindingRuleID {
+			return nil, BadRequestError{Reason: "BindingRule ID in URL and payload do not match"}
+		} else if args.BindingRule.ID == "" {
+			args.BindingRule.ID = bindingRuleID
+		}
+	}
+
+	var out structs.ACLBindingRule
+	if err := s.agent.RPC("ACL.BindingRuleUpsert", args, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func (s *HTTPServer) ACLBindingRuleDelete(resp http.ResponseWriter, req *http.Request, bindingRuleID string) (interface{}, error) {
+	args := structs.ACLBindingRuleDeleteRequest{
+		Datacenter:    s.agent.config.Datacenter,
+		BindingRuleID: bindingRuleID,
+	}
+	s.parseToken(req, &args.Token)
+
+	var ignored bool
+	if err := s.agent.RPC("ACL.BindingRuleDelete", args, &ignored); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func (s *HTTPServer) ACLLogin(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	args := &structs.ACLLoginRequest{
+		Datacenter: s.agent.config.Datacenter,
+		Auth:       &structs.ACLLoginParams{},
+	}
+	s.parseDC(req, &args.Datacenter)
+
+	if err := decodeBody(req, args.Auth, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Failed to decode request body: %v", err)}
+	}
+
+	var out structs.ACLToken
+	if err := s.agent.RPC("ACL.Login", args, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// ACLAuthorize decides whether the token of the request has the access to
+// each of the given resources, without attempting any operation. The token is
+// resolved by the ACL resolver of the agent, the same as for the checks the
+// agent does itself.
+func (s *HTTPServer) ACLAuthorize(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	var token string
+	s.parseToken(req, &token)
+
+	var checks []structs.ACLAuthorizationRequest
+	if err := decodeBody(req, &checks, nil); err != nil {
+		return nil, BadRequestError{Reason: fmt.Sprintf("Failed to decode request body: %v", err)}
+	}
+
+	var authz acl.Authorizer
+	if acl.RootAuthorizer(token) != nil {
+		return nil, acl.ErrRootDenied
+	} else if s.agent.tokens.IsAgentMasterToken(token) {
+		authz = s.agent.aclMasterAuthorizer
+	} else {
+		var err error
+		if authz, err = s.agent.delegate.ResolveToken(token); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]structs.ACLAuthorizationResponse, 0, len(checks))
+	for i, check := range checks {
+		allow, err := acl.Allowed(authz, check.Resource, check.Segment, check.Access)
+		if err != nil {
+			return nil, BadRequestError{Reason: fmt.Sprintf("Invalid check %d: %v", i, err)}
+		}
+		out = append(out, structs.ACLAuthorizationResponse{
+			ACLAuthorizationRequest: check,
+			Allow:                   allow,
+		})
+	}
+
+	return out, nil
+}
+
+func (s *HTTPServer) ACLLogout(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.checkACLDisabled(resp, req) {
+		return nil, nil
+	}
+
+	args := structs.ACLLogoutRequest{
+		Datacenter: s.agent.config.Datacenter,
+	}
+	s.parseDC(req, &args.Datacenter)
+	s.parseTokenWithoutResolvingProxyToken(req, &args.Token)
+
+	if args.Token == "" {
+		return nil, acl.ErrNotFound
+	}
+
+	var ignored bool
+	if err := s.agent.RPC("ACL.Logout", &args, &ignored); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
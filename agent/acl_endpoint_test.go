@@ -3,11 +3,17 @@ package agent
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/authmethod/testauth"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/stretchr/testify/require"
@@ -40,6 +46,16 @@ func TestACL_Disabled_Response(t *testing.T) {
 		{"ACLTokenCreate", a.srv.ACLTokenCreate},
 		{"ACLTokenSelf", a.srv.ACLTokenSelf},
 		{"ACLTokenCRUD", a.srv.ACLTokenCRUD},
+		{"ACLAuthMethodList", a.srv.ACLAuthMethodList},
+		{"ACLAuthMethodCreate", a.srv.ACLAuthMethodCreate},
+		{"ACLAuthMethodCRUD", a.srv.ACLAuthMethodCRUD},
+		{"ACLBindingRuleList", a.srv.ACLBindingRuleList},
+		{"ACLBindingRuleCreate", a.srv.ACLBindingRuleCreate},
+		{"ACLBindingRuleCRUD", a.srv.ACLBindingRuleCRUD},
+		{"ACLLogin", a.srv.ACLLogin},
+		{"ACLLogout", a.srv.ACLLogout},
+		{"ACLAuthorize", a.srv.ACLAuthorize},
+		{"ACLPolicyValidate", a.srv.ACLPolicyValidate},
 	}
 	testrpc.WaitForLeader(t, a.RPC, "dc1")
 	for _, tt := range tests {
@@ -110,6 +126,61 @@ func TestACL_Bootstrap(t *testing.T) {
 	}
 }
 
+func TestACL_Bootstrap_RateLimit(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	a := &TestAgent{Name: t.Name(), LogOutput: buf, HCL: TestACLConfig() + `
+		acl_master_token = ""
+		limits {
+			acl_bootstrap_rate = 0.001
+			acl_bootstrap_max_burst = 2
+		}
+	`}
+	a.Start()
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	codes := []int{http.StatusOK, http.StatusForbidden, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i, code := range codes {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/v1/acl/bootstrap", nil)
+		req.RemoteAddr = "1.2.3.4:5678"
+		if _, err := a.srv.ACLBootstrap(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got, want := resp.Code, code; got != want {
+			t.Fatalf("attempt %d: got %d want %d", i, got, want)
+		}
+	}
+
+	// Other sources aren't limited.
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/v1/acl/bootstrap", nil)
+	req.RemoteAddr = "5.6.7.8:5678"
+	if _, err := a.srv.ACLBootstrap(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := resp.Code, http.StatusForbidden; got != want {
+		t.Fatalf("got %d want %d", got, want)
+	}
+
+	for _, want := range []string{
+		"[INFO] http: ACL bootstrap attempt result=success from=1.2.3.4",
+		"[WARN] http: ACL bootstrap attempt result=denied from=1.2.3.4",
+		"[WARN] http: ACL bootstrap attempt result=rate_limited from=1.2.3.4 attempts=1",
+		"[WARN] http: ACL bootstrap attempt result=denied from=5.6.7.8",
+	} {
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Fatalf("got %s want %s", got, want)
+		}
+	}
+
+	// Further rate limited attempts aren't logged one by one.
+	if got := strings.Count(buf.String(), "result=rate_limited"); got != 1 {
+		t.Fatalf("got %d rate limited reports want 1", got)
+	}
+}
+
 func TestACL_HTTP(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), TestACLConfig())
@@ -344,6 +415,22 @@ func TestACL_HTTP(t *testing.T) {
 			}
 		})
 
+		t.Run("List Filtered", func(t *testing.T) {
+			filter := url.QueryEscape(`Name == "read-all-nodes" or Name == global-management`)
+			req, _ := http.NewRequest("GET", "/v1/acl/policies?token=root&filter="+filter, nil)
+			resp := httptest.NewRecorder()
+			raw, err := a.srv.ACLPolicyList(resp, req)
+			require.NoError(t, err)
+			policies := raw.(structs.ACLPolicyListStubs)
+			require.Len(t, policies, 2)
+
+			// The selectors are validated before the request is sent
+			req, _ = http.NewRequest("GET", "/v1/acl/policies?token=root&filter="+url.QueryEscape("CreateTime > 0"), nil)
+			_, err = a.srv.ACLPolicyList(httptest.NewRecorder(), req)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		})
+
 		t.Run("Read", func(t *testing.T) {
 			req, _ := http.NewRequest("GET", "/v1/acl/policy/"+idMap["policy-read-all-nodes"]+"?token=root", nil)
 			resp := httptest.NewRecorder()
@@ -592,5 +679,505 @@ func TestACL_HTTP(t *testing.T) {
 			require.Len(t, token.Policies, 1)
 			require.Equal(t, structs.ACLPolicyGlobalManagementID, token.Policies[0].ID)
 		})
+		t.Run("List Paginated", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/tokens?token=root&limit=3", nil)
+			resp := httptest.NewRecorder()
+			raw, err := a.srv.ACLTokenList(resp, req)
+			require.NoError(t, err)
+			page := raw.(structs.ACLTokenListStubs)
+			require.Len(t, page, 3)
+			next := resp.Header().Get("X-Consul-Next")
+			require.NotEmpty(t, next)
+			require.True(t, page[2].AccessorID < next)
+
+			req, _ = http.NewRequest("GET", "/v1/acl/tokens?token=root&limit=3&next="+next, nil)
+			resp = httptest.NewRecorder()
+			raw, err = a.srv.ACLTokenList(resp, req)
+			require.NoError(t, err)
+			page = raw.(structs.ACLTokenListStubs)
+			require.Len(t, page, 1)
+			require.Equal(t, next, page[0].AccessorID)
+			require.Empty(t, resp.Header().Get("X-Consul-Next"))
+
+			req, _ = http.NewRequest("GET", "/v1/acl/tokens?token=root&prefix="+next[:9], nil)
+			resp = httptest.NewRecorder()
+			raw, err = a.srv.ACLTokenList(resp, req)
+			require.NoError(t, err)
+			page = raw.(structs.ACLTokenListStubs)
+			require.Len(t, page, 1)
+			require.Equal(t, next, page[0].AccessorID)
+
+			for _, query := range []string{"limit=-1", "limit=x", "prefix=nothex"} {
+				req, _ = http.NewRequest("GET", "/v1/acl/tokens?token=root&"+query, nil)
+				_, err = a.srv.ACLTokenList(httptest.NewRecorder(), req)
+				_, ok := err.(BadRequestError)
+				require.True(t, ok, query)
+			}
+		})
+		t.Run("List Filtered", func(t *testing.T) {
+			filter := url.QueryEscape(`Policies.Name == global-management and CreateTime > 2019-01-01`)
+			req, _ := http.NewRequest("GET", "/v1/acl/tokens?token=root&filter="+filter, nil)
+			resp := httptest.NewRecorder()
+			raw, err := a.srv.ACLTokenList(resp, req)
+			require.NoError(t, err)
+			tokens := raw.(structs.ACLTokenListStubs)
+			require.Len(t, tokens, 1)
+			require.Equal(t, "Master Token", tokens[0].Description)
+
+			req, _ = http.NewRequest("GET", "/v1/acl/tokens?token=root&filter="+url.QueryEscape("Description =="), nil)
+			_, err = a.srv.ACLTokenList(httptest.NewRecorder(), req)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		})
+		t.Run("Filter Delete", func(t *testing.T) {
+			filter := url.QueryEscape(`Description == "local" and Local == true`)
+			req, _ := http.NewRequest("DELETE", "/v1/acl/tokens?token=root&dry-run&filter="+filter, nil)
+			resp := httptest.NewRecorder()
+			raw, err := a.srv.ACLTokens(resp, req)
+			require.NoError(t, err)
+			tokens, ok := raw.(structs.ACLTokenListStubs)
+			require.True(t, ok)
+			require.Len(t, tokens, 1)
+			require.Equal(t, "local", tokens[0].Description)
+
+			req, _ = http.NewRequest("DELETE", "/v1/acl/tokens?token=root&filter="+filter, nil)
+			resp = httptest.NewRecorder()
+			raw, err = a.srv.ACLTokens(resp, req)
+			require.NoError(t, err)
+			require.Len(t, raw.(structs.ACLTokenListStubs), 1)
+
+			req, _ = http.NewRequest("GET", "/v1/acl/tokens?token=root", nil)
+			resp = httptest.NewRecorder()
+			raw, err = a.srv.ACLTokens(resp, req)
+			require.NoError(t, err)
+			require.Len(t, raw.(structs.ACLTokenListStubs), 3)
+
+			// a filter is required
+			req, _ = http.NewRequest("DELETE", "/v1/acl/tokens?token=root", nil)
+			resp = httptest.NewRecorder()
+			_, err = a.srv.ACLTokens(resp, req)
+			_, ok = err.(BadRequestError)
+			require.True(t, ok)
+		})
+		t.Run("Create with Expiration TTL", func(t *testing.T) {
+			body := bytes.NewBufferString(`{"Description": "temporary", "ExpirationTTL": "1h"}`)
+			req, _ := http.NewRequest("PUT", "/v1/acl/token?token=root", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLTokenCreate(resp, req)
+			require.NoError(t, err)
+
+			token, ok := obj.(*structs.ACLToken)
+			require.True(t, ok)
+			require.Zero(t, token.ExpirationTTL)
+			require.NotNil(t, token.ExpirationTime)
+			require.Equal(t, token.CreateTime.Add(time.Hour), *token.ExpirationTime)
+
+			// the expiration time is passed back unchanged on update
+			body = bytes.NewBufferString(fmt.Sprintf(`{"Description": "still temporary", "ExpirationTime": %q}`,
+				token.ExpirationTime.Format(time.RFC3339Nano)))
+			req, _ = http.NewRequest("PUT", "/v1/acl/token/"+token.AccessorID+"?token=root", body)
+			resp = httptest.NewRecorder()
+			obj, err = a.srv.ACLTokenCRUD(resp, req)
+			require.NoError(t, err)
+			updated, ok := obj.(*structs.ACLToken)
+			require.True(t, ok)
+			require.Equal(t, "still temporary", updated.Description)
+			require.True(t, token.ExpirationTime.Equal(*updated.ExpirationTime))
+		})
+	})
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+	testauth.InstallSessionToken(testSessionID, "fake-web", "default", "web", "abc123")
+
+	t.Run("AuthMethod", func(t *testing.T) {
+		t.Run("Create", func(t *testing.T) {
+			body := bytes.NewBufferString(fmt.Sprintf(`{
+				"Name": "test",
+				"Type": "testing",
+				"Description": "test auth method",
+				"Policies": [{"Name": "test"}],
+				"MaxTokenTTL": "10m",
+				"Config": {"SessionID": %q}
+			}`, testSessionID))
+			req, _ := http.NewRequest("PUT", "/v1/acl/auth-method?token=root", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLAuthMethodCreate(resp, req)
+			require.NoError(t, err)
+
+			method, ok := obj.(*structs.ACLAuthMethod)
+			require.True(t, ok)
+			require.Equal(t, "test", method.Name)
+			require.Equal(t, "testing", method.Type)
+			require.Equal(t, 10*time.Minute, method.MaxTokenTTL)
+			require.Equal(t, []structs.ACLTokenPolicyLink{{ID: idMap["policy-test"]}}, method.Policies)
+			require.Equal(t, testSessionID, method.Config["SessionID"])
+			require.True(t, method.CreateIndex > 0)
+		})
+
+		t.Run("Update Name Mismatch", func(t *testing.T) {
+			body := bytes.NewBufferString(`{"Name": "other"}`)
+			req, _ := http.NewRequest("PUT", "/v1/acl/auth-method/test?token=root", body)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLAuthMethodCRUD(resp, req)
+			require.Error(t, err)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		})
+
+		t.Run("Update", func(t *testing.T) {
+			body := bytes.NewBufferString(fmt.Sprintf(`{
+				"Description": "updated",
+				"Policies": [{"Name": "test"}],
+				"MaxTokenTTL": "10m",
+				"Config": {"SessionID": %q}
+			}`, testSessionID))
+			req, _ := http.NewRequest("PUT", "/v1/acl/auth-method/test?token=root", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLAuthMethodCRUD(resp, req)
+			require.NoError(t, err)
+
+			method, ok := obj.(*structs.ACLAuthMethod)
+			require.True(t, ok)
+			require.Equal(t, "test", method.Name)
+			require.Equal(t, "testing", method.Type)
+			require.Equal(t, "updated", method.Description)
+		})
+
+		t.Run("Read", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/auth-method/test?token=root", nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLAuthMethodCRUD(resp, req)
+			require.NoError(t, err)
+
+			method, ok := obj.(*structs.ACLAuthMethod)
+			require.True(t, ok)
+			require.Equal(t, "updated", method.Description)
+			// The config values are strings again once the response is
+			// prepared.
+			require.Equal(t, testSessionID, method.Config["SessionID"])
+		})
+
+		t.Run("Read Missing", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/auth-method/missing?token=root", nil)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLAuthMethodCRUD(resp, req)
+			require.Error(t, err)
+			require.True(t, acl.IsErrNotFound(err))
+		})
+
+		t.Run("List", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/auth-methods?token=root", nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLAuthMethodList(resp, req)
+			require.NoError(t, err)
+
+			methods, ok := obj.(structs.ACLAuthMethodListStubs)
+			require.True(t, ok)
+			require.Len(t, methods, 1)
+			require.Equal(t, "test", methods[0].Name)
+		})
+	})
+
+	t.Run("BindingRule", func(t *testing.T) {
+		var ruleID string
+		t.Run("Create", func(t *testing.T) {
+			body := bytes.NewBufferString(`{
+				"AuthMethod": "test",
+				"Description": "test binding rule",
+				"Selector": "serviceaccount.namespace == default",
+				"BindType": "service",
+				"BindName": "${serviceaccount.name}"
+			}`)
+			req, _ := http.NewRequest("PUT", "/v1/acl/binding-rule?token=root", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLBindingRuleCreate(resp, req)
+			require.NoError(t, err)
+
+			rule, ok := obj.(*structs.ACLBindingRule)
+			require.True(t, ok)
+			require.NotEmpty(t, rule.ID)
+			require.Equal(t, "test", rule.AuthMethod)
+			require.Equal(t, "${serviceaccount.name}", rule.BindName)
+			ruleID = rule.ID
+		})
+
+		t.Run("Create Unknown Field", func(t *testing.T) {
+			body := bytes.NewBufferString(`{
+				"AuthMethod": "test",
+				"BindType": "service",
+				"BindName": "${serviceaccount.missing}"
+			}`)
+			req, _ := http.NewRequest("PUT", "/v1/acl/binding-rule?token=root", body)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLBindingRuleCreate(resp, req)
+			require.Error(t, err)
+		})
+
+		t.Run("Update", func(t *testing.T) {
+			body := bytes.NewBufferString(`{
+				"Description": "updated",
+				"Selector": "serviceaccount.namespace == default",
+				"BindType": "service",
+				"BindName": "${serviceaccount.name}"
+			}`)
+			req, _ := http.NewRequest("PUT", "/v1/acl/binding-rule/"+ruleID+"?token=root", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLBindingRuleCRUD(resp, req)
+			require.NoError(t, err)
+
+			rule, ok := obj.(*structs.ACLBindingRule)
+			require.True(t, ok)
+			require.Equal(t, "updated", rule.Description)
+			require.Equal(t, "test", rule.AuthMethod)
+		})
+
+		t.Run("Read", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/binding-rule/"+ruleID+"?token=root", nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLBindingRuleCRUD(resp, req)
+			require.NoError(t, err)
+
+			rule, ok := obj.(*structs.ACLBindingRule)
+			require.True(t, ok)
+			require.Equal(t, "updated", rule.Description)
+		})
+
+		t.Run("List", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/acl/binding-rules?authmethod=test&token=root", nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLBindingRuleList(resp, req)
+			require.NoError(t, err)
+
+			rules, ok := obj.(structs.ACLBindingRules)
+			require.True(t, ok)
+			require.Len(t, rules, 1)
+			require.Equal(t, ruleID, rules[0].ID)
+		})
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		var token *structs.ACLToken
+		t.Run("Login", func(t *testing.T) {
+			body := bytes.NewBufferString(`{"AuthMethod": "test", "BearerToken": "fake-web"}`)
+			req, _ := http.NewRequest("POST", "/v1/acl/login", body)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.ACLLogin(resp, req)
+			require.NoError(t, err)
+
+			var ok bool
+			token, ok = obj.(*structs.ACLToken)
+			require.True(t, ok)
+			require.Equal(t, "test", token.AuthMethod)
+			require.True(t, token.Local)
+			require.NotNil(t, token.ExpirationTime)
+			require.Equal(t, []*structs.ACLServiceIdentity{{ServiceName: "web"}}, token.ServiceIdentities)
+		})
+
+		t.Run("Login Invalid", func(t *testing.T) {
+			body := bytes.NewBufferString(`{"AuthMethod": "test", "BearerToken": "fake-db"}`)
+			req, _ := http.NewRequest("POST", "/v1/acl/login", body)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLLogin(resp, req)
+			require.Error(t, err)
+			require.True(t, acl.IsErrPermissionDenied(err))
+		})
+
+		t.Run("Logout", func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1/acl/logout", nil)
+			req.Header.Set("X-Consul-Token", token.SecretID)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLLogout(resp, req)
+			require.NoError(t, err)
+
+			req, _ = http.NewRequest("GET", "/v1/acl/token/"+token.AccessorID+"?token=root", nil)
+			resp = httptest.NewRecorder()
+			_, err = a.srv.ACLTokenCRUD(resp, req)
+			require.Error(t, err)
+			require.True(t, acl.IsErrNotFound(err))
+		})
+
+		t.Run("Delete Auth Method", func(t *testing.T) {
+			req, _ := http.NewRequest("DELETE", "/v1/acl/auth-method/test?token=root", nil)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLAuthMethodCRUD(resp, req)
+			require.NoError(t, err)
+
+			req, _ = http.NewRequest("GET", "/v1/acl/auth-methods?token=root", nil)
+			resp = httptest.NewRecorder()
+			obj, err := a.srv.ACLAuthMethodList(resp, req)
+			require.NoError(t, err)
+			require.Len(t, obj, 0)
+
+			// The binding rules of the auth method are deleted with it
+			req, _ = http.NewRequest("GET", "/v1/acl/binding-rules?token=root", nil)
+			resp = httptest.NewRecorder()
+			obj, err = a.srv.ACLBindingRuleList(resp, req)
+			require.NoError(t, err)
+			require.Len(t, obj, 0)
+		})
+	})
+}
+
+func TestACL_Authorize(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	policyInput := &structs.ACLPolicy{
+		Name: "web",
+		Rules: `
+			service "web" { policy = "write" }
+			node_prefix "" { policy = "read" }
+			key_prefix "app/" { policy = "list" }
+		`,
+	}
+	req, _ := http.NewRequest("PUT", "/v1/acl/policy?token=root", jsonBody(policyInput))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.ACLPolicyCreate(resp, req)
+	require.NoError(t, err)
+	policy := obj.(*structs.ACLPolicy)
+
+	tokenInput := &structs.ACLToken{
+		Policies: []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+	}
+	req, _ = http.NewRequest("PUT", "/v1/acl/token?token=root", jsonBody(tokenInput))
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.ACLTokenCreate(resp, req)
+	require.NoError(t, err)
+	token := obj.(*structs.ACLToken)
+
+	checks := []structs.ACLAuthorizationRequest{
+		{Resource: "service", Segment: "web", Access: "write"},
+		{Resource: "service", Segment: "db", Access: "read"},
+		{Resource: "node", Segment: "foo", Access: "read"},
+		{Resource: "node", Segment: "foo", Access: "write"},
+		{Resource: "key", Segment: "app/config", Access: "list"},
+		{Resource: "key", Segment: "app/config", Access: "write"},
+		{Resource: "operator", Access: "read"},
+	}
+
+	t.Run("Token", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/acl/authorize?token="+token.SecretID, jsonBody(checks))
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.ACLAuthorize(resp, req)
+		require.NoError(t, err)
+
+		out, ok := obj.([]structs.ACLAuthorizationResponse)
+		require.True(t, ok)
+		require.Len(t, out, len(checks))
+		var allowed []bool
+		for i, decision := range out {
+			require.Equal(t, checks[i], decision.ACLAuthorizationRequest)
+			allowed = append(allowed, decision.Allow)
+		}
+		require.Equal(t, []bool{true, false, true, false, true, false, false}, allowed)
+	})
+
+	t.Run("Master Token", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/acl/authorize?token=root", jsonBody(checks))
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.ACLAuthorize(resp, req)
+		require.NoError(t, err)
+
+		for _, decision := range obj.([]structs.ACLAuthorizationResponse) {
+			require.True(t, decision.Allow)
+		}
+	})
+
+	t.Run("Agent Master Token", func(t *testing.T) {
+		agentChecks := []structs.ACLAuthorizationRequest{
+			{Resource: "agent", Segment: a.Config.NodeName, Access: "write"},
+			{Resource: "service", Segment: "web", Access: "read"},
+		}
+		req, _ := http.NewRequest("POST", "/v1/acl/authorize?token=towel", jsonBody(agentChecks))
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.ACLAuthorize(resp, req)
+		require.NoError(t, err)
+
+		out := obj.([]structs.ACLAuthorizationResponse)
+		require.True(t, out[0].Allow)
+		require.False(t, out[1].Allow)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, check := range []structs.ACLAuthorizationRequest{
+			{Resource: "nope", Access: "read"},
+			{Resource: "service", Segment: "web", Access: "list"},
+		} {
+			body := []structs.ACLAuthorizationRequest{check}
+			req, _ := http.NewRequest("POST", "/v1/acl/authorize?token=root", jsonBody(body))
+			resp := httptest.NewRecorder()
+			_, err := a.srv.ACLAuthorize(resp, req)
+			require.Error(t, err)
+			_, ok := err.(BadRequestError)
+			require.True(t, ok)
+		}
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/acl/authorize?token=8f0d3d4a-7d3c-4d4e-9a53-6b5c0f6a3c2e", jsonBody(checks))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.ACLAuthorize(resp, req)
+		require.True(t, acl.IsErrNotFound(err))
+	})
+}
+
+func TestACL_PolicyValidate(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	validate := func(t *testing.T, url, rules string) structs.ACLPolicyValidateResponse {
+		policy := &structs.ACLPolicy{Rules: rules}
+		req, _ := http.NewRequest("PUT", url, jsonBody(policy))
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.ACLPolicyValidate(resp, req)
+		require.NoError(t, err)
+		out, ok := obj.(structs.ACLPolicyValidateResponse)
+		require.True(t, ok)
+		return out
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		out := validate(t, "/v1/acl/policy/validate", `service "web" { policy = "read" }`)
+		require.True(t, out.Valid)
+		require.Nil(t, out.Error)
+		require.Empty(t, out.CanonicalRules)
+	})
+
+	t.Run("Canonical", func(t *testing.T) {
+		out := validate(t, "/v1/acl/policy/validate?canonical", `service "web" { policy = "read" }`)
+		require.True(t, out.Valid)
+		require.Equal(t, "service \"web\" {\n  policy = \"read\"\n}\n", out.CanonicalRules)
+	})
+
+	t.Run("Syntax Error", func(t *testing.T) {
+		out := validate(t, "/v1/acl/policy/validate?canonical", "service \"web\" {\n  policy = read\n}")
+		require.False(t, out.Valid)
+		require.Empty(t, out.CanonicalRules)
+		require.Equal(t, 2, out.Error.Line)
+		require.Equal(t, 12, out.Error.Column)
+		require.Contains(t, out.Error.Message, "Unknown token")
+	})
+
+	t.Run("Invalid Policy", func(t *testing.T) {
+		out := validate(t, "/v1/acl/policy/validate", "acl = \"read\"\nservice \"web\" {\n  policy = \"admin\"\n}")
+		require.False(t, out.Valid)
+		require.Equal(t, 2, out.Error.Line)
+		require.Equal(t, 1, out.Error.Column)
+		require.Contains(t, out.Error.Message, "Invalid service policy")
+	})
+
+	t.Run("Nothing Stored", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/acl/policies?token=root", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.ACLPolicyList(resp, req)
+		require.NoError(t, err)
+		require.Len(t, obj.(structs.ACLPolicyListStubs), 1)
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/lib"
 )
 
@@ -75,6 +76,17 @@ type StateSyncer struct {
 	// in a non-blocking way.
 	SyncChanges *Trigger
 
+	// RetryMaxInterval caps the interval between retries of a failed full
+	// sync, which doubles with every consecutive failure.
+	RetryMaxInterval time.Duration
+
+	// RetryJitter is the fraction of the retry interval which is added as
+	// a staggered random delay.
+	RetryJitter float64
+
+	// failures is the number of consecutive failed full syncs.
+	failures int
+
 	// paused stores whether sync runs are temporarily disabled.
 	pauseLock sync.Mutex
 	paused    int
@@ -115,6 +127,8 @@ func NewStateSyncer(state SyncState, intv time.Duration, shutdownCh chan struct{
 		Logger:            logger,
 		SyncFull:          NewTrigger(),
 		SyncChanges:       NewTrigger(),
+		RetryMaxInterval:  retryFailIntv,
+		RetryJitter:       1,
 		serverUpInterval:  serverUpIntv,
 		retryFailInterval: retryFailIntv,
 	}
@@ -167,9 +181,12 @@ func (s *StateSyncer) nextFSMState(fs fsmState) fsmState {
 		err := s.State.SyncFull()
 		if err != nil {
 			s.Logger.Printf("[ERR] agent: failed to sync remote state: %v", err)
+			metrics.IncrCounter([]string{"agent", "anti_entropy", "retry"}, 1)
+			s.failures++
 			return retryFullSyncState
 		}
 
+		s.failures = 0
 		return partialSyncState
 
 	case retryFullSyncState:
@@ -242,7 +259,7 @@ func (s *StateSyncer) retrySyncFullEventFn() event {
 
 	// retry full sync after some time
 	// todo(fs): why don't we use s.Interval here?
-	case <-time.After(s.retryFailInterval + s.stagger(s.retryFailInterval)):
+	case <-time.After(s.retryInterval()):
 		return syncFullTimerEvent
 
 	case <-s.ShutdownCh:
@@ -280,6 +297,20 @@ func (s *StateSyncer) syncChangesEventFn() event {
 	}
 }
 
+// retryInterval returns the time to wait before a failed full sync is
+// retried. The interval backs off exponentially with the number of
+// consecutive failures up to RetryMaxInterval, and the jitter is staggered
+// by cluster size like the other sync runs.
+func (s *StateSyncer) retryInterval() time.Duration {
+	retry := s.failures - 1
+	if retry < 0 {
+		retry = 0
+	}
+	b := lib.Backoff{Min: s.retryFailInterval, Max: s.RetryMaxInterval}
+	d := b.Interval(retry)
+	return d + s.stagger(time.Duration(float64(d)*s.RetryJitter))
+}
+
 // stubbed out for testing
 var libRandomStagger = lib.RandomStagger
 
@@ -317,6 +317,48 @@ func TestAE_RetrySyncFullEvent(t *testing.T) {
 	})
 }
 
+func TestAE_retryInterval(t *testing.T) {
+	l := testSyncer()
+	l.retryFailInterval = 10 * time.Second
+	l.RetryMaxInterval = time.Minute
+	l.RetryJitter = 0.5
+
+	// the stagger of testSyncer returns the full jitter
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 15 * time.Second},
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, 60 * time.Second},
+		{4, 90 * time.Second},
+		{10, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		l.failures = tt.failures
+		if got := l.retryInterval(); got != tt.want {
+			t.Fatalf("%d failures: got %v want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAE_FSM_failuresResetAfterSync(t *testing.T) {
+	l := testSyncer()
+	l.State = &mock{syncFull: func() error { return errors.New("boom") }}
+	l.nextFSMState(fullSyncState)
+	l.nextFSMState(fullSyncState)
+	if got, want := l.failures, 2; got != want {
+		t.Fatalf("got %d failures want %d", got, want)
+	}
+
+	l.State = &mock{}
+	l.nextFSMState(fullSyncState)
+	if got, want := l.failures, 0; got != want {
+		t.Fatalf("got %d failures want %d", got, want)
+	}
+}
+
 func TestAE_SyncChangesEvent(t *testing.T) {
 	t.Run("trigger shutdownEvent", func(t *testing.T) {
 		l := testSyncer()
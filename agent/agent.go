@@ -28,6 +28,8 @@ import (
 	"github.com/hashicorp/consul/agent/checks"
 	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/agent/consul"
+	"github.com/hashicorp/consul/agent/discovery"
+	"github.com/hashicorp/consul/agent/exec"
 	"github.com/hashicorp/consul/agent/local"
 	"github.com/hashicorp/consul/agent/proxycfg"
 	"github.com/hashicorp/consul/agent/proxyprocess"
@@ -40,6 +42,8 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/lib/file"
 	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/render"
+	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/consul/watch"
 	"github.com/hashicorp/go-multierror"
@@ -89,6 +93,7 @@ type delegate interface {
 	JoinLAN(addrs []string) (n int, err error)
 	RemoveFailedNode(node string) error
 	ResolveToken(secretID string) (acl.Authorizer, error)
+	TokenAccessorID(secretID string) string
 	RPC(method string, args interface{}, reply interface{}) error
 	ACLsEnabled() bool
 	UseLegacyACLs() bool
@@ -99,7 +104,7 @@ type delegate interface {
 	enterpriseDelegate
 }
 
-// notifier is called after a successful JoinLAN.
+// notifier is used to report the state of the agent to systemd.
 type notifier interface {
 	Notify(string) error
 }
@@ -126,6 +131,14 @@ type Agent struct {
 	// In-memory sink used for collecting metrics
 	MemSink *metrics.InmemSink
 
+	// configSourcesFn computes the sources of the fields of the runtime
+	// configuration, which are cached in configSources on first use since
+	// the attribution is expensive. It is only used for introspection and
+	// may be nil.
+	configSourcesFn   func() (map[string][]string, error)
+	configSources     map[string][]string
+	configSourcesLock sync.Mutex
+
 	// delegate is either a *consul.Server or *consul.Client
 	// depending on the configuration
 	delegate delegate
@@ -153,6 +166,10 @@ type Agent struct {
 	// reap its associated service
 	checkReapAfter map[types.CheckID]time.Duration
 
+	// checkTypes maps the check ID to the definition the check was
+	// registered with so that it can be updated at runtime
+	checkTypes map[types.CheckID]*structs.CheckType
+
 	// checkMonitors maps the check ID to an associated monitor
 	checkMonitors map[types.CheckID]*checks.CheckMonitor
 
@@ -177,6 +194,12 @@ type Agent struct {
 	// checkLock protects updates to the check* maps
 	checkLock sync.Mutex
 
+	// serviceWarmups maps the ID of local services which registered with a
+	// warmup period to the end of it. Entries outlive the warmup so that
+	// reloading the configuration doesn't start it over again.
+	serviceWarmups     map[string]time.Time
+	serviceWarmupsLock sync.Mutex
+
 	// dockerClient is the client for performing docker health checks.
 	dockerClient *checks.DockerClient
 
@@ -192,14 +215,23 @@ type Agent struct {
 	eventLock   sync.RWMutex
 	eventNotify NotifyGroup
 
-	reloadCh chan chan error
+	reloadCh chan chan ReloadResponse
 
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
 
-	// joinLANNotifier is called after a successful JoinLAN.
-	joinLANNotifier notifier
+	// systemdNotifier reports startup completion, reloads and shutdown
+	// to systemd and sends the watchdog keepalives.
+	systemdNotifier notifier
+
+	// systemdWatchdogInterval returns the timeout of the systemd watchdog,
+	// or zero if it isn't enabled.
+	systemdWatchdogInterval func() (time.Duration, error)
+
+	// ready is set to 1 once startup completion has been reported to
+	// systemd. It must be accessed atomically.
+	ready int32
 
 	// retryJoinCh transports errors from the retry join
 	// attempts.
@@ -214,6 +246,10 @@ type Agent struct {
 	// dnsServer provides the DNS API
 	dnsServers []*DNSServer
 
+	// dnsRecursors holds the upstream DNS servers of the DNS servers and
+	// tracks their health.
+	dnsRecursors *recursorPool
+
 	// httpServers provides the HTTP API on various endpoints
 	httpServers []*HTTPServer
 
@@ -224,11 +260,48 @@ type Agent struct {
 	// agent.
 	watchPlans []*watch.Plan
 
+	// renderers tracks the currently-running template renderers for the
+	// agent.
+	renderers []*render.Renderer
+
+	// hooks runs the hooks configured for the agent lifecycle events.
+	hooks *hookRunner
+
+	// aclBootstrapLimiter rate limits the ACL bootstrap attempts per
+	// source. It is nil if the attempts aren't limited.
+	aclBootstrapLimiter *aclBootstrapLimiter
+
+	// idempotencyKeys keeps the responses of the write requests made with
+	// an Idempotency-Key header for retries.
+	idempotencyKeys *idempotencyKeyStore
+
+	// faults injects synthetic failures. It is nil unless fault injection
+	// is enabled.
+	faults *faultInjector
+
+	// resources are the cgroup limits of the agent and the runtime settings
+	// derived from them.
+	resources ResourceLimits
+
+	// revocation checks the certificates of TLS peers against CRLs and
+	// OCSP. It is nil unless a revocation check is configured.
+	revocation *tlsutil.Revocation
+
 	// tokens holds ACL tokens initially from the configuration, but can
 	// be updated at runtime, so should always be used instead of going to
 	// the configuration directly.
 	tokens *token.Store
 
+	// persistedTokensLock serializes the updates of the file with the
+	// tokens which were set with the API.
+	persistedTokensLock sync.Mutex
+
+	// nodeMetaLock serializes the updates of the node metadata and of the
+	// file with the node metadata which was set with the API.
+	// configNodeMeta is the node metadata from the agent config.
+	nodeMetaLock   sync.Mutex
+	configNodeMeta map[string]string
+
 	// proxyManager is the proxy process manager for managed Connect proxies.
 	proxyManager *proxyprocess.Manager
 
@@ -246,8 +319,12 @@ type Agent struct {
 	// xdsServer is the Server instance that serves xDS gRPC API.
 	xdsServer *xds.Server
 
+	// discoveryServer is the Server instance that serves the Discovery gRPC
+	// API.
+	discoveryServer *discovery.Server
+
 	// grpcServer is the server instance used currently to serve xDS API for
-	// Envoy.
+	// Envoy and the Discovery API.
 	grpcServer *grpc.Server
 }
 
@@ -260,23 +337,26 @@ func New(c *config.RuntimeConfig) (*Agent, error) {
 	}
 
 	a := &Agent{
-		config:          c,
-		checkReapAfter:  make(map[types.CheckID]time.Duration),
-		checkMonitors:   make(map[types.CheckID]*checks.CheckMonitor),
-		checkTTLs:       make(map[types.CheckID]*checks.CheckTTL),
-		checkHTTPs:      make(map[types.CheckID]*checks.CheckHTTP),
-		checkTCPs:       make(map[types.CheckID]*checks.CheckTCP),
-		checkGRPCs:      make(map[types.CheckID]*checks.CheckGRPC),
-		checkDockers:    make(map[types.CheckID]*checks.CheckDocker),
-		checkAliases:    make(map[types.CheckID]*checks.CheckAlias),
-		eventCh:         make(chan serf.UserEvent, 1024),
-		eventBuf:        make([]*UserEvent, 256),
-		joinLANNotifier: &systemd.Notifier{},
-		reloadCh:        make(chan chan error),
-		retryJoinCh:     make(chan error),
-		shutdownCh:      make(chan struct{}),
-		endpoints:       make(map[string]string),
-		tokens:          new(token.Store),
+		config:                  c,
+		checkReapAfter:          make(map[types.CheckID]time.Duration),
+		checkTypes:              make(map[types.CheckID]*structs.CheckType),
+		checkMonitors:           make(map[types.CheckID]*checks.CheckMonitor),
+		checkTTLs:               make(map[types.CheckID]*checks.CheckTTL),
+		checkHTTPs:              make(map[types.CheckID]*checks.CheckHTTP),
+		checkTCPs:               make(map[types.CheckID]*checks.CheckTCP),
+		checkGRPCs:              make(map[types.CheckID]*checks.CheckGRPC),
+		checkDockers:            make(map[types.CheckID]*checks.CheckDocker),
+		checkAliases:            make(map[types.CheckID]*checks.CheckAlias),
+		serviceWarmups:          make(map[string]time.Time),
+		eventCh:                 make(chan serf.UserEvent, 1024),
+		eventBuf:                make([]*UserEvent, 256),
+		systemdNotifier:         &systemd.Notifier{},
+		systemdWatchdogInterval: systemd.WatchdogInterval,
+		reloadCh:                make(chan chan ReloadResponse),
+		retryJoinCh:             make(chan error),
+		shutdownCh:              make(chan struct{}),
+		endpoints:               make(map[string]string),
+		tokens:                  new(token.Store),
 	}
 
 	if err := a.initializeACLs(); err != nil {
@@ -288,6 +368,7 @@ func New(c *config.RuntimeConfig) (*Agent, error) {
 	a.tokens.UpdateAgentToken(a.config.ACLAgentToken)
 	a.tokens.UpdateAgentMasterToken(a.config.ACLAgentMasterToken)
 	a.tokens.UpdateACLReplicationToken(a.config.ACLReplicationToken)
+	a.tokens.UpdateConnectReplicationToken(a.config.ConnectReplicationToken)
 
 	return a, nil
 }
@@ -351,6 +432,13 @@ func (a *Agent) Start() error {
 		return fmt.Errorf("Failed to setup node ID: %v", err)
 	}
 
+	// Tokens which were set with the API replace the configured ones.
+	a.loadPersistedTokens()
+
+	// Adapt the runtime to the CPU and memory limits of a container before
+	// the servers and caches are set up.
+	a.resources = applyResourceLimits(a.logger)
+
 	// Warn if the node name is incompatible with DNS
 	if InvalidDnsRe.MatchString(a.config.NodeName) {
 		a.logger.Printf("[WARN] agent: Node name %q will not be discoverable "+
@@ -368,10 +456,24 @@ func (a *Agent) Start() error {
 	// create the state synchronization manager which performs
 	// regular and on-demand state synchronizations (anti-entropy).
 	a.sync = ae.NewStateSyncer(a.State, c.AEInterval, a.shutdownCh, a.logger)
+	if c.AERetryMaxInterval > 0 {
+		a.sync.RetryMaxInterval = c.AERetryMaxInterval
+		a.sync.RetryJitter = c.AERetryJitter
+	}
 
 	// create the cache
 	a.cache = cache.New(nil)
 
+	// Load the CRLs before the TLS listeners and the RPC client are set up.
+	if rc := c.RevocationConfig(); rc.Enabled() {
+		revocation, err := tlsutil.NewRevocation(rc, a.logger)
+		if err != nil {
+			return fmt.Errorf("Failed to setup certificate revocation checks: %v", err)
+		}
+		a.revocation = revocation
+		go a.revocation.Run(a.shutdownCh)
+	}
+
 	// create the config for the rpc server/client
 	consulCfg, err := a.consulConfig()
 	if err != nil {
@@ -383,6 +485,30 @@ func (a *Agent) Start() error {
 	// waiting to discover a consul server
 	consulCfg.ServerUp = a.sync.SyncFull.Trigger
 
+	// Run the hooks on leadership changes of the server and status changes
+	// of the local checks.
+	a.hooks, err = newHookRunner(c.Hooks, c.NodeName, c.Datacenter, a.logger)
+	if err != nil {
+		return err
+	}
+	consulCfg.LeadershipChanged = func(isLeader bool) {
+		event := config.HookEventLostLeader
+		if isLeader {
+			event = config.HookEventBecameLeader
+		}
+		a.hooks.Fire(&HookEvent{Event: event})
+	}
+	a.State.CheckStatusChanged = func(check *structs.HealthCheck, previous string) {
+		a.hooks.Fire(&HookEvent{
+			Event:          config.HookEventCheckStateChanged,
+			Check:          check,
+			PreviousStatus: previous,
+		})
+	}
+
+	a.aclBootstrapLimiter = newACLBootstrapLimiter(c.ACLBootstrapRateLimit, c.ACLBootstrapMaxBurst)
+	a.idempotencyKeys = newIdempotencyKeyStore(idempotencyKeyTTL, idempotencyKeyMaxEntries)
+
 	// Setup either the client or the server.
 	if c.ServerMode {
 		server, err := consul.NewServerLogger(consulCfg, a.logger, a.tokens)
@@ -398,6 +524,19 @@ func (a *Agent) Start() error {
 		a.delegate = client
 	}
 
+	if c.EnableFaultInjection {
+		var stepDown func() (bool, error)
+		if server, ok := a.delegate.(*consul.Server); ok {
+			stepDown = func() (bool, error) {
+				if !server.IsLeader() {
+					return false, nil
+				}
+				return true, server.StepDown()
+			}
+		}
+		a.faults = newFaultInjector(stepDown, a.logger)
+	}
+
 	// the staggering of the state syncing depends on the cluster size.
 	a.sync.ClusterSize = func() int { return len(a.delegate.LANMembers()) }
 
@@ -504,10 +643,32 @@ func (a *Agent) Start() error {
 		return err
 	}
 
+	// start rendering templates
+	if err := a.reloadTemplates(a.config); err != nil {
+		return err
+	}
+
 	// start retry join
 	go a.retryJoinLAN()
 	go a.retryJoinWAN()
 
+	// request an agent token from the servers with the introduction token
+	if c.ACLsEnabled && c.ACLIntroductionToken != "" && !a.tokens.HasAgentToken() {
+		go a.introduceAgent()
+	}
+
+	// keep the cached agent profile in sync with the servers
+	if c.AgentProfile != "" {
+		go a.watchProfile()
+	}
+
+	// emit the size and the convergence estimates of the gossip pools
+	go a.emitGossipMetrics()
+
+	// report startup completion and liveness to systemd
+	go a.notifyReady()
+	a.startWatchdog()
+
 	return nil
 }
 
@@ -528,6 +689,19 @@ func (a *Agent) listenAndServeGRPC() error {
 		return err
 	}
 
+	a.discoveryServer = &discovery.Server{
+		Logger:     a.logger,
+		Cache:      a.cache,
+		Datacenter: a.config.Datacenter,
+		Source: structs.QuerySource{
+			Node:       a.config.NodeName,
+			Datacenter: a.config.Datacenter,
+			Segment:    a.config.SegmentName,
+		},
+		TranslateAddresses: a.TranslateAddresses,
+	}
+	discovery.RegisterDiscoveryServer(a.grpcServer, a.discoveryServer)
+
 	ln, err := a.startListeners(a.config.GRPCAddrs)
 	if err != nil {
 		return err
@@ -547,6 +721,13 @@ func (a *Agent) listenAndServeGRPC() error {
 }
 
 func (a *Agent) listenAndServeDNS() error {
+	recursors, err := newRecursorPool(a.config, a.logger)
+	if err != nil {
+		return err
+	}
+	a.dnsRecursors = recursors
+	go recursors.run(a.shutdownCh)
+
 	notif := make(chan net.Addr, len(a.config.DNSAddrs))
 	errCh := make(chan error, len(a.config.DNSAddrs))
 	for _, addr := range a.config.DNSAddrs {
@@ -561,7 +742,8 @@ func (a *Agent) listenAndServeDNS() error {
 		a.wgServers.Add(1)
 		go func(addr net.Addr) {
 			defer a.wgServers.Done()
-			err := s.ListenAndServe(addr.Network(), addr.String(), func() { notif <- addr })
+			network := listenNetwork(addr, a.config.DNSAddrs)
+			err := s.ListenAndServe(network, addr.String(), func() { notif <- addr })
 			if err != nil && !strings.Contains(err.Error(), "accept") {
 				errCh <- err
 			}
@@ -593,13 +775,17 @@ func (a *Agent) startListeners(addrs []net.Addr) ([]net.Listener, error) {
 
 		switch x := addr.(type) {
 		case *net.UnixAddr:
-			l, err = a.listenSocket(x.Name)
+			if x.Net == "npipe" {
+				l, err = listenPipe(x.Name)
+			} else {
+				l, err = a.listenSocket(x.Name)
+			}
 			if err != nil {
 				return nil, err
 			}
 
 		case *net.TCPAddr:
-			l, err = net.Listen("tcp", x.String())
+			l, err = net.Listen(listenNetwork(x, addrs), x.String())
 			if err != nil {
 				return nil, err
 			}
@@ -613,6 +799,45 @@ func (a *Agent) startListeners(addrs []net.Addr) ([]net.Listener, error) {
 	return ln, nil
 }
 
+// listenNetwork returns the network for listening on addr. Go listens on
+// the IPv4 and IPv6 wildcard addresses with a dual-stack socket for "tcp"
+// and "udp" which conflicts with a listener for the other IP family on the
+// same port. If addrs contains such a listener the family specific network
+// is used so that the agent can listen on both.
+func listenNetwork(addr net.Addr, addrs []net.Addr) string {
+	ip, port := addrIPPort(addr)
+	if ip == nil {
+		return addr.Network()
+	}
+	ipv4 := ip.To4() != nil
+	for _, x := range addrs {
+		if x.Network() != addr.Network() {
+			continue
+		}
+		xip, xport := addrIPPort(x)
+		if xip == nil || xport != port || (xip.To4() != nil) == ipv4 {
+			continue
+		}
+		if ipv4 {
+			return addr.Network() + "4"
+		}
+		return addr.Network() + "6"
+	}
+	return addr.Network()
+}
+
+// addrIPPort returns the IP and port of a TCP or UDP address.
+func addrIPPort(addr net.Addr) (net.IP, int) {
+	switch x := addr.(type) {
+	case *net.TCPAddr:
+		return x.IP, x.Port
+	case *net.UDPAddr:
+		return x.IP, x.Port
+	default:
+		return nil, 0
+	}
+}
+
 // listenHTTP binds listeners to the provided addresses and also returns
 // pre-configured HTTP servers which are not yet started. The motivation is
 // that in the current startup/shutdown setup we de-couple the listener
@@ -641,7 +866,7 @@ func (a *Agent) listenHTTP() ([]*HTTPServer, error) {
 			var tlscfg *tls.Config
 			_, isTCP := l.(*tcpKeepAliveListener)
 			if isTCP && proto == "https" {
-				tlscfg, err = a.config.IncomingHTTPSConfig()
+				tlscfg, err = a.config.IncomingHTTPSConfig(a.revocation)
 				if err != nil {
 					return err
 				}
@@ -655,10 +880,25 @@ func (a *Agent) listenHTTP() ([]*HTTPServer, error) {
 				ln:        l,
 				agent:     a,
 				blacklist: NewBlacklist(a.config.HTTPBlockEndpoints),
-				proto:     proto,
+				certAllowlist: NewClientCertAllowlist(
+					a.config.HTTPClientCertAllowlistCommonNames,
+					a.config.HTTPClientCertAllowlistOrgUnits,
+					a.config.HTTPClientCertAllowlistURIs,
+					a.config.HTTPClientCertAllowlistWriteOnly,
+				),
+				proto: proto,
 			}
 			srv.Server.Handler = srv.handler(a.config.EnableDebug)
 
+			// Use the TLS configuration from the last reload for new
+			// connections so that certificates can be rotated.
+			if tlscfg != nil {
+				tlscfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+					c, _ := srv.tlsConfig.Load().(*tls.Config)
+					return c, nil
+				}
+			}
+
 			// This will enable upgrading connections to HTTP/2 as
 			// part of TLS negotiation.
 			if proto == "https" {
@@ -866,6 +1106,48 @@ func (a *Agent) reloadWatches(cfg *config.RuntimeConfig) error {
 	return nil
 }
 
+// reloadTemplates stops the running template renderers and starts
+// rendering the given set of templates.
+func (a *Agent) reloadTemplates(cfg *config.RuntimeConfig) error {
+	for _, r := range a.renderers {
+		r.Stop()
+	}
+	a.renderers = nil
+
+	if len(cfg.Templates) == 0 {
+		return nil
+	}
+
+	// Templates use the API to talk to this agent, so that must be enabled.
+	if len(cfg.HTTPAddrs) == 0 && len(cfg.HTTPSAddrs) == 0 {
+		return fmt.Errorf("templates require an HTTP or HTTPS endpoint")
+	}
+
+	apiConfig, err := cfg.APIConfig(true)
+	if err != nil {
+		return err
+	}
+	client, err := api.NewClient(apiConfig)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to agent: %v", err)
+	}
+
+	var renderers []*render.Renderer
+	for i := range cfg.Templates {
+		r, err := render.New(client, &cfg.Templates[i], a.logger)
+		if err != nil {
+			return fmt.Errorf("Failed to load template %q: %v", cfg.Templates[i].Source, err)
+		}
+		renderers = append(renderers, r)
+	}
+
+	a.renderers = renderers
+	for _, r := range renderers {
+		go r.Run()
+	}
+	return nil
+}
+
 // consulConfig is used to return a consul configuration
 func (a *Agent) consulConfig() (*consul.Config, error) {
 	// Start with the provided config or default config
@@ -876,6 +1158,7 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 
 	// Apply dev mode
 	base.DevMode = a.config.DevMode
+	base.DevPersist = a.config.DevPersist
 
 	// Override with our config
 	// todo(fs): these are now always set in the runtime config so we can simplify this
@@ -941,6 +1224,7 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 		}
 		base.Segments = segments
 	}
+	base.RPCRoutes = a.config.RPCRoutes
 	if a.config.Bootstrap {
 		base.Bootstrap = true
 	}
@@ -974,6 +1258,9 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.ACLPolicyTTL != 0 {
 		base.ACLPolicyTTL = a.config.ACLPolicyTTL
 	}
+	if a.config.ACLBlockingQueryRecheckInterval != 0 {
+		base.ACLBlockingQueryRecheckInterval = a.config.ACLBlockingQueryRecheckInterval
+	}
 	if a.config.ACLDefaultPolicy != "" {
 		base.ACLDefaultPolicy = a.config.ACLDefaultPolicy
 	}
@@ -989,6 +1276,20 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.SessionTTLMin != 0 {
 		base.SessionTTLMin = a.config.SessionTTLMin
 	}
+	base.PreparedQuerySlowThreshold = a.config.PreparedQuerySlowThreshold
+	base.RequestLog = a.config.RequestLogConfig()
+	base.GossipKeyRotationInterval = a.config.GossipKeyRotationInterval
+	base.GossipKeyRotationRetireAfter = a.config.GossipKeyRotationRetireAfter
+	base.KVRecycleBinRetention = a.config.KVRecycleBinRetention
+	if a.config.VirtualIPCIDR != "" {
+		_, cidr, err := net.ParseCIDR(a.config.VirtualIPCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid virtual_ip_cidr: %v", err)
+		}
+		base.VirtualIPCIDR = cidr
+	}
+	base.KVReplicationPrefixes = a.config.KVReplicationPrefixes
+	base.KVReplicationConflictPolicy = a.config.KVReplicationConflictPolicy
 	if a.config.NonVotingServer {
 		base.NonVoter = a.config.NonVotingServer
 	}
@@ -1015,11 +1316,22 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.RPCMaxBurst > 0 {
 		base.RPCMaxBurst = a.config.RPCMaxBurst
 	}
+	if a.config.MaxQueryTime > 0 {
+		base.MaxQueryTime = a.config.MaxQueryTime
+	}
+	if a.config.DefaultQueryTime > 0 {
+		base.DefaultQueryTime = a.config.DefaultQueryTime
+	}
+	base.MaxBlockingQueriesPerToken = a.config.MaxBlockingQueriesPerToken
 
 	// RPC-related performance configs.
 	if a.config.RPCHoldTimeout > 0 {
 		base.RPCHoldTimeout = a.config.RPCHoldTimeout
 	}
+	if a.config.RPCRetryMaxInterval > 0 {
+		base.RPCRetryMaxInterval = a.config.RPCRetryMaxInterval
+		base.RPCRetryJitter = a.config.RPCRetryJitter
+	}
 	if a.config.LeaveDrainTime > 0 {
 		base.LeaveDrainTime = a.config.LeaveDrainTime
 	}
@@ -1044,6 +1356,7 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	}
 	base.VerifyOutgoing = a.config.VerifyOutgoing
 	base.VerifyServerHostname = a.config.VerifyServerHostname
+	base.VerifyServerHostnamePolicies = a.config.VerifyServerHostnamePolicies
 	base.CAFile = a.config.CAFile
 	base.CAPath = a.config.CAPath
 	base.CertFile = a.config.CertFile
@@ -1053,6 +1366,9 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	base.TLSMinVersion = a.config.TLSMinVersion
 	base.TLSCipherSuites = a.config.TLSCipherSuites
 	base.TLSPreferServerCipherSuites = a.config.TLSPreferServerCipherSuites
+	base.TLSRevocation = a.revocation
+
+	base.MemoryLimit = a.resources.MemoryLimit
 
 	// Copy the Connect CA bootstrap config
 	if a.config.ConnectEnabled {
@@ -1391,6 +1707,9 @@ func (a *Agent) RPC(method string, args interface{}, reply interface{}) error {
 		}
 	}
 	a.endpointsLock.RUnlock()
+	if err := a.faults.BeforeRPC(); err != nil {
+		return err
+	}
 	return a.delegate.RPC(method, args, reply)
 }
 
@@ -1405,7 +1724,70 @@ func (a *Agent) SnapshotRPC(args *structs.SnapshotRequest, in io.Reader, out io.
 
 // Leave is used to prepare the agent for a graceful shutdown
 func (a *Agent) Leave() error {
-	return a.delegate.Leave()
+	a.deregisterServicesOnLeave()
+	if err := a.delegate.Leave(); err != nil {
+		return err
+	}
+
+	// Wait for the hooks so that they are done before the agent shuts
+	// down.
+	a.hooks.Run(&HookEvent{Event: config.HookEventLeft})
+	return nil
+}
+
+// deregisterOnShutdown returns whether the given local service should be
+// removed from the catalog when the agent leaves gracefully.
+func (a *Agent) deregisterOnShutdown(service *structs.NodeService) bool {
+	if service.DeregisterOnShutdown != nil {
+		return *service.DeregisterOnShutdown
+	}
+	return a.config.DeregisterServicesOnShutdown
+}
+
+// deregisterServicesOnLeave applies the deregister_on_shutdown policy of
+// the local services before the agent leaves the cluster. The servers
+// deregister the node of a member which left together with all of its
+// services. If any service should be retained, the services which should
+// not are deregistered explicitly and the node info is marked so that the
+// servers mark it as critical instead. Both are done by RPCs which complete
+// before the leave, so the servers know about them when they see it.
+func (a *Agent) deregisterServicesOnLeave() {
+	var deregister []string
+	retain := false
+	for id, service := range a.State.Services() {
+		if a.deregisterOnShutdown(service) {
+			deregister = append(deregister, id)
+		} else {
+			retain = true
+		}
+	}
+	if !retain {
+		return
+	}
+
+	// Make sure anti-entropy does not register the services again.
+	a.PauseSync()
+
+	for _, id := range deregister {
+		req := structs.DeregisterRequest{
+			Datacenter:   a.config.Datacenter,
+			Node:         a.config.NodeName,
+			ServiceID:    id,
+			WriteRequest: structs.WriteRequest{Token: a.State.ServiceToken(id)},
+		}
+		var out struct{}
+		if err := a.RPC("Catalog.Deregister", &req, &out); err != nil {
+			a.logger.Printf("[WARN] agent: Failed to deregister service %q before leaving, no services will be retained: %v", id, err)
+			return
+		}
+		a.logger.Printf("[INFO] agent: Deregistered service %q before leaving", id)
+	}
+
+	if err := a.State.SyncNodeInfoRetained(); err != nil {
+		a.logger.Printf("[WARN] agent: Failed to retain services after leaving: %v", err)
+		return
+	}
+	a.logger.Printf("[INFO] agent: Retaining services in the catalog after leaving")
 }
 
 // ShutdownAgent is used to hard stop the agent. Should be preceded by
@@ -1419,6 +1801,7 @@ func (a *Agent) ShutdownAgent() error {
 		return nil
 	}
 	a.logger.Println("[INFO] agent: Requesting shutdown")
+	a.notify(systemd.Stopping)
 
 	// Stop all the checks
 	a.checkLock.Lock()
@@ -1445,6 +1828,14 @@ func (a *Agent) ShutdownAgent() error {
 		chk.Stop()
 	}
 
+	// Stop rendering templates
+	for _, r := range a.renderers {
+		r.Stop()
+	}
+
+	// Stop the leadership churn
+	a.faults.Stop()
+
 	// Stop gRPC
 	if a.grpcServer != nil {
 		a.grpcServer.Stop()
@@ -1533,7 +1924,7 @@ func (a *Agent) ShutdownEndpoints() {
 
 // ReloadCh is used to return a channel that can be
 // used for triggering reloads and returning a response.
-func (a *Agent) ReloadCh() chan chan error {
+func (a *Agent) ReloadCh() chan chan ReloadResponse {
 	return a.reloadCh
 }
 
@@ -1554,10 +1945,8 @@ func (a *Agent) JoinLAN(addrs []string) (n int, err error) {
 	a.logger.Printf("[INFO] agent: (LAN) joining: %v", addrs)
 	n, err = a.delegate.JoinLAN(addrs)
 	a.logger.Printf("[INFO] agent: (LAN) joined: %d Err: %v", n, err)
-	if err == nil && a.joinLANNotifier != nil {
-		if notifErr := a.joinLANNotifier.Notify(systemd.Ready); notifErr != nil {
-			a.logger.Printf("[DEBUG] agent: systemd notify failed: %v", notifErr)
-		}
+	if n > 0 {
+		a.hooks.Fire(&HookEvent{Event: config.HookEventJoined})
 	}
 	return
 }
@@ -1745,6 +2134,8 @@ func (a *Agent) reapServicesInternal() {
 		if timeout > 0 && cs.CriticalFor() > timeout {
 			reaped[serviceID] = true
 			a.RemoveService(serviceID, true)
+			metrics.IncrCounterWithLabels([]string{"agent", "service", "deregistered_critical"}, 1,
+				[]metrics.Label{{Name: "service", Value: cs.Check.ServiceName}})
 			a.logger.Printf("[INFO] agent: Check %q for service %q has been critical for too long; deregistered service",
 				checkID, serviceID)
 		}
@@ -1904,6 +2295,13 @@ func (a *Agent) AddService(service *structs.NodeService, chkTypes []*structs.Che
 		}
 	}
 
+	// Add the default meta of the agent. The service is persisted without
+	// it so that changes to the defaults apply when it is loaded again.
+	localService := a.serviceWithMetaDefaults(service)
+	if err := structs.ValidateMetadata(localService.Meta, false); err != nil {
+		return fmt.Errorf("Invalid service meta with the agent defaults: %v", err)
+	}
+
 	// Pause the service syncs during modification
 	a.PauseSync()
 	defer a.ResumeSync()
@@ -1914,7 +2312,7 @@ func (a *Agent) AddService(service *structs.NodeService, chkTypes []*structs.Che
 	defer a.restoreCheckState(snap)
 
 	// Add the service
-	a.State.AddService(service, token)
+	a.State.AddService(localService, token)
 
 	// Persist the service to a file
 	if persist && a.config.DataDir != "" {
@@ -1954,9 +2352,37 @@ func (a *Agent) AddService(service *structs.NodeService, chkTypes []*structs.Che
 		}
 	}
 
+	// Keep the service out of queries until it has warmed up
+	if service.Warmup > 0 {
+		if err := a.startServiceWarmup(service, token); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// serviceWithMetaDefaults returns the service with the default service meta
+// of the agent added to its meta. Keys defined by the service take
+// precedence over the defaults. The service is returned as is if there are
+// no defaults.
+func (a *Agent) serviceWithMetaDefaults(service *structs.NodeService) *structs.NodeService {
+	if len(a.config.ServiceMetaDefaults) == 0 {
+		return service
+	}
+
+	meta := make(map[string]string, len(a.config.ServiceMetaDefaults)+len(service.Meta))
+	for k, v := range a.config.ServiceMetaDefaults {
+		meta[k] = v
+	}
+	for k, v := range service.Meta {
+		meta[k] = v
+	}
+	ns := *service
+	ns.Meta = meta
+	return &ns
+}
+
 // RemoveService is used to remove a service entry.
 // The agent will make a best effort to ensure it is deregistered
 func (a *Agent) RemoveService(serviceID string, persist bool) error {
@@ -1971,11 +2397,16 @@ func (a *Agent) RemoveService(serviceID string, persist bool) error {
 		return nil
 	}
 
-	// Remove the service from the data dir
+	// Remove the service from the data dir. Services are only removed
+	// without persisting when the configuration is reloaded, in which case
+	// a running warmup carries on once they are added again.
 	if persist {
 		if err := a.purgeService(serviceID); err != nil {
 			return err
 		}
+		a.serviceWarmupsLock.Lock()
+		delete(a.serviceWarmups, serviceID)
+		a.serviceWarmupsLock.Unlock()
 	}
 
 	// Deregister any associated health checks
@@ -2070,10 +2501,14 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			}
 
 			// Restore persisted state, if any
-			if err := a.loadCheckState(check); err != nil {
+			remaining, err := a.loadCheckState(check)
+			if err != nil {
 				a.logger.Printf("[WARN] agent: failed restoring state for check %q: %s",
 					check.CheckID, err)
 			}
+			if remaining > 0 {
+				ttl.Restore(check.Output, remaining)
+			}
 
 			ttl.Start()
 			a.checkTTLs[check.CheckID] = ttl
@@ -2101,6 +2536,7 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				Header:          chkType.Header,
 				Method:          chkType.Method,
 				Interval:        chkType.Interval,
+				IntervalJitter:  a.config.CheckIntervalJitter,
 				Timeout:         chkType.Timeout,
 				Logger:          a.logger,
 				TLSClientConfig: tlsClientConfig,
@@ -2120,12 +2556,13 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			}
 
 			tcp := &checks.CheckTCP{
-				Notify:   a.State,
-				CheckID:  check.CheckID,
-				TCP:      chkType.TCP,
-				Interval: chkType.Interval,
-				Timeout:  chkType.Timeout,
-				Logger:   a.logger,
+				Notify:         a.State,
+				CheckID:        check.CheckID,
+				TCP:            chkType.TCP,
+				Interval:       chkType.Interval,
+				IntervalJitter: a.config.CheckIntervalJitter,
+				Timeout:        chkType.Timeout,
+				Logger:         a.logger,
 			}
 			tcp.Start()
 			a.checkTCPs[check.CheckID] = tcp
@@ -2155,6 +2592,7 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				CheckID:         check.CheckID,
 				GRPC:            chkType.GRPC,
 				Interval:        chkType.Interval,
+				IntervalJitter:  a.config.CheckIntervalJitter,
 				Timeout:         chkType.Timeout,
 				Logger:          a.logger,
 				TLSClientConfig: tlsClientConfig,
@@ -2174,7 +2612,12 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			}
 
 			if a.dockerClient == nil {
-				dc, err := checks.NewDockerClient(os.Getenv("DOCKER_HOST"), checks.BufSize)
+				tlsConfig, err := checks.DockerTLSConfigFromEnv()
+				if err != nil {
+					a.logger.Printf("[ERR] agent: error creating docker client: %s", err)
+					return err
+				}
+				dc, err := checks.NewDockerClient(os.Getenv("DOCKER_HOST"), tlsConfig, checks.BufSize)
 				if err != nil {
 					a.logger.Printf("[ERR] agent: error creating docker client: %s", err)
 					return err
@@ -2190,6 +2633,7 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 				Shell:             chkType.Shell,
 				ScriptArgs:        chkType.ScriptArgs,
 				Interval:          chkType.Interval,
+				IntervalJitter:    a.config.CheckIntervalJitter,
 				Logger:            a.logger,
 				Client:            a.dockerClient,
 			}
@@ -2211,12 +2655,19 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			}
 
 			monitor := &checks.CheckMonitor{
-				Notify:     a.State,
-				CheckID:    check.CheckID,
-				ScriptArgs: chkType.ScriptArgs,
-				Interval:   chkType.Interval,
-				Timeout:    chkType.Timeout,
-				Logger:     a.logger,
+				Notify:         a.State,
+				CheckID:        check.CheckID,
+				ScriptArgs:     chkType.ScriptArgs,
+				Interval:       chkType.Interval,
+				IntervalJitter: a.config.CheckIntervalJitter,
+				Timeout:        chkType.Timeout,
+				Logger:         a.logger,
+				Limits: exec.Limits{
+					User:    a.config.ScriptCheckUser,
+					Cgroup:  a.config.ScriptCheckCgroup,
+					CPUTime: a.config.ScriptCheckCPUTime,
+					Memory:  uint64(a.config.ScriptCheckMemoryBytes),
+				},
 			}
 			monitor.Start()
 			a.checkMonitors[check.CheckID] = monitor
@@ -2254,17 +2705,25 @@ func (a *Agent) AddCheck(check *structs.HealthCheck, chkType *structs.CheckType,
 			return fmt.Errorf("Check type is not valid")
 		}
 
-		if chkType.DeregisterCriticalServiceAfter > 0 {
-			timeout := chkType.DeregisterCriticalServiceAfter
-			if timeout < a.config.CheckDeregisterIntervalMin {
-				timeout = a.config.CheckDeregisterIntervalMin
+		timeout := chkType.DeregisterCriticalServiceAfter
+		if timeout == 0 && check.ServiceID != "" {
+			timeout = a.config.DeregisterCriticalServiceAfterDefault
+		}
+		if timeout > 0 {
+			min := a.config.CheckDeregisterIntervalMin
+			if a.config.DeregisterCriticalServiceAfterMin > min {
+				min = a.config.DeregisterCriticalServiceAfterMin
+			}
+			if timeout < min {
+				timeout = min
 				a.logger.Println(fmt.Sprintf("[WARN] agent: check '%s' has deregister interval below minimum of %v",
-					check.CheckID, a.config.CheckDeregisterIntervalMin))
+					check.CheckID, min))
 			}
 			a.checkReapAfter[check.CheckID] = timeout
 		} else {
 			delete(a.checkReapAfter, check.CheckID)
 		}
+		a.checkTypes[check.CheckID] = chkType
 	}
 
 	// Add to the local state for anti-entropy
@@ -2667,7 +3126,7 @@ func (a *Agent) verifyProxyToken(token, targetService,
 		return "", false, err
 	}
 	if rule != nil && !rule.ServiceWrite(targetService, nil) {
-		return "", false, acl.ErrPermissionDenied
+		return "", false, acl.PermissionDenied("service", targetService, "write")
 	}
 
 	return token, false, nil
@@ -2676,6 +3135,7 @@ func (a *Agent) verifyProxyToken(token, targetService,
 func (a *Agent) cancelCheckMonitors(checkID types.CheckID) {
 	// Stop any monitors
 	delete(a.checkReapAfter, checkID)
+	delete(a.checkTypes, checkID)
 	if check, ok := a.checkMonitors[checkID]; ok {
 		check.Stop()
 		delete(a.checkMonitors, checkID)
@@ -2730,16 +3190,59 @@ func (a *Agent) updateTTLCheck(checkID types.CheckID, status, output string) err
 	return nil
 }
 
+// updateCheckDefinition is used to change the interval, timeout, TTL or
+// critical service deregistration timeout of a registered check via the
+// Agent API. The check is restarted with the new definition but keeps its
+// status and the service it belongs to is not touched.
+func (a *Agent) updateCheckDefinition(checkID types.CheckID, update *checkDefinitionUpdate) error {
+	check := a.State.Check(checkID)
+	if check == nil {
+		return fmt.Errorf("CheckID %q does not exist", checkID)
+	}
+
+	a.checkLock.Lock()
+	existing, ok := a.checkTypes[checkID]
+	a.checkLock.Unlock()
+	if !ok {
+		return fmt.Errorf("CheckID %q does not have an associated definition", checkID)
+	}
+
+	chkType := *existing
+	if update.Interval != nil {
+		chkType.Interval = *update.Interval
+	}
+	if update.Timeout != nil {
+		chkType.Timeout = *update.Timeout
+	}
+	if update.TTL != nil {
+		chkType.TTL = *update.TTL
+	}
+	if update.DeregisterCriticalServiceAfter != nil {
+		chkType.DeregisterCriticalServiceAfter = *update.DeregisterCriticalServiceAfter
+	}
+
+	// The command of a script check cannot be changed here, so the check
+	// is re-added as a local one to not trip over enable_script_checks.
+	err := a.AddCheck(check.Clone(), &chkType, true, a.State.CheckToken(checkID), ConfigSourceLocal)
+	if err != nil {
+		return err
+	}
+	a.logger.Printf("[DEBUG] agent: updated definition of check %q", checkID)
+	return nil
+}
+
 // persistCheckState is used to record the check status into the data dir.
 // This allows the state to be restored on a later agent start. Currently
 // only useful for TTL based checks.
 func (a *Agent) persistCheckState(check *checks.CheckTTL, status, output string) error {
 	// Create the persisted state
+	now := time.Now()
 	state := persistedCheckState{
 		CheckID: check.CheckID,
 		Status:  status,
 		Output:  output,
-		Expires: time.Now().Add(check.TTL).Unix(),
+		Expires: now.Add(check.TTL).Unix(),
+		Updated: now.Unix(),
 	}
 
 	// Encode the state
@@ -2771,35 +3274,46 @@ func (a *Agent) persistCheckState(check *checks.CheckTTL, status, output string)
 	return nil
 }
 
-// loadCheckState is used to restore the persisted state of a check.
-func (a *Agent) loadCheckState(check *structs.HealthCheck) error {
+// loadCheckState is used to restore the persisted state of a check. It
+// returns the time until the TTL of the restored state expires, or zero if
+// no state was restored.
+func (a *Agent) loadCheckState(check *structs.HealthCheck) (time.Duration, error) {
 	// Try to read the persisted state for this check
 	file := filepath.Join(a.config.DataDir, checkStateDir, checkIDHash(check.CheckID))
 	buf, err := ioutil.ReadFile(file)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return 0, nil
 		}
-		return fmt.Errorf("failed reading file %q: %s", file, err)
+		return 0, fmt.Errorf("failed reading file %q: %s", file, err)
 	}
 
 	// Decode the state data
 	var p persistedCheckState
 	if err := json.Unmarshal(buf, &p); err != nil {
 		a.logger.Printf("[ERR] agent: failed decoding check state: %s", err)
-		return a.purgeCheckState(check.CheckID)
+		return 0, a.purgeCheckState(check.CheckID)
 	}
 
 	// Check if the state has expired
-	if time.Now().Unix() >= p.Expires {
+	now := time.Now()
+	if now.Unix() >= p.Expires {
 		a.logger.Printf("[DEBUG] agent: check state expired for %q, not restoring", check.CheckID)
-		return a.purgeCheckState(check.CheckID)
+		return 0, a.purgeCheckState(check.CheckID)
+	}
+
+	// Check if the state is too old to be trusted even though the TTL is
+	// longer. States of older versions don't record their age.
+	if maxAge := a.config.CheckStateMaxAge; maxAge > 0 && p.Updated > 0 &&
+		now.Sub(time.Unix(p.Updated, 0)) > maxAge {
+		a.logger.Printf("[DEBUG] agent: check state for %q is older than %s, not restoring", check.CheckID, maxAge)
+		return 0, a.purgeCheckState(check.CheckID)
 	}
 
 	// Restore the fields from the state
 	check.Output = p.Output
 	check.Status = p.Status
-	return nil
+	return time.Unix(p.Expires, 0).Sub(now), nil
 }
 
 // purgeCheckState is used to purge the state of a check from the data dir
@@ -3216,22 +3730,6 @@ func (a *Agent) restoreCheckState(snap map[types.CheckID]*structs.HealthCheck) {
 	}
 }
 
-// loadMetadata loads node metadata fields from the agent config and
-// updates them on the local agent.
-func (a *Agent) loadMetadata(conf *config.RuntimeConfig) error {
-	meta := map[string]string{}
-	for k, v := range conf.NodeMeta {
-		meta[k] = v
-	}
-	meta[structs.MetaSegmentKey] = conf.SegmentName
-	return a.State.LoadMetadata(meta)
-}
-
-// unloadMetadata resets the local metadata state
-func (a *Agent) unloadMetadata() {
-	a.State.UnloadMetadata()
-}
-
 // serviceMaintCheckID returns the ID of a given service's maintenance check
 func serviceMaintCheckID(serviceID string) types.CheckID {
 	return types.CheckID(structs.ServiceMaintPrefix + serviceID)
@@ -3292,6 +3790,67 @@ func (a *Agent) DisableServiceMaintenance(serviceID string) error {
 	return nil
 }
 
+// serviceWarmupCheckID returns the ID of a given service's warmup check
+func serviceWarmupCheckID(serviceID string) types.CheckID {
+	return types.CheckID(structs.ServiceWarmupPrefix + serviceID)
+}
+
+// startServiceWarmup registers a critical health check against the service
+// until its warmup period has ended. This excludes it from DNS and ?passing
+// results even if its own checks are passing.
+func (a *Agent) startServiceWarmup(service *structs.NodeService, token string) error {
+	a.serviceWarmupsLock.Lock()
+	end, ok := a.serviceWarmups[service.ID]
+	if !ok {
+		end = time.Now().Add(service.Warmup)
+		a.serviceWarmups[service.ID] = end
+	}
+	a.serviceWarmupsLock.Unlock()
+
+	wait := time.Until(end)
+	if wait <= 0 {
+		return nil
+	}
+
+	checkID := serviceWarmupCheckID(service.ID)
+	if _, ok := a.State.Checks()[checkID]; !ok {
+		check := &structs.HealthCheck{
+			Node:        a.config.NodeName,
+			CheckID:     checkID,
+			Name:        "Service Warmup",
+			Notes:       fmt.Sprintf("Service is warming up until %s", end.Format(time.RFC3339)),
+			ServiceID:   service.ID,
+			ServiceName: service.Service,
+			Status:      api.HealthCritical,
+		}
+		if err := a.AddCheck(check, nil, false, token, ConfigSourceLocal); err != nil {
+			return err
+		}
+		a.logger.Printf("[INFO] agent: Service %q is warming up for %s", service.ID, wait)
+	}
+
+	time.AfterFunc(wait, func() { a.endServiceWarmup(service.ID, end) })
+	return nil
+}
+
+// endServiceWarmup deregisters the warmup check of the service unless the
+// service has been registered again with a new warmup period since.
+func (a *Agent) endServiceWarmup(serviceID string, end time.Time) {
+	a.serviceWarmupsLock.Lock()
+	current, ok := a.serviceWarmups[serviceID]
+	a.serviceWarmupsLock.Unlock()
+	if !ok || !current.Equal(end) {
+		return
+	}
+
+	checkID := serviceWarmupCheckID(serviceID)
+	if _, ok := a.State.Checks()[checkID]; !ok {
+		return
+	}
+	a.RemoveCheck(checkID, false)
+	a.logger.Printf("[INFO] agent: Service %q finished warming up", serviceID)
+}
+
 // EnableNodeMaintenance places a node into maintenance mode.
 func (a *Agent) EnableNodeMaintenance(reason, token string) {
 	// Ensure node maintenance is not already enabled
@@ -3325,12 +3884,64 @@ func (a *Agent) DisableNodeMaintenance() {
 	a.logger.Printf("[INFO] agent: Node left maintenance mode")
 }
 
+// reloadHTTPSConfig replaces the TLS configuration of the HTTPS servers
+// for new connections. Existing connections are not affected.
+func (a *Agent) reloadHTTPSConfig(conf *config.RuntimeConfig) error {
+	for _, srv := range a.httpServers {
+		if srv.proto != "https" {
+			continue
+		}
+		tlscfg, err := conf.IncomingHTTPSConfig(a.revocation)
+		if err != nil {
+			return err
+		}
+		// keep the protocols configured for HTTP/2
+		tlscfg.NextProtos = srv.Server.TLSConfig.NextProtos
+		srv.tlsConfig.Store(tlscfg)
+	}
+	return nil
+}
+
 func (a *Agent) loadLimits(conf *config.RuntimeConfig) {
 	a.config.RPCRateLimit = conf.RPCRateLimit
 	a.config.RPCMaxBurst = conf.RPCMaxBurst
 }
 
+// SetConfigSources sets the function which computes the sources of the
+// fields of the runtime configuration reported by the /v1/agent/config
+// endpoint. It is called on start and on every successful reload.
+func (a *Agent) SetConfigSources(fn func() (map[string][]string, error)) {
+	a.configSourcesLock.Lock()
+	a.configSourcesFn = fn
+	a.configSources = nil
+	a.configSourcesLock.Unlock()
+}
+
+// ConfigSources returns the sources of the fields of the runtime
+// configuration. They are computed on the first call after the config was
+// loaded. The returned map must not be modified.
+func (a *Agent) ConfigSources() (map[string][]string, error) {
+	a.configSourcesLock.Lock()
+	defer a.configSourcesLock.Unlock()
+
+	if a.configSources == nil && a.configSourcesFn != nil {
+		sources, err := a.configSourcesFn()
+		if err != nil {
+			return nil, err
+		}
+		a.configSources = sources
+	}
+	return a.configSources, nil
+}
+
 func (a *Agent) ReloadConfig(newCfg *config.RuntimeConfig) error {
+	// Only report the reload to systemd after startup has completed so
+	// that the agent does not appear ready too early.
+	if a.isReady() {
+		a.notify(systemd.Reloading)
+		defer a.notify(systemd.Ready)
+	}
+
 	// Bulk update the services and checks
 	a.PauseSync()
 	defer a.ResumeSync()
@@ -3352,6 +3963,12 @@ func (a *Agent) ReloadConfig(newCfg *config.RuntimeConfig) error {
 	}
 	a.unloadMetadata()
 
+	// Update the service defaults before the services and checks are
+	// reloaded so that they apply to them.
+	a.config.ServiceMetaDefaults = newCfg.ServiceMetaDefaults
+	a.config.DeregisterCriticalServiceAfterDefault = newCfg.DeregisterCriticalServiceAfterDefault
+	a.config.DeregisterCriticalServiceAfterMin = newCfg.DeregisterCriticalServiceAfterMin
+
 	// Reload service/check definitions and metadata.
 	if err := a.loadServices(newCfg); err != nil {
 		return fmt.Errorf("Failed reloading services: %s", err)
@@ -3370,8 +3987,22 @@ func (a *Agent) ReloadConfig(newCfg *config.RuntimeConfig) error {
 		return fmt.Errorf("Failed reloading watches: %v", err)
 	}
 
+	if err := a.reloadTemplates(newCfg); err != nil {
+		return fmt.Errorf("Failed reloading templates: %v", err)
+	}
+
 	a.loadLimits(newCfg)
 
+	for _, srv := range a.dnsServers {
+		if err := srv.ReloadConfig(newCfg); err != nil {
+			return fmt.Errorf("Failed reloading dns config: %v", err)
+		}
+	}
+
+	if err := a.reloadHTTPSConfig(newCfg); err != nil {
+		return fmt.Errorf("Failed reloading HTTPS TLS config: %v", err)
+	}
+
 	// create the config for the rpc server/client
 	consulCfg, err := a.consulConfig()
 	if err != nil {
@@ -3382,9 +4013,10 @@ func (a *Agent) ReloadConfig(newCfg *config.RuntimeConfig) error {
 		return err
 	}
 
-	// Update filtered metrics
-	metrics.UpdateFilter(newCfg.Telemetry.AllowedPrefixes,
-		newCfg.Telemetry.BlockedPrefixes)
+	// Update the telemetry sinks and filtered metrics
+	if err := lib.ReloadTelemetry(newCfg.Telemetry); err != nil {
+		return fmt.Errorf("Failed reloading telemetry: %v", err)
+	}
 
 	a.State.SetDiscardCheckOutput(newCfg.DiscardCheckOutput)
 
@@ -3412,6 +4044,7 @@ func (a *Agent) registerCache() {
 	a.cache.RegisterType(cachetype.ConnectCALeafName, &cachetype.ConnectCALeaf{
 		RPC:   a,
 		Cache: a.cache,
+		Node:  a.config.NodeName,
 	}, &cache.RegisterOptions{
 		// Maintain a blocking query, retry dropped connections quickly
 		Refresh:        true,
@@ -3437,6 +4070,15 @@ func (a *Agent) registerCache() {
 		RefreshTimeout: 10 * time.Minute,
 	})
 
+	a.cache.RegisterType(cachetype.CatalogServiceVirtualIPsName, &cachetype.CatalogServiceVirtualIPs{
+		RPC: a,
+	}, &cache.RegisterOptions{
+		// Maintain a blocking query, retry dropped connections quickly
+		Refresh:        true,
+		RefreshTimer:   0 * time.Second,
+		RefreshTimeout: 10 * time.Minute,
+	})
+
 	a.cache.RegisterType(cachetype.HealthServicesName, &cachetype.HealthServices{
 		RPC: a,
 	}, &cache.RegisterOptions{
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -40,6 +41,7 @@ type Self struct {
 	Member      serf.Member
 	Stats       map[string]map[string]string
 	Meta        map[string]string
+	Resources   ResourceLimits
 }
 
 func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -51,7 +53,7 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 		return nil, err
 	}
 	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
 	}
 
 	var cs lib.CoordinateSet
@@ -84,6 +86,42 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 		Member:      s.agent.LocalMember(),
 		Stats:       s.agent.Stats(),
 		Meta:        s.agent.State.Metadata(),
+		Resources:   s.agent.resources,
+	}, nil
+}
+
+// RuntimeConfig is the response of the /v1/agent/config endpoint.
+type RuntimeConfig struct {
+	// Config is the complete runtime configuration with secrets redacted.
+	Config map[string]interface{}
+
+	// Sources maps the fields of Config which have been set to the
+	// sources which set them, in the order they were applied.
+	Sources map[string][]string
+}
+
+func (s *HTTPServer) AgentRuntimeConfig(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Fetch the ACL token, if any, and enforce agent policy.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
+	}
+
+	sources, err := s.agent.ConfigSources()
+	if err != nil {
+		return nil, err
+	}
+	if sources == nil {
+		sources = make(map[string][]string)
+	}
+	return RuntimeConfig{
+		Config:  s.agent.config.Sanitized(),
+		Sources: sources,
 	}, nil
 }
 
@@ -104,7 +142,7 @@ func (s *HTTPServer) AgentMetrics(resp http.ResponseWriter, req *http.Request) (
 		return nil, err
 	}
 	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
 	}
 	if enablePrometheusOutput(req) {
 		if s.agent.config.Telemetry.PrometheusRetentionTime < 1 {
@@ -124,6 +162,38 @@ func (s *HTTPServer) AgentMetrics(resp http.ResponseWriter, req *http.Request) (
 	return s.agent.MemSink.DisplayMetrics(resp, req)
 }
 
+// AgentCheckMetrics exports the status and the runs of the local checks in
+// the Prometheus format. Unlike the other metrics they don't depend on the
+// telemetry config, since they are collected for every request.
+func (s *HTTPServer) AgentCheckMetrics(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Fetch the ACL token, if any.
+	var token string
+	s.parseToken(req, &token)
+
+	checks := s.agent.State.Checks()
+	if err := s.agent.filterChecks(token, &checks); err != nil {
+		return nil, err
+	}
+
+	registry := prometheus.NewRegistry()
+	collector := &checkMetricsCollector{
+		node:   s.agent.config.NodeName,
+		checks: checks,
+		states: s.agent.State.CheckStates(),
+	}
+	if err := registry.Register(collector); err != nil {
+		return nil, err
+	}
+
+	handlerOptions := promhttp.HandlerOpts{
+		ErrorLog:      s.agent.logger,
+		ErrorHandling: promhttp.ContinueOnError,
+	}
+	handler := promhttp.HandlerFor(registry, handlerOptions)
+	handler.ServeHTTP(resp, req)
+	return nil, nil
+}
+
 func (s *HTTPServer) AgentReload(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any, and enforce agent policy.
 	var token string
@@ -133,23 +203,26 @@ func (s *HTTPServer) AgentReload(resp http.ResponseWriter, req *http.Request) (i
 		return nil, err
 	}
 	if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
 	}
 
 	// Trigger the reload
-	errCh := make(chan error, 0)
+	respCh := make(chan ReloadResponse, 0)
 	select {
 	case <-s.agent.shutdownCh:
 		return nil, fmt.Errorf("Agent was shutdown before reload could be completed")
-	case s.agent.reloadCh <- errCh:
+	case s.agent.reloadCh <- respCh:
 	}
 
 	// Wait for the result of the reload, or for the agent to shutdown
 	select {
 	case <-s.agent.shutdownCh:
 		return nil, fmt.Errorf("Agent was shutdown before reload could be completed")
-	case err := <-errCh:
-		return nil, err
+	case resp := <-respCh:
+		if resp.Err != nil {
+			return nil, resp.Err
+		}
+		return resp.Result, nil
 	}
 }
 
@@ -181,17 +254,18 @@ func (s *HTTPServer) AgentServices(resp http.ResponseWriter, req *http.Request)
 			weights.Warning = s.Weights.Warning
 		}
 		as := &api.AgentService{
-			Kind:              api.ServiceKind(s.Kind),
-			ID:                s.ID,
-			Service:           s.Service,
-			Tags:              s.Tags,
-			Meta:              s.Meta,
-			Port:              s.Port,
-			Address:           s.Address,
-			EnableTagOverride: s.EnableTagOverride,
-			CreateIndex:       s.CreateIndex,
-			ModifyIndex:       s.ModifyIndex,
-			Weights:           weights,
+			Kind:                 api.ServiceKind(s.Kind),
+			ID:                   s.ID,
+			Service:              s.Service,
+			Tags:                 s.Tags,
+			Meta:                 s.Meta,
+			Port:                 s.Port,
+			Address:              s.Address,
+			EnableTagOverride:    s.EnableTagOverride,
+			CreateIndex:          s.CreateIndex,
+			ModifyIndex:          s.ModifyIndex,
+			Weights:              weights,
+			DeregisterOnShutdown: s.DeregisterOnShutdown,
 		}
 
 		if as.Tags == nil {
@@ -337,7 +411,7 @@ func (s *HTTPServer) AgentService(resp http.ResponseWriter, req *http.Request) (
 				return "", nil, err
 			}
 			if rule != nil && !rule.ServiceRead(svc.Service) {
-				return "", nil, acl.ErrPermissionDenied
+				return "", nil, acl.PermissionDenied("service", svc.Service, "read")
 			}
 
 			var connect *api.AgentServiceConnect
@@ -363,17 +437,18 @@ func (s *HTTPServer) AgentService(resp http.ResponseWriter, req *http.Request) (
 
 			// Calculate the content hash over the response, minus the hash field
 			reply := &api.AgentService{
-				Kind:              api.ServiceKind(svc.Kind),
-				ID:                svc.ID,
-				Service:           svc.Service,
-				Tags:              svc.Tags,
-				Meta:              svc.Meta,
-				Port:              svc.Port,
-				Address:           svc.Address,
-				EnableTagOverride: svc.EnableTagOverride,
-				Weights:           weights,
-				Proxy:             proxy,
-				Connect:           connect,
+				Kind:                 api.ServiceKind(svc.Kind),
+				ID:                   svc.ID,
+				Service:              svc.Service,
+				Tags:                 svc.Tags,
+				Meta:                 svc.Meta,
+				Port:                 svc.Port,
+				Address:              svc.Address,
+				EnableTagOverride:    svc.EnableTagOverride,
+				Weights:              weights,
+				DeregisterOnShutdown: svc.DeregisterOnShutdown,
+				Proxy:                proxy,
+				Connect:              connect,
 			}
 
 			rawHash, err := hashstructure.Hash(reply, nil)
@@ -464,7 +539,7 @@ func (s *HTTPServer) AgentJoin(resp http.ResponseWriter, req *http.Request) (int
 		return nil, err
 	}
 	if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
 	}
 
 	// Check if the WAN is being queried
@@ -492,7 +567,7 @@ func (s *HTTPServer) AgentLeave(resp http.ResponseWriter, req *http.Request) (in
 		return nil, err
 	}
 	if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
 	}
 
 	if err := s.agent.Leave(); err != nil {
@@ -510,7 +585,7 @@ func (s *HTTPServer) AgentForceLeave(resp http.ResponseWriter, req *http.Request
 		return nil, err
 	}
 	if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
 	}
 
 	addr := strings.TrimPrefix(req.URL.Path, "/v1/agent/force-leave/")
@@ -704,6 +779,59 @@ func (s *HTTPServer) AgentCheckUpdate(resp http.ResponseWriter, req *http.Reques
 	return nil, nil
 }
 
+// checkDefinitionUpdate is the payload for a PUT to
+// AgentCheckUpdateDefinition. Fields which are nil are left unchanged.
+type checkDefinitionUpdate struct {
+	Interval                       *time.Duration
+	Timeout                        *time.Duration
+	TTL                            *time.Duration
+	DeregisterCriticalServiceAfter *time.Duration
+}
+
+// AgentCheckUpdateDefinition changes the timing of a registered check
+// without re-registering it or the service it belongs to.
+func (s *HTTPServer) AgentCheckUpdateDefinition(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var update checkDefinitionUpdate
+	// Fixup the type decode of the durations.
+	decodeCB := func(raw interface{}) error {
+		return FixupCheckType(raw)
+	}
+	if err := decodeBody(req, &update, decodeCB); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		return nil, nil
+	}
+
+	for _, d := range []*time.Duration{update.Interval, update.Timeout, update.TTL, update.DeregisterCriticalServiceAfter} {
+		if d != nil && *d < 0 {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(resp, "Durations must not be negative")
+			return nil, nil
+		}
+	}
+
+	checkID := types.CheckID(strings.TrimPrefix(req.URL.Path, "/v1/agent/check/update-definition/"))
+
+	// Get the provided token, if any, and vet against any ACL policies.
+	var token string
+	s.parseToken(req, &token)
+	if err := s.agent.vetCheckUpdate(token, checkID); err != nil {
+		return nil, err
+	}
+
+	if s.agent.State.Check(checkID) == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "Unknown check %q", checkID)
+		return nil, nil
+	}
+
+	if err := s.agent.updateCheckDefinition(checkID, &update); err != nil {
+		return nil, err
+	}
+	s.syncChanges()
+	return nil, nil
+}
+
 func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var args structs.ServiceDefinition
 	// Fixup the type decode of TTL or Interval if a check if provided.
@@ -716,7 +844,8 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 		// see https://github.com/hashicorp/consul/pull/3557 why we need this
 		// and why we should get rid of it.
 		config.TranslateKeys(rawMap, map[string]string{
-			"enable_tag_override": "EnableTagOverride",
+			"enable_tag_override":    "EnableTagOverride",
+			"deregister_on_shutdown": "DeregisterOnShutdown",
 			// Managed Proxy Config
 			"exec_mode": "ExecMode",
 			// Proxy Upstreams
@@ -730,6 +859,9 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 			"destination_service_id":   "DestinationServiceID",
 			"local_service_port":       "LocalServicePort",
 			"local_service_address":    "LocalServiceAddress",
+			"transparent_proxy":        "TransparentProxy",
+			"outbound_listener_port":   "OutboundListenerPort",
+			"allowed_services":         "AllowedServices",
 			// SidecarService
 			"sidecar_service": "SidecarService",
 
@@ -768,6 +900,14 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 						return err
 					}
 				}
+			case "warmup":
+				if str, ok := v.(string); ok {
+					dur, err := time.ParseDuration(str)
+					if err != nil {
+						return fmt.Errorf("invalid warmup: %v", err)
+					}
+					rawMap[k] = dur
+				}
 			}
 		}
 		return nil
@@ -793,6 +933,12 @@ func (s *HTTPServer) AgentRegisterService(resp http.ResponseWriter, req *http.Re
 		return nil, nil
 	}
 
+	if args.Warmup < 0 {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(resp, "Invalid warmup: must not be negative")
+		return nil, nil
+	}
+
 	// Get the node service.
 	ns := args.NodeService()
 	if ns.Weights != nil {
@@ -1010,7 +1156,7 @@ func (s *HTTPServer) AgentNodeMaintenance(resp http.ResponseWriter, req *http.Re
 		return nil, err
 	}
 	if rule != nil && !rule.NodeWrite(s.agent.config.NodeName, nil) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("node", s.agent.config.NodeName, "write")
 	}
 
 	if enable {
@@ -1022,6 +1168,150 @@ func (s *HTTPServer) AgentNodeMaintenance(resp http.ResponseWriter, req *http.Re
 	return nil, nil
 }
 
+// AgentNodeMeta is used to read and update the metadata of the local node
+// at runtime.
+func (s *HTTPServer) AgentNodeMeta(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Get the provided token, if any, and vet against any ACL policies.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		if rule != nil && !rule.NodeRead(s.agent.config.NodeName) {
+			return nil, acl.PermissionDenied("node", s.agent.config.NodeName, "read")
+		}
+		return s.agent.State.Metadata(), nil
+
+	case "PUT", "DELETE":
+		if rule != nil && !rule.NodeWrite(s.agent.config.NodeName, nil) {
+			return nil, acl.PermissionDenied("node", s.agent.config.NodeName, "write")
+		}
+
+		var set map[string]string
+		var remove []string
+		if req.Method == "PUT" {
+			if err := decodeBody(req, &set, nil); err != nil {
+				resp.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(resp, "Request decode failed: %v", err)
+				return nil, nil
+			}
+		} else {
+			remove = req.URL.Query()["key"]
+			if len(remove) == 0 {
+				resp.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(resp, "Missing key to delete")
+				return nil, nil
+			}
+		}
+
+		meta, err := s.agent.updateNodeMeta(set, remove)
+		if err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Invalid node metadata: %v", err)
+			return nil, nil
+		}
+		s.syncChanges()
+		return meta, nil
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+}
+
+// AgentFaultInjection is used to inspect and change the synthetic failures
+// injected by the agent. The endpoint only exists if fault injection is
+// enabled.
+func (s *HTTPServer) AgentFaultInjection(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.faults == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(resp, "Fault injection is disabled")
+		return nil, nil
+	}
+
+	// Get the provided token, if any, and vet against any ACL policies.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+			return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
+		}
+		return s.agent.faults.Faults(), nil
+
+	case "PUT", "DELETE":
+		if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
+			return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
+		}
+
+		var faults api.AgentFaultInjection
+		if req.Method == "PUT" {
+			durations := NewDurationFixer("rpclatency", "leaderchurninterval")
+			if err := decodeBody(req, &faults, durations.FixupDurations); err != nil {
+				resp.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(resp, "Request decode failed: %v", err)
+				return nil, nil
+			}
+		}
+		if err := s.agent.faults.SetFaults(faults); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Invalid fault injection: %v", err)
+			return nil, nil
+		}
+		return nil, nil
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+}
+
+// AgentCache returns the statistics of the agent cache per type, or
+// invalidates the entries of a type, or of all types if none is given, so
+// that they are fetched again from the servers.
+func (s *HTTPServer) AgentCache(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Get the provided token, if any, and vet against any ACL policies.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
+			return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
+		}
+		return s.agent.cache.Stats(), nil
+
+	case "DELETE":
+		if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
+			return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
+		}
+
+		t := req.URL.Query().Get("type")
+		removed, ok := s.agent.cache.Invalidate(t)
+		if !ok {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Unknown cache type: %s", t)
+			return nil, nil
+		}
+		s.agent.logger.Printf("[INFO] agent: Invalidated %d cache entries", removed)
+		return api.AgentCacheInvalidation{Invalidated: removed}, nil
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "DELETE"}}
+	}
+}
+
 func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any, and enforce agent policy.
 	var token string
@@ -1031,7 +1321,7 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 		return nil, err
 	}
 	if rule != nil && !rule.AgentRead(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "read")
 	}
 
 	// Get the provided loglevel.
@@ -1051,6 +1341,14 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 		fmt.Fprintf(resp, "Unknown log level: %s", filter.MinLevel)
 		return nil, nil
 	}
+
+	// Get the optional subsystem filters and output format.
+	subsystems := &logger.SubsystemFilter{
+		Include: parseMonitorSubsystems(req.URL.Query()["include"]),
+		Exclude: parseMonitorSubsystems(req.URL.Query()["exclude"]),
+	}
+	_, logJSON := req.URL.Query()["logjson"]
+
 	flusher, ok := resp.(http.Flusher)
 	if !ok {
 		return nil, fmt.Errorf("Streaming not supported")
@@ -1058,9 +1356,10 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 
 	// Set up a log handler.
 	handler := &httpLogHandler{
-		filter: filter,
-		logCh:  make(chan string, 512),
-		logger: s.agent.logger,
+		filter:     filter,
+		subsystems: subsystems,
+		logCh:      make(chan string, 512),
+		logger:     s.agent.logger,
 	}
 	s.agent.LogWriter.RegisterHandler(handler)
 	defer s.agent.LogWriter.DeregisterHandler(handler)
@@ -1084,14 +1383,36 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 			}
 			return nil, nil
 		case log := <-handler.logCh:
+			if logJSON {
+				buf, err := json.Marshal(logger.ParseLine(log))
+				if err != nil {
+					continue
+				}
+				log = string(buf)
+			}
 			fmt.Fprintln(resp, log)
 			flusher.Flush()
 		}
 	}
 }
 
+// parseMonitorSubsystems returns the subsystem names of the given query
+// parameter values, which may be repeated or comma separated.
+func parseMonitorSubsystems(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
 type httpLogHandler struct {
 	filter       *logutils.LevelFilter
+	subsystems   *logger.SubsystemFilter
 	logCh        chan string
 	logger       *log.Logger
 	droppedCount int
@@ -1103,6 +1424,11 @@ func (h *httpLogHandler) HandleLog(log string) {
 		return
 	}
 
+	// Check the subsystem
+	if !h.subsystems.Empty() && !h.subsystems.Check(logger.ParseLine(log).Subsystem) {
+		return
+	}
+
 	// Do a non-blocking send
 	select {
 	case h.logCh <- log:
@@ -1126,7 +1452,7 @@ func (s *HTTPServer) AgentToken(resp http.ResponseWriter, req *http.Request) (in
 		return nil, err
 	}
 	if rule != nil && !rule.AgentWrite(s.agent.config.NodeName) {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("agent", s.agent.config.NodeName, "write")
 	}
 
 	// The body is just the token, but it's in a JSON object so we can add
@@ -1140,28 +1466,18 @@ func (s *HTTPServer) AgentToken(resp http.ResponseWriter, req *http.Request) (in
 
 	// Figure out the target token.
 	target := strings.TrimPrefix(req.URL.Path, "/v1/agent/token/")
-	switch target {
-	case "acl_token":
-		s.agent.tokens.UpdateUserToken(args.Token)
-
-	case "acl_agent_token":
-		s.agent.tokens.UpdateAgentToken(args.Token)
-
-	case "acl_agent_master_token":
-		s.agent.tokens.UpdateAgentMasterToken(args.Token)
-
-	case "acl_replication_token":
-		s.agent.tokens.UpdateACLReplicationToken(args.Token)
-
-	case "connect_replication_token":
-		s.agent.tokens.UpdateConnectReplicationToken(args.Token)
-
-	default:
+	if !s.agent.updateToken(target, args.Token) {
 		resp.WriteHeader(http.StatusNotFound)
 		fmt.Fprintf(resp, "Token %q is unknown", target)
 		return nil, nil
 	}
 
+	if s.agent.config.ACLEnableTokenPersistence {
+		if err := s.agent.persistToken(target, args.Token); err != nil {
+			return nil, fmt.Errorf("Failed to persist token %q: %v", target, err)
+		}
+	}
+
 	s.agent.logger.Printf("[INFO] agent: Updated agent's ACL token %q", target)
 	return nil, nil
 }
@@ -1367,6 +1683,146 @@ func (s *HTTPServer) AgentConnectProxyConfig(resp http.ResponseWriter, req *http
 		})
 }
 
+// GET /v1/agent/connect/proxy-snapshot/:service_id
+//
+// Returns the resolved configuration of a local Connect proxy as it is pushed
+// to the proxy, for debugging. The ID may be the ID of the proxy or of the
+// service it proxies. Requires service:write for the target service.
+func (s *HTTPServer) AgentConnectProxySnapshot(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(req.URL.Path, "/v1/agent/connect/proxy-snapshot/")
+
+	var args structs.DCSpecificRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	proxy := s.agent.State.Service(id)
+	if proxy == nil || proxy.Kind != structs.ServiceKindConnectProxy {
+		proxy = nil
+		for _, svc := range s.agent.State.Services() {
+			if svc.Kind == structs.ServiceKindConnectProxy && svc.Proxy.DestinationServiceID == id {
+				proxy = svc
+				break
+			}
+		}
+	}
+	if proxy == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "unknown proxy service ID: %s", id)
+		return nil, nil
+	}
+	target := proxy.Proxy.DestinationServiceName
+
+	rule, err := s.agent.resolveToken(args.Token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && !rule.ServiceWrite(target, nil) {
+		return nil, acl.PermissionDenied("service", target, "write")
+	}
+
+	snap := s.agent.proxyConfig.Snapshot(proxy.ID)
+	if snap == nil {
+		return nil, fmt.Errorf("Config of proxy %q is not available yet", proxy.ID)
+	}
+
+	reply := &api.ConnectProxySnapshot{
+		ProxyServiceID:    snap.ProxyID,
+		TargetServiceID:   snap.Proxy.DestinationServiceID,
+		TargetServiceName: target,
+		Address:           snap.Address,
+		Port:              snap.Port,
+		Config:            snap.Proxy.Config,
+		Upstreams:         make([]api.ConnectProxySnapshotUpstream, 0, len(snap.Proxy.Upstreams)),
+		Leaf: api.ConnectProxySnapshotLeaf{
+			SerialNumber: snap.Leaf.SerialNumber,
+			ServiceURI:   snap.Leaf.ServiceURI,
+			ValidAfter:   snap.Leaf.ValidAfter,
+			ValidBefore:  snap.Leaf.ValidBefore,
+		},
+		TrustDomain: snap.Roots.TrustDomain,
+		Roots:       make([]api.ConnectProxySnapshotRoot, 0, len(snap.Roots.Roots)),
+		Intentions:  make([]api.ConnectProxySnapshotIntention, 0),
+	}
+
+	for _, u := range snap.Proxy.Upstreams {
+		upstream := api.ConnectProxySnapshotUpstream{
+			Upstream:  u.ToAPI(),
+			Endpoints: make([]api.ConnectProxySnapshotEndpoint, 0),
+		}
+		for _, csn := range snap.UpstreamEndpoints[u.Identifier()] {
+			addr := csn.Service.Address
+			if addr == "" {
+				addr = csn.Node.Address
+			}
+			checks := make(api.HealthChecks, 0, len(csn.Checks))
+			for _, check := range csn.Checks {
+				checks = append(checks, &api.HealthCheck{
+					CheckID: string(check.CheckID),
+					Status:  check.Status,
+				})
+			}
+			upstream.Endpoints = append(upstream.Endpoints, api.ConnectProxySnapshotEndpoint{
+				Node:      csn.Node.Node,
+				ServiceID: csn.Service.ID,
+				Address:   addr,
+				Port:      csn.Service.Port,
+				Status:    checks.AggregatedStatus(),
+			})
+		}
+		reply.Upstreams = append(reply.Upstreams, upstream)
+	}
+
+	for _, root := range snap.Roots.Roots {
+		reply.Roots = append(reply.Roots, api.ConnectProxySnapshotRoot{
+			ID:           root.ID,
+			Name:         root.Name,
+			SerialNumber: root.SerialNumber,
+			NotBefore:    root.NotBefore,
+			NotAfter:     root.NotAfter,
+			Active:       root.Active,
+		})
+	}
+
+	// Evaluate the intentions the same way the authorize endpoint does.
+	raw, _, err := s.agent.cache.Get(cachetype.IntentionMatchName, &structs.IntentionQueryRequest{
+		Datacenter: s.agent.config.Datacenter,
+		Match: &structs.IntentionQueryMatch{
+			Type: structs.IntentionMatchDestination,
+			Entries: []structs.IntentionMatchEntry{
+				{
+					Namespace: structs.IntentionDefaultNamespace,
+					Name:      target,
+				},
+			},
+		},
+		QueryOptions: structs.QueryOptions{Token: args.Token},
+	})
+	if err != nil {
+		return nil, err
+	}
+	matches, ok := raw.(*structs.IndexedIntentionMatches)
+	if !ok || len(matches.Matches) != 1 {
+		return nil, fmt.Errorf("internal error: response type not correct")
+	}
+	for _, ixn := range matches.Matches[0] {
+		reply.Intentions = append(reply.Intentions, api.ConnectProxySnapshotIntention{
+			ID:         ixn.ID,
+			SourceName: ixn.SourceName,
+			Action:     api.IntentionAction(ixn.Action),
+			Precedence: ixn.Precedence,
+		})
+	}
+
+	rule, err = s.agent.resolveToken("")
+	if err != nil {
+		return nil, err
+	}
+	reply.DefaultAllow = rule == nil || rule.IntentionDefaultAllow()
+
+	return reply, nil
+}
+
 type agentLocalBlockingFunc func(ws memdb.WatchSet) (string, interface{}, error)
 
 // agentLocalBlockingQuery performs a blocking query in a generic way against
@@ -1483,7 +1939,7 @@ func (s *HTTPServer) AgentHost(resp http.ResponseWriter, req *http.Request) (int
 	// TODO(pearkes): Is agent:read appropriate here? There could be relatively
 	// sensitive information made available in this API
 	if rule != nil && !rule.OperatorRead() {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("operator", "", "read")
 	}
 
 	return debug.CollectHostInfo(), nil
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
@@ -11,12 +12,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
 	"github.com/hashicorp/consul/agent/checks"
 	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/agent/connect"
@@ -27,6 +32,7 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/serf/serf"
@@ -273,7 +279,7 @@ func TestAgent_Service(t *testing.T) {
 		Service:     "web-sidecar-proxy",
 		Port:        8000,
 		Proxy:       expectProxy.ToAPI(),
-		ContentHash: "3442362e971c43d1",
+		ContentHash: "e55e053c4f03440f",
 		Weights: api.AgentWeights{
 			Passing: 1,
 			Warning: 1,
@@ -283,14 +289,14 @@ func TestAgent_Service(t *testing.T) {
 	// Copy and modify
 	updatedResponse := *expectedResponse
 	updatedResponse.Port = 9999
-	updatedResponse.ContentHash = "90b5c19bf0f5073"
+	updatedResponse.ContentHash = "d8176f0b671057ad"
 
 	// Simple response for non-proxy service registered in TestAgent config
 	expectWebResponse := &api.AgentService{
 		ID:          "web",
 		Service:     "web",
 		Port:        8181,
-		ContentHash: "69351c1ac865b034",
+		ContentHash: "208def6aebe4863d",
 		Weights: api.AgentWeights{
 			Passing: 1,
 			Warning: 1,
@@ -702,6 +708,9 @@ func TestAgent_Self(t *testing.T) {
 	if !reflect.DeepEqual(a.config.NodeMeta, val.Meta) {
 		t.Fatalf("meta fields are not equal: %v != %v", a.config.NodeMeta, val.Meta)
 	}
+	if val.Resources.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Fatalf("bad: %#v", val.Resources)
+	}
 }
 
 func TestAgent_Self_ACLDeny(t *testing.T) {
@@ -733,6 +742,54 @@ func TestAgent_Self_ACLDeny(t *testing.T) {
 	})
 }
 
+func TestAgent_RuntimeConfig(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		acl {
+			tokens {
+				agent = "secret-agent-token"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	a.SetConfigSources(func() (map[string][]string, error) {
+		return map[string][]string{
+			"HTTPPort": []string{config.SourceDefault, "/etc/consul.d/ports.json"},
+		}, nil
+	})
+
+	req, _ := http.NewRequest("GET", "/v1/agent/config", nil)
+	obj, err := a.srv.AgentRuntimeConfig(nil, req)
+	require.NoError(t, err)
+
+	val := obj.(RuntimeConfig)
+	require.Equal(t, a.Config.SerfPortLAN, val.Config["SerfPortLAN"])
+	require.Equal(t, "hidden", val.Config["ACLAgentToken"])
+	require.Equal(t, []string{config.SourceDefault, "/etc/consul.d/ports.json"}, val.Sources["HTTPPort"])
+}
+
+func TestAgent_RuntimeConfig_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/config", nil)
+		if _, err := a.srv.AgentRuntimeConfig(nil, req); !acl.IsErrPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("read-only token", func(t *testing.T) {
+		ro := makeReadOnlyAgentACL(t, a.srv)
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/v1/agent/config?token=%s", ro), nil)
+		if _, err := a.srv.AgentRuntimeConfig(nil, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestAgent_Metrics_ACLDeny(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), TestACLConfig())
@@ -762,6 +819,81 @@ func TestAgent_Metrics_ACLDeny(t *testing.T) {
 	})
 }
 
+func TestAgent_CheckMetrics(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	args := &structs.CheckDefinition{
+		Name: "web",
+		TTL:  15 * time.Second,
+	}
+	req, _ := http.NewRequest("PUT", "/v1/agent/check/register", jsonReader(args))
+	if _, err := a.srv.AgentRegisterCheck(nil, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.updateTTLCheck("web", api.HealthPassing, "ok"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/agent/metrics/checks", nil)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.AgentCheckMetrics(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	body := resp.Body.String()
+	labels := fmt.Sprintf(`check_id="web",check_name="web",node=%q,service_id="",service_name=""`, a.config.NodeName)
+	for _, want := range []string{
+		`consul_check_status{` + labels + `,status="passing"} 1`,
+		`consul_check_status{` + labels + `,status="critical"} 0`,
+		`consul_check_status_changes_total{` + labels + `} 1`,
+		`consul_check_last_run_timestamp_seconds{` + labels + `} `,
+		`consul_check_last_run_duration_seconds{` + labels + `} `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestAgent_CheckMetrics_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	chk := &structs.HealthCheck{
+		Node:    a.config.NodeName,
+		CheckID: "web",
+		Name:    "web",
+		Status:  api.HealthPassing,
+	}
+	a.State.AddCheck(chk, "")
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics/checks", nil)
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.AgentCheckMetrics(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if strings.Contains(resp.Body.String(), `check_id="web"`) {
+			t.Fatalf("bad: %s", resp.Body.String())
+		}
+	})
+
+	t.Run("root token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics/checks?token=root", nil)
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.AgentCheckMetrics(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !strings.Contains(resp.Body.String(), `check_id="web"`) {
+			t.Fatalf("bad: %s", resp.Body.String())
+		}
+	})
+}
+
 func TestAgent_Reload(t *testing.T) {
 	t.Parallel()
 	dc1 := "dc1"
@@ -810,6 +942,7 @@ func TestAgent_Reload(t *testing.T) {
         rpc_rate=2
         rpc_max_burst=200
       }
+			recursors = ["8.8.8.8"]
 		`,
 	})
 
@@ -828,6 +961,13 @@ func TestAgent_Reload(t *testing.T) {
 		t.Fatalf("RPC max burst not set correctly.  Got %v. Want 200", a.config.RPCMaxBurst)
 	}
 
+	for _, srv := range a.dnsServers {
+		got := srv.recursors.forName("example.com.")
+		if !reflect.DeepEqual(got, []string{"8.8.8.8:53"}) {
+			t.Fatalf("DNS recursors not set correctly. Got %v", got)
+		}
+	}
+
 	for _, wp := range a.watchPlans {
 		if !wp.IsStopped() {
 			t.Fatalf("Reloading configs should stop watch plans of the previous configuration")
@@ -1030,73 +1170,124 @@ func TestAgent_Join_ACLDeny(t *testing.T) {
 	})
 }
 
-type mockNotifier struct{ s string }
-
-func (n *mockNotifier) Notify(state string) error {
-	n.s = state
-	return nil
-}
-
-func TestAgent_JoinLANNotify(t *testing.T) {
+func TestAgent_Leave(t *testing.T) {
 	t.Parallel()
 	a1 := NewTestAgent(t.Name(), "")
 	defer a1.Shutdown()
 	testrpc.WaitForLeader(t, a1.RPC, "dc1")
 
 	a2 := NewTestAgent(t.Name(), `
-		server = false
-		bootstrap = false
-	`)
+ 		server = false
+ 		bootstrap = false
+ 	`)
 	defer a2.Shutdown()
 
-	notif := &mockNotifier{}
-	a1.joinLANNotifier = notif
-
+	// Join first
 	addr := fmt.Sprintf("127.0.0.1:%d", a2.Config.SerfPortLAN)
 	_, err := a1.JoinLAN([]string{addr})
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	if got, want := notif.s, "READY=1"; got != want {
-		t.Fatalf("got joinLAN notification %q want %q", got, want)
+	// Graceful leave now
+	req, _ := http.NewRequest("PUT", "/v1/agent/leave", nil)
+	obj, err := a2.srv.AgentLeave(nil, req)
+	if err != nil {
+		t.Fatalf("Err: %v", err)
 	}
+	if obj != nil {
+		t.Fatalf("Err: %v", obj)
+	}
+	retry.Run(t, func(r *retry.R) {
+		m := a1.LANMembers()
+		if got, want := m[1].Status, serf.StatusLeft; got != want {
+			r.Fatalf("got status %q want %q", got, want)
+		}
+	})
 }
 
-func TestAgent_Leave(t *testing.T) {
+func TestAgent_Leave_DeregisterOnShutdown(t *testing.T) {
 	t.Parallel()
 	a1 := NewTestAgent(t.Name(), "")
 	defer a1.Shutdown()
 	testrpc.WaitForLeader(t, a1.RPC, "dc1")
 
 	a2 := NewTestAgent(t.Name(), `
- 		server = false
- 		bootstrap = false
- 	`)
+		server = false
+		bootstrap = false
+		deregister_services_on_shutdown = false
+		services = [
+			{
+				name = "retained"
+				port = 8000
+			},
+			{
+				name = "removed"
+				port = 8001
+				deregister_on_shutdown = true
+			}
+		]
+	`)
 	defer a2.Shutdown()
 
 	// Join first
 	addr := fmt.Sprintf("127.0.0.1:%d", a2.Config.SerfPortLAN)
-	_, err := a1.JoinLAN([]string{addr})
-	if err != nil {
+	if _, err := a1.JoinLAN([]string{addr}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
+	nodeServices := func(r *retry.R) map[string]*structs.NodeService {
+		args := structs.NodeSpecificRequest{Datacenter: "dc1", Node: a2.Config.NodeName}
+		var out structs.IndexedNodeServices
+		if err := a1.RPC("Catalog.NodeServices", &args, &out); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if out.NodeServices == nil {
+			r.Fatalf("node %q not found", a2.Config.NodeName)
+		}
+		return out.NodeServices.Services
+	}
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(nodeServices(r)), 2; got != want {
+			r.Fatalf("got %d services want %d", got, want)
+		}
+	})
+
 	// Graceful leave now
 	req, _ := http.NewRequest("PUT", "/v1/agent/leave", nil)
-	obj, err := a2.srv.AgentLeave(nil, req)
-	if err != nil {
+	if _, err := a2.srv.AgentLeave(nil, req); err != nil {
 		t.Fatalf("Err: %v", err)
 	}
-	if obj != nil {
-		t.Fatalf("Err: %v", obj)
-	}
 	retry.Run(t, func(r *retry.R) {
 		m := a1.LANMembers()
 		if got, want := m[1].Status, serf.StatusLeft; got != want {
 			r.Fatalf("got status %q want %q", got, want)
 		}
 	})
+
+	// Only the retained service is left and the node is critical.
+	retry.Run(t, func(r *retry.R) {
+		services := nodeServices(r)
+		if _, ok := services["retained"]; !ok || len(services) != 1 {
+			r.Fatalf("bad: %v", services)
+		}
+
+		args := structs.NodeSpecificRequest{Datacenter: "dc1", Node: a2.Config.NodeName}
+		var out structs.IndexedHealthChecks
+		if err := a1.RPC("Health.NodeChecks", &args, &out); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		for _, check := range out.HealthChecks {
+			if check.CheckID != structs.SerfCheckID {
+				continue
+			}
+			if check.Status != api.HealthCritical || check.Output != structs.SerfCheckLeftOutput {
+				r.Fatalf("bad: %#v", check)
+			}
+			return
+		}
+		r.Fatalf("no serf check: %v", out.HealthChecks)
+	})
 }
 
 func TestAgent_Leave_ACLDeny(t *testing.T) {
@@ -1797,6 +1988,141 @@ func TestAgent_UpdateCheck_ACLDeny(t *testing.T) {
 	})
 }
 
+func TestAgent_UpdateCheckDefinition(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	chk := &structs.HealthCheck{Name: "test", CheckID: "test", Status: api.HealthWarning}
+	chkType := &structs.CheckType{
+		HTTP:     "http://127.0.0.1:0/health",
+		Interval: time.Hour,
+		Timeout:  time.Second,
+	}
+	if err := a.AddCheck(chk, chkType, true, "", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		args := map[string]interface{}{
+			"Interval":                          "2h",
+			"deregister_critical_service_after": "3h",
+		}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/test", jsonReader(args))
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.AgentCheckUpdateDefinition(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj != nil {
+			t.Fatalf("bad: %v", obj)
+		}
+		if resp.Code != 200 {
+			t.Fatalf("expected 200, got %d", resp.Code)
+		}
+
+		a.checkLock.Lock()
+		httpCheck := a.checkHTTPs["test"]
+		reapAfter := a.checkReapAfter["test"]
+		a.checkLock.Unlock()
+		if httpCheck.Interval != 2*time.Hour || httpCheck.Timeout != time.Second {
+			t.Fatalf("bad: %#v", httpCheck)
+		}
+		if reapAfter != 3*time.Hour {
+			t.Fatalf("bad: %v", reapAfter)
+		}
+
+		// The status must not be reset.
+		if got, want := a.State.Check("test").Status, api.HealthWarning; got != want {
+			t.Fatalf("got status %q want %q", got, want)
+		}
+
+		// The new definition must be persisted.
+		file := filepath.Join(a.Config.DataDir, checksDir, checkIDHash("test"))
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		var p persistedCheck
+		if err := json.Unmarshal(buf, &p); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if p.ChkType.Interval != 2*time.Hour {
+			t.Fatalf("bad: %#v", p.ChkType)
+		}
+	})
+
+	t.Run("invalid definition", func(t *testing.T) {
+		args := map[string]interface{}{"TTL": "10s"}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/test", jsonReader(args))
+		if _, err := a.srv.AgentCheckUpdateDefinition(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("should have failed")
+		}
+
+		// The check keeps running with the old definition.
+		a.checkLock.Lock()
+		httpCheck := a.checkHTTPs["test"]
+		a.checkLock.Unlock()
+		if httpCheck == nil || httpCheck.Interval != 2*time.Hour {
+			t.Fatalf("bad: %#v", httpCheck)
+		}
+	})
+
+	t.Run("negative duration", func(t *testing.T) {
+		args := map[string]interface{}{"Timeout": "-1s"}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/test", jsonReader(args))
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.AgentCheckUpdateDefinition(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Code != 400 {
+			t.Fatalf("expected 400, got %d", resp.Code)
+		}
+	})
+
+	t.Run("unknown check", func(t *testing.T) {
+		args := map[string]interface{}{"Interval": "10s"}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/nope", jsonReader(args))
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.AgentCheckUpdateDefinition(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Code != 404 {
+			t.Fatalf("expected 404, got %d", resp.Code)
+		}
+	})
+}
+
+func TestAgent_UpdateCheckDefinition_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	chk := &structs.HealthCheck{Name: "test", CheckID: "test"}
+	chkType := &structs.CheckType{TTL: 15 * time.Second}
+	if err := a.AddCheck(chk, chkType, false, "", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	t.Run("no token", func(t *testing.T) {
+		args := map[string]interface{}{"TTL": "30s"}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/test", jsonReader(args))
+		if _, err := a.srv.AgentCheckUpdateDefinition(nil, req); !acl.IsErrPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("root token", func(t *testing.T) {
+		args := map[string]interface{}{"TTL": "30s"}
+		req, _ := http.NewRequest("PUT", "/v1/agent/check/update-definition/test?token=root", jsonReader(args))
+		if _, err := a.srv.AgentCheckUpdateDefinition(nil, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestAgent_RegisterService(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -1864,6 +2190,37 @@ func TestAgent_RegisterService(t *testing.T) {
 	}
 }
 
+func TestAgent_RegisterService_Warmup(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	for _, tc := range []struct {
+		warmup string
+		code   int
+	}{
+		{"10m", http.StatusOK},
+		{"-1s", http.StatusBadRequest},
+		{"soon", http.StatusBadRequest},
+	} {
+		t.Run(tc.warmup, func(t *testing.T) {
+			body := bytes.NewBufferString(`{"Name": "test", "Port": 8000, "warmup": "` + tc.warmup + `"}`)
+			req, _ := http.NewRequest("PUT", "/v1/agent/service/register", body)
+			resp := httptest.NewRecorder()
+			_, err := a.srv.AgentRegisterService(resp, req)
+			require.NoError(t, err)
+			require.Equal(t, tc.code, resp.Code, resp.Body.String())
+		})
+	}
+
+	// The service is excluded from queries while it warms up
+	require.Equal(t, 10*time.Minute, a.State.Service("test").Warmup)
+	check, ok := a.State.Checks()[serviceWarmupCheckID("test")]
+	require.True(t, ok)
+	require.Equal(t, api.HealthCritical, check.Status)
+}
+
 func TestAgent_RegisterService_TranslateKeys(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), `
@@ -3451,6 +3808,209 @@ func TestAgent_NodeMaintenance_ACLDeny(t *testing.T) {
 	})
 }
 
+func TestAgent_NodeMeta(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		node_meta {
+			rack = "r1"
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Set a new field and override a configured one.
+	body := bytes.NewBufferString(`{"maintenance-window": "true", "rack": "r2"}`)
+	req, _ := http.NewRequest("PUT", "/v1/agent/node-meta", body)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.AgentNodeMeta(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Code)
+	meta := obj.(map[string]string)
+	require.Equal(t, "true", meta["maintenance-window"])
+	require.Equal(t, "r2", meta["rack"])
+
+	// The catalog is updated.
+	retry.Run(t, func(r *retry.R) {
+		args := structs.NodeSpecificRequest{Datacenter: "dc1", Node: a.Config.NodeName}
+		var out structs.IndexedNodeServices
+		if err := a.RPC("Catalog.NodeServices", &args, &out); err != nil {
+			r.Fatal(err)
+		}
+		if out.NodeServices == nil || out.NodeServices.Node.Meta["maintenance-window"] != "true" {
+			r.Fatalf("bad: %#v", out.NodeServices)
+		}
+	})
+
+	// The fields survive a reload.
+	newConfig := *a.Config
+	require.NoError(t, a.ReloadConfig(&newConfig))
+	require.Equal(t, "r2", a.State.Metadata()["rack"])
+
+	// Deleting a configured field restores the configured value.
+	req, _ = http.NewRequest("DELETE", "/v1/agent/node-meta?key=rack&key=maintenance-window", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.AgentNodeMeta(resp, req)
+	require.NoError(t, err)
+	meta = obj.(map[string]string)
+	require.Equal(t, "r1", meta["rack"])
+	require.NotContains(t, meta, "maintenance-window")
+
+	// Reserved keys are rejected.
+	body = bytes.NewBufferString(`{"consul-version": "1"}`)
+	req, _ = http.NewRequest("PUT", "/v1/agent/node-meta", body)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.AgentNodeMeta(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.Code)
+	require.Contains(t, resp.Body.String(), "reserved for internal use")
+}
+
+func TestAgent_FaultInjection(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		enable_fault_injection = true
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	body := bytes.NewBufferString(`{"RPCLatency": "10ms", "RPCDropPercent": 100}`)
+	req, _ := http.NewRequest("PUT", "/v1/agent/fault-injection", body)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.AgentFaultInjection(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/v1/agent/fault-injection", nil)
+	obj, err := a.srv.AgentFaultInjection(nil, req)
+	require.NoError(t, err)
+	require.Equal(t, api.AgentFaultInjection{
+		RPCLatency:     api.ReadableDuration(10 * time.Millisecond),
+		RPCDropPercent: 100,
+	}, obj)
+
+	// All RPCs are dropped now.
+	req, _ = http.NewRequest("GET", "/v1/catalog/nodes", nil)
+	_, err = a.srv.CatalogNodes(httptest.NewRecorder(), req)
+	require.Equal(t, errFaultInjectionDrop, err)
+
+	// Invalid values are rejected.
+	req, _ = http.NewRequest("PUT", "/v1/agent/fault-injection", bytes.NewBufferString(`{"RPCDropPercent": 200}`))
+	resp = httptest.NewRecorder()
+	_, err = a.srv.AgentFaultInjection(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 400, resp.Code)
+
+	req, _ = http.NewRequest("DELETE", "/v1/agent/fault-injection", nil)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.AgentFaultInjection(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.Code)
+
+	req, _ = http.NewRequest("GET", "/v1/catalog/nodes", nil)
+	_, err = a.srv.CatalogNodes(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+}
+
+func TestAgent_FaultInjection_Disabled(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	req, _ := http.NewRequest("GET", "/v1/agent/fault-injection", nil)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.AgentFaultInjection(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.Code)
+}
+
+func TestAgent_Cache(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Fill the cache with the CA roots.
+	req, _ := http.NewRequest("GET", "/v1/agent/connect/ca/roots", nil)
+	_, err := a.srv.AgentConnectCARoots(httptest.NewRecorder(), req)
+	require.NoError(err)
+
+	cacheStats := func() cache.TypeStats {
+		req, _ := http.NewRequest("GET", "/v1/agent/cache", nil)
+		obj, err := a.srv.AgentCache(nil, req)
+		require.NoError(err)
+		for _, s := range obj.([]cache.TypeStats) {
+			if s.Type == cachetype.ConnectCARootName {
+				return s
+			}
+		}
+		t.Fatalf("missing type %s", cachetype.ConnectCARootName)
+		return cache.TypeStats{}
+	}
+
+	s := cacheStats()
+	require.Equal(1, s.Entries)
+	require.Equal(uint64(1), s.MissesNew)
+	require.Equal(uint64(1), s.FetchSuccess)
+
+	// Unknown types are rejected.
+	req, _ = http.NewRequest("DELETE", "/v1/agent/cache?type=nope", nil)
+	resp := httptest.NewRecorder()
+	_, err = a.srv.AgentCache(resp, req)
+	require.NoError(err)
+	require.Equal(400, resp.Code)
+
+	req, _ = http.NewRequest("DELETE", "/v1/agent/cache?type="+cachetype.ConnectCARootName, nil)
+	obj, err := a.srv.AgentCache(httptest.NewRecorder(), req)
+	require.NoError(err)
+	require.Equal(api.AgentCacheInvalidation{Invalidated: 1}, obj)
+
+	s = cacheStats()
+	require.Equal(0, s.Entries)
+	require.Equal(uint64(1), s.Invalidations)
+}
+
+func TestAgent_Cache_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/agent/cache", nil)
+	_, err := a.srv.AgentCache(nil, req)
+	require.True(t, acl.IsErrPermissionDenied(err))
+
+	req, _ = http.NewRequest("DELETE", "/v1/agent/cache", nil)
+	_, err = a.srv.AgentCache(nil, req)
+	require.True(t, acl.IsErrPermissionDenied(err))
+
+	req, _ = http.NewRequest("DELETE", "/v1/agent/cache?token=root", nil)
+	_, err = a.srv.AgentCache(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+}
+
+func TestAgent_NodeMeta_ACLDeny(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/node-meta", bytes.NewBufferString(`{"a": "b"}`))
+		if _, err := a.srv.AgentNodeMeta(nil, req); !acl.IsErrPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("root token", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/node-meta?token=root", bytes.NewBufferString(`{"a": "b"}`))
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.AgentNodeMeta(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestAgent_RegisterCheck_Service(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -3544,6 +4104,41 @@ func TestAgent_Monitor(t *testing.T) {
 			r.Fatalf("got %q and did not find %q", got, want)
 		}
 	})
+
+	// Stream only the raft logs as JSON
+	retry.Run(t, func(r *retry.R) {
+		req, _ = http.NewRequest("GET", "/v1/agent/monitor?loglevel=debug&include=raft&exclude=agent&logjson", nil)
+		resp = newClosableRecorder()
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := a.srv.AgentMonitor(resp, req)
+			errCh <- err
+		}()
+
+		resp.Close()
+		if err := <-errCh; err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var found bool
+		body := resp.Body.String()
+		scanner := bufio.NewScanner(strings.NewReader(body))
+		for scanner.Scan() {
+			var line logger.Line
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				r.Fatalf("bad line %q: %v", scanner.Text(), err)
+			}
+			if line.Subsystem != "raft" {
+				r.Fatalf("bad subsystem: %#v", line)
+			}
+			if strings.HasPrefix(line.Message, "Initial configuration (index=1)") && line.Level == "INFO" {
+				found = true
+			}
+		}
+		if !found {
+			r.Fatalf("did not find the initial configuration log in %q", body)
+		}
+	})
 }
 
 type closableRecorder struct {
@@ -3744,6 +4339,78 @@ func TestAgent_Token(t *testing.T) {
 	})
 }
 
+func TestAgent_Token_Persistence(t *testing.T) {
+	t.Parallel()
+	dataDir := testutil.TempDir(t, "agent")
+	defer os.RemoveAll(dataDir)
+
+	// The restarted server needs the same node id to recover its raft
+	// state.
+	hcl := TestACLConfig() + `
+		acl_token = "config-user"
+		acl_agent_token = "config-agent"
+		acl {
+			enable_token_persistence = true
+		}
+		data_dir = "` + dataDir + `"
+		node_id = "` + NodeID() + `"
+	`
+	a := &TestAgent{Name: t.Name(), HCL: hcl, DataDir: dataDir}
+	a.Start()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	body := jsonReader(&api.AgentToken{Token: "api-agent"})
+	req, _ := http.NewRequest("PUT", "/v1/agent/token/acl_agent_token?token=root", body)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.AgentToken(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.Shutdown()
+
+	// The tokens file must only be readable by the agent.
+	fi, err := os.Stat(filepath.Join(dataDir, tokensFile))
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		require.Equal(t, os.FileMode(tokensFileMode), fi.Mode().Perm())
+	}
+
+	// The persisted token replaces the configured one after a restart
+	// but tokens which were not set with the API are left alone.
+	a = &TestAgent{Name: t.Name(), HCL: hcl, DataDir: dataDir}
+	a.Start()
+	defer a.Shutdown()
+	require.Equal(t, "api-agent", a.tokens.AgentToken())
+	require.Equal(t, "config-user", a.tokens.UserToken())
+}
+
+func TestAgent_Token_PersistenceFileMode(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on windows")
+	}
+	dataDir := testutil.TempDir(t, "agent")
+	defer os.RemoveAll(dataDir)
+
+	path := filepath.Join(dataDir, tokensFile)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"acl_token":"api-user"}`), 0644))
+	require.NoError(t, os.Chmod(path, 0644))
+
+	hcl := TestACLConfig() + `
+		acl {
+			enable_token_persistence = true
+		}
+		data_dir = "` + dataDir + `"
+	`
+	a := &TestAgent{Name: t.Name(), HCL: hcl, DataDir: dataDir}
+	a.Start()
+	defer a.Shutdown()
+	require.Equal(t, "api-user", a.tokens.UserToken())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(tokensFileMode), fi.Mode().Perm())
+}
+
 func TestAgentConnectCARoots_empty(t *testing.T) {
 	t.Parallel()
 
@@ -5164,6 +5831,145 @@ func TestAgentConnectAuthorize_idNotService(t *testing.T) {
 }
 
 // Test when there is an intention allowing the connection
+func TestAgentConnectProxySnapshot(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Deny traffic from api to web.
+	var ixnID string
+	{
+		req := structs.IntentionRequest{
+			Datacenter: "dc1",
+			Op:         structs.IntentionOpCreate,
+			Intention:  structs.TestIntention(t),
+		}
+		req.Intention.SourceNS = structs.IntentionDefaultNamespace
+		req.Intention.SourceName = "api"
+		req.Intention.DestinationNS = structs.IntentionDefaultNamespace
+		req.Intention.DestinationName = "web"
+		req.Intention.Action = structs.IntentionActionDeny
+		require.NoError(a.RPC("Intention.Apply", &req, &ixnID))
+	}
+
+	// Register a service with a sidecar proxy and an instance of its upstream.
+	for _, reg := range []*structs.ServiceDefinition{
+		{
+			Name: "web",
+			Port: 8080,
+			Connect: &structs.ServiceConnect{
+				SidecarService: &structs.ServiceDefinition{
+					Proxy: &structs.ConnectProxyConfig{
+						Upstreams: structs.Upstreams{
+							{
+								DestinationType: structs.UpstreamDestTypeService,
+								DestinationName: "db",
+								LocalBindPort:   9191,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    "db-proxy",
+			Kind:    structs.ServiceKindConnectProxy,
+			Address: "10.0.0.2",
+			Port:    20000,
+			Proxy: &structs.ConnectProxyConfig{
+				DestinationServiceName: "db",
+			},
+		},
+	} {
+		req, _ := http.NewRequest("PUT", "/v1/agent/service/register", jsonReader(reg))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.AgentRegisterService(resp, req)
+		require.NoError(err)
+		require.Equal(200, resp.Code, resp.Body.String())
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/connect/proxy-snapshot/nope", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.AgentConnectProxySnapshot(resp, req)
+		require.NoError(err)
+		require.Nil(obj)
+		require.Equal(404, resp.Code)
+	})
+
+	// The ID of the proxy and the ID of the service resolve to the same proxy.
+	for _, id := range []string{"web-sidecar-proxy", "web"} {
+		var snap *api.ConnectProxySnapshot
+		retry.Run(t, func(r *retry.R) {
+			req, _ := http.NewRequest("GET", "/v1/agent/connect/proxy-snapshot/"+id, nil)
+			resp := httptest.NewRecorder()
+			obj, err := a.srv.AgentConnectProxySnapshot(resp, req)
+			if err != nil {
+				r.Fatal(err)
+			}
+			snap = obj.(*api.ConnectProxySnapshot)
+			if len(snap.Upstreams) != 1 || len(snap.Upstreams[0].Endpoints) != 1 {
+				r.Fatalf("upstream not resolved yet: %#v", snap.Upstreams)
+			}
+		})
+
+		require.Equal("web-sidecar-proxy", snap.ProxyServiceID)
+		require.Equal("web", snap.TargetServiceID)
+		require.Equal("web", snap.TargetServiceName)
+		require.Equal("db", snap.Upstreams[0].DestinationName)
+		require.Equal(9191, snap.Upstreams[0].LocalBindPort)
+		require.Equal(api.ConnectProxySnapshotEndpoint{
+			Node:      a.config.NodeName,
+			ServiceID: "db-proxy",
+			Address:   "10.0.0.2",
+			Port:      20000,
+			Status:    api.HealthPassing,
+		}, snap.Upstreams[0].Endpoints[0])
+		require.Equal("spiffe://"+snap.TrustDomain+"/ns/default/dc/dc1/svc/web", snap.Leaf.ServiceURI)
+		require.NotEmpty(snap.Leaf.SerialNumber)
+		require.Len(snap.Roots, 1)
+		require.True(snap.Roots[0].Active)
+		require.Equal([]api.ConnectProxySnapshotIntention{
+			{
+				ID:         ixnID,
+				SourceName: "api",
+				Action:     api.IntentionActionDeny,
+				Precedence: 9,
+			},
+		}, snap.Intentions)
+		require.True(snap.DefaultAllow)
+	}
+}
+
+func TestAgentConnectProxySnapshot_aclServiceWrite(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), TestACLConfig())
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	reg := &structs.ServiceDefinition{
+		Name: "web",
+		Port: 8080,
+		Connect: &structs.ServiceConnect{
+			SidecarService: &structs.ServiceDefinition{},
+		},
+	}
+	req, _ := http.NewRequest("PUT", "/v1/agent/service/register?token=root", jsonReader(reg))
+	resp := httptest.NewRecorder()
+	_, err := a.srv.AgentRegisterService(resp, req)
+	require.NoError(err)
+
+	req, _ = http.NewRequest("GET", "/v1/agent/connect/proxy-snapshot/web", nil)
+	resp = httptest.NewRecorder()
+	_, err = a.srv.AgentConnectProxySnapshot(resp, req)
+	require.True(acl.IsErrPermissionDenied(err))
+}
+
 func TestAgentConnectAuthorize_allow(t *testing.T) {
 	t.Parallel()
 
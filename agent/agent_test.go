@@ -22,6 +22,8 @@ import (
 	"github.com/hashicorp/consul/agent/connect"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/lib/freeport"
+	"github.com/hashicorp/consul/render"
 	"github.com/hashicorp/consul/testutil"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/consul/types"
@@ -109,6 +111,67 @@ func TestAgent_ConnectClusterIDConfig(t *testing.T) {
 	}
 }
 
+func TestListenNetwork(t *testing.T) {
+	t.Parallel()
+	tcp := func(s string) net.Addr {
+		a, err := net.ResolveTCPAddr("tcp", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	udp := func(s string) net.Addr {
+		a, err := net.ResolveUDPAddr("udp", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	tests := []struct {
+		desc  string
+		addr  net.Addr
+		addrs []net.Addr
+		want  string
+	}{
+		{"ipv4 only", tcp("0.0.0.0:8500"), []net.Addr{tcp("0.0.0.0:8500")}, "tcp"},
+		{"ipv4", tcp("0.0.0.0:8500"), []net.Addr{tcp("0.0.0.0:8500"), tcp("[::]:8500")}, "tcp4"},
+		{"ipv6 only", tcp("[::]:8500"), []net.Addr{tcp("[::]:8500")}, "tcp"},
+		{"ipv6 specific", tcp("[::1]:8500"), []net.Addr{tcp("127.0.0.1:8500"), tcp("[::1]:8500")}, "tcp6"},
+		{"dual stack", tcp("[::]:8500"), []net.Addr{tcp("0.0.0.0:8500"), tcp("[::]:8500")}, "tcp6"},
+		{"dual stack specific ipv4", tcp("[::]:8500"), []net.Addr{tcp("127.0.0.1:8500"), tcp("[::]:8500")}, "tcp6"},
+		{"other port", tcp("[::]:8500"), []net.Addr{tcp("0.0.0.0:8501"), tcp("[::]:8500")}, "tcp"},
+		{"other network", udp("[::]:8600"), []net.Addr{tcp("0.0.0.0:8600"), udp("[::]:8600")}, "udp"},
+		{"dual stack udp", udp("[::]:8600"), []net.Addr{udp("0.0.0.0:8600"), udp("[::]:8600")}, "udp6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			require.Equal(t, tt.want, listenNetwork(tt.addr, tt.addrs))
+		})
+	}
+}
+
+func TestAgent_StartListeners_DualStack(t *testing.T) {
+	t.Parallel()
+	a := &Agent{}
+	port := freeport.Get(1)[0]
+	addrs := []net.Addr{
+		&net.TCPAddr{IP: net.IPv4zero, Port: port},
+		&net.TCPAddr{IP: net.IPv6unspecified, Port: port},
+	}
+	ln, err := a.startListeners(addrs)
+	if err != nil {
+		if strings.Contains(err.Error(), "address family not supported") {
+			t.Skip("IPv6 is not supported")
+		}
+		t.Fatalf("err: %v", err)
+	}
+	for _, l := range ln {
+		l.Close()
+	}
+	require.Len(t, ln, 2)
+}
+
 func TestAgent_StartStop(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -2062,6 +2125,49 @@ func TestAgent_unloadChecks(t *testing.T) {
 	}
 }
 
+func TestAgent_AddService_MetaDefaults(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		service_meta_defaults {
+			rack = "r1"
+			cluster = "c1"
+		}
+		service = {
+			id = "redis"
+			name = "redis"
+			port = 8000
+			meta {
+				cluster = "mine"
+			}
+		}
+	`)
+	defer a.Shutdown()
+
+	// The defaults are added to the services from the config and the
+	// services registered later.
+	svc := &structs.NodeService{
+		ID:      "web",
+		Service: "web",
+		Port:    8001,
+	}
+	require.NoError(t, a.AddService(svc, nil, true, "", ConfigSourceRemote))
+	require.Nil(t, svc.Meta)
+
+	services := a.State.Services()
+	require.Equal(t, map[string]string{"rack": "r1", "cluster": "mine"}, services["redis"].Meta)
+	require.Equal(t, map[string]string{"rack": "r1", "cluster": "c1"}, services["web"].Meta)
+
+	// Changes of the defaults apply to the services on reload, including
+	// the persisted ones.
+	newConfig := *a.Config
+	newConfig.ServiceMetaDefaults = map[string]string{"rack": "r2"}
+	require.NoError(t, a.ReloadConfig(&newConfig))
+
+	services = a.State.Services()
+	require.Equal(t, map[string]string{"rack": "r2", "cluster": "mine"}, services["redis"].Meta)
+	require.Equal(t, map[string]string{"rack": "r2"}, services["web"].Meta)
+}
+
 func TestAgent_loadServices_token(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), `
@@ -2339,6 +2445,83 @@ func TestAgent_Service_MaintenanceMode(t *testing.T) {
 	}
 }
 
+func TestAgent_Service_Warmup(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	svc := &structs.NodeService{
+		ID:      "redis",
+		Service: "redis",
+		Port:    8000,
+		Warmup:  500 * time.Millisecond,
+	}
+	chkTypes := []*structs.CheckType{
+		&structs.CheckType{
+			TTL:    time.Minute,
+			Status: api.HealthPassing,
+		},
+	}
+
+	// Register the service with a passing check
+	if err := a.AddService(svc, chkTypes, false, "mytoken", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Make sure the critical warmup check was added next to the passing one
+	checkID := serviceWarmupCheckID("redis")
+	check, ok := a.State.Checks()[checkID]
+	if !ok || check.Status != api.HealthCritical {
+		t.Fatalf("should have registered critical warmup check: %#v", check)
+	}
+	if token := a.State.CheckToken(checkID); token != "mytoken" {
+		t.Fatalf("expected 'mytoken', got: '%s'", token)
+	}
+	if check := a.State.Checks()["service:redis"]; check.Status != api.HealthPassing {
+		t.Fatalf("bad: %#v", check)
+	}
+
+	// Reloading the service keeps the warmup going
+	if err := a.RemoveService("redis", false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.AddService(svc, chkTypes, false, "", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := a.State.Checks()[checkID]; !ok {
+		t.Fatalf("should have registered warmup check")
+	}
+
+	// The check is removed once the warmup period is over
+	retry.Run(t, func(r *retry.R) {
+		if _, ok := a.State.Checks()[checkID]; ok {
+			r.Fatalf("should have deregistered warmup check")
+		}
+	})
+
+	// Reloading doesn't start the warmup again
+	if err := a.RemoveService("redis", false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.AddService(svc, chkTypes, false, "", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := a.State.Checks()[checkID]; ok {
+		t.Fatalf("should not have registered warmup check")
+	}
+
+	// Registering the service again after deregistering it does
+	if err := a.RemoveService("redis", true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.AddService(svc, chkTypes, false, "", ConfigSourceLocal); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := a.State.Checks()[checkID]; !ok {
+		t.Fatalf("should have registered warmup check")
+	}
+}
+
 func TestAgent_Service_Reap(t *testing.T) {
 	// t.Parallel() // timing test. no parallel
 	a := NewTestAgent(t.Name(), `
@@ -2414,6 +2597,59 @@ func TestAgent_Service_Reap(t *testing.T) {
 	}
 }
 
+func TestAgent_Service_ReapDefaults(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		check_deregister_interval_min = "0s"
+		deregister_critical_service_after_default = "2m"
+		deregister_critical_service_after_min = "90s"
+	`)
+	defer a.Shutdown()
+
+	svc := &structs.NodeService{
+		ID:      "redis",
+		Service: "redis",
+		Port:    8000,
+	}
+	chkTypes := []*structs.CheckType{
+		&structs.CheckType{
+			CheckID: "default",
+			TTL:     time.Minute,
+		},
+		&structs.CheckType{
+			CheckID:                        "below-min",
+			TTL:                            time.Minute,
+			DeregisterCriticalServiceAfter: 30 * time.Second,
+		},
+		&structs.CheckType{
+			CheckID:                        "explicit",
+			TTL:                            time.Minute,
+			DeregisterCriticalServiceAfter: 5 * time.Minute,
+		},
+	}
+	require.NoError(t, a.AddService(svc, chkTypes, false, "", ConfigSourceLocal))
+
+	// The default only applies to service checks.
+	health := &structs.HealthCheck{
+		Node:    a.Config.NodeName,
+		CheckID: "node",
+		Name:    "node check",
+	}
+	require.NoError(t, a.AddCheck(health, &structs.CheckType{TTL: time.Minute}, false, "", ConfigSourceLocal))
+
+	a.checkLock.Lock()
+	reapAfter := make(map[types.CheckID]time.Duration)
+	for id, timeout := range a.checkReapAfter {
+		reapAfter[id] = timeout
+	}
+	a.checkLock.Unlock()
+	require.Equal(t, map[types.CheckID]time.Duration{
+		"default":   2 * time.Minute,
+		"below-min": 90 * time.Second,
+		"explicit":  5 * time.Minute,
+	}, reapAfter)
+}
+
 func TestAgent_Service_NoReap(t *testing.T) {
 	// t.Parallel() // timing test. no parallel
 	a := NewTestAgent(t.Name(), `
@@ -2716,7 +2952,7 @@ func TestAgent_loadCheckState(t *testing.T) {
 		CheckID: "check1",
 		Status:  api.HealthCritical,
 	}
-	if err := a.loadCheckState(health); err != nil {
+	if _, err := a.loadCheckState(health); err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
@@ -2742,7 +2978,8 @@ func TestAgent_loadCheckState(t *testing.T) {
 	}
 
 	// Try to load
-	if err := a.loadCheckState(health); err != nil {
+	remaining, err := a.loadCheckState(health)
+	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
 
@@ -2753,6 +2990,59 @@ func TestAgent_loadCheckState(t *testing.T) {
 	if health.Output != "yup" {
 		t.Fatalf("bad: %#v", health)
 	}
+
+	// The TTL keeps running from when the state was persisted
+	if remaining <= 0 || remaining > time.Minute {
+		t.Fatalf("bad: %v", remaining)
+	}
+}
+
+func TestAgent_loadCheckState_maxAge(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		check_state_max_age = "1h"
+	`)
+	defer a.Shutdown()
+
+	// Persist a state which was updated two hours ago and whose TTL
+	// didn't expire yet.
+	state := persistedCheckState{
+		CheckID: "check1",
+		Status:  api.HealthPassing,
+		Output:  "yup",
+		Expires: time.Now().Add(time.Hour).Unix(),
+		Updated: time.Now().Add(-2 * time.Hour).Unix(),
+	}
+	buf, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	dir := filepath.Join(a.Config.DataDir, checkStateDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	file := filepath.Join(dir, checkIDHash("check1"))
+	if err := ioutil.WriteFile(file, buf, 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Should not have restored the status since it's too old
+	health := &structs.HealthCheck{
+		CheckID: "check1",
+		Status:  api.HealthCritical,
+	}
+	remaining, err := a.loadCheckState(health)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if remaining != 0 || health.Status != api.HealthCritical || health.Output != "" {
+		t.Fatalf("bad: %v %#v", remaining, health)
+	}
+
+	// Should have purged the state
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("should have purged state")
+	}
 }
 
 func TestAgent_purgeCheckState(t *testing.T) {
@@ -2866,6 +3156,47 @@ func TestAgent_reloadWatches(t *testing.T) {
 	}
 }
 
+func TestAgent_reloadTemplates(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	dir := testutil.TempDir(t, "templates")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "in.tpl")
+	require.NoError(t, ioutil.WriteFile(src, []byte(`{{ keyOrDefault "app/name" "none" }}`), 0644))
+	dest := filepath.Join(dir, "out")
+
+	newConf := *a.config
+	newConf.Templates = []render.Template{{Source: src, Destination: dest}}
+	require.NoError(t, a.reloadTemplates(&newConf))
+	require.Len(t, a.renderers, 1)
+
+	retry.Run(t, func(r *retry.R) {
+		out, err := ioutil.ReadFile(dest)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if string(out) != "none" {
+			r.Fatalf("bad: %q", out)
+		}
+	})
+
+	// Should fail to load a missing template
+	newConf.Templates = []render.Template{{Source: filepath.Join(dir, "missing"), Destination: dest}}
+	err := a.reloadTemplates(&newConf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to read template")
+
+	// Should fail to reload with no http or https addrs
+	newConf.HTTPAddrs = make([]net.Addr, 0)
+	newConf.Templates = []render.Template{{Source: src, Destination: dest}}
+	err = a.reloadTemplates(&newConf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "templates require an HTTP or HTTPS endpoint")
+}
+
 func TestAgent_reloadWatchesHTTPS(t *testing.T) {
 	t.Parallel()
 	a := TestAgent{Name: t.Name(), UseTLS: true}
@@ -0,0 +1,50 @@
+package cachetype
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/cache"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// Recommended name for registration.
+const CatalogServiceVirtualIPsName = "catalog-service-virtual-ips"
+
+// CatalogServiceVirtualIPs supports fetching the virtual IPs of the services
+// of a datacenter.
+type CatalogServiceVirtualIPs struct {
+	RPC RPC
+}
+
+func (c *CatalogServiceVirtualIPs) Fetch(opts cache.FetchOptions, req cache.Request) (cache.FetchResult, error) {
+	var result cache.FetchResult
+
+	// The request should be a DCSpecificRequest.
+	reqReal, ok := req.(*structs.DCSpecificRequest)
+	if !ok {
+		return result, fmt.Errorf(
+			"Internal cache failure: request wrong type: %T", req)
+	}
+
+	// Set the minimum query index to our current index so we block
+	reqReal.QueryOptions.MinQueryIndex = opts.MinIndex
+	reqReal.QueryOptions.MaxQueryTime = opts.Timeout
+
+	// Always allow stale - there's no point in hitting leader if the request is
+	// going to be served from cache and end up arbitrarily stale anyway.
+	reqReal.AllowStale = true
+
+	// Fetch
+	var reply structs.IndexedServiceVirtualIPs
+	if err := c.RPC.RPC("Catalog.ServiceVirtualIPs", reqReal, &reply); err != nil {
+		return result, err
+	}
+
+	result.Value = &reply
+	result.Index = reply.QueryMeta.Index
+	return result, nil
+}
+
+func (c *CatalogServiceVirtualIPs) SupportsBlocking() bool {
+	return true
+}
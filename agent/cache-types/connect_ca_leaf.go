@@ -26,6 +26,7 @@ type ConnectCALeaf struct {
 
 	RPC   RPC          // RPC client for remote requests
 	Cache *cache.Cache // Cache that has CA root certs via ConnectCARoot
+	Node  string       // Name of the agent, sent along with sign requests
 }
 
 // issuedKey returns the issuedCerts cache key for a given service and token. We
@@ -168,6 +169,7 @@ func (c *ConnectCALeaf) Fetch(opts cache.FetchOptions, req cache.Request) (cache
 		WriteRequest: structs.WriteRequest{Token: reqReal.Token},
 		Datacenter:   reqReal.Datacenter,
 		CSR:          csr,
+		Node:         c.Node,
 	}
 	if err := c.RPC.RPC("ConnectCA.Sign", &args, &reply); err != nil {
 		return result, err
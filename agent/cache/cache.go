@@ -73,6 +73,12 @@ type Cache struct {
 	entries           map[string]cacheEntry
 	entriesExpiryHeap *expiryHeap
 
+	// generations counts the invalidations of each type. A fetch which
+	// completes after the entries of its type were invalidated discards its
+	// result, so that it doesn't resurrect the entry. Access must be
+	// protected by entriesLock.
+	generations map[string]uint64
+
 	// stopped is used as an atomic flag to signal that the Cache has been
 	// discarded so background fetches and expiry processing should stop.
 	stopped uint32
@@ -82,8 +88,9 @@ type Cache struct {
 
 // typeEntry is a single type that is registered with a Cache.
 type typeEntry struct {
-	Type Type
-	Opts *RegisterOptions
+	Type  Type
+	Opts  *RegisterOptions
+	Stats *typeStats
 }
 
 // ResultMeta is returned from Get calls along with the value and can be used
@@ -133,6 +140,7 @@ func New(*Options) *Cache {
 		types:             make(map[string]typeEntry),
 		entries:           make(map[string]cacheEntry),
 		entriesExpiryHeap: h,
+		generations:       make(map[string]uint64),
 		stopCh:            make(chan struct{}),
 	}
 
@@ -192,7 +200,7 @@ func (c *Cache) RegisterType(n string, typ Type, opts *RegisterOptions) {
 
 	c.typesLock.Lock()
 	defer c.typesLock.Unlock()
-	c.types[n] = typeEntry{Type: typ, Opts: opts}
+	c.types[n] = typeEntry{Type: typ, Opts: opts, Stats: &typeStats{}}
 }
 
 // Get loads the data for the given type and request. If data satisfying the
@@ -282,6 +290,7 @@ RETRY_GET:
 		meta := ResultMeta{Index: entry.Index}
 		if first {
 			metrics.IncrCounter([]string{"consul", "cache", t, "hit"}, 1)
+			atomic.AddUint64(&tEntry.Stats.Hits, 1)
 			meta.Hit = true
 		}
 
@@ -328,8 +337,10 @@ RETRY_GET:
 		// or if we're missing because we're blocking on a set index.
 		if minIndex == 0 {
 			metrics.IncrCounter([]string{"consul", "cache", t, "miss_new"}, 1)
+			atomic.AddUint64(&tEntry.Stats.MissesNew, 1)
 		} else {
 			metrics.IncrCounter([]string{"consul", "cache", t, "miss_block"}, 1)
+			atomic.AddUint64(&tEntry.Stats.MissesBlock, 1)
 		}
 	}
 
@@ -415,6 +426,10 @@ func (c *Cache) fetch(t, key string, r Request, allowNew bool, attempt uint) (<-
 	c.entries[key] = entry
 	metrics.SetGauge([]string{"consul", "cache", "entries_count"}, float32(len(c.entries)))
 
+	// Remember the generation of the type to notice if the entry is
+	// invalidated while the fetch is running.
+	generation := c.generations[t]
+
 	// The actual Fetch must be performed in a goroutine.
 	go func() {
 		// If we have background refresh and currently are in "disconnected" state,
@@ -479,6 +494,7 @@ func (c *Cache) fetch(t, key string, r Request, allowNew bool, attempt uint) (<-
 		if err == nil {
 			metrics.IncrCounter([]string{"consul", "cache", "fetch_success"}, 1)
 			metrics.IncrCounter([]string{"consul", "cache", t, "fetch_success"}, 1)
+			atomic.AddUint64(&tEntry.Stats.FetchSuccess, 1)
 
 			if result.Index > 0 {
 				// Reset the attempts counter so we don't have any backoff
@@ -508,6 +524,7 @@ func (c *Cache) fetch(t, key string, r Request, allowNew bool, attempt uint) (<-
 		} else {
 			metrics.IncrCounter([]string{"consul", "cache", "fetch_error"}, 1)
 			metrics.IncrCounter([]string{"consul", "cache", t, "fetch_error"}, 1)
+			atomic.AddUint64(&tEntry.Stats.FetchErrors, 1)
 
 			// Increment attempt counter
 			attempt++
@@ -534,6 +551,15 @@ func (c *Cache) fetch(t, key string, r Request, allowNew bool, attempt uint) (<-
 		// Set our entry
 		c.entriesLock.Lock()
 
+		// If the entries of the type were invalidated during the fetch, drop
+		// the result and stop refreshing. A Get after the invalidation has
+		// created a new entry with its own fetch, which must not be replaced.
+		if c.generations[t] != generation {
+			c.entriesLock.Unlock()
+			close(entry.Waiter)
+			return
+		}
+
 		// If this is a new entry (not in the heap yet), then setup the
 		// initial expiry information and insert. If we're already in
 		// the heap we do nothing since we're reusing the same entry.
@@ -573,6 +599,7 @@ func (c *Cache) fetchDirect(t string, r Request, minIndex uint64) (interface{},
 	if !ok {
 		return nil, ResultMeta{}, fmt.Errorf("unknown type in cache: %s", t)
 	}
+	atomic.AddUint64(&tEntry.Stats.Bypass, 1)
 
 	// Fetch it with the min index specified directly by the request.
 	result, err := tEntry.Type.Fetch(FetchOptions{
@@ -659,8 +686,17 @@ func (c *Cache) runExpiryLoop() {
 		case <-expiryCh:
 			c.entriesLock.Lock()
 
+			// The entry may have been invalidated since the timer was set.
+			if entry.HeapIndex < 0 {
+				c.entriesLock.Unlock()
+				continue
+			}
+
 			// Entry expired! Remove it.
 			delete(c.entries, entry.Key)
+			if tEntry, ok := c.typeEntryForKey(entry.Key); ok {
+				atomic.AddUint64(&tEntry.Stats.Evictions, 1)
+			}
 			heap.Remove(c.entriesExpiryHeap, entry.HeapIndex)
 
 			// This is subtle but important: if we race and simultaneously
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// typeStats are the counters of a registered type. They are updated with
+// atomic operations since they are incremented outside of the locks.
+type typeStats struct {
+	Hits          uint64
+	MissesNew     uint64
+	MissesBlock   uint64
+	Bypass        uint64
+	FetchSuccess  uint64
+	FetchErrors   uint64
+	Evictions     uint64
+	Invalidations uint64
+}
+
+// TypeStats are the statistics of a registered type, which are used to
+// diagnose the cache, e.g. to find out why stale data is being served.
+type TypeStats struct {
+	// Type is the name of the type.
+	Type string
+
+	// Entries is the number of entries of the type in the cache.
+	Entries int
+
+	// FetchingEntries is the number of entries with an active fetch. For
+	// types with background refresh this includes the blocking queries.
+	FetchingEntries int
+
+	// ErrorEntries is the number of entries whose last fetch failed.
+	ErrorEntries int
+
+	// StaleEntries is the number of entries of a type with background refresh
+	// which lost contact with the servers and so may be out of date.
+	StaleEntries int
+
+	// Hits, MissesNew and MissesBlock count the Get calls which were served
+	// from the cache, which had no value yet and which blocked for a newer
+	// index. Bypass counts the requests which could not be cached.
+	Hits        uint64
+	MissesNew   uint64
+	MissesBlock uint64
+	Bypass      uint64
+
+	// HitRate is the fraction of the cached Get calls which were hits.
+	HitRate float64
+
+	// FetchSuccess and FetchErrors count the fetches from the servers.
+	FetchSuccess uint64
+	FetchErrors  uint64
+
+	// Evictions counts the entries removed because they were not accessed
+	// for the LastGetTTL of the type, and Invalidations the entries removed
+	// with Invalidate.
+	Evictions     uint64
+	Invalidations uint64
+
+	// OldestEntryAge is the time since the oldest value of the type was
+	// fetched. For types with background refresh it tells how long a value
+	// stayed unchanged on the servers.
+	OldestEntryAge time.Duration
+
+	// MaxStaleAge is the longest time since an entry of a type with
+	// background refresh lost contact with the servers.
+	MaxStaleAge time.Duration
+}
+
+// Stats returns the statistics of every registered type, sorted by type.
+func (c *Cache) Stats() []TypeStats {
+	c.typesLock.RLock()
+	stats := make(map[string]*TypeStats, len(c.types))
+	for name, tEntry := range c.types {
+		s := &TypeStats{
+			Type:          name,
+			Hits:          atomic.LoadUint64(&tEntry.Stats.Hits),
+			MissesNew:     atomic.LoadUint64(&tEntry.Stats.MissesNew),
+			MissesBlock:   atomic.LoadUint64(&tEntry.Stats.MissesBlock),
+			Bypass:        atomic.LoadUint64(&tEntry.Stats.Bypass),
+			FetchSuccess:  atomic.LoadUint64(&tEntry.Stats.FetchSuccess),
+			FetchErrors:   atomic.LoadUint64(&tEntry.Stats.FetchErrors),
+			Evictions:     atomic.LoadUint64(&tEntry.Stats.Evictions),
+			Invalidations: atomic.LoadUint64(&tEntry.Stats.Invalidations),
+		}
+		if total := s.Hits + s.MissesNew + s.MissesBlock; total > 0 {
+			s.HitRate = float64(s.Hits) / float64(total)
+		}
+		stats[name] = s
+	}
+	c.typesLock.RUnlock()
+
+	now := time.Now()
+	c.entriesLock.RLock()
+	for key, entry := range c.entries {
+		s, ok := stats[typeFromKey(key)]
+		if !ok {
+			continue
+		}
+		s.Entries++
+		if entry.Fetching {
+			s.FetchingEntries++
+		}
+		if entry.Error != nil {
+			s.ErrorEntries++
+		}
+		if !entry.FetchedAt.IsZero() {
+			if age := now.Sub(entry.FetchedAt); age > s.OldestEntryAge {
+				s.OldestEntryAge = age
+			}
+		}
+		if !entry.RefreshLostContact.IsZero() {
+			s.StaleEntries++
+			if age := now.Sub(entry.RefreshLostContact); age > s.MaxStaleAge {
+				s.MaxStaleAge = age
+			}
+		}
+	}
+	c.entriesLock.RUnlock()
+
+	out := make([]TypeStats, 0, len(stats))
+	for _, s := range stats {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+// Invalidate removes all the entries of the type, or of all the types if t is
+// empty, so that the next Get fetches fresh values from the servers. Their
+// background refresh stops and the results of active fetches are dropped. It
+// returns the number of entries removed and false if the type is unknown.
+func (c *Cache) Invalidate(t string) (int, bool) {
+	// Copy the types since typesLock must not be held while acquiring
+	// entriesLock, the expiry loop acquires them in the opposite order.
+	c.typesLock.RLock()
+	types := make(map[string]typeEntry, len(c.types))
+	for name, tEntry := range c.types {
+		if t == "" || name == t {
+			types[name] = tEntry
+		}
+	}
+	c.typesLock.RUnlock()
+	if t != "" && len(types) == 0 {
+		return 0, false
+	}
+
+	c.entriesLock.Lock()
+	defer c.entriesLock.Unlock()
+
+	removed := 0
+	for key, entry := range c.entries {
+		tEntry, ok := types[typeFromKey(key)]
+		if !ok {
+			continue
+		}
+
+		delete(c.entries, key)
+		if entry.Expiry != nil && entry.Expiry.HeapIndex >= 0 {
+			heap.Remove(c.entriesExpiryHeap, entry.Expiry.HeapIndex)
+			entry.Expiry.HeapIndex = -1
+		}
+		atomic.AddUint64(&tEntry.Stats.Invalidations, 1)
+		removed++
+	}
+
+	for name := range types {
+		c.generations[name]++
+	}
+
+	// Removing the last entry of the heap doesn't notify, restart the expiry
+	// loop so that it doesn't wait for a removed entry.
+	c.entriesExpiryHeap.notify()
+
+	metrics.SetGauge([]string{"consul", "cache", "entries_count"}, float32(len(c.entries)))
+	return removed, true
+}
+
+// typeEntryForKey returns the registered type of the entry with the key.
+func (c *Cache) typeEntryForKey(key string) (typeEntry, bool) {
+	c.typesLock.RLock()
+	defer c.typesLock.RUnlock()
+	tEntry, ok := c.types[typeFromKey(key)]
+	return tEntry, ok
+}
+
+// typeFromKey returns the type of the entry with the key. See entryKey for
+// the format of the key, type names don't contain slashes.
+func typeFromKey(key string) string {
+	if idx := strings.Index(key, "/"); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
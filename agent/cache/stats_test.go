@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheStats(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	typ := TestType(t)
+	defer typ.AssertExpectations(t)
+	c := TestCache(t)
+	c.RegisterType("t", typ, nil)
+	c.RegisterType("other", TestType(t), nil)
+
+	// Configure the type
+	typ.Static(FetchResult{Value: 42, Index: 1}, nil).Times(1)
+	typ.Static(FetchResult{}, fmt.Errorf("error")).Times(1)
+
+	req := TestRequest(t, RequestInfo{Key: "hello"})
+	_, _, err := c.Get("t", req)
+	require.NoError(err)
+	_, _, err = c.Get("t", req)
+	require.NoError(err)
+
+	_, _, err = c.Get("t", TestRequest(t, RequestInfo{Key: "failing"}))
+	require.Error(err)
+
+	stats := c.Stats()
+	require.Len(stats, 2)
+	require.Equal("other", stats[0].Type)
+	require.Equal(0, stats[0].Entries)
+
+	s := stats[1]
+	require.Equal("t", s.Type)
+	require.Equal(2, s.Entries)
+	require.Equal(1, s.ErrorEntries)
+	require.Equal(uint64(1), s.Hits)
+	require.Equal(uint64(2), s.MissesNew)
+	require.Equal(uint64(1), s.FetchSuccess)
+	require.Equal(uint64(1), s.FetchErrors)
+	require.InDelta(1.0/3, s.HitRate, 0.001)
+	require.True(s.OldestEntryAge > 0)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	typ := TestType(t)
+	defer typ.AssertExpectations(t)
+	other := TestType(t)
+	defer other.AssertExpectations(t)
+	c := TestCache(t)
+	c.RegisterType("t", typ, nil)
+	c.RegisterType("other", other, nil)
+
+	// Configure the types, the invalidated entry is fetched again
+	typ.Static(FetchResult{Value: 42, Index: 1}, nil).Times(1)
+	typ.Static(FetchResult{Value: 43, Index: 2}, nil).Times(1)
+	other.Static(FetchResult{Value: 1, Index: 1}, nil).Times(1)
+
+	req := TestRequest(t, RequestInfo{Key: "hello"})
+	result, _, err := c.Get("t", req)
+	require.NoError(err)
+	require.Equal(42, result)
+	_, _, err = c.Get("other", req)
+	require.NoError(err)
+
+	_, ok := c.Invalidate("missing")
+	require.False(ok)
+
+	removed, ok := c.Invalidate("t")
+	require.True(ok)
+	require.Equal(1, removed)
+
+	result, meta, err := c.Get("t", req)
+	require.NoError(err)
+	require.Equal(43, result)
+	require.False(meta.Hit)
+
+	// The other type is untouched
+	_, meta, err = c.Get("other", req)
+	require.NoError(err)
+	require.True(meta.Hit)
+
+	stats := c.Stats()
+	require.Equal(uint64(1), stats[1].Invalidations)
+	require.Equal(uint64(0), stats[0].Invalidations)
+}
+
+// Test that a refresh which is blocking when the entry is invalidated doesn't
+// resurrect it.
+func TestCacheInvalidate_activeFetch(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	typ := TestType(t)
+	defer typ.AssertExpectations(t)
+	c := TestCache(t)
+	c.RegisterType("t", typ, &RegisterOptions{
+		Refresh:        true,
+		RefreshTimer:   0,
+		RefreshTimeout: 5 * time.Minute,
+	})
+
+	// The first fetch returns immediately and the refresh blocks until the
+	// trigger is closed.
+	triggerCh := make(chan time.Time)
+	typ.Static(FetchResult{Value: 1, Index: 4}, nil).Once()
+	typ.Static(FetchResult{Value: 12, Index: 5}, nil).WaitUntil(triggerCh).Once()
+
+	req := TestRequest(t, RequestInfo{Key: "hello"})
+	result, _, err := c.Get("t", req)
+	require.NoError(err)
+	require.Equal(1, result)
+
+	// Wait for the refresh to start blocking
+	time.Sleep(20 * time.Millisecond)
+
+	removed, ok := c.Invalidate("")
+	require.True(ok)
+	require.Equal(1, removed)
+
+	close(triggerCh)
+	time.Sleep(20 * time.Millisecond)
+
+	stats := c.Stats()
+	require.Equal(0, stats[0].Entries)
+	typ.AssertNumberOfCalls(t, "Fetch", 2)
+}
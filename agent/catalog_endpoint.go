@@ -38,6 +38,7 @@ func (s *HTTPServer) CatalogRegister(resp http.ResponseWriter, req *http.Request
 	}
 	metrics.IncrCounterWithLabels([]string{"client", "api", "success", "catalog_register"}, 1,
 		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+	s.setWriteIndex(resp, args.Datacenter)
 	return true, nil
 }
 
@@ -67,6 +68,7 @@ func (s *HTTPServer) CatalogDeregister(resp http.ResponseWriter, req *http.Reque
 	}
 	metrics.IncrCounterWithLabels([]string{"client", "api", "success", "catalog_deregister"}, 1,
 		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+	s.setWriteIndex(resp, args.Datacenter)
 	return true, nil
 }
 
@@ -123,6 +125,61 @@ RETRY_ONCE:
 	return out.Nodes, nil
 }
 
+// CatalogNodeActivity returns the nodes with the times of their last
+// registrations.
+func (s *HTTPServer) CatalogNodeActivity(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	metrics.IncrCounterWithLabels([]string{"client", "api", "catalog_node_activity"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+
+	args := structs.DCSpecificRequest{}
+	args.NodeMetaFilters = s.parseMetaFilter(req)
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IndexedNodeActivities
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Catalog.NodeActivity", &args, &out); err != nil {
+		metrics.IncrCounterWithLabels([]string{"client", "rpc", "error", "catalog_node_activity"}, 1,
+			[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Nodes == nil {
+		out.Nodes = make(structs.NodeActivities, 0)
+	}
+	metrics.IncrCounterWithLabels([]string{"client", "api", "success", "catalog_node_activity"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+	return out.Nodes, nil
+}
+
+func (s *HTTPServer) CatalogServiceVirtualIPs(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	metrics.IncrCounterWithLabels([]string{"client", "api", "catalog_service_virtual_ips"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+
+	args := structs.DCSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IndexedServiceVirtualIPs
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Catalog.ServiceVirtualIPs", &args, &out); err != nil {
+		metrics.IncrCounterWithLabels([]string{"client", "rpc", "error", "catalog_service_virtual_ips"}, 1,
+			[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+		return nil, err
+	}
+
+	// Use empty map instead of nil
+	if out.VirtualIPs == nil {
+		out.VirtualIPs = make(map[string]string)
+	}
+	metrics.IncrCounterWithLabels([]string{"client", "api", "success", "catalog_service_virtual_ips"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+	return out.VirtualIPs, nil
+}
+
 func (s *HTTPServer) CatalogServices(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	metrics.IncrCounterWithLabels([]string{"client", "api", "catalog_services"}, 1,
 		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
@@ -130,6 +187,7 @@ func (s *HTTPServer) CatalogServices(resp http.ResponseWriter, req *http.Request
 	// Set default DC
 	args := structs.DCSpecificRequest{}
 	args.NodeMetaFilters = s.parseMetaFilter(req)
+	args.ExternalSource = req.URL.Query().Get("external-source")
 	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
 		return nil, nil
 	}
@@ -163,9 +221,49 @@ func (s *HTTPServer) CatalogConnectServiceNodes(resp http.ResponseWriter, req *h
 }
 
 func (s *HTTPServer) CatalogServiceNodes(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if strings.HasSuffix(req.URL.Path, "/history") {
+		return s.catalogServiceHistory(resp, req)
+	}
 	return s.catalogServiceNodes(resp, req, false)
 }
 
+// catalogServiceHistory returns the recorded events of the instances of a
+// service.
+func (s *HTTPServer) catalogServiceHistory(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	metrics.IncrCounterWithLabels([]string{"client", "api", "catalog_service_history"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+
+	args := structs.ServiceSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	// Pull out the service name
+	args.ServiceName = strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/v1/catalog/service/"), "/history")
+	if args.ServiceName == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(resp, "Missing service name")
+		return nil, nil
+	}
+
+	// Make the RPC request
+	var out structs.IndexedServiceInstanceEvents
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Catalog.ServiceHistory", &args, &out); err != nil {
+		metrics.IncrCounterWithLabels([]string{"client", "rpc", "error", "catalog_service_history"}, 1,
+			[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Events == nil {
+		out.Events = make(structs.ServiceInstanceEvents, 0)
+	}
+	metrics.IncrCounterWithLabels([]string{"client", "api", "success", "catalog_service_history"}, 1,
+		[]metrics.Label{{Name: "node", Value: s.nodeName()}})
+	return out.Events, nil
+}
+
 func (s *HTTPServer) catalogServiceNodes(resp http.ResponseWriter, req *http.Request, connect bool) (interface{}, error) {
 	metricsKey := "catalog_service_nodes"
 	pathPrefix := "/v1/catalog/service/"
@@ -181,6 +279,7 @@ func (s *HTTPServer) catalogServiceNodes(resp http.ResponseWriter, req *http.Req
 	args := structs.ServiceSpecificRequest{Connect: connect}
 	s.parseSource(req, &args.Source)
 	args.NodeMetaFilters = s.parseMetaFilter(req)
+	args.ExternalSource = req.URL.Query().Get("external-source")
 	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
 		return nil, nil
 	}
@@ -32,7 +32,7 @@ func TestCatalogRegister_Service_InvalidAddress(t *testing.T) {
 				},
 			}
 			req, _ := http.NewRequest("PUT", "/v1/catalog/register", jsonReader(args))
-			_, err := a.srv.CatalogRegister(nil, req)
+			_, err := a.srv.CatalogRegister(httptest.NewRecorder(), req)
 			if err == nil || err.Error() != "Invalid service address" {
 				t.Fatalf("err: %v", err)
 			}
@@ -48,7 +48,7 @@ func TestCatalogDeregister(t *testing.T) {
 	// Register node
 	args := &structs.DeregisterRequest{Node: "foo"}
 	req, _ := http.NewRequest("PUT", "/v1/catalog/deregister", jsonReader(args))
-	obj, err := a.srv.CatalogDeregister(nil, req)
+	obj, err := a.srv.CatalogDeregister(httptest.NewRecorder(), req)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -442,6 +442,38 @@ func TestCatalogServices(t *testing.T) {
 	}
 }
 
+func TestCatalogServiceVirtualIPs(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			Service: "api",
+		},
+	}
+
+	var out struct{}
+	if err := a.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/catalog/virtual-ips?dc=dc1", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.CatalogServiceVirtualIPs(resp, req)
+	require.NoError(t, err)
+
+	assertIndex(t, resp)
+
+	vips := obj.(map[string]string)
+	require.Equal(t, "240.0.0.1", vips["consul"])
+	require.Equal(t, "240.0.0.2", vips["api"])
+}
+
 func TestCatalogServices_NodeMetaFilter(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -483,6 +515,113 @@ func TestCatalogServices_NodeMetaFilter(t *testing.T) {
 	}
 }
 
+func TestCatalogServices_ExternalSource(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Register services of different sources.
+	for _, source := range []string{"k8s", "terraform"} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      "api-" + source,
+				Service: "api-" + source,
+				Meta:    map[string]string{structs.MetaExternalSource: source},
+			},
+		}
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/catalog/services?external-source=k8s", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.CatalogServices(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	require.Equal(t, structs.Services{"api-k8s": []string{}}, obj.(structs.Services))
+
+	req, _ = http.NewRequest("GET", "/v1/catalog/service/api-terraform?external-source=k8s", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.CatalogServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	require.Len(t, obj.(structs.ServiceNodes), 0)
+
+	req, _ = http.NewRequest("GET", "/v1/catalog/service/api-terraform?external-source=terraform", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.CatalogServiceNodes(resp, req)
+	require.NoError(t, err)
+	nodes := obj.(structs.ServiceNodes)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "terraform", nodes[0].ServiceMeta[structs.MetaExternalSource])
+}
+
+func TestCatalogServiceHistory(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Make sure an empty list is returned, not a nil
+	{
+		req, _ := http.NewRequest("GET", "/v1/catalog/service/api/history", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.CatalogServiceNodes(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		events := obj.(structs.ServiceInstanceEvents)
+		if events == nil || len(events) != 0 {
+			t.Fatalf("bad: %v", obj)
+		}
+	}
+
+	// Register and deregister an instance
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "api1",
+			Service: "api",
+		},
+	}
+	var out struct{}
+	if err := a.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dereg := &structs.DeregisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+	}
+	if err := a.RPC("Catalog.Deregister", dereg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/catalog/service/api/history", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.CatalogServiceNodes(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertIndex(t, resp)
+
+	events := obj.(structs.ServiceInstanceEvents)
+	if len(events) != 2 {
+		t.Fatalf("bad: %v", obj)
+	}
+	if events[0].Type != structs.ServiceInstanceRegistered || events[1].Type != structs.ServiceInstanceNodeDeregistered {
+		t.Fatalf("bad: %v %v", events[0], events[1])
+	}
+	if events[1].Node != "foo" || events[1].ServiceID != "api1" {
+		t.Fatalf("bad: %v", events[1])
+	}
+}
+
 func TestCatalogServiceNodes(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -22,4 +22,8 @@ type persistedCheckState struct {
 	Output  string
 	Status  string
 	Expires int64
+
+	// Updated is the time the state was persisted. It is zero for states
+	// persisted by older versions.
+	Updated int64
 }
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"github.com/hashicorp/consul/agent/local"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	checkMetricsLabels = []string{"node", "check_id", "check_name", "service_id", "service_name"}
+
+	checkStatusDesc = prometheus.NewDesc(
+		"consul_check_status",
+		"Whether the check has the given status.",
+		append(checkMetricsLabels, "status"), nil)
+	checkLastRunDesc = prometheus.NewDesc(
+		"consul_check_last_run_timestamp_seconds",
+		"The time the check last ran, or was last updated for TTL checks.",
+		checkMetricsLabels, nil)
+	checkLastRunDurationDesc = prometheus.NewDesc(
+		"consul_check_last_run_duration_seconds",
+		"How long the last run of the check took.",
+		checkMetricsLabels, nil)
+	checkStatusChangesDesc = prometheus.NewDesc(
+		"consul_check_status_changes_total",
+		"The number of times the status of the check changed since it was added.",
+		checkMetricsLabels, nil)
+)
+
+// checkMetricsCollector exports the local checks of the agent as Prometheus
+// metrics. It is created for every request with the checks the request is
+// allowed to see.
+type checkMetricsCollector struct {
+	node   string
+	checks map[types.CheckID]*structs.HealthCheck
+	states map[types.CheckID]*local.CheckState
+}
+
+// Describe implements prometheus.Collector.
+func (c *checkMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- checkStatusDesc
+	ch <- checkLastRunDesc
+	ch <- checkLastRunDurationDesc
+	ch <- checkStatusChangesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *checkMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for id, check := range c.checks {
+		labels := []string{c.node, string(id), check.Name, check.ServiceID, check.ServiceName}
+
+		for _, status := range []string{api.HealthPassing, api.HealthWarning, api.HealthCritical} {
+			var value float64
+			if check.Status == status {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(checkStatusDesc, prometheus.GaugeValue,
+				value, append(labels, status)...)
+		}
+
+		state, ok := c.states[id]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(checkStatusChangesDesc, prometheus.CounterValue,
+			float64(state.StatusChanges), labels...)
+		if state.LastRun.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(checkLastRunDesc, prometheus.GaugeValue,
+			float64(state.LastRun.UnixNano())/1e9, labels...)
+		ch <- prometheus.MustNewConstMetric(checkLastRunDurationDesc, prometheus.GaugeValue,
+			state.LastRunDuration.Seconds(), labels...)
+	}
+}
@@ -45,6 +45,17 @@ type RPC interface {
 	RPC(method string, args interface{}, reply interface{}) error
 }
 
+// jitterInterval returns the time until the next run of a check. It is the
+// interval moved randomly by up to jitter percent of it in either direction,
+// so that checks which started together don't keep running at the same time.
+func jitterInterval(interval time.Duration, jitter int) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	spread := interval * time.Duration(jitter) / 100
+	return interval - spread/2 + lib.RandomStagger(spread)
+}
+
 // CheckNotifier interface is used by the CheckMonitor
 // to notify when a check has a status update. The update
 // should take care to be idempotent.
@@ -52,17 +63,35 @@ type CheckNotifier interface {
 	UpdateCheck(checkID types.CheckID, status, output string)
 }
 
+// CheckRunNotifier is implemented by notifiers which record when the
+// checks run and how long they take.
+type CheckRunNotifier interface {
+	CheckRun(checkID types.CheckID, start time.Time, duration time.Duration)
+}
+
+// notifyRun reports a run of the check which started at the given time if
+// the notifier records them.
+func notifyRun(notify CheckNotifier, checkID types.CheckID, start time.Time) {
+	if n, ok := notify.(CheckRunNotifier); ok {
+		n.CheckRun(checkID, start, time.Since(start))
+	}
+}
+
 // CheckMonitor is used to periodically invoke a script to
 // determine the health of a given check. It is compatible with
 // nagios plugins and expects the output in the same format.
 type CheckMonitor struct {
-	Notify     CheckNotifier
-	CheckID    types.CheckID
-	Script     string
-	ScriptArgs []string
-	Interval   time.Duration
-	Timeout    time.Duration
-	Logger     *log.Logger
+	Notify         CheckNotifier
+	CheckID        types.CheckID
+	Script         string
+	ScriptArgs     []string
+	Interval       time.Duration
+	IntervalJitter int
+	Timeout        time.Duration
+	Logger         *log.Logger
+
+	// Limits restrict the user and the resources of the script.
+	Limits exec.Limits
 
 	stop     bool
 	stopCh   chan struct{}
@@ -97,8 +126,10 @@ func (c *CheckMonitor) run() {
 	for {
 		select {
 		case <-next:
+			start := time.Now()
 			c.check()
-			next = time.After(c.Interval)
+			notifyRun(c.Notify, c.CheckID, start)
+			next = time.After(jitterInterval(c.Interval, c.IntervalJitter))
 		case <-c.stopCh:
 			return
 		}
@@ -126,6 +157,13 @@ func (c *CheckMonitor) check() {
 	cmd.Stdout = output
 	cmd.Stderr = output
 	exec.SetSysProcAttr(cmd)
+	if c.Limits.User != "" {
+		if err := exec.SetUser(cmd, c.Limits.User); err != nil {
+			c.Logger.Printf("[ERR] agent: Check %q failed to setup: %s", c.CheckID, err)
+			c.Notify.UpdateCheck(c.CheckID, api.HealthCritical, err.Error())
+			return
+		}
+	}
 
 	truncateAndLogOutput := func() string {
 		outputStr := string(output.Bytes())
@@ -137,8 +175,9 @@ func (c *CheckMonitor) check() {
 		return outputStr
 	}
 
-	// Start the check
-	if err := cmd.Start(); err != nil {
+	// Start the check with its resources restricted, a check which can't
+	// be restricted isn't allowed to run.
+	if err := exec.StartLimited(cmd, c.Limits); err != nil {
 		c.Logger.Printf("[ERR] agent: Check %q failed to invoke: %s", c.CheckID, err)
 		c.Notify.UpdateCheck(c.CheckID, api.HealthCritical, err.Error())
 		return
@@ -196,6 +235,17 @@ func (c *CheckMonitor) check() {
 				c.Notify.UpdateCheck(c.CheckID, api.HealthWarning, outputStr)
 				return
 			}
+
+			// Report why the script was killed, e.g. because it exceeded
+			// its CPU time or was killed by the OOM killer of its cgroup.
+			if status.Signaled() {
+				msg := fmt.Sprintf("Check was killed by signal: %s", status.Signal())
+				c.Logger.Printf("[WARN] agent: Check %q: %s", c.CheckID, msg)
+				if len(outputStr) > 0 {
+					msg += "\n\n" + outputStr
+				}
+				outputStr = msg
+			}
 		}
 	}
 
@@ -216,6 +266,9 @@ type CheckTTL struct {
 
 	timer *time.Timer
 
+	// remaining is the time until a restored check expires, see Restore.
+	remaining time.Duration
+
 	lastOutput     string
 	lastOutputLock sync.RWMutex
 
@@ -230,10 +283,25 @@ func (c *CheckTTL) Start() {
 	defer c.stopLock.Unlock()
 	c.stop = false
 	c.stopCh = make(chan struct{})
-	c.timer = time.NewTimer(c.TTL)
+	ttl := c.TTL
+	if c.remaining > 0 {
+		ttl = c.remaining
+		c.remaining = 0
+	}
+	c.timer = time.NewTimer(ttl)
 	go c.run()
 }
 
+// Restore sets the output and the time until the TTL expires of a check
+// whose state was persisted before the agent restarted, so that it expires
+// when it would have without the restart. It must be called before Start.
+func (c *CheckTTL) Restore(output string, remaining time.Duration) {
+	c.lastOutputLock.Lock()
+	c.lastOutput = output
+	c.lastOutputLock.Unlock()
+	c.remaining = remaining
+}
+
 // Stop is used to stop a check ttl.
 func (c *CheckTTL) Stop() {
 	c.stopLock.Lock()
@@ -277,7 +345,9 @@ func (c *CheckTTL) getExpiredOutput() string {
 // and to renew the TTL. If expired, TTL is restarted.
 func (c *CheckTTL) SetStatus(status, output string) {
 	c.Logger.Printf("[DEBUG] agent: Check %q status is now %s", c.CheckID, status)
+	start := time.Now()
 	c.Notify.UpdateCheck(c.CheckID, status, output)
+	notifyRun(c.Notify, c.CheckID, start)
 
 	// Store the last output so we can retain it if the TTL expires.
 	c.lastOutputLock.Lock()
@@ -300,6 +370,7 @@ type CheckHTTP struct {
 	Header          map[string][]string
 	Method          string
 	Interval        time.Duration
+	IntervalJitter  int
 	Timeout         time.Duration
 	Logger          *log.Logger
 	TLSClientConfig *tls.Config
@@ -364,8 +435,10 @@ func (c *CheckHTTP) run() {
 	for {
 		select {
 		case <-next:
+			start := time.Now()
 			c.check()
-			next = time.After(c.Interval)
+			notifyRun(c.Notify, c.CheckID, start)
+			next = time.After(jitterInterval(c.Interval, c.IntervalJitter))
 		case <-c.stopCh:
 			return
 		}
@@ -445,12 +518,13 @@ func (c *CheckHTTP) check() {
 // The check is passing if the connection succeeds
 // The check is critical if the connection returns an error
 type CheckTCP struct {
-	Notify   CheckNotifier
-	CheckID  types.CheckID
-	TCP      string
-	Interval time.Duration
-	Timeout  time.Duration
-	Logger   *log.Logger
+	Notify         CheckNotifier
+	CheckID        types.CheckID
+	TCP            string
+	Interval       time.Duration
+	IntervalJitter int
+	Timeout        time.Duration
+	Logger         *log.Logger
 
 	dialer   *net.Dialer
 	stop     bool
@@ -501,8 +575,10 @@ func (c *CheckTCP) run() {
 	for {
 		select {
 		case <-next:
+			start := time.Now()
 			c.check()
-			next = time.After(c.Interval)
+			notifyRun(c.Notify, c.CheckID, start)
+			next = time.After(jitterInterval(c.Interval, c.IntervalJitter))
 		case <-c.stopCh:
 			return
 		}
@@ -534,6 +610,7 @@ type CheckDocker struct {
 	DockerContainerID string
 	Shell             string
 	Interval          time.Duration
+	IntervalJitter    int
 	Logger            *log.Logger
 	Client            *DockerClient
 
@@ -573,8 +650,10 @@ func (c *CheckDocker) run() {
 	for {
 		select {
 		case <-next:
+			start := time.Now()
 			c.check()
-			next = time.After(c.Interval)
+			notifyRun(c.Notify, c.CheckID, start)
+			next = time.After(jitterInterval(c.Interval, c.IntervalJitter))
 		case <-c.stop:
 			return
 		}
@@ -614,6 +693,11 @@ func (c *CheckDocker) doCheck() (string, *circbuf.Buffer, error) {
 	}
 
 	execID, err := c.Client.CreateExec(c.DockerContainerID, cmd)
+	if _, ok := err.(*errContainerNotFound); ok {
+		// A missing container is a warning to tell it apart from a
+		// failing check, it is usually being replaced.
+		return api.HealthWarning, nil, err
+	}
 	if err != nil {
 		return api.HealthCritical, nil, err
 	}
@@ -650,6 +734,7 @@ type CheckGRPC struct {
 	CheckID         types.CheckID
 	GRPC            string
 	Interval        time.Duration
+	IntervalJitter  int
 	Timeout         time.Duration
 	TLSClientConfig *tls.Config
 	Logger          *log.Logger
@@ -680,8 +765,10 @@ func (c *CheckGRPC) run() {
 	for {
 		select {
 		case <-next:
+			start := time.Now()
 			c.check()
-			next = time.After(c.Interval)
+			notifyRun(c.Notify, c.CheckID, start)
+			next = time.After(jitterInterval(c.Interval, c.IntervalJitter))
 		case <-c.stopCh:
 			return
 		}
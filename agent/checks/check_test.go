@@ -11,10 +11,12 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/agent/exec"
 	"github.com/hashicorp/consul/agent/mock"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testutil/retry"
@@ -124,6 +126,73 @@ func TestCheckMonitor_Timeout(t *testing.T) {
 	}
 }
 
+func TestCheckMonitor_Limits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on Linux")
+	}
+	t.Parallel()
+	notif := mock.NewNotify()
+	check := &CheckMonitor{
+		Notify:     notif,
+		CheckID:    types.CheckID("foo"),
+		ScriptArgs: []string{"sh", "-c", "ulimit -t; ulimit -v"},
+		Interval:   time.Second,
+		Logger:     log.New(ioutil.Discard, uniqueID(), log.LstdFlags),
+		Limits:     exec.Limits{CPUTime: time.Second, Memory: 512 << 20},
+	}
+	check.Start()
+	defer check.Stop()
+
+	// The limits are in place when the script starts. The memory limit is
+	// reported in KiB.
+	retry.Run(t, func(r *retry.R) {
+		if got, want := notif.State("foo"), api.HealthPassing; got != want {
+			r.Fatalf("got state %q want %q", got, want)
+		}
+		if got, want := notif.Output("foo"), "1\n524288\n"; got != want {
+			r.Fatalf("got output %q want %q", got, want)
+		}
+	})
+}
+
+func TestCheckMonitor_LimitsFailed(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only supported on Linux")
+	}
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "consul")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ran := dir + "/ran"
+
+	notif := mock.NewNotify()
+	check := &CheckMonitor{
+		Notify:     notif,
+		CheckID:    types.CheckID("foo"),
+		ScriptArgs: []string{"touch", ran},
+		Interval:   time.Second,
+		Logger:     log.New(ioutil.Discard, uniqueID(), log.LstdFlags),
+		Limits:     exec.Limits{Cgroup: dir + "/missing"},
+	}
+	check.Start()
+	defer check.Stop()
+
+	// The script doesn't run if the limits can't be applied.
+	retry.Run(t, func(r *retry.R) {
+		if got, want := notif.State("foo"), api.HealthCritical; got != want {
+			r.Fatalf("got state %q want %q", got, want)
+		}
+		if got, want := notif.Output("foo"), "failed to move process into cgroup"; !strings.Contains(got, want) {
+			r.Fatalf("got output %q want %q", got, want)
+		}
+	})
+	if _, err := os.Stat(ran); !os.IsNotExist(err) {
+		t.Fatalf("script ran: %v", err)
+	}
+}
+
 func TestCheckMonitor_RandomStagger(t *testing.T) {
 	// t.Parallel() // timing test. no parallel
 	notif := mock.NewNotify()
@@ -149,6 +218,19 @@ func TestCheckMonitor_RandomStagger(t *testing.T) {
 	}
 }
 
+func TestJitterInterval(t *testing.T) {
+	t.Parallel()
+	if got := jitterInterval(10*time.Second, 0); got != 10*time.Second {
+		t.Fatalf("bad: %v", got)
+	}
+	for i := 0; i < 100; i++ {
+		got := jitterInterval(10*time.Second, 20)
+		if got < 9*time.Second || got > 11*time.Second {
+			t.Fatalf("bad: %v", got)
+		}
+	}
+}
+
 func TestCheckMonitor_LimitOutput(t *testing.T) {
 	t.Parallel()
 	notif := mock.NewNotify()
@@ -215,6 +297,30 @@ func TestCheckTTL(t *testing.T) {
 	}
 }
 
+func TestCheckTTL_Restore(t *testing.T) {
+	// t.Parallel() // timing test. no parallel
+	notif := mock.NewNotify()
+	check := &CheckTTL{
+		Notify:  notif,
+		CheckID: types.CheckID("foo"),
+		TTL:     time.Minute,
+		Logger:  log.New(ioutil.Discard, uniqueID(), log.LstdFlags),
+	}
+	check.Restore("restored-output", 50*time.Millisecond)
+	check.Start()
+	defer check.Stop()
+
+	// The restored TTL expires instead of the full one
+	retry.Run(t, func(r *retry.R) {
+		if notif.State("foo") != api.HealthCritical {
+			r.Fatalf("should be critical %v", notif.StateMap())
+		}
+	})
+	if !strings.Contains(notif.Output("foo"), "restored-output") {
+		t.Fatalf("should have retained output %v", notif.OutputMap())
+	}
+}
+
 func TestCheckHTTP(t *testing.T) {
 	t.Parallel()
 
@@ -537,7 +643,7 @@ func TestCheck_Docker(t *testing.T) {
 				},
 			},
 			out:   regexp.MustCompile("^create exec failed for unknown container 123$"),
-			state: api.HealthCritical,
+			state: api.HealthWarning,
 		},
 		{
 			desc: "create exec: paused container",
@@ -618,6 +724,22 @@ func TestCheck_Docker(t *testing.T) {
 			out:   regexp.MustCompile("^start exec failed for container 123 with status 999: body: some output err: <nil>$"),
 			state: api.HealthCritical,
 		},
+		{
+			desc: "start exec: output limit",
+			handlers: map[string]http.HandlerFunc{
+				"POST /containers/123/exec": func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(201)
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprint(w, `{"Id":"456"}`)
+				},
+				"POST /exec/456/start": func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(200)
+					fmt.Fprint(w, strings.Repeat("x", 101)) // more than 100 bytes
+				},
+			},
+			out:   regexp.MustCompile("^start exec failed for container 123: output exceeded 100 bytes$"),
+			state: api.HealthCritical,
+		},
 		{
 			desc: "inspect exec: bad exec id",
 			handlers: map[string]http.HandlerFunc{
@@ -767,7 +889,14 @@ func TestCheck_Docker(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
 			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				x := r.Method + " " + r.RequestURI
+				if r.RequestURI == "/_ping" {
+					w.Header().Set("API-Version", "1.39")
+					return
+				}
+				if !strings.HasPrefix(r.RequestURI, "/v1.39/") {
+					t.Fatalf("missing api version in url %s", r.RequestURI)
+				}
+				x := r.Method + " " + strings.TrimPrefix(r.RequestURI, "/v1.39")
 				h := tt.handlers[x]
 				if h == nil {
 					t.Fatalf("bad url %s", x)
@@ -778,10 +907,11 @@ func TestCheck_Docker(t *testing.T) {
 
 			// create a docker client with a tiny output buffer
 			// to test the truncation
-			c, err := NewDockerClient(srv.URL, 20)
+			c, err := NewDockerClient(srv.URL, nil, 20)
 			if err != nil {
 				t.Fatal(err)
 			}
+			c.maxread = 100
 
 			notif, upd := mock.NewNotifyChan()
 			id := types.CheckID("chk")
@@ -807,3 +937,63 @@ func TestCheck_Docker(t *testing.T) {
 		})
 	}
 }
+
+func TestDockerClient_negotiateVersion(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		daemon string
+		want   string
+	}{
+		"older daemon":   {"1.24", "/v1.24/exec/456/json"},
+		"newer daemon":   {"1.41", "/v" + DockerAPIVersion + "/exec/456/json"},
+		"unknown daemon": {"", "/exec/456/json"},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.RequestURI == "/_ping" {
+					if tc.daemon != "" {
+						w.Header().Set("API-Version", tc.daemon)
+					}
+					return
+				}
+				got = r.RequestURI
+				fmt.Fprint(w, `{"ExitCode":0}`)
+			}))
+			defer srv.Close()
+
+			c, err := NewDockerClient(srv.URL, nil, BufSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.InspectExec("123", "456"); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	t.Parallel()
+	cases := map[string][]string{
+		"docker.example.com":          {"--", "docker.example.com", "docker", "system", "dial-stdio"},
+		"admin@docker.example.com:22": {"-l", "admin", "-p", "22", "--", "docker.example.com", "docker", "system", "dial-stdio"},
+	}
+	for addr, want := range cases {
+		got, err := sshArgs(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v want %v", addr, got, want)
+		}
+	}
+	if _, err := sshArgs(""); err == nil {
+		t.Fatal("expected error for missing host")
+	}
+}
@@ -2,18 +2,52 @@ package checks
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/armon/circbuf"
 	"github.com/docker/go-connections/sockets"
+	"github.com/mitchellh/go-homedir"
 )
 
+const (
+	// DockerAPIVersion is the latest version of the Docker Engine API the
+	// client uses. Older daemons are talked to with their own version.
+	DockerAPIVersion = "1.40"
+
+	// DockerMaxOutput is the number of bytes of the output of a check the
+	// client reads at most. Checks which write more fail, only the last
+	// bytes which fit into the buffer of the client are kept anyways.
+	DockerMaxOutput = 1024 * 1024
+)
+
+// errOutputLimit is returned by call when the response is larger than the
+// read limit of the client.
+var errOutputLimit = errors.New("output limit exceeded")
+
+// errContainerNotFound is returned when the container of a check doesn't
+// exist, e.g. because it was removed before the check was deregistered.
+type errContainerNotFound struct {
+	containerID string
+}
+
+func (e *errContainerNotFound) Error() string {
+	return fmt.Sprintf("create exec failed for unknown container %s", e.containerID)
+}
+
 // DockerClient is a simplified client for the Docker Engine API
 // to execute the health checks and avoid significant dependencies.
 // It also consumes all data returned from the Docker API through
@@ -26,10 +60,21 @@ type DockerClient struct {
 	addr     string
 	basepath string
 	maxbuf   int64
+	maxread  int64
 	client   *http.Client
+
+	// version is the negotiated API version, it is empty until the
+	// negotiation succeeded or if the daemon didn't report its version.
+	version    string
+	negotiated bool
+	versionMu  sync.Mutex
 }
 
-func NewDockerClient(host string, maxbuf int64) (*DockerClient, error) {
+// NewDockerClient returns a client for the Docker daemon at the given host,
+// which is either a unix://, npipe://, tcp:// or ssh:// URL. Connections to
+// ssh:// hosts use the ssh binary and the docker binary on the remote host.
+// tlsConfig is used for tcp:// hosts, it may be nil.
+func NewDockerClient(host string, tlsConfig *tls.Config, maxbuf int64) (*DockerClient, error) {
 	if host == "" {
 		host = DefaultDockerHost
 	}
@@ -39,21 +84,74 @@ func NewDockerClient(host string, maxbuf int64) (*DockerClient, error) {
 		return nil, err
 	}
 
+	scheme := "http"
 	transport := new(http.Transport)
-	sockets.ConfigureTransport(transport, proto, addr)
+	switch proto {
+	case "ssh":
+		transport.Dial = func(string, string) (net.Conn, error) {
+			return dialSSH(addr)
+		}
+	default:
+		if err := sockets.ConfigureTransport(transport, proto, addr); err != nil {
+			return nil, err
+		}
+		if proto == "tcp" && tlsConfig != nil {
+			scheme = "https"
+			transport.TLSClientConfig = tlsConfig
+		}
+	}
 	client := &http.Client{Transport: transport}
 
 	return &DockerClient{
 		host:     host,
-		scheme:   "http",
+		scheme:   scheme,
 		proto:    proto,
 		addr:     addr,
 		basepath: basepath,
 		maxbuf:   maxbuf,
+		maxread:  DockerMaxOutput,
 		client:   client,
 	}, nil
 }
 
+// DockerTLSConfigFromEnv returns the TLS configuration for the Docker
+// client from the DOCKER_CERT_PATH and DOCKER_TLS_VERIFY environment
+// variables the docker CLI uses. It returns nil if TLS isn't configured.
+func DockerTLSConfigFromEnv() (*tls.Config, error) {
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	verify := os.Getenv("DOCKER_TLS_VERIFY") != ""
+	if certPath == "" && !verify {
+		return nil, nil
+	}
+	if certPath == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, err
+		}
+		certPath = filepath.Join(home, ".docker")
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load docker client certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: !verify,
+	}
+	if verify {
+		ca, err := ioutil.ReadFile(filepath.Join(certPath, "ca.pem"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load docker CA certificate: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to parse docker CA certificate")
+		}
+	}
+	return tlsConfig, nil
+}
+
 func (c *DockerClient) Close() error {
 	if t, ok := c.client.Transport.(*http.Transport); ok {
 		t.CloseIdleConnections()
@@ -86,10 +184,64 @@ func ParseHost(host string) (string, string, string, error) {
 	return proto, addr, basePath, nil
 }
 
+// negotiateVersion sets the API version of the requests to the lower one
+// of DockerAPIVersion and the version of the daemon. Daemons which don't
+// report their version are talked to without a version, which means the
+// latest one they support.
+func (c *DockerClient) negotiateVersion() error {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if c.negotiated {
+		return nil
+	}
+
+	_, _, header, err := c.do("GET", c.basepath+"/_ping", nil)
+	if err != nil {
+		return err
+	}
+	if v := header.Get("API-Version"); v != "" {
+		c.version = DockerAPIVersion
+		if versionLess(v, DockerAPIVersion) {
+			c.version = v
+		}
+	}
+	c.negotiated = true
+	return nil
+}
+
+// versionLess returns true if the API version a is lower than b.
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			return x < y
+		}
+	}
+	return len(as) < len(bs)
+}
+
 func (c *DockerClient) call(method, uri string, v interface{}) (*circbuf.Buffer, int, error) {
+	if err := c.negotiateVersion(); err != nil {
+		return nil, 0, err
+	}
+
+	c.versionMu.Lock()
+	version := c.version
+	c.versionMu.Unlock()
+	if version != "" {
+		uri = "/v" + version + uri
+	}
+
+	b, code, _, err := c.do(method, c.basepath+uri, v)
+	return b, code, err
+}
+
+func (c *DockerClient) do(method, uri string, v interface{}) (*circbuf.Buffer, int, http.Header, error) {
 	req, err := http.NewRequest(method, uri, nil)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 
 	if c.proto == "unix" || c.proto == "npipe" {
@@ -104,7 +256,7 @@ func (c *DockerClient) call(method, uri string, v interface{}) (*circbuf.Buffer,
 	if v != nil {
 		var b bytes.Buffer
 		if err := json.NewEncoder(&b).Encode(v); err != nil {
-			return nil, 0, err
+			return nil, 0, nil, err
 		}
 		req.Body = ioutil.NopCloser(&b)
 		req.Header.Set("Content-Type", "application/json")
@@ -112,16 +264,19 @@ func (c *DockerClient) call(method, uri string, v interface{}) (*circbuf.Buffer,
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	b, err := circbuf.NewBuffer(c.maxbuf)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
+	}
+	n, err := io.Copy(b, io.LimitReader(resp.Body, c.maxread+1))
+	if err == nil && n > c.maxread {
+		err = errOutputLimit
 	}
-	_, err = io.Copy(b, resp.Body)
-	return b, resp.StatusCode, err
+	return b, resp.StatusCode, resp.Header, err
 }
 
 func (c *DockerClient) CreateExec(containerID string, cmd []string) (string, error) {
@@ -149,7 +304,7 @@ func (c *DockerClient) CreateExec(containerID string, cmd []string) (string, err
 		}
 		return resp.Id, nil
 	case code == 404:
-		return "", fmt.Errorf("create exec failed for unknown container %s", containerID)
+		return "", &errContainerNotFound{containerID: containerID}
 	case code == 409:
 		return "", fmt.Errorf("create exec failed since container %s is paused or stopped", containerID)
 	default:
@@ -168,6 +323,8 @@ func (c *DockerClient) StartExec(containerID, execID string) (*circbuf.Buffer, e
 	// todo(fs): even though both body and status code have been received. My current is
 	// todo(fs): that the docker agent closes this prematurely but I don't understand why.
 	// todo(fs): the code below ignores this error.
+	case err == errOutputLimit:
+		return nil, fmt.Errorf("start exec failed for container %s: output exceeded %d bytes", containerID, c.maxread)
 	case err != nil && !strings.Contains(err.Error(), "connection reset by peer"):
 		return nil, fmt.Errorf("start exec failed for container %s: %v", containerID, err)
 	case code == 200:
@@ -0,0 +1,88 @@
+package checks
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// sshArgs returns the arguments of the ssh command which connects to the
+// Docker daemon at addr, which is given as [user@]host[:port].
+func sshArgs(addr string) ([]string, error) {
+	u, err := url.Parse("ssh://" + addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing host in docker host ssh://%s", addr)
+	}
+
+	var args []string
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	return append(args, "--", u.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// dialSSH connects to the Docker daemon at addr through the docker CLI on
+// the remote host, the same way the docker CLI does for ssh:// hosts.
+func dialSSH(addr string) (net.Conn, error) {
+	args, err := sshArgs(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("ssh", args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ssh: %s", err)
+	}
+	return &cmdConn{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+// cmdConn is a connection over the stdin and stdout of a command.
+// Deadlines are not supported.
+type cmdConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+
+	closeOnce sync.Once
+}
+
+func (c *cmdConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *cmdConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+func (c *cmdConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *cmdConn) LocalAddr() net.Addr                { return dummyAddr{} }
+func (c *cmdConn) RemoteAddr() net.Addr               { return dummyAddr{} }
+func (c *cmdConn) SetDeadline(t time.Time) error      { return nil }
+func (c *cmdConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *cmdConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type dummyAddr struct{}
+
+func (dummyAddr) Network() string { return "ssh" }
+func (dummyAddr) String() string  { return "ssh" }
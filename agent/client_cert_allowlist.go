@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+)
+
+// ClientCertAllowlist restricts the HTTP API to requests which present a TLS
+// client certificate matching one of the configured patterns. It applies in
+// addition to the ACLs, so a leaked token alone isn't enough to use the
+// restricted endpoints.
+type ClientCertAllowlist struct {
+	commonNames []string
+	orgUnits    []string
+	uris        []string
+	writeOnly   bool
+}
+
+// NewClientCertAllowlist returns an allowlist for the given patterns of
+// common names, organizational units and URI SANs. A "*" in a pattern
+// matches any sequence of characters. If writeOnly is set only the write
+// requests are restricted. It returns nil if no patterns are given, which
+// allows all requests.
+func NewClientCertAllowlist(commonNames, orgUnits, uris []string, writeOnly bool) *ClientCertAllowlist {
+	if len(commonNames) == 0 && len(orgUnits) == 0 && len(uris) == 0 {
+		return nil
+	}
+	return &ClientCertAllowlist{
+		commonNames: commonNames,
+		orgUnits:    orgUnits,
+		uris:        uris,
+		writeOnly:   writeOnly,
+	}
+}
+
+// Allow returns true if the request is allowed. Requests without a verified
+// client certificate, e.g. requests over plain HTTP, are only allowed if
+// they aren't restricted.
+func (l *ClientCertAllowlist) Allow(req *http.Request) bool {
+	if l == nil {
+		return true
+	}
+	if l.writeOnly && isReadMethod(req.Method) {
+		return true
+	}
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return false
+	}
+	return l.allowCert(req.TLS.VerifiedChains[0][0])
+}
+
+// allowCert returns true if any attribute of the certificate matches one of
+// the patterns.
+func (l *ClientCertAllowlist) allowCert(cert *x509.Certificate) bool {
+	if matchCertPatterns(l.commonNames, cert.Subject.CommonName) {
+		return true
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if matchCertPatterns(l.orgUnits, ou) {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if matchCertPatterns(l.uris, uri.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadMethod returns true for the HTTP methods which don't modify state.
+func isReadMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// matchCertPatterns returns true if the value matches any of the patterns.
+// A "*" in a pattern matches any sequence of characters.
+func matchCertPatterns(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matchGlob(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches the value against a pattern in which "*" matches any
+// sequence of characters.
+func matchGlob(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
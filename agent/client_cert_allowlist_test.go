@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestClientCertAllowlist(t *testing.T) {
+	t.Parallel()
+
+	uri, _ := url.Parse("spiffe://example.com/deploy/ci")
+	deploy := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "deploy-1", OrganizationalUnit: []string{"Tooling"}},
+		URIs:    []*url.URL{uri},
+	}
+	other := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "web-1", OrganizationalUnit: []string{"Web"}},
+	}
+
+	tests := []struct {
+		desc      string
+		allowlist *ClientCertAllowlist
+		method    string
+		cert      *x509.Certificate
+		allow     bool
+	}{
+		{"nothing configured", NewClientCertAllowlist(nil, nil, nil, false), "PUT", nil, true},
+		{"no certificate", NewClientCertAllowlist([]string{"deploy-*"}, nil, nil, false), "GET", nil, false},
+		{"common name", NewClientCertAllowlist([]string{"deploy-*"}, nil, nil, false), "GET", deploy, true},
+		{"common name mismatch", NewClientCertAllowlist([]string{"deploy-*"}, nil, nil, false), "GET", other, false},
+		{"organizational unit", NewClientCertAllowlist(nil, []string{"Tooling"}, nil, false), "PUT", deploy, true},
+		{"organizational unit mismatch", NewClientCertAllowlist(nil, []string{"Tooling"}, nil, false), "PUT", other, false},
+		{"uri", NewClientCertAllowlist(nil, nil, []string{"spiffe://example.com/deploy/*"}, false), "DELETE", deploy, true},
+		{"uri mismatch", NewClientCertAllowlist(nil, nil, []string{"spiffe://example.com/web/*"}, false), "DELETE", deploy, false},
+		{"write only read", NewClientCertAllowlist([]string{"deploy-*"}, nil, nil, true), "GET", nil, true},
+		{"write only write", NewClientCertAllowlist([]string{"deploy-*"}, nil, nil, true), "PUT", other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/v1/kv/foo", nil)
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{tt.cert}},
+				}
+			}
+			if got, want := tt.allowlist.Allow(req), tt.allow; got != want {
+				t.Fatalf("got %v want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestClientCertAllowlist_matchGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern, value string
+		match          bool
+	}{
+		{"deploy", "deploy", true},
+		{"deploy", "deploy-1", false},
+		{"deploy-*", "deploy-1", true},
+		{"deploy-*", "deploy-", true},
+		{"deploy-*", "web-1", false},
+		{"*-1", "deploy-1", true},
+		{"*", "anything", true},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXcYb", false},
+		{"ab*ba", "aba", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.value); got != tt.match {
+			t.Fatalf("%q %q: got %v want %v", tt.pattern, tt.value, got, tt.match)
+		}
+	}
+}
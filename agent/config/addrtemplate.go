@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hashicorp/go-sockaddr/template"
+)
+
+// addressFamilies maps the supported values for the address family
+// settings to a function which reports whether an IP address belongs
+// to that family.
+var addressFamilies = map[string]func(net.IP) bool{
+	"ipv4": func(ip net.IP) bool { return ip.To4() != nil },
+	"ipv6": func(ip net.IP) bool { return ip.To4() == nil },
+}
+
+// splitAddrAlternatives splits an address template into its alternatives
+// which are separated by "||". Separators within a template action, i.e.
+// between "{{" and "}}", are ignored.
+func splitAddrAlternatives(s string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], "}}") && depth > 0:
+			depth--
+			i++
+		case depth == 0 && strings.HasPrefix(s[i:], "||"):
+			alts = append(alts, strings.TrimSpace(s[start:i]))
+			start = i + 2
+			i++
+		}
+	}
+	return append(alts, strings.TrimSpace(s[start:]))
+}
+
+// evalAddrTemplate evaluates the alternatives of the go-sockaddr template
+// in s in order and returns the IP address of the first one which results
+// in exactly one address of the given family. An empty family matches all
+// addresses.
+//
+// If all alternatives evaluate to no address then nil is returned without
+// an error so that the caller can apply its default. Otherwise, the error
+// contains the evaluation trace of every alternative.
+func evalAddrTemplate(s, family string) (*net.IPAddr, error) {
+	match := addressFamilies[family]
+	if family != "" && match == nil {
+		return nil, fmt.Errorf("invalid address family %q. Must be one of 'ipv4' or 'ipv6'", family)
+	}
+
+	var trace []string
+	failed := false
+	for _, alt := range splitAddrAlternatives(s) {
+		ip, reason := evalAddrAlternative(alt, family, match)
+		if ip != nil {
+			return ip, nil
+		}
+		if reason != "" {
+			failed = true
+		} else {
+			reason = "no address found"
+		}
+		trace = append(trace, fmt.Sprintf("%q: %s", alt, reason))
+	}
+	if !failed {
+		return nil, nil
+	}
+
+	what := "address"
+	if family != "" {
+		what = family + " address"
+	}
+	return nil, fmt.Errorf("no single %s found in %q:\n\t%s", what, s, strings.Join(trace, "\n\t"))
+}
+
+// evalAddrAlternative evaluates a single alternative of an address
+// template. It returns either the address or the reason why the
+// alternative could not be used. Both are empty if the alternative
+// evaluates to no address.
+func evalAddrAlternative(alt, family string, match func(net.IP) bool) (*net.IPAddr, string) {
+	x, err := template.Parse(alt)
+	if err != nil {
+		return nil, fmt.Sprintf("error parsing template: %s", err)
+	}
+
+	fields := strings.Fields(x)
+	if len(fields) == 0 {
+		return nil, ""
+	}
+
+	var ips []*net.IPAddr
+	for _, a := range fields {
+		if strings.HasPrefix(a, "unix://") || strings.HasPrefix(a, "npipe://") {
+			return nil, fmt.Sprintf("evaluated to %q: cannot be a unix socket", x)
+		}
+		// net.ParseIP does not like '[::]'
+		if a == "[::]" {
+			a = "::"
+		}
+		ip := net.ParseIP(a)
+		if ip == nil {
+			return nil, fmt.Sprintf("evaluated to %q: invalid ip address: %s", x, a)
+		}
+		if match == nil || match(ip) {
+			ips = append(ips, &net.IPAddr{IP: ip})
+		}
+	}
+
+	switch len(ips) {
+	case 0:
+		return nil, fmt.Sprintf("evaluated to %q: no %s address", x, family)
+	case 1:
+		return ips[0], ""
+	default:
+		var addrs []string
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+		return nil, fmt.Sprintf("evaluated to %q: multiple addresses found: %s", x, strings.Join(addrs, " "))
+	}
+}
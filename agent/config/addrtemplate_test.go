@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAddrAlternatives(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{in: "1.2.3.4", out: []string{"1.2.3.4"}},
+		{in: "1.2.3.4 || 5.6.7.8", out: []string{"1.2.3.4", "5.6.7.8"}},
+		{in: `{{ GetInterfaceIP "eth0" }}||::1`, out: []string{`{{ GetInterfaceIP "eth0" }}`, "::1"}},
+		{in: `{{ printf "||" }} || ::1`, out: []string{`{{ printf "||" }}`, "::1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			require.Equal(t, tt.out, splitAddrAlternatives(tt.in))
+		})
+	}
+}
+
+func TestEvalAddrTemplate(t *testing.T) {
+	tests := []struct {
+		in, family, out, err string
+	}{
+		{in: "1.2.3.4", out: "1.2.3.4"},
+		{in: "[::]", out: "::"},
+		{in: `{{ printf "" }}`, out: ""},
+		{in: `{{ printf "" }} || 1.2.3.4`, out: "1.2.3.4"},
+		{in: `{{ printf "1.2.3.4 5.6.7.8" }} || ::1`, out: "::1"},
+		{in: `{{ printf "1.2.3.4 dead::1" }}`, family: "ipv4", out: "1.2.3.4"},
+		{in: `{{ printf "1.2.3.4 dead::1" }}`, family: "ipv6", out: "dead::1"},
+		{in: `1.2.3.4 || dead::1`, family: "ipv6", out: "dead::1"},
+		{in: "1.2.3.4", family: "ipx", err: `invalid address family "ipx"`},
+		{in: "unix:///tmp/sock", err: `"unix:///tmp/sock": evaluated to "unix:///tmp/sock": cannot be a unix socket`},
+		{in: "1.2.3.4", family: "ipv6", err: `"1.2.3.4": evaluated to "1.2.3.4": no ipv6 address`},
+		{
+			in: `{{ printf "1.2.3.4 5.6.7.8" }} || {{ printf "" }} || {{ nope }}`,
+			err: `no single address found in "{{ printf \"1.2.3.4 5.6.7.8\" }} || {{ printf \"\" }} || {{ nope }}":` + "\n\t" +
+				`"{{ printf \"1.2.3.4 5.6.7.8\" }}": evaluated to "1.2.3.4 5.6.7.8": multiple addresses found: 1.2.3.4 5.6.7.8` + "\n\t" +
+				`"{{ printf \"\" }}": no address found` + "\n\t" +
+				`"{{ nope }}": error parsing template: `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in+" "+tt.family, func(t *testing.T) {
+			ip, err := evalAddrTemplate(tt.in, tt.family)
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.out == "" {
+				require.Nil(t, ip)
+				return
+			}
+			require.Equal(t, tt.out, ip.String())
+		})
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/ipaddr"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/render"
 	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/consul/types"
 	multierror "github.com/hashicorp/go-multierror"
@@ -65,6 +68,10 @@ type Builder struct {
 	// parsing the configuration.
 	Warnings []string
 
+	// LookupEnv returns the value of an environment variable for the
+	// interpolation of config files. If nil, os.LookupEnv is called.
+	LookupEnv func(key string) (string, bool)
+
 	// Hostname returns the hostname of the machine. If nil, os.Hostname
 	// is called.
 	Hostname func() (string, error)
@@ -77,6 +84,10 @@ type Builder struct {
 	// err contains the first error that occurred during
 	// building the runtime configuration.
 	err error
+
+	// envSources contains the names of the config sources whose data
+	// was changed by the interpolation of environment variables.
+	envSources map[string]bool
 }
 
 // NewBuilder returns a new configuration builder based on the given command
@@ -130,6 +141,12 @@ func NewBuilder(flags Flags) (*Builder, error) {
 	b.Tail = append(b.Tail, NonUserSource(), DefaultConsulSource(), DefaultEnterpriseSource(), DefaultVersionSource())
 	if b.boolVal(b.Flags.DevMode) {
 		b.Tail = append(b.Tail, DevConsulSource())
+
+		// A persistent dev agent always uses the given directory as its
+		// data dir so that the state survives a restart.
+		if dir := b.stringVal(b.Flags.DevPersist); dir != "" {
+			b.Tail = append(b.Tail, newSource("flags.dev-persist", Config{DataDir: &dir}))
+		}
 	}
 	return b, nil
 }
@@ -231,18 +248,100 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 	b.err = nil
 	b.Warnings = nil
 
+	_, c, err := b.mergedSources()
+	if err != nil {
+		return RuntimeConfig{}, err
+	}
+	return b.build(c)
+}
+
+// mergedSources returns the config sources in the order in which they
+// are merged, including the profiles, and the configuration merged from
+// them.
+func (b *Builder) mergedSources() ([]Source, Config, error) {
 	// ----------------------------------------------------------------
 	// merge config sources as follows
 	//
 
-	configFormat := b.stringVal(b.Flags.ConfigFormat)
-	if configFormat != "" && configFormat != "json" && configFormat != "hcl" {
-		return RuntimeConfig{}, fmt.Errorf("config: -config-format must be either 'hcl' or 'json'")
+	files, err := b.ConfigSources()
+	if err != nil {
+		return nil, Config{}, err
 	}
 
 	// build the list of config sources
 	var srcs []Source
 	srcs = append(srcs, b.Head...)
+	srcs = append(srcs, files...)
+	srcs = append(srcs, b.Tail...)
+
+	// parse the config sources into a configuration
+	c, err := b.mergeSources(srcs)
+	if err != nil {
+		return nil, Config{}, err
+	}
+
+	// The gossip profiles and an agent profile fetched from the servers
+	// are merged after the defaults and before the config files so that
+	// the local configuration always takes precedence. Since the profile
+	// names and the data dir are only known after merging all sources
+	// the sources are merged again when a profile is used.
+	var profiles []Source
+	dataDir := b.stringVal(c.DataDir)
+	gossipLANProfile, err := b.gossipProfile("gossip_lan", c.GossipLAN.Profile, dataDir)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	gossipWANProfile, err := b.gossipProfile("gossip_wan", c.GossipWAN.Profile, dataDir)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	for _, p := range []struct{ section, name string }{
+		{"gossip_lan", gossipLANProfile},
+		{"gossip_wan", gossipWANProfile},
+	} {
+		if p.name == "" {
+			continue
+		}
+		src, err := gossipProfileSource(p.section, p.name)
+		if err != nil {
+			return nil, Config{}, err
+		}
+		profiles = append(profiles, src)
+	}
+	if name := b.stringVal(c.AgentProfile); name != "" {
+		src, err := b.cachedProfileSource(name, dataDir)
+		if err != nil {
+			return nil, Config{}, err
+		}
+		if src != nil {
+			profiles = append(profiles, *src)
+		}
+	}
+	if len(profiles) > 0 {
+		var withProfiles []Source
+		withProfiles = append(withProfiles, srcs[:len(b.Head)]...)
+		withProfiles = append(withProfiles, profiles...)
+		withProfiles = append(withProfiles, srcs[len(b.Head):]...)
+		if c, err = b.mergeSources(withProfiles); err != nil {
+			return nil, Config{}, err
+		}
+		srcs = withProfiles
+	}
+	return srcs, c, nil
+}
+
+// ConfigSources returns the config sources in b.Sources as they are
+// merged: with their format set, sources in an unknown format skipped and
+// environment variables interpolated if enabled.
+func (b *Builder) ConfigSources() ([]Source, error) {
+	b.envSources = make(map[string]bool)
+
+	configFormat := b.stringVal(b.Flags.ConfigFormat)
+	if configFormat != "" && configFormat != "json" && configFormat != "hcl" {
+		return nil, fmt.Errorf("config: -config-format must be either 'hcl' or 'json'")
+	}
+
+	var srcs []Source
 	for _, src := range b.Sources {
 		src.Format = FormatFrom(src.Name)
 		if configFormat != "" {
@@ -260,36 +359,38 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 			}
 		}
 		if src.Format == "" {
-			return RuntimeConfig{}, fmt.Errorf(`config: Missing or invalid file extension for %q. Please use ".json" or ".hcl".`, src.Name)
+			return nil, fmt.Errorf(`config: Missing or invalid file extension for %q. Please use ".json" or ".hcl".`, src.Name)
+		}
+		if b.boolVal(b.Flags.ConfigInterpolateEnv) {
+			lookup := b.LookupEnv
+			if lookup == nil {
+				lookup = os.LookupEnv
+			}
+			data, err := interpolateEnv(src.Data, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("Error interpolating %s: %s", src.Name, err)
+			}
+			if data != src.Data {
+				b.envSources[src.Name] = true
+			}
+			src.Data = data
 		}
 		srcs = append(srcs, src)
 	}
-	srcs = append(srcs, b.Tail...)
-
-	// parse the config sources into a configuration
-	var c Config
-	for _, s := range srcs {
-		if s.Name == "" || s.Data == "" {
-			continue
-		}
-		c2, err := Parse(s.Data, s.Format)
-		if err != nil {
-			return RuntimeConfig{}, fmt.Errorf("Error parsing %s: %s", s.Name, err)
-		}
-
-		// if we have a single 'check' or 'service' we need to add them to the
-		// list of checks and services first since we cannot merge them
-		// generically and later values would clobber earlier ones.
-		if c2.Check != nil {
-			c2.Checks = append(c2.Checks, *c2.Check)
-			c2.Check = nil
-		}
-		if c2.Service != nil {
-			c2.Services = append(c2.Services, *c2.Service)
-			c2.Service = nil
-		}
+	return srcs, nil
+}
 
-		c = Merge(c, c2)
+// build constructs the runtime configuration from the merged config
+// sources.
+func (b *Builder) build(c Config) (rt RuntimeConfig, err error) {
+	dataDir := b.stringVal(c.DataDir)
+	gossipLANProfile, err := b.gossipProfile("gossip_lan", c.GossipLAN.Profile, dataDir)
+	if err != nil {
+		return RuntimeConfig{}, err
+	}
+	gossipWANProfile, err := b.gossipProfile("gossip_wan", c.GossipWAN.Profile, dataDir)
+	if err != nil {
+		return RuntimeConfig{}, err
 	}
 
 	// ----------------------------------------------------------------
@@ -339,6 +440,21 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		checks = append(checks, b.checkVal(&check))
 	}
 
+	var hooks []RuntimeHookConfig
+	for i, hook := range c.Hooks {
+		hooks = append(hooks, b.hookVal(i, &hook))
+	}
+
+	var templates []render.Template
+	for i, tmpl := range c.Templates {
+		templates = append(templates, b.templateVal(i, &tmpl))
+	}
+
+	uiMetricsProxyPathAllowlist := c.UIMetricsProxy.PathAllowlist
+	if uiMetricsProxyPathAllowlist == nil {
+		uiMetricsProxyPathAllowlist = []string{"/api/v1/query", "/api/v1/query_range"}
+	}
+
 	var services []*structs.ServiceDefinition
 	for _, service := range c.Services {
 		services = append(services, b.serviceVal(&service))
@@ -401,21 +517,30 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		return RuntimeConfig{}, fmt.Errorf("Advertise WAN address cannot be 0.0.0.0, :: or [::]")
 	}
 
+	lanFamily := b.stringVal(c.AdvertiseAddrLANFamily)
+	wanFamily := b.stringVal(c.AdvertiseAddrWANFamily)
+	if _, ok := addressFamilies[lanFamily]; lanFamily != "" && !ok {
+		return RuntimeConfig{}, fmt.Errorf("advertise_addr_family: invalid address family %q. Must be one of 'ipv4' or 'ipv6'", lanFamily)
+	}
+	if _, ok := addressFamilies[wanFamily]; wanFamily != "" && !ok {
+		return RuntimeConfig{}, fmt.Errorf("advertise_addr_wan_family: invalid address family %q. Must be one of 'ipv4' or 'ipv6'", wanFamily)
+	}
+
 	bindAddr := bindAddrs[0].(*net.IPAddr)
-	advertiseAddr := b.makeIPAddr(b.expandFirstIP("advertise_addr", c.AdvertiseAddrLAN), bindAddr)
+	advertiseAddr := b.makeIPAddr(b.expandFirstIPFamily("advertise_addr", c.AdvertiseAddrLAN, lanFamily), bindAddr)
 	if ipaddr.IsAny(advertiseAddr) {
 
 		var addrtyp string
 		var detect func() ([]*net.IPAddr, error)
 		switch {
-		case ipaddr.IsAnyV4(advertiseAddr):
+		case lanFamily == "ipv4" || lanFamily == "" && ipaddr.IsAnyV4(advertiseAddr):
 			addrtyp = "private IPv4"
 			detect = b.GetPrivateIPv4
 			if detect == nil {
 				detect = ipaddr.GetPrivateIPv4
 			}
 
-		case ipaddr.IsAnyV6(advertiseAddr):
+		case lanFamily == "ipv6" || lanFamily == "" && ipaddr.IsAnyV6(advertiseAddr):
 			addrtyp = "public IPv6"
 			detect = b.GetPublicIPv6
 			if detect == nil {
@@ -447,8 +572,14 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 	}
 
 	// derive other advertise addresses from the advertise address
-	advertiseAddrLAN := b.makeIPAddr(b.expandFirstIP("advertise_addr", c.AdvertiseAddrLAN), advertiseAddr)
-	advertiseAddrWAN := b.makeIPAddr(b.expandFirstIP("advertise_addr_wan", c.AdvertiseAddrWAN), advertiseAddrLAN)
+	advertiseAddrLAN := advertiseAddr
+	advertiseAddrWAN := b.makeIPAddr(b.expandFirstIPFamily("advertise_addr_wan", c.AdvertiseAddrWAN, wanFamily), advertiseAddrLAN)
+	if match := addressFamilies[lanFamily]; match != nil && !match(advertiseAddrLAN.IP) {
+		return RuntimeConfig{}, fmt.Errorf("advertise_addr: %s is not an %s address", advertiseAddrLAN.IP, lanFamily)
+	}
+	if match := addressFamilies[wanFamily]; match != nil && !match(advertiseAddrWAN.IP) {
+		return RuntimeConfig{}, fmt.Errorf("advertise_addr_wan: %s is not an %s address", advertiseAddrWAN.IP, wanFamily)
+	}
 	rpcAdvertiseAddr := &net.TCPAddr{IP: advertiseAddrLAN.IP, Port: serverPort}
 	serfAdvertiseAddrLAN := &net.TCPAddr{IP: advertiseAddrLAN.IP, Port: serfPortLAN}
 	// Only initialize serf WAN advertise address when its enabled
@@ -471,23 +602,17 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 	}
 
 	// expand dns recursors
-	uniq := map[string]bool{}
-	dnsRecursors := []string{}
-	for _, r := range c.DNSRecursors {
-		x, err := template.Parse(r)
+	dnsRecursors, err := expandDNSRecursors(c.DNSRecursors)
+	if err != nil {
+		return RuntimeConfig{}, err
+	}
+	dnsDomainRecursors := map[string][]string{}
+	for domain, recursors := range c.DNS.DomainRecursors {
+		addrs, err := expandDNSRecursors(recursors)
 		if err != nil {
-			return RuntimeConfig{}, fmt.Errorf("Invalid DNS recursor template %q: %s", r, err)
-		}
-		for _, addr := range strings.Fields(x) {
-			if strings.HasPrefix(addr, "unix://") {
-				return RuntimeConfig{}, fmt.Errorf("DNS Recursors cannot be unix sockets: %s", addr)
-			}
-			if uniq[addr] {
-				continue
-			}
-			uniq[addr] = true
-			dnsRecursors = append(dnsRecursors, addr)
+			return RuntimeConfig{}, err
 		}
+		dnsDomainRecursors[domain] = addrs
 	}
 
 	// Create the default set of tagged addresses.
@@ -497,6 +622,42 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 	c.TaggedAddresses["lan"] = advertiseAddrLAN.IP.String()
 	c.TaggedAddresses["wan"] = advertiseAddrWAN.IP.String()
 
+	// A dual-stack node advertises an address of each IP family in the
+	// lan_ipv4, lan_ipv6, wan_ipv4 and wan_ipv6 tagged addresses. They are
+	// only set if one of them is configured and default to the LAN and WAN
+	// advertise addresses of the matching family.
+	dualStack := []struct {
+		tag, name, family string
+		addr              *string
+		def               *net.IPAddr
+	}{
+		{"lan_ipv4", "advertise_addr_ipv4", "ipv4", c.AdvertiseAddrLANIPv4, advertiseAddrLAN},
+		{"lan_ipv6", "advertise_addr_ipv6", "ipv6", c.AdvertiseAddrLANIPv6, advertiseAddrLAN},
+		{"wan_ipv4", "advertise_addr_wan_ipv4", "ipv4", c.AdvertiseAddrWANIPv4, advertiseAddrWAN},
+		{"wan_ipv6", "advertise_addr_wan_ipv6", "ipv6", c.AdvertiseAddrWANIPv6, advertiseAddrWAN},
+	}
+	dualStackEnabled := false
+	for _, x := range dualStack {
+		if b.stringVal(x.addr) != "" {
+			dualStackEnabled = true
+		}
+	}
+	if dualStackEnabled {
+		for _, x := range dualStack {
+			ip := b.expandFirstIPFamily(x.name, x.addr, x.family)
+			if ip == nil && addressFamilies[x.family](x.def.IP) {
+				ip = x.def
+			}
+			switch {
+			case ip == nil:
+				continue
+			case ip.IP.IsUnspecified():
+				return RuntimeConfig{}, fmt.Errorf("%s cannot be 0.0.0.0, :: or [::]", x.name)
+			}
+			c.TaggedAddresses[x.tag] = ip.IP.String()
+		}
+	}
+
 	// segments
 	var segments []structs.NetworkSegment
 	for _, s := range c.Segments {
@@ -526,6 +687,33 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		})
 	}
 
+	// rpc routes
+	var rpcRoutes []structs.RPCRoute
+	for _, r := range c.RPCRoutes {
+		var via map[string]int
+		if len(r.Via) > 0 {
+			via = make(map[string]int, len(r.Via))
+			for dc, weight := range r.Via {
+				via[strings.ToLower(dc)] = weight
+			}
+		}
+		rpcRoutes = append(rpcRoutes, structs.RPCRoute{
+			Datacenter: strings.ToLower(b.stringVal(r.Datacenter)),
+			Via:        via,
+			Deny:       b.boolVal(r.Deny),
+		})
+	}
+
+	// server hostname policies
+	var serverHostnamePolicies []tlsutil.ServerHostnamePolicy
+	for _, p := range c.VerifyServerHostnamePolicies {
+		serverHostnamePolicies = append(serverHostnamePolicies, tlsutil.ServerHostnamePolicy{
+			Datacenter: strings.ToLower(b.stringVal(p.Datacenter)),
+			Names:      p.Names,
+			Exempt:     b.boolVal(p.Exempt),
+		})
+	}
+
 	// Parse the metric filters
 	var telemetryAllowedPrefixes, telemetryBlockedPrefixes []string
 	for _, rule := range c.Telemetry.PrefixFilter {
@@ -548,9 +736,26 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 	if performanceRaftMultiplier < 1 || uint(performanceRaftMultiplier) > consul.MaxRaftMultiplier {
 		return RuntimeConfig{}, fmt.Errorf("performance.raft_multiplier cannot be %d. Must be between 1 and %d", performanceRaftMultiplier, consul.MaxRaftMultiplier)
 	}
-	consulRaftElectionTimeout := b.durationVal("consul.raft.election_timeout", c.Consul.Raft.ElectionTimeout) * time.Duration(performanceRaftMultiplier)
+	// The election timeout can be scaled separately, so that servers on
+	// high-latency links wait longer before they start an election.
+	performanceRaftElectionMultiplier := performanceRaftMultiplier
+	if c.Performance.RaftElectionMultiplier != nil {
+		performanceRaftElectionMultiplier = b.intVal(c.Performance.RaftElectionMultiplier)
+		if performanceRaftElectionMultiplier < performanceRaftMultiplier || uint(performanceRaftElectionMultiplier) > consul.MaxRaftMultiplier {
+			return RuntimeConfig{}, fmt.Errorf("performance.raft_election_multiplier cannot be %d. Must be between performance.raft_multiplier (%d) and %d",
+				performanceRaftElectionMultiplier, performanceRaftMultiplier, consul.MaxRaftMultiplier)
+		}
+	}
+	consulRaftElectionTimeout := b.durationVal("consul.raft.election_timeout", c.Consul.Raft.ElectionTimeout) * time.Duration(performanceRaftElectionMultiplier)
 	consulRaftHeartbeatTimeout := b.durationVal("consul.raft.heartbeat_timeout", c.Consul.Raft.HeartbeatTimeout) * time.Duration(performanceRaftMultiplier)
 	consulRaftLeaderLeaseTimeout := b.durationVal("consul.raft.leader_lease_timeout", c.Consul.Raft.LeaderLeaseTimeout) * time.Duration(performanceRaftMultiplier)
+	if c.Performance.RaftLeaderLeaseTimeout != nil {
+		consulRaftLeaderLeaseTimeout = b.durationVal("performance.raft_leader_lease_timeout", c.Performance.RaftLeaderLeaseTimeout)
+		if consulRaftLeaderLeaseTimeout < 5*time.Millisecond || consulRaftLeaderLeaseTimeout > consulRaftHeartbeatTimeout {
+			return RuntimeConfig{}, fmt.Errorf("performance.raft_leader_lease_timeout cannot be %s. Must be between 5ms and the heartbeat timeout of %s",
+				consulRaftLeaderLeaseTimeout, consulRaftHeartbeatTimeout)
+		}
+	}
 
 	// Connect proxy defaults.
 	connectEnabled := b.boolVal(c.Connect.Enabled)
@@ -640,12 +845,14 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		ConsulServerHealthInterval:       b.durationVal("consul.server.health_interval", c.Consul.Server.HealthInterval),
 
 		// gossip configuration
+		GossipLANProfile:        gossipLANProfile,
 		GossipLANGossipInterval: b.durationVal("gossip_lan..gossip_interval", c.GossipLAN.GossipInterval),
 		GossipLANGossipNodes:    b.intVal(c.GossipLAN.GossipNodes),
 		GossipLANProbeInterval:  b.durationVal("gossip_lan..probe_interval", c.GossipLAN.ProbeInterval),
 		GossipLANProbeTimeout:   b.durationVal("gossip_lan..probe_timeout", c.GossipLAN.ProbeTimeout),
 		GossipLANSuspicionMult:  b.intVal(c.GossipLAN.SuspicionMult),
 		GossipLANRetransmitMult: b.intVal(c.GossipLAN.RetransmitMult),
+		GossipWANProfile:        gossipWANProfile,
 		GossipWANGossipInterval: b.durationVal("gossip_wan..gossip_interval", c.GossipWAN.GossipInterval),
 		GossipWANGossipNodes:    b.intVal(c.GossipWAN.GossipNodes),
 		GossipWANProbeInterval:  b.durationVal("gossip_wan..probe_interval", c.GossipWAN.ProbeInterval),
@@ -654,20 +861,26 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		GossipWANRetransmitMult: b.intVal(c.GossipWAN.RetransmitMult),
 
 		// ACL
-		ACLEnforceVersion8:     b.boolValWithDefault(c.ACLEnforceVersion8, true),
-		ACLsEnabled:            aclsEnabled,
-		ACLAgentMasterToken:    b.stringValWithDefault(c.ACL.Tokens.AgentMaster, b.stringVal(c.ACLAgentMasterToken)),
-		ACLAgentToken:          b.stringValWithDefault(c.ACL.Tokens.Agent, b.stringVal(c.ACLAgentToken)),
-		ACLDatacenter:          aclDC,
-		ACLDefaultPolicy:       b.stringValWithDefault(c.ACL.DefaultPolicy, b.stringVal(c.ACLDefaultPolicy)),
-		ACLDownPolicy:          b.stringValWithDefault(c.ACL.DownPolicy, b.stringVal(c.ACLDownPolicy)),
-		ACLEnableKeyListPolicy: b.boolValWithDefault(c.ACL.EnableKeyListPolicy, b.boolVal(c.ACLEnableKeyListPolicy)),
-		ACLMasterToken:         b.stringValWithDefault(c.ACL.Tokens.Master, b.stringVal(c.ACLMasterToken)),
-		ACLReplicationToken:    b.stringValWithDefault(c.ACL.Tokens.Replication, b.stringVal(c.ACLReplicationToken)),
-		ACLTokenTTL:            b.durationValWithDefault("acl.token_ttl", c.ACL.TokenTTL, b.durationVal("acl_ttl", c.ACLTTL)),
-		ACLPolicyTTL:           b.durationVal("acl.policy_ttl", c.ACL.PolicyTTL),
-		ACLToken:               b.stringValWithDefault(c.ACL.Tokens.Default, b.stringVal(c.ACLToken)),
-		ACLTokenReplication:    b.boolValWithDefault(c.ACL.TokenReplication, b.boolValWithDefault(c.EnableACLReplication, enableTokenReplication)),
+		ACLEnforceVersion8:               b.boolValWithDefault(c.ACLEnforceVersion8, true),
+		ACLsEnabled:                      aclsEnabled,
+		ACLAgentMasterToken:              b.stringValWithDefault(c.ACL.Tokens.AgentMaster, b.stringVal(c.ACLAgentMasterToken)),
+		ACLAgentToken:                    b.stringValWithDefault(c.ACL.Tokens.Agent, b.stringVal(c.ACLAgentToken)),
+		ACLBlockingQueryRecheckInterval:  b.durationVal("acl.blocking_query_recheck_interval", c.ACL.BlockingQueryRecheckInterval),
+		ACLBootstrapRateLimit:            rate.Limit(b.float64Val(c.Limits.ACLBootstrapRate)),
+		ACLBootstrapMaxBurst:             b.intVal(c.Limits.ACLBootstrapMaxBurst),
+		ACLDatacenter:                    aclDC,
+		ACLDefaultPolicy:                 b.stringValWithDefault(c.ACL.DefaultPolicy, b.stringVal(c.ACLDefaultPolicy)),
+		ACLDownPolicy:                    b.stringValWithDefault(c.ACL.DownPolicy, b.stringVal(c.ACLDownPolicy)),
+		ACLEnableDeniedPermissionDetails: b.boolVal(c.ACL.DeniedPermissionDetails),
+		ACLEnableKeyListPolicy:           b.boolValWithDefault(c.ACL.EnableKeyListPolicy, b.boolVal(c.ACLEnableKeyListPolicy)),
+		ACLEnableTokenPersistence:        b.boolVal(c.ACL.TokenPersistence),
+		ACLIntroductionToken:             b.stringVal(c.ACL.Tokens.Introduction),
+		ACLMasterToken:                   b.stringValWithDefault(c.ACL.Tokens.Master, b.stringVal(c.ACLMasterToken)),
+		ACLReplicationToken:              b.stringValWithDefault(c.ACL.Tokens.Replication, b.stringVal(c.ACLReplicationToken)),
+		ACLTokenTTL:                      b.durationValWithDefault("acl.token_ttl", c.ACL.TokenTTL, b.durationVal("acl_ttl", c.ACLTTL)),
+		ACLPolicyTTL:                     b.durationVal("acl.policy_ttl", c.ACL.PolicyTTL),
+		ACLToken:                         b.stringValWithDefault(c.ACL.Tokens.Default, b.stringVal(c.ACLToken)),
+		ACLTokenReplication:              b.boolValWithDefault(c.ACL.TokenReplication, b.boolValWithDefault(c.EnableACLReplication, enableTokenReplication)),
 
 		// Autopilot
 		AutopilotCleanupDeadServers:      b.boolVal(c.Autopilot.CleanupDeadServers),
@@ -679,22 +892,25 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		AutopilotUpgradeVersionTag:       b.stringVal(c.Autopilot.UpgradeVersionTag),
 
 		// DNS
-		DNSAddrs:              dnsAddrs,
-		DNSAllowStale:         b.boolVal(c.DNS.AllowStale),
-		DNSARecordLimit:       b.intVal(c.DNS.ARecordLimit),
-		DNSDisableCompression: b.boolVal(c.DNS.DisableCompression),
-		DNSDomain:             b.stringVal(c.DNSDomain),
-		DNSEnableTruncate:     b.boolVal(c.DNS.EnableTruncate),
-		DNSMaxStale:           b.durationVal("dns_config.max_stale", c.DNS.MaxStale),
-		DNSNodeTTL:            b.durationVal("dns_config.node_ttl", c.DNS.NodeTTL),
-		DNSOnlyPassing:        b.boolVal(c.DNS.OnlyPassing),
-		DNSPort:               dnsPort,
-		DNSRecursorTimeout:    b.durationVal("recursor_timeout", c.DNS.RecursorTimeout),
-		DNSRecursors:          dnsRecursors,
-		DNSServiceTTL:         dnsServiceTTL,
-		DNSSOA:                soa,
-		DNSUDPAnswerLimit:     b.intVal(c.DNS.UDPAnswerLimit),
-		DNSNodeMetaTXT:        b.boolValWithDefault(c.DNS.NodeMetaTXT, true),
+		DNSAddrs:                       dnsAddrs,
+		DNSAllowStale:                  b.boolVal(c.DNS.AllowStale),
+		DNSARecordLimit:                b.intVal(c.DNS.ARecordLimit),
+		DNSDisableCompression:          b.boolVal(c.DNS.DisableCompression),
+		DNSDomain:                      b.stringVal(c.DNSDomain),
+		DNSDomainRecursors:             dnsDomainRecursors,
+		DNSEnableTruncate:              b.boolVal(c.DNS.EnableTruncate),
+		DNSMaxStale:                    b.durationVal("dns_config.max_stale", c.DNS.MaxStale),
+		DNSNodeTTL:                     b.durationVal("dns_config.node_ttl", c.DNS.NodeTTL),
+		DNSOnlyPassing:                 b.boolVal(c.DNS.OnlyPassing),
+		DNSPort:                        dnsPort,
+		DNSRecursorHealthCheckInterval: b.durationVal("dns_config.recursor_health_check_interval", c.DNS.RecursorHealthCheckInterval),
+		DNSRecursorTimeout:             b.durationVal("recursor_timeout", c.DNS.RecursorTimeout),
+		DNSRecursors:                   dnsRecursors,
+		DNSServiceTTL:                  dnsServiceTTL,
+		DNSSOA:                         soa,
+		DNSUDPAnswerLimit:              b.intVal(c.DNS.UDPAnswerLimit),
+		DNSNodeMetaTXT:                 b.boolValWithDefault(c.DNS.NodeMetaTXT, true),
+		DNSPreparedQueryDatacenterTXT:  b.boolVal(c.DNS.PreparedQueryDatacenterTXT),
 
 		// HTTP
 		HTTPPort:            httpPort,
@@ -704,6 +920,11 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		HTTPBlockEndpoints:  c.HTTPConfig.BlockEndpoints,
 		HTTPResponseHeaders: c.HTTPConfig.ResponseHeaders,
 
+		HTTPClientCertAllowlistCommonNames: c.HTTPConfig.ClientCertAllowlist.CommonNames,
+		HTTPClientCertAllowlistOrgUnits:    c.HTTPConfig.ClientCertAllowlist.OrganizationalUnits,
+		HTTPClientCertAllowlistURIs:        c.HTTPConfig.ClientCertAllowlist.URIs,
+		HTTPClientCertAllowlistWriteOnly:   b.boolVal(c.HTTPConfig.ClientCertAllowlist.WriteOnly),
+
 		// Telemetry
 		Telemetry: lib.TelemetryConfig{
 			CirconusAPIApp:                     b.stringVal(c.Telemetry.CirconusAPIApp),
@@ -732,14 +953,22 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		},
 
 		// Agent
+		AERetryJitter:                           b.float64Val(c.Performance.AERetryJitter),
+		AERetryMaxInterval:                      b.durationVal("performance.ae_retry_max_interval", c.Performance.AERetryMaxInterval),
 		AdvertiseAddrLAN:                        advertiseAddrLAN,
 		AdvertiseAddrWAN:                        advertiseAddrWAN,
+		AgentProfile:                            b.stringVal(c.AgentProfile),
 		BindAddr:                                bindAddr,
 		Bootstrap:                               b.boolVal(c.Bootstrap),
 		BootstrapExpect:                         b.intVal(c.BootstrapExpect),
 		CAFile:                                  b.stringVal(c.CAFile),
 		CAPath:                                  b.stringVal(c.CAPath),
+		CRLFile:                                 b.stringVal(c.CRLFile),
+		CRLRefreshInterval:                      b.durationVal("crl_refresh_interval", c.CRLRefreshInterval),
+		CRLURL:                                  b.stringVal(c.CRLURL),
 		CertFile:                                b.stringVal(c.CertFile),
+		CheckIntervalJitter:                     b.intVal(c.CheckIntervalJitter),
+		CheckStateMaxAge:                        b.durationVal("check_state_max_age", c.CheckStateMaxAge),
 		CheckUpdateInterval:                     b.durationVal("check_update_interval", c.CheckUpdateInterval),
 		Checks:                                  checks,
 		ClientAddrs:                             clientAddrs,
@@ -759,6 +988,10 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		DataDir:                                 b.stringVal(c.DataDir),
 		Datacenter:                              datacenter,
 		DevMode:                                 b.boolVal(b.Flags.DevMode),
+		DevPersist:                              b.stringVal(b.Flags.DevPersist) != "",
+		DeregisterCriticalServiceAfterDefault:   b.durationVal("deregister_critical_service_after_default", c.DeregisterCriticalAfterDefault),
+		DeregisterCriticalServiceAfterMin:       b.durationVal("deregister_critical_service_after_min", c.DeregisterCriticalAfterMin),
+		DeregisterServicesOnShutdown:            b.boolVal(c.DeregisterServicesOnShutdown),
 		DisableAnonymousSignature:               b.boolVal(c.DisableAnonymousSignature),
 		DisableCoordinates:                      b.boolVal(c.DisableCoordinates),
 		DisableHostNodeID:                       b.boolVal(c.DisableHostNodeID),
@@ -770,15 +1003,23 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		DiscoveryMaxStale:                       b.durationVal("discovery_max_stale", c.DiscoveryMaxStale),
 		EnableAgentTLSForChecks:                 b.boolVal(c.EnableAgentTLSForChecks),
 		EnableDebug:                             b.boolVal(c.EnableDebug),
+		EnableFaultInjection:                    b.boolVal(c.EnableFaultInjection),
 		EnableRemoteScriptChecks:                enableRemoteScriptChecks,
 		EnableLocalScriptChecks:                 enableLocalScriptChecks,
 		EnableSyslog:                            b.boolVal(c.EnableSyslog),
 		EnableUI:                                b.boolVal(c.UI),
+		EnableWindowsEventLog:                   b.boolVal(c.EnableWindowsEventLog),
 		EncryptKey:                              b.stringVal(c.EncryptKey),
 		EncryptVerifyIncoming:                   b.boolVal(c.EncryptVerifyIncoming),
 		EncryptVerifyOutgoing:                   b.boolVal(c.EncryptVerifyOutgoing),
+		GossipKeyRotationInterval:               b.durationVal("gossip_key_rotation.interval", c.GossipKeyRotation.Interval),
+		GossipKeyRotationRetireAfter:            b.durationValWithDefault("gossip_key_rotation.retire_after", c.GossipKeyRotation.RetireAfter, time.Hour),
 		GRPCPort:                                grpcPort,
 		GRPCAddrs:                               grpcAddrs,
+		Hooks:                                   hooks,
+		KVRecycleBinRetention:                   b.durationVal("kv_recycle_bin_retention", c.KVRecycleBinRetention),
+		KVReplicationConflictPolicy:             b.stringValWithDefault(c.KVReplication.ConflictPolicy, structs.KVReplicationOverwrite),
+		KVReplicationPrefixes:                   c.KVReplication.Prefixes,
 		KeyFile:                                 b.stringVal(c.KeyFile),
 		LeaveDrainTime:                          b.durationVal("performance.leave_drain_time", c.Performance.LeaveDrainTime),
 		LeaveOnTerm:                             leaveOnTerm,
@@ -790,26 +1031,41 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		NodeMeta:                                c.NodeMeta,
 		NodeName:                                b.nodeName(c.NodeName),
 		NonVotingServer:                         b.boolVal(c.NonVotingServer),
+		OCSPStapling:                            b.boolVal(c.OCSPStapling),
 		PidFile:                                 b.stringVal(c.PidFile),
+		PreparedQuerySlowThreshold:              b.durationVal("prepared_query_slow_threshold", c.PreparedQuerySlowThreshold),
 		PrimaryDatacenter:                       primaryDatacenter,
 		RPCAdvertiseAddr:                        rpcAdvertiseAddr,
 		RPCBindAddr:                             rpcBindAddr,
 		RPCHoldTimeout:                          b.durationVal("performance.rpc_hold_timeout", c.Performance.RPCHoldTimeout),
 		RPCMaxBurst:                             b.intVal(c.Limits.RPCMaxBurst),
+		MaxQueryTime:                            b.durationVal("limits.max_query_time", c.Limits.MaxQueryTime),
+		DefaultQueryTime:                        b.durationVal("limits.default_query_time", c.Limits.DefaultQueryTime),
+		MaxBlockingQueriesPerToken:              b.intVal(c.Limits.MaxBlockingQueriesPerToken),
 		RPCProtocol:                             b.intVal(c.RPCProtocol),
 		RPCRateLimit:                            rate.Limit(b.float64Val(c.Limits.RPCRate)),
+		RPCRetryJitter:                          b.float64Val(c.Performance.RPCRetryJitter),
+		RPCRetryMaxInterval:                     b.durationVal("performance.rpc_retry_max_interval", c.Performance.RPCRetryMaxInterval),
+		RPCRoutes:                               rpcRoutes,
 		RaftProtocol:                            b.intVal(c.RaftProtocol),
 		RaftSnapshotThreshold:                   b.intVal(c.RaftSnapshotThreshold),
 		RaftSnapshotInterval:                    b.durationVal("raft_snapshot_interval", c.RaftSnapshotInterval),
 		ReconnectTimeoutLAN:                     b.durationVal("reconnect_timeout", c.ReconnectTimeoutLAN),
 		ReconnectTimeoutWAN:                     b.durationVal("reconnect_timeout_wan", c.ReconnectTimeoutWAN),
 		RejoinAfterLeave:                        b.boolVal(c.RejoinAfterLeave),
+		RequestLogSampleRate:                    b.float64Val(c.RequestLogging.SampleRate),
+		RequestLogSlowReadThreshold:             b.durationVal("request_logging.slow_read_threshold", c.RequestLogging.SlowReadThreshold),
+		RequestLogSlowWriteThreshold:            b.durationVal("request_logging.slow_write_threshold", c.RequestLogging.SlowWriteThreshold),
 		RetryJoinIntervalLAN:                    b.durationVal("retry_interval", c.RetryJoinIntervalLAN),
 		RetryJoinIntervalWAN:                    b.durationVal("retry_interval_wan", c.RetryJoinIntervalWAN),
 		RetryJoinLAN:                            b.expandAllOptionalAddrs("retry_join", c.RetryJoinLAN),
 		RetryJoinMaxAttemptsLAN:                 b.intVal(c.RetryJoinMaxAttemptsLAN),
 		RetryJoinMaxAttemptsWAN:                 b.intVal(c.RetryJoinMaxAttemptsWAN),
 		RetryJoinWAN:                            b.expandAllOptionalAddrs("retry_join_wan", c.RetryJoinWAN),
+		ScriptCheckCPUTime:                      b.durationVal("script_check_limits.cpu_time", c.ScriptCheckLimits.CPUTime),
+		ScriptCheckCgroup:                       b.stringVal(c.ScriptCheckLimits.Cgroup),
+		ScriptCheckMemoryBytes:                  b.intVal(c.ScriptCheckLimits.MemoryBytes),
+		ScriptCheckUser:                         b.stringVal(c.ScriptCheckLimits.User),
 		SegmentName:                             b.stringVal(c.SegmentName),
 		Segments:                                segments,
 		SerfAdvertiseAddrLAN:                    serfAdvertiseAddrLAN,
@@ -821,6 +1077,7 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		ServerMode:                              b.boolVal(c.ServerMode),
 		ServerName:                              b.stringVal(c.ServerName),
 		ServerPort:                              serverPort,
+		ServiceMetaDefaults:                     c.ServiceMetaDefaults,
 		Services:                                services,
 		SessionTTLMin:                           b.durationVal("session_ttl_min", c.SessionTTLMin),
 		SkipLeaveOnInt:                          skipLeaveOnInt,
@@ -831,16 +1088,23 @@ func (b *Builder) Build() (rt RuntimeConfig, err error) {
 		TLSMinVersion:                           b.stringVal(c.TLSMinVersion),
 		TLSPreferServerCipherSuites:             b.boolVal(c.TLSPreferServerCipherSuites),
 		TaggedAddresses:                         c.TaggedAddresses,
+		Templates:                               templates,
 		TranslateWANAddrs:                       b.boolVal(c.TranslateWANAddrs),
 		UIDir:                                   b.stringVal(c.UIDir),
+		UIMetricsProxyBaseURL:                   b.stringVal(c.UIMetricsProxy.BaseURL),
+		UIMetricsProxyPathAllowlist:             uiMetricsProxyPathAllowlist,
+		UIMetricsProxyAddHeaders:                c.UIMetricsProxy.AddHeaders,
 		UnixSocketGroup:                         b.stringVal(c.UnixSocket.Group),
 		UnixSocketMode:                          b.stringVal(c.UnixSocket.Mode),
 		UnixSocketUser:                          b.stringVal(c.UnixSocket.User),
 		VerifyIncoming:                          b.boolVal(c.VerifyIncoming),
 		VerifyIncomingHTTPS:                     b.boolVal(c.VerifyIncomingHTTPS),
 		VerifyIncomingRPC:                       b.boolVal(c.VerifyIncomingRPC),
+		VerifyOCSP:                              b.boolVal(c.VerifyOCSP),
 		VerifyOutgoing:                          b.boolVal(c.VerifyOutgoing),
 		VerifyServerHostname:                    b.boolVal(c.VerifyServerHostname),
+		VerifyServerHostnamePolicies:            serverHostnamePolicies,
+		VirtualIPCIDR:                           b.stringValWithDefault(c.VirtualIPCIDR, consul.DefaultVirtualIPCIDR),
 		Watches:                                 c.Watches,
 	}
 
@@ -868,6 +1132,9 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 	if !reDatacenter.MatchString(rt.Datacenter) {
 		return fmt.Errorf("datacenter cannot be %q. Please use only [a-z0-9-_].", rt.Datacenter)
 	}
+	if rt.DevPersist && !rt.DevMode {
+		return fmt.Errorf("-dev-persist requires -dev")
+	}
 	if rt.DataDir == "" && !rt.DevMode {
 		return fmt.Errorf("data_dir cannot be empty")
 	}
@@ -883,6 +1150,18 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 	if rt.NodeName == "" {
 		return fmt.Errorf("node_name cannot be empty")
 	}
+	if rt.AgentProfile != "" && !reProfileName.MatchString(rt.AgentProfile) {
+		return fmt.Errorf("agent_profile cannot be %q. Please use only [a-zA-Z0-9-_].", rt.AgentProfile)
+	}
+	if rt.ACLEnableTokenPersistence && rt.DataDir == "" {
+		return fmt.Errorf("acl.enable_token_persistence requires data_dir")
+	}
+	if rt.ACLIntroductionToken != "" && !rt.ACLEnableTokenPersistence {
+		return fmt.Errorf("acl.tokens.introduction requires acl.enable_token_persistence")
+	}
+	if rt.AgentProfile != "" && rt.DataDir == "" {
+		return fmt.Errorf("agent_profile requires data_dir to be set")
+	}
 	if ipaddr.IsAny(rt.AdvertiseAddrLAN.IP) {
 		return fmt.Errorf("Advertise address cannot be 0.0.0.0, :: or [::]")
 	}
@@ -892,16 +1171,91 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 	if err := b.validateSegments(rt); err != nil {
 		return err
 	}
+	if err := validateRPCRoutes(rt.Datacenter, rt.RPCRoutes); err != nil {
+		return err
+	}
+	if err := validateServerHostnamePolicies(rt.VerifyServerHostname, rt.VerifyServerHostnamePolicies); err != nil {
+		return err
+	}
+	if _, cidr, err := net.ParseCIDR(rt.VirtualIPCIDR); err != nil {
+		return fmt.Errorf("virtual_ip_cidr: %v", err)
+	} else if structs.VirtualIPMaxOffset(cidr) == 0 {
+		return fmt.Errorf("virtual_ip_cidr: %s has no room for virtual IPs", rt.VirtualIPCIDR)
+	}
+	if rt.ACLBootstrapRateLimit > 0 && rt.ACLBootstrapMaxBurst <= 0 {
+		return fmt.Errorf("limits.acl_bootstrap_max_burst must be positive, got %d", rt.ACLBootstrapMaxBurst)
+	}
+	if rt.HTTPClientCertAllowlistEnabled() && rt.CAFile == "" && rt.CAPath == "" {
+		return fmt.Errorf("http_config.client_cert_allowlist requires ca_file or ca_path to verify the client certificates")
+	}
+	if (rt.CRLFile != "" || rt.CRLURL != "" || rt.VerifyOCSP || rt.OCSPStapling) && rt.CAFile == "" && rt.CAPath == "" {
+		return fmt.Errorf("crl_file, crl_url, verify_ocsp and ocsp_stapling require ca_file or ca_path")
+	}
+	if (rt.CRLFile != "" || rt.CRLURL != "") && rt.CRLRefreshInterval <= 0 {
+		return fmt.Errorf("crl_refresh_interval must be positive, got %s", rt.CRLRefreshInterval)
+	}
+	if rt.OCSPStapling && rt.CertFile == "" {
+		return fmt.Errorf("ocsp_stapling requires cert_file")
+	}
 	for _, a := range rt.DNSAddrs {
 		if _, ok := a.(*net.UnixAddr); ok {
 			return fmt.Errorf("DNS address cannot be a unix socket")
 		}
 	}
+	for _, a := range append(rt.HTTPSAddrs, rt.GRPCAddrs...) {
+		if isPipeAddr(a) {
+			return fmt.Errorf("Only the HTTP address can be a named pipe")
+		}
+	}
 	for _, a := range rt.DNSRecursors {
 		if ipaddr.IsAny(a) {
 			return fmt.Errorf("DNS recursor address cannot be 0.0.0.0, :: or [::]")
 		}
 	}
+	for domain, recursors := range rt.DNSDomainRecursors {
+		if strings.Trim(domain, ".") == "" {
+			return fmt.Errorf("dns_config.domain_recursors cannot have an empty domain")
+		}
+		if len(recursors) == 0 {
+			return fmt.Errorf("dns_config.domain_recursors[%q] must have at least one recursor", domain)
+		}
+		for _, a := range recursors {
+			if ipaddr.IsAny(a) {
+				return fmt.Errorf("DNS recursor address cannot be 0.0.0.0, :: or [::]")
+			}
+		}
+	}
+	if rt.DNSRecursorHealthCheckInterval < 0 {
+		return fmt.Errorf("dns_config.recursor_health_check_interval cannot be negative")
+	}
+	if rt.ScriptCheckCPUTime < 0 {
+		return fmt.Errorf("script_check_limits.cpu_time cannot be negative")
+	}
+	if rt.ScriptCheckMemoryBytes < 0 {
+		return fmt.Errorf("script_check_limits.memory_bytes cannot be negative")
+	}
+	if rt.ACLBlockingQueryRecheckInterval < 0 {
+		return fmt.Errorf("acl.blocking_query_recheck_interval cannot be negative")
+	}
+	if rt.MaxQueryTime <= 0 {
+		return fmt.Errorf("limits.max_query_time must be positive, got %s", rt.MaxQueryTime)
+	}
+	if rt.DefaultQueryTime <= 0 {
+		return fmt.Errorf("limits.default_query_time must be positive, got %s", rt.DefaultQueryTime)
+	}
+	if rt.DefaultQueryTime > rt.MaxQueryTime {
+		return fmt.Errorf("limits.default_query_time (%s) cannot be larger than limits.max_query_time (%s)",
+			rt.DefaultQueryTime, rt.MaxQueryTime)
+	}
+	if rt.MaxBlockingQueriesPerToken < 0 {
+		return fmt.Errorf("limits.max_blocking_queries_per_token cannot be negative")
+	}
+	if rt.CheckStateMaxAge < 0 {
+		return fmt.Errorf("check_state_max_age cannot be negative")
+	}
+	if rt.CheckIntervalJitter < 0 || rt.CheckIntervalJitter > 100 {
+		return fmt.Errorf("check_interval_jitter cannot be %d. Must be between 0 and 100", rt.CheckIntervalJitter)
+	}
 	if rt.Bootstrap && !rt.ServerMode {
 		return fmt.Errorf("'bootstrap = true' requires 'server = true'")
 	}
@@ -932,15 +1286,73 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 				"If trying to use your own web UI resources, use the ui-dir flag.\n" +
 				"If using Consul version 0.7.0 or later, the web UI is included in the binary so use ui to enable it")
 	}
+	if rt.UIMetricsProxyBaseURL != "" {
+		u, err := url.Parse(rt.UIMetricsProxyBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ui_metrics_proxy.base_url must be a valid http or https URL, got %q", rt.UIMetricsProxyBaseURL)
+		}
+	}
+	for _, p := range rt.UIMetricsProxyPathAllowlist {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("ui_metrics_proxy.path_allowlist cannot contain %q. Paths must start with \"/\"", p)
+		}
+	}
 	if rt.DNSUDPAnswerLimit < 0 {
 		return fmt.Errorf("dns_config.udp_answer_limit cannot be %d. Must be greater than or equal to zero", rt.DNSUDPAnswerLimit)
 	}
 	if rt.DNSARecordLimit < 0 {
 		return fmt.Errorf("dns_config.a_record_limit cannot be %d. Must be greater than or equal to zero", rt.DNSARecordLimit)
 	}
+	if rt.DeregisterCriticalServiceAfterDefault < 0 {
+		return fmt.Errorf("deregister_critical_service_after_default cannot be %s. Must be greater than or equal to zero", rt.DeregisterCriticalServiceAfterDefault)
+	}
+	if rt.DeregisterCriticalServiceAfterMin < 0 {
+		return fmt.Errorf("deregister_critical_service_after_min cannot be %s. Must be greater than or equal to zero", rt.DeregisterCriticalServiceAfterMin)
+	}
+	if rt.DeregisterCriticalServiceAfterDefault > 0 && rt.DeregisterCriticalServiceAfterDefault < rt.DeregisterCriticalServiceAfterMin {
+		return fmt.Errorf("deregister_critical_service_after_default cannot be %s. Must be greater than or equal to deregister_critical_service_after_min (%s)",
+			rt.DeregisterCriticalServiceAfterDefault, rt.DeregisterCriticalServiceAfterMin)
+	}
+	if rt.GossipKeyRotationInterval < 0 {
+		return fmt.Errorf("gossip_key_rotation.interval cannot be %s. Must be greater than or equal to zero", rt.GossipKeyRotationInterval)
+	}
+	if rt.GossipKeyRotationInterval > 0 && (rt.GossipKeyRotationRetireAfter <= 0 || rt.GossipKeyRotationRetireAfter >= rt.GossipKeyRotationInterval) {
+		return fmt.Errorf("gossip_key_rotation.retire_after must be greater than zero and less than gossip_key_rotation.interval (%s), got %s",
+			rt.GossipKeyRotationInterval, rt.GossipKeyRotationRetireAfter)
+	}
+	if rt.KVRecycleBinRetention < 0 {
+		return fmt.Errorf("kv_recycle_bin_retention cannot be %s. Must be greater than or equal to zero", rt.KVRecycleBinRetention)
+	}
+	if err := validateKVReplication(rt.KVReplicationConflictPolicy, rt.KVReplicationPrefixes); err != nil {
+		return err
+	}
+	if rt.RPCRetryMaxInterval <= 0 {
+		return fmt.Errorf("performance.rpc_retry_max_interval cannot be %s. Must be greater than zero", rt.RPCRetryMaxInterval)
+	}
+	if rt.RPCRetryJitter < 0 || rt.RPCRetryJitter > 1 {
+		return fmt.Errorf("performance.rpc_retry_jitter must be between 0 and 1, got %v", rt.RPCRetryJitter)
+	}
+	if rt.AERetryMaxInterval <= 0 {
+		return fmt.Errorf("performance.ae_retry_max_interval cannot be %s. Must be greater than zero", rt.AERetryMaxInterval)
+	}
+	if rt.AERetryJitter < 0 || rt.AERetryJitter > 1 {
+		return fmt.Errorf("performance.ae_retry_jitter must be between 0 and 1, got %v", rt.AERetryJitter)
+	}
+	if rt.RequestLogSampleRate < 0 || rt.RequestLogSampleRate > 1 {
+		return fmt.Errorf("request_logging.sample_rate must be between 0 and 1, got %v", rt.RequestLogSampleRate)
+	}
+	if rt.RequestLogSlowReadThreshold < 0 {
+		return fmt.Errorf("request_logging.slow_read_threshold cannot be %s. Must be greater than or equal to zero", rt.RequestLogSlowReadThreshold)
+	}
+	if rt.RequestLogSlowWriteThreshold < 0 {
+		return fmt.Errorf("request_logging.slow_write_threshold cannot be %s. Must be greater than or equal to zero", rt.RequestLogSlowWriteThreshold)
+	}
 	if err := structs.ValidateMetadata(rt.NodeMeta, false); err != nil {
 		return fmt.Errorf("node_meta invalid: %v", err)
 	}
+	if err := structs.ValidateMetadata(rt.ServiceMetaDefaults, false); err != nil {
+		return fmt.Errorf("service_meta_defaults invalid: %v", err)
+	}
 	if rt.EncryptKey != "" {
 		if _, err := decodeBytes(rt.EncryptKey); err != nil {
 			return fmt.Errorf("encrypt has invalid key: %s", err)
@@ -1010,6 +1422,23 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 		}
 	}
 
+	// Check for errors in the hook definitions
+	for i, h := range rt.Hooks {
+		if err := validateHook(h); err != nil {
+			return fmt.Errorf("hooks[%d]: %s", i, err)
+		}
+	}
+
+	// Check for errors in the template definitions
+	for i, t := range rt.Templates {
+		switch {
+		case t.Source == "" || t.Destination == "":
+			return fmt.Errorf("templates[%d]: source and destination must be set", i)
+		case t.Command != "" && len(t.Args) > 0:
+			return fmt.Errorf("templates[%d]: only one of command or args can be set", i)
+		}
+	}
+
 	// Validate the given Connect CA provider config
 	validCAProviders := map[string]bool{
 		"":                       true,
@@ -1054,6 +1483,35 @@ func (b *Builder) Validate(rt RuntimeConfig) error {
 	return nil
 }
 
+// mergeSources parses the given config sources and merges them in order.
+func (b *Builder) mergeSources(srcs []Source) (Config, error) {
+	var c Config
+	for _, s := range srcs {
+		if s.Name == "" || s.Data == "" {
+			continue
+		}
+		c2, err := Parse(s.Data, s.Format)
+		if err != nil {
+			return Config{}, fmt.Errorf("Error parsing %s: %s", s.Name, err)
+		}
+
+		// if we have a single 'check' or 'service' we need to add them to the
+		// list of checks and services first since we cannot merge them
+		// generically and later values would clobber earlier ones.
+		if c2.Check != nil {
+			c2.Checks = append(c2.Checks, *c2.Check)
+			c2.Check = nil
+		}
+		if c2.Service != nil {
+			c2.Services = append(c2.Services, *c2.Service)
+			c2.Service = nil
+		}
+
+		c = Merge(c, c2)
+	}
+	return c, nil
+}
+
 // addrUnique checks if the given address is already in use for another
 // protocol.
 func addrUnique(inuse map[string]string, name string, addr net.Addr) error {
@@ -1065,6 +1523,29 @@ func addrUnique(inuse map[string]string, name string, addr net.Addr) error {
 	return nil
 }
 
+// validateHook returns an error if the hook has no or unknown events or
+// doesn't run exactly one of a command or an HTTP request.
+func validateHook(h RuntimeHookConfig) error {
+	if len(h.Events) == 0 {
+		return fmt.Errorf("events must not be empty")
+	}
+	for _, event := range h.Events {
+		if !lib.StrContains(HookEvents, event) {
+			return fmt.Errorf("invalid event %q, must be one of %s", event, strings.Join(HookEvents, ", "))
+		}
+	}
+	switch {
+	case len(h.Args) == 0 && h.HTTP == "":
+		return fmt.Errorf("one of args or http must be set")
+	case len(h.Args) > 0 && h.HTTP != "":
+		return fmt.Errorf("only one of args or http can be set")
+	}
+	if h.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+	return nil
+}
+
 // addrsUnique checks if any of the give addresses is already in use for
 // another protocol.
 func addrsUnique(inuse map[string]string, name string, addrs []net.Addr) error {
@@ -1130,6 +1611,37 @@ func (b *Builder) checkVal(v *CheckDefinition) *structs.CheckDefinition {
 	}
 }
 
+func (b *Builder) hookVal(i int, v *Hook) RuntimeHookConfig {
+	return RuntimeHookConfig{
+		Events:  v.Events,
+		Args:    v.Args,
+		HTTP:    b.stringVal(v.HTTP),
+		Method:  b.stringValWithDefault(v.Method, "POST"),
+		Header:  v.Header,
+		Payload: b.stringVal(v.Payload),
+		Timeout: b.durationValWithDefault(fmt.Sprintf("hooks[%d].timeout", i), v.Timeout, 10*time.Second),
+	}
+}
+
+func (b *Builder) templateVal(i int, v *Template) render.Template {
+	var perms uint64
+	if v.Perms != nil {
+		var err error
+		perms, err = strconv.ParseUint(*v.Perms, 8, 32)
+		if err != nil {
+			b.err = multierror.Append(b.err, fmt.Errorf("templates[%d].perms: invalid permissions: %q", i, *v.Perms))
+		}
+	}
+	return render.Template{
+		Source:         b.stringVal(v.Source),
+		Destination:    b.stringVal(v.Destination),
+		Perms:          os.FileMode(perms),
+		Command:        b.stringVal(v.Command),
+		Args:           v.Args,
+		CommandTimeout: b.durationVal(fmt.Sprintf("templates[%d].command_timeout", i), v.CommandTimeout),
+	}
+}
+
 func (b *Builder) serviceVal(v *ServiceDefinition) *structs.ServiceDefinition {
 	if v == nil {
 		return nil
@@ -1162,18 +1674,25 @@ func (b *Builder) serviceVal(v *ServiceDefinition) *structs.ServiceDefinition {
 	if err := structs.ValidateWeights(serviceWeights); err != nil {
 		b.err = multierror.Append(fmt.Errorf("Invalid weight definition for service %s: %s", b.stringVal(v.Name), err))
 	}
+
+	warmup := b.durationVal(fmt.Sprintf("service[%s].warmup", b.stringVal(v.Name)), v.Warmup)
+	if warmup < 0 {
+		b.err = multierror.Append(b.err, fmt.Errorf("Invalid warmup for service %s: must not be negative", b.stringVal(v.Name)))
+	}
 	return &structs.ServiceDefinition{
-		Kind:              b.serviceKindVal(v.Kind),
-		ID:                b.stringVal(v.ID),
-		Name:              b.stringVal(v.Name),
-		Tags:              v.Tags,
-		Address:           b.stringVal(v.Address),
-		Meta:              meta,
-		Port:              b.intVal(v.Port),
-		Token:             b.stringVal(v.Token),
-		EnableTagOverride: b.boolVal(v.EnableTagOverride),
-		Weights:           serviceWeights,
-		Checks:            checks,
+		Kind:                 b.serviceKindVal(v.Kind),
+		ID:                   b.stringVal(v.ID),
+		Name:                 b.stringVal(v.Name),
+		Tags:                 v.Tags,
+		Address:              b.stringVal(v.Address),
+		Meta:                 meta,
+		Port:                 b.intVal(v.Port),
+		Token:                b.stringVal(v.Token),
+		EnableTagOverride:    b.boolVal(v.EnableTagOverride),
+		DeregisterOnShutdown: v.DeregisterOnShutdown,
+		Warmup:               warmup,
+		Weights:              serviceWeights,
+		Checks:               checks,
 		// DEPRECATED (ProxyDestination) - don't populate deprecated field, just use
 		// it as a default below on read. Remove that when remofing ProxyDestination
 		Proxy:   b.serviceProxyVal(v.Proxy, v.ProxyDestination),
@@ -1203,14 +1722,20 @@ func (b *Builder) serviceProxyVal(v *ServiceProxy, deprecatedDest *string) *stru
 		return nil
 	}
 
-	return &structs.ConnectProxyConfig{
+	proxy := &structs.ConnectProxyConfig{
 		DestinationServiceName: b.stringVal(v.DestinationServiceName),
 		DestinationServiceID:   b.stringVal(v.DestinationServiceID),
 		LocalServiceAddress:    b.stringVal(v.LocalServiceAddress),
 		LocalServicePort:       b.intVal(v.LocalServicePort),
 		Config:                 v.Config,
 		Upstreams:              b.upstreamsVal(v.Upstreams),
+		Mode:                   structs.ProxyMode(b.stringVal(v.Mode)),
+	}
+	if v.TransparentProxy != nil {
+		proxy.TransparentProxy.OutboundListenerPort = b.intVal(v.TransparentProxy.OutboundListenerPort)
+		proxy.TransparentProxy.AllowedServices = v.TransparentProxy.AllowedServices
 	}
+	return proxy
 }
 
 func (b *Builder) upstreamsVal(v []Upstream) structs.Upstreams {
@@ -1384,6 +1909,8 @@ func (b *Builder) expandAddrs(name string, s *string) []net.Addr {
 		switch {
 		case strings.HasPrefix(a, "unix://"):
 			addrs = append(addrs, &net.UnixAddr{Name: a[len("unix://"):], Net: "unix"})
+		case strings.HasPrefix(a, "npipe://"):
+			addrs = append(addrs, &net.UnixAddr{Name: a[len("npipe://"):], Net: "npipe"})
 		default:
 			// net.ParseIP does not like '[::]'
 			ip := net.ParseIP(a)
@@ -1463,52 +1990,27 @@ func (b *Builder) expandIPs(name string, s *string) []*net.IPAddr {
 	return x
 }
 
-// expandFirstAddr expands the go-sockaddr template in s and returns the
-// first address which is either a *net.IPAddr or a *net.UnixAddr. If
-// the template expands to multiple addresses an error is set and nil
+// expandFirstIP expands the go-sockaddr template in s and returns the
+// address of the first alternative which evaluates to a single IP
+// address. If the template cannot be evaluated an error is set and nil
 // is returned.
-func (b *Builder) expandFirstAddr(name string, s *string) net.Addr {
-	if s == nil || *s == "" {
-		return nil
-	}
-
-	addrs := b.expandAddrs(name, s)
-	if len(addrs) == 0 {
-		return nil
-	}
-	if len(addrs) > 1 {
-		var x []string
-		for _, a := range addrs {
-			x = append(x, a.String())
-		}
-		b.err = multierror.Append(b.err, fmt.Errorf("%s: multiple addresses found: %s", name, strings.Join(x, " ")))
-		return nil
-	}
-	return addrs[0]
+func (b *Builder) expandFirstIP(name string, s *string) *net.IPAddr {
+	return b.expandFirstIPFamily(name, s, "")
 }
 
-// expandFirstIP expands the go-sockaddr template in s and returns the
-// first address if it is not a unix socket address. If the template
-// expands to multiple addresses an error is set and nil is returned.
-func (b *Builder) expandFirstIP(name string, s *string) *net.IPAddr {
+// expandFirstIPFamily works like expandFirstIP but only considers
+// addresses of the given address family.
+func (b *Builder) expandFirstIPFamily(name string, s *string, family string) *net.IPAddr {
 	if s == nil || *s == "" {
 		return nil
 	}
 
-	addr := b.expandFirstAddr(name, s)
-	if addr == nil {
-		return nil
-	}
-	switch a := addr.(type) {
-	case *net.IPAddr:
-		return a
-	case *net.UnixAddr:
-		b.err = multierror.Append(b.err, fmt.Errorf("%s cannot be a unix socket", name))
-		return nil
-	default:
-		b.err = multierror.Append(b.err, fmt.Errorf("%s has invalid address type %T", name, a))
+	ip, err := evalAddrTemplate(*s, family)
+	if err != nil {
+		b.err = multierror.Append(b.err, fmt.Errorf("%s: %s", name, err))
 		return nil
 	}
+	return ip
 }
 
 func (b *Builder) makeIPAddr(pri *net.IPAddr, sec *net.IPAddr) *net.IPAddr {
@@ -1579,6 +2081,37 @@ func (b *Builder) makeAddrs(pri []net.Addr, sec []*net.IPAddr, port int) []net.A
 	return x
 }
 
+// isPipeAddr returns true when the given address is a Windows named pipe.
+// Named pipes are stored as *net.UnixAddr with the "npipe" network.
+// expandDNSRecursors expands the go-sockaddr templates in the given
+// recursor addresses and removes duplicates.
+func expandDNSRecursors(recursors []string) ([]string, error) {
+	uniq := map[string]bool{}
+	addrs := []string{}
+	for _, r := range recursors {
+		x, err := template.Parse(r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid DNS recursor template %q: %s", r, err)
+		}
+		for _, addr := range strings.Fields(x) {
+			if strings.HasPrefix(addr, "unix://") {
+				return nil, fmt.Errorf("DNS Recursors cannot be unix sockets: %s", addr)
+			}
+			if uniq[addr] {
+				continue
+			}
+			uniq[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+func isPipeAddr(a net.Addr) bool {
+	x, ok := a.(*net.UnixAddr)
+	return ok && x.Net == "npipe"
+}
+
 // isUnixAddr returns true when the given address is a unix socket address type.
 func (b *Builder) isUnixAddr(a net.Addr) bool {
 	_, ok := a.(*net.UnixAddr)
@@ -1599,3 +2132,86 @@ func isUnixAddr(a net.Addr) bool {
 	_, ok := a.(*net.UnixAddr)
 	return ok
 }
+
+// validateKVReplication checks the conflict policy of the KV replication
+// and that the replicated prefixes don't overlap, since every prefix is
+// replicated on its own.
+func validateKVReplication(policy string, prefixes []string) error {
+	switch policy {
+	case structs.KVReplicationOverwrite, structs.KVReplicationKeepLocal:
+	default:
+		return fmt.Errorf("kv_replication.conflict_policy must be %q or %q, got %q",
+			structs.KVReplicationOverwrite, structs.KVReplicationKeepLocal, policy)
+	}
+	for i, p := range prefixes {
+		if p == "" {
+			return fmt.Errorf("kv_replication.prefixes cannot contain an empty prefix")
+		}
+		for _, other := range prefixes[i+1:] {
+			if strings.HasPrefix(p, other) || strings.HasPrefix(other, p) {
+				return fmt.Errorf("kv_replication.prefixes %q and %q overlap", p, other)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRPCRoutes checks that the RPC routes are unique, don't route the
+// local datacenter and either deny the requests or forward them via other
+// datacenters with positive weights.
+func validateRPCRoutes(local string, routes []structs.RPCRoute) error {
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		switch {
+		case r.Datacenter == "":
+			return fmt.Errorf("rpc_routes: datacenter cannot be empty")
+		case seen[r.Datacenter]:
+			return fmt.Errorf("rpc_routes: duplicate route for datacenter %q", r.Datacenter)
+		case r.Datacenter == local:
+			return fmt.Errorf("rpc_routes[%s]: cannot route the local datacenter", r.Datacenter)
+		case r.Deny && len(r.Via) > 0:
+			return fmt.Errorf("rpc_routes[%s]: cannot set both deny and via", r.Datacenter)
+		case !r.Deny && len(r.Via) == 0:
+			return fmt.Errorf("rpc_routes[%s]: one of deny or via must be set", r.Datacenter)
+		}
+		seen[r.Datacenter] = true
+
+		for dc, weight := range r.Via {
+			if dc == local {
+				return fmt.Errorf("rpc_routes[%s]: cannot route via the local datacenter", r.Datacenter)
+			}
+			if weight <= 0 {
+				return fmt.Errorf("rpc_routes[%s]: weight of %q must be positive", r.Datacenter, dc)
+			}
+		}
+	}
+	return nil
+}
+
+// validateServerHostnamePolicies checks that the server hostname policies
+// are only used with hostname verification, are unique per datacenter and
+// either exempt the datacenter or list valid server names.
+func validateServerHostnamePolicies(verify bool, policies []tlsutil.ServerHostnamePolicy) error {
+	if len(policies) > 0 && !verify {
+		return fmt.Errorf("verify_server_hostname_policies requires verify_server_hostname")
+	}
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		switch {
+		case p.Datacenter == "":
+			return fmt.Errorf("verify_server_hostname_policies: datacenter cannot be empty")
+		case seen[p.Datacenter]:
+			return fmt.Errorf("verify_server_hostname_policies: duplicate policy for datacenter %q", p.Datacenter)
+		case p.Exempt && len(p.Names) > 0:
+			return fmt.Errorf("verify_server_hostname_policies[%s]: cannot set both exempt and names", p.Datacenter)
+		}
+		seen[p.Datacenter] = true
+
+		for _, name := range p.Names {
+			if name == "" || strings.Contains(strings.TrimPrefix(name, "*."), "*") {
+				return fmt.Errorf("verify_server_hostname_policies[%s]: invalid name %q, only a leading \"*.\" wildcard is supported", p.Datacenter, name)
+			}
+		}
+	}
+	return nil
+}
@@ -79,11 +79,15 @@ func Parse(data string, format string) (c Config, err error) {
 	// todo(fs): but this approach works for now.
 	m := patchSliceOfMaps(raw, []string{
 		"checks",
+		"rpc_routes",
+		"verify_server_hostname_policies",
 		"segments",
 		"service.checks",
 		"services",
 		"services.checks",
 		"watches",
+		"hooks",
+		"templates",
 		"service.connect.proxy.config.upstreams", // Deprecated
 		"services.connect.proxy.config.upstreams", // Deprecated
 		"service.connect.proxy.upstreams",
@@ -173,14 +177,26 @@ type Config struct {
 	Addresses                        Addresses                `json:"addresses,omitempty" hcl:"addresses" mapstructure:"addresses"`
 	AdvertiseAddrLAN                 *string                  `json:"advertise_addr,omitempty" hcl:"advertise_addr" mapstructure:"advertise_addr"`
 	AdvertiseAddrWAN                 *string                  `json:"advertise_addr_wan,omitempty" hcl:"advertise_addr_wan" mapstructure:"advertise_addr_wan"`
+	AdvertiseAddrLANFamily           *string                  `json:"advertise_addr_family,omitempty" hcl:"advertise_addr_family" mapstructure:"advertise_addr_family"`
+	AdvertiseAddrWANFamily           *string                  `json:"advertise_addr_wan_family,omitempty" hcl:"advertise_addr_wan_family" mapstructure:"advertise_addr_wan_family"`
+	AdvertiseAddrLANIPv4             *string                  `json:"advertise_addr_ipv4,omitempty" hcl:"advertise_addr_ipv4" mapstructure:"advertise_addr_ipv4"`
+	AdvertiseAddrLANIPv6             *string                  `json:"advertise_addr_ipv6,omitempty" hcl:"advertise_addr_ipv6" mapstructure:"advertise_addr_ipv6"`
+	AdvertiseAddrWANIPv4             *string                  `json:"advertise_addr_wan_ipv4,omitempty" hcl:"advertise_addr_wan_ipv4" mapstructure:"advertise_addr_wan_ipv4"`
+	AdvertiseAddrWANIPv6             *string                  `json:"advertise_addr_wan_ipv6,omitempty" hcl:"advertise_addr_wan_ipv6" mapstructure:"advertise_addr_wan_ipv6"`
+	AgentProfile                     *string                  `json:"agent_profile,omitempty" hcl:"agent_profile" mapstructure:"agent_profile"`
 	Autopilot                        Autopilot                `json:"autopilot,omitempty" hcl:"autopilot" mapstructure:"autopilot"`
 	BindAddr                         *string                  `json:"bind_addr,omitempty" hcl:"bind_addr" mapstructure:"bind_addr"`
 	Bootstrap                        *bool                    `json:"bootstrap,omitempty" hcl:"bootstrap" mapstructure:"bootstrap"`
 	BootstrapExpect                  *int                     `json:"bootstrap_expect,omitempty" hcl:"bootstrap_expect" mapstructure:"bootstrap_expect"`
 	CAFile                           *string                  `json:"ca_file,omitempty" hcl:"ca_file" mapstructure:"ca_file"`
 	CAPath                           *string                  `json:"ca_path,omitempty" hcl:"ca_path" mapstructure:"ca_path"`
+	CRLFile                          *string                  `json:"crl_file,omitempty" hcl:"crl_file" mapstructure:"crl_file"`
+	CRLRefreshInterval               *string                  `json:"crl_refresh_interval,omitempty" hcl:"crl_refresh_interval" mapstructure:"crl_refresh_interval"`
+	CRLURL                           *string                  `json:"crl_url,omitempty" hcl:"crl_url" mapstructure:"crl_url"`
 	CertFile                         *string                  `json:"cert_file,omitempty" hcl:"cert_file" mapstructure:"cert_file"`
 	Check                            *CheckDefinition         `json:"check,omitempty" hcl:"check" mapstructure:"check"` // needs to be a pointer to avoid partial merges
+	CheckIntervalJitter              *int                     `json:"check_interval_jitter,omitempty" hcl:"check_interval_jitter" mapstructure:"check_interval_jitter"`
+	CheckStateMaxAge                 *string                  `json:"check_state_max_age,omitempty" hcl:"check_state_max_age" mapstructure:"check_state_max_age"`
 	CheckUpdateInterval              *string                  `json:"check_update_interval,omitempty" hcl:"check_update_interval" mapstructure:"check_update_interval"`
 	Checks                           []CheckDefinition        `json:"checks,omitempty" hcl:"checks" mapstructure:"checks"`
 	ClientAddr                       *string                  `json:"client_addr,omitempty" hcl:"client_addr" mapstructure:"client_addr"`
@@ -190,6 +206,9 @@ type Config struct {
 	DNSRecursors                     []string                 `json:"recursors,omitempty" hcl:"recursors" mapstructure:"recursors"`
 	DataDir                          *string                  `json:"data_dir,omitempty" hcl:"data_dir" mapstructure:"data_dir"`
 	Datacenter                       *string                  `json:"datacenter,omitempty" hcl:"datacenter" mapstructure:"datacenter"`
+	DeregisterCriticalAfterDefault   *string                  `json:"deregister_critical_service_after_default,omitempty" hcl:"deregister_critical_service_after_default" mapstructure:"deregister_critical_service_after_default"`
+	DeregisterCriticalAfterMin       *string                  `json:"deregister_critical_service_after_min,omitempty" hcl:"deregister_critical_service_after_min" mapstructure:"deregister_critical_service_after_min"`
+	DeregisterServicesOnShutdown     *bool                    `json:"deregister_services_on_shutdown,omitempty" hcl:"deregister_services_on_shutdown" mapstructure:"deregister_services_on_shutdown"`
 	DisableAnonymousSignature        *bool                    `json:"disable_anonymous_signature,omitempty" hcl:"disable_anonymous_signature" mapstructure:"disable_anonymous_signature"`
 	DisableCoordinates               *bool                    `json:"disable_coordinates,omitempty" hcl:"disable_coordinates" mapstructure:"disable_coordinates"`
 	DisableHostNodeID                *bool                    `json:"disable_host_node_id,omitempty" hcl:"disable_host_node_id" mapstructure:"disable_host_node_id"`
@@ -202,15 +221,21 @@ type Config struct {
 	EnableACLReplication             *bool                    `json:"enable_acl_replication,omitempty" hcl:"enable_acl_replication" mapstructure:"enable_acl_replication"`
 	EnableAgentTLSForChecks          *bool                    `json:"enable_agent_tls_for_checks,omitempty" hcl:"enable_agent_tls_for_checks" mapstructure:"enable_agent_tls_for_checks"`
 	EnableDebug                      *bool                    `json:"enable_debug,omitempty" hcl:"enable_debug" mapstructure:"enable_debug"`
+	EnableFaultInjection             *bool                    `json:"enable_fault_injection,omitempty" hcl:"enable_fault_injection" mapstructure:"enable_fault_injection"`
 	EnableScriptChecks               *bool                    `json:"enable_script_checks,omitempty" hcl:"enable_script_checks" mapstructure:"enable_script_checks"`
 	EnableLocalScriptChecks          *bool                    `json:"enable_local_script_checks,omitempty" hcl:"enable_local_script_checks" mapstructure:"enable_local_script_checks"`
 	EnableSyslog                     *bool                    `json:"enable_syslog,omitempty" hcl:"enable_syslog" mapstructure:"enable_syslog"`
+	EnableWindowsEventLog            *bool                    `json:"enable_windows_event_log,omitempty" hcl:"enable_windows_event_log" mapstructure:"enable_windows_event_log"`
 	EncryptKey                       *string                  `json:"encrypt,omitempty" hcl:"encrypt" mapstructure:"encrypt"`
 	EncryptVerifyIncoming            *bool                    `json:"encrypt_verify_incoming,omitempty" hcl:"encrypt_verify_incoming" mapstructure:"encrypt_verify_incoming"`
 	EncryptVerifyOutgoing            *bool                    `json:"encrypt_verify_outgoing,omitempty" hcl:"encrypt_verify_outgoing" mapstructure:"encrypt_verify_outgoing"`
+	GossipKeyRotation                GossipKeyRotation        `json:"gossip_key_rotation,omitempty" hcl:"gossip_key_rotation" mapstructure:"gossip_key_rotation"`
 	GossipLAN                        GossipLANConfig          `json:"gossip_lan,omitempty" hcl:"gossip_lan" mapstructure:"gossip_lan"`
 	GossipWAN                        GossipWANConfig          `json:"gossip_wan,omitempty" hcl:"gossip_wan" mapstructure:"gossip_wan"`
 	HTTPConfig                       HTTPConfig               `json:"http_config,omitempty" hcl:"http_config" mapstructure:"http_config"`
+	Hooks                            []Hook                   `json:"hooks,omitempty" hcl:"hooks" mapstructure:"hooks"`
+	KVRecycleBinRetention            *string                  `json:"kv_recycle_bin_retention,omitempty" hcl:"kv_recycle_bin_retention" mapstructure:"kv_recycle_bin_retention"`
+	KVReplication                    KVReplication            `json:"kv_replication,omitempty" hcl:"kv_replication" mapstructure:"kv_replication"`
 	KeyFile                          *string                  `json:"key_file,omitempty" hcl:"key_file" mapstructure:"key_file"`
 	LeaveOnTerm                      *bool                    `json:"leave_on_terminate,omitempty" hcl:"leave_on_terminate" mapstructure:"leave_on_terminate"`
 	Limits                           Limits                   `json:"limits,omitempty" hcl:"limits" mapstructure:"limits"`
@@ -222,9 +247,11 @@ type Config struct {
 	NodeMeta                         map[string]string        `json:"node_meta,omitempty" hcl:"node_meta" mapstructure:"node_meta"`
 	NodeName                         *string                  `json:"node_name,omitempty" hcl:"node_name" mapstructure:"node_name"`
 	NonVotingServer                  *bool                    `json:"non_voting_server,omitempty" hcl:"non_voting_server" mapstructure:"non_voting_server"`
+	OCSPStapling                     *bool                    `json:"ocsp_stapling,omitempty" hcl:"ocsp_stapling" mapstructure:"ocsp_stapling"`
 	Performance                      Performance              `json:"performance,omitempty" hcl:"performance" mapstructure:"performance"`
 	PidFile                          *string                  `json:"pid_file,omitempty" hcl:"pid_file" mapstructure:"pid_file"`
 	Ports                            Ports                    `json:"ports,omitempty" hcl:"ports" mapstructure:"ports"`
+	PreparedQuerySlowThreshold       *string                  `json:"prepared_query_slow_threshold,omitempty" hcl:"prepared_query_slow_threshold" mapstructure:"prepared_query_slow_threshold"`
 	PrimaryDatacenter                *string                  `json:"primary_datacenter,omitempty" hcl:"primary_datacenter" mapstructure:"primary_datacenter"`
 	RPCProtocol                      *int                     `json:"protocol,omitempty" hcl:"protocol" mapstructure:"protocol"`
 	RaftProtocol                     *int                     `json:"raft_protocol,omitempty" hcl:"raft_protocol" mapstructure:"raft_protocol"`
@@ -233,12 +260,15 @@ type Config struct {
 	ReconnectTimeoutLAN              *string                  `json:"reconnect_timeout,omitempty" hcl:"reconnect_timeout" mapstructure:"reconnect_timeout"`
 	ReconnectTimeoutWAN              *string                  `json:"reconnect_timeout_wan,omitempty" hcl:"reconnect_timeout_wan" mapstructure:"reconnect_timeout_wan"`
 	RejoinAfterLeave                 *bool                    `json:"rejoin_after_leave,omitempty" hcl:"rejoin_after_leave" mapstructure:"rejoin_after_leave"`
+	RequestLogging                   RequestLogging           `json:"request_logging,omitempty" hcl:"request_logging" mapstructure:"request_logging"`
 	RetryJoinIntervalLAN             *string                  `json:"retry_interval,omitempty" hcl:"retry_interval" mapstructure:"retry_interval"`
 	RetryJoinIntervalWAN             *string                  `json:"retry_interval_wan,omitempty" hcl:"retry_interval_wan" mapstructure:"retry_interval_wan"`
 	RetryJoinLAN                     []string                 `json:"retry_join,omitempty" hcl:"retry_join" mapstructure:"retry_join"`
 	RetryJoinMaxAttemptsLAN          *int                     `json:"retry_max,omitempty" hcl:"retry_max" mapstructure:"retry_max"`
 	RetryJoinMaxAttemptsWAN          *int                     `json:"retry_max_wan,omitempty" hcl:"retry_max_wan" mapstructure:"retry_max_wan"`
 	RetryJoinWAN                     []string                 `json:"retry_join_wan,omitempty" hcl:"retry_join_wan" mapstructure:"retry_join_wan"`
+	RPCRoutes                        []RPCRoute               `json:"rpc_routes,omitempty" hcl:"rpc_routes" mapstructure:"rpc_routes"`
+	ScriptCheckLimits                ScriptCheckLimits        `json:"script_check_limits,omitempty" hcl:"script_check_limits" mapstructure:"script_check_limits"`
 	SegmentName                      *string                  `json:"segment,omitempty" hcl:"segment" mapstructure:"segment"`
 	Segments                         []Segment                `json:"segments,omitempty" hcl:"segments" mapstructure:"segments"`
 	SerfBindAddrLAN                  *string                  `json:"serf_lan,omitempty" hcl:"serf_lan" mapstructure:"serf_lan"`
@@ -246,6 +276,7 @@ type Config struct {
 	ServerMode                       *bool                    `json:"server,omitempty" hcl:"server" mapstructure:"server"`
 	ServerName                       *string                  `json:"server_name,omitempty" hcl:"server_name" mapstructure:"server_name"`
 	Service                          *ServiceDefinition       `json:"service,omitempty" hcl:"service" mapstructure:"service"`
+	ServiceMetaDefaults              map[string]string        `json:"service_meta_defaults,omitempty" hcl:"service_meta_defaults" mapstructure:"service_meta_defaults"`
 	Services                         []ServiceDefinition      `json:"services,omitempty" hcl:"services" mapstructure:"services"`
 	SessionTTLMin                    *string                  `json:"session_ttl_min,omitempty" hcl:"session_ttl_min" mapstructure:"session_ttl_min"`
 	SkipLeaveOnInt                   *bool                    `json:"skip_leave_on_interrupt,omitempty" hcl:"skip_leave_on_interrupt" mapstructure:"skip_leave_on_interrupt"`
@@ -257,15 +288,20 @@ type Config struct {
 	TLSPreferServerCipherSuites      *bool                    `json:"tls_prefer_server_cipher_suites,omitempty" hcl:"tls_prefer_server_cipher_suites" mapstructure:"tls_prefer_server_cipher_suites"`
 	TaggedAddresses                  map[string]string        `json:"tagged_addresses,omitempty" hcl:"tagged_addresses" mapstructure:"tagged_addresses"`
 	Telemetry                        Telemetry                `json:"telemetry,omitempty" hcl:"telemetry" mapstructure:"telemetry"`
+	Templates                        []Template               `json:"templates,omitempty" hcl:"templates" mapstructure:"templates"`
 	TranslateWANAddrs                *bool                    `json:"translate_wan_addrs,omitempty" hcl:"translate_wan_addrs" mapstructure:"translate_wan_addrs"`
 	UI                               *bool                    `json:"ui,omitempty" hcl:"ui" mapstructure:"ui"`
 	UIDir                            *string                  `json:"ui_dir,omitempty" hcl:"ui_dir" mapstructure:"ui_dir"`
+	UIMetricsProxy                   UIMetricsProxy           `json:"ui_metrics_proxy,omitempty" hcl:"ui_metrics_proxy" mapstructure:"ui_metrics_proxy"`
 	UnixSocket                       UnixSocket               `json:"unix_sockets,omitempty" hcl:"unix_sockets" mapstructure:"unix_sockets"`
 	VerifyIncoming                   *bool                    `json:"verify_incoming,omitempty" hcl:"verify_incoming" mapstructure:"verify_incoming"`
 	VerifyIncomingHTTPS              *bool                    `json:"verify_incoming_https,omitempty" hcl:"verify_incoming_https" mapstructure:"verify_incoming_https"`
 	VerifyIncomingRPC                *bool                    `json:"verify_incoming_rpc,omitempty" hcl:"verify_incoming_rpc" mapstructure:"verify_incoming_rpc"`
+	VerifyOCSP                       *bool                    `json:"verify_ocsp,omitempty" hcl:"verify_ocsp" mapstructure:"verify_ocsp"`
 	VerifyOutgoing                   *bool                    `json:"verify_outgoing,omitempty" hcl:"verify_outgoing" mapstructure:"verify_outgoing"`
 	VerifyServerHostname             *bool                    `json:"verify_server_hostname,omitempty" hcl:"verify_server_hostname" mapstructure:"verify_server_hostname"`
+	VerifyServerHostnamePolicies     []ServerHostnamePolicy   `json:"verify_server_hostname_policies,omitempty" hcl:"verify_server_hostname_policies" mapstructure:"verify_server_hostname_policies"`
+	VirtualIPCIDR                    *string                  `json:"virtual_ip_cidr,omitempty" hcl:"virtual_ip_cidr" mapstructure:"virtual_ip_cidr"`
 	Watches                          []map[string]interface{} `json:"watches,omitempty" hcl:"watches" mapstructure:"watches"`
 
 	// This isn't used by Consul but we've documented a feature where users
@@ -291,6 +327,7 @@ type Config struct {
 }
 
 type GossipLANConfig struct {
+	Profile        *string `json:"profile,omitempty" hcl:"profile" mapstructure:"profile"`
 	GossipNodes    *int    `json:"gossip_nodes,omitempty" hcl:"gossip_nodes" mapstructure:"gossip_nodes"`
 	GossipInterval *string `json:"gossip_interval,omitempty" hcl:"gossip_interval" mapstructure:"gossip_interval"`
 	ProbeInterval  *string `json:"probe_interval,omitempty" hcl:"probe_interval" mapstructure:"probe_interval"`
@@ -299,7 +336,13 @@ type GossipLANConfig struct {
 	RetransmitMult *int    `json:"retransmit_mult,omitempty" hcl:"retransmit_mult" mapstructure:"retransmit_mult"`
 }
 
+type GossipKeyRotation struct {
+	Interval    *string `json:"interval,omitempty" hcl:"interval" mapstructure:"interval"`
+	RetireAfter *string `json:"retire_after,omitempty" hcl:"retire_after" mapstructure:"retire_after"`
+}
+
 type GossipWANConfig struct {
+	Profile        *string `json:"profile,omitempty" hcl:"profile" mapstructure:"profile"`
 	GossipNodes    *int    `json:"gossip_nodes,omitempty" hcl:"gossip_nodes" mapstructure:"gossip_nodes"`
 	GossipInterval *string `json:"gossip_interval,omitempty" hcl:"gossip_interval" mapstructure:"gossip_interval"`
 	ProbeInterval  *string `json:"probe_interval,omitempty" hcl:"probe_interval" mapstructure:"probe_interval"`
@@ -356,18 +399,20 @@ type ServiceWeights struct {
 }
 
 type ServiceDefinition struct {
-	Kind              *string           `json:"kind,omitempty" hcl:"kind" mapstructure:"kind"`
-	ID                *string           `json:"id,omitempty" hcl:"id" mapstructure:"id"`
-	Name              *string           `json:"name,omitempty" hcl:"name" mapstructure:"name"`
-	Tags              []string          `json:"tags,omitempty" hcl:"tags" mapstructure:"tags"`
-	Address           *string           `json:"address,omitempty" hcl:"address" mapstructure:"address"`
-	Meta              map[string]string `json:"meta,omitempty" hcl:"meta" mapstructure:"meta"`
-	Port              *int              `json:"port,omitempty" hcl:"port" mapstructure:"port"`
-	Check             *CheckDefinition  `json:"check,omitempty" hcl:"check" mapstructure:"check"`
-	Checks            []CheckDefinition `json:"checks,omitempty" hcl:"checks" mapstructure:"checks"`
-	Token             *string           `json:"token,omitempty" hcl:"token" mapstructure:"token"`
-	Weights           *ServiceWeights   `json:"weights,omitempty" hcl:"weights" mapstructure:"weights"`
-	EnableTagOverride *bool             `json:"enable_tag_override,omitempty" hcl:"enable_tag_override" mapstructure:"enable_tag_override"`
+	Kind                 *string           `json:"kind,omitempty" hcl:"kind" mapstructure:"kind"`
+	ID                   *string           `json:"id,omitempty" hcl:"id" mapstructure:"id"`
+	Name                 *string           `json:"name,omitempty" hcl:"name" mapstructure:"name"`
+	Tags                 []string          `json:"tags,omitempty" hcl:"tags" mapstructure:"tags"`
+	Address              *string           `json:"address,omitempty" hcl:"address" mapstructure:"address"`
+	Meta                 map[string]string `json:"meta,omitempty" hcl:"meta" mapstructure:"meta"`
+	Port                 *int              `json:"port,omitempty" hcl:"port" mapstructure:"port"`
+	Check                *CheckDefinition  `json:"check,omitempty" hcl:"check" mapstructure:"check"`
+	Checks               []CheckDefinition `json:"checks,omitempty" hcl:"checks" mapstructure:"checks"`
+	Token                *string           `json:"token,omitempty" hcl:"token" mapstructure:"token"`
+	Weights              *ServiceWeights   `json:"weights,omitempty" hcl:"weights" mapstructure:"weights"`
+	EnableTagOverride    *bool             `json:"enable_tag_override,omitempty" hcl:"enable_tag_override" mapstructure:"enable_tag_override"`
+	DeregisterOnShutdown *bool             `json:"deregister_on_shutdown,omitempty" hcl:"deregister_on_shutdown" mapstructure:"deregister_on_shutdown"`
+	Warmup               *string           `json:"warmup,omitempty" hcl:"warmup" mapstructure:"warmup"`
 	// DEPRECATED (ProxyDestination) - remove this when removing ProxyDestination
 	ProxyDestination *string         `json:"proxy_destination,omitempty" hcl:"proxy_destination" mapstructure:"proxy_destination"`
 	Proxy            *ServiceProxy   `json:"proxy,omitempty" hcl:"proxy" mapstructure:"proxy"`
@@ -458,6 +503,25 @@ type ServiceProxy struct {
 	// Upstreams describes any upstream dependencies the proxy instance should
 	// setup.
 	Upstreams []Upstream `json:"upstreams,omitempty" hcl:"upstreams" mapstructure:"upstreams"`
+
+	// Mode is how the application reaches its upstreams, "direct" or
+	// "transparent".
+	Mode *string `json:"mode,omitempty" hcl:"mode" mapstructure:"mode"`
+
+	// TransparentProxy is the configuration of the transparent mode.
+	TransparentProxy *TransparentProxyConfig `json:"transparent_proxy,omitempty" hcl:"transparent_proxy" mapstructure:"transparent_proxy"`
+}
+
+// TransparentProxyConfig is the configuration of a proxy in the transparent
+// mode.
+type TransparentProxyConfig struct {
+	// OutboundListenerPort is the port the outbound traffic of the application
+	// is redirected to.
+	OutboundListenerPort *int `json:"outbound_listener_port,omitempty" hcl:"outbound_listener_port" mapstructure:"outbound_listener_port"`
+
+	// AllowedServices are the services the application may reach through the
+	// proxy.
+	AllowedServices []string `json:"allowed_services,omitempty" hcl:"allowed_services" mapstructure:"allowed_services"`
 }
 
 // Upstream represents a single upstream dependency for a service or proxy. It
@@ -544,29 +608,65 @@ type SOA struct {
 }
 
 type DNS struct {
-	AllowStale         *bool             `json:"allow_stale,omitempty" hcl:"allow_stale" mapstructure:"allow_stale"`
-	ARecordLimit       *int              `json:"a_record_limit,omitempty" hcl:"a_record_limit" mapstructure:"a_record_limit"`
-	DisableCompression *bool             `json:"disable_compression,omitempty" hcl:"disable_compression" mapstructure:"disable_compression"`
-	EnableTruncate     *bool             `json:"enable_truncate,omitempty" hcl:"enable_truncate" mapstructure:"enable_truncate"`
-	MaxStale           *string           `json:"max_stale,omitempty" hcl:"max_stale" mapstructure:"max_stale"`
-	NodeTTL            *string           `json:"node_ttl,omitempty" hcl:"node_ttl" mapstructure:"node_ttl"`
-	OnlyPassing        *bool             `json:"only_passing,omitempty" hcl:"only_passing" mapstructure:"only_passing"`
-	RecursorTimeout    *string           `json:"recursor_timeout,omitempty" hcl:"recursor_timeout" mapstructure:"recursor_timeout"`
-	ServiceTTL         map[string]string `json:"service_ttl,omitempty" hcl:"service_ttl" mapstructure:"service_ttl"`
-	UDPAnswerLimit     *int              `json:"udp_answer_limit,omitempty" hcl:"udp_answer_limit" mapstructure:"udp_answer_limit"`
-	NodeMetaTXT        *bool             `json:"enable_additional_node_meta_txt,omitempty" hcl:"enable_additional_node_meta_txt" mapstructure:"enable_additional_node_meta_txt"`
-	SOA                *SOA              `json:"soa,omitempty" hcl:"soa" mapstructure:"soa"`
+	AllowStale                  *bool               `json:"allow_stale,omitempty" hcl:"allow_stale" mapstructure:"allow_stale"`
+	ARecordLimit                *int                `json:"a_record_limit,omitempty" hcl:"a_record_limit" mapstructure:"a_record_limit"`
+	DisableCompression          *bool               `json:"disable_compression,omitempty" hcl:"disable_compression" mapstructure:"disable_compression"`
+	DomainRecursors             map[string][]string `json:"domain_recursors,omitempty" hcl:"domain_recursors" mapstructure:"domain_recursors"`
+	EnableTruncate              *bool               `json:"enable_truncate,omitempty" hcl:"enable_truncate" mapstructure:"enable_truncate"`
+	MaxStale                    *string             `json:"max_stale,omitempty" hcl:"max_stale" mapstructure:"max_stale"`
+	NodeTTL                     *string             `json:"node_ttl,omitempty" hcl:"node_ttl" mapstructure:"node_ttl"`
+	OnlyPassing                 *bool               `json:"only_passing,omitempty" hcl:"only_passing" mapstructure:"only_passing"`
+	RecursorHealthCheckInterval *string             `json:"recursor_health_check_interval,omitempty" hcl:"recursor_health_check_interval" mapstructure:"recursor_health_check_interval"`
+	RecursorTimeout             *string             `json:"recursor_timeout,omitempty" hcl:"recursor_timeout" mapstructure:"recursor_timeout"`
+	ServiceTTL                  map[string]string   `json:"service_ttl,omitempty" hcl:"service_ttl" mapstructure:"service_ttl"`
+	UDPAnswerLimit              *int                `json:"udp_answer_limit,omitempty" hcl:"udp_answer_limit" mapstructure:"udp_answer_limit"`
+	NodeMetaTXT                 *bool               `json:"enable_additional_node_meta_txt,omitempty" hcl:"enable_additional_node_meta_txt" mapstructure:"enable_additional_node_meta_txt"`
+	PreparedQueryDatacenterTXT  *bool               `json:"enable_prepared_query_datacenter_txt,omitempty" hcl:"enable_prepared_query_datacenter_txt" mapstructure:"enable_prepared_query_datacenter_txt"`
+	SOA                         *SOA                `json:"soa,omitempty" hcl:"soa" mapstructure:"soa"`
 }
 
 type HTTPConfig struct {
-	BlockEndpoints  []string          `json:"block_endpoints,omitempty" hcl:"block_endpoints" mapstructure:"block_endpoints"`
-	ResponseHeaders map[string]string `json:"response_headers,omitempty" hcl:"response_headers" mapstructure:"response_headers"`
+	BlockEndpoints      []string            `json:"block_endpoints,omitempty" hcl:"block_endpoints" mapstructure:"block_endpoints"`
+	ClientCertAllowlist ClientCertAllowlist `json:"client_cert_allowlist,omitempty" hcl:"client_cert_allowlist" mapstructure:"client_cert_allowlist"`
+	ResponseHeaders     map[string]string   `json:"response_headers,omitempty" hcl:"response_headers" mapstructure:"response_headers"`
+}
+
+type ClientCertAllowlist struct {
+	CommonNames         []string `json:"common_names,omitempty" hcl:"common_names" mapstructure:"common_names"`
+	OrganizationalUnits []string `json:"organizational_units,omitempty" hcl:"organizational_units" mapstructure:"organizational_units"`
+	URIs                []string `json:"uris,omitempty" hcl:"uris" mapstructure:"uris"`
+	WriteOnly           *bool    `json:"write_only,omitempty" hcl:"write_only" mapstructure:"write_only"`
+}
+
+type Hook struct {
+	Events  []string            `json:"events,omitempty" hcl:"events" mapstructure:"events"`
+	Args    []string            `json:"args,omitempty" hcl:"args" mapstructure:"args"`
+	HTTP    *string             `json:"http,omitempty" hcl:"http" mapstructure:"http"`
+	Method  *string             `json:"method,omitempty" hcl:"method" mapstructure:"method"`
+	Header  map[string][]string `json:"header,omitempty" hcl:"header" mapstructure:"header"`
+	Payload *string             `json:"payload,omitempty" hcl:"payload" mapstructure:"payload"`
+	Timeout *string             `json:"timeout,omitempty" hcl:"timeout" mapstructure:"timeout"`
+}
+
+type Template struct {
+	Source         *string  `json:"source,omitempty" hcl:"source" mapstructure:"source"`
+	Destination    *string  `json:"destination,omitempty" hcl:"destination" mapstructure:"destination"`
+	Perms          *string  `json:"perms,omitempty" hcl:"perms" mapstructure:"perms"`
+	Command        *string  `json:"command,omitempty" hcl:"command" mapstructure:"command"`
+	Args           []string `json:"args,omitempty" hcl:"args" mapstructure:"args"`
+	CommandTimeout *string  `json:"command_timeout,omitempty" hcl:"command_timeout" mapstructure:"command_timeout"`
 }
 
 type Performance struct {
-	LeaveDrainTime *string `json:"leave_drain_time,omitempty" hcl:"leave_drain_time" mapstructure:"leave_drain_time"`
-	RaftMultiplier *int    `json:"raft_multiplier,omitempty" hcl:"raft_multiplier" mapstructure:"raft_multiplier"` // todo(fs): validate as uint
-	RPCHoldTimeout *string `json:"rpc_hold_timeout" hcl:"rpc_hold_timeout" mapstructure:"rpc_hold_timeout"`
+	LeaveDrainTime         *string  `json:"leave_drain_time,omitempty" hcl:"leave_drain_time" mapstructure:"leave_drain_time"`
+	RaftMultiplier         *int     `json:"raft_multiplier,omitempty" hcl:"raft_multiplier" mapstructure:"raft_multiplier"` // todo(fs): validate as uint
+	RaftElectionMultiplier *int     `json:"raft_election_multiplier,omitempty" hcl:"raft_election_multiplier" mapstructure:"raft_election_multiplier"`
+	RaftLeaderLeaseTimeout *string  `json:"raft_leader_lease_timeout,omitempty" hcl:"raft_leader_lease_timeout" mapstructure:"raft_leader_lease_timeout"`
+	RPCHoldTimeout         *string  `json:"rpc_hold_timeout" hcl:"rpc_hold_timeout" mapstructure:"rpc_hold_timeout"`
+	RPCRetryMaxInterval    *string  `json:"rpc_retry_max_interval,omitempty" hcl:"rpc_retry_max_interval" mapstructure:"rpc_retry_max_interval"`
+	RPCRetryJitter         *float64 `json:"rpc_retry_jitter,omitempty" hcl:"rpc_retry_jitter" mapstructure:"rpc_retry_jitter"`
+	AERetryMaxInterval     *string  `json:"ae_retry_max_interval,omitempty" hcl:"ae_retry_max_interval" mapstructure:"ae_retry_max_interval"`
+	AERetryJitter          *float64 `json:"ae_retry_jitter,omitempty" hcl:"ae_retry_jitter" mapstructure:"ae_retry_jitter"`
 }
 
 type Telemetry struct {
@@ -608,15 +708,44 @@ type Ports struct {
 	SidecarMaxPort *int `json:"sidecar_max_port,omitempty" hcl:"sidecar_max_port" mapstructure:"sidecar_max_port"`
 }
 
+type UIMetricsProxy struct {
+	BaseURL       *string           `json:"base_url,omitempty" hcl:"base_url" mapstructure:"base_url"`
+	PathAllowlist []string          `json:"path_allowlist,omitempty" hcl:"path_allowlist" mapstructure:"path_allowlist"`
+	AddHeaders    map[string]string `json:"add_headers,omitempty" hcl:"add_headers" mapstructure:"add_headers"`
+}
+
 type UnixSocket struct {
 	Group *string `json:"group,omitempty" hcl:"group" mapstructure:"group"`
 	Mode  *string `json:"mode,omitempty" hcl:"mode" mapstructure:"mode"`
 	User  *string `json:"user,omitempty" hcl:"user" mapstructure:"user"`
 }
 
+type KVReplication struct {
+	ConflictPolicy *string  `json:"conflict_policy,omitempty" hcl:"conflict_policy" mapstructure:"conflict_policy"`
+	Prefixes       []string `json:"prefixes,omitempty" hcl:"prefixes" mapstructure:"prefixes"`
+}
+
+type RequestLogging struct {
+	SampleRate         *float64 `json:"sample_rate,omitempty" hcl:"sample_rate" mapstructure:"sample_rate"`
+	SlowReadThreshold  *string  `json:"slow_read_threshold,omitempty" hcl:"slow_read_threshold" mapstructure:"slow_read_threshold"`
+	SlowWriteThreshold *string  `json:"slow_write_threshold,omitempty" hcl:"slow_write_threshold" mapstructure:"slow_write_threshold"`
+}
+
 type Limits struct {
-	RPCMaxBurst *int     `json:"rpc_max_burst,omitempty" hcl:"rpc_max_burst" mapstructure:"rpc_max_burst"`
-	RPCRate     *float64 `json:"rpc_rate,omitempty" hcl:"rpc_rate" mapstructure:"rpc_rate"`
+	ACLBootstrapMaxBurst       *int     `json:"acl_bootstrap_max_burst,omitempty" hcl:"acl_bootstrap_max_burst" mapstructure:"acl_bootstrap_max_burst"`
+	ACLBootstrapRate           *float64 `json:"acl_bootstrap_rate,omitempty" hcl:"acl_bootstrap_rate" mapstructure:"acl_bootstrap_rate"`
+	DefaultQueryTime           *string  `json:"default_query_time,omitempty" hcl:"default_query_time" mapstructure:"default_query_time"`
+	MaxBlockingQueriesPerToken *int     `json:"max_blocking_queries_per_token,omitempty" hcl:"max_blocking_queries_per_token" mapstructure:"max_blocking_queries_per_token"`
+	MaxQueryTime               *string  `json:"max_query_time,omitempty" hcl:"max_query_time" mapstructure:"max_query_time"`
+	RPCMaxBurst                *int     `json:"rpc_max_burst,omitempty" hcl:"rpc_max_burst" mapstructure:"rpc_max_burst"`
+	RPCRate                    *float64 `json:"rpc_rate,omitempty" hcl:"rpc_rate" mapstructure:"rpc_rate"`
+}
+
+type ScriptCheckLimits struct {
+	CPUTime     *string `json:"cpu_time,omitempty" hcl:"cpu_time" mapstructure:"cpu_time"`
+	Cgroup      *string `json:"cgroup,omitempty" hcl:"cgroup" mapstructure:"cgroup"`
+	MemoryBytes *int    `json:"memory_bytes,omitempty" hcl:"memory_bytes" mapstructure:"memory_bytes"`
+	User        *string `json:"user,omitempty" hcl:"user" mapstructure:"user"`
 }
 
 type Segment struct {
@@ -627,22 +756,38 @@ type Segment struct {
 	RPCListener *bool   `json:"rpc_listener,omitempty" hcl:"rpc_listener" mapstructure:"rpc_listener"`
 }
 
+type RPCRoute struct {
+	Datacenter *string        `json:"datacenter,omitempty" hcl:"datacenter" mapstructure:"datacenter"`
+	Deny       *bool          `json:"deny,omitempty" hcl:"deny" mapstructure:"deny"`
+	Via        map[string]int `json:"via,omitempty" hcl:"via" mapstructure:"via"`
+}
+
+type ServerHostnamePolicy struct {
+	Datacenter *string  `json:"datacenter,omitempty" hcl:"datacenter" mapstructure:"datacenter"`
+	Exempt     *bool    `json:"exempt,omitempty" hcl:"exempt" mapstructure:"exempt"`
+	Names      []string `json:"names,omitempty" hcl:"names" mapstructure:"names"`
+}
+
 type ACL struct {
-	Enabled             *bool   `json:"enabled,omitempty" hcl:"enabled" mapstructure:"enabled"`
-	TokenReplication    *bool   `json:"enable_token_replication,omitempty" hcl:"enable_token_replication" mapstructure:"enable_token_replication"`
-	PolicyTTL           *string `json:"policy_ttl,omitempty" hcl:"policy_ttl" mapstructure:"policy_ttl"`
-	TokenTTL            *string `json:"token_ttl,omitempty" hcl:"token_ttl" mapstructure:"token_ttl"`
-	DownPolicy          *string `json:"down_policy,omitempty" hcl:"down_policy" mapstructure:"down_policy"`
-	DefaultPolicy       *string `json:"default_policy,omitempty" hcl:"default_policy" mapstructure:"default_policy"`
-	EnableKeyListPolicy *bool   `json:"enable_key_list_policy,omitempty" hcl:"enable_key_list_policy" mapstructure:"enable_key_list_policy"`
-	Tokens              Tokens  `json:"tokens,omitempty" hcl:"tokens" mapstructure:"tokens"`
-	DisabledTTL         *string `json:"disabled_ttl,omitempty" hcl:"disabled_ttl" mapstructure:"disabled_ttl"`
+	Enabled                      *bool   `json:"enabled,omitempty" hcl:"enabled" mapstructure:"enabled"`
+	TokenReplication             *bool   `json:"enable_token_replication,omitempty" hcl:"enable_token_replication" mapstructure:"enable_token_replication"`
+	PolicyTTL                    *string `json:"policy_ttl,omitempty" hcl:"policy_ttl" mapstructure:"policy_ttl"`
+	TokenTTL                     *string `json:"token_ttl,omitempty" hcl:"token_ttl" mapstructure:"token_ttl"`
+	DownPolicy                   *string `json:"down_policy,omitempty" hcl:"down_policy" mapstructure:"down_policy"`
+	DefaultPolicy                *string `json:"default_policy,omitempty" hcl:"default_policy" mapstructure:"default_policy"`
+	EnableKeyListPolicy          *bool   `json:"enable_key_list_policy,omitempty" hcl:"enable_key_list_policy" mapstructure:"enable_key_list_policy"`
+	DeniedPermissionDetails      *bool   `json:"enable_denied_permission_details,omitempty" hcl:"enable_denied_permission_details" mapstructure:"enable_denied_permission_details"`
+	TokenPersistence             *bool   `json:"enable_token_persistence,omitempty" hcl:"enable_token_persistence" mapstructure:"enable_token_persistence"`
+	BlockingQueryRecheckInterval *string `json:"blocking_query_recheck_interval,omitempty" hcl:"blocking_query_recheck_interval" mapstructure:"blocking_query_recheck_interval"`
+	Tokens                       Tokens  `json:"tokens,omitempty" hcl:"tokens" mapstructure:"tokens"`
+	DisabledTTL                  *string `json:"disabled_ttl,omitempty" hcl:"disabled_ttl" mapstructure:"disabled_ttl"`
 }
 
 type Tokens struct {
-	Master      *string `json:"master,omitempty" hcl:"master" mapstructure:"master"`
-	Replication *string `json:"replication,omitempty" hcl:"replication" mapstructure:"replication"`
-	AgentMaster *string `json:"agent_master,omitempty" hcl:"agent_master" mapstructure:"agent_master"`
-	Default     *string `json:"default,omitempty" hcl:"default" mapstructure:"default"`
-	Agent       *string `json:"agent,omitempty" hcl:"agent" mapstructure:"agent"`
+	Master       *string `json:"master,omitempty" hcl:"master" mapstructure:"master"`
+	Replication  *string `json:"replication,omitempty" hcl:"replication" mapstructure:"replication"`
+	AgentMaster  *string `json:"agent_master,omitempty" hcl:"agent_master" mapstructure:"agent_master"`
+	Default      *string `json:"default,omitempty" hcl:"default" mapstructure:"default"`
+	Agent        *string `json:"agent,omitempty" hcl:"agent" mapstructure:"agent"`
+	Introduction *string `json:"introduction,omitempty" hcl:"introduction" mapstructure:"introduction"`
 }
@@ -50,8 +50,10 @@ func DefaultSource() Source {
 		bootstrap = false
 		bootstrap_expect = 0
 		check_update_interval = "5m"
+		crl_refresh_interval = "1h"
 		client_addr = "127.0.0.1"
 		datacenter = "` + consul.DefaultDC + `"
+		deregister_services_on_shutdown = true
 		disable_coordinates = false
 		disable_host_node_id = true
 		disable_remote_exec = true
@@ -95,16 +97,26 @@ func DefaultSource() Source {
 			a_record_limit = 0
 			udp_answer_limit = 3
 			max_stale = "87600h"
+			recursor_health_check_interval = "10s"
 			recursor_timeout = "2s"
 		}
 		limits = {
+			acl_bootstrap_rate = 0.1
+			acl_bootstrap_max_burst = 3
 			rpc_rate = -1
 			rpc_max_burst = 1000
+			max_query_time = "600s"
+			default_query_time = "300s"
+			max_blocking_queries_per_token = 0
 		}
 		performance = {
 			leave_drain_time = "5s"
 			raft_multiplier = ` + strconv.Itoa(int(consul.DefaultRaftMultiplier)) + `
 			rpc_hold_timeout = "7s"
+			rpc_retry_max_interval = "1s"
+			rpc_retry_jitter = 1.0
+			ae_retry_max_interval = "1m"
+			ae_retry_jitter = 1.0
 		}
 		ports = {
 			dns = 8600
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff returns the sorted names of the fields which differ between a
+// and b. Nested structs are compared field by field and their fields are
+// reported with dotted names, e.g. "Telemetry.StatsdAddr". All other
+// values are compared as a whole.
+func Diff(a, b RuntimeConfig) []string {
+	names := diffFields("", reflect.ValueOf(a), reflect.ValueOf(b))
+	sort.Strings(names)
+	return names
+}
+
+func diffFields(prefix string, a, b reflect.Value) []string {
+	var names []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// skip unexported fields
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fa, fb := a.Field(i), b.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			names = append(names, diffFields(name, fa, fb)...)
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	a := RuntimeConfig{
+		DNSRecursors: []string{"8.8.8.8"},
+		HTTPAddrs:    []net.Addr{&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8500}},
+		Services:     []*structs.ServiceDefinition{{Name: "web"}},
+	}
+	a.Telemetry.StatsdAddr = "127.0.0.1:8125"
+
+	b := a
+	require.Empty(t, Diff(a, b))
+
+	b.DNSRecursors = []string{"8.8.4.4"}
+	b.HTTPAddrs = []net.Addr{&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8500}}
+	b.Services = []*structs.ServiceDefinition{{Name: "web", Port: 80}}
+	b.Telemetry.StatsdAddr = "127.0.0.1:9125"
+	b.LogLevel = "DEBUG"
+	require.Equal(t, []string{"DNSRecursors", "LogLevel", "Services", "Telemetry.StatsdAddr"}, Diff(a, b))
+}
@@ -20,11 +20,19 @@ type Flags struct {
 	// format independent of their extension.
 	ConfigFormat *string
 
+	// ConfigInterpolateEnv enables the replacement of ${NAME} references
+	// to environment variables in the config files.
+	ConfigInterpolateEnv *bool
+
 	// HCL contains an arbitrary config in hcl format.
 	// DevMode indicates whether the agent should be started in development
 	// mode. This cannot be configured in a config file.
 	DevMode *bool
 
+	// DevPersist is the directory in which a dev mode agent keeps its
+	// state across restarts. It requires DevMode.
+	DevPersist *string
+
 	HCL []string
 
 	// Args contains the remaining unparsed flags.
@@ -63,9 +71,11 @@ func AddFlags(fs *flag.FlagSet, f *Flags) {
 	add(&f.ConfigFiles, "config-dir", "Path to a directory to read configuration files from. This will read every file ending in '.json' as configuration in this directory in alphabetical order. Can be specified multiple times.")
 	add(&f.ConfigFiles, "config-file", "Path to a JSON file to read configuration from. Can be specified multiple times.")
 	add(&f.ConfigFormat, "config-format", "Config files are in this format irrespective of their extension. Must be 'hcl' or 'json'")
+	add(&f.ConfigInterpolateEnv, "config-interpolate-env", "Replaces ${NAME} and ${NAME:-default} references to environment variables in config files with their values. Use $${ for a literal ${.")
 	add(&f.Config.DataDir, "data-dir", "Path to a data directory to store agent state.")
 	add(&f.Config.Datacenter, "datacenter", "Datacenter of the agent.")
 	add(&f.DevMode, "dev", "Starts the agent in development mode.")
+	add(&f.DevPersist, "dev-persist", "Path to a directory in which a development mode agent keeps its Raft state and other data across restarts. Requires -dev.")
 	add(&f.Config.DisableHostNodeID, "disable-host-node-id", "Setting this to true will prevent Consul from using information from the host to generate a node ID, and will cause Consul to generate a random node ID instead.")
 	add(&f.Config.DisableKeyringFile, "disable-keyring-file", "Disables the backing up of the keyring to a file.")
 	add(&f.Config.Ports.DNS, "dns-port", "DNS port to use.")
@@ -40,6 +40,10 @@ func TestParseFlags(t *testing.T) {
 			args:  []string{`-config-file`, `a`, `-config-dir`, `b`, `-config-file`, `c`, `-config-dir`, `d`},
 			flags: Flags{ConfigFiles: []string{"a", "b", "c", "d"}},
 		},
+		{
+			args:  []string{`-config-interpolate-env`},
+			flags: Flags{ConfigInterpolateEnv: pBool(true)},
+		},
 		{
 			args:  []string{`-datacenter`, `a`},
 			flags: Flags{Config: Config{Datacenter: pString("a")}},
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gossipProfileDir is the directory in the data dir which holds the gossip
+// profiles selected through the operator API.
+const gossipProfileDir = "gossip-profiles"
+
+// GossipProfile is a named set of gossip tuning values. Any value which is
+// set explicitly in the gossip_lan or gossip_wan config takes precedence
+// over the value of the selected profile.
+type GossipProfile struct {
+	GossipNodes    int
+	GossipInterval time.Duration
+	ProbeInterval  time.Duration
+	ProbeTimeout   time.Duration
+	SuspicionMult  int
+	RetransmitMult int
+}
+
+// GossipProfiles are the gossip tuning profiles which can be selected for
+// the LAN and the WAN pool.
+var GossipProfiles = map[string]GossipProfile{
+	// lan are the memberlist defaults for a local network.
+	"lan": {
+		GossipNodes:    3,
+		GossipInterval: 200 * time.Millisecond,
+		ProbeInterval:  1 * time.Second,
+		ProbeTimeout:   500 * time.Millisecond,
+		SuspicionMult:  4,
+		RetransmitMult: 4,
+	},
+	// wan are the memberlist defaults for a network with higher latencies.
+	"wan": {
+		GossipNodes:    4,
+		GossipInterval: 500 * time.Millisecond,
+		ProbeInterval:  5 * time.Second,
+		ProbeTimeout:   3 * time.Second,
+		SuspicionMult:  6,
+		RetransmitMult: 4,
+	},
+	// cloud tolerates the latency spikes and packet loss between the
+	// availability zones of a region and noisy neighbors.
+	"cloud": {
+		GossipNodes:    4,
+		GossipInterval: 200 * time.Millisecond,
+		ProbeInterval:  2 * time.Second,
+		ProbeTimeout:   1 * time.Second,
+		SuspicionMult:  5,
+		RetransmitMult: 4,
+	},
+	// large-cluster gossips less often but to more nodes at a time, which
+	// keeps the convergence time of pools with many thousand members low
+	// while using less bandwidth per node.
+	"large-cluster": {
+		GossipNodes:    6,
+		GossipInterval: 500 * time.Millisecond,
+		ProbeInterval:  2 * time.Second,
+		ProbeTimeout:   1 * time.Second,
+		SuspicionMult:  6,
+		RetransmitMult: 5,
+	},
+}
+
+// GossipProfileNames returns the sorted names of the gossip profiles.
+func GossipProfileNames() []string {
+	var names []string
+	for name := range GossipProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GossipProfilePath returns the path of the file which holds the name of
+// the gossip profile selected through the operator API for the given pool.
+func GossipProfilePath(dataDir, pool string) string {
+	return filepath.Join(dataDir, gossipProfileDir, pool)
+}
+
+// ReadGossipProfile returns the name of the gossip profile selected through
+// the operator API for the given pool or an empty string if none was
+// selected.
+func ReadGossipProfile(dataDir, pool string) (string, error) {
+	// The data dir is validated later on.
+	if fi, err := os.Stat(dataDir); err != nil || !fi.IsDir() {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(GossipProfilePath(dataDir, pool))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error reading gossip profile for %s pool: %s", pool, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// gossipProfileSource returns the values of the named gossip profile as a
+// config source for the gossip_lan or gossip_wan section.
+func gossipProfileSource(section, name string) (Source, error) {
+	p, ok := GossipProfiles[name]
+	if !ok {
+		return Source{}, fmt.Errorf("%s.profile must be one of %s, got %q",
+			section, strings.Join(GossipProfileNames(), ", "), name)
+	}
+	return Source{
+		Name:   "gossip-profile:" + name,
+		Format: "hcl",
+		Data: fmt.Sprintf(`
+		%s = {
+			gossip_interval = %q
+			gossip_nodes = %d
+			probe_interval = %q
+			probe_timeout = %q
+			retransmit_mult = %d
+			suspicion_mult = %d
+		}`, section, p.GossipInterval, p.GossipNodes, p.ProbeInterval,
+			p.ProbeTimeout, p.RetransmitMult, p.SuspicionMult),
+	}, nil
+}
+
+// gossipProfile returns the name of the gossip profile for the given config
+// section. A profile selected through the operator API takes precedence
+// over the configured one.
+func (b *Builder) gossipProfile(section string, configured *string, dataDir string) (string, error) {
+	selected, err := ReadGossipProfile(dataDir, strings.TrimPrefix(section, "gossip_"))
+	if err != nil {
+		return "", err
+	}
+	if selected != "" {
+		return selected, nil
+	}
+	return b.stringVal(configured), nil
+}
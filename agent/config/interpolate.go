@@ -0,0 +1,66 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// reEnvName defines a regexp for a valid environment variable name.
+var reEnvName = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// interpolateEnv replaces references to environment variables in the
+// given config fragment with their values. The following forms are
+// supported:
+//
+//	${NAME}             the value of NAME. It is an error if NAME is not set.
+//	${NAME:-default}    the value of NAME or "default" if NAME is unset or empty.
+//	$${                 a literal "${".
+//
+// The replacement is done on the raw text before it is parsed which allows
+// references for non-string values like ports. Values are inserted verbatim
+// and are therefore not escaped for the target format.
+func interpolateEnv(data string, lookup func(string) (string, bool)) (string, error) {
+	var buf bytes.Buffer
+	for {
+		i := strings.Index(data, "${")
+		if i < 0 {
+			buf.WriteString(data)
+			return buf.String(), nil
+		}
+
+		// $${ is an escaped ${
+		if i > 0 && data[i-1] == '$' {
+			buf.WriteString(data[:i-1])
+			buf.WriteString("${")
+			data = data[i+2:]
+			continue
+		}
+
+		buf.WriteString(data[:i])
+		end := strings.Index(data[i:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated environment variable reference %q", data[i:])
+		}
+		ref := data[i+2 : i+end]
+		data = data[i+end+1:]
+
+		name, def, hasDefault := ref, "", false
+		if j := strings.Index(ref, ":-"); j >= 0 {
+			name, def, hasDefault = ref[:j], ref[j+2:], true
+		}
+		if !reEnvName.MatchString(name) {
+			return "", fmt.Errorf("invalid environment variable name %q", name)
+		}
+
+		val, ok := lookup(name)
+		switch {
+		case hasDefault && val == "":
+			val = def
+		case !ok:
+			return "", fmt.Errorf("environment variable %q is not set", name)
+		}
+		buf.WriteString(val)
+	}
+}
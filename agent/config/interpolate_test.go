@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	env := map[string]string{
+		"ADDR":  "10.0.0.1",
+		"PORT":  "8600",
+		"EMPTY": "",
+	}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	tests := []struct {
+		in, out, err string
+	}{
+		{in: `bind_addr = "10.0.0.1"`, out: `bind_addr = "10.0.0.1"`},
+		{in: `bind_addr = "${ADDR}"`, out: `bind_addr = "10.0.0.1"`},
+		{in: `ports { dns = ${PORT} }`, out: `ports { dns = 8600 }`},
+		{in: `a = "${ADDR}:${PORT}"`, out: `a = "10.0.0.1:8600"`},
+		{in: `a = "${MISSING:-foo}"`, out: `a = "foo"`},
+		{in: `a = "${EMPTY:-foo}"`, out: `a = "foo"`},
+		{in: `a = "${ADDR:-foo}"`, out: `a = "10.0.0.1"`},
+		{in: `a = "${MISSING:-}"`, out: `a = ""`},
+		{in: `a = "${EMPTY}"`, out: `a = ""`},
+		{in: `a = "$${ADDR}"`, out: `a = "${ADDR}"`},
+		{in: `a = "$HOME"`, out: `a = "$HOME"`},
+		{in: `a = "${MISSING}"`, err: `environment variable "MISSING" is not set`},
+		{in: `a = "${1FOO}"`, err: `invalid environment variable name "1FOO"`},
+		{in: `a = "${ADDR"`, err: `unterminated environment variable reference`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			out, err := interpolateEnv(tt.in, lookup)
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestBuilder_ConfigInterpolateEnv(t *testing.T) {
+	lookup := func(k string) (string, bool) {
+		if k == "HTTP_PORT" {
+			return "9500", true
+		}
+		return "", false
+	}
+	src := Source{Name: "a.hcl", Data: `ports { http = ${HTTP_PORT} } node_name = "${NODE:-foo}"`}
+
+	enabled := true
+	b, err := NewBuilder(Flags{ConfigInterpolateEnv: &enabled})
+	require.NoError(t, err)
+	b.LookupEnv = lookup
+	b.Sources = append(b.Sources, src)
+	rt, err := b.Build()
+	require.NoError(t, err)
+	require.Equal(t, 9500, rt.HTTPPort)
+	require.Equal(t, "foo", rt.NodeName)
+
+	// interpolation is disabled by default
+	b, err = NewBuilder(Flags{})
+	require.NoError(t, err)
+	b.LookupEnv = lookup
+	b.Sources = append(b.Sources, src)
+	_, err = b.Build()
+	require.Error(t, err)
+}
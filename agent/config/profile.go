@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ProfileKVPrefix is the prefix of the KV keys under which the agent
+// profiles are stored on the servers.
+const ProfileKVPrefix = "consul/agent-profiles/"
+
+// profileDir is the directory in the data dir which holds the last
+// version of the agent profiles fetched from the servers.
+const profileDir = "agent-profiles"
+
+// reProfileName defines a regexp for a valid agent profile name.
+var reProfileName = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// profileAllowedKeys are the config sections an agent profile may set.
+// Profiles are meant for fleet-wide tuning and must not be able to
+// change the identity or the security settings of an agent.
+var profileAllowedKeys = []string{
+	"dns_config",
+	"limits",
+	"telemetry",
+}
+
+// ProfileCachePath returns the path of the file which holds the cached
+// copy of the named agent profile.
+func ProfileCachePath(dataDir, name string) string {
+	return filepath.Join(dataDir, profileDir, name)
+}
+
+// ProfileSource validates the contents of an agent profile and returns
+// it as a config source. Profiles are stored in HCL or JSON format and
+// may only contain the sections listed in profileAllowedKeys.
+func ProfileSource(name, data string) (Source, error) {
+	src := Source{Name: "profile:" + name, Format: "hcl", Data: data}
+	c, err := Parse(data, src.Format)
+	if err != nil {
+		return Source{}, fmt.Errorf("agent profile %q: %s", name, err)
+	}
+
+	for _, key := range configKeys("", reflect.ValueOf(c)) {
+		allowed := false
+		for _, prefix := range profileAllowedKeys {
+			if key == prefix || strings.HasPrefix(key, prefix+".") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return Source{}, fmt.Errorf("agent profile %q: %s cannot be set in an agent profile", name, key)
+		}
+	}
+	return src, nil
+}
+
+// cachedProfileSource returns the cached copy of the named agent profile
+// from the data dir or nil if the profile has not been fetched yet.
+func (b *Builder) cachedProfileSource(name, dataDir string) (*Source, error) {
+	if dataDir == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(ProfileCachePath(dataDir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading agent profile %q: %s", name, err)
+	}
+	src, err := ProfileSource(name, string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &src, nil
+}
+
+// configKeys returns the dotted names of all fields which are set in
+// the given Config value. Structs are descended into while slices,
+// maps and pointers are reported as a single key.
+func configKeys(prefix string, v reflect.Value) []string {
+	var keys []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Struct:
+			keys = append(keys, configKeys(name, f)...)
+		case reflect.Ptr, reflect.Interface:
+			if !f.IsNil() {
+				keys = append(keys, name)
+			}
+		case reflect.Slice, reflect.Map:
+			if f.Len() > 0 {
+				keys = append(keys, name)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileSource(t *testing.T) {
+	tests := []struct {
+		desc string
+		data string
+		err  string
+	}{
+		{
+			desc: "hcl",
+			data: `limits { rpc_rate = 10 } dns_config { node_ttl = "5s" }`,
+		},
+		{
+			desc: "json",
+			data: `{"telemetry": {"statsd_address": "127.0.0.1:8125"}}`,
+		},
+		{
+			desc: "not allowed",
+			data: `{"telemetry": {"statsd_address": "127.0.0.1:8125"}, "acl": {"tokens": {"agent": "foo"}}}`,
+			err:  `agent profile "web": acl.tokens.agent cannot be set in an agent profile`,
+		},
+		{
+			desc: "invalid",
+			data: `limits { rpc_rate = `,
+			err:  `agent profile "web"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			src, err := ProfileSource("web", tt.data)
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "profile:web", src.Name)
+		})
+	}
+}
+
+func TestBuilder_CachedProfile(t *testing.T) {
+	dataDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(dataDir)
+
+	path := ProfileCachePath(dataDir, "web")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(`limits { rpc_rate = 10 rpc_max_burst = 20 }`), 0600))
+
+	b, err := NewBuilder(Flags{})
+	require.NoError(t, err)
+	b.Sources = append(b.Sources, Source{
+		Name:   "a.json",
+		Format: "json",
+		Data:   `{"agent_profile": "web", "data_dir": "` + dataDir + `", "limits": {"rpc_max_burst": 30}}`,
+	})
+	rt, err := b.Build()
+	require.NoError(t, err)
+
+	// the profile overrides the defaults but not the local config
+	require.Equal(t, "web", rt.AgentProfile)
+	require.Equal(t, float64(10), float64(rt.RPCRateLimit))
+	require.Equal(t, 30, rt.RPCMaxBurst)
+
+	fields, err := b.FieldSources()
+	require.NoError(t, err)
+	require.Equal(t, []string{SourceDefault, "profile:web"}, fields["RPCRateLimit"])
+	require.Equal(t, []string{SourceDefault, "profile:web", "a.json"}, fields["RPCMaxBurst"])
+}
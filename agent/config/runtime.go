@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/render"
 	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/consul/types"
 	"golang.org/x/time/rate"
@@ -23,6 +24,53 @@ type RuntimeSOAConfig struct {
 	Minttl  uint32 // 0,
 }
 
+// The agent lifecycle events hooks can be run for.
+const (
+	HookEventBecameLeader      = "became_leader"
+	HookEventLostLeader        = "lost_leader"
+	HookEventJoined            = "joined"
+	HookEventLeft              = "left"
+	HookEventCheckStateChanged = "check_state_changed"
+	HookEventACLBootstrap      = "acl_bootstrap_attempt"
+)
+
+// HookEvents lists the valid values of the events of a hook.
+var HookEvents = []string{
+	HookEventBecameLeader,
+	HookEventLostLeader,
+	HookEventJoined,
+	HookEventLeft,
+	HookEventCheckStateChanged,
+	HookEventACLBootstrap,
+}
+
+// RuntimeHookConfig is a hook which is run when one of the given agent
+// lifecycle events happens. Either Args or HTTP is set.
+type RuntimeHookConfig struct {
+	// Events are the events which trigger the hook.
+	Events []string
+
+	// Args is the command, and its arguments, which is run with the
+	// payload on stdin.
+	Args []string
+
+	// HTTP is the URL the payload is sent to.
+	HTTP string
+
+	// Method is the HTTP method of the request. The default is POST.
+	Method string
+
+	// Header are the headers of the HTTP request.
+	Header map[string][]string
+
+	// Payload is a text/template which renders the payload of the event.
+	// The event is encoded as JSON if it is empty.
+	Payload string
+
+	// Timeout is how long the hook may run. The default is 10s.
+	Timeout time.Duration
+}
+
 // RuntimeConfig specifies the configuration the consul agent actually
 // uses. Is is derived from one or more Config structures which can come
 // from files, flags and/or environment variables.
@@ -75,6 +123,24 @@ type RuntimeConfig struct {
 	// hcl: acl.tokens.agent = string
 	ACLAgentToken string
 
+	// ACLBlockingQueryRecheckInterval is the interval at which servers
+	// resolve the token of a blocking query again. Queries whose token was
+	// deleted fail and queries whose token's policies changed return early,
+	// so that revoking a token ends its watches in bounded time. Zero
+	// disables the recheck.
+	//
+	// hcl: acl.blocking_query_recheck_interval = "duration"
+	ACLBlockingQueryRecheckInterval time.Duration
+
+	// ACLBootstrapRateLimit and ACLBootstrapMaxBurst limit how often the
+	// ACL bootstrap endpoint of the agent may be called from the same
+	// source IP. The attempts are always logged. A rate which isn't
+	// positive disables the limit.
+	//
+	// hcl: limits { acl_bootstrap_rate = float64 acl_bootstrap_max_burst = int }
+	ACLBootstrapRateLimit rate.Limit
+	ACLBootstrapMaxBurst  int
+
 	// ACLDatacenter is the central datacenter that holds authoritative
 	// ACL records. This must be the same for the entire cluster.
 	// If this is not set, ACLs are not enabled. Off by default.
@@ -112,6 +178,14 @@ type RuntimeConfig struct {
 	// hcl: acl_enforce_version_8 = (true|false)
 	ACLEnforceVersion8 bool
 
+	// ACLEnableDeniedPermissionDetails makes the HTTP API name the
+	// permission a token lacks when a request is denied by ACLs, such as
+	// "service:web:write", in the X-Consul-ACL-Denied-Permission header and
+	// the response body. The rules of the token are never included.
+	//
+	// hcl: acl.enable_denied_permission_details = (true|false)
+	ACLEnableDeniedPermissionDetails bool
+
 	// ACLEnableKeyListPolicy is used to opt-in to the "list" policy added to
 	// KV ACLs in Consul 1.0.
 	//
@@ -121,6 +195,21 @@ type RuntimeConfig struct {
 	// hcl: acl.enable_key_list_policy = (true|false)
 	ACLEnableKeyListPolicy bool
 
+	// ACLEnableTokenPersistence stores the tokens which are set with the
+	// /v1/agent/token API in the data dir and restores them on startup.
+	// Restored tokens replace the tokens from the configuration.
+	//
+	// hcl: acl.enable_token_persistence = (true|false)
+	ACLEnableTokenPersistence bool
+
+	// ACLIntroductionToken is used by the agent to request an agent token
+	// with its node identity from the servers when it has no agent token.
+	// The token only needs the privileges granted by the node identity of
+	// the agent and should be short-lived.
+	//
+	// hcl: acl.tokens.introduction = string
+	ACLIntroductionToken string
+
 	// ACLMasterToken is used to bootstrap the ACL system. It should be specified
 	// on the servers in the ACLDatacenter. When the leader comes online, it ensures
 	// that the Master token is available. This provides the initial token.
@@ -248,6 +337,13 @@ type RuntimeConfig struct {
 	// flag: -domain string
 	DNSDomain string
 
+	// DNSDomainRecursors routes the recursive queries for a domain and its
+	// subdomains to specific upstream DNS servers instead of DNSRecursors.
+	// The most specific domain wins.
+	//
+	// hcl: dns_config { domain_recursors = map[string][]string }
+	DNSDomainRecursors map[string][]string
+
 	// DNSEnableTruncate is used to enable setting the truncate
 	// flag for UDP DNS queries.  This allows unmodified
 	// clients to re-query the consul server using TCP
@@ -278,6 +374,15 @@ type RuntimeConfig struct {
 	// hcl: dns_config { only_passing = "duration" }
 	DNSOnlyPassing bool
 
+	// DNSRecursorHealthCheckInterval is the interval at which the upstream
+	// DNS servers are probed. Servers which fail are only used when all the
+	// healthy ones failed to answer a query. A value of zero disables the
+	// probes so that servers are only marked failed or healthy by the
+	// queries sent to them.
+	//
+	// hcl: dns_config { recursor_health_check_interval = "duration" }
+	DNSRecursorHealthCheckInterval time.Duration
+
 	// DNSRecursorTimeout specifies the timeout in seconds
 	// for Consul's internal dns client used for recursion.
 	// This value is used for the connection, read and write timeout.
@@ -307,6 +412,13 @@ type RuntimeConfig struct {
 	// request (query type = TXT). If unset this will default to true
 	DNSNodeMetaTXT bool
 
+	// DNSPreparedQueryDatacenterTXT controls whether the SRV responses of
+	// prepared queries include a TXT record in the additional section with
+	// the datacenter which answered the query and the number of failovers.
+	//
+	// hcl: dns_config { enable_prepared_query_datacenter_txt = (true|false) }
+	DNSPreparedQueryDatacenterTXT bool
+
 	// DNSRecursors can be set to allow the DNS servers to recursively
 	// resolve non-consul domains.
 	//
@@ -320,6 +432,27 @@ type RuntimeConfig struct {
 	// hcl: http_config { block_endpoints = []string }
 	HTTPBlockEndpoints []string
 
+	// HTTPClientCertAllowlistCommonNames, HTTPClientCertAllowlistOrgUnits
+	// and HTTPClientCertAllowlistURIs restrict the HTTP API to requests
+	// with a verified TLS client certificate whose common name,
+	// organizational unit or URI SAN matches one of the patterns. A "*"
+	// matches any sequence of characters. The restriction applies in
+	// addition to the ACLs. Requests over plain HTTP are rejected.
+	//
+	// hcl: http_config { client_cert_allowlist { common_names = []string } }
+	// hcl: http_config { client_cert_allowlist { organizational_units = []string } }
+	// hcl: http_config { client_cert_allowlist { uris = []string } }
+	HTTPClientCertAllowlistCommonNames []string
+	HTTPClientCertAllowlistOrgUnits    []string
+	HTTPClientCertAllowlistURIs        []string
+
+	// HTTPClientCertAllowlistWriteOnly limits the client certificate
+	// allowlist to write requests, i.e. all requests other than GET, HEAD
+	// and OPTIONS.
+	//
+	// hcl: http_config { client_cert_allowlist { write_only = (true|false) } }
+	HTTPClientCertAllowlistWriteOnly bool
+
 	// HTTPResponseHeaders are used to add HTTP header response fields to the HTTP API responses.
 	//
 	// hcl: http_config { response_headers = map[string]string }
@@ -356,6 +489,18 @@ type RuntimeConfig struct {
 	// flag: -node string
 	NodeName string
 
+	// AERetryMaxInterval caps the interval between retries of a failed
+	// anti-entropy sync, which doubles with every consecutive failure.
+	//
+	// hcl: performance { ae_retry_max_interval = "duration" }
+	AERetryMaxInterval time.Duration
+
+	// AERetryJitter is the fraction of the anti-entropy retry interval which
+	// is added as a random delay, staggered by cluster size.
+	//
+	// hcl: performance { ae_retry_jitter = float64 }
+	AERetryJitter float64
+
 	// AdvertiseAddrLAN is the address we use for advertising our Serf, and
 	// Consul RPC IP. The address can be specified as an ip address or as a
 	// go-sockaddr template which resolves to a single ip address. If not
@@ -372,6 +517,14 @@ type RuntimeConfig struct {
 	// hcl: advertise_addr_wan = string
 	AdvertiseAddrWAN *net.IPAddr
 
+	// AgentProfile is the name of the agent profile which is fetched from
+	// the KV store of the servers under ProfileKVPrefix. The profile can
+	// set a restricted set of tuning parameters and is merged after the
+	// defaults but before the local config files.
+	//
+	// hcl: agent_profile = string
+	AgentProfile string
+
 	// BindAddr is used to control the address we bind to.
 	// If not specified, the first private IP we find is used.
 	// This controls the address we use for cluster facing
@@ -413,12 +566,47 @@ type RuntimeConfig struct {
 	// hcl: ca_path = string
 	CAPath string
 
+	// CRLFile is a path to a certificate revocation list in PEM or DER
+	// format. Certificates of peers which are listed in it are rejected on
+	// incoming and outgoing TLS connections. The CRL has to be signed by a
+	// certificate in CAFile or CAPath.
+	//
+	// hcl: crl_file = string
+	CRLFile string
+
+	// CRLRefreshInterval is how often CRLFile and CRLURL are reloaded.
+	//
+	// hcl: crl_refresh_interval = "duration"
+	CRLRefreshInterval time.Duration
+
+	// CRLURL is a HTTP(S) URL a certificate revocation list is downloaded
+	// from. It is used in addition to CRLFile.
+	//
+	// hcl: crl_url = string
+	CRLURL string
+
 	// CertFile is used to provide a TLS certificate that is used for serving
 	// TLS connections. Must be provided to serve TLS connections.
 	//
 	// hcl: cert_file = string
 	CertFile string
 
+	// CheckIntervalJitter is the percentage of the interval by which the
+	// runs of script, HTTP, TCP, gRPC and Docker checks are randomly moved.
+	// It keeps checks which were registered together from running at the
+	// same time forever. The average interval stays the same.
+	//
+	// hcl: check_interval_jitter = int
+	CheckIntervalJitter int
+
+	// CheckStateMaxAge is the age after which the persisted state of a TTL
+	// check isn't restored on a restart of the agent, even if its TTL didn't
+	// expire yet. Zero means the states are restored until their TTL
+	// expires.
+	//
+	// hcl: check_state_max_age = "duration"
+	CheckStateMaxAge time.Duration
+
 	// CheckUpdateInterval controls the interval on which the output of a health check
 	// is updated if there is no change to the state. For example, a check in a steady
 	// state may run every 5 second generating a unique output (timestamp, etc), forcing
@@ -579,6 +767,40 @@ type RuntimeConfig struct {
 	// flag: -dev
 	DevMode bool
 
+	// DevPersist keeps the state of a dev mode agent in the DataDir instead
+	// of memory so that it survives a restart. All other dev mode defaults
+	// still apply.
+	//
+	// flag: -dev-persist string
+	DevPersist bool
+
+	// DeregisterCriticalServiceAfterDefault is the timeout after which a
+	// service is deregistered when one of its checks is critical, for the
+	// service checks which don't set their own deregister_critical_service_after.
+	// Zero disables the default. (reloadable)
+	//
+	// hcl: deregister_critical_service_after_default = "duration"
+	DeregisterCriticalServiceAfterDefault time.Duration
+
+	// DeregisterCriticalServiceAfterMin is the minimum deregister critical
+	// service after timeout of the checks. Lower timeouts are raised to it
+	// so that services are not removed from the catalog by a short outage.
+	// CheckDeregisterIntervalMin applies instead when it is higher.
+	// (reloadable)
+	//
+	// hcl: deregister_critical_service_after_min = "duration"
+	DeregisterCriticalServiceAfterMin time.Duration
+
+	// DeregisterServicesOnShutdown is the default for removing the locally
+	// registered services from the catalog when the agent leaves the
+	// cluster gracefully. Services can override it with their own
+	// deregister_on_shutdown setting. Services which are not deregistered
+	// are retained together with the node, which is marked as critical,
+	// until the agent rejoins or the node is reaped.
+	//
+	// hcl: deregister_services_on_shutdown = (true|false)
+	DeregisterServicesOnShutdown bool
+
 	// DisableAnonymousSignature is used to turn off the anonymous signature
 	// send with the update check. This is used to deduplicate messages.
 	//
@@ -647,6 +869,13 @@ type RuntimeConfig struct {
 	// hcl: enable_debug = (true|false)
 	EnableDebug bool
 
+	// EnableFaultInjection enables the /v1/agent/fault-injection endpoint
+	// which injects synthetic failures like RPC latency, dropped RPCs and
+	// leadership churn. This is only meant for test clusters.
+	//
+	// hcl: enable_fault_injection = (true|false)
+	EnableFaultInjection bool
+
 	// EnableLocalScriptChecks controls whether health checks declared from the local
 	// config file which execute scripts are enabled. This includes regular script
 	// checks and Docker checks.
@@ -677,6 +906,13 @@ type RuntimeConfig struct {
 	// flag: -ui
 	EnableUI bool
 
+	// EnableWindowsEventLog is used to also tee all the logs over to the
+	// Windows application event log. Only supported on Windows. Other
+	// platforms will generate an error.
+	//
+	// hcl: enable_windows_event_log = (true|false)
+	EnableWindowsEventLog bool
+
 	// EncryptKey contains the encryption key to use for the Serf communication.
 	//
 	// hcl: encrypt = string
@@ -725,6 +961,9 @@ type RuntimeConfig struct {
 	// space separated list of ip addresses, UNIX socket paths and/or
 	// go-sockaddr templates. UNIX socket paths must be written as
 	// 'unix://<full path>', e.g. 'unix:///var/run/consul-http.sock'.
+	// On Windows, named pipes can be used with 'npipe://<pipe path>',
+	// e.g. 'npipe:////./pipe/consul'. They are stored as *net.UnixAddr
+	// with the "npipe" network.
 	//
 	// If 'addresses.http' was not provided the 'client_addr' addresses are
 	// used.
@@ -760,6 +999,44 @@ type RuntimeConfig struct {
 	// hcl: ports { https = int }
 	HTTPSPort int
 
+	// Hooks are run on agent lifecycle events, e.g. when the server becomes
+	// the leader or the state of the check of a local service changes.
+	//
+	// hcl: hooks = [
+	//   {
+	//     events = []string
+	//     args = []string
+	//     http = string
+	//     method = string
+	//     header = map[string][]string
+	//     payload = string
+	//     timeout = "duration"
+	//   },
+	//   ...
+	// ]
+	Hooks []RuntimeHookConfig
+
+	// KVRecycleBinRetention is how long the servers keep deleted KV entries
+	// in the recycle bin, from where they can be restored. Zero disables the
+	// recycle bin.
+	//
+	// hcl: kv_recycle_bin_retention = "duration"
+	KVRecycleBinRetention time.Duration
+
+	// KVReplicationConflictPolicy decides what happens to the keys under the
+	// replicated prefixes of a secondary datacenter which don't exist in
+	// the primary datacenter. "overwrite" deletes them and "keep-local"
+	// keeps them.
+	//
+	// hcl: kv_replication { conflict_policy = ("overwrite"|"keep-local") }
+	KVReplicationConflictPolicy string
+
+	// KVReplicationPrefixes are the KV prefixes which the servers of a
+	// secondary datacenter replicate from the primary datacenter.
+	//
+	// hcl: kv_replication { prefixes = []string }
+	KVReplicationPrefixes []string
+
 	// KeyFile is used to provide a TLS key that is used for serving TLS
 	// connections. Must be provided to serve TLS connections.
 	//
@@ -826,11 +1103,26 @@ type RuntimeConfig struct {
 	// flag: -non-voting-server
 	NonVotingServer bool
 
+	// OCSPStapling enables fetching the OCSP response for CertFile from its
+	// OCSP responder and stapling it to the TLS handshakes of incoming
+	// connections, so that peers with VerifyOCSP don't need to ask the
+	// responder.
+	//
+	// hcl: ocsp_stapling = (true|false)
+	OCSPStapling bool
+
 	// PidFile is the file to store our PID in.
 	//
 	// hcl: pid_file = string
 	PidFile string
 
+	// PreparedQuerySlowThreshold is the execution time above which the
+	// servers log prepared query executions as slow, including the
+	// datacenters they failed over to. Zero disables the slow query log.
+	//
+	// hcl: prepared_query_slow_threshold = "duration"
+	PreparedQuerySlowThreshold time.Duration
+
 	// PrimaryDatacenter is the central datacenter that holds authoritative
 	// ACL records, replicates intentions and holds the root CA for Connect.
 	// This must be the same for the entire cluster. Off by default.
@@ -860,6 +1152,18 @@ type RuntimeConfig struct {
 	// hcl: performance { rpc_hold_timeout = "duration" }
 	RPCHoldTimeout time.Duration
 
+	// RPCRetryMaxInterval caps the interval between two retries of a held
+	// RPC, which grows exponentially from 100ms.
+	//
+	// hcl: performance { rpc_retry_max_interval = "duration" }
+	RPCRetryMaxInterval time.Duration
+
+	// RPCRetryJitter is the fraction of the RPC retry interval which is
+	// added as a random delay to keep agents from retrying in sync.
+	//
+	// hcl: performance { rpc_retry_jitter = float64 }
+	RPCRetryJitter float64
+
 	// RPCRateLimit and RPCMaxBurst control how frequently RPC calls are allowed
 	// to happen. In any large enough time interval, rate limiter limits the
 	// rate to RPCRate tokens per second, with a maximum burst size of
@@ -873,11 +1177,48 @@ type RuntimeConfig struct {
 	RPCRateLimit rate.Limit
 	RPCMaxBurst  int
 
+	// MaxQueryTime is the longest time a blocking query can wait for a
+	// change on the servers, and DefaultQueryTime is the time it waits
+	// when the client doesn't ask for one.
+	//
+	// hcl: limits { max_query_time = "duration" default_query_time = "duration" }
+	MaxQueryTime     time.Duration
+	DefaultQueryTime time.Duration
+
+	// MaxBlockingQueriesPerToken limits the number of blocking queries a
+	// single token can have in progress on a server. Zero disables the
+	// limit.
+	//
+	// hcl: limits { max_blocking_queries_per_token = int }
+	MaxBlockingQueriesPerToken int
+
 	// RPCProtocol is the Consul protocol version to use.
 	//
 	// hcl: protocol = int
 	RPCProtocol int
 
+	// RPCRoutes configures how the servers forward the requests for remote
+	// datacenters. A route either sends the requests for a datacenter
+	// through other datacenters, picked at random according to their
+	// weights, or denies them. Requests which were already forwarded by
+	// another datacenter always go directly to their destination.
+	//
+	// hcl: rpc_routes = [
+	//   {
+	//     # datacenter is the destination the route applies to.
+	//     datacenter = string
+	//
+	//     # via maps the datacenters the requests are forwarded through
+	//     # to their weights.
+	//     via = map[string]int
+	//
+	//     # deny rejects the requests for the datacenter.
+	//     deny = (true|false)
+	//   },
+	//   ...
+	// ]
+	RPCRoutes []structs.RPCRoute
+
 	// RaftProtocol sets the Raft protocol version to use on this server.
 	// Defaults to 3.
 	//
@@ -918,6 +1259,21 @@ type RuntimeConfig struct {
 	// flag: -rejoin
 	RejoinAfterLeave bool
 
+	// RequestLogSampleRate is the fraction of HTTP and RPC requests which
+	// are logged with their latency and the accessor of their token.
+	//
+	// hcl: request_logging { sample_rate = float64 }
+	RequestLogSampleRate float64
+
+	// RequestLogSlowReadThreshold and RequestLogSlowWriteThreshold are the
+	// latencies above which HTTP and RPC reads and writes are logged as
+	// slow. Blocking queries are never logged as slow. Zero disables the
+	// slow request log.
+	//
+	// hcl: request_logging { slow_read_threshold = "duration" slow_write_threshold = "duration" }
+	RequestLogSlowReadThreshold  time.Duration
+	RequestLogSlowWriteThreshold time.Duration
+
 	// RetryJoinIntervalLAN specifies the amount of time to wait in between join
 	// attempts on agent start. The minimum allowed value is 1 second and
 	// the default is 30s.
@@ -966,6 +1322,32 @@ type RuntimeConfig struct {
 	// flag: -retry-join-wan string -retry-join-wan string
 	RetryJoinWAN []string
 
+	// ScriptCheckCPUTime is the CPU time after which a script check is
+	// killed. It is only supported on Linux.
+	//
+	// hcl: script_check_limits { cpu_time = "duration" }
+	ScriptCheckCPUTime time.Duration
+
+	// ScriptCheckCgroup is the path of a cgroup directory script checks
+	// are moved into, e.g. to limit their memory and CPU usage together. It
+	// is only supported on Linux.
+	//
+	// hcl: script_check_limits { cgroup = string }
+	ScriptCheckCgroup string
+
+	// ScriptCheckMemoryBytes is the maximum size of the address space of a
+	// script check. It is only supported on Linux.
+	//
+	// hcl: script_check_limits { memory_bytes = int }
+	ScriptCheckMemoryBytes int
+
+	// ScriptCheckUser is the name or the numeric ID of the user script
+	// checks run as instead of the user of the agent. The agent needs to
+	// run as root to change the user.
+	//
+	// hcl: script_check_limits { user = string }
+	ScriptCheckUser string
+
 	// SegmentName is the network segment for this client to join.
 	// (Enterprise-only)
 	//
@@ -1044,6 +1426,27 @@ type RuntimeConfig struct {
 	// hcl: ports { serf_wan = int }
 	SerfPortWAN int
 
+	// GossipKeyRotationInterval is how often the servers of the primary
+	// datacenter rotate the gossip encryption keys of all datacenters. Zero
+	// disables the rotation.
+	//
+	// hcl: gossip_key_rotation { interval = "duration" }
+	GossipKeyRotationInterval time.Duration
+
+	// GossipKeyRotationRetireAfter is how long the previous primary key stays
+	// installed after a rotation made the new key primary. Defaults to 1h.
+	//
+	// hcl: gossip_key_rotation { retire_after = "duration" }
+	GossipKeyRotationRetireAfter time.Duration
+
+	// GossipLANProfile is the name of the gossip tuning profile of the LAN
+	// pool. It provides the defaults for the other GossipLAN values. A
+	// profile selected through the operator API takes precedence over the
+	// configured one.
+	//
+	// hcl: gossip_lan { profile = (lan|wan|cloud|large-cluster) }
+	GossipLANProfile string
+
 	// GossipLANGossipInterval is the interval between sending messages that need
 	// to be gossiped that haven't been able to piggyback on probing messages.
 	// If this is set to zero, non-piggyback gossip is disabled. By lowering
@@ -1121,6 +1524,14 @@ type RuntimeConfig struct {
 	// hcl: gossip_lan { retransmit_mult = int }
 	GossipLANRetransmitMult int
 
+	// GossipWANProfile is the name of the gossip tuning profile of the WAN
+	// pool. It provides the defaults for the other GossipWAN values. A
+	// profile selected through the operator API takes precedence over the
+	// configured one.
+	//
+	// hcl: gossip_wan { profile = (lan|wan|cloud|large-cluster) }
+	GossipWANProfile string
+
 	// GossipWANGossipInterval  is the interval between sending messages that need
 	// to be gossiped that haven't been able to piggyback on probing messages.
 	// If this is set to zero, non-piggyback gossip is disabled. By lowering
@@ -1218,6 +1629,13 @@ type RuntimeConfig struct {
 	// hcl: ports { server = int }
 	ServerPort int
 
+	// ServiceMetaDefaults contains metadata key/value pairs which are added
+	// to the meta of every service registered with the agent, unless the
+	// service defines the key itself. (reloadable)
+	//
+	// hcl: service_meta_defaults = map[string]string
+	ServiceMetaDefaults map[string]string
+
 	// Services contains the provided service definitions:
 	//
 	// hcl: services = [
@@ -1230,6 +1648,7 @@ type RuntimeConfig struct {
 	//     checks = [ { check definition}, ... ]
 	//     token = string
 	//     enable_tag_override = (true|false)
+	//     deregister_on_shutdown = (true|false)
 	//   },
 	//   ...
 	// ]
@@ -1325,6 +1744,22 @@ type RuntimeConfig struct {
 	// hcl: tagged_addresses = map[string]string
 	TaggedAddresses map[string]string
 
+	// Templates are rendered by the agent with data from the KV store and
+	// the catalog and rerendered whenever that data changes.
+	//
+	// hcl: templates = [
+	//   {
+	//     source = string
+	//     destination = string
+	//     perms = string
+	//     command = string
+	//     args = []string
+	//     command_timeout = "duration"
+	//   },
+	//   ...
+	// ]
+	Templates []render.Template
+
 	// TranslateWANAddrs controls whether or not Consul should prefer
 	// the "wan" tagged address when doing lookups in remote datacenters.
 	// See TaggedAddresses below for more details.
@@ -1339,6 +1774,26 @@ type RuntimeConfig struct {
 	// flag: -ui-dir string
 	UIDir string
 
+	// UIMetricsProxyBaseURL is the URL of the Prometheus server the UI
+	// metrics proxy forwards queries to. The proxy is disabled if it is
+	// empty.
+	//
+	// hcl: ui_metrics_proxy { base_url = string }
+	UIMetricsProxyBaseURL string
+
+	// UIMetricsProxyPathAllowlist are the paths of the Prometheus API the
+	// UI metrics proxy forwards requests for. The default allows instant
+	// and range queries.
+	//
+	// hcl: ui_metrics_proxy { path_allowlist = []string }
+	UIMetricsProxyPathAllowlist []string
+
+	// UIMetricsProxyAddHeaders are added to the requests of the UI metrics
+	// proxy, e.g. to authenticate with the Prometheus server.
+	//
+	// hcl: ui_metrics_proxy { add_headers = map[string]string }
+	UIMetricsProxyAddHeaders map[string]string
+
 	// UnixSocketGroup contains the group of the file permissions when
 	// Consul binds to UNIX sockets.
 	//
@@ -1381,6 +1836,14 @@ type RuntimeConfig struct {
 	// hcl: verify_incoming_rpc = (true|false)
 	VerifyIncomingRPC bool
 
+	// VerifyOCSP enables checking the certificates of peers with the OCSP
+	// responder they list, or the OCSP response stapled by a server.
+	// Certificates reported as revoked are rejected, while failures to reach
+	// the responder are only logged.
+	//
+	// hcl: verify_ocsp = (true|false)
+	VerifyOCSP bool
+
 	// VerifyOutgoing is used to verify the authenticity of outgoing
 	// connections. This means that TLS requests are used. TLS connections must
 	// match a provided certificate authority. This is used to verify
@@ -1400,6 +1863,37 @@ type RuntimeConfig struct {
 	// hcl: verify_server_hostname = (true|false)
 	VerifyServerHostname bool
 
+	// VerifyServerHostnamePolicies refine the hostname verification of the
+	// servers of some datacenters, e.g. to accept other names or to exempt
+	// datacenters which are not migrated yet. The servers of datacenters
+	// without a policy have to present a certificate valid for
+	// server.<datacenter>.<domain>.
+	//
+	// hcl: verify_server_hostname_policies = [
+	//   {
+	//     # datacenter is the datacenter the policy applies to, "*"
+	//     # applies to all datacenters without their own policy.
+	//     datacenter = string
+	//
+	//     # names are the accepted server names, a leading "*." matches
+	//     # any single label.
+	//     names = []string
+	//
+	//     # exempt only verifies that the certificates are signed by the CA.
+	//     exempt = (true|false)
+	//   },
+	//   ...
+	// ]
+	VerifyServerHostnamePolicies []tlsutil.ServerHostnamePolicy
+
+	// VirtualIPCIDR is the range from which the servers allocate the virtual
+	// IPs of the services, which are resolved with
+	// <service>.virtual.<domain>. It should be the same on all servers and
+	// changing it changes the virtual IPs of all the services.
+	//
+	// hcl: virtual_ip_cidr = string
+	VirtualIPCIDR string
+
 	// Watches are used to monitor various endpoints and to invoke a
 	// handler to act appropriately. These are managed entirely in the
 	// agent layer using the standard APIs.
@@ -1415,9 +1909,24 @@ type RuntimeConfig struct {
 	Watches []map[string]interface{}
 }
 
+// RevocationConfig returns the configuration of the revocation checks of
+// the certificates of TLS peers.
+func (c *RuntimeConfig) RevocationConfig() tlsutil.RevocationConfig {
+	return tlsutil.RevocationConfig{
+		CRLFile:            c.CRLFile,
+		CRLURL:             c.CRLURL,
+		CRLRefreshInterval: c.CRLRefreshInterval,
+		VerifyOCSP:         c.VerifyOCSP,
+		OCSPStapling:       c.OCSPStapling,
+		CAFile:             c.CAFile,
+		CAPath:             c.CAPath,
+		CertFile:           c.CertFile,
+	}
+}
+
 // IncomingHTTPSConfig returns the TLS configuration for HTTPS
-// connections to consul.
-func (c *RuntimeConfig) IncomingHTTPSConfig() (*tls.Config, error) {
+// connections to consul. The revocation checks are optional.
+func (c *RuntimeConfig) IncomingHTTPSConfig(revocation *tlsutil.Revocation) (*tls.Config, error) {
 	tc := &tlsutil.Config{
 		VerifyIncoming:           c.VerifyIncoming || c.VerifyIncomingHTTPS,
 		VerifyOutgoing:           c.VerifyOutgoing,
@@ -1430,8 +1939,27 @@ func (c *RuntimeConfig) IncomingHTTPSConfig() (*tls.Config, error) {
 		TLSMinVersion:            c.TLSMinVersion,
 		CipherSuites:             c.TLSCipherSuites,
 		PreferServerCipherSuites: c.TLSPreferServerCipherSuites,
+		Revocation:               revocation,
+	}
+	tlsConfig, err := tc.IncomingTLSConfig()
+	if err != nil {
+		return nil, err
 	}
-	return tc.IncomingTLSConfig()
+
+	// Ask for the client certificates which are checked against the
+	// allowlist even if they are not required.
+	if c.HTTPClientCertAllowlistEnabled() && tlsConfig.ClientAuth == tls.NoClientCert {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// HTTPClientCertAllowlistEnabled returns true if the HTTP API is restricted
+// to allowed client certificates.
+func (c *RuntimeConfig) HTTPClientCertAllowlistEnabled() bool {
+	return len(c.HTTPClientCertAllowlistCommonNames) > 0 ||
+		len(c.HTTPClientCertAllowlistOrgUnits) > 0 ||
+		len(c.HTTPClientCertAllowlistURIs) > 0
 }
 
 func (c *RuntimeConfig) apiAddresses(maxPerType int) (unixAddrs, httpAddrs, httpsAddrs []string) {
@@ -1448,8 +1976,12 @@ func (c *RuntimeConfig) apiAddresses(maxPerType int) (unixAddrs, httpAddrs, http
 		unix_count := 0
 		http_count := 0
 		for _, addr := range c.HTTPAddrs {
-			switch addr.(type) {
+			switch x := addr.(type) {
 			case *net.UnixAddr:
+				// named pipes cannot be used by the API client
+				if x.Net == "npipe" {
+					continue
+				}
 				if maxPerType < 1 || unix_count < maxPerType {
 					unixAddrs = append(unixAddrs, addr.String())
 					unix_count += 1
@@ -1564,6 +2096,16 @@ func (c *RuntimeConfig) APIConfig(includeClientCerts bool) (*api.Config, error)
 	return cfg, nil
 }
 
+// RequestLogConfig returns the configuration of the HTTP and RPC request
+// logging.
+func (c *RuntimeConfig) RequestLogConfig() lib.RequestLogConfig {
+	return lib.RequestLogConfig{
+		SampleRate:         c.RequestLogSampleRate,
+		SlowReadThreshold:  c.RequestLogSlowReadThreshold,
+		SlowWriteThreshold: c.RequestLogSlowWriteThreshold,
+	}
+}
+
 // Sanitized returns a JSON/HCL compatible representation of the runtime
 // configuration where all fields with potential secrets had their
 // values replaced by 'hidden'. In addition, network addresses and
@@ -1576,7 +2118,7 @@ func (c *RuntimeConfig) Sanitized() map[string]interface{} {
 // may contain a secret.
 func isSecret(name string) bool {
 	name = strings.ToLower(name)
-	return strings.Contains(name, "key") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+	return strings.Contains(name, "key") || strings.Contains(name, "token") || strings.Contains(name, "secret") || strings.Contains(name, "authorization")
 }
 
 // cleanRetryJoin sanitizes the go-discover config strings key=val key=val...
@@ -1609,6 +2151,9 @@ func sanitize(name string, v reflect.Value) reflect.Value {
 		case *net.UDPAddr:
 			return reflect.ValueOf("udp://" + x.String())
 		case *net.UnixAddr:
+			if x.Net == "npipe" {
+				return reflect.ValueOf("npipe://" + x.String())
+			}
 			return reflect.ValueOf("unix://" + x.String())
 		case *net.IPAddr:
 			return reflect.ValueOf(x.IP.String())
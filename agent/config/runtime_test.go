@@ -20,7 +20,9 @@ import (
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/render"
 	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/consul/types"
 	"github.com/pascaldekloe/goe/verify"
 	"github.com/stretchr/testify/require"
@@ -299,6 +301,60 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 				rt.GRPCAddrs = []net.Addr{tcpAddr("127.0.0.1:8502")}
 			},
 		},
+		{
+			desc: "-dev-persist",
+			args: []string{
+				`-dev`,
+				`-dev-persist=` + dataDir,
+			},
+			patch: func(rt *RuntimeConfig) {
+				rt.AdvertiseAddrLAN = ipAddr("127.0.0.1")
+				rt.AdvertiseAddrWAN = ipAddr("127.0.0.1")
+				rt.BindAddr = ipAddr("127.0.0.1")
+				rt.ConnectEnabled = true
+				rt.DataDir = dataDir
+				rt.DevMode = true
+				rt.DevPersist = true
+				rt.DisableAnonymousSignature = true
+				rt.DisableKeyringFile = true
+				rt.EnableDebug = true
+				rt.EnableUI = true
+				rt.LeaveOnTerm = false
+				rt.LogLevel = "DEBUG"
+				rt.RPCAdvertiseAddr = tcpAddr("127.0.0.1:8300")
+				rt.RPCBindAddr = tcpAddr("127.0.0.1:8300")
+				rt.SerfAdvertiseAddrLAN = tcpAddr("127.0.0.1:8301")
+				rt.SerfAdvertiseAddrWAN = tcpAddr("127.0.0.1:8302")
+				rt.SerfBindAddrLAN = tcpAddr("127.0.0.1:8301")
+				rt.SerfBindAddrWAN = tcpAddr("127.0.0.1:8302")
+				rt.ServerMode = true
+				rt.SkipLeaveOnInt = true
+				rt.TaggedAddresses = map[string]string{"lan": "127.0.0.1", "wan": "127.0.0.1"}
+				rt.ConsulCoordinateUpdatePeriod = 100 * time.Millisecond
+				rt.ConsulRaftElectionTimeout = 52 * time.Millisecond
+				rt.ConsulRaftHeartbeatTimeout = 35 * time.Millisecond
+				rt.ConsulRaftLeaderLeaseTimeout = 20 * time.Millisecond
+				rt.GossipLANGossipInterval = 100 * time.Millisecond
+				rt.GossipLANProbeInterval = 100 * time.Millisecond
+				rt.GossipLANProbeTimeout = 100 * time.Millisecond
+				rt.GossipLANSuspicionMult = 3
+				rt.GossipWANGossipInterval = 100 * time.Millisecond
+				rt.GossipWANProbeInterval = 100 * time.Millisecond
+				rt.GossipWANProbeTimeout = 100 * time.Millisecond
+				rt.GossipWANSuspicionMult = 3
+				rt.ConsulServerHealthInterval = 10 * time.Millisecond
+				rt.GRPCPort = 8502
+				rt.GRPCAddrs = []net.Addr{tcpAddr("127.0.0.1:8502")}
+			},
+		},
+		{
+			desc: "-dev-persist without -dev",
+			args: []string{
+				`-dev-persist=` + dataDir,
+				`-data-dir=` + dataDir,
+			},
+			err: "-dev-persist requires -dev",
+		},
 		{
 			desc: "-disable-host-node-id",
 			args: []string{
@@ -1016,6 +1072,23 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 				rt.DataDir = dataDir
 			},
 		},
+		{
+			desc: "http named pipe",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "addresses": { "http": "npipe:////./pipe/consul 1.2.3.4" } }`},
+			hcl:  []string{`addresses { http = "npipe:////./pipe/consul 1.2.3.4" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.HTTPAddrs = []net.Addr{&net.UnixAddr{Net: "npipe", Name: "//./pipe/consul"}, tcpAddr("1.2.3.4:8500")}
+				rt.DataDir = dataDir
+			},
+		},
+		{
+			desc: "https named pipe",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "addresses": { "https": "npipe:////./pipe/consul" }, "ports": { "https": 8501 } }`},
+			hcl:  []string{`addresses { https = "npipe:////./pipe/consul" } ports { https = 8501 }`},
+			err:  "Only the HTTP address can be a named pipe",
+		},
 		{
 			desc: "advertise address lan template",
 			args: []string{`-data-dir=` + dataDir},
@@ -1049,6 +1122,80 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 				rt.DataDir = dataDir
 			},
 		},
+		{
+			desc: "advertise address wan template with fallback and family",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{
+				"advertise_addr_wan": "{{ printf \"\" }} || {{ printf \"1.2.3.4 dead:beef::1\" }}",
+				"advertise_addr_wan_family": "ipv6"
+			}`},
+			hcl: []string{`
+				advertise_addr_wan = "{{ printf \"\" }} || {{ printf \"1.2.3.4 dead:beef::1\" }}"
+				advertise_addr_wan_family = "ipv6"
+			`},
+			patch: func(rt *RuntimeConfig) {
+				rt.AdvertiseAddrWAN = ipAddr("dead:beef::1")
+				rt.SerfAdvertiseAddrWAN = tcpAddr("[dead:beef::1]:8302")
+				rt.TaggedAddresses = map[string]string{
+					"lan": "10.0.0.1",
+					"wan": "dead:beef::1",
+				}
+				rt.DataDir = dataDir
+			},
+		},
+		{
+			desc: "advertise address template without single address",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr": "{{ printf \"1.2.3.4 5.6.7.8\" }} || 1.2.3" }`},
+			hcl:  []string{`advertise_addr = "{{ printf \"1.2.3.4 5.6.7.8\" }} || 1.2.3"`},
+			err: `advertise_addr: no single address found in "{{ printf \"1.2.3.4 5.6.7.8\" }} || 1.2.3":
+	"{{ printf \"1.2.3.4 5.6.7.8\" }}": evaluated to "1.2.3.4 5.6.7.8": multiple addresses found: 1.2.3.4 5.6.7.8
+	"1.2.3": evaluated to "1.2.3": invalid ip address: 1.2.3`,
+		},
+		{
+			desc: "advertise address wan family mismatch",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr_wan_family": "ipv6" }`},
+			hcl:  []string{`advertise_addr_wan_family = "ipv6"`},
+			err:  "advertise_addr_wan: 10.0.0.1 is not an ipv6 address",
+		},
+		{
+			desc: "advertise address invalid family",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr_family": "ipx" }`},
+			hcl:  []string{`advertise_addr_family = "ipx"`},
+			err:  `advertise_addr_family: invalid address family "ipx". Must be one of 'ipv4' or 'ipv6'`,
+		},
+		{
+			desc: "dual-stack advertise addresses",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr_ipv6": "{{ printf \"1.2.3.4 dead:beef::1\" }}" }`},
+			hcl:  []string{`advertise_addr_ipv6 = "{{ printf \"1.2.3.4 dead:beef::1\" }}"`},
+			patch: func(rt *RuntimeConfig) {
+				rt.TaggedAddresses = map[string]string{
+					"lan":      "10.0.0.1",
+					"lan_ipv4": "10.0.0.1",
+					"lan_ipv6": "dead:beef::1",
+					"wan":      "10.0.0.1",
+					"wan_ipv4": "10.0.0.1",
+				}
+				rt.DataDir = dataDir
+			},
+		},
+		{
+			desc: "dual-stack advertise address of wrong family",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr_wan_ipv6": "1.2.3.4" }`},
+			hcl:  []string{`advertise_addr_wan_ipv6 = "1.2.3.4"`},
+			err:  `advertise_addr_wan_ipv6: no single ipv6 address found in "1.2.3.4"`,
+		},
+		{
+			desc: "dual-stack advertise address any",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "advertise_addr_ipv6": "::" }`},
+			hcl:  []string{`advertise_addr_ipv6 = "::"`},
+			err:  "advertise_addr_ipv6 cannot be 0.0.0.0, :: or [::]",
+		},
 		{
 			desc: "advertise address lan with ports",
 			args: []string{`-data-dir=` + dataDir},
@@ -1350,6 +1497,18 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 				rt.DataDir = dataDir
 			},
 		},
+		{
+			desc: "raft election and leader lease tuning",
+			args: []string{`-data-dir=` + dataDir},
+			json: []string{`{ "performance": { "raft_multiplier": 2, "raft_election_multiplier": 8, "raft_leader_lease_timeout": "300ms" } }`},
+			hcl:  []string{`performance = { raft_multiplier = 2 raft_election_multiplier = 8 raft_leader_lease_timeout = "300ms" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.ConsulRaftElectionTimeout = 8 * 1000 * time.Millisecond
+				rt.ConsulRaftHeartbeatTimeout = 2 * 1000 * time.Millisecond
+				rt.ConsulRaftLeaderLeaseTimeout = 300 * time.Millisecond
+				rt.DataDir = dataDir
+			},
+		},
 
 		// ------------------------------------------------------------
 		// validations
@@ -1542,6 +1701,240 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			hcl:  []string{`bootstrap_expect = -1`},
 			err:  "bootstrap_expect cannot be -1. Must be greater than or equal to zero",
 		},
+		{
+			desc: "script_check_limits.cpu_time negative",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "script_check_limits": { "cpu_time": "-1s" } }`},
+			hcl:  []string{`script_check_limits { cpu_time = "-1s" }`},
+			err:  "script_check_limits.cpu_time cannot be negative",
+		},
+		{
+			desc: "check_state_max_age negative",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "check_state_max_age": "-1s" }`},
+			hcl:  []string{`check_state_max_age = "-1s"`},
+			err:  "check_state_max_age cannot be negative",
+		},
+		{
+			desc: "rpc_routes via local datacenter",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "rpc_routes": [ { "datacenter": "b", "via": { "a": 1 } } ] }`},
+			hcl:  []string{`rpc_routes { datacenter = "b" via { a = 1 } }`},
+			err:  "rpc_routes[b]: cannot route via the local datacenter",
+		},
+		{
+			desc: "rpc_routes deny and via",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "rpc_routes": [ { "datacenter": "B", "deny": true, "via": { "c": 1 } } ] }`},
+			hcl:  []string{`rpc_routes { datacenter = "B" deny = true via { c = 1 } }`},
+			err:  "rpc_routes[b]: cannot set both deny and via",
+		},
+		{
+			desc: "rpc_routes weight not positive",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "rpc_routes": [ { "datacenter": "b", "via": { "c": 0 } } ] }`},
+			hcl:  []string{`rpc_routes { datacenter = "b" via { c = 0 } }`},
+			err:  `rpc_routes[b]: weight of "c" must be positive`,
+		},
+		{
+			desc: "acl.tokens.introduction without token persistence",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "acl": { "enabled": true, "tokens": { "introduction": "foo" } } }`},
+			hcl:  []string{`acl { enabled = true tokens { introduction = "foo" } }`},
+			err:  "acl.tokens.introduction requires acl.enable_token_persistence",
+		},
+		{
+			desc: "limits.acl_bootstrap_max_burst not positive",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "limits": { "acl_bootstrap_max_burst": 0 } }`},
+			hcl:  []string{`limits { acl_bootstrap_max_burst = 0 }`},
+			err:  "limits.acl_bootstrap_max_burst must be positive, got 0",
+		},
+		{
+			desc: "limits.max_query_time not positive",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "limits": { "max_query_time": "0s" } }`},
+			hcl:  []string{`limits { max_query_time = "0s" }`},
+			err:  "limits.max_query_time must be positive, got 0s",
+		},
+		{
+			desc: "limits.default_query_time larger than max_query_time",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "limits": { "max_query_time": "1m", "default_query_time": "2m" } }`},
+			hcl:  []string{`limits { max_query_time = "1m" default_query_time = "2m" }`},
+			err:  "limits.default_query_time (2m0s) cannot be larger than limits.max_query_time (1m0s)",
+		},
+		{
+			desc: "limits.max_blocking_queries_per_token negative",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "limits": { "max_blocking_queries_per_token": -1 } }`},
+			hcl:  []string{`limits { max_blocking_queries_per_token = -1 }`},
+			err:  "limits.max_blocking_queries_per_token cannot be negative",
+		},
+		{
+			desc: "http_config.client_cert_allowlist without CA",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "http_config": { "client_cert_allowlist": { "common_names": ["deploy-*"] } } }`},
+			hcl:  []string{`http_config { client_cert_allowlist { common_names = ["deploy-*"] } }`},
+			err:  "http_config.client_cert_allowlist requires ca_file or ca_path to verify the client certificates",
+		},
+		{
+			desc: "crl_file without CA",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "crl_file": "crl.pem" }`},
+			hcl:  []string{`crl_file = "crl.pem"`},
+			err:  "crl_file, crl_url, verify_ocsp and ocsp_stapling require ca_file or ca_path",
+		},
+		{
+			desc: "crl_refresh_interval not positive",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "ca_file": "ca.pem", "crl_url": "https://crl.example.com/ca.crl", "crl_refresh_interval": "0s" }`},
+			hcl:  []string{`ca_file = "ca.pem" crl_url = "https://crl.example.com/ca.crl" crl_refresh_interval = "0s"`},
+			err:  "crl_refresh_interval must be positive, got 0s",
+		},
+		{
+			desc: "ocsp_stapling without cert_file",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "ca_file": "ca.pem", "ocsp_stapling": true }`},
+			hcl:  []string{`ca_file = "ca.pem" ocsp_stapling = true`},
+			err:  "ocsp_stapling requires cert_file",
+		},
+		{
+			desc: "verify_server_hostname_policies without verify_server_hostname",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "verify_server_hostname_policies": [ { "datacenter": "b", "exempt": true } ] }`},
+			hcl:  []string{`verify_server_hostname_policies { datacenter = "b" exempt = true }`},
+			err:  "verify_server_hostname_policies requires verify_server_hostname",
+		},
+		{
+			desc: "verify_server_hostname_policies exempt and names",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "verify_server_hostname": true, "verify_server_hostname_policies": [ { "datacenter": "B", "exempt": true, "names": ["*.b.example.com"] } ] }`},
+			hcl:  []string{`verify_server_hostname = true verify_server_hostname_policies { datacenter = "B" exempt = true names = ["*.b.example.com"] }`},
+			err:  "verify_server_hostname_policies[b]: cannot set both exempt and names",
+		},
+		{
+			desc: "verify_server_hostname_policies invalid wildcard",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "verify_server_hostname": true, "verify_server_hostname_policies": [ { "datacenter": "b", "names": ["server.*.example.com"] } ] }`},
+			hcl:  []string{`verify_server_hostname = true verify_server_hostname_policies { datacenter = "b" names = ["server.*.example.com"] }`},
+			err:  `verify_server_hostname_policies[b]: invalid name "server.*.example.com", only a leading "*." wildcard is supported`,
+		},
+		{
+			desc: "virtual_ip_cidr invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "virtual_ip_cidr": "240.0.0.0" }`},
+			hcl:  []string{`virtual_ip_cidr = "240.0.0.0"`},
+			err:  `virtual_ip_cidr: invalid CIDR address: 240.0.0.0`,
+		},
+		{
+			desc: "virtual_ip_cidr too small",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "virtual_ip_cidr": "240.0.0.0/31" }`},
+			hcl:  []string{`virtual_ip_cidr = "240.0.0.0/31"`},
+			err:  `virtual_ip_cidr: 240.0.0.0/31 has no room for virtual IPs`,
+		},
+		{
+			desc: "gossip_lan profile",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "gossip_lan": { "profile": "large-cluster", "probe_timeout": "3s" } }`},
+			hcl:  []string{`gossip_lan { profile = "large-cluster" probe_timeout = "3s" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.DataDir = dataDir
+				rt.GossipLANProfile = "large-cluster"
+				rt.GossipLANGossipInterval = 500 * time.Millisecond
+				rt.GossipLANGossipNodes = 6
+				rt.GossipLANProbeInterval = 2 * time.Second
+				rt.GossipLANProbeTimeout = 3 * time.Second
+				rt.GossipLANSuspicionMult = 6
+				rt.GossipLANRetransmitMult = 5
+			},
+		},
+		{
+			desc: "gossip_wan profile selected through the operator API",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "gossip_wan": { "profile": "wan" } }`},
+			hcl:  []string{`gossip_wan { profile = "wan" }`},
+			patch: func(rt *RuntimeConfig) {
+				rt.DataDir = dataDir
+				rt.GossipWANProfile = "cloud"
+				rt.GossipWANGossipInterval = 200 * time.Millisecond
+				rt.GossipWANGossipNodes = 4
+				rt.GossipWANProbeInterval = 2 * time.Second
+				rt.GossipWANProbeTimeout = 1 * time.Second
+				rt.GossipWANSuspicionMult = 5
+				rt.GossipWANRetransmitMult = 4
+			},
+			pre: func() {
+				writeFile(GossipProfilePath(dataDir, "wan"), []byte("cloud"))
+			},
+		},
+		{
+			desc: "gossip_lan unknown profile",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "gossip_lan": { "profile": "huge" } }`},
+			hcl:  []string{`gossip_lan { profile = "huge" }`},
+			err:  `gossip_lan.profile must be one of cloud, lan, large-cluster, wan, got "huge"`,
+		},
+		{
+			desc: "check_interval_jitter out of range",
+			args: []string{
+				`-datacenter=a`,
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "check_interval_jitter": 101 }`},
+			hcl:  []string{`check_interval_jitter = 101`},
+			err:  "check_interval_jitter cannot be 101. Must be between 0 and 100",
+		},
 		{
 			desc: "bootstrap-expect and dev mode",
 			args: []string{
@@ -1719,6 +2112,195 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			hcl:  []string{`dns_config = { a_record_limit = -1 }`},
 			err:  "dns_config.a_record_limit cannot be -1. Must be greater than or equal to zero",
 		},
+		{
+			desc: "deregister_critical_service_after_default invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "deregister_critical_service_after_default": "-1s" }`},
+			hcl:  []string{`deregister_critical_service_after_default = "-1s"`},
+			err:  "deregister_critical_service_after_default cannot be -1s. Must be greater than or equal to zero",
+		},
+		{
+			desc: "deregister_critical_service_after_min invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "deregister_critical_service_after_min": "-1s" }`},
+			hcl:  []string{`deregister_critical_service_after_min = "-1s"`},
+			err:  "deregister_critical_service_after_min cannot be -1s. Must be greater than or equal to zero",
+		},
+		{
+			desc: "deregister_critical_service_after_default below min",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "deregister_critical_service_after_default": "5m", "deregister_critical_service_after_min": "10m" }`},
+			hcl:  []string{`deregister_critical_service_after_default = "5m" deregister_critical_service_after_min = "10m"`},
+			err:  "deregister_critical_service_after_default cannot be 5m0s. Must be greater than or equal to deregister_critical_service_after_min (10m0s)",
+		},
+		{
+			desc: "ui_metrics_proxy.base_url invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "ui_metrics_proxy": { "base_url": "prometheus:9090" } }`},
+			hcl:  []string{`ui_metrics_proxy { base_url = "prometheus:9090" }`},
+			err:  `ui_metrics_proxy.base_url must be a valid http or https URL, got "prometheus:9090"`,
+		},
+		{
+			desc: "ui_metrics_proxy.path_allowlist invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "ui_metrics_proxy": { "path_allowlist": [ "api/v1/query" ] } }`},
+			hcl:  []string{`ui_metrics_proxy { path_allowlist = [ "api/v1/query" ] }`},
+			err:  `ui_metrics_proxy.path_allowlist cannot contain "api/v1/query". Paths must start with "/"`,
+		},
+		{
+			desc: "kv_recycle_bin_retention invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "kv_recycle_bin_retention": "-1s" }`},
+			hcl:  []string{`kv_recycle_bin_retention = "-1s"`},
+			err:  "kv_recycle_bin_retention cannot be -1s. Must be greater than or equal to zero",
+		},
+		{
+			desc: "kv_replication invalid conflict policy",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "kv_replication": { "conflict_policy": "merge" } }`},
+			hcl:  []string{`kv_replication { conflict_policy = "merge" }`},
+			err:  `kv_replication.conflict_policy must be "overwrite" or "keep-local", got "merge"`,
+		},
+		{
+			desc: "kv_replication overlapping prefixes",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "kv_replication": { "prefixes": ["config/", "config/app/"] } }`},
+			hcl:  []string{`kv_replication { prefixes = ["config/", "config/app/"] }`},
+			err:  `kv_replication.prefixes "config/" and "config/app/" overlap`,
+		},
+		{
+			desc: "kv_replication empty prefix",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "kv_replication": { "prefixes": [""] } }`},
+			hcl:  []string{`kv_replication { prefixes = [""] }`},
+			err:  `kv_replication.prefixes cannot contain an empty prefix`,
+		},
+		{
+			desc: "gossip_key_rotation.retire_after longer than the interval",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "gossip_key_rotation": { "interval": "1h", "retire_after": "2h" } }`},
+			hcl:  []string{`gossip_key_rotation { interval = "1h" retire_after = "2h" }`},
+			err:  `gossip_key_rotation.retire_after must be greater than zero and less than gossip_key_rotation.interval (1h0m0s), got 2h0m0s`,
+		},
+		{
+			desc: "performance.rpc_retry_jitter invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "performance": { "rpc_retry_jitter": 2 } }`},
+			hcl:  []string{`performance { rpc_retry_jitter = 2 }`},
+			err:  `performance.rpc_retry_jitter must be between 0 and 1, got 2`,
+		},
+		{
+			desc: "performance.ae_retry_max_interval invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "performance": { "ae_retry_max_interval": "0s" } }`},
+			hcl:  []string{`performance { ae_retry_max_interval = "0s" }`},
+			err:  `performance.ae_retry_max_interval cannot be 0s. Must be greater than zero`,
+		},
+		{
+			desc: "request_logging.sample_rate invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "request_logging": { "sample_rate": 1.5 } }`},
+			hcl:  []string{`request_logging { sample_rate = 1.5 }`},
+			err:  `request_logging.sample_rate must be between 0 and 1, got 1.5`,
+		},
+		{
+			desc: "request_logging.slow_read_threshold invalid",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "request_logging": { "slow_read_threshold": "-1s" } }`},
+			hcl:  []string{`request_logging { slow_read_threshold = "-1s" }`},
+			err:  `request_logging.slow_read_threshold cannot be -1s. Must be greater than or equal to zero`,
+		},
+		{
+			desc: "templates without destination",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "templates": [ { "source": "in.tpl" } ] }`},
+			hcl:  []string{`templates = [ { source = "in.tpl" } ]`},
+			err:  "templates[0]: source and destination must be set",
+		},
+		{
+			desc: "templates invalid perms",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "templates": [ { "source": "in.tpl", "destination": "out", "perms": "rw-" } ] }`},
+			hcl:  []string{`templates = [ { source = "in.tpl" destination = "out" perms = "rw-" } ]`},
+			err:  `templates[0].perms: invalid permissions: "rw-"`,
+		},
+		{
+			desc: "hooks invalid event",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "hooks": [ { "events": [ "became_leader", "crashed" ], "args": [ "true" ] } ] }`},
+			hcl:  []string{`hooks = [ { events = [ "became_leader", "crashed" ] args = [ "true" ] } ]`},
+			err:  `hooks[0]: invalid event "crashed", must be one of became_leader, lost_leader, joined, left, check_state_changed`,
+		},
+		{
+			desc: "hooks without handler",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "hooks": [ { "events": [ "joined" ] } ] }`},
+			hcl:  []string{`hooks = [ { events = [ "joined" ] } ]`},
+			err:  "hooks[0]: one of args or http must be set",
+		},
+		{
+			desc: "hooks with args and http",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "hooks": [ { "events": [ "joined" ], "args": [ "true" ], "http": "http://localhost/" } ] }`},
+			hcl:  []string{`hooks = [ { events = [ "joined" ] args = [ "true" ] http = "http://localhost/" } ]`},
+			err:  "hooks[0]: only one of args or http can be set",
+		},
+		{
+			desc: "dns_config.domain_recursors without recursors",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "dns_config": { "domain_recursors": { "corp.example.com": [] } } }`},
+			hcl:  []string{`dns_config = { domain_recursors = { "corp.example.com" = [] } }`},
+			err:  `dns_config.domain_recursors["corp.example.com"] must have at least one recursor`,
+		},
+		{
+			desc: "dns_config.domain_recursors any address",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "dns_config": { "domain_recursors": { "corp.example.com": ["0.0.0.0"] } } }`},
+			hcl:  []string{`dns_config = { domain_recursors = { "corp.example.com" = ["0.0.0.0"] } }`},
+			err:  "DNS recursor address cannot be 0.0.0.0, :: or [::]",
+		},
 		{
 			desc: "performance.raft_multiplier < 0",
 			args: []string{
@@ -1746,6 +2328,24 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			hcl:  []string{`performance = { raft_multiplier = 20 }`},
 			err:  `performance.raft_multiplier cannot be 20. Must be between 1 and 10`,
 		},
+		{
+			desc: "performance.raft_election_multiplier < performance.raft_multiplier",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "performance": { "raft_multiplier": 5, "raft_election_multiplier": 3 } }`},
+			hcl:  []string{`performance = { raft_multiplier = 5 raft_election_multiplier = 3 }`},
+			err:  `performance.raft_election_multiplier cannot be 3. Must be between performance.raft_multiplier (5) and 10`,
+		},
+		{
+			desc: "performance.raft_leader_lease_timeout > heartbeat timeout",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{`{ "performance": { "raft_multiplier": 1, "raft_leader_lease_timeout": "2s" } }`},
+			hcl:  []string{`performance = { raft_multiplier = 1 raft_leader_lease_timeout = "2s" }`},
+			err:  `performance.raft_leader_lease_timeout cannot be 2s. Must be between 5ms and the heartbeat timeout of 1s`,
+		},
 		{
 			desc: "node_name invalid",
 			args: []string{
@@ -1785,6 +2385,19 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			},
 			err: "Value is too long (limit: 512 characters)",
 		},
+		{
+			desc: "service_meta_defaults value too long",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{
+				`{ "service_meta_defaults": { "a": "` + randomString(520) + `" } }`,
+			},
+			hcl: []string{
+				`service_meta_defaults = { "a" = "` + randomString(520) + `" }`,
+			},
+			err: "Value is too long (limit: 512 characters)",
+		},
 		{
 			desc: "node_meta too many keys",
 			args: []string{
@@ -2171,6 +2784,19 @@ func TestConfigFlagsAndEdgecases(t *testing.T) {
 			},
 			err: `Value is too long`,
 		},
+		{
+			desc: "service with negative warmup",
+			args: []string{
+				`-data-dir=` + dataDir,
+			},
+			json: []string{
+				`{ "service": { "name": "a", "port": 80, "warmup": "-1s" } }`,
+			},
+			hcl: []string{
+				`service = { name = "a" port = 80 warmup = "-1s" }`,
+			},
+			err: `Invalid warmup for service a: must not be negative`,
+		},
 		{
 			desc: "service with wrong meta: too many meta",
 			args: []string{
@@ -2801,7 +3427,7 @@ func TestFullConfig(t *testing.T) {
 	dataDir := testutil.TempDir(t, "consul")
 	defer os.RemoveAll(dataDir)
 
-	flagSrc := []string{`-dev`}
+	flagSrc := []string{`-dev`, `-dev-persist=` + dataDir}
 	src := map[string]string{
 		"json": `{
 			"acl_agent_master_token": "furuQD0b",
@@ -2820,6 +3446,9 @@ func TestFullConfig(t *testing.T) {
 				"down_policy" : "03eb2aee",
 				"default_policy" : "72c2e7a0",
 				"enable_key_list_policy": false,
+				"enable_denied_permission_details": true,
+				"enable_token_persistence": true,
+				"blocking_query_recheck_interval": "2418s",
 				"policy_ttl": "1123s",
 				"token_ttl": "3321s",
 				"enable_token_replication" : true,
@@ -2828,7 +3457,8 @@ func TestFullConfig(t *testing.T) {
 					"agent_master" : "64fd0e08",
 					"replication" : "5795983a",
 					"agent" : "bed2377c",
-					"default" : "418fdff1"
+					"default" : "418fdff1",
+					"introduction" : "c5e3ab8d"
 				}
 			},
 			"addresses": {
@@ -2839,6 +3469,13 @@ func TestFullConfig(t *testing.T) {
 			},
 			"advertise_addr": "17.99.29.16",
 			"advertise_addr_wan": "78.63.37.19",
+			"advertise_addr_family": "ipv4",
+			"advertise_addr_wan_family": "ipv4",
+			"advertise_addr_ipv4": "17.99.29.16",
+			"advertise_addr_ipv6": "2001:db8::a9",
+			"advertise_addr_wan_ipv4": "78.63.37.19",
+			"advertise_addr_wan_ipv6": "2001:db8::3f",
+			"agent_profile": "Jt3KnF9q",
 			"autopilot": {
 				"cleanup_dead_servers": true,
 				"disable_upgrade_migration": true,
@@ -2854,6 +3491,9 @@ func TestFullConfig(t *testing.T) {
 			"ca_file": "erA7T0PM",
 			"ca_path": "mQEN1Mfp",
 			"cert_file": "7s4QAzDk",
+			"crl_file": "Wm3iGhBz",
+			"crl_refresh_interval": "17m",
+			"crl_url": "https://crl.example.com/Pq8nTrXe.crl",
 			"check": {
 				"id": "fZaCAXww",
 				"name": "OOM2eo0f",
@@ -2925,6 +3565,8 @@ func TestFullConfig(t *testing.T) {
 					"deregister_critical_service_after": "2366s"
 				}
 			],
+			"check_interval_jitter": 17,
+			"check_state_max_age": "24117s",
 			"check_update_interval": "16507s",
 			"client_addr": "93.83.18.19",
 			"connect": {
@@ -2946,6 +3588,7 @@ func TestFullConfig(t *testing.T) {
 				}
 			},
 			"gossip_lan" : {
+				"profile": "cloud",
 				"gossip_nodes": 6,
 				"gossip_interval" : "25252s",
 				"retransmit_mult" : 1234,
@@ -2954,6 +3597,7 @@ func TestFullConfig(t *testing.T) {
 				"probe_timeout"   : "102ms"
 			},
 			"gossip_wan" : {
+				"profile" : "large-cluster",
 				"gossip_nodes" : 2,
 				"gossip_interval" : "6966s",
 				"retransmit_mult" : 16384,
@@ -2963,6 +3607,9 @@ func TestFullConfig(t *testing.T) {
 			},
 			"data_dir": "` + dataDir + `",
 			"datacenter": "rzo029wg",
+			"deregister_critical_service_after_default": "4921s",
+			"deregister_critical_service_after_min": "3157s",
+			"deregister_services_on_shutdown": false,
 			"disable_anonymous_signature": true,
 			"disable_coordinates": true,
 			"disable_host_node_id": true,
@@ -2977,10 +3624,15 @@ func TestFullConfig(t *testing.T) {
 				"allow_stale": true,
 				"a_record_limit": 29907,
 				"disable_compression": true,
+				"domain_recursors": {
+					"corp.example.com": ["10.16.91.3", "10.16.91.4:5353"]
+				},
+				"enable_prepared_query_datacenter_txt": true,
 				"enable_truncate": true,
 				"max_stale": "29685s",
 				"node_ttl": "7084s",
 				"only_passing": true,
+				"recursor_health_check_interval": "2361s",
 				"recursor_timeout": "4427s",
 				"service_ttl": {
 					"*": "32030s"
@@ -2990,24 +3642,62 @@ func TestFullConfig(t *testing.T) {
 			"enable_acl_replication": true,
 			"enable_agent_tls_for_checks": true,
 			"enable_debug": true,
+			"enable_fault_injection": true,
 			"enable_script_checks": true,
 			"enable_local_script_checks": true,
 			"enable_syslog": true,
+			"enable_windows_event_log": true,
 			"encrypt": "A4wELWqH",
 			"encrypt_verify_incoming": true,
 			"encrypt_verify_outgoing": true,
+			"gossip_key_rotation": {
+				"interval": "2160h",
+				"retire_after": "2h"
+			},
 			"http_config": {
 				"block_endpoints": [ "RBvAFcGD", "fWOWFznh" ],
+				"client_cert_allowlist": {
+					"common_names": [ "deploy-*" ],
+					"organizational_units": [ "Z3hXKx0W" ],
+					"uris": [ "spiffe://qzjk5rw2/deploy/*" ],
+					"write_only": true
+				},
 				"response_headers": {
 					"M6TKa9NP": "xjuxjOzQ",
 					"JRCrHZed": "rl0mTx81"
 				}
 			},
+			"hooks": [
+				{
+					"events": [ "became_leader", "lost_leader" ],
+					"args": [ "dX0Qa1Pv", "-notify" ],
+					"timeout": "4178s"
+				},
+				{
+					"events": [ "check_state_changed" ],
+					"http": "https://hooks.example.com/uVlxcTLC",
+					"method": "PUT",
+					"header": {
+						"tvzoEHkq": [ "Qd3FJCVw", "uFjp7ZtG" ]
+					},
+					"payload": "{{ .Event }} {{ .Node }}"
+				}
+			],
 			"key_file": "IEkkwgIA",
+			"kv_recycle_bin_retention": "31h",
+			"kv_replication": {
+				"conflict_policy": "keep-local",
+				"prefixes": ["config/", "shared/"]
+			},
 			"leave_on_terminate": true,
 			"limits": {
+				"acl_bootstrap_rate": 0.5,
+				"acl_bootstrap_max_burst": 7,
 				"rpc_rate": 12029.43,
-				"rpc_max_burst": 44848
+				"rpc_max_burst": 44848,
+				"max_query_time": "2917s",
+				"default_query_time": "1283s",
+				"max_blocking_queries_per_token": 79
 			},
 			"log_level": "k1zo9Spt",
 			"node_id": "AsUIlw99",
@@ -3017,10 +3707,17 @@ func TestFullConfig(t *testing.T) {
 			},
 			"node_name": "otlLxGaI",
 			"non_voting_server": true,
+			"ocsp_stapling": true,
 			"performance": {
 				"leave_drain_time": "8265s",
 				"raft_multiplier": 5,
-				"rpc_hold_timeout": "15707s"
+				"raft_election_multiplier": 7,
+				"raft_leader_lease_timeout": "19374s",
+				"rpc_hold_timeout": "15707s",
+				"rpc_retry_max_interval": "24013s",
+				"rpc_retry_jitter": 0.25,
+				"ae_retry_max_interval": "31544s",
+				"ae_retry_jitter": 0.75
 			},
 			"pid_file": "43xN80Km",
 			"ports": {
@@ -3035,6 +3732,7 @@ func TestFullConfig(t *testing.T) {
 				"sidecar_max_port": 9999
 			},
 			"protocol": 30793,
+			"prepared_query_slow_threshold": "45ms",
 			"primary_datacenter": "ejtmd43d",
 			"raft_protocol": 19016,
 			"raft_snapshot_threshold": 16384,
@@ -3043,12 +3741,27 @@ func TestFullConfig(t *testing.T) {
 			"reconnect_timeout_wan": "26694s",
 			"recursors": [ "63.38.39.58", "92.49.18.18" ],
 			"rejoin_after_leave": true,
+			"request_logging": {
+				"sample_rate": 0.25,
+				"slow_read_threshold": "250ms",
+				"slow_write_threshold": "2s"
+			},
 			"retry_interval": "8067s",
 			"retry_interval_wan": "28866s",
 			"retry_join": [ "pbsSFY7U", "l0qLtWij" ],
 			"retry_join_wan": [ "PFsR02Ye", "rJdQIhER" ],
 			"retry_max": 913,
 			"retry_max_wan": 23160,
+			"rpc_routes": [
+				{ "datacenter": "ftx3zglm", "via": { "vd5kqydx": 3, "ftx3zglm": 1 } },
+				{ "datacenter": "zql4ymac", "deny": true }
+			],
+			"script_check_limits": {
+				"cpu_time": "12871s",
+				"cgroup": "/sys/fs/cgroup/cpu/F1LUcKNz",
+				"memory_bytes": 30471,
+				"user": "mcQ9DV0w"
+			},
 			"segment": "BC2NhTDi",
 			"segments": [
 				{
@@ -3070,6 +3783,9 @@ func TestFullConfig(t *testing.T) {
 			"serf_wan": "67.88.33.19",
 			"server": true,
 			"server_name": "Oerr9n1G",
+			"service_meta_defaults": {
+				"rack": "Wd9kPXpQ"
+			},
 			"service": {
 				"id": "dLOXpSCI",
 				"name": "o1ynPkp0",
@@ -3085,6 +3801,8 @@ func TestFullConfig(t *testing.T) {
 					"warning": 1
 				},
 				"enable_tag_override": true,
+				"deregister_on_shutdown": true,
+				"warmup": "2m30s",
 				"check": {
 					"id": "RMi85Dv8",
 					"name": "iehanzuq",
@@ -3267,6 +3985,11 @@ func TestFullConfig(t *testing.T) {
 						"destination_service_name": "6L6BVfgH",
 						"local_service_address": "127.0.0.2",
 						"local_service_port": 23759,
+						"mode": "transparent",
+						"transparent_proxy": {
+							"outbound_listener_port": 15201,
+							"allowed_services": ["KPtAj2cb"]
+						},
 						"upstreams": [
 							{
 								"destination_name": "KPtAj2cb",
@@ -3319,12 +4042,33 @@ func TestFullConfig(t *testing.T) {
 				"statsd_address": "drce87cy",
 				"statsite_address": "HpFwKB8R"
 			},
+			"templates": [
+				{
+					"source": "qGfYXAd9",
+					"destination": "zMZ8d2oe",
+					"perms": "0640",
+					"args": [ "Lm6E2HHs", "-reload" ],
+					"command_timeout": "1734s"
+				},
+				{
+					"source": "c7HgaGZA",
+					"destination": "Xgt0o3Yk",
+					"command": "mWw6qZcM"
+				}
+			],
 			"tls_cipher_suites": "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
 			"tls_min_version": "pAOWafkR",
 			"tls_prefer_server_cipher_suites": true,
 			"translate_wan_addrs": true,
 			"ui": true,
 			"ui_dir": "11IFzAUn",
+			"ui_metrics_proxy": {
+				"base_url": "http://prometheus.example.com:9090",
+				"path_allowlist": [ "/api/v1/query", "/api/v1/series" ],
+				"add_headers": {
+					"Authorization": "Bearer Cf0rKaD7"
+				}
+			},
 			"unix_sockets": {
 				"group": "8pFodrV8",
 				"mode": "E8sAwOv4",
@@ -3333,8 +4077,14 @@ func TestFullConfig(t *testing.T) {
 			"verify_incoming": true,
 			"verify_incoming_https": true,
 			"verify_incoming_rpc": true,
+			"verify_ocsp": true,
 			"verify_outgoing": true,
 			"verify_server_hostname": true,
+			"verify_server_hostname_policies": [
+				{ "datacenter": "*", "names": ["*.qh5pzwu4.example.com"] },
+				{ "datacenter": "xn7ynrdc", "exempt": true }
+			],
+			"virtual_ip_cidr": "10.217.0.0/16",
 			"watches": [
 				{
 					"type": "key",
@@ -3366,6 +4116,9 @@ func TestFullConfig(t *testing.T) {
 				down_policy = "03eb2aee"
 				default_policy = "72c2e7a0"
 				enable_key_list_policy = false
+				enable_denied_permission_details = true
+				enable_token_persistence = true
+				blocking_query_recheck_interval = "2418s"
 				policy_ttl = "1123s"
 				token_ttl = "3321s"
 				enable_token_replication = true
@@ -3374,7 +4127,8 @@ func TestFullConfig(t *testing.T) {
 					agent_master = "64fd0e08",
 					replication = "5795983a",
 					agent = "bed2377c",
-					default = "418fdff1"
+					default = "418fdff1",
+					introduction = "c5e3ab8d"
 				}
 			}
 			addresses = {
@@ -3385,6 +4139,13 @@ func TestFullConfig(t *testing.T) {
 			}
 			advertise_addr = "17.99.29.16"
 			advertise_addr_wan = "78.63.37.19"
+			advertise_addr_family = "ipv4"
+			advertise_addr_wan_family = "ipv4"
+			advertise_addr_ipv4 = "17.99.29.16"
+			advertise_addr_ipv6 = "2001:db8::a9"
+			advertise_addr_wan_ipv4 = "78.63.37.19"
+			advertise_addr_wan_ipv6 = "2001:db8::3f"
+			agent_profile = "Jt3KnF9q"
 			autopilot = {
 				cleanup_dead_servers = true
 				disable_upgrade_migration = true
@@ -3400,6 +4161,9 @@ func TestFullConfig(t *testing.T) {
 			ca_file = "erA7T0PM"
 			ca_path = "mQEN1Mfp"
 			cert_file = "7s4QAzDk"
+			crl_file = "Wm3iGhBz"
+			crl_refresh_interval = "17m"
+			crl_url = "https://crl.example.com/Pq8nTrXe.crl"
 			check = {
 				id = "fZaCAXww"
 				name = "OOM2eo0f"
@@ -3471,6 +4235,8 @@ func TestFullConfig(t *testing.T) {
 					deregister_critical_service_after = "2366s"
 				}
 			]
+			check_interval_jitter = 17
+			check_state_max_age = "24117s"
 			check_update_interval = "16507s"
 			client_addr = "93.83.18.19"
 			connect {
@@ -3494,6 +4260,7 @@ func TestFullConfig(t *testing.T) {
 				}
 			}
 			gossip_lan {
+				profile         = "cloud"
 				gossip_nodes    = 6
 				gossip_interval = "25252s"
 				retransmit_mult = 1234
@@ -3502,6 +4269,7 @@ func TestFullConfig(t *testing.T) {
 				probe_timeout   = "102ms"
 			}
 			gossip_wan {
+				profile         = "large-cluster"
 				gossip_nodes    = 2
 				gossip_interval = "6966s"
 				retransmit_mult = 16384
@@ -3511,6 +4279,9 @@ func TestFullConfig(t *testing.T) {
 			}
 			data_dir = "` + dataDir + `"
 			datacenter = "rzo029wg"
+			deregister_critical_service_after_default = "4921s"
+			deregister_critical_service_after_min = "3157s"
+			deregister_services_on_shutdown = false
 			disable_anonymous_signature = true
 			disable_coordinates = true
 			disable_host_node_id = true
@@ -3525,10 +4296,15 @@ func TestFullConfig(t *testing.T) {
 				allow_stale = true
 				a_record_limit = 29907
 				disable_compression = true
+				domain_recursors = {
+					"corp.example.com" = ["10.16.91.3", "10.16.91.4:5353"]
+				}
+				enable_prepared_query_datacenter_txt = true
 				enable_truncate = true
 				max_stale = "29685s"
 				node_ttl = "7084s"
 				only_passing = true
+				recursor_health_check_interval = "2361s"
 				recursor_timeout = "4427s"
 				service_ttl = {
 					"*" = "32030s"
@@ -3538,24 +4314,62 @@ func TestFullConfig(t *testing.T) {
 			enable_acl_replication = true
 			enable_agent_tls_for_checks = true
 			enable_debug = true
+			enable_fault_injection = true
 			enable_script_checks = true
 			enable_local_script_checks = true
 			enable_syslog = true
+			enable_windows_event_log = true
 			encrypt = "A4wELWqH"
 			encrypt_verify_incoming = true
 			encrypt_verify_outgoing = true
+			gossip_key_rotation {
+				interval = "2160h"
+				retire_after = "2h"
+			}
 			http_config {
 				block_endpoints = [ "RBvAFcGD", "fWOWFznh" ]
+				client_cert_allowlist {
+					common_names = [ "deploy-*" ]
+					organizational_units = [ "Z3hXKx0W" ]
+					uris = [ "spiffe://qzjk5rw2/deploy/*" ]
+					write_only = true
+				}
 				response_headers = {
 					"M6TKa9NP" = "xjuxjOzQ"
 					"JRCrHZed" = "rl0mTx81"
 				}
 			}
+			hooks = [
+				{
+					events = [ "became_leader", "lost_leader" ]
+					args = [ "dX0Qa1Pv", "-notify" ]
+					timeout = "4178s"
+				},
+				{
+					events = [ "check_state_changed" ]
+					http = "https://hooks.example.com/uVlxcTLC"
+					method = "PUT"
+					header = {
+						"tvzoEHkq" = [ "Qd3FJCVw", "uFjp7ZtG" ]
+					}
+					payload = "{{ .Event }} {{ .Node }}"
+				}
+			]
 			key_file = "IEkkwgIA"
+			kv_recycle_bin_retention = "31h"
+			kv_replication {
+				conflict_policy = "keep-local"
+				prefixes = ["config/", "shared/"]
+			}
 			leave_on_terminate = true
 			limits {
+				acl_bootstrap_rate = 0.5
+				acl_bootstrap_max_burst = 7
 				rpc_rate = 12029.43
 				rpc_max_burst = 44848
+				max_query_time = "2917s"
+				default_query_time = "1283s"
+				max_blocking_queries_per_token = 79
 			}
 			log_level = "k1zo9Spt"
 			node_id = "AsUIlw99"
@@ -3565,10 +4379,17 @@ func TestFullConfig(t *testing.T) {
 			}
 			node_name = "otlLxGaI"
 			non_voting_server = true
+			ocsp_stapling = true
 			performance {
 				leave_drain_time = "8265s"
 				raft_multiplier = 5
+				raft_election_multiplier = 7
+				raft_leader_lease_timeout = "19374s"
 				rpc_hold_timeout = "15707s"
+				rpc_retry_max_interval = "24013s"
+				rpc_retry_jitter = 0.25
+				ae_retry_max_interval = "31544s"
+				ae_retry_jitter = 0.75
 			}
 			pid_file = "43xN80Km"
 			ports {
@@ -3583,6 +4404,7 @@ func TestFullConfig(t *testing.T) {
 				sidecar_max_port = 9999
 			}
 			protocol = 30793
+			prepared_query_slow_threshold = "45ms"
 			primary_datacenter = "ejtmd43d"
 			raft_protocol = 19016
 			raft_snapshot_threshold = 16384
@@ -3591,12 +4413,27 @@ func TestFullConfig(t *testing.T) {
 			reconnect_timeout_wan = "26694s"
 			recursors = [ "63.38.39.58", "92.49.18.18" ]
 			rejoin_after_leave = true
+			request_logging {
+				sample_rate = 0.25
+				slow_read_threshold = "250ms"
+				slow_write_threshold = "2s"
+			}
 			retry_interval = "8067s"
 			retry_interval_wan = "28866s"
 			retry_join = [ "pbsSFY7U", "l0qLtWij" ]
 			retry_join_wan = [ "PFsR02Ye", "rJdQIhER" ]
 			retry_max = 913
 			retry_max_wan = 23160
+			rpc_routes = [
+				{ datacenter = "ftx3zglm" via { vd5kqydx = 3 ftx3zglm = 1 } },
+				{ datacenter = "zql4ymac" deny = true }
+			]
+			script_check_limits {
+				cpu_time = "12871s"
+				cgroup = "/sys/fs/cgroup/cpu/F1LUcKNz"
+				memory_bytes = 30471
+				user = "mcQ9DV0w"
+			}
 			segment = "BC2NhTDi"
 			segments = [
 				{
@@ -3618,6 +4455,9 @@ func TestFullConfig(t *testing.T) {
 			serf_wan = "67.88.33.19"
 			server = true
 			server_name = "Oerr9n1G"
+			service_meta_defaults {
+				rack = "Wd9kPXpQ"
+			}
 			service = {
 				id = "dLOXpSCI"
 				name = "o1ynPkp0"
@@ -3633,6 +4473,8 @@ func TestFullConfig(t *testing.T) {
 					warning = 1
 				}
 				enable_tag_override = true
+				deregister_on_shutdown = true
+				warmup = "2m30s"
 				check = {
 					id = "RMi85Dv8"
 					name = "iehanzuq"
@@ -3812,6 +4654,11 @@ func TestFullConfig(t *testing.T) {
 						destination_service_id = "6L6BVfgH-id"
 						local_service_address = "127.0.0.2"
 						local_service_port = 23759
+						mode = "transparent"
+						transparent_proxy {
+							outbound_listener_port = 15201
+							allowed_services = ["KPtAj2cb"]
+						}
 						config {
 							cedGGtZf = "pWrUNiWw"
 						}
@@ -3867,12 +4714,33 @@ func TestFullConfig(t *testing.T) {
 				statsd_address = "drce87cy"
 				statsite_address = "HpFwKB8R"
 			}
+			templates = [
+				{
+					source = "qGfYXAd9"
+					destination = "zMZ8d2oe"
+					perms = "0640"
+					args = [ "Lm6E2HHs", "-reload" ]
+					command_timeout = "1734s"
+				},
+				{
+					source = "c7HgaGZA"
+					destination = "Xgt0o3Yk"
+					command = "mWw6qZcM"
+				}
+			]
 			tls_cipher_suites = "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"
 			tls_min_version = "pAOWafkR"
 			tls_prefer_server_cipher_suites = true
 			translate_wan_addrs = true
 			ui = true
 			ui_dir = "11IFzAUn"
+			ui_metrics_proxy {
+				base_url = "http://prometheus.example.com:9090"
+				path_allowlist = [ "/api/v1/query", "/api/v1/series" ]
+				add_headers {
+					"Authorization" = "Bearer Cf0rKaD7"
+				}
+			}
 			unix_sockets = {
 				group = "8pFodrV8"
 				mode = "E8sAwOv4"
@@ -3881,8 +4749,14 @@ func TestFullConfig(t *testing.T) {
 			verify_incoming = true
 			verify_incoming_https = true
 			verify_incoming_rpc = true
+			verify_ocsp = true
 			verify_outgoing = true
 			verify_server_hostname = true
+			verify_server_hostname_policies = [
+				{ datacenter = "*" names = ["*.qh5pzwu4.example.com"] },
+				{ datacenter = "xn7ynrdc" exempt = true }
+			]
+			virtual_ip_cidr = "10.217.0.0/16"
 			watches = [{
 				type = "key"
 				datacenter = "GyE6jpeW"
@@ -4002,15 +4876,17 @@ func TestFullConfig(t *testing.T) {
 		ConsulCoordinateUpdateBatchSize:  9244,
 		ConsulCoordinateUpdateMaxBatches: 15164,
 		ConsulCoordinateUpdatePeriod:     25093 * time.Second,
-		ConsulRaftElectionTimeout:        5 * 31947 * time.Second,
+		ConsulRaftElectionTimeout:        7 * 31947 * time.Second,
 		ConsulRaftHeartbeatTimeout:       5 * 25699 * time.Second,
-		ConsulRaftLeaderLeaseTimeout:     5 * 15351 * time.Second,
+		ConsulRaftLeaderLeaseTimeout:     19374 * time.Second,
+		GossipLANProfile:                 "cloud",
 		GossipLANGossipInterval:          25252 * time.Second,
 		GossipLANGossipNodes:             6,
 		GossipLANProbeInterval:           101 * time.Millisecond,
 		GossipLANProbeTimeout:            102 * time.Millisecond,
 		GossipLANSuspicionMult:           1235,
 		GossipLANRetransmitMult:          1234,
+		GossipWANProfile:                 "large-cluster",
 		GossipWANGossipInterval:          6966 * time.Second,
 		GossipWANGossipNodes:             2,
 		GossipWANProbeInterval:           103 * time.Millisecond,
@@ -4024,19 +4900,28 @@ func TestFullConfig(t *testing.T) {
 		ACLAgentMasterToken:              "64fd0e08",
 		ACLAgentToken:                    "bed2377c",
 		ACLsEnabled:                      true,
+		ACLBlockingQueryRecheckInterval:  2418 * time.Second,
+		ACLBootstrapRateLimit:            0.5,
+		ACLBootstrapMaxBurst:             7,
 		ACLDatacenter:                    "ejtmd43d",
 		ACLDefaultPolicy:                 "72c2e7a0",
 		ACLDownPolicy:                    "03eb2aee",
 		ACLEnforceVersion8:               true,
+		ACLEnableDeniedPermissionDetails: true,
 		ACLEnableKeyListPolicy:           false,
+		ACLEnableTokenPersistence:        true,
+		ACLIntroductionToken:             "c5e3ab8d",
 		ACLMasterToken:                   "8a19ac27",
 		ACLReplicationToken:              "5795983a",
 		ACLTokenTTL:                      3321 * time.Second,
 		ACLPolicyTTL:                     1123 * time.Second,
 		ACLToken:                         "418fdff1",
 		ACLTokenReplication:              true,
+		AERetryJitter:                    0.75,
+		AERetryMaxInterval:               31544 * time.Second,
 		AdvertiseAddrLAN:                 ipAddr("17.99.29.16"),
 		AdvertiseAddrWAN:                 ipAddr("78.63.37.19"),
+		AgentProfile:                     "Jt3KnF9q",
 		AutopilotCleanupDeadServers:      true,
 		AutopilotDisableUpgradeMigration: true,
 		AutopilotLastContactThreshold:    12705 * time.Second,
@@ -4049,6 +4934,9 @@ func TestFullConfig(t *testing.T) {
 		BootstrapExpect:                  53,
 		CAFile:                           "erA7T0PM",
 		CAPath:                           "mQEN1Mfp",
+		CRLFile:                          "Wm3iGhBz",
+		CRLRefreshInterval:               17 * time.Minute,
+		CRLURL:                           "https://crl.example.com/Pq8nTrXe.crl",
 		CertFile:                         "7s4QAzDk",
 		Checks: []*structs.CheckDefinition{
 			&structs.CheckDefinition{
@@ -4121,6 +5009,8 @@ func TestFullConfig(t *testing.T) {
 				DeregisterCriticalServiceAfter: 13209 * time.Second,
 			},
 		},
+		CheckIntervalJitter:     17,
+		CheckStateMaxAge:        24117 * time.Second,
 		CheckUpdateInterval:     16507 * time.Second,
 		ClientAddrs:             []*net.IPAddr{ipAddr("93.83.18.19")},
 		ConnectEnabled:          true,
@@ -4143,80 +5033,132 @@ func TestFullConfig(t *testing.T) {
 			"connect_timeout_ms": float64(1000),
 			"pedantic_mode":      true,
 		},
-		DNSAddrs:                         []net.Addr{tcpAddr("93.95.95.81:7001"), udpAddr("93.95.95.81:7001")},
-		DNSARecordLimit:                  29907,
-		DNSAllowStale:                    true,
-		DNSDisableCompression:            true,
-		DNSDomain:                        "7W1xXSqd",
-		DNSEnableTruncate:                true,
-		DNSMaxStale:                      29685 * time.Second,
-		DNSNodeTTL:                       7084 * time.Second,
-		DNSOnlyPassing:                   true,
-		DNSPort:                          7001,
-		DNSRecursorTimeout:               4427 * time.Second,
-		DNSRecursors:                     []string{"63.38.39.58", "92.49.18.18"},
-		DNSSOA:                           RuntimeSOAConfig{Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 0},
-		DNSServiceTTL:                    map[string]time.Duration{"*": 32030 * time.Second},
-		DNSUDPAnswerLimit:                29909,
-		DNSNodeMetaTXT:                   true,
-		DataDir:                          dataDir,
-		Datacenter:                       "rzo029wg",
-		DevMode:                          true,
-		DisableAnonymousSignature:        true,
-		DisableCoordinates:               true,
-		DisableHostNodeID:                true,
-		DisableHTTPUnprintableCharFilter: true,
-		DisableKeyringFile:               true,
-		DisableRemoteExec:                true,
-		DisableUpdateCheck:               true,
-		DiscardCheckOutput:               true,
-		DiscoveryMaxStale:                5 * time.Second,
-		EnableAgentTLSForChecks:          true,
-		EnableDebug:                      true,
-		EnableRemoteScriptChecks:         true,
-		EnableLocalScriptChecks:          true,
-		EnableSyslog:                     true,
-		EnableUI:                         true,
-		EncryptKey:                       "A4wELWqH",
-		EncryptVerifyIncoming:            true,
-		EncryptVerifyOutgoing:            true,
-		GRPCPort:                         4881,
-		GRPCAddrs:                        []net.Addr{tcpAddr("32.31.61.91:4881")},
-		HTTPAddrs:                        []net.Addr{tcpAddr("83.39.91.39:7999")},
-		HTTPBlockEndpoints:               []string{"RBvAFcGD", "fWOWFznh"},
-		HTTPPort:                         7999,
-		HTTPResponseHeaders:              map[string]string{"M6TKa9NP": "xjuxjOzQ", "JRCrHZed": "rl0mTx81"},
-		HTTPSAddrs:                       []net.Addr{tcpAddr("95.17.17.19:15127")},
-		HTTPSPort:                        15127,
-		KeyFile:                          "IEkkwgIA",
-		LeaveDrainTime:                   8265 * time.Second,
-		LeaveOnTerm:                      true,
-		LogLevel:                         "k1zo9Spt",
-		NodeID:                           types.NodeID("AsUIlw99"),
-		NodeMeta:                         map[string]string{"5mgGQMBk": "mJLtVMSG", "A7ynFMJB": "0Nx6RGab"},
-		NodeName:                         "otlLxGaI",
-		NonVotingServer:                  true,
-		PidFile:                          "43xN80Km",
-		PrimaryDatacenter:                "ejtmd43d",
-		RPCAdvertiseAddr:                 tcpAddr("17.99.29.16:3757"),
-		RPCBindAddr:                      tcpAddr("16.99.34.17:3757"),
-		RPCHoldTimeout:                   15707 * time.Second,
-		RPCProtocol:                      30793,
-		RPCRateLimit:                     12029.43,
-		RPCMaxBurst:                      44848,
-		RaftProtocol:                     19016,
-		RaftSnapshotThreshold:            16384,
-		RaftSnapshotInterval:             30 * time.Second,
-		ReconnectTimeoutLAN:              23739 * time.Second,
-		ReconnectTimeoutWAN:              26694 * time.Second,
-		RejoinAfterLeave:                 true,
-		RetryJoinIntervalLAN:             8067 * time.Second,
-		RetryJoinIntervalWAN:             28866 * time.Second,
-		RetryJoinLAN:                     []string{"pbsSFY7U", "l0qLtWij"},
-		RetryJoinMaxAttemptsLAN:          913,
-		RetryJoinMaxAttemptsWAN:          23160,
-		RetryJoinWAN:                     []string{"PFsR02Ye", "rJdQIhER"},
-		SegmentName:                      "BC2NhTDi",
+		DNSAddrs:                              []net.Addr{tcpAddr("93.95.95.81:7001"), udpAddr("93.95.95.81:7001")},
+		DNSARecordLimit:                       29907,
+		DNSAllowStale:                         true,
+		DNSDisableCompression:                 true,
+		DNSDomain:                             "7W1xXSqd",
+		DNSDomainRecursors:                    map[string][]string{"corp.example.com": []string{"10.16.91.3", "10.16.91.4:5353"}},
+		DNSEnableTruncate:                     true,
+		DNSMaxStale:                           29685 * time.Second,
+		DNSNodeTTL:                            7084 * time.Second,
+		DNSOnlyPassing:                        true,
+		DNSPort:                               7001,
+		DNSRecursorHealthCheckInterval:        2361 * time.Second,
+		DNSRecursorTimeout:                    4427 * time.Second,
+		DNSRecursors:                          []string{"63.38.39.58", "92.49.18.18"},
+		DNSSOA:                                RuntimeSOAConfig{Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 0},
+		DNSServiceTTL:                         map[string]time.Duration{"*": 32030 * time.Second},
+		DNSUDPAnswerLimit:                     29909,
+		DNSNodeMetaTXT:                        true,
+		DNSPreparedQueryDatacenterTXT:         true,
+		DataDir:                               dataDir,
+		Datacenter:                            "rzo029wg",
+		DeregisterCriticalServiceAfterDefault: 4921 * time.Second,
+		DeregisterCriticalServiceAfterMin:     3157 * time.Second,
+		DeregisterServicesOnShutdown:          false,
+		DevMode:                               true,
+		DevPersist:                            true,
+		DisableAnonymousSignature:             true,
+		DisableCoordinates:                    true,
+		DisableHostNodeID:                     true,
+		DisableHTTPUnprintableCharFilter:      true,
+		DisableKeyringFile:                    true,
+		DisableRemoteExec:                     true,
+		DisableUpdateCheck:                    true,
+		DiscardCheckOutput:                    true,
+		DiscoveryMaxStale:                     5 * time.Second,
+		EnableAgentTLSForChecks:               true,
+		EnableDebug:                           true,
+		EnableFaultInjection:                  true,
+		EnableRemoteScriptChecks:              true,
+		EnableLocalScriptChecks:               true,
+		EnableSyslog:                          true,
+		EnableUI:                              true,
+		EnableWindowsEventLog:                 true,
+		EncryptKey:                            "A4wELWqH",
+		EncryptVerifyIncoming:                 true,
+		EncryptVerifyOutgoing:                 true,
+		GossipKeyRotationInterval:             2160 * time.Hour,
+		GossipKeyRotationRetireAfter:          2 * time.Hour,
+		GRPCPort:                              4881,
+		GRPCAddrs:                             []net.Addr{tcpAddr("32.31.61.91:4881")},
+		HTTPAddrs:                             []net.Addr{tcpAddr("83.39.91.39:7999")},
+		HTTPBlockEndpoints:                    []string{"RBvAFcGD", "fWOWFznh"},
+		HTTPClientCertAllowlistCommonNames:    []string{"deploy-*"},
+		HTTPClientCertAllowlistOrgUnits:       []string{"Z3hXKx0W"},
+		HTTPClientCertAllowlistURIs:           []string{"spiffe://qzjk5rw2/deploy/*"},
+		HTTPClientCertAllowlistWriteOnly:      true,
+		HTTPPort:                              7999,
+		HTTPResponseHeaders:                   map[string]string{"M6TKa9NP": "xjuxjOzQ", "JRCrHZed": "rl0mTx81"},
+		HTTPSAddrs:                            []net.Addr{tcpAddr("95.17.17.19:15127")},
+		HTTPSPort:                             15127,
+		KVRecycleBinRetention:                 31 * time.Hour,
+		KVReplicationConflictPolicy:           "keep-local",
+		KVReplicationPrefixes:                 []string{"config/", "shared/"},
+		KeyFile:                               "IEkkwgIA",
+		LeaveDrainTime:                        8265 * time.Second,
+		LeaveOnTerm:                           true,
+		LogLevel:                              "k1zo9Spt",
+		NodeID:                                types.NodeID("AsUIlw99"),
+		NodeMeta:                              map[string]string{"5mgGQMBk": "mJLtVMSG", "A7ynFMJB": "0Nx6RGab"},
+		NodeName:                              "otlLxGaI",
+		NonVotingServer:                       true,
+		OCSPStapling:                          true,
+		PidFile:                               "43xN80Km",
+		PreparedQuerySlowThreshold:            45 * time.Millisecond,
+		PrimaryDatacenter:                     "ejtmd43d",
+		RPCAdvertiseAddr:                      tcpAddr("17.99.29.16:3757"),
+		RPCBindAddr:                           tcpAddr("16.99.34.17:3757"),
+		RPCHoldTimeout:                        15707 * time.Second,
+		RPCProtocol:                           30793,
+		RPCRateLimit:                          12029.43,
+		RPCRetryJitter:                        0.25,
+		RPCRetryMaxInterval:                   24013 * time.Second,
+		RPCMaxBurst:                           44848,
+		MaxQueryTime:                          2917 * time.Second,
+		DefaultQueryTime:                      1283 * time.Second,
+		MaxBlockingQueriesPerToken:            79,
+		RaftProtocol:                          19016,
+		RaftSnapshotThreshold:                 16384,
+		RaftSnapshotInterval:                  30 * time.Second,
+		ReconnectTimeoutLAN:                   23739 * time.Second,
+		ReconnectTimeoutWAN:                   26694 * time.Second,
+		RejoinAfterLeave:                      true,
+		RequestLogSampleRate:                  0.25,
+		RequestLogSlowReadThreshold:           250 * time.Millisecond,
+		RequestLogSlowWriteThreshold:          2 * time.Second,
+		RetryJoinIntervalLAN:                  8067 * time.Second,
+		RetryJoinIntervalWAN:                  28866 * time.Second,
+		RetryJoinLAN:                          []string{"pbsSFY7U", "l0qLtWij"},
+		RetryJoinMaxAttemptsLAN:               913,
+		RetryJoinMaxAttemptsWAN:               23160,
+		RetryJoinWAN:                          []string{"PFsR02Ye", "rJdQIhER"},
+		ScriptCheckCPUTime:                    12871 * time.Second,
+		ScriptCheckCgroup:                     "/sys/fs/cgroup/cpu/F1LUcKNz",
+		ScriptCheckMemoryBytes:                30471,
+		ScriptCheckUser:                       "mcQ9DV0w",
+		SegmentName:                           "BC2NhTDi",
+		RPCRoutes: []structs.RPCRoute{
+			{Datacenter: "ftx3zglm", Via: map[string]int{"vd5kqydx": 3, "ftx3zglm": 1}},
+			{Datacenter: "zql4ymac", Deny: true},
+		},
+		Hooks: []RuntimeHookConfig{
+			{
+				Events:  []string{"became_leader", "lost_leader"},
+				Args:    []string{"dX0Qa1Pv", "-notify"},
+				Method:  "POST",
+				Timeout: 4178 * time.Second,
+			},
+			{
+				Events:  []string{"check_state_changed"},
+				HTTP:    "https://hooks.example.com/uVlxcTLC",
+				Method:  "PUT",
+				Header:  map[string][]string{"tvzoEHkq": {"Qd3FJCVw", "uFjp7ZtG"}},
+				Payload: "{{ .Event }} {{ .Node }}",
+				Timeout: 10 * time.Second,
+			},
+		},
 		Segments: []structs.NetworkSegment{
 			{
 				Name:        "PExYMe2E",
@@ -4231,11 +5173,12 @@ func TestFullConfig(t *testing.T) {
 				RPCListener: true,
 			},
 		},
-		SerfPortLAN: 8301,
-		SerfPortWAN: 8302,
-		ServerMode:  true,
-		ServerName:  "Oerr9n1G",
-		ServerPort:  3757,
+		SerfPortLAN:         8301,
+		SerfPortWAN:         8302,
+		ServerMode:          true,
+		ServerName:          "Oerr9n1G",
+		ServerPort:          3757,
+		ServiceMetaDefaults: map[string]string{"rack": "Wd9kPXpQ"},
 		Services: []*structs.ServiceDefinition{
 			{
 				ID:      "wI1dzxS4",
@@ -4361,6 +5304,11 @@ func TestFullConfig(t *testing.T) {
 					DestinationServiceID:   "6L6BVfgH-id",
 					LocalServiceAddress:    "127.0.0.2",
 					LocalServicePort:       23759,
+					Mode:                   structs.ProxyModeTransparent,
+					TransparentProxy: structs.TransparentProxyConfig{
+						OutboundListenerPort: 15201,
+						AllowedServices:      []string{"KPtAj2cb"},
+					},
 					Config: map[string]interface{}{
 						"cedGGtZf": "pWrUNiWw",
 					},
@@ -4399,7 +5347,9 @@ func TestFullConfig(t *testing.T) {
 					Passing: 100,
 					Warning: 1,
 				},
-				EnableTagOverride: true,
+				EnableTagOverride:    true,
+				DeregisterOnShutdown: pBool(true),
+				Warmup:               150 * time.Second,
 				Connect: &structs.ServiceConnect{
 					Native: true,
 				},
@@ -4511,18 +5461,45 @@ func TestFullConfig(t *testing.T) {
 			"7MYgHrYH": "dALJAhLD",
 			"h6DdBy6K": "ebrr9zZ8",
 			"lan":      "17.99.29.16",
+			"lan_ipv4": "17.99.29.16",
+			"lan_ipv6": "2001:db8::a9",
 			"wan":      "78.63.37.19",
+			"wan_ipv4": "78.63.37.19",
+			"wan_ipv6": "2001:db8::3f",
 		},
-		TranslateWANAddrs:    true,
-		UIDir:                "11IFzAUn",
-		UnixSocketUser:       "E0nB1DwA",
-		UnixSocketGroup:      "8pFodrV8",
-		UnixSocketMode:       "E8sAwOv4",
-		VerifyIncoming:       true,
-		VerifyIncomingHTTPS:  true,
-		VerifyIncomingRPC:    true,
-		VerifyOutgoing:       true,
-		VerifyServerHostname: true,
+		Templates: []render.Template{
+			{
+				Source:         "qGfYXAd9",
+				Destination:    "zMZ8d2oe",
+				Perms:          0640,
+				Args:           []string{"Lm6E2HHs", "-reload"},
+				CommandTimeout: 1734 * time.Second,
+			},
+			{
+				Source:      "c7HgaGZA",
+				Destination: "Xgt0o3Yk",
+				Command:     "mWw6qZcM",
+			},
+		},
+		TranslateWANAddrs:           true,
+		UIDir:                       "11IFzAUn",
+		UIMetricsProxyBaseURL:       "http://prometheus.example.com:9090",
+		UIMetricsProxyPathAllowlist: []string{"/api/v1/query", "/api/v1/series"},
+		UIMetricsProxyAddHeaders:    map[string]string{"Authorization": "Bearer Cf0rKaD7"},
+		UnixSocketUser:              "E0nB1DwA",
+		UnixSocketGroup:             "8pFodrV8",
+		UnixSocketMode:              "E8sAwOv4",
+		VerifyIncoming:              true,
+		VerifyIncomingHTTPS:         true,
+		VerifyIncomingRPC:           true,
+		VerifyOCSP:                  true,
+		VerifyOutgoing:              true,
+		VerifyServerHostname:        true,
+		VerifyServerHostnamePolicies: []tlsutil.ServerHostnamePolicy{
+			{Datacenter: "*", Names: []string{"*.qh5pzwu4.example.com"}},
+			{Datacenter: "xn7ynrdc", Exempt: true},
+		},
+		VirtualIPCIDR: "10.217.0.0/16",
 		Watches: []map[string]interface{}{
 			map[string]interface{}{
 				"type":       "key",
@@ -4596,6 +5573,7 @@ func TestFullConfig(t *testing.T) {
 			// we are patching a handful of safe fields to make validation pass.
 			rt.Bootstrap = false
 			rt.DevMode = false
+			rt.DevPersist = false
 			rt.EnableUI = false
 			rt.SegmentName = ""
 			rt.Segments = nil
@@ -4836,12 +5814,18 @@ func TestSanitize(t *testing.T) {
 	rtJSON := `{
 		"ACLAgentMasterToken": "hidden",
 		"ACLAgentToken": "hidden",
+		"ACLBlockingQueryRecheckInterval": "0s",
+		"ACLBootstrapMaxBurst": 0,
+		"ACLBootstrapRateLimit": 0,
 		"ACLDatacenter": "",
 		"ACLDefaultPolicy": "",
 		"ACLDisabledTTL": "0s",
 		"ACLDownPolicy": "",
+		"ACLEnableDeniedPermissionDetails": false,
 		"ACLEnableKeyListPolicy": false,
+		"ACLEnableTokenPersistence": false,
 		"ACLEnforceVersion8": false,
+		"ACLIntroductionToken": "hidden",
 		"ACLMasterToken": "hidden",
 		"ACLPolicyTTL": "0s",
 		"ACLReplicationToken": "hidden",
@@ -4850,8 +5834,11 @@ func TestSanitize(t *testing.T) {
 		"ACLToken": "hidden",
 		"ACLsEnabled": false,
 		"AEInterval": "0s",
+		"AERetryJitter": 0,
+		"AERetryMaxInterval": "0s",
 		"AdvertiseAddrLAN": "",
 		"AdvertiseAddrWAN": "",
+		"AgentProfile": "",
 		"AutopilotCleanupDeadServers": false,
 		"AutopilotDisableUpgradeMigration": false,
 		"AutopilotLastContactThreshold": "0s",
@@ -4864,9 +5851,14 @@ func TestSanitize(t *testing.T) {
 		"BootstrapExpect": 0,
 		"CAFile": "",
 		"CAPath": "",
+		"CRLFile": "",
+		"CRLRefreshInterval": "0s",
+		"CRLURL": "",
 		"CertFile": "",
 		"CheckDeregisterIntervalMin": "0s",
+		"CheckIntervalJitter": 0,
 		"CheckReapInterval": "0s",
+		"CheckStateMaxAge": "0s",
 		"CheckUpdateInterval": "0s",
 		"Checks": [{
 			"AliasNode": "",
@@ -4914,16 +5906,20 @@ func TestSanitize(t *testing.T) {
 		"ConsulRaftElectionTimeout": "0s",
 		"ConsulRaftHeartbeatTimeout": "0s",
 		"ConsulRaftLeaderLeaseTimeout": "0s",
+		"GossipKeyRotationInterval": "0s",
+		"GossipKeyRotationRetireAfter": "0s",
 		"GossipLANGossipInterval": "0s",
 		"GossipLANGossipNodes": 0,
 		"GossipLANProbeInterval": "0s",
 		"GossipLANProbeTimeout": "0s",
+		"GossipLANProfile": "",
 		"GossipLANRetransmitMult": 0,
 		"GossipLANSuspicionMult": 0,
 		"GossipWANGossipInterval": "0s",
 		"GossipWANGossipNodes": 0,
 		"GossipWANProbeInterval": "0s",
 		"GossipWANProbeTimeout": "0s",
+		"GossipWANProfile": "",
 		"GossipWANRetransmitMult": 0,
 		"GossipWANSuspicionMult": 0,
 		"ConsulServerHealthInterval": "0s",
@@ -4935,12 +5931,15 @@ func TestSanitize(t *testing.T) {
 		"DNSAllowStale": false,
 		"DNSDisableCompression": false,
 		"DNSDomain": "",
+		"DNSDomainRecursors": {},
 		"DNSEnableTruncate": false,
 		"DNSMaxStale": "0s",
 		"DNSNodeMetaTXT": false,
 		"DNSNodeTTL": "0s",
 		"DNSOnlyPassing": false,
 		"DNSPort": 0,
+		"DNSPreparedQueryDatacenterTXT": false,
+		"DNSRecursorHealthCheckInterval": "0s",
 		"DNSRecursorTimeout": "0s",
 		"DNSRecursors": [],
 		"DNSServiceTTL": {},
@@ -4953,7 +5952,11 @@ func TestSanitize(t *testing.T) {
 		"DNSUDPAnswerLimit": 0,
 		"DataDir": "",
 		"Datacenter": "",
+		"DeregisterCriticalServiceAfterDefault": "0s",
+		"DeregisterCriticalServiceAfterMin": "0s",
+		"DeregisterServicesOnShutdown": false,
 		"DevMode": false,
+		"DevPersist": false,
 		"DisableAnonymousSignature": false,
 		"DisableCoordinates": false,
 		"DisableHTTPUnprintableCharFilter": false,
@@ -4965,10 +5968,12 @@ func TestSanitize(t *testing.T) {
 		"DiscoveryMaxStale": "0s",
 		"EnableAgentTLSForChecks": false,
 		"EnableDebug": false,
+		"EnableFaultInjection": false,
 		"EnableLocalScriptChecks": false,
 		"EnableRemoteScriptChecks": false,
 		"EnableSyslog": false,
 		"EnableUI": false,
+		"EnableWindowsEventLog": false,
 		"EncryptKey": "hidden",
 		"EncryptVerifyIncoming": false,
 		"EncryptVerifyOutgoing": false,
@@ -4979,10 +5984,18 @@ func TestSanitize(t *testing.T) {
 			"unix:///var/run/foo"
 		],
 		"HTTPBlockEndpoints": [],
+		"HTTPClientCertAllowlistCommonNames": [],
+		"HTTPClientCertAllowlistOrgUnits": [],
+		"HTTPClientCertAllowlistURIs": [],
+		"HTTPClientCertAllowlistWriteOnly": false,
 		"HTTPPort": 0,
 		"HTTPResponseHeaders": {},
 		"HTTPSAddrs": [],
 		"HTTPSPort": 0,
+		"Hooks": [],
+		"KVRecycleBinRetention": "0s",
+		"KVReplicationConflictPolicy": "",
+		"KVReplicationPrefixes": [],
 		"KeyFile": "hidden",
 		"LeaveDrainTime": "0s",
 		"LeaveOnTerm": false,
@@ -4994,20 +6007,31 @@ func TestSanitize(t *testing.T) {
 		"NodeMeta": {},
 		"NodeName": "",
 		"NonVotingServer": false,
+		"OCSPStapling": false,
 		"PidFile": "",
+		"PreparedQuerySlowThreshold": "0s",
 		"PrimaryDatacenter": "",
 		"RPCAdvertiseAddr": "",
 		"RPCBindAddr": "",
 		"RPCHoldTimeout": "0s",
 		"RPCMaxBurst": 0,
+		"MaxQueryTime": "0s",
+		"DefaultQueryTime": "0s",
+		"MaxBlockingQueriesPerToken": 0,
 		"RPCProtocol": 0,
 		"RPCRateLimit": 0,
+		"RPCRetryJitter": 0,
+		"RPCRetryMaxInterval": "0s",
+		"RPCRoutes": [],
 		"RaftProtocol": 0,
 		"RaftSnapshotInterval": "0s",
 		"RaftSnapshotThreshold": 0,
 		"ReconnectTimeoutLAN": "0s",
 		"ReconnectTimeoutWAN": "0s",
 		"RejoinAfterLeave": false,
+		"RequestLogSampleRate": 0,
+		"RequestLogSlowReadThreshold": "0s",
+		"RequestLogSlowWriteThreshold": "0s",
 		"RetryJoinIntervalLAN": "0s",
 		"RetryJoinIntervalWAN": "0s",
 		"RetryJoinLAN": [
@@ -5019,6 +6043,10 @@ func TestSanitize(t *testing.T) {
 			"wan_foo=bar wan_key=hidden wan_secret=hidden wan_bang=bar"
 		],
 		"Revision": "",
+		"ScriptCheckCPUTime": "0s",
+		"ScriptCheckCgroup": "",
+		"ScriptCheckMemoryBytes": 0,
+		"ScriptCheckUser": "",
 		"SegmentLimit": 0,
 		"SegmentName": "",
 		"SegmentNameLimit": 0,
@@ -5032,6 +6060,7 @@ func TestSanitize(t *testing.T) {
 		"ServerMode": false,
 		"ServerName": "",
 		"ServerPort": 0,
+		"ServiceMetaDefaults": {},
 		"Services": [{
 			"Address": "",
 			"Check": {
@@ -5058,6 +6087,7 @@ func TestSanitize(t *testing.T) {
 			},
 			"Checks": [],
 			"Connect": null,
+			"DeregisterOnShutdown": null,
 			"EnableTagOverride": false,
 			"ID": "",
 			"Kind": "",
@@ -5068,6 +6098,7 @@ func TestSanitize(t *testing.T) {
 			"ProxyDestination": "",
 			"Tags": [],
 			"Token": "hidden",
+			"Warmup": "0s",
 			"Weights": {
 				"Passing": 67,
 				"Warning": 3
@@ -5109,18 +6140,25 @@ func TestSanitize(t *testing.T) {
 			"StatsdAddr": "",
 			"StatsiteAddr": ""
 		},
+		"Templates": [],
 		"TranslateWANAddrs": false,
 		"UIDir": "",
+		"UIMetricsProxyAddHeaders": {},
+		"UIMetricsProxyBaseURL": "",
+		"UIMetricsProxyPathAllowlist": [],
 		"UnixSocketGroup": "",
 		"UnixSocketMode": "",
 		"UnixSocketUser": "",
 		"VerifyIncoming": false,
 		"VerifyIncomingHTTPS": false,
 		"VerifyIncomingRPC": false,
+		"VerifyOCSP": false,
 		"VerifyOutgoing": false,
 		"VerifyServerHostname": false,
+		"VerifyServerHostnamePolicies": [],
 		"Version": "",
 		"VersionPrerelease": "",
+		"VirtualIPCIDR": "",
 		"Watches": []
 	}`
 	b, err := json.MarshalIndent(rt.Sanitized(), "", "    ")
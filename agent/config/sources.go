@@ -0,0 +1,136 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Labels used in Builder.FieldSources for sources which are not files.
+const (
+	SourceDefault = "default"
+	SourceFlags   = "flags"
+	SourceEnv     = "env"
+)
+
+// sourceLabel returns the label under which the fields set by the given
+// source are recorded. Built-in sources and command line flags are
+// grouped since their individual names are an implementation detail.
+func sourceLabel(name string) string {
+	switch {
+	case name == "flags.slices" || name == "flags.values":
+		return SourceFlags
+	case strings.HasPrefix(name, "flags-") && strings.HasSuffix(name, ".hcl"):
+		return SourceFlags
+	}
+	switch name {
+	case "default", "dev", "non-user", "consul", "consul-dev", "enterprise", "version":
+		return SourceDefault
+	}
+	return name
+}
+
+// FieldSources returns the sources which set the fields of the runtime
+// configuration in the order in which they were merged. The fields are
+// named like in RuntimeConfig.Sanitized, e.g. "HTTPPort", and fields of
+// nested structs by their path, e.g. "Telemetry.StatsdAddr". Files are
+// recorded by name, built-in defaults and command line flags as
+// SourceDefault and SourceFlags. A file which references environment
+// variables is followed by SourceEnv for all fields it sets since the
+// interpolated values are not tracked individually.
+//
+// The runtime configuration is built once for every source and a source
+// is recorded for the fields it changed. This is considerably more
+// expensive than Build and only meant for introspection.
+func (b *Builder) FieldSources() (map[string][]string, error) {
+	warnings, berr := b.Warnings, b.err
+	defer func() {
+		b.Warnings, b.err = warnings, berr
+	}()
+
+	srcs, _, err := b.mergedSources()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string][]string)
+	var c Config
+	var prev RuntimeConfig
+	for _, s := range srcs {
+		c2, err := b.mergeSources([]Source{s})
+		if err != nil {
+			return nil, err
+		}
+		c = Merge(c, c2)
+
+		// A partial configuration may not build, in which case its
+		// fields are attributed to the next source.
+		b.Warnings, b.err = nil, nil
+		rt, err := b.build(c)
+		if err != nil {
+			continue
+		}
+
+		labels := []string{sourceLabel(s.Name)}
+		if b.envSources[s.Name] {
+			labels = append(labels, SourceEnv)
+		}
+		for _, name := range changedFields("", reflect.ValueOf(prev), reflect.ValueOf(rt)) {
+			for _, label := range labels {
+				srcs := fields[name]
+				if n := len(srcs); n > 0 && srcs[n-1] == label {
+					continue
+				}
+				fields[name] = append(srcs, label)
+			}
+		}
+		prev = rt
+	}
+	return fields, nil
+}
+
+// changedFields returns the names of the exported fields which differ
+// between the two struct values. Structs are descended into while all
+// other values are compared with equalValues.
+func changedFields(prefix string, a, b reflect.Value) []string {
+	var names []string
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := prefix + f.Name
+		x, y := a.Field(i), b.Field(i)
+		if f.Type.Kind() == reflect.Struct && hasExportedFields(f.Type) {
+			names = append(names, changedFields(name+".", x, y)...)
+			continue
+		}
+		if !equalValues(x, y) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// equalValues returns true if both values are deeply equal. Nil and
+// empty slices and maps are considered equal since the builder does not
+// set them consistently.
+func equalValues(x, y reflect.Value) bool {
+	switch x.Kind() {
+	case reflect.Slice, reflect.Map:
+		if x.Len() == 0 && y.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(x.Interface(), y.Interface())
+}
+
+// hasExportedFields returns true if the struct type has exported fields.
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
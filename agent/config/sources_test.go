@@ -0,0 +1,43 @@
+package config
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder_FieldSources(t *testing.T) {
+	var flags Flags
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	AddFlags(fs, &flags)
+	require.NoError(t, fs.Parse([]string{"-datacenter=dc2", "-retry-join=10.0.0.3", "-config-interpolate-env"}))
+
+	b, err := NewBuilder(flags)
+	require.NoError(t, err)
+	b.LookupEnv = func(key string) (string, bool) {
+		if key == "STATSD_ADDR" {
+			return "127.0.0.1:8125", true
+		}
+		return "", false
+	}
+	b.Sources = append(b.Sources,
+		Source{Name: "a.json", Data: `{"ports":{"http":9500}, "retry_join":["10.0.0.1"], "acl":{"tokens":{"agent":"foo"}}}`},
+		Source{Name: "b.hcl", Data: `ports { http = 9600 } retry_join = ["10.0.0.2"]`},
+		Source{Name: "c.hcl", Data: `telemetry { statsd_address = "${STATSD_ADDR}" }`},
+	)
+	_, err = b.Build()
+	require.NoError(t, err)
+
+	fields, err := b.FieldSources()
+	require.NoError(t, err)
+	require.Empty(t, b.Warnings)
+
+	require.Equal(t, []string{SourceDefault, "a.json", "b.hcl"}, fields["HTTPPort"])
+	require.Equal(t, []string{SourceFlags, "a.json", "b.hcl"}, fields["RetryJoinLAN"])
+	require.Equal(t, []string{"a.json"}, fields["ACLAgentToken"])
+	require.Equal(t, []string{SourceDefault, SourceFlags}, fields["Datacenter"])
+	require.Equal(t, []string{"c.hcl", SourceEnv}, fields["Telemetry.StatsdAddr"])
+	require.Equal(t, []string{SourceDefault, "a.json", "b.hcl"}, fields["HTTPAddrs"])
+	require.NotContains(t, fields, "ACLToken")
+}
@@ -31,6 +31,8 @@ type CertURI interface {
 var (
 	spiffeIDServiceRegexp = regexp.MustCompile(
 		`^/ns/([^/]+)/dc/([^/]+)/svc/([^/]+)$`)
+	spiffeIDAgentRegexp = regexp.MustCompile(
+		`^/agent/([^/]+)/([^/]+)/([^/]+)$`)
 )
 
 // ParseCertURIFromString attempts to parse a string representation of a
@@ -87,6 +89,32 @@ func ParseCertURI(input *url.URL) (CertURI, error) {
 		}, nil
 	}
 
+	// Test for agent IDs
+	if v := spiffeIDAgentRegexp.FindStringSubmatch(path); v != nil {
+		role := v[1]
+		dc := v[2]
+		node := v[3]
+		if input.RawPath != "" {
+			var err error
+			if role, err = url.PathUnescape(v[1]); err != nil {
+				return nil, fmt.Errorf("Invalid role: %s", err)
+			}
+			if dc, err = url.PathUnescape(v[2]); err != nil {
+				return nil, fmt.Errorf("Invalid datacenter: %s", err)
+			}
+			if node, err = url.PathUnescape(v[3]); err != nil {
+				return nil, fmt.Errorf("Invalid node: %s", err)
+			}
+		}
+
+		return &SpiffeIDAgent{
+			Host:       input.Host,
+			Role:       role,
+			Datacenter: dc,
+			Node:       node,
+		}, nil
+	}
+
 	// Test for signing ID
 	if input.Path == "" {
 		idx := strings.Index(input.Host, ".")
@@ -0,0 +1,34 @@
+package connect
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// SpiffeIDAgent is the structure to represent the SPIFFE ID of a Consul
+// agent. It identifies the agent in the TLS certificates of the agents rather
+// than in Connect. The role is "server", "client" or "cli".
+type SpiffeIDAgent struct {
+	Host       string
+	Role       string
+	Datacenter string
+	Node       string
+}
+
+// URI returns the *url.URL for this SPIFFE ID.
+func (id *SpiffeIDAgent) URI() *url.URL {
+	var result url.URL
+	result.Scheme = "spiffe"
+	result.Host = id.Host
+	result.Path = fmt.Sprintf("/agent/%s/%s/%s",
+		id.Role, id.Datacenter, id.Node)
+	return &result
+}
+
+// CertURI impl.
+func (id *SpiffeIDAgent) Authorize(ixn *structs.Intention) (bool, bool) {
+	// Agents are never authorized as a client of a service.
+	return false, true
+}
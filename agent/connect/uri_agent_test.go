@@ -0,0 +1,31 @@
+package connect
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpiffeIDAgent(t *testing.T) {
+	id := &SpiffeIDAgent{
+		Host:       "1234.consul",
+		Role:       "client",
+		Datacenter: "dc01",
+		Node:       "node1",
+	}
+	require.Equal(t, "spiffe://1234.consul/agent/client/dc01/node1", id.URI().String())
+
+	parsed, err := ParseCertURI(id.URI())
+	require.NoError(t, err)
+	require.Equal(t, id, parsed)
+
+	// Agents never match intentions as a service would.
+	auth, match := id.Authorize(&structs.Intention{
+		SourceNS:   structs.IntentionWildcard,
+		SourceName: structs.IntentionWildcard,
+		Action:     structs.IntentionActionAllow,
+	})
+	require.False(t, auth)
+	require.True(t, match)
+}
@@ -112,6 +112,12 @@ func TestSpiffeIDSigning_CanSign(t *testing.T) {
 			input: &SpiffeIDService{TestClusterID + ".fake", "default", "dc1", "web"},
 			want:  false,
 		},
+		{
+			name:  "agent - same cluster",
+			id:    testSigning,
+			input: &SpiffeIDAgent{TestClusterID + ".consul", "server", "dc1", "node1"},
+			want:  false,
+		},
 	}
 
 	for _, tt := range tests {
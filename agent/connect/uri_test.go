@@ -45,6 +45,18 @@ var testCertURICases = []struct {
 		"",
 	},
 
+	{
+		"agent ID",
+		"spiffe://1234.consul/agent/server/dc01/node1",
+		&SpiffeIDAgent{
+			Host:       "1234.consul",
+			Role:       "server",
+			Datacenter: "dc01",
+			Node:       "node1",
+		},
+		"",
+	},
+
 	{
 		"signing ID",
 		"spiffe://1234.consul",
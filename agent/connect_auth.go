@@ -59,7 +59,7 @@ func (a *Agent) ConnectAuthorize(token string,
 		return returnErr(err)
 	}
 	if rule != nil && !rule.ServiceWrite(req.Target, nil) {
-		return returnErr(acl.ErrPermissionDenied)
+		return returnErr(acl.PermissionDenied("service", req.Target, "write"))
 	}
 
 	// Validate the trust domain matches ours. Later we will support explicit
@@ -23,6 +23,22 @@ func (s *HTTPServer) ConnectCARoots(resp http.ResponseWriter, req *http.Request)
 	return reply, nil
 }
 
+// GET /v1/connect/ca/leaves
+func (s *HTTPServer) ConnectCALeaves(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var args structs.DCSpecificRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var reply structs.IndexedCALeaves
+	defer setMeta(resp, &reply.QueryMeta)
+	if err := s.agent.RPC("ConnectCA.Leaves", &args, &reply); err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
 // /v1/connect/ca/configuration
 func (s *HTTPServer) ConnectCAConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	switch req.Method {
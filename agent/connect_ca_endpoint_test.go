@@ -61,6 +61,46 @@ func TestConnectCARoots_list(t *testing.T) {
 	}
 }
 
+func TestConnectCALeaves(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Register a local service and fetch its leaf certificate, which is
+	// signed on behalf of this agent.
+	{
+		args := &structs.ServiceDefinition{
+			Name: "test",
+			Port: 8000,
+		}
+		req, _ := http.NewRequest("PUT", "/v1/agent/service/register", jsonReader(args))
+		resp := httptest.NewRecorder()
+		_, err := a.srv.AgentRegisterService(resp, req)
+		require.NoError(err)
+	}
+	req, _ := http.NewRequest("GET", "/v1/agent/connect/ca/leaf/test", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.AgentConnectCALeafCert(resp, req)
+	require.NoError(err)
+	issued := obj.(*structs.IssuedCert)
+
+	req, _ = http.NewRequest("GET", "/v1/connect/ca/leaves", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.ConnectCALeaves(resp, req)
+	require.NoError(err)
+
+	value := obj.(structs.IndexedCALeaves)
+	require.Len(value.Leaves, 1)
+	leaf := value.Leaves[0]
+	require.Equal(a.Config.NodeName, leaf.Node)
+	require.Equal("test", leaf.Service)
+	require.Equal(value.ActiveRootID, leaf.RootID)
+	require.Equal(issued.SerialNumber, leaf.SerialNumber)
+}
+
 func TestConnectCAConfig(t *testing.T) {
 	t.Parallel()
 
@@ -54,6 +54,39 @@ const (
 	aclModeCheckMaxInterval = 30 * time.Second
 )
 
+// aclCacheFullMemory is the memory limit from which the ACL caches have
+// their full size.
+const aclCacheFullMemory = 1 << 30
+
+// scaleACLCacheConfig returns the ACL cache sizes for an agent with the
+// given memory limit, which is 0 if the memory is not limited. Below 1GiB
+// the caches shrink proportionally down to an eighth of their full size.
+func scaleACLCacheConfig(config *structs.ACLCachesConfig, memoryLimit uint64) *structs.ACLCachesConfig {
+	if memoryLimit == 0 || memoryLimit >= aclCacheFullMemory {
+		return config
+	}
+
+	factor := float64(memoryLimit) / aclCacheFullMemory
+	if factor < 0.125 {
+		factor = 0.125
+	}
+	scale := func(n int) int {
+		if n <= 0 {
+			return n
+		}
+		if scaled := int(float64(n) * factor); scaled > 0 {
+			return scaled
+		}
+		return 1
+	}
+	return &structs.ACLCachesConfig{
+		Identities:     scale(config.Identities),
+		Policies:       scale(config.Policies),
+		ParsedPolicies: scale(config.ParsedPolicies),
+		Authorizers:    scale(config.Authorizers),
+	}
+}
+
 func minTTL(a time.Duration, b time.Duration) time.Duration {
 	if a < b {
 		return a
@@ -293,7 +326,7 @@ func (r *ACLResolver) resolveTokenLegacy(token string) (acl.Authorizer, error) {
 	// done or the server is within the primary datacenter.
 	if done, identity, err := r.delegate.ResolveIdentityFromToken(token); done {
 		if err == nil && identity != nil {
-			policies, err := r.resolvePoliciesForIdentity(identity)
+			policies, err := r.resolvePoliciesForIdentity(identity, "")
 			if err != nil {
 				return nil, err
 			}
@@ -538,37 +571,82 @@ func (r *ACLResolver) resolvePoliciesAsyncForIdentity(identity structs.ACLIdenti
 	return
 }
 
-func (r *ACLResolver) filterPoliciesByScope(policies structs.ACLPolicies) structs.ACLPolicies {
-	var out structs.ACLPolicies
+// filterPoliciesByScope splits the policies into the ones which apply in this
+// datacenter and the ones which are scoped to other datacenters. If the
+// request was forwarded from another datacenter, the policies must also apply
+// in the source datacenter.
+func (r *ACLResolver) filterPoliciesByScope(policies structs.ACLPolicies, sourceDC string) (structs.ACLPolicies, structs.ACLPolicies) {
+	var out, filtered structs.ACLPolicies
 	for _, policy := range policies {
-		if len(policy.Datacenters) == 0 {
+		if policyInScope(policy, r.config.Datacenter) && (sourceDC == "" || policyInScope(policy, sourceDC)) {
 			out = append(out, policy)
-			continue
+		} else {
+			filtered = append(filtered, policy)
 		}
+	}
 
-		for _, dc := range policy.Datacenters {
-			if dc == r.config.Datacenter {
-				out = append(out, policy)
-				continue
-			}
+	return out, filtered
+}
+
+// policyInScope returns whether the policy applies in the given datacenter.
+func policyInScope(policy *structs.ACLPolicy, dc string) bool {
+	if len(policy.Datacenters) == 0 {
+		return true
+	}
+
+	for _, policyDC := range policy.Datacenters {
+		if policyDC == dc {
+			return true
 		}
 	}
+	return false
+}
 
-	return out
+func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity, sourceDC string) (structs.ACLPolicies, error) {
+	policies, _, err := r.scopePoliciesForIdentity(identity, sourceDC)
+	return policies, err
 }
 
-func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (structs.ACLPolicies, error) {
+// scopePoliciesForIdentity returns the policies of the identity which apply to
+// a request from the given source datacenter and the ones which were filtered
+// out by their scope.
+func (r *ACLResolver) scopePoliciesForIdentity(identity structs.ACLIdentity, sourceDC string) (structs.ACLPolicies, structs.ACLPolicies, error) {
 	policyIDs := identity.PolicyIDs()
-	if len(policyIDs) == 0 {
+	nodeIdentities := identity.NodeIdentityList()
+	serviceIdentities := identity.ServiceIdentityList()
+	if len(policyIDs) == 0 && len(nodeIdentities) == 0 && len(serviceIdentities) == 0 {
 		policy := identity.EmbeddedPolicy()
 		if policy != nil {
-			return []*structs.ACLPolicy{policy}, nil
+			return []*structs.ACLPolicy{policy}, nil, nil
 		}
 
 		// In this case the default policy will be all that is in effect.
-		return nil, nil
+		return nil, nil, nil
+	}
+
+	policies, err := r.collectPoliciesForIdentity(identity, policyIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Node and service identities are expanded into synthetic policies which
+	// are scoped to the datacenters of the identity like any other policy.
+	for _, nodeIdent := range nodeIdentities {
+		policies = append(policies, nodeIdent.SyntheticPolicy())
+	}
+	for _, svcIdent := range serviceIdentities {
+		policies = append(policies, svcIdent.SyntheticPolicy())
 	}
 
+	out, filtered := r.filterPoliciesByScope(policies, sourceDC)
+	return out, filtered, nil
+}
+
+// collectPoliciesForIdentity returns the policies with the given IDs, fetching
+// the missing or expired ones from the ACL datacenter. The policies have not
+// been filtered by scope yet.
+func (r *ACLResolver) collectPoliciesForIdentity(identity structs.ACLIdentity, policyIDs []string) ([]*structs.ACLPolicy, error) {
+
 	// For the new ACLs policy replication is mandatory for correct operation on servers. Therefore
 	// we only attempt to resolve policies locally
 	policies := make([]*structs.ACLPolicy, 0, len(policyIDs))
@@ -615,7 +693,7 @@ func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (
 
 	// Hot-path if we have no missing or expired policies
 	if len(missing)+len(expired) == 0 {
-		return r.filterPoliciesByScope(policies), nil
+		return policies, nil
 	}
 
 	fetchIDs := missing
@@ -656,7 +734,7 @@ func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (
 	if !waitForResult {
 		// waitForResult being false requires that all the policies were cached already
 		policies = append(policies, expired...)
-		return r.filterPoliciesByScope(policies), nil
+		return policies, nil
 	}
 
 	for i := 0; i < len(newAsyncFetchIds); i++ {
@@ -671,20 +749,23 @@ func (r *ACLResolver) resolvePoliciesForIdentity(identity structs.ACLIdentity) (
 		}
 	}
 
-	return r.filterPoliciesByScope(policies), nil
+	return policies, nil
 }
 
-func (r *ACLResolver) resolveTokenToPolicies(token string) (structs.ACLPolicies, error) {
+func (r *ACLResolver) resolveTokenToPolicies(token, sourceDC string) (structs.ACLPolicies, error) {
 	// Resolve the token to an ACLIdentity
 	identity, err := r.resolveIdentityFromToken(token)
 	if err != nil {
 		return nil, err
 	} else if identity == nil {
 		return nil, acl.ErrNotFound
+	} else if identity.IsExpired(time.Now()) {
+		// Expired tokens are treated as revoked until the leader reaps them
+		return nil, acl.ErrNotFound
 	}
 
 	// Resolve the ACLIdentity to ACLPolicies
-	return r.resolvePoliciesForIdentity(identity)
+	return r.resolvePoliciesForIdentity(identity, sourceDC)
 }
 
 func (r *ACLResolver) disableACLsWhenUpstreamDisabled(err error) error {
@@ -701,6 +782,13 @@ func (r *ACLResolver) disableACLsWhenUpstreamDisabled(err error) error {
 }
 
 func (r *ACLResolver) ResolveToken(token string) (acl.Authorizer, error) {
+	return r.ResolveTokenFromDatacenter(token, "")
+}
+
+// ResolveTokenFromDatacenter resolves the token of a request which was
+// forwarded from the given datacenter. Policies which don't apply in the
+// source datacenter are ignored, sourceDC may be empty for local requests.
+func (r *ACLResolver) ResolveTokenFromDatacenter(token, sourceDC string) (acl.Authorizer, error) {
 	if !r.ACLsEnabled() {
 		return nil, nil
 	}
@@ -721,7 +809,7 @@ func (r *ACLResolver) ResolveToken(token string) (acl.Authorizer, error) {
 
 	defer metrics.MeasureSince([]string{"acl", "ResolveToken"}, time.Now())
 
-	policies, err := r.resolveTokenToPolicies(token)
+	policies, err := r.resolveTokenToPolicies(token, sourceDC)
 	if err != nil {
 		r.disableACLsWhenUpstreamDisabled(err)
 		if IsACLRemoteError(err) {
@@ -738,6 +826,65 @@ func (r *ACLResolver) ResolveToken(token string) (acl.Authorizer, error) {
 
 }
 
+// filteredPolicyNames returns the names of the policies of the token which
+// don't apply to a request from the given source datacenter because they are
+// scoped to other datacenters. The token is only looked up locally and in the
+// cache, since the request already resolved it.
+func (r *ACLResolver) filteredPolicyNames(token, sourceDC string) ([]string, error) {
+	if !r.ACLsEnabled() || r.delegate.UseLegacyACLs() || acl.RootAuthorizer(token) != nil {
+		return nil, nil
+	}
+
+	if token == "" {
+		token = anonymousToken
+	}
+
+	done, identity, err := r.delegate.ResolveIdentityFromToken(token)
+	if !done {
+		if entry := r.cache.GetIdentity(token); entry != nil {
+			identity, err = entry.Identity, nil
+		}
+	}
+	if err != nil || identity == nil {
+		return nil, err
+	}
+
+	_, filtered, err := r.scopePoliciesForIdentity(identity, sourceDC)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, policy := range filtered {
+		names = append(names, policy.Name)
+	}
+	return names, nil
+}
+
+// TokenAccessorID returns the accessor ID of the given token so that it can
+// be logged instead of the secret. The token is only looked up locally and in
+// the cache, an empty string is returned if it isn't known.
+func (r *ACLResolver) TokenAccessorID(token string) string {
+	if !r.ACLsEnabled() || r.delegate.UseLegacyACLs() || acl.RootAuthorizer(token) != nil {
+		return ""
+	}
+
+	if token == "" {
+		token = anonymousToken
+	}
+
+	done, identity, err := r.delegate.ResolveIdentityFromToken(token)
+	if !done {
+		if entry := r.cache.GetIdentity(token); entry != nil {
+			identity, err = entry.Identity, nil
+		}
+	}
+	if err != nil || identity == nil {
+		return ""
+	}
+	return identity.ID()
+}
+
 func (r *ACLResolver) ACLsEnabled() bool {
 	// Whether we desire ACLs to be enabled according to configuration
 	if !r.delegate.ACLsEnabled() {
@@ -755,7 +902,7 @@ func (r *ACLResolver) ACLsEnabled() bool {
 }
 
 func (r *ACLResolver) GetMergedPolicyForToken(token string) (*acl.Policy, error) {
-	policies, err := r.resolveTokenToPolicies(token)
+	policies, err := r.resolveTokenToPolicies(token, "")
 	if err != nil {
 		return nil, err
 	}
@@ -858,6 +1005,22 @@ func (f *aclFilter) filterServiceNodes(nodes *structs.ServiceNodes) {
 	*nodes = sn
 }
 
+// filterServiceInstanceEvents is used to filter the history of service
+// instances based on the configured ACL rules.
+func (f *aclFilter) filterServiceInstanceEvents(events *structs.ServiceInstanceEvents) {
+	e := *events
+	for i := 0; i < len(e); i++ {
+		ev := e[i]
+		if f.allowNode(ev.Node) && f.allowService(ev.ServiceName) {
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping event of node %q from result due to ACLs", ev.Node)
+		e = append(e[:i], e[i+1:]...)
+		i--
+	}
+	*events = e
+}
+
 // filterNodeServices is used to filter services on a given node base on ACLs.
 func (f *aclFilter) filterNodeServices(services **structs.NodeServices) {
 	if *services == nil {
@@ -908,6 +1071,46 @@ func (f *aclFilter) filterSessions(sessions *structs.Sessions) {
 	*sessions = s
 }
 
+// filterKeyLocks is used to filter locks based on ACL rules. Locks are
+// dropped if either the key or the session holding it is not readable.
+func (f *aclFilter) filterKeyLocks(locks *structs.KeyLocks) {
+	l := *locks
+	for i := 0; i < len(l); i++ {
+		lock := l[i]
+		if f.authorizer.KeyRead(lock.Key) && (lock.Session == "" || f.allowSession(lock.Node)) {
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping lock %q from result due to ACLs", lock.Key)
+		l = append(l[:i], l[i+1:]...)
+		i--
+	}
+	*locks = l
+}
+
+// filterProtocolConflicts is used to filter protocol conflicts based on ACL
+// rules. Conflicts are dropped if the service is not readable, and the
+// assumptions of proxies which are not readable are dropped from them.
+func (f *aclFilter) filterProtocolConflicts(conflicts *structs.ProtocolConflicts) {
+	c := *conflicts
+	for i := 0; i < len(c); i++ {
+		conflict := c[i]
+		if f.allowService(conflict.Service) {
+			var as []*structs.ProtocolAssumption
+			for _, a := range conflict.Assumptions {
+				if f.allowNode(a.Node) {
+					as = append(as, a)
+				}
+			}
+			conflict.Assumptions = as
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping protocol conflict of service %q from result due to ACLs", conflict.Service)
+		c = append(c[:i], c[i+1:]...)
+		i--
+	}
+	*conflicts = c
+}
+
 // filterCoordinates is used to filter nodes in a coordinate dump based on ACL
 // rules.
 func (f *aclFilter) filterCoordinates(coords *structs.Coordinates) {
@@ -991,6 +1194,22 @@ func (f *aclFilter) filterNodeDump(dump *structs.NodeDump) {
 	*dump = nd
 }
 
+// filterNodeDetail is used to filter the services, checks and sessions of a
+// node. The detail is set to nil if the node is not readable.
+func (f *aclFilter) filterNodeDetail(detail **structs.NodeDetail) {
+	d := *detail
+	if d == nil {
+		return
+	}
+	dump := structs.NodeDump{d.NodeInfo}
+	f.filterNodeDump(&dump)
+	if len(dump) == 0 {
+		*detail = nil
+		return
+	}
+	f.filterSessions(&d.Sessions)
+}
+
 // filterNodes is used to filter through all parts of a node list and remove
 // elements the provided ACL token cannot access.
 func (f *aclFilter) filterNodes(nodes *structs.Nodes) {
@@ -1007,6 +1226,22 @@ func (f *aclFilter) filterNodes(nodes *structs.Nodes) {
 	*nodes = n
 }
 
+// filterNodeActivities is used to filter the activity of nodes based on the
+// configured ACL rules.
+func (f *aclFilter) filterNodeActivities(nodes *structs.NodeActivities) {
+	n := *nodes
+	for i := 0; i < len(n); i++ {
+		node := n[i].Node
+		if f.allowNode(node) {
+			continue
+		}
+		f.logger.Printf("[DEBUG] consul: dropping node %q from result due to ACLs", node)
+		n = append(n[:i], n[i+1:]...)
+		i--
+	}
+	*nodes = n
+}
+
 // redactPreparedQueryTokens will redact any tokens unless the client has a
 // management token. This eases the transition to delegated authority over
 // prepared queries, since it was easy to capture management tokens in Consul
@@ -1107,9 +1342,24 @@ func (r *ACLResolver) filterACLWithAuthorizer(authorizer acl.Authorizer, subj in
 	case *structs.IndexedIntentions:
 		filt.filterIntentions(&v.Intentions)
 
+	case *structs.IndexedKeyLocks:
+		filt.filterKeyLocks(&v.Locks)
+
+	case *structs.IndexedProtocolConflicts:
+		filt.filterProtocolConflicts(&v.Conflicts)
+
+	case *structs.IndexedServiceInstanceEvents:
+		filt.filterServiceInstanceEvents(&v.Events)
+
+	case *structs.IndexedNodeActivities:
+		filt.filterNodeActivities(&v.Nodes)
+
 	case *structs.IndexedNodeDump:
 		filt.filterNodeDump(&v.Dump)
 
+	case *structs.IndexedNodeDetail:
+		filt.filterNodeDetail(&v.Detail)
+
 	case *structs.IndexedNodes:
 		filt.filterNodes(&v.Nodes)
 
@@ -1147,8 +1397,14 @@ func (r *ACLResolver) filterACLWithAuthorizer(authorizer acl.Authorizer, subj in
 // filterACL is used to filter results from our service catalog based on the
 // rules configured for the provided token.
 func (r *ACLResolver) filterACL(token string, subj interface{}) error {
+	return r.filterACLFromDatacenter(token, "", subj)
+}
+
+// filterACLFromDatacenter is like filterACL for a request which was forwarded
+// from the given datacenter.
+func (r *ACLResolver) filterACLFromDatacenter(token, sourceDC string, subj interface{}) error {
 	// Get the ACL from the token
-	authorizer, err := r.ResolveToken(token)
+	authorizer, err := r.ResolveTokenFromDatacenter(token, sourceDC)
 	if err != nil {
 		return err
 	}
@@ -1222,7 +1478,7 @@ func vetRegisterWithACL(rule acl.Authorizer, subj *structs.RegisterRequest,
 	needsNode := ns == nil || subj.ChangesNode(ns.Node)
 
 	if needsNode && !rule.NodeWrite(subj.Node, scope) {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("node", subj.Node, "write")
 	}
 
 	// Vet the service change. This includes making sure they can register
@@ -1230,7 +1486,7 @@ func vetRegisterWithACL(rule acl.Authorizer, subj *structs.RegisterRequest,
 	// is being modified by id (if any).
 	if subj.Service != nil {
 		if !rule.ServiceWrite(subj.Service.Service, scope) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", subj.Service.Service, "write")
 		}
 
 		if ns != nil {
@@ -1240,7 +1496,7 @@ func vetRegisterWithACL(rule acl.Authorizer, subj *structs.RegisterRequest,
 			// sentinel scope to the service we are overwriting, just
 			// the regular ACL policy.
 			if ok && !rule.ServiceWrite(other.Service, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", other.Service, "write")
 			}
 		}
 	}
@@ -1269,7 +1525,7 @@ func vetRegisterWithACL(rule acl.Authorizer, subj *structs.RegisterRequest,
 		// Node-level check.
 		if check.ServiceID == "" {
 			if !rule.NodeWrite(subj.Node, scope) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("node", subj.Node, "write")
 			}
 			continue
 		}
@@ -1297,7 +1553,7 @@ func vetRegisterWithACL(rule acl.Authorizer, subj *structs.RegisterRequest,
 		// since the sentinel policy doesn't apply to adding checks at
 		// this time.
 		if !rule.ServiceWrite(other.Service, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", other.Service, "write")
 		}
 	}
 
@@ -1328,7 +1584,7 @@ func vetDeregisterWithACL(rule acl.Authorizer, subj *structs.DeregisterRequest,
 			return fmt.Errorf("Unknown service '%s'", subj.ServiceID)
 		}
 		if !rule.ServiceWrite(ns.Service, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", ns.Service, "write")
 		}
 	} else if subj.CheckID != "" {
 		if nc == nil {
@@ -1336,16 +1592,16 @@ func vetDeregisterWithACL(rule acl.Authorizer, subj *structs.DeregisterRequest,
 		}
 		if nc.ServiceID != "" {
 			if !rule.ServiceWrite(nc.ServiceName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", nc.ServiceName, "write")
 			}
 		} else {
 			if !rule.NodeWrite(subj.Node, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("node", subj.Node, "write")
 			}
 		}
 	} else {
 		if !rule.NodeWrite(subj.Node, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("node", subj.Node, "write")
 		}
 	}
 
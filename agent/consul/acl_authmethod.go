@@ -0,0 +1,151 @@
+package consul
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/agent/consul/authmethod"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib/filter"
+)
+
+// loginFields are the fields describing the identity of a workload which
+// logged in with an auth method. The selectors of binding rules filter them.
+type loginFields map[string]string
+
+func (f loginFields) FilterValues(selector string) ([]string, bool) {
+	v, ok := f[selector]
+	if !ok {
+		return nil, false
+	}
+	return []string{v}, true
+}
+
+// availableLoginFields returns the fields a validator may return, without
+// any values, to validate the selectors and bind names of binding rules.
+func availableLoginFields(validator authmethod.Validator) loginFields {
+	fields := make(loginFields)
+	for _, name := range validator.AvailableFields() {
+		fields[name] = ""
+	}
+	return fields
+}
+
+// loginBindings are the privileges granted to the token of a login by the
+// binding rules of the auth method.
+type loginBindings struct {
+	ServiceIdentities []*structs.ACLServiceIdentity
+	NodeIdentities    []*structs.ACLNodeIdentity
+	PolicyNames       []string
+}
+
+// evaluateBindingRules returns the privileges granted by the binding rules
+// whose selectors match the fields of the login.
+func (s *Server) evaluateBindingRules(method *structs.ACLAuthMethod, fields loginFields) (*loginBindings, error) {
+	_, rules, err := s.fsm.State().ACLBindingRuleList(nil, method.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := &loginBindings{}
+	seen := make(map[string]struct{})
+	for _, rule := range rules {
+		if rule.Selector != "" {
+			expr, err := filter.Parse(rule.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector of binding rule %q: %v", rule.ID, err)
+			}
+			if !expr.Match(fields) {
+				continue
+			}
+		}
+
+		name, err := interpolateBindName(rule.BindName, fields)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compute the bind name of binding rule %q: %v", rule.ID, err)
+		}
+		if !isValidBindName(rule.BindType, name) {
+			return nil, fmt.Errorf("computed bind name %q of binding rule %q is invalid", name, rule.ID)
+		}
+
+		key := rule.BindType + "\x00" + name
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		switch rule.BindType {
+		case structs.BindingRuleBindTypeService:
+			bindings.ServiceIdentities = append(bindings.ServiceIdentities, &structs.ACLServiceIdentity{
+				ServiceName: name,
+			})
+		case structs.BindingRuleBindTypeNode:
+			bindings.NodeIdentities = append(bindings.NodeIdentities, &structs.ACLNodeIdentity{
+				NodeName:   name,
+				Datacenter: s.config.Datacenter,
+			})
+		case structs.BindingRuleBindTypePolicy:
+			bindings.PolicyNames = append(bindings.PolicyNames, name)
+		}
+	}
+	return bindings, nil
+}
+
+// interpolateBindName replaces the ${field} references of a bind name with
+// the values of the fields. Unknown fields are an error.
+func interpolateBindName(name string, fields loginFields) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(name, "${")
+		if start < 0 {
+			out.WriteString(name)
+			return out.String(), nil
+		}
+		end := strings.Index(name[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated field reference in %q", name)
+		}
+		end += start
+
+		field := strings.TrimSpace(name[start+2 : end])
+		value, ok := fields[field]
+		if !ok {
+			return "", fmt.Errorf("unknown field %q", field)
+		}
+		out.WriteString(name[:start])
+		out.WriteString(value)
+		name = name[end+1:]
+	}
+}
+
+// validateBindName checks that the bind name only references fields which the
+// auth method provides, and that it is valid for the bind type once they are
+// interpolated.
+func validateBindName(bindType, bindName string, available loginFields) error {
+	sample := make(loginFields, len(available))
+	for name := range available {
+		sample[name] = "x"
+	}
+
+	name, err := interpolateBindName(bindName, sample)
+	if err != nil {
+		return err
+	}
+	if !isValidBindName(bindType, name) {
+		return fmt.Errorf("bind name %q is not valid for bind type %q", bindName, bindType)
+	}
+	return nil
+}
+
+// isValidBindName returns true if the name can be bound with the bind type.
+func isValidBindName(bindType, name string) bool {
+	switch bindType {
+	case structs.BindingRuleBindTypeService:
+		return isValidServiceIdentityName(name)
+	case structs.BindingRuleBindTypeNode:
+		return isValidNodeIdentityName(name)
+	case structs.BindingRuleBindTypePolicy:
+		return validPolicyName.MatchString(name)
+	}
+	return false
+}
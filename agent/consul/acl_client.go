@@ -99,3 +99,9 @@ func (c *Client) ResolvePolicyFromID(policyID string) (bool, *structs.ACLPolicy,
 func (c *Client) ResolveToken(token string) (acl.Authorizer, error) {
 	return c.acls.ResolveToken(token)
 }
+
+// TokenAccessorID returns the accessor ID of the given token, or an empty
+// string if it isn't known.
+func (c *Client) TokenAccessorID(token string) string {
+	return c.acls.TokenAccessorID(token)
+}
@@ -1,18 +1,25 @@
 package consul
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/authmethod"
 	"github.com/hashicorp/consul/agent/consul/state"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/lib/filter"
+	"github.com/hashicorp/consul/sentinel"
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-uuid"
 )
@@ -25,6 +32,14 @@ const (
 
 // Regex for matching
 var validPolicyName = regexp.MustCompile(`^[A-Za-z0-9\-_]{1,128}$`)
+var validNodeIdentityName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9\-_.]*[A-Za-z0-9])?$`)
+var validServiceIdentityName = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-_]*[a-z0-9])?$`)
+var validAuthMethodName = regexp.MustCompile(`^[A-Za-z0-9\-_]{1,128}$`)
+
+// errAuthMethodsRequireTokenReplication is returned by the auth method and
+// login endpoints in datacenters without local tokens, as the tokens created
+// with a login are always local.
+var errAuthMethodsRequireTokenReplication = errors.New("Token replication is required for auth methods to function")
 
 // ACL endpoint is used to manipulate ACLs
 type ACL struct {
@@ -196,10 +211,10 @@ func (a *ACL) TokenRead(args *structs.ACLTokenReadRequest, reply *structs.ACLTok
 		// Only ACLRead privileges are required to list tokens
 		// However if you do not have ACLWrite as well the token
 		// secrets will be redacted
-		if rule, err = a.srv.ResolveToken(args.Token); err != nil {
+		if rule, err = a.srv.ResolveRequestToken(args); err != nil {
 			return err
 		} else if rule == nil || !rule.ACLRead() {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("acl", "", "read")
 		}
 	}
 
@@ -222,6 +237,11 @@ func (a *ACL) TokenRead(args *structs.ACLTokenReadRequest, reply *structs.ACLTok
 				return err
 			}
 
+			// Expired tokens are treated as deleted until they get reaped
+			if token != nil && token.IsExpired(time.Now()) {
+				token = nil
+			}
+
 			reply.Index, reply.Token = index, token
 			return nil
 		})
@@ -244,16 +264,16 @@ func (a *ACL) TokenClone(args *structs.ACLTokenUpsertRequest, reply *structs.ACL
 
 	defer metrics.MeasureSince([]string{"acl", "token", "clone"}, time.Now())
 
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	_, token, err := a.srv.fsm.State().ACLTokenGetByAccessor(nil, args.ACLToken.AccessorID)
 	if err != nil {
 		return err
-	} else if token == nil {
+	} else if token == nil || token.IsExpired(time.Now()) {
 		return acl.ErrNotFound
 	} else if !a.srv.InACLDatacenter() && !token.Local {
 		// global token writes must be forwarded to the primary DC
@@ -265,12 +285,19 @@ func (a *ACL) TokenClone(args *structs.ACLTokenUpsertRequest, reply *structs.ACL
 		return fmt.Errorf("Cannot clone a legacy ACL with this endpoint")
 	}
 
+	if token.AuthMethod != "" {
+		return fmt.Errorf("Cannot clone a token created from an auth method")
+	}
+
 	cloneReq := structs.ACLTokenUpsertRequest{
 		Datacenter: args.Datacenter,
 		ACLToken: structs.ACLToken{
-			Policies:    token.Policies,
-			Local:       token.Local,
-			Description: token.Description,
+			Policies:          token.Policies,
+			NodeIdentities:    token.NodeIdentityList(),
+			ServiceIdentities: token.ServiceIdentityList(),
+			Local:             token.Local,
+			Description:       token.Description,
+			ExpirationTime:    token.ExpirationTime,
 		},
 		WriteRequest: args.WriteRequest,
 	}
@@ -282,6 +309,119 @@ func (a *ACL) TokenClone(args *structs.ACLTokenUpsertRequest, reply *structs.ACL
 	return a.tokenUpsertInternal(&cloneReq, reply, false)
 }
 
+// AgentIntroduce creates an agent token with the node identity of the agent
+// which made the request. The request is authorized with an introduction
+// token instead of a token with ACL write privileges, which only needs the
+// privileges granted by the node identity so the agent cannot gain any
+// privileges the introduction token does not already have.
+func (a *ACL) AgentIntroduce(args *structs.ACLAgentIntroduceRequest, reply *structs.ACLToken) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if args.NodeIdentity.Datacenter == "" {
+		args.NodeIdentity.Datacenter = args.Datacenter
+	}
+
+	// The agent token is local to the datacenter of the agent unless local
+	// tokens are disabled, in which case a global token is created in the
+	// ACL datacenter.
+	if !a.srv.LocalTokensEnabled() {
+		args.Datacenter = a.srv.config.ACLDatacenter
+	}
+
+	if done, err := a.srv.forward("ACL.AgentIntroduce", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "token", "introduce"}, time.Now())
+
+	nodeIdent := args.NodeIdentity
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.NodeWrite(nodeIdent.NodeName, nil) || !rule.ServiceRead("") {
+		return acl.ErrPermissionDenied
+	}
+
+	introducer, err := a.introducerID(args.Token)
+	if err != nil {
+		return err
+	}
+
+	req := structs.ACLTokenUpsertRequest{
+		Datacenter: args.Datacenter,
+		ACLToken: structs.ACLToken{
+			Description:    fmt.Sprintf("Agent token for node %q", nodeIdent.NodeName),
+			NodeIdentities: []*structs.ACLNodeIdentity{&nodeIdent},
+			Local:          nodeIdent.Datacenter == a.srv.config.Datacenter,
+			IntroducedBy:   introducer,
+		},
+		WriteRequest: args.WriteRequest,
+	}
+
+	return a.tokenUpsertInternal(&req, reply, false)
+}
+
+// AgentIntroduceRevoke deletes an agent token created by AgentIntroduce which
+// the agent did not use. Like AgentIntroduce it is authorized with an
+// introduction token instead of a token with ACL write privileges, so it
+// only deletes tokens which were created with the same introduction token.
+func (a *ACL) AgentIntroduceRevoke(args *structs.ACLTokenDeleteRequest, reply *string) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		args.Datacenter = a.srv.config.ACLDatacenter
+	}
+
+	if done, err := a.srv.forward("ACL.AgentIntroduceRevoke", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "token", "introduce", "revoke"}, time.Now())
+
+	if _, err := uuid.ParseUUID(args.TokenID); err != nil {
+		return fmt.Errorf("Accessor ID is missing or an invalid UUID")
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil {
+		return acl.ErrPermissionDenied
+	}
+	introducer, err := a.introducerID(args.Token)
+	if err != nil {
+		return err
+	}
+
+	_, token, err := a.srv.fsm.State().ACLTokenGetByAccessor(nil, args.TokenID)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token.IntroducedBy == "" || token.IntroducedBy != introducer {
+		return acl.PermissionDeniedError{Cause: "Not permitted to revoke a token that was not introduced with this token"}
+	}
+
+	return a.tokenDeleteInternal("ACL.AgentIntroduceRevoke", args, token, reply)
+}
+
+// introducerID returns the accessor ID of the introduction token of a
+// request, which is recorded in the introduced agent tokens.
+func (a *ACL) introducerID(token string) (string, error) {
+	_, identity, err := a.srv.ResolveIdentityFromToken(token)
+	if err != nil {
+		return "", err
+	}
+	if identity == nil || identity.ID() == "" {
+		return "", acl.ErrPermissionDenied
+	}
+	return identity.ID(), nil
+}
+
 func (a *ACL) TokenUpsert(args *structs.ACLTokenUpsertRequest, reply *structs.ACLToken) error {
 	if err := a.aclPreCheck(); err != nil {
 		return err
@@ -301,10 +441,20 @@ func (a *ACL) TokenUpsert(args *structs.ACLTokenUpsertRequest, reply *structs.AC
 	defer metrics.MeasureSince([]string{"acl", "token", "upsert"}, time.Now())
 
 	// Verify token is permitted to modify ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	// Tokens are only linked to an auth method by a login
+	if args.ACLToken.AccessorID == "" && args.ACLToken.AuthMethod != "" {
+		return fmt.Errorf("AuthMethod field is disallowed outside of Login")
+	}
+
+	// Tokens are only linked to an introduction token by an introduction
+	if args.ACLToken.AccessorID == "" && args.ACLToken.IntroducedBy != "" {
+		return fmt.Errorf("IntroducedBy field is disallowed outside of AgentIntroduce")
 	}
 
 	return a.tokenUpsertInternal(args, reply, false)
@@ -339,6 +489,34 @@ func (a *ACL) tokenUpsertInternal(args *structs.ACLTokenUpsertRequest, reply *st
 		}
 
 		token.CreateTime = time.Now()
+
+		// Turn the TTL into an expiration time
+		if token.ExpirationTTL != 0 {
+			if token.ExpirationTTL < 0 {
+				return fmt.Errorf("Token Expiration TTL '%s' should be > 0", token.ExpirationTTL)
+			}
+			if token.HasExpirationTime() {
+				return fmt.Errorf("Token Expiration TTL and Expiration Time cannot both be set")
+			}
+			expirationTime := token.CreateTime.Add(token.ExpirationTTL)
+			token.ExpirationTime = &expirationTime
+			token.ExpirationTTL = 0
+		}
+
+		if token.HasExpirationTime() {
+			if token.CreateTime.After(*token.ExpirationTime) {
+				return fmt.Errorf("ExpirationTime cannot be before CreateTime")
+			}
+
+			expiresIn := token.ExpirationTime.Sub(token.CreateTime)
+			if expiresIn > a.srv.config.ACLTokenMaxExpirationTTL {
+				return fmt.Errorf("ExpirationTime cannot be more than %s in the future (was %s)",
+					a.srv.config.ACLTokenMaxExpirationTTL, expiresIn)
+			} else if expiresIn < a.srv.config.ACLTokenMinExpirationTTL {
+				return fmt.Errorf("ExpirationTime cannot be less than %s in the future (was %s)",
+					a.srv.config.ACLTokenMinExpirationTTL, expiresIn)
+			}
+		}
 	} else {
 		// Token Update
 		if _, err := uuid.ParseUUID(token.AccessorID); err != nil {
@@ -362,7 +540,7 @@ func (a *ACL) tokenUpsertInternal(args *structs.ACLTokenUpsertRequest, reply *st
 		if err != nil {
 			return fmt.Errorf("Failed to lookup the acl token %q: %v", token.AccessorID, err)
 		}
-		if existing == nil {
+		if existing == nil || existing.IsExpired(time.Now()) {
 			return fmt.Errorf("Cannot find token %q", token.AccessorID)
 		}
 		if token.SecretID == "" {
@@ -376,11 +554,33 @@ func (a *ACL) tokenUpsertInternal(args *structs.ACLTokenUpsertRequest, reply *st
 			return fmt.Errorf("cannot toggle local mode of %s", token.AccessorID)
 		}
 
+		if token.AuthMethod == "" {
+			token.AuthMethod = existing.AuthMethod
+		} else if existing.AuthMethod != token.AuthMethod {
+			return fmt.Errorf("Cannot change AuthMethod of %s", token.AccessorID)
+		}
+
+		if token.IntroducedBy == "" {
+			token.IntroducedBy = existing.IntroducedBy
+		} else if existing.IntroducedBy != token.IntroducedBy {
+			return fmt.Errorf("Cannot change IntroducedBy of %s", token.AccessorID)
+		}
+
 		if upgrade {
 			token.CreateTime = time.Now()
 		} else {
 			token.CreateTime = existing.CreateTime
 		}
+
+		// The expiration of a token is fixed when it is created
+		if token.ExpirationTTL != 0 {
+			return fmt.Errorf("Cannot change expiration time of %s", token.AccessorID)
+		}
+		if !token.HasExpirationTime() {
+			token.ExpirationTime = existing.ExpirationTime
+		} else if !existing.HasExpirationTime() || !token.ExpirationTime.Equal(*existing.ExpirationTime) {
+			return fmt.Errorf("Cannot change expiration time of %s", token.AccessorID)
+		}
 	}
 
 	policyIDs := make(map[string]struct{})
@@ -410,6 +610,62 @@ func (a *ACL) tokenUpsertInternal(args *structs.ACLTokenUpsertRequest, reply *st
 	}
 	token.Policies = policies
 
+	var nodeIdentities []*structs.ACLNodeIdentity
+	nodeIdentityKeys := make(map[string]struct{})
+	for _, nodeIdent := range token.NodeIdentities {
+		if nodeIdent.NodeName == "" {
+			return fmt.Errorf("Node identity is missing the node name field on this token")
+		}
+		if nodeIdent.Datacenter == "" {
+			return fmt.Errorf("Node identity %q is missing the datacenter field on this token", nodeIdent.NodeName)
+		}
+		if !isValidNodeIdentityName(nodeIdent.NodeName) {
+			return fmt.Errorf("Node identity %q has an invalid name. Only alphanumeric characters, '-', '_' and '.' are allowed", nodeIdent.NodeName)
+		}
+
+		// dedup node identities by name and datacenter
+		key := nodeIdent.NodeName + "\x00" + nodeIdent.Datacenter
+		if _, ok := nodeIdentityKeys[key]; !ok {
+			nodeIdentities = append(nodeIdentities, nodeIdent)
+			nodeIdentityKeys[key] = struct{}{}
+		}
+	}
+	token.NodeIdentities = nodeIdentities
+
+	var serviceIdentities []*structs.ACLServiceIdentity
+	serviceIdentityIndex := make(map[string]*structs.ACLServiceIdentity)
+	for _, svcIdent := range token.ServiceIdentities {
+		if svcIdent.ServiceName == "" {
+			return fmt.Errorf("Service identity is missing the service name field on this token")
+		}
+		if !isValidServiceIdentityName(svcIdent.ServiceName) {
+			return fmt.Errorf("Service identity %q has an invalid name. Only lowercase alphanumeric characters, '-' and '_' are allowed", svcIdent.ServiceName)
+		}
+		if token.Local && len(svcIdent.Datacenters) > 0 {
+			return fmt.Errorf("Service identity %q cannot specify a list of datacenters on a local token", svcIdent.ServiceName)
+		}
+
+		// dedup service identities by name, merging their datacenters. An
+		// identity without datacenters is valid in all of them.
+		existing, ok := serviceIdentityIndex[svcIdent.ServiceName]
+		if !ok {
+			svcIdent = svcIdent.Clone()
+			serviceIdentities = append(serviceIdentities, svcIdent)
+			serviceIdentityIndex[svcIdent.ServiceName] = svcIdent
+			continue
+		}
+		if len(existing.Datacenters) == 0 || len(svcIdent.Datacenters) == 0 {
+			existing.Datacenters = nil
+			continue
+		}
+		for _, dc := range svcIdent.Datacenters {
+			if !lib.StrContains(existing.Datacenters, dc) {
+				existing.Datacenters = append(existing.Datacenters, dc)
+			}
+		}
+	}
+	token.ServiceIdentities = serviceIdentities
+
 	if token.Rules != "" {
 		return fmt.Errorf("Rules cannot be specified for this token")
 	}
@@ -446,6 +702,27 @@ func (a *ACL) tokenUpsertInternal(args *structs.ACLTokenUpsertRequest, reply *st
 	return nil
 }
 
+// isValidNodeIdentityName returns true if the provided name can be used as
+// the node name of a node identity. The name is interpolated into the rules of
+// the synthetic policy so it must not contain any characters needing escaping.
+func isValidNodeIdentityName(name string) bool {
+	if len(name) < 1 || len(name) > 256 {
+		return false
+	}
+	return validNodeIdentityName.MatchString(name)
+}
+
+// isValidServiceIdentityName returns true if the provided name can be used as
+// an ACLServiceIdentity ServiceName. This is more restrictive than standard
+// catalog registration, which basically takes the view that "everything is
+// valid".
+func isValidServiceIdentityName(name string) bool {
+	if len(name) < 1 || len(name) > 256 {
+		return false
+	}
+	return validServiceIdentityName.MatchString(name)
+}
+
 func (a *ACL) TokenDelete(args *structs.ACLTokenDeleteRequest, reply *string) error {
 	if err := a.aclPreCheck(); err != nil {
 		return err
@@ -462,10 +739,10 @@ func (a *ACL) TokenDelete(args *structs.ACLTokenDeleteRequest, reply *string) er
 	defer metrics.MeasureSince([]string{"acl", "token", "delete"}, time.Now())
 
 	// Verify token is permitted to modify ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	if _, err := uuid.ParseUUID(args.TokenID); err != nil {
@@ -482,9 +759,16 @@ func (a *ACL) TokenDelete(args *structs.ACLTokenDeleteRequest, reply *string) er
 		return err
 	}
 
+	return a.tokenDeleteInternal("ACL.TokenDelete", args, token, reply)
+}
+
+// tokenDeleteInternal deletes a token after the request has been authorized.
+// Global tokens are deleted in the ACL datacenter, so the request is forwarded
+// there with the given method.
+func (a *ACL) tokenDeleteInternal(method string, args *structs.ACLTokenDeleteRequest, token *structs.ACLToken, reply *string) error {
 	if !a.srv.InACLDatacenter() && !token.Local {
 		args.Datacenter = a.srv.config.ACLDatacenter
-		return a.srv.forwardDC("ACL.TokenDelete", a.srv.config.ACLDatacenter, args, reply)
+		return a.srv.forwardDC(method, a.srv.config.ACLDatacenter, args, reply)
 	}
 
 	req := &structs.ACLTokenBatchDeleteRequest{
@@ -512,6 +796,96 @@ func (a *ACL) TokenDelete(args *structs.ACLTokenDeleteRequest, reply *string) er
 	return nil
 }
 
+// TokenFilterDelete deletes all the tokens matching a filter expression in
+// batches. Global tokens are only deleted in the ACL datacenter, other
+// datacenters only delete their local tokens. The anonymous token and the
+// token used to make the request are never deleted.
+func (a *ACL) TokenFilterDelete(args *structs.ACLTokenFilterDeleteRequest, reply *structs.ACLTokenFilterDeleteResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		args.Datacenter = a.srv.config.ACLDatacenter
+	}
+
+	if done, err := a.srv.forward("ACL.TokenFilterDelete", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "token", "filter_delete"}, time.Now())
+
+	// Verify token is permitted to modify ACLs
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	expr, err := filter.Parse(args.Filter)
+	if err != nil {
+		return fmt.Errorf("Invalid filter: %v", err)
+	}
+	if err := expr.Validate(&structs.ACLToken{}); err != nil {
+		return fmt.Errorf("Invalid filter: %v", err)
+	}
+
+	_, tokens, err := a.srv.fsm.State().ACLTokenList(nil, true, a.srv.InACLDatacenter(), "")
+	if err != nil {
+		return err
+	}
+
+	var matches structs.ACLTokens
+	for _, token := range tokens {
+		if token.AccessorID == structs.ACLTokenAnonymousID || token.SecretID == args.Token {
+			continue
+		}
+		if expr.Match(token) {
+			matches = append(matches, token)
+		}
+	}
+
+	reply.Tokens = make(structs.ACLTokenListStubs, 0, len(matches))
+	if args.DryRun {
+		for _, token := range matches {
+			reply.Tokens = append(reply.Tokens, token.Stub())
+		}
+		return nil
+	}
+
+	for i := 0; i < len(matches); i += aclBatchDeleteSize {
+		batch := matches[i:]
+		if len(batch) > aclBatchDeleteSize {
+			batch = batch[:aclBatchDeleteSize]
+		}
+
+		req := &structs.ACLTokenBatchDeleteRequest{}
+		for _, token := range batch {
+			req.TokenIDs = append(req.TokenIDs, token.AccessorID)
+		}
+
+		resp, err := a.srv.raftApply(structs.ACLTokenDeleteRequestType, req)
+		if err != nil {
+			return fmt.Errorf("Failed to apply token delete request: %v", err)
+		}
+
+		// Purge the identities from the cache to prevent using the deleted tokens
+		for _, token := range batch {
+			a.srv.acls.cache.RemoveIdentity(token.SecretID)
+		}
+
+		if respErr, ok := resp.(error); ok {
+			return respErr
+		}
+
+		for _, token := range batch {
+			reply.Tokens = append(reply.Tokens, token.Stub())
+		}
+	}
+
+	return nil
+}
+
 func (a *ACL) TokenList(args *structs.ACLTokenListRequest, reply *structs.ACLTokenListResponse) error {
 	if err := a.aclPreCheck(); err != nil {
 		return err
@@ -530,25 +904,47 @@ func (a *ACL) TokenList(args *structs.ACLTokenListRequest, reply *structs.ACLTok
 		return err
 	}
 
-	rule, err := a.srv.ResolveToken(args.Token)
+	rule, err := a.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	} else if rule == nil || !rule.ACLRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	expr, err := parseACLListFilter(args.Filter, &structs.ACLToken{})
+	if err != nil {
+		return err
 	}
 
 	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
 		func(ws memdb.WatchSet, state *state.Store) error {
-			index, tokens, err := state.ACLTokenList(ws, args.IncludeLocal, args.IncludeGlobal, args.Policy)
+			var index uint64
+			var tokens structs.ACLTokens
+			var next string
+			var err error
+			if args.AccessorPrefix != "" || args.Next != "" || args.Limit > 0 {
+				index, tokens, next, err = state.ACLTokenListPage(ws, args.IncludeLocal, args.IncludeGlobal,
+					args.Policy, args.AccessorPrefix, args.Next, args.Limit)
+			} else {
+				index, tokens, err = state.ACLTokenList(ws, args.IncludeLocal, args.IncludeGlobal, args.Policy)
+			}
 			if err != nil {
 				return err
 			}
 
+			now := time.Now()
 			stubs := make([]*structs.ACLTokenListStub, 0, len(tokens))
 			for _, token := range tokens {
+				// Expired tokens are treated as deleted until they get reaped
+				if token.IsExpired(now) {
+					continue
+				}
+				if expr != nil && !expr.Match(token) {
+					continue
+				}
 				stubs = append(stubs, token.Stub())
 			}
-			reply.Index, reply.Tokens = index, stubs
+			reply.Index, reply.Tokens, reply.Next = index, stubs, next
 			return nil
 		})
 }
@@ -566,11 +962,11 @@ func (a *ACL) TokenBatchRead(args *structs.ACLTokenBatchReadRequest, reply *stru
 		return err
 	}
 
-	rule, err := a.srv.ResolveToken(args.Token)
+	rule, err := a.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	} else if rule == nil || !rule.ACLRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "read")
 	}
 
 	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
@@ -596,10 +992,10 @@ func (a *ACL) PolicyRead(args *structs.ACLPolicyReadRequest, reply *structs.ACLP
 		return err
 	}
 
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "read")
 	}
 
 	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
@@ -624,10 +1020,10 @@ func (a *ACL) PolicyBatchRead(args *structs.ACLPolicyBatchReadRequest, reply *st
 		return err
 	}
 
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "read")
 	}
 
 	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
@@ -658,10 +1054,10 @@ func (a *ACL) PolicyUpsert(args *structs.ACLPolicyUpsertRequest, reply *structs.
 	defer metrics.MeasureSince([]string{"acl", "policy", "upsert"}, time.Now())
 
 	// Verify token is permitted to modify ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	policy := &args.Policy
@@ -775,10 +1171,10 @@ func (a *ACL) PolicyDelete(args *structs.ACLPolicyDeleteRequest, reply *string)
 	defer metrics.MeasureSince([]string{"acl", "policy", "delete"}, time.Now())
 
 	// Verify token is permitted to modify ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	_, policy, err := a.srv.fsm.State().ACLPolicyGetByID(nil, args.PolicyID)
@@ -820,91 +1216,350 @@ func (a *ACL) PolicyDelete(args *structs.ACLPolicyDeleteRequest, reply *string)
 	return nil
 }
 
-func (a *ACL) PolicyList(args *structs.ACLPolicyListRequest, reply *structs.ACLPolicyListResponse) error {
+// PolicyImpact returns the tokens linked to a policy whose access would change
+// if the policy was updated with the given rules or deleted. The access of a
+// token is evaluated for the resources the rules of the policy apply to, before
+// and after the change, regardless of the datacenters the policies are scoped
+// to. Nothing is changed.
+func (a *ACL) PolicyImpact(args *structs.ACLPolicyImpactRequest, reply *structs.ACLPolicyImpactResponse) error {
 	if err := a.aclPreCheck(); err != nil {
 		return err
 	}
 
-	if done, err := a.srv.forward("ACL.PolicyList", args, args, reply); done {
-		return err
+	if !a.srv.InACLDatacenter() {
+		args.Datacenter = a.srv.config.ACLDatacenter
 	}
 
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if done, err := a.srv.forward("ACL.PolicyImpact", args, args, reply); done {
 		return err
-	} else if rule == nil || !rule.ACLRead() {
-		return acl.ErrPermissionDenied
 	}
 
-	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
-		func(ws memdb.WatchSet, state *state.Store) error {
-			index, policies, err := state.ACLPolicyList(ws, args.DCScope)
-			if err != nil {
-				return err
-			}
-
-			var stubs structs.ACLPolicyListStubs
-			for _, policy := range policies {
-				stubs = append(stubs, policy.Stub())
-			}
-
-			reply.Index, reply.Policies = index, stubs
-			return nil
-		})
-}
+	defer metrics.MeasureSince([]string{"acl", "policy", "impact"}, time.Now())
 
-// PolicyResolve is used to retrieve a subset of the policies associated with a given token
-// The policy ids in the args simply act as a filter on the policy set assigned to the token
-func (a *ACL) PolicyResolve(args *structs.ACLPolicyBatchReadRequest, reply *structs.ACLPoliciesResponse) error {
-	if err := a.aclPreCheck(); err != nil {
+	// The same permission as for applying the change is required, as the
+	// impact of a change is only of interest to those who can make it.
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
-	if done, err := a.srv.forward("ACL.PolicyResolve", args, args, reply); done {
-		return err
+	if a.srv.UseLegacyACLs() {
+		return fmt.Errorf("The impact of policy changes is not available with legacy ACLs")
 	}
 
-	// get full list of policies for this token
-	policies, err := a.srv.acls.resolveTokenToPolicies(args.Token)
+	state := a.srv.fsm.State()
+	_, existing, err := state.ACLPolicyGetByID(nil, args.Policy.ID)
 	if err != nil {
-		return err
+		return fmt.Errorf("acl policy lookup failed: %v", err)
+	} else if existing == nil {
+		return fmt.Errorf("cannot find policy %s", args.Policy.ID)
 	}
 
-	idMap := make(map[string]*structs.ACLPolicy)
-	for _, policy := range policies {
-		idMap[policy.ID] = policy
+	before, err := acl.NewPolicyFromSource("", 0, existing.Rules, existing.Syntax, a.srv.sentinel)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %v", existing.Name, err)
 	}
 
-	for _, policyID := range args.PolicyIDs {
-		if policy, ok := idMap[policyID]; ok {
-			reply.Policies = append(reply.Policies, policy)
+	proposed := *existing
+	proposed.Rules = args.Policy.Rules
+	proposed.Syntax = args.Policy.Syntax
+	var after *acl.Policy
+	if !args.Delete {
+		if after, err = acl.NewPolicyFromSource("", 0, proposed.Rules, proposed.Syntax, a.srv.sentinel); err != nil {
+			return err
 		}
 	}
-	a.srv.setQueryMeta(&reply.QueryMeta)
-
-	return nil
-}
-
-// makeACLETag returns an ETag for the given parent and policy.
-func makeACLETag(parent string, policy *acl.Policy) string {
-	return fmt.Sprintf("%s:%s", parent, policy.ID)
-}
-
-// GetPolicy is used to retrieve a compiled policy object with a TTL. Does not
-// support a blocking query.
-func (a *ACL) GetPolicy(args *structs.ACLPolicyResolveLegacyRequest, reply *structs.ACLPolicyResolveLegacyResponse) error {
-	if done, err := a.srv.forward("ACL.GetPolicy", args, args, reply); done {
-		return err
-	}
-
-	// Verify we are allowed to serve this request
-	if a.srv.config.ACLDatacenter != a.srv.config.Datacenter {
-		return acl.ErrDisabled
-	}
+	resources := policyImpactResources(before, after)
 
-	// Get the policy via the cache
-	parent := a.srv.config.ACLDefaultPolicy
-
-	policy, err := a.srv.acls.GetMergedPolicyForToken(args.ACL)
+	_, tokens, err := state.ACLTokenList(nil, true, true, existing.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	parent := acl.RootAuthorizer(a.srv.config.ACLDefaultPolicy)
+	for _, token := range tokens {
+		// Expired tokens are treated as deleted until they get reaped
+		if token.IsExpired(now) {
+			continue
+		}
+
+		_, policies, err := state.ACLPolicyBatchRead(nil, token.PolicyIDs())
+		if err != nil {
+			return err
+		}
+		for _, nodeIdent := range token.NodeIdentityList() {
+			policies = append(policies, nodeIdent.SyntheticPolicy())
+		}
+		for _, svcIdent := range token.ServiceIdentityList() {
+			policies = append(policies, svcIdent.SyntheticPolicy())
+		}
+
+		changed := make(structs.ACLPolicies, 0, len(policies))
+		for _, policy := range policies {
+			if policy.ID != existing.ID {
+				changed = append(changed, policy)
+			} else if !args.Delete {
+				changed = append(changed, &proposed)
+			}
+		}
+
+		authzBefore, err := compileUncached(policies, parent, a.srv.sentinel)
+		if err != nil {
+			return err
+		}
+		authzAfter, err := compileUncached(changed, parent, a.srv.sentinel)
+		if err != nil {
+			return err
+		}
+
+		var changes []*structs.ACLAccessChange
+		for _, res := range resources {
+			levelBefore := aclAccessLevel(authzBefore, res.Resource, res.Segment)
+			levelAfter := aclAccessLevel(authzAfter, res.Resource, res.Segment)
+			if levelBefore != levelAfter {
+				changes = append(changes, &structs.ACLAccessChange{
+					Resource: res.Resource,
+					Segment:  res.Segment,
+					Before:   levelBefore,
+					After:    levelAfter,
+				})
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		reply.Tokens = append(reply.Tokens, &structs.ACLTokenImpact{
+			AccessorID:  token.AccessorID,
+			Description: token.Description,
+			Local:       token.Local,
+			Changes:     changes,
+		})
+	}
+
+	return nil
+}
+
+// compileUncached builds the authorizer of the policies without the caches of
+// the ACL resolver, as the proposed policies have not been stored.
+func compileUncached(policies structs.ACLPolicies, parent acl.Authorizer, sentinel sentinel.Evaluator) (acl.Authorizer, error) {
+	parsed := make([]*acl.Policy, 0, len(policies))
+	for _, policy := range policies {
+		p, err := acl.NewPolicyFromSource(policy.ID, policy.ModifyIndex, policy.Rules, policy.Syntax, sentinel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %v", policy.Name, err)
+		}
+		parsed = append(parsed, p)
+	}
+	return acl.NewPolicyAuthorizer(parent, parsed, sentinel)
+}
+
+// policyImpactResources returns the resources the rules of either policy
+// apply to, sorted by resource and segment. The after policy may be nil.
+func policyImpactResources(before, after *acl.Policy) []*structs.ACLAccessChange {
+	seen := make(map[structs.ACLAccessChange]struct{})
+	var out []*structs.ACLAccessChange
+	add := func(resource, segment string) {
+		res := structs.ACLAccessChange{Resource: resource, Segment: segment}
+		if _, ok := seen[res]; ok {
+			return
+		}
+		seen[res] = struct{}{}
+		out = append(out, &res)
+	}
+
+	for _, policy := range []*acl.Policy{before, after} {
+		if policy == nil {
+			continue
+		}
+		if policy.ACL != "" {
+			add("acl", "")
+		}
+		if policy.Keyring != "" {
+			add("keyring", "")
+		}
+		if policy.Operator != "" {
+			add("operator", "")
+		}
+		for _, rule := range policy.Agents {
+			add("agent", rule.Node)
+		}
+		for _, rule := range policy.AgentPrefixes {
+			add("agent_prefix", rule.Node)
+		}
+		for _, rule := range policy.Keys {
+			add("key", rule.Prefix)
+		}
+		for _, rule := range policy.KeyPrefixes {
+			add("key_prefix", rule.Prefix)
+		}
+		for _, rule := range policy.Nodes {
+			add("node", rule.Name)
+		}
+		for _, rule := range policy.NodePrefixes {
+			add("node_prefix", rule.Name)
+		}
+		for _, rule := range policy.Services {
+			add("service", rule.Name)
+		}
+		for _, rule := range policy.ServicePrefixes {
+			add("service_prefix", rule.Name)
+		}
+		for _, rule := range policy.Sessions {
+			add("session", rule.Node)
+		}
+		for _, rule := range policy.SessionPrefixes {
+			add("session_prefix", rule.Node)
+		}
+		for _, rule := range policy.Events {
+			add("event", rule.Event)
+		}
+		for _, rule := range policy.EventPrefixes {
+			add("event_prefix", rule.Event)
+		}
+		for _, rule := range policy.PreparedQueries {
+			add("query", rule.Prefix)
+		}
+		for _, rule := range policy.PreparedQueryPrefixes {
+			add("query_prefix", rule.Prefix)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Resource != out[j].Resource {
+			return out[i].Resource < out[j].Resource
+		}
+		return out[i].Segment < out[j].Segment
+	})
+	return out
+}
+
+// aclAccessLevel returns the access of the authorizer to the resource, which
+// is "deny", "read" or "write". The rules for prefixes are evaluated for the
+// prefix itself.
+func aclAccessLevel(authz acl.Authorizer, resource, segment string) string {
+	resource = strings.TrimSuffix(resource, "_prefix")
+	if write, _ := acl.Allowed(authz, resource, segment, acl.PolicyWrite); write {
+		return acl.PolicyWrite
+	}
+	if read, _ := acl.Allowed(authz, resource, segment, acl.PolicyRead); read {
+		return acl.PolicyRead
+	}
+	return acl.PolicyDeny
+}
+
+// parseACLListFilter parses the optional filter expression of a list request,
+// which may only use the selectors of the listed objects. It returns nil if
+// there is no filter.
+func parseACLListFilter(expression string, fields filter.Fields) (*filter.Expression, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	expr, err := filter.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid filter: %v", err)
+	}
+	if err := expr.Validate(fields); err != nil {
+		return nil, fmt.Errorf("Invalid filter: %v", err)
+	}
+	return expr, nil
+}
+
+func (a *ACL) PolicyList(args *structs.ACLPolicyListRequest, reply *structs.ACLPolicyListResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if done, err := a.srv.forward("ACL.PolicyList", args, args, reply); done {
+		return err
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	expr, err := parseACLListFilter(args.Filter, &structs.ACLPolicy{})
+	if err != nil {
+		return err
+	}
+
+	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, policies, err := state.ACLPolicyList(ws, args.DCScope)
+			if err != nil {
+				return err
+			}
+
+			var stubs structs.ACLPolicyListStubs
+			for _, policy := range policies {
+				if expr != nil && !expr.Match(policy) {
+					continue
+				}
+				stubs = append(stubs, policy.Stub())
+			}
+
+			reply.Index, reply.Policies = index, stubs
+			return nil
+		})
+}
+
+// PolicyResolve is used to retrieve a subset of the policies associated with a given token
+// The policy ids in the args simply act as a filter on the policy set assigned to the token
+func (a *ACL) PolicyResolve(args *structs.ACLPolicyBatchReadRequest, reply *structs.ACLPoliciesResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if done, err := a.srv.forward("ACL.PolicyResolve", args, args, reply); done {
+		return err
+	}
+
+	// get full list of policies for this token
+	policies, err := a.srv.acls.resolveTokenToPolicies(args.Token, "")
+	if err != nil {
+		return err
+	}
+
+	idMap := make(map[string]*structs.ACLPolicy)
+	for _, policy := range policies {
+		idMap[policy.ID] = policy
+	}
+
+	for _, policyID := range args.PolicyIDs {
+		if policy, ok := idMap[policyID]; ok {
+			reply.Policies = append(reply.Policies, policy)
+		}
+	}
+	a.srv.setQueryMeta(&reply.QueryMeta)
+
+	return nil
+}
+
+// makeACLETag returns an ETag for the given parent and policy.
+func makeACLETag(parent string, policy *acl.Policy) string {
+	return fmt.Sprintf("%s:%s", parent, policy.ID)
+}
+
+// GetPolicy is used to retrieve a compiled policy object with a TTL. Does not
+// support a blocking query.
+func (a *ACL) GetPolicy(args *structs.ACLPolicyResolveLegacyRequest, reply *structs.ACLPolicyResolveLegacyResponse) error {
+	if done, err := a.srv.forward("ACL.GetPolicy", args, args, reply); done {
+		return err
+	}
+
+	// Verify we are allowed to serve this request
+	if a.srv.config.ACLDatacenter != a.srv.config.Datacenter {
+		return acl.ErrDisabled
+	}
+
+	// Get the policy via the cache
+	parent := a.srv.config.ACLDefaultPolicy
+
+	policy, err := a.srv.acls.GetMergedPolicyForToken(args.ACL)
 	if err != nil {
 		return err
 	}
@@ -949,3 +1604,643 @@ func (a *ACL) ReplicationStatus(args *structs.DCSpecificRequest,
 	a.srv.aclReplicationStatusLock.RUnlock()
 	return nil
 }
+
+func (a *ACL) AuthMethodRead(args *structs.ACLAuthMethodReadRequest, reply *structs.ACLAuthMethodResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.AuthMethodRead", args, args, reply); done {
+		return err
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, method, err := state.ACLAuthMethodGetByName(ws, args.AuthMethodName)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.AuthMethod = index, method
+			return nil
+		})
+}
+
+func (a *ACL) AuthMethodUpsert(args *structs.ACLAuthMethodUpsertRequest, reply *structs.ACLAuthMethod) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.AuthMethodUpsert", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "authmethod", "upsert"}, time.Now())
+
+	// Verify token is permitted to modify ACLs
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	method := &args.AuthMethod
+	state := a.srv.fsm.State()
+
+	// ensure a name is set
+	if method.Name == "" {
+		return fmt.Errorf("Invalid Auth Method: no Name is set")
+	}
+	if !validAuthMethodName.MatchString(method.Name) {
+		return fmt.Errorf("Invalid Auth Method: invalid Name. Only alphanumeric characters, '-' and '_' are allowed")
+	}
+
+	_, existing, err := state.ACLAuthMethodGetByName(nil, method.Name)
+	if err != nil {
+		return fmt.Errorf("acl auth method lookup failed: %v", err)
+	}
+
+	if existing == nil {
+		if method.Type == "" {
+			return fmt.Errorf("Invalid Auth Method: Type is required")
+		}
+	} else if method.Type == "" {
+		method.Type = existing.Type
+	} else if method.Type != existing.Type {
+		return fmt.Errorf("Invalid Auth Method: cannot change Type")
+	}
+
+	if !authmethod.IsRegisteredType(method.Type) {
+		return fmt.Errorf("Invalid Auth Method: Type should be one of: %v", authmethod.Types())
+	}
+
+	if method.MaxTokenTTL != 0 {
+		if method.MaxTokenTTL > a.srv.config.ACLTokenMaxExpirationTTL {
+			return fmt.Errorf("Invalid Auth Method: MaxTokenTTL cannot be more than %s (was %s)",
+				a.srv.config.ACLTokenMaxExpirationTTL, method.MaxTokenTTL)
+		} else if method.MaxTokenTTL < a.srv.config.ACLTokenMinExpirationTTL {
+			return fmt.Errorf("Invalid Auth Method: MaxTokenTTL cannot be less than %s (was %s)",
+				a.srv.config.ACLTokenMinExpirationTTL, method.MaxTokenTTL)
+		}
+	}
+
+	// Validate all the policy names and convert them to policy IDs
+	policyIDs := make(map[string]struct{})
+	var policies []structs.ACLTokenPolicyLink
+	for _, link := range method.Policies {
+		if link.ID == "" {
+			_, policy, err := state.ACLPolicyGetByName(nil, link.Name)
+			if err != nil {
+				return fmt.Errorf("Error looking up policy for name %q: %v", link.Name, err)
+			}
+			if policy == nil {
+				return fmt.Errorf("No such ACL policy with name %q", link.Name)
+			}
+			link.ID = policy.ID
+		} else {
+			_, policy, err := state.ACLPolicyGetByID(nil, link.ID)
+			if err != nil {
+				return fmt.Errorf("Error looking up policy for id %q: %v", link.ID, err)
+			}
+			if policy == nil {
+				return fmt.Errorf("No such ACL policy with ID %q", link.ID)
+			}
+		}
+
+		// Do not store the policy name as the policy could be renamed in the future.
+		link.Name = ""
+
+		// dedup policy links by id
+		if _, ok := policyIDs[link.ID]; !ok {
+			policies = append(policies, link)
+			policyIDs[link.ID] = struct{}{}
+		}
+	}
+	method.Policies = policies
+
+	// Instantiate a validator to check the config of the auth method.
+	if _, err := authmethod.NewValidator(method); err != nil {
+		return fmt.Errorf("Invalid Auth Method: %v", err)
+	}
+
+	req := &structs.ACLAuthMethodBatchUpsertRequest{
+		AuthMethods: structs.ACLAuthMethods{method},
+	}
+
+	resp, err := a.srv.raftApply(structs.ACLAuthMethodUpsertRequestType, req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply auth method upsert request: %v", err)
+	}
+
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	if _, method, err := a.srv.fsm.State().ACLAuthMethodGetByName(nil, method.Name); err == nil && method != nil {
+		*reply = *method
+	}
+
+	return nil
+}
+
+func (a *ACL) AuthMethodDelete(args *structs.ACLAuthMethodDeleteRequest, reply *string) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.AuthMethodDelete", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "authmethod", "delete"}, time.Now())
+
+	// Verify token is permitted to modify ACLs
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	state := a.srv.fsm.State()
+
+	_, method, err := state.ACLAuthMethodGetByName(nil, args.AuthMethodName)
+	if err != nil {
+		return err
+	}
+
+	if method == nil {
+		return nil
+	}
+
+	// The tokens created with the auth method are deleted along with it, grab
+	// them here so we can invalidate our cache later on.
+	_, tokens, err := state.ACLTokenListByAuthMethod(nil, method.Name)
+	if err != nil {
+		return err
+	}
+
+	req := structs.ACLAuthMethodBatchDeleteRequest{
+		AuthMethodNames: []string{args.AuthMethodName},
+	}
+
+	resp, err := a.srv.raftApply(structs.ACLAuthMethodDeleteRequestType, &req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply auth method delete request: %v", err)
+	}
+
+	// Purge the identities from the cache to prevent using the deleted tokens
+	for _, token := range tokens {
+		a.srv.acls.cache.RemoveIdentity(token.SecretID)
+	}
+
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	*reply = method.Name
+
+	return nil
+}
+
+func (a *ACL) AuthMethodList(args *structs.ACLAuthMethodListRequest, reply *structs.ACLAuthMethodListResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.AuthMethodList", args, args, reply); done {
+		return err
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, methods, err := state.ACLAuthMethodList(ws)
+			if err != nil {
+				return err
+			}
+
+			var stubs structs.ACLAuthMethodListStubs
+			for _, method := range methods {
+				stubs = append(stubs, method.Stub())
+			}
+
+			reply.Index, reply.AuthMethods = index, stubs
+			return nil
+		})
+}
+
+func (a *ACL) BindingRuleRead(args *structs.ACLBindingRuleReadRequest, reply *structs.ACLBindingRuleResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.BindingRuleRead", args, args, reply); done {
+		return err
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, rule, err := state.ACLBindingRuleGetByID(ws, args.BindingRuleID)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.BindingRule = index, rule
+			return nil
+		})
+}
+
+func (a *ACL) BindingRuleUpsert(args *structs.ACLBindingRuleUpsertRequest, reply *structs.ACLBindingRule) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.BindingRuleUpsert", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "bindingrule", "upsert"}, time.Now())
+
+	// Verify token is permitted to modify ACLs
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	rule := &args.BindingRule
+	state := a.srv.fsm.State()
+
+	if rule.ID == "" {
+		// with no binding rule ID one will be generated
+		var err error
+
+		rule.ID, err = lib.GenerateUUID(a.srv.checkBindingRuleUUID)
+		if err != nil {
+			return err
+		}
+
+		if rule.AuthMethod == "" {
+			return fmt.Errorf("Invalid Binding Rule: no AuthMethod is set")
+		}
+	} else {
+		if _, err := uuid.ParseUUID(rule.ID); err != nil {
+			return fmt.Errorf("Binding Rule ID invalid UUID")
+		}
+
+		// Verify the binding rule exists
+		_, existing, err := state.ACLBindingRuleGetByID(nil, rule.ID)
+		if err != nil {
+			return fmt.Errorf("acl binding rule lookup failed: %v", err)
+		} else if existing == nil {
+			return fmt.Errorf("cannot find binding rule %s", rule.ID)
+		}
+
+		if rule.AuthMethod == "" {
+			rule.AuthMethod = existing.AuthMethod
+		} else if existing.AuthMethod != rule.AuthMethod {
+			return fmt.Errorf("the AuthMethod field of a Binding Rule is immutable")
+		}
+	}
+
+	_, method, err := state.ACLAuthMethodGetByName(nil, rule.AuthMethod)
+	if err != nil {
+		return fmt.Errorf("acl auth method lookup failed: %v", err)
+	} else if method == nil {
+		return fmt.Errorf("Invalid Binding Rule: no such auth method %q", rule.AuthMethod)
+	}
+
+	switch rule.BindType {
+	case structs.BindingRuleBindTypeService, structs.BindingRuleBindTypeNode, structs.BindingRuleBindTypePolicy:
+	case "":
+		return fmt.Errorf("Invalid Binding Rule: no BindType is set")
+	default:
+		return fmt.Errorf("Invalid Binding Rule: unknown BindType %q", rule.BindType)
+	}
+
+	if rule.BindName == "" {
+		return fmt.Errorf("Invalid Binding Rule: no BindName is set")
+	}
+
+	// The selector and the bind name may only use the fields the auth method
+	// provides about the identity of a login.
+	validator, err := authmethod.NewValidator(method)
+	if err != nil {
+		return err
+	}
+	available := availableLoginFields(validator)
+
+	if rule.Selector != "" {
+		expr, err := filter.Parse(rule.Selector)
+		if err != nil {
+			return fmt.Errorf("Invalid Binding Rule: invalid Selector: %v", err)
+		}
+		if err := expr.Validate(available); err != nil {
+			return fmt.Errorf("Invalid Binding Rule: invalid Selector: %v", err)
+		}
+	}
+
+	if err := validateBindName(rule.BindType, rule.BindName, available); err != nil {
+		return fmt.Errorf("Invalid Binding Rule: invalid BindName: %v", err)
+	}
+
+	req := &structs.ACLBindingRuleBatchUpsertRequest{
+		BindingRules: structs.ACLBindingRules{rule},
+	}
+
+	resp, err := a.srv.raftApply(structs.ACLBindingRuleUpsertRequestType, req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply binding rule upsert request: %v", err)
+	}
+
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	if _, rule, err := a.srv.fsm.State().ACLBindingRuleGetByID(nil, rule.ID); err == nil && rule != nil {
+		*reply = *rule
+	}
+
+	return nil
+}
+
+func (a *ACL) BindingRuleDelete(args *structs.ACLBindingRuleDeleteRequest, reply *bool) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.BindingRuleDelete", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "bindingrule", "delete"}, time.Now())
+
+	// Verify token is permitted to modify ACLs
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLWrite() {
+		return acl.PermissionDenied("acl", "", "write")
+	}
+
+	_, rule, err := a.srv.fsm.State().ACLBindingRuleGetByID(nil, args.BindingRuleID)
+	if err != nil {
+		return err
+	}
+
+	if rule == nil {
+		return nil
+	}
+
+	req := structs.ACLBindingRuleBatchDeleteRequest{
+		BindingRuleIDs: []string{args.BindingRuleID},
+	}
+
+	resp, err := a.srv.raftApply(structs.ACLBindingRuleDeleteRequestType, &req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply binding rule delete request: %v", err)
+	}
+
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	*reply = true
+
+	return nil
+}
+
+func (a *ACL) BindingRuleList(args *structs.ACLBindingRuleListRequest, reply *structs.ACLBindingRuleListResponse) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if done, err := a.srv.forward("ACL.BindingRuleList", args, args, reply); done {
+		return err
+	}
+
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
+		return err
+	} else if rule == nil || !rule.ACLRead() {
+		return acl.PermissionDenied("acl", "", "read")
+	}
+
+	return a.srv.blockingQuery(&args.QueryOptions, &reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, rules, err := state.ACLBindingRuleList(ws, args.AuthMethod)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.BindingRules = index, rules
+			return nil
+		})
+}
+
+// Login exchanges the credentials presented to an auth method for a local
+// token which is linked to the policies of the auth method and granted the
+// privileges of the binding rules matching the identity of the workload. No
+// ACL privileges are needed, the credentials are the authorization.
+func (a *ACL) Login(args *structs.ACLLoginRequest, reply *structs.ACLToken) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if args.Token != "" { // This shouldn't happen.
+		return fmt.Errorf("do not provide a token when logging in")
+	}
+
+	if done, err := a.srv.forward("ACL.Login", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "login"}, time.Now())
+
+	auth := args.Auth
+	if auth == nil || auth.AuthMethod == "" {
+		return fmt.Errorf("Invalid Login: no AuthMethod is set")
+	}
+	if auth.BearerToken == "" {
+		return fmt.Errorf("Invalid Login: no BearerToken is set")
+	}
+
+	state := a.srv.fsm.State()
+
+	_, method, err := state.ACLAuthMethodGetByName(nil, auth.AuthMethod)
+	if err != nil {
+		return err
+	} else if method == nil {
+		return acl.ErrNotFound
+	}
+
+	validator, err := authmethod.NewValidator(method)
+	if err != nil {
+		return err
+	}
+
+	// Check the credentials with the backend of the auth method.
+	fields, err := validator.ValidateLogin(auth.BearerToken)
+	if err != nil {
+		return acl.PermissionDeniedError{Cause: err.Error()}
+	}
+
+	bindings, err := a.srv.evaluateBindingRules(method, fields)
+	if err != nil {
+		return err
+	}
+
+	// Policies deleted since the auth method was written are skipped.
+	var policies []structs.ACLTokenPolicyLink
+	for _, link := range method.Policies {
+		_, policy, err := state.ACLPolicyGetByID(nil, link.ID)
+		if err != nil {
+			return fmt.Errorf("Error looking up policy for id %q: %v", link.ID, err)
+		}
+		if policy != nil {
+			policies = append(policies, structs.ACLTokenPolicyLink{ID: policy.ID})
+		}
+	}
+	for _, name := range bindings.PolicyNames {
+		_, policy, err := state.ACLPolicyGetByName(nil, name)
+		if err != nil {
+			return fmt.Errorf("Error looking up policy for name %q: %v", name, err)
+		}
+		if policy != nil {
+			policies = append(policies, structs.ACLTokenPolicyLink{ID: policy.ID})
+		}
+	}
+
+	// A token without any privileges is no use to the workload.
+	if len(policies) == 0 && len(bindings.ServiceIdentities) == 0 && len(bindings.NodeIdentities) == 0 {
+		return acl.ErrPermissionDenied
+	}
+
+	description := "token created via login"
+	if len(auth.Meta) > 0 {
+		metaJSON, err := json.Marshal(auth.Meta)
+		if err != nil {
+			return err
+		}
+		description += ": " + string(metaJSON)
+	}
+
+	req := structs.ACLTokenUpsertRequest{
+		Datacenter: args.Datacenter,
+		ACLToken: structs.ACLToken{
+			Description:       description,
+			Policies:          policies,
+			ServiceIdentities: bindings.ServiceIdentities,
+			NodeIdentities:    bindings.NodeIdentities,
+			Local:             true,
+			AuthMethod:        method.Name,
+			ExpirationTTL:     method.MaxTokenTTL,
+		},
+		WriteRequest: args.WriteRequest,
+	}
+
+	return a.tokenUpsertInternal(&req, reply, false)
+}
+
+// Logout deletes the token of the request, which must have been created with
+// a login.
+func (a *ACL) Logout(args *structs.ACLLogoutRequest, reply *bool) error {
+	if err := a.aclPreCheck(); err != nil {
+		return err
+	}
+
+	if !a.srv.LocalTokensEnabled() {
+		return errAuthMethodsRequireTokenReplication
+	}
+
+	if args.Token == "" {
+		return acl.ErrNotFound
+	}
+
+	if done, err := a.srv.forward("ACL.Logout", args, args, reply); done {
+		return err
+	}
+
+	defer metrics.MeasureSince([]string{"acl", "logout"}, time.Now())
+
+	_, token, err := a.srv.fsm.State().ACLTokenGetBySecret(nil, args.Token)
+	if err != nil {
+		return err
+	} else if token == nil || token.IsExpired(time.Now()) {
+		return acl.ErrNotFound
+	} else if token.AuthMethod == "" {
+		// Can't "logout" of a token that wasn't a result of login.
+		return acl.PermissionDeniedError{Cause: "Not permitted to logout of a token that was not created via login"}
+	}
+
+	req := &structs.ACLTokenBatchDeleteRequest{
+		TokenIDs: []string{token.AccessorID},
+	}
+
+	resp, err := a.srv.raftApply(structs.ACLTokenDeleteRequestType, req)
+	if err != nil {
+		return fmt.Errorf("Failed to apply token delete request: %v", err)
+	}
+
+	// Purge the identity from the cache to prevent using the previous definition of the identity
+	a.srv.acls.cache.RemoveIdentity(token.SecretID)
+
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	*reply = true
+
+	return nil
+}
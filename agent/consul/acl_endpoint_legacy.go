@@ -150,10 +150,10 @@ func (a *ACL) Apply(args *structs.ACLRequest, reply *string) error {
 	}
 
 	// Verify token is permitted to modify ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	// If no ID is provided, generate a new ID. This must be done prior to
@@ -231,10 +231,10 @@ func (a *ACL) List(args *structs.DCSpecificRequest,
 	}
 
 	// Verify token is permitted to list ACLs
-	if rule, err := a.srv.ResolveToken(args.Token); err != nil {
+	if rule, err := a.srv.ResolveRequestToken(args); err != nil {
 		return err
 	} else if rule == nil || !rule.ACLWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("acl", "", "write")
 	}
 
 	return a.srv.blockingQuery(&args.QueryOptions,
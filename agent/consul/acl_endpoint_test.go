@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/authmethod/testauth"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/testrpc"
@@ -736,6 +737,8 @@ func TestACLEndpoint_TokenClone(t *testing.T) {
 	assert.Equal(t1.Description, t2.Description)
 	assert.Equal(t1.Policies, t2.Policies)
 	assert.Equal(t1.Rules, t2.Rules)
+	assert.Equal(t1.NodeIdentities, t2.NodeIdentities)
+	assert.Equal(t1.ServiceIdentities, t2.ServiceIdentities)
 	assert.Equal(t1.Local, t2.Local)
 	assert.NotEqual(t1.AccessorID, t2.AccessorID)
 	assert.NotEqual(t1.SecretID, t2.SecretID)
@@ -813,7 +816,365 @@ func TestACLEndpoint_TokenUpsert(t *testing.T) {
 		assert.Equal(token.Description, "new-description")
 		assert.Equal(token.AccessorID, resp.AccessorID)
 	}
+	// Add node identities
+	{
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				Description: "new-description",
+				AccessorID:  tokenID,
+				NodeIdentities: []*structs.ACLNodeIdentity{
+					&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+					&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+					&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc2"},
+				},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+
+		resp := structs.ACLToken{}
+
+		err := acl.TokenUpsert(&req, &resp)
+		assert.NoError(err)
+
+		tokenResp, err := retrieveTestToken(codec, "root", "dc1", resp.AccessorID)
+		assert.NoError(err)
+		token := tokenResp.Token
+
+		assert.Equal([]*structs.ACLNodeIdentity{
+			&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+			&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc2"},
+		}, token.NodeIdentities)
+	}
+	// Invalid node identities
+	for _, nodeIdent := range []*structs.ACLNodeIdentity{
+		&structs.ACLNodeIdentity{Datacenter: "dc1"},
+		&structs.ACLNodeIdentity{NodeName: "web-1"},
+		&structs.ACLNodeIdentity{NodeName: "web\"\n", Datacenter: "dc1"},
+	} {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				AccessorID:     tokenID,
+				NodeIdentities: []*structs.ACLNodeIdentity{nodeIdent},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+
+		resp := structs.ACLToken{}
+		assert.Error(acl.TokenUpsert(&req, &resp))
+	}
+	// Add service identities
+	{
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				Description: "new-description",
+				AccessorID:  tokenID,
+				ServiceIdentities: []*structs.ACLServiceIdentity{
+					&structs.ACLServiceIdentity{ServiceName: "web", Datacenters: []string{"dc1"}},
+					&structs.ACLServiceIdentity{ServiceName: "web", Datacenters: []string{"dc1", "dc2"}},
+					&structs.ACLServiceIdentity{ServiceName: "db", Datacenters: []string{"dc1"}},
+					&structs.ACLServiceIdentity{ServiceName: "db"},
+				},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+
+		resp := structs.ACLToken{}
+
+		err := acl.TokenUpsert(&req, &resp)
+		assert.NoError(err)
+
+		tokenResp, err := retrieveTestToken(codec, "root", "dc1", resp.AccessorID)
+		assert.NoError(err)
+		token := tokenResp.Token
+
+		assert.Equal([]*structs.ACLServiceIdentity{
+			&structs.ACLServiceIdentity{ServiceName: "web", Datacenters: []string{"dc1", "dc2"}},
+			&structs.ACLServiceIdentity{ServiceName: "db"},
+		}, token.ServiceIdentities)
+	}
+	// Invalid service identities
+	for _, svcIdent := range []*structs.ACLServiceIdentity{
+		&structs.ACLServiceIdentity{Datacenters: []string{"dc1"}},
+		&structs.ACLServiceIdentity{ServiceName: "Web"},
+		&structs.ACLServiceIdentity{ServiceName: "web\"\n"},
+	} {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				AccessorID:        tokenID,
+				ServiceIdentities: []*structs.ACLServiceIdentity{svcIdent},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+
+		resp := structs.ACLToken{}
+		assert.Error(acl.TokenUpsert(&req, &resp))
+	}
+}
+
+func TestACLEndpoint_TokenUpsert_Expiration(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLTokenMinExpirationTTL = 10 * time.Millisecond
+		c.ACLTokenMaxExpirationTTL = 5 * time.Second
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	aclEp := ACL{srv: s1}
+
+	// Invalid expirations
+	future := time.Now().Add(time.Second)
+	for _, tc := range []struct {
+		name    string
+		token   structs.ACLToken
+		wantErr string
+	}{
+		{"negative ttl", structs.ACLToken{ExpirationTTL: -time.Second}, "should be > 0"},
+		{"ttl too short", structs.ACLToken{ExpirationTTL: time.Millisecond}, "cannot be less than"},
+		{"ttl too long", structs.ACLToken{ExpirationTTL: time.Hour}, "cannot be more than"},
+		{"ttl and time", structs.ACLToken{ExpirationTTL: time.Second, ExpirationTime: &future}, "cannot both be set"},
+	} {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter:   "dc1",
+			ACLToken:     tc.token,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		resp := structs.ACLToken{}
+		err := aclEp.TokenUpsert(&req, &resp)
+		require.Error(err, tc.name)
+		require.Contains(err.Error(), tc.wantErr, tc.name)
+	}
+
+	// Create a token with a TTL
+	req := structs.ACLTokenUpsertRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description:   "temporary",
+			ExpirationTTL: 500 * time.Millisecond,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	token := structs.ACLToken{}
+	require.NoError(aclEp.TokenUpsert(&req, &token))
+	require.Zero(token.ExpirationTTL)
+	require.NotNil(token.ExpirationTime)
+	require.Equal(token.CreateTime.Add(500*time.Millisecond), *token.ExpirationTime)
+
+	// The expiration can't be changed by an update
+	expires := token.ExpirationTime.Add(time.Second)
+	for _, update := range []structs.ACLToken{
+		{AccessorID: token.AccessorID, ExpirationTTL: time.Second},
+		{AccessorID: token.AccessorID, ExpirationTime: &expires},
+	} {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter:   "dc1",
+			ACLToken:     update,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		resp := structs.ACLToken{}
+		err := aclEp.TokenUpsert(&req, &resp)
+		require.Error(err)
+		require.Contains(err.Error(), "Cannot change expiration time")
+	}
+
+	// Updates without an expiration keep it
+	req = structs.ACLTokenUpsertRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			AccessorID:  token.AccessorID,
+			Description: "still temporary",
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	updated := structs.ACLToken{}
+	require.NoError(aclEp.TokenUpsert(&req, &updated))
+	require.NotNil(updated.ExpirationTime)
+	require.True(token.ExpirationTime.Equal(*updated.ExpirationTime))
+
+	// The token works until it expires
+	_, err := s1.ResolveToken(token.SecretID)
+	require.NoError(err)
+
+	time.Sleep(time.Until(*token.ExpirationTime) + 10*time.Millisecond)
+
+	// Once expired the token is treated as deleted
+	_, err = s1.ResolveToken(token.SecretID)
+	require.True(acl.IsErrNotFound(err), "err: %v", err)
+
+	tokenResp, err := retrieveTestToken(codec, "root", "dc1", token.AccessorID)
+	require.NoError(err)
+	require.Nil(tokenResp.Token)
+
+	listReq := structs.ACLTokenListRequest{
+		Datacenter:    "dc1",
+		IncludeLocal:  true,
+		IncludeGlobal: true,
+		QueryOptions:  structs.QueryOptions{Token: "root"},
+	}
+	listResp := structs.ACLTokenListResponse{}
+	require.NoError(aclEp.TokenList(&listReq, &listResp))
+	for _, stub := range listResp.Tokens {
+		require.NotEqual(token.AccessorID, stub.AccessorID)
+	}
+
+	req = structs.ACLTokenUpsertRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			AccessorID:  token.AccessorID,
+			Description: "expired",
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	err = aclEp.TokenUpsert(&req, &updated)
+	require.Error(err)
+	require.Contains(err.Error(), "Cannot find token")
 }
+
+func TestACLEndpoint_AgentIntroduce(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Create the introduction token which may introduce the web nodes
+	policyReq := structs.ACLPolicyUpsertRequest{
+		Datacenter: "dc1",
+		Policy: structs.ACLPolicy{
+			Name:  "introduce-web",
+			Rules: `node_prefix "web-" { policy = "write" } service_prefix "" { policy = "read" }`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var policy structs.ACLPolicy
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.PolicyUpsert", &policyReq, &policy))
+
+	tokenReq := structs.ACLTokenUpsertRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "introduction",
+			Policies:    []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var introToken structs.ACLToken
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &tokenReq, &introToken))
+
+	t.Run("allowed node", func(t *testing.T) {
+		req := structs.ACLAgentIntroduceRequest{
+			Datacenter:   "dc1",
+			NodeIdentity: structs.ACLNodeIdentity{NodeName: "web-1"},
+			WriteRequest: structs.WriteRequest{Token: introToken.SecretID},
+		}
+		var out structs.ACLToken
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduce", &req, &out))
+		require.NotEmpty(out.SecretID)
+		require.True(out.Local)
+		require.Empty(out.Policies)
+		require.Equal([]*structs.ACLNodeIdentity{
+			&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+		}, out.NodeIdentities)
+
+		// The new token may only register its own node
+		authz, err := s1.ResolveToken(out.SecretID)
+		require.NoError(err)
+		require.True(authz.NodeWrite("web-1", nil))
+		require.False(authz.NodeWrite("web-2", nil))
+	})
+
+	t.Run("denied node", func(t *testing.T) {
+		req := structs.ACLAgentIntroduceRequest{
+			Datacenter:   "dc1",
+			NodeIdentity: structs.ACLNodeIdentity{NodeName: "db-1"},
+			WriteRequest: structs.WriteRequest{Token: introToken.SecretID},
+		}
+		var out structs.ACLToken
+		err := msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduce", &req, &out)
+		require.True(acl.IsErrPermissionDenied(err), err)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		req := structs.ACLAgentIntroduceRequest{
+			Datacenter:   "dc1",
+			NodeIdentity: structs.ACLNodeIdentity{NodeName: "web-2"},
+			WriteRequest: structs.WriteRequest{Token: introToken.SecretID},
+		}
+		var out structs.ACLToken
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduce", &req, &out))
+		require.Equal(introToken.AccessorID, out.IntroducedBy)
+
+		// Tokens which were not created by an introduction cannot be
+		// deleted with the introduction token, even if they only have a
+		// node identity the introduction token may write
+		nodeReq := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				NodeIdentities: []*structs.ACLNodeIdentity{{NodeName: "web-3", Datacenter: "dc1"}},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var nodeToken structs.ACLToken
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &nodeReq, &nodeToken))
+
+		revokeReq := structs.ACLTokenDeleteRequest{
+			Datacenter:   "dc1",
+			TokenID:      nodeToken.AccessorID,
+			WriteRequest: structs.WriteRequest{Token: introToken.SecretID},
+		}
+		var accessorID string
+		err := msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduceRevoke", &revokeReq, &accessorID)
+		require.True(acl.IsErrPermissionDenied(err), err)
+
+		// Only the introduction token which created a token may revoke it
+		tokenReq.ACLToken.Description = "other introduction"
+		var otherToken structs.ACLToken
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &tokenReq, &otherToken))
+		revokeReq.TokenID = out.AccessorID
+		revokeReq.Token = otherToken.SecretID
+		err = msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduceRevoke", &revokeReq, &accessorID)
+		require.True(acl.IsErrPermissionDenied(err), err)
+		revokeReq.Token = introToken.SecretID
+
+		// Operators cannot mark tokens as introduced
+		nodeReq.ACLToken = structs.ACLToken{IntroducedBy: introToken.AccessorID}
+		err = msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &nodeReq, &nodeToken)
+		require.Error(err)
+		require.Contains(err.Error(), "IntroducedBy field is disallowed")
+
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduceRevoke", &revokeReq, &accessorID))
+		require.Equal(out.AccessorID, accessorID)
+		_, token, err := s1.fsm.State().ACLTokenGetByAccessor(nil, out.AccessorID)
+		require.NoError(err)
+		require.Nil(token)
+
+		// Deleting it again is not an error
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.AgentIntroduceRevoke", &revokeReq, &accessorID))
+	})
+}
+
 func TestACLEndpoint_TokenUpsert_anon(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -919,6 +1280,97 @@ func TestACLEndpoint_TokenDelete(t *testing.T) {
 		assert.NoError(err)
 	}
 }
+func TestACLEndpoint_TokenFilterDelete(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	var ciTokens []string
+	for _, desc := range []string{"ci-1", "ci-2", "ci-3", "deploy"} {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter:   "dc1",
+			ACLToken:     structs.ACLToken{Description: desc},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var out structs.ACLToken
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &req, &out))
+		if desc != "deploy" {
+			ciTokens = append(ciTokens, out.AccessorID)
+		}
+	}
+
+	accessors := func(stubs structs.ACLTokenListStubs) []string {
+		var out []string
+		for _, stub := range stubs {
+			out = append(out, stub.AccessorID)
+		}
+		return out
+	}
+
+	// A dry run returns the matching tokens without deleting them
+	req := structs.ACLTokenFilterDeleteRequest{
+		Datacenter:   "dc1",
+		Filter:       `Description contains "ci-"`,
+		DryRun:       true,
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var resp structs.ACLTokenFilterDeleteResponse
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenFilterDelete", &req, &resp))
+	require.ElementsMatch(ciTokens, accessors(resp.Tokens))
+	for _, id := range ciTokens {
+		tokenResp, err := retrieveTestToken(codec, "root", "dc1", id)
+		require.NoError(err)
+		require.NotNil(tokenResp.Token)
+	}
+
+	// Delete the tokens
+	req.DryRun = false
+	resp = structs.ACLTokenFilterDeleteResponse{}
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenFilterDelete", &req, &resp))
+	require.ElementsMatch(ciTokens, accessors(resp.Tokens))
+	for _, id := range ciTokens {
+		tokenResp, err := retrieveTestToken(codec, "root", "dc1", id)
+		require.NoError(err)
+		require.Nil(tokenResp.Token)
+	}
+
+	// The token making the request and the anonymous token are kept
+	req.Filter = `Description is not empty or Description is empty`
+	resp = structs.ACLTokenFilterDeleteResponse{}
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenFilterDelete", &req, &resp))
+	require.Len(resp.Tokens, 1)
+	require.Equal("deploy", resp.Tokens[0].Description)
+
+	listReq := structs.ACLTokenListRequest{
+		Datacenter:    "dc1",
+		IncludeLocal:  true,
+		IncludeGlobal: true,
+		QueryOptions:  structs.QueryOptions{Token: "root"},
+	}
+	var listResp structs.ACLTokenListResponse
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenList", &listReq, &listResp))
+	require.Len(listResp.Tokens, 2)
+
+	// Invalid filters are rejected
+	for _, f := range []string{"", "Description contains", "Policy == foo"} {
+		req.Filter = f
+		err := msgpackrpc.CallWithCodec(codec, "ACL.TokenFilterDelete", &req, &resp)
+		require.Error(err)
+		require.Contains(err.Error(), "Invalid filter")
+	}
+}
+
 func TestACLEndpoint_TokenDelete_anon(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -1334,6 +1786,112 @@ func TestACLEndpoint_PolicyDelete_globalManagement(t *testing.T) {
 	assert.EqualError(err, "Delete operation not permitted on the builtin global-management policy")
 }
 
+func TestACLEndpoint_PolicyImpact(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	policyReq := structs.ACLPolicyUpsertRequest{
+		Datacenter: "dc1",
+		Policy: structs.ACLPolicy{
+			Name:  "web",
+			Rules: `service "web" { policy = "write" } key_prefix "web/" { policy = "read" }`,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var policy structs.ACLPolicy
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.PolicyUpsert", &policyReq, &policy))
+
+	tokenReq := structs.ACLTokenUpsertRequest{
+		Datacenter: "dc1",
+		ACLToken: structs.ACLToken{
+			Description: "web token",
+			Policies:    []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var token structs.ACLToken
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ACL.TokenUpsert", &tokenReq, &token))
+
+	// A token without the policy is never impacted
+	_, err := upsertTestToken(codec, "root", "dc1")
+	require.NoError(err)
+
+	aclEp := ACL{srv: s1}
+
+	t.Run("update", func(t *testing.T) {
+		req := structs.ACLPolicyImpactRequest{
+			Datacenter: "dc1",
+			Policy: structs.ACLPolicy{
+				ID:    policy.ID,
+				Rules: `service "web" { policy = "read" } key_prefix "web/" { policy = "read" }`,
+			},
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var resp structs.ACLPolicyImpactResponse
+		require.NoError(aclEp.PolicyImpact(&req, &resp))
+
+		require.Len(resp.Tokens, 1)
+		require.Equal(token.AccessorID, resp.Tokens[0].AccessorID)
+		require.Equal("web token", resp.Tokens[0].Description)
+		require.Equal([]*structs.ACLAccessChange{
+			{Resource: "service", Segment: "web", Before: "write", After: "read"},
+		}, resp.Tokens[0].Changes)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		req := structs.ACLPolicyImpactRequest{
+			Datacenter:   "dc1",
+			Policy:       structs.ACLPolicy{ID: policy.ID},
+			Delete:       true,
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var resp structs.ACLPolicyImpactResponse
+		require.NoError(aclEp.PolicyImpact(&req, &resp))
+
+		require.Len(resp.Tokens, 1)
+		require.Equal([]*structs.ACLAccessChange{
+			{Resource: "key_prefix", Segment: "web/", Before: "read", After: "deny"},
+			{Resource: "service", Segment: "web", Before: "write", After: "deny"},
+		}, resp.Tokens[0].Changes)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		req := structs.ACLPolicyImpactRequest{
+			Datacenter:   "dc1",
+			Policy:       structs.ACLPolicy{ID: policy.ID, Rules: policy.Rules},
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		var resp structs.ACLPolicyImpactResponse
+		require.NoError(aclEp.PolicyImpact(&req, &resp))
+		require.Empty(resp.Tokens)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		req := structs.ACLPolicyImpactRequest{
+			Datacenter:   "dc1",
+			Policy:       structs.ACLPolicy{ID: policy.ID},
+			Delete:       true,
+			QueryOptions: structs.QueryOptions{Token: token.SecretID},
+		}
+		var resp structs.ACLPolicyImpactResponse
+		err := aclEp.PolicyImpact(&req, &resp)
+		require.True(acl.IsErrPermissionDenied(err), err)
+	})
+}
+
 func TestACLEndpoint_PolicyList(t *testing.T) {
 	t.Parallel()
 	assert := assert.New(t)
@@ -1441,6 +1999,610 @@ func TestACLEndpoint_PolicyResolve(t *testing.T) {
 }
 
 // upsertTestToken creates a token for testing purposes
+func TestACLEndpoint_AuthMethodUpsert(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	aclEp := ACL{srv: s1}
+
+	upsert := func(method structs.ACLAuthMethod) (*structs.ACLAuthMethod, error) {
+		req := structs.ACLAuthMethodUpsertRequest{
+			Datacenter:   "dc1",
+			AuthMethod:   method,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLAuthMethod
+		if err := aclEp.AuthMethodUpsert(&req, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	t.Run("Create it", func(t *testing.T) {
+		method, err := upsert(structs.ACLAuthMethod{
+			Name:        "test",
+			Type:        testauth.Type,
+			Description: "test auth method",
+			Policies:    []structs.ACLTokenPolicyLink{{Name: policy.Name}},
+			MaxTokenTTL: 10 * time.Minute,
+			Config: map[string]interface{}{
+				"SessionID": testSessionID,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "test", method.Name)
+		require.Equal(t, testauth.Type, method.Type)
+		require.Equal(t, []structs.ACLTokenPolicyLink{{ID: policy.ID}}, method.Policies)
+		require.Equal(t, 10*time.Minute, method.MaxTokenTTL)
+	})
+
+	t.Run("Update it", func(t *testing.T) {
+		method, err := upsert(structs.ACLAuthMethod{
+			Name:        "test",
+			Description: "updated",
+			Config: map[string]interface{}{
+				"SessionID": testSessionID,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "updated", method.Description)
+		require.Equal(t, testauth.Type, method.Type)
+	})
+
+	cases := map[string]struct {
+		method structs.ACLAuthMethod
+		err    string
+	}{
+		"missing name": {
+			method: structs.ACLAuthMethod{Type: testauth.Type},
+			err:    "no Name is set",
+		},
+		"invalid name": {
+			method: structs.ACLAuthMethod{Name: "a b", Type: testauth.Type},
+			err:    "invalid Name",
+		},
+		"missing type": {
+			method: structs.ACLAuthMethod{Name: "other"},
+			err:    "Type is required",
+		},
+		"unknown type": {
+			method: structs.ACLAuthMethod{Name: "other", Type: "bogus"},
+			err:    "Type should be one of",
+		},
+		"change type": {
+			method: structs.ACLAuthMethod{Name: "test", Type: "kubernetes"},
+			err:    "cannot change Type",
+		},
+		"invalid config": {
+			method: structs.ACLAuthMethod{Name: "other", Type: testauth.Type},
+			err:    "Config.SessionID is required",
+		},
+		"missing policy": {
+			method: structs.ACLAuthMethod{
+				Name:     "other",
+				Type:     testauth.Type,
+				Policies: []structs.ACLTokenPolicyLink{{Name: "missing"}},
+				Config:   map[string]interface{}{"SessionID": testSessionID},
+			},
+			err: `No such ACL policy with name "missing"`,
+		},
+		"max token ttl too long": {
+			method: structs.ACLAuthMethod{
+				Name:        "other",
+				Type:        testauth.Type,
+				MaxTokenTTL: 48 * time.Hour,
+				Config:      map[string]interface{}{"SessionID": testSessionID},
+			},
+			err: "MaxTokenTTL cannot be more than",
+		},
+		"max token ttl too short": {
+			method: structs.ACLAuthMethod{
+				Name:        "other",
+				Type:        testauth.Type,
+				MaxTokenTTL: time.Second,
+				Config:      map[string]interface{}{"SessionID": testSessionID},
+			},
+			err: "MaxTokenTTL cannot be less than",
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, err := upsert(tc.method)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestACLEndpoint_AuthMethodRead_List_Delete(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+
+	method1, err := upsertTestAuthMethod(codec, "root", "dc1", testSessionID)
+	require.NoError(t, err)
+	method2, err := upsertTestAuthMethod(codec, "root", "dc1", testSessionID)
+	require.NoError(t, err)
+
+	aclEp := ACL{srv: s1}
+
+	t.Run("read", func(t *testing.T) {
+		req := structs.ACLAuthMethodReadRequest{
+			Datacenter:     "dc1",
+			AuthMethodName: method1.Name,
+			QueryOptions:   structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLAuthMethodResponse{}
+		require.NoError(t, aclEp.AuthMethodRead(&req, &resp))
+		require.Equal(t, method1.Name, resp.AuthMethod.Name)
+		require.Len(t, resp.AuthMethod.Config, 1)
+	})
+
+	t.Run("read missing", func(t *testing.T) {
+		req := structs.ACLAuthMethodReadRequest{
+			Datacenter:     "dc1",
+			AuthMethodName: "missing",
+			QueryOptions:   structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLAuthMethodResponse{}
+		require.NoError(t, aclEp.AuthMethodRead(&req, &resp))
+		require.Nil(t, resp.AuthMethod)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		req := structs.ACLAuthMethodListRequest{
+			Datacenter:   "dc1",
+			QueryOptions: structs.QueryOptions{Token: "root"},
+		}
+		resp := structs.ACLAuthMethodListResponse{}
+		require.NoError(t, aclEp.AuthMethodList(&req, &resp))
+		var names []string
+		for _, stub := range resp.AuthMethods {
+			names = append(names, stub.Name)
+		}
+		require.ElementsMatch(t, []string{method1.Name, method2.Name}, names)
+	})
+
+	t.Run("list denied", func(t *testing.T) {
+		req := structs.ACLAuthMethodListRequest{
+			Datacenter: "dc1",
+		}
+		resp := structs.ACLAuthMethodListResponse{}
+		err := aclEp.AuthMethodList(&req, &resp)
+		require.True(t, acl.IsErrPermissionDenied(err), "bad: %v", err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		req := structs.ACLAuthMethodDeleteRequest{
+			Datacenter:     "dc1",
+			AuthMethodName: method1.Name,
+			WriteRequest:   structs.WriteRequest{Token: "root"},
+		}
+		var resp string
+		require.NoError(t, aclEp.AuthMethodDelete(&req, &resp))
+		require.Equal(t, method1.Name, resp)
+
+		_, method, err := s1.fsm.State().ACLAuthMethodGetByName(nil, method1.Name)
+		require.NoError(t, err)
+		require.Nil(t, method)
+
+		// Deleting a missing auth method is not an error.
+		require.NoError(t, aclEp.AuthMethodDelete(&req, &resp))
+	})
+}
+
+func TestACLEndpoint_Login_Logout(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+	testauth.InstallSessionToken(testSessionID, "fake-web", "default", "web", "abc123")
+
+	method, err := upsertTestAuthMethod(codec, "root", "dc1", testSessionID)
+	require.NoError(t, err)
+
+	aclEp := ACL{srv: s1}
+
+	login := func(methodName, bearerToken string) (*structs.ACLToken, error) {
+		req := structs.ACLLoginRequest{
+			Auth: &structs.ACLLoginParams{
+				AuthMethod:  methodName,
+				BearerToken: bearerToken,
+				Meta:        map[string]string{"pod": "web-1"},
+			},
+			Datacenter: "dc1",
+		}
+		var resp structs.ACLToken
+		if err := aclEp.Login(&req, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	logout := func(secretID string) error {
+		req := structs.ACLLogoutRequest{
+			Datacenter:   "dc1",
+			WriteRequest: structs.WriteRequest{Token: secretID},
+		}
+		var ignored bool
+		return aclEp.Logout(&req, &ignored)
+	}
+
+	t.Run("unknown auth method", func(t *testing.T) {
+		_, err := login("missing", "fake-web")
+		require.True(t, acl.IsErrNotFound(err), "bad: %v", err)
+	})
+
+	t.Run("invalid bearer token", func(t *testing.T) {
+		_, err := login(method.Name, "fake-db")
+		require.True(t, acl.IsErrPermissionDenied(err), "bad: %v", err)
+	})
+
+	t.Run("auth method without policies", func(t *testing.T) {
+		_, err := login(method.Name, "fake-web")
+		require.True(t, acl.IsErrPermissionDenied(err), "bad: %v", err)
+	})
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+	method.Policies = []structs.ACLTokenPolicyLink{{ID: policy.ID}}
+	method.MaxTokenTTL = 10 * time.Minute
+	{
+		req := structs.ACLAuthMethodUpsertRequest{
+			Datacenter:   "dc1",
+			AuthMethod:   *method,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLAuthMethod
+		require.NoError(t, aclEp.AuthMethodUpsert(&req, &resp))
+	}
+
+	var token *structs.ACLToken
+	t.Run("login", func(t *testing.T) {
+		var err error
+		token, err = login(method.Name, "fake-web")
+		require.NoError(t, err)
+		require.Equal(t, method.Name, token.AuthMethod)
+		require.True(t, token.Local)
+		require.Equal(t, `token created via login: {"pod":"web-1"}`, token.Description)
+		require.Equal(t, []structs.ACLTokenPolicyLink{{ID: policy.ID, Name: policy.Name}}, token.Policies)
+		require.NotNil(t, token.ExpirationTime)
+		require.Equal(t, 10*time.Minute, token.ExpirationTime.Sub(token.CreateTime))
+	})
+
+	t.Run("auth method can't be changed", func(t *testing.T) {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				AccessorID:     token.AccessorID,
+				Local:          true,
+				AuthMethod:     "other",
+				ExpirationTime: token.ExpirationTime,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLToken
+		err := aclEp.TokenUpsert(&req, &resp)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Cannot change AuthMethod")
+	})
+
+	t.Run("auth method can't be set on create", func(t *testing.T) {
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				Local:      true,
+				AuthMethod: method.Name,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLToken
+		err := aclEp.TokenUpsert(&req, &resp)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "AuthMethod field is disallowed outside of Login")
+	})
+
+	t.Run("logout of a token not created via login", func(t *testing.T) {
+		err := logout("root")
+		require.True(t, acl.IsErrPermissionDenied(err), "bad: %v", err)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		require.NoError(t, logout(token.SecretID))
+
+		_, rtoken, err := s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+		require.NoError(t, err)
+		require.Nil(t, rtoken)
+
+		err = logout(token.SecretID)
+		require.True(t, acl.IsErrNotFound(err), "bad: %v", err)
+	})
+
+	t.Run("deleting the auth method deletes its tokens", func(t *testing.T) {
+		token, err := login(method.Name, "fake-web")
+		require.NoError(t, err)
+
+		req := structs.ACLAuthMethodDeleteRequest{
+			Datacenter:     "dc1",
+			AuthMethodName: method.Name,
+			WriteRequest:   structs.WriteRequest{Token: "root"},
+		}
+		var resp string
+		require.NoError(t, aclEp.AuthMethodDelete(&req, &resp))
+
+		_, rtoken, err := s1.fsm.State().ACLTokenGetByAccessor(nil, token.AccessorID)
+		require.NoError(t, err)
+		require.Nil(t, rtoken)
+	})
+}
+
+func TestACLEndpoint_BindingRuleUpsert(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+
+	method, err := upsertTestAuthMethod(codec, "root", "dc1", testSessionID)
+	require.NoError(t, err)
+
+	aclEp := ACL{srv: s1}
+
+	upsert := func(rule structs.ACLBindingRule) (*structs.ACLBindingRule, error) {
+		req := structs.ACLBindingRuleUpsertRequest{
+			Datacenter:   "dc1",
+			BindingRule:  rule,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLBindingRule
+		if err := aclEp.BindingRuleUpsert(&req, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	for _, tc := range []struct {
+		name string
+		rule structs.ACLBindingRule
+		err  string
+	}{
+		{
+			name: "no auth method",
+			rule: structs.ACLBindingRule{BindType: "service", BindName: "web"},
+			err:  "no AuthMethod is set",
+		},
+		{
+			name: "unknown auth method",
+			rule: structs.ACLBindingRule{AuthMethod: "missing", BindType: "service", BindName: "web"},
+			err:  "no such auth method",
+		},
+		{
+			name: "unknown bind type",
+			rule: structs.ACLBindingRule{AuthMethod: method.Name, BindType: "role", BindName: "web"},
+			err:  "unknown BindType",
+		},
+		{
+			name: "no bind name",
+			rule: structs.ACLBindingRule{AuthMethod: method.Name, BindType: "service"},
+			err:  "no BindName is set",
+		},
+		{
+			name: "unknown selector field",
+			rule: structs.ACLBindingRule{AuthMethod: method.Name, Selector: "pod == web", BindType: "service", BindName: "web"},
+			err:  "invalid Selector",
+		},
+		{
+			name: "unknown bind name field",
+			rule: structs.ACLBindingRule{AuthMethod: method.Name, BindType: "service", BindName: "${pod}"},
+			err:  "invalid BindName",
+		},
+		{
+			name: "invalid bind name",
+			rule: structs.ACLBindingRule{AuthMethod: method.Name, BindType: "service", BindName: "Web_${serviceaccount.name}"},
+			err:  "invalid BindName",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := upsert(tc.rule)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	t.Run("create and update", func(t *testing.T) {
+		rule, err := upsert(structs.ACLBindingRule{
+			AuthMethod: method.Name,
+			Selector:   "serviceaccount.namespace == default",
+			BindType:   structs.BindingRuleBindTypeService,
+			BindName:   "${serviceaccount.name}",
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, rule.ID)
+		require.Equal(t, method.Name, rule.AuthMethod)
+
+		// The auth method may be omitted on update.
+		updated, err := upsert(structs.ACLBindingRule{
+			ID:          rule.ID,
+			Description: "updated",
+			BindType:    structs.BindingRuleBindTypeService,
+			BindName:    "${serviceaccount.name}",
+		})
+		require.NoError(t, err)
+		require.Equal(t, method.Name, updated.AuthMethod)
+		require.Equal(t, "updated", updated.Description)
+
+		_, err = upsert(structs.ACLBindingRule{
+			ID:         rule.ID,
+			AuthMethod: "other",
+			BindType:   structs.BindingRuleBindTypeService,
+			BindName:   "web",
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "immutable")
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := upsert(structs.ACLBindingRule{
+			ID:         "9cd5ea1d-ae3b-4dd4-a5c4-5f1c0c0b2a71",
+			AuthMethod: method.Name,
+			BindType:   structs.BindingRuleBindTypeService,
+			BindName:   "web",
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot find binding rule")
+	})
+}
+
+func TestACLEndpoint_Login_BindingRules(t *testing.T) {
+	t.Parallel()
+
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+	testauth.InstallSessionToken(testSessionID, "fake-web", "default", "web", "abc123")
+	testauth.InstallSessionToken(testSessionID, "fake-db", "prod", "db", "def456")
+
+	method, err := upsertTestAuthMethod(codec, "root", "dc1", testSessionID)
+	require.NoError(t, err)
+
+	policy, err := upsertTestPolicy(codec, "root", "dc1")
+	require.NoError(t, err)
+
+	aclEp := ACL{srv: s1}
+
+	for _, rule := range []structs.ACLBindingRule{
+		{
+			AuthMethod: method.Name,
+			Selector:   "serviceaccount.namespace == default",
+			BindType:   structs.BindingRuleBindTypeService,
+			BindName:   "${serviceaccount.name}",
+		},
+		{
+			AuthMethod: method.Name,
+			Selector:   "serviceaccount.namespace == prod",
+			BindType:   structs.BindingRuleBindTypeNode,
+			BindName:   "${serviceaccount.name}-node",
+		},
+		{
+			AuthMethod: method.Name,
+			Selector:   "serviceaccount.namespace == prod",
+			BindType:   structs.BindingRuleBindTypePolicy,
+			BindName:   policy.Name,
+		},
+	} {
+		req := structs.ACLBindingRuleUpsertRequest{
+			Datacenter:   "dc1",
+			BindingRule:  rule,
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var resp structs.ACLBindingRule
+		require.NoError(t, aclEp.BindingRuleUpsert(&req, &resp))
+	}
+
+	login := func(bearerToken string) (*structs.ACLToken, error) {
+		req := structs.ACLLoginRequest{
+			Auth: &structs.ACLLoginParams{
+				AuthMethod:  method.Name,
+				BearerToken: bearerToken,
+			},
+			Datacenter: "dc1",
+		}
+		var resp structs.ACLToken
+		if err := aclEp.Login(&req, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	t.Run("service identity", func(t *testing.T) {
+		token, err := login("fake-web")
+		require.NoError(t, err)
+		require.Equal(t, []*structs.ACLServiceIdentity{{ServiceName: "web"}}, token.ServiceIdentities)
+		require.Empty(t, token.NodeIdentities)
+		require.Empty(t, token.Policies)
+	})
+
+	t.Run("node identity and policy", func(t *testing.T) {
+		token, err := login("fake-db")
+		require.NoError(t, err)
+		require.Empty(t, token.ServiceIdentities)
+		require.Equal(t, []*structs.ACLNodeIdentity{{NodeName: "db-node", Datacenter: "dc1"}}, token.NodeIdentities)
+		require.Equal(t, []structs.ACLTokenPolicyLink{{ID: policy.ID, Name: policy.Name}}, token.Policies)
+	})
+
+	t.Run("no matching rules", func(t *testing.T) {
+		testauth.InstallSessionToken(testSessionID, "fake-api", "staging", "api", "ghi789")
+		_, err := login("fake-api")
+		require.True(t, acl.IsErrPermissionDenied(err), "bad: %v", err)
+	})
+}
+
 func upsertTestToken(codec rpc.ClientCodec, masterToken string, datacenter string) (*structs.ACLToken, error) {
 	arg := structs.ACLTokenUpsertRequest{
 		Datacenter: datacenter,
@@ -1536,3 +2698,35 @@ func retrieveTestPolicy(codec rpc.ClientCodec, masterToken string, datacenter st
 
 	return &out, nil
 }
+
+// upsertTestAuthMethod creates an auth method of the testing type for testing
+// purposes
+func upsertTestAuthMethod(codec rpc.ClientCodec, masterToken string, datacenter string, sessionID string) (*structs.ACLAuthMethod, error) {
+	// Make sure test auth methods can't collide
+	methodUnq, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	arg := structs.ACLAuthMethodUpsertRequest{
+		Datacenter: datacenter,
+		AuthMethod: structs.ACLAuthMethod{
+			Name: "test-method-" + methodUnq,
+			Type: testauth.Type,
+			Config: map[string]interface{}{
+				"SessionID": sessionID,
+			},
+		},
+		WriteRequest: structs.WriteRequest{Token: masterToken},
+	}
+
+	var out structs.ACLAuthMethod
+
+	err = msgpackrpc.CallWithCodec(codec, "ACL.AuthMethodUpsert", &arg, &out)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -55,6 +55,17 @@ func (s *Server) checkPolicyUUID(id string) (bool, error) {
 	return !structs.ACLIDReserved(id), nil
 }
 
+func (s *Server) checkBindingRuleUUID(id string) (bool, error) {
+	state := s.fsm.State()
+	if _, rule, err := state.ACLBindingRuleGetByID(nil, id); err != nil {
+		return false, err
+	} else if rule != nil {
+		return false, nil
+	}
+
+	return !structs.ACLIDReserved(id), nil
+}
+
 func (s *Server) updateACLAdvertisement() {
 	// One thing to note is that once in new ACL mode the server will
 	// never transition to legacy ACL mode. This is not currently a
@@ -170,10 +181,31 @@ func (s *Server) ResolveToken(token string) (acl.Authorizer, error) {
 	return s.acls.ResolveToken(token)
 }
 
+// TokenAccessorID returns the accessor ID of the given token, or an empty
+// string if it isn't known.
+func (s *Server) TokenAccessorID(token string) string {
+	return s.acls.TokenAccessorID(token)
+}
+
+// ResolveRequestToken resolves the token of the request. If the request was
+// forwarded from another datacenter, the policies which don't apply there are
+// ignored.
+func (s *Server) ResolveRequestToken(info structs.RPCInfo) (acl.Authorizer, error) {
+	return s.acls.ResolveTokenFromDatacenter(info.TokenSecret(), info.RequestSourceDatacenter())
+}
+
 func (s *Server) filterACL(token string, subj interface{}) error {
 	return s.acls.filterACL(token, subj)
 }
 
+func (s *Server) filterACLFromDatacenter(token, sourceDC string, subj interface{}) error {
+	return s.acls.filterACLFromDatacenter(token, sourceDC, subj)
+}
+
+func (s *Server) filterRequestACL(info structs.RPCInfo, subj interface{}) error {
+	return s.acls.filterACLFromDatacenter(info.TokenSecret(), info.RequestSourceDatacenter(), subj)
+}
+
 func (s *Server) filterACLWithAuthorizer(authorizer acl.Authorizer, subj interface{}) error {
 	return s.acls.filterACLWithAuthorizer(authorizer, subj)
 }
@@ -94,6 +94,33 @@ func testIdentityForToken(token string) (bool, structs.ACLIdentity, error) {
 				},
 			},
 		}, nil
+	case "node-identity":
+		return true, &structs.ACLToken{
+			AccessorID: "3ae7b0b9-5b8d-4a45-8cc4-36a0f3e1a1cb",
+			SecretID:   "0e7e5a8e-0a69-4b8a-9e9c-c2c5e3f1b7a1",
+			Policies: []structs.ACLTokenPolicyLink{
+				structs.ACLTokenPolicyLink{
+					ID: "dc2-key-wr",
+				},
+			},
+			NodeIdentities: []*structs.ACLNodeIdentity{
+				&structs.ACLNodeIdentity{
+					NodeName:   "web-1",
+					Datacenter: "dc1",
+				},
+			},
+		}, nil
+	case "service-identity":
+		return true, &structs.ACLToken{
+			AccessorID: "7b8e2a4c-3f2d-4b7e-9a1c-5d6e7f8a9b0c",
+			SecretID:   "c4d5e6f7-8a9b-4c0d-9e1f-2a3b4c5d6e7f",
+			ServiceIdentities: []*structs.ACLServiceIdentity{
+				&structs.ACLServiceIdentity{
+					ServiceName: "web",
+					Datacenters: []string{"dc1"},
+				},
+			},
+		}, nil
 	case anonymousToken:
 		return true, &structs.ACLToken{
 			AccessorID: "00000000-0000-0000-0000-000000000002",
@@ -655,6 +682,121 @@ func TestACLResolver_DatacenterScoping(t *testing.T) {
 		require.False(t, authz.NodeWrite("foo", nil))
 		require.True(t, authz.KeyWrite("foo", nil))
 	})
+
+	t.Run("forwarded from dc2", func(t *testing.T) {
+		delegate := &ACLResolverTestDelegate{
+			enabled:       true,
+			datacenter:    "dc1",
+			legacy:        false,
+			localTokens:   true,
+			localPolicies: true,
+			// No need to provide any of the RPC callbacks
+		}
+		r := newTestACLResolver(t, delegate, nil)
+
+		// Neither the dc1 nor the dc2 policy apply to both datacenters.
+		authz, err := r.ResolveTokenFromDatacenter("found", "dc2")
+		require.NotNil(t, authz)
+		require.NoError(t, err)
+		require.False(t, authz.NodeWrite("foo", nil))
+		require.False(t, authz.KeyWrite("foo", nil))
+
+		names, err := r.filteredPolicyNames("found", "dc2")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"node-wr", "dc2-key-wr"}, names)
+
+		names, err = r.filteredPolicyNames("found", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"dc2-key-wr"}, names)
+	})
+}
+
+func TestACLResolver_NodeIdentities(t *testing.T) {
+	t.Parallel()
+	t.Run("dc1", func(t *testing.T) {
+		delegate := &ACLResolverTestDelegate{
+			enabled:       true,
+			datacenter:    "dc1",
+			legacy:        false,
+			localTokens:   true,
+			localPolicies: true,
+			// No need to provide any of the RPC callbacks
+		}
+		r := newTestACLResolver(t, delegate, nil)
+
+		authz, err := r.ResolveToken("node-identity")
+		require.NotNil(t, authz)
+		require.NoError(t, err)
+		require.True(t, authz.NodeWrite("web-1", nil))
+		require.False(t, authz.NodeWrite("web-2", nil))
+		require.True(t, authz.ServiceRead("foo"))
+		require.False(t, authz.ServiceWrite("foo", nil))
+		require.False(t, authz.KeyWrite("foo", nil))
+	})
+
+	t.Run("dc2", func(t *testing.T) {
+		delegate := &ACLResolverTestDelegate{
+			enabled:       true,
+			datacenter:    "dc2",
+			legacy:        false,
+			localTokens:   true,
+			localPolicies: true,
+			// No need to provide any of the RPC callbacks
+		}
+		r := newTestACLResolver(t, delegate, func(config *ACLResolverConfig) {
+			config.Config.Datacenter = "dc2"
+		})
+
+		authz, err := r.ResolveToken("node-identity")
+		require.NotNil(t, authz)
+		require.NoError(t, err)
+		require.False(t, authz.NodeWrite("web-1", nil))
+		require.True(t, authz.KeyWrite("foo", nil))
+	})
+}
+
+func TestACLResolver_ServiceIdentities(t *testing.T) {
+	t.Parallel()
+	t.Run("dc1", func(t *testing.T) {
+		delegate := &ACLResolverTestDelegate{
+			enabled:       true,
+			datacenter:    "dc1",
+			legacy:        false,
+			localTokens:   true,
+			localPolicies: true,
+			// No need to provide any of the RPC callbacks
+		}
+		r := newTestACLResolver(t, delegate, nil)
+
+		authz, err := r.ResolveToken("service-identity")
+		require.NotNil(t, authz)
+		require.NoError(t, err)
+		require.True(t, authz.ServiceWrite("web", nil))
+		require.True(t, authz.ServiceWrite("web-sidecar-proxy", nil))
+		require.False(t, authz.ServiceWrite("api", nil))
+		require.True(t, authz.ServiceRead("api"))
+		require.True(t, authz.NodeRead("web-1"))
+		require.False(t, authz.NodeWrite("web-1", nil))
+	})
+
+	t.Run("dc2", func(t *testing.T) {
+		delegate := &ACLResolverTestDelegate{
+			enabled:       true,
+			datacenter:    "dc2",
+			legacy:        false,
+			localTokens:   true,
+			localPolicies: true,
+			// No need to provide any of the RPC callbacks
+		}
+		r := newTestACLResolver(t, delegate, func(config *ACLResolverConfig) {
+			config.Config.Datacenter = "dc2"
+		})
+
+		authz, err := r.ResolveToken("service-identity")
+		require.NotNil(t, authz)
+		require.NoError(t, err)
+		require.False(t, authz.ServiceWrite("web", nil))
+	})
 }
 
 func TestACLResolver_LocalTokensAndPolicies(t *testing.T) {
@@ -2460,3 +2602,32 @@ service "service" {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestScaleACLCacheConfig(t *testing.T) {
+	t.Parallel()
+	config := &structs.ACLCachesConfig{
+		Identities:     1024,
+		Policies:       0,
+		ParsedPolicies: 128,
+		Authorizers:    4,
+	}
+
+	// No or a large memory limit keeps the full size.
+	require.Equal(t, config, scaleACLCacheConfig(config, 0))
+	require.Equal(t, config, scaleACLCacheConfig(config, 2<<30))
+
+	require.Equal(t, &structs.ACLCachesConfig{
+		Identities:     512,
+		Policies:       0,
+		ParsedPolicies: 64,
+		Authorizers:    2,
+	}, scaleACLCacheConfig(config, 512<<20))
+
+	// The caches don't shrink below an eighth and keep at least one entry.
+	require.Equal(t, &structs.ACLCachesConfig{
+		Identities:     128,
+		Policies:       0,
+		ParsedPolicies: 16,
+		Authorizers:    1,
+	}, scaleACLCacheConfig(config, 16<<20))
+}
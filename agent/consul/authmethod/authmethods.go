@@ -0,0 +1,121 @@
+// Package authmethod holds the validators of the ACL auth methods, which
+// check the credentials presented in a login and extract the identity of
+// the workload from them.
+package authmethod
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Validator checks the credentials presented to an auth method.
+type Validator interface {
+	// Name returns the name of the auth method backing this validator.
+	Name() string
+
+	// ValidateLogin checks the credential presented in a login and returns
+	// the fields describing the identity it belongs to.
+	ValidateLogin(loginToken string) (map[string]string, error)
+
+	// AvailableFields returns the names of the fields returned by
+	// ValidateLogin.
+	AvailableFields() []string
+}
+
+// ValidatorFactory creates the validator of an auth method. It returns an
+// error if the configuration of the auth method is invalid.
+type ValidatorFactory func(method *structs.ACLAuthMethod) (Validator, error)
+
+var (
+	typesLock sync.RWMutex
+	types     = make(map[string]ValidatorFactory)
+)
+
+// Register makes an auth method type available. It panics if the type is
+// registered twice.
+func Register(name string, factory ValidatorFactory) {
+	typesLock.Lock()
+	defer typesLock.Unlock()
+
+	if factory == nil {
+		panic("authmethod: Register factory is nil for type " + name)
+	}
+	if _, dup := types[name]; dup {
+		panic("authmethod: Register called twice for type " + name)
+	}
+	types[name] = factory
+}
+
+// IsRegisteredType returns true if the auth method type is available.
+func IsRegisteredType(typeName string) bool {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+
+	_, ok := types[typeName]
+	return ok
+}
+
+// Types returns the sorted names of the available auth method types.
+func Types() []string {
+	typesLock.RLock()
+	defer typesLock.RUnlock()
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewValidator returns the validator of the auth method, which also checks
+// its configuration.
+func NewValidator(method *structs.ACLAuthMethod) (Validator, error) {
+	typesLock.RLock()
+	factory, ok := types[method.Type]
+	typesLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no auth method registered with type: %s", method.Type)
+	}
+	return factory(method)
+}
+
+// ParseConfig decodes the configuration of an auth method into out, which
+// must be a pointer to a struct. Unknown keys are an error and durations may
+// be given as strings like "30s".
+func ParseConfig(rawConfig map[string]interface{}, out interface{}) error {
+	decodeConf := &mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			bytesToStringHookFunc,
+			mapstructure.StringToTimeDurationHookFunc(),
+		),
+		Result:           out,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+	}
+
+	decoder, err := mapstructure.NewDecoder(decodeConf)
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(rawConfig); err != nil {
+		return fmt.Errorf("error decoding config: %s", err)
+	}
+	return nil
+}
+
+// bytesToStringHookFunc converts []uint8 values to strings, which is how
+// strings come back once the config went through msgpack.
+func bytesToStringHookFunc(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+	if b, ok := data.([]uint8); ok && t.Kind() != reflect.Slice {
+		return string(b), nil
+	}
+	return data, nil
+}
@@ -0,0 +1,387 @@
+// Package jwtauth implements the "jwt" auth method, which logs in workloads
+// with a JWT issued by an OIDC identity provider, such as the identity tokens
+// of CI systems.
+package jwtauth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/hashicorp/consul/agent/consul/authmethod"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// Type is the auth method type of this package.
+const Type = "jwt"
+
+// fieldPrefix prefixes the names of the fields of the mapped claims.
+const fieldPrefix = "value."
+
+func init() {
+	authmethod.Register(Type, func(method *structs.ACLAuthMethod) (authmethod.Validator, error) {
+		v, err := NewValidator(method)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// Config is the configuration of a jwt auth method. Exactly one of JWKSURL,
+// OIDCDiscoveryURL and JWTValidationPubKeys must be set.
+type Config struct {
+	// JWKSURL is the URL of the JSON Web Key Set with which the tokens are
+	// verified.
+	JWKSURL string
+
+	// OIDCDiscoveryURL is the issuer URL of an OIDC identity provider, from
+	// whose discovery document the JWKS URL is taken.
+	OIDCDiscoveryURL string
+
+	// JWKSCACert is the PEM encoded CA certificate used to fetch the JWKS
+	// and the discovery document. The system roots are used if empty.
+	JWKSCACert string
+
+	// JWTValidationPubKeys are PEM encoded public keys with which the tokens
+	// are verified, for identity providers without a JWKS.
+	JWTValidationPubKeys []string
+
+	// BoundIssuer is the required "iss" claim. It defaults to the issuer of
+	// the discovery document. At least one of BoundIssuer and BoundAudiences
+	// must be set.
+	BoundIssuer string
+
+	// BoundAudiences are accepted values of the "aud" claim, of which the
+	// token must have at least one. Tokens with an "aud" claim are rejected
+	// if it is empty.
+	BoundAudiences []string
+
+	// BoundClaims are claims which must have the given value, or one of the
+	// given values if a list is given, for a login to be accepted.
+	BoundClaims map[string]interface{}
+
+	// ClaimMappings maps the names of claims to the names of fields which
+	// are made available as "value.<name>".
+	ClaimMappings map[string]string
+
+	// ClockSkewLeeway is the clock skew allowed when checking the "exp",
+	// "nbf" and "iat" claims.
+	ClockSkewLeeway time.Duration
+}
+
+// Validator checks JWTs against the keys of the configured identity provider
+// and the bound claims.
+type Validator struct {
+	name   string
+	config *Config
+
+	fetcher    *keyFetcher
+	staticKeys []crypto.PublicKey
+	now        func() time.Time
+}
+
+var (
+	fetchersLock sync.Mutex
+	fetchers     = make(map[string]*cachedFetcher)
+)
+
+// cachedFetcher keeps the key set of an auth method across logins, as a new
+// validator is created for every login.
+type cachedFetcher struct {
+	source  string
+	fetcher *keyFetcher
+}
+
+// NewValidator returns the validator of a jwt auth method.
+func NewValidator(method *structs.ACLAuthMethod) (*Validator, error) {
+	if method.Type != Type {
+		return nil, fmt.Errorf("%q is not a %s auth method", method.Name, Type)
+	}
+
+	var config Config
+	if err := authmethod.ParseConfig(method.Config, &config); err != nil {
+		return nil, err
+	}
+
+	sources := 0
+	for _, set := range []bool{config.JWKSURL != "", config.OIDCDiscoveryURL != "", len(config.JWTValidationPubKeys) > 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.New("exactly one of Config.JWKSURL, Config.OIDCDiscoveryURL and Config.JWTValidationPubKeys must be set")
+	}
+	if config.JWKSCACert != "" && len(config.JWTValidationPubKeys) > 0 {
+		return nil, errors.New("Config.JWKSCACert cannot be used with Config.JWTValidationPubKeys")
+	}
+	if config.BoundIssuer == "" && len(config.BoundAudiences) == 0 {
+		return nil, errors.New("at least one of Config.BoundIssuer and Config.BoundAudiences must be set")
+	}
+	if config.ClockSkewLeeway < 0 {
+		return nil, errors.New("Config.ClockSkewLeeway cannot be negative")
+	}
+	for claim, field := range config.ClaimMappings {
+		if claim == "" || field == "" {
+			return nil, errors.New("Config.ClaimMappings cannot have empty claims or names")
+		}
+	}
+	for claim, value := range config.BoundClaims {
+		if _, err := boundValues(value); err != nil {
+			return nil, fmt.Errorf("Config.BoundClaims value of %q: %v", claim, err)
+		}
+	}
+
+	v := &Validator{
+		name:   method.Name,
+		config: &config,
+		now:    time.Now,
+	}
+
+	if len(config.JWTValidationPubKeys) > 0 {
+		for i, pem := range config.JWTValidationPubKeys {
+			key, err := parsePublicKeyPEM([]byte(pem))
+			if err != nil {
+				return nil, fmt.Errorf("Config.JWTValidationPubKeys[%d]: %v", i, err)
+			}
+			v.staticKeys = append(v.staticKeys, key)
+		}
+		return v, nil
+	}
+
+	// Reuse the fetcher of the auth method unless the source of its keys
+	// changed.
+	source := strings.Join([]string{config.JWKSURL, config.OIDCDiscoveryURL, config.JWKSCACert}, "\x00")
+	fetchersLock.Lock()
+	defer fetchersLock.Unlock()
+	if cached, ok := fetchers[method.Name]; ok && cached.source == source {
+		v.fetcher = cached.fetcher
+		return v, nil
+	}
+	fetcher, err := newKeyFetcher(&config)
+	if err != nil {
+		return nil, err
+	}
+	fetchers[method.Name] = &cachedFetcher{source: source, fetcher: fetcher}
+	v.fetcher = fetcher
+	return v, nil
+}
+
+func (v *Validator) Name() string {
+	return v.name
+}
+
+// ValidateLogin verifies the signature and the claims of the JWT and returns
+// the values of the mapped claims.
+func (v *Validator) ValidateLogin(loginToken string) (map[string]string, error) {
+	issuer := v.config.BoundIssuer
+
+	parser := &jwt.Parser{
+		ValidMethods: []string{
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+		},
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(loginToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if len(v.staticKeys) > 0 {
+			return v.verifyStatic(token, loginToken)
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		keys, discoveredIssuer, err := v.fetcher.Keys(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keys.keys[kid]; !ok && kid != "" {
+			// The identity provider may have rotated its keys.
+			if keys, discoveredIssuer, err = v.fetcher.Keys(true); err != nil {
+				return nil, err
+			}
+		}
+		if issuer == "" {
+			issuer = discoveredIssuer
+		}
+
+		if key, ok := keys.keys[kid]; ok {
+			return key, nil
+		}
+		if len(keys.static) == 1 {
+			return keys.static[0], nil
+		}
+		return nil, fmt.Errorf("no key with ID %q in the JWKS", kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT: %v", err)
+	}
+
+	if err := v.validateClaims(claims, issuer); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(v.config.ClaimMappings))
+	for claim, field := range v.config.ClaimMappings {
+		raw, ok := claims[claim]
+		if !ok {
+			continue
+		}
+		value, ok := stringifyClaim(raw)
+		if !ok {
+			return nil, fmt.Errorf("claim %q cannot be mapped, it is not a string, number or boolean", claim)
+		}
+		fields[fieldPrefix+field] = value
+	}
+	return fields, nil
+}
+
+// verifyStatic returns the static key which verifies the signature of the
+// token, if any. The signature isn't set on the token before its key is
+// returned, so it is taken from the raw token.
+func (v *Validator) verifyStatic(token *jwt.Token, raw string) (interface{}, error) {
+	i := strings.LastIndex(raw, ".")
+	if i < 0 {
+		return nil, errors.New("token is malformed")
+	}
+	signingString, signature := raw[:i], raw[i+1:]
+	for _, key := range v.staticKeys {
+		if token.Method.Verify(signingString, signature, key) == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no validation key matches the signature")
+}
+
+func (v *Validator) validateClaims(claims jwt.MapClaims, issuer string) error {
+	now := v.now()
+	leeway := v.config.ClockSkewLeeway
+
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return errors.New("JWT has no expiration time")
+	}
+	if now.After(exp.Add(leeway)) {
+		return errors.New("JWT is expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Before(nbf.Add(-leeway)) {
+		return errors.New("JWT is not valid yet")
+	}
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Before(iat.Add(-leeway)) {
+		return errors.New("JWT was issued in the future")
+	}
+
+	if issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != issuer {
+			return fmt.Errorf("JWT issuer %q is not the bound issuer", iss)
+		}
+	}
+
+	// A token meant for another audience must not be accepted just because
+	// no audiences are bound.
+	if len(v.config.BoundAudiences) > 0 {
+		audiences, _ := boundValues(claims["aud"])
+		if !matchesAny(audiences, v.config.BoundAudiences) {
+			return errors.New("JWT audience doesn't match any of the bound audiences")
+		}
+	} else if _, ok := claims["aud"]; ok {
+		return errors.New("JWT has an audience, but no audiences are bound")
+	}
+
+	for claim, bound := range v.config.BoundClaims {
+		expected, _ := boundValues(bound)
+		actual, err := boundValues(claims[claim])
+		if err != nil || !matchesAny(actual, expected) {
+			return fmt.Errorf("claim %q doesn't match the bound claims", claim)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) AvailableFields() []string {
+	fields := make([]string, 0, len(v.config.ClaimMappings))
+	for _, field := range v.config.ClaimMappings {
+		fields = append(fields, fieldPrefix+field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// numericClaim returns the time of a NumericDate claim like "exp".
+func numericClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// boundValues returns the strings of a claim or bound claim value, which is
+// either a string or a list of strings.
+func boundValues(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []uint8:
+		return []string{string(v)}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			switch s := item.(type) {
+			case string:
+				values = append(values, s)
+			case []uint8:
+				values = append(values, string(s))
+			default:
+				return nil, errors.New("list must only contain strings")
+			}
+		}
+		return values, nil
+	default:
+		return nil, errors.New("must be a string or a list of strings")
+	}
+}
+
+func matchesAny(actual, expected []string) bool {
+	for _, a := range actual {
+		for _, e := range expected {
+			if a == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stringifyClaim(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case bool:
+		return fmt.Sprintf("%t", v), true
+	case float64:
+		return fmt.Sprintf("%v", v), true
+	default:
+		return "", false
+	}
+}
+
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not a PEM encoded RSA or ECDSA public key")
+}
@@ -0,0 +1,483 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdentityProvider is a fake OIDC identity provider which serves a
+// discovery document and a JWKS with the public keys of its signing keys.
+type testIdentityProvider struct {
+	srv *httptest.Server
+
+	lock sync.Mutex
+	keys map[string]interface{} // kid => private key
+}
+
+func startTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	idp := &testIdentityProvider{keys: make(map[string]interface{})}
+	idp.srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":   idp.srv.URL,
+				"jwks_uri": idp.srv.URL + "/keys",
+			})
+		case "/keys":
+			idp.lock.Lock()
+			defer idp.lock.Unlock()
+			var keys []map[string]string
+			for kid, key := range idp.keys {
+				keys = append(keys, testJWK(t, kid, key))
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return idp
+}
+
+func (idp *testIdentityProvider) addKey(kid string, key interface{}) {
+	idp.lock.Lock()
+	defer idp.lock.Unlock()
+	idp.keys[kid] = key
+}
+
+func (idp *testIdentityProvider) caCert() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: idp.srv.Certificate().Raw,
+	}))
+}
+
+func testJWK(t *testing.T, kid string, key interface{}) map[string]string {
+	enc := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   enc(k.N),
+			"e":   enc(big.NewInt(int64(k.E))),
+		}
+	case *ecdsa.PrivateKey:
+		return map[string]string{
+			"kty": "EC",
+			"kid": kid,
+			"crv": k.Curve.Params().Name,
+			"x":   enc(k.X),
+			"y":   enc(k.Y),
+		}
+	default:
+		t.Fatalf("unsupported key type %T", key)
+		return nil
+	}
+}
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func testECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func testPublicKeyPEM(t *testing.T, pub interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func testSign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func testMethod(name string, config map[string]interface{}) *structs.ACLAuthMethod {
+	return &structs.ACLAuthMethod{
+		Name:   name,
+		Type:   Type,
+		Config: config,
+	}
+}
+
+func TestValidator_NewValidator(t *testing.T) {
+	t.Parallel()
+
+	pubKey := testPublicKeyPEM(t, &testRSAKey(t).PublicKey)
+
+	cases := map[string]struct {
+		method *structs.ACLAuthMethod
+		err    string
+	}{
+		"jwks url": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":     "https://idp.example.com/keys",
+				"BoundIssuer": "https://idp.example.com",
+			}),
+		},
+		"discovery url": {
+			method: testMethod("jwt", map[string]interface{}{
+				"OIDCDiscoveryURL": "https://idp.example.com",
+				"BoundAudiences":   []interface{}{"consul"},
+				"BoundClaims": map[string]interface{}{
+					"repository": "example/web",
+					"ref":        []interface{}{"refs/heads/master", []uint8("refs/heads/release")},
+				},
+				"ClaimMappings":   map[string]interface{}{"repository": "repo"},
+				"ClockSkewLeeway": "30s",
+			}),
+		},
+		"decoded from msgpack": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":        []uint8("https://idp.example.com/keys"),
+				"BoundAudiences": []interface{}{[]uint8("consul")},
+				"BoundClaims": map[interface{}]interface{}{
+					"ref": []interface{}{[]uint8("refs/heads/master")},
+				},
+				"ClaimMappings":   map[interface{}]interface{}{"repository": []uint8("repo")},
+				"ClockSkewLeeway": []uint8("30s"),
+			}),
+		},
+		"static keys": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWTValidationPubKeys": []interface{}{pubKey},
+				"BoundAudiences":       []interface{}{"consul"},
+			}),
+		},
+		"wrong type": {
+			method: &structs.ACLAuthMethod{Name: "jwt", Type: "other"},
+			err:    "is not a jwt auth method",
+		},
+		"no key source": {
+			method: testMethod("jwt", map[string]interface{}{
+				"BoundIssuer": "https://idp.example.com",
+			}),
+			err: "exactly one of",
+		},
+		"two key sources": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":          "https://idp.example.com/keys",
+				"OIDCDiscoveryURL": "https://idp.example.com",
+			}),
+			err: "exactly one of",
+		},
+		"nothing bound": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":     "https://idp.example.com/keys",
+				"BoundClaims": map[string]interface{}{"project": "web"},
+			}),
+			err: "at least one of Config.BoundIssuer and Config.BoundAudiences must be set",
+		},
+		"invalid ca cert": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":     "https://idp.example.com/keys",
+				"JWKSCACert":  "not a cert",
+				"BoundIssuer": "https://idp.example.com",
+			}),
+			err: "Config.JWKSCACert is not a valid PEM encoded certificate",
+		},
+		"invalid static key": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWTValidationPubKeys": []interface{}{"not a key"},
+				"BoundIssuer":          "https://idp.example.com",
+			}),
+			err: "Config.JWTValidationPubKeys[0]",
+		},
+		"negative leeway": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":         "https://idp.example.com/keys",
+				"BoundIssuer":     "https://idp.example.com",
+				"ClockSkewLeeway": "-1s",
+			}),
+			err: "Config.ClockSkewLeeway cannot be negative",
+		},
+		"invalid bound claim": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL":     "https://idp.example.com/keys",
+				"BoundIssuer": "https://idp.example.com",
+				"BoundClaims": map[string]interface{}{"admin": true},
+			}),
+			err: `Config.BoundClaims value of "admin"`,
+		},
+		"unknown key": {
+			method: testMethod("jwt", map[string]interface{}{
+				"JWKSURL": "https://idp.example.com/keys",
+				"Bogus":   "yes",
+			}),
+			err: "Bogus",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			v, err := NewValidator(tc.method)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "jwt", v.Name())
+		})
+	}
+}
+
+func TestValidator_ValidateLogin(t *testing.T) {
+	t.Parallel()
+
+	idp := startTestIdentityProvider(t)
+	defer idp.srv.Close()
+
+	rsaKey, ecKey := testRSAKey(t), testECKey(t)
+	idp.addKey("rsa", rsaKey)
+	idp.addKey("ec", ecKey)
+
+	v, err := NewValidator(testMethod("ci-discovery", map[string]interface{}{
+		"OIDCDiscoveryURL": idp.srv.URL,
+		"JWKSCACert":       idp.caCert(),
+		"BoundAudiences":   []interface{}{"consul"},
+		"BoundClaims": map[string]interface{}{
+			"ref": []interface{}{"refs/heads/master", "refs/heads/release"},
+		},
+		"ClaimMappings": map[string]interface{}{
+			"repository": "repo",
+			"run_number": "run",
+		},
+		"ClockSkewLeeway": "1m",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"value.repo", "value.run"}, v.AvailableFields())
+
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":        idp.srv.URL,
+			"aud":        []string{"consul", "other"},
+			"sub":        "repo:example/web",
+			"exp":        now.Add(5 * time.Minute).Unix(),
+			"iat":        now.Unix(),
+			"ref":        "refs/heads/master",
+			"repository": "example/web",
+			"run_number": 42,
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	t.Run("rsa key", func(t *testing.T) {
+		fields, err := v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)))
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"value.repo": "example/web",
+			"value.run":  "42",
+		}, fields)
+	})
+
+	t.Run("ec key", func(t *testing.T) {
+		_, err := v.ValidateLogin(testSign(t, jwt.SigningMethodES256, "ec", ecKey, claims(nil)))
+		require.NoError(t, err)
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		newKey := testRSAKey(t)
+		idp.addKey("rotated", newKey)
+
+		// The key set is only fetched again once it is old enough.
+		v.fetcher.lock.Lock()
+		v.fetcher.fetchedAt = time.Now().Add(-keySetMinRefresh)
+		v.fetcher.lock.Unlock()
+
+		_, err := v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rotated", newKey, claims(nil)))
+		require.NoError(t, err)
+	})
+
+	cases := map[string]struct {
+		token string
+		err   string
+	}{
+		"unknown key": {
+			token: testSign(t, jwt.SigningMethodRS256, "unknown", testRSAKey(t), claims(nil)),
+			err:   `no key with ID "unknown"`,
+		},
+		"wrong signature": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", testRSAKey(t), claims(nil)),
+			err:   "invalid JWT",
+		},
+		"hmac": {
+			token: testSign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), claims(nil)),
+			err:   "invalid JWT",
+		},
+		"expired": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"exp": now.Add(-2 * time.Minute).Unix(),
+			})),
+			err: "JWT is expired",
+		},
+		"no expiration": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"exp": nil,
+			})),
+			err: "JWT has no expiration time",
+		},
+		"not valid yet": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"nbf": now.Add(2 * time.Minute).Unix(),
+			})),
+			err: "JWT is not valid yet",
+		},
+		"wrong issuer": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"iss": "https://evil.example.com",
+			})),
+			err: "is not the bound issuer",
+		},
+		"wrong audience": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"aud": "other",
+			})),
+			err: "JWT audience doesn't match",
+		},
+		"wrong bound claim": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"ref": "refs/heads/feature",
+			})),
+			err: `claim "ref" doesn't match the bound claims`,
+		},
+		"missing bound claim": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"ref": nil,
+			})),
+			err: `claim "ref" doesn't match the bound claims`,
+		},
+		"unmappable claim": {
+			token: testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+				"repository": []string{"a", "b"},
+			})),
+			err: `claim "repository" cannot be mapped`,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, err := v.ValidateLogin(tc.token)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	t.Run("leeway", func(t *testing.T) {
+		_, err := v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{
+			"exp": now.Add(-30 * time.Second).Unix(),
+		})))
+		require.NoError(t, err)
+	})
+}
+
+func TestValidator_ValidateLogin_JWKSURL(t *testing.T) {
+	t.Parallel()
+
+	idp := startTestIdentityProvider(t)
+	defer idp.srv.Close()
+
+	key := testRSAKey(t)
+	idp.addKey("rsa", key)
+
+	v, err := NewValidator(testMethod("ci-jwks", map[string]interface{}{
+		"JWKSURL":     idp.srv.URL + "/keys",
+		"JWKSCACert":  idp.caCert(),
+		"BoundIssuer": "https://ci.example.com",
+	}))
+	require.NoError(t, err)
+
+	exp := time.Now().Add(5 * time.Minute).Unix()
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rsa", key, jwt.MapClaims{
+		"iss": "https://ci.example.com",
+		"exp": exp,
+	}))
+	require.NoError(t, err)
+
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rsa", key, jwt.MapClaims{
+		"iss": idp.srv.URL,
+		"exp": exp,
+	}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not the bound issuer")
+
+	// Without bound audiences, tokens meant for another audience are
+	// rejected.
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "rsa", key, jwt.MapClaims{
+		"iss": "https://ci.example.com",
+		"aud": "other",
+		"exp": exp,
+	}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no audiences are bound")
+}
+
+func TestValidator_ValidateLogin_StaticKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, ecKey := testRSAKey(t), testECKey(t)
+
+	v, err := NewValidator(testMethod("ci-static", map[string]interface{}{
+		"JWTValidationPubKeys": []interface{}{
+			testPublicKeyPEM(t, &rsaKey.PublicKey),
+			testPublicKeyPEM(t, &ecKey.PublicKey),
+		},
+		"BoundAudiences": []interface{}{"consul"},
+		"BoundClaims":    map[string]interface{}{"project": "web"},
+	}))
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"aud":     "consul",
+		"exp":     time.Now().Add(5 * time.Minute).Unix(),
+		"project": "web",
+	}
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "", rsaKey, claims))
+	require.NoError(t, err)
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodES256, "", ecKey, claims))
+	require.NoError(t, err)
+
+	_, err = v.ValidateLogin(testSign(t, jwt.SigningMethodRS256, "", testRSAKey(t), claims))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no validation key matches the signature")
+}
@@ -0,0 +1,250 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keySetTTL is how long a fetched key set is used before it is fetched
+	// again.
+	keySetTTL = 5 * time.Minute
+
+	// keySetMinRefresh is how long a key set is used at least, even if a
+	// token was signed with an unknown key, so that tokens with made up key
+	// IDs can't make the servers hammer the identity provider.
+	keySetMinRefresh = 10 * time.Second
+
+	// fetchTimeout bounds the requests to the identity provider.
+	fetchTimeout = 10 * time.Second
+
+	// maxResponseSize bounds the size of the documents which are fetched.
+	maxResponseSize = 1 << 20
+)
+
+// keySet holds the public keys with which tokens are verified, by key ID.
+// Static keys have no ID and are tried in turn.
+type keySet struct {
+	keys   map[string]crypto.PublicKey
+	static []crypto.PublicKey
+}
+
+// keyFetcher fetches the key set of an identity provider, either directly
+// from its JWKS URL or from the jwks_uri of its OIDC discovery document,
+// and caches it.
+type keyFetcher struct {
+	jwksURL      string
+	discoveryURL string
+	client       *http.Client
+
+	lock      sync.Mutex
+	keys      *keySet
+	issuer    string
+	fetchedAt time.Time
+}
+
+func newKeyFetcher(config *Config) (*keyFetcher, error) {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: fetchTimeout,
+	}
+	if config.JWKSCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.JWKSCACert)) {
+			return nil, errors.New("Config.JWKSCACert is not a valid PEM encoded certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &keyFetcher{
+		jwksURL:      config.JWKSURL,
+		discoveryURL: strings.TrimSuffix(config.OIDCDiscoveryURL, "/"),
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   fetchTimeout,
+		},
+	}, nil
+}
+
+// Keys returns the key set of the identity provider. It is fetched again
+// once it is older than keySetTTL, or if refresh is set because a token was
+// signed with a key which isn't in it.
+func (f *keyFetcher) Keys(refresh bool) (*keySet, string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	age := time.Since(f.fetchedAt)
+	if f.keys != nil && age < keySetTTL && (!refresh || age < keySetMinRefresh) {
+		return f.keys, f.issuer, nil
+	}
+
+	jwksURL, issuer := f.jwksURL, ""
+	if f.discoveryURL != "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := f.getJSON(f.discoveryURL+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, "", fmt.Errorf("failed to fetch the OIDC discovery document: %v", err)
+		}
+		if strings.TrimSuffix(discovery.Issuer, "/") != f.discoveryURL {
+			return nil, "", fmt.Errorf("issuer %q of the OIDC discovery document doesn't match the discovery URL", discovery.Issuer)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, "", errors.New("the OIDC discovery document has no jwks_uri")
+		}
+		jwksURL, issuer = discovery.JWKSURI, discovery.Issuer
+	}
+
+	var jwks jsonWebKeySet
+	if err := f.getJSON(jwksURL, &jwks); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch the JWKS: %v", err)
+	}
+	keys, err := jwks.keySet()
+	if err != nil {
+		return nil, "", err
+	}
+
+	f.keys, f.issuer, f.fetchedAt = keys, issuer, time.Now()
+	return keys, issuer, nil
+}
+
+func (f *keyFetcher) getJSON(url string, out interface{}) error {
+	resp, err := f.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response code %d from %s", resp.StatusCode, url)
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jsonWebKeySet is a JWK Set as defined by RFC 7517.
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC keys
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// keySet returns the signing keys of the set. Keys of unsupported types are
+// skipped.
+func (s *jsonWebKeySet) keySet() (*keySet, error) {
+	keys := &keySet{keys: make(map[string]crypto.PublicKey)}
+	for _, jwk := range s.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.KeyType {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in the JWKS: %v", jwk.KeyID, err)
+		}
+
+		if jwk.KeyID == "" {
+			keys.static = append(keys.static, key)
+		} else {
+			keys.keys[jwk.KeyID] = key
+		}
+	}
+
+	if len(keys.keys) == 0 && len(keys.static) == 0 {
+		return nil, errors.New("the JWKS has no signing keys")
+	}
+	return keys, nil
+}
+
+func (k *jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k *jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Curve {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+	}
+
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
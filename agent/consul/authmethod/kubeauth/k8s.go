@@ -0,0 +1,148 @@
+// Package kubeauth implements the "kubernetes" auth method, which logs in
+// workloads with the JWT of their Kubernetes service account.
+package kubeauth
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/agent/consul/authmethod"
+	"github.com/hashicorp/consul/agent/structs"
+	authv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/kubernetes"
+	client_authv1 "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	"k8s.io/client-go/rest"
+)
+
+// Type is the auth method type of this package.
+const Type = "kubernetes"
+
+const (
+	serviceAccountNamespaceField = "serviceaccount.namespace"
+	serviceAccountNameField      = "serviceaccount.name"
+	serviceAccountUIDField       = "serviceaccount.uid"
+)
+
+func init() {
+	authmethod.Register(Type, func(method *structs.ACLAuthMethod) (authmethod.Validator, error) {
+		v, err := NewValidator(method)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// Config is the configuration of a kubernetes auth method.
+type Config struct {
+	// Host is the address of the Kubernetes API server, like
+	// "https://kubernetes.example.com:8443".
+	Host string
+
+	// CACert is the PEM encoded CA certificate of the Kubernetes API server.
+	CACert string
+
+	// ServiceAccountJWT is the JWT of a service account which is allowed
+	// to create TokenReviews, with which the presented JWTs are checked.
+	ServiceAccountJWT string
+}
+
+// Validator checks Kubernetes service account JWTs with the TokenReview API
+// of the configured cluster.
+type Validator struct {
+	name   string
+	config *Config
+
+	trGetter client_authv1.TokenReviewsGetter
+}
+
+// NewValidator returns the validator of a kubernetes auth method.
+func NewValidator(method *structs.ACLAuthMethod) (*Validator, error) {
+	if method.Type != Type {
+		return nil, fmt.Errorf("%q is not a %s auth method", method.Name, Type)
+	}
+
+	var config Config
+	if err := authmethod.ParseConfig(method.Config, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Host == "" {
+		return nil, errors.New("Config.Host is required")
+	}
+	if config.CACert == "" {
+		return nil, errors.New("Config.CACert is required")
+	}
+	if block, _ := pem.Decode([]byte(config.CACert)); block == nil {
+		return nil, errors.New("Config.CACert is not a valid PEM encoded certificate")
+	}
+	if config.ServiceAccountJWT == "" {
+		return nil, errors.New("Config.ServiceAccountJWT is required")
+	}
+
+	client, err := kubernetes.NewForConfig(&rest.Config{
+		Host:        config.Host,
+		BearerToken: config.ServiceAccountJWT,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: []byte(config.CACert),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{
+		name:     method.Name,
+		config:   &config,
+		trGetter: client.AuthenticationV1(),
+	}, nil
+}
+
+func (v *Validator) Name() string {
+	return v.name
+}
+
+// ValidateLogin checks the service account JWT with the TokenReview API and
+// returns the namespace, name and UID of the service account.
+func (v *Validator) ValidateLogin(loginToken string) (map[string]string, error) {
+	review, err := v.trGetter.TokenReviews().Create(&authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token: loginToken,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Status.Error != "" {
+		return nil, fmt.Errorf("lookup failed: %s", review.Status.Error)
+	}
+	if !review.Status.Authenticated {
+		return nil, errors.New("lookup failed: service account jwt not valid")
+	}
+
+	// The username is of format: system:serviceaccount:(NAMESPACE):(SERVICEACCOUNT)
+	parts := strings.Split(review.Status.User.Username, ":")
+	if len(parts) != 4 || parts[0] != "system" || parts[1] != "serviceaccount" {
+		return nil, errors.New("lookup failed: unexpected username format")
+	}
+	if review.Status.User.UID == "" {
+		return nil, errors.New("lookup failed: no uid returned")
+	}
+
+	return map[string]string{
+		serviceAccountNamespaceField: parts[2],
+		serviceAccountNameField:      parts[3],
+		serviceAccountUIDField:       review.Status.User.UID,
+	}, nil
+}
+
+func (v *Validator) AvailableFields() []string {
+	return []string{
+		serviceAccountNamespaceField,
+		serviceAccountNameField,
+		serviceAccountUIDField,
+	}
+}
@@ -0,0 +1,187 @@
+package kubeauth
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
+	authv1 "k8s.io/api/authentication/v1"
+)
+
+const testReviewerJWT = "reviewer-jwt"
+
+// startTestAPIServer starts a fake Kubernetes API server which only
+// implements the TokenReview API. It accepts the tokens of the given map,
+// which maps a token to the username of its service account.
+func startTestAPIServer(t *testing.T, tokens map[string]string) (*httptest.Server, string) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/apis/authentication.k8s.io/v1/tokenreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+testReviewerJWT {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var review authv1.TokenReview
+		if err := json.Unmarshal(body, &review); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if username, ok := tokens[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.User.Username = username
+			review.Status.User.UID = "uid-" + review.Spec.Token
+		} else {
+			review.Status.Error = "invalid token"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&review)
+	}))
+
+	caCert := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	})
+	return srv, string(caCert)
+}
+
+func TestValidator_NewValidator(t *testing.T) {
+	t.Parallel()
+
+	method := func(config map[string]interface{}) *structs.ACLAuthMethod {
+		return &structs.ACLAuthMethod{
+			Name:   "k8s",
+			Type:   Type,
+			Config: config,
+		}
+	}
+
+	caCert := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+	cases := map[string]struct {
+		method *structs.ACLAuthMethod
+		err    string
+	}{
+		"valid": {
+			method: method(map[string]interface{}{
+				"Host":              "https://k8s.example.com",
+				"CACert":            caCert,
+				"ServiceAccountJWT": testReviewerJWT,
+			}),
+		},
+		"wrong type": {
+			method: &structs.ACLAuthMethod{Name: "k8s", Type: "other"},
+			err:    "is not a kubernetes auth method",
+		},
+		"missing host": {
+			method: method(map[string]interface{}{
+				"CACert":            caCert,
+				"ServiceAccountJWT": testReviewerJWT,
+			}),
+			err: "Config.Host is required",
+		},
+		"missing ca cert": {
+			method: method(map[string]interface{}{
+				"Host":              "https://k8s.example.com",
+				"ServiceAccountJWT": testReviewerJWT,
+			}),
+			err: "Config.CACert is required",
+		},
+		"invalid ca cert": {
+			method: method(map[string]interface{}{
+				"Host":              "https://k8s.example.com",
+				"CACert":            "not a cert",
+				"ServiceAccountJWT": testReviewerJWT,
+			}),
+			err: "Config.CACert is not a valid PEM encoded certificate",
+		},
+		"missing jwt": {
+			method: method(map[string]interface{}{
+				"Host":   "https://k8s.example.com",
+				"CACert": caCert,
+			}),
+			err: "Config.ServiceAccountJWT is required",
+		},
+		"unknown key": {
+			method: method(map[string]interface{}{
+				"Host":              "https://k8s.example.com",
+				"CACert":            caCert,
+				"ServiceAccountJWT": testReviewerJWT,
+				"Bogus":             "yes",
+			}),
+			err: "Bogus",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			v, err := NewValidator(tc.method)
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "k8s", v.Name())
+		})
+	}
+}
+
+func TestValidator_ValidateLogin(t *testing.T) {
+	t.Parallel()
+
+	srv, caCert := startTestAPIServer(t, map[string]string{
+		"good-jwt":     "system:serviceaccount:default:demo",
+		"bad-username": "jane",
+	})
+	defer srv.Close()
+
+	v, err := NewValidator(&structs.ACLAuthMethod{
+		Name: "k8s",
+		Type: Type,
+		Config: map[string]interface{}{
+			"Host":              srv.URL,
+			"CACert":            caCert,
+			"ServiceAccountJWT": testReviewerJWT,
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("valid token", func(t *testing.T) {
+		fields, err := v.ValidateLogin("good-jwt")
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"serviceaccount.namespace": "default",
+			"serviceaccount.name":      "demo",
+			"serviceaccount.uid":       "uid-good-jwt",
+		}, fields)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := v.ValidateLogin("other-jwt")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid token")
+	})
+
+	t.Run("not a service account", func(t *testing.T) {
+		_, err := v.ValidateLogin("bad-username")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unexpected username format")
+	})
+}
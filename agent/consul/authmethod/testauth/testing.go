@@ -0,0 +1,148 @@
+// Package testauth implements the "testing" auth method, which accepts the
+// login tokens installed in memory by tests. It must only be used in tests.
+package testauth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/agent/consul/authmethod"
+	"github.com/hashicorp/consul/agent/structs"
+	uuid "github.com/hashicorp/go-uuid"
+)
+
+// Type is the auth method type of this package.
+const Type = "testing"
+
+func init() {
+	authmethod.Register(Type, func(method *structs.ACLAuthMethod) (authmethod.Validator, error) {
+		v, err := NewValidator(method)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+var (
+	tokenDatabaseMu sync.Mutex
+	tokenDatabase   map[string]map[string]map[string]string // session => token => fields
+)
+
+// StartSession returns a new session ID with which tokens can be installed.
+// Each test should use its own session.
+func StartSession() string {
+	sessionID, err := uuid.GenerateUUID()
+	if err != nil {
+		panic(err)
+	}
+	return sessionID
+}
+
+// ResetSession drops all the tokens of the session.
+func ResetSession(sessionID string) {
+	tokenDatabaseMu.Lock()
+	defer tokenDatabaseMu.Unlock()
+	if tokenDatabase != nil {
+		delete(tokenDatabase, sessionID)
+	}
+}
+
+// InstallSessionToken makes the auth methods of the session accept the token
+// as the given service account.
+func InstallSessionToken(sessionID string, token string, namespace, name, uid string) {
+	fields := map[string]string{
+		"serviceaccount.namespace": namespace,
+		"serviceaccount.name":      name,
+		"serviceaccount.uid":       uid,
+	}
+
+	tokenDatabaseMu.Lock()
+	defer tokenDatabaseMu.Unlock()
+	if tokenDatabase == nil {
+		tokenDatabase = make(map[string]map[string]map[string]string)
+	}
+	sdb, ok := tokenDatabase[sessionID]
+	if !ok {
+		sdb = make(map[string]map[string]string)
+		tokenDatabase[sessionID] = sdb
+	}
+	sdb[token] = fields
+}
+
+// GetSessionToken returns the fields of a token installed in the session.
+func GetSessionToken(sessionID string, token string) (map[string]string, bool) {
+	tokenDatabaseMu.Lock()
+	defer tokenDatabaseMu.Unlock()
+	if tokenDatabase == nil {
+		return nil, false
+	}
+	sdb, ok := tokenDatabase[sessionID]
+	if !ok {
+		return nil, false
+	}
+	fields, ok := sdb[token]
+	if !ok {
+		return nil, false
+	}
+	fieldsCopy := make(map[string]string, len(fields))
+	for k, v := range fields {
+		fieldsCopy[k] = v
+	}
+	return fieldsCopy, true
+}
+
+// Config is the configuration of a testing auth method.
+type Config struct {
+	// SessionID is the session of which the installed tokens are accepted.
+	SessionID string
+}
+
+// Validator accepts the tokens installed in its session.
+type Validator struct {
+	name   string
+	config *Config
+}
+
+// NewValidator returns the validator of a testing auth method.
+func NewValidator(method *structs.ACLAuthMethod) (*Validator, error) {
+	if method.Type != Type {
+		return nil, fmt.Errorf("%q is not a %s auth method", method.Name, Type)
+	}
+
+	var config Config
+	if err := authmethod.ParseConfig(method.Config, &config); err != nil {
+		return nil, err
+	}
+	if config.SessionID == "" {
+		return nil, errors.New("Config.SessionID is required")
+	}
+
+	return &Validator{
+		name:   method.Name,
+		config: &config,
+	}, nil
+}
+
+func (v *Validator) Name() string {
+	return v.name
+}
+
+// ValidateLogin returns the fields of the token if it was installed in the
+// session of the validator.
+func (v *Validator) ValidateLogin(loginToken string) (map[string]string, error) {
+	fields, valid := GetSessionToken(v.config.SessionID, loginToken)
+	if !valid {
+		return nil, errors.New("lookup failed: service account jwt not valid")
+	}
+	return fields, nil
+}
+
+func (v *Validator) AvailableFields() []string {
+	return []string{
+		"serviceaccount.namespace",
+		"serviceaccount.name",
+		"serviceaccount.uid",
+	}
+}
@@ -0,0 +1,54 @@
+package consul
+
+import (
+	"sync"
+)
+
+// blockingQueryTracker counts the blocking queries in progress on this server
+// for each token, so that a single client can't pin the resources of the
+// server with many concurrent watches.
+type blockingQueryTracker struct {
+	lock    sync.Mutex
+	byToken map[string]int
+}
+
+// newBlockingQueryTracker returns a tracker without any query in progress.
+func newBlockingQueryTracker() *blockingQueryTracker {
+	return &blockingQueryTracker{byToken: make(map[string]int)}
+}
+
+// Acquire records a new blocking query for the given token. It returns false
+// without recording anything if the token already has max queries in
+// progress. A max of zero or less disables the limit.
+func (t *blockingQueryTracker) Acquire(token string, max int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if max > 0 && t.byToken[token] >= max {
+		return false
+	}
+	t.byToken[token]++
+	return true
+}
+
+// Release records the end of a blocking query previously acquired for the
+// given token.
+func (t *blockingQueryTracker) Release(token string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.byToken[token] <= 1 {
+		delete(t.byToken, token)
+		return
+	}
+	t.byToken[token]--
+}
+
+// InFlight returns the number of blocking queries in progress for the given
+// token.
+func (t *blockingQueryTracker) InFlight(token string) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.byToken[token]
+}
@@ -0,0 +1,35 @@
+package consul
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockingQueryTracker(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	tr := newBlockingQueryTracker()
+	require.True(tr.Acquire("a", 2))
+	require.True(tr.Acquire("a", 2))
+	require.False(tr.Acquire("a", 2))
+	require.Equal(2, tr.InFlight("a"))
+
+	// Other tokens have their own count.
+	require.True(tr.Acquire("b", 2))
+	require.Equal(1, tr.InFlight("b"))
+
+	tr.Release("a")
+	require.True(tr.Acquire("a", 2))
+
+	// Zero disables the limit.
+	require.True(tr.Acquire("a", 0))
+	require.Equal(3, tr.InFlight("a"))
+
+	for i := 0; i < 3; i++ {
+		tr.Release("a")
+	}
+	require.Equal(0, tr.InFlight("a"))
+	require.Len(tr.byToken, 1)
+}
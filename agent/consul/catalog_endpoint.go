@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/serf/serf"
 )
 
 // Catalog endpoint is used to manipulate the service catalog
@@ -41,7 +42,7 @@ func (c *Catalog) Register(args *structs.RegisterRequest, reply *struct{}) error
 	}
 
 	// Fetch the ACL token, if any.
-	rule, err := c.srv.ResolveToken(args.Token)
+	rule, err := c.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -78,14 +79,14 @@ func (c *Catalog) Register(args *structs.RegisterRequest, reply *struct{}) error
 		// delete this and do all the ACL checks down there.
 		if args.Service.Service != structs.ConsulServiceName {
 			if rule != nil && !rule.ServiceWrite(args.Service.Service, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", args.Service.Service, "write")
 			}
 		}
 
 		// Proxies must have write permission on their destination
 		if args.Service.Kind == structs.ServiceKindConnectProxy {
 			if rule != nil && !rule.ServiceWrite(args.Service.Proxy.DestinationServiceName, nil) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("service", args.Service.Proxy.DestinationServiceName, "write")
 			}
 		}
 	}
@@ -139,7 +140,7 @@ func (c *Catalog) Deregister(args *structs.DeregisterRequest, reply *struct{}) e
 	}
 
 	// Fetch the ACL token, if any.
-	rule, err := c.srv.ResolveToken(args.Token)
+	rule, err := c.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -214,7 +215,7 @@ func (c *Catalog) ListNodes(args *structs.DCSpecificRequest, reply *structs.Inde
 			}
 
 			reply.Index, reply.Nodes = index, nodes
-			if err := c.srv.filterACL(args.Token, reply); err != nil {
+			if err := c.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return c.srv.sortNodesByDistanceFrom(args.Source, reply.Nodes)
@@ -234,7 +235,9 @@ func (c *Catalog) ListServices(args *structs.DCSpecificRequest, reply *structs.I
 			var index uint64
 			var services structs.Services
 			var err error
-			if len(args.NodeMetaFilters) > 0 {
+			if args.ExternalSource != "" {
+				index, services, err = state.ServicesByExternalSource(ws, args.ExternalSource, args.NodeMetaFilters)
+			} else if len(args.NodeMetaFilters) > 0 {
 				index, services, err = state.ServicesByNodeMeta(ws, args.NodeMetaFilters)
 			} else {
 				index, services, err = state.Services(ws)
@@ -244,7 +247,7 @@ func (c *Catalog) ListServices(args *structs.DCSpecificRequest, reply *structs.I
 			}
 
 			reply.Index, reply.Services = index, services
-			return c.srv.filterACL(args.Token, reply)
+			return c.srv.filterRequestACL(args, reply)
 		})
 }
 
@@ -285,7 +288,7 @@ func (c *Catalog) ServiceNodes(args *structs.ServiceSpecificRequest, reply *stru
 	// we're trying to find proxies for, so check that.
 	if args.Connect {
 		// Fetch the ACL token, if any.
-		rule, err := c.srv.ResolveToken(args.Token)
+		rule, err := c.srv.ResolveRequestToken(args)
 		if err != nil {
 			return err
 		}
@@ -315,7 +318,16 @@ func (c *Catalog) ServiceNodes(args *structs.ServiceSpecificRequest, reply *stru
 				}
 				reply.ServiceNodes = filtered
 			}
-			if err := c.srv.filterACL(args.Token, reply); err != nil {
+			if args.ExternalSource != "" {
+				var filtered structs.ServiceNodes
+				for _, service := range reply.ServiceNodes {
+					if service.ServiceMeta[structs.MetaExternalSource] == args.ExternalSource {
+						filtered = append(filtered, service)
+					}
+				}
+				reply.ServiceNodes = filtered
+			}
+			if err := c.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return c.srv.sortNodesByDistanceFrom(args.Source, reply.ServiceNodes)
@@ -377,6 +389,146 @@ func (c *Catalog) NodeServices(args *structs.NodeSpecificRequest, reply *structs
 			}
 
 			reply.Index, reply.NodeServices = index, services
-			return c.srv.filterACL(args.Token, reply)
+			return c.srv.filterRequestACL(args, reply)
+		})
+}
+
+// ServiceHistory returns the recent registrations, deregistrations and
+// health changes of the instances of a service.
+func (c *Catalog) ServiceHistory(args *structs.ServiceSpecificRequest, reply *structs.IndexedServiceInstanceEvents) error {
+	if done, err := c.srv.forward("Catalog.ServiceHistory", args, args, reply); done {
+		return err
+	}
+
+	// Verify the arguments
+	if args.ServiceName == "" {
+		return fmt.Errorf("Must provide service name")
+	}
+
+	return c.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, events, err := state.ServiceHistory(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Events = index, events
+			return c.srv.filterRequestACL(args, reply)
+		})
+}
+
+// ServiceVirtualIP returns the virtual IP of a service, which allows to route
+// to the service by destination IP. The IP is empty if the service isn't
+// registered or the token can't read it.
+func (c *Catalog) ServiceVirtualIP(args *structs.ServiceSpecificRequest, reply *structs.ServiceVirtualIPResponse) error {
+	if done, err := c.srv.forward("Catalog.ServiceVirtualIP", args, args, reply); done {
+		return err
+	}
+
+	// Verify the arguments
+	if args.ServiceName == "" {
+		return fmt.Errorf("Must provide service name")
+	}
+
+	rule, err := c.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+
+	return c.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, vip, err := state.ServiceVirtualIP(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+
+			// Services the token can't read look like they aren't
+			// registered, the same as for the service lookups.
+			reply.Index, reply.IP = index, ""
+			if vip != nil && (rule == nil || rule.ServiceRead(args.ServiceName)) {
+				// An offset allocated before the range was narrowed has no
+				// virtual IP anymore.
+				ip, err := structs.VirtualIP(c.srv.config.VirtualIPCIDR, vip.Offset)
+				if err != nil {
+					c.srv.logger.Printf("[WARN] consul.catalog: Service %q has no virtual IP: %v", vip.Service, err)
+					return nil
+				}
+				reply.IP = ip.String()
+			}
+			return nil
+		})
+}
+
+// ServiceVirtualIPs returns the virtual IPs of all the services the token can
+// read, by service name.
+func (c *Catalog) ServiceVirtualIPs(args *structs.DCSpecificRequest, reply *structs.IndexedServiceVirtualIPs) error {
+	if done, err := c.srv.forward("Catalog.ServiceVirtualIPs", args, args, reply); done {
+		return err
+	}
+
+	rule, err := c.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+
+	return c.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, vips, err := state.ServiceVirtualIPs(ws)
+			if err != nil {
+				return err
+			}
+
+			reply.Index = index
+			reply.VirtualIPs = make(map[string]string, len(vips))
+			for _, vip := range vips {
+				if rule != nil && !rule.ServiceRead(vip.Service) {
+					continue
+				}
+				ip, err := structs.VirtualIP(c.srv.config.VirtualIPCIDR, vip.Offset)
+				if err != nil {
+					c.srv.logger.Printf("[WARN] consul.catalog: Service %q has no virtual IP: %v", vip.Service, err)
+					continue
+				}
+				reply.VirtualIPs[vip.Service] = ip.String()
+			}
+			return nil
+		})
+}
+
+// NodeActivity returns the nodes with the times of their last registrations
+// and whether they are members of the LAN gossip pool, in order to find nodes
+// which aren't kept up to date anymore.
+func (c *Catalog) NodeActivity(args *structs.DCSpecificRequest, reply *structs.IndexedNodeActivities) error {
+	if done, err := c.srv.forward("Catalog.NodeActivity", args, args, reply); done {
+		return err
+	}
+
+	return c.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, nodes, err := state.NodeActivity(ws, args.NodeMetaFilters)
+			if err != nil {
+				return err
+			}
+
+			members := make(map[string]bool)
+			for _, m := range c.srv.LANMembers() {
+				if m.Status != serf.StatusLeft {
+					members[m.Name] = true
+				}
+			}
+			for _, n := range nodes {
+				n.SerfMember = members[n.Node]
+			}
+
+			reply.Index, reply.Nodes = index, nodes
+			return c.srv.filterRequestACL(args, reply)
 		})
 }
@@ -2,6 +2,7 @@ package consul
 
 import (
 	"fmt"
+	"net"
 	"net/rpc"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/state"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib"
@@ -2124,6 +2126,392 @@ func TestCatalog_NodeServices(t *testing.T) {
 	}
 }
 
+func TestCatalog_ServiceHistory(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Register an instance and deregister it.
+	reg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "db1",
+			Service: "db",
+		},
+	}
+	var out struct{}
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dereg := structs.DeregisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		ServiceID:  "db1",
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.Deregister", &dereg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	args := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "db",
+	}
+	var reply structs.IndexedServiceInstanceEvents
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceHistory", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Events) != 2 {
+		t.Fatalf("bad: %#v", reply.Events)
+	}
+	if ev := reply.Events[0]; ev.Type != structs.ServiceInstanceRegistered || ev.Node != "foo" || ev.ServiceID != "db1" {
+		t.Fatalf("bad: %#v", ev)
+	}
+	if ev := reply.Events[1]; ev.Type != structs.ServiceInstanceDeregistered || ev.Node != "foo" || ev.ServiceID != "db1" {
+		t.Fatalf("bad: %#v", ev)
+	}
+	if reply.Index != reply.Events[1].Index {
+		t.Fatalf("bad index: %d", reply.Index)
+	}
+
+	// A service name is required.
+	args.ServiceName = ""
+	err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceHistory", &args, &reply)
+	if err == nil || !strings.Contains(err.Error(), "Must provide service name") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestCatalog_ServiceHistory_FilterACL(t *testing.T) {
+	t.Parallel()
+	dir, token, srv, codec := testACLFilterServer(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer codec.Close()
+
+	for service, allowed := range map[string]bool{"foo": true, "bar": false} {
+		opt := structs.ServiceSpecificRequest{
+			Datacenter:   "dc1",
+			ServiceName:  service,
+			QueryOptions: structs.QueryOptions{Token: token},
+		}
+		reply := structs.IndexedServiceInstanceEvents{}
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceHistory", &opt, &reply); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if allowed && len(reply.Events) == 0 {
+			t.Fatalf("%s: missing events", service)
+		}
+		if !allowed && len(reply.Events) != 0 {
+			t.Fatalf("%s: bad: %#v", service, reply.Events)
+		}
+	}
+}
+
+func TestCatalog_NodeActivity(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Register an external node.
+	reg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.2",
+		NodeMeta:   map[string]string{"external": "true"},
+	}
+	var out struct{}
+	start := time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	args := structs.DCSpecificRequest{
+		Datacenter: "dc1",
+	}
+	var reply structs.IndexedNodeActivities
+	retry.Run(t, func(r *retry.R) {
+		reply = structs.IndexedNodeActivities{}
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.NodeActivity", &args, &reply); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 2 {
+			r.Fatalf("bad: %#v", reply.Nodes)
+		}
+	})
+	for _, n := range reply.Nodes {
+		switch n.Node {
+		case s1.config.NodeName:
+			if !n.SerfMember {
+				t.Fatalf("bad: %#v", n)
+			}
+		case "foo":
+			if n.SerfMember || n.Address != "127.0.0.2" || n.LastRegistered.Before(start) {
+				t.Fatalf("bad: %#v", n)
+			}
+		default:
+			t.Fatalf("bad: %#v", n)
+		}
+	}
+
+	// Filter by node metadata.
+	args.NodeMetaFilters = map[string]string{"external": "true"}
+	var filtered structs.IndexedNodeActivities
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.NodeActivity", &args, &filtered); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(filtered.Nodes) != 1 || filtered.Nodes[0].Node != "foo" {
+		t.Fatalf("bad: %#v", filtered.Nodes)
+	}
+}
+
+func TestCatalog_ServiceVirtualIP(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		_, c.VirtualIPCIDR, _ = net.ParseCIDR("10.217.0.0/16")
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1")
+
+	args := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "db",
+	}
+	var reply structs.ServiceVirtualIPResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.IP != "" {
+		t.Fatalf("bad: %#v", reply)
+	}
+
+	for _, service := range []string{"web", "db"} {
+		reg := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      service,
+				Service: service,
+				Port:    8000,
+			},
+		}
+		var out struct{}
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// The consul service of the server was registered first.
+	if reply.IP != "10.217.0.3" || reply.Index == 0 {
+		t.Fatalf("bad: %#v", reply)
+	}
+
+	args.ServiceName = ""
+	err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply)
+	if err == nil || !strings.Contains(err.Error(), "Must provide service name") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCatalog_ServiceVirtualIP_FilterACL(t *testing.T) {
+	t.Parallel()
+	dir, token, srv, codec := testACLFilterServer(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer codec.Close()
+
+	args := structs.ServiceSpecificRequest{
+		Datacenter:   "dc1",
+		ServiceName:  "foo",
+		QueryOptions: structs.QueryOptions{Token: token},
+	}
+	var reply structs.ServiceVirtualIPResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.IP == "" {
+		t.Fatalf("bad: %#v", reply)
+	}
+
+	// Register a service the token can't read.
+	reg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       srv.config.NodeName,
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "bar",
+			Service: "bar",
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var out struct{}
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	args.ServiceName = "bar"
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.IP != "" {
+		t.Fatalf("bad: %#v", reply)
+	}
+
+	args.Token = "root"
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.IP == "" {
+		t.Fatalf("bad: %#v", reply)
+	}
+}
+
+func TestCatalog_ServiceVirtualIPs(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		_, c.VirtualIPCIDR, _ = net.ParseCIDR("10.217.0.0/16")
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1")
+
+	reg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "web",
+			Service: "web",
+			Port:    8000,
+		},
+	}
+	var out struct{}
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	args := structs.DCSpecificRequest{
+		Datacenter: "dc1",
+	}
+	var reply structs.IndexedServiceVirtualIPs
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIPs", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// The consul service of the server was registered first.
+	require.Equal(t, map[string]string{
+		"consul": "10.217.0.1",
+		"web":    "10.217.0.2",
+	}, reply.VirtualIPs)
+	require.NotZero(t, reply.Index)
+}
+
+func TestCatalog_ServiceVirtualIPs_OutOfRange(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		_, c.VirtualIPCIDR, _ = net.ParseCIDR("10.217.0.0/30")
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForTestAgent(t, s1.RPC, "dc1")
+
+	// The range has room for the consul service and one more.
+	for i, service := range []string{"web", "db"} {
+		reg := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      service,
+				Service: service,
+				Port:    8000,
+			},
+		}
+		var out struct{}
+		err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &reg, &out)
+		if i == 0 {
+			require.NoError(t, err)
+		} else {
+			require.Error(t, err)
+			require.Contains(t, err.Error(), state.ErrVirtualIPsExhausted.Error())
+		}
+	}
+
+	// Offsets allocated before the range was narrowed are left out.
+	restore := s1.fsm.State().Restore()
+	require.NoError(t, restore.ServiceVirtualIP(&structs.ServiceVirtualIP{
+		Service:   "old",
+		Offset:    1000,
+		RaftIndex: structs.RaftIndex{CreateIndex: 1, ModifyIndex: 1},
+	}))
+	restore.Commit()
+
+	args := structs.DCSpecificRequest{
+		Datacenter: "dc1",
+	}
+	var reply structs.IndexedServiceVirtualIPs
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIPs", &args, &reply))
+	require.Equal(t, map[string]string{
+		"consul": "10.217.0.1",
+		"web":    "10.217.0.2",
+	}, reply.VirtualIPs)
+
+	single := structs.ServiceSpecificRequest{
+		Datacenter:  "dc1",
+		ServiceName: "old",
+	}
+	var vip structs.ServiceVirtualIPResponse
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIP", &single, &vip))
+	require.Empty(t, vip.IP)
+}
+
+func TestCatalog_ServiceVirtualIPs_FilterACL(t *testing.T) {
+	t.Parallel()
+	dir, token, srv, codec := testACLFilterServer(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer codec.Close()
+
+	args := structs.DCSpecificRequest{
+		Datacenter:   "dc1",
+		QueryOptions: structs.QueryOptions{Token: token},
+	}
+	var reply structs.IndexedServiceVirtualIPs
+	if err := msgpackrpc.CallWithCodec(codec, "Catalog.ServiceVirtualIPs", &args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := reply.VirtualIPs["foo"]; !ok {
+		t.Fatalf("bad: %#v", reply.VirtualIPs)
+	}
+	if _, ok := reply.VirtualIPs["consul"]; ok {
+		t.Fatalf("bad: %#v", reply.VirtualIPs)
+	}
+}
+
 func TestCatalog_NodeServices_ConnectProxy(t *testing.T) {
 	t.Parallel()
 
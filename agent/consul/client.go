@@ -154,7 +154,7 @@ func NewClientLogger(config *Config, logger *log.Logger) (*Client, error) {
 		Delegate:    c,
 		Logger:      logger,
 		AutoDisable: true,
-		CacheConfig: clientACLCacheConfig,
+		CacheConfig: scaleACLCacheConfig(clientACLCacheConfig, config.MemoryLimit),
 		Sentinel:    nil,
 	}
 	if c.acls, err = NewACLResolver(&aclConfig); err != nil {
@@ -281,6 +281,7 @@ func (c *Client) RPC(method string, args interface{}, reply interface{}) error {
 	// starting the timer here we won't potentially double up the delay.
 	// TODO (slackpad) Plumb a deadline here with a context.
 	firstCheck := time.Now()
+	retries := 0
 
 TRY:
 	server := c.routers.FindServer()
@@ -311,9 +312,11 @@ TRY:
 
 	// We can wait a bit and retry!
 	if time.Since(firstCheck) < c.config.RPCHoldTimeout {
-		jitter := lib.RandomStagger(c.config.RPCHoldTimeout / jitterFraction)
+		metrics.IncrCounter([]string{"client", "rpc", "retry"}, 1)
+		wait := rpcRetryWait(c.config, retries, firstCheck)
+		retries++
 		select {
-		case <-time.After(jitter):
+		case <-time.After(wait):
 			goto TRY
 		case <-c.shutdownCh:
 		}
@@ -33,6 +33,11 @@ const (
 	// MaxRaftMultiplier is a fairly arbitrary upper bound that limits the
 	// amount of performance detuning that's possible.
 	MaxRaftMultiplier uint = 10
+
+	// DefaultVirtualIPCIDR is the reserved range from which the virtual IPs
+	// of the services are allocated, so that they don't clash with real
+	// addresses.
+	DefaultVirtualIPCIDR = "240.0.0.0/4"
 )
 
 var (
@@ -88,6 +93,10 @@ type Config struct {
 	// DevMode is used to enable a development server mode.
 	DevMode bool
 
+	// DevPersist stores the Raft state of a dev mode server in the DataDir
+	// instead of memory.
+	DevPersist bool
+
 	// NodeID is a unique identifier for this node across space and time.
 	NodeID types.NodeID
 
@@ -130,6 +139,10 @@ type Config struct {
 	// bind on.
 	Segments []NetworkSegment
 
+	// RPCRoutes configures how the requests for remote datacenters are
+	// forwarded, e.g. through a hub datacenter instead of directly.
+	RPCRoutes []structs.RPCRoute
+
 	// SerfLANConfig is the configuration for the intra-dc serf
 	SerfLANConfig *serf.Config
 
@@ -177,6 +190,10 @@ type Config struct {
 	// existing clients.
 	VerifyServerHostname bool
 
+	// VerifyServerHostnamePolicies refine the hostname verification of the
+	// servers of some datacenters. See tlsutil.ServerHostnamePolicy.
+	VerifyServerHostnamePolicies []tlsutil.ServerHostnamePolicy
+
 	// CAFile is a path to a certificate authority file. This is used with VerifyIncoming
 	// or VerifyOutgoing to verify the TLS connection.
 	CAFile string
@@ -207,6 +224,10 @@ type Config struct {
 	// over the client ciphersuites.
 	TLSPreferServerCipherSuites bool
 
+	// TLSRevocation checks the certificates of TLS peers against CRLs and
+	// OCSP. It is optional.
+	TLSRevocation *tlsutil.Revocation
+
 	// RejoinAfterLeave controls our interaction with Serf.
 	// When set to false (default), a leave causes a Consul to not rejoin
 	// the cluster until an explicit join is received. If this is set to
@@ -243,10 +264,20 @@ type Config struct {
 	// a substantial cost.
 	ACLPolicyTTL time.Duration
 
+	// ACLBlockingQueryRecheckInterval is the interval at which the token
+	// of a blocking query is resolved again, so that revoking it ends the
+	// query in bounded time. Zero disables the recheck.
+	ACLBlockingQueryRecheckInterval time.Duration
+
 	// ACLDisabledTTL is the time between checking if ACLs should be
 	// enabled. This
 	ACLDisabledTTL time.Duration
 
+	// ACLTokenMinExpirationTTL and ACLTokenMaxExpirationTTL bound how far
+	// in the future the expiration time of a new token may be.
+	ACLTokenMinExpirationTTL time.Duration
+	ACLTokenMaxExpirationTTL time.Duration
+
 	// ACLTokenReplication is used to enabled token replication.
 	//
 	// By default policy-only replication is enabled. When token
@@ -313,6 +344,36 @@ type Config struct {
 	// Minimum Session TTL
 	SessionTTLMin time.Duration
 
+	// KVRecycleBinRetention is how long deleted KV entries are kept in the
+	// recycle bin. Zero disables the recycle bin.
+	KVRecycleBinRetention time.Duration
+
+	// VirtualIPCIDR is the range of the virtual IPs of the services. The
+	// state store allocates offsets into the range, so changing it changes
+	// the virtual IPs of all the services.
+	VirtualIPCIDR *net.IPNet
+
+	// KVReplicationPrefixes are the KV prefixes which the servers of a
+	// secondary datacenter replicate from the primary datacenter.
+	// KVReplicationConflictPolicy decides what happens to the local keys
+	// under these prefixes which don't exist in the primary datacenter.
+	KVReplicationPrefixes       []string
+	KVReplicationConflictPolicy string
+
+	// GossipKeyRotationInterval is how often the leader of the primary
+	// datacenter rotates the gossip encryption keys. Zero disables the
+	// rotation. GossipKeyRotationRetireAfter is how long the previous primary
+	// key stays installed after the new key was made primary.
+	GossipKeyRotationInterval    time.Duration
+	GossipKeyRotationRetireAfter time.Duration
+
+	// PreparedQuerySlowThreshold is the execution time above which prepared
+	// query executions are logged as slow. Zero disables the slow query log.
+	PreparedQuerySlowThreshold time.Duration
+
+	// RequestLog configures the logging of slow and sampled RPC requests.
+	RequestLog lib.RequestLogConfig
+
 	// ServerUp callback can be used to trigger a notification that
 	// a Consul server is now up and known about.
 	ServerUp func()
@@ -321,6 +382,10 @@ type Config struct {
 	// user events. This function should not block.
 	UserEventHandler func(serf.UserEvent)
 
+	// LeadershipChanged callback is called when the server acquires or
+	// loses the cluster leadership. This function should not block.
+	LeadershipChanged func(isLeader bool)
+
 	// CoordinateUpdatePeriod controls how long a server batches coordinate
 	// updates before applying them in a Raft transaction. A larger period
 	// leads to fewer Raft transactions, but also the stored coordinates
@@ -343,6 +408,13 @@ type Config struct {
 	// place, and a small jitter is applied to avoid a thundering herd.
 	RPCHoldTimeout time.Duration
 
+	// RPCRetryMaxInterval caps the exponentially growing interval between
+	// two retries of an RPC which is held, and RPCRetryJitter is the fraction
+	// of the interval which is added as a random delay. Together they keep
+	// the agents from retrying in sync after a server restart.
+	RPCRetryMaxInterval time.Duration
+	RPCRetryJitter      float64
+
 	// RPCRate and RPCMaxBurst control how frequently RPC calls are allowed
 	// to happen. In any large enough time interval, rate limiter limits the
 	// rate to RPCRate tokens per second, with a maximum burst size of
@@ -354,6 +426,18 @@ type Config struct {
 	RPCRate     rate.Limit
 	RPCMaxBurst int
 
+	// MaxQueryTime is the longest time a blocking query can wait for a
+	// change, and DefaultQueryTime is the time it waits when the client
+	// doesn't ask for one. Longer waits requested by clients are cut down
+	// to MaxQueryTime.
+	MaxQueryTime     time.Duration
+	DefaultQueryTime time.Duration
+
+	// MaxBlockingQueriesPerToken limits the number of blocking queries
+	// which can be in progress on this server for a single token. Zero
+	// disables the limit.
+	MaxBlockingQueriesPerToken int
+
 	// LeaveDrainTime is used to wait after a server has left the LAN Serf
 	// pool for RPCs to drain and new requests to be sent to other servers.
 	LeaveDrainTime time.Duration
@@ -380,6 +464,10 @@ type Config struct {
 
 	// ConnectReplicationToken is used to control Intention replication.
 	ConnectReplicationToken string
+
+	// MemoryLimit is the memory limit of the agent in bytes, which is 0 if
+	// the memory is not limited. The ACL caches are sized according to it.
+	MemoryLimit uint64
 }
 
 // CheckProtocolVersion validates the protocol version.
@@ -431,6 +519,8 @@ func DefaultConfig() *Config {
 		ProtocolVersion:          ProtocolVersion2Compatible,
 		ACLPolicyTTL:             30 * time.Second,
 		ACLTokenTTL:              30 * time.Second,
+		ACLTokenMinExpirationTTL: 1 * time.Minute,
+		ACLTokenMaxExpirationTTL: 24 * time.Hour,
 		ACLDefaultPolicy:         "allow",
 		ACLDownPolicy:            "extend-cache",
 		ACLReplicationRate:       1,
@@ -440,6 +530,10 @@ func DefaultConfig() *Config {
 		TombstoneTTLGranularity:  30 * time.Second,
 		SessionTTLMin:            10 * time.Second,
 
+		KVReplicationConflictPolicy: structs.KVReplicationOverwrite,
+
+		GossipKeyRotationRetireAfter: time.Hour,
+
 		// These are tuned to provide a total throughput of 128 updates
 		// per second. If you update these, you should update the client-
 		// side SyncCoordinateRateTarget parameter accordingly.
@@ -450,6 +544,12 @@ func DefaultConfig() *Config {
 		RPCRate:     rate.Inf,
 		RPCMaxBurst: 1000,
 
+		MaxQueryTime:     600 * time.Second,
+		DefaultQueryTime: 300 * time.Second,
+
+		RPCRetryMaxInterval: time.Second,
+		RPCRetryJitter:      1,
+
 		TLSMinVersion: "tls10",
 
 		// TODO (slackpad) - Until #3744 is done, we need to keep these
@@ -472,6 +572,7 @@ func DefaultConfig() *Config {
 		ServerHealthInterval: 2 * time.Second,
 		AutopilotInterval:    10 * time.Second,
 	}
+	_, conf.VirtualIPCIDR, _ = net.ParseCIDR(DefaultVirtualIPCIDR)
 
 	// Increase our reap interval to 3 days instead of 24h.
 	conf.SerfLANConfig.ReconnectTimeout = 3 * 24 * time.Hour
@@ -504,19 +605,21 @@ func DefaultConfig() *Config {
 // tlsConfig maps this config into a tlsutil config.
 func (c *Config) tlsConfig() *tlsutil.Config {
 	tlsConf := &tlsutil.Config{
-		VerifyIncoming:           c.VerifyIncoming,
-		VerifyOutgoing:           c.VerifyOutgoing,
-		VerifyServerHostname:     c.VerifyServerHostname,
-		UseTLS:                   c.UseTLS,
-		CAFile:                   c.CAFile,
-		CAPath:                   c.CAPath,
-		CertFile:                 c.CertFile,
-		KeyFile:                  c.KeyFile,
-		NodeName:                 c.NodeName,
-		ServerName:               c.ServerName,
-		Domain:                   c.Domain,
-		TLSMinVersion:            c.TLSMinVersion,
-		PreferServerCipherSuites: c.TLSPreferServerCipherSuites,
+		VerifyIncoming:               c.VerifyIncoming,
+		VerifyOutgoing:               c.VerifyOutgoing,
+		VerifyServerHostname:         c.VerifyServerHostname,
+		VerifyServerHostnamePolicies: c.VerifyServerHostnamePolicies,
+		UseTLS:                       c.UseTLS,
+		CAFile:                       c.CAFile,
+		CAPath:                       c.CAPath,
+		CertFile:                     c.CertFile,
+		KeyFile:                      c.KeyFile,
+		NodeName:                     c.NodeName,
+		ServerName:                   c.ServerName,
+		Domain:                       c.Domain,
+		TLSMinVersion:                c.TLSMinVersion,
+		PreferServerCipherSuites:     c.TLSPreferServerCipherSuites,
+		Revocation:                   c.TLSRevocation,
 	}
 	return tlsConf
 }
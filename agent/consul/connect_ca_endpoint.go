@@ -36,12 +36,12 @@ func (s *ConnectCA) ConfigurationGet(
 	}
 
 	// This action requires operator read access.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "read")
 	}
 
 	state := s.srv.fsm.State()
@@ -68,12 +68,12 @@ func (s *ConnectCA) ConfigurationSet(
 	}
 
 	// This action requires operator write access.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "write")
 	}
 
 	// Exit early if it's a no-op change
@@ -349,12 +349,12 @@ func (s *ConnectCA) Sign(
 	}
 
 	// Verify that the ACL token provided has permission to act as this service
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.ServiceWrite(serviceID.Service, nil) {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("service", serviceID.Service, "write")
 	}
 
 	// Verify that the DC in the service URI matches us. We might relax this
@@ -407,5 +407,52 @@ func (s *ConnectCA) Sign(
 		},
 	}
 
+	s.srv.caLeaves.Record(&structs.CALeaf{
+		Node:         args.Node,
+		Service:      serviceID.Service,
+		RootID:       caRoot.ID,
+		SerialNumber: reply.SerialNumber,
+		ValidAfter:   reply.ValidAfter,
+		ValidBefore:  reply.ValidBefore,
+	})
+
+	return nil
+}
+
+// Leaves returns the leaf certificates the leader signed which are still
+// valid, along with the root which was active when they were signed. It's
+// used to follow the progress of a root rotation.
+func (s *ConnectCA) Leaves(
+	args *structs.DCSpecificRequest,
+	reply *structs.IndexedCALeaves) error {
+	// Exit early if Connect hasn't been enabled.
+	if !s.srv.config.ConnectEnabled {
+		return ErrConnectNotEnabled
+	}
+
+	// Only the leader knows about the signed leaves, so this can't be
+	// served by a follower.
+	args.AllowStale = false
+	if done, err := s.srv.forward("ConnectCA.Leaves", args, args, reply); done {
+		return err
+	}
+
+	// This action requires operator read access.
+	rule, err := s.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.OperatorRead() {
+		return acl.PermissionDenied("operator", "", "read")
+	}
+
+	_, root, err := s.srv.fsm.State().CARootActive(nil)
+	if err != nil {
+		return err
+	}
+	if root != nil {
+		reply.ActiveRootID = root.ID
+	}
+	reply.Leaves = s.srv.caLeaves.Leaves(time.Now())
 	return nil
 }
@@ -336,6 +336,56 @@ func TestConnectCASign(t *testing.T) {
 	assert.Equal(spiffeId.URI().String(), reply.ServiceURI)
 }
 
+func TestConnectCALeaves(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Sign a certificate for two services and sign the first one again, which
+	// replaces the earlier certificate.
+	var serial string
+	for _, sign := range []struct{ node, service string }{
+		{"node1", "web"},
+		{"node1", "db"},
+		{"node1", "web"},
+	} {
+		csr, _ := connect.TestCSR(t, connect.TestSpiffeIDService(t, sign.service))
+		args := &structs.CASignRequest{
+			Datacenter: "dc1",
+			CSR:        csr,
+			Node:       sign.node,
+		}
+		var reply structs.IssuedCert
+		require.NoError(msgpackrpc.CallWithCodec(codec, "ConnectCA.Sign", args, &reply))
+		serial = reply.SerialNumber
+	}
+
+	_, ca, err := s1.fsm.State().CARootActive(nil)
+	require.NoError(err)
+
+	args := &structs.DCSpecificRequest{
+		Datacenter: "dc1",
+	}
+	var reply structs.IndexedCALeaves
+	require.NoError(msgpackrpc.CallWithCodec(codec, "ConnectCA.Leaves", args, &reply))
+	require.Equal(ca.ID, reply.ActiveRootID)
+	require.Len(reply.Leaves, 2)
+	require.Equal("db", reply.Leaves[0].Service)
+	require.Equal("web", reply.Leaves[1].Service)
+	require.Equal(serial, reply.Leaves[1].SerialNumber)
+	for _, leaf := range reply.Leaves {
+		require.Equal("node1", leaf.Node)
+		require.Equal(ca.ID, leaf.RootID)
+	}
+}
+
 func TestConnectCASignValidation(t *testing.T) {
 	t.Parallel()
 
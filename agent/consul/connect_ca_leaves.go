@@ -0,0 +1,61 @@
+package consul
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// caLeafTracker keeps track of the most recent leaf certificate the leader
+// signed for each service on each node, so that operators can follow the
+// progress of a CA root rotation. The entries are only kept in memory, so
+// a new leader only knows about the certificates it signed itself.
+type caLeafTracker struct {
+	leaves map[caLeafKey]*structs.CALeaf
+	lock   sync.Mutex
+}
+
+type caLeafKey struct {
+	node    string
+	service string
+}
+
+func newCALeafTracker() *caLeafTracker {
+	return &caLeafTracker{
+		leaves: make(map[caLeafKey]*structs.CALeaf),
+	}
+}
+
+// Record stores the leaf, replacing any previous one of the same service on
+// the same node.
+func (t *caLeafTracker) Record(leaf *structs.CALeaf) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.leaves[caLeafKey{leaf.Node, leaf.Service}] = leaf
+}
+
+// Leaves returns the leaves which are still valid, sorted by node and
+// service. Expired leaves are dropped.
+func (t *caLeafTracker) Leaves(now time.Time) []*structs.CALeaf {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	leaves := make([]*structs.CALeaf, 0, len(t.leaves))
+	for key, leaf := range t.leaves {
+		if !now.Before(leaf.ValidBefore) {
+			delete(t.leaves, key)
+			continue
+		}
+		leaves = append(leaves, leaf)
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		if leaves[i].Node != leaves[j].Node {
+			return leaves[i].Node < leaves[j].Node
+		}
+		return leaves[i].Service < leaves[j].Service
+	})
+	return leaves
+}
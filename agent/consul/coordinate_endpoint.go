@@ -134,13 +134,13 @@ func (c *Coordinate) Update(args *structs.CoordinateUpdateRequest, reply *struct
 	}
 
 	// Fetch the ACL token, if any, and enforce the node policy if enabled.
-	rule, err := c.srv.ResolveToken(args.Token)
+	rule, err := c.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && c.srv.config.ACLEnforceVersion8 {
 		if !rule.NodeWrite(args.Node, nil) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("node", args.Node, "write")
 		}
 	}
 
@@ -190,7 +190,7 @@ func (c *Coordinate) ListNodes(args *structs.DCSpecificRequest, reply *structs.I
 			}
 
 			reply.Index, reply.Coordinates = index, coords
-			if err := c.srv.filterACL(args.Token, reply); err != nil {
+			if err := c.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 
@@ -205,13 +205,13 @@ func (c *Coordinate) Node(args *structs.NodeSpecificRequest, reply *structs.Inde
 	}
 
 	// Fetch the ACL token, if any, and enforce the node policy if enabled.
-	rule, err := c.srv.ResolveToken(args.Token)
+	rule, err := c.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && c.srv.config.ACLEnforceVersion8 {
 		if !rule.NodeRead(args.Node) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("node", args.Node, "read")
 		}
 	}
 
@@ -27,6 +27,28 @@ func FilterDirEnt(authorizer acl.Authorizer, ent structs.DirEntries) structs.Dir
 	return ent[:FilterEntries(&df)]
 }
 
+type recycledDirEntFilter struct {
+	authorizer acl.Authorizer
+	ent        structs.RecycledDirEntries
+}
+
+func (d *recycledDirEntFilter) Len() int {
+	return len(d.ent)
+}
+func (d *recycledDirEntFilter) Filter(i int) bool {
+	return !d.authorizer.KeyRead(d.ent[i].Key)
+}
+func (d *recycledDirEntFilter) Move(dst, src, span int) {
+	copy(d.ent[dst:dst+span], d.ent[src:src+span])
+}
+
+// FilterRecycledDirEnt is used to filter a list of recycled directory
+// entries by applying an ACL policy
+func FilterRecycledDirEnt(authorizer acl.Authorizer, ent structs.RecycledDirEntries) structs.RecycledDirEntries {
+	df := recycledDirEntFilter{authorizer: authorizer, ent: ent}
+	return ent[:FilterEntries(&df)]
+}
+
 type keyFilter struct {
 	authorizer acl.Authorizer
 	keys       []string
@@ -28,6 +28,13 @@ func init() {
 	registerCommand(structs.ACLBootstrapRequestType, (*FSM).applyACLTokenBootstrap)
 	registerCommand(structs.ACLPolicyUpsertRequestType, (*FSM).applyACLPolicyUpsertOperation)
 	registerCommand(structs.ACLPolicyDeleteRequestType, (*FSM).applyACLPolicyDeleteOperation)
+	registerCommand(structs.KVSRecycleBinRequestType, (*FSM).applyKVSRecycleBinOperation)
+	registerCommand(structs.UIConfigRequestType, (*FSM).applyUIConfigUpdate)
+	registerCommand(structs.GossipKeyRotationRequestType, (*FSM).applyGossipKeyRotationUpdate)
+	registerCommand(structs.ACLAuthMethodUpsertRequestType, (*FSM).applyACLAuthMethodUpsertOperation)
+	registerCommand(structs.ACLAuthMethodDeleteRequestType, (*FSM).applyACLAuthMethodDeleteOperation)
+	registerCommand(structs.ACLBindingRuleUpsertRequestType, (*FSM).applyACLBindingRuleUpsertOperation)
+	registerCommand(structs.ACLBindingRuleDeleteRequestType, (*FSM).applyACLBindingRuleDeleteOperation)
 }
 
 func (c *FSM) applyRegister(buf []byte, index uint64) interface{} {
@@ -81,18 +88,31 @@ func (c *FSM) applyKVSOperation(buf []byte, index uint64) interface{} {
 	}
 	defer metrics.MeasureSinceWithLabels([]string{"fsm", "kvs"}, time.Now(),
 		[]metrics.Label{{Name: "op", Value: string(req.Op)}})
+	recycle := !req.RecycledAt.IsZero()
 	switch req.Op {
 	case api.KVSet:
 		return c.state.KVSSet(index, &req.DirEnt)
 	case api.KVDelete:
+		if recycle {
+			return c.state.KVSRecycle(index, req.DirEnt.Key, req.RecycledAt)
+		}
 		return c.state.KVSDelete(index, req.DirEnt.Key)
 	case api.KVDeleteCAS:
-		act, err := c.state.KVSDeleteCAS(index, req.DirEnt.ModifyIndex, req.DirEnt.Key)
+		var act bool
+		var err error
+		if recycle {
+			act, err = c.state.KVSRecycleCAS(index, req.DirEnt.ModifyIndex, req.DirEnt.Key, req.RecycledAt)
+		} else {
+			act, err = c.state.KVSDeleteCAS(index, req.DirEnt.ModifyIndex, req.DirEnt.Key)
+		}
 		if err != nil {
 			return err
 		}
 		return act
 	case api.KVDeleteTree:
+		if recycle {
+			return c.state.KVSRecycleTree(index, req.DirEnt.Key, req.RecycledAt)
+		}
 		return c.state.KVSDeleteTree(index, req.DirEnt.Key)
 	case api.KVCAS:
 		act, err := c.state.KVSSetCAS(index, &req.DirEnt)
@@ -119,6 +139,21 @@ func (c *FSM) applyKVSOperation(buf []byte, index uint64) interface{} {
 	}
 }
 
+func (c *FSM) applyKVSRecycleBinOperation(buf []byte, index uint64) interface{} {
+	var req structs.KVSRecycleBinRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSinceWithLabels([]string{"fsm", "kvs_recycle_bin"}, time.Now(),
+		[]metrics.Label{{Name: "op", Value: string(req.Op)}})
+	n, err := c.state.KVSRecycleBinApply(index, req.Op, req.Key, req.Recurse, req.DeletedBefore)
+	if err != nil {
+		c.logger.Printf("[WARN] consul.fsm: %v", err)
+		return err
+	}
+	return n
+}
+
 func (c *FSM) applySessionOperation(buf []byte, index uint64) interface{} {
 	var req structs.SessionRequest
 	if err := structs.Decode(buf, &req); err != nil {
@@ -269,6 +304,33 @@ func (c *FSM) applyAutopilotUpdate(buf []byte, index uint64) interface{} {
 	return c.state.AutopilotSetConfig(index, &req.Config)
 }
 
+func (c *FSM) applyUIConfigUpdate(buf []byte, index uint64) interface{} {
+	var req structs.UIConfigSetRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSince([]string{"fsm", "ui_config"}, time.Now())
+
+	if req.CAS {
+		act, err := c.state.UIConfigCAS(index, req.Config.ModifyIndex, &req.Config)
+		if err != nil {
+			return err
+		}
+		return act
+	}
+	return c.state.UIConfigSet(index, &req.Config)
+}
+
+func (c *FSM) applyGossipKeyRotationUpdate(buf []byte, index uint64) interface{} {
+	var req structs.GossipKeyRotationRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSince([]string{"fsm", "gossip_key_rotation"}, time.Now())
+
+	return c.state.GossipKeyRotationSet(index, &req.Rotation)
+}
+
 // applyIntentionOperation applies the given intention operation to the state store.
 func (c *FSM) applyIntentionOperation(buf []byte, index uint64) interface{} {
 	var req structs.IntentionRequest
@@ -404,3 +466,47 @@ func (c *FSM) applyACLPolicyDeleteOperation(buf []byte, index uint64) interface{
 
 	return c.state.ACLPoliciesDelete(index, req.PolicyIDs)
 }
+
+func (c *FSM) applyACLAuthMethodUpsertOperation(buf []byte, index uint64) interface{} {
+	var req structs.ACLAuthMethodBatchUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSinceWithLabels([]string{"fsm", "acl", "authmethod"}, time.Now(),
+		[]metrics.Label{{Name: "op", Value: "upsert"}})
+
+	return c.state.ACLAuthMethodsUpsert(index, req.AuthMethods)
+}
+
+func (c *FSM) applyACLAuthMethodDeleteOperation(buf []byte, index uint64) interface{} {
+	var req structs.ACLAuthMethodBatchDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSinceWithLabels([]string{"fsm", "acl", "authmethod"}, time.Now(),
+		[]metrics.Label{{Name: "op", Value: "delete"}})
+
+	return c.state.ACLAuthMethodsDelete(index, req.AuthMethodNames)
+}
+
+func (c *FSM) applyACLBindingRuleUpsertOperation(buf []byte, index uint64) interface{} {
+	var req structs.ACLBindingRuleBatchUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSinceWithLabels([]string{"fsm", "acl", "bindingrule"}, time.Now(),
+		[]metrics.Label{{Name: "op", Value: "upsert"}})
+
+	return c.state.ACLBindingRulesUpsert(index, req.BindingRules)
+}
+
+func (c *FSM) applyACLBindingRuleDeleteOperation(buf []byte, index uint64) interface{} {
+	var req structs.ACLBindingRuleBatchDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSinceWithLabels([]string{"fsm", "acl", "bindingrule"}, time.Now(),
+		[]metrics.Label{{Name: "op", Value: "delete"}})
+
+	return c.state.ACLBindingRulesDelete(index, req.BindingRuleIDs)
+}
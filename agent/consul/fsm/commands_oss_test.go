@@ -18,6 +18,7 @@ import (
 	"github.com/mitchellh/mapstructure"
 	"github.com/pascaldekloe/goe/verify"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func generateUUID() (ret string) {
@@ -393,6 +394,67 @@ func TestFSM_KVSDelete(t *testing.T) {
 	}
 }
 
+func TestFSM_KVSRecycleBin(t *testing.T) {
+	t.Parallel()
+	fsm, err := New(nil, os.Stderr)
+	require.NoError(t, err)
+
+	req := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "/test/path",
+			Flags: 42,
+			Value: []byte("test"),
+		},
+	}
+	buf, err := structs.Encode(structs.KVSRequestType, req)
+	require.NoError(t, err)
+	require.Nil(t, fsm.Apply(makeLog(buf)))
+
+	// Run a delete which moves the key to the recycle bin.
+	req.Op = api.KVDelete
+	req.RecycledAt = time.Now().UTC()
+	buf, err = structs.Encode(structs.KVSRequestType, req)
+	require.NoError(t, err)
+	require.Nil(t, fsm.Apply(makeLog(buf)))
+
+	_, d, err := fsm.state.KVSGet(nil, "/test/path")
+	require.NoError(t, err)
+	require.Nil(t, d)
+	_, recycled, err := fsm.state.KVSRecycled(nil, "/test")
+	require.NoError(t, err)
+	require.Len(t, recycled, 1)
+
+	// Restore the key.
+	binReq := structs.KVSRecycleBinRequest{
+		Datacenter: "dc1",
+		Op:         structs.KVSRecycleBinRestore,
+		Key:        "/test/path",
+	}
+	buf, err = structs.Encode(structs.KVSRecycleBinRequestType, binReq)
+	require.NoError(t, err)
+	require.Equal(t, 1, fsm.Apply(makeLog(buf)))
+
+	_, d, err = fsm.state.KVSGet(nil, "/test/path")
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	require.Equal(t, uint64(42), d.Flags)
+	require.Equal(t, []byte("test"), d.Value)
+	_, recycled, err = fsm.state.KVSRecycled(nil, "/test")
+	require.NoError(t, err)
+	require.Len(t, recycled, 0)
+
+	// An invalid operation returns an error.
+	binReq.Op = "nope"
+	buf, err = structs.Encode(structs.KVSRecycleBinRequestType, binReq)
+	require.NoError(t, err)
+	resp := fsm.Apply(makeLog(buf))
+	if _, ok := resp.(error); !ok {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
 func TestFSM_KVSDeleteTree(t *testing.T) {
 	t.Parallel()
 	fsm, err := New(nil, os.Stderr)
@@ -1150,6 +1212,61 @@ func TestFSM_Autopilot(t *testing.T) {
 	}
 }
 
+func TestFSM_UIConfig(t *testing.T) {
+	t.Parallel()
+	fsm, err := New(nil, os.Stderr)
+	require.NoError(t, err)
+
+	// Set the UI config using a request.
+	req := structs.UIConfigSetRequest{
+		Datacenter: "dc1",
+		Config: structs.UIConfig{
+			BannerMessage: "hello",
+		},
+	}
+	buf, err := structs.Encode(structs.UIConfigRequestType, req)
+	require.NoError(t, err)
+	require.Nil(t, fsm.Apply(makeLog(buf)))
+
+	_, config, err := fsm.state.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "hello", config.BannerMessage)
+
+	// Now use CAS and provide an old index
+	req.CAS = true
+	req.Config.BannerMessage = "world"
+	req.Config.ModifyIndex = config.ModifyIndex - 1
+	buf, err = structs.Encode(structs.UIConfigRequestType, req)
+	require.NoError(t, err)
+	require.Equal(t, false, fsm.Apply(makeLog(buf)))
+
+	_, config, err = fsm.state.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "hello", config.BannerMessage)
+}
+
+func TestFSM_GossipKeyRotation(t *testing.T) {
+	t.Parallel()
+	fsm, err := New(nil, os.Stderr)
+	require.NoError(t, err)
+
+	req := structs.GossipKeyRotationRequest{
+		Datacenter: "dc1",
+		Rotation: structs.GossipKeyRotation{
+			Phase:  structs.GossipKeyRotationDistributing,
+			NewKey: "H1dfkSZOVnP/JUnaBfTzXg==",
+		},
+	}
+	buf, err := structs.Encode(structs.GossipKeyRotationRequestType, req)
+	require.NoError(t, err)
+	require.Nil(t, fsm.Apply(makeLog(buf)))
+
+	_, rotation, err := fsm.state.GossipKeyRotation(nil)
+	require.NoError(t, err)
+	require.Equal(t, structs.GossipKeyRotationDistributing, rotation.Phase)
+	require.Equal(t, "H1dfkSZOVnP/JUnaBfTzXg==", rotation.NewKey)
+}
+
 func TestFSM_Intention_CRUD(t *testing.T) {
 	t.Parallel()
 
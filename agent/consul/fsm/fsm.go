@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/consul/agent/consul/state"
@@ -60,6 +61,14 @@ type FSM struct {
 	state     *state.Store
 
 	gc *state.TombstoneGC
+
+	// virtualIPMaxOffset bounds the virtual IPs allocated by the state
+	// store, and is kept for the state stores created by Restore.
+	virtualIPMaxOffset uint64
+
+	// lastSnapshot is the time of the last snapshot in Unix nanoseconds.
+	// It is updated atomically.
+	lastSnapshot int64
 }
 
 // New is used to construct a new FSM with a blank state.
@@ -88,6 +97,13 @@ func New(gc *state.TombstoneGC, logOutput io.Writer) (*FSM, error) {
 	return fsm, nil
 }
 
+// SetVirtualIPMaxOffset bounds the offsets of the virtual IPs allocated to
+// services. It has to be called before any log is applied.
+func (c *FSM) SetVirtualIPMaxOffset(max uint64) {
+	c.virtualIPMaxOffset = max
+	c.state.SetVirtualIPMaxOffset(max)
+}
+
 // State is used to return a handle to the current state
 func (c *FSM) State() *state.Store {
 	c.stateLock.RLock()
@@ -127,9 +143,20 @@ func (c *FSM) Snapshot() (raft.FSMSnapshot, error) {
 		c.logger.Printf("[INFO] consul.fsm: snapshot created in %v", time.Since(start))
 	}(time.Now())
 
+	atomic.StoreInt64(&c.lastSnapshot, time.Now().UnixNano())
 	return &snapshot{c.state.Snapshot()}, nil
 }
 
+// LastSnapshot returns when the last snapshot was taken, or the zero time if
+// none was taken since the server started.
+func (c *FSM) LastSnapshot() time.Time {
+	ns := atomic.LoadInt64(&c.lastSnapshot)
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
 // Restore streams in the snapshot and replaces the current state store with a
 // new one based on the snapshot if all goes OK during the restore.
 func (c *FSM) Restore(old io.ReadCloser) error {
@@ -140,40 +167,15 @@ func (c *FSM) Restore(old io.ReadCloser) error {
 	if err != nil {
 		return err
 	}
+	stateNew.SetVirtualIPMaxOffset(c.virtualIPMaxOffset)
 
 	// Set up a new restore transaction
 	restore := stateNew.Restore()
 	defer restore.Abort()
 
-	// Create a decoder
-	dec := codec.NewDecoder(old, msgpackHandle)
-
-	// Read in the header
-	var header snapshotHeader
-	if err := dec.Decode(&header); err != nil {
-		return err
-	}
-
 	// Populate the new state
-	msgType := make([]byte, 1)
-	for {
-		// Read the message type
-		_, err := old.Read(msgType)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-
-		// Decode
-		msg := structs.MessageType(msgType[0])
-		if fn := restorers[msg]; fn != nil {
-			if err := fn(&header, restore, dec); err != nil {
-				return err
-			}
-		} else {
-			return fmt.Errorf("Unrecognized msg type %d", msg)
-		}
+	if _, err := restoreRecords(old, restore); err != nil {
+		return err
 	}
 	restore.Commit()
 
@@ -2,6 +2,7 @@ package fsm
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -82,3 +83,68 @@ func (s *snapshot) Persist(sink raft.SnapshotSink) error {
 func (s *snapshot) Release() {
 	s.state.Close()
 }
+
+// restoreRecords decodes the records of a snapshot into the restore
+// transaction. It returns the number of records of each message type.
+func restoreRecords(in io.Reader, restore *state.Restore) (map[structs.MessageType]int, error) {
+	// Create a decoder
+	dec := codec.NewDecoder(in, msgpackHandle)
+
+	// Read in the header
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[structs.MessageType]int)
+	msgType := make([]byte, 1)
+	for {
+		// Read the message type
+		_, err := in.Read(msgType)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Decode
+		msg := structs.MessageType(msgType[0])
+		if fn := restorers[msg]; fn != nil {
+			if err := fn(&header, restore, dec); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("Unrecognized msg type %d", msg)
+		}
+		counts[msg]++
+	}
+	return counts, nil
+}
+
+// Verify decodes the state of a snapshot into a scratch state store, which
+// is thrown away afterwards, to check that it can be restored by this
+// version without touching the live state. It returns the number of records
+// of each kind.
+func Verify(in io.Reader) (map[string]int, error) {
+	stateNew, err := state.NewStateStore(nil)
+	if err != nil {
+		return nil, err
+	}
+	restore := stateNew.Restore()
+	defer restore.Abort()
+
+	counts, err := restoreRecords(in, restore)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]int, len(counts))
+	for msg, n := range counts {
+		name, ok := recordNames[msg]
+		if !ok {
+			name = fmt.Sprintf("Type%d", msg)
+		}
+		records[name] += n
+	}
+	return records, nil
+}
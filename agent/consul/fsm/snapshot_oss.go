@@ -14,12 +14,15 @@ func init() {
 	registerRestorer(structs.RegisterRequestType, restoreRegistration)
 	registerRestorer(structs.KVSRequestType, restoreKV)
 	registerRestorer(structs.TombstoneRequestType, restoreTombstone)
+	registerRestorer(structs.KVSRecycleBinRequestType, restoreRecycledKV)
 	registerRestorer(structs.SessionRequestType, restoreSession)
 	registerRestorer(structs.ACLRequestType, restoreACL)
 	registerRestorer(structs.ACLBootstrapRequestType, restoreACLBootstrap)
 	registerRestorer(structs.CoordinateBatchUpdateType, restoreCoordinates)
 	registerRestorer(structs.PreparedQueryRequestType, restorePreparedQuery)
 	registerRestorer(structs.AutopilotRequestType, restoreAutopilot)
+	registerRestorer(structs.UIConfigRequestType, restoreUIConfig)
+	registerRestorer(structs.GossipKeyRotationRequestType, restoreGossipKeyRotation)
 	registerRestorer(structs.IntentionRequestType, restoreIntention)
 	registerRestorer(structs.ConnectCARequestType, restoreConnectCA)
 	registerRestorer(structs.ConnectCAProviderStateType, restoreConnectCAProviderState)
@@ -27,9 +30,44 @@ func init() {
 	registerRestorer(structs.IndexRequestType, restoreIndex)
 	registerRestorer(structs.ACLTokenUpsertRequestType, restoreToken)
 	registerRestorer(structs.ACLPolicyUpsertRequestType, restorePolicy)
+	registerRestorer(structs.ACLAuthMethodUpsertRequestType, restoreAuthMethod)
+	registerRestorer(structs.ACLBindingRuleUpsertRequestType, restoreBindingRule)
+	registerRestorer(structs.ServiceVirtualIPType, restoreServiceVirtualIP)
+}
+
+// recordNames are the names of the snapshot records in the summary of
+// Verify.
+var recordNames = map[structs.MessageType]string{
+	structs.RegisterRequestType:             "Registrations",
+	structs.KVSRequestType:                  "KVEntries",
+	structs.TombstoneRequestType:            "Tombstones",
+	structs.KVSRecycleBinRequestType:        "RecycledKVEntries",
+	structs.SessionRequestType:              "Sessions",
+	structs.ACLRequestType:                  "LegacyACLs",
+	structs.ACLBootstrapRequestType:         "ACLBootstrap",
+	structs.CoordinateBatchUpdateType:       "Coordinates",
+	structs.PreparedQueryRequestType:        "PreparedQueries",
+	structs.AutopilotRequestType:            "AutopilotConfig",
+	structs.UIConfigRequestType:             "UIConfig",
+	structs.GossipKeyRotationRequestType:    "GossipKeyRotations",
+	structs.IntentionRequestType:            "Intentions",
+	structs.ConnectCARequestType:            "ConnectCARoots",
+	structs.ConnectCAProviderStateType:      "ConnectCAProviderStates",
+	structs.ConnectCAConfigType:             "ConnectCAConfig",
+	structs.IndexRequestType:                "Indexes",
+	structs.ACLTokenUpsertRequestType:       "ACLTokens",
+	structs.ACLPolicyUpsertRequestType:      "ACLPolicies",
+	structs.ACLAuthMethodUpsertRequestType:  "ACLAuthMethods",
+	structs.ACLBindingRuleUpsertRequestType: "ACLBindingRules",
+	structs.ServiceVirtualIPType:            "ServiceVirtualIPs",
 }
 
 func persistOSS(s *snapshot, sink raft.SnapshotSink, encoder *codec.Encoder) error {
+	// The virtual IPs are restored before the services, which would
+	// otherwise be allocated new ones.
+	if err := s.persistServiceVirtualIPs(sink, encoder); err != nil {
+		return err
+	}
 	if err := s.persistNodes(sink, encoder); err != nil {
 		return err
 	}
@@ -45,12 +83,21 @@ func persistOSS(s *snapshot, sink raft.SnapshotSink, encoder *codec.Encoder) err
 	if err := s.persistTombstones(sink, encoder); err != nil {
 		return err
 	}
+	if err := s.persistRecycledKVs(sink, encoder); err != nil {
+		return err
+	}
 	if err := s.persistPreparedQueries(sink, encoder); err != nil {
 		return err
 	}
 	if err := s.persistAutopilot(sink, encoder); err != nil {
 		return err
 	}
+	if err := s.persistUIConfig(sink, encoder); err != nil {
+		return err
+	}
+	if err := s.persistGossipKeyRotation(sink, encoder); err != nil {
+		return err
+	}
 	if err := s.persistIntentions(sink, encoder); err != nil {
 		return err
 	}
@@ -195,6 +242,34 @@ func (s *snapshot) persistACLs(sink raft.SnapshotSink,
 		}
 	}
 
+	methods, err := s.state.ACLAuthMethods()
+	if err != nil {
+		return err
+	}
+
+	for method := methods.Next(); method != nil; method = methods.Next() {
+		if _, err := sink.Write([]byte{byte(structs.ACLAuthMethodUpsertRequestType)}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(method.(*structs.ACLAuthMethod)); err != nil {
+			return err
+		}
+	}
+
+	rules, err := s.state.ACLBindingRules()
+	if err != nil {
+		return err
+	}
+
+	for rule := rules.Next(); rule != nil; rule = rules.Next() {
+		if _, err := sink.Write([]byte{byte(structs.ACLBindingRuleUpsertRequestType)}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(rule.(*structs.ACLBindingRule)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -216,6 +291,24 @@ func (s *snapshot) persistKVs(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *snapshot) persistRecycledKVs(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	entries, err := s.state.RecycledKVs()
+	if err != nil {
+		return err
+	}
+
+	for entry := entries.Next(); entry != nil; entry = entries.Next() {
+		if _, err := sink.Write([]byte{byte(structs.KVSRecycleBinRequestType)}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(entry.(*structs.RecycledDirEntry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *snapshot) persistTombstones(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
 	stones, err := s.state.Tombstones()
@@ -282,6 +375,62 @@ func (s *snapshot) persistAutopilot(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *snapshot) persistServiceVirtualIPs(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	vips, err := s.state.ServiceVirtualIPs()
+	if err != nil {
+		return err
+	}
+
+	for vip := vips.Next(); vip != nil; vip = vips.Next() {
+		if _, err := sink.Write([]byte{byte(structs.ServiceVirtualIPType)}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(vip.(*structs.ServiceVirtualIP)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *snapshot) persistUIConfig(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	config, err := s.state.UIConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	if _, err := sink.Write([]byte{byte(structs.UIConfigRequestType)}); err != nil {
+		return err
+	}
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *snapshot) persistGossipKeyRotation(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	rotation, err := s.state.GossipKeyRotation()
+	if err != nil {
+		return err
+	}
+	if rotation == nil {
+		return nil
+	}
+
+	if _, err := sink.Write([]byte{byte(structs.GossipKeyRotationRequestType)}); err != nil {
+		return err
+	}
+	if err := encoder.Encode(rotation); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (s *snapshot) persistConnectCA(sink raft.SnapshotSink,
 	encoder *codec.Encoder) error {
 	roots, err := s.state.CARoots()
@@ -395,6 +544,17 @@ func restoreKV(header *snapshotHeader, restore *state.Restore, decoder *codec.De
 	return nil
 }
 
+func restoreRecycledKV(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.RecycledDirEntry
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	if err := restore.RecycledKVS(&req); err != nil {
+		return err
+	}
+	return nil
+}
+
 func restoreTombstone(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
 	var req structs.DirEntry
 	if err := decoder.Decode(&req); err != nil {
@@ -482,6 +642,39 @@ func restoreAutopilot(header *snapshotHeader, restore *state.Restore, decoder *c
 	return nil
 }
 
+func restoreServiceVirtualIP(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.ServiceVirtualIP
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	if err := restore.ServiceVirtualIP(&req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func restoreUIConfig(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.UIConfig
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	if err := restore.UIConfig(&req); err != nil {
+		return err
+	}
+	return nil
+}
+
+func restoreGossipKeyRotation(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.GossipKeyRotation
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	if err := restore.GossipKeyRotation(&req); err != nil {
+		return err
+	}
+	return nil
+}
+
 func restoreIntention(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
 	var req structs.Intention
 	if err := decoder.Decode(&req); err != nil {
@@ -549,3 +742,19 @@ func restorePolicy(header *snapshotHeader, restore *state.Restore, decoder *code
 	}
 	return restore.ACLPolicy(&req)
 }
+
+func restoreAuthMethod(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.ACLAuthMethod
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	return restore.ACLAuthMethod(&req)
+}
+
+func restoreBindingRule(header *snapshotHeader, restore *state.Restore, decoder *codec.Decoder) error {
+	var req structs.ACLBindingRule
+	if err := decoder.Decode(&req); err != nil {
+		return err
+	}
+	return restore.ACLBindingRule(&req)
+}
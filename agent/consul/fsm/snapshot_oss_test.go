@@ -94,11 +94,32 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 	}
 	require.NoError(t, fsm.state.ACLBootstrap(10, 0, token, false))
 
+	method := &structs.ACLAuthMethod{
+		Name:        "some-method",
+		Type:        "testing",
+		Description: "test auth method",
+		Config: map[string]interface{}{
+			"SessionID": "952ebfa8-2a42-46f0-bcd3-fd98a842000e",
+		},
+	}
+	require.NoError(t, fsm.state.ACLAuthMethodSet(10, method))
+
+	bindingRule := &structs.ACLBindingRule{
+		ID:          "85184c52-5997-4a84-9817-5945f2632a17",
+		Description: "test binding rule",
+		AuthMethod:  method.Name,
+		Selector:    "serviceaccount.namespace == default",
+		BindType:    structs.BindingRuleBindTypeService,
+		BindName:    "${serviceaccount.name}",
+	}
+	require.NoError(t, fsm.state.ACLBindingRuleSet(10, bindingRule))
+
 	fsm.state.KVSSet(11, &structs.DirEntry{
 		Key:   "/remove",
 		Value: []byte("foo"),
 	})
-	fsm.state.KVSDelete(12, "/remove")
+	deletedAt := time.Now().UTC().Round(0)
+	fsm.state.KVSRecycle(12, "/remove", deletedAt)
 	idx, _, err := fsm.state.KVSList(nil, "/remove")
 	if err != nil {
 		t.Fatalf("err: %s", err)
@@ -144,6 +165,17 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
+	uiConf := &structs.UIConfig{
+		DashboardURLTemplates: map[string]string{"*": "https://grafana/{{Service}}"},
+	}
+	require.NoError(t, fsm.state.UIConfigSet(15, uiConf))
+
+	rotation := &structs.GossipKeyRotation{
+		Phase:        structs.GossipKeyRotationIdle,
+		LastRotation: time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, fsm.state.GossipKeyRotationSet(15, rotation))
+
 	// Intentions
 	ixn := structs.TestIntention(t)
 	ixn.ID = generateUUID()
@@ -186,11 +218,13 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 	assert.Nil(err)
 
 	// Snapshot
+	assert.True(fsm.LastSnapshot().IsZero())
 	snap, err := fsm.Snapshot()
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 	defer snap.Release()
+	assert.False(fsm.LastSnapshot().IsZero())
 
 	// Persist
 	buf := bytes.NewBuffer(nil)
@@ -250,6 +284,15 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		t.Fatalf("got: %v, want: %v", connectSrv.Connect, connectConf)
 	}
 
+	// Verify the virtual IPs are restored as allocated, rather than in
+	// the order the services are restored in.
+	_, webVIP, err := fsm2.state.ServiceVirtualIP(nil, "web")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), webVIP.Offset)
+	_, dbVIP, err := fsm2.state.ServiceVirtualIP(nil, "db")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), dbVIP.Offset)
+
 	_, checks, err := fsm2.state.NodeChecks(nil, "foo")
 	if err != nil {
 		t.Fatalf("err: %s", err)
@@ -295,6 +338,18 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, policy.Name, policy2.Name)
 
+	// Verify ACL Auth Method is restored
+	_, method2, err := fsm2.state.ACLAuthMethodGetByName(nil, method.Name)
+	require.NoError(t, err)
+	require.Equal(t, method.Name, method2.Name)
+	require.Equal(t, method.Type, method2.Type)
+	require.Len(t, method2.Config, 1)
+
+	// Verify ACL Binding Rule is restored
+	_, bindingRule2, err := fsm2.state.ACLBindingRuleGetByID(nil, bindingRule.ID)
+	require.NoError(t, err)
+	require.Equal(t, bindingRule, bindingRule2)
+
 	// Verify tombstones are restored
 	func() {
 		snap := fsm2.state.Snapshot()
@@ -315,6 +370,15 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		}
 	}()
 
+	// Verify the recycle bin is restored
+	_, recycled, err := fsm2.state.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Len(t, recycled, 1)
+	require.Equal(t, "/remove", recycled[0].Key)
+	require.Equal(t, []byte("foo"), recycled[0].Value)
+	require.Equal(t, uint64(12), recycled[0].DeleteIndex)
+	require.True(t, deletedAt.Equal(recycled[0].DeletedAt))
+
 	// Verify coordinates are restored
 	_, coords, err := fsm2.state.Coordinates(nil)
 	if err != nil {
@@ -345,6 +409,16 @@ func TestFSM_SnapshotRestore_OSS(t *testing.T) {
 		t.Fatalf("bad: %#v, %#v", restoredConf, autopilotConf)
 	}
 
+	// Verify UI config is restored.
+	_, restoredUIConf, err := fsm2.state.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, uiConf, restoredUIConf)
+
+	// Verify the gossip key rotation is restored.
+	_, restoredRotation, err := fsm2.state.GossipKeyRotation(nil)
+	require.NoError(t, err)
+	require.Equal(t, rotation, restoredRotation)
+
 	// Verify intentions are restored.
 	_, ixns, err := fsm2.state.Intentions(nil)
 	assert.Nil(err)
@@ -432,3 +506,41 @@ func TestFSM_BadRestore_OSS(t *testing.T) {
 	default:
 	}
 }
+
+func TestFSM_Verify_OSS(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	fsm, err := New(nil, os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Add some state.
+	fsm.state.EnsureNode(1, &structs.Node{Node: "foo", Address: "127.0.0.1"})
+	fsm.state.KVSSet(2, &structs.DirEntry{Key: "/test", Value: []byte("foo")})
+	fsm.state.KVSSet(3, &structs.DirEntry{Key: "/test2", Value: []byte("bar")})
+
+	// Snapshot
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Release()
+
+	// Persist
+	buf := bytes.NewBuffer(nil)
+	sink := &MockSink{buf, false}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Verify and check the summary.
+	records, err := Verify(sink)
+	assert.Nil(err)
+	assert.Equal(1, records["Registrations"])
+	assert.Equal(2, records["KVEntries"])
+
+	// A bad snapshot should fail to verify.
+	_, err = Verify(bytes.NewBuffer([]byte("bad snapshot")))
+	assert.NotNil(err)
+}
@@ -0,0 +1,292 @@
+package consul
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+var (
+	// gossipKeyRotationCheckInterval is how often the leader checks whether
+	// the next step of the gossip key rotation is due.
+	gossipKeyRotationCheckInterval = time.Minute
+)
+
+// gossipKeyRotationConfigured returns true if this server rotates the gossip
+// keys when it is the leader. Rotations are driven by the primary datacenter
+// since keyring operations apply to all datacenters.
+func (s *Server) gossipKeyRotationConfigured() bool {
+	primary := s.config.PrimaryDatacenter == "" || s.config.PrimaryDatacenter == s.config.Datacenter
+	return s.config.GossipKeyRotationInterval > 0 && primary && s.Encrypted()
+}
+
+// startGossipKeyRotation starts the goroutine which rotates the gossip keys
+// on the configured schedule.
+func (s *Server) startGossipKeyRotation() {
+	s.gossipKeyRotationLock.Lock()
+	defer s.gossipKeyRotationLock.Unlock()
+
+	if s.gossipKeyRotationEnabled || !s.gossipKeyRotationConfigured() {
+		return
+	}
+
+	s.gossipKeyRotationCh = make(chan struct{})
+
+	go func(stopCh chan struct{}) {
+		ticker := time.NewTicker(gossipKeyRotationCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.rotateGossipKey(time.Now().UTC()); err != nil {
+				s.setGossipKeyRotationError(err)
+				s.logger.Printf("[ERR] consul: error rotating gossip key (will retry): %v", err)
+			}
+
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}(s.gossipKeyRotationCh)
+
+	s.gossipKeyRotationEnabled = true
+}
+
+// stopGossipKeyRotation stops the gossip key rotation. A rotation in
+// progress is continued by the next leader.
+func (s *Server) stopGossipKeyRotation() {
+	s.gossipKeyRotationLock.Lock()
+	defer s.gossipKeyRotationLock.Unlock()
+
+	if !s.gossipKeyRotationEnabled {
+		return
+	}
+
+	close(s.gossipKeyRotationCh)
+	s.gossipKeyRotationEnabled = false
+}
+
+// rotateGossipKey performs the steps of the gossip key rotation which are
+// due at the given time. A rotation generates a new key, installs it on all
+// nodes, makes it the primary key once every node has it and removes the
+// previous primary key after the retirement delay. The state is stored in
+// Raft after every step so that failed steps can be retried.
+func (s *Server) rotateGossipKey(now time.Time) error {
+	_, rotation, err := s.fsm.State().GossipKeyRotation(nil)
+	if err != nil {
+		return err
+	}
+	if rotation == nil {
+		// The schedule starts when the rotation is enabled.
+		return s.setGossipKeyRotation(&structs.GossipKeyRotation{
+			Phase:        structs.GossipKeyRotationIdle,
+			PhaseStarted: now,
+			LastRotation: now,
+		})
+	}
+	next := *rotation
+
+	switch rotation.Phase {
+	case structs.GossipKeyRotationIdle:
+		if now.Before(rotation.LastRotation.Add(s.config.GossipKeyRotationInterval)) {
+			return nil
+		}
+		key, err := s.generateGossipKey()
+		if err != nil {
+			return err
+		}
+		next.Phase = structs.GossipKeyRotationDistributing
+		next.PhaseStarted = now
+		next.NewKey = key
+		next.OldKey = ""
+		if err := s.setGossipKeyRotation(&next); err != nil {
+			return err
+		}
+		s.logger.Printf("[INFO] consul: started gossip key rotation")
+		fallthrough
+
+	case structs.GossipKeyRotationDistributing:
+		if _, err := s.gossipKeyringOperation(structs.KeyringInstall, next.NewKey); err != nil {
+			return fmt.Errorf("failed to install the new key: %v", err)
+		}
+		list, err := s.gossipKeyringOperation(structs.KeyringList, "")
+		if err != nil {
+			return fmt.Errorf("failed to list the keys: %v", err)
+		}
+		primaryKeys := make(map[string]int)
+		for _, resp := range list.Responses {
+			if resp.Keys[next.NewKey] != resp.NumNodes {
+				return fmt.Errorf("the new key is installed on %d/%d nodes of the %s pool",
+					resp.Keys[next.NewKey], resp.NumNodes, keyringPoolName(resp))
+			}
+			for key, count := range resp.PrimaryKeys {
+				primaryKeys[key] += count
+			}
+		}
+
+		// Remember the key to retire before switching, a new leader
+		// wouldn't know it afterwards.
+		if next.OldKey == "" {
+			for key, count := range primaryKeys {
+				if key != next.NewKey && count > primaryKeys[next.OldKey] {
+					next.OldKey = key
+				}
+			}
+			if err := s.setGossipKeyRotation(&next); err != nil {
+				return err
+			}
+		}
+
+		if _, err := s.gossipKeyringOperation(structs.KeyringUse, next.NewKey); err != nil {
+			return fmt.Errorf("failed to make the new key primary: %v", err)
+		}
+		next.Phase = structs.GossipKeyRotationPromoted
+		next.PhaseStarted = now
+		if err := s.setGossipKeyRotation(&next); err != nil {
+			return err
+		}
+		s.logger.Printf("[INFO] consul: new gossip key is the primary key, previous key retires in %v",
+			s.config.GossipKeyRotationRetireAfter)
+
+	case structs.GossipKeyRotationPromoted:
+		if now.Before(rotation.PhaseStarted.Add(s.config.GossipKeyRotationRetireAfter)) {
+			return nil
+		}
+		if next.OldKey != "" && next.OldKey != next.NewKey {
+			if _, err := s.gossipKeyringOperation(structs.KeyringRemove, next.OldKey); err != nil {
+				return fmt.Errorf("failed to remove the previous key: %v", err)
+			}
+		}
+		next.Phase = structs.GossipKeyRotationIdle
+		next.PhaseStarted = now
+		next.LastRotation = now
+		next.NewKey = ""
+		next.OldKey = ""
+		if err := s.setGossipKeyRotation(&next); err != nil {
+			return err
+		}
+		metrics.IncrCounter([]string{"leader", "gossip_key_rotation"}, 1)
+		s.logger.Printf("[INFO] consul: finished gossip key rotation")
+
+	default:
+		return fmt.Errorf("unknown gossip key rotation phase %q", rotation.Phase)
+	}
+
+	s.setGossipKeyRotationError(nil)
+	return nil
+}
+
+// generateGossipKey returns a new random gossip key with the length of the
+// current primary key.
+func (s *Server) generateGossipKey() (string, error) {
+	size := 16
+	if keyring := s.config.SerfLANConfig.MemberlistConfig.Keyring; keyring != nil {
+		if primary := keyring.GetPrimaryKey(); len(primary) > 0 {
+			size = len(primary)
+		}
+	}
+
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// gossipKeyringOperation performs a keyring operation on the gossip pools of
+// all datacenters with the agent token. It returns an error if any pool
+// reported an error.
+func (s *Server) gossipKeyringOperation(op structs.KeyringOp, key string) (*structs.KeyringResponses, error) {
+	args := structs.KeyringRequest{
+		Operation:    op,
+		Key:          key,
+		Datacenter:   s.config.Datacenter,
+		QueryOptions: structs.QueryOptions{Token: s.tokens.AgentToken()},
+	}
+	var reply structs.KeyringResponses
+	if err := s.RPC("Internal.KeyringOperation", &args, &reply); err != nil {
+		return nil, err
+	}
+	for _, resp := range reply.Responses {
+		if resp.Error != "" {
+			return nil, fmt.Errorf("%s pool: %s", keyringPoolName(resp), resp.Error)
+		}
+	}
+	return &reply, nil
+}
+
+// keyringPoolName returns the name of the gossip pool of a keyring response
+// for errors.
+func keyringPoolName(resp *structs.KeyringResponse) string {
+	if resp.WAN {
+		return "WAN"
+	}
+	if resp.Segment != "" {
+		return fmt.Sprintf("%s (LAN segment %s)", resp.Datacenter, resp.Segment)
+	}
+	return resp.Datacenter + " (LAN)"
+}
+
+// setGossipKeyRotation stores the state of the gossip key rotation.
+func (s *Server) setGossipKeyRotation(rotation *structs.GossipKeyRotation) error {
+	req := structs.GossipKeyRotationRequest{
+		Datacenter: s.config.Datacenter,
+		Rotation:   *rotation,
+	}
+	resp, err := s.raftApply(structs.GossipKeyRotationRequestType, &req)
+	if err != nil {
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	return nil
+}
+
+// setGossipKeyRotationError records the last error of the rotation, or
+// clears it after a successful step.
+func (s *Server) setGossipKeyRotationError(err error) {
+	s.gossipKeyRotationStatusLock.Lock()
+	defer s.gossipKeyRotationStatusLock.Unlock()
+
+	if err == nil {
+		s.gossipKeyRotationLastError = ""
+		return
+	}
+	s.gossipKeyRotationLastError = err.Error()
+	s.gossipKeyRotationLastErrorTime = time.Now().Round(time.Second).UTC()
+}
+
+// getGossipKeyRotationStatus returns the status of the gossip key rotation.
+func (s *Server) getGossipKeyRotationStatus() (*structs.GossipKeyRotationStatus, error) {
+	status := &structs.GossipKeyRotationStatus{
+		Enabled:     s.gossipKeyRotationConfigured(),
+		Interval:    s.config.GossipKeyRotationInterval,
+		RetireAfter: s.config.GossipKeyRotationRetireAfter,
+	}
+	if !status.Enabled {
+		return status, nil
+	}
+
+	_, rotation, err := s.fsm.State().GossipKeyRotation(nil)
+	if err != nil {
+		return nil, err
+	}
+	if rotation != nil {
+		status.Phase = rotation.Phase
+		status.PhaseStarted = rotation.PhaseStarted
+		status.LastRotation = rotation.LastRotation
+		status.NextRotation = rotation.LastRotation.Add(s.config.GossipKeyRotationInterval)
+	}
+
+	s.gossipKeyRotationStatusLock.RLock()
+	status.LastError = s.gossipKeyRotationLastError
+	status.LastErrorTime = s.gossipKeyRotationLastErrorTime
+	s.gossipKeyRotationStatusLock.RUnlock()
+	return status, nil
+}
@@ -0,0 +1,110 @@
+package consul
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGossipKeyRotation(t *testing.T) {
+	t.Parallel()
+	key := "H1dfkSZOVnP/JUnaBfTzXg=="
+	keyBytes, err := base64.StdEncoding.DecodeString(key)
+	require.NoError(t, err)
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.SerfLANConfig.MemberlistConfig.SecretKey = keyBytes
+		c.SerfWANConfig.MemberlistConfig.SecretKey = keyBytes
+		c.GossipKeyRotationInterval = time.Hour
+		c.GossipKeyRotationRetireAfter = 10 * time.Minute
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// The leader starts the schedule.
+	retry.Run(t, func(r *retry.R) {
+		_, rotation, err := s1.fsm.State().GossipKeyRotation(nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if rotation == nil || rotation.Phase != structs.GossipKeyRotationIdle {
+			r.Fatalf("bad: %#v", rotation)
+		}
+	})
+
+	listKeys := func() *structs.KeyringResponses {
+		list, err := s1.gossipKeyringOperation(structs.KeyringList, "")
+		require.NoError(t, err)
+		require.Len(t, list.Responses, 2)
+		return list
+	}
+
+	// Nothing happens before the interval passed.
+	now := time.Now().UTC()
+	require.NoError(t, s1.rotateGossipKey(now))
+	for _, resp := range listKeys().Responses {
+		require.Equal(t, map[string]int{key: 1}, resp.Keys)
+	}
+
+	// The new key is installed and made primary.
+	require.NoError(t, s1.rotateGossipKey(now.Add(2*time.Hour)))
+	_, rotation, err := s1.fsm.State().GossipKeyRotation(nil)
+	require.NoError(t, err)
+	require.Equal(t, structs.GossipKeyRotationPromoted, rotation.Phase)
+	require.Equal(t, key, rotation.OldKey)
+	newKey := rotation.NewKey
+	require.NotEmpty(t, newKey)
+	for _, resp := range listKeys().Responses {
+		require.Equal(t, map[string]int{key: 1, newKey: 1}, resp.Keys)
+		require.Equal(t, map[string]int{newKey: 1}, resp.PrimaryKeys)
+	}
+
+	// The previous key is kept until the retirement delay passed.
+	require.NoError(t, s1.rotateGossipKey(now.Add(2*time.Hour+5*time.Minute)))
+	for _, resp := range listKeys().Responses {
+		require.Len(t, resp.Keys, 2)
+	}
+
+	finished := now.Add(2*time.Hour + 11*time.Minute)
+	require.NoError(t, s1.rotateGossipKey(finished))
+	for _, resp := range listKeys().Responses {
+		require.Equal(t, map[string]int{newKey: 1}, resp.Keys)
+	}
+
+	args := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var status structs.GossipKeyRotationStatus
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.KeyringRotationStatus", &args, &status))
+	require.True(t, status.Enabled)
+	require.Equal(t, structs.GossipKeyRotationIdle, status.Phase)
+	require.True(t, finished.Equal(status.LastRotation))
+	require.True(t, finished.Add(time.Hour).Equal(status.NextRotation))
+	require.Empty(t, status.LastError)
+}
+
+func TestGossipKeyRotation_NotEncrypted(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.GossipKeyRotationInterval = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	args := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var status structs.GossipKeyRotationStatus
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.KeyringRotationStatus", &args, &status))
+	require.False(t, status.Enabled)
+}
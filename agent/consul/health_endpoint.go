@@ -38,7 +38,7 @@ func (h *Health) ChecksInState(args *structs.ChecksInStateRequest,
 				return err
 			}
 			reply.Index, reply.HealthChecks = index, checks
-			if err := h.srv.filterACL(args.Token, reply); err != nil {
+			if err := h.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return h.srv.sortNodesByDistanceFrom(args.Source, reply.HealthChecks)
@@ -61,7 +61,7 @@ func (h *Health) NodeChecks(args *structs.NodeSpecificRequest,
 				return err
 			}
 			reply.Index, reply.HealthChecks = index, checks
-			return h.srv.filterACL(args.Token, reply)
+			return h.srv.filterRequestACL(args, reply)
 		})
 }
 
@@ -94,7 +94,7 @@ func (h *Health) ServiceChecks(args *structs.ServiceSpecificRequest,
 				return err
 			}
 			reply.Index, reply.HealthChecks = index, checks
-			if err := h.srv.filterACL(args.Token, reply); err != nil {
+			if err := h.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return h.srv.sortNodesByDistanceFrom(args.Source, reply.HealthChecks)
@@ -127,7 +127,7 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 	// we're trying to find proxies for, so check that.
 	if args.Connect {
 		// Fetch the ACL token, if any.
-		rule, err := h.srv.ResolveToken(args.Token)
+		rule, err := h.srv.ResolveRequestToken(args)
 		if err != nil {
 			return err
 		}
@@ -151,7 +151,10 @@ func (h *Health) ServiceNodes(args *structs.ServiceSpecificRequest, reply *struc
 			if len(args.NodeMetaFilters) > 0 {
 				reply.Nodes = nodeMetaFilter(args.NodeMetaFilters, reply.Nodes)
 			}
-			if err := h.srv.filterACL(args.Token, reply); err != nil {
+			if args.ExternalSource != "" {
+				reply.Nodes = externalSourceFilter(args.ExternalSource, reply.Nodes)
+			}
+			if err := h.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return h.srv.sortNodesByDistanceFrom(args.Source, reply.Nodes)
@@ -209,3 +212,15 @@ func (h *Health) serviceNodesTagFilter(ws memdb.WatchSet, s *state.Store, args *
 func (h *Health) serviceNodesDefault(ws memdb.WatchSet, s *state.Store, args *structs.ServiceSpecificRequest) (uint64, structs.CheckServiceNodes, error) {
 	return s.CheckServiceNodes(ws, args.ServiceName)
 }
+
+// externalSourceFilter returns a list of the nodes whose service was
+// registered by the given external source.
+func externalSourceFilter(source string, nodes structs.CheckServiceNodes) structs.CheckServiceNodes {
+	var filtered structs.CheckServiceNodes
+	for _, node := range nodes {
+		if node.Service.Meta[structs.MetaExternalSource] == source {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
@@ -3,6 +3,7 @@ package consul
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -29,6 +30,11 @@ type Intention struct {
 func (s *Intention) Apply(
 	args *structs.IntentionRequest,
 	reply *string) error {
+	// The intentions of secondary datacenters are replicated from the
+	// primary datacenter, so they are changed there.
+	if s.srv.intentionReplicationConfigured() {
+		args.Datacenter = s.srv.config.PrimaryDatacenter
+	}
 	if done, err := s.srv.forward("Intention.Apply", args, args, reply); done {
 		return err
 	}
@@ -74,7 +80,7 @@ func (s *Intention) Apply(
 	*reply = args.Intention.ID
 
 	// Get the ACL token for the request for the checks below.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -83,7 +89,7 @@ func (s *Intention) Apply(
 	if prefix, ok := args.Intention.GetACLPrefix(); ok {
 		if rule != nil && !rule.IntentionWrite(prefix) {
 			s.srv.logger.Printf("[WARN] consul.intention: Operation on intention '%s' denied due to ACLs", args.Intention.ID)
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("intention", prefix, "write")
 		}
 	}
 
@@ -103,7 +109,7 @@ func (s *Intention) Apply(
 		if prefix, ok := ixn.GetACLPrefix(); ok {
 			if rule != nil && !rule.IntentionWrite(prefix) {
 				s.srv.logger.Printf("[WARN] consul.intention: Operation on intention '%s' denied due to ACLs", args.Intention.ID)
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("intention", prefix, "write")
 			}
 		}
 	}
@@ -173,7 +179,7 @@ func (s *Intention) Get(
 			reply.Intentions = structs.Intentions{ixn}
 
 			// Filter
-			if err := s.srv.filterACL(args.Token, reply); err != nil {
+			if err := s.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 
@@ -210,7 +216,7 @@ func (s *Intention) List(
 				reply.Intentions = make(structs.Intentions, 0)
 			}
 
-			return s.srv.filterACL(args.Token, reply)
+			return s.srv.filterRequestACL(args, reply)
 		},
 	)
 }
@@ -225,7 +231,7 @@ func (s *Intention) Match(
 	}
 
 	// Get the ACL token for the request for the checks below.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -236,7 +242,7 @@ func (s *Intention) Match(
 		for _, entry := range args.Match.Entries {
 			if prefix := entry.Name; prefix != "" && !rule.IntentionRead(prefix) {
 				s.srv.logger.Printf("[WARN] consul.intention: Operation on intention prefix '%s' denied due to ACLs", prefix)
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("intention", prefix, "read")
 			}
 		}
 	}
@@ -291,7 +297,7 @@ func (s *Intention) Check(
 	}
 
 	// Get the ACL token for the request for the checks below.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -302,7 +308,7 @@ func (s *Intention) Check(
 	if prefix, ok := query.GetACLPrefix(); ok {
 		if rule != nil && !rule.ServiceRead(prefix) {
 			s.srv.logger.Printf("[WARN] consul.intention: test on intention '%s' denied due to ACLs", prefix)
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("service", prefix, "read")
 		}
 	}
 
@@ -356,3 +362,214 @@ func (s *Intention) Check(
 
 	return nil
 }
+
+// Impact returns the connections between the services of the mesh whose
+// authorization would change if the given intention request was applied. The
+// services of the mesh are the destination services of the Connect proxies in
+// the catalog. Nothing is changed.
+func (s *Intention) Impact(
+	args *structs.IntentionRequest,
+	reply *structs.IntentionImpactResponse) error {
+	// Forward maybe
+	if done, err := s.srv.forward("Intention.Impact", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"intention", "impact"}, time.Now())
+
+	// Always set a non-nil intention to avoid nil-access below
+	if args.Intention == nil {
+		args.Intention = &structs.Intention{}
+	}
+	ixn := args.Intention
+
+	// Get the ACL token for the request for the checks below.
+	rule, err := s.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+
+	// The same ACL checks as for applying the request are performed, as the
+	// impact of a change is only of interest to those who can make it.
+	state := s.srv.fsm.State()
+	var existing *structs.Intention
+	switch args.Op {
+	case structs.IntentionOpCreate:
+		if ixn.ID != "" {
+			return fmt.Errorf("ID must be empty when creating a new intention")
+		}
+
+	case structs.IntentionOpUpdate, structs.IntentionOpDelete:
+		_, existing, err = state.IntentionGet(nil, ixn.ID)
+		if err != nil {
+			return fmt.Errorf("Intention lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("Cannot modify non-existent intention: '%s'", ixn.ID)
+		}
+		if prefix, ok := existing.GetACLPrefix(); ok {
+			if rule != nil && !rule.IntentionWrite(prefix) {
+				s.srv.logger.Printf("[WARN] consul.intention: Impact of operation on intention '%s' denied due to ACLs", ixn.ID)
+				return acl.PermissionDenied("intention", prefix, "write")
+			}
+		}
+
+	default:
+		return fmt.Errorf("Invalid intention operation: %q", args.Op)
+	}
+
+	if args.Op == structs.IntentionOpDelete {
+		ixn = existing
+	} else {
+		if prefix, ok := ixn.GetACLPrefix(); ok {
+			if rule != nil && !rule.IntentionWrite(prefix) {
+				s.srv.logger.Printf("[WARN] consul.intention: Impact of operation on intention '%s' denied due to ACLs", ixn.ID)
+				return acl.PermissionDenied("intention", prefix, "write")
+			}
+		}
+
+		// Apply the same defaults as an actual change
+		if ixn.SourceType == "" {
+			ixn.SourceType = structs.IntentionSourceConsul
+		}
+		if ixn.SourceNS == "" {
+			ixn.SourceNS = structs.IntentionDefaultNamespace
+		}
+		if ixn.DestinationNS == "" {
+			ixn.DestinationNS = structs.IntentionDefaultNamespace
+		}
+		ixn.UpdatePrecedence()
+		if err := ixn.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Find the services of the mesh
+	_, proxies, err := state.ConnectProxies(nil)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{})
+	var names []string
+	for _, proxy := range proxies {
+		name := proxy.ServiceProxy.DestinationServiceName
+		if _, ok := seen[name]; ok || name == "" {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]structs.IntentionMatchEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, structs.IntentionMatchEntry{
+			Namespace: structs.IntentionDefaultNamespace,
+			Name:      name,
+		})
+	}
+	_, matches, err := state.IntentionMatch(nil, &structs.IntentionQueryMatch{
+		Type:    structs.IntentionMatchDestination,
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	if len(matches) != len(names) {
+		return errors.New("internal error loading matches")
+	}
+
+	// The default behavior is the one of the anonymous token, as for Check.
+	defaultAllow := true
+	anon, err := s.srv.ResolveToken("")
+	if err != nil {
+		return err
+	}
+	if anon != nil {
+		defaultAllow = anon.IntentionDefaultAllow()
+	}
+
+	for i, dest := range names {
+		before := matches[i]
+
+		// The intentions as they would be after the change
+		after := make(structs.Intentions, 0, len(before)+1)
+		for _, match := range before {
+			if existing == nil || match.ID != existing.ID {
+				after = append(after, match)
+			}
+		}
+		if args.Op != structs.IntentionOpDelete && intentionMatchesDestination(ixn, structs.IntentionDefaultNamespace, dest) {
+			after = append(after, ixn)
+		}
+		sort.Sort(structs.IntentionPrecedenceSorter(after))
+
+		for _, source := range names {
+			if source == dest {
+				continue
+			}
+
+			uri := &connect.SpiffeIDService{
+				Namespace: structs.IntentionDefaultNamespace,
+				Service:   source,
+			}
+			allowed := intentionAllowed(after, uri, defaultAllow)
+			if allowed == intentionAllowed(before, uri, defaultAllow) {
+				continue
+			}
+
+			// Only report the connections between the services the token
+			// can see.
+			if rule != nil && (!rule.ServiceRead(source) || !rule.ServiceRead(dest)) {
+				continue
+			}
+
+			conn := structs.IntentionConnection{SourceName: source, DestinationName: dest}
+			if allowed {
+				reply.Allowed = append(reply.Allowed, conn)
+			} else {
+				reply.Denied = append(reply.Denied, conn)
+			}
+		}
+	}
+
+	return nil
+}
+
+// intentionMatchesDestination returns whether the intention applies to the
+// given destination service.
+func intentionMatchesDestination(ixn *structs.Intention, ns, name string) bool {
+	if ixn.DestinationNS != structs.IntentionWildcard && ixn.DestinationNS != ns {
+		return false
+	}
+	return ixn.DestinationName == structs.IntentionWildcard || ixn.DestinationName == name
+}
+
+// intentionAllowed returns whether the source is allowed to connect given the
+// intentions of a destination, sorted by precedence.
+func intentionAllowed(ixns structs.Intentions, uri connect.CertURI, defaultAllow bool) bool {
+	for _, ixn := range ixns {
+		if auth, ok := uri.Authorize(ixn); ok {
+			return auth
+		}
+	}
+	return defaultAllow
+}
+
+// ReplicationStatus is used to retrieve the current status of the
+// replication of the intentions from the primary datacenter.
+func (s *Intention) ReplicationStatus(
+	args *structs.DCSpecificRequest,
+	reply *structs.IntentionReplicationStatus) error {
+	// This must be sent to the leader, so we fix the args since we are
+	// re-using a structure where we don't support all the options.
+	args.RequireConsistent = true
+	args.AllowStale = false
+	if done, err := s.srv.forward("Intention.ReplicationStatus", args, args, reply); done {
+		return err
+	}
+
+	// There's no ACL token required here since this doesn't leak any
+	// sensitive information, like the ACL replication status.
+	*reply = s.srv.getIntentionReplicationStatus()
+	return nil
+}
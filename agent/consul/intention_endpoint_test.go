@@ -1276,3 +1276,90 @@ service "bar" {
 		require.False(resp.Allowed)
 	}
 }
+
+func TestIntentionImpact(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Register the proxies of the services of the mesh
+	for _, name := range []string{"web", "api", "db"} {
+		args := structs.TestRegisterRequestProxy(t)
+		args.Service.Service = name + "-proxy"
+		args.Service.Proxy.DestinationServiceName = name
+		var out struct{}
+		require.Nil(msgpackrpc.CallWithCodec(codec, "Catalog.Register", args, &out))
+	}
+
+	// Propose to deny all connections to db
+	ixn := structs.IntentionRequest{
+		Datacenter: "dc1",
+		Op:         structs.IntentionOpCreate,
+		Intention: &structs.Intention{
+			SourceName:      "*",
+			DestinationName: "db",
+			Action:          structs.IntentionActionDeny,
+		},
+	}
+	var resp structs.IntentionImpactResponse
+	require.Nil(msgpackrpc.CallWithCodec(codec, "Intention.Impact", &ixn, &resp))
+	require.Empty(resp.Allowed)
+	require.Equal([]structs.IntentionConnection{
+		{SourceName: "api", DestinationName: "db"},
+		{SourceName: "web", DestinationName: "db"},
+	}, resp.Denied)
+
+	// Nothing was created by the impact request
+	{
+		req := &structs.DCSpecificRequest{Datacenter: "dc1"}
+		var resp structs.IndexedIntentions
+		require.Nil(msgpackrpc.CallWithCodec(codec, "Intention.List", req, &resp))
+		require.Empty(resp.Intentions)
+	}
+
+	// Create it
+	var reply string
+	require.Nil(msgpackrpc.CallWithCodec(codec, "Intention.Apply", &ixn, &reply))
+
+	// Propose to allow web to connect to db again, with higher precedence
+	{
+		req := structs.IntentionRequest{
+			Datacenter: "dc1",
+			Op:         structs.IntentionOpCreate,
+			Intention: &structs.Intention{
+				SourceName:      "web",
+				DestinationName: "db",
+				Action:          structs.IntentionActionAllow,
+			},
+		}
+		var resp structs.IntentionImpactResponse
+		require.Nil(msgpackrpc.CallWithCodec(codec, "Intention.Impact", &req, &resp))
+		require.Equal([]structs.IntentionConnection{
+			{SourceName: "web", DestinationName: "db"},
+		}, resp.Allowed)
+		require.Empty(resp.Denied)
+	}
+
+	// Propose to delete the intention
+	{
+		req := structs.IntentionRequest{
+			Datacenter: "dc1",
+			Op:         structs.IntentionOpDelete,
+			Intention:  &structs.Intention{ID: reply},
+		}
+		var resp structs.IntentionImpactResponse
+		require.Nil(msgpackrpc.CallWithCodec(codec, "Intention.Impact", &req, &resp))
+		require.Equal([]structs.IntentionConnection{
+			{SourceName: "api", DestinationName: "db"},
+			{SourceName: "web", DestinationName: "db"},
+		}, resp.Allowed)
+		require.Empty(resp.Denied)
+	}
+}
@@ -0,0 +1,244 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const (
+	// intentionReplicationMaxRetryBackoff is the max number of seconds to
+	// wait between failed intention replication attempts.
+	intentionReplicationMaxRetryBackoff = 64
+
+	// intentionReplicationWaitTime is the maximum time a replication round
+	// waits for changes in the primary datacenter.
+	intentionReplicationWaitTime = 30 * time.Second
+
+	// intentionReplicationTokenWait is how often the replication checks
+	// whether a Connect replication token was set.
+	intentionReplicationTokenWait = 5 * time.Second
+)
+
+// intentionReplicationSecondary returns true if this is a secondary
+// datacenter with Connect enabled, whose leader replicates the intentions
+// once a Connect replication token is set.
+func (s *Server) intentionReplicationSecondary() bool {
+	return s.config.ConnectEnabled &&
+		s.config.PrimaryDatacenter != "" &&
+		s.config.PrimaryDatacenter != s.config.Datacenter
+}
+
+// intentionReplicationConfigured returns true if the servers of this
+// datacenter replicate the intentions from the primary datacenter.
+func (s *Server) intentionReplicationConfigured() bool {
+	return s.intentionReplicationSecondary() && s.tokens.ConnectReplicationToken() != ""
+}
+
+// startIntentionReplication starts the replication of the intentions from
+// the primary datacenter if it is configured.
+func (s *Server) startIntentionReplication() {
+	s.intentionReplicationLock.Lock()
+	defer s.intentionReplicationLock.Unlock()
+
+	if s.intentionReplicationEnabled || !s.intentionReplicationSecondary() {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.intentionReplicationCancel = cancel
+
+	s.intentionReplicationStatusLock.Lock()
+	s.intentionReplicationStatus.Running = true
+	s.intentionReplicationStatusLock.Unlock()
+
+	go s.runIntentionReplication(ctx)
+
+	s.logger.Printf("[INFO] consul: started intention replication")
+	s.intentionReplicationEnabled = true
+}
+
+// stopIntentionReplication stops the intention replication.
+func (s *Server) stopIntentionReplication() {
+	s.intentionReplicationLock.Lock()
+	defer s.intentionReplicationLock.Unlock()
+
+	if !s.intentionReplicationEnabled {
+		return
+	}
+
+	s.intentionReplicationCancel()
+	s.intentionReplicationCancel = nil
+	s.intentionReplicationEnabled = false
+
+	s.intentionReplicationStatusLock.Lock()
+	s.intentionReplicationStatus.Running = false
+	s.intentionReplicationStatusLock.Unlock()
+}
+
+// runIntentionReplication replicates the intentions until the context is
+// cancelled.
+func (s *Server) runIntentionReplication(ctx context.Context) {
+	var failedAttempts uint
+	var lastRemoteIndex uint64
+	for {
+		// The token can be set through the API at any time, so wait for
+		// it rather than requiring a leader election.
+		if s.tokens.ConnectReplicationToken() == "" {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(intentionReplicationTokenWait):
+				continue
+			}
+		}
+
+		index, exit, err := s.replicateIntentions(ctx, lastRemoteIndex)
+		if exit {
+			return
+		}
+
+		if err != nil {
+			lastRemoteIndex = 0
+			s.intentionReplicationStatusLock.Lock()
+			s.intentionReplicationStatus.LastError = time.Now().Round(time.Second).UTC()
+			s.intentionReplicationStatusLock.Unlock()
+			s.logger.Printf("[WARN] consul: intention replication error (will retry if still leader): %v", err)
+			if (1 << failedAttempts) < intentionReplicationMaxRetryBackoff {
+				failedAttempts++
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After((1 << failedAttempts) * time.Second):
+				// do nothing
+			}
+		} else {
+			lastRemoteIndex = index
+			failedAttempts = 0
+			s.intentionReplicationStatusLock.Lock()
+			s.intentionReplicationStatus.ReplicatedIndex = index
+			s.intentionReplicationStatus.LastSuccess = time.Now().Round(time.Second).UTC()
+			s.intentionReplicationStatusLock.Unlock()
+		}
+	}
+}
+
+// replicateIntentions waits for the intentions in the primary datacenter
+// to change after the given index and copies them to the local state. It
+// returns the remote index and whether replication should stop.
+func (s *Server) replicateIntentions(ctx context.Context, lastRemoteIndex uint64) (uint64, bool, error) {
+	req := structs.DCSpecificRequest{
+		Datacenter: s.config.PrimaryDatacenter,
+		QueryOptions: structs.QueryOptions{
+			Token:         s.tokens.ConnectReplicationToken(),
+			AllowStale:    true,
+			MinQueryIndex: lastRemoteIndex,
+			MaxQueryTime:  intentionReplicationWaitTime,
+		},
+	}
+	var remote structs.IndexedIntentions
+	if err := s.RPC("Intention.List", &req, &remote); err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve remote intentions: %v", err)
+	}
+
+	// The fetch is a blocking query during which leadership could have been
+	// lost.
+	select {
+	case <-ctx.Done():
+		return 0, true, nil
+	default:
+	}
+
+	// If the remote index ever goes backwards, it's a good indication that
+	// the remote side was rebuilt and we should do a full sync.
+	if remote.Index < lastRemoteIndex {
+		return 0, false, nil
+	}
+
+	_, local, err := s.fsm.State().Intentions(nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve local intentions: %v", err)
+	}
+
+	ops := intentionReplicationOps(local, remote.Intentions)
+	if len(ops) > 0 {
+		if err := s.applyIntentionReplicationOps(ops); err != nil {
+			return 0, false, err
+		}
+		s.logger.Printf("[DEBUG] consul: intention replication applied %d changes through remote index %d",
+			len(ops), remote.Index)
+	}
+	return remote.Index, false, nil
+}
+
+// intentionReplicationOps returns the operations which make the local
+// intentions match the remote ones. Intentions are compared by the time of
+// their last update in the primary datacenter, which is replicated along
+// with them. The deletions come first so that an intention which was
+// recreated with a new ID doesn't conflict with the old one.
+func intentionReplicationOps(local, remote structs.Intentions) []*structs.IntentionRequest {
+	remoteByID := make(map[string]*structs.Intention, len(remote))
+	for _, ixn := range remote {
+		remoteByID[ixn.ID] = ixn
+	}
+
+	var ops []*structs.IntentionRequest
+	localByID := make(map[string]*structs.Intention, len(local))
+	for _, ixn := range local {
+		localByID[ixn.ID] = ixn
+		if _, ok := remoteByID[ixn.ID]; !ok {
+			ops = append(ops, &structs.IntentionRequest{
+				Op:        structs.IntentionOpDelete,
+				Intention: &structs.Intention{ID: ixn.ID},
+			})
+		}
+	}
+
+	for _, ixn := range remote {
+		existing, ok := localByID[ixn.ID]
+		if ok && existing.UpdatedAt.Equal(ixn.UpdatedAt) {
+			continue
+		}
+		op := structs.IntentionOpCreate
+		if ok {
+			op = structs.IntentionOpUpdate
+		}
+		ops = append(ops, &structs.IntentionRequest{Op: op, Intention: ixn})
+	}
+	return ops
+}
+
+// applyIntentionReplicationOps applies the given operations to the local
+// state.
+func (s *Server) applyIntentionReplicationOps(ops []*structs.IntentionRequest) error {
+	defer metrics.MeasureSince([]string{"leader", "replication", "intentions", "apply"}, time.Now())
+
+	for _, op := range ops {
+		op.Datacenter = s.config.Datacenter
+		resp, err := s.raftApply(structs.IntentionRequestType, op)
+		if err != nil {
+			return fmt.Errorf("failed to apply intention %s: %v", op.Op, err)
+		}
+		if respErr, ok := resp.(error); ok {
+			return fmt.Errorf("failed to apply intention %s: %v", op.Op, respErr)
+		}
+	}
+	return nil
+}
+
+// getIntentionReplicationStatus returns the current status of the
+// intention replication.
+func (s *Server) getIntentionReplicationStatus() structs.IntentionReplicationStatus {
+	s.intentionReplicationStatusLock.RLock()
+	out := s.intentionReplicationStatus
+	s.intentionReplicationStatusLock.RUnlock()
+
+	out.Enabled = s.intentionReplicationConfigured()
+	out.SourceDatacenter = s.config.PrimaryDatacenter
+	return out
+}
@@ -0,0 +1,139 @@
+package consul
+
+import (
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentionReplicationOps(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	local := structs.Intentions{
+		{ID: "a", UpdatedAt: now},
+		{ID: "b", UpdatedAt: now},
+		{ID: "local", UpdatedAt: now},
+	}
+	remote := structs.Intentions{
+		{ID: "a", UpdatedAt: now},
+		{ID: "b", UpdatedAt: now.Add(time.Second)},
+		{ID: "c", UpdatedAt: now},
+	}
+
+	var got []string
+	for _, op := range intentionReplicationOps(local, remote) {
+		got = append(got, string(op.Op)+" "+op.Intention.ID)
+	}
+	require.Equal(t, []string{
+		"delete local",
+		"update b",
+		"create c",
+	}, got)
+}
+
+func TestIntentionReplication(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	codec1 := rpcClient(t, s1)
+	defer codec1.Close()
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.PrimaryDatacenter = "dc1"
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	apply := func(codec rpc.ClientCodec, dc string, op structs.IntentionOp, ixn *structs.Intention) string {
+		arg := structs.IntentionRequest{Datacenter: dc, Op: op, Intention: ixn}
+		var reply string
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "Intention.Apply", &arg, &reply))
+		return reply
+	}
+	newIntention := func(src, dst string) *structs.Intention {
+		return &structs.Intention{
+			SourceNS:        structs.IntentionDefaultNamespace,
+			SourceName:      src,
+			DestinationNS:   structs.IntentionDefaultNamespace,
+			DestinationName: dst,
+			Action:          structs.IntentionActionAllow,
+			SourceType:      structs.IntentionSourceConsul,
+			Meta:            map[string]string{},
+		}
+	}
+
+	// A local intention of the secondary datacenter is written there as
+	// long as there's no replication token.
+	apply(codec2, "dc2", structs.IntentionOpCreate, newIntention("web", "db"))
+
+	webAPI := newIntention("web", "api")
+	webAPI.ID = apply(codec1, "dc1", structs.IntentionOpCreate, webAPI)
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+	s2.tokens.UpdateConnectReplicationToken("root")
+
+	checkIntentions := func(r *retry.R, want map[string]structs.IntentionAction) {
+		_, ixns, err := s2.fsm.State().Intentions(nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		got := make(map[string]structs.IntentionAction)
+		for _, ixn := range ixns {
+			got[ixn.SourceName+"->"+ixn.DestinationName] = ixn.Action
+		}
+		if len(got) != len(want) {
+			r.Fatalf("bad: %v", got)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				r.Fatalf("bad: %v", got)
+			}
+		}
+	}
+	retry.Run(t, func(r *retry.R) {
+		checkIntentions(r, map[string]structs.IntentionAction{
+			"web->api": structs.IntentionActionAllow,
+		})
+	})
+
+	// Changes in the primary are replicated, and writes to the secondary
+	// are sent to the primary.
+	webAPI.Action = structs.IntentionActionDeny
+	apply(codec1, "dc1", structs.IntentionOpUpdate, webAPI)
+	apply(codec2, "dc2", structs.IntentionOpCreate, newIntention("web", "cache"))
+	retry.Run(t, func(r *retry.R) {
+		checkIntentions(r, map[string]structs.IntentionAction{
+			"web->api":   structs.IntentionActionDeny,
+			"web->cache": structs.IntentionActionAllow,
+		})
+	})
+	_, ixns, err := s1.fsm.State().Intentions(nil)
+	require.NoError(t, err)
+	require.Len(t, ixns, 2)
+
+	retry.Run(t, func(r *retry.R) {
+		args := structs.DCSpecificRequest{Datacenter: "dc2"}
+		var status structs.IntentionReplicationStatus
+		if err := msgpackrpc.CallWithCodec(codec2, "Intention.ReplicationStatus", &args, &status); err != nil {
+			r.Fatal(err)
+		}
+		if !status.Enabled || !status.Running || status.SourceDatacenter != "dc1" ||
+			status.ReplicatedIndex == 0 || status.LastSuccess.IsZero() {
+			r.Fatalf("bad: %#v", status)
+		}
+	})
+}
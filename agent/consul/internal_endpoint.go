@@ -2,6 +2,7 @@ package consul
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/state"
@@ -35,7 +36,62 @@ func (m *Internal) NodeInfo(args *structs.NodeSpecificRequest,
 			}
 
 			reply.Index, reply.Dump = index, dump
-			return m.srv.filterACL(args.Token, reply)
+			return m.srv.filterRequestACL(args, reply)
+		})
+}
+
+// NodeDetail is used to retrieve a node together with its sessions and
+// network coordinates.
+func (m *Internal) NodeDetail(args *structs.NodeSpecificRequest,
+	reply *structs.IndexedNodeDetail) error {
+	if done, err := m.srv.forward("Internal.NodeDetail", args, args, reply); done {
+		return err
+	}
+
+	return m.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, dump, err := state.NodeInfo(ws, args.Node)
+			if err != nil {
+				return err
+			}
+			sessionIndex, sessions, err := state.NodeSessions(ws, args.Node)
+			if err != nil {
+				return err
+			}
+			coordIndex, nodeCoords, err := state.Coordinate(args.Node, ws)
+			if err != nil {
+				return err
+			}
+			if sessionIndex > index {
+				index = sessionIndex
+			}
+			if coordIndex > index {
+				index = coordIndex
+			}
+
+			reply.Index, reply.Detail = index, nil
+			if len(dump) == 0 {
+				return nil
+			}
+			detail := &structs.NodeDetail{
+				NodeInfo:    dump[0],
+				Sessions:    sessions,
+				Coordinates: make(structs.Coordinates, 0, len(nodeCoords)),
+			}
+			for segment, coord := range nodeCoords {
+				detail.Coordinates = append(detail.Coordinates, &structs.Coordinate{
+					Node:    args.Node,
+					Segment: segment,
+					Coord:   coord,
+				})
+			}
+			sort.Slice(detail.Coordinates, func(i, j int) bool {
+				return detail.Coordinates[i].Segment < detail.Coordinates[j].Segment
+			})
+			reply.Detail = detail
+			return m.srv.filterRequestACL(args, reply)
 		})
 }
 
@@ -56,7 +112,52 @@ func (m *Internal) NodeDump(args *structs.DCSpecificRequest,
 			}
 
 			reply.Index, reply.Dump = index, dump
-			return m.srv.filterACL(args.Token, reply)
+			return m.srv.filterRequestACL(args, reply)
+		})
+}
+
+// KeyLocks is used to retrieve the KV keys which are locked by sessions,
+// under a lock-delay or contended. Sessions rejected because of a lock-delay
+// are only seen by the leader, so stale reads may report fewer waiters.
+func (m *Internal) KeyLocks(args *structs.DCSpecificRequest,
+	reply *structs.IndexedKeyLocks) error {
+	if done, err := m.srv.forward("Internal.KeyLocks", args, args, reply); done {
+		return err
+	}
+
+	return m.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, locks, err := state.KVSLocks(ws)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Locks = index, locks
+			return m.srv.filterRequestACL(args, reply)
+		})
+}
+
+// ProtocolConflicts is used to find the services for which the Connect
+// proxies assume different protocols.
+func (m *Internal) ProtocolConflicts(args *structs.DCSpecificRequest,
+	reply *structs.IndexedProtocolConflicts) error {
+	if done, err := m.srv.forward("Internal.ProtocolConflicts", args, args, reply); done {
+		return err
+	}
+
+	return m.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, proxies, err := state.ConnectProxies(ws)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Conflicts = index, structs.FindProtocolConflicts(proxies)
+			return m.srv.filterRequestACL(args, reply)
 		})
 }
 
@@ -70,14 +171,14 @@ func (m *Internal) EventFire(args *structs.EventFireRequest,
 	}
 
 	// Check ACLs
-	rule, err := m.srv.ResolveToken(args.Token)
+	rule, err := m.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 
 	if rule != nil && !rule.EventWrite(args.Name) {
 		m.srv.logger.Printf("[WARN] consul: user event %q blocked by ACLs", args.Name)
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("event", args.Name, "write")
 	}
 
 	// Set the query meta data
@@ -105,7 +206,7 @@ func (m *Internal) KeyringOperation(
 	reply *structs.KeyringResponses) error {
 
 	// Check ACLs
-	rule, err := m.srv.ResolveToken(args.Token)
+	rule, err := m.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -148,33 +249,34 @@ func (m *Internal) executeKeyringOp(
 	wan bool) {
 
 	if wan {
-		mgr := m.srv.KeyManagerWAN()
-		m.executeKeyringOpMgr(mgr, args, reply, wan, "")
+		m.executeKeyringOpPool(m.srv.serfWAN, args, reply, wan, "")
 	} else {
 		segments := m.srv.LANSegments()
 		for name, segment := range segments {
-			mgr := segment.KeyManager()
-			m.executeKeyringOpMgr(mgr, args, reply, wan, name)
+			m.executeKeyringOpPool(segment, args, reply, wan, name)
 		}
 	}
 }
 
-// executeKeyringOpMgr executes the appropriate keyring-related function based on
-// the type of keyring operation in the request. It takes the KeyManager as an
-// argument, so it can handle any operation for either LAN or WAN pools.
-func (m *Internal) executeKeyringOpMgr(
-	mgr *serf.KeyManager,
+// executeKeyringOpPool executes the appropriate keyring-related function based
+// on the type of keyring operation in the request. It takes the gossip pool as
+// an argument, so it can handle any operation for either LAN or WAN pools.
+func (m *Internal) executeKeyringOpPool(
+	pool *serf.Serf,
 	args *structs.KeyringRequest,
 	reply *structs.KeyringResponses,
 	wan bool,
 	segment string) {
 	var serfResp *serf.KeyResponse
+	var listing *keyringListing
 	var err error
 
+	mgr := pool.KeyManager()
 	opts := &serf.KeyRequestOptions{RelayFactor: args.RelayFactor}
 	switch args.Operation {
 	case structs.KeyringList:
-		serfResp, err = mgr.ListKeysWithOptions(opts)
+		listing, err = listKeys(pool, args.RelayFactor)
+		serfResp = &listing.KeyResponse
 	case structs.KeyringInstall:
 		serfResp, err = mgr.InstallKeyWithOptions(args.Key, opts)
 	case structs.KeyringUse:
@@ -188,7 +290,7 @@ func (m *Internal) executeKeyringOpMgr(
 		errStr = err.Error()
 	}
 
-	reply.Responses = append(reply.Responses, &structs.KeyringResponse{
+	resp := &structs.KeyringResponse{
 		WAN:        wan,
 		Datacenter: m.srv.config.Datacenter,
 		Segment:    segment,
@@ -196,5 +298,53 @@ func (m *Internal) executeKeyringOpMgr(
 		Keys:       serfResp.Keys,
 		NumNodes:   serfResp.NumNodes,
 		Error:      errStr,
-	})
+	}
+	if listing != nil {
+		resp.PrimaryKeys = listing.PrimaryKeys
+		if args.Detailed {
+			resp.NodeKeys = listing.NodeKeys
+			resp.Alerts = keyringAlerts(listing)
+		}
+	}
+	reply.Responses = append(reply.Responses, resp)
+}
+
+// keyringAlerts returns the problems with the keyring of a pool which are
+// visible in the given key listing: nodes using different primary keys and
+// nodes which don't have the primary key of the pool installed. The primary
+// key of the pool is the one used by most nodes.
+func keyringAlerts(resp *keyringListing) []string {
+	var alerts []string
+	var primary string
+	for key, count := range resp.PrimaryKeys {
+		if count > resp.PrimaryKeys[primary] || (count == resp.PrimaryKeys[primary] && key < primary) {
+			primary = key
+		}
+	}
+	if len(resp.PrimaryKeys) > 1 {
+		alerts = append(alerts, fmt.Sprintf("%d different primary keys are in use", len(resp.PrimaryKeys)))
+	}
+
+	var nodes []string
+	for node := range resp.NodeKeys {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		installed := false
+		for _, key := range resp.NodeKeys[node] {
+			if key == primary {
+				installed = true
+				break
+			}
+		}
+		if !installed {
+			alerts = append(alerts, fmt.Sprintf("node %q doesn't have the primary key installed", node))
+		}
+	}
+
+	if resp.NumResp < resp.NumNodes {
+		alerts = append(alerts, fmt.Sprintf("%d/%d nodes didn't respond", resp.NumNodes-resp.NumResp, resp.NumNodes))
+	}
+	return alerts
 }
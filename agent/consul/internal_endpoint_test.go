@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
@@ -11,6 +12,8 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/serf/serf"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInternal_NodeInfo(t *testing.T) {
@@ -67,6 +70,76 @@ func TestInternal_NodeInfo(t *testing.T) {
 	}
 }
 
+func TestInternal_NodeDetail(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "db",
+			Service: "db",
+		},
+		Check: &structs.HealthCheck{
+			Name:      "db connect",
+			Status:    api.HealthPassing,
+			ServiceID: "db",
+		},
+	}
+	var out struct{}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Catalog.Register", &arg, &out))
+
+	state := s1.fsm.State()
+	require.NoError(t, state.SessionCreate(100, &structs.Session{ID: generateUUID(), Node: "foo"}))
+	coord := generateRandomCoordinate()
+	require.NoError(t, state.CoordinateBatchUpdate(101, structs.Coordinates{
+		{Node: "foo", Coord: coord},
+	}))
+
+	req := structs.NodeSpecificRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+	}
+	var reply structs.IndexedNodeDetail
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Internal.NodeDetail", &req, &reply))
+	require.Equal(t, uint64(101), reply.Index)
+	detail := reply.Detail
+	require.NotNil(t, detail)
+	require.Equal(t, "foo", detail.Node)
+	require.Len(t, detail.Services, 1)
+	require.Len(t, detail.Checks, 1)
+	require.Len(t, detail.Sessions, 1)
+	require.Equal(t, structs.Coordinates{{Node: "foo", Coord: coord}}, detail.Coordinates)
+
+	// Blocks until one of the parts changes.
+	start := time.Now()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		state.SessionCreate(102, &structs.Session{ID: generateUUID(), Node: "foo"})
+	}()
+	req.MinQueryIndex = reply.Index
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Internal.NodeDetail", &req, &reply))
+	require.True(t, time.Since(start) >= 100*time.Millisecond)
+	require.Equal(t, uint64(102), reply.Index)
+	require.Len(t, reply.Detail.Sessions, 2)
+
+	// An unknown node has no detail.
+	req = structs.NodeSpecificRequest{
+		Datacenter: "dc1",
+		Node:       "nope",
+	}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Internal.NodeDetail", &req, &reply))
+	require.Nil(t, reply.Detail)
+}
+
 func TestInternal_NodeDump(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -242,6 +315,38 @@ func TestInternal_KeyringOperation(t *testing.T) {
 	}
 }
 
+func TestInternal_keyringAlerts(t *testing.T) {
+	t.Parallel()
+	key1, key2 := "H3/9gBxcKKRf45CaI2DlRg==", "z90lFx3sZZLtTOkutXcwYg=="
+
+	// All nodes agree on the primary key.
+	resp := &keyringListing{
+		KeyResponse: serf.KeyResponse{NumNodes: 2, NumResp: 2},
+		PrimaryKeys: map[string]int{key1: 2},
+		NodeKeys: map[string][]string{
+			"node1": {key1, key2},
+			"node2": {key1},
+		},
+	}
+	require.Empty(t, keyringAlerts(resp))
+
+	// A node switched its primary key before the others installed it.
+	resp = &keyringListing{
+		KeyResponse: serf.KeyResponse{NumNodes: 4, NumResp: 3},
+		PrimaryKeys: map[string]int{key1: 2, key2: 1},
+		NodeKeys: map[string][]string{
+			"node1": {key1},
+			"node2": {key1},
+			"node3": {key2},
+		},
+	}
+	require.Equal(t, []string{
+		"2 different primary keys are in use",
+		`node "node3" doesn't have the primary key installed`,
+		"1/4 nodes didn't respond",
+	}, keyringAlerts(resp))
+}
+
 func TestInternal_NodeInfo_FilterACL(t *testing.T) {
 	t.Parallel()
 	dir, token, srv, codec := testACLFilterServer(t)
@@ -293,6 +398,29 @@ func TestInternal_NodeInfo_FilterACL(t *testing.T) {
 	// for now until we change the sense of the version 8 ACL flag).
 }
 
+func TestInternal_NodeDetail_FilterACL(t *testing.T) {
+	t.Parallel()
+	dir, token, srv, codec := testACLFilterServer(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer codec.Close()
+
+	opt := structs.NodeSpecificRequest{
+		Datacenter:   "dc1",
+		Node:         srv.config.NodeName,
+		QueryOptions: structs.QueryOptions{Token: token},
+	}
+	reply := structs.IndexedNodeDetail{}
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Internal.NodeDetail", &opt, &reply))
+	require.NotNil(t, reply.Detail)
+	for _, svc := range reply.Detail.Services {
+		require.NotEqual(t, "bar", svc.Service)
+	}
+	for _, chk := range reply.Detail.Checks {
+		require.NotEqual(t, "bar", chk.ServiceName)
+	}
+}
+
 func TestInternal_NodeDump_FilterACL(t *testing.T) {
 	t.Parallel()
 	dir, token, srv, codec := testACLFilterServer(t)
@@ -0,0 +1,118 @@
+package consul
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/serf/serf"
+)
+
+const (
+	// serfListKeysQuery is the internal query serf answers with the keys
+	// installed on a node.
+	serfListKeysQuery = serf.InternalQueryPrefix + "list-keys"
+
+	// serfKeyRequestType and serfKeyResponseType are the serf message types
+	// of the payloads of key queries and their responses.
+	serfKeyRequestType  = 7
+	serfKeyResponseType = 8
+)
+
+// serfKeyRequest and serfNodeKeyResponse mirror the messages serf exchanges
+// for key queries.
+type serfKeyRequest struct {
+	Key []byte
+}
+
+type serfNodeKeyResponse struct {
+	Result  bool
+	Message string
+	Keys    []string
+}
+
+// keyringListing is the result of listing the keys of a gossip pool. Unlike
+// the listing of serf's key manager, it keeps the keys of every node, which
+// list the primary key of the node first.
+type keyringListing struct {
+	serf.KeyResponse
+
+	// PrimaryKeys is the number of nodes which use each key as their primary
+	// key.
+	PrimaryKeys map[string]int
+
+	// NodeKeys are the keys installed on every node which responded.
+	NodeKeys map[string][]string
+}
+
+// listKeys lists the keys installed on the nodes of a gossip pool. It sends
+// the same query as the key manager of serf and fails in the same cases.
+func listKeys(pool *serf.Serf, relayFactor uint8) (*keyringListing, error) {
+	resp := &keyringListing{
+		KeyResponse: serf.KeyResponse{
+			Messages: make(map[string]string),
+			Keys:     make(map[string]int),
+		},
+		PrimaryKeys: make(map[string]int),
+		NodeKeys:    make(map[string][]string),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(serfKeyRequestType)
+	if err := codec.NewEncoder(&buf, &codec.MsgpackHandle{}).Encode(&serfKeyRequest{}); err != nil {
+		return resp, err
+	}
+
+	params := pool.DefaultQueryParams()
+	params.RelayFactor = relayFactor
+	query, err := pool.Query(serfListKeysQuery, buf.Bytes(), params)
+	if err != nil {
+		return resp, err
+	}
+
+	resp.NumNodes = pool.Memberlist().NumMembers()
+	for r := range query.ResponseCh() {
+		resp.NumResp++
+		if err := resp.add(r); err != nil {
+			resp.Messages[r.From] = err.Error()
+			resp.NumErr++
+		}
+
+		// Return early if all nodes have responded.
+		if resp.NumResp == resp.NumNodes {
+			break
+		}
+	}
+
+	if resp.NumErr != 0 {
+		return resp, fmt.Errorf("%d/%d nodes reported failure", resp.NumErr, resp.NumNodes)
+	}
+	if resp.NumResp != resp.NumNodes {
+		return resp, fmt.Errorf("%d/%d nodes reported success", resp.NumResp, resp.NumNodes)
+	}
+	return resp, nil
+}
+
+// add adds the keys of a node to the listing.
+func (l *keyringListing) add(r serf.NodeResponse) error {
+	if len(r.Payload) < 1 || r.Payload[0] != serfKeyResponseType {
+		return fmt.Errorf("Invalid key query response type: %v", r.Payload)
+	}
+	var nodeResp serfNodeKeyResponse
+	if err := codec.NewDecoder(bytes.NewReader(r.Payload[1:]), &codec.MsgpackHandle{}).Decode(&nodeResp); err != nil {
+		return fmt.Errorf("Failed to decode key query response: %v", r.Payload)
+	}
+
+	for _, key := range nodeResp.Keys {
+		l.Keys[key]++
+	}
+	if len(nodeResp.Keys) > 0 {
+		l.PrimaryKeys[nodeResp.Keys[0]]++
+		l.NodeKeys[r.From] = nodeResp.Keys
+	}
+	if !nodeResp.Result {
+		return errors.New(nodeResp.Message)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// kvReplicationMaxRetryBackoff is the max number of seconds to wait
+	// between failed replication attempts of a KV prefix.
+	kvReplicationMaxRetryBackoff = 64
+
+	// kvReplicationWaitTime is the maximum time a replication round waits
+	// for changes in the primary datacenter. It bounds the lag reported
+	// while the primary doesn't change.
+	kvReplicationWaitTime = 30 * time.Second
+
+	// kvReplicationBatchSize is the max size of the values written in a
+	// single transaction. kvReplicationBatchOps is the max number of
+	// operations of a transaction.
+	kvReplicationBatchSize = 256 * 1024
+	kvReplicationBatchOps  = 64
+)
+
+// startKVReplication starts a goroutine for every configured KV prefix
+// which replicates it from the primary datacenter. It does nothing in the
+// primary datacenter.
+func (s *Server) startKVReplication() {
+	s.kvReplicationLock.Lock()
+	defer s.kvReplicationLock.Unlock()
+
+	if s.kvReplicationEnabled || len(s.config.KVReplicationPrefixes) == 0 ||
+		s.config.PrimaryDatacenter == s.config.Datacenter {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.kvReplicationCancel = cancel
+
+	status := make(map[string]*structs.KVReplicationPrefixStatus)
+	for _, prefix := range s.config.KVReplicationPrefixes {
+		status[prefix] = &structs.KVReplicationPrefixStatus{Prefix: prefix, Running: true}
+	}
+	s.kvReplicationStatusLock.Lock()
+	s.kvReplicationStatus = status
+	s.kvReplicationStatusLock.Unlock()
+
+	for _, prefix := range s.config.KVReplicationPrefixes {
+		go s.runKVReplication(ctx, prefix)
+	}
+
+	s.logger.Printf("[INFO] consul: started KV replication of %d prefixes", len(s.config.KVReplicationPrefixes))
+	s.kvReplicationEnabled = true
+}
+
+// stopKVReplication stops the KV replication.
+func (s *Server) stopKVReplication() {
+	s.kvReplicationLock.Lock()
+	defer s.kvReplicationLock.Unlock()
+
+	if !s.kvReplicationEnabled {
+		return
+	}
+
+	s.kvReplicationCancel()
+	s.kvReplicationCancel = nil
+	s.kvReplicationEnabled = false
+
+	s.kvReplicationStatusLock.Lock()
+	for _, status := range s.kvReplicationStatus {
+		status.Running = false
+	}
+	s.kvReplicationStatusLock.Unlock()
+}
+
+// runKVReplication replicates the given prefix until the context is
+// cancelled.
+func (s *Server) runKVReplication(ctx context.Context, prefix string) {
+	var failedAttempts uint
+	var lastRemoteIndex uint64
+	for {
+		index, exit, err := s.replicateKVPrefix(ctx, prefix, lastRemoteIndex)
+		if exit {
+			return
+		}
+
+		if err != nil {
+			lastRemoteIndex = 0
+			s.updateKVReplicationStatus(prefix, func(status *structs.KVReplicationPrefixStatus) {
+				status.LastError = time.Now().Round(time.Second).UTC()
+			})
+			s.logger.Printf("[WARN] consul: KV replication error for prefix %q (will retry if still leader): %v", prefix, err)
+			if (1 << failedAttempts) < kvReplicationMaxRetryBackoff {
+				failedAttempts++
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After((1 << failedAttempts) * time.Second):
+				// do nothing
+			}
+		} else {
+			lastRemoteIndex = index
+			failedAttempts = 0
+			s.updateKVReplicationStatus(prefix, func(status *structs.KVReplicationPrefixStatus) {
+				status.ReplicatedIndex = index
+				status.LastSuccess = time.Now().Round(time.Second).UTC()
+			})
+		}
+	}
+}
+
+// replicateKVPrefix waits for the keys under the prefix in the primary
+// datacenter to change after the given index and copies them to the local
+// state. It returns the remote index and whether replication should stop.
+func (s *Server) replicateKVPrefix(ctx context.Context, prefix string, lastRemoteIndex uint64) (uint64, bool, error) {
+	req := structs.KeyRequest{
+		Datacenter: s.config.PrimaryDatacenter,
+		Key:        prefix,
+		QueryOptions: structs.QueryOptions{
+			Token:         s.tokens.ACLReplicationToken(),
+			AllowStale:    true,
+			MinQueryIndex: lastRemoteIndex,
+			MaxQueryTime:  kvReplicationWaitTime,
+		},
+	}
+	var remote structs.IndexedDirEntries
+	if err := s.RPC("KVS.List", &req, &remote); err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve remote keys: %v", err)
+	}
+
+	// The fetch is a blocking query during which leadership could have been
+	// lost.
+	select {
+	case <-ctx.Done():
+		return 0, true, nil
+	default:
+	}
+
+	// If the remote index ever goes backwards, it's a good indication that
+	// the remote side was rebuilt and we should do a full sync.
+	if remote.Index < lastRemoteIndex {
+		return 0, false, nil
+	}
+
+	_, local, err := s.fsm.State().KVSList(nil, prefix)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve local keys: %v", err)
+	}
+
+	ops, conflicts := kvReplicationOps(local, remote.Entries, s.config.KVReplicationConflictPolicy)
+	if len(ops) > 0 {
+		if err := s.applyKVReplicationOps(ops); err != nil {
+			return 0, false, err
+		}
+		s.logger.Printf("[DEBUG] consul: KV replication of prefix %q applied %d changes through remote index %d",
+			prefix, len(ops), remote.Index)
+	}
+
+	s.updateKVReplicationStatus(prefix, func(status *structs.KVReplicationPrefixStatus) {
+		status.Keys = len(remote.Entries)
+		status.Conflicts = conflicts
+	})
+	return remote.Index, false, nil
+}
+
+// kvReplicationOps returns the operations which make the local entries
+// match the remote entries and the number of local entries which are kept
+// according to the conflict policy.
+func kvReplicationOps(local, remote structs.DirEntries, policy string) (structs.TxnOps, int) {
+	localKeys := make(map[string]*structs.DirEntry, len(local))
+	for _, entry := range local {
+		localKeys[entry.Key] = entry
+	}
+
+	var ops structs.TxnOps
+	for _, entry := range remote {
+		existing, ok := localKeys[entry.Key]
+		delete(localKeys, entry.Key)
+		if ok && existing.Flags == entry.Flags && bytes.Equal(existing.Value, entry.Value) {
+			continue
+		}
+
+		// Sessions are local to a datacenter so locks aren't replicated.
+		ops = append(ops, &structs.TxnOp{
+			KV: &structs.TxnKVOp{
+				Verb: api.KVSet,
+				DirEnt: structs.DirEntry{
+					Key:   entry.Key,
+					Flags: entry.Flags,
+					Value: entry.Value,
+				},
+			},
+		})
+	}
+
+	if policy == structs.KVReplicationKeepLocal {
+		return ops, len(localKeys)
+	}
+	for _, entry := range local {
+		if _, ok := localKeys[entry.Key]; !ok {
+			continue
+		}
+		ops = append(ops, &structs.TxnOp{
+			KV: &structs.TxnKVOp{
+				Verb:   api.KVDelete,
+				DirEnt: structs.DirEntry{Key: entry.Key},
+			},
+		})
+	}
+	return ops, 0
+}
+
+// applyKVReplicationOps applies the given operations in transactions which
+// are limited in size.
+func (s *Server) applyKVReplicationOps(ops structs.TxnOps) error {
+	defer metrics.MeasureSince([]string{"leader", "replication", "kv", "apply"}, time.Now())
+
+	for len(ops) > 0 {
+		var size, n int
+		for n < len(ops) && n < kvReplicationBatchOps {
+			size += len(ops[n].KV.DirEnt.Value)
+			n++
+			if size >= kvReplicationBatchSize {
+				break
+			}
+		}
+
+		req := structs.TxnRequest{
+			Datacenter: s.config.Datacenter,
+			Ops:        ops[:n],
+		}
+		resp, err := s.raftApply(structs.TxnRequestType, &req)
+		if err != nil {
+			return fmt.Errorf("failed to apply KV changes: %v", err)
+		}
+		if respErr, ok := resp.(error); ok {
+			return fmt.Errorf("failed to apply KV changes: %v", respErr)
+		}
+		if txnResp, ok := resp.(structs.TxnResponse); ok && len(txnResp.Errors) > 0 {
+			return fmt.Errorf("failed to apply KV changes: %v", txnResp.Error())
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// updateKVReplicationStatus updates the replication status of the given
+// prefix.
+func (s *Server) updateKVReplicationStatus(prefix string, fn func(*structs.KVReplicationPrefixStatus)) {
+	s.kvReplicationStatusLock.Lock()
+	defer s.kvReplicationStatusLock.Unlock()
+
+	if status, ok := s.kvReplicationStatus[prefix]; ok {
+		fn(status)
+	}
+}
+
+// getKVReplicationStatus returns the current status of the KV replication.
+func (s *Server) getKVReplicationStatus() structs.KVReplicationStatus {
+	out := structs.KVReplicationStatus{
+		Enabled:          len(s.config.KVReplicationPrefixes) > 0 && s.config.PrimaryDatacenter != s.config.Datacenter,
+		SourceDatacenter: s.config.PrimaryDatacenter,
+		ConflictPolicy:   s.config.KVReplicationConflictPolicy,
+	}
+	if !out.Enabled {
+		return out
+	}
+
+	s.kvReplicationStatusLock.RLock()
+	defer s.kvReplicationStatusLock.RUnlock()
+
+	now := time.Now()
+	for _, prefix := range s.config.KVReplicationPrefixes {
+		status := &structs.KVReplicationPrefixStatus{Prefix: prefix}
+		if current, ok := s.kvReplicationStatus[prefix]; ok {
+			*status = *current
+		}
+		if !status.LastSuccess.IsZero() {
+			status.Lag = now.Sub(status.LastSuccess)
+		}
+		out.Prefixes = append(out.Prefixes, status)
+	}
+	return out
+}
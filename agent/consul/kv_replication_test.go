@@ -0,0 +1,185 @@
+package consul
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVReplicationOps(t *testing.T) {
+	t.Parallel()
+	local := structs.DirEntries{
+		{Key: "shared/a", Value: []byte("a")},
+		{Key: "shared/b", Value: []byte("old")},
+		{Key: "shared/c", Value: []byte("c"), Flags: 1},
+		{Key: "shared/local", Value: []byte("local")},
+	}
+	remote := structs.DirEntries{
+		{Key: "shared/a", Value: []byte("a")},
+		{Key: "shared/b", Value: []byte("new")},
+		{Key: "shared/c", Value: []byte("c"), Flags: 2},
+		{Key: "shared/d", Value: []byte("d")},
+	}
+
+	ops, conflicts := kvReplicationOps(local, remote, structs.KVReplicationOverwrite)
+	require.Equal(t, 0, conflicts)
+	var got []string
+	for _, op := range ops {
+		got = append(got, string(op.KV.Verb)+" "+op.KV.DirEnt.Key)
+	}
+	require.Equal(t, []string{
+		"set shared/b",
+		"set shared/c",
+		"set shared/d",
+		"delete shared/local",
+	}, got)
+
+	ops, conflicts = kvReplicationOps(local, remote, structs.KVReplicationKeepLocal)
+	require.Equal(t, 1, conflicts)
+	require.Len(t, ops, 3)
+}
+
+func TestKVReplication(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	codec1 := rpcClient(t, s1)
+	defer codec1.Close()
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.PrimaryDatacenter = "dc1"
+		c.KVReplicationPrefixes = []string{"shared/"}
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	kvSet := func(key, value string) {
+		arg := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   key,
+				Value: []byte(value),
+			},
+		}
+		var out bool
+		require.NoError(t, msgpackrpc.CallWithCodec(codec1, "KVS.Apply", &arg, &out))
+	}
+	kvSet("shared/a", "a")
+	kvSet("other/b", "b")
+
+	// A local key which doesn't exist in the primary is deleted.
+	arg := structs.KVSRequest{
+		Datacenter: "dc2",
+		Op:         api.KVSet,
+		DirEnt:     structs.DirEntry{Key: "shared/local", Value: []byte("local")},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec2, "KVS.Apply", &arg, &out))
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+
+	checkKeys := func(r *retry.R, want map[string]string) {
+		_, entries, err := s2.fsm.State().KVSList(nil, "")
+		if err != nil {
+			r.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, e := range entries {
+			got[e.Key] = string(e.Value)
+		}
+		if len(got) != len(want) {
+			r.Fatalf("bad: %v", got)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				r.Fatalf("bad: %v", got)
+			}
+		}
+	}
+	retry.Run(t, func(r *retry.R) {
+		checkKeys(r, map[string]string{"shared/a": "a"})
+	})
+
+	// Changes in the primary are replicated.
+	kvSet("shared/a", "updated")
+	kvSet("shared/c", "c")
+	retry.Run(t, func(r *retry.R) {
+		checkKeys(r, map[string]string{"shared/a": "updated", "shared/c": "c"})
+	})
+
+	retry.Run(t, func(r *retry.R) {
+		args := structs.DCSpecificRequest{Datacenter: "dc2"}
+		var status structs.KVReplicationStatus
+		if err := msgpackrpc.CallWithCodec(codec2, "Operator.KVReplicationStatus", &args, &status); err != nil {
+			r.Fatal(err)
+		}
+		if !status.Enabled || status.SourceDatacenter != "dc1" || len(status.Prefixes) != 1 {
+			r.Fatalf("bad: %#v", status)
+		}
+		prefix := status.Prefixes[0]
+		if prefix.Prefix != "shared/" || !prefix.Running || prefix.Keys != 2 ||
+			prefix.ReplicatedIndex == 0 || prefix.LastSuccess.IsZero() {
+			r.Fatalf("bad: %#v", prefix)
+		}
+	})
+}
+
+func TestKVReplication_KeepLocal(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.PrimaryDatacenter = "dc1"
+		c.KVReplicationPrefixes = []string{"shared/"}
+		c.KVReplicationConflictPolicy = structs.KVReplicationKeepLocal
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc2",
+		Op:         api.KVSet,
+		DirEnt:     structs.DirEntry{Key: "shared/local", Value: []byte("local")},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec2, "KVS.Apply", &arg, &out))
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+
+	retry.Run(t, func(r *retry.R) {
+		args := structs.DCSpecificRequest{Datacenter: "dc2"}
+		var status structs.KVReplicationStatus
+		if err := msgpackrpc.CallWithCodec(codec2, "Operator.KVReplicationStatus", &args, &status); err != nil {
+			r.Fatal(err)
+		}
+		if len(status.Prefixes) != 1 || status.Prefixes[0].LastSuccess.IsZero() || status.Prefixes[0].Conflicts != 1 {
+			r.Fatalf("bad: %#v", status)
+		}
+	})
+
+	_, entry, err := s2.fsm.State().KVSGet(nil, "shared/local")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+}
@@ -33,7 +33,7 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 		switch op {
 		case api.KVDeleteTree:
 			if !rule.KeyWritePrefix(dirEnt.Key) {
-				return false, acl.ErrPermissionDenied
+				return false, acl.PermissionDenied("key_prefix", dirEnt.Key, "write")
 			}
 
 		case api.KVGet, api.KVGetTree:
@@ -44,7 +44,7 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 			// of the transaction, and they operate on individual
 			// keys so we check them here.
 			if !rule.KeyRead(dirEnt.Key) {
-				return false, acl.ErrPermissionDenied
+				return false, acl.PermissionDenied("key", dirEnt.Key, "read")
 			}
 
 		default:
@@ -52,7 +52,7 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 				return sentinel.ScopeKVUpsert(dirEnt.Key, dirEnt.Value, dirEnt.Flags)
 			}
 			if !rule.KeyWrite(dirEnt.Key, scope) {
-				return false, acl.ErrPermissionDenied
+				return false, acl.PermissionDenied("key", dirEnt.Key, "write")
 			}
 		}
 	}
@@ -69,6 +69,7 @@ func kvsPreApply(srv *Server, rule acl.Authorizer, op api.KVOp, dirEnt *structs.
 		if expires.After(time.Now()) {
 			srv.logger.Printf("[WARN] consul.kvs: Rejecting lock of %s due to lock-delay until %v",
 				dirEnt.Key, expires)
+			state.KVSLockRejected(dirEnt.Key, dirEnt.Session)
 			return false, nil
 		}
 	}
@@ -84,7 +85,7 @@ func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 	defer metrics.MeasureSince([]string{"kvs", "apply"}, time.Now())
 
 	// Perform the pre-apply checks.
-	acl, err := k.srv.ResolveToken(args.Token)
+	acl, err := k.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -97,6 +98,16 @@ func (k *KVS) Apply(args *structs.KVSRequest, reply *bool) error {
 		return nil
 	}
 
+	// Deleted entries are moved to the recycle bin if it's enabled. The
+	// deletion time is set by the leader so all the servers agree on it.
+	args.RecycledAt = time.Time{}
+	if k.srv.config.KVRecycleBinRetention > 0 {
+		switch args.Op {
+		case api.KVDelete, api.KVDeleteCAS, api.KVDeleteTree:
+			args.RecycledAt = time.Now().UTC()
+		}
+	}
+
 	// Apply the update.
 	resp, err := k.srv.raftApply(structs.KVSRequestType, args)
 	if err != nil {
@@ -120,7 +131,7 @@ func (k *KVS) Get(args *structs.KeyRequest, reply *structs.IndexedDirEntries) er
 		return err
 	}
 
-	aclRule, err := k.srv.ResolveToken(args.Token)
+	aclRule, err := k.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -133,7 +144,7 @@ func (k *KVS) Get(args *structs.KeyRequest, reply *structs.IndexedDirEntries) er
 				return err
 			}
 			if aclRule != nil && !aclRule.KeyRead(args.Key) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("key", args.Key, "read")
 			}
 
 			if ent == nil {
@@ -159,13 +170,13 @@ func (k *KVS) List(args *structs.KeyRequest, reply *structs.IndexedDirEntries) e
 		return err
 	}
 
-	aclToken, err := k.srv.ResolveToken(args.Token)
+	aclToken, err := k.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 
 	if aclToken != nil && k.srv.config.ACLEnableKeyListPolicy && !aclToken.KeyList(args.Key) {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("key", args.Key, "list")
 	}
 
 	return k.srv.blockingQuery(
@@ -203,13 +214,13 @@ func (k *KVS) ListKeys(args *structs.KeyListRequest, reply *structs.IndexedKeyLi
 		return err
 	}
 
-	aclToken, err := k.srv.ResolveToken(args.Token)
+	aclToken, err := k.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 
 	if aclToken != nil && k.srv.config.ACLEnableKeyListPolicy && !aclToken.KeyList(args.Prefix) {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("key", args.Prefix, "list")
 	}
 
 	return k.srv.blockingQuery(
@@ -236,3 +247,89 @@ func (k *KVS) ListKeys(args *structs.KeyListRequest, reply *structs.IndexedKeyLi
 			return nil
 		})
 }
+
+// ListRecycled is used to list the entries of the recycle bin with a given
+// prefix.
+func (k *KVS) ListRecycled(args *structs.KeyRequest, reply *structs.IndexedRecycledDirEntries) error {
+	if done, err := k.srv.forward("KVS.ListRecycled", args, args, reply); done {
+		return err
+	}
+
+	aclToken, err := k.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+
+	if aclToken != nil && k.srv.config.ACLEnableKeyListPolicy && !aclToken.KeyList(args.Key) {
+		return acl.PermissionDenied("key", args.Key, "list")
+	}
+
+	return k.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, ent, err := state.KVSRecycled(ws, args.Key)
+			if err != nil {
+				return err
+			}
+			if aclToken != nil {
+				ent = FilterRecycledDirEnt(aclToken, ent)
+			}
+
+			// Must provide non-zero index to prevent blocking
+			// Index 1 is impossible anyways (due to Raft internals)
+			if index == 0 {
+				reply.Index = 1
+			} else {
+				reply.Index = index
+			}
+			reply.Entries = ent
+			return nil
+		})
+}
+
+// RecycleBin is used to restore or purge the recycled entry of a key, or of
+// all the keys with a given prefix. The number of entries which were
+// restored or purged is returned.
+func (k *KVS) RecycleBin(args *structs.KVSRecycleBinRequest, reply *int) error {
+	if done, err := k.srv.forward("KVS.RecycleBin", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"kvs", "recycle_bin"}, time.Now())
+
+	switch args.Op {
+	case structs.KVSRecycleBinRestore, structs.KVSRecycleBinPurge:
+	default:
+		return fmt.Errorf("Invalid recycle bin operation '%s'", args.Op)
+	}
+	if args.Key == "" && !args.Recurse {
+		return fmt.Errorf("Must provide key")
+	}
+
+	// Restoring or purging entries requires write access to the keys.
+	rule, err := k.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+	if rule != nil {
+		if args.Recurse && !rule.KeyWritePrefix(args.Key) {
+			return acl.PermissionDenied("key_prefix", args.Key, "write")
+		}
+		if !args.Recurse && !rule.KeyWrite(args.Key, nil) {
+			return acl.PermissionDenied("key", args.Key, "write")
+		}
+	}
+
+	resp, err := k.srv.raftApply(structs.KVSRecycleBinRequestType, args)
+	if err != nil {
+		k.srv.logger.Printf("[ERR] consul.kvs: Recycle bin %s failed: %v", args.Op, err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	if n, ok := resp.(int); ok {
+		*reply = n
+	}
+	return nil
+}
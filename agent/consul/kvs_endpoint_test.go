@@ -2,6 +2,7 @@ package consul
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -970,3 +971,217 @@ key "test/priv" {
 	policy = "read"
 }
 `
+
+func TestKVS_RecycleBin(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.KVRecycleBinRetention = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "test",
+			Flags: 42,
+			Value: []byte("test"),
+		},
+	}
+	var out bool
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	arg.Op = api.KVDelete
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The deleted key should be in the recycle bin.
+	getR := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        "te",
+	}
+	var recycled structs.IndexedRecycledDirEntries
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.ListRecycled", &getR, &recycled); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(recycled.Entries) != 1 {
+		t.Fatalf("bad: %v", recycled.Entries)
+	}
+	e := recycled.Entries[0]
+	if e.Key != "test" || e.Flags != 42 || string(e.Value) != "test" || e.DeletedAt.IsZero() {
+		t.Fatalf("bad: %v", e)
+	}
+
+	// Restore it.
+	binArg := structs.KVSRecycleBinRequest{
+		Datacenter: "dc1",
+		Op:         structs.KVSRecycleBinRestore,
+		Key:        "test",
+	}
+	var n int
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.RecycleBin", &binArg, &n); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+
+	state := s1.fsm.State()
+	_, d, err := state.KVSGet(nil, "test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if d == nil || d.Flags != 42 || string(d.Value) != "test" {
+		t.Fatalf("bad: %v", d)
+	}
+	_, entries, err := state.KVSRecycled(nil, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("bad: %v", entries)
+	}
+
+	// An unknown operation is rejected.
+	binArg.Op = "nope"
+	err = msgpackrpc.CallWithCodec(codec, "KVS.RecycleBin", &binArg, &n)
+	if err == nil || !strings.Contains(err.Error(), "Invalid recycle bin operation") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestKVS_RecycleBin_Disabled(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "test",
+			Value: []byte("test"),
+		},
+	}
+	var out bool
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A recycle time set by the client is ignored.
+	arg.Op = api.KVDelete
+	arg.RecycledAt = time.Now()
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, entries, err := s1.fsm.State().KVSRecycled(nil, "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("bad: %v", entries)
+	}
+}
+
+func TestKVS_RecycleBin_ACLDeny(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.KVRecycleBinRetention = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	keys := []string{
+		"abe",
+		"foo",
+		"test",
+	}
+	for _, key := range keys {
+		arg := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key: key,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var out bool
+		if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		arg.Op = api.KVDelete
+		if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	arg := structs.ACLRequest{
+		Datacenter: "dc1",
+		Op:         structs.ACLSet,
+		ACL: structs.ACL{
+			Name:  "User token",
+			Type:  structs.ACLTokenTypeClient,
+			Rules: testListRules,
+		},
+		WriteRequest: structs.WriteRequest{Token: "root"},
+	}
+	var id string
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Apply", &arg, &id); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the readable entries are listed.
+	getR := structs.KeyRequest{
+		Datacenter:   "dc1",
+		QueryOptions: structs.QueryOptions{Token: id},
+	}
+	var recycled structs.IndexedRecycledDirEntries
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.ListRecycled", &getR, &recycled); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(recycled.Entries) != 2 || recycled.Entries[0].Key != "foo" || recycled.Entries[1].Key != "test" {
+		t.Fatalf("bad: %v", recycled.Entries)
+	}
+
+	// Restoring requires write access.
+	binArg := structs.KVSRecycleBinRequest{
+		Datacenter:   "dc1",
+		Op:           structs.KVSRecycleBinRestore,
+		Key:          "foo",
+		WriteRequest: structs.WriteRequest{Token: id},
+	}
+	var n int
+	err := msgpackrpc.CallWithCodec(codec, "KVS.RecycleBin", &binArg, &n)
+	if !acl.IsErrPermissionDenied(err) {
+		t.Fatalf("err: %v", err)
+	}
+
+	binArg.Key = "test"
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.RecycleBin", &binArg, &n); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+}
@@ -0,0 +1,60 @@
+package consul
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow keeps the durations of the most recent operations so that
+// percentiles of their latency can be reported.
+type latencyWindow struct {
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// newLatencyWindow returns a window which keeps the given number of samples.
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+// Add records the duration of an operation, replacing the oldest one if the
+// window is full.
+func (w *latencyWindow) Add(d time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.samples[w.next] = d
+	w.next++
+	if w.next == len(w.samples) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// Percentile returns the given percentile, between 0 and 100, of the
+// recorded durations. The second return value is false if nothing was
+// recorded yet.
+func (w *latencyWindow) Percentile(p float64) (time.Duration, bool) {
+	w.lock.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	w.lock.Unlock()
+
+	if n == 0 {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	i := int(p / 100 * float64(n))
+	if i >= n {
+		i = n - 1
+	}
+	return sorted[i], true
+}
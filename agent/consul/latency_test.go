@@ -0,0 +1,32 @@
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	t.Parallel()
+	w := newLatencyWindow(10)
+
+	_, ok := w.Percentile(50)
+	require.False(t, ok)
+
+	for i := 1; i <= 5; i++ {
+		w.Add(time.Duration(i) * time.Millisecond)
+	}
+	d, ok := w.Percentile(50)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Millisecond, d)
+	d, _ = w.Percentile(100)
+	require.Equal(t, 5*time.Millisecond, d)
+
+	// Old samples are replaced once the window is full.
+	for i := 0; i < 10; i++ {
+		w.Add(time.Second)
+	}
+	d, _ = w.Percentile(0)
+	require.Equal(t, time.Second, d)
+}
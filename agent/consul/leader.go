@@ -37,6 +37,14 @@ var (
 	// caRootPruneInterval is how often we check for stale CARoots to remove.
 	caRootPruneInterval = time.Hour
 
+	// kvRecycleBinPurgeInterval is how often we check for expired entries in
+	// the KV recycle bin.
+	kvRecycleBinPurgeInterval = time.Minute
+
+	// aclTokenReapingInterval is how often we check for expired ACL tokens
+	// to delete.
+	aclTokenReapingInterval = time.Minute
+
 	// minAutopilotVersion is the minimum Consul version in which Autopilot features
 	// are supported.
 	minAutopilotVersion = version.Must(version.NewVersion("0.8.0"))
@@ -76,6 +84,9 @@ func (s *Server) monitorLeadership() {
 					s.leaderLoop(ch)
 				}(weAreLeaderCh)
 				s.logger.Printf("[INFO] consul: cluster leadership acquired")
+				if s.config.LeadershipChanged != nil {
+					s.config.LeadershipChanged(true)
+				}
 
 			default:
 				if weAreLeaderCh == nil {
@@ -88,6 +99,9 @@ func (s *Server) monitorLeadership() {
 				leaderLoop.Wait()
 				weAreLeaderCh = nil
 				s.logger.Printf("[INFO] consul: cluster leadership lost")
+				if s.config.LeadershipChanged != nil {
+					s.config.LeadershipChanged(false)
+				}
 			}
 		case <-aclUpgradeCh:
 			if atomic.LoadInt32(&s.useNewACLs) == 0 {
@@ -122,6 +136,37 @@ func (s *Server) monitorLeadership() {
 	}
 }
 
+// monitorElections emits metrics about the leader changes and the vote
+// requests seen by this server until it shuts down. Unlike the metrics of
+// the Raft library, they are also emitted by the followers, which makes
+// election churn visible on every server.
+func (s *Server) monitorElections() {
+	ch := make(chan raft.Observation, 16)
+	observer := raft.NewObserver(ch, false, func(o *raft.Observation) bool {
+		switch o.Data.(type) {
+		case raft.LeaderObservation, raft.RequestVoteRequest:
+			return true
+		}
+		return false
+	})
+	s.raft.RegisterObserver(observer)
+	defer s.raft.DeregisterObserver(observer)
+
+	for {
+		select {
+		case o := <-ch:
+			switch o.Data.(type) {
+			case raft.LeaderObservation:
+				metrics.IncrCounter([]string{"election", "leader_changes"}, 1)
+			case raft.RequestVoteRequest:
+				metrics.IncrCounter([]string{"election", "vote_requests"}, 1)
+			}
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
 // leaderLoop runs as long as we are the leader to run various
 // maintenance activities
 func (s *Server) leaderLoop(stopCh chan struct{}) {
@@ -273,6 +318,18 @@ func (s *Server) establishLeadership() error {
 
 	s.startCARootPruning()
 
+	s.startKVRecycleBinPurging()
+
+	s.startACLTokenReaping()
+
+	s.startUIConfigReplication()
+
+	s.startKVReplication()
+
+	s.startIntentionReplication()
+
+	s.startGossipKeyRotation()
+
 	s.setConsistentReadReady()
 	return nil
 }
@@ -293,6 +350,18 @@ func (s *Server) revokeLeadership() error {
 
 	s.stopCARootPruning()
 
+	s.stopKVRecycleBinPurging()
+
+	s.stopACLTokenReaping()
+
+	s.stopUIConfigReplication()
+
+	s.stopKVReplication()
+
+	s.stopIntentionReplication()
+
+	s.stopGossipKeyRotation()
+
 	s.setCAProvider(nil, nil)
 
 	s.stopACLUpgrade()
@@ -1118,6 +1187,191 @@ func (s *Server) stopCARootPruning() {
 	s.caPruningEnabled = false
 }
 
+// startKVRecycleBinPurging starts a goroutine that removes the entries of
+// the KV recycle bin which are past their retention.
+func (s *Server) startKVRecycleBinPurging() {
+	s.kvRecycleBinLock.Lock()
+	defer s.kvRecycleBinLock.Unlock()
+
+	if s.kvRecycleBinEnabled || s.config.KVRecycleBinRetention <= 0 {
+		return
+	}
+
+	s.kvRecycleBinCh = make(chan struct{})
+
+	go func(stopCh chan struct{}) {
+		ticker := time.NewTicker(kvRecycleBinPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := s.purgeKVRecycleBin(); err != nil {
+					s.logger.Printf("[ERR] consul: error purging KV recycle bin: %v", err)
+				}
+			}
+		}
+	}(s.kvRecycleBinCh)
+
+	s.kvRecycleBinEnabled = true
+}
+
+// purgeKVRecycleBin removes the entries of the KV recycle bin which were
+// deleted before the retention.
+func (s *Server) purgeKVRecycleBin() error {
+	deletedBefore := time.Now().UTC().Add(-s.config.KVRecycleBinRetention)
+
+	_, entries, err := s.fsm.State().KVSRecycled(nil, "")
+	if err != nil {
+		return err
+	}
+
+	// Return early if there's nothing to remove.
+	expired := 0
+	for _, e := range entries {
+		if e.DeletedAt.Before(deletedBefore) {
+			expired++
+		}
+	}
+	if expired == 0 {
+		return nil
+	}
+
+	defer metrics.MeasureSince([]string{"leader", "purgeKVRecycleBin"}, time.Now())
+	req := structs.KVSRecycleBinRequest{
+		Datacenter:    s.config.Datacenter,
+		Op:            structs.KVSRecycleBinPurge,
+		Recurse:       true,
+		DeletedBefore: deletedBefore,
+	}
+	resp, err := s.raftApply(structs.KVSRecycleBinRequestType, &req)
+	if err != nil {
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	return nil
+}
+
+// stopKVRecycleBinPurging stops the KV recycle bin purging process.
+func (s *Server) stopKVRecycleBinPurging() {
+	s.kvRecycleBinLock.Lock()
+	defer s.kvRecycleBinLock.Unlock()
+
+	if !s.kvRecycleBinEnabled {
+		return
+	}
+
+	close(s.kvRecycleBinCh)
+	s.kvRecycleBinEnabled = false
+}
+
+// startACLTokenReaping starts a goroutine that deletes the ACL tokens which
+// are past their expiration time.
+func (s *Server) startACLTokenReaping() {
+	s.aclTokenReapingLock.Lock()
+	defer s.aclTokenReapingLock.Unlock()
+
+	if s.aclTokenReapingEnabled || !s.ACLsEnabled() {
+		return
+	}
+
+	s.aclTokenReapingCh = make(chan struct{})
+
+	go func(stopCh chan struct{}) {
+		ticker := time.NewTicker(aclTokenReapingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := s.reapExpiredACLTokens(); err != nil {
+					s.logger.Printf("[ERR] acl: error reaping expired tokens: %v", err)
+				}
+			}
+		}
+	}(s.aclTokenReapingCh)
+
+	s.aclTokenReapingEnabled = true
+}
+
+// reapExpiredACLTokens deletes the expired local tokens, and the expired
+// global tokens when we are in the ACL datacenter. Other datacenters get rid
+// of expired global tokens through replication.
+func (s *Server) reapExpiredACLTokens() error {
+	if s.UseLegacyACLs() {
+		return nil
+	}
+
+	if s.LocalTokensEnabled() {
+		if err := s.reapExpiredTokens(true); err != nil {
+			return err
+		}
+	}
+	if s.InACLDatacenter() {
+		if err := s.reapExpiredTokens(false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reapExpiredTokens deletes the expired local or global tokens in batches.
+func (s *Server) reapExpiredTokens(local bool) error {
+	now := time.Now()
+	for {
+		tokens, err := s.fsm.State().ACLTokenListExpired(local, now, aclBatchDeleteSize)
+		if err != nil {
+			return err
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		req := &structs.ACLTokenBatchDeleteRequest{}
+		for _, token := range tokens {
+			req.TokenIDs = append(req.TokenIDs, token.AccessorID)
+		}
+
+		resp, err := s.raftApply(structs.ACLTokenDeleteRequestType, req)
+		if err != nil {
+			return fmt.Errorf("Failed to apply token expiration deletions: %v", err)
+		}
+
+		// Purge the identities from the cache to prevent using the deleted tokens
+		for _, token := range tokens {
+			s.acls.cache.RemoveIdentity(token.SecretID)
+		}
+
+		if respErr, ok := resp.(error); ok {
+			return respErr
+		}
+
+		s.logger.Printf("[DEBUG] acl: reaped %d expired tokens", len(tokens))
+		if len(tokens) < aclBatchDeleteSize {
+			return nil
+		}
+	}
+}
+
+// stopACLTokenReaping stops the reaping of expired ACL tokens.
+func (s *Server) stopACLTokenReaping() {
+	s.aclTokenReapingLock.Lock()
+	defer s.aclTokenReapingLock.Unlock()
+
+	if !s.aclTokenReapingEnabled {
+		return
+	}
+
+	close(s.aclTokenReapingCh)
+	s.aclTokenReapingEnabled = false
+}
+
 // reconcileReaped is used to reconcile nodes that have failed and been reaped
 // from Serf but remain in the catalog. This is done by looking for unknown nodes with serfHealth checks registered.
 // We generate a "reap" event to cause the node to be cleaned up.
@@ -1321,6 +1575,12 @@ AFTER_CHECK:
 // handleFailedMember is used to mark the node's status
 // as being critical, along with all checks as unknown.
 func (s *Server) handleFailedMember(member serf.Member) error {
+	return s.markMemberCritical(member, "failed", structs.SerfCheckFailedOutput)
+}
+
+// markMemberCritical is used to mark the node's serf health check as
+// critical with the given output. Its services are left untouched.
+func (s *Server) markMemberCritical(member serf.Member, reason, output string) error {
 	// Check if the node exists
 	state := s.fsm.State()
 	_, node, err := state.GetNode(member.Name)
@@ -1339,7 +1599,7 @@ func (s *Server) handleFailedMember(member serf.Member) error {
 			}
 		}
 	}
-	s.logger.Printf("[INFO] consul: member '%s' failed, marking health critical", member.Name)
+	s.logger.Printf("[INFO] consul: member '%s' %s, marking health critical", member.Name, reason)
 
 	// Register with the catalog
 	req := structs.RegisterRequest{
@@ -1352,7 +1612,7 @@ func (s *Server) handleFailedMember(member serf.Member) error {
 			CheckID: structs.SerfCheckID,
 			Name:    structs.SerfCheckName,
 			Status:  api.HealthCritical,
-			Output:  structs.SerfCheckFailedOutput,
+			Output:  output,
 		},
 
 		// If there's existing information about the node, do not
@@ -1364,11 +1624,38 @@ func (s *Server) handleFailedMember(member serf.Member) error {
 }
 
 // handleLeftMember is used to handle members that gracefully
-// left. They are deregistered if necessary.
+// left. They are deregistered if necessary, unless they asked to
+// retain their services by marking their node before leaving.
 func (s *Server) handleLeftMember(member serf.Member) error {
+	_, node, err := s.fsm.State().GetNode(member.Name)
+	if err != nil {
+		return err
+	}
+	if node != nil && node.Meta[structs.MetaRetainServicesKey] == "true" {
+		return s.handleRetainedMember(member)
+	}
 	return s.handleDeregisterMember("left", member)
 }
 
+// handleRetainedMember is used to handle members that gracefully left
+// but retain their services for a fast restart. They are marked as
+// critical like failed members until they rejoin or are reaped.
+func (s *Server) handleRetainedMember(member serf.Member) error {
+	// Do not mark ourself, see handleDeregisterMember.
+	if member.Name == s.config.NodeName {
+		return nil
+	}
+
+	// Remove from Raft peers if this was a server
+	if valid, parts := metadata.IsConsulServer(member); valid {
+		if err := s.removeConsulServer(member, parts.Port); err != nil {
+			return err
+		}
+	}
+
+	return s.markMemberCritical(member, "left", structs.SerfCheckLeftOutput)
+}
+
 // handleReapMember is used to handle members that have been
 // reaped after a prolonged failure. They are deregistered.
 func (s *Server) handleReapMember(member serf.Member) error {
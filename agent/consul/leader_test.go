@@ -1,6 +1,7 @@
 package consul
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
@@ -195,6 +196,69 @@ func TestLeader_LeftMember(t *testing.T) {
 		}
 	})
 }
+func TestLeader_LeftMember_RetainServices(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	dir2, c1 := testClient(t)
+	defer os.RemoveAll(dir2)
+	defer c1.Shutdown()
+
+	joinLAN(t, c1, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	state := s1.fsm.State()
+	retry.Run(t, func(r *retry.R) {
+		_, node, err := state.GetNode(c1.config.NodeName)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if node == nil {
+			r.Fatal("client not registered")
+		}
+	})
+
+	// The agent marks its node before leaving, like it does for services
+	// which aren't deregistered on shutdown.
+	var member serf.Member
+	for _, m := range s1.LANMembers() {
+		if m.Name == c1.config.NodeName {
+			member = m
+		}
+	}
+	req := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       c1.config.NodeName,
+		Address:    member.Addr.String(),
+		NodeMeta:   map[string]string{structs.MetaRetainServicesKey: "true"},
+		Service: &structs.NodeService{
+			ID:      "web",
+			Service: "web",
+		},
+	}
+	var out struct{}
+	require.NoError(t, s1.RPC("Catalog.Register", &req, &out))
+
+	// The leave is processed with the member as the servers last saw it,
+	// the decision doesn't depend on any gossip after the leave.
+	member.Status = serf.StatusLeft
+	require.NoError(t, s1.handleLeftMember(member))
+
+	_, services, err := state.NodeServices(nil, c1.config.NodeName)
+	require.NoError(t, err)
+	require.NotNil(t, services)
+	require.Contains(t, services.Services, "web")
+
+	_, checks, err := state.NodeChecks(nil, c1.config.NodeName)
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+	require.Equal(t, structs.SerfCheckID, checks[0].CheckID)
+	require.Equal(t, api.HealthCritical, checks[0].Status)
+	require.Equal(t, structs.SerfCheckLeftOutput, checks[0].Output)
+}
+
 func TestLeader_ReapMember(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServerWithConfig(t, func(c *Config) {
@@ -1156,3 +1220,77 @@ func TestLeader_PersistIntermediateCAs(t *testing.T) {
 		}
 	})
 }
+
+func TestLeader_KVRecycleBinPurging(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.KVRecycleBinRetention = time.Hour
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Put one expired and one recent entry in the recycle bin.
+	state := s1.fsm.State()
+	now := time.Now().UTC()
+	require.NoError(state.KVSSet(100, &structs.DirEntry{Key: "old"}))
+	require.NoError(state.KVSSet(101, &structs.DirEntry{Key: "new"}))
+	require.NoError(state.KVSRecycle(102, "old", now.Add(-2*time.Hour)))
+	require.NoError(state.KVSRecycle(103, "new", now))
+
+	require.NoError(s1.purgeKVRecycleBin())
+
+	_, entries, err := state.KVSRecycled(nil, "")
+	require.NoError(err)
+	require.Len(entries, 1)
+	require.Equal("new", entries[0].Key)
+}
+
+func TestLeader_ACLTokenReaping(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Put one expired and one unexpired token of each kind in the state.
+	state := s1.fsm.State()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	var tokens structs.ACLTokens
+	for i, local := range []bool{false, true} {
+		for j, expires := range []time.Time{past, future} {
+			expires := expires
+			token := &structs.ACLToken{
+				AccessorID:     fmt.Sprintf("%d%d234d1c-a6ff-4b74-9e04-7b1a37d15c11", i, j),
+				SecretID:       fmt.Sprintf("%d%d234d1c-a6ff-4b74-9e04-7b1a37d15c12", i, j),
+				Local:          local,
+				ExpirationTime: &expires,
+			}
+			tokens = append(tokens, token)
+		}
+	}
+	require.NoError(state.ACLTokensUpsert(100, tokens, true))
+
+	require.NoError(s1.reapExpiredACLTokens())
+
+	for _, token := range tokens {
+		_, out, err := state.ACLTokenGetByAccessor(nil, token.AccessorID)
+		require.NoError(err)
+		if token.IsExpired(time.Now()) {
+			require.Nil(out, "expired token %s was not reaped", token.AccessorID)
+		} else {
+			require.NotNil(out, "token %s was reaped", token.AccessorID)
+		}
+	}
+}
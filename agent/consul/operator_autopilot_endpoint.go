@@ -15,12 +15,12 @@ func (op *Operator) AutopilotGetConfiguration(args *structs.DCSpecificRequest, r
 	}
 
 	// This action requires operator read access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "read")
 	}
 
 	state := op.srv.fsm.State()
@@ -44,12 +44,12 @@ func (op *Operator) AutopilotSetConfiguration(args *structs.AutopilotSetConfigRe
 	}
 
 	// This action requires operator write access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "write")
 	}
 
 	// Apply the update
@@ -80,12 +80,12 @@ func (op *Operator) ServerHealth(args *structs.DCSpecificRequest, reply *autopil
 	}
 
 	// This action requires operator read access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "read")
 	}
 
 	// Exit early if the min Raft version is too low
@@ -0,0 +1,34 @@
+package consul
+
+import (
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// KeyringRotationStatus is used to retrieve the status of the automatic
+// gossip key rotation.
+func (op *Operator) KeyringRotationStatus(args *structs.DCSpecificRequest, reply *structs.GossipKeyRotationStatus) error {
+	// This must be sent to the leader, so we fix the args since we are
+	// re-using a structure where we don't support all the options.
+	args.RequireConsistent = true
+	args.AllowStale = false
+	if done, err := op.srv.forward("Operator.KeyringRotationStatus", args, args, reply); done {
+		return err
+	}
+
+	// This action requires keyring read access.
+	rule, err := op.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.KeyringRead() {
+		return acl.PermissionDenied("keyring", "", "read")
+	}
+
+	status, err := op.srv.getGossipKeyRotationStatus()
+	if err != nil {
+		return err
+	}
+	*reply = *status
+	return nil
+}
@@ -0,0 +1,30 @@
+package consul
+
+import (
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// KVReplicationStatus is used to retrieve the status of the replication of
+// KV prefixes from the primary datacenter.
+func (op *Operator) KVReplicationStatus(args *structs.DCSpecificRequest, reply *structs.KVReplicationStatus) error {
+	// This must be sent to the leader, so we fix the args since we are
+	// re-using a structure where we don't support all the options.
+	args.RequireConsistent = true
+	args.AllowStale = false
+	if done, err := op.srv.forward("Operator.KVReplicationStatus", args, args, reply); done {
+		return err
+	}
+
+	// This action requires operator read access.
+	rule, err := op.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.OperatorRead() {
+		return acl.PermissionDenied("operator", "", "read")
+	}
+
+	*reply = op.srv.getKVReplicationStatus()
+	return nil
+}
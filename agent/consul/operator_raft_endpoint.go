@@ -18,12 +18,12 @@ func (op *Operator) RaftGetConfiguration(args *structs.DCSpecificRequest, reply
 	}
 
 	// This action requires operator read access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorRead() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "read")
 	}
 
 	// We can't fetch the leader and the configuration atomically with
@@ -80,12 +80,12 @@ func (op *Operator) RaftRemovePeerByAddress(args *structs.RaftRemovePeerRequest,
 
 	// This is a super dangerous operation that requires operator write
 	// access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "write")
 	}
 
 	// Since this is an operation designed for humans to use, we will return
@@ -147,12 +147,12 @@ func (op *Operator) RaftRemovePeerByID(args *structs.RaftRemovePeerRequest, repl
 
 	// This is a super dangerous operation that requires operator write
 	// access.
-	rule, err := op.srv.ResolveToken(args.Token)
+	rule, err := op.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && !rule.OperatorWrite() {
-		return acl.ErrPermissionDenied
+		return acl.PermissionDenied("operator", "", "write")
 	}
 
 	// Since this is an operation designed for humans to use, we will return
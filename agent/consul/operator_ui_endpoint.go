@@ -0,0 +1,68 @@
+package consul
+
+import (
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/state"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// UIConfigGet is used to retrieve the UI config. It requires no ACL since
+// the UI reads it for every user.
+func (op *Operator) UIConfigGet(args *structs.DCSpecificRequest, reply *structs.IndexedUIConfig) error {
+	if done, err := op.srv.forward("Operator.UIConfigGet", args, args, reply); done {
+		return err
+	}
+
+	return op.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, config, err := state.UIConfig(ws)
+			if err != nil {
+				return err
+			}
+
+			reply.Index, reply.Config = index, config
+			return nil
+		})
+}
+
+// UIConfigSet is used to set the UI config. The config is owned by the
+// primary datacenter, so requests are forwarded there and the other
+// datacenters replicate it.
+func (op *Operator) UIConfigSet(args *structs.UIConfigSetRequest, reply *bool) error {
+	args.Datacenter = op.srv.config.PrimaryDatacenter
+	if done, err := op.srv.forward("Operator.UIConfigSet", args, args, reply); done {
+		return err
+	}
+
+	// This action requires operator write access.
+	rule, err := op.srv.ResolveRequestToken(args)
+	if err != nil {
+		return err
+	}
+	if rule != nil && !rule.OperatorWrite() {
+		return acl.PermissionDenied("operator", "", "write")
+	}
+
+	if err := args.Config.Validate(); err != nil {
+		return err
+	}
+
+	// Apply the update
+	resp, err := op.srv.raftApply(structs.UIConfigRequestType, args)
+	if err != nil {
+		op.srv.logger.Printf("[ERR] consul.operator: Apply failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	// Check if the return type is a bool.
+	if respBool, ok := resp.(bool); ok {
+		*reply = respBool
+	}
+	return nil
+}
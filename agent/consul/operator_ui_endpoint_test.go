@@ -0,0 +1,179 @@
+package consul
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperator_UIConfig(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Nothing is set by default.
+	get := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var out structs.IndexedUIConfig
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigGet", &get, &out))
+	require.Nil(t, out.Config)
+
+	// Set a config.
+	arg := structs.UIConfigSetRequest{
+		Datacenter: "dc1",
+		Config: structs.UIConfig{
+			DashboardURLTemplates: map[string]string{
+				"*": "https://grafana.example.com/d/{{Service}}?dc={{Datacenter}}",
+			},
+			BannerMessage: "Maintenance tonight",
+		},
+	}
+	var reply bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply))
+
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigGet", &get, &out))
+	require.NotNil(t, out.Config)
+	require.Equal(t, arg.Config.DashboardURLTemplates, out.Config.DashboardURLTemplates)
+	require.Equal(t, "Maintenance tonight", out.Config.BannerMessage)
+	require.Equal(t, structs.UIBannerInfo, out.Config.BannerLevel)
+	require.Equal(t, out.Index, out.Config.ModifyIndex)
+
+	// A CAS with a stale index fails.
+	arg.CAS = true
+	arg.Config.BannerMessage = ""
+	arg.Config.ModifyIndex = out.Config.ModifyIndex - 1
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply))
+	require.False(t, reply)
+
+	// A CAS with the current index succeeds.
+	arg.Config.ModifyIndex = out.Config.ModifyIndex
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply))
+	require.True(t, reply)
+
+	var out2 structs.IndexedUIConfig
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigGet", &get, &out2))
+	require.Empty(t, out2.Config.BannerMessage)
+
+	// An invalid config is rejected.
+	arg.CAS = false
+	arg.Config.BannerLevel = "loud"
+	err := msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid banner level")
+}
+
+func TestOperator_UIConfig_ACLDeny(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Try to set the config without permissions.
+	arg := structs.UIConfigSetRequest{
+		Datacenter: "dc1",
+		Config: structs.UIConfig{
+			BannerMessage: "hello",
+		},
+	}
+	var reply bool
+	err := msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply)
+	if !acl.IsErrPermissionDenied(err) {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an ACL with operator write permissions.
+	var token string
+	{
+		req := structs.ACLRequest{
+			Datacenter: "dc1",
+			Op:         structs.ACLSet,
+			ACL: structs.ACL{
+				Name:  "User token",
+				Type:  structs.ACLTokenTypeClient,
+				Rules: `operator = "write"`,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.Apply", &req, &token))
+	}
+
+	// Now we can update the config.
+	arg.Token = token
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply))
+
+	// Reading the config requires no token.
+	get := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var out structs.IndexedUIConfig
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigGet", &get, &out))
+	require.NotNil(t, out.Config)
+	require.Equal(t, "hello", out.Config.BannerMessage)
+}
+
+func TestOperator_UIConfig_Replication(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.PrimaryDatacenter = "dc1"
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+
+	// Set the config through the secondary datacenter, the request is
+	// forwarded to the primary.
+	codec := rpcClient(t, s2)
+	defer codec.Close()
+	arg := structs.UIConfigSetRequest{
+		Datacenter: "dc2",
+		Config: structs.UIConfig{
+			DashboardURLTemplates: map[string]string{
+				"web": "https://grafana.example.com/d/web",
+			},
+		},
+	}
+	var reply bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "Operator.UIConfigSet", &arg, &reply))
+
+	_, config, err := s1.fsm.State().UIConfig(nil)
+	require.NoError(t, err)
+	require.NotNil(t, config)
+	require.Equal(t, arg.Config.DashboardURLTemplates, config.DashboardURLTemplates)
+
+	// The secondary datacenter replicates the config.
+	retry.Run(t, func(r *retry.R) {
+		_, local, err := s2.fsm.State().UIConfig(nil)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if local == nil || local.DashboardURLTemplates["web"] != "https://grafana.example.com/d/web" {
+			r.Fatalf("bad: %#v", local)
+		}
+	})
+}
@@ -60,7 +60,7 @@ func (p *PreparedQuery) Apply(args *structs.PreparedQueryRequest, reply *string)
 	*reply = args.Query.ID
 
 	// Get the ACL token for the request for the checks below.
-	rule, err := p.srv.ResolveToken(args.Token)
+	rule, err := p.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -71,7 +71,7 @@ func (p *PreparedQuery) Apply(args *structs.PreparedQueryRequest, reply *string)
 	if prefix, ok := args.Query.GetACLPrefix(); ok {
 		if rule != nil && !rule.PreparedQueryWrite(prefix) {
 			p.srv.logger.Printf("[WARN] consul.prepared_query: Operation on prepared query '%s' denied due to ACLs", args.Query.ID)
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("query", prefix, "write")
 		}
 	}
 
@@ -91,7 +91,7 @@ func (p *PreparedQuery) Apply(args *structs.PreparedQueryRequest, reply *string)
 		if prefix, ok := query.GetACLPrefix(); ok {
 			if rule != nil && !rule.PreparedQueryWrite(prefix) {
 				p.srv.logger.Printf("[WARN] consul.prepared_query: Operation on prepared query '%s' denied due to ACLs", args.Query.ID)
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("query", prefix, "write")
 			}
 		}
 	}
@@ -237,11 +237,11 @@ func (p *PreparedQuery) Get(args *structs.PreparedQuerySpecificRequest,
 			reply.Index = index
 			reply.Queries = structs.PreparedQueries{query}
 			if _, ok := query.GetACLPrefix(); !ok {
-				return p.srv.filterACL(args.Token, &reply.Queries[0])
+				return p.srv.filterRequestACL(args, &reply.Queries[0])
 			}
 
 			// Otherwise, attempt to filter it the usual way.
-			if err := p.srv.filterACL(args.Token, reply); err != nil {
+			if err := p.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 
@@ -273,7 +273,7 @@ func (p *PreparedQuery) List(args *structs.DCSpecificRequest, reply *structs.Ind
 			}
 
 			reply.Index, reply.Queries = index, queries
-			return p.srv.filterACL(args.Token, reply)
+			return p.srv.filterRequestACL(args, reply)
 		})
 }
 
@@ -311,7 +311,7 @@ func (p *PreparedQuery) Explain(args *structs.PreparedQueryExecuteRequest,
 	queries := &structs.IndexedPreparedQueries{
 		Queries: structs.PreparedQueries{query},
 	}
-	if err := p.srv.filterACL(args.Token, queries); err != nil {
+	if err := p.srv.filterRequestACL(args, queries); err != nil {
 		return err
 	}
 
@@ -329,11 +329,12 @@ func (p *PreparedQuery) Explain(args *structs.PreparedQueryExecuteRequest,
 // failover logic if no local results are available. This is typically called as
 // part of a DNS lookup, or when executing prepared queries from the HTTP API.
 func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
-	reply *structs.PreparedQueryExecuteResponse) error {
+	reply *structs.PreparedQueryExecuteResponse) (err error) {
 	if done, err := p.srv.forward("PreparedQuery.Execute", args, args, reply); done {
 		return err
 	}
-	defer metrics.MeasureSince([]string{"prepared-query", "execute"}, time.Now())
+	start := time.Now()
+	defer metrics.MeasureSince([]string{"prepared-query", "execute"}, start)
 
 	// We have to do this ourselves since we are not doing a blocking RPC.
 	p.srv.setQueryMeta(&reply.QueryMeta)
@@ -352,6 +353,9 @@ func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
 	if query == nil {
 		return ErrQueryNotFound
 	}
+	defer func() {
+		p.recordExecution(query, reply, start, err)
+	}()
 
 	// Execute the query for the local DC.
 	if err := p.execute(query, reply, args.Connect); err != nil {
@@ -364,7 +368,7 @@ func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
 	if query.Token != "" {
 		token = query.Token
 	}
-	if err := p.srv.filterACL(token, &reply.Nodes); err != nil {
+	if err := p.srv.filterACLFromDatacenter(token, args.QueryOptions.SourceDatacenter, &reply.Nodes); err != nil {
 		return err
 	}
 
@@ -458,6 +462,49 @@ func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
 	return nil
 }
 
+// recordExecution emits the per-query metrics of an execution and logs it if
+// it took longer than the slow query threshold. Queries are identified by their
+// name, or by their ID if they don't have one, so that template queries are
+// counted once for all of the names they match.
+func (p *PreparedQuery) recordExecution(query *structs.PreparedQuery,
+	reply *structs.PreparedQueryExecuteResponse, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	name := query.Name
+	if name == "" {
+		name = query.ID
+	}
+	labels := []metrics.Label{{Name: "query", Value: name}}
+	metrics.IncrCounterWithLabels([]string{"prepared-query", "query", "execute"}, 1, labels)
+	metrics.AddSampleWithLabels([]string{"prepared-query", "query", "execute_time"},
+		float32(elapsed.Seconds()*1000), labels)
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"prepared-query", "query", "error"}, 1, labels)
+	}
+	if reply.Failovers > 0 {
+		metrics.IncrCounterWithLabels([]string{"prepared-query", "query", "failover"}, 1,
+			append(labels, metrics.Label{Name: "datacenter", Value: reply.Datacenter}))
+		metrics.AddSampleWithLabels([]string{"prepared-query", "query", "failover_hops"},
+			float32(reply.Failovers), labels)
+	}
+
+	threshold := p.srv.config.PreparedQuerySlowThreshold
+	if threshold <= 0 || elapsed < threshold {
+		return
+	}
+	switch {
+	case err != nil:
+		p.srv.logger.Printf("[WARN] consul.prepared_query: Slow execution of query '%s' for service '%s' took %v and failed: %v",
+			name, query.Service.Service, elapsed, err)
+	case reply.Failovers > 0:
+		p.srv.logger.Printf("[WARN] consul.prepared_query: Slow execution of query '%s' for service '%s' took %v: no healthy local nodes, got %d nodes from datacenter '%s' after %d failover hops",
+			name, query.Service.Service, elapsed, len(reply.Nodes), reply.Datacenter, reply.Failovers)
+	default:
+		p.srv.logger.Printf("[WARN] consul.prepared_query: Slow execution of query '%s' for service '%s' took %v: got %d nodes from datacenter '%s'",
+			name, query.Service.Service, elapsed, len(reply.Nodes), reply.Datacenter)
+	}
+}
+
 // ExecuteRemote is used when a local node doesn't have any instances of a
 // service available and needs to probe remote DCs. This sends the full query
 // over since the remote side won't have it in its state store, and this doesn't
@@ -489,7 +536,7 @@ func (p *PreparedQuery) ExecuteRemote(args *structs.PreparedQueryExecuteRemoteRe
 	if args.Query.Token != "" {
 		token = args.Query.Token
 	}
-	if err := p.srv.filterACL(token, &reply.Nodes); err != nil {
+	if err := p.srv.filterACLFromDatacenter(token, args.QueryOptions.SourceDatacenter, &reply.Nodes); err != nil {
 		return err
 	}
 
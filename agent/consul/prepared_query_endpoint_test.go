@@ -3296,3 +3296,65 @@ func TestPreparedQuery_queryFailover(t *testing.T) {
 		}
 	}
 }
+
+func TestPreparedQuery_recordExecution_SlowLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	p := &PreparedQuery{
+		srv: &Server{
+			config: config,
+			logger: log.New(&buf, "", 0),
+		},
+	}
+
+	query := &structs.PreparedQuery{
+		ID:   "f004177f-2c28-83b7-4229-eacc25fe55d1",
+		Name: "geo-db",
+		Service: structs.ServiceQuery{
+			Service: "mysql",
+		},
+	}
+	reply := &structs.PreparedQueryExecuteResponse{
+		Datacenter: "dc2",
+		Failovers:  2,
+		Nodes:      make(structs.CheckServiceNodes, 3),
+	}
+	start := time.Now().Add(-time.Second)
+
+	// Nothing is logged with the threshold disabled.
+	p.recordExecution(query, reply, start, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("bad: %s", buf.String())
+	}
+
+	// Nothing is logged for executions faster than the threshold.
+	config.PreparedQuerySlowThreshold = time.Hour
+	p.recordExecution(query, reply, start, nil)
+	if buf.Len() != 0 {
+		t.Fatalf("bad: %s", buf.String())
+	}
+
+	// Slow executions are logged with their failover details.
+	config.PreparedQuerySlowThreshold = 500 * time.Millisecond
+	p.recordExecution(query, reply, start, nil)
+	out := buf.String()
+	for _, want := range []string{"'geo-db'", "'mysql'", "3 nodes", "'dc2'", "2 failover hops"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q: %s", want, out)
+		}
+	}
+
+	// Queries without a name are identified by their ID, and errors are
+	// logged.
+	buf.Reset()
+	query.Name = ""
+	p.recordExecution(query, reply, start, fmt.Errorf("boom"))
+	out = buf.String()
+	for _, want := range []string{query.ID, "failed: boom"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q: %s", want, out)
+		}
+	}
+}
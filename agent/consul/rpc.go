@@ -4,11 +4,15 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
+	"net/rpc"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/state"
 	"github.com/hashicorp/consul/agent/metadata"
 	"github.com/hashicorp/consul/agent/pool"
@@ -21,19 +25,16 @@ import (
 )
 
 const (
-	// maxQueryTime is used to bound the limit of a blocking query
-	maxQueryTime = 600 * time.Second
-
-	// defaultQueryTime is the amount of time we block waiting for a change
-	// if no time is specified. Previously we would wait the maxQueryTime.
-	defaultQueryTime = 300 * time.Second
-
 	// jitterFraction is a the limit to the amount of jitter we apply
 	// to a user specified MaxQueryTime. We divide the specified time by
 	// the fraction. So 16 == 6.25% limit of jitter. This same fraction
 	// is applied to the RPCHoldTimeout
 	jitterFraction = 16
 
+	// rpcRetryMinInterval is the interval before the first retry of an RPC
+	// which is held. It grows exponentially up to RPCRetryMaxInterval.
+	rpcRetryMinInterval = 100 * time.Millisecond
+
 	// Warn if the Raft command is larger than this.
 	// If it's over 1MB something is probably being abusive.
 	raftWarnSize = 1024 * 1024
@@ -144,7 +145,10 @@ func (s *Server) handleMultiplexV2(conn net.Conn) {
 // handleConsulConn is used to service a single Consul RPC connection
 func (s *Server) handleConsulConn(conn net.Conn) {
 	defer conn.Close()
-	rpcCodec := msgpackrpc.NewServerCodec(conn)
+	var rpcCodec rpc.ServerCodec = msgpackrpc.NewServerCodec(conn)
+	if s.config.RequestLog.Enabled() {
+		rpcCodec = &requestLogCodec{ServerCodec: rpcCodec, srv: s, conn: conn}
+	}
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -196,6 +200,7 @@ func canRetry(args interface{}, err error) bool {
 // Returns a bool of if forwarding was performed, as well as any error
 func (s *Server) forward(method string, info structs.RPCInfo, args interface{}, reply interface{}) (bool, error) {
 	var firstCheck time.Time
+	var retries int
 
 	// Handle DC forwarding
 	dc := info.RequestDatacenter()
@@ -205,7 +210,8 @@ func (s *Server) forward(method string, info structs.RPCInfo, args interface{},
 	}
 
 	// Check if we can allow a stale read, ensure our local DB is initialized
-	if info.IsRead() && info.AllowStaleRead() && !s.raft.LastContact().IsZero() {
+	// and has applied the index the client asked for
+	if info.IsRead() && info.AllowStaleRead() && !s.raft.LastContact().IsZero() && s.hasAppliedIndex(info) {
 		return false, nil
 	}
 
@@ -242,9 +248,11 @@ RETRY:
 		firstCheck = time.Now()
 	}
 	if time.Since(firstCheck) < s.config.RPCHoldTimeout {
-		jitter := lib.RandomStagger(s.config.RPCHoldTimeout / jitterFraction)
+		metrics.IncrCounter([]string{"rpc", "retry"}, 1)
+		wait := rpcRetryWait(s.config, retries, firstCheck)
+		retries++
 		select {
-		case <-time.After(jitter):
+		case <-time.After(wait):
 			goto CHECK_LEADER
 		case <-s.leaveCh:
 		case <-s.shutdownCh:
@@ -255,6 +263,22 @@ RETRY:
 	return true, rpcErr
 }
 
+// rpcRetryWait returns how long to wait before the given retry of an RPC
+// which was first attempted at firstCheck. The wait backs off exponentially
+// with jitter, but never exceeds the remaining RPC hold time.
+func rpcRetryWait(config *Config, retry int, firstCheck time.Time) time.Duration {
+	b := lib.Backoff{
+		Min:    rpcRetryMinInterval,
+		Max:    config.RPCRetryMaxInterval,
+		Jitter: config.RPCRetryJitter,
+	}
+	wait := b.Wait(retry)
+	if remaining := config.RPCHoldTimeout - time.Since(firstCheck); wait > remaining {
+		wait = remaining
+	}
+	return wait
+}
+
 // getLeader returns if the current node is the leader, and if not then it
 // returns the leader which is potentially nil if the cluster has not yet
 // elected a leader.
@@ -279,23 +303,79 @@ func (s *Server) getLeader() (bool, *metadata.Server) {
 
 // forwardDC is used to forward an RPC call to a remote DC, or fail if no servers
 func (s *Server) forwardDC(method, dc string, args interface{}, reply interface{}) error {
-	manager, server, ok := s.router.FindRoute(dc)
+	// Requests which another datacenter forwarded to us go straight to
+	// their destination, so that routes through a hub can't loop.
+	info, ok := args.(structs.RPCInfo)
+	forwarded := ok && info.RequestSourceDatacenter() != ""
+	via, err := s.routeDC(dc, forwarded)
+	if err != nil {
+		s.logger.Printf("[WARN] consul.rpc: RPC request for DC %q denied by the RPC routes", dc)
+		return err
+	}
+
+	manager, server, ok := s.router.FindRoute(via)
 	if !ok {
-		s.logger.Printf("[WARN] consul.rpc: RPC request for DC %q, no path found", dc)
+		s.logger.Printf("[WARN] consul.rpc: RPC request for DC %q, no path found", via)
 		return structs.ErrNoDCPath
 	}
 
 	metrics.IncrCounterWithLabels([]string{"rpc", "cross-dc"}, 1,
 		[]metrics.Label{{Name: "datacenter", Value: dc}})
-	if err := s.connPool.RPC(dc, server.Addr, server.Version, method, server.UseTLS, args, reply); err != nil {
+
+	// Record where the request came from so that the destination only
+	// applies the policies which are also valid in this datacenter.
+	if req, ok := args.(interface{ SetSourceDatacenter(string) }); ok && !forwarded {
+		req.SetSourceDatacenter(s.config.Datacenter)
+	}
+	if err := s.connPool.RPC(via, server.Addr, server.Version, method, server.UseTLS, args, reply); err != nil {
 		manager.NotifyFailedServer(server)
-		s.logger.Printf("[ERR] consul: RPC failed to server %s in DC %q: %v", server.Addr, dc, err)
+		s.logger.Printf("[ERR] consul: RPC failed to server %s in DC %q: %v", server.Addr, via, err)
 		return err
 	}
 
 	return nil
 }
 
+// routeDC returns the datacenter a request for the given datacenter is sent
+// to according to the RPC routes. Requests which were already forwarded by
+// another datacenter always go directly to their destination.
+func (s *Server) routeDC(dc string, forwarded bool) (string, error) {
+	for _, route := range s.config.RPCRoutes {
+		if route.Datacenter != dc {
+			continue
+		}
+		if route.Deny {
+			return "", structs.ErrDCRouteDenied
+		}
+		if forwarded || len(route.Via) == 0 {
+			return dc, nil
+		}
+		return pickWeighted(route.Via), nil
+	}
+	return dc, nil
+}
+
+// pickWeighted returns one of the keys of the map at random, with a
+// probability proportional to its value.
+func pickWeighted(weights map[string]int) string {
+	keys := make([]string, 0, len(weights))
+	total := 0
+	for k, w := range weights {
+		keys = append(keys, k)
+		total += w
+	}
+	sort.Strings(keys)
+
+	n := rand.Intn(total)
+	for _, k := range keys {
+		n -= weights[k]
+		if n < 0 {
+			return k
+		}
+	}
+	return keys[len(keys)-1]
+}
+
 // globalRPC is used to forward an RPC request to one server in each datacenter.
 // This will only error for RPC-related errors. Otherwise, application-level
 // errors can be sent in the response objects.
@@ -332,6 +412,15 @@ func (s *Server) globalRPC(method string, args interface{},
 	return nil
 }
 
+// hasAppliedIndex returns whether the local server has applied the Raft
+// index the request asked for, if any.
+func (s *Server) hasAppliedIndex(info structs.RPCInfo) bool {
+	if r, ok := info.(interface{ RequestMinAppliedIndex() uint64 }); ok {
+		return s.raft.AppliedIndex() >= r.RequestMinAppliedIndex()
+	}
+	return true
+}
+
 // raftApply is used to encode a message, run it through raft, and return
 // the FSM response along with any errors
 func (s *Server) raftApply(t structs.MessageType, msg interface{}) (interface{}, error) {
@@ -345,10 +434,12 @@ func (s *Server) raftApply(t structs.MessageType, msg interface{}) (interface{},
 		s.logger.Printf("[WARN] consul: Attempting to apply large raft entry (%d bytes)", n)
 	}
 
+	start := time.Now()
 	future := s.raft.Apply(buf, enqueueLimit)
 	if err := future.Error(); err != nil {
 		return nil, err
 	}
+	s.raftApplyLatency.Add(time.Since(start))
 
 	return future.Response(), nil
 }
@@ -364,18 +455,39 @@ type queryFn func(memdb.WatchSet, *state.Store) error
 func (s *Server) blockingQuery(queryOpts *structs.QueryOptions, queryMeta *structs.QueryMeta,
 	fn queryFn) error {
 	var timeout *time.Timer
+	var recheckCh chan struct{}
+	var authz acl.Authorizer
+
+	// Report the policies of the token which are ignored because of their
+	// scope, since it's otherwise hard to tell why a request forwarded from
+	// another datacenter is denied. This resolves the policies once more, so
+	// it's skipped for local requests, where the scope rarely surprises.
+	if sourceDC := queryOpts.SourceDatacenter; sourceDC != "" && sourceDC != s.config.Datacenter && s.ACLsEnabled() {
+		if names, err := s.acls.filteredPolicyNames(queryOpts.Token, queryOpts.SourceDatacenter); err == nil {
+			queryMeta.FilteredPolicies = names
+		}
+	}
 
 	// Fast path right to the non-blocking query.
 	if queryOpts.MinQueryIndex == 0 {
 		goto RUN_QUERY
 	}
 
+	// Restrict the number of blocking queries a single token can have in
+	// progress on this server.
+	if !s.blockingQueries.Acquire(queryOpts.Token, s.config.MaxBlockingQueriesPerToken) {
+		metrics.IncrCounter([]string{"rpc", "query", "token_limited"}, 1)
+		return structs.ErrTooManyBlockingQueries
+	}
+	defer s.blockingQueries.Release(queryOpts.Token)
+
 	// Restrict the max query time, and ensure there is always one.
-	if queryOpts.MaxQueryTime > maxQueryTime {
-		queryOpts.MaxQueryTime = maxQueryTime
+	if queryOpts.MaxQueryTime > s.config.MaxQueryTime {
+		queryOpts.MaxQueryTime = s.config.MaxQueryTime
 	} else if queryOpts.MaxQueryTime <= 0 {
-		queryOpts.MaxQueryTime = defaultQueryTime
+		queryOpts.MaxQueryTime = s.config.DefaultQueryTime
 	}
+	queryMeta.EffectiveWait = queryOpts.MaxQueryTime
 
 	// Apply a small amount of jitter to the request.
 	queryOpts.MaxQueryTime += lib.RandomStagger(queryOpts.MaxQueryTime / jitterFraction)
@@ -384,12 +496,25 @@ func (s *Server) blockingQuery(queryOpts *structs.QueryOptions, queryMeta *struc
 	timeout = time.NewTimer(queryOpts.MaxQueryTime)
 	defer timeout.Stop()
 
+	// Resolve the token again periodically while blocking, so that
+	// revoking it ends the query in bounded time.
+	if s.config.ACLBlockingQueryRecheckInterval > 0 && s.ACLsEnabled() {
+		a, err := s.acls.ResolveTokenFromDatacenter(queryOpts.Token, queryOpts.SourceDatacenter)
+		if err != nil {
+			return err
+		}
+		authz = a
+		recheckCh = closeAfter(s.config.ACLBlockingQueryRecheckInterval)
+	}
+
 RUN_QUERY:
 	// Update the query metadata.
 	s.setQueryMeta(queryMeta)
 
 	// If the read must be consistent we verify that we are still the leader.
-	if queryOpts.RequireConsistent {
+	// A leader which has not applied the index the client asked for yet
+	// has just been elected and waits for its barrier the same way.
+	if queryOpts.RequireConsistent || queryOpts.MinAppliedIndex > s.raft.AppliedIndex() {
 		if err := s.consistentRead(); err != nil {
 			return err
 		}
@@ -411,6 +536,10 @@ RUN_QUERY:
 		// This channel will be closed if a snapshot is restored and the
 		// whole state store is abandoned.
 		ws.Add(state.AbandonCh())
+
+		if recheckCh != nil {
+			ws.Add(recheckCh)
+		}
 	}
 
 	// Block up to the timeout if we didn't see anything fresh.
@@ -428,6 +557,26 @@ RUN_QUERY:
 	}
 	if err == nil && queryOpts.MinQueryIndex > 0 && queryMeta.Index <= queryOpts.MinQueryIndex {
 		if expired := ws.Watch(timeout.C); !expired {
+			if recheckCh != nil {
+				select {
+				case <-recheckCh:
+					// A deleted token fails the query like a new one
+					// would. If the policies of the token changed, the
+					// current result is returned so that the client
+					// retries with them.
+					current, err := s.acls.ResolveTokenFromDatacenter(queryOpts.Token, queryOpts.SourceDatacenter)
+					if err != nil {
+						return err
+					}
+					if current != authz {
+						return nil
+					}
+					recheckCh = closeAfter(s.config.ACLBlockingQueryRecheckInterval)
+					goto RUN_QUERY
+				default:
+				}
+			}
+
 			// If a restore may have woken us up then bail out from
 			// the query immediately. This is slightly race-ey since
 			// this might have been interrupted for other reasons,
@@ -443,6 +592,13 @@ RUN_QUERY:
 	return err
 }
 
+// closeAfter returns a channel which is closed after the given interval.
+func closeAfter(interval time.Duration) chan struct{} {
+	ch := make(chan struct{})
+	time.AfterFunc(interval, func() { close(ch) })
+	return ch
+}
+
 // setQueryMeta is used to populate the QueryMeta data for an RPC call
 func (s *Server) setQueryMeta(m *structs.QueryMeta) {
 	if s.IsLeader() {
@@ -0,0 +1,62 @@
+package consul
+
+import (
+	"net"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// requestLogCodec wraps the codec of a RPC connection to log requests which
+// are slow or sampled according to the request log configuration. The RPC
+// server handles the requests of a codec one at a time, so the state of the
+// current request is kept in the codec.
+type requestLogCodec struct {
+	rpc.ServerCodec
+	srv  *Server
+	conn net.Conn
+
+	method   string
+	start    time.Time
+	read     bool
+	blocking bool
+	token    string
+}
+
+// ReadRequestHeader implements rpc.ServerCodec.
+func (c *requestLogCodec) ReadRequestHeader(r *rpc.Request) error {
+	err := c.ServerCodec.ReadRequestHeader(r)
+	c.method = r.ServiceMethod
+	c.start = time.Now()
+	c.read, c.blocking, c.token = true, false, ""
+	return err
+}
+
+// ReadRequestBody implements rpc.ServerCodec.
+func (c *requestLogCodec) ReadRequestBody(body interface{}) error {
+	err := c.ServerCodec.ReadRequestBody(body)
+	if info, ok := body.(structs.RPCInfo); ok {
+		c.read = info.IsRead()
+		c.token = info.TokenSecret()
+	}
+	if q, ok := body.(interface{ IsBlockingQuery() bool }); ok {
+		c.blocking = q.IsBlockingQuery()
+	}
+	return err
+}
+
+// WriteResponse implements rpc.ServerCodec.
+func (c *requestLogCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	elapsed := time.Since(c.start)
+	slow, sampled := c.srv.config.RequestLog.Check(c.read, c.blocking, elapsed)
+	switch {
+	case slow:
+		c.srv.logger.Printf("[WARN] consul.rpc: Slow request method=%s latency=%v accessor=%s error=%q %s",
+			c.method, elapsed, c.srv.TokenAccessorID(c.token), r.Error, logConn(c.conn))
+	case sampled:
+		c.srv.logger.Printf("[INFO] consul.rpc: Sampled request method=%s latency=%v accessor=%s error=%q %s",
+			c.method, elapsed, c.srv.TokenAccessorID(c.token), r.Error, logConn(c.conn))
+	}
+	return c.ServerCodec.WriteResponse(r, body)
+}
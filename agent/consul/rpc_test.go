@@ -3,11 +3,14 @@ package consul
 import (
 	"bytes"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/state"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/go-memdb"
@@ -139,6 +142,29 @@ func (m *MockSink) Close() error {
 	return nil
 }
 
+func TestRPC_retryWait(t *testing.T) {
+	t.Parallel()
+	config := &Config{
+		RPCHoldTimeout:      7 * time.Second,
+		RPCRetryMaxInterval: time.Second,
+		RPCRetryJitter:      0,
+	}
+	now := time.Now()
+	require.Equal(t, 100*time.Millisecond, rpcRetryWait(config, 0, now))
+	require.Equal(t, 400*time.Millisecond, rpcRetryWait(config, 2, now))
+	require.Equal(t, time.Second, rpcRetryWait(config, 10, now))
+
+	// The wait never exceeds the remaining hold time.
+	wait := rpcRetryWait(config, 10, now.Add(-6800*time.Millisecond))
+	require.True(t, wait <= 200*time.Millisecond, "wait %v", wait)
+
+	config.RPCRetryJitter = 1
+	for i := 0; i < 10; i++ {
+		wait := rpcRetryWait(config, 1, now)
+		require.True(t, wait >= 200*time.Millisecond && wait < 400*time.Millisecond, "wait %v", wait)
+	}
+}
+
 func TestRPC_blockingQuery(t *testing.T) {
 	t.Parallel()
 	dir, s := testServer(t)
@@ -284,6 +310,299 @@ func TestRPC_blockingQuery(t *testing.T) {
 	}
 }
 
+func TestRPC_blockingQuery_Limits(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	dir, s := testServerWithConfig(t, func(c *Config) {
+		c.MaxQueryTime = 50 * time.Millisecond
+		c.DefaultQueryTime = 20 * time.Millisecond
+		c.MaxBlockingQueriesPerToken = 1
+	})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown()
+
+	fn := func(ws memdb.WatchSet, state *state.Store) error {
+		return nil
+	}
+
+	// A longer wait than allowed is cut down to the max.
+	{
+		opts := structs.QueryOptions{
+			MinQueryIndex: 1,
+			MaxQueryTime:  10 * time.Minute,
+		}
+		var meta structs.QueryMeta
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+		require.Equal(50*time.Millisecond, meta.EffectiveWait)
+	}
+
+	// Without a wait the default is used.
+	{
+		opts := structs.QueryOptions{
+			MinQueryIndex: 1,
+		}
+		var meta structs.QueryMeta
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+		require.Equal(20*time.Millisecond, meta.EffectiveWait)
+	}
+
+	// Non-blocking queries don't report a wait.
+	{
+		var opts structs.QueryOptions
+		var meta structs.QueryMeta
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+		require.Zero(meta.EffectiveWait)
+	}
+
+	// Only one blocking query can be in progress for a token.
+	{
+		require.True(s.blockingQueries.Acquire("busy", 1))
+		opts := structs.QueryOptions{
+			MinQueryIndex: 1,
+			Token:         "busy",
+		}
+		var meta structs.QueryMeta
+		err := s.blockingQuery(&opts, &meta, fn)
+		require.True(structs.IsErrTooManyBlockingQueries(err), "err: %v", err)
+
+		// Non-blocking queries are not limited.
+		opts.MinQueryIndex = 0
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+
+		// Other tokens are not limited either, and release their slot.
+		opts.MinQueryIndex = 1
+		opts.Token = "other"
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+		require.Equal(0, s.blockingQueries.InFlight("other"))
+
+		s.blockingQueries.Release("busy")
+		opts.Token = "busy"
+		require.NoError(s.blockingQuery(&opts, &meta, fn))
+	}
+}
+
+func TestRPC_blockingQuery_ACLRecheck(t *testing.T) {
+	t.Parallel()
+	dir, s := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLBlockingQueryRecheckInterval = 50 * time.Millisecond
+	})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown()
+	codec := rpcClient(t, s)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s.RPC, "dc1")
+
+	// Create a token to block with.
+	var token string
+	{
+		req := structs.ACLRequest{
+			Datacenter: "dc1",
+			Op:         structs.ACLSet,
+			ACL: structs.ACL{
+				Name:  "User token",
+				Type:  structs.ACLTokenTypeClient,
+				Rules: `key "" { policy = "read" }`,
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.Apply", &req, &token))
+	}
+
+	// Block on a query which never wakes up by itself.
+	opts := structs.QueryOptions{
+		Token:         token,
+		MinQueryIndex: 3,
+		MaxQueryTime:  time.Minute,
+	}
+	var meta structs.QueryMeta
+	fn := func(ws memdb.WatchSet, state *state.Store) error {
+		meta.Index = 3
+		ws.Add(make(chan struct{}))
+		return nil
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.blockingQuery(&opts, &meta, fn)
+	}()
+
+	// The query keeps blocking while the token is valid.
+	select {
+	case err := <-errCh:
+		t.Fatalf("query returned early: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Deleting the token ends the query.
+	{
+		req := structs.ACLRequest{
+			Datacenter:   "dc1",
+			Op:           structs.ACLDelete,
+			ACL:          structs.ACL{ID: token},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		var out string
+		require.NoError(t, msgpackrpc.CallWithCodec(codec, "ACL.Apply", &req, &out))
+	}
+	select {
+	case err := <-errCh:
+		require.True(t, acl.IsErrNotFound(err), "unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("query didn't end after the token was deleted")
+	}
+}
+
+func TestRPC_forwardDC_Routes(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.RPCRoutes = []structs.RPCRoute{
+			{Datacenter: "dc2", Deny: true},
+			{Datacenter: "dc3", Via: map[string]int{"dc2": 1}},
+		}
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// The hub denies the requests for dc3, so requests which are routed
+	// through it fail.
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.RPCRoutes = []structs.RPCRoute{
+			{Datacenter: "dc3", Deny: true},
+		}
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc2")
+
+	dir3, s3 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc3"
+	})
+	defer os.RemoveAll(dir3)
+	defer s3.Shutdown()
+	testrpc.WaitForLeader(t, s3.RPC, "dc3")
+
+	joinWAN(t, s2, s1)
+	joinWAN(t, s3, s1)
+
+	var out structs.IndexedNodes
+	err := s1.RPC("Catalog.ListNodes", &structs.DCSpecificRequest{Datacenter: "dc2"}, &out)
+	require.Equal(t, structs.ErrDCRouteDenied, err)
+
+	err = s1.RPC("Catalog.ListNodes", &structs.DCSpecificRequest{Datacenter: "dc3"}, &out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), structs.ErrDCRouteDenied.Error())
+
+	// The hub itself can still reach dc1, and dc3 can reach everything.
+	require.NoError(t, s2.RPC("Catalog.ListNodes", &structs.DCSpecificRequest{Datacenter: "dc1"}, &out))
+	require.NoError(t, s3.RPC("Catalog.ListNodes", &structs.DCSpecificRequest{Datacenter: "dc2"}, &out))
+}
+
+func TestRPC_pickWeighted(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "a", pickWeighted(map[string]int{"a": 1}))
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[pickWeighted(map[string]int{"a": 1, "b": 3})]++
+	}
+	require.Len(t, counts, 2)
+	require.True(t, counts["b"] > counts["a"], "counts: %v", counts)
+}
+
+func TestRPC_forwardDC_PolicyScope(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLMasterToken = "root"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec1 := rpcClient(t, s1)
+	defer codec1.Close()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.PrimaryDatacenter = "dc1"
+		c.ACLDatacenter = "dc1"
+		c.ACLsEnabled = true
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	joinWAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc2")
+
+	// Create a token with a policy which is only valid in dc1.
+	var policy structs.ACLPolicy
+	{
+		req := structs.ACLPolicyUpsertRequest{
+			Datacenter: "dc1",
+			Policy: structs.ACLPolicy{
+				Name:        "dc1-kv",
+				Rules:       `key_prefix "" { policy = "write" }`,
+				Datacenters: []string{"dc1"},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec1, "ACL.PolicyUpsert", &req, &policy))
+	}
+	var token structs.ACLToken
+	{
+		req := structs.ACLTokenUpsertRequest{
+			Datacenter: "dc1",
+			ACLToken: structs.ACLToken{
+				Policies: []structs.ACLTokenPolicyLink{{ID: policy.ID}},
+			},
+			WriteRequest: structs.WriteRequest{Token: "root"},
+		}
+		require.NoError(t, msgpackrpc.CallWithCodec(codec1, "ACL.TokenUpsert", &req, &token))
+	}
+
+	apply := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "foo",
+			Value: []byte("bar"),
+		},
+		WriteRequest: structs.WriteRequest{Token: token.SecretID},
+	}
+	var ok bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec1, "KVS.Apply", &apply, &ok))
+
+	// The policy doesn't apply to requests forwarded from dc2.
+	err := msgpackrpc.CallWithCodec(codec2, "KVS.Apply", &apply, &ok)
+	require.True(t, acl.IsErrPermissionDenied(err), "err: %v", err)
+
+	// Reads report the policy which was ignored.
+	list := structs.KeyRequest{
+		Datacenter:   "dc1",
+		Key:          "",
+		QueryOptions: structs.QueryOptions{Token: token.SecretID},
+	}
+	var out structs.IndexedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec1, "KVS.List", &list, &out))
+	require.Len(t, out.Entries, 1)
+	require.Empty(t, out.FilteredPolicies)
+
+	var out2 structs.IndexedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec2, "KVS.List", &list, &out2))
+	require.Empty(t, out2.Entries)
+	require.Equal(t, []string{"dc1-kv"}, out2.FilteredPolicies)
+}
+
 func TestRPC_ReadyForConsistentReads(t *testing.T) {
 	t.Parallel()
 	dir, s := testServerWithConfig(t, func(c *Config) {
@@ -315,3 +634,73 @@ func TestRPC_ReadyForConsistentReads(t *testing.T) {
 		}
 	})
 }
+
+func TestRPC_hasAppliedIndex(t *testing.T) {
+	t.Parallel()
+	dir, s := testServer(t)
+	defer os.RemoveAll(dir)
+	defer s.Shutdown()
+
+	testrpc.WaitForLeader(t, s.RPC, "dc1")
+
+	applied := s.raft.AppliedIndex()
+	require.True(t, s.hasAppliedIndex(&structs.DCSpecificRequest{}))
+	require.True(t, s.hasAppliedIndex(&structs.DCSpecificRequest{
+		QueryOptions: structs.QueryOptions{MinAppliedIndex: applied},
+	}))
+	require.False(t, s.hasAppliedIndex(&structs.DCSpecificRequest{
+		QueryOptions: structs.QueryOptions{MinAppliedIndex: applied + 100},
+	}))
+
+	// Write requests don't carry an index.
+	require.True(t, s.hasAppliedIndex(&structs.KVSRequest{}))
+}
+
+// lockedBuffer is a bytes.Buffer which can be written by the server while
+// the test reads it.
+type lockedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestRPC_RequestLog(t *testing.T) {
+	t.Parallel()
+	logs := new(lockedBuffer)
+	dir, s := testServerWithConfig(t, func(c *Config) {
+		c.LogOutput = logs
+		c.RequestLog.SlowWriteThreshold = time.Nanosecond
+	})
+	defer os.RemoveAll(dir)
+	defer s.Shutdown()
+	codec := rpcClient(t, s)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s.RPC, "dc1")
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt:     structs.DirEntry{Key: "test", Value: []byte("test")},
+	}
+	var out bool
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Apply", &arg, &out))
+	require.Contains(t, logs.String(), "[WARN] consul.rpc: Slow request method=KVS.Apply")
+
+	// Reads have no threshold.
+	getArgs := structs.KeyRequest{Datacenter: "dc1", Key: "test"}
+	var dirent structs.IndexedDirEntries
+	require.NoError(t, msgpackrpc.CallWithCodec(codec, "KVS.Get", &getArgs, &dirent))
+	require.NotContains(t, logs.String(), "method=KVS.Get")
+}
@@ -66,6 +66,10 @@ const (
 	// open to a server
 	serverMaxStreams = 64
 
+	// raftApplyLatencySamples is the number of recent raft applies whose
+	// latency is reported in the stats.
+	raftApplyLatencySamples = 1024
+
 	// raftLogCacheSize is the maximum number of logs to cache in-memory.
 	// This is used to reduce disk I/O for the recently committed entries.
 	raftLogCacheSize = 512
@@ -135,6 +139,65 @@ type Server struct {
 	caPruningLock    sync.RWMutex
 	caPruningEnabled bool
 
+	// caLeaves tracks the leaf certificates signed while we are the leader.
+	caLeaves *caLeafTracker
+
+	// blockingQueries counts the blocking queries in progress for each
+	// token, to enforce MaxBlockingQueriesPerToken.
+	blockingQueries *blockingQueryTracker
+
+	// kvRecycleBinCh is used to shut down the KV recycle bin purging
+	// goroutine when we lose leadership.
+	kvRecycleBinCh      chan struct{}
+	kvRecycleBinLock    sync.Mutex
+	kvRecycleBinEnabled bool
+
+	// aclTokenReapingCh is used to shut down the reaping of expired ACL
+	// tokens when we lose leadership.
+	aclTokenReapingCh      chan struct{}
+	aclTokenReapingLock    sync.Mutex
+	aclTokenReapingEnabled bool
+
+	// uiConfigReplicationCancel is used to stop the replication of the UI
+	// config from the primary datacenter when we lose leadership.
+	uiConfigReplicationCancel  context.CancelFunc
+	uiConfigReplicationLock    sync.Mutex
+	uiConfigReplicationEnabled bool
+
+	// kvReplicationCancel is used to stop the replication of the KV
+	// prefixes from the primary datacenter when we lose leadership.
+	kvReplicationCancel  context.CancelFunc
+	kvReplicationLock    sync.Mutex
+	kvReplicationEnabled bool
+
+	// intentionReplicationCancel is used to stop the replication of the
+	// intentions from the primary datacenter when we lose leadership.
+	intentionReplicationCancel  context.CancelFunc
+	intentionReplicationLock    sync.Mutex
+	intentionReplicationEnabled bool
+
+	// gossipKeyRotationCh is used to stop the gossip key rotation when
+	// leadership is lost.
+	gossipKeyRotationCh      chan struct{}
+	gossipKeyRotationLock    sync.Mutex
+	gossipKeyRotationEnabled bool
+
+	// gossipKeyRotationLastError (and its associated lock) record the last
+	// error of the gossip key rotation for the status endpoint.
+	gossipKeyRotationLastError     string
+	gossipKeyRotationLastErrorTime time.Time
+	gossipKeyRotationStatusLock    sync.RWMutex
+
+	// kvReplicationStatus (and its associated lock) provide information
+	// about the health of the KV replication, indexed by prefix.
+	kvReplicationStatus     map[string]*structs.KVReplicationPrefixStatus
+	kvReplicationStatusLock sync.RWMutex
+
+	// intentionReplicationStatus (and its associated lock) provide
+	// information about the health of the intention replication.
+	intentionReplicationStatus     structs.IntentionReplicationStatus
+	intentionReplicationStatusLock sync.RWMutex
+
 	// Consul configuration
 	config *Config
 
@@ -170,6 +233,10 @@ type Server struct {
 	raftTransport *raft.NetworkTransport
 	raftInmem     *raft.InmemStore
 
+	// raftApplyLatency keeps the time it took to commit the recent raft
+	// applies of this server.
+	raftApplyLatency *latencyWindow
+
 	// raftNotifyCh is set up by setupRaft() and ensures that we get reliable leader
 	// transition notifications from the Raft layer.
 	raftNotifyCh <-chan bool
@@ -303,6 +370,11 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store) (*
 		return nil, err
 	}
 
+	// Make sure the other servers accept our certificate.
+	if err := tlsConf.VerifyServerCertificate(config.Datacenter); err != nil {
+		return nil, err
+	}
+
 	// Get the incoming TLS config.
 	incomingTLS, err := tlsConf.IncomingTLSConfig()
 	if err != nil {
@@ -329,6 +401,8 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store) (*
 
 	// Create server.
 	s := &Server{
+		blockingQueries:  newBlockingQueryTracker(),
+		caLeaves:         newCALeafTracker(),
 		config:           config,
 		tokens:           tokens,
 		connPool:         connPool,
@@ -346,6 +420,7 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store) (*
 		tombstoneGC:      gc,
 		serverLookup:     NewServerLookup(),
 		shutdownCh:       shutdownCh,
+		raftApplyLatency: newLatencyWindow(raftApplyLatencySamples),
 	}
 
 	// Initialize enterprise specific server functionality
@@ -362,7 +437,7 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store) (*
 	aclConfig := ACLResolverConfig{
 		Config:      config,
 		Delegate:    s,
-		CacheConfig: serverACLCacheConfig,
+		CacheConfig: scaleACLCacheConfig(serverACLCacheConfig, config.MemoryLimit),
 		AutoDisable: false,
 		Logger:      logger,
 		Sentinel:    s.sentinel,
@@ -466,6 +541,9 @@ func NewServerLogger(config *Config, logger *log.Logger, tokens *token.Store) (*
 	// since it can fire events when leadership is obtained.
 	go s.monitorLeadership()
 
+	// Start emitting the election metrics.
+	go s.monitorElections()
+
 	// Start listening for RPC requests.
 	go s.listen(s.Listener)
 
@@ -500,6 +578,7 @@ func (s *Server) setupRaft() error {
 	if err != nil {
 		return err
 	}
+	s.fsm.SetVirtualIPMaxOffset(structs.VirtualIPMaxOffset(s.config.VirtualIPCIDR))
 
 	var serverAddressProvider raft.ServerAddressProvider = nil
 	if s.config.RaftConfig.ProtocolVersion >= 3 { //ServerAddressProvider needs server ids to work correctly, which is only supported in protocol version 3 or higher
@@ -528,12 +607,12 @@ func (s *Server) setupRaft() error {
 		s.config.RaftConfig.LocalID = raft.ServerID(s.config.NodeID)
 	}
 
-	// Build an all in-memory setup for dev mode, otherwise prepare a full
-	// disk-based setup.
+	// Build an all in-memory setup for dev mode unless it should be
+	// persisted, otherwise prepare a full disk-based setup.
 	var log raft.LogStore
 	var stable raft.StableStore
 	var snap raft.SnapshotStore
-	if s.config.DevMode {
+	if s.config.DevMode && !s.config.DevPersist {
 		store := raft.NewInmemStore()
 		s.raftInmem = store
 		stable = store
@@ -607,6 +686,7 @@ func (s *Server) setupRaft() error {
 			if err != nil {
 				return fmt.Errorf("recovery failed to make temp FSM: %v", err)
 			}
+			tmpFsm.SetVirtualIPMaxOffset(structs.VirtualIPMaxOffset(s.config.VirtualIPCIDR))
 			if err := raft.RecoverCluster(s.config.RaftConfig, tmpFsm,
 				log, stable, snap, trans, configuration); err != nil {
 				return fmt.Errorf("recovery failed: %v", err)
@@ -935,6 +1015,39 @@ func (s *Server) IsLeader() bool {
 	return s.raft.State() == raft.Leader
 }
 
+// StepDown makes the leader give up its leadership by removing itself from
+// the Raft configuration, which forces the other servers to elect a new
+// leader. The new leader adds the server back when it reconciles the
+// members, just like a joining server. This is used to inject leadership
+// churn, so it refuses to step down without other peers.
+func (s *Server) StepDown() error {
+	if !s.IsLeader() {
+		return fmt.Errorf("Not the leader")
+	}
+
+	numPeers, err := s.numPeers()
+	if err != nil {
+		return err
+	}
+	if numPeers < 2 {
+		return fmt.Errorf("Cannot step down without other servers")
+	}
+
+	minRaftProtocol, err := s.autopilot.MinRaftProtocol()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Printf("[WARN] consul: stepping down as leader")
+	var future raft.Future
+	if minRaftProtocol >= 2 && s.config.RaftConfig.ProtocolVersion >= 3 {
+		future = s.raft.RemoveServer(raft.ServerID(s.config.NodeID), 0, 0)
+	} else {
+		future = s.raft.RemovePeer(s.raftTransport.LocalAddr())
+	}
+	return future.Error()
+}
+
 // KeyManagerLAN returns the LAN Serf keyring manager
 func (s *Server) KeyManagerLAN() *serf.KeyManager {
 	return s.serfLAN.KeyManager()
@@ -1075,6 +1188,24 @@ func (s *Server) Stats() map[string]map[string]string {
 		stats["serf_wan"] = s.serfWAN.Stats()
 	}
 
+	for _, p := range []int{50, 90, 99} {
+		if d, ok := s.raftApplyLatency.Percentile(float64(p)); ok {
+			stats["raft"][fmt.Sprintf("commit_latency_p%d", p)] = d.String()
+		}
+	}
+	if last := s.fsm.LastSnapshot(); !last.IsZero() {
+		stats["raft"]["last_snapshot_age"] = time.Since(last).Round(time.Second).String()
+	}
+
+	// Autopilot only tracks the health of the servers on the leader.
+	if s.IsLeader() {
+		health := s.autopilot.GetClusterHealth()
+		stats["autopilot"] = map[string]string{
+			"healthy":           fmt.Sprintf("%v", health.Healthy),
+			"failure_tolerance": strconv.Itoa(health.FailureTolerance),
+		}
+	}
+
 	for outerKey, outerValue := range s.enterpriseStats() {
 		if _, ok := stats[outerKey]; ok {
 			for innerKey, innerValue := range outerValue {
@@ -1,5 +1,11 @@
 package consul
 
+import (
+	// Register the auth method types available in OSS.
+	_ "github.com/hashicorp/consul/agent/consul/authmethod/jwtauth"
+	_ "github.com/hashicorp/consul/agent/consul/authmethod/kubeauth"
+)
+
 func init() {
 	registerEndpoint(func(s *Server) interface{} { return &ACL{s} })
 	registerEndpoint(func(s *Server) interface{} { return &Catalog{s} })
@@ -102,7 +102,7 @@ func (s *Server) setupSerf(conf *serf.Config, ch chan serf.Event, path string, w
 	// node which is rather unexpected.
 	conf.EnableNameConflictResolution = false
 
-	if !s.config.DevMode {
+	if !s.config.DevMode || s.config.DevPersist {
 		conf.SnapshotPath = filepath.Join(s.config.DataDir, path)
 	}
 	if err := lib.EnsurePath(conf.SnapshotPath, false); err != nil {
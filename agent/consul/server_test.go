@@ -14,6 +14,7 @@ import (
 	"github.com/hashicorp/consul/agent/metadata"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/agent/token"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib/freeport"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil"
@@ -212,6 +213,62 @@ func TestServer_StartStop(t *testing.T) {
 	}
 }
 
+func TestServer_DevPersist(t *testing.T) {
+	t.Parallel()
+	dir1, conf1 := testServerConfig(t)
+	defer os.RemoveAll(dir1)
+	conf1.Bootstrap = false
+	conf1.DevMode = true
+	conf1.DevPersist = true
+	s1, err := newServer(conf1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "foo",
+			Value: []byte("bar"),
+		},
+	}
+	var applied bool
+	if err := s1.RPC("KVS.Apply", &arg, &applied); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s1.Shutdown()
+
+	// Restart with the same data dir and node id and the state
+	// must still be there.
+	_, conf2 := testServerConfig(t)
+	conf2.Bootstrap = false
+	conf2.DevMode = true
+	conf2.DevPersist = true
+	conf2.DataDir = conf1.DataDir
+	conf2.NodeID = conf1.NodeID
+	conf2.NodeName = conf1.NodeName
+	s2, err := newServer(conf2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer s2.Shutdown()
+	testrpc.WaitForLeader(t, s2.RPC, "dc1")
+
+	getR := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        "foo",
+	}
+	var dirent structs.IndexedDirEntries
+	if err := s2.RPC("KVS.Get", &getR, &dirent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(dirent.Entries) != 1 || string(dirent.Entries[0].Value) != "bar" {
+		t.Fatalf("bad: %v", dirent.Entries)
+	}
+}
+
 func TestServer_JoinLAN(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -444,6 +501,65 @@ func TestServer_LeaveLeader(t *testing.T) {
 	})
 }
 
+func TestServer_StepDown(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	// A single server can't step down.
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	if err := s1.StepDown(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	dir2, s2 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+
+	dir3, s3 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir3)
+	defer s3.Shutdown()
+
+	joinLAN(t, s2, s1)
+	joinLAN(t, s3, s1)
+	servers := []*Server{s1, s2, s3}
+	retry.Run(t, func(r *retry.R) {
+		for _, s := range servers {
+			r.Check(wantPeers(s, 3))
+		}
+	})
+
+	var leader *Server
+	for _, s := range servers {
+		if s.IsLeader() {
+			leader = s
+		} else if err := s.StepDown(); err == nil {
+			t.Fatal("expected error for a follower")
+		}
+	}
+	if leader == nil {
+		t.Fatal("no leader")
+	}
+	if err := leader.StepDown(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Another server takes over and adds the old leader back.
+	retry.Run(t, func(r *retry.R) {
+		if leader.IsLeader() {
+			r.Fatal("still the leader")
+		}
+		for _, s := range servers {
+			if s != leader && s.IsLeader() {
+				r.Check(wantPeers(s, 3))
+				return
+			}
+		}
+		r.Fatal("no new leader")
+	})
+}
+
 func TestServer_Leave(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -849,3 +965,36 @@ func TestServer_RevokeLeadershipIdempotent(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestServer_Stats(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Commit something so that there is a latency to report.
+	arg := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "foo",
+			Value: []byte("bar"),
+		},
+	}
+	var out bool
+	if err := s1.RPC("KVS.Apply", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	stats := s1.Stats()
+	for _, key := range []string{"commit_latency_p50", "commit_latency_p90", "commit_latency_p99"} {
+		if _, ok := stats["raft"][key]; !ok {
+			t.Fatalf("missing raft stat %q: %v", key, stats["raft"])
+		}
+	}
+	if _, ok := stats["autopilot"]["failure_tolerance"]; !ok {
+		t.Fatalf("missing autopilot stats: %v", stats)
+	}
+}
@@ -34,7 +34,7 @@ func (s *Session) Apply(args *structs.SessionRequest, reply *string) error {
 	}
 
 	// Fetch the ACL token, if any, and apply the policy.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -50,12 +50,12 @@ func (s *Session) Apply(args *structs.SessionRequest, reply *string) error {
 				return fmt.Errorf("Unknown session %q", args.Session.ID)
 			}
 			if !rule.SessionWrite(existing.Node) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("session", existing.Node, "write")
 			}
 
 		case structs.SessionCreate:
 			if !rule.SessionWrite(args.Session.Node) {
-				return acl.ErrPermissionDenied
+				return acl.PermissionDenied("session", args.Session.Node, "write")
 			}
 
 		default:
@@ -74,6 +74,13 @@ func (s *Session) Apply(args *structs.SessionRequest, reply *string) error {
 		return fmt.Errorf("Invalid Behavior setting '%s'", args.Session.Behavior)
 	}
 
+	// Ensure the Session metadata is valid if provided
+	if args.Op == structs.SessionCreate {
+		if err := structs.ValidateMetadata(args.Session.Meta, false); err != nil {
+			return fmt.Errorf("Invalid Session Meta: %v", err)
+		}
+	}
+
 	// Ensure the Session TTL is valid if provided
 	if args.Session.TTL != "" {
 		ttl, err := time.ParseDuration(args.Session.TTL)
@@ -160,7 +167,7 @@ func (s *Session) Get(args *structs.SessionSpecificRequest,
 			} else {
 				reply.Sessions = nil
 			}
-			if err := s.srv.filterACL(args.Token, reply); err != nil {
+			if err := s.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return nil
@@ -184,7 +191,7 @@ func (s *Session) List(args *structs.DCSpecificRequest,
 			}
 
 			reply.Index, reply.Sessions = index, sessions
-			if err := s.srv.filterACL(args.Token, reply); err != nil {
+			if err := s.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return nil
@@ -192,7 +199,7 @@ func (s *Session) List(args *structs.DCSpecificRequest,
 }
 
 // NodeSessions is used to get all the sessions for a particular node
-func (s *Session) NodeSessions(args *structs.NodeSpecificRequest,
+func (s *Session) NodeSessions(args *structs.NodeSessionsRequest,
 	reply *structs.IndexedSessions) error {
 	if done, err := s.srv.forward("Session.NodeSessions", args, args, reply); done {
 		return err
@@ -207,8 +214,18 @@ func (s *Session) NodeSessions(args *structs.NodeSpecificRequest,
 				return err
 			}
 
+			if len(args.MetaFilters) > 0 {
+				var filtered structs.Sessions
+				for _, session := range sessions {
+					if structs.SatisfiesMetaFilters(session.Meta, args.MetaFilters) {
+						filtered = append(filtered, session)
+					}
+				}
+				sessions = filtered
+			}
+
 			reply.Index, reply.Sessions = index, sessions
-			if err := s.srv.filterACL(args.Token, reply); err != nil {
+			if err := s.srv.filterRequestACL(args, reply); err != nil {
 				return err
 			}
 			return nil
@@ -236,13 +253,13 @@ func (s *Session) Renew(args *structs.SessionSpecificRequest,
 	}
 
 	// Fetch the ACL token, if any, and apply the policy.
-	rule, err := s.srv.ResolveToken(args.Token)
+	rule, err := s.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
 	if rule != nil && s.srv.config.ACLEnforceVersion8 {
 		if !rule.SessionWrite(session.Node) {
-			return acl.ErrPermissionDenied
+			return acl.PermissionDenied("session", session.Node, "write")
 		}
 	}
 
@@ -1,7 +1,9 @@
 package consul
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -841,6 +843,89 @@ func TestSession_NodeSessions(t *testing.T) {
 	}
 }
 
+func TestSession_NodeSessions_Meta(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	s1.fsm.State().EnsureNode(1, &structs.Node{Node: "foo", Address: "127.0.0.1"})
+	ids := []string{}
+	for i := 0; i < 10; i++ {
+		arg := structs.SessionRequest{
+			Datacenter: "dc1",
+			Op:         structs.SessionCreate,
+			Session: structs.Session{
+				Node: "foo",
+				Meta: map[string]string{"deploy": fmt.Sprintf("deploy-%d", i%2)},
+			},
+		}
+		var out string
+		if err := msgpackrpc.CallWithCodec(codec, "Session.Apply", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if i%2 == 1 {
+			ids = append(ids, out)
+		}
+	}
+
+	getR := structs.NodeSessionsRequest{
+		Datacenter:  "dc1",
+		Node:        "foo",
+		MetaFilters: map[string]string{"deploy": "deploy-1"},
+	}
+	var sessions structs.IndexedSessions
+	if err := msgpackrpc.CallWithCodec(codec, "Session.NodeSessions", &getR, &sessions); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if sessions.Index == 0 {
+		t.Fatalf("Bad: %v", sessions)
+	}
+	if len(sessions.Sessions) != 5 {
+		t.Fatalf("Bad: %v", sessions.Sessions)
+	}
+	for _, s := range sessions.Sessions {
+		if !lib.StrContains(ids, s.ID) {
+			t.Fatalf("bad: %v", s)
+		}
+		if s.Meta["deploy"] != "deploy-1" {
+			t.Fatalf("bad: %v", s)
+		}
+	}
+}
+
+func TestSession_Apply_BadMeta(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	s1.fsm.State().EnsureNode(1, &structs.Node{Node: "foo", Address: "127.0.0.1"})
+	arg := structs.SessionRequest{
+		Datacenter: "dc1",
+		Op:         structs.SessionCreate,
+		Session: structs.Session{
+			Node: "foo",
+			Meta: map[string]string{"consul-reserved": "nope"},
+		},
+	}
+
+	var out string
+	err := msgpackrpc.CallWithCodec(codec, "Session.Apply", &arg, &out)
+	if err == nil || !strings.Contains(err.Error(), "Invalid Session Meta") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestSession_Apply_BadTTL(t *testing.T) {
 	t.Parallel()
 	dir1, s1 := testServer(t)
@@ -9,6 +9,7 @@ package consul
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -17,10 +18,12 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/consul/fsm"
 	"github.com/hashicorp/consul/agent/pool"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/snapshot"
 	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/hashicorp/raft"
 )
 
 // dispatchSnapshotRequest takes an incoming request structure with possibly some
@@ -37,6 +40,9 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 			return nil, structs.ErrNoDCPath
 		}
 
+		if args.SourceDatacenter == "" {
+			args.SourceDatacenter = s.config.Datacenter
+		}
 		snap, err := SnapshotRPC(s.connPool, dc, server.Addr, server.UseTLS, args, in, reply)
 		if err != nil {
 			manager.NotifyFailedServer(server)
@@ -59,10 +65,10 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 	// Verify token is allowed to operate on snapshots. There's only a
 	// single ACL sense here (not read and write) since reading gets you
 	// all the ACLs and you could escalate from there.
-	if rule, err := s.ResolveToken(args.Token); err != nil {
+	if rule, err := s.ResolveRequestToken(args); err != nil {
 		return nil, err
 	} else if rule != nil && !rule.Snapshot() {
-		return nil, acl.ErrPermissionDenied
+		return nil, acl.PermissionDenied("acl", "", "write")
 	}
 
 	// Dispatch the operation.
@@ -140,6 +146,36 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 		// stream back.
 		return ioutil.NopCloser(bytes.NewReader([]byte(""))), nil
 
+	case structs.SnapshotVerify:
+		// Check the archive and decode its state without applying it, so
+		// the caller can tell whether a restore would succeed.
+		var summary structs.SnapshotSummary
+		verifyFn := func(meta *raft.SnapshotMeta, state io.Reader) error {
+			records, err := fsm.Verify(state)
+			if err != nil {
+				return fmt.Errorf("failed to decode snapshot state: %v", err)
+			}
+			summary = structs.SnapshotSummary{
+				ID:      meta.ID,
+				Size:    meta.Size,
+				Index:   meta.Index,
+				Term:    meta.Term,
+				Version: int(meta.Version),
+				Records: records,
+			}
+			return nil
+		}
+		if err := snapshot.VerifyState(s.logger, in, verifyFn); err != nil {
+			return nil, err
+		}
+
+		// Stream the summary back to the caller.
+		buf, err := json.Marshal(&summary)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+
 	default:
 		return nil, fmt.Errorf("unrecognized snapshot op %q", args.Op)
 	}
@@ -2,6 +2,7 @@ package consul
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -408,3 +409,98 @@ func TestSnapshot_AllowStale(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshot_Verify(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Set a key.
+	{
+		args := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   "test",
+				Value: []byte("hello"),
+			},
+		}
+		var out bool
+		if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &args, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Take a snapshot.
+	args := structs.SnapshotRequest{
+		Datacenter: "dc1",
+		Op:         structs.SnapshotSave,
+	}
+	var reply structs.SnapshotResponse
+	snap, err := SnapshotRPC(s1.connPool, s1.config.Datacenter, s1.config.RPCAddr, false,
+		&args, bytes.NewReader([]byte("")), &reply)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Close()
+
+	// Remove the key.
+	{
+		args := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVDelete,
+			DirEnt: structs.DirEntry{
+				Key: "test",
+			},
+		}
+		var out bool
+		if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &args, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Verify the snapshot.
+	args.Op = structs.SnapshotVerify
+	verify, err := SnapshotRPC(s1.connPool, s1.config.Datacenter, s1.config.RPCAddr, false,
+		&args, snap, &reply)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer verify.Close()
+
+	var summary structs.SnapshotSummary
+	if err := json.NewDecoder(verify).Decode(&summary); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if summary.ID == "" || summary.Index == 0 || summary.Version == 0 {
+		t.Fatalf("bad: %#v", summary)
+	}
+	if summary.Records["KVEntries"] != 1 {
+		t.Fatalf("bad: %#v", summary.Records)
+	}
+
+	// The snapshot must not have been applied.
+	getR := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        "test",
+	}
+	var dirent structs.IndexedDirEntries
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Get", &getR, &dirent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(dirent.Entries) != 0 {
+		t.Fatalf("bad: %v", dirent)
+	}
+
+	// A bad snapshot should be rejected.
+	_, err = SnapshotRPC(s1.connPool, s1.config.Datacenter, s1.config.RPCAddr, false,
+		&args, bytes.NewReader([]byte("not a snapshot")), &reply)
+	if err == nil || !strings.Contains(err.Error(), "failed to decompress snapshot") {
+		t.Fatalf("err: %v", err)
+	}
+}
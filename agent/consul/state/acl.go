@@ -1,7 +1,10 @@
 package state
 
 import (
+	"encoding/binary"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/go-memdb"
@@ -58,6 +61,53 @@ func (s *TokenPoliciesIndex) PrefixFromArgs(args ...interface{}) ([]byte, error)
 	return val, nil
 }
 
+// TokenExpirationIndex indexes the tokens which expire by their expiration
+// time, so they can be iterated in the order they expire. LocalFilter picks
+// whether local or global tokens are indexed.
+type TokenExpirationIndex struct {
+	LocalFilter bool
+}
+
+func (s *TokenExpirationIndex) encodeTime(t time.Time) []byte {
+	val := t.Unix()
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(val))
+	return buf
+}
+
+func (s *TokenExpirationIndex) FromObject(obj interface{}) (bool, []byte, error) {
+	token, ok := obj.(*structs.ACLToken)
+	if !ok {
+		return false, nil, fmt.Errorf("object is not an ACLToken")
+	}
+	if s.LocalFilter != token.Local {
+		return false, nil, nil
+	}
+	if !token.HasExpirationTime() {
+		return false, nil, nil
+	}
+	if token.ExpirationTime.Unix() < 0 {
+		return false, nil, fmt.Errorf("token expiration time cannot be before the unix epoch: %s", token.ExpirationTime)
+	}
+
+	return true, s.encodeTime(*token.ExpirationTime), nil
+}
+
+func (s *TokenExpirationIndex) FromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("must provide only a single argument")
+	}
+	arg, ok := args[0].(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("argument must be a time.Time: %#v", args[0])
+	}
+	if arg.Unix() < 0 {
+		return nil, fmt.Errorf("argument must be a time.Time after the unix epoch: %s", args[0])
+	}
+
+	return s.encodeTime(arg), nil
+}
+
 func tokensTableSchema() *memdb.TableSchema {
 	return &memdb.TableSchema{
 		Name: "acl-tokens",
@@ -99,6 +149,27 @@ func tokensTableSchema() *memdb.TableSchema {
 					},
 				},
 			},
+			"authmethod": &memdb.IndexSchema{
+				Name:         "authmethod",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "AuthMethod",
+					Lowercase: true,
+				},
+			},
+			"expires-global": &memdb.IndexSchema{
+				Name:         "expires-global",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer:      &TokenExpirationIndex{LocalFilter: false},
+			},
+			"expires-local": &memdb.IndexSchema{
+				Name:         "expires-local",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer:      &TokenExpirationIndex{LocalFilter: true},
+			},
 
 			//DEPRECATED (ACL-Legacy-Compat) - This index is only needed while we support upgrading v1 to v2 acls
 			// This table indexes all the ACL tokens that do not have an AccessorID
@@ -154,9 +225,53 @@ func policiesTableSchema() *memdb.TableSchema {
 	}
 }
 
+func authMethodsTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl-auth-methods",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Name",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+func bindingRulesTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl-binding-rules",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "ID",
+				},
+			},
+			"authmethod": &memdb.IndexSchema{
+				Name:         "authmethod",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "AuthMethod",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
 func init() {
 	registerSchema(tokensTableSchema)
 	registerSchema(policiesTableSchema)
+	registerSchema(authMethodsTableSchema)
+	registerSchema(bindingRulesTableSchema)
 }
 
 // ACLTokens is used when saving a snapshot
@@ -201,6 +316,46 @@ func (s *Restore) ACLPolicy(policy *structs.ACLPolicy) error {
 	return nil
 }
 
+// ACLAuthMethods is used when saving a snapshot
+func (s *Snapshot) ACLAuthMethods() (memdb.ResultIterator, error) {
+	iter, err := s.tx.Get("acl-auth-methods", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+func (s *Restore) ACLAuthMethod(method *structs.ACLAuthMethod) error {
+	if err := s.tx.Insert("acl-auth-methods", method); err != nil {
+		return fmt.Errorf("failed restoring acl auth method: %s", err)
+	}
+
+	if err := indexUpdateMaxTxn(s.tx, method.ModifyIndex, "acl-auth-methods"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// ACLBindingRules is used when saving a snapshot
+func (s *Snapshot) ACLBindingRules() (memdb.ResultIterator, error) {
+	iter, err := s.tx.Get("acl-binding-rules", "id")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+func (s *Restore) ACLBindingRule(rule *structs.ACLBindingRule) error {
+	if err := s.tx.Insert("acl-binding-rules", rule); err != nil {
+		return fmt.Errorf("failed restoring acl binding rule: %s", err)
+	}
+
+	if err := indexUpdateMaxTxn(s.tx, rule.ModifyIndex, "acl-binding-rules"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
 // ACLBootstrap is used to perform a one-time ACL bootstrap operation on a
 // cluster to get the first management token.
 func (s *Store) ACLBootstrap(idx, resetIndex uint64, token *structs.ACLToken, legacy bool) error {
@@ -489,6 +644,71 @@ func (s *Store) ACLTokenList(ws memdb.WatchSet, local, global bool, policy strin
 	return idx, result, nil
 }
 
+// ACLTokenListPage is like ACLTokenList but lists the tokens in the order of
+// their accessor IDs, so that they can be listed in pages. Only the tokens
+// whose accessor ID starts with prefix are listed, starting at the accessor
+// ID next and up to limit tokens, or all of them if limit is 0. It returns the
+// accessor ID which starts the next page, or an empty string if this is the
+// last page.
+func (s *Store) ACLTokenListPage(ws memdb.WatchSet, local, global bool, policy, prefix, next string, limit int) (uint64, structs.ACLTokens, string, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// The accessor index only looks up prefixes of whole bytes, the last
+	// character of a prefix of odd length is matched below.
+	prefix = strings.ToLower(prefix)
+	next = strings.ToLower(next)
+	indexPrefix := strings.Replace(prefix, "-", "", -1)
+	indexPrefix = indexPrefix[:len(indexPrefix)-len(indexPrefix)%2]
+
+	iter, err := tx.Get("acl-tokens", "accessor_prefix", indexPrefix)
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("failed acl token lookup: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "acl-tokens")
+
+	// go-memdb can't seek to the cursor, the tokens before it are skipped
+	// without being resolved.
+	var result structs.ACLTokens
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		token := raw.(*structs.ACLToken)
+		if token.AccessorID < next || !strings.HasPrefix(token.AccessorID, prefix) {
+			continue
+		}
+		if global != local && token.Local != local {
+			continue
+		}
+		if policy != "" && !tokenHasPolicy(token, policy) {
+			continue
+		}
+
+		if limit > 0 && len(result) == limit {
+			return idx, result, token.AccessorID, nil
+		}
+
+		if err := s.resolveTokenPolicyLinks(tx, token, true); err != nil {
+			return 0, nil, "", err
+		}
+		result = append(result, token)
+	}
+
+	return idx, result, "", nil
+}
+
+// tokenHasPolicy returns whether the token is linked to the policy with the
+// given ID.
+func tokenHasPolicy(token *structs.ACLToken, policyID string) bool {
+	for _, link := range token.Policies {
+		if link.ID == policyID {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Store) ACLTokenListUpgradeable(max int) (structs.ACLTokens, <-chan struct{}, error) {
 	tx := s.db.Txn(false)
 	defer tx.Abort()
@@ -511,6 +731,40 @@ func (s *Store) ACLTokenListUpgradeable(max int) (structs.ACLTokens, <-chan stru
 	return tokens, iter.WatchCh(), nil
 }
 
+// ACLTokenListExpired returns up to max of the local or global tokens which
+// expired before the given time, in the order they expired.
+func (s *Store) ACLTokenListExpired(local bool, asOf time.Time, max int) (structs.ACLTokens, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	index := "expires-global"
+	if local {
+		index = "expires-local"
+	}
+
+	iter, err := tx.Get("acl-tokens", index)
+	if err != nil {
+		return nil, fmt.Errorf("failed acl token listing: %v", err)
+	}
+
+	var tokens structs.ACLTokens
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		token := raw.(*structs.ACLToken)
+		if !token.IsExpired(asOf) {
+			// The tokens are ordered by expiration time so the
+			// remaining ones haven't expired either
+			break
+		}
+
+		tokens = append(tokens, token)
+		if len(tokens) >= max {
+			break
+		}
+	}
+
+	return tokens, nil
+}
+
 // ACLTokenDeleteSecret is used to remove an existing ACL from the state store. If
 // the ACL does not exist this is a no-op and no error is returned.
 func (s *Store) ACLTokenDeleteSecret(idx uint64, secret string) error {
@@ -802,3 +1056,338 @@ func (s *Store) aclPolicyDeleteTxn(tx *memdb.Txn, idx uint64, value, index strin
 	}
 	return nil
 }
+
+func (s *Store) ACLAuthMethodsUpsert(idx uint64, methods structs.ACLAuthMethods) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	for _, method := range methods {
+		if err := s.aclAuthMethodSetTxn(tx, idx, method); err != nil {
+			return err
+		}
+	}
+
+	if err := indexUpdateMaxTxn(tx, idx, "acl-auth-methods"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return nil
+}
+
+func (s *Store) ACLAuthMethodSet(idx uint64, method *structs.ACLAuthMethod) error {
+	return s.ACLAuthMethodsUpsert(idx, structs.ACLAuthMethods{method})
+}
+
+func (s *Store) aclAuthMethodSetTxn(tx *memdb.Txn, idx uint64, method *structs.ACLAuthMethod) error {
+	if method.Name == "" {
+		return ErrMissingACLAuthMethodName
+	}
+
+	if method.Type == "" {
+		return ErrMissingACLAuthMethodType
+	}
+
+	existing, err := tx.First("acl-auth-methods", "id", method.Name)
+	if err != nil {
+		return fmt.Errorf("failed acl auth method lookup: %v", err)
+	}
+
+	// Set the indexes
+	if existing != nil {
+		existingMethod := existing.(*structs.ACLAuthMethod)
+		if method.Type != existingMethod.Type {
+			return fmt.Errorf("Changing the Type of an auth method is not permitted")
+		}
+		method.CreateIndex = existingMethod.CreateIndex
+		method.ModifyIndex = idx
+	} else {
+		method.CreateIndex = idx
+		method.ModifyIndex = idx
+	}
+
+	if err := tx.Insert("acl-auth-methods", method); err != nil {
+		return fmt.Errorf("failed inserting acl auth method: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) ACLAuthMethodGetByName(ws memdb.WatchSet, name string) (uint64, *structs.ACLAuthMethod, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	watchCh, method, err := tx.FirstWatch("acl-auth-methods", "id", name)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl auth method lookup: %v", err)
+	}
+	ws.Add(watchCh)
+
+	idx := maxIndexTxn(tx, "acl-auth-methods")
+	if method == nil {
+		return idx, nil, nil
+	}
+	return idx, method.(*structs.ACLAuthMethod), nil
+}
+
+func (s *Store) ACLAuthMethodList(ws memdb.WatchSet) (uint64, structs.ACLAuthMethods, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	iter, err := tx.Get("acl-auth-methods", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl auth method lookup: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var result structs.ACLAuthMethods
+	for method := iter.Next(); method != nil; method = iter.Next() {
+		result = append(result, method.(*structs.ACLAuthMethod))
+	}
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "acl-auth-methods")
+
+	return idx, result, nil
+}
+
+// ACLTokenListByAuthMethod returns the tokens created with a login to the
+// named auth method.
+func (s *Store) ACLTokenListByAuthMethod(ws memdb.WatchSet, name string) (uint64, structs.ACLTokens, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	iter, err := tx.Get("acl-tokens", "authmethod", name)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl token lookup: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var result structs.ACLTokens
+	for token := iter.Next(); token != nil; token = iter.Next() {
+		result = append(result, token.(*structs.ACLToken))
+	}
+
+	idx := maxIndexTxn(tx, "acl-tokens")
+
+	return idx, result, nil
+}
+
+func (s *Store) ACLAuthMethodDeleteByName(idx uint64, name string) error {
+	return s.ACLAuthMethodsDelete(idx, []string{name})
+}
+
+// ACLAuthMethodsDelete deletes the named auth methods along with the tokens
+// which were created with a login to them.
+func (s *Store) ACLAuthMethodsDelete(idx uint64, names []string) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	for _, name := range names {
+		if err := s.aclAuthMethodDeleteTxn(tx, idx, name); err != nil {
+			return err
+		}
+	}
+
+	if err := indexUpdateMaxTxn(tx, idx, "acl-auth-methods"); err != nil {
+		return fmt.Errorf("failed updating index: %v", err)
+	}
+	tx.Commit()
+	return nil
+}
+
+func (s *Store) aclAuthMethodDeleteTxn(tx *memdb.Txn, idx uint64, name string) error {
+	rawMethod, err := tx.First("acl-auth-methods", "id", name)
+	if err != nil {
+		return fmt.Errorf("failed acl auth method lookup: %v", err)
+	}
+
+	if rawMethod == nil {
+		return nil
+	}
+	method := rawMethod.(*structs.ACLAuthMethod)
+
+	// The tokens of the auth method can't be used without it.
+	iter, err := tx.Get("acl-tokens", "authmethod", method.Name)
+	if err != nil {
+		return fmt.Errorf("failed acl token lookup: %v", err)
+	}
+	var tokens structs.ACLTokens
+	for token := iter.Next(); token != nil; token = iter.Next() {
+		tokens = append(tokens, token.(*structs.ACLToken))
+	}
+	if len(tokens) > 0 {
+		for _, token := range tokens {
+			if err := tx.Delete("acl-tokens", token); err != nil {
+				return fmt.Errorf("failed deleting acl token: %v", err)
+			}
+		}
+		if err := indexUpdateMaxTxn(tx, idx, "acl-tokens"); err != nil {
+			return fmt.Errorf("failed updating index: %v", err)
+		}
+	}
+
+	// The binding rules of the auth method are useless without it.
+	iter, err = tx.Get("acl-binding-rules", "authmethod", method.Name)
+	if err != nil {
+		return fmt.Errorf("failed acl binding rule lookup: %v", err)
+	}
+	var rules structs.ACLBindingRules
+	for rule := iter.Next(); rule != nil; rule = iter.Next() {
+		rules = append(rules, rule.(*structs.ACLBindingRule))
+	}
+	if len(rules) > 0 {
+		for _, rule := range rules {
+			if err := tx.Delete("acl-binding-rules", rule); err != nil {
+				return fmt.Errorf("failed deleting acl binding rule: %v", err)
+			}
+		}
+		if err := indexUpdateMaxTxn(tx, idx, "acl-binding-rules"); err != nil {
+			return fmt.Errorf("failed updating index: %v", err)
+		}
+	}
+
+	if err := tx.Delete("acl-auth-methods", method); err != nil {
+		return fmt.Errorf("failed deleting acl auth method: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) ACLBindingRulesUpsert(idx uint64, rules structs.ACLBindingRules) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	for _, rule := range rules {
+		if err := s.aclBindingRuleSetTxn(tx, idx, rule); err != nil {
+			return err
+		}
+	}
+
+	if err := indexUpdateMaxTxn(tx, idx, "acl-binding-rules"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return nil
+}
+
+func (s *Store) ACLBindingRuleSet(idx uint64, rule *structs.ACLBindingRule) error {
+	return s.ACLBindingRulesUpsert(idx, structs.ACLBindingRules{rule})
+}
+
+func (s *Store) aclBindingRuleSetTxn(tx *memdb.Txn, idx uint64, rule *structs.ACLBindingRule) error {
+	if rule.ID == "" {
+		return ErrMissingACLBindingRuleID
+	}
+
+	if rule.AuthMethod == "" {
+		return ErrMissingACLBindingRuleAuthMethod
+	}
+
+	existing, err := tx.First("acl-binding-rules", "id", rule.ID)
+	if err != nil {
+		return fmt.Errorf("failed acl binding rule lookup: %v", err)
+	}
+
+	// Set the indexes
+	if existing != nil {
+		existingRule := existing.(*structs.ACLBindingRule)
+		if rule.AuthMethod != existingRule.AuthMethod {
+			return fmt.Errorf("Changing the AuthMethod of a binding rule is not permitted")
+		}
+		rule.CreateIndex = existingRule.CreateIndex
+		rule.ModifyIndex = idx
+	} else {
+		rule.CreateIndex = idx
+		rule.ModifyIndex = idx
+	}
+
+	method, err := tx.First("acl-auth-methods", "id", rule.AuthMethod)
+	if err != nil {
+		return fmt.Errorf("failed acl auth method lookup: %v", err)
+	} else if method == nil {
+		return fmt.Errorf("failed inserting acl binding rule: auth method not found")
+	}
+
+	if err := tx.Insert("acl-binding-rules", rule); err != nil {
+		return fmt.Errorf("failed inserting acl binding rule: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) ACLBindingRuleGetByID(ws memdb.WatchSet, id string) (uint64, *structs.ACLBindingRule, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	watchCh, rule, err := tx.FirstWatch("acl-binding-rules", "id", id)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl binding rule lookup: %v", err)
+	}
+	ws.Add(watchCh)
+
+	idx := maxIndexTxn(tx, "acl-binding-rules")
+	if rule == nil {
+		return idx, nil, nil
+	}
+	return idx, rule.(*structs.ACLBindingRule), nil
+}
+
+// ACLBindingRuleList returns the binding rules, only those of the named auth
+// method if it is not empty.
+func (s *Store) ACLBindingRuleList(ws memdb.WatchSet, methodName string) (uint64, structs.ACLBindingRules, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	var (
+		iter memdb.ResultIterator
+		err  error
+	)
+	if methodName != "" {
+		iter, err = tx.Get("acl-binding-rules", "authmethod", methodName)
+	} else {
+		iter, err = tx.Get("acl-binding-rules", "id")
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed acl binding rule lookup: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var result structs.ACLBindingRules
+	for rule := iter.Next(); rule != nil; rule = iter.Next() {
+		result = append(result, rule.(*structs.ACLBindingRule))
+	}
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "acl-binding-rules")
+
+	return idx, result, nil
+}
+
+func (s *Store) ACLBindingRuleDeleteByID(idx uint64, id string) error {
+	return s.ACLBindingRulesDelete(idx, []string{id})
+}
+
+func (s *Store) ACLBindingRulesDelete(idx uint64, ids []string) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	for _, id := range ids {
+		rawRule, err := tx.First("acl-binding-rules", "id", id)
+		if err != nil {
+			return fmt.Errorf("failed acl binding rule lookup: %v", err)
+		}
+
+		if rawRule == nil {
+			continue
+		}
+
+		if err := tx.Delete("acl-binding-rules", rawRule); err != nil {
+			return fmt.Errorf("failed deleting acl binding rule: %v", err)
+		}
+	}
+
+	if err := indexUpdateMaxTxn(tx, idx, "acl-binding-rules"); err != nil {
+		return fmt.Errorf("failed updating index: %v", err)
+	}
+	tx.Commit()
+	return nil
+}
@@ -303,6 +303,167 @@ func TestStateStore_ACLDelete(t *testing.T) {
 }
 */
 
+func TestStateStore_ACLTokens_ListExpired(t *testing.T) {
+	t.Parallel()
+	s := testStateStore(t)
+	setupGlobalManagement(t, s)
+
+	now := time.Now()
+	expires := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	tokens := structs.ACLTokens{
+		&structs.ACLToken{
+			AccessorID:     "a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:       "a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			ExpirationTime: expires(-2 * time.Minute),
+		},
+		&structs.ACLToken{
+			AccessorID:     "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:       "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			ExpirationTime: expires(-3 * time.Minute),
+		},
+		&structs.ACLToken{
+			AccessorID:     "c1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:       "c1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			ExpirationTime: expires(time.Hour),
+		},
+		&structs.ACLToken{
+			AccessorID:     "d1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:       "d1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			ExpirationTime: expires(-time.Minute),
+			Local:          true,
+		},
+		// Tokens without an expiration are never listed.
+		&structs.ACLToken{
+			AccessorID: "e1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:   "e1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+		},
+	}
+	require.NoError(t, s.ACLTokensUpsert(2, tokens, true))
+
+	accessors := func(tokens structs.ACLTokens) []string {
+		var out []string
+		for _, token := range tokens {
+			out = append(out, token.AccessorID)
+		}
+		return out
+	}
+
+	// The expired global tokens are listed in the order they expired.
+	expired, err := s.ACLTokenListExpired(false, now, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+		"a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+	}, accessors(expired))
+
+	// The list is capped at max.
+	expired, err = s.ACLTokenListExpired(false, now, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01"}, accessors(expired))
+
+	expired, err = s.ACLTokenListExpired(true, now, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"d1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01"}, accessors(expired))
+
+	// Later on the other global token has expired too.
+	expired, err = s.ACLTokenListExpired(false, now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 3)
+}
+
+func TestStateStore_ACLTokens_ListPage(t *testing.T) {
+	t.Parallel()
+	s := testStateStore(t)
+	setupGlobalManagement(t, s)
+
+	tokens := structs.ACLTokens{
+		&structs.ACLToken{
+			AccessorID: "c1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:   "c1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+		},
+		&structs.ACLToken{
+			AccessorID: "a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:   "a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			Policies: []structs.ACLTokenPolicyLink{
+				{ID: structs.ACLPolicyGlobalManagementID},
+			},
+		},
+		&structs.ACLToken{
+			AccessorID: "a2b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:   "a2b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			Local:      true,
+		},
+		&structs.ACLToken{
+			AccessorID: "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+			SecretID:   "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a02",
+			Policies: []structs.ACLTokenPolicyLink{
+				{ID: structs.ACLPolicyGlobalManagementID},
+			},
+		},
+	}
+	require.NoError(t, s.ACLTokensUpsert(2, tokens, true))
+
+	accessors := func(tokens structs.ACLTokens) []string {
+		var out []string
+		for _, token := range tokens {
+			out = append(out, token.AccessorID)
+		}
+		return out
+	}
+
+	// The tokens are listed in the order of their accessor IDs.
+	idx, page, next, err := s.ACLTokenListPage(nil, true, true, "", "", "", 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), idx)
+	require.Equal(t, []string{
+		"a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+		"a2b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+	}, accessors(page))
+	require.Equal(t, "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01", next)
+
+	_, page, next, err = s.ACLTokenListPage(nil, true, true, "", "", next, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+		"c1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01",
+	}, accessors(page))
+	require.Equal(t, "", next)
+
+	// The policy links are resolved.
+	require.Equal(t, "global-management", page[0].Policies[0].Name)
+
+	// Prefixes of odd length are matched too.
+	_, page, next, err = s.ACLTokenListPage(nil, true, true, "", "A", "", 0)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, "", next)
+
+	_, page, _, err = s.ACLTokenListPage(nil, true, true, "", "a1b6e5b8-8", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01"}, accessors(page))
+
+	_, page, _, err = s.ACLTokenListPage(nil, true, true, "", "d", "", 0)
+	require.NoError(t, err)
+	require.Empty(t, page)
+
+	// The other filters still apply.
+	_, page, _, err = s.ACLTokenListPage(nil, false, true, "", "a", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01"}, accessors(page))
+
+	_, page, next, err = s.ACLTokenListPage(nil, true, true, structs.ACLPolicyGlobalManagementID, "", "", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01"}, accessors(page))
+	require.Equal(t, "b1b6e5b8-8bd4-4a5e-bd65-1e6a2d6f2a01", next)
+
+	// Invalid prefixes are rejected.
+	_, _, _, err = s.ACLTokenListPage(nil, true, true, "", "nothex", "", 0)
+	require.Error(t, err)
+}
+
 func TestStateStore_ACLTokens_Snapshot_Restore(t *testing.T) {
 	s := testStateStore(t)
 
@@ -442,3 +603,229 @@ func TestStateStore_ACLPolicies_Snapshot_Restore(t *testing.T) {
 		require.Equal(t, uint64(2), s.maxIndex("acl-policies"))
 	}()
 }
+
+func TestStateStore_ACLAuthMethods(t *testing.T) {
+	t.Parallel()
+	s := testStateStore(t)
+
+	require.Equal(t, ErrMissingACLAuthMethodName, s.ACLAuthMethodSet(1, &structs.ACLAuthMethod{Type: "testing"}))
+	require.Equal(t, ErrMissingACLAuthMethodType, s.ACLAuthMethodSet(1, &structs.ACLAuthMethod{Name: "test"}))
+
+	method := &structs.ACLAuthMethod{
+		Name:        "test",
+		Type:        "testing",
+		Description: "test auth method",
+	}
+	require.NoError(t, s.ACLAuthMethodSet(2, method))
+
+	idx, rmethod, err := s.ACLAuthMethodGetByName(nil, "test")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), idx)
+	require.Equal(t, "test auth method", rmethod.Description)
+	require.Equal(t, uint64(2), rmethod.CreateIndex)
+	require.Equal(t, uint64(2), rmethod.ModifyIndex)
+
+	// The type is fixed once the auth method exists.
+	err = s.ACLAuthMethodSet(3, &structs.ACLAuthMethod{Name: "test", Type: "other"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Changing the Type of an auth method is not permitted")
+
+	require.NoError(t, s.ACLAuthMethodSet(4, &structs.ACLAuthMethod{
+		Name:        "test",
+		Type:        "testing",
+		Description: "updated",
+	}))
+	_, rmethod, err = s.ACLAuthMethodGetByName(nil, "test")
+	require.NoError(t, err)
+	require.Equal(t, "updated", rmethod.Description)
+	require.Equal(t, uint64(2), rmethod.CreateIndex)
+	require.Equal(t, uint64(4), rmethod.ModifyIndex)
+
+	require.NoError(t, s.ACLAuthMethodSet(5, &structs.ACLAuthMethod{Name: "other", Type: "testing"}))
+	idx, methods, err := s.ACLAuthMethodList(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), idx)
+	require.Len(t, methods, 2)
+
+	// Deleting an auth method deletes the tokens created with it.
+	tokens := structs.ACLTokens{
+		&structs.ACLToken{
+			AccessorID: "68016c3d-835b-450c-a6f9-75db9ba740be",
+			SecretID:   "838f72b5-5c15-4a9e-aa6d-31734c3a0286",
+			Local:      true,
+			AuthMethod: "test",
+		},
+		&structs.ACLToken{
+			AccessorID: "b2125a1b-2a52-41d4-88f3-c58761998a46",
+			SecretID:   "ba5d9239-a4ab-49b9-ae09-1f19eed92204",
+			Local:      true,
+			AuthMethod: "other",
+		},
+		&structs.ACLToken{
+			AccessorID: "c9b7b1a0-3c5a-4c4e-9a71-0e7dc6c7c1b4",
+			SecretID:   "2e1f3c3a-6b7e-4f5b-8b2a-1a6a3c6e7b0d",
+		},
+	}
+	require.NoError(t, s.ACLTokensUpsert(6, tokens, true))
+
+	_, byMethod, err := s.ACLTokenListByAuthMethod(nil, "test")
+	require.NoError(t, err)
+	require.Len(t, byMethod, 1)
+	require.Equal(t, tokens[0].AccessorID, byMethod[0].AccessorID)
+
+	require.NoError(t, s.ACLAuthMethodDeleteByName(7, "test"))
+
+	_, rmethod, err = s.ACLAuthMethodGetByName(nil, "test")
+	require.NoError(t, err)
+	require.Nil(t, rmethod)
+
+	_, rtoken, err := s.ACLTokenGetByAccessor(nil, tokens[0].AccessorID)
+	require.NoError(t, err)
+	require.Nil(t, rtoken)
+	for _, token := range tokens[1:] {
+		_, rtoken, err := s.ACLTokenGetByAccessor(nil, token.AccessorID)
+		require.NoError(t, err)
+		require.NotNil(t, rtoken)
+	}
+	require.Equal(t, uint64(7), s.maxIndex("acl-tokens"))
+
+	// Deleting a missing auth method is not an error.
+	require.NoError(t, s.ACLAuthMethodDeleteByName(8, "test"))
+}
+
+func TestStateStore_ACLBindingRules(t *testing.T) {
+	t.Parallel()
+	s := testStateStore(t)
+
+	require.Equal(t, ErrMissingACLBindingRuleID, s.ACLBindingRuleSet(1, &structs.ACLBindingRule{AuthMethod: "test"}))
+	require.Equal(t, ErrMissingACLBindingRuleAuthMethod, s.ACLBindingRuleSet(1, &structs.ACLBindingRule{ID: "3ed2c9c4-4a0c-4b0a-a0a4-5d1e2e7c7d36"}))
+
+	rule := &structs.ACLBindingRule{
+		ID:         "3ed2c9c4-4a0c-4b0a-a0a4-5d1e2e7c7d36",
+		AuthMethod: "test",
+		Selector:   "serviceaccount.namespace == default",
+		BindType:   structs.BindingRuleBindTypeService,
+		BindName:   "${serviceaccount.name}",
+	}
+
+	// The auth method must exist.
+	err := s.ACLBindingRuleSet(1, rule)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "auth method not found")
+
+	require.NoError(t, s.ACLAuthMethodSet(2, &structs.ACLAuthMethod{Name: "test", Type: "testing"}))
+	require.NoError(t, s.ACLAuthMethodSet(3, &structs.ACLAuthMethod{Name: "other", Type: "testing"}))
+	require.NoError(t, s.ACLBindingRuleSet(4, rule))
+
+	idx, rrule, err := s.ACLBindingRuleGetByID(nil, rule.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), idx)
+	require.Equal(t, "${serviceaccount.name}", rrule.BindName)
+	require.Equal(t, uint64(4), rrule.CreateIndex)
+	require.Equal(t, uint64(4), rrule.ModifyIndex)
+
+	// The auth method is fixed once the binding rule exists.
+	moved := rule.Clone()
+	moved.AuthMethod = "other"
+	err = s.ACLBindingRuleSet(5, moved)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Changing the AuthMethod of a binding rule is not permitted")
+
+	updated := rule.Clone()
+	updated.Description = "updated"
+	require.NoError(t, s.ACLBindingRuleSet(6, updated))
+	_, rrule, err = s.ACLBindingRuleGetByID(nil, rule.ID)
+	require.NoError(t, err)
+	require.Equal(t, "updated", rrule.Description)
+	require.Equal(t, uint64(4), rrule.CreateIndex)
+	require.Equal(t, uint64(6), rrule.ModifyIndex)
+
+	otherRule := &structs.ACLBindingRule{
+		ID:         "9a1f5b8e-7a43-4f87-b6c8-0f2f3c2a4e11",
+		AuthMethod: "other",
+		BindType:   structs.BindingRuleBindTypeNode,
+		BindName:   "node-${serviceaccount.name}",
+	}
+	require.NoError(t, s.ACLBindingRuleSet(7, otherRule))
+
+	idx, rules, err := s.ACLBindingRuleList(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), idx)
+	require.Len(t, rules, 2)
+
+	_, rules, err = s.ACLBindingRuleList(nil, "other")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, otherRule.ID, rules[0].ID)
+
+	// Deleting an auth method deletes its binding rules.
+	require.NoError(t, s.ACLAuthMethodDeleteByName(8, "other"))
+	_, rrule, err = s.ACLBindingRuleGetByID(nil, otherRule.ID)
+	require.NoError(t, err)
+	require.Nil(t, rrule)
+
+	require.NoError(t, s.ACLBindingRuleDeleteByID(9, rule.ID))
+	_, rrule, err = s.ACLBindingRuleGetByID(nil, rule.ID)
+	require.NoError(t, err)
+	require.Nil(t, rrule)
+
+	// Deleting a missing binding rule is not an error.
+	require.NoError(t, s.ACLBindingRuleDeleteByID(10, rule.ID))
+}
+
+func TestStateStore_ACLAuthMethods_Snapshot_Restore(t *testing.T) {
+	s := testStateStore(t)
+
+	methods := structs.ACLAuthMethods{
+		&structs.ACLAuthMethod{
+			Name:        "test-1",
+			Type:        "testing",
+			Description: "test-1",
+			RaftIndex:   structs.RaftIndex{CreateIndex: 1, ModifyIndex: 2},
+		},
+		&structs.ACLAuthMethod{
+			Name:        "test-2",
+			Type:        "testing",
+			Description: "test-2",
+			RaftIndex:   structs.RaftIndex{CreateIndex: 1, ModifyIndex: 2},
+		},
+	}
+
+	require.NoError(t, s.ACLAuthMethodsUpsert(2, methods))
+
+	// Snapshot the ACLs.
+	snap := s.Snapshot()
+	defer snap.Close()
+
+	// Alter the real state store.
+	require.NoError(t, s.ACLAuthMethodDeleteByName(3, "test-1"))
+
+	// Verify the snapshot.
+	require.Equal(t, uint64(2), snap.LastIndex())
+
+	iter, err := snap.ACLAuthMethods()
+	require.NoError(t, err)
+
+	var dump structs.ACLAuthMethods
+	for method := iter.Next(); method != nil; method = iter.Next() {
+		dump = append(dump, method.(*structs.ACLAuthMethod))
+	}
+	require.ElementsMatch(t, dump, methods)
+
+	// Restore the values into a new state store.
+	func() {
+		s := testStateStore(t)
+		restore := s.Restore()
+		for _, method := range dump {
+			require.NoError(t, restore.ACLAuthMethod(method))
+		}
+		restore.Commit()
+
+		// Read the restored ACLs back out and verify that they match.
+		idx, res, err := s.ACLAuthMethodList(nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), idx)
+		require.ElementsMatch(t, methods, res)
+		require.Equal(t, uint64(2), s.maxIndex("acl-auth-methods"))
+	}()
+}
@@ -3,6 +3,7 @@ package state
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
@@ -264,6 +265,7 @@ func (s *Store) EnsureRegistration(idx uint64, req *structs.RegisterRequest) err
 	}
 
 	tx.Commit()
+	s.nodeActivity.Touch(req.Node)
 	return nil
 }
 
@@ -555,6 +557,34 @@ func (s *Store) NodesByMeta(ws memdb.WatchSet, filters map[string]string) (uint6
 	return idx, results, nil
 }
 
+// NodeActivity returns the nodes, optionally filtered by their metadata, with
+// the times of their last registrations.
+func (s *Store) NodeActivity(ws memdb.WatchSet, filters map[string]string) (uint64, structs.NodeActivities, error) {
+	var idx uint64
+	var nodes structs.Nodes
+	var err error
+	if len(filters) > 0 {
+		idx, nodes, err = s.NodesByMeta(ws, filters)
+	} else {
+		idx, nodes, err = s.Nodes(ws)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var results structs.NodeActivities
+	for _, n := range nodes {
+		results = append(results, &structs.NodeActivity{
+			Node:           n.Node,
+			Address:        n.Address,
+			Meta:           n.Meta,
+			LastRegistered: s.nodeActivity.LastRegistered(n.Node),
+			RaftIndex:      n.RaftIndex,
+		})
+	}
+	return idx, results, nil
+}
+
 // DeleteNode is used to delete a given node by its ID.
 func (s *Store) DeleteNode(idx uint64, nodeName string) error {
 	tx := s.db.Txn(true)
@@ -597,7 +627,7 @@ func (s *Store) deleteNodeTxn(tx *memdb.Txn, idx uint64, nodeName string) error
 
 	// Do the delete in a separate loop so we don't trash the iterator.
 	for _, sid := range sids {
-		if err := s.deleteServiceTxn(tx, idx, nodeName, sid); err != nil {
+		if err := s.removeServiceTxn(tx, idx, nodeName, sid, structs.ServiceInstanceNodeDeregistered); err != nil {
 			return err
 		}
 	}
@@ -641,6 +671,7 @@ func (s *Store) deleteNodeTxn(tx *memdb.Txn, idx uint64, nodeName string) error
 	if err := tx.Insert("index", &IndexEntry{"nodes", idx}); err != nil {
 		return fmt.Errorf("failed updating index: %s", err)
 	}
+	tx.Defer(func() { s.nodeActivity.Forget(nodeName) })
 
 	// Invalidate any sessions for this node.
 	sessions, err := tx.Get("sessions", "node", nodeName)
@@ -727,6 +758,29 @@ func (s *Store) ensureServiceTxn(tx *memdb.Txn, idx uint64, node string, svc *st
 		return fmt.Errorf("failed updating index: %s", err)
 	}
 
+	if err := s.ensureServiceVirtualIPTxn(tx, idx, svc); err != nil {
+		return err
+	}
+	if existing != nil {
+		// The instance may have been the last one of a service it was
+		// renamed from.
+		if err := s.freeServiceVirtualIPTxn(tx, idx, existing.(*structs.ServiceNode).ServiceName); err != nil {
+			return err
+		}
+	}
+
+	event := structs.ServiceInstanceRegistered
+	if existing != nil {
+		event = structs.ServiceInstanceUpdated
+	}
+	s.recordServiceEvent(tx, &structs.ServiceInstanceEvent{
+		Index:       idx,
+		Type:        event,
+		Node:        node,
+		ServiceID:   svc.ID,
+		ServiceName: svc.Service,
+	})
+
 	return nil
 }
 
@@ -771,6 +825,30 @@ func (s *Store) Services(ws memdb.WatchSet) (uint64, structs.Services, error) {
 	return idx, results, nil
 }
 
+// ConnectProxies returns all the registered Connect proxy service instances.
+func (s *Store) ConnectProxies(ws memdb.WatchSet) (uint64, structs.ServiceNodes, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "services")
+
+	services, err := tx.Get("services", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed querying services: %s", err)
+	}
+	ws.Add(services.WatchCh())
+
+	var results structs.ServiceNodes
+	for service := services.Next(); service != nil; service = services.Next() {
+		svc := service.(*structs.ServiceNode)
+		if svc.ServiceKind == structs.ServiceKindConnectProxy {
+			results = append(results, svc)
+		}
+	}
+	return idx, results, nil
+}
+
 // ServicesByNodeMeta returns all services, filtered by the given node metadata.
 func (s *Store) ServicesByNodeMeta(ws memdb.WatchSet, filters map[string]string) (uint64, structs.Services, error) {
 	tx := s.db.Txn(false)
@@ -840,6 +918,70 @@ func (s *Store) ServicesByNodeMeta(ws memdb.WatchSet, filters map[string]string)
 	return idx, results, nil
 }
 
+// ServicesByExternalSource returns the services with instances registered
+// by the given external source, optionally filtered by the given node
+// metadata. Only the tags of those instances are returned.
+func (s *Store) ServicesByExternalSource(ws memdb.WatchSet, source string, filters map[string]string) (uint64, structs.Services, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "services")
+	if len(filters) > 0 {
+		idx = maxIndexTxn(tx, "services", "nodes")
+
+		// Watch the nodes in case their metadata changes.
+		nodes, err := tx.Get("nodes", "id")
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed nodes lookup: %s", err)
+		}
+		ws.Add(nodes.WatchCh())
+	}
+
+	// List all the services.
+	services, err := tx.Get("services", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed querying services: %s", err)
+	}
+	ws.Add(services.WatchCh())
+
+	unique := make(map[string]map[string]struct{})
+	for service := services.Next(); service != nil; service = services.Next() {
+		svc := service.(*structs.ServiceNode)
+		if svc.ServiceMeta[structs.MetaExternalSource] != source {
+			continue
+		}
+		if len(filters) > 0 {
+			n, err := tx.First("nodes", "id", svc.Node)
+			if err != nil {
+				return 0, nil, fmt.Errorf("failed node lookup: %s", err)
+			}
+			if n == nil || !structs.SatisfiesMetaFilters(n.(*structs.Node).Meta, filters) {
+				continue
+			}
+		}
+
+		tags, ok := unique[svc.ServiceName]
+		if !ok {
+			unique[svc.ServiceName] = make(map[string]struct{})
+			tags = unique[svc.ServiceName]
+		}
+		for _, tag := range svc.ServiceTags {
+			tags[tag] = struct{}{}
+		}
+	}
+
+	// Generate the output structure.
+	var results = make(structs.Services)
+	for service, tags := range unique {
+		results[service] = make([]string, 0)
+		for tag := range tags {
+			results[service] = append(results[service], tag)
+		}
+	}
+	return idx, results, nil
+}
+
 // maxIndexForService return the maximum Raft Index for a service
 // If the index is not set for the service, it will return:
 // - maxIndex(nodes, services) if checks is false
@@ -1137,6 +1279,42 @@ func (s *Store) NodeServices(ws memdb.WatchSet, nodeNameOrID string) (uint64, *s
 	return idx, ns, nil
 }
 
+// ServiceHistory returns the recorded events of the instances of a service,
+// oldest first.
+func (s *Store) ServiceHistory(ws memdb.WatchSet, serviceName string) (uint64, structs.ServiceInstanceEvents, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Watch the instances and their checks since every event changes one
+	// of them.
+	services, err := tx.Get("services", "service", serviceName)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed service lookup: %s", err)
+	}
+	ws.Add(services.WatchCh())
+	checks, err := tx.Get("checks", "service", serviceName)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed check lookup: %s", err)
+	}
+	ws.Add(checks.WatchCh())
+
+	events := s.serviceHistory.Events(serviceName)
+	idx := maxIndexForService(tx, serviceName, true)
+	if n := len(events); n > 0 && events[n-1].Index > idx {
+		idx = events[n-1].Index
+	}
+	return idx, events, nil
+}
+
+// recordServiceEvent adds an event to the service history once the given
+// transaction is committed.
+func (s *Store) recordServiceEvent(tx *memdb.Txn, ev *structs.ServiceInstanceEvent) {
+	tx.Defer(func() {
+		ev.Time = time.Now()
+		s.serviceHistory.Record(ev)
+	})
+}
+
 // DeleteService is used to delete a given service associated with a node.
 func (s *Store) DeleteService(idx uint64, nodeName, serviceID string) error {
 	tx := s.db.Txn(true)
@@ -1158,6 +1336,12 @@ func serviceIndexName(name string) string {
 // deleteServiceTxn is the inner method called to remove a service
 // registration within an existing transaction.
 func (s *Store) deleteServiceTxn(tx *memdb.Txn, idx uint64, nodeName, serviceID string) error {
+	return s.removeServiceTxn(tx, idx, nodeName, serviceID, structs.ServiceInstanceDeregistered)
+}
+
+// removeServiceTxn removes a service and records the given event in the
+// service history.
+func (s *Store) removeServiceTxn(tx *memdb.Txn, idx uint64, nodeName, serviceID, event string) error {
 	// Look up the service.
 	service, err := tx.First("services", "id", nodeName, serviceID)
 	if err != nil {
@@ -1218,6 +1402,18 @@ func (s *Store) deleteServiceTxn(tx *memdb.Txn, idx uint64, nodeName, serviceID
 	} else {
 		return fmt.Errorf("Could not find any service %s: %s", svc.ServiceName, err)
 	}
+
+	if err := s.freeServiceVirtualIPTxn(tx, idx, svc.ServiceName); err != nil {
+		return err
+	}
+
+	s.recordServiceEvent(tx, &structs.ServiceInstanceEvent{
+		Index:       idx,
+		Type:        event,
+		Node:        nodeName,
+		ServiceID:   serviceID,
+		ServiceName: svc.ServiceName,
+	})
 	return nil
 }
 
@@ -1275,6 +1471,20 @@ func (s *Store) ensureCheckTxn(tx *memdb.Txn, idx uint64, hc *structs.HealthChec
 		hc.Status = api.HealthCritical
 	}
 
+	// Keep the status and output from before the last status change. The
+	// registrations don't carry them, so they are copied over as long as
+	// the status stays the same.
+	if existing != nil {
+		existingCheck := existing.(*structs.HealthCheck)
+		if existingCheck.Status != hc.Status {
+			hc.PreviousStatus = existingCheck.Status
+			hc.PreviousOutput = existingCheck.Output
+		} else {
+			hc.PreviousStatus = existingCheck.PreviousStatus
+			hc.PreviousOutput = existingCheck.PreviousOutput
+		}
+	}
+
 	// Get the node
 	node, err := tx.First("nodes", "id", hc.Node)
 	if err != nil {
@@ -1308,6 +1518,20 @@ func (s *Store) ensureCheckTxn(tx *memdb.Txn, idx uint64, hc *structs.HealthChec
 				return fmt.Errorf("failed updating index: %s", err)
 			}
 		}
+		if existing != nil && existing.(*structs.HealthCheck).Status != hc.Status {
+			s.recordServiceEvent(tx, &structs.ServiceInstanceEvent{
+				Index:          idx,
+				Type:           structs.ServiceInstanceHealthChanged,
+				Node:           hc.Node,
+				ServiceID:      hc.ServiceID,
+				ServiceName:    svc.ServiceName,
+				CheckID:        hc.CheckID,
+				Status:         hc.Status,
+				Output:         hc.Output,
+				PreviousStatus: hc.PreviousStatus,
+				PreviousOutput: hc.PreviousOutput,
+			})
+		}
 	} else {
 		if existing != nil && existing.(*structs.HealthCheck).IsSame(hc) {
 			modified = false
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
@@ -1557,6 +1558,67 @@ func TestStateStore_Services(t *testing.T) {
 	}
 }
 
+func TestStateStore_ServicesByExternalSource(t *testing.T) {
+	s := testStateStore(t)
+
+	// Listing with no results returns an empty map.
+	ws := memdb.NewWatchSet()
+	idx, res, err := s.ServicesByExternalSource(ws, "k8s", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), idx)
+	require.Len(t, res, 0)
+
+	// Create some nodes and services in the state store.
+	testRegisterNodeWithMeta(t, s, 0, "node0", map[string]string{"role": "client"})
+	testRegisterNodeWithMeta(t, s, 1, "node1", map[string]string{"role": "server"})
+	require.NoError(t, s.EnsureService(2, "node0", &structs.NodeService{
+		ID:      "redis",
+		Service: "redis",
+		Tags:    []string{"master"},
+		Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
+	}))
+	require.NoError(t, s.EnsureService(3, "node1", &structs.NodeService{
+		ID:      "redis",
+		Service: "redis",
+		Tags:    []string{"slave"},
+		Meta:    map[string]string{structs.MetaExternalSource: "terraform"},
+	}))
+	require.NoError(t, s.EnsureService(4, "node1", &structs.NodeService{
+		ID:      "web",
+		Service: "web",
+		Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
+	}))
+	require.NoError(t, s.EnsureService(5, "node1", &structs.NodeService{
+		ID:      "db",
+		Service: "db",
+	}))
+	require.True(t, watchFired(ws))
+
+	// Only the instances of the source are considered.
+	ws = memdb.NewWatchSet()
+	idx, res, err = s.ServicesByExternalSource(ws, "k8s", nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), idx)
+	require.Equal(t, structs.Services{
+		"redis": []string{"master"},
+		"web":   []string{},
+	}, res)
+
+	// The node metadata filters are applied too.
+	_, res, err = s.ServicesByExternalSource(ws, "k8s", map[string]string{"role": "server"})
+	require.NoError(t, err)
+	require.Equal(t, structs.Services{"web": []string{}}, res)
+
+	// Changing the source of a service fires the watch.
+	require.False(t, watchFired(ws))
+	require.NoError(t, s.EnsureService(6, "node1", &structs.NodeService{
+		ID:      "db",
+		Service: "db",
+		Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
+	}))
+	require.True(t, watchFired(ws))
+}
+
 func TestStateStore_ServicesByNodeMeta(t *testing.T) {
 	s := testStateStore(t)
 
@@ -1962,6 +2024,79 @@ func TestStateStore_ServiceTagNodes_MultipleTags(t *testing.T) {
 	require.Equal(t, nodes[0].ServicePort, 8001)
 }
 
+func TestStateStore_ServiceHistory(t *testing.T) {
+	s := testStateStore(t)
+
+	// Querying with no results returns nil.
+	ws := memdb.NewWatchSet()
+	idx, events, err := s.ServiceHistory(ws, "service1")
+	if idx != 0 || events != nil || err != nil {
+		t.Fatalf("expected (0, nil, nil), got: (%d, %#v, %#v)", idx, events, err)
+	}
+
+	// Register, update and flap the health of an instance.
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterNode(t, s, 2, "node2")
+	testRegisterService(t, s, 3, "node1", "service1")
+	testRegisterService(t, s, 4, "node2", "service1")
+	testRegisterCheck(t, s, 5, "node1", "service1", "check1", api.HealthPassing)
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	testRegisterServiceWithChange(t, s, 6, "node1", "service1", true)
+	testRegisterCheck(t, s, 7, "node1", "service1", "check1", api.HealthCritical)
+
+	// Re-registering an unchanged instance or check is not an event.
+	testRegisterService(t, s, 8, "node2", "service1")
+	testRegisterCheck(t, s, 9, "node1", "service1", "check1", api.HealthCritical)
+
+	// Remove the instances on their own and with their node.
+	if err := s.DeleteService(10, "node1", "service1"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.DeleteNode(11, "node2"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ws = memdb.NewWatchSet()
+	idx, events, err = s.ServiceHistory(ws, "service1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 11 {
+		t.Fatalf("bad index: %d", idx)
+	}
+	for _, ev := range events {
+		if ev.Time.IsZero() {
+			t.Fatalf("missing time: %#v", ev)
+		}
+		ev.Time = time.Time{}
+	}
+	expected := structs.ServiceInstanceEvents{
+		{Index: 3, Type: structs.ServiceInstanceRegistered, Node: "node1", ServiceID: "service1", ServiceName: "service1"},
+		{Index: 4, Type: structs.ServiceInstanceRegistered, Node: "node2", ServiceID: "service1", ServiceName: "service1"},
+		{Index: 6, Type: structs.ServiceInstanceUpdated, Node: "node1", ServiceID: "service1", ServiceName: "service1"},
+		{Index: 7, Type: structs.ServiceInstanceHealthChanged, Node: "node1", ServiceID: "service1", ServiceName: "service1",
+			CheckID: "check1", Status: api.HealthCritical, PreviousStatus: api.HealthPassing},
+		{Index: 10, Type: structs.ServiceInstanceDeregistered, Node: "node1", ServiceID: "service1", ServiceName: "service1"},
+		{Index: 11, Type: structs.ServiceInstanceNodeDeregistered, Node: "node2", ServiceID: "service1", ServiceName: "service1"},
+	}
+	verify.Values(t, "", events, expected)
+
+	// The history is bounded.
+	testRegisterNode(t, s, 12, "node1")
+	for i := uint64(0); i < serviceHistoryLimit; i++ {
+		testRegisterServiceWithChange(t, s, 13+i, "node1", "service1", true)
+	}
+	_, events, err = s.ServiceHistory(nil, "service1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(events) != serviceHistoryLimit || events[0].Index != 13 {
+		t.Fatalf("bad: %d events starting at %d", len(events), events[0].Index)
+	}
+}
+
 func TestStateStore_DeleteService(t *testing.T) {
 	s := testStateStore(t)
 
@@ -2264,6 +2399,66 @@ func TestStateStore_EnsureCheck_defaultStatus(t *testing.T) {
 	}
 }
 
+func TestStateStore_EnsureCheck_previousStatus(t *testing.T) {
+	s := testStateStore(t)
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "service1")
+
+	register := func(idx uint64, status, output string) *structs.HealthCheck {
+		t.Helper()
+		check := &structs.HealthCheck{
+			Node:      "node1",
+			CheckID:   "check1",
+			ServiceID: "service1",
+			Status:    status,
+			Output:    output,
+		}
+		if err := s.EnsureCheck(idx, check); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		_, result, err := s.NodeChecks(nil, "node1")
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if len(result) != 1 {
+			t.Fatalf("bad: %#v", result)
+		}
+		return result[0]
+	}
+
+	// A new check has no previous status.
+	check := register(3, api.HealthPassing, "HTTP 200")
+	if check.PreviousStatus != "" || check.PreviousOutput != "" {
+		t.Fatalf("bad: %#v", check)
+	}
+
+	// A status change remembers the replaced status and output.
+	check = register(4, api.HealthCritical, "HTTP 503")
+	if check.PreviousStatus != api.HealthPassing || check.PreviousOutput != "HTTP 200" {
+		t.Fatalf("bad: %#v", check)
+	}
+
+	// They are kept as long as the status doesn't change, even if the
+	// output does.
+	check = register(5, api.HealthCritical, "connection refused")
+	if check.PreviousStatus != api.HealthPassing || check.PreviousOutput != "HTTP 200" {
+		t.Fatalf("bad: %#v", check)
+	}
+
+	// The health event carries both outputs.
+	_, events, err := s.ServiceHistory(nil, "service1")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	ev := events[len(events)-1]
+	if ev.Type != structs.ServiceInstanceHealthChanged || ev.Index != 4 ||
+		ev.CheckID != "check1" || ev.Status != api.HealthCritical ||
+		ev.Output != "HTTP 503" || ev.PreviousStatus != api.HealthPassing ||
+		ev.PreviousOutput != "HTTP 200" {
+		t.Fatalf("bad: %#v", ev)
+	}
+}
+
 func TestStateStore_NodeChecks(t *testing.T) {
 	s := testStateStore(t)
 
@@ -3401,3 +3596,50 @@ func TestStateStore_NodeInfo_NodeDump(t *testing.T) {
 		t.Fatalf("bad")
 	}
 }
+
+func TestStateStore_NodeActivity(t *testing.T) {
+	s := testStateStore(t)
+	start := s.nodeActivity.since
+
+	register := func(idx uint64, node string, meta map[string]string) {
+		t.Helper()
+		req := &structs.RegisterRequest{
+			Node:     node,
+			Address:  "1.2.3.4",
+			NodeMeta: meta,
+		}
+		require.NoError(t, s.EnsureRegistration(idx, req))
+	}
+
+	// Nodes added with a restore or before the tracking started were last
+	// registered at the start.
+	testRegisterNode(t, s, 1, "node1")
+	register(2, "node2", map[string]string{"external": "true"})
+	register(3, "node3", map[string]string{"external": "true"})
+
+	idx, nodes, err := s.NodeActivity(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), idx)
+	require.Len(t, nodes, 3)
+	require.Equal(t, "node1", nodes[0].Node)
+	require.Equal(t, start, nodes[0].LastRegistered)
+	require.True(t, nodes[1].LastRegistered.After(start))
+	node2 := nodes[1].LastRegistered
+
+	// Re-registering an unchanged node still counts.
+	time.Sleep(time.Millisecond)
+	register(4, "node2", map[string]string{"external": "true"})
+	idx, nodes, err = s.NodeActivity(nil, map[string]string{"external": "true"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), idx)
+	require.Len(t, nodes, 2)
+	require.Equal(t, "node2", nodes[0].Node)
+	require.Equal(t, "1.2.3.4", nodes[0].Address)
+	require.Equal(t, uint64(2), nodes[0].ModifyIndex)
+	require.True(t, nodes[0].LastRegistered.After(node2))
+
+	// Deregistered nodes are forgotten.
+	require.NoError(t, s.DeleteNode(5, "node2"))
+	_, ok := s.nodeActivity.last["node2"]
+	require.False(t, ok)
+}
@@ -52,3 +52,16 @@ func (d *Delay) SetExpiration(key string, now time.Time, delay time.Duration) {
 		d.lock.Unlock()
 	})
 }
+
+// GetExpirations returns the active lock delay expiration times, organized
+// by key.
+func (d *Delay) GetExpirations() map[string]time.Time {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	expirations := make(map[string]time.Time, len(d.delay))
+	for key, expires := range d.delay {
+		expirations[key] = expires
+	}
+	return expirations
+}
@@ -0,0 +1,110 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// gossipKeyRotationTableSchema returns a new table schema used for storing
+// the state of the gossip key rotation.
+func gossipKeyRotationTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "gossip-key-rotation",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: true,
+				Unique:       true,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) { return true, nil },
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	registerSchema(gossipKeyRotationTableSchema)
+}
+
+// GossipKeyRotation is used to pull the gossip key rotation state from the
+// snapshot.
+func (s *Snapshot) GossipKeyRotation() (*structs.GossipKeyRotation, error) {
+	r, err := s.tx.First("gossip-key-rotation", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, ok := r.(*structs.GossipKeyRotation)
+	if !ok {
+		return nil, nil
+	}
+
+	return rotation, nil
+}
+
+// GossipKeyRotation is used when restoring from a snapshot.
+func (s *Restore) GossipKeyRotation(rotation *structs.GossipKeyRotation) error {
+	if err := s.tx.Insert("gossip-key-rotation", rotation); err != nil {
+		return fmt.Errorf("failed restoring gossip key rotation: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, rotation.ModifyIndex, "gossip-key-rotation"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	return nil
+}
+
+// GossipKeyRotation is used to get the state of the gossip key rotation. The
+// state is nil if the rotation was never enabled.
+func (s *Store) GossipKeyRotation(ws memdb.WatchSet) (uint64, *structs.GossipKeyRotation, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "gossip-key-rotation")
+
+	watchCh, r, err := tx.FirstWatch("gossip-key-rotation", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed gossip key rotation lookup: %s", err)
+	}
+	ws.Add(watchCh)
+
+	rotation, ok := r.(*structs.GossipKeyRotation)
+	if !ok {
+		return idx, nil, nil
+	}
+
+	return idx, rotation, nil
+}
+
+// GossipKeyRotationSet is used to set the state of the gossip key rotation.
+func (s *Store) GossipKeyRotationSet(idx uint64, rotation *structs.GossipKeyRotation) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	// Check for an existing state
+	existing, err := tx.First("gossip-key-rotation", "id")
+	if err != nil {
+		return fmt.Errorf("failed gossip key rotation lookup: %s", err)
+	}
+
+	// Set the indexes.
+	if existing != nil {
+		rotation.CreateIndex = existing.(*structs.GossipKeyRotation).CreateIndex
+	} else {
+		rotation.CreateIndex = idx
+	}
+	rotation.ModifyIndex = idx
+
+	if err := tx.Insert("gossip-key-rotation", rotation); err != nil {
+		return fmt.Errorf("failed updating gossip key rotation: %s", err)
+	}
+	if err := tx.Insert("index", &IndexEntry{"gossip-key-rotation", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return nil
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore_GossipKeyRotation(t *testing.T) {
+	s := testStateStore(t)
+
+	// Nothing is returned before the rotation is enabled.
+	ws := memdb.NewWatchSet()
+	idx, rotation, err := s.GossipKeyRotation(ws)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), idx)
+	require.Nil(t, rotation)
+
+	now := time.Now().UTC()
+	expected := &structs.GossipKeyRotation{
+		Phase:        structs.GossipKeyRotationIdle,
+		PhaseStarted: now,
+		LastRotation: now,
+	}
+	require.NoError(t, s.GossipKeyRotationSet(1, expected))
+	require.True(t, watchFired(ws))
+
+	idx, rotation, err = s.GossipKeyRotation(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), idx)
+	require.Equal(t, expected, rotation)
+
+	// The create index is kept on update.
+	require.NoError(t, s.GossipKeyRotationSet(2, &structs.GossipKeyRotation{
+		Phase:  structs.GossipKeyRotationDistributing,
+		NewKey: "H1dfkSZOVnP/JUnaBfTzXg==",
+	}))
+	idx, rotation, err = s.GossipKeyRotation(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), idx)
+	require.Equal(t, structs.GossipKeyRotationDistributing, rotation.Phase)
+	require.Equal(t, structs.RaftIndex{CreateIndex: 1, ModifyIndex: 2}, rotation.RaftIndex)
+}
@@ -2,6 +2,7 @@ package state
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -105,6 +106,9 @@ func (s *Store) ReapTombstones(index uint64) error {
 	if err := s.kvsGraveyard.ReapTxn(tx, index); err != nil {
 		return fmt.Errorf("failed to reap kvs tombstones: %s", err)
 	}
+	if err := reapServiceVirtualIPsTxn(tx, index); err != nil {
+		return err
+	}
 
 	tx.Commit()
 	return nil
@@ -363,6 +367,7 @@ func (s *Store) kvsDeleteTxn(tx *memdb.Txn, idx uint64, key string) error {
 		return fmt.Errorf("failed updating index: %s", err)
 	}
 
+	s.lockTracker.Forget(key)
 	return nil
 }
 
@@ -499,6 +504,111 @@ func (s *Store) KVSLockDelay(key string) time.Time {
 	return s.lockDelay.GetExpiration(key)
 }
 
+// KVSLockRejected records that the given session failed to acquire the lock
+// on the given key because of a lock-delay.
+func (s *Store) KVSLockRejected(key, session string) {
+	s.lockTracker.Rejected(key, session)
+}
+
+// KVSLocks returns the keys which are locked by a session, under a lock-delay,
+// or have sessions waiting to acquire them, sorted by key.
+func (s *Store) KVSLocks(ws memdb.WatchSet) (uint64, structs.KeyLocks, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "kvs", "sessions")
+
+	// Collect the keys which are held by the sessions.
+	sessions, err := tx.Get("sessions", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed session lookup: %s", err)
+	}
+	ws.Add(sessions.WatchCh())
+
+	locks := make(map[string]*structs.KeyLock)
+	live := make(map[string]bool)
+	for raw := sessions.Next(); raw != nil; raw = sessions.Next() {
+		session := raw.(*structs.Session)
+		live[session.ID] = true
+
+		entries, err := tx.Get("kvs", "session", session.ID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed kvs lookup: %s", err)
+		}
+		ws.Add(entries.WatchCh())
+		for entry := entries.Next(); entry != nil; entry = entries.Next() {
+			e := entry.(*structs.DirEntry)
+			locks[e.Key] = &structs.KeyLock{
+				Key:         e.Key,
+				Session:     session.ID,
+				SessionName: session.Name,
+				Node:        session.Node,
+				LockIndex:   e.LockIndex,
+				AcquiredAt:  s.lockTracker.AcquiredAt(e.Key, session.ID),
+			}
+		}
+	}
+
+	// lookup returns the lock of a key which is not held by a session.
+	lookup := func(key string) (*structs.KeyLock, error) {
+		if lock, ok := locks[key]; ok {
+			return lock, nil
+		}
+		lock := &structs.KeyLock{Key: key}
+		entry, err := tx.First("kvs", "id", key)
+		if err != nil {
+			return nil, fmt.Errorf("failed kvs lookup: %s", err)
+		}
+		if entry != nil {
+			lock.LockIndex = entry.(*structs.DirEntry).LockIndex
+		}
+		return lock, nil
+	}
+
+	// Add the keys which are under a lock-delay.
+	now := time.Now()
+	for key, expires := range s.lockDelay.GetExpirations() {
+		if !expires.After(now) {
+			continue
+		}
+		lock, err := lookup(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		lock.LockDelayUntil = expires
+		locks[key] = lock
+	}
+
+	// Add the keys which have sessions waiting to acquire them.
+	for _, key := range s.lockTracker.Keys() {
+		waiters := 0
+		for _, session := range s.lockTracker.Waiters(key) {
+			if live[session] {
+				waiters++
+			}
+		}
+		if waiters == 0 {
+			continue
+		}
+		lock, err := lookup(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		lock.Waiters = waiters
+		locks[key] = lock
+	}
+
+	result := make(structs.KeyLocks, 0, len(locks))
+	for _, lock := range locks {
+		result = append(result, lock)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return idx, result, nil
+}
+
 // KVSLock is similar to KVSSet but only performs the set if the lock can be
 // acquired.
 func (s *Store) KVSLock(idx uint64, entry *structs.DirEntry) (bool, error) {
@@ -546,6 +656,7 @@ func (s *Store) kvsLockTxn(tx *memdb.Txn, idx uint64, entry *structs.DirEntry) (
 			entry.LockIndex = e.LockIndex
 		} else if e.Session != "" {
 			// Bail out, someone else holds this lock.
+			s.lockTracker.Rejected(entry.Key, entry.Session)
 			return false, nil
 		} else {
 			// Set up a new lock with this session.
@@ -562,6 +673,9 @@ func (s *Store) kvsLockTxn(tx *memdb.Txn, idx uint64, entry *structs.DirEntry) (
 	if err := s.kvsSetTxn(tx, idx, entry, true); err != nil {
 		return false, err
 	}
+	if existing == nil || existing.(*structs.DirEntry).Session != entry.Session {
+		s.lockTracker.Acquired(entry.Key, entry.Session, time.Now())
+	}
 	return true, nil
 }
 
@@ -615,6 +729,7 @@ func (s *Store) kvsUnlockTxn(tx *memdb.Txn, idx uint64, entry *structs.DirEntry)
 	if err := s.kvsSetTxn(tx, idx, entry, true); err != nil {
 		return false, err
 	}
+	s.lockTracker.Released(entry.Key)
 	return true, nil
 }
 
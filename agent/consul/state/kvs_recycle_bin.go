@@ -0,0 +1,239 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// kvsRecycledTableSchema returns a new table schema used for storing the KV
+// entries which were moved to the recycle bin when they were deleted.
+func kvsRecycledTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "kvs_recycled",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Key",
+					Lowercase: false,
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	registerSchema(kvsRecycledTableSchema)
+}
+
+// RecycledKVs is used to pull the full list of the recycle bin for use
+// during snapshots.
+func (s *Snapshot) RecycledKVs() (memdb.ResultIterator, error) {
+	iter, err := s.tx.Get("kvs_recycled", "id_prefix")
+	if err != nil {
+		return nil, err
+	}
+	return iter, nil
+}
+
+// RecycledKVS is used when restoring from a snapshot.
+func (s *Restore) RecycledKVS(entry *structs.RecycledDirEntry) error {
+	if err := s.tx.Insert("kvs_recycled", entry); err != nil {
+		return fmt.Errorf("failed inserting recycled kvs entry: %s", err)
+	}
+
+	if err := indexUpdateMaxTxn(s.tx, entry.DeleteIndex, "kvs_recycled"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// KVSRecycle is used to delete a single key, moving it to the recycle bin.
+func (s *Store) KVSRecycle(idx uint64, key string, deletedAt time.Time) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if err := s.kvsRecycleTxn(tx, idx, key, false, deletedAt); err != nil {
+		return err
+	}
+	if err := s.kvsDeleteTxn(tx, idx, key); err != nil {
+		return err
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// KVSRecycleCAS is used to do a CAS delete of a key, moving it to the
+// recycle bin if it was deleted.
+func (s *Store) KVSRecycleCAS(idx, cidx uint64, key string, deletedAt time.Time) (bool, error) {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if err := s.kvsRecycleTxn(tx, idx, key, false, deletedAt); err != nil {
+		return false, err
+	}
+	set, err := s.kvsDeleteCASTxn(tx, idx, cidx, key)
+	if !set || err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+	return true, nil
+}
+
+// KVSRecycleTree is used to do a recursive delete on a key prefix, moving
+// the deleted keys to the recycle bin.
+func (s *Store) KVSRecycleTree(idx uint64, prefix string, deletedAt time.Time) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if err := s.kvsRecycleTxn(tx, idx, prefix, true, deletedAt); err != nil {
+		return err
+	}
+	if err := s.kvsDeleteTreeTxn(tx, idx, prefix); err != nil {
+		return err
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// kvsRecycleTxn copies the entry of a key, or of all the keys with the given
+// prefix, to the recycle bin. The entries must be deleted within the same
+// transaction. A recycled entry replaces the previously recycled entry of the
+// same key.
+func (s *Store) kvsRecycleTxn(tx *memdb.Txn, idx uint64, key string, prefix bool, deletedAt time.Time) error {
+	index := "id"
+	if prefix {
+		index = "id_prefix"
+	}
+	entries, err := tx.Get("kvs", index, key)
+	if err != nil {
+		return fmt.Errorf("failed kvs lookup: %s", err)
+	}
+
+	var recycled structs.RecycledDirEntries
+	for entry := entries.Next(); entry != nil; entry = entries.Next() {
+		e := entry.(*structs.DirEntry)
+		recycled = append(recycled, &structs.RecycledDirEntry{
+			Key:         e.Key,
+			Flags:       e.Flags,
+			Value:       e.Value,
+			DeletedAt:   deletedAt,
+			DeleteIndex: idx,
+		})
+	}
+	if len(recycled) == 0 {
+		return nil
+	}
+
+	// Do the inserts in a separate loop so we don't trash the iterator.
+	for _, e := range recycled {
+		if err := tx.Insert("kvs_recycled", e); err != nil {
+			return fmt.Errorf("failed inserting recycled kvs entry: %s", err)
+		}
+	}
+	if err := tx.Insert("index", &IndexEntry{"kvs_recycled", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// KVSRecycled returns the entries of the recycle bin with the given key
+// prefix.
+func (s *Store) KVSRecycled(ws memdb.WatchSet, prefix string) (uint64, structs.RecycledDirEntries, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "kvs_recycled")
+
+	entries, err := tx.Get("kvs_recycled", "id_prefix", prefix)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed recycled kvs lookup: %s", err)
+	}
+	ws.Add(entries.WatchCh())
+
+	var results structs.RecycledDirEntries
+	for entry := entries.Next(); entry != nil; entry = entries.Next() {
+		results = append(results, entry.(*structs.RecycledDirEntry))
+	}
+	return idx, results, nil
+}
+
+// KVSRecycleBinApply restores or purges the recycled entry of a key, or of
+// all the keys with the given prefix if recurse is set, and returns the
+// number of entries which were restored or purged. Entries are not restored
+// over keys which exist again; they are left in the recycle bin. If
+// deletedBefore is set, only the entries deleted before it are purged.
+func (s *Store) KVSRecycleBinApply(idx uint64, op structs.KVSRecycleBinOp, key string, recurse bool, deletedBefore time.Time) (int, error) {
+	switch op {
+	case structs.KVSRecycleBinRestore, structs.KVSRecycleBinPurge:
+	default:
+		return 0, fmt.Errorf("Invalid recycle bin operation '%s'", op)
+	}
+
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	index := "id"
+	if recurse {
+		index = "id_prefix"
+	}
+	entries, err := tx.Get("kvs_recycled", index, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed recycled kvs lookup: %s", err)
+	}
+	var recycled structs.RecycledDirEntries
+	for entry := entries.Next(); entry != nil; entry = entries.Next() {
+		recycled = append(recycled, entry.(*structs.RecycledDirEntry))
+	}
+
+	// Do the changes in a separate loop so we don't trash the iterator.
+	var changed int
+	for _, e := range recycled {
+		switch op {
+		case structs.KVSRecycleBinRestore:
+			existing, err := tx.First("kvs", "id", e.Key)
+			if err != nil {
+				return 0, fmt.Errorf("failed kvs lookup: %s", err)
+			}
+			if existing != nil {
+				continue
+			}
+			entry := &structs.DirEntry{
+				Key:   e.Key,
+				Flags: e.Flags,
+				Value: e.Value,
+			}
+			if err := s.kvsSetTxn(tx, idx, entry, false); err != nil {
+				return 0, err
+			}
+
+		case structs.KVSRecycleBinPurge:
+			if !deletedBefore.IsZero() && !e.DeletedAt.Before(deletedBefore) {
+				continue
+			}
+		}
+
+		if err := tx.Delete("kvs_recycled", e); err != nil {
+			return 0, fmt.Errorf("failed deleting recycled kvs entry: %s", err)
+		}
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	if err := tx.Insert("index", &IndexEntry{"kvs_recycled", idx}); err != nil {
+		return 0, fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return changed, nil
+}
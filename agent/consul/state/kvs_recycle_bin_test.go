@@ -0,0 +1,116 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore_KVSRecycle(t *testing.T) {
+	s := testStateStore(t)
+	now := time.Now().UTC()
+
+	testSetKey(t, s, 1, "foo", "foo")
+	testSetKey(t, s, 2, "foo/bar", "bar")
+	testSetKey(t, s, 3, "foo/baz", "baz")
+	testSetKey(t, s, 4, "zip", "zip")
+
+	// Recycle a single key.
+	require.NoError(t, s.KVSRecycle(5, "zip", now))
+	idx, entries, err := s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), idx)
+	require.Len(t, entries, 1)
+	require.Equal(t, "zip", entries[0].Key)
+	require.Equal(t, []byte("zip"), entries[0].Value)
+	require.Equal(t, uint64(5), entries[0].DeleteIndex)
+	require.True(t, now.Equal(entries[0].DeletedAt))
+
+	// A failed CAS doesn't recycle the key.
+	ok, err := s.KVSRecycleCAS(6, 2, "foo", now)
+	require.NoError(t, err)
+	require.False(t, ok)
+	idx, entries, err = s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), idx)
+	require.Len(t, entries, 1)
+
+	// Recycle a tree.
+	require.NoError(t, s.KVSRecycleTree(7, "foo/", now))
+	idx, entries, err = s.KVSRecycled(nil, "foo")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), idx)
+	require.Len(t, entries, 2)
+	require.Equal(t, "foo/bar", entries[0].Key)
+	require.Equal(t, "foo/baz", entries[1].Key)
+
+	_, remaining, err := s.KVSList(nil, "")
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, "foo", remaining[0].Key)
+}
+
+func TestStateStore_KVSRecycleBinApply(t *testing.T) {
+	s := testStateStore(t)
+	now := time.Now().UTC()
+
+	testSetKey(t, s, 1, "foo/a", "a")
+	testSetKey(t, s, 2, "foo/b", "b")
+	testSetKey(t, s, 3, "foo/c", "c")
+	require.NoError(t, s.KVSRecycleTree(4, "foo/", now.Add(-time.Hour)))
+
+	// Recreate one of the keys, which must not be overwritten.
+	testSetKey(t, s, 5, "foo/b", "new")
+
+	n, err := s.KVSRecycleBinApply(6, structs.KVSRecycleBinRestore, "foo/", true, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, e, err := s.KVSGet(nil, "foo/a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), e.Value)
+	require.Equal(t, uint64(6), e.ModifyIndex)
+	_, e, err = s.KVSGet(nil, "foo/b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), e.Value)
+
+	// The entry of the recreated key is left in the recycle bin.
+	idx, entries, err := s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), idx)
+	require.Len(t, entries, 1)
+	require.Equal(t, "foo/b", entries[0].Key)
+
+	// Purging with a deletion time only removes the older entries.
+	require.NoError(t, s.KVSRecycle(7, "foo/a", now))
+	n, err = s.KVSRecycleBinApply(8, structs.KVSRecycleBinPurge, "", true, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	idx, entries, err = s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), idx)
+	require.Len(t, entries, 1)
+	require.Equal(t, "foo/a", entries[0].Key)
+
+	// Purge a single key.
+	n, err = s.KVSRecycleBinApply(9, structs.KVSRecycleBinPurge, "foo/a", false, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, entries, err = s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 0)
+
+	// Nothing left to do doesn't bump the index.
+	n, err = s.KVSRecycleBinApply(10, structs.KVSRecycleBinPurge, "foo/a", false, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	idx, _, err = s.KVSRecycled(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, uint64(9), idx)
+
+	// Invalid operations are rejected.
+	_, err = s.KVSRecycleBinApply(11, "nope", "foo/a", false, time.Time{})
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -1134,6 +1135,101 @@ func TestStateStore_KVSLockDelay(t *testing.T) {
 	}
 }
 
+func TestStateStore_KVSLocks(t *testing.T) {
+	s := testStateStore(t)
+
+	// Nothing is locked yet.
+	idx, locks, err := s.KVSLocks(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 0 || len(locks) != 0 {
+		t.Fatalf("bad: %d %v", idx, locks)
+	}
+
+	testRegisterNode(t, s, 1, "node1")
+	session1, session2, session3 := testUUID(), testUUID(), testUUID()
+	for i, id := range []string{session1, session2, session3} {
+		sess := &structs.Session{ID: id, Node: "node1", Name: fmt.Sprintf("session%d", i+1)}
+		if err := s.SessionCreate(uint64(2+i), sess); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Acquire a lock and let another session fail to acquire it.
+	before := time.Now()
+	ok, err := s.KVSLock(5, &structs.DirEntry{Key: "foo", Session: session1})
+	if !ok || err != nil {
+		t.Fatalf("didn't get the lock: %v %s", ok, err)
+	}
+	ok, err = s.KVSLock(6, &structs.DirEntry{Key: "foo", Session: session2})
+	if ok || err != nil {
+		t.Fatalf("got the lock: %v %s", ok, err)
+	}
+
+	// Simulate a session rejected due to a lock-delay on another key.
+	s.lockDelay.SetExpiration("bar", time.Now(), time.Minute)
+	s.KVSLockRejected("bar", session3)
+
+	// Make sure the watch fires when the lock is released.
+	ws := memdb.NewWatchSet()
+	idx, locks, err = s.KVSLocks(ws)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 5 || len(locks) != 2 {
+		t.Fatalf("bad: %d %v", idx, locks)
+	}
+	foo, bar := locks[1], locks[0]
+	if foo.Key != "foo" || foo.Session != session1 || foo.SessionName != "session1" ||
+		foo.Node != "node1" || foo.LockIndex != 1 || foo.Waiters != 1 ||
+		foo.AcquiredAt.Before(before) || !foo.LockDelayUntil.IsZero() {
+		t.Fatalf("bad: %#v", foo)
+	}
+	if bar.Key != "bar" || bar.Session != "" || bar.Waiters != 1 ||
+		!bar.LockDelayUntil.After(time.Now()) {
+		t.Fatalf("bad: %#v", bar)
+	}
+
+	ok, err = s.KVSUnlock(7, &structs.DirEntry{Key: "foo", Session: session1})
+	if !ok || err != nil {
+		t.Fatalf("didn't handle unlocking: %v %s", ok, err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// The released key is still reported while session2 waits for it.
+	_, locks, err = s.KVSLocks(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(locks) != 2 || locks[1].Session != "" || locks[1].Waiters != 1 ||
+		!locks[1].AcquiredAt.IsZero() {
+		t.Fatalf("bad: %#v", locks[1])
+	}
+
+	// Acquiring the lock resets its waiters and destroying the waiting
+	// session drops it from the count.
+	ok, err = s.KVSLock(8, &structs.DirEntry{Key: "foo", Session: session2})
+	if !ok || err != nil {
+		t.Fatalf("didn't get the lock: %v %s", ok, err)
+	}
+	if err := s.SessionDestroy(9, session3); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	_, locks, err = s.KVSLocks(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(locks) != 2 || locks[1].Session != session2 || locks[1].Waiters != 0 {
+		t.Fatalf("bad: %#v", locks[1])
+	}
+	if locks[0].Key != "bar" || locks[0].Waiters != 0 {
+		t.Fatalf("bad: %#v", locks[0])
+	}
+}
+
 func TestStateStore_KVSLock(t *testing.T) {
 	s := testStateStore(t)
 
@@ -0,0 +1,118 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// LockTracker records when the locks on keys were acquired and which
+// sessions failed to acquire them since, in order to make lock contention
+// observable. Like the lock-delay this relies on wall-time and is not part of
+// the replicated state, so it is only maintained for the lifetime of the
+// state store and may differ slightly between servers.
+type LockTracker struct {
+	// acquired has the session and time of the last acquisition of a lock,
+	// organized by key.
+	acquired map[string]lockAcquisition
+
+	// waiters has the sessions which failed to acquire a lock since it was
+	// last acquired, organized by key.
+	waiters map[string]map[string]struct{}
+
+	// lock protects the maps.
+	lock sync.RWMutex
+}
+
+type lockAcquisition struct {
+	session string
+	time    time.Time
+}
+
+// NewLockTracker returns a new lock tracker.
+func NewLockTracker() *LockTracker {
+	return &LockTracker{
+		acquired: make(map[string]lockAcquisition),
+		waiters:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Acquired records that the given session acquired the lock on a key at the
+// given time. The waiters of the key are reset.
+func (t *LockTracker) Acquired(key, session string, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.acquired[key] = lockAcquisition{session: session, time: now}
+	delete(t.waiters, key)
+}
+
+// Rejected records that the given session failed to acquire the lock on a
+// key, either because it is held by another session or because of a
+// lock-delay.
+func (t *LockTracker) Rejected(key, session string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	waiters, ok := t.waiters[key]
+	if !ok {
+		waiters = make(map[string]struct{})
+		t.waiters[key] = waiters
+	}
+	waiters[session] = struct{}{}
+}
+
+// Released records that the lock on a key was released. The waiters of the
+// key are kept since they are still waiting to acquire it.
+func (t *LockTracker) Released(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.acquired, key)
+}
+
+// AcquiredAt returns the time the given session acquired the lock on a key,
+// or the zero time if it is not known.
+func (t *LockTracker) AcquiredAt(key, session string) time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	a, ok := t.acquired[key]
+	if !ok || a.session != session {
+		return time.Time{}
+	}
+	return a.time
+}
+
+// Waiters returns the sessions which failed to acquire the lock on a key
+// since it was last acquired.
+func (t *LockTracker) Waiters(key string) []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var sessions []string
+	for session := range t.waiters[key] {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Keys returns the keys which have waiters.
+func (t *LockTracker) Keys() []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var keys []string
+	for key := range t.waiters {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Forget drops everything recorded about a key because it was deleted.
+func (t *LockTracker) Forget(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.acquired, key)
+	delete(t.waiters, key)
+}
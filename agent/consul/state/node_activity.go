@@ -0,0 +1,59 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// NodeActivity records when the nodes were last registered, including
+// registrations of their services and checks which didn't change anything.
+// Like the service history it is not part of the replicated state, so it
+// only covers the registrations applied since the state store was created
+// and the times may differ slightly between servers.
+type NodeActivity struct {
+	// since is when the tracking started.
+	since time.Time
+
+	// last has the time of the last registration of each node.
+	last map[string]time.Time
+
+	// lock protects the map.
+	lock sync.RWMutex
+}
+
+// NewNodeActivity returns a new node activity tracker.
+func NewNodeActivity() *NodeActivity {
+	return &NodeActivity{
+		since: time.Now(),
+		last:  make(map[string]time.Time),
+	}
+}
+
+// Touch records a registration of the node.
+func (a *NodeActivity) Touch(node string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.last[node] = time.Now()
+}
+
+// Forget drops the node, e.g. because it was deregistered.
+func (a *NodeActivity) Forget(node string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.last, node)
+}
+
+// LastRegistered returns the time of the last registration of the node. The
+// time the tracking started is returned for nodes which weren't registered
+// since, so the node was last registered at or before the returned time.
+func (a *NodeActivity) LastRegistered(node string) time.Time {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if t, ok := a.last[node]; ok {
+		return t
+	}
+	return a.since
+}
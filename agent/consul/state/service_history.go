@@ -0,0 +1,68 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// serviceHistoryLimit is the number of events kept for each service.
+const serviceHistoryLimit = 256
+
+// ServiceHistory records the registrations, deregistrations and health
+// changes of service instances. Like the lock tracker it is not part of the
+// replicated state, so it only covers the changes applied since the state
+// store was created and the event times may differ slightly between servers.
+type ServiceHistory struct {
+	// events has the most recent events, organized by service name. The
+	// events of a service are ordered by index.
+	events map[string]structs.ServiceInstanceEvents
+
+	// limit is the number of events kept for each service.
+	limit int
+
+	// lock protects the map.
+	lock sync.RWMutex
+}
+
+// NewServiceHistory returns a new service history which keeps the given
+// number of events for each service.
+func NewServiceHistory(limit int) *ServiceHistory {
+	return &ServiceHistory{
+		events: make(map[string]structs.ServiceInstanceEvents),
+		limit:  limit,
+	}
+}
+
+// Record appends an event to the history of its service, dropping the
+// oldest event of the service if it is full.
+func (h *ServiceHistory) Record(ev *structs.ServiceInstanceEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	events := append(h.events[ev.ServiceName], ev)
+	if len(events) > h.limit {
+		events = append(structs.ServiceInstanceEvents(nil), events[len(events)-h.limit:]...)
+	}
+	h.events[ev.ServiceName] = events
+}
+
+// Events returns a copy of the history of a service.
+func (h *ServiceHistory) Events(service string) structs.ServiceInstanceEvents {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	events := h.events[service]
+	if len(events) == 0 {
+		return nil
+	}
+	return append(structs.ServiceInstanceEvents(nil), events...)
+}
+
+// Reset drops all the recorded events.
+func (h *ServiceHistory) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.events = make(map[string]structs.ServiceInstanceEvents)
+}
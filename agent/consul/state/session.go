@@ -367,6 +367,7 @@ func (s *Store) deleteSessionTxn(tx *memdb.Txn, idx uint64, sessionID string) er
 			if err := s.kvsSetTxn(tx, idx, e, true); err != nil {
 				return fmt.Errorf("failed kvs update: %s", err)
 			}
+			s.lockTracker.Released(e.Key)
 
 			// Apply the lock delay if present.
 			if delay > 0 {
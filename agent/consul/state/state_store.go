@@ -37,6 +37,22 @@ var (
 	// an policy with an empty Name.
 	ErrMissingACLPolicyName = errors.New("Missing ACL Policy Name")
 
+	// ErrMissingACLAuthMethodName is returned when an auth method set is
+	// called on an auth method with an empty Name.
+	ErrMissingACLAuthMethodName = errors.New("Missing ACL Auth Method Name")
+
+	// ErrMissingACLAuthMethodType is returned when an auth method set is
+	// called on an auth method with an empty Type.
+	ErrMissingACLAuthMethodType = errors.New("Missing ACL Auth Method Type")
+
+	// ErrMissingACLBindingRuleID is returned when a binding rule set is
+	// called on a binding rule with an empty ID.
+	ErrMissingACLBindingRuleID = errors.New("Missing ACL Binding Rule ID")
+
+	// ErrMissingACLBindingRuleAuthMethod is returned when a binding rule set
+	// is called on a binding rule with an empty AuthMethod.
+	ErrMissingACLBindingRuleAuthMethod = errors.New("Missing ACL Binding Rule Auth Method")
+
 	// ErrMissingQueryID is returned when a Query set is called on
 	// a Query with an empty ID.
 	ErrMissingQueryID = errors.New("Missing Query ID")
@@ -48,6 +64,10 @@ var (
 	// ErrMissingIntentionID is returned when an Intention set is called
 	// with an Intention with an empty ID.
 	ErrMissingIntentionID = errors.New("Missing Intention ID")
+
+	// ErrVirtualIPsExhausted is returned when a service is registered but
+	// all the virtual IPs of the range are allocated.
+	ErrVirtualIPsExhausted = errors.New("Virtual IP range exhausted")
 )
 
 const (
@@ -77,8 +97,26 @@ type Store struct {
 	// kvsGraveyard manages tombstones for the key value store.
 	kvsGraveyard *Graveyard
 
+	// gc is hinted about the virtual IPs freed by deregistrations so that
+	// they are reaped along with the tombstones of the key value store.
+	gc *TombstoneGC
+
 	// lockDelay holds expiration times for locks associated with keys.
 	lockDelay *Delay
+
+	// lockTracker holds acquisition times and waiters of locks associated
+	// with keys.
+	lockTracker *LockTracker
+
+	// serviceHistory holds the recent changes of service instances.
+	serviceHistory *ServiceHistory
+
+	// nodeActivity holds the times of the last registrations of nodes.
+	nodeActivity *NodeActivity
+
+	// virtualIPMaxOffset is the highest offset which is allocated to the
+	// virtual IP of a service, or zero if the offsets aren't bounded.
+	virtualIPMaxOffset uint64
 }
 
 // Snapshot is used to provide a point-in-time snapshot. It
@@ -123,11 +161,15 @@ func NewStateStore(gc *TombstoneGC) (*Store, error) {
 
 	// Create and return the state store.
 	s := &Store{
-		schema:       schema,
-		db:           db,
-		abandonCh:    make(chan struct{}),
-		kvsGraveyard: NewGraveyard(gc),
-		lockDelay:    NewDelay(),
+		schema:         schema,
+		db:             db,
+		abandonCh:      make(chan struct{}),
+		kvsGraveyard:   NewGraveyard(gc),
+		gc:             gc,
+		lockDelay:      NewDelay(),
+		lockTracker:    NewLockTracker(),
+		serviceHistory: NewServiceHistory(serviceHistoryLimit),
+		nodeActivity:   NewNodeActivity(),
 	}
 	return s, nil
 }
@@ -189,6 +231,10 @@ func (s *Restore) Abort() {
 // called.
 func (s *Restore) Commit() {
 	s.tx.Commit()
+
+	// The restored registrations are not changes of the service instances
+	// so they are not part of their history.
+	s.store.serviceHistory.Reset()
 }
 
 // AbandonCh returns a channel you can wait on to know if the state store was
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// uiConfigTableSchema returns a new table schema used for storing the UI
+// config.
+func uiConfigTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "ui-config",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: true,
+				Unique:       true,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) { return true, nil },
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	registerSchema(uiConfigTableSchema)
+}
+
+// UIConfig is used to pull the UI config from the snapshot.
+func (s *Snapshot) UIConfig() (*structs.UIConfig, error) {
+	c, err := s.tx.First("ui-config", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	config, ok := c.(*structs.UIConfig)
+	if !ok {
+		return nil, nil
+	}
+
+	return config, nil
+}
+
+// UIConfig is used when restoring from a snapshot.
+func (s *Restore) UIConfig(config *structs.UIConfig) error {
+	if err := s.tx.Insert("ui-config", config); err != nil {
+		return fmt.Errorf("failed restoring UI config: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, config.ModifyIndex, "ui-config"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	return nil
+}
+
+// UIConfig is used to get the current UI config. The config is nil if it
+// was never set.
+func (s *Store) UIConfig(ws memdb.WatchSet) (uint64, *structs.UIConfig, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "ui-config")
+
+	watchCh, c, err := tx.FirstWatch("ui-config", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed UI config lookup: %s", err)
+	}
+	ws.Add(watchCh)
+
+	config, ok := c.(*structs.UIConfig)
+	if !ok {
+		return idx, nil, nil
+	}
+
+	return idx, config, nil
+}
+
+// UIConfigSet is used to set the current UI config.
+func (s *Store) UIConfigSet(idx uint64, config *structs.UIConfig) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	if err := s.uiConfigSetTxn(idx, tx, config); err != nil {
+		return err
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// UIConfigCAS is used to try updating the UI config with a given Raft
+// index. If the CAS index specified is not equal to the last observed index
+// for the config, then the call is a noop. An index of 0 only sets the
+// config if it doesn't exist yet.
+func (s *Store) UIConfigCAS(idx, cidx uint64, config *structs.UIConfig) (bool, error) {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	// Check for an existing config
+	existing, err := tx.First("ui-config", "id")
+	if err != nil {
+		return false, fmt.Errorf("failed UI config lookup: %s", err)
+	}
+
+	// If the existing index does not match the provided CAS
+	// index arg, then we shouldn't update anything and can safely
+	// return early here.
+	if e, ok := existing.(*structs.UIConfig); ok && e.ModifyIndex != cidx {
+		return false, nil
+	}
+	if existing == nil && cidx != 0 {
+		return false, nil
+	}
+
+	if err := s.uiConfigSetTxn(idx, tx, config); err != nil {
+		return false, err
+	}
+
+	tx.Commit()
+	return true, nil
+}
+
+func (s *Store) uiConfigSetTxn(idx uint64, tx *memdb.Txn, config *structs.UIConfig) error {
+	// Check for an existing config
+	existing, err := tx.First("ui-config", "id")
+	if err != nil {
+		return fmt.Errorf("failed UI config lookup: %s", err)
+	}
+
+	// Set the indexes.
+	if existing != nil {
+		config.CreateIndex = existing.(*structs.UIConfig).CreateIndex
+	} else {
+		config.CreateIndex = idx
+	}
+	config.ModifyIndex = idx
+
+	if err := tx.Insert("ui-config", config); err != nil {
+		return fmt.Errorf("failed updating UI config: %s", err)
+	}
+	if err := tx.Insert("index", &IndexEntry{"ui-config", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore_UIConfig(t *testing.T) {
+	s := testStateStore(t)
+
+	// Nothing is returned before the config is set.
+	ws := memdb.NewWatchSet()
+	idx, config, err := s.UIConfig(ws)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), idx)
+	require.Nil(t, config)
+
+	expected := &structs.UIConfig{
+		DashboardURLTemplates: map[string]string{
+			"*": "https://grafana/d/{{Service}}",
+		},
+		BannerMessage: "Maintenance tonight",
+		BannerLevel:   structs.UIBannerWarning,
+	}
+	require.NoError(t, s.UIConfigSet(1, expected))
+	require.True(t, watchFired(ws))
+
+	idx, config, err = s.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), idx)
+	require.Equal(t, expected, config)
+	require.Equal(t, uint64(1), config.CreateIndex)
+
+	// The create index is kept on update.
+	require.NoError(t, s.UIConfigSet(2, &structs.UIConfig{}))
+	idx, config, err = s.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), idx)
+	require.Equal(t, structs.RaftIndex{CreateIndex: 1, ModifyIndex: 2}, config.RaftIndex)
+}
+
+func TestStateStore_UIConfigCAS(t *testing.T) {
+	s := testStateStore(t)
+
+	// A CAS with index 0 only succeeds if there is no config yet.
+	ok, err := s.UIConfigCAS(1, 5, &structs.UIConfig{BannerMessage: "a"})
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = s.UIConfigCAS(1, 0, &structs.UIConfig{BannerMessage: "a"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.UIConfigCAS(2, 0, &structs.UIConfig{BannerMessage: "b"})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Do a CAS with the wrong index.
+	ok, err = s.UIConfigCAS(2, 5, &structs.UIConfig{BannerMessage: "b"})
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, config, err := s.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, "a", config.BannerMessage)
+
+	// Do another CAS, this time with the correct index.
+	ok, err = s.UIConfigCAS(2, 1, &structs.UIConfig{BannerMessage: "b"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	idx, config, err := s.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), idx)
+	require.Equal(t, "b", config.BannerMessage)
+}
+
+func TestStateStore_UIConfig_Snapshot_Restore(t *testing.T) {
+	s := testStateStore(t)
+	before := &structs.UIConfig{BannerMessage: "hello"}
+	require.NoError(t, s.UIConfigSet(99, before))
+
+	snap := s.Snapshot()
+	defer snap.Close()
+
+	require.NoError(t, s.UIConfigSet(100, &structs.UIConfig{}))
+
+	config, err := snap.UIConfig()
+	require.NoError(t, err)
+	require.Equal(t, "hello", config.BannerMessage)
+
+	s2 := testStateStore(t)
+	restore := s2.Restore()
+	require.NoError(t, restore.UIConfig(config))
+	restore.Commit()
+
+	idx, res, err := s2.UIConfig(nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(99), idx)
+	require.Equal(t, config, res)
+}
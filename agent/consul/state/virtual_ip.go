@@ -0,0 +1,257 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// serviceVirtualIPsTableSchema returns a new table schema used for storing
+// the virtual IPs allocated to the services.
+func serviceVirtualIPsTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "service-virtual-ips",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field:     "Service",
+					Lowercase: true,
+				},
+			},
+		},
+	}
+}
+
+func init() {
+	registerSchema(serviceVirtualIPsTableSchema)
+}
+
+// ServiceVirtualIPs is used to pull the virtual IPs from the snapshot.
+func (s *Snapshot) ServiceVirtualIPs() (memdb.ResultIterator, error) {
+	return s.tx.Get("service-virtual-ips", "id")
+}
+
+// ServiceVirtualIP is used when restoring from a snapshot.
+func (s *Restore) ServiceVirtualIP(vip *structs.ServiceVirtualIP) error {
+	if err := s.tx.Insert("service-virtual-ips", vip); err != nil {
+		return fmt.Errorf("failed restoring service virtual IP: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, vip.ModifyIndex, "service-virtual-ips"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// SetVirtualIPMaxOffset bounds the offsets which are allocated to the virtual
+// IPs of new services. It has to be called before the store is used, with the
+// same value on all the servers. Services keep offsets above it which they
+// were allocated before.
+func (s *Store) SetVirtualIPMaxOffset(max uint64) {
+	s.virtualIPMaxOffset = max
+}
+
+// ServiceVirtualIP returns the virtual IP allocated to a service, or nil if
+// the service isn't registered.
+func (s *Store) ServiceVirtualIP(ws memdb.WatchSet, service string) (uint64, *structs.ServiceVirtualIP, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "service-virtual-ips")
+
+	watchCh, vip, err := tx.FirstWatch("service-virtual-ips", "id", service)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed service virtual IP lookup: %s", err)
+	}
+	ws.Add(watchCh)
+
+	if vip == nil || vip.(*structs.ServiceVirtualIP).FreedIndex != 0 {
+		return idx, nil, nil
+	}
+	return idx, vip.(*structs.ServiceVirtualIP), nil
+}
+
+// ServiceVirtualIPs returns the virtual IPs allocated to all the services.
+func (s *Store) ServiceVirtualIPs(ws memdb.WatchSet) (uint64, []*structs.ServiceVirtualIP, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	idx := maxIndexTxn(tx, "service-virtual-ips")
+
+	iter, err := tx.Get("service-virtual-ips", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed service virtual IP lookup: %s", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var results []*structs.ServiceVirtualIP
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if vip := raw.(*structs.ServiceVirtualIP); vip.FreedIndex == 0 {
+			results = append(results, vip)
+		}
+	}
+	return idx, results, nil
+}
+
+// ensureServiceVirtualIPTxn allocates a virtual IP to the service if it
+// doesn't have one yet. A service which was deregistered recently gets its
+// previous offset back. Otherwise the offset of the service which was freed
+// first is reused once it has been reaped, so that clients which still
+// cache the IP of a deregistered service don't reach another one. If there
+// is none, the offset after the highest one ever allocated is used, unless
+// it is above the maximum offset, which fails the registration. Proxies
+// don't get a virtual IP since they are reached through the virtual IP of
+// the service they proxy for.
+func (s *Store) ensureServiceVirtualIPTxn(tx *memdb.Txn, idx uint64, svc *structs.NodeService) error {
+	if svc.Kind != structs.ServiceKindTypical {
+		return nil
+	}
+
+	existing, err := tx.First("service-virtual-ips", "id", svc.Service)
+	if err != nil {
+		return fmt.Errorf("failed service virtual IP lookup: %s", err)
+	}
+	if existing != nil {
+		freed := existing.(*structs.ServiceVirtualIP)
+		if freed.FreedIndex == 0 {
+			return nil
+		}
+		vip := *freed
+		vip.FreedIndex = 0
+		vip.ModifyIndex = idx
+		return insertServiceVirtualIPTxn(tx, idx, &vip)
+	}
+
+	// New services are rare compared to the updates of their instances, so
+	// the reaped offsets are found by scanning the allocated ones.
+	reapIndex, err := indexValueTxn(tx, "service-virtual-ips-reaped")
+	if err != nil {
+		return err
+	}
+	highest, err := indexValueTxn(tx, "service-virtual-ips-offset")
+	if err != nil {
+		return err
+	}
+	iter, err := tx.Get("service-virtual-ips", "id")
+	if err != nil {
+		return fmt.Errorf("failed service virtual IP lookup: %s", err)
+	}
+	var reuse *structs.ServiceVirtualIP
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		vip := raw.(*structs.ServiceVirtualIP)
+		if vip.Offset > highest {
+			highest = vip.Offset
+		}
+		if vip.FreedIndex == 0 || vip.FreedIndex > reapIndex || !s.virtualIPInRange(vip.Offset) {
+			continue
+		}
+		if reuse == nil || vip.FreedIndex < reuse.FreedIndex ||
+			(vip.FreedIndex == reuse.FreedIndex && vip.Offset < reuse.Offset) {
+			reuse = vip
+		}
+	}
+
+	offset := highest + 1
+	if reuse == nil && !s.virtualIPInRange(offset) {
+		return ErrVirtualIPsExhausted
+	}
+	if reuse != nil {
+		offset = reuse.Offset
+		if err := tx.Delete("service-virtual-ips", reuse); err != nil {
+			return fmt.Errorf("failed deleting service virtual IP: %s", err)
+		}
+	} else if err := tx.Insert("index", &IndexEntry{"service-virtual-ips-offset", offset}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	vip := &structs.ServiceVirtualIP{
+		Service: svc.Service,
+		Offset:  offset,
+		RaftIndex: structs.RaftIndex{
+			CreateIndex: idx,
+			ModifyIndex: idx,
+		},
+	}
+	return insertServiceVirtualIPTxn(tx, idx, vip)
+}
+
+// virtualIPInRange returns true if the offset can be allocated.
+func (s *Store) virtualIPInRange(offset uint64) bool {
+	return s.virtualIPMaxOffset == 0 || offset <= s.virtualIPMaxOffset
+}
+
+// freeServiceVirtualIPTxn frees the virtual IP of a service once its last
+// instance is removed. The offset stays with the service until it is reaped
+// with the KV tombstones, which happens after the tombstone TTL.
+func (s *Store) freeServiceVirtualIPTxn(tx *memdb.Txn, idx uint64, service string) error {
+	remaining, err := tx.First("services", "service", service)
+	if err != nil {
+		return fmt.Errorf("failed service lookup: %s", err)
+	}
+	if remaining != nil {
+		return nil
+	}
+
+	existing, err := tx.First("service-virtual-ips", "id", service)
+	if err != nil {
+		return fmt.Errorf("failed service virtual IP lookup: %s", err)
+	}
+	if existing == nil || existing.(*structs.ServiceVirtualIP).FreedIndex != 0 {
+		return nil
+	}
+	vip := *existing.(*structs.ServiceVirtualIP)
+	vip.FreedIndex = idx
+	vip.ModifyIndex = idx
+	if err := insertServiceVirtualIPTxn(tx, idx, &vip); err != nil {
+		return err
+	}
+
+	// Hint the GC so that the leader reaps the freed offset.
+	if s.gc != nil {
+		tx.Defer(func() { s.gc.Hint(idx) })
+	}
+	return nil
+}
+
+// reapServiceVirtualIPsTxn makes the offsets which were freed at or before
+// the given index available to other services.
+func reapServiceVirtualIPsTxn(tx *memdb.Txn, index uint64) error {
+	reapIndex, err := indexValueTxn(tx, "service-virtual-ips-reaped")
+	if err != nil {
+		return err
+	}
+	if index <= reapIndex {
+		return nil
+	}
+	if err := tx.Insert("index", &IndexEntry{"service-virtual-ips-reaped", index}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// insertServiceVirtualIPTxn inserts or updates the virtual IP of a service.
+func insertServiceVirtualIPTxn(tx *memdb.Txn, idx uint64, vip *structs.ServiceVirtualIP) error {
+	if err := tx.Insert("service-virtual-ips", vip); err != nil {
+		return fmt.Errorf("failed inserting service virtual IP: %s", err)
+	}
+	if err := indexUpdateMaxTxn(tx, idx, "service-virtual-ips"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// indexValueTxn returns the value of an entry of the index table which is
+// not a table index, or zero if it isn't set.
+func indexValueTxn(tx *memdb.Txn, key string) (uint64, error) {
+	raw, err := tx.First("index", "id", key)
+	if err != nil {
+		return 0, fmt.Errorf("failed index lookup: %s", err)
+	}
+	if raw == nil {
+		return 0, nil
+	}
+	return raw.(*IndexEntry).Value, nil
+}
@@ -0,0 +1,236 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStore_ServiceVirtualIP(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	// Services without instances have no virtual IP.
+	ws := memdb.NewWatchSet()
+	idx, vip, err := s.ServiceVirtualIP(ws, "redis")
+	require.NoError(err)
+	require.Nil(vip)
+	require.Equal(uint64(0), idx)
+
+	// The first instance of a service allocates its virtual IP.
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterNode(t, s, 2, "node2")
+	testRegisterService(t, s, 3, "node1", "redis")
+	require.True(watchFired(ws))
+
+	ws = memdb.NewWatchSet()
+	idx, vip, err = s.ServiceVirtualIP(ws, "redis")
+	require.NoError(err)
+	require.Equal(uint64(3), idx)
+	require.Equal("redis", vip.Service)
+	require.Equal(uint64(1), vip.Offset)
+
+	// Lookups are case insensitive.
+	_, vip, err = s.ServiceVirtualIP(nil, "REDIS")
+	require.NoError(err)
+	require.Equal(uint64(1), vip.Offset)
+
+	// Other services get the next offset, further instances keep it.
+	testRegisterService(t, s, 4, "node1", "web")
+	testRegisterService(t, s, 5, "node2", "redis")
+	require.False(watchFired(ws))
+
+	_, vip, err = s.ServiceVirtualIP(nil, "web")
+	require.NoError(err)
+	require.Equal(uint64(2), vip.Offset)
+
+	// Proxies don't get a virtual IP.
+	proxy := &structs.NodeService{
+		Kind:    structs.ServiceKindConnectProxy,
+		ID:      "web-proxy",
+		Service: "web-proxy",
+		Port:    20000,
+		Proxy:   structs.ConnectProxyConfig{DestinationServiceName: "web"},
+	}
+	require.NoError(s.EnsureService(6, "node1", proxy))
+	_, vip, err = s.ServiceVirtualIP(nil, "web-proxy")
+	require.NoError(err)
+	require.Nil(vip)
+
+	// The virtual IP is kept while instances remain.
+	require.NoError(s.DeleteService(7, "node1", "redis"))
+	require.False(watchFired(ws))
+	_, vip, err = s.ServiceVirtualIP(nil, "redis")
+	require.NoError(err)
+	require.Equal(uint64(1), vip.Offset)
+
+	// Removing the last instance frees it.
+	require.NoError(s.DeleteNode(8, "node2"))
+	require.True(watchFired(ws))
+	idx, vip, err = s.ServiceVirtualIP(nil, "redis")
+	require.NoError(err)
+	require.Nil(vip)
+	require.Equal(uint64(8), idx)
+
+	// Freed offsets aren't reused before they are reaped, new services
+	// continue after the highest offset.
+	testRegisterService(t, s, 9, "node1", "db")
+	_, vip, err = s.ServiceVirtualIP(nil, "db")
+	require.NoError(err)
+	require.Equal(uint64(3), vip.Offset)
+
+	// A service which comes back gets its offset again.
+	testRegisterService(t, s, 10, "node1", "redis")
+	_, vip, err = s.ServiceVirtualIP(nil, "redis")
+	require.NoError(err)
+	require.Equal(uint64(1), vip.Offset)
+
+	// Offsets are reused once they are reaped, the oldest first.
+	require.NoError(s.DeleteService(11, "node1", "web"))
+	require.NoError(s.DeleteService(12, "node1", "redis"))
+	require.NoError(s.ReapTombstones(11))
+	testRegisterService(t, s, 13, "node1", "api")
+	_, vip, err = s.ServiceVirtualIP(nil, "api")
+	require.NoError(err)
+	require.Equal(uint64(2), vip.Offset)
+
+	// The offset of redis is still in its grace period.
+	testRegisterService(t, s, 14, "node1", "cache")
+	_, vip, err = s.ServiceVirtualIP(nil, "cache")
+	require.NoError(err)
+	require.Equal(uint64(4), vip.Offset)
+
+	require.NoError(s.ReapTombstones(12))
+	testRegisterService(t, s, 15, "node1", "queue")
+	_, vip, err = s.ServiceVirtualIP(nil, "queue")
+	require.NoError(err)
+	require.Equal(uint64(1), vip.Offset)
+
+	// Reaped offsets at the top are reused as well, after them the
+	// allocation continues from the highest offset.
+	require.NoError(s.DeleteService(16, "node1", "cache"))
+	require.NoError(s.ReapTombstones(16))
+	testRegisterService(t, s, 17, "node1", "search")
+	_, vip, err = s.ServiceVirtualIP(nil, "search")
+	require.NoError(err)
+	require.Equal(uint64(4), vip.Offset)
+	testRegisterService(t, s, 18, "node1", "mail")
+	_, vip, err = s.ServiceVirtualIP(nil, "mail")
+	require.NoError(err)
+	require.Equal(uint64(5), vip.Offset)
+}
+
+func TestStateStore_ServiceVirtualIPs(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	ws := memdb.NewWatchSet()
+	idx, vips, err := s.ServiceVirtualIPs(ws)
+	require.NoError(err)
+	require.Len(vips, 0)
+	require.Equal(uint64(0), idx)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "redis")
+	testRegisterService(t, s, 3, "node1", "web")
+	require.True(watchFired(ws))
+
+	idx, vips, err = s.ServiceVirtualIPs(nil)
+	require.NoError(err)
+	require.Equal(uint64(3), idx)
+	require.Len(vips, 2)
+	require.Equal("redis", vips[0].Service)
+	require.Equal(uint64(1), vips[0].Offset)
+	require.Equal("web", vips[1].Service)
+	require.Equal(uint64(2), vips[1].Offset)
+}
+
+func TestStateStore_ServiceVirtualIP_MaxOffset(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "redis")
+	testRegisterService(t, s, 3, "node1", "web")
+	testRegisterService(t, s, 4, "node1", "db")
+
+	// Once the range is exhausted, new services can't be registered.
+	s.SetVirtualIPMaxOffset(2)
+	svc := &structs.NodeService{ID: "api", Service: "api", Port: 8000}
+	require.Equal(ErrVirtualIPsExhausted, s.EnsureService(5, "node1", svc))
+
+	// Services keep their offsets above the maximum, but the offsets aren't
+	// reused once they are freed.
+	require.NoError(s.DeleteService(6, "node1", "db"))
+	require.NoError(s.ReapTombstones(6))
+	require.Equal(ErrVirtualIPsExhausted, s.EnsureService(7, "node1", svc))
+
+	require.NoError(s.DeleteService(8, "node1", "web"))
+	require.NoError(s.ReapTombstones(8))
+	require.NoError(s.EnsureService(9, "node1", svc))
+	_, vip, err := s.ServiceVirtualIP(nil, "api")
+	require.NoError(err)
+	require.Equal(uint64(2), vip.Offset)
+}
+
+func TestStateStore_ServiceVirtualIP_Rename(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "redis")
+
+	// Renaming the only instance moves the service to a new virtual IP.
+	svc := &structs.NodeService{
+		ID:      "redis",
+		Service: "cache",
+		Port:    1111,
+	}
+	require.NoError(s.EnsureService(3, "node1", svc))
+
+	_, vip, err := s.ServiceVirtualIP(nil, "redis")
+	require.NoError(err)
+	require.Nil(vip)
+
+	_, vip, err = s.ServiceVirtualIP(nil, "cache")
+	require.NoError(err)
+	require.Equal(uint64(2), vip.Offset)
+}
+
+func TestStateStore_ServiceVirtualIP_Snapshot_Restore(t *testing.T) {
+	require := require.New(t)
+	s := testStateStore(t)
+
+	testRegisterNode(t, s, 1, "node1")
+	testRegisterService(t, s, 2, "node1", "redis")
+	testRegisterService(t, s, 3, "node1", "web")
+
+	snap := s.Snapshot()
+	defer snap.Close()
+
+	// Changes after the snapshot aren't in it.
+	testRegisterService(t, s, 4, "node1", "db")
+
+	iter, err := snap.ServiceVirtualIPs()
+	require.NoError(err)
+	var dump []*structs.ServiceVirtualIP
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		dump = append(dump, raw.(*structs.ServiceVirtualIP))
+	}
+	require.Len(dump, 2)
+
+	// Restore the values into a new state store.
+	s2 := testStateStore(t)
+	restore := s2.Restore()
+	for _, vip := range dump {
+		require.NoError(restore.ServiceVirtualIP(vip))
+	}
+	restore.Commit()
+
+	idx, vip, err := s2.ServiceVirtualIP(nil, "web")
+	require.NoError(err)
+	require.Equal(uint64(3), idx)
+	require.Equal(uint64(2), vip.Offset)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/hashicorp/consul/agent/consul/autopilot"
+	"github.com/hashicorp/consul/agent/structs"
 )
 
 // Status endpoint is used to check on server status
@@ -41,6 +42,24 @@ func (s *Status) Peers(args struct{}, reply *[]string) error {
 	return nil
 }
 
+// AppliedIndex returns the Raft index the leader has applied. Since a write
+// is applied on the leader before it completes, reads which require this
+// index are guaranteed to see all writes which completed before the call.
+func (s *Status) AppliedIndex(args *structs.DCSpecificRequest, reply *uint64) error {
+	if done, err := s.server.forward("Status.AppliedIndex", args, args, reply); done {
+		return err
+	}
+
+	// A new leader may not have applied all committed entries until its
+	// barrier completed. The last log index covers them as well.
+	if s.server.isReadyForConsistentReads() {
+		*reply = s.server.raft.AppliedIndex()
+	} else {
+		*reply = s.server.raft.LastIndex()
+	}
+	return nil
+}
+
 // Used by Autopilot to query the raft stats of the local server.
 func (s *Status) RaftStats(args struct{}, reply *autopilot.ServerStats) error {
 	stats := s.server.raft.Stats()
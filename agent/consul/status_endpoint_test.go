@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/agent/pool"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 )
@@ -68,3 +70,45 @@ func TestStatusPeers(t *testing.T) {
 		t.Fatalf("no peers: %v", peers)
 	}
 }
+
+func TestStatusAppliedIndex(t *testing.T) {
+	t.Parallel()
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	write := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVSet,
+		DirEnt: structs.DirEntry{
+			Key:   "test",
+			Value: []byte("test"),
+		},
+	}
+	var ok bool
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Apply", &write, &ok); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	get := structs.KeyRequest{Datacenter: "dc1", Key: "test"}
+	var entries structs.IndexedDirEntries
+	if err := msgpackrpc.CallWithCodec(codec, "KVS.Get", &get, &entries); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(entries.Entries) != 1 {
+		t.Fatalf("bad: %v", entries)
+	}
+
+	arg := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var index uint64
+	if err := msgpackrpc.CallWithCodec(codec, "Status.AppliedIndex", &arg, &index); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index < entries.Entries[0].ModifyIndex {
+		t.Fatalf("bad: index %d is before the write at %d", index, entries.Entries[0].ModifyIndex)
+	}
+}
@@ -49,7 +49,7 @@ func (t *Txn) Apply(args *structs.TxnRequest, reply *structs.TxnResponse) error
 	defer metrics.MeasureSince([]string{"txn", "apply"}, time.Now())
 
 	// Run the pre-checks before we send the transaction into Raft.
-	authorizer, err := t.srv.ResolveToken(args.Token)
+	authorizer, err := t.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -100,7 +100,7 @@ func (t *Txn) Read(args *structs.TxnReadRequest, reply *structs.TxnReadResponse)
 	}
 
 	// Run the pre-checks before we perform the read.
-	authorizer, err := t.srv.ResolveToken(args.Token)
+	authorizer, err := t.srv.ResolveRequestToken(args)
 	if err != nil {
 		return err
 	}
@@ -316,7 +316,7 @@ func TestTxn_Apply_ACLDeny(t *testing.T) {
 		default:
 			expected.Errors = append(expected.Errors, &structs.TxnError{
 				OpIndex: i,
-				What:    acl.ErrPermissionDenied.Error(),
+				What:    txnKVDenied(op.KV.Verb, op.KV.DirEnt.Key),
 			})
 		}
 	}
@@ -581,7 +581,7 @@ func TestTxn_Read_ACLDeny(t *testing.T) {
 		default:
 			expected.Errors = append(expected.Errors, &structs.TxnError{
 				OpIndex: i,
-				What:    acl.ErrPermissionDenied.Error(),
+				What:    txnKVDenied(op.KV.Verb, op.KV.DirEnt.Key),
 			})
 		}
 	}
@@ -589,3 +589,16 @@ func TestTxn_Read_ACLDeny(t *testing.T) {
 		t.Fatalf("bad %v", out)
 	}
 }
+
+// txnKVDenied returns the error of a KV operation of a transaction which is
+// denied by ACLs.
+func txnKVDenied(verb api.KVOp, key string) string {
+	switch verb {
+	case api.KVDeleteTree:
+		return acl.PermissionDenied("key_prefix", key, "write").Error()
+	case api.KVCheckSession, api.KVCheckIndex:
+		return acl.PermissionDenied("key", key, "read").Error()
+	default:
+		return acl.PermissionDenied("key", key, "write").Error()
+	}
+}
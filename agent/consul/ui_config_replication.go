@@ -0,0 +1,145 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+const (
+	// uiConfigReplicationMaxRetryBackoff is the max number of seconds to
+	// wait between failed replication attempts of the UI config.
+	uiConfigReplicationMaxRetryBackoff = 64
+)
+
+// startUIConfigReplication starts a goroutine which replicates the UI config
+// from the primary datacenter. It does nothing in the primary datacenter.
+func (s *Server) startUIConfigReplication() {
+	s.uiConfigReplicationLock.Lock()
+	defer s.uiConfigReplicationLock.Unlock()
+
+	if s.uiConfigReplicationEnabled || s.config.PrimaryDatacenter == s.config.Datacenter {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.uiConfigReplicationCancel = cancel
+
+	go func() {
+		var failedAttempts uint
+		var lastRemoteIndex uint64
+		for {
+			index, exit, err := s.replicateUIConfig(lastRemoteIndex, ctx)
+			if exit {
+				return
+			}
+
+			if err != nil {
+				lastRemoteIndex = 0
+				s.logger.Printf("[WARN] consul: UI config replication error (will retry if still leader): %v", err)
+				if (1 << failedAttempts) < uiConfigReplicationMaxRetryBackoff {
+					failedAttempts++
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After((1 << failedAttempts) * time.Second):
+					// do nothing
+				}
+			} else {
+				lastRemoteIndex = index
+				failedAttempts = 0
+			}
+		}
+	}()
+
+	s.logger.Printf("[INFO] consul: started UI config replication")
+	s.uiConfigReplicationEnabled = true
+}
+
+// stopUIConfigReplication stops the UI config replication.
+func (s *Server) stopUIConfigReplication() {
+	s.uiConfigReplicationLock.Lock()
+	defer s.uiConfigReplicationLock.Unlock()
+
+	if !s.uiConfigReplicationEnabled {
+		return
+	}
+
+	s.uiConfigReplicationCancel()
+	s.uiConfigReplicationCancel = nil
+	s.uiConfigReplicationEnabled = false
+}
+
+// replicateUIConfig waits for the UI config of the primary datacenter to
+// change after the given index and copies it to the local state. It returns
+// the remote index and whether replication should stop.
+func (s *Server) replicateUIConfig(lastRemoteIndex uint64, ctx context.Context) (uint64, bool, error) {
+	req := structs.DCSpecificRequest{
+		Datacenter: s.config.PrimaryDatacenter,
+		QueryOptions: structs.QueryOptions{
+			AllowStale:    true,
+			MinQueryIndex: lastRemoteIndex,
+		},
+	}
+	var remote structs.IndexedUIConfig
+	if err := s.RPC("Operator.UIConfigGet", &req, &remote); err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve remote UI config: %v", err)
+	}
+
+	// The fetch is a blocking query during which leadership could have been
+	// lost.
+	select {
+	case <-ctx.Done():
+		return 0, true, nil
+	default:
+	}
+
+	// If the remote index ever goes backwards, it's a good indication that
+	// the remote side was rebuilt and we should do a full sync.
+	if remote.Index < lastRemoteIndex {
+		return 0, false, nil
+	}
+	if remote.Config == nil {
+		return remote.Index, false, nil
+	}
+
+	_, local, err := s.fsm.State().UIConfig(nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve local UI config: %v", err)
+	}
+	if local != nil && uiConfigEqual(local, remote.Config) {
+		return remote.Index, false, nil
+	}
+
+	defer metrics.MeasureSince([]string{"leader", "replication", "ui-config", "apply"}, time.Now())
+	update := *remote.Config
+	update.RaftIndex = structs.RaftIndex{}
+	args := structs.UIConfigSetRequest{
+		Datacenter: s.config.Datacenter,
+		Config:     update,
+	}
+	resp, err := s.raftApply(structs.UIConfigRequestType, &args)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to apply UI config: %v", err)
+	}
+	if respErr, ok := resp.(error); ok {
+		return 0, false, fmt.Errorf("failed to apply UI config: %v", respErr)
+	}
+
+	s.logger.Printf("[DEBUG] consul: UI config replication completed through remote index %d", remote.Index)
+	return remote.Index, false, nil
+}
+
+// uiConfigEqual returns true if the settings of the configs are equal,
+// ignoring their Raft indexes.
+func uiConfigEqual(a, b *structs.UIConfig) bool {
+	x, y := *a, *b
+	x.RaftIndex, y.RaftIndex = structs.RaftIndex{}, structs.RaftIndex{}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,445 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: agent/discovery/discovery.proto
+
+package discovery // import "github.com/hashicorp/consul/agent/discovery"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type ResolveServiceRequest struct {
+	Service              string   `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Query                string   `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Datacenter           string   `protobuf:"bytes,3,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Tags                 []string `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Connect              bool     `protobuf:"varint,5,opt,name=connect,proto3" json:"connect,omitempty"`
+	PassingOnly          bool     `protobuf:"varint,6,opt,name=passing_only,json=passingOnly,proto3" json:"passing_only,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResolveServiceRequest) Reset()         { *m = ResolveServiceRequest{} }
+func (m *ResolveServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*ResolveServiceRequest) ProtoMessage()    {}
+func (*ResolveServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_b925c7e2615707c8, []int{0}
+}
+func (m *ResolveServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResolveServiceRequest.Unmarshal(m, b)
+}
+func (m *ResolveServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResolveServiceRequest.Marshal(b, m, deterministic)
+}
+func (dst *ResolveServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResolveServiceRequest.Merge(dst, src)
+}
+func (m *ResolveServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_ResolveServiceRequest.Size(m)
+}
+func (m *ResolveServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResolveServiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResolveServiceRequest proto.InternalMessageInfo
+
+func (m *ResolveServiceRequest) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *ResolveServiceRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *ResolveServiceRequest) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *ResolveServiceRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *ResolveServiceRequest) GetConnect() bool {
+	if m != nil {
+		return m.Connect
+	}
+	return false
+}
+
+func (m *ResolveServiceRequest) GetPassingOnly() bool {
+	if m != nil {
+		return m.PassingOnly
+	}
+	return false
+}
+
+type ServiceInstance struct {
+	Node                 string            `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	NodeAddress          string            `protobuf:"bytes,2,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	Datacenter           string            `protobuf:"bytes,3,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Id                   string            `protobuf:"bytes,4,opt,name=id,proto3" json:"id,omitempty"`
+	Service              string            `protobuf:"bytes,5,opt,name=service,proto3" json:"service,omitempty"`
+	Address              string            `protobuf:"bytes,6,opt,name=address,proto3" json:"address,omitempty"`
+	Port                 int32             `protobuf:"varint,7,opt,name=port,proto3" json:"port,omitempty"`
+	Tags                 []string          `protobuf:"bytes,8,rep,name=tags,proto3" json:"tags,omitempty"`
+	Meta                 map[string]string `protobuf:"bytes,9,rep,name=meta,proto3" json:"meta,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Health               string            `protobuf:"bytes,10,opt,name=health,proto3" json:"health,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ServiceInstance) Reset()         { *m = ServiceInstance{} }
+func (m *ServiceInstance) String() string { return proto.CompactTextString(m) }
+func (*ServiceInstance) ProtoMessage()    {}
+func (*ServiceInstance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_b925c7e2615707c8, []int{1}
+}
+func (m *ServiceInstance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServiceInstance.Unmarshal(m, b)
+}
+func (m *ServiceInstance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServiceInstance.Marshal(b, m, deterministic)
+}
+func (dst *ServiceInstance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServiceInstance.Merge(dst, src)
+}
+func (m *ServiceInstance) XXX_Size() int {
+	return xxx_messageInfo_ServiceInstance.Size(m)
+}
+func (m *ServiceInstance) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServiceInstance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServiceInstance proto.InternalMessageInfo
+
+func (m *ServiceInstance) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetNodeAddress() string {
+	if m != nil {
+		return m.NodeAddress
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetDatacenter() string {
+	if m != nil {
+		return m.Datacenter
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *ServiceInstance) GetPort() int32 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *ServiceInstance) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+func (m *ServiceInstance) GetMeta() map[string]string {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+func (m *ServiceInstance) GetHealth() string {
+	if m != nil {
+		return m.Health
+	}
+	return ""
+}
+
+type ResolveServiceResponse struct {
+	Instances            []*ServiceInstance `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	Index                uint64             `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ResolveServiceResponse) Reset()         { *m = ResolveServiceResponse{} }
+func (m *ResolveServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ResolveServiceResponse) ProtoMessage()    {}
+func (*ResolveServiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_b925c7e2615707c8, []int{2}
+}
+func (m *ResolveServiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResolveServiceResponse.Unmarshal(m, b)
+}
+func (m *ResolveServiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResolveServiceResponse.Marshal(b, m, deterministic)
+}
+func (dst *ResolveServiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResolveServiceResponse.Merge(dst, src)
+}
+func (m *ResolveServiceResponse) XXX_Size() int {
+	return xxx_messageInfo_ResolveServiceResponse.Size(m)
+}
+func (m *ResolveServiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResolveServiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResolveServiceResponse proto.InternalMessageInfo
+
+func (m *ResolveServiceResponse) GetInstances() []*ServiceInstance {
+	if m != nil {
+		return m.Instances
+	}
+	return nil
+}
+
+func (m *ResolveServiceResponse) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*ResolveServiceRequest)(nil), "hashicorp.consul.discovery.ResolveServiceRequest")
+	proto.RegisterType((*ServiceInstance)(nil), "hashicorp.consul.discovery.ServiceInstance")
+	proto.RegisterMapType((map[string]string)(nil), "hashicorp.consul.discovery.ServiceInstance.MetaEntry")
+	proto.RegisterType((*ResolveServiceResponse)(nil), "hashicorp.consul.discovery.ResolveServiceResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// DiscoveryClient is the client API for Discovery service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type DiscoveryClient interface {
+	// ResolveService returns the instances of a service or the result of a
+	// prepared query.
+	ResolveService(ctx context.Context, in *ResolveServiceRequest, opts ...grpc.CallOption) (*ResolveServiceResponse, error)
+	// WatchService sends the instances of a service and then sends them again
+	// every time they change until the client cancels the call. Prepared
+	// queries can't be watched.
+	WatchService(ctx context.Context, in *ResolveServiceRequest, opts ...grpc.CallOption) (Discovery_WatchServiceClient, error)
+}
+
+type discoveryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewDiscoveryClient(cc *grpc.ClientConn) DiscoveryClient {
+	return &discoveryClient{cc}
+}
+
+func (c *discoveryClient) ResolveService(ctx context.Context, in *ResolveServiceRequest, opts ...grpc.CallOption) (*ResolveServiceResponse, error) {
+	out := new(ResolveServiceResponse)
+	err := c.cc.Invoke(ctx, "/hashicorp.consul.discovery.Discovery/ResolveService", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryClient) WatchService(ctx context.Context, in *ResolveServiceRequest, opts ...grpc.CallOption) (Discovery_WatchServiceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Discovery_serviceDesc.Streams[0], "/hashicorp.consul.discovery.Discovery/WatchService", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &discoveryWatchServiceClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Discovery_WatchServiceClient interface {
+	Recv() (*ResolveServiceResponse, error)
+	grpc.ClientStream
+}
+
+type discoveryWatchServiceClient struct {
+	grpc.ClientStream
+}
+
+func (x *discoveryWatchServiceClient) Recv() (*ResolveServiceResponse, error) {
+	m := new(ResolveServiceResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DiscoveryServer is the server API for Discovery service.
+type DiscoveryServer interface {
+	// ResolveService returns the instances of a service or the result of a
+	// prepared query.
+	ResolveService(context.Context, *ResolveServiceRequest) (*ResolveServiceResponse, error)
+	// WatchService sends the instances of a service and then sends them again
+	// every time they change until the client cancels the call. Prepared
+	// queries can't be watched.
+	WatchService(*ResolveServiceRequest, Discovery_WatchServiceServer) error
+}
+
+func RegisterDiscoveryServer(s *grpc.Server, srv DiscoveryServer) {
+	s.RegisterService(&_Discovery_serviceDesc, srv)
+}
+
+func _Discovery_ResolveService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryServer).ResolveService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hashicorp.consul.discovery.Discovery/ResolveService",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryServer).ResolveService(ctx, req.(*ResolveServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Discovery_WatchService_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ResolveServiceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DiscoveryServer).WatchService(m, &discoveryWatchServiceServer{stream})
+}
+
+type Discovery_WatchServiceServer interface {
+	Send(*ResolveServiceResponse) error
+	grpc.ServerStream
+}
+
+type discoveryWatchServiceServer struct {
+	grpc.ServerStream
+}
+
+func (x *discoveryWatchServiceServer) Send(m *ResolveServiceResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Discovery_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "hashicorp.consul.discovery.Discovery",
+	HandlerType: (*DiscoveryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ResolveService",
+			Handler:    _Discovery_ResolveService_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchService",
+			Handler:       _Discovery_WatchService_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agent/discovery/discovery.proto",
+}
+
+func init() {
+	proto.RegisterFile("agent/discovery/discovery.proto", fileDescriptor_discovery_b925c7e2615707c8)
+}
+
+var fileDescriptor_discovery_b925c7e2615707c8 = []byte{
+	// 464 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x54, 0x4f, 0x8f, 0xd3, 0x3e,
+	0x10, 0x55, 0xd2, 0xb4, 0xdd, 0x4c, 0x57, 0xfb, 0xfb, 0xc9, 0x82, 0x95, 0xd5, 0x03, 0x84, 0x9e,
+	0x22, 0xad, 0x48, 0xa1, 0x08, 0x81, 0xb8, 0x81, 0xe0, 0xd0, 0x03, 0x42, 0x0a, 0x07, 0x24, 0x2e,
+	0x2b, 0xaf, 0x33, 0x6a, 0x2c, 0xb2, 0x76, 0xd6, 0x76, 0xba, 0xe4, 0x9b, 0xf1, 0xb1, 0xf8, 0x02,
+	0x48, 0xc8, 0x4e, 0xd2, 0xed, 0x56, 0xfc, 0xd1, 0x5e, 0x38, 0x75, 0xde, 0x1b, 0xcf, 0xbc, 0xe7,
+	0x19, 0x37, 0xf0, 0x90, 0x6d, 0x50, 0xda, 0x65, 0x21, 0x0c, 0x57, 0x5b, 0xd4, 0xed, 0x4d, 0x94,
+	0xd5, 0x5a, 0x59, 0x45, 0xe6, 0x25, 0x33, 0xa5, 0xe0, 0x4a, 0xd7, 0x19, 0x57, 0xd2, 0x34, 0x55,
+	0xb6, 0x3b, 0xb1, 0xf8, 0x16, 0xc0, 0xfd, 0x1c, 0x8d, 0xaa, 0xb6, 0xf8, 0x11, 0xf5, 0x56, 0x70,
+	0xcc, 0xf1, 0xaa, 0x41, 0x63, 0x09, 0x85, 0xa9, 0xe9, 0x18, 0x1a, 0x24, 0x41, 0x1a, 0xe7, 0x03,
+	0x24, 0xf7, 0x60, 0x7c, 0xd5, 0xa0, 0x6e, 0x69, 0xe8, 0xf9, 0x0e, 0x90, 0x07, 0x00, 0x05, 0xb3,
+	0x8c, 0xa3, 0xb4, 0xa8, 0xe9, 0xc8, 0xa7, 0xf6, 0x18, 0x42, 0x20, 0xb2, 0x6c, 0x63, 0x68, 0x94,
+	0x8c, 0xd2, 0x38, 0xf7, 0xb1, 0xd3, 0xe0, 0x4a, 0x4a, 0xe4, 0x96, 0x8e, 0x93, 0x20, 0x3d, 0xca,
+	0x07, 0x48, 0x1e, 0xc1, 0x71, 0xcd, 0x8c, 0x11, 0x72, 0x73, 0xae, 0x64, 0xd5, 0xd2, 0x89, 0x4f,
+	0xcf, 0x7a, 0xee, 0x83, 0xac, 0xda, 0xc5, 0xf7, 0x10, 0xfe, 0xeb, 0x3d, 0xaf, 0xa5, 0xb1, 0x4c,
+	0x72, 0x74, 0x22, 0x52, 0x15, 0x83, 0x63, 0x1f, 0xbb, 0x56, 0xee, 0xf7, 0x9c, 0x15, 0x85, 0x46,
+	0x63, 0x7a, 0xd7, 0x33, 0xc7, 0xbd, 0xee, 0xa8, 0xbf, 0x7a, 0x3f, 0x81, 0x50, 0x14, 0x34, 0xf2,
+	0x7c, 0x28, 0x8a, 0xfd, 0xd9, 0x8c, 0x6f, 0xcf, 0x86, 0xc2, 0x74, 0xd0, 0x99, 0x74, 0x99, 0x1e,
+	0x3a, 0x6b, 0xb5, 0xd2, 0x96, 0x4e, 0x93, 0x20, 0x1d, 0xe7, 0x3e, 0xde, 0xcd, 0xe4, 0x68, 0x6f,
+	0x26, 0x6b, 0x88, 0x2e, 0xd1, 0x32, 0x1a, 0x27, 0xa3, 0x74, 0xb6, 0x7a, 0x9e, 0xfd, 0x7e, 0x79,
+	0xd9, 0xc1, 0xed, 0xb3, 0xf7, 0x68, 0xd9, 0x3b, 0x69, 0x75, 0x9b, 0xfb, 0x16, 0xe4, 0x14, 0x26,
+	0x25, 0xb2, 0xca, 0x96, 0x14, 0xbc, 0x97, 0x1e, 0xcd, 0x5f, 0x40, 0xbc, 0x3b, 0x4a, 0xfe, 0x87,
+	0xd1, 0x17, 0x6c, 0xfb, 0x89, 0xb9, 0xd0, 0xed, 0x77, 0xcb, 0xaa, 0x06, 0x87, 0xfd, 0x7a, 0xf0,
+	0x2a, 0x7c, 0x19, 0x2c, 0x5a, 0x38, 0x3d, 0x7c, 0x2c, 0xa6, 0x56, 0xd2, 0x20, 0x59, 0x43, 0x2c,
+	0x7a, 0x1b, 0x86, 0x06, 0xde, 0xfa, 0xd9, 0x1d, 0xac, 0xe7, 0x37, 0xd5, 0x4e, 0x5e, 0xc8, 0x02,
+	0xbf, 0x7a, 0xf9, 0x28, 0xef, 0xc0, 0xea, 0x47, 0x00, 0xf1, 0xdb, 0xa1, 0x9c, 0x5c, 0xc3, 0xc9,
+	0x6d, 0x23, 0xe4, 0xe9, 0x9f, 0xd4, 0x7e, 0xf9, 0xc2, 0xe7, 0xab, 0xbb, 0x94, 0xf4, 0xf7, 0xbc,
+	0x86, 0xe3, 0x4f, 0xcc, 0xf2, 0xf2, 0xdf, 0xca, 0x3e, 0x09, 0xde, 0x3c, 0xfe, 0x7c, 0xb6, 0x11,
+	0xb6, 0x6c, 0x2e, 0x32, 0xae, 0x2e, 0x97, 0xbb, 0x0e, 0xcb, 0xae, 0xc3, 0xf2, 0xe0, 0x1b, 0x70,
+	0x31, 0xf1, 0x7f, 0xfd, 0x67, 0x3f, 0x07, 0x00, 0x64, 0x87, 0x35, 0x57, 0x1d, 0x04, 0x00, 0x00,
+}
@@ -0,0 +1,237 @@
+// Package discovery implements the Discovery gRPC service of the agent. It
+// resolves services and prepared queries for clients which can't use DNS or
+// the HTTP API, and streams the instances of a service as they change instead
+// of relying on DNS TTLs.
+package discovery
+
+import (
+	"context"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/consul"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:../.. --proto_path=../.. agent/discovery/discovery.proto
+
+// Cache is the interface Server requires to fetch and watch results. It is
+// satisfied by the agent cache.
+type Cache interface {
+	Get(t string, r cache.Request) (interface{}, cache.ResultMeta, error)
+	Notify(ctx context.Context, t string, r cache.Request, correlationID string, ch chan<- cache.UpdateEvent) error
+}
+
+// Server implements DiscoveryServer. All of its public members must be set
+// before the gRPC server is started.
+type Server struct {
+	Logger *log.Logger
+	Cache  Cache
+
+	// Datacenter is the datacenter of the agent, which is used if a request
+	// doesn't set one.
+	Datacenter string
+
+	// Source is the agent, which prepared query results are sorted relative
+	// to.
+	Source structs.QuerySource
+
+	// TranslateAddresses is Agent.TranslateAddresses. It translates the
+	// addresses of the results from other datacenters.
+	TranslateAddresses func(dc string, subj interface{})
+}
+
+// ResolveService implements DiscoveryServer.
+func (s *Server) ResolveService(ctx context.Context, req *ResolveServiceRequest) (*ResolveServiceResponse, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.Query != "" {
+		return s.executeQuery(ctx, req)
+	}
+
+	args := s.serviceRequest(ctx, req)
+	raw, _, err := s.Cache.Get(cachetype.HealthServicesName, args)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	reply, ok := raw.(*structs.IndexedCheckServiceNodes)
+	if !ok {
+		// This should never happen, but we want to protect against panics
+		return nil, status.Errorf(codes.Internal, "internal error: response type not correct")
+	}
+	return s.response(args.Datacenter, reply.Nodes, reply.Index, req.PassingOnly), nil
+}
+
+// WatchService implements DiscoveryServer.
+func (s *Server) WatchService(req *ResolveServiceRequest, stream Discovery_WatchServiceServer) error {
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+	if req.Query != "" {
+		return status.Errorf(codes.InvalidArgument, "prepared queries can't be watched")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	args := s.serviceRequest(ctx, req)
+	ch := make(chan cache.UpdateEvent, 1)
+	if err := s.Cache.Notify(ctx, cachetype.HealthServicesName, args, req.Service, ch); err != nil {
+		return rpcError(err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case u := <-ch:
+			if u.Err != nil {
+				// The cache keeps retrying with a backoff, so only permanent
+				// errors end the stream.
+				if acl.IsErrPermissionDenied(u.Err) || acl.IsErrNotFound(u.Err) {
+					return rpcError(u.Err)
+				}
+				s.Logger.Printf("[WARN] discovery: Failed to watch service %q: %v", req.Service, u.Err)
+				continue
+			}
+			reply, ok := u.Result.(*structs.IndexedCheckServiceNodes)
+			if !ok {
+				return status.Errorf(codes.Internal, "internal error: response type not correct")
+			}
+			resp := s.response(args.Datacenter, reply.Nodes, reply.Index, req.PassingOnly)
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) executeQuery(ctx context.Context, req *ResolveServiceRequest) (*ResolveServiceResponse, error) {
+	args := &structs.PreparedQueryExecuteRequest{
+		Datacenter:    s.datacenter(req),
+		QueryIDOrName: req.Query,
+		Connect:       req.Connect,
+		Agent:         s.Source,
+	}
+	args.Token = tokenFromContext(ctx)
+	raw, _, err := s.Cache.Get(cachetype.PreparedQueryName, args)
+	if err != nil {
+		// We have to check the string since the RPC sheds the specific
+		// error type.
+		if err.Error() == consul.ErrQueryNotFound.Error() {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, rpcError(err)
+	}
+	reply, ok := raw.(*structs.PreparedQueryExecuteResponse)
+	if !ok {
+		// This should never happen, but we want to protect against panics
+		return nil, status.Errorf(codes.Internal, "internal error: response type not correct")
+	}
+	return s.response(reply.Datacenter, reply.Nodes, reply.Index, req.PassingOnly), nil
+}
+
+func (s *Server) serviceRequest(ctx context.Context, req *ResolveServiceRequest) *structs.ServiceSpecificRequest {
+	args := &structs.ServiceSpecificRequest{
+		Datacenter:  s.datacenter(req),
+		ServiceName: req.Service,
+		ServiceTags: req.Tags,
+		TagFilter:   len(req.Tags) > 0,
+		Connect:     req.Connect,
+	}
+	args.Token = tokenFromContext(ctx)
+	return args
+}
+
+func (s *Server) datacenter(req *ResolveServiceRequest) string {
+	if req.Datacenter != "" {
+		return req.Datacenter
+	}
+	return s.Datacenter
+}
+
+// response converts the given nodes into a response. Nodes with critical
+// checks are always dropped, the ones with warning checks only if
+// passingOnly is set.
+func (s *Server) response(dc string, nodes structs.CheckServiceNodes, index uint64, passingOnly bool) *ResolveServiceResponse {
+	// The nodes are shared with the cache, so they are copied before they
+	// are filtered and their addresses are translated.
+	filtered := make(structs.CheckServiceNodes, len(nodes))
+	for i, n := range nodes {
+		node, svc := *n.Node, *n.Service
+		filtered[i] = structs.CheckServiceNode{Node: &node, Service: &svc, Checks: n.Checks}
+	}
+	filtered = filtered.Filter(passingOnly)
+	if s.TranslateAddresses != nil {
+		s.TranslateAddresses(dc, filtered)
+	}
+
+	resp := &ResolveServiceResponse{Index: index}
+	for _, n := range filtered {
+		addr := n.Service.Address
+		if addr == "" {
+			addr = n.Node.Address
+		}
+		health := api.HealthPassing
+		for _, c := range n.Checks {
+			if c.Status == api.HealthWarning {
+				health = api.HealthWarning
+				break
+			}
+		}
+		resp.Instances = append(resp.Instances, &ServiceInstance{
+			Node:        n.Node.Node,
+			NodeAddress: n.Node.Address,
+			Datacenter:  n.Node.Datacenter,
+			Id:          n.Service.ID,
+			Service:     n.Service.Service,
+			Address:     addr,
+			Port:        int32(n.Service.Port),
+			Tags:        n.Service.Tags,
+			Meta:        n.Service.Meta,
+			Health:      health,
+		})
+	}
+	return resp
+}
+
+func validateRequest(req *ResolveServiceRequest) error {
+	switch {
+	case req.Service == "" && req.Query == "":
+		return status.Errorf(codes.InvalidArgument, "service or query must be set")
+	case req.Service != "" && req.Query != "":
+		return status.Errorf(codes.InvalidArgument, "service and query can't both be set")
+	case req.Query != "" && len(req.Tags) > 0:
+		return status.Errorf(codes.InvalidArgument, "tags can't be used with a prepared query")
+	}
+	return nil
+}
+
+// rpcError converts an error of the agent RPC into a gRPC status error.
+func rpcError(err error) error {
+	if acl.IsErrPermissionDenied(err) || acl.IsErrNotFound(err) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+func tokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	toks, ok := md["x-consul-token"]
+	if ok && len(toks) > 0 {
+		return toks[0]
+	}
+	return ""
+}
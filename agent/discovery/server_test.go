@@ -0,0 +1,283 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/cache"
+	cachetype "github.com/hashicorp/consul/agent/cache-types"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+)
+
+// testCache is a mock of the agent cache which returns fixed results and
+// delivers watch updates sent on its updates chan.
+type testCache struct {
+	sync.Mutex
+	results map[string]interface{}
+	err     error
+	reqs    []cache.Request
+	updates chan cache.UpdateEvent
+}
+
+func (c *testCache) Get(t string, r cache.Request) (interface{}, cache.ResultMeta, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.reqs = append(c.reqs, r)
+	if c.err != nil {
+		return nil, cache.ResultMeta{}, c.err
+	}
+	return c.results[t], cache.ResultMeta{}, nil
+}
+
+func (c *testCache) Notify(ctx context.Context, t string, r cache.Request, correlationID string, ch chan<- cache.UpdateEvent) error {
+	c.Lock()
+	c.reqs = append(c.reqs, r)
+	c.Unlock()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case u := <-c.updates:
+				ch <- u
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *testCache) lastRequest() cache.Request {
+	c.Lock()
+	defer c.Unlock()
+	return c.reqs[len(c.reqs)-1]
+}
+
+func testNodes() structs.CheckServiceNodes {
+	return structs.CheckServiceNodes{
+		{
+			Node:    &structs.Node{Node: "node1", Address: "10.0.0.1", Datacenter: "dc1"},
+			Service: &structs.NodeService{ID: "web1", Service: "web", Port: 8080, Tags: []string{"v1"}},
+			Checks:  structs.HealthChecks{{Status: api.HealthPassing}},
+		},
+		{
+			Node:    &structs.Node{Node: "node2", Address: "10.0.0.2", Datacenter: "dc1"},
+			Service: &structs.NodeService{ID: "web2", Service: "web", Address: "10.1.0.2", Port: 8080, Meta: map[string]string{"a": "b"}},
+			Checks:  structs.HealthChecks{{Status: api.HealthWarning}},
+		},
+		{
+			Node:    &structs.Node{Node: "node3", Address: "10.0.0.3", Datacenter: "dc1"},
+			Service: &structs.NodeService{ID: "web3", Service: "web", Port: 8080},
+			Checks:  structs.HealthChecks{{Status: api.HealthCritical}},
+		},
+	}
+}
+
+func testClient(t *testing.T, c *testCache) (DiscoveryClient, func()) {
+	t.Helper()
+
+	srv := grpc.NewServer()
+	RegisterDiscoveryServer(srv, &Server{
+		Logger:     log.New(os.Stderr, "", log.LstdFlags),
+		Cache:      c,
+		Datacenter: "dc1",
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(ln)
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return NewDiscoveryClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func tokenContext(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-consul-token", token)
+}
+
+func TestServer_ResolveService(t *testing.T) {
+	t.Parallel()
+
+	c := &testCache{results: map[string]interface{}{
+		cachetype.HealthServicesName: &structs.IndexedCheckServiceNodes{
+			Nodes:     testNodes(),
+			QueryMeta: structs.QueryMeta{Index: 42},
+		},
+	}}
+	client, stop := testClient(t, c)
+	defer stop()
+
+	t.Run("all", func(t *testing.T) {
+		resp, err := client.ResolveService(tokenContext("foo"), &ResolveServiceRequest{
+			Service: "web",
+			Tags:    []string{"v1"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint64(42), resp.Index)
+		require.Len(t, resp.Instances, 2)
+		require.Equal(t, "web1", resp.Instances[0].Id)
+		require.Equal(t, "10.0.0.1", resp.Instances[0].Address)
+		require.Equal(t, int32(8080), resp.Instances[0].Port)
+		require.Equal(t, api.HealthPassing, resp.Instances[0].Health)
+		require.Equal(t, "10.1.0.2", resp.Instances[1].Address)
+		require.Equal(t, "10.0.0.2", resp.Instances[1].NodeAddress)
+		require.Equal(t, map[string]string{"a": "b"}, resp.Instances[1].Meta)
+		require.Equal(t, api.HealthWarning, resp.Instances[1].Health)
+
+		req := c.lastRequest().(*structs.ServiceSpecificRequest)
+		require.Equal(t, "dc1", req.Datacenter)
+		require.Equal(t, "web", req.ServiceName)
+		require.Equal(t, []string{"v1"}, req.ServiceTags)
+		require.True(t, req.TagFilter)
+		require.Equal(t, "foo", req.Token)
+	})
+
+	t.Run("passing only", func(t *testing.T) {
+		resp, err := client.ResolveService(context.Background(), &ResolveServiceRequest{
+			Service:     "web",
+			Datacenter:  "dc2",
+			PassingOnly: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Instances, 1)
+		require.Equal(t, "web1", resp.Instances[0].Id)
+		require.Equal(t, "dc2", c.lastRequest().(*structs.ServiceSpecificRequest).Datacenter)
+	})
+
+	t.Run("cached result is not modified", func(t *testing.T) {
+		nodes := c.results[cachetype.HealthServicesName].(*structs.IndexedCheckServiceNodes).Nodes
+		require.Equal(t, testNodes(), nodes)
+	})
+}
+
+func TestServer_ResolveService_Query(t *testing.T) {
+	t.Parallel()
+
+	c := &testCache{results: map[string]interface{}{
+		cachetype.PreparedQueryName: &structs.PreparedQueryExecuteResponse{
+			Service:    "web",
+			Nodes:      testNodes(),
+			Datacenter: "dc2",
+		},
+	}}
+	client, stop := testClient(t, c)
+	defer stop()
+
+	resp, err := client.ResolveService(tokenContext("foo"), &ResolveServiceRequest{
+		Query:   "web-query",
+		Connect: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Instances, 2)
+
+	req := c.lastRequest().(*structs.PreparedQueryExecuteRequest)
+	require.Equal(t, "web-query", req.QueryIDOrName)
+	require.True(t, req.Connect)
+	require.Equal(t, "foo", req.Token)
+}
+
+func TestServer_ResolveService_Errors(t *testing.T) {
+	t.Parallel()
+
+	c := &testCache{err: errors.New("Query not found")}
+	client, stop := testClient(t, c)
+	defer stop()
+
+	cases := []struct {
+		name string
+		req  *ResolveServiceRequest
+		err  error
+		code codes.Code
+	}{
+		{"no service", &ResolveServiceRequest{}, nil, codes.InvalidArgument},
+		{"service and query", &ResolveServiceRequest{Service: "web", Query: "web"}, nil, codes.InvalidArgument},
+		{"query with tags", &ResolveServiceRequest{Query: "web", Tags: []string{"v1"}}, nil, codes.InvalidArgument},
+		{"query not found", &ResolveServiceRequest{Query: "web"}, errors.New("Query not found"), codes.NotFound},
+		{"permission denied", &ResolveServiceRequest{Service: "web"}, acl.ErrPermissionDenied, codes.PermissionDenied},
+		{"rpc error", &ResolveServiceRequest{Service: "web"}, errors.New("No known Consul servers"), codes.Unavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.Lock()
+			c.err = tc.err
+			c.Unlock()
+			_, err := client.ResolveService(context.Background(), tc.req)
+			require.Error(t, err)
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
+
+func TestServer_WatchService(t *testing.T) {
+	t.Parallel()
+
+	c := &testCache{updates: make(chan cache.UpdateEvent)}
+	client, stop := testClient(t, c)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(tokenContext("foo"))
+	defer cancel()
+	stream, err := client.WatchService(ctx, &ResolveServiceRequest{Service: "web"})
+	require.NoError(t, err)
+
+	deliver := func(index uint64, nodes structs.CheckServiceNodes) {
+		select {
+		case c.updates <- cache.UpdateEvent{
+			Result: &structs.IndexedCheckServiceNodes{
+				Nodes:     nodes,
+				QueryMeta: structs.QueryMeta{Index: index},
+			},
+		}:
+		case <-time.After(5 * time.Second):
+			t.Fatal("watch not started")
+		}
+	}
+
+	deliver(10, testNodes())
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), resp.Index)
+	require.Len(t, resp.Instances, 2)
+	require.Equal(t, "foo", c.lastRequest().(*structs.ServiceSpecificRequest).Token)
+
+	// Errors which the cache retries don't end the stream.
+	c.updates <- cache.UpdateEvent{Err: errors.New("rpc error")}
+
+	deliver(11, testNodes()[:1])
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(11), resp.Index)
+	require.Len(t, resp.Instances, 1)
+
+	// Permission errors do.
+	c.updates <- cache.UpdateEvent{Err: acl.ErrPermissionDenied}
+	_, err = stream.Recv()
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_WatchService_Query(t *testing.T) {
+	t.Parallel()
+
+	client, stop := testClient(t, &testCache{})
+	defer stop()
+	stream, err := client.WatchService(context.Background(), &ResolveServiceRequest{Query: "web"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
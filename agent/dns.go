@@ -62,17 +62,20 @@ type dnsConfig struct {
 	ARecordLimit    int
 	NodeMetaTXT     bool
 	dnsSOAConfig    dnsSOAConfig
+
+	// PreparedQueryDatacenterTXT adds a TXT record with the datacenter
+	// which answered a prepared query to the SRV responses.
+	PreparedQueryDatacenterTXT bool
 }
 
 // DNSServer is used to wrap an Agent and expose various
 // service discovery endpoints using a DNS interface.
 type DNSServer struct {
 	*dns.Server
-	agent     *Agent
-	config    *dnsConfig
-	domain    string
-	recursors []string
-	logger    *log.Logger
+	agent  *Agent
+	config *dnsConfig
+	domain string
+	logger *log.Logger
 	// Those are handling prefix lookups
 	ttlRadix  *radix.Tree
 	ttlStrict map[string]time.Duration
@@ -81,18 +84,13 @@ type DNSServer struct {
 	// be safely changed at runtime. It always contains a bool and is
 	// initialized with the value from config.DisableCompression.
 	disableCompression atomic.Value
+
+	// recursors contains the upstream DNS servers which can be changed
+	// at runtime. It is shared with the other DNS servers of the agent.
+	recursors *recursorPool
 }
 
 func NewDNSServer(a *Agent) (*DNSServer, error) {
-	var recursors []string
-	for _, r := range a.config.DNSRecursors {
-		ra, err := recursorAddr(r)
-		if err != nil {
-			return nil, fmt.Errorf("Invalid recursor address: %v", err)
-		}
-		recursors = append(recursors, ra)
-	}
-
 	// Make sure domain is FQDN, make it case insensitive for ServeMux
 	domain := dns.Fqdn(strings.ToLower(a.config.DNSDomain))
 
@@ -102,7 +100,7 @@ func NewDNSServer(a *Agent) (*DNSServer, error) {
 		config:    dnscfg,
 		domain:    domain,
 		logger:    a.logger,
-		recursors: recursors,
+		recursors: a.dnsRecursors,
 		ttlRadix:  radix.New(),
 		ttlStrict: make(map[string]time.Duration),
 	}
@@ -123,6 +121,30 @@ func NewDNSServer(a *Agent) (*DNSServer, error) {
 	return srv, nil
 }
 
+// ReloadConfig applies the settings of the DNS server which can be changed
+// at runtime.
+func (d *DNSServer) ReloadConfig(cfg *config.RuntimeConfig) error {
+	if err := d.recursors.update(cfg); err != nil {
+		return err
+	}
+	d.disableCompression.Store(cfg.DNSDisableCompression)
+	return nil
+}
+
+// recursorAddrs returns the addresses of the given recursors with the
+// default port added where necessary.
+func recursorAddrs(recursors []string) ([]string, error) {
+	var addrs []string
+	for _, r := range recursors {
+		ra, err := recursorAddr(r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid recursor address: %v", err)
+		}
+		addrs = append(addrs, ra)
+	}
+	return addrs, nil
+}
+
 // GetDNSConfig takes global config and creates the config used by DNS server
 func GetDNSConfig(conf *config.RuntimeConfig) *dnsConfig {
 	return &dnsConfig{
@@ -145,6 +167,7 @@ func GetDNSConfig(conf *config.RuntimeConfig) *dnsConfig {
 			Refresh: conf.DNSSOA.Refresh,
 			Retry:   conf.DNSSOA.Retry,
 		},
+		PreparedQueryDatacenterTXT: conf.DNSPreparedQueryDatacenterTXT,
 	}
 }
 
@@ -168,9 +191,7 @@ func (d *DNSServer) ListenAndServe(network, addr string, notif func()) error {
 	mux := dns.NewServeMux()
 	mux.HandleFunc("arpa.", d.handlePtr)
 	mux.HandleFunc(d.domain, d.handleQuery)
-	if len(d.recursors) > 0 {
-		mux.HandleFunc(".", d.handleRecurse)
-	}
+	mux.HandleFunc(".", d.handleRecurse)
 
 	d.Server = &dns.Server{
 		Addr:              addr,
@@ -178,7 +199,7 @@ func (d *DNSServer) ListenAndServe(network, addr string, notif func()) error {
 		Handler:           mux,
 		NotifyStartedFunc: notif,
 	}
-	if network == "udp" {
+	if strings.HasPrefix(network, "udp") {
 		d.UDPSize = 65535
 	}
 	return d.Server.ListenAndServe()
@@ -257,7 +278,7 @@ func (d *DNSServer) handlePtr(resp dns.ResponseWriter, req *dns.Msg) {
 	m.SetReply(req)
 	m.Compress = !d.disableCompression.Load().(bool)
 	m.Authoritative = true
-	m.RecursionAvailable = (len(d.recursors) > 0)
+	m.RecursionAvailable = d.recursors.available()
 
 	// Only add the SOA if requested
 	if req.Question[0].Qtype == dns.TypeSOA {
@@ -359,7 +380,7 @@ func (d *DNSServer) handleQuery(resp dns.ResponseWriter, req *dns.Msg) {
 	m.SetReply(req)
 	m.Compress = !d.disableCompression.Load().(bool)
 	m.Authoritative = true
-	m.RecursionAvailable = (len(d.recursors) > 0)
+	m.RecursionAvailable = d.recursors.available()
 
 	ecsGlobal := true
 
@@ -542,6 +563,14 @@ PARSE:
 		// name.connect.consul
 		d.serviceLookup(network, datacenter, labels[n-2], "", true, req, resp)
 
+	case "virtual":
+		if n == 1 {
+			goto INVALID
+		}
+
+		// name.virtual.consul
+		d.virtualIPLookup(network, datacenter, labels[n-2], req, resp)
+
 	case "node":
 		if n == 1 {
 			goto INVALID
@@ -689,6 +718,76 @@ RPC:
 	}
 }
 
+// virtualIPLookup is used to handle a service virtual IP query
+func (d *DNSServer) virtualIPLookup(network, datacenter, service string, req, resp *dns.Msg) {
+	// Only handle ANY, A and AAAA type requests
+	qType := req.Question[0].Qtype
+	if qType != dns.TypeANY && qType != dns.TypeA && qType != dns.TypeAAAA {
+		return
+	}
+
+	// Make an RPC request
+	args := structs.ServiceSpecificRequest{
+		Datacenter:  datacenter,
+		ServiceName: service,
+		QueryOptions: structs.QueryOptions{
+			Token:      d.agent.tokens.UserToken(),
+			AllowStale: d.config.AllowStale,
+		},
+	}
+	var out structs.ServiceVirtualIPResponse
+RPC:
+	if err := d.agent.RPC("Catalog.ServiceVirtualIP", &args, &out); err != nil {
+		d.logger.Printf("[ERR] dns: rpc error: %v", err)
+		resp.SetRcode(req, dns.RcodeServerFailure)
+		return
+	}
+
+	// Verify that request is not too stale, redo the request
+	if args.AllowStale {
+		if out.LastContact > d.config.MaxStale {
+			args.AllowStale = false
+			d.logger.Printf("[WARN] dns: Query results too stale, re-requesting")
+			goto RPC
+		} else if out.LastContact > staleCounterThreshold {
+			metrics.IncrCounter([]string{"dns", "stale_queries"}, 1)
+		}
+	}
+
+	// If the service has no virtual IP, return not found!
+	ip := net.ParseIP(out.IP)
+	if ip == nil {
+		d.addSOA(resp)
+		resp.SetRcode(req, dns.RcodeNameError)
+		return
+	}
+
+	ttl := uint32(d.config.NodeTTL / time.Second)
+	if ipv4 := ip.To4(); ipv4 != nil {
+		if qType == dns.TypeANY || qType == dns.TypeA {
+			resp.Answer = append(resp.Answer, &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   req.Question[0].Name,
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    ttl,
+				},
+				A: ipv4,
+			})
+		}
+	} else if qType == dns.TypeANY || qType == dns.TypeAAAA {
+		resp.Answer = append(resp.Answer, &dns.AAAA{
+			Hdr: dns.RR_Header{
+				Name:   req.Question[0].Name,
+				Rrtype: dns.TypeAAAA,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			AAAA: ip,
+		})
+	}
+}
+
 // encodeKVasRFC1464 encodes a key-value pair according to RFC1464
 func encodeKVasRFC1464(key, value string) (txt string) {
 	// For details on these replacements c.f. https://www.ietf.org/rfc/rfc1464.txt
@@ -776,6 +875,33 @@ func (d *DNSServer) formatNodeRecord(node *structs.Node, addr, qName string, qTy
 		}
 	}
 
+	// A dual-stack node also has an address of the other IP family.
+	if alt := dualStackAddr(node, addr, ipv4 != nil); alt != nil {
+		switch {
+		case alt.To4() != nil && (qType == dns.TypeANY || qType == dns.TypeA):
+			records = append(records, &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   qName,
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    uint32(ttl / time.Second),
+				},
+				A: alt,
+			})
+
+		case alt.To4() == nil && (qType == dns.TypeANY || qType == dns.TypeAAAA):
+			records = append(records, &dns.AAAA{
+				Hdr: dns.RR_Header{
+					Name:   qName,
+					Rrtype: dns.TypeAAAA,
+					Class:  dns.ClassINET,
+					Ttl:    uint32(ttl / time.Second),
+				},
+				AAAA: alt,
+			})
+		}
+	}
+
 	if node != nil {
 		for key, value := range node.Meta {
 			txt := value
@@ -798,6 +924,38 @@ func (d *DNSServer) formatNodeRecord(node *structs.Node, addr, qName string, qTy
 	return records, meta
 }
 
+// dualStackAddr returns the address of the other IP family for the IPv4 or
+// IPv6 address addr of a dual-stack node. It is taken from the lan_ipv4,
+// lan_ipv6, wan_ipv4 and wan_ipv6 tagged addresses depending on whether
+// addr is the node address or its WAN address. It returns nil if there
+// is no such address.
+func dualStackAddr(node *structs.Node, addr string, ipv4 bool) net.IP {
+	if node == nil || net.ParseIP(addr) == nil {
+		return nil
+	}
+
+	var tag string
+	switch addr {
+	case node.Address:
+		tag = "lan"
+	case node.TaggedAddresses["wan"]:
+		tag = "wan"
+	default:
+		return nil
+	}
+	if ipv4 {
+		tag += "_ipv6"
+	} else {
+		tag += "_ipv4"
+	}
+
+	ip := net.ParseIP(node.TaggedAddresses[tag])
+	if ip == nil || (ip.To4() == nil) != ipv4 {
+		return nil
+	}
+	return ip
+}
+
 // indexRRs populates a map which indexes a given list of RRs by name. NOTE that
 // the names are all squashed to lower case so we can perform case-insensitive
 // lookups; the RRs are not modified.
@@ -1202,6 +1360,13 @@ RPC:
 
 	d.trimDNSResponse(network, req, resp)
 
+	// Tell the clients which datacenter answered the query, so they can
+	// notice when they are served from a failover datacenter. The record
+	// is added after trimming since it doesn't belong to any answer.
+	if qType == dns.TypeSRV && d.config.PreparedQueryDatacenterTXT && len(resp.Answer) > 0 {
+		d.addPreparedQueryTXT(network, req, resp, &out, ttl)
+	}
+
 	// If the answer is empty and the response isn't truncated, return not found
 	if len(resp.Answer) == 0 && !resp.Truncated {
 		d.addSOA(resp)
@@ -1209,6 +1374,37 @@ RPC:
 	}
 }
 
+// addPreparedQueryTXT adds a TXT record with the datacenter which answered a
+// prepared query and the number of failovers it took to the additional
+// section. For UDP the record is left out if the response would not fit.
+func (d *DNSServer) addPreparedQueryTXT(network string, req, resp *dns.Msg, out *structs.PreparedQueryExecuteResponse, ttl time.Duration) {
+	txt := &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   req.Question[0].Name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(ttl / time.Second),
+		},
+		Txt: []string{
+			"consul-datacenter=" + out.Datacenter,
+			fmt.Sprintf("consul-failovers=%d", out.Failovers),
+		},
+	}
+	resp.Extra = append(resp.Extra, txt)
+
+	if network != "tcp" {
+		maxSize := defaultMaxUDPSize
+		if edns := req.IsEdns0(); edns != nil {
+			if size := edns.UDPSize(); size > uint16(maxSize) {
+				maxSize = int(size)
+			}
+		}
+		if resp.Len() > maxSize {
+			resp.Extra = resp.Extra[:len(resp.Extra)-1]
+		}
+	}
+}
+
 // serviceNodeRecords is used to add the node records for a service lookup
 func (d *DNSServer) serviceNodeRecords(dc string, nodes structs.CheckServiceNodes, req, resp *dns.Msg, ttl time.Duration) {
 	qName := req.Question[0].Name
@@ -1400,7 +1596,16 @@ func (d *DNSServer) serviceSRVRecords(dc string, nodes structs.CheckServiceNodes
 
 // handleRecurse is used to handle recursive DNS queries
 func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
+	// The handler is always registered since recursors can be added
+	// on reload. Without recursors the query fails like it would
+	// without a handler.
 	q := req.Question[0]
+	recursors := d.recursors.forName(q.Name)
+	if len(recursors) == 0 {
+		dns.HandleFailed(resp, req)
+		return
+	}
+
 	network := "udp"
 	defer func(s time.Time) {
 		d.logger.Printf("[DEBUG] dns: request for %v (%s) (%v) from client %s (%s)",
@@ -1418,8 +1623,9 @@ func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
 	var r *dns.Msg
 	var rtt time.Duration
 	var err error
-	for _, recursor := range d.recursors {
+	for _, recursor := range recursors {
 		r, rtt, err = c.Exchange(req, recursor)
+		d.recursors.report(recursor, rtt, err)
 		// Check if the response is valid and has the desired Response code
 		if r != nil && (r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError) {
 			d.logger.Printf("[DEBUG] dns: recurse RTT for %v (%v) Recursor queried: %v Status returned: %v", q, rtt, recursor, dns.RcodeToString[r.Rcode])
@@ -1472,7 +1678,8 @@ func (d *DNSServer) resolveCNAME(name string) []dns.RR {
 	}
 
 	// Do nothing if we don't have a recursor
-	if len(d.recursors) == 0 {
+	recursors := d.recursors.forName(name)
+	if len(recursors) == 0 {
 		return nil
 	}
 
@@ -1485,8 +1692,9 @@ func (d *DNSServer) resolveCNAME(name string) []dns.RR {
 	var r *dns.Msg
 	var rtt time.Duration
 	var err error
-	for _, recursor := range d.recursors {
+	for _, recursor := range recursors {
 		r, rtt, err = c.Exchange(m, recursor)
+		d.recursors.report(recursor, rtt, err)
 		if err == nil {
 			d.logger.Printf("[DEBUG] dns: cname recurse RTT for %v (%v)", name, rtt)
 			return r.Answer
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/miekg/dns"
+)
+
+// recursorPool holds the upstream DNS servers which resolve the names
+// outside of the Consul domain and tracks their health. Recursors which
+// failed to answer are only tried after the healthy ones so that a recursor
+// which is down doesn't add its timeout to every recursive query. The pool
+// is shared by all the DNS servers of an agent.
+type recursorPool struct {
+	logger *log.Logger
+
+	// interval is the interval at which the recursors are probed. The
+	// probes are disabled if it is zero.
+	interval time.Duration
+
+	// lock protects the fields below.
+	lock sync.RWMutex
+
+	// timeout is used for the connection, read and write timeout of a
+	// probe.
+	timeout time.Duration
+
+	// recursors are the addresses of the default recursors in the order
+	// they were configured.
+	recursors []string
+
+	// domains are the addresses of the recursors of specific domains,
+	// organized by the lower-cased FQDN of the domain.
+	domains map[string][]string
+
+	// failed has the last error of the recursors which failed to answer,
+	// organized by address.
+	failed map[string]error
+}
+
+// newRecursorPool returns a pool with the recursors of the given
+// configuration, which are all assumed to be healthy.
+func newRecursorPool(cfg *config.RuntimeConfig, logger *log.Logger) (*recursorPool, error) {
+	p := &recursorPool{
+		logger:   logger,
+		interval: cfg.DNSRecursorHealthCheckInterval,
+		failed:   make(map[string]error),
+	}
+	if err := p.update(cfg); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// update replaces the recursors of the pool with the ones of the given
+// configuration. The health of the recursors which are kept is retained.
+func (p *recursorPool) update(cfg *config.RuntimeConfig) error {
+	recursors, err := recursorAddrs(cfg.DNSRecursors)
+	if err != nil {
+		return err
+	}
+	domains := make(map[string][]string)
+	for domain, rs := range cfg.DNSDomainRecursors {
+		addrs, err := recursorAddrs(rs)
+		if err != nil {
+			return fmt.Errorf("Invalid recursor for domain %q: %v", domain, err)
+		}
+		domains[dns.Fqdn(strings.ToLower(domain))] = addrs
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.timeout = cfg.DNSRecursorTimeout
+	p.recursors = recursors
+	p.domains = domains
+	known := make(map[string]bool)
+	for _, addr := range p.addrsLocked() {
+		known[addr] = true
+	}
+	for addr := range p.failed {
+		if !known[addr] {
+			delete(p.failed, addr)
+		}
+	}
+	return nil
+}
+
+// addrsLocked returns the unique addresses of all the recursors. The lock
+// must be held.
+func (p *recursorPool) addrsLocked() []string {
+	seen := make(map[string]bool)
+	var addrs []string
+	add := func(rs []string) {
+		for _, addr := range rs {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	add(p.recursors)
+	for _, rs := range p.domains {
+		add(rs)
+	}
+	return addrs
+}
+
+// available returns whether any recursors are configured.
+func (p *recursorPool) available() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.recursors) > 0 || len(p.domains) > 0
+}
+
+// forName returns the addresses of the recursors which should be asked to
+// resolve the given name, in the order they should be tried. These are the
+// recursors of the most specific domain of the name, or the default
+// recursors if there are none. The recursors which failed are moved to the
+// end but keep their relative order.
+func (p *recursorPool) forName(name string) []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	recursors := p.recursors
+	for n := dns.Fqdn(strings.ToLower(name)); n != "" && n != "."; {
+		if rs, ok := p.domains[n]; ok {
+			recursors = rs
+			break
+		}
+		i := strings.Index(n, ".")
+		n = n[i+1:]
+	}
+
+	addrs := make([]string, 0, len(recursors))
+	var failed []string
+	for _, addr := range recursors {
+		if _, ok := p.failed[addr]; ok {
+			failed = append(failed, addr)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return append(addrs, failed...)
+}
+
+// report records the outcome of an exchange with a recursor. Only errors
+// are considered failures; a recursor which answers with an error code is
+// still up.
+func (p *recursorPool) report(addr string, rtt time.Duration, err error) {
+	labels := []metrics.Label{{Name: "recursor", Value: addr}}
+	if err != nil && err != dns.ErrTruncated {
+		metrics.IncrCounterWithLabels([]string{"dns", "recursor", "failure"}, 1, labels)
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if _, ok := p.failed[addr]; !ok {
+			p.logger.Printf("[WARN] dns: recursor %s failed and will only be used when the other recursors fail: %v", addr, err)
+		}
+		p.failed[addr] = err
+		return
+	}
+	metrics.AddSampleWithLabels([]string{"dns", "recursor", "rtt"}, float32(rtt.Seconds()*1000), labels)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.failed[addr]; ok {
+		p.logger.Printf("[INFO] dns: recursor %s is healthy again", addr)
+		delete(p.failed, addr)
+	}
+}
+
+// run probes the recursors at the configured interval until the shutdown
+// channel is closed.
+func (p *recursorPool) run(shutdownCh <-chan struct{}) {
+	if p.interval <= 0 {
+		return
+	}
+	for {
+		select {
+		case <-time.After(p.interval):
+			p.probe()
+		case <-shutdownCh:
+			return
+		}
+	}
+}
+
+// probe asks all the recursors for the name servers of the root zone and
+// reports the outcome.
+func (p *recursorPool) probe() {
+	p.lock.RLock()
+	addrs := p.addrsLocked()
+	c := &dns.Client{Net: "udp", Timeout: p.timeout}
+	p.lock.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			m := new(dns.Msg)
+			m.SetQuestion(".", dns.TypeNS)
+			_, rtt, err := c.Exchange(m, addr)
+			p.report(addr, rtt, err)
+		}(addr)
+	}
+	wg.Wait()
+}
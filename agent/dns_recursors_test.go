@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/config"
+)
+
+func TestRecursorPool_forName(t *testing.T) {
+	t.Parallel()
+	cfg := &config.RuntimeConfig{
+		DNSRecursors: []string{"10.0.0.1", "10.0.0.2"},
+		DNSDomainRecursors: map[string][]string{
+			"corp.example.com":     []string{"10.1.0.1", "10.1.0.2:5353"},
+			"eu.corp.example.com.": []string{"10.2.0.1"},
+		},
+	}
+	p, err := newRecursorPool(cfg, log.New(os.Stderr, "", log.LstdFlags))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"google.com.", []string{"10.0.0.1:53", "10.0.0.2:53"}},
+		{"example.com.", []string{"10.0.0.1:53", "10.0.0.2:53"}},
+		{"corp.example.com.", []string{"10.1.0.1:53", "10.1.0.2:5353"}},
+		{"Host.CORP.example.com", []string{"10.1.0.1:53", "10.1.0.2:5353"}},
+		{"host.eu.corp.example.com.", []string{"10.2.0.1:53"}},
+		{"notcorp.example.com.", []string{"10.0.0.1:53", "10.0.0.2:53"}},
+		{".", []string{"10.0.0.1:53", "10.0.0.2:53"}},
+	}
+	for _, tt := range tests {
+		if got := p.forName(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: got %v want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Failed recursors are tried last until they answer again.
+	p.report("10.0.0.1:53", 0, errors.New("i/o timeout"))
+	p.report("10.1.0.1:53", 0, errors.New("i/o timeout"))
+	if got, want := p.forName("google.com."), []string{"10.0.0.2:53", "10.0.0.1:53"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if got, want := p.forName("corp.example.com."), []string{"10.1.0.2:5353", "10.1.0.1:53"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	p.report("10.0.0.1:53", 0, nil)
+	if got, want := p.forName("google.com."), []string{"10.0.0.1:53", "10.0.0.2:53"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+
+	// Removed recursors are forgotten on update.
+	cfg.DNSDomainRecursors = nil
+	if err := p.update(cfg); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(p.failed) != 0 {
+		t.Fatalf("bad: %v", p.failed)
+	}
+	if got, want := p.forName("corp.example.com."), []string{"10.0.0.1:53", "10.0.0.2:53"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
@@ -383,6 +383,68 @@ func TestDNS_NodeLookup_AAAA(t *testing.T) {
 	}
 }
 
+func TestDNS_DualStackLookup(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Register a dual-stack node with a service
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "bar",
+		Address:    "127.0.0.2",
+		TaggedAddresses: map[string]string{
+			"lan_ipv4": "127.0.0.2",
+			"lan_ipv6": "::4242:4242",
+		},
+		Service: &structs.NodeService{
+			Service: "db",
+			Port:    12345,
+		},
+	}
+
+	var out struct{}
+	if err := a.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		qType uint16
+		want  []string
+	}{
+		{"bar.node.consul.", dns.TypeA, []string{"127.0.0.2"}},
+		{"bar.node.consul.", dns.TypeAAAA, []string{"::4242:4242"}},
+		{"bar.node.consul.", dns.TypeANY, []string{"127.0.0.2", "::4242:4242"}},
+		{"db.service.consul.", dns.TypeA, []string{"127.0.0.2"}},
+		{"db.service.consul.", dns.TypeAAAA, []string{"::4242:4242"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+dns.TypeToString[tt.qType], func(t *testing.T) {
+			m := new(dns.Msg)
+			m.SetQuestion(tt.name, tt.qType)
+
+			c := new(dns.Client)
+			in, _, err := c.Exchange(m, a.DNSAddr())
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			var got []string
+			for _, rr := range in.Answer {
+				switch x := rr.(type) {
+				case *dns.A:
+					got = append(got, x.A.String())
+				case *dns.AAAA:
+					got = append(got, x.AAAA.String())
+				}
+			}
+			verify.Values(t, "", got, tt.want)
+		})
+	}
+}
+
 func TestDNSCycleRecursorCheck(t *testing.T) {
 	t.Parallel()
 	// Start a DNS recursor that returns a SERVFAIL
@@ -446,6 +508,106 @@ func TestDNSCycleRecursorCheckAllFail(t *testing.T) {
 	//Verify if we hit SERVFAIL from Consul
 	verify.Values(t, "Answer", in.Rcode, dns.RcodeServerFailure)
 }
+func TestDNS_Recurse_FailedRecursorLast(t *testing.T) {
+	t.Parallel()
+	timeout := 500 * time.Millisecond
+
+	// A recursor which never answers.
+	deadAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dead, err := net.ListenUDP("udp", deadAddr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer dead.Close()
+
+	recursor := makeRecursor(t, dns.Msg{
+		Answer: []dns.RR{dnsA("www.google.com", "172.21.45.67")},
+	})
+	defer recursor.Shutdown()
+
+	a := NewTestAgent(t.Name(), `
+		recursors = ["`+dead.LocalAddr().String()+`", "`+recursor.Addr+`"]
+		dns_config {
+			recursor_timeout = "`+timeout.String()+`"
+		}
+	`)
+	defer a.Shutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("google.com.", dns.TypeA)
+	c := &dns.Client{Timeout: 5 * time.Second}
+
+	// The first query waits for the dead recursor to time out.
+	in, _, err := c.Exchange(m, a.DNSAddr())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(in.Answer) != 1 {
+		t.Fatalf("Bad: %#v", in)
+	}
+
+	// The dead recursor is now tried last so the next query is answered
+	// without waiting for it.
+	start := time.Now()
+	in, _, err = c.Exchange(m, a.DNSAddr())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(in.Answer) != 1 {
+		t.Fatalf("Bad: %#v", in)
+	}
+	if d := time.Since(start); d >= timeout {
+		t.Fatalf("query took %v, expected the dead recursor to be skipped", d)
+	}
+}
+
+func TestDNS_Recurse_DomainRecursors(t *testing.T) {
+	t.Parallel()
+	public := makeRecursor(t, dns.Msg{
+		Answer: []dns.RR{dnsA("host.corp.example.com", "1.2.3.4")},
+	})
+	defer public.Shutdown()
+	corp := makeRecursor(t, dns.Msg{
+		Answer: []dns.RR{dnsA("host.corp.example.com", "10.16.91.3")},
+	})
+	defer corp.Shutdown()
+
+	a := NewTestAgent(t.Name(), `
+		recursors = ["`+public.Addr+`"]
+		dns_config {
+			domain_recursors = {
+				"corp.example.com" = ["`+corp.Addr+`"]
+			}
+		}
+	`)
+	defer a.Shutdown()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"host.corp.example.com.", "10.16.91.3"},
+		{"www.example.com.", "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tt.name, dns.TypeA)
+		in, _, err := new(dns.Client).Exchange(m, a.DNSAddr())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(in.Answer) != 1 {
+			t.Fatalf("Bad: %#v", in)
+		}
+		if got := in.Answer[0].(*dns.A).A.String(); got != tt.want {
+			t.Fatalf("%s: got %s want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestDNS_NodeLookup_CNAME(t *testing.T) {
 	t.Parallel()
 	recursor := makeRecursor(t, dns.Msg{
@@ -755,6 +917,92 @@ func TestDNS_EDNS0(t *testing.T) {
 	}
 }
 
+func TestDNS_PreparedQuery_DatacenterTXT(t *testing.T) {
+	t.Parallel()
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			a := NewTestAgent(t.Name(), fmt.Sprintf(`
+				dns_config {
+					enable_prepared_query_datacenter_txt = %t
+				}
+			`, enabled))
+			defer a.Shutdown()
+			testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+			// Register a node with a service.
+			{
+				args := &structs.RegisterRequest{
+					Datacenter: "dc1",
+					Node:       "foo",
+					Address:    "127.0.0.1",
+					Service: &structs.NodeService{
+						Service: "db",
+						Port:    12345,
+					},
+				}
+
+				var out struct{}
+				require.NoError(t, a.RPC("Catalog.Register", args, &out))
+			}
+
+			// Register an equivalent prepared query.
+			{
+				args := &structs.PreparedQueryRequest{
+					Datacenter: "dc1",
+					Op:         structs.PreparedQueryCreate,
+					Query: &structs.PreparedQuery{
+						Name: "test",
+						Service: structs.ServiceQuery{
+							Service: "db",
+						},
+					},
+				}
+				var id string
+				require.NoError(t, a.RPC("PreparedQuery.Apply", args, &id))
+			}
+
+			txtRecords := func(rrs []dns.RR) []*dns.TXT {
+				var txts []*dns.TXT
+				for _, rr := range rrs {
+					if txt, ok := rr.(*dns.TXT); ok {
+						txts = append(txts, txt)
+					}
+				}
+				return txts
+			}
+
+			m := new(dns.Msg)
+			m.SetQuestion("test.query.consul.", dns.TypeSRV)
+			c := new(dns.Client)
+			in, _, err := c.Exchange(m, a.DNSAddr())
+			require.NoError(t, err)
+			require.Len(t, in.Answer, 1)
+
+			// The node meta TXT records are not for the query name.
+			var txts []*dns.TXT
+			for _, txt := range txtRecords(in.Extra) {
+				if txt.Hdr.Name == "test.query.consul." {
+					txts = append(txts, txt)
+				}
+			}
+			if !enabled {
+				require.Len(t, txts, 0)
+				return
+			}
+			require.Len(t, txts, 1)
+			require.Equal(t, []string{"consul-datacenter=dc1", "consul-failovers=0"}, txts[0].Txt)
+
+			// Only SRV responses have the record.
+			m = new(dns.Msg)
+			m.SetQuestion("test.query.consul.", dns.TypeA)
+			in, _, err = c.Exchange(m, a.DNSAddr())
+			require.NoError(t, err)
+			require.Len(t, in.Answer, 1)
+			require.Len(t, txtRecords(in.Extra), 0)
+		})
+	}
+}
+
 func TestDNS_EDNS0_ECS(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -1611,6 +1859,77 @@ func TestDNS_ConnectServiceLookup(t *testing.T) {
 	}
 }
 
+func TestDNS_VirtualIPLookup(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), `
+		virtual_ip_cidr = "10.217.0.0/16"
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Register
+	{
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       "foo",
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				Service: "db",
+				Port:    12345,
+			},
+		}
+		var out struct{}
+		require.NoError(a.RPC("Catalog.Register", args, &out))
+	}
+
+	// Look up the virtual IP of the service, the consul service of the
+	// server has the first one.
+	questions := []string{
+		"db.virtual.consul.",
+		"db.virtual.dc1.consul.",
+	}
+	for _, question := range questions {
+		m := new(dns.Msg)
+		m.SetQuestion(question, dns.TypeA)
+
+		c := new(dns.Client)
+		in, _, err := c.Exchange(m, a.DNSAddr())
+		require.NoError(err)
+		require.Len(in.Answer, 1)
+
+		aRec, ok := in.Answer[0].(*dns.A)
+		require.True(ok)
+		require.Equal(question, aRec.Hdr.Name)
+		require.Equal("10.217.0.2", aRec.A.String())
+	}
+
+	// There is no IPv6 address.
+	{
+		m := new(dns.Msg)
+		m.SetQuestion("db.virtual.consul.", dns.TypeAAAA)
+
+		c := new(dns.Client)
+		in, _, err := c.Exchange(m, a.DNSAddr())
+		require.NoError(err)
+		require.Len(in.Answer, 0)
+		require.Equal(dns.RcodeSuccess, in.Rcode)
+	}
+
+	// Unknown services don't exist.
+	{
+		m := new(dns.Msg)
+		m.SetQuestion("nope.virtual.consul.", dns.TypeA)
+
+		c := new(dns.Client)
+		in, _, err := c.Exchange(m, a.DNSAddr())
+		require.NoError(err)
+		require.Len(in.Answer, 0)
+		require.Equal(dns.RcodeNameError, in.Rcode)
+	}
+}
+
 func TestDNS_ExternalServiceLookup(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
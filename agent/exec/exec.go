@@ -3,6 +3,7 @@ package exec
 import (
 	"fmt"
 	"os/exec"
+	"time"
 )
 
 // Subprocess returns a command to execute a subprocess directly.
@@ -12,3 +13,27 @@ func Subprocess(args []string) (*exec.Cmd, error) {
 	}
 	return exec.Command(args[0], args[1:]...), nil
 }
+
+// Limits restrict the resources of a command. The zero value doesn't
+// restrict anything.
+type Limits struct {
+	// User is the name or the numeric ID of the user the command runs as.
+	// The agent needs to run as root to change the user.
+	User string
+
+	// Cgroup is the path of a cgroup directory, e.g.
+	// /sys/fs/cgroup/memory/consul-checks, the command is moved into.
+	Cgroup string
+
+	// CPUTime is the CPU time after which the command is killed.
+	CPUTime time.Duration
+
+	// Memory is the maximum size of the address space of the command in
+	// bytes.
+	Memory uint64
+}
+
+// IsZero returns true if the limits don't restrict anything.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
@@ -3,6 +3,7 @@
 package exec
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -28,3 +29,8 @@ func SetSysProcAttr(cmd *exec.Cmd) {}
 func KillCommandSubtree(cmd *exec.Cmd) error {
 	return cmd.Process.Kill()
 }
+
+// SetUser is not supported on Windows.
+func SetUser(cmd *exec.Cmd, name string) error {
+	return fmt.Errorf("running commands as another user is not supported on Windows")
+}
@@ -0,0 +1,95 @@
+package exec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// StartLimited starts the command with its resources restricted. The command
+// is started through a shell which waits until it was moved into the cgroup
+// and its CPU time and memory were restricted before it execs the command,
+// so the limits cover the command from its start. The limits are applied by
+// the agent, which may be privileged while the command runs as another user.
+// The command doesn't run if the limits can't be applied.
+func StartLimited(cmd *exec.Cmd, l Limits) error {
+	if l.Cgroup == "" && l.CPUTime <= 0 && l.Memory == 0 {
+		return cmd.Start()
+	}
+
+	gate, release, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer release.Close()
+
+	// The shell reads from the gate, which is passed as the next file
+	// descriptor after the extra files, and closes it for the command.
+	fd := 3 + len(cmd.ExtraFiles)
+	script := fmt.Sprintf(`read _ <&%d && exec "$@" %d<&-`, fd, fd)
+	cmd.Args = append([]string{"/bin/sh", "-c", script, "consul-limits", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+	cmd.ExtraFiles = append(cmd.ExtraFiles, gate)
+
+	err = cmd.Start()
+	gate.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := applyLimits(cmd.Process.Pid, l); err != nil {
+		// Closing the gate makes the shell exit without running the
+		// command.
+		release.Close()
+		cmd.Wait()
+		return err
+	}
+	if _, err := release.Write([]byte("\n")); err != nil {
+		cmd.Wait()
+		return fmt.Errorf("failed to start limited process: %s", err)
+	}
+	return nil
+}
+
+// applyLimits moves the process into the cgroup of the limits and restricts
+// its CPU time and memory. The limits are inherited by the programs it execs
+// and the processes it forks.
+func applyLimits(pid int, l Limits) error {
+	if l.Cgroup != "" {
+		procs := filepath.Join(l.Cgroup, "cgroup.procs")
+		if err := ioutil.WriteFile(procs, []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return fmt.Errorf("failed to move process into cgroup: %s", err)
+		}
+	}
+	if l.CPUTime > 0 {
+		// The kernel counts whole seconds. Reaching the soft limit sends
+		// SIGXCPU, the hard limit one second later SIGKILL in case the
+		// process handles the signal.
+		secs := uint64((l.CPUTime + time.Second - 1) / time.Second)
+		if err := prlimit(pid, syscall.RLIMIT_CPU, secs, secs+1); err != nil {
+			return fmt.Errorf("failed to limit CPU time: %s", err)
+		}
+	}
+	if l.Memory > 0 {
+		if err := prlimit(pid, syscall.RLIMIT_AS, l.Memory, l.Memory); err != nil {
+			return fmt.Errorf("failed to limit memory: %s", err)
+		}
+	}
+	return nil
+}
+
+func prlimit(pid int, resource int, cur, max uint64) error {
+	rlim := syscall.Rlimit{Cur: cur, Max: max}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(&rlim)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package exec
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// StartLimited starts the command. Resource limits are only supported on
+// Linux, so it fails if any resource is restricted.
+func StartLimited(cmd *exec.Cmd, l Limits) error {
+	if l.Cgroup != "" || l.CPUTime > 0 || l.Memory > 0 {
+		return fmt.Errorf("resource limits are only supported on Linux")
+	}
+	return cmd.Start()
+}
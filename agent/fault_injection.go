@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// errFaultInjectionDrop is returned for the RPCs which are dropped by the
+// fault injection. It looks like an RPC error so that it is reported as a
+// server error to the clients of the HTTP API.
+var errFaultInjectionDrop = fmt.Errorf("rpc error: dropped by fault injection")
+
+// faultInjector injects synthetic failures into the agent to test how the
+// clients cope with them. It is only created if enable_fault_injection is
+// set, and a nil faultInjector injects nothing.
+type faultInjector struct {
+	// stepDown makes the server give up its leadership. It is nil for
+	// client agents.
+	stepDown func() (bool, error)
+	logger   *log.Logger
+
+	lock   sync.Mutex
+	faults api.AgentFaultInjection
+	rand   *rand.Rand
+
+	// churnStopCh stops the current leadership churn.
+	churnStopCh chan struct{}
+}
+
+// newFaultInjector returns a fault injector which uses the given function
+// to make the server step down. stepDown must return false if the server
+// isn't the leader, and is nil for client agents.
+func newFaultInjector(stepDown func() (bool, error), logger *log.Logger) *faultInjector {
+	return &faultInjector{
+		stepDown: stepDown,
+		logger:   logger,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Faults returns the injected failures.
+func (f *faultInjector) Faults() api.AgentFaultInjection {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.faults
+}
+
+// SetFaults replaces the injected failures. The zero value stops injecting
+// failures.
+func (f *faultInjector) SetFaults(faults api.AgentFaultInjection) error {
+	if faults.RPCLatency < 0 {
+		return fmt.Errorf("RPCLatency must not be negative")
+	}
+	if faults.RPCDropPercent < 0 || faults.RPCDropPercent > 100 {
+		return fmt.Errorf("RPCDropPercent must be between 0 and 100")
+	}
+	if faults.LeaderChurnInterval < 0 {
+		return fmt.Errorf("LeaderChurnInterval must not be negative")
+	}
+	if faults.LeaderChurnInterval > 0 && f.stepDown == nil {
+		return fmt.Errorf("LeaderChurnInterval is only supported by servers")
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.faults = faults
+	seed := faults.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	f.rand = rand.New(rand.NewSource(seed))
+
+	if f.churnStopCh != nil {
+		close(f.churnStopCh)
+		f.churnStopCh = nil
+	}
+	if faults.LeaderChurnInterval > 0 {
+		f.churnStopCh = make(chan struct{})
+		go f.churn(faults.LeaderChurnInterval.Duration(), f.churnStopCh)
+	}
+
+	if faults == (api.AgentFaultInjection{}) {
+		f.logger.Printf("[INFO] agent: Stopped injecting faults")
+	} else {
+		f.logger.Printf("[WARN] agent: Injecting faults rpc_latency=%s rpc_drop_percent=%v leader_churn_interval=%s",
+			faults.RPCLatency.Duration(), faults.RPCDropPercent, faults.LeaderChurnInterval.Duration())
+	}
+	return nil
+}
+
+// Stop stops the leadership churn when the agent shuts down.
+func (f *faultInjector) Stop() {
+	if f == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.churnStopCh != nil {
+		close(f.churnStopCh)
+		f.churnStopCh = nil
+	}
+}
+
+// BeforeRPC is called before the agent makes an RPC. It delays the RPC by
+// the configured latency and returns an error if the RPC is to be dropped.
+func (f *faultInjector) BeforeRPC() error {
+	if f == nil {
+		return nil
+	}
+
+	f.lock.Lock()
+	latency := f.faults.RPCLatency.Duration()
+	drop := f.faults.RPCDropPercent > 0 && f.rand.Float64()*100 < f.faults.RPCDropPercent
+	f.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if drop {
+		return errFaultInjectionDrop
+	}
+	return nil
+}
+
+// churn makes the server step down every interval while it is the leader
+// until the stop channel is closed.
+func (f *faultInjector) churn(interval time.Duration, stopCh chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stepped, err := f.stepDown()
+			if err != nil {
+				f.logger.Printf("[ERR] agent: Failed to step down for fault injection: %v", err)
+			} else if stepped {
+				f.logger.Printf("[WARN] agent: Stepped down as leader for fault injection")
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
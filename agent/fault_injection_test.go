@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjector_Nil(t *testing.T) {
+	t.Parallel()
+	var f *faultInjector
+	require.NoError(t, f.BeforeRPC())
+	f.Stop()
+}
+
+func TestFaultInjector_Validate(t *testing.T) {
+	t.Parallel()
+	f := newFaultInjector(nil, log.New(os.Stderr, "", log.LstdFlags))
+
+	cases := []api.AgentFaultInjection{
+		{RPCLatency: -1},
+		{RPCDropPercent: -1},
+		{RPCDropPercent: 101},
+		{LeaderChurnInterval: -1},
+		// Clients can't step down.
+		{LeaderChurnInterval: api.ReadableDuration(time.Second)},
+	}
+	for _, faults := range cases {
+		require.Error(t, f.SetFaults(faults), "%#v", faults)
+	}
+	require.Equal(t, api.AgentFaultInjection{}, f.Faults())
+}
+
+func TestFaultInjector_BeforeRPC(t *testing.T) {
+	t.Parallel()
+	f := newFaultInjector(nil, log.New(os.Stderr, "", log.LstdFlags))
+
+	drops := func() []bool {
+		var out []bool
+		for i := 0; i < 100; i++ {
+			out = append(out, f.BeforeRPC() == errFaultInjectionDrop)
+		}
+		return out
+	}
+	count := func(drops []bool) int {
+		n := 0
+		for _, d := range drops {
+			if d {
+				n++
+			}
+		}
+		return n
+	}
+
+	require.Equal(t, 0, count(drops()))
+
+	require.NoError(t, f.SetFaults(api.AgentFaultInjection{RPCDropPercent: 100}))
+	require.Equal(t, 100, count(drops()))
+
+	// The drops can be repeated with the same seed.
+	faults := api.AgentFaultInjection{RPCDropPercent: 50, Seed: 42}
+	require.NoError(t, f.SetFaults(faults))
+	first := drops()
+	require.NoError(t, f.SetFaults(faults))
+	require.Equal(t, first, drops())
+	require.True(t, count(first) > 0 && count(first) < 100)
+
+	// Latency is added before the RPC.
+	require.NoError(t, f.SetFaults(api.AgentFaultInjection{RPCLatency: api.ReadableDuration(50 * time.Millisecond)}))
+	start := time.Now()
+	require.NoError(t, f.BeforeRPC())
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestFaultInjector_Churn(t *testing.T) {
+	t.Parallel()
+	stepped := make(chan struct{}, 10)
+	f := newFaultInjector(func() (bool, error) {
+		stepped <- struct{}{}
+		return true, nil
+	}, log.New(os.Stderr, "", log.LstdFlags))
+
+	require.NoError(t, f.SetFaults(api.AgentFaultInjection{
+		LeaderChurnInterval: api.ReadableDuration(10 * time.Millisecond),
+	}))
+	for i := 0; i < 2; i++ {
+		select {
+		case <-stepped:
+		case <-time.After(time.Second):
+			t.Fatalf("didn't step down")
+		}
+	}
+
+	f.Stop()
+	time.Sleep(20 * time.Millisecond)
+	for len(stepped) > 0 {
+		<-stepped
+	}
+	select {
+	case <-stepped:
+		t.Fatalf("stepped down after stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
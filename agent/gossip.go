@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+// gossipMetricsInterval is the interval in which the gossip metrics are
+// emitted.
+const gossipMetricsInterval = 10 * time.Second
+
+// gossipPools returns the gossip settings of the pools the agent is a
+// member of.
+func (a *Agent) gossipPools() ([]*api.GossipPool, error) {
+	pools := []string{"lan"}
+	if a.config.ServerMode {
+		pools = append(pools, "wan")
+	}
+
+	var out []*api.GossipPool
+	for _, pool := range pools {
+		p, err := a.gossipPool(pool)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// gossipPool returns the gossip settings of the given pool.
+func (a *Agent) gossipPool(pool string) (*api.GossipPool, error) {
+	var p *api.GossipPool
+	var members int
+	switch pool {
+	case "lan":
+		p = &api.GossipPool{
+			Pool:           pool,
+			Profile:        a.config.GossipLANProfile,
+			GossipNodes:    a.config.GossipLANGossipNodes,
+			GossipInterval: api.ReadableDuration(a.config.GossipLANGossipInterval),
+			ProbeInterval:  api.ReadableDuration(a.config.GossipLANProbeInterval),
+			ProbeTimeout:   api.ReadableDuration(a.config.GossipLANProbeTimeout),
+			SuspicionMult:  a.config.GossipLANSuspicionMult,
+			RetransmitMult: a.config.GossipLANRetransmitMult,
+		}
+		members = len(a.LANMembers())
+	case "wan":
+		if !a.config.ServerMode {
+			return nil, fmt.Errorf("Only servers are members of the WAN pool")
+		}
+		p = &api.GossipPool{
+			Pool:           pool,
+			Profile:        a.config.GossipWANProfile,
+			GossipNodes:    a.config.GossipWANGossipNodes,
+			GossipInterval: api.ReadableDuration(a.config.GossipWANGossipInterval),
+			ProbeInterval:  api.ReadableDuration(a.config.GossipWANProbeInterval),
+			ProbeTimeout:   api.ReadableDuration(a.config.GossipWANProbeTimeout),
+			SuspicionMult:  a.config.GossipWANSuspicionMult,
+			RetransmitMult: a.config.GossipWANRetransmitMult,
+		}
+		members = len(a.WANMembers())
+	default:
+		return nil, fmt.Errorf("Invalid gossip pool %q, must be lan or wan", pool)
+	}
+
+	selected, err := config.ReadGossipProfile(a.config.DataDir, pool)
+	if err != nil {
+		return nil, err
+	}
+	if selected != p.Profile {
+		p.PendingProfile = selected
+	}
+
+	p.Members = members
+	if stats, ok := a.delegate.Stats()["serf_"+pool]; ok {
+		p.HealthScore, _ = strconv.Atoi(stats["health_score"])
+	}
+
+	limit, convergence, suspicion := gossipEstimates(members, p.GossipNodes,
+		p.GossipInterval.Duration(), p.ProbeInterval.Duration(),
+		p.SuspicionMult, p.RetransmitMult)
+	p.RetransmitLimit = limit
+	p.ConvergenceTime = api.ReadableDuration(convergence)
+	p.SuspicionTimeout = api.ReadableDuration(suspicion)
+	return p, nil
+}
+
+// setGossipProfile selects the gossip profile of the given pool. The
+// selection is stored in the data dir and takes effect when the agent is
+// restarted since the gossip settings of a running pool can't be changed.
+// An empty profile removes the selection.
+func (a *Agent) setGossipProfile(pool, profile string) error {
+	if pool != "lan" && pool != "wan" {
+		return fmt.Errorf("Invalid gossip pool %q, must be lan or wan", pool)
+	}
+	if pool == "wan" && !a.config.ServerMode {
+		return fmt.Errorf("Only servers are members of the WAN pool")
+	}
+	if a.config.DataDir == "" {
+		return fmt.Errorf("A data dir is required to select a gossip profile")
+	}
+
+	path := config.GossipProfilePath(a.config.DataDir, pool)
+	if profile == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		a.logger.Printf("[INFO] agent: Removed the gossip profile selection for the %s pool", pool)
+		return nil
+	}
+	if _, ok := config.GossipProfiles[profile]; !ok {
+		return fmt.Errorf("Invalid gossip profile %q", profile)
+	}
+	if err := file.WriteAtomic(path, []byte(profile)); err != nil {
+		return err
+	}
+	a.logger.Printf("[INFO] agent: Selected gossip profile %q for the %s pool, restart the agent to apply it", profile, pool)
+	return nil
+}
+
+// gossipEstimates returns the retransmit limit of memberlist, the estimated
+// time for a message to reach all members of a pool with the given size
+// and settings and the time after which a suspected member is declared dead.
+// The convergence time assumes that every member which knows a message
+// passes it on to the given number of nodes in every gossip interval.
+func gossipEstimates(members, gossipNodes int, gossipInterval, probeInterval time.Duration,
+	suspicionMult, retransmitMult int) (int, time.Duration, time.Duration) {
+	// These match the calculations of memberlist.
+	limit := retransmitMult * int(math.Ceil(math.Log10(float64(members+1))))
+	nodeScale := math.Max(1.0, math.Log10(math.Max(1.0, float64(members))))
+	suspicion := time.Duration(suspicionMult) * time.Duration(nodeScale*1000) * probeInterval / 1000
+
+	var convergence time.Duration
+	if members > 1 && gossipNodes > 0 {
+		rounds := math.Ceil(math.Log(float64(members)) / math.Log(float64(gossipNodes+1)))
+		convergence = time.Duration(rounds) * gossipInterval
+	}
+	return limit, convergence, suspicion
+}
+
+// emitGossipMetrics periodically emits the size and the convergence
+// estimates of the gossip pools.
+func (a *Agent) emitGossipMetrics() {
+	for {
+		select {
+		case <-time.After(gossipMetricsInterval):
+		case <-a.shutdownCh:
+			return
+		}
+
+		pools, err := a.gossipPools()
+		if err != nil {
+			a.logger.Printf("[ERR] agent: Failed to get gossip pools: %v", err)
+			continue
+		}
+		for _, p := range pools {
+			metrics.SetGauge([]string{"gossip", p.Pool, "members"}, float32(p.Members))
+			metrics.SetGauge([]string{"gossip", p.Pool, "convergence_time"},
+				float32(p.ConvergenceTime.Duration().Seconds()*1000))
+			metrics.SetGauge([]string{"gossip", p.Pool, "suspicion_timeout"},
+				float32(p.SuspicionTimeout.Duration().Seconds()*1000))
+		}
+	}
+}
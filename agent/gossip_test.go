@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGossipEstimates(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name        string
+		members     int
+		gossipNodes int
+		limit       int
+		convergence time.Duration
+		suspicion   time.Duration
+	}{
+		{"single member", 1, 3, 4, 0, 4 * time.Second},
+		{"small pool", 50, 3, 8, 600 * time.Millisecond, 6792 * time.Millisecond},
+		{"large pool", 10000, 3, 20, 1400 * time.Millisecond, 16 * time.Second},
+		{"large pool more fanout", 10000, 6, 20, 1000 * time.Millisecond, 16 * time.Second},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, convergence, suspicion := gossipEstimates(tc.members, tc.gossipNodes,
+				200*time.Millisecond, time.Second, 4, 4)
+			require.Equal(t, tc.limit, limit)
+			require.Equal(t, tc.convergence, convergence)
+			require.Equal(t, tc.suspicion, suspicion)
+		})
+	}
+}
@@ -580,6 +580,93 @@ func TestHealthServiceNodes_NodeMetaFilter(t *testing.T) {
 	}
 }
 
+func TestHealthServiceNodes_InlineNodeDetails(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter:      "dc1",
+		Node:            "bar",
+		Address:         "127.0.0.1",
+		TaggedAddresses: map[string]string{"wan": "127.0.0.2"},
+		NodeMeta:        map[string]string{"somekey": "somevalue"},
+		Service: &structs.NodeService{
+			ID:      "test",
+			Service: "test",
+			Weights: &structs.Weights{Passing: 10, Warning: 1},
+		},
+	}
+	var out struct{}
+	if err := a.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The node details and weights are part of every instance so that load
+	// balancers don't have to look up the nodes separately.
+	req, _ := http.NewRequest("GET", "/v1/health/service/test?dc=dc1", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthServiceNodes(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	nodes := obj.(structs.CheckServiceNodes)
+	if len(nodes) != 1 {
+		t.Fatalf("bad: %v", obj)
+	}
+	node, service := nodes[0].Node, nodes[0].Service
+	if !reflect.DeepEqual(node.Meta, args.NodeMeta) {
+		t.Fatalf("bad: %v", node.Meta)
+	}
+	if !reflect.DeepEqual(node.TaggedAddresses, args.TaggedAddresses) {
+		t.Fatalf("bad: %v", node.TaggedAddresses)
+	}
+	if !reflect.DeepEqual(service.Weights, args.Service.Weights) {
+		t.Fatalf("bad: %v", service.Weights)
+	}
+}
+
+func TestHealthServiceNodes_ExternalSource(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// Register the instances of a service synced from different sources.
+	for _, source := range []string{"k8s", "terraform"} {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       source,
+			Address:    "127.0.0.1",
+			Service: &structs.NodeService{
+				ID:      "test",
+				Service: "test",
+				Meta:    map[string]string{structs.MetaExternalSource: source},
+			},
+		}
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/health/service/test?dc=dc1&external-source=k8s", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+	nodes := obj.(structs.CheckServiceNodes)
+	require.Len(t, nodes, 1)
+	require.Equal(t, "k8s", nodes[0].Node.Node)
+
+	req, _ = http.NewRequest("GET", "/v1/health/service/test?dc=dc1&external-source=esm", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.HealthServiceNodes(resp, req)
+	require.NoError(t, err)
+	nodes = obj.(structs.CheckServiceNodes)
+	require.NotNil(t, nodes)
+	require.Len(t, nodes, 0)
+}
+
 func TestHealthServiceNodes_DistanceSort(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
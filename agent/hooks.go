@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/armon/circbuf"
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/exec"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/go-cleanhttp"
+	"golang.org/x/net/context"
+)
+
+// HookEvent is the payload of a hook. It is passed to the payload template
+// of the hook or sent as JSON if the hook has no template.
+type HookEvent struct {
+	// Event is the name of the lifecycle event, e.g. "became_leader".
+	Event string
+
+	// Node and Datacenter identify the agent the event happened on.
+	Node       string
+	Datacenter string
+
+	// Timestamp is the time the event happened.
+	Timestamp time.Time
+
+	// Check and PreviousStatus are set for the check_state_changed event.
+	Check          *structs.HealthCheck `json:",omitempty"`
+	PreviousStatus string               `json:",omitempty"`
+
+	// Source, Result and Attempts are set for the acl_bootstrap_attempt
+	// event. Source is the IP address the attempts came from. Attempts is
+	// one, except for rate limited attempts, which are reported at most once
+	// a minute per source.
+	Source   string `json:",omitempty"`
+	Result   string `json:",omitempty"`
+	Attempts int    `json:",omitempty"`
+}
+
+// hookTemplateFuncs are the functions available in payload templates.
+var hookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// hook is a configured hook with its parsed payload template.
+type hook struct {
+	config.RuntimeHookConfig
+	payload *template.Template
+}
+
+// hookRunner runs the hooks configured for the agent lifecycle events.
+type hookRunner struct {
+	node       string
+	datacenter string
+	logger     *log.Logger
+
+	// hooks maps the event names to the hooks which are run for them.
+	hooks map[string][]*hook
+}
+
+// newHookRunner returns a runner for the given hooks. An error is returned
+// if the payload template of a hook can't be parsed.
+func newHookRunner(hooks []config.RuntimeHookConfig, node, datacenter string, logger *log.Logger) (*hookRunner, error) {
+	r := &hookRunner{
+		node:       node,
+		datacenter: datacenter,
+		logger:     logger,
+		hooks:      make(map[string][]*hook),
+	}
+	for i, cfg := range hooks {
+		h := &hook{RuntimeHookConfig: cfg}
+		if cfg.Payload != "" {
+			tmpl, err := template.New(fmt.Sprintf("hooks[%d]", i)).Funcs(hookTemplateFuncs).Parse(cfg.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse payload of hook %d: %v", i, err)
+			}
+			h.payload = tmpl
+		}
+		for _, event := range cfg.Events {
+			r.hooks[event] = append(r.hooks[event], h)
+		}
+	}
+	return r, nil
+}
+
+// Fire runs the hooks of the event in the background. The node, datacenter
+// and timestamp of the event are set by the runner.
+func (r *hookRunner) Fire(e *HookEvent) {
+	if r == nil || len(r.hooks[e.Event]) == 0 {
+		return
+	}
+	r.stamp(e)
+	go r.run(e)
+}
+
+// Run runs the hooks of the event like Fire but waits until they are done.
+// Since every hook has a timeout this doesn't block forever.
+func (r *hookRunner) Run(e *HookEvent) {
+	if r == nil || len(r.hooks[e.Event]) == 0 {
+		return
+	}
+	r.stamp(e)
+	r.run(e)
+}
+
+func (r *hookRunner) stamp(e *HookEvent) {
+	e.Node = r.node
+	e.Datacenter = r.datacenter
+	e.Timestamp = time.Now().UTC()
+}
+
+// run runs all hooks of the event concurrently.
+func (r *hookRunner) run(e *HookEvent) {
+	var wg sync.WaitGroup
+	for _, h := range r.hooks[e.Event] {
+		wg.Add(1)
+		go func(h *hook) {
+			defer wg.Done()
+			r.runHook(h, e)
+		}(h)
+	}
+	wg.Wait()
+}
+
+// runHook runs a single hook for the event and logs the result.
+func (r *hookRunner) runHook(h *hook, e *HookEvent) {
+	var payload bytes.Buffer
+	if h.payload != nil {
+		if err := h.payload.Execute(&payload, e); err != nil {
+			r.logger.Printf("[ERR] agent: Failed to render payload of hook for %q: %v", e.Event, err)
+			return
+		}
+	} else if err := json.NewEncoder(&payload).Encode(e); err != nil {
+		r.logger.Printf("[ERR] agent: Failed to encode payload of hook for %q: %v", e.Event, err)
+		return
+	}
+
+	if h.HTTP != "" {
+		r.runHTTP(h, e, &payload)
+	} else {
+		r.runArgs(h, e, &payload)
+	}
+}
+
+// runArgs runs the command of the hook with the payload on stdin.
+func (r *hookRunner) runArgs(h *hook, e *HookEvent, payload io.Reader) {
+	cmd, err := exec.Subprocess(h.Args)
+	if err != nil {
+		r.logger.Printf("[ERR] agent: Failed to setup hook %v for %q: %v", h.Args, e.Event, err)
+		return
+	}
+	cmd.Env = append(os.Environ(), "CONSUL_HOOK_EVENT="+e.Event)
+	cmd.Stdin = payload
+
+	output, _ := circbuf.NewBuffer(WatchBufSize)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	exec.SetSysProcAttr(cmd)
+
+	if err := cmd.Start(); err != nil {
+		r.logger.Printf("[ERR] agent: Failed to run hook %v for %q: %v", h.Args, e.Event, err)
+		return
+	}
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	select {
+	case <-time.After(h.Timeout):
+		if err := exec.KillCommandSubtree(cmd); err != nil {
+			r.logger.Printf("[WARN] agent: Failed to kill hook %v after timeout: %v", h.Args, err)
+		}
+		<-waitCh
+		err = fmt.Errorf("timed out after %s", h.Timeout)
+	case err = <-waitCh:
+	}
+
+	outputStr := truncatedOutput(output)
+	if err != nil {
+		r.logger.Printf("[ERR] agent: Hook %v for %q failed: %v, output: %s", h.Args, e.Event, err, outputStr)
+		return
+	}
+	r.logger.Printf("[DEBUG] agent: Hook %v for %q output: %s", h.Args, e.Event, outputStr)
+}
+
+// runHTTP sends the payload to the URL of the hook.
+func (r *hookRunner) runHTTP(h *hook, e *HookEvent, payload io.Reader) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequest(h.Method, h.HTTP, payload)
+	if err != nil {
+		r.logger.Printf("[ERR] agent: Failed to setup hook '%s' for %q: %v", h.HTTP, e.Event, err)
+		return
+	}
+	req = req.WithContext(ctx)
+	if h.payload == nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Consul-Hook-Event", e.Event)
+	for key, values := range h.Header {
+		for _, val := range values {
+			req.Header.Add(key, val)
+		}
+	}
+
+	httpClient := &http.Client{Transport: cleanhttp.DefaultTransport()}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		r.logger.Printf("[ERR] agent: Failed to invoke hook '%s' for %q: %v", h.HTTP, e.Event, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	output, _ := circbuf.NewBuffer(WatchBufSize)
+	io.Copy(output, resp.Body)
+
+	outputStr := truncatedOutput(output)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		r.logger.Printf("[ERR] agent: Hook '%s' for %q got '%s' with output: %s",
+			h.HTTP, e.Event, resp.Status, outputStr)
+		return
+	}
+	r.logger.Printf("[TRACE] agent: Hook '%s' for %q output: %s", h.HTTP, e.Event, outputStr)
+}
+
+// truncatedOutput returns the captured output with a note if it was
+// truncated.
+func truncatedOutput(output *circbuf.Buffer) string {
+	outputStr := string(output.Bytes())
+	if output.TotalWritten() > output.Size() {
+		outputStr = fmt.Sprintf("Captured %d of %d bytes\n...\n%s",
+			output.Size(), output.TotalWritten(), outputStr)
+	}
+	return outputStr
+}
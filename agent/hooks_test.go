@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookRunner_Args(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "hooks")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	r, err := newHookRunner([]config.RuntimeHookConfig{
+		{
+			Events:  []string{config.HookEventLeft},
+			Args:    []string{"sh", "-c", "echo $CONSUL_HOOK_EVENT > " + out + " && cat >> " + out},
+			Timeout: 10 * time.Second,
+		},
+	}, "node1", "dc1", log.New(os.Stderr, "", log.LstdFlags))
+	require.NoError(t, err)
+
+	r.Run(&HookEvent{Event: config.HookEventLeft})
+
+	raw, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	lines := strings.SplitN(string(raw), "\n", 2)
+	require.Equal(t, "left", lines[0])
+
+	var e HookEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &e))
+	require.Equal(t, "left", e.Event)
+	require.Equal(t, "node1", e.Node)
+	require.Equal(t, "dc1", e.Datacenter)
+	require.False(t, e.Timestamp.IsZero())
+	require.Nil(t, e.Check)
+}
+
+func TestHookRunner_ArgsTimeout(t *testing.T) {
+	t.Parallel()
+	r, err := newHookRunner([]config.RuntimeHookConfig{
+		{
+			Events:  []string{config.HookEventLeft},
+			Args:    []string{"sleep", "10"},
+			Timeout: 100 * time.Millisecond,
+		},
+	}, "node1", "dc1", log.New(os.Stderr, "", log.LstdFlags))
+	require.NoError(t, err)
+
+	start := time.Now()
+	r.Run(&HookEvent{Event: config.HookEventLeft})
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestHookRunner_HTTP(t *testing.T) {
+	t.Parallel()
+	type request struct {
+		method string
+		header http.Header
+		body   string
+	}
+	reqCh := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		reqCh <- request{r.Method, r.Header, string(body)}
+	}))
+	defer server.Close()
+
+	r, err := newHookRunner([]config.RuntimeHookConfig{
+		{
+			Events:  []string{config.HookEventCheckStateChanged},
+			HTTP:    server.URL,
+			Method:  "PUT",
+			Header:  map[string][]string{"X-Custom": {"abc"}},
+			Payload: `{{ .Node }} {{ .Check.CheckID }} {{ .PreviousStatus }} -> {{ .Check.Status }} {{ json .Check.Name }}`,
+			Timeout: 10 * time.Second,
+		},
+	}, "node1", "dc1", log.New(os.Stderr, "", log.LstdFlags))
+	require.NoError(t, err)
+
+	// Hooks are only run for their events.
+	r.Fire(&HookEvent{Event: config.HookEventJoined})
+
+	r.Fire(&HookEvent{
+		Event: config.HookEventCheckStateChanged,
+		Check: &structs.HealthCheck{
+			CheckID: "web",
+			Name:    "web check",
+			Status:  api.HealthCritical,
+		},
+		PreviousStatus: api.HealthPassing,
+	})
+
+	select {
+	case req := <-reqCh:
+		require.Equal(t, "PUT", req.method)
+		require.Equal(t, "abc", req.header.Get("X-Custom"))
+		require.Equal(t, "check_state_changed", req.header.Get("X-Consul-Hook-Event"))
+		require.Equal(t, `node1 web passing -> critical "web check"`, req.body)
+	case <-time.After(10 * time.Second):
+		t.Fatal("hook was not run")
+	}
+}
+
+func TestNewHookRunner_InvalidPayload(t *testing.T) {
+	t.Parallel()
+	_, err := newHookRunner([]config.RuntimeHookConfig{
+		{
+			Events:  []string{config.HookEventJoined},
+			Args:    []string{"true"},
+			Payload: "{{ .Node ",
+		},
+	}, "node1", "dc1", log.New(os.Stderr, "", log.LstdFlags))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to parse payload of hook 0")
+}
+
+func TestAgent_Hooks_CheckStateChanged(t *testing.T) {
+	t.Parallel()
+	eventCh := make(chan HookEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e HookEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err == nil {
+			eventCh <- e
+		}
+	}))
+	defer server.Close()
+
+	a := NewTestAgent(t.Name(), `
+		hooks = [
+			{
+				events = [ "check_state_changed" ]
+				http = "`+server.URL+`"
+			}
+		]
+	`)
+	defer a.Shutdown()
+
+	chk := &structs.HealthCheck{
+		Node:    a.Config.NodeName,
+		CheckID: "ttl",
+		Name:    "ttl",
+		Status:  api.HealthCritical,
+	}
+	chkType := &structs.CheckType{TTL: time.Minute}
+	require.NoError(t, a.AddCheck(chk, chkType, false, "", ConfigSourceLocal))
+	require.NoError(t, a.updateTTLCheck("ttl", api.HealthPassing, "ok"))
+
+	retry.Run(t, func(r *retry.R) {
+		select {
+		case e := <-eventCh:
+			if e.Event != "check_state_changed" || e.Check == nil {
+				r.Fatalf("bad: %#v", e)
+			}
+			if e.Check.CheckID != "ttl" || e.Check.Status != api.HealthPassing || e.PreviousStatus != api.HealthCritical {
+				r.Fatalf("bad: %#v %#v", e, e.Check)
+			}
+			if e.Node != a.Config.NodeName {
+				r.Fatalf("bad: %#v", e)
+			}
+		default:
+			r.Fatal("no event")
+		}
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
@@ -19,6 +20,7 @@ import (
 	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/cache"
 	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/mitchellh/mapstructure"
 )
@@ -49,8 +51,17 @@ type HTTPServer struct {
 	agent     *Agent
 	blacklist *Blacklist
 
+	// certAllowlist restricts the requests to the allowed client
+	// certificates. It is nil if all requests are allowed.
+	certAllowlist *ClientCertAllowlist
+
 	// proto is filled by the agent to "http" or "https".
 	proto string
+
+	// tlsConfig holds the *tls.Config which replaces the initial TLS
+	// configuration of an HTTPS server after a reload. It is empty
+	// until the configuration has been reloaded.
+	tlsConfig atomic.Value
 }
 
 type redirectFS struct {
@@ -296,6 +307,14 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 			return
 		}
 
+		if !s.certAllowlist.Allow(req) {
+			errMsg := "Client certificate is not allowed by agent configuration"
+			s.agent.logger.Printf("[ERR] http: Request %s %v, error: %v from=%s", req.Method, logURL, errMsg, req.RemoteAddr)
+			resp.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(resp, errMsg)
+			return
+		}
+
 		isMethodNotAllowed := func(err error) bool {
 			_, ok := err.(MethodNotAllowedError)
 			return ok
@@ -313,11 +332,25 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 		handleErr := func(err error) {
 			s.agent.logger.Printf("[ERR] http: Request %s %v, error: %v from=%s", req.Method, logURL, err, req.RemoteAddr)
 			switch {
-			case acl.IsErrPermissionDenied(err) || acl.IsErrNotFound(err):
+			case acl.IsErrPermissionDenied(err):
+				// The permission the token lacks is only disclosed when
+				// enabled, as it tells the caller about the rules of the
+				// token.
+				permission, msg := acl.DeniedPermission(err)
+				if permission != "" && s.agent.config.ACLEnableDeniedPermissionDetails {
+					resp.Header().Set("X-Consul-ACL-Denied-Permission", permission)
+					msg = err.Error()
+				}
+				resp.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(resp, msg)
+			case acl.IsErrNotFound(err):
 				resp.WriteHeader(http.StatusForbidden)
 				fmt.Fprint(resp, err.Error())
 			case structs.IsErrRPCRateExceeded(err):
 				resp.WriteHeader(http.StatusTooManyRequests)
+			case structs.IsErrTooManyBlockingQueries(err) || err == errIdempotencyKeysFull:
+				resp.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(resp, err.Error())
 			case isMethodNotAllowed(err):
 				// RFC2616 states that for 405 Method Not Allowed the response
 				// MUST include an Allow header containing the list of valid
@@ -340,6 +373,14 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 			s.agent.logger.Printf("[DEBUG] http: Request %s %v (%v) from=%s", req.Method, logURL, time.Since(start), req.RemoteAddr)
 		}()
 
+		if requestLog := s.agent.config.RequestLogConfig(); requestLog.Enabled() {
+			statusResp := &statusResponseWriter{ResponseWriter: resp, status: http.StatusOK}
+			resp = statusResp
+			defer func() {
+				s.logRequest(requestLog, req, logURL, statusResp.status, time.Since(start))
+			}()
+		}
+
 		var obj interface{}
 
 		// if this endpoint has declared methods, respond appropriately to OPTIONS requests. Otherwise let the endpoint handle that.
@@ -360,8 +401,22 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 		if !methodFound {
 			err = MethodNotAllowedError{req.Method, append([]string{"OPTIONS"}, methods...)}
 		} else {
+			// Retries of write requests with an idempotency key get the
+			// response of the first request.
+			var finish func()
+			var replayed bool
+			resp, finish, replayed, err = s.idempotentRequest(resp, req)
+			if finish != nil {
+				defer finish()
+			}
+			if replayed {
+				return
+			}
+
 			// Invoke the handler
-			obj, err = handler(resp, req)
+			if err == nil {
+				obj, err = handler(resp, req)
+			}
 		}
 
 		if err != nil {
@@ -382,6 +437,55 @@ func (s *HTTPServer) wrap(handler endpoint, methods []string) http.HandlerFunc {
 	}
 }
 
+// logRequest logs the request if it was slow or is sampled according to the
+// request log configuration. Reads are GET and HEAD requests, blocking
+// queries are never logged as slow.
+func (s *HTTPServer) logRequest(config lib.RequestLogConfig, req *http.Request, logURL string, status int, elapsed time.Duration) {
+	read := req.Method == "GET" || req.Method == "HEAD"
+	query := req.URL.Query()
+	blocking := query.Get("index") != "" || query.Get("hash") != ""
+	slow, sampled := config.Check(read, blocking, elapsed)
+	if !slow && !sampled {
+		return
+	}
+
+	var token string
+	s.parseTokenInternal(req, &token, false)
+	accessor := s.agent.tokenAccessorID(token)
+	if slow {
+		s.agent.logger.Printf("[WARN] http: Slow request method=%s url=%v status=%d latency=%v accessor=%s from=%s",
+			req.Method, logURL, status, elapsed, accessor, req.RemoteAddr)
+	} else {
+		s.agent.logger.Printf("[INFO] http: Sampled request method=%s url=%v status=%d latency=%v accessor=%s from=%s",
+			req.Method, logURL, status, elapsed, accessor, req.RemoteAddr)
+	}
+}
+
+// statusResponseWriter records the status code of a response. It passes
+// flushes and close notifications through for the streaming endpoints.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return nil
+}
+
 // marshalJSON marshals the object into JSON, respecting the user's pretty-ness
 // configuration.
 func (s *HTTPServer) marshalJSON(req *http.Request, obj interface{}) ([]byte, error) {
@@ -470,6 +574,24 @@ func setIndex(resp http.ResponseWriter, index uint64) {
 	resp.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
 }
 
+// setWriteIndex is used to set the X-Consul-Write-Index header after a
+// successful write. It holds the Raft index the leader of the given
+// datacenter has applied which includes the write. Clients pass it in the
+// X-Consul-MinIndex header of subsequent reads to be guaranteed to see the
+// write, even when stale reads are allowed.
+func (s *HTTPServer) setWriteIndex(resp http.ResponseWriter, dc string) {
+	if dc == "" {
+		dc = s.agent.config.Datacenter
+	}
+	args := structs.DCSpecificRequest{Datacenter: dc}
+	var index uint64
+	if err := s.agent.RPC("Status.AppliedIndex", &args, &index); err != nil {
+		s.agent.logger.Printf("[WARN] http: Failed to get the write index: %v", err)
+		return
+	}
+	resp.Header().Set("X-Consul-Write-Index", strconv.FormatUint(index, 10))
+}
+
 // setKnownLeader is used to set the known leader header
 func setKnownLeader(resp http.ResponseWriter, known bool) {
 	s := "true"
@@ -485,6 +607,22 @@ func setConsistency(resp http.ResponseWriter, consistency string) {
 	}
 }
 
+// setFilteredPolicies is used to set the header listing the policies which
+// were ignored because of their datacenter scope
+func setFilteredPolicies(resp http.ResponseWriter, policies []string) {
+	if len(policies) > 0 {
+		resp.Header().Set("X-Consul-ACL-Filtered-Policies", strings.Join(policies, ","))
+	}
+}
+
+// setEffectiveWait is used to set the header with the time a blocking query
+// was allowed to wait after the limits of the server were applied
+func setEffectiveWait(resp http.ResponseWriter, wait time.Duration) {
+	if wait > 0 {
+		resp.Header().Set("X-Consul-Effective-Wait", wait.String())
+	}
+}
+
 // setLastContact is used to set the last contact header
 func setLastContact(resp http.ResponseWriter, last time.Duration) {
 	if last < 0 {
@@ -500,6 +638,8 @@ func setMeta(resp http.ResponseWriter, m *structs.QueryMeta) {
 	setLastContact(resp, m.LastContact)
 	setKnownLeader(resp, m.KnownLeader)
 	setConsistency(resp, m.ConsistencyLevel)
+	setFilteredPolicies(resp, m.FilteredPolicies)
+	setEffectiveWait(resp, m.EffectiveWait)
 }
 
 // setCacheMeta sets http response headers to indicate cache status.
@@ -640,6 +780,15 @@ func (s *HTTPServer) parseConsistency(resp http.ResponseWriter, req *http.Reques
 			defaults = false
 		}
 	}
+	if minIndex := req.Header.Get("X-Consul-MinIndex"); minIndex != "" {
+		index, err := strconv.ParseUint(minIndex, 10, 64)
+		if err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Invalid X-Consul-MinIndex value %q", minIndex)
+			return true
+		}
+		b.MinAppliedIndex = index
+	}
 	// No specific Consistency has been specified by caller
 	if defaults {
 		path := req.URL.Path
@@ -660,6 +809,11 @@ func (s *HTTPServer) parseConsistency(resp http.ResponseWriter, req *http.Reques
 		fmt.Fprint(resp, "Cannot specify ?cached with ?consistent, conflicting semantics.")
 		return true
 	}
+	// The agent cache doesn't know which index its entries reflect so reads
+	// which must see a preceding write bypass it.
+	if b.MinAppliedIndex > 0 {
+		b.UseCache = false
+	}
 	return false
 }
 
@@ -13,20 +13,36 @@ func init() {
 	registerEndpoint("/v1/acl/replication", []string{"GET"}, (*HTTPServer).ACLReplicationStatus)
 	registerEndpoint("/v1/acl/policies", []string{"GET"}, (*HTTPServer).ACLPolicyList)
 	registerEndpoint("/v1/acl/policy", []string{"PUT"}, (*HTTPServer).ACLPolicyCreate)
+	registerEndpoint("/v1/acl/policy/impact", []string{"PUT"}, (*HTTPServer).ACLPolicyImpact)
+	registerEndpoint("/v1/acl/policy/validate", []string{"PUT"}, (*HTTPServer).ACLPolicyValidate)
 	registerEndpoint("/v1/acl/policy/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).ACLPolicyCRUD)
 	registerEndpoint("/v1/acl/rules/translate", []string{"POST"}, (*HTTPServer).ACLRulesTranslate)
 	registerEndpoint("/v1/acl/rules/translate/", []string{"GET"}, (*HTTPServer).ACLRulesTranslateLegacyToken)
-	registerEndpoint("/v1/acl/tokens", []string{"GET"}, (*HTTPServer).ACLTokenList)
+	registerEndpoint("/v1/acl/tokens", []string{"GET", "DELETE"}, (*HTTPServer).ACLTokens)
 	registerEndpoint("/v1/acl/token", []string{"PUT"}, (*HTTPServer).ACLTokenCreate)
 	registerEndpoint("/v1/acl/token/self", []string{"GET"}, (*HTTPServer).ACLTokenSelf)
 	registerEndpoint("/v1/acl/token/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).ACLTokenCRUD)
+	registerEndpoint("/v1/acl/auth-methods", []string{"GET"}, (*HTTPServer).ACLAuthMethodList)
+	registerEndpoint("/v1/acl/auth-method", []string{"PUT"}, (*HTTPServer).ACLAuthMethodCreate)
+	registerEndpoint("/v1/acl/auth-method/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).ACLAuthMethodCRUD)
+	registerEndpoint("/v1/acl/binding-rules", []string{"GET"}, (*HTTPServer).ACLBindingRuleList)
+	registerEndpoint("/v1/acl/binding-rule", []string{"PUT"}, (*HTTPServer).ACLBindingRuleCreate)
+	registerEndpoint("/v1/acl/binding-rule/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).ACLBindingRuleCRUD)
+	registerEndpoint("/v1/acl/login", []string{"POST"}, (*HTTPServer).ACLLogin)
+	registerEndpoint("/v1/acl/logout", []string{"POST"}, (*HTTPServer).ACLLogout)
+	registerEndpoint("/v1/acl/authorize", []string{"POST"}, (*HTTPServer).ACLAuthorize)
 	registerEndpoint("/v1/agent/token/", []string{"PUT"}, (*HTTPServer).AgentToken)
 	registerEndpoint("/v1/agent/self", []string{"GET"}, (*HTTPServer).AgentSelf)
+	registerEndpoint("/v1/agent/config", []string{"GET"}, (*HTTPServer).AgentRuntimeConfig)
 	registerEndpoint("/v1/agent/host", []string{"GET"}, (*HTTPServer).AgentHost)
 	registerEndpoint("/v1/agent/maintenance", []string{"PUT"}, (*HTTPServer).AgentNodeMaintenance)
+	registerEndpoint("/v1/agent/node-meta", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).AgentNodeMeta)
+	registerEndpoint("/v1/agent/fault-injection", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).AgentFaultInjection)
+	registerEndpoint("/v1/agent/cache", []string{"GET", "DELETE"}, (*HTTPServer).AgentCache)
 	registerEndpoint("/v1/agent/reload", []string{"PUT"}, (*HTTPServer).AgentReload)
 	registerEndpoint("/v1/agent/monitor", []string{"GET"}, (*HTTPServer).AgentMonitor)
 	registerEndpoint("/v1/agent/metrics", []string{"GET"}, (*HTTPServer).AgentMetrics)
+	registerEndpoint("/v1/agent/metrics/checks", []string{"GET"}, (*HTTPServer).AgentCheckMetrics)
 	registerEndpoint("/v1/agent/services", []string{"GET"}, (*HTTPServer).AgentServices)
 	registerEndpoint("/v1/agent/service/", []string{"GET"}, (*HTTPServer).AgentService)
 	registerEndpoint("/v1/agent/checks", []string{"GET"}, (*HTTPServer).AgentChecks)
@@ -40,10 +56,12 @@ func init() {
 	registerEndpoint("/v1/agent/check/warn/", []string{"PUT"}, (*HTTPServer).AgentCheckWarn)
 	registerEndpoint("/v1/agent/check/fail/", []string{"PUT"}, (*HTTPServer).AgentCheckFail)
 	registerEndpoint("/v1/agent/check/update/", []string{"PUT"}, (*HTTPServer).AgentCheckUpdate)
+	registerEndpoint("/v1/agent/check/update-definition/", []string{"PUT"}, (*HTTPServer).AgentCheckUpdateDefinition)
 	registerEndpoint("/v1/agent/connect/authorize", []string{"POST"}, (*HTTPServer).AgentConnectAuthorize)
 	registerEndpoint("/v1/agent/connect/ca/roots", []string{"GET"}, (*HTTPServer).AgentConnectCARoots)
 	registerEndpoint("/v1/agent/connect/ca/leaf/", []string{"GET"}, (*HTTPServer).AgentConnectCALeafCert)
 	registerEndpoint("/v1/agent/connect/proxy/", []string{"GET"}, (*HTTPServer).AgentConnectProxyConfig)
+	registerEndpoint("/v1/agent/connect/proxy-snapshot/", []string{"GET"}, (*HTTPServer).AgentConnectProxySnapshot)
 	registerEndpoint("/v1/agent/service/register", []string{"PUT"}, (*HTTPServer).AgentRegisterService)
 	registerEndpoint("/v1/agent/service/deregister/", []string{"PUT"}, (*HTTPServer).AgentDeregisterService)
 	registerEndpoint("/v1/agent/service/maintenance/", []string{"PUT"}, (*HTTPServer).AgentServiceMaintenance)
@@ -52,14 +70,19 @@ func init() {
 	registerEndpoint("/v1/catalog/deregister", []string{"PUT"}, (*HTTPServer).CatalogDeregister)
 	registerEndpoint("/v1/catalog/datacenters", []string{"GET"}, (*HTTPServer).CatalogDatacenters)
 	registerEndpoint("/v1/catalog/nodes", []string{"GET"}, (*HTTPServer).CatalogNodes)
+	registerEndpoint("/v1/catalog/node-activity", []string{"GET"}, (*HTTPServer).CatalogNodeActivity)
 	registerEndpoint("/v1/catalog/services", []string{"GET"}, (*HTTPServer).CatalogServices)
+	registerEndpoint("/v1/catalog/virtual-ips", []string{"GET"}, (*HTTPServer).CatalogServiceVirtualIPs)
 	registerEndpoint("/v1/catalog/service/", []string{"GET"}, (*HTTPServer).CatalogServiceNodes)
 	registerEndpoint("/v1/catalog/node/", []string{"GET"}, (*HTTPServer).CatalogNodeServices)
 	registerEndpoint("/v1/connect/ca/configuration", []string{"GET", "PUT"}, (*HTTPServer).ConnectCAConfiguration)
+	registerEndpoint("/v1/connect/ca/leaves", []string{"GET"}, (*HTTPServer).ConnectCALeaves)
 	registerEndpoint("/v1/connect/ca/roots", []string{"GET"}, (*HTTPServer).ConnectCARoots)
 	registerEndpoint("/v1/connect/intentions", []string{"GET", "POST"}, (*HTTPServer).IntentionEndpoint)
 	registerEndpoint("/v1/connect/intentions/match", []string{"GET"}, (*HTTPServer).IntentionMatch)
 	registerEndpoint("/v1/connect/intentions/check", []string{"GET"}, (*HTTPServer).IntentionCheck)
+	registerEndpoint("/v1/connect/intentions/impact", []string{"PUT"}, (*HTTPServer).IntentionImpact)
+	registerEndpoint("/v1/connect/intentions/replication", []string{"GET"}, (*HTTPServer).IntentionReplicationStatus)
 	registerEndpoint("/v1/connect/intentions/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).IntentionSpecific)
 	registerEndpoint("/v1/coordinate/datacenters", []string{"GET"}, (*HTTPServer).CoordinateDatacenters)
 	registerEndpoint("/v1/coordinate/nodes", []string{"GET"}, (*HTTPServer).CoordinateNodes)
@@ -74,13 +97,22 @@ func init() {
 	registerEndpoint("/v1/health/connect/", []string{"GET"}, (*HTTPServer).HealthConnectServiceNodes)
 	registerEndpoint("/v1/internal/ui/nodes", []string{"GET"}, (*HTTPServer).UINodes)
 	registerEndpoint("/v1/internal/ui/node/", []string{"GET"}, (*HTTPServer).UINodeInfo)
+	registerEndpoint("/v1/internal/ui/node-detail/", []string{"GET"}, (*HTTPServer).UINodeDetail)
 	registerEndpoint("/v1/internal/ui/services", []string{"GET"}, (*HTTPServer).UIServices)
+	registerEndpoint("/v1/internal/ui/metrics-proxy/", []string{"GET"}, (*HTTPServer).UIMetricsProxy)
+	registerEndpoint("/v1/internal/locks", []string{"GET"}, (*HTTPServer).KVSLocks)
+	registerEndpoint("/v1/internal/protocol-conflicts", []string{"GET"}, (*HTTPServer).ProtocolConflicts)
 	registerEndpoint("/v1/kv/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).KVSEndpoint)
+	registerEndpoint("/v1/kv-restore/", []string{"GET", "PUT", "DELETE"}, (*HTTPServer).KVSRecycleBin)
 	registerEndpoint("/v1/operator/raft/configuration", []string{"GET"}, (*HTTPServer).OperatorRaftConfiguration)
 	registerEndpoint("/v1/operator/raft/peer", []string{"DELETE"}, (*HTTPServer).OperatorRaftPeer)
 	registerEndpoint("/v1/operator/keyring", []string{"GET", "POST", "PUT", "DELETE"}, (*HTTPServer).OperatorKeyringEndpoint)
+	registerEndpoint("/v1/operator/keyring/rotation", []string{"GET"}, (*HTTPServer).OperatorKeyringRotation)
 	registerEndpoint("/v1/operator/autopilot/configuration", []string{"GET", "PUT"}, (*HTTPServer).OperatorAutopilotConfiguration)
 	registerEndpoint("/v1/operator/autopilot/health", []string{"GET"}, (*HTTPServer).OperatorServerHealth)
+	registerEndpoint("/v1/operator/ui-config", []string{"GET", "PUT"}, (*HTTPServer).OperatorUIConfiguration)
+	registerEndpoint("/v1/operator/gossip", []string{"GET", "PUT"}, (*HTTPServer).OperatorGossip)
+	registerEndpoint("/v1/operator/kv-replication", []string{"GET"}, (*HTTPServer).OperatorKVReplication)
 	registerEndpoint("/v1/query", []string{"GET", "POST"}, (*HTTPServer).PreparedQueryGeneral)
 	// specific prepared query endpoints have more complex rules for allowed methods, so
 	// the prefix is registered with no methods.
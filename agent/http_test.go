@@ -3,6 +3,9 @@ package agent
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testrpc"
@@ -260,6 +264,36 @@ func TestSetLastContact(t *testing.T) {
 	}
 }
 
+func TestSetFilteredPolicies(t *testing.T) {
+	t.Parallel()
+	resp := httptest.NewRecorder()
+	setFilteredPolicies(resp, nil)
+	if _, ok := resp.Header()["X-Consul-Acl-Filtered-Policies"]; ok {
+		t.Fatalf("Bad: %v", resp.Header())
+	}
+	resp = httptest.NewRecorder()
+	setFilteredPolicies(resp, []string{"dc1-only", "dc2-only"})
+	header := resp.Header().Get("X-Consul-ACL-Filtered-Policies")
+	if header != "dc1-only,dc2-only" {
+		t.Fatalf("Bad: %v", header)
+	}
+}
+
+func TestSetEffectiveWait(t *testing.T) {
+	t.Parallel()
+	resp := httptest.NewRecorder()
+	setEffectiveWait(resp, 0)
+	if _, ok := resp.Header()["X-Consul-Effective-Wait"]; ok {
+		t.Fatalf("Bad: %v", resp.Header())
+	}
+	resp = httptest.NewRecorder()
+	setEffectiveWait(resp, 10*time.Minute)
+	header := resp.Header().Get("X-Consul-Effective-Wait")
+	if header != "10m0s" {
+		t.Fatalf("Bad: %v", header)
+	}
+}
+
 func TestSetMeta(t *testing.T) {
 	t.Parallel()
 	meta := structs.QueryMeta{
@@ -318,6 +352,103 @@ func TestHTTPAPI_BlockEndpoints(t *testing.T) {
 	}
 }
 
+func TestHTTPAPI_DeniedPermissionDetails(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{false, true} {
+		enabled := enabled
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			t.Parallel()
+			a := NewTestAgent(t.Name(), TestACLConfig()+fmt.Sprintf(`
+				acl {
+					enable_denied_permission_details = %t
+				}
+			`, enabled))
+			defer a.Shutdown()
+			testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+			// Register a service with the anonymous token, which is denied
+			// by the servers.
+			body := bytes.NewBufferString(`{"Node": "foo", "Address": "127.0.0.1", "Service": {"Service": "web"}}`)
+			req, _ := http.NewRequest("PUT", "/v1/catalog/register", body)
+			resp := httptest.NewRecorder()
+			a.srv.Handler.ServeHTTP(resp, req)
+			require.Equal(t, http.StatusForbidden, resp.Code)
+
+			if enabled {
+				require.Equal(t, "service:web:write", resp.Header().Get("X-Consul-ACL-Denied-Permission"))
+				require.Equal(t, "Permission denied (requires service:web:write)", resp.Body.String())
+			} else {
+				require.Empty(t, resp.Header().Get("X-Consul-ACL-Denied-Permission"))
+				require.Equal(t, "Permission denied", resp.Body.String())
+			}
+
+			// Denials without a known permission are unchanged.
+			handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+				return nil, acl.ErrPermissionDenied
+			}
+			req, _ = http.NewRequest("GET", "/v1/agent/self", nil)
+			resp = httptest.NewRecorder()
+			a.srv.wrap(handler, []string{"GET"})(resp, req)
+			require.Equal(t, http.StatusForbidden, resp.Code)
+			require.Empty(t, resp.Header().Get("X-Consul-ACL-Denied-Permission"))
+			require.Equal(t, "Permission denied", resp.Body.String())
+		})
+	}
+}
+
+func TestHTTPAPI_ClientCertAllowlist(t *testing.T) {
+	t.Parallel()
+
+	a := NewTestAgent(t.Name(), `
+		ca_file = "../test/ca/root.cer"
+		http_config {
+			client_cert_allowlist {
+				common_names = ["deploy-*"]
+				write_only = true
+			}
+		}
+	`)
+	defer a.Shutdown()
+
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return nil, nil
+	}
+
+	// Writes without an allowed certificate get a 403.
+	{
+		req, _ := http.NewRequest("PUT", "/v1/kv/foo", nil)
+		resp := httptest.NewRecorder()
+		a.srv.wrap(handler, []string{"PUT"})(resp, req)
+		if got, want := resp.Code, http.StatusForbidden; got != want {
+			t.Fatalf("bad response code got %d want %d", got, want)
+		}
+	}
+
+	// Writes with an allowed certificate work.
+	{
+		req, _ := http.NewRequest("PUT", "/v1/kv/foo", nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "deploy-1"}}}},
+		}
+		resp := httptest.NewRecorder()
+		a.srv.wrap(handler, []string{"PUT"})(resp, req)
+		if got, want := resp.Code, http.StatusOK; got != want {
+			t.Fatalf("bad response code got %d want %d", got, want)
+		}
+	}
+
+	// Reads aren't restricted.
+	{
+		req, _ := http.NewRequest("GET", "/v1/kv/foo", nil)
+		resp := httptest.NewRecorder()
+		a.srv.wrap(handler, []string{"GET"})(resp, req)
+		if got, want := resp.Code, http.StatusOK; got != want {
+			t.Fatalf("bad response code got %d want %d", got, want)
+		}
+	}
+}
+
 func TestHTTPAPI_Ban_Nonprintable_Characters(t *testing.T) {
 	a := NewTestAgent(t.Name(), "")
 	defer a.Shutdown()
@@ -492,6 +623,45 @@ func TestHTTP_wrap_obfuscateLog(t *testing.T) {
 	}
 }
 
+func TestHTTP_wrap_requestLog(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	a := &TestAgent{Name: t.Name(), LogOutput: buf, HCL: TestACLConfig() + `
+		request_logging {
+			slow_read_threshold = "1ns"
+		}
+	`}
+	a.Start()
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return nil, BadRequestError{Reason: "bad"}
+	}
+
+	// Reads which exceed the threshold are logged with the accessor of
+	// the token.
+	req, _ := http.NewRequest("GET", "/some/url?token=", nil)
+	a.srv.wrap(handler, []string{"GET"})(httptest.NewRecorder(), req)
+	want := "[WARN] http: Slow request method=GET url=/some/url?token=<hidden> status=400"
+	if got := buf.String(); !strings.Contains(got, want) ||
+		!strings.Contains(got, "accessor="+structs.ACLTokenAnonymousID) {
+		t.Fatalf("got %s want %s", got, want)
+	}
+
+	// Blocking queries and writes without a threshold aren't logged.
+	for _, r := range []struct{ method, url string }{
+		{"GET", "/blocking?index=12"},
+		{"PUT", "/write"},
+	} {
+		req, _ := http.NewRequest(r.method, r.url, nil)
+		a.srv.wrap(handler, []string{r.method})(httptest.NewRecorder(), req)
+		if got := buf.String(); strings.Contains(got, "url="+r.url) {
+			t.Fatalf("got %s", got)
+		}
+	}
+}
+
 func TestPrettyPrint(t *testing.T) {
 	t.Parallel()
 	testPrettyPrint("pretty=1", t)
@@ -888,6 +1058,28 @@ func TestParseConsistency(t *testing.T) {
 	}
 }
 
+func TestParseConsistency_MinIndex(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	var b structs.QueryOptions
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/catalog/nodes?stale&cached", nil)
+	req.Header.Set("X-Consul-MinIndex", "1234")
+	require.False(t, a.srv.parseConsistency(resp, req, &b))
+	require.Equal(t, uint64(1234), b.MinAppliedIndex)
+	require.True(t, b.AllowStale)
+	require.False(t, b.UseCache)
+
+	b = structs.QueryOptions{}
+	resp = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/catalog/nodes", nil)
+	req.Header.Set("X-Consul-MinIndex", "nope")
+	require.True(t, a.srv.parseConsistency(resp, req, &b))
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
 // ensureConsistency check if consistency modes are correctly applied
 // if maxStale < 0 => stale, without MaxStaleDuration
 // if maxStale == 0 => no stale
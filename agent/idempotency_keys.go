@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotencyKeyHeader is the header with which clients make a write
+	// request safe to retry.
+	idempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotencyKeyTTL is how long the response of a request is replayed
+	// for retries with the same key.
+	idempotencyKeyTTL = 10 * time.Minute
+
+	// idempotencyKeyMaxLen is the longest key which is accepted.
+	idempotencyKeyMaxLen = 128
+
+	// idempotencyKeyMaxEntries is the number of responses which are kept
+	// at most. Further requests with a new key are rejected until older
+	// responses expire.
+	idempotencyKeyMaxEntries = 16384
+)
+
+var (
+	errIdempotencyKeyReused = errors.New("Idempotency-Key was already used for a different request")
+	errIdempotencyKeysFull  = errors.New("Too many Idempotency-Key requests in progress")
+)
+
+// idempotencyKeyStore keeps the responses of the write requests which were
+// made with an idempotency key, so that a client retrying a request over a
+// flaky network doesn't apply it twice.
+type idempotencyKeyStore struct {
+	ttl time.Duration
+	max int
+
+	lock    sync.Mutex
+	entries map[string]*idempotentResponse
+}
+
+// idempotentResponse is the response of a single request. The response is
+// only complete once done is closed.
+type idempotentResponse struct {
+	fingerprint string
+	expires     time.Time
+	done        chan struct{}
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// newIdempotencyKeyStore returns a store which keeps at most max responses
+// for the given time.
+func newIdempotencyKeyStore(ttl time.Duration, max int) *idempotencyKeyStore {
+	return &idempotencyKeyStore{
+		ttl:     ttl,
+		max:     max,
+		entries: make(map[string]*idempotentResponse),
+	}
+}
+
+// Begin looks up the response of the request with the given key. If there
+// is none, a new response is started which the caller must complete with
+// Finish, and the second return value is true. A request with the same key
+// but a different fingerprint is an error.
+func (s *idempotencyKeyStore) Begin(key, fingerprint string) (*idempotentResponse, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if r, ok := s.entries[key]; ok && !r.expired(now) {
+		if r.fingerprint != fingerprint {
+			return nil, false, errIdempotencyKeyReused
+		}
+		return r, false, nil
+	}
+
+	if len(s.entries) >= s.max {
+		s.prune(now)
+		if len(s.entries) >= s.max {
+			return nil, false, errIdempotencyKeysFull
+		}
+	}
+	r := &idempotentResponse{
+		fingerprint: fingerprint,
+		done:        make(chan struct{}),
+	}
+	s.entries[key] = r
+	return r, true, nil
+}
+
+// Finish completes a response started by Begin. Unless keep is true, the
+// response is only passed to the requests which are already waiting for it,
+// and a later retry is handled again.
+func (s *idempotencyKeyStore) Finish(key string, r *idempotentResponse, keep bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if keep {
+		r.expires = time.Now().Add(s.ttl)
+	} else if s.entries[key] == r {
+		delete(s.entries, key)
+	}
+	close(r.done)
+}
+
+// prune drops the expired responses. The lock must be held.
+func (s *idempotencyKeyStore) prune(now time.Time) {
+	for key, r := range s.entries {
+		if r.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// expired returns true if the response is complete and older than its TTL.
+// Responses in progress never expire.
+func (r *idempotentResponse) expired(now time.Time) bool {
+	return !r.expires.IsZero() && now.After(r.expires)
+}
+
+// replay writes the recorded response.
+func (r *idempotentResponse) replay(resp http.ResponseWriter) {
+	for k, v := range r.header {
+		resp.Header()[k] = v
+	}
+	resp.Header().Set("X-Consul-Idempotent-Replay", "true")
+	resp.WriteHeader(r.status)
+	resp.Write(r.body)
+}
+
+// idempotentResponseWriter records the response which is written so that it
+// can be replayed.
+type idempotentResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *idempotentResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idempotentResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// idempotentRequest handles the Idempotency-Key header of write requests.
+// If the key was already used for the same request, the response of the
+// first request is replayed and replayed is true. Otherwise the request
+// must be handled with the returned writer, and finish must be called once
+// the response is written. finish is nil if the request has no key.
+func (s *HTTPServer) idempotentRequest(resp http.ResponseWriter, req *http.Request) (w http.ResponseWriter, finish func(), replayed bool, err error) {
+	key := req.Header.Get(idempotencyKeyHeader)
+	if key == "" || s.agent.idempotencyKeys == nil {
+		return resp, nil, false, nil
+	}
+	switch req.Method {
+	case "PUT", "POST", "DELETE":
+	default:
+		return resp, nil, false, nil
+	}
+	if len(key) > idempotencyKeyMaxLen {
+		return resp, nil, false, BadRequestError{Reason: fmt.Sprintf("%s cannot be longer than %d characters", idempotencyKeyHeader, idempotencyKeyMaxLen)}
+	}
+
+	// The body is read ahead to fingerprint the request, and handed on to
+	// the endpoint.
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return resp, nil, false, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// Keys are scoped to the token, so that one client can't see the
+	// responses of another one.
+	var token string
+	s.parseTokenInternal(req, &token, false)
+	scope := sha256.Sum256([]byte(token))
+	storeKey := hex.EncodeToString(scope[:]) + "/" + key
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %s\n", req.Method, req.URL.Path, req.URL.RawQuery)
+	h.Write(body)
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	r, first, err := s.agent.idempotencyKeys.Begin(storeKey, fingerprint)
+	switch {
+	case err == errIdempotencyKeyReused:
+		return resp, nil, false, BadRequestError{Reason: err.Error()}
+	case err != nil:
+		return resp, nil, false, err
+	}
+
+	if !first {
+		select {
+		case <-r.done:
+			r.replay(resp)
+		case <-req.Context().Done():
+		}
+		return resp, nil, true, nil
+	}
+
+	rw := &idempotentResponseWriter{ResponseWriter: resp}
+	finish = func() {
+		r.status = rw.status
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		r.header = make(http.Header, len(resp.Header()))
+		for k, v := range resp.Header() {
+			r.header[k] = v
+		}
+		r.body = rw.body.Bytes()
+
+		// Failures which may be temporary are not kept, so that the
+		// client can retry them.
+		keep := r.status < 500 && r.status != http.StatusTooManyRequests
+		s.agent.idempotencyKeys.Finish(storeKey, r, keep)
+	}
+	return rw, finish, false, nil
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyStore(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	s := newIdempotencyKeyStore(20*time.Millisecond, 2)
+	r, first, err := s.Begin("a", "req1")
+	require.NoError(err)
+	require.True(first)
+
+	// A retry in progress waits for the first response.
+	r2, first, err := s.Begin("a", "req1")
+	require.NoError(err)
+	require.False(first)
+	require.True(r == r2)
+
+	// The same key for another request is an error.
+	_, _, err = s.Begin("a", "req2")
+	require.Equal(errIdempotencyKeyReused, err)
+
+	// Responses in progress are never pruned, so the store is full.
+	_, _, err = s.Begin("b", "req1")
+	require.NoError(err)
+	_, _, err = s.Begin("c", "req1")
+	require.Equal(errIdempotencyKeysFull, err)
+
+	s.Finish("a", r, true)
+	select {
+	case <-r.done:
+	default:
+		t.Fatal("response should be done")
+	}
+
+	// Completed responses expire.
+	time.Sleep(30 * time.Millisecond)
+	_, first, err = s.Begin("a", "req2")
+	require.NoError(err)
+	require.True(first)
+
+	// Responses which aren't kept are handled again.
+	s = newIdempotencyKeyStore(time.Minute, 2)
+	r, _, err = s.Begin("a", "req1")
+	require.NoError(err)
+	s.Finish("a", r, false)
+	_, first, err = s.Begin("a", "req1")
+	require.NoError(err)
+	require.True(first)
+}
+
+func TestHTTPAPI_IdempotencyKey(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	calls := 0
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		calls++
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if string(body) == "fail" {
+			return nil, fmt.Errorf("failed")
+		}
+		resp.Header().Set("X-Test", "yes")
+		return fmt.Sprintf("call %d", calls), nil
+	}
+	do := func(method, key, token, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/v1/kv/test", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		if token != "" {
+			req.Header.Set("X-Consul-Token", token)
+		}
+		resp := httptest.NewRecorder()
+		a.srv.wrap(handler, []string{"GET", "PUT"})(resp, req)
+		return resp
+	}
+
+	t.Run("retry is replayed", func(t *testing.T) {
+		resp := do("PUT", "k1", "", "value")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `"call 1"`, resp.Body.String())
+
+		resp = do("PUT", "k1", "", "value")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `"call 1"`, resp.Body.String())
+		require.Equal(t, "yes", resp.Header().Get("X-Test"))
+		require.Equal(t, "true", resp.Header().Get("X-Consul-Idempotent-Replay"))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("key with another request", func(t *testing.T) {
+		resp := do("PUT", "k1", "", "other value")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "already used for a different request")
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("keys are scoped to the token", func(t *testing.T) {
+		resp := do("PUT", "k1", "other-token", "value")
+		require.Equal(t, `"call 2"`, resp.Body.String())
+		require.Empty(t, resp.Header().Get("X-Consul-Idempotent-Replay"))
+	})
+
+	t.Run("reads and requests without a key are not deduplicated", func(t *testing.T) {
+		do("GET", "k2", "", "")
+		do("GET", "k2", "", "")
+		do("PUT", "", "", "value")
+		do("PUT", "", "", "value")
+		require.Equal(t, 6, calls)
+	})
+
+	t.Run("errors are handled again", func(t *testing.T) {
+		resp := do("PUT", "k3", "", "fail")
+		require.Equal(t, http.StatusInternalServerError, resp.Code)
+		resp = do("PUT", "k3", "", "fail")
+		require.Equal(t, http.StatusInternalServerError, resp.Code)
+		require.Empty(t, resp.Header().Get("X-Consul-Idempotent-Replay"))
+		require.Equal(t, 8, calls)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		resp := do("PUT", strings.Repeat("k", idempotencyKeyMaxLen+1), "", "value")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Equal(t, 8, calls)
+	})
+}
@@ -173,6 +173,63 @@ func (s *HTTPServer) IntentionCheck(resp http.ResponseWriter, req *http.Request)
 	return &reply, nil
 }
 
+// PUT /v1/connect/intentions/impact
+func (s *HTTPServer) IntentionImpact(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// The proposed intention is a new one without an ID, an update of the
+	// intention with its ID, or the deletion of it with the delete parameter.
+	args := structs.IntentionRequest{}
+	s.parseDC(req, &args.Datacenter)
+	s.parseToken(req, &args.Token)
+	if err := decodeBody(req, &args.Intention, nil); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		return nil, nil
+	}
+	if args.Intention == nil {
+		args.Intention = &structs.Intention{}
+	}
+
+	_, del := req.URL.Query()["delete"]
+	switch {
+	case del:
+		args.Op = structs.IntentionOpDelete
+	case args.Intention.ID == "":
+		args.Op = structs.IntentionOpCreate
+	default:
+		args.Op = structs.IntentionOpUpdate
+	}
+
+	var reply structs.IntentionImpactResponse
+	if err := s.agent.RPC("Intention.Impact", &args, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Allowed == nil {
+		reply.Allowed = make([]structs.IntentionConnection, 0)
+	}
+	if reply.Denied == nil {
+		reply.Denied = make([]structs.IntentionConnection, 0)
+	}
+
+	return reply, nil
+}
+
+// GET /v1/connect/intentions/replication
+func (s *HTTPServer) IntentionReplicationStatus(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Note that we do not forward to the primary datacenter here. This is
+	// a query for any datacenter that's doing replication.
+	args := structs.DCSpecificRequest{}
+	s.parseSource(req, &args.Source)
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IntentionReplicationStatus
+	if err := s.agent.RPC("Intention.ReplicationStatus", &args, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IntentionSpecific handles the endpoint for /v1/connection/intentions/:id
 func (s *HTTPServer) IntentionSpecific(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	id := strings.TrimPrefix(req.URL.Path, "/v1/connect/intentions/")
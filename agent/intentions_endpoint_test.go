@@ -274,6 +274,39 @@ func TestIntentionsCheck_noDestination(t *testing.T) {
 	require.Nil(obj)
 }
 
+func TestIntentionsImpact_basic(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	// Register the proxies of the services of the mesh
+	for _, name := range []string{"web", "db"} {
+		args := structs.TestRegisterRequestProxy(t)
+		args.Service.Service = name + "-proxy"
+		args.Service.Proxy.DestinationServiceName = name
+		var out struct{}
+		require.Nil(a.RPC("Catalog.Register", args, &out))
+	}
+
+	ixn := structs.TestIntention(t)
+	ixn.SourceNS = structs.IntentionDefaultNamespace
+	ixn.SourceName = "web"
+	ixn.DestinationNS = structs.IntentionDefaultNamespace
+	ixn.Action = structs.IntentionActionDeny
+
+	req, _ := http.NewRequest("PUT", "/v1/connect/intentions/impact", jsonReader(ixn))
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.IntentionImpact(resp, req)
+	require.Nil(err)
+	value := obj.(structs.IntentionImpactResponse)
+	require.Empty(value.Allowed)
+	require.Equal([]structs.IntentionConnection{
+		{SourceName: "web", DestinationName: "db"},
+	}, value.Denied)
+}
+
 func TestIntentionsCreate_good(t *testing.T) {
 	t.Parallel()
 
@@ -517,3 +550,20 @@ func TestParseIntentionMatchEntry(t *testing.T) {
 		})
 	}
 }
+
+func TestIntentionsReplicationStatus(t *testing.T) {
+	t.Parallel()
+
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	// The intentions of the primary datacenter aren't replicated.
+	req, _ := http.NewRequest("GET", "/v1/connect/intentions/replication", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.IntentionReplicationStatus(resp, req)
+	require.NoError(t, err)
+
+	status := obj.(structs.IntentionReplicationStatus)
+	require.False(t, status.Enabled)
+	require.False(t, status.Running)
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib"
+)
+
+// introductionRetryInterval is the base interval between attempts to
+// exchange the introduction token for an agent token after an error.
+const introductionRetryInterval = 10 * time.Second
+
+// introduceAgent exchanges the configured introduction token for an agent
+// token with the node identity of the agent. It retries until it succeeds or
+// the agent is shut down, since the servers may not be known yet right after
+// the agent has started. The new agent token is persisted, which the config
+// builder enforces, so the introduction only happens on the first start of
+// the agent.
+func (a *Agent) introduceAgent() {
+	var out structs.ACLToken
+	for {
+		args := structs.ACLAgentIntroduceRequest{
+			Datacenter: a.config.Datacenter,
+			NodeIdentity: structs.ACLNodeIdentity{
+				NodeName:   a.config.NodeName,
+				Datacenter: a.config.Datacenter,
+			},
+			WriteRequest: structs.WriteRequest{Token: a.config.ACLIntroductionToken},
+		}
+		err := a.RPC("ACL.AgentIntroduce", &args, &out)
+		if err == nil {
+			break
+		}
+
+		a.logger.Printf("[WARN] agent: Failed to exchange the introduction token for an agent token: %v", err)
+		select {
+		case <-time.After(introductionRetryInterval + lib.RandomStagger(introductionRetryInterval)):
+		case <-a.shutdownCh:
+			return
+		}
+	}
+
+	// A token set with the API in the meantime takes precedence.
+	if a.tokens.HasAgentToken() {
+		a.logger.Printf("[INFO] agent: Discarding introduced agent token %s since an agent token was set", out.AccessorID)
+		a.revokeIntroducedToken(out.AccessorID)
+		return
+	}
+	a.tokens.UpdateAgentToken(out.SecretID)
+	a.logger.Printf("[INFO] agent: Introduced node %q with agent token %s", a.config.NodeName, out.AccessorID)
+
+	if err := a.persistToken("acl_agent_token", out.SecretID); err != nil {
+		a.logger.Printf("[ERR] agent: Failed to persist the introduced agent token: %v", err)
+	}
+
+	// Register the node with the new token right away.
+	a.sync.SyncFull.Trigger()
+}
+
+// revokeIntroducedToken deletes an introduced agent token which is not used
+// by the agent. It retries until it succeeds or the agent is shut down.
+func (a *Agent) revokeIntroducedToken(accessorID string) {
+	for {
+		args := structs.ACLTokenDeleteRequest{
+			Datacenter:   a.config.Datacenter,
+			TokenID:      accessorID,
+			WriteRequest: structs.WriteRequest{Token: a.config.ACLIntroductionToken},
+		}
+		var out string
+		err := a.RPC("ACL.AgentIntroduceRevoke", &args, &out)
+		if err == nil {
+			return
+		}
+
+		a.logger.Printf("[WARN] agent: Failed to delete the discarded agent token %s: %v", accessorID, err)
+		select {
+		case <-time.After(introductionRetryInterval + lib.RandomStagger(introductionRetryInterval)):
+		case <-a.shutdownCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_IntroduceAgent(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		node_name = "web-1"
+		primary_datacenter = "dc1"
+		acl {
+			enabled = true
+			default_policy = "deny"
+			enable_token_persistence = true
+			tokens {
+				master = "root"
+				introduction = "root"
+				agent = "root"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// The configured agent token keeps the agent from introducing itself in
+	// the background on start, so the introduction can run synchronously.
+	a.tokens.UpdateAgentToken("")
+	a.introduceAgent()
+	require.True(t, a.tokens.HasAgentToken())
+	secret := a.tokens.AgentToken()
+
+	req := structs.ACLTokenReadRequest{
+		Datacenter:   "dc1",
+		TokenID:      secret,
+		TokenIDType:  structs.ACLTokenSecret,
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	var resp structs.ACLTokenResponse
+	require.NoError(t, a.RPC("ACL.TokenRead", &req, &resp))
+	require.NotNil(t, resp.Token)
+	require.True(t, resp.Token.Local)
+	require.Equal(t, []*structs.ACLNodeIdentity{
+		&structs.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+	}, resp.Token.NodeIdentities)
+
+	// The agent token is persisted so it survives a restart
+	tokens, err := a.readPersistedTokens()
+	require.NoError(t, err)
+	require.Equal(t, secret, tokens["acl_agent_token"])
+}
+
+func TestAgent_IntroduceAgent_Discard(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		node_name = "web-1"
+		primary_datacenter = "dc1"
+		acl {
+			enabled = true
+			default_policy = "deny"
+			enable_token_persistence = true
+			tokens {
+				master = "root"
+				introduction = "root"
+				agent = "root"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// An agent token set with the API while the introduction was in flight
+	// wins and the introduced token is deleted. The configured agent token
+	// keeps the agent from introducing itself in the background on start.
+	a.introduceAgent()
+	require.Equal(t, "root", a.tokens.AgentToken())
+
+	req := structs.ACLTokenListRequest{
+		Datacenter:    "dc1",
+		IncludeLocal:  true,
+		IncludeGlobal: true,
+		QueryOptions:  structs.QueryOptions{Token: "root"},
+	}
+	var resp structs.ACLTokenListResponse
+	require.NoError(t, a.RPC("ACL.TokenList", &req, &resp))
+	for _, token := range resp.Tokens {
+		require.Empty(t, token.NodeIdentities, "introduced token %s was not deleted", token.AccessorID)
+	}
+}
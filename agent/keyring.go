@@ -138,6 +138,14 @@ func (a *Agent) ListKeys(token string, relayFactor uint8) (*structs.KeyringRespo
 	return a.keyringProcess(&args)
 }
 
+// ListKeysDetailed lists the keys like ListKeys and also returns the keys
+// installed on every node and alerts about the state of the keyrings.
+func (a *Agent) ListKeysDetailed(token string, relayFactor uint8) (*structs.KeyringResponses, error) {
+	args := structs.KeyringRequest{Operation: structs.KeyringList, Detailed: true}
+	parseKeyringRequest(&args, token, relayFactor)
+	return a.keyringProcess(&args)
+}
+
 // InstallKey installs a new gossip encryption key
 func (a *Agent) InstallKey(key, token string, relayFactor uint8) (*structs.KeyringResponses, error) {
 	args := structs.KeyringRequest{Key: key, Operation: structs.KeyringInstall}
@@ -200,6 +200,7 @@ func (s *HTTPServer) KVSPut(resp http.ResponseWriter, req *http.Request, args *s
 	if err := s.agent.RPC("KVS.Apply", &applyReq, &out); err != nil {
 		return nil, err
 	}
+	s.setWriteIndex(resp, applyReq.Datacenter)
 
 	// Only use the out value if this was a CAS
 	if applyReq.Op == api.KVSet {
@@ -245,6 +246,7 @@ func (s *HTTPServer) KVSDelete(resp http.ResponseWriter, req *http.Request, args
 	if err := s.agent.RPC("KVS.Apply", &applyReq, &out); err != nil {
 		return nil, err
 	}
+	s.setWriteIndex(resp, applyReq.Datacenter)
 
 	// Only use the out value if this was a CAS
 	if applyReq.Op == api.KVDeleteCAS {
@@ -281,3 +283,89 @@ func conflictingFlags(resp http.ResponseWriter, req *http.Request, flags ...stri
 
 	return false
 }
+
+// KVSLocks lists the keys which are locked by sessions, under a lock-delay or
+// have sessions waiting to acquire them
+func (s *HTTPServer) KVSLocks(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.DCSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IndexedKeyLocks
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Internal.KeyLocks", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Locks == nil {
+		out.Locks = make(structs.KeyLocks, 0)
+	}
+	return out.Locks, nil
+}
+
+// KVSRecycleBin lists, restores or purges the entries of the KV recycle bin
+func (s *HTTPServer) KVSRecycleBin(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Set default DC
+	args := structs.KeyRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	// Pull out the key name, validation left to each sub-handler
+	args.Key = strings.TrimPrefix(req.URL.Path, "/v1/kv-restore/")
+
+	// Switch on the method
+	switch req.Method {
+	case "GET":
+		return s.KVSRecycled(resp, req, &args)
+	case "PUT":
+		return s.KVSRecycleBinApply(resp, req, &args, structs.KVSRecycleBinRestore)
+	case "DELETE":
+		return s.KVSRecycleBinApply(resp, req, &args, structs.KVSRecycleBinPurge)
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT", "DELETE"}}
+	}
+}
+
+// KVSRecycled lists the entries of the recycle bin with the given prefix
+func (s *HTTPServer) KVSRecycled(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest) (interface{}, error) {
+	var out structs.IndexedRecycledDirEntries
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("KVS.ListRecycled", args, &out); err != nil {
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Entries == nil {
+		out.Entries = make(structs.RecycledDirEntries, 0)
+	}
+	return out.Entries, nil
+}
+
+// KVSRecycleBinApply restores or purges the recycled entry of a key, or of
+// all the keys with the given prefix if recurse is set
+func (s *HTTPServer) KVSRecycleBinApply(resp http.ResponseWriter, req *http.Request, args *structs.KeyRequest, op structs.KVSRecycleBinOp) (interface{}, error) {
+	applyReq := structs.KVSRecycleBinRequest{
+		Datacenter: args.Datacenter,
+		Op:         op,
+		Key:        args.Key,
+	}
+	applyReq.Token = args.Token
+
+	// Check for recurse
+	params := req.URL.Query()
+	if _, ok := params["recurse"]; ok {
+		applyReq.Recurse = true
+	} else if missingKey(resp, args) {
+		return nil, nil
+	}
+
+	// Make the RPC
+	var out int
+	if err := s.agent.RPC("KVS.RecycleBin", &applyReq, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
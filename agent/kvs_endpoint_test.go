@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/hashicorp/consul/testrpc"
@@ -72,6 +73,37 @@ func TestKVSEndpoint_PUT_GET_DELETE(t *testing.T) {
 	}
 }
 
+func TestKVSEndpoint_WriteIndex(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	buf := bytes.NewBuffer([]byte("test"))
+	req, _ := http.NewRequest("PUT", "/v1/kv/foo", buf)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.KVSEndpoint(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	index, err := strconv.ParseUint(resp.Header().Get("X-Consul-Write-Index"), 10, 64)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A read which requires the write index sees the write.
+	req, _ = http.NewRequest("GET", "/v1/kv/foo?stale", nil)
+	req.Header.Set("X-Consul-MinIndex", strconv.FormatUint(index, 10))
+	resp = httptest.NewRecorder()
+	obj, err := a.srv.KVSEndpoint(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	res := obj.(structs.DirEntries)
+	if len(res) != 1 || res[0].ModifyIndex > index {
+		t.Fatalf("bad: %v", res)
+	}
+}
+
 func TestKVSEndpoint_Recurse(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -428,6 +460,122 @@ func TestKVSEndpoint_GET_Raw(t *testing.T) {
 	}
 }
 
+func TestKVSEndpoint_Locks(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Acquire the lock and fail to acquire it with another session
+	id := makeTestSession(t, a.srv)
+	waiter := makeTestSession(t, a.srv)
+	for _, session := range []string{id, waiter} {
+		req, _ := http.NewRequest("PUT", "/v1/kv/test?acquire="+session, bytes.NewReader(nil))
+		if _, err := a.srv.KVSEndpoint(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/internal/locks", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSLocks(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertIndex(t, resp)
+
+	locks := obj.(structs.KeyLocks)
+	if len(locks) != 1 {
+		t.Fatalf("bad: %v", locks)
+	}
+	if l := locks[0]; l.Key != "test" || l.Session != id || l.Node != a.Config.NodeName ||
+		l.Waiters != 1 || l.AcquiredAt.IsZero() {
+		t.Fatalf("bad: %#v", l)
+	}
+}
+
+func TestKVSEndpoint_RecycleBin(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `kv_recycle_bin_retention = "1h"`)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	keys := []string{"foo/a", "foo/b", "zip"}
+	for _, key := range keys {
+		buf := bytes.NewBuffer([]byte("test"))
+		req, _ := http.NewRequest("PUT", "/v1/kv/"+key, buf)
+		if _, err := a.srv.KVSEndpoint(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req, _ = http.NewRequest("DELETE", "/v1/kv/"+key, nil)
+		if _, err := a.srv.KVSEndpoint(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// List the deleted keys with a prefix.
+	req, _ := http.NewRequest("GET", "/v1/kv-restore/foo", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.KVSRecycleBin(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertIndex(t, resp)
+
+	entries := obj.(structs.RecycledDirEntries)
+	if len(entries) != 2 || entries[0].Key != "foo/a" || entries[1].Key != "foo/b" {
+		t.Fatalf("bad: %v", entries)
+	}
+
+	// Restoring a single key requires a key.
+	req, _ = http.NewRequest("PUT", "/v1/kv-restore/", nil)
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.KVSRecycleBin(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != 400 {
+		t.Fatalf("expected 400, got %d", resp.Code)
+	}
+
+	// Restore the prefix.
+	req, _ = http.NewRequest("PUT", "/v1/kv-restore/foo?recurse", nil)
+	obj, err = a.srv.KVSRecycleBin(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := obj.(int); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+	req, _ = http.NewRequest("GET", "/v1/kv/foo?recurse", nil)
+	obj, err = a.srv.KVSEndpoint(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if res := obj.(structs.DirEntries); len(res) != 2 {
+		t.Fatalf("bad: %v", res)
+	}
+
+	// Purge the remaining key.
+	req, _ = http.NewRequest("DELETE", "/v1/kv-restore/zip", nil)
+	obj, err = a.srv.KVSRecycleBin(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := obj.(int); n != 1 {
+		t.Fatalf("bad: %d", n)
+	}
+	req, _ = http.NewRequest("GET", "/v1/kv-restore/", nil)
+	obj, err = a.srv.KVSRecycleBin(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entries := obj.(structs.RecycledDirEntries); len(entries) != 0 {
+		t.Fatalf("bad: %v", entries)
+	}
+}
+
 func TestKVSEndpoint_PUT_ConflictingFlags(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), "")
@@ -93,6 +93,16 @@ type CheckState struct {
 	// Deleted is true when the health check record has been marked as
 	// deleted but has not been removed on the server yet.
 	Deleted bool
+
+	// LastRun is the time the check last ran and LastRunDuration how long
+	// it took. For TTL checks this is the time of the last update. Both
+	// are zero for checks which don't report their runs.
+	LastRun         time.Time
+	LastRunDuration time.Duration
+
+	// StatusChanges is the number of times the status of the check changed
+	// since it was added. Frequent changes show a flapping check.
+	StatusChanges int
 }
 
 // Clone returns a shallow copy of the object. The check record and the
@@ -164,6 +174,13 @@ type State struct {
 	// created.
 	TriggerSyncChanges func()
 
+	// CheckStatusChanged is called with a copy of a local check and its
+	// previous status when the status of the check changes. It is called
+	// with the lock held and must not block.
+	//
+	// It is optional and set before the checks are started.
+	CheckStatusChanged func(check *structs.HealthCheck, previous string)
+
 	logger *log.Logger
 
 	// Config is the agent config
@@ -498,6 +515,20 @@ func (l *State) RemoveCheck(id types.CheckID) error {
 	return nil
 }
 
+// CheckRun is used to record a run of a check. This allows us to export
+// the execution of checks, e.g. to detect checks which time out.
+func (l *State) CheckRun(id types.CheckID, start time.Time, duration time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	c := l.checks[id]
+	if c == nil || c.Deleted {
+		return
+	}
+	c.LastRun = start
+	c.LastRunDuration = duration
+}
+
 // UpdateCheck is used to update the status of a check
 func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 	l.Lock()
@@ -512,6 +543,10 @@ func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 		output = ""
 	}
 
+	if c.Check.Status != status {
+		c.StatusChanges++
+	}
+
 	// Update the critical time tracking (this doesn't cause a server updates
 	// so we can always keep this up to date).
 	if status == api.HealthCritical {
@@ -570,10 +605,15 @@ func (l *State) UpdateCheck(id types.CheckID, status, output string) {
 	}
 
 	// Update status and mark out of sync
+	previous := c.Check.Status
 	c.Check.Status = status
 	c.Check.Output = output
 	c.InSync = false
 	l.TriggerSyncChanges()
+
+	if previous != status && l.CheckStatusChanged != nil {
+		l.CheckStatusChanged(c.Check.Clone(), previous)
+	}
 }
 
 // Check returns the locally registered check that the
@@ -924,6 +964,7 @@ func (l *State) LoadMetadata(data map[string]string) error {
 	for k, v := range data {
 		l.metadata[k] = v
 	}
+	l.nodeInfoInSync = false
 	l.TriggerSyncChanges()
 	return nil
 }
@@ -1379,6 +1420,31 @@ func (l *State) syncCheck(id types.CheckID) error {
 	}
 }
 
+// SyncNodeInfoRetained registers the node info with the MetaRetainServicesKey
+// set, so that the servers retain the services of the node when it leaves.
+// Anti-entropy must be paused, otherwise the next sync removes the key.
+func (l *State) SyncNodeInfoRetained() error {
+	l.RLock()
+	meta := make(map[string]string, len(l.metadata)+1)
+	for k, v := range l.metadata {
+		meta[k] = v
+	}
+	l.RUnlock()
+	meta[structs.MetaRetainServicesKey] = "true"
+
+	req := structs.RegisterRequest{
+		Datacenter:      l.config.Datacenter,
+		ID:              l.config.NodeID,
+		Node:            l.config.NodeName,
+		Address:         l.config.AdvertiseAddr,
+		TaggedAddresses: l.config.TaggedAddresses,
+		NodeMeta:        meta,
+		WriteRequest:    structs.WriteRequest{Token: l.tokens.AgentToken()},
+	}
+	var out struct{}
+	return l.Delegate.RPC("Catalog.Register", &req, &out)
+}
+
 func (l *State) syncNodeInfo() error {
 	req := structs.RegisterRequest{
 		Datacenter:      l.config.Datacenter,
@@ -1023,6 +1023,11 @@ func TestAgentAntiEntropy_Checks(t *testing.T) {
 				t.Fatalf("bad: %v %v", chk, chk1)
 			}
 		case "redis":
+			// The servers remember the status the sync replaced.
+			if chk.PreviousStatus != api.HealthCritical {
+				t.Fatalf("bad: %v", chk)
+			}
+			chk.PreviousStatus = ""
 			if !reflect.DeepEqual(chk, chk2) {
 				t.Fatalf("bad: %v %v", chk, chk2)
 			}
@@ -1763,6 +1768,40 @@ func TestAgent_CheckCriticalTime(t *testing.T) {
 	}
 }
 
+func TestAgent_CheckRuns(t *testing.T) {
+	t.Parallel()
+	cfg := config.DefaultRuntimeConfig(`bind_addr = "127.0.0.1" data_dir = "dummy"`)
+	l := local.NewState(agent.LocalConfig(cfg), nil, new(token.Store))
+	l.TriggerSyncChanges = func() {}
+
+	checkID := types.CheckID("web")
+	l.AddCheck(&structs.HealthCheck{
+		Node:    "node",
+		CheckID: checkID,
+		Name:    "web",
+		Status:  api.HealthCritical,
+	}, "")
+
+	// Only changes of the status are counted.
+	l.UpdateCheck(checkID, api.HealthPassing, "")
+	l.UpdateCheck(checkID, api.HealthPassing, "still ok")
+	l.UpdateCheck(checkID, api.HealthWarning, "")
+	l.UpdateCheck(checkID, api.HealthPassing, "")
+
+	start := time.Now()
+	l.CheckRun(checkID, start, 250*time.Millisecond)
+
+	c := l.CheckStates()[checkID]
+	require.NotNil(t, c)
+	require.Equal(t, 3, c.StatusChanges)
+	require.Equal(t, start, c.LastRun)
+	require.Equal(t, 250*time.Millisecond, c.LastRunDuration)
+
+	// Runs of unknown checks are ignored.
+	l.CheckRun("unknown", start, time.Second)
+	require.NotContains(t, l.CheckStates(), types.CheckID("unknown"))
+}
+
 func TestAgent_AddCheckFailure(t *testing.T) {
 	t.Parallel()
 	cfg := config.DefaultRuntimeConfig(`bind_addr = "127.0.0.1" data_dir = "dummy"`)
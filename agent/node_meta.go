@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+// nodeMetaFile is the name of the file in the data dir which contains the
+// node metadata set with the /v1/agent/node-meta API.
+const nodeMetaFile = "node-meta.json"
+
+// readPersistedNodeMeta returns the node metadata stored in the node meta
+// file. A missing file is not an error.
+func (a *Agent) readPersistedNodeMeta() (map[string]string, error) {
+	path := filepath.Join(a.config.DataDir, nodeMetaFile)
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]string{}
+	if err := json.Unmarshal(buf, &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// loadMetadata loads node metadata fields from the agent config and the
+// node metadata set with the API, which takes precedence.
+func (a *Agent) loadMetadata(conf *config.RuntimeConfig) error {
+	a.nodeMetaLock.Lock()
+	defer a.nodeMetaLock.Unlock()
+
+	a.configNodeMeta = conf.NodeMeta
+	persisted, err := a.readPersistedNodeMeta()
+	if err != nil {
+		a.logger.Printf("[WARN] agent: Failed to load persisted node metadata: %v", err)
+		persisted = nil
+	}
+	return a.State.LoadMetadata(a.mergeNodeMeta(persisted))
+}
+
+// mergeNodeMeta returns the node metadata from the agent config merged
+// with the given metadata set with the API.
+func (a *Agent) mergeNodeMeta(persisted map[string]string) map[string]string {
+	meta := map[string]string{}
+	for k, v := range a.configNodeMeta {
+		meta[k] = v
+	}
+	for k, v := range persisted {
+		meta[k] = v
+	}
+	meta[structs.MetaSegmentKey] = a.config.SegmentName
+	return meta
+}
+
+// unloadMetadata resets the local metadata state
+func (a *Agent) unloadMetadata() {
+	a.State.UnloadMetadata()
+}
+
+// updateNodeMeta sets and removes node metadata fields of the local node
+// at runtime. The changes are stored in the data dir so that they survive
+// reloads and restarts of the agent. Removing a field which is also set in
+// the agent config restores the configured value. It returns the
+// resulting node metadata.
+func (a *Agent) updateNodeMeta(set map[string]string, remove []string) (map[string]string, error) {
+	if err := structs.ValidateMetadata(set, false); err != nil {
+		return nil, err
+	}
+
+	a.nodeMetaLock.Lock()
+	defer a.nodeMetaLock.Unlock()
+
+	persisted, err := a.readPersistedNodeMeta()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read persisted node metadata: %v", err)
+	}
+	for _, k := range remove {
+		delete(persisted, k)
+	}
+	for k, v := range set {
+		persisted[k] = v
+	}
+
+	meta := a.mergeNodeMeta(persisted)
+	delete(meta, structs.MetaSegmentKey)
+	if err := structs.ValidateMetadata(meta, false); err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(persisted)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.WriteAtomic(filepath.Join(a.config.DataDir, nodeMetaFile), buf); err != nil {
+		return nil, fmt.Errorf("Failed to persist node metadata: %v", err)
+	}
+
+	// Replace the metadata without syncing the empty state in between.
+	a.PauseSync()
+	defer a.ResumeSync()
+	a.State.UnloadMetadata()
+	if err := a.State.LoadMetadata(a.mergeNodeMeta(persisted)); err != nil {
+		return nil, err
+	}
+	return a.State.Metadata(), nil
+}
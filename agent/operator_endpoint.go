@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/consul/autopilot"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
@@ -129,9 +130,14 @@ func (s *HTTPServer) KeyringInstall(resp http.ResponseWriter, req *http.Request,
 	return nil, keyringErrorsOrNil(responses.Responses)
 }
 
-// KeyringList is used to list the keys installed in the cluster
+// KeyringList is used to list the keys installed in the cluster. With the
+// detailed parameter, the keys of every node and alerts are included.
 func (s *HTTPServer) KeyringList(resp http.ResponseWriter, req *http.Request, args *keyringArgs) (interface{}, error) {
-	responses, err := s.agent.ListKeys(args.Token, args.RelayFactor)
+	list := s.agent.ListKeys
+	if _, ok := req.URL.Query()["detailed"]; ok {
+		list = s.agent.ListKeysDetailed
+	}
+	responses, err := list(args.Token, args.RelayFactor)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +264,111 @@ func (s *HTTPServer) OperatorAutopilotConfiguration(resp http.ResponseWriter, re
 	}
 }
 
+// OperatorUIConfiguration is used to inspect and update the settings of the
+// web UI. Reading them requires no ACL since the UI reads them for every user.
+func (s *HTTPServer) OperatorUIConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Switch on the method
+	switch req.Method {
+	case "GET":
+		var args structs.DCSpecificRequest
+		if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+			return nil, nil
+		}
+
+		var reply structs.IndexedUIConfig
+		defer setMeta(resp, &reply.QueryMeta)
+		if err := s.agent.RPC("Operator.UIConfigGet", &args, &reply); err != nil {
+			return nil, err
+		}
+
+		out := reply.Config
+		if out == nil {
+			out = &structs.UIConfig{}
+		}
+		if out.DashboardURLTemplates == nil {
+			out.DashboardURLTemplates = make(map[string]string)
+		}
+		return out, nil
+
+	case "PUT":
+		var args structs.UIConfigSetRequest
+		s.parseDC(req, &args.Datacenter)
+		s.parseToken(req, &args.Token)
+
+		if err := decodeBody(req, &args.Config, nil); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Error parsing UI config: %v", err)
+			return nil, nil
+		}
+		args.Config.RaftIndex = structs.RaftIndex{}
+		if err := args.Config.Validate(); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Invalid UI config: %v", err)
+			return nil, nil
+		}
+
+		// Check for cas value
+		params := req.URL.Query()
+		if _, ok := params["cas"]; ok {
+			casVal, err := strconv.ParseUint(params.Get("cas"), 10, 64)
+			if err != nil {
+				resp.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(resp, "Error parsing cas value: %v", err)
+				return nil, nil
+			}
+			args.Config.ModifyIndex = casVal
+			args.CAS = true
+		}
+
+		var reply bool
+		if err := s.agent.RPC("Operator.UIConfigSet", &args, &reply); err != nil {
+			return nil, err
+		}
+
+		// Only use the out value if this was a CAS
+		if !args.CAS {
+			return true, nil
+		}
+		return reply, nil
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT"}}
+	}
+}
+
+// OperatorKVReplication is used to get the status of the replication of KV
+// prefixes from the primary datacenter.
+func (s *HTTPServer) OperatorKVReplication(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var args structs.DCSpecificRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var reply structs.KVReplicationStatus
+	if err := s.agent.RPC("Operator.KVReplicationStatus", &args, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Prefixes == nil {
+		reply.Prefixes = make([]*structs.KVReplicationPrefixStatus, 0)
+	}
+	return reply, nil
+}
+
+// OperatorKeyringRotation is used to get the status of the automatic gossip
+// key rotation.
+func (s *HTTPServer) OperatorKeyringRotation(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var args structs.DCSpecificRequest
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var reply structs.GossipKeyRotationStatus
+	if err := s.agent.RPC("Operator.KeyringRotationStatus", &args, &reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
 // OperatorServerHealth is used to get the health of the servers in the local DC
 func (s *HTTPServer) OperatorServerHealth(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var args structs.DCSpecificRequest
@@ -298,3 +409,43 @@ func (s *HTTPServer) OperatorServerHealth(resp http.ResponseWriter, req *http.Re
 
 	return out, nil
 }
+
+// OperatorGossip is used to inspect the gossip settings of the agent and to
+// select the gossip profiles of its pools.
+func (s *HTTPServer) OperatorGossip(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case "GET":
+		if rule != nil && !rule.OperatorRead() {
+			return nil, acl.PermissionDenied("operator", "", "read")
+		}
+		return s.agent.gossipPools()
+
+	case "PUT":
+		if rule != nil && !rule.OperatorWrite() {
+			return nil, acl.PermissionDenied("operator", "", "write")
+		}
+
+		var args api.GossipProfileRequest
+		if err := decodeBody(req, &args, nil); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(resp, "Error parsing gossip profile: %v", err)
+			return nil, nil
+		}
+		if err := s.agent.setGossipProfile(args.Pool, args.Profile); err != nil {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(resp, err.Error())
+			return nil, nil
+		}
+		return s.agent.gossipPool(args.Pool)
+
+	default:
+		return nil, MethodNotAllowedError{req.Method, []string{"GET", "PUT"}}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/testrpc"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOperator_RaftConfiguration(t *testing.T) {
@@ -161,6 +163,69 @@ func TestOperator_KeyringList(t *testing.T) {
 	}
 }
 
+func TestOperator_KeyringListDetailed(t *testing.T) {
+	t.Parallel()
+	key := "H3/9gBxcKKRf45CaI2DlRg=="
+	a := NewTestAgent(t.Name(), `
+		encrypt = "`+key+`"
+	`)
+	defer a.Shutdown()
+
+	req, _ := http.NewRequest("GET", "/v1/operator/keyring?detailed=true", nil)
+	resp := httptest.NewRecorder()
+	r, err := a.srv.OperatorKeyringEndpoint(resp, req)
+	require.NoError(t, err)
+	responses, ok := r.([]*structs.KeyringResponse)
+	require.True(t, ok)
+	require.Len(t, responses, 2)
+	for _, response := range responses {
+		require.Equal(t, map[string]int{key: 1}, response.PrimaryKeys)
+		require.Len(t, response.NodeKeys, 1)
+		for _, keys := range response.NodeKeys {
+			require.Equal(t, []string{key}, keys)
+		}
+		require.Empty(t, response.Alerts)
+	}
+
+	// Without the parameter the keys of the nodes aren't returned.
+	req, _ = http.NewRequest("GET", "/v1/operator/keyring", nil)
+	r, err = a.srv.OperatorKeyringEndpoint(httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	for _, response := range r.([]*structs.KeyringResponse) {
+		require.Equal(t, map[string]int{key: 1}, response.PrimaryKeys)
+		require.Nil(t, response.NodeKeys)
+	}
+}
+
+func TestOperator_KeyringRotation(t *testing.T) {
+	t.Parallel()
+	key := "H3/9gBxcKKRf45CaI2DlRg=="
+	a := NewTestAgent(t.Name(), `
+		encrypt = "`+key+`"
+		gossip_key_rotation {
+			interval = "720h"
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/operator/keyring/rotation", nil)
+		obj, err := a.srv.OperatorKeyringRotation(httptest.NewRecorder(), req)
+		if err != nil {
+			r.Fatal(err)
+		}
+		status := obj.(structs.GossipKeyRotationStatus)
+		if !status.Enabled || status.Phase != structs.GossipKeyRotationIdle ||
+			status.Interval != 720*time.Hour || status.RetireAfter != time.Hour {
+			r.Fatalf("bad: %#v", status)
+		}
+		if status.NextRotation.Sub(status.LastRotation) != 720*time.Hour {
+			r.Fatalf("bad: %#v", status)
+		}
+	})
+}
+
 func TestOperator_KeyringRemove(t *testing.T) {
 	t.Parallel()
 	key := "H3/9gBxcKKRf45CaI2DlRg=="
@@ -409,6 +474,180 @@ func TestOperator_AutopilotCASConfiguration(t *testing.T) {
 	}
 }
 
+func TestOperator_UIConfiguration(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// An empty config is returned if nothing was set.
+	req, _ := http.NewRequest("GET", "/v1/operator/ui-config", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.OperatorUIConfiguration(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out := obj.(*structs.UIConfig)
+	if out.BannerMessage != "" || len(out.DashboardURLTemplates) != 0 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	body := bytes.NewBuffer([]byte(`{"DashboardURLTemplates": {"*": "https://grafana/{{Service}}"}, "BannerMessage": "hi"}`))
+	req, _ = http.NewRequest("PUT", "/v1/operator/ui-config", body)
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.OperatorUIConfiguration(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != 200 {
+		t.Fatalf("bad code: %d", resp.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/operator/ui-config", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.OperatorUIConfiguration(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out = obj.(*structs.UIConfig)
+	if out.BannerMessage != "hi" || out.BannerLevel != structs.UIBannerInfo {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out.DashboardURLTemplates["*"] != "https://grafana/{{Service}}" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Create a CAS request, bad index
+	{
+		buf := bytes.NewBuffer([]byte(`{"BannerMessage": "bye"}`))
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/v1/operator/ui-config?cas=%d", out.ModifyIndex-1), buf)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.OperatorUIConfiguration(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if res := obj.(bool); res {
+			t.Fatalf("should NOT work")
+		}
+	}
+
+	// Create a CAS request, good index
+	{
+		buf := bytes.NewBuffer([]byte(`{"BannerMessage": "bye"}`))
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/v1/operator/ui-config?cas=%d", out.ModifyIndex), buf)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.OperatorUIConfiguration(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if res := obj.(bool); !res {
+			t.Fatalf("should work")
+		}
+	}
+
+	// An invalid config is rejected.
+	{
+		buf := bytes.NewBuffer([]byte(`{"DashboardURLTemplates": {"web": "https://grafana/{{Node}}"}}`))
+		req, _ := http.NewRequest("PUT", "/v1/operator/ui-config", buf)
+		resp := httptest.NewRecorder()
+		if _, err := a.srv.OperatorUIConfiguration(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Code != 400 {
+			t.Fatalf("bad code: %d", resp.Code)
+		}
+		if !strings.Contains(resp.Body.String(), "unknown placeholder") {
+			t.Fatalf("bad: %s", resp.Body.String())
+		}
+	}
+}
+
+func TestOperator_Gossip(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		gossip_lan {
+			profile = "cloud"
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	req, _ := http.NewRequest("GET", "/v1/operator/gossip", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.OperatorGossip(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pools := obj.([]*api.GossipPool)
+	if len(pools) != 2 || pools[0].Pool != "lan" || pools[1].Pool != "wan" {
+		t.Fatalf("bad: %#v", pools)
+	}
+	if pools[0].Profile != "cloud" || pools[0].PendingProfile != "" || pools[0].Members != 1 {
+		t.Fatalf("bad: %#v", pools[0])
+	}
+
+	// Select a profile, it is pending until the agent is restarted.
+	body := bytes.NewBuffer([]byte(`{"Pool": "lan", "Profile": "large-cluster"}`))
+	req, _ = http.NewRequest("PUT", "/v1/operator/gossip", body)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.OperatorGossip(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != 200 {
+		t.Fatalf("bad code: %d", resp.Code)
+	}
+	pool := obj.(*api.GossipPool)
+	if pool.Profile != "cloud" || pool.PendingProfile != "large-cluster" {
+		t.Fatalf("bad: %#v", pool)
+	}
+
+	// An unknown profile is rejected.
+	body = bytes.NewBuffer([]byte(`{"Pool": "lan", "Profile": "huge"}`))
+	req, _ = http.NewRequest("PUT", "/v1/operator/gossip", body)
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.OperatorGossip(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Code != 400 || !strings.Contains(resp.Body.String(), `Invalid gossip profile "huge"`) {
+		t.Fatalf("bad: %d %s", resp.Code, resp.Body.String())
+	}
+
+	// Removing the selection clears the pending profile.
+	body = bytes.NewBuffer([]byte(`{"Pool": "lan"}`))
+	req, _ = http.NewRequest("PUT", "/v1/operator/gossip", body)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.OperatorGossip(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pool := obj.(*api.GossipPool); pool.PendingProfile != "" {
+		t.Fatalf("bad: %#v", pool)
+	}
+}
+
+func TestOperator_KVReplication(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `
+		kv_replication {
+			prefixes = ["shared/"]
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	// The primary datacenter doesn't replicate.
+	req, _ := http.NewRequest("GET", "/v1/operator/kv-replication", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.OperatorKVReplication(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	status := obj.(structs.KVReplicationStatus)
+	if status.Enabled || status.ConflictPolicy != "overwrite" || status.Prefixes == nil {
+		t.Fatalf("bad: %#v", status)
+	}
+}
+
 func TestOperator_ServerHealth(t *testing.T) {
 	t.Parallel()
 	a := NewTestAgent(t.Name(), `
@@ -0,0 +1,13 @@
+// +build !windows
+
+package agent
+
+import (
+	"fmt"
+	"net"
+)
+
+// listenPipe returns an error since named pipes only exist on Windows.
+func listenPipe(path string) (net.Listener, error) {
+	return nil, fmt.Errorf("named pipe %q: named pipes are only supported on Windows", path)
+}
@@ -0,0 +1,16 @@
+// +build windows
+
+package agent
+
+import (
+	"net"
+
+	winio "github.com/Microsoft/go-winio"
+)
+
+// listenPipe creates a listener on the Windows named pipe at path. The
+// pipe uses the default security descriptor for named pipes which only
+// allows administrators, LocalSystem and the owner to connect.
+func listenPipe(path string) (net.Listener, error) {
+	return winio.ListenPipe(path, nil)
+}
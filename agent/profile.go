@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+const (
+	// profileRetryInterval is the base interval between attempts to fetch
+	// the agent profile after an error.
+	profileRetryInterval = 10 * time.Second
+
+	// profileMaxQueryTime is the maximum time a blocking query for the
+	// agent profile waits for a change.
+	profileMaxQueryTime = 10 * time.Minute
+)
+
+// watchProfile keeps the cached copy of the configured agent profile in
+// sync with the KV store on the servers. Whenever the profile changes the
+// new version is written to the data dir and a configuration reload is
+// triggered which merges the cached profile into the configuration.
+func (a *Agent) watchProfile() {
+	name := a.config.AgentProfile
+	path := config.ProfileCachePath(a.config.DataDir, name)
+
+	// The profile the agent was started with is the baseline. A missing
+	// file is the same as an empty profile.
+	last, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		a.logger.Printf("[ERR] agent: Failed to read cached agent profile %q: %v", name, err)
+	}
+
+	var index uint64
+	for {
+		args := structs.KeyRequest{
+			Datacenter: a.config.Datacenter,
+			Key:        config.ProfileKVPrefix + name,
+			QueryOptions: structs.QueryOptions{
+				Token:         a.tokens.AgentToken(),
+				MinQueryIndex: index,
+				MaxQueryTime:  profileMaxQueryTime,
+			},
+		}
+		var out structs.IndexedDirEntries
+		if err := a.RPC("KVS.Get", &args, &out); err != nil {
+			a.logger.Printf("[WARN] agent: Failed to fetch agent profile %q: %v", name, err)
+			select {
+			case <-time.After(profileRetryInterval + lib.RandomStagger(profileRetryInterval)):
+				continue
+			case <-a.shutdownCh:
+				return
+			}
+		}
+
+		// Reset the index if it goes backwards, e.g. after a snapshot restore.
+		if out.Index < index {
+			index = 0
+		} else {
+			index = out.Index
+		}
+
+		var data []byte
+		if len(out.Entries) > 0 {
+			data = out.Entries[0].Value
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+
+		if data == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				a.logger.Printf("[ERR] agent: Failed to remove cached agent profile %q: %v", name, err)
+				continue
+			}
+		} else {
+			if _, err := config.ProfileSource(name, string(data)); err != nil {
+				a.logger.Printf("[ERR] agent: Ignoring invalid agent profile: %v", err)
+				continue
+			}
+			if err := file.WriteAtomic(path, data); err != nil {
+				a.logger.Printf("[ERR] agent: Failed to cache agent profile %q: %v", name, err)
+				continue
+			}
+		}
+		last = data
+
+		a.logger.Printf("[INFO] agent: Agent profile %q changed, reloading configuration", name)
+		respCh := make(chan ReloadResponse, 1)
+		select {
+		case a.reloadCh <- respCh:
+		case <-a.shutdownCh:
+			return
+		}
+		select {
+		case resp := <-respCh:
+			if resp.Err != nil {
+				a.logger.Printf("[ERR] agent: Failed to apply agent profile %q: %v", name, resp.Err)
+			}
+		case <-a.shutdownCh:
+			return
+		}
+	}
+}
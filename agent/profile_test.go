@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_WatchProfile(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), `agent_profile = "web"`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	path := config.ProfileCachePath(a.Config.DataDir, "web")
+	waitReload := func() {
+		t.Helper()
+		select {
+		case respCh := <-a.ReloadCh():
+			respCh <- ReloadResponse{}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for reload")
+		}
+	}
+
+	// an invalid profile is not cached
+	setKV(t, a.Agent, config.ProfileKVPrefix+"web", []byte(`node_name = "foo"`), "")
+	select {
+	case <-a.ReloadCh():
+		t.Fatal("unexpected reload")
+	case <-time.After(500 * time.Millisecond):
+	}
+	_, err := os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	// a valid profile is cached and triggers a reload
+	profile := []byte(`limits { rpc_rate = 10 }`)
+	setKV(t, a.Agent, config.ProfileKVPrefix+"web", profile, "")
+	waitReload()
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, profile, data)
+
+	// deleting the profile removes the cached copy
+	del := structs.KVSRequest{
+		Datacenter: "dc1",
+		Op:         api.KVDelete,
+		DirEnt:     structs.DirEntry{Key: config.ProfileKVPrefix + "web"},
+	}
+	var ok bool
+	require.NoError(t, a.RPC("KVS.Apply", &del, &ok))
+	waitReload()
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
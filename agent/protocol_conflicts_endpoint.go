@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/consul/agent/structs"
+)
+
+// ProtocolConflicts lists the services for which the Connect proxies assume
+// different protocols. It supports blocking queries.
+func (s *HTTPServer) ProtocolConflicts(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.DCSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var out structs.IndexedProtocolConflicts
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Internal.ProtocolConflicts", &args, &out); err != nil {
+		return nil, err
+	}
+
+	// Use empty list instead of nil
+	if out.Conflicts == nil {
+		out.Conflicts = make(structs.ProtocolConflicts, 0)
+	}
+	return out.Conflicts, nil
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolConflicts(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Make sure an empty list is non-nil.
+	req, _ := http.NewRequest("GET", "/v1/internal/protocol-conflicts", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.ProtocolConflicts(resp, req)
+	require.NoError(t, err)
+	require.NotNil(t, obj)
+	require.Len(t, obj.(structs.ProtocolConflicts), 0)
+
+	// Register two proxies for the web service which disagree about its
+	// protocol.
+	for _, node := range []string{"foo", "bar"} {
+		args := structs.TestRegisterRequestProxy(t)
+		args.Node = node
+		if node == "bar" {
+			args.Service.Proxy.Config = map[string]interface{}{"protocol": "http"}
+		}
+		var out struct{}
+		require.NoError(t, a.RPC("Catalog.Register", args, &out))
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/internal/protocol-conflicts", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.ProtocolConflicts(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	conflicts := obj.(structs.ProtocolConflicts)
+	require.Len(t, conflicts, 1)
+	require.Equal(t, "web", conflicts[0].Service)
+	require.Len(t, conflicts[0].Assumptions, 2)
+}
@@ -306,6 +306,19 @@ func (m *Manager) Watch(proxyID string) (<-chan *ConfigSnapshot, CancelFunc) {
 	}
 }
 
+// Snapshot returns the current snapshot of a proxy. It returns nil if the
+// proxy isn't registered or its snapshot isn't valid yet.
+func (m *Manager) Snapshot(proxyID string) *ConfigSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.proxies[proxyID]
+	if !ok {
+		return nil
+	}
+	return state.CurrentSnapshot()
+}
+
 // closeWatchLocked cleans up state related to a single watcher. It assumes the
 // lock is held.
 func (m *Manager) closeWatchLocked(proxyID string, watchIdx uint64) {
@@ -136,6 +136,10 @@ func TestManager_BasicLifecycle(t *testing.T) {
 	// New watcher should immediately receive the current state
 	assertWatchChanRecvs(t, wCh2, expectSnap)
 
+	// The current state can also be read synchronously
+	require.Equal(expectSnap, m.Snapshot(webProxy.ID))
+	require.Nil(m.Snapshot("unknown-proxy"))
+
 	// Change token
 	require.NoError(state.AddService(webProxy, "other-token"))
 	assertWatchChanRecvs(t, wCh, expectSnap)
@@ -220,6 +224,131 @@ func assertWatchChanRecvs(t *testing.T, ch <-chan *ConfigSnapshot, expect *Confi
 	}
 }
 
+func TestManager_TransparentProxy(t *testing.T) {
+	// Use a mocked cache to make life simpler
+	types := NewTestCacheTypes(t)
+	c := TestCacheWithTypes(t, types)
+
+	require := require.New(t)
+
+	roots, leaf := TestCerts(t)
+
+	// Setup initial values
+	types.roots.value.Store(roots)
+	types.leaf.value.Store(leaf)
+	intentions := func(action structs.IntentionAction) *structs.IndexedIntentionMatches {
+		return &structs.IndexedIntentionMatches{
+			Matches: []structs.Intentions{
+				[]*structs.Intention{
+					{SourceNS: "default", SourceName: "web", DestinationNS: "default", DestinationName: "db", Action: action},
+					{SourceNS: "default", SourceName: "web", DestinationNS: "default", DestinationName: "*", Action: structs.IntentionActionAllow},
+					{SourceNS: "default", SourceName: "*", DestinationNS: "default", DestinationName: "db", Action: structs.IntentionActionAllow},
+				},
+			},
+		}
+	}
+	types.intentions.value.Store(intentions(structs.IntentionActionAllow))
+	types.health.value.Store(
+		&structs.IndexedCheckServiceNodes{
+			Nodes: TestUpstreamNodes(t),
+		})
+	types.virtualIPs.value.Store(
+		&structs.IndexedServiceVirtualIPs{
+			VirtualIPs: map[string]string{
+				"web":   "240.0.0.1",
+				"db":    "240.0.0.2",
+				"cache": "240.0.0.3",
+			},
+		})
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	state := local.NewState(local.Config{}, logger, &token.Store{})
+	source := &structs.QuerySource{
+		Node:       "node1",
+		Datacenter: "dc1",
+	}
+
+	// Stub state syncing
+	state.TriggerSyncChanges = func() {}
+
+	m, err := NewManager(ManagerConfig{c, state, source, logger})
+	require.NoError(err)
+	go func() {
+		err := m.Run()
+		require.NoError(err)
+	}()
+	defer m.Close()
+
+	// Register a transparent proxy for "web" without upstreams
+	webProxy := &structs.NodeService{
+		Kind:    structs.ServiceKindConnectProxy,
+		ID:      "web-sidecar-proxy",
+		Service: "web-sidecar-proxy",
+		Port:    9999,
+		Proxy: structs.ConnectProxyConfig{
+			DestinationServiceID:   "web",
+			DestinationServiceName: "web",
+			LocalServiceAddress:    "127.0.0.1",
+			LocalServicePort:       8080,
+			Mode:                   structs.ProxyModeTransparent,
+		},
+	}
+
+	wCh, cancel := m.Watch(webProxy.ID)
+	defer cancel()
+	require.NoError(state.AddService(webProxy, "my-token"))
+
+	// The endpoints of the services with a virtual IP which the intentions
+	// of web allow are watched, the wildcard intention is ignored.
+	expectSnap := &ConfigSnapshot{
+		ProxyID:           webProxy.ID,
+		Address:           webProxy.Address,
+		Port:              webProxy.Port,
+		Proxy:             webProxy.Proxy,
+		Roots:             roots,
+		Leaf:              leaf,
+		UpstreamEndpoints: map[string]structs.CheckServiceNodes{},
+		VirtualIPs: map[string]string{
+			"web":   "240.0.0.1",
+			"db":    "240.0.0.2",
+			"cache": "240.0.0.3",
+		},
+		TransparentEndpoints: map[string]structs.CheckServiceNodes{
+			"db": TestUpstreamNodes(t),
+		},
+	}
+	assertWatchChanRecvs(t, wCh, expectSnap)
+
+	healthReq := types.health.lastReq.Load().(*structs.ServiceSpecificRequest)
+	require.Equal("db", healthReq.ServiceName)
+	require.True(healthReq.Connect)
+	require.Equal("my-token", healthReq.Token)
+
+	// Services which web may no longer reach are dropped. The intention with
+	// the highest precedence decides.
+	types.intentions.Set(intentions(structs.IntentionActionDeny))
+	expectSnap.TransparentEndpoints = map[string]structs.CheckServiceNodes{}
+	assertWatchChanRecvs(t, wCh, expectSnap)
+
+	types.intentions.Set(intentions(structs.IntentionActionAllow))
+	expectSnap.TransparentEndpoints = map[string]structs.CheckServiceNodes{
+		"db": TestUpstreamNodes(t),
+	}
+	assertWatchChanRecvs(t, wCh, expectSnap)
+
+	// Services whose virtual IP is freed are dropped.
+	types.virtualIPs.Set(&structs.IndexedServiceVirtualIPs{
+		VirtualIPs: map[string]string{
+			"web": "240.0.0.1",
+		},
+	})
+	expectSnap.VirtualIPs = map[string]string{
+		"web": "240.0.0.1",
+	}
+	expectSnap.TransparentEndpoints = map[string]structs.CheckServiceNodes{}
+	assertWatchChanRecvs(t, wCh, expectSnap)
+}
+
 func TestManager_deliverLatest(t *testing.T) {
 	// None of these need to do anything to test this method just be valid
 	logger := log.New(os.Stderr, "", log.LstdFlags)
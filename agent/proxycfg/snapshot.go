@@ -17,6 +17,12 @@ type ConfigSnapshot struct {
 	Leaf              *structs.IssuedCert
 	UpstreamEndpoints map[string]structs.CheckServiceNodes
 
+	// VirtualIPs are the virtual IPs of the services by service name, and
+	// TransparentEndpoints the Connect endpoints of those services, for
+	// proxies in the transparent mode.
+	VirtualIPs           map[string]string
+	TransparentEndpoints map[string]structs.CheckServiceNodes
+
 	// Skip intentions for now as we don't push those down yet, just pre-warm them.
 }
 
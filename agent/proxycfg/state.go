@@ -20,6 +20,9 @@ const (
 	rootsWatchID          = "roots"
 	leafWatchID           = "leaf"
 	intentionsWatchID     = "intentions"
+	virtualIPsWatchID     = "virtual-ips"
+	sourceIntentionsID    = "source-intentions"
+	transparentIDPrefix   = "transparent:"
 	serviceIDPrefix       = string(structs.UpstreamDestTypeService) + ":"
 	preparedQueryIDPrefix = string(structs.UpstreamDestTypePreparedQuery) + ":"
 )
@@ -43,6 +46,15 @@ type state struct {
 	proxyCfg structs.ConnectProxyConfig
 	token    string
 
+	// transparentWatches cancels the watches of the endpoints of the services
+	// with virtual IPs, by service name, for proxies in the transparent mode.
+	transparentWatches map[string]context.CancelFunc
+
+	// transparentAllowed are the services a proxy in the transparent mode may
+	// reach, from its allowed services or from the intentions with its
+	// service as source. Only the endpoints of these services are watched.
+	transparentAllowed map[string]struct{}
+
 	ch     chan cache.UpdateEvent
 	snapCh chan ConfigSnapshot
 	reqCh  chan chan *ConfigSnapshot
@@ -71,11 +83,12 @@ func newState(ns *structs.NodeService, token string) (*state, error) {
 	}
 
 	return &state{
-		proxyID:  ns.ID,
-		address:  ns.Address,
-		port:     ns.Port,
-		proxyCfg: proxyCfg,
-		token:    token,
+		proxyID:            ns.ID,
+		address:            ns.Address,
+		port:               ns.Port,
+		proxyCfg:           proxyCfg,
+		token:              token,
+		transparentWatches: make(map[string]context.CancelFunc),
 		// 10 is fairly arbitrary here but allow for the 3 mandatory and a
 		// reasonable number of upstream watches to all deliver their initial
 		// messages in parallel without blocking the cache.Notify loops. It's not a
@@ -155,6 +168,44 @@ func (s *state) initWatches() error {
 		return err
 	}
 
+	// In the transparent mode the upstreams are the services with virtual IPs
+	// the proxy may reach, whose endpoints are watched as they appear. Unless
+	// they are configured, they are the services the intentions with the
+	// service of the proxy as source allow.
+	if s.proxyCfg.Mode == structs.ProxyModeTransparent {
+		if allowed := s.proxyCfg.TransparentProxy.AllowedServices; len(allowed) > 0 {
+			s.transparentAllowed = make(map[string]struct{})
+			for _, service := range allowed {
+				s.transparentAllowed[service] = struct{}{}
+			}
+		} else {
+			err = s.cache.Notify(s.ctx, cachetype.IntentionMatchName, &structs.IntentionQueryRequest{
+				Datacenter:   s.source.Datacenter,
+				QueryOptions: structs.QueryOptions{Token: s.token},
+				Match: &structs.IntentionQueryMatch{
+					Type: structs.IntentionMatchSource,
+					Entries: []structs.IntentionMatchEntry{
+						{
+							Namespace: structs.IntentionDefaultNamespace,
+							Name:      s.proxyCfg.DestinationServiceName,
+						},
+					},
+				},
+			}, sourceIntentionsID, s.ch)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.cache.Notify(s.ctx, cachetype.CatalogServiceVirtualIPsName, &structs.DCSpecificRequest{
+			Datacenter:   s.source.Datacenter,
+			QueryOptions: structs.QueryOptions{Token: s.token},
+		}, virtualIPsWatchID, s.ch)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Watch for updates to service endpoints for all upstreams
 	for _, u := range s.proxyCfg.Upstreams {
 		dc := s.source.Datacenter
@@ -208,6 +259,10 @@ func (s *state) run() {
 		Proxy:             s.proxyCfg,
 		UpstreamEndpoints: make(map[string]structs.CheckServiceNodes),
 	}
+	if s.proxyCfg.Mode == structs.ProxyModeTransparent {
+		snap.VirtualIPs = make(map[string]string)
+		snap.TransparentEndpoints = make(map[string]structs.CheckServiceNodes)
+	}
 	// This turns out to be really fiddly/painful by just using time.Timer.C
 	// directly in the code below since you can't detect when a timer is stopped
 	// vs waiting in order to know to reset it. So just use a chan to send
@@ -296,9 +351,38 @@ func (s *state) handleUpdate(u cache.UpdateEvent, snap *ConfigSnapshot) error {
 		snap.Leaf = leaf
 	case intentionsWatchID:
 		// Not in snapshot currently, no op
+	case virtualIPsWatchID:
+		resp, ok := u.Result.(*structs.IndexedServiceVirtualIPs)
+		if !ok {
+			return fmt.Errorf("invalid type for virtual IPs response: %T", u.Result)
+		}
+		snap.VirtualIPs = resp.VirtualIPs
+		return s.watchTransparentUpstreams(snap)
+	case sourceIntentionsID:
+		resp, ok := u.Result.(*structs.IndexedIntentionMatches)
+		if !ok {
+			return fmt.Errorf("invalid type for intentions response: %T", u.Result)
+		}
+		var ixns structs.Intentions
+		if len(resp.Matches) > 0 {
+			ixns = resp.Matches[0]
+		}
+		s.transparentAllowed = allowedDestinations(ixns)
+		return s.watchTransparentUpstreams(snap)
 	default:
 		// Service discovery result, figure out which type
 		switch {
+		case strings.HasPrefix(u.CorrelationID, transparentIDPrefix):
+			resp, ok := u.Result.(*structs.IndexedCheckServiceNodes)
+			if !ok {
+				return fmt.Errorf("invalid type for service response: %T", u.Result)
+			}
+			// Drop late results of the watches of services which are gone.
+			service := strings.TrimPrefix(u.CorrelationID, transparentIDPrefix)
+			if _, ok := s.transparentWatches[service]; ok {
+				snap.TransparentEndpoints[service] = resp.Nodes
+			}
+
 		case strings.HasPrefix(u.CorrelationID, serviceIDPrefix):
 			resp, ok := u.Result.(*structs.IndexedCheckServiceNodes)
 			if !ok {
@@ -320,6 +404,68 @@ func (s *state) handleUpdate(u cache.UpdateEvent, snap *ConfigSnapshot) error {
 	return nil
 }
 
+// watchTransparentUpstreams starts watching the endpoints of the services
+// which got a virtual IP and which the proxy may reach, and stops watching
+// those of the services whose virtual IP was freed or which it may no longer
+// reach. The destination service of the proxy is skipped, as the application
+// doesn't need to go through the mesh to reach itself.
+func (s *state) watchTransparentUpstreams(snap *ConfigSnapshot) error {
+	for service, cancel := range s.transparentWatches {
+		_, hasIP := snap.VirtualIPs[service]
+		_, allowed := s.transparentAllowed[service]
+		if !hasIP || !allowed {
+			cancel()
+			delete(s.transparentWatches, service)
+			delete(snap.TransparentEndpoints, service)
+		}
+	}
+
+	for service := range snap.VirtualIPs {
+		if _, ok := s.transparentWatches[service]; ok || service == s.proxyCfg.DestinationServiceName {
+			continue
+		}
+		if _, ok := s.transparentAllowed[service]; !ok {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(s.ctx)
+		err := s.cache.Notify(ctx, cachetype.HealthServicesName, &structs.ServiceSpecificRequest{
+			Datacenter:   s.source.Datacenter,
+			QueryOptions: structs.QueryOptions{Token: s.token},
+			ServiceName:  service,
+			Connect:      true,
+		}, transparentIDPrefix+service, s.ch)
+		if err != nil {
+			cancel()
+			return err
+		}
+		s.transparentWatches[service] = cancel
+	}
+	return nil
+}
+
+// allowedDestinations returns the destination services the intentions, which
+// are sorted by precedence, allow for a source. The intention with the
+// highest precedence decides for each destination. Intentions with a wildcard
+// destination are ignored, as they would make every service an upstream.
+func allowedDestinations(ixns structs.Intentions) map[string]struct{} {
+	allowed := make(map[string]struct{})
+	decided := make(map[string]struct{})
+	for _, ixn := range ixns {
+		if ixn.DestinationName == structs.IntentionWildcard {
+			continue
+		}
+		if _, ok := decided[ixn.DestinationName]; ok {
+			continue
+		}
+		decided[ixn.DestinationName] = struct{}{}
+		if ixn.Action == structs.IntentionActionAllow {
+			allowed[ixn.DestinationName] = struct{}{}
+		}
+	}
+	return allowed
+}
+
 // CurrentSnapshot synchronously returns the current ConfigSnapshot if there is
 // one ready. If we don't have one yet because not all necessary parts have been
 // returned (i.e. both roots and leaf cert), nil is returned.
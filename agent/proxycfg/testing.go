@@ -21,6 +21,7 @@ type TestCacheTypes struct {
 	intentions *ControllableCacheType
 	health     *ControllableCacheType
 	query      *ControllableCacheType
+	virtualIPs *ControllableCacheType
 }
 
 // NewTestCacheTypes creates a set of ControllableCacheTypes for all types that
@@ -33,6 +34,7 @@ func NewTestCacheTypes(t testing.T) *TestCacheTypes {
 		intentions: NewControllableCacheType(t),
 		health:     NewControllableCacheType(t),
 		query:      NewControllableCacheType(t),
+		virtualIPs: NewControllableCacheType(t),
 	}
 	ct.query.blocking = false
 	return ct
@@ -65,6 +67,11 @@ func TestCacheWithTypes(t testing.T, types *TestCacheTypes) *cache.Cache {
 	c.RegisterType(cachetype.PreparedQueryName, types.query, &cache.RegisterOptions{
 		Refresh: false,
 	})
+	c.RegisterType(cachetype.CatalogServiceVirtualIPsName, types.virtualIPs, &cache.RegisterOptions{
+		Refresh:        true,
+		RefreshTimer:   0,
+		RefreshTimeout: 10 * time.Minute,
+	})
 	return c
 }
 
@@ -170,6 +177,23 @@ func TestConfigSnapshot(t testing.T) *ConfigSnapshot {
 	}
 }
 
+// TestConfigSnapshotTransparent returns a fully populated snapshot of a proxy
+// in the transparent mode
+func TestConfigSnapshotTransparent(t testing.T) *ConfigSnapshot {
+	snap := TestConfigSnapshot(t)
+	snap.Proxy.Mode = structs.ProxyModeTransparent
+	snap.Proxy.Upstreams = nil
+	snap.UpstreamEndpoints = map[string]structs.CheckServiceNodes{}
+	snap.VirtualIPs = map[string]string{
+		"web": "240.0.0.1",
+		"db":  "240.0.0.2",
+	}
+	snap.TransparentEndpoints = map[string]structs.CheckServiceNodes{
+		"db": TestUpstreamNodes(t),
+	}
+	return snap
+}
+
 // ControllableCacheType is a cache.Type that simulates a typical blocking RPC
 // but lets us control the responses and when they are delivered easily.
 type ControllableCacheType struct {
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/hashicorp/consul/agent/config"
+)
+
+// reloadableFields lists the settings of the runtime configuration which
+// are applied by a configuration reload. Nested fields are matched by
+// their dotted name. All other settings only take effect after a restart.
+var reloadableFields = []string{
+	"Checks",
+	"DiscardCheckOutput",
+	"DNSDisableCompression",
+	"DNSRecursors",
+	"DeregisterCriticalServiceAfterDefault",
+	"DeregisterCriticalServiceAfterMin",
+	"LeaveOnTerm",
+	"LogLevel",
+	"NodeMeta",
+	"RPCMaxBurst",
+	"RPCRateLimit",
+	"ServiceMetaDefaults",
+	"Services",
+	"SkipLeaveOnInt",
+	"Watches",
+
+	// telemetry sinks which can be replaced at runtime
+	"Telemetry.AllowedPrefixes",
+	"Telemetry.BlockedPrefixes",
+	"Telemetry.DogstatsdAddr",
+	"Telemetry.DogstatsdTags",
+	"Telemetry.StatsdAddr",
+	"Telemetry.StatsiteAddr",
+
+	// TLS material for the HTTPS API
+	"CAFile",
+	"CAPath",
+	"CertFile",
+	"KeyFile",
+	"VerifyIncomingHTTPS",
+}
+
+// ReloadResult describes the effect of a configuration reload.
+type ReloadResult struct {
+	// Applied lists the changed settings which have been applied.
+	Applied []string
+
+	// Ignored lists the changed settings which only take effect after
+	// the agent has been restarted.
+	Ignored []string
+}
+
+// ReloadResponse is sent back on the channel of a reload request.
+type ReloadResponse struct {
+	Result *ReloadResult
+	Err    error
+}
+
+// NewReloadResult determines the effect of reloading the configuration
+// from prev to next. Reloadable settings are reported when they differ
+// between prev and next. All other settings are reported as long as they
+// differ from start, the configuration the agent was started with, since
+// they are only applied by a restart.
+func NewReloadResult(start, prev, next *config.RuntimeConfig) *ReloadResult {
+	r := &ReloadResult{Applied: []string{}, Ignored: []string{}}
+	for _, name := range config.Diff(*prev, *next) {
+		if isReloadable(name) {
+			r.Applied = append(r.Applied, name)
+		}
+	}
+	for _, name := range config.Diff(*start, *next) {
+		if !isReloadable(name) {
+			r.Ignored = append(r.Ignored, name)
+		}
+	}
+	return r
+}
+
+func isReloadable(name string) bool {
+	for _, f := range reloadableFields {
+		if name == f || strings.HasPrefix(name, f+".") {
+			return true
+		}
+	}
+	return false
+}
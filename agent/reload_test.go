@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReloadResult(t *testing.T) {
+	t.Parallel()
+	start := &config.RuntimeConfig{NodeName: "a", LogLevel: "INFO"}
+
+	// the first reload changes the node name which is not applied
+	prev := &config.RuntimeConfig{NodeName: "b", LogLevel: "INFO"}
+	r := NewReloadResult(start, start, prev)
+	require.Equal(t, []string{}, r.Applied)
+	require.Equal(t, []string{"NodeName"}, r.Ignored)
+
+	// the second reload only reports the new change as applied but
+	// still reports the pending restart
+	next := &config.RuntimeConfig{NodeName: "b", LogLevel: "DEBUG", DNSRecursors: []string{"8.8.8.8"}}
+	next.Telemetry.StatsdAddr = "127.0.0.1:8125"
+	r = NewReloadResult(start, prev, next)
+	require.Equal(t, []string{"DNSRecursors", "LogLevel", "Telemetry.StatsdAddr"}, r.Applied)
+	require.Equal(t, []string{"NodeName"}, r.Ignored)
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/hashicorp/consul/lib/cgroup"
+)
+
+// limitedGCPercent is the GC percent used if the memory of the agent is
+// limited. The heap then grows by at most half of the live data between two
+// collections instead of doubling, which leaves more headroom below the
+// limit at the expense of more frequent collections.
+const limitedGCPercent = 50
+
+// ResourceLimits are the CPU and memory limits of the cgroups of the agent
+// along with the runtime settings derived from them. They are returned by
+// /v1/agent/self.
+type ResourceLimits struct {
+	// CPUQuota is the number of CPUs the agent may use, or 0 if the CPU is
+	// not limited.
+	CPUQuota float64
+
+	// MemoryLimit is the memory limit of the agent in bytes, or 0 if the
+	// memory is not limited.
+	MemoryLimit uint64
+
+	// GOMAXPROCS is the number of OS threads which execute Go code
+	// simultaneously.
+	GOMAXPROCS int
+
+	// GCPercent is the garbage collection target percentage. It is -1 if
+	// the garbage collection is disabled.
+	GCPercent int
+}
+
+var (
+	// resourceLimitsOnce makes sure that the runtime settings, which are
+	// global to the process, are only adapted once even if the process
+	// runs multiple agents.
+	resourceLimitsOnce sync.Once
+	resourceLimits     ResourceLimits
+)
+
+// applyResourceLimits detects the cgroup limits of the agent and adapts
+// GOMAXPROCS and the GC percent to them. The GOMAXPROCS and GOGC environment
+// variables take precedence over the detected limits.
+func applyResourceLimits(logger *log.Logger) ResourceLimits {
+	resourceLimitsOnce.Do(func() {
+		resourceLimits = detectResourceLimits(logger)
+	})
+	return resourceLimits
+}
+
+func detectResourceLimits(logger *log.Logger) ResourceLimits {
+	var r ResourceLimits
+	limits, err := cgroup.Detect()
+	if err != nil {
+		logger.Printf("[WARN] agent: Failed to detect the cgroup limits: %v", err)
+		limits = &cgroup.Limits{}
+	}
+	r.CPUQuota = limits.CPUQuota
+	r.MemoryLimit = limits.MemoryLimit
+
+	r.GOMAXPROCS = runtime.GOMAXPROCS(0)
+	if procs := maxProcsForQuota(limits.CPUQuota); procs > 0 && procs < r.GOMAXPROCS && os.Getenv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(procs)
+		logger.Printf("[INFO] agent: Set GOMAXPROCS to %d for a CPU quota of %v", procs, limits.CPUQuota)
+		r.GOMAXPROCS = procs
+	}
+
+	// SetGCPercent returns the previous value, so the current one has to be
+	// restored if it is not changed.
+	r.GCPercent = debug.SetGCPercent(-1)
+	if limits.MemoryLimit > 0 && os.Getenv("GOGC") == "" && r.GCPercent > limitedGCPercent {
+		logger.Printf("[INFO] agent: Set the GC percent to %d for a memory limit of %d bytes", limitedGCPercent, limits.MemoryLimit)
+		r.GCPercent = limitedGCPercent
+	}
+	debug.SetGCPercent(r.GCPercent)
+	return r
+}
+
+// maxProcsForQuota returns the GOMAXPROCS for the given CPU quota, which is
+// the quota rounded up. It returns 0 if the CPU is not limited.
+func maxProcsForQuota(quota float64) int {
+	if quota <= 0 {
+		return 0
+	}
+	return int(math.Ceil(quota))
+}
@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"testing"
+)
+
+func TestMaxProcsForQuota(t *testing.T) {
+	t.Parallel()
+	cases := map[float64]int{
+		0:    0,
+		-1:   0,
+		0.25: 1,
+		1:    1,
+		1.5:  2,
+		4:    4,
+	}
+	for quota, want := range cases {
+		if got := maxProcsForQuota(quota); got != want {
+			t.Fatalf("quota %v: got %d, want %d", quota, got, want)
+		}
+	}
+}
@@ -65,6 +65,7 @@ func (s *HTTPServer) SessionCreate(resp http.ResponseWriter, req *http.Request)
 	if err := s.agent.RPC("Session.Apply", &args, &out); err != nil {
 		return nil, err
 	}
+	s.setWriteIndex(resp, args.Datacenter)
 
 	// Format the response as a JSON object
 	return sessionCreateResponse{out}, nil
@@ -150,6 +151,7 @@ func (s *HTTPServer) SessionDestroy(resp http.ResponseWriter, req *http.Request)
 	if err := s.agent.RPC("Session.Apply", &args, &out); err != nil {
 		return nil, err
 	}
+	s.setWriteIndex(resp, args.Datacenter)
 	return true, nil
 }
 
@@ -230,10 +232,11 @@ func (s *HTTPServer) SessionList(resp http.ResponseWriter, req *http.Request) (i
 
 // SessionsForNode returns all the nodes belonging to a node
 func (s *HTTPServer) SessionsForNode(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
-	args := structs.NodeSpecificRequest{}
+	args := structs.NodeSessionsRequest{}
 	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
 		return nil, nil
 	}
+	args.MetaFilters = s.parseSessionMetaFilter(req)
 
 	// Pull out the node name
 	args.Node = strings.TrimPrefix(req.URL.Path, "/v1/session/node/")
@@ -255,3 +258,17 @@ func (s *HTTPServer) SessionsForNode(resp http.ResponseWriter, req *http.Request
 	}
 	return out.Sessions, nil
 }
+
+// parseSessionMetaFilter is used to parse the ?meta=key:value query parameter,
+// used for filtering sessions by their metadata
+func (s *HTTPServer) parseSessionMetaFilter(req *http.Request) map[string]string {
+	if filterList, ok := req.URL.Query()["meta"]; ok {
+		filters := make(map[string]string)
+		for _, filter := range filterList {
+			key, value := ParseMetaPair(filter)
+			filters[key] = value
+		}
+		return filters
+	}
+	return nil
+}
@@ -72,6 +72,7 @@ func TestSessionCreate(t *testing.T) {
 			"Node":      a.Config.NodeName,
 			"Checks":    []types.CheckID{structs.SerfCheckID, "consul"},
 			"LockDelay": "20s",
+			"Meta":      map[string]string{"deploy": "abc123"},
 		}
 		enc.Encode(raw)
 
@@ -89,6 +90,7 @@ func TestSessionCreate(t *testing.T) {
 			Checks:    []types.CheckID{structs.SerfCheckID, "consul"},
 			LockDelay: 20 * time.Second,
 			Behavior:  structs.SessionKeysRelease,
+			Meta:      map[string]string{"deploy": "abc123"},
 		}
 		verifySession(r, a, want)
 	})
@@ -540,6 +542,44 @@ func TestSessionList(t *testing.T) {
 			t.Fatalf("bad: %v", respObj)
 		}
 	})
+
+	t.Run("meta", func(t *testing.T) {
+		a := NewTestAgent(t.Name(), "")
+		defer a.Shutdown()
+		testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+		makeTestSession(t, a.srv)
+		var ids []string
+		for _, owner := range []string{"alice", "bob"} {
+			body := bytes.NewBuffer(nil)
+			enc := json.NewEncoder(body)
+			raw := map[string]interface{}{
+				"Meta": map[string]string{"owner": owner, "deploy": "abc123"},
+			}
+			enc.Encode(raw)
+
+			req, _ := http.NewRequest("PUT", "/v1/session/create", body)
+			obj, err := a.srv.SessionCreate(httptest.NewRecorder(), req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			ids = append(ids, obj.(sessionCreateResponse).ID)
+		}
+
+		req, _ := http.NewRequest("GET", "/v1/session/node/"+a.Config.NodeName+"?meta=deploy:abc123&meta=owner:bob", nil)
+		resp := httptest.NewRecorder()
+		obj, err := a.srv.SessionsForNode(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respObj, ok := obj.(structs.Sessions)
+		if !ok {
+			t.Fatalf("should work")
+		}
+		if len(respObj) != 1 || respObj[0].ID != ids[1] || respObj[0].Meta["owner"] != "bob" {
+			t.Fatalf("bad: %v", respObj)
+		}
+	})
 }
 
 func TestSessionsForNode(t *testing.T) {
@@ -70,6 +70,10 @@ func (a *Agent) sidecarServiceFromNodeService(ns *structs.NodeService, token str
 		// Inherit address from the service if it's provided
 		sidecar.Address = ns.Address
 	}
+	if sidecar.DeregisterOnShutdown == nil {
+		// Retain the sidecar together with the service
+		sidecar.DeregisterOnShutdown = ns.DeregisterOnShutdown
+	}
 	// Proxy defaults
 	if sidecar.Proxy.DestinationServiceName == "" {
 		sidecar.Proxy.DestinationServiceName = ns.Service
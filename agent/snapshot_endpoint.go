@@ -38,6 +38,10 @@ func (s *HTTPServer) Snapshot(resp http.ResponseWriter, req *http.Request) (inte
 
 	case "PUT":
 		args.Op = structs.SnapshotRestore
+		if _, ok := req.URL.Query()["verify"]; ok {
+			args.Op = structs.SnapshotVerify
+			resp.Header().Set("Content-Type", "application/json")
+		}
 		if err := s.agent.SnapshotRPC(&args, req.Body, resp, nil); err != nil {
 			return nil, err
 		}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
 )
 
 func TestSnapshot(t *testing.T) {
@@ -52,6 +54,37 @@ func TestSnapshot(t *testing.T) {
 	})
 }
 
+func TestSnapshot_Verify(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	body := bytes.NewBuffer(nil)
+	req, _ := http.NewRequest("GET", "/v1/snapshot?token=root", body)
+	resp := httptest.NewRecorder()
+	if _, err := a.srv.Snapshot(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	snap := resp.Body
+
+	req, _ = http.NewRequest("PUT", "/v1/snapshot?verify&token=root", snap)
+	resp = httptest.NewRecorder()
+	if _, err := a.srv.Snapshot(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var summary structs.SnapshotSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if summary.ID == "" || summary.Index == 0 || len(summary.Records) == 0 {
+		t.Fatalf("bad: %#v", summary)
+	}
+	if ct := resp.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("bad: %v", ct)
+	}
+}
+
 func TestSnapshot_Options(t *testing.T) {
 	t.Parallel()
 	for _, method := range []string{"GET", "PUT"} {
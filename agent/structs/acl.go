@@ -4,8 +4,10 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
 	"hash/fnv"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -83,6 +85,30 @@ session_prefix "" {
 
 	// This is the policy ID for anonymous access. This is configurable by the
 	ACLTokenAnonymousID = "00000000-0000-0000-0000-000000000002"
+
+	// This is the template of the synthetic policy of node identities.
+	aclPolicyTemplateNodeIdentity = `
+node "%s" {
+	policy = "write"
+}
+service_prefix "" {
+	policy = "read"
+}`
+
+	// This is the template of the synthetic policy of service identities.
+	aclPolicyTemplateServiceIdentity = `
+service "%[1]s" {
+	policy = "write"
+}
+service "%[1]s-sidecar-proxy" {
+	policy = "write"
+}
+service_prefix "" {
+	policy = "read"
+}
+node_prefix "" {
+	policy = "read"
+}`
 )
 
 func ACLIDReserved(id string) bool {
@@ -112,6 +138,9 @@ type ACLIdentity interface {
 	SecretToken() string
 	PolicyIDs() []string
 	EmbeddedPolicy() *ACLPolicy
+	NodeIdentityList() []*ACLNodeIdentity
+	ServiceIdentityList() []*ACLServiceIdentity
+	IsExpired(asOf time.Time) bool
 }
 
 type ACLTokenPolicyLink struct {
@@ -119,6 +148,110 @@ type ACLTokenPolicyLink struct {
 	Name string `hash:"ignore"`
 }
 
+// ACLNodeIdentity represents a high-level grant of all necessary privileges
+// to assume the identity of the named Node in the Catalog and within the
+// associated datacenter.
+type ACLNodeIdentity struct {
+	// NodeName identifies the Node that this identity authorizes access to
+	NodeName string
+
+	// Datacenter is required and is the datacenter in which the node
+	// identity is valid
+	Datacenter string
+}
+
+func (s *ACLNodeIdentity) Clone() *ACLNodeIdentity {
+	s2 := *s
+	return &s2
+}
+
+func (s *ACLNodeIdentity) AddToHash(h hash.Hash) {
+	h.Write([]byte(s.NodeName))
+	h.Write([]byte(s.Datacenter))
+}
+
+func (s *ACLNodeIdentity) EstimateSize() int {
+	return len(s.NodeName) + len(s.Datacenter)
+}
+
+// SyntheticPolicy returns the policy granting the privileges of the node
+// identity. The policy is scoped to the datacenter of the identity.
+func (s *ACLNodeIdentity) SyntheticPolicy() *ACLPolicy {
+	// Given that we validate this string name before persisting, we do not
+	// have to escape it before doing the following interpolation.
+	rules := fmt.Sprintf(aclPolicyTemplateNodeIdentity, s.NodeName)
+
+	hasher := fnv.New128a()
+	hashID := fmt.Sprintf("%x", hasher.Sum([]byte(rules)))
+
+	policy := &ACLPolicy{}
+	policy.ID = hashID
+	policy.Name = fmt.Sprintf("synthetic-policy-%s", hashID)
+	policy.Description = "synthetic policy"
+	policy.Rules = rules
+	policy.Syntax = acl.SyntaxCurrent
+	policy.Datacenters = []string{s.Datacenter}
+	policy.SetHash(true)
+	return policy
+}
+
+// ACLServiceIdentity represents a high-level grant of all necessary privileges
+// to register the named service, and its sidecar proxy, and to discover other
+// services and nodes.
+type ACLServiceIdentity struct {
+	// ServiceName is the name of the service the identity may register
+	ServiceName string
+
+	// Datacenters are the datacenters in which the service identity is
+	// valid. It is valid in all of them if empty.
+	Datacenters []string `json:",omitempty"`
+}
+
+func (s *ACLServiceIdentity) Clone() *ACLServiceIdentity {
+	s2 := *s
+	s2.Datacenters = append([]string(nil), s.Datacenters...)
+	return &s2
+}
+
+func (s *ACLServiceIdentity) AddToHash(h hash.Hash) {
+	h.Write([]byte(s.ServiceName))
+	for _, dc := range s.Datacenters {
+		h.Write([]byte(dc))
+	}
+}
+
+func (s *ACLServiceIdentity) EstimateSize() int {
+	size := len(s.ServiceName)
+	for _, dc := range s.Datacenters {
+		size += len(dc)
+	}
+	return size
+}
+
+// SyntheticPolicy returns the policy granting the privileges of the service
+// identity. The policy is scoped to the datacenters of the identity.
+func (s *ACLServiceIdentity) SyntheticPolicy() *ACLPolicy {
+	// Given that we validate this string name before persisting, we do not
+	// have to escape it before doing the following interpolation.
+	rules := fmt.Sprintf(aclPolicyTemplateServiceIdentity, s.ServiceName)
+
+	// The datacenters are part of the ID as the same rules may be scoped to
+	// different datacenters by different identities.
+	hasher := fnv.New128a()
+	hasher.Write([]byte(strings.Join(s.Datacenters, ",")))
+	hashID := fmt.Sprintf("%x", hasher.Sum([]byte(rules)))
+
+	policy := &ACLPolicy{}
+	policy.ID = hashID
+	policy.Name = fmt.Sprintf("synthetic-policy-%s", hashID)
+	policy.Description = "synthetic policy"
+	policy.Rules = rules
+	policy.Syntax = acl.SyntaxCurrent
+	policy.Datacenters = append([]string(nil), s.Datacenters...)
+	policy.SetHash(true)
+	return policy
+}
+
 type ACLToken struct {
 	// This is the UUID used for tracking and management purposes
 	AccessorID string
@@ -134,6 +267,14 @@ type ACLToken struct {
 	// the list of policy names gets validated and the policy IDs get stored herein
 	Policies []ACLTokenPolicyLink
 
+	// List of node identities that should be used to generate synthetic
+	// policies granting the privileges of the named nodes
+	NodeIdentities []*ACLNodeIdentity `json:",omitempty"`
+
+	// List of service identities that should be used to generate synthetic
+	// policies granting the privileges of the named services
+	ServiceIdentities []*ACLServiceIdentity `json:",omitempty"`
+
 	// Type is the V1 Token Type
 	// DEPRECATED (ACL-Legacy-Compat) - remove once we no longer support v1 ACL compat
 	// Even though we are going to auto upgrade management tokens we still
@@ -149,9 +290,28 @@ type ACLToken struct {
 	// to the ACL datacenter and replicated to others.
 	Local bool
 
+	// AuthMethod is the name of the auth method this token was created
+	// with by a login. It is empty for tokens created any other way.
+	AuthMethod string `json:",omitempty"`
+
+	// IntroducedBy is the accessor ID of the introduction token this agent
+	// token was created with by ACL.AgentIntroduce. Only that token may
+	// revoke it again. It is empty for tokens created any other way.
+	IntroducedBy string `json:",omitempty"`
+
 	// The time when this token was created
 	CreateTime time.Time `json:",omitempty"`
 
+	// ExpirationTime is the time after which the token is treated as
+	// revoked and gets deleted by the leader. A nil value means the token
+	// never expires. It is a pointer so it can be omitted from JSON.
+	ExpirationTime *time.Time `json:",omitempty"`
+
+	// ExpirationTTL can be given instead of ExpirationTime when a token is
+	// created, to expire it at CreateTime+ExpirationTTL. It is only used
+	// to set ExpirationTime and is never persisted.
+	ExpirationTTL time.Duration `json:",omitempty"`
+
 	// Hash of the contents of the token
 	//
 	// This is needed mainly for replication purposes. When replicating from
@@ -179,6 +339,44 @@ func (t *ACLToken) PolicyIDs() []string {
 	return ids
 }
 
+func (t *ACLToken) NodeIdentityList() []*ACLNodeIdentity {
+	if len(t.NodeIdentities) == 0 {
+		return nil
+	}
+
+	out := make([]*ACLNodeIdentity, 0, len(t.NodeIdentities))
+	for _, s := range t.NodeIdentities {
+		out = append(out, s.Clone())
+	}
+	return out
+}
+
+func (t *ACLToken) ServiceIdentityList() []*ACLServiceIdentity {
+	if len(t.ServiceIdentities) == 0 {
+		return nil
+	}
+
+	out := make([]*ACLServiceIdentity, 0, len(t.ServiceIdentities))
+	for _, s := range t.ServiceIdentities {
+		out = append(out, s.Clone())
+	}
+	return out
+}
+
+// HasExpirationTime returns whether the token expires.
+func (t *ACLToken) HasExpirationTime() bool {
+	return t.ExpirationTime != nil && !t.ExpirationTime.IsZero()
+}
+
+// IsExpired returns whether the token was past its expiration time as of
+// the given time.
+func (t *ACLToken) IsExpired(asOf time.Time) bool {
+	if asOf.IsZero() || !t.HasExpirationTime() {
+		return false
+	}
+	return t.ExpirationTime.Before(asOf)
+}
+
 func (t *ACLToken) EmbeddedPolicy() *ACLPolicy {
 	// DEPRECATED (ACL-Legacy-Compat)
 	//
@@ -232,6 +430,14 @@ func (t *ACLToken) SetHash(force bool) []byte {
 			hash.Write([]byte(link.ID))
 		}
 
+		for _, nodeIdent := range t.NodeIdentities {
+			nodeIdent.AddToHash(hash)
+		}
+
+		for _, svcIdent := range t.ServiceIdentities {
+			svcIdent.AddToHash(hash)
+		}
+
 		// Finalize the hash
 		hashVal := hash.Sum(nil)
 
@@ -241,12 +447,82 @@ func (t *ACLToken) SetHash(force bool) []byte {
 	return t.Hash
 }
 
+// FilterValues returns the values of the token fields which can be used in
+// filter expressions.
+func (t *ACLToken) FilterValues(selector string) ([]string, bool) {
+	switch selector {
+	case "AccessorID":
+		return []string{t.AccessorID}, true
+	case "Description":
+		return []string{t.Description}, true
+	case "Local":
+		return []string{strconv.FormatBool(t.Local)}, true
+	case "Legacy":
+		return []string{strconv.FormatBool(t.Rules != "")}, true
+	case "AuthMethod":
+		return []string{t.AuthMethod}, true
+	case "CreateTime":
+		return []string{filterTime(t.CreateTime)}, true
+	case "ExpirationTime":
+		if t.ExpirationTime == nil {
+			return []string{""}, true
+		}
+		return []string{filterTime(*t.ExpirationTime)}, true
+	case "Policies.ID":
+		var out []string
+		for _, link := range t.Policies {
+			out = append(out, link.ID)
+		}
+		return out, true
+	case "Policies.Name":
+		var out []string
+		for _, link := range t.Policies {
+			out = append(out, link.Name)
+		}
+		return out, true
+	case "NodeIdentities.NodeName":
+		var out []string
+		for _, nodeIdent := range t.NodeIdentities {
+			out = append(out, nodeIdent.NodeName)
+		}
+		return out, true
+	case "NodeIdentities.Datacenter":
+		var out []string
+		for _, nodeIdent := range t.NodeIdentities {
+			out = append(out, nodeIdent.Datacenter)
+		}
+		return out, true
+	case "ServiceIdentities.ServiceName":
+		var out []string
+		for _, svcIdent := range t.ServiceIdentities {
+			out = append(out, svcIdent.ServiceName)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// filterTime formats a time for filter expressions, in UTC so that the times
+// are ordered as strings. The zero time is empty.
+func filterTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 func (t *ACLToken) EstimateSize() int {
-	// 33 = 16 (RaftIndex) + 8 (Hash) + 8 (CreateTime) + 1 (Local)
-	size := 33 + len(t.AccessorID) + len(t.SecretID) + len(t.Description) + len(t.Type) + len(t.Rules)
+	// 41 = 16 (RaftIndex) + 8 (Hash) + 8 (CreateTime) + 8 (ExpirationTime) + 1 (Local)
+	size := 41 + len(t.AccessorID) + len(t.SecretID) + len(t.Description) + len(t.Type) + len(t.Rules) + len(t.AuthMethod) + len(t.IntroducedBy)
 	for _, link := range t.Policies {
 		size += len(link.ID) + len(link.Name)
 	}
+	for _, nodeIdent := range t.NodeIdentities {
+		size += nodeIdent.EstimateSize()
+	}
+	for _, svcIdent := range t.ServiceIdentities {
+		size += svcIdent.EstimateSize()
+	}
 	return size
 }
 
@@ -254,30 +530,38 @@ func (t *ACLToken) EstimateSize() int {
 type ACLTokens []*ACLToken
 
 type ACLTokenListStub struct {
-	AccessorID  string
-	Description string
-	Policies    []ACLTokenPolicyLink
-	Local       bool
-	CreateTime  time.Time `json:",omitempty"`
-	Hash        []byte
-	CreateIndex uint64
-	ModifyIndex uint64
-	Legacy      bool `json:",omitempty"`
+	AccessorID        string
+	Description       string
+	Policies          []ACLTokenPolicyLink
+	NodeIdentities    []*ACLNodeIdentity    `json:",omitempty"`
+	ServiceIdentities []*ACLServiceIdentity `json:",omitempty"`
+	Local             bool
+	AuthMethod        string     `json:",omitempty"`
+	CreateTime        time.Time  `json:",omitempty"`
+	ExpirationTime    *time.Time `json:",omitempty"`
+	Hash              []byte
+	CreateIndex       uint64
+	ModifyIndex       uint64
+	Legacy            bool `json:",omitempty"`
 }
 
 type ACLTokenListStubs []*ACLTokenListStub
 
 func (token *ACLToken) Stub() *ACLTokenListStub {
 	return &ACLTokenListStub{
-		AccessorID:  token.AccessorID,
-		Description: token.Description,
-		Policies:    token.Policies,
-		Local:       token.Local,
-		CreateTime:  token.CreateTime,
-		Hash:        token.Hash,
-		CreateIndex: token.CreateIndex,
-		ModifyIndex: token.ModifyIndex,
-		Legacy:      token.Rules != "",
+		AccessorID:        token.AccessorID,
+		Description:       token.Description,
+		Policies:          token.Policies,
+		NodeIdentities:    token.NodeIdentities,
+		ServiceIdentities: token.ServiceIdentities,
+		Local:             token.Local,
+		AuthMethod:        token.AuthMethod,
+		CreateTime:        token.CreateTime,
+		ExpirationTime:    token.ExpirationTime,
+		Hash:              token.Hash,
+		CreateIndex:       token.CreateIndex,
+		ModifyIndex:       token.ModifyIndex,
+		Legacy:            token.Rules != "",
 	}
 }
 
@@ -339,6 +623,24 @@ type ACLPolicyListStub struct {
 	ModifyIndex uint64
 }
 
+// FilterValues returns the values of the policy fields which can be used in
+// filter expressions.
+func (p *ACLPolicy) FilterValues(selector string) ([]string, bool) {
+	switch selector {
+	case "ID":
+		return []string{p.ID}, true
+	case "Name":
+		return []string{p.Name}, true
+	case "Description":
+		return []string{p.Description}, true
+	case "Rules":
+		return []string{p.Rules}, true
+	case "Datacenters":
+		return p.Datacenters, true
+	}
+	return nil, false
+}
+
 func (p *ACLPolicy) Stub() *ACLPolicyListStub {
 	return &ACLPolicyListStub{
 		ID:          p.ID,
@@ -511,6 +813,18 @@ func (r *ACLTokenUpsertRequest) RequestDatacenter() string {
 	return r.Datacenter
 }
 
+// ACLAgentIntroduceRequest is used by agents to exchange an introduction
+// token for an agent token with the node identity of the agent.
+type ACLAgentIntroduceRequest struct {
+	NodeIdentity ACLNodeIdentity // The node identity of the introduced agent
+	Datacenter   string          // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLAgentIntroduceRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
 // ACLTokenReadRequest is used for token read operations at the RPC layer
 type ACLTokenReadRequest struct {
 	TokenID     string         // id used for the token lookup
@@ -534,12 +848,35 @@ func (r *ACLTokenDeleteRequest) RequestDatacenter() string {
 	return r.Datacenter
 }
 
+// ACLTokenFilterDeleteRequest is used at the RPC layer to delete all the
+// tokens matching a filter expression
+type ACLTokenFilterDeleteRequest struct {
+	Filter     string // The filter expression selecting the tokens
+	DryRun     bool   // Only return the matching tokens without deleting them
+	Datacenter string // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLTokenFilterDeleteRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLTokenFilterDeleteResponse returns the tokens which were deleted, or
+// which would have been deleted for a dry run
+type ACLTokenFilterDeleteResponse struct {
+	Tokens ACLTokenListStubs
+}
+
 // ACLTokenListRequest is used for token listing operations at the RPC layer
 type ACLTokenListRequest struct {
-	IncludeLocal  bool   // Whether local tokens should be included
-	IncludeGlobal bool   // Whether global tokens should be included
-	Policy        string // Policy filter
-	Datacenter    string // The datacenter to perform the request within
+	IncludeLocal   bool   // Whether local tokens should be included
+	IncludeGlobal  bool   // Whether global tokens should be included
+	Policy         string // Policy filter
+	AccessorPrefix string // Accessor ID prefix filter
+	Next           string // Accessor ID which starts the page, from the Next of the previous page
+	Limit          int    // Maximum number of tokens in the page, 0 lists all tokens
+	Filter         string // Filter expression over the token fields, see ACLToken.FilterValues
+	Datacenter     string // The datacenter to perform the request within
 	QueryOptions
 }
 
@@ -551,6 +888,11 @@ func (r *ACLTokenListRequest) RequestDatacenter() string {
 // of the tokens
 type ACLTokenListResponse struct {
 	Tokens ACLTokenListStubs
+
+	// Next is the accessor ID which starts the next page of a paginated
+	// listing, it is empty on the last page.
+	Next string
+
 	QueryMeta
 }
 
@@ -645,6 +987,7 @@ func (r *ACLPolicyReadRequest) RequestDatacenter() string {
 // ACLPolicyListRequest is used at the RPC layer to request a listing of policies
 type ACLPolicyListRequest struct {
 	DCScope    string
+	Filter     string // Filter expression over the policy fields, see ACLPolicy.FilterValues
 	Datacenter string // The datacenter to perform the request within
 	QueryOptions
 }
@@ -681,6 +1024,77 @@ type ACLPoliciesResponse struct {
 	QueryMeta
 }
 
+// ACLPolicyImpactRequest is used at the RPC layer to request the impact of a
+// proposed change of a policy on the tokens linked to it
+type ACLPolicyImpactRequest struct {
+	Policy     ACLPolicy // The proposed policy, which must already exist
+	Delete     bool      // Whether the policy would be deleted instead
+	Datacenter string    // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLPolicyImpactRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLPolicyImpactResponse lists the tokens whose access would change with a
+// proposed change of a policy
+type ACLPolicyImpactResponse struct {
+	Tokens []*ACLTokenImpact
+}
+
+// ACLTokenImpact is the change of the access of a single token
+type ACLTokenImpact struct {
+	AccessorID  string
+	Description string
+	Local       bool
+	Changes     []*ACLAccessChange
+}
+
+// ACLAccessChange is the change of the access of a token to a resource. The
+// access levels are "deny", "read" and "write".
+type ACLAccessChange struct {
+	Resource string
+	Segment  string `json:",omitempty"`
+	Before   string
+	After    string
+}
+
+// ACLAuthorizationRequest is a single check of a batch authorization
+// request, whether a token has the access to a resource. The access is "read",
+// "write" or "list" for keys.
+type ACLAuthorizationRequest struct {
+	Resource string
+	Segment  string `json:",omitempty"`
+	Access   string
+}
+
+// ACLAuthorizationResponse is the decision for a single check of a batch
+// authorization request
+type ACLAuthorizationResponse struct {
+	ACLAuthorizationRequest
+	Allow bool
+}
+
+// ACLPolicyValidateResponse is the result of checking the rules of a policy
+// without storing it
+type ACLPolicyValidateResponse struct {
+	Valid bool
+	Error *ACLRulesError `json:",omitempty"`
+
+	// CanonicalRules are the rules in the canonical form, if they are valid
+	// and it was requested
+	CanonicalRules string `json:",omitempty"`
+}
+
+// ACLRulesError is an error in the rules of a policy. The line and column are
+// zero if the position of the error isn't known.
+type ACLRulesError struct {
+	Message string
+	Line    int `json:",omitempty"`
+	Column  int `json:",omitempty"`
+}
+
 // ACLPolicyBatchUpsertRequest is used at the Raft layer for batching
 // multiple policy creations and updates
 //
@@ -696,3 +1110,284 @@ type ACLPolicyBatchUpsertRequest struct {
 type ACLPolicyBatchDeleteRequest struct {
 	PolicyIDs []string
 }
+
+// ACLAuthMethod is a way for workloads to exchange a credential of another
+// system, like a Kubernetes service account token, for an ACL token with a
+// login instead of having a token provisioned for them.
+type ACLAuthMethod struct {
+	// Name is the unique name of the auth method.
+	Name string
+
+	// Type is the type of the auth method, like "kubernetes", which
+	// determines how the credentials are validated.
+	Type string
+
+	// Human readable description (Optional)
+	Description string
+
+	// Policies are linked to every token created with a login to this auth
+	// method.
+	Policies []ACLTokenPolicyLink `json:",omitempty"`
+
+	// MaxTokenTTL is the time after which the tokens created with a login
+	// to this auth method expire. Zero means they don't expire.
+	MaxTokenTTL time.Duration `json:",omitempty"`
+
+	// Config is the configuration specific to the type of the auth method.
+	Config map[string]interface{}
+
+	// Embedded Raft Metadata
+	RaftIndex `hash:"ignore"`
+}
+
+type ACLAuthMethodListStub struct {
+	Name        string
+	Type        string
+	Description string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+func (m *ACLAuthMethod) Stub() *ACLAuthMethodListStub {
+	return &ACLAuthMethodListStub{
+		Name:        m.Name,
+		Type:        m.Type,
+		Description: m.Description,
+		CreateIndex: m.CreateIndex,
+		ModifyIndex: m.ModifyIndex,
+	}
+}
+
+type ACLAuthMethods []*ACLAuthMethod
+type ACLAuthMethodListStubs []*ACLAuthMethodListStub
+
+func (methods ACLAuthMethods) Sort() {
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+}
+
+// ACLAuthMethodUpsertRequest is used at the RPC layer for creation and update requests
+type ACLAuthMethodUpsertRequest struct {
+	AuthMethod ACLAuthMethod // The auth method to upsert
+	Datacenter string        // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLAuthMethodUpsertRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLAuthMethodDeleteRequest is used at the RPC layer deletion requests
+type ACLAuthMethodDeleteRequest struct {
+	AuthMethodName string // The name of the auth method to delete
+	Datacenter     string // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLAuthMethodDeleteRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLAuthMethodReadRequest is used at the RPC layer to perform auth method read operations
+type ACLAuthMethodReadRequest struct {
+	AuthMethodName string // name used for the auth method lookup
+	Datacenter     string // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLAuthMethodReadRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLAuthMethodListRequest is used at the RPC layer to request a listing of auth methods
+type ACLAuthMethodListRequest struct {
+	Datacenter string // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLAuthMethodListRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+type ACLAuthMethodListResponse struct {
+	AuthMethods ACLAuthMethodListStubs
+	QueryMeta
+}
+
+// ACLAuthMethodResponse returns a single auth method + metadata
+type ACLAuthMethodResponse struct {
+	AuthMethod *ACLAuthMethod
+	QueryMeta
+}
+
+// ACLAuthMethodBatchUpsertRequest is used at the Raft layer for batching
+// multiple auth method creations and updates
+type ACLAuthMethodBatchUpsertRequest struct {
+	AuthMethods ACLAuthMethods
+}
+
+// ACLAuthMethodBatchDeleteRequest is used at the Raft layer for batching
+// multiple auth method deletions
+type ACLAuthMethodBatchDeleteRequest struct {
+	AuthMethodNames []string
+}
+
+const (
+	// BindingRuleBindTypeService binds to a service identity with the given
+	// name.
+	BindingRuleBindTypeService = "service"
+
+	// BindingRuleBindTypeNode binds to a node identity with the given name,
+	// in the datacenter of the login.
+	BindingRuleBindTypeNode = "node"
+
+	// BindingRuleBindTypePolicy binds to the policy with the given name.
+	BindingRuleBindTypePolicy = "policy"
+)
+
+// ACLBindingRule maps the identities of the workloads which log in with an
+// auth method to the privileges of the token created for them.
+type ACLBindingRule struct {
+	// ID is the internal UUID associated with the binding rule
+	ID string
+
+	// Human readable description (Optional)
+	Description string
+
+	// AuthMethod is the name of the auth method the rule applies to.
+	AuthMethod string
+
+	// Selector is a filter expression on the fields of the identity of the
+	// workload, e.g. `serviceaccount.namespace == default`. The rule applies
+	// to all the logins of the auth method if it is empty.
+	Selector string
+
+	// BindType is the kind of privilege granted by the rule, which is one
+	// of "service", "node" or "policy".
+	BindType string
+
+	// BindName is the name of the service identity, node identity or policy
+	// granted by the rule. The fields of the identity may be interpolated
+	// with ${field}, e.g. "${serviceaccount.name}".
+	BindName string
+
+	// Embedded Raft Metadata
+	RaftIndex `hash:"ignore"`
+}
+
+func (r *ACLBindingRule) Clone() *ACLBindingRule {
+	r2 := *r
+	return &r2
+}
+
+type ACLBindingRules []*ACLBindingRule
+
+func (rules ACLBindingRules) Sort() {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// ACLBindingRuleUpsertRequest is used at the RPC layer for creation and update requests
+type ACLBindingRuleUpsertRequest struct {
+	BindingRule ACLBindingRule // The binding rule to upsert
+	Datacenter  string         // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLBindingRuleUpsertRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLBindingRuleDeleteRequest is used at the RPC layer deletion requests
+type ACLBindingRuleDeleteRequest struct {
+	BindingRuleID string // The id of the binding rule to delete
+	Datacenter    string // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLBindingRuleDeleteRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLBindingRuleReadRequest is used at the RPC layer to perform binding rule read operations
+type ACLBindingRuleReadRequest struct {
+	BindingRuleID string // id used for the binding rule lookup
+	Datacenter    string // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLBindingRuleReadRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLBindingRuleListRequest is used at the RPC layer to request a listing of binding rules
+type ACLBindingRuleListRequest struct {
+	AuthMethod string // optional filter
+	Datacenter string // The datacenter to perform the request within
+	QueryOptions
+}
+
+func (r *ACLBindingRuleListRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+type ACLBindingRuleListResponse struct {
+	BindingRules ACLBindingRules
+	QueryMeta
+}
+
+// ACLBindingRuleResponse returns a single binding rule + metadata
+type ACLBindingRuleResponse struct {
+	BindingRule *ACLBindingRule
+	QueryMeta
+}
+
+// ACLBindingRuleBatchUpsertRequest is used at the Raft layer for batching
+// multiple binding rule creations and updates
+type ACLBindingRuleBatchUpsertRequest struct {
+	BindingRules ACLBindingRules
+}
+
+// ACLBindingRuleBatchDeleteRequest is used at the Raft layer for batching
+// multiple binding rule deletions
+type ACLBindingRuleBatchDeleteRequest struct {
+	BindingRuleIDs []string
+}
+
+// ACLLoginParams are the credentials presented to an auth method in a login.
+type ACLLoginParams struct {
+	// AuthMethod is the name of the auth method to log in with.
+	AuthMethod string
+
+	// BearerToken is the credential which is validated by the auth
+	// method, like a Kubernetes service account JWT.
+	BearerToken string
+
+	// Meta is added to the description of the created token.
+	Meta map[string]string `json:",omitempty"`
+}
+
+// ACLLoginRequest is used at the RPC layer to exchange the credentials of an
+// auth method for a token
+type ACLLoginRequest struct {
+	Auth       *ACLLoginParams
+	Datacenter string // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLLoginRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// ACLLogoutRequest is used at the RPC layer to delete the token of the
+// request, which must have been created with a login
+type ACLLogoutRequest struct {
+	Datacenter string // The datacenter to perform the request within
+	WriteRequest
+}
+
+func (r *ACLLogoutRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/acl"
 
@@ -120,6 +121,69 @@ func TestStructs_ACLToken_EmbeddedPolicy(t *testing.T) {
 	})
 }
 
+func TestStructs_ACLNodeIdentity_SyntheticPolicy(t *testing.T) {
+	t.Parallel()
+
+	nodeIdent := &ACLNodeIdentity{
+		NodeName:   "web-1",
+		Datacenter: "dc1",
+	}
+
+	policy := nodeIdent.SyntheticPolicy()
+	require.NotEmpty(t, policy.ID)
+	require.Equal(t, "synthetic-policy-"+policy.ID, policy.Name)
+	require.Equal(t, []string{"dc1"}, policy.Datacenters)
+	require.Equal(t, acl.SyntaxCurrent, policy.Syntax)
+	require.NotNil(t, policy.Hash)
+
+	parsed, err := acl.NewPolicyFromSource("", 0, policy.Rules, policy.Syntax, nil)
+	require.NoError(t, err)
+	authz, err := acl.NewPolicyAuthorizer(acl.DenyAll(), []*acl.Policy{parsed}, nil)
+	require.NoError(t, err)
+	require.True(t, authz.NodeWrite("web-1", nil))
+	require.False(t, authz.NodeWrite("web-2", nil))
+	require.True(t, authz.ServiceRead("api"))
+	require.False(t, authz.ServiceWrite("api", nil))
+
+	// The same identity in another datacenter shares the rules but not the
+	// scope of the policy.
+	other := (&ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc2"}).SyntheticPolicy()
+	require.Equal(t, policy.ID, other.ID)
+	require.Equal(t, []string{"dc2"}, other.Datacenters)
+}
+
+func TestStructs_ACLServiceIdentity_SyntheticPolicy(t *testing.T) {
+	t.Parallel()
+
+	svcIdent := &ACLServiceIdentity{
+		ServiceName: "web",
+		Datacenters: []string{"dc1", "dc2"},
+	}
+
+	policy := svcIdent.SyntheticPolicy()
+	require.NotEmpty(t, policy.ID)
+	require.Equal(t, "synthetic-policy-"+policy.ID, policy.Name)
+	require.Equal(t, []string{"dc1", "dc2"}, policy.Datacenters)
+	require.Equal(t, acl.SyntaxCurrent, policy.Syntax)
+	require.NotNil(t, policy.Hash)
+
+	parsed, err := acl.NewPolicyFromSource("", 0, policy.Rules, policy.Syntax, nil)
+	require.NoError(t, err)
+	authz, err := acl.NewPolicyAuthorizer(acl.DenyAll(), []*acl.Policy{parsed}, nil)
+	require.NoError(t, err)
+	require.True(t, authz.ServiceWrite("web", nil))
+	require.True(t, authz.ServiceWrite("web-sidecar-proxy", nil))
+	require.False(t, authz.ServiceWrite("api", nil))
+	require.True(t, authz.ServiceRead("api"))
+	require.True(t, authz.NodeRead("web-1"))
+	require.False(t, authz.NodeWrite("web-1", nil))
+
+	// The same identity scoped to all datacenters has a policy of its own.
+	other := (&ACLServiceIdentity{ServiceName: "web"}).SyntheticPolicy()
+	require.NotEqual(t, policy.ID, other.ID)
+	require.Empty(t, other.Datacenters)
+}
+
 func TestStructs_ACLToken_SetHash(t *testing.T) {
 	t.Parallel()
 
@@ -163,6 +227,24 @@ func TestStructs_ACLToken_SetHash(t *testing.T) {
 		h := token.SetHash(true)
 		require.NotEqual(t, original, h)
 	})
+
+	t.Run("Node Identities - Generate", func(t *testing.T) {
+		original := token.Hash
+		token.NodeIdentities = []*ACLNodeIdentity{
+			&ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+		}
+		h := token.SetHash(true)
+		require.NotEqual(t, original, h)
+	})
+
+	t.Run("Service Identities - Generate", func(t *testing.T) {
+		original := token.Hash
+		token.ServiceIdentities = []*ACLServiceIdentity{
+			&ACLServiceIdentity{ServiceName: "web"},
+		}
+		h := token.SetHash(true)
+		require.NotEqual(t, original, h)
+	})
 }
 
 func TestStructs_ACLToken_EstimateSize(t *testing.T) {
@@ -188,7 +270,31 @@ func TestStructs_ACLToken_EstimateSize(t *testing.T) {
 
 	// this test is very contrived. Basically just tests that the
 	// math is okay and returns the value.
-	require.Equal(t, 120, token.EstimateSize())
+	require.Equal(t, 128, token.EstimateSize())
+}
+
+func TestStructs_ACLToken_IsExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	token := &ACLToken{}
+	require.False(t, token.HasExpirationTime())
+	require.False(t, token.IsExpired(now))
+
+	token.ExpirationTime = &time.Time{}
+	require.False(t, token.HasExpirationTime())
+	require.False(t, token.IsExpired(now))
+
+	token.ExpirationTime = &future
+	require.True(t, token.HasExpirationTime())
+	require.False(t, token.IsExpired(now))
+	require.False(t, token.IsExpired(time.Time{}))
+
+	token.ExpirationTime = &past
+	require.True(t, token.IsExpired(now))
 }
 
 func TestStructs_ACLToken_Stub(t *testing.T) {
@@ -11,6 +11,7 @@ const (
 	SerfCheckName                       = "Serf Health Status"
 	SerfCheckAliveOutput                = "Agent alive and reachable"
 	SerfCheckFailedOutput               = "Agent not live or unreachable"
+	SerfCheckLeftOutput                 = "Agent left, services retained"
 )
 
 const (
@@ -99,6 +99,11 @@ type CASignRequest struct {
 	// CSR is the PEM-encoded CSR.
 	CSR string
 
+	// Node is the name of the agent requesting the certificate. The leader
+	// uses it to report which agents still hold certificates of an old root
+	// during a rotation.
+	Node string
+
 	// WriteRequest is a common struct containing ACL tokens and other
 	// write-related common elements for requests.
 	WriteRequest
@@ -134,6 +139,39 @@ type IssuedCert struct {
 	RaftIndex
 }
 
+// CALeaf describes the most recent leaf certificate the leader signed for a
+// service on a node.
+type CALeaf struct {
+	// Node and Service identify the agent and the service the certificate
+	// was issued to.
+	Node    string
+	Service string
+
+	// RootID is the ID of the root which was active when the certificate was
+	// signed.
+	RootID string
+
+	// SerialNumber is the serial number of the certificate.
+	SerialNumber string
+
+	// ValidAfter and ValidBefore are the validity periods for the
+	// certificate.
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
+// IndexedCALeaves is the list of leaf certificates signed by the leader which
+// are still valid.
+type IndexedCALeaves struct {
+	// ActiveRootID is the ID of the root which currently signs certificates.
+	ActiveRootID string
+
+	// Leaves are the certificates, sorted by node and service.
+	Leaves []*CALeaf
+
+	QueryMeta
+}
+
 // CAOp is the operation for a request related to intentions.
 type CAOp string
 
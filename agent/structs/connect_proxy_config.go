@@ -43,6 +43,58 @@ type ConnectProxyConfig struct {
 	// Upstreams describes any upstream dependencies the proxy instance should
 	// setup.
 	Upstreams Upstreams `json:",omitempty"`
+
+	// Mode is how the application reaches its upstreams. In the transparent
+	// mode the outbound traffic of the application is redirected to the proxy,
+	// which routes it by the virtual IP of the destination service, so the
+	// upstreams don't have to be configured.
+	Mode ProxyMode `json:",omitempty"`
+
+	// TransparentProxy is the configuration of the transparent mode.
+	TransparentProxy TransparentProxyConfig `json:",omitempty"`
+}
+
+// ProxyMode is how the application of a proxy reaches its upstreams.
+type ProxyMode string
+
+const (
+	// ProxyModeDefault is the mode when it isn't configured, which is the
+	// direct mode.
+	ProxyModeDefault ProxyMode = ""
+
+	// ProxyModeDirect is the mode in which the application connects to the
+	// local listeners of its explicit upstreams.
+	ProxyModeDirect ProxyMode = "direct"
+
+	// ProxyModeTransparent is the mode in which the outbound traffic of the
+	// application is redirected to the proxy.
+	ProxyModeTransparent ProxyMode = "transparent"
+)
+
+// DefaultOutboundListenerPort is the default port of the outbound listener of
+// proxies in the transparent mode.
+const DefaultOutboundListenerPort = 15001
+
+// TransparentProxyConfig is the configuration of a proxy in the transparent
+// mode.
+type TransparentProxyConfig struct {
+	// OutboundListenerPort is the port the outbound traffic of the application
+	// is redirected to. It defaults to DefaultOutboundListenerPort.
+	OutboundListenerPort int `json:",omitempty"`
+
+	// AllowedServices are the services the application may reach through the
+	// proxy. If empty, they are the services the intentions with the
+	// destination service as source allow.
+	AllowedServices []string `json:",omitempty"`
+}
+
+// OutboundListenerPort returns the port of the outbound listener of a proxy
+// in the transparent mode.
+func (c *ConnectProxyConfig) OutboundListenerPort() int {
+	if c.TransparentProxy.OutboundListenerPort > 0 {
+		return c.TransparentProxy.OutboundListenerPort
+	}
+	return DefaultOutboundListenerPort
 }
 
 // ToAPI returns the api struct with the same fields. We have duplicates to
@@ -57,6 +109,11 @@ func (c *ConnectProxyConfig) ToAPI() *api.AgentServiceConnectProxyConfig {
 		LocalServicePort:       c.LocalServicePort,
 		Config:                 c.Config,
 		Upstreams:              c.Upstreams.ToAPI(),
+		Mode:                   api.ProxyMode(c.Mode),
+		TransparentProxy: api.TransparentProxyConfig{
+			OutboundListenerPort: c.TransparentProxy.OutboundListenerPort,
+			AllowedServices:      c.TransparentProxy.AllowedServices,
+		},
 	}
 }
 
@@ -8,19 +8,23 @@ import (
 const (
 	errNoLeader                   = "No cluster leader"
 	errNoDCPath                   = "No path to datacenter"
+	errDCRouteDenied              = "Forwarding to datacenter is denied"
 	errNoServers                  = "No known Consul servers"
 	errNotReadyForConsistentReads = "Not ready to serve consistent reads"
 	errSegmentsNotSupported       = "Network segments are not supported in this version of Consul"
 	errRPCRateExceeded            = "RPC rate limit exceeded"
+	errTooManyBlockingQueries     = "Too many concurrent blocking queries for token"
 )
 
 var (
 	ErrNoLeader                   = errors.New(errNoLeader)
 	ErrNoDCPath                   = errors.New(errNoDCPath)
+	ErrDCRouteDenied              = errors.New(errDCRouteDenied)
 	ErrNoServers                  = errors.New(errNoServers)
 	ErrNotReadyForConsistentReads = errors.New(errNotReadyForConsistentReads)
 	ErrSegmentsNotSupported       = errors.New(errSegmentsNotSupported)
 	ErrRPCRateExceeded            = errors.New(errRPCRateExceeded)
+	ErrTooManyBlockingQueries     = errors.New(errTooManyBlockingQueries)
 )
 
 func IsErrNoLeader(err error) bool {
@@ -30,3 +34,7 @@ func IsErrNoLeader(err error) bool {
 func IsErrRPCRateExceeded(err error) bool {
 	return err != nil && strings.Contains(err.Error(), errRPCRateExceeded)
 }
+
+func IsErrTooManyBlockingQueries(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errTooManyBlockingQueries)
+}
@@ -0,0 +1,77 @@
+package structs
+
+import (
+	"time"
+)
+
+const (
+	// GossipKeyRotationIdle is the phase between rotations.
+	GossipKeyRotationIdle = "idle"
+
+	// GossipKeyRotationDistributing is the phase in which a new key was
+	// generated and is installed on all nodes.
+	GossipKeyRotationDistributing = "distributing"
+
+	// GossipKeyRotationPromoted is the phase in which the new key is the
+	// primary key and the previous primary key waits to be retired.
+	GossipKeyRotationPromoted = "promoted"
+)
+
+// GossipKeyRotation is the state of the automatic gossip key rotation. It is
+// stored by the servers of the primary datacenter so that a new leader
+// continues a rotation where the previous one stopped.
+type GossipKeyRotation struct {
+	// Phase is the current phase of the rotation and PhaseStarted the time
+	// it was entered.
+	Phase        string
+	PhaseStarted time.Time
+
+	// NewKey is the key which is rotated in and OldKey the primary key it
+	// replaces. They are only set during a rotation.
+	NewKey string
+	OldKey string
+
+	// LastRotation is the time the last rotation finished, or the time the
+	// rotation was enabled.
+	LastRotation time.Time
+
+	RaftIndex
+}
+
+// GossipKeyRotationRequest is used by the leader to update the state of the
+// gossip key rotation.
+type GossipKeyRotationRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// Rotation is the new state of the rotation.
+	Rotation GossipKeyRotation
+
+	// WriteRequest holds the ACL token to go along with this request.
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *GossipKeyRotationRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// GossipKeyRotationStatus provides information about the automatic gossip
+// key rotation. The keys aren't included.
+type GossipKeyRotationStatus struct {
+	// Enabled is true if the keys are rotated automatically. Rotations are
+	// driven by the leader of the primary datacenter.
+	Enabled     bool
+	Interval    time.Duration
+	RetireAfter time.Duration
+
+	Phase        string
+	PhaseStarted time.Time
+	LastRotation time.Time
+	NextRotation time.Time
+
+	// LastError is the last error of the rotation on the current leader
+	// and LastErrorTime when it happened. Failed steps are retried.
+	LastError     string
+	LastErrorTime time.Time
+}
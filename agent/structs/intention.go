@@ -402,6 +402,23 @@ type IntentionQueryCheckResponse struct {
 	Allowed bool
 }
 
+// IntentionImpactResponse is the response for an impact request. It lists the
+// connections between the services of the mesh whose authorization would
+// change if the requested intention change was applied.
+type IntentionImpactResponse struct {
+	// Allowed are the connections which are denied now and would be allowed.
+	Allowed []IntentionConnection
+
+	// Denied are the connections which are allowed now and would be denied.
+	Denied []IntentionConnection
+}
+
+// IntentionConnection is a connection from a source to a destination service.
+type IntentionConnection struct {
+	SourceName      string
+	DestinationName string
+}
+
 // IntentionPrecedenceSorter takes a list of intentions and sorts them
 // based on the match precedence rules for intentions. The intentions
 // closer to the head of the list have higher precedence. i.e. index 0 has
@@ -436,3 +453,14 @@ func (s IntentionPrecedenceSorter) Less(i, j int) bool {
 	}
 	return a.DestinationName < b.DestinationName
 }
+
+// IntentionReplicationStatus provides information about the health of the
+// replication of the intentions from the primary datacenter.
+type IntentionReplicationStatus struct {
+	Enabled          bool
+	Running          bool
+	SourceDatacenter string
+	ReplicatedIndex  uint64
+	LastSuccess      time.Time
+	LastError        time.Time
+}
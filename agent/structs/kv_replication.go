@@ -0,0 +1,47 @@
+package structs
+
+import (
+	"time"
+)
+
+const (
+	// KVReplicationOverwrite makes the replicated prefixes of a secondary
+	// datacenter mirror the primary datacenter. Local changes are
+	// overwritten and keys which don't exist in the primary are deleted.
+	KVReplicationOverwrite = "overwrite"
+
+	// KVReplicationKeepLocal replicates the keys of the primary datacenter
+	// like KVReplicationOverwrite but keeps the keys which only exist in the
+	// secondary datacenter. They are reported as conflicts.
+	KVReplicationKeepLocal = "keep-local"
+)
+
+// KVReplicationStatus provides information about the health of the KV
+// replication.
+type KVReplicationStatus struct {
+	Enabled          bool
+	SourceDatacenter string
+	ConflictPolicy   string
+	Prefixes         []*KVReplicationPrefixStatus
+}
+
+// KVReplicationPrefixStatus provides information about the replication of
+// a single KV prefix.
+type KVReplicationPrefixStatus struct {
+	Prefix          string
+	Running         bool
+	ReplicatedIndex uint64
+
+	// Keys is the number of replicated keys and Conflicts the number of
+	// local keys which don't exist in the primary datacenter and were
+	// kept.
+	Keys      int
+	Conflicts int
+
+	LastSuccess time.Time
+	LastError   time.Time
+
+	// Lag is the time since the prefix was last known to match the primary
+	// datacenter. It is an upper bound of how far the prefix is behind.
+	Lag time.Duration
+}
@@ -102,3 +102,20 @@ type NetworkSegment struct {
 	// for this segment.
 	RPCListener bool
 }
+
+// RPCRoute configures how the servers forward the requests for a remote
+// datacenter.
+type RPCRoute struct {
+	// Datacenter is the datacenter the route applies to.
+	Datacenter string
+
+	// Via maps the datacenters the requests are forwarded through to their
+	// weights. Each request picks one of them at random according to the
+	// weights. The destination itself may be listed to forward a share of
+	// the requests directly.
+	Via map[string]int
+
+	// Deny rejects the requests for the datacenter instead of forwarding
+	// them.
+	Deny bool
+}
@@ -0,0 +1,138 @@
+package structs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// ProtocolTCP is the protocol assumed for a service if its Connect
+	// proxies don't configure one.
+	ProtocolTCP   = "tcp"
+	ProtocolHTTP  = "http"
+	ProtocolHTTP2 = "http2"
+	ProtocolGRPC  = "grpc"
+
+	// ProtocolConfigKey is the key of the opaque proxy and upstream config
+	// which sets the protocol spoken by a service.
+	ProtocolConfigKey = "protocol"
+)
+
+// ProxyConfigProtocol returns the protocol set in the given opaque proxy or
+// upstream config, or an empty string if it isn't set. It returns an error
+// if the protocol is not a known one.
+func ProxyConfigProtocol(config map[string]interface{}) (string, error) {
+	raw, ok := config[ProtocolConfigKey]
+	if !ok {
+		return "", nil
+	}
+	// Strings in opaque config come back from msgpack as byte slices.
+	var protocol string
+	switch v := raw.(type) {
+	case string:
+		protocol = v
+	case []byte:
+		protocol = string(v)
+	default:
+		return "", fmt.Errorf("protocol must be a string")
+	}
+	protocol = strings.ToLower(protocol)
+	switch protocol {
+	case ProtocolTCP, ProtocolHTTP, ProtocolHTTP2, ProtocolGRPC:
+		return protocol, nil
+	default:
+		return "", fmt.Errorf("unknown protocol %q", protocol)
+	}
+}
+
+// ProtocolAssumption describes the protocol that a Connect proxy assumes
+// for a service. It is either set in the config of a proxy for the service
+// itself, or in the config of an upstream of another proxy.
+type ProtocolAssumption struct {
+	Node string
+
+	// ProxyID is the service ID of the proxy.
+	ProxyID string
+
+	// Upstream is true if the protocol is set for an upstream of the proxy
+	// rather than for the service the proxy is registered for.
+	Upstream bool
+
+	Protocol string
+}
+
+// ProtocolConflict describes a service for which the Connect proxies
+// assume different protocols, so the proxies would configure incompatible
+// listeners for it.
+type ProtocolConflict struct {
+	Service     string
+	Assumptions []*ProtocolAssumption
+}
+type ProtocolConflicts []*ProtocolConflict
+
+type IndexedProtocolConflicts struct {
+	Conflicts ProtocolConflicts
+	QueryMeta
+}
+
+// FindProtocolConflicts returns the services for which the given Connect
+// proxies assume different protocols, sorted by service name. A proxy which
+// doesn't set the protocol for its service assumes tcp, and an upstream
+// which doesn't set it leaves it to the proxies of the upstream service.
+// Invalid protocols are ignored since the registration validates them.
+func FindProtocolConflicts(proxies ServiceNodes) ProtocolConflicts {
+	assumptions := make(map[string][]*ProtocolAssumption)
+	for _, proxy := range proxies {
+		if proxy.ServiceKind != ServiceKindConnectProxy {
+			continue
+		}
+
+		protocol, err := ProxyConfigProtocol(proxy.ServiceProxy.Config)
+		if err != nil {
+			continue
+		}
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		service := proxy.ServiceProxy.DestinationServiceName
+		assumptions[service] = append(assumptions[service], &ProtocolAssumption{
+			Node:     proxy.Node,
+			ProxyID:  proxy.ServiceID,
+			Protocol: protocol,
+		})
+
+		for _, u := range proxy.ServiceProxy.Upstreams {
+			if u.DestinationType != "" && u.DestinationType != UpstreamDestTypeService {
+				continue
+			}
+			protocol, err := ProxyConfigProtocol(u.Config)
+			if err != nil || protocol == "" {
+				continue
+			}
+			assumptions[u.DestinationName] = append(assumptions[u.DestinationName], &ProtocolAssumption{
+				Node:     proxy.Node,
+				ProxyID:  proxy.ServiceID,
+				Upstream: true,
+				Protocol: protocol,
+			})
+		}
+	}
+
+	var conflicts ProtocolConflicts
+	for service, as := range assumptions {
+		for _, a := range as[1:] {
+			if a.Protocol != as[0].Protocol {
+				conflicts = append(conflicts, &ProtocolConflict{
+					Service:     service,
+					Assumptions: as,
+				})
+				break
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Service < conflicts[j].Service
+	})
+	return conflicts
+}
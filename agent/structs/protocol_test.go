@@ -0,0 +1,64 @@
+package structs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProtocolConflicts(t *testing.T) {
+	proxy := func(node, id, service, protocol string, upstreams ...Upstream) *ServiceNode {
+		sn := &ServiceNode{
+			Node:        node,
+			ServiceKind: ServiceKindConnectProxy,
+			ServiceID:   id,
+			ServiceProxy: ConnectProxyConfig{
+				DestinationServiceName: service,
+				Upstreams:              upstreams,
+			},
+		}
+		if protocol != "" {
+			sn.ServiceProxy.Config = map[string]interface{}{"protocol": protocol}
+		}
+		return sn
+	}
+	upstream := func(service, protocol string) Upstream {
+		return Upstream{
+			DestinationType: UpstreamDestTypeService,
+			DestinationName: service,
+			LocalBindPort:   8080,
+			Config:          map[string]interface{}{"protocol": protocol},
+		}
+	}
+
+	proxies := ServiceNodes{
+		// The proxies of api disagree.
+		proxy("node1", "api-proxy", "api", "http"),
+		proxy("node2", "api-proxy", "api", "grpc"),
+
+		// db is assumed to be tcp by its proxy but http by the web proxy.
+		proxy("node1", "db-proxy", "db", ""),
+		proxy("node2", "web-proxy", "web", "http",
+			upstream("db", "http"), upstream("cache", "tcp")),
+
+		// The cache proxy and the upstream agree.
+		proxy("node3", "cache-proxy", "cache", "TCP"),
+
+		// Typical services are ignored.
+		{Node: "node3", ServiceID: "api", ServiceName: "api"},
+	}
+
+	conflicts := FindProtocolConflicts(proxies)
+	require.Len(t, conflicts, 2)
+
+	require.Equal(t, "api", conflicts[0].Service)
+	require.Len(t, conflicts[0].Assumptions, 2)
+
+	require.Equal(t, "db", conflicts[1].Service)
+	require.Equal(t, []*ProtocolAssumption{
+		{Node: "node1", ProxyID: "db-proxy", Protocol: "tcp"},
+		{Node: "node2", ProxyID: "web-proxy", Upstream: true, Protocol: "http"},
+	}, conflicts[1].Assumptions)
+
+	require.Empty(t, FindProtocolConflicts(proxies[4:]))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/mitchellh/copystructure"
@@ -26,6 +27,11 @@ type ServiceDefinition struct {
 	Weights           *Weights
 	Token             string
 	EnableTagOverride bool
+	// DeregisterOnShutdown is nil if the agent default applies.
+	DeregisterOnShutdown *bool `json:",omitempty"`
+	// Warmup is the period after the registration during which the service
+	// is excluded from queries regardless of the status of its checks.
+	Warmup time.Duration `json:",omitempty"`
 	// DEPRECATED (ProxyDestination) - remove this when removing ProxyDestination
 	// ProxyDestination is deprecated in favour of Proxy.DestinationServiceName
 	ProxyDestination string `json:",omitempty"`
@@ -47,15 +53,17 @@ type ServiceDefinition struct {
 
 func (s *ServiceDefinition) NodeService() *NodeService {
 	ns := &NodeService{
-		Kind:              s.Kind,
-		ID:                s.ID,
-		Service:           s.Name,
-		Tags:              s.Tags,
-		Address:           s.Address,
-		Meta:              s.Meta,
-		Port:              s.Port,
-		Weights:           s.Weights,
-		EnableTagOverride: s.EnableTagOverride,
+		Kind:                 s.Kind,
+		ID:                   s.ID,
+		Service:              s.Name,
+		Tags:                 s.Tags,
+		Address:              s.Address,
+		Meta:                 s.Meta,
+		Port:                 s.Port,
+		Weights:              s.Weights,
+		EnableTagOverride:    s.EnableTagOverride,
+		DeregisterOnShutdown: s.DeregisterOnShutdown,
+		Warmup:               s.Warmup,
 	}
 	if s.Connect != nil {
 		ns.Connect = *s.Connect
@@ -5,6 +5,7 @@ type SnapshotOp int
 const (
 	SnapshotSave SnapshotOp = iota
 	SnapshotRestore
+	SnapshotVerify
 )
 
 // SnapshotReplyFn gets a peek at the reply before the snapshot streams, which
@@ -23,14 +24,38 @@ type SnapshotRequest struct {
 	// then all operations require a management token.
 	Token string
 
+	// SourceDatacenter is the datacenter which forwarded the request, so
+	// that only the policies which are valid there are applied.
+	SourceDatacenter string
+
 	// If set, any follower can service the request. Results may be
-	// arbitrarily stale. Only applies to SnapshotSave.
+	// arbitrarily stale. Only applies to SnapshotSave and SnapshotVerify.
 	AllowStale bool
 
 	// Op is the operation code for the RPC.
 	Op SnapshotOp
 }
 
+func (r *SnapshotRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+func (r *SnapshotRequest) IsRead() bool {
+	return r.Op != SnapshotRestore
+}
+
+func (r *SnapshotRequest) AllowStaleRead() bool {
+	return r.AllowStale
+}
+
+func (r *SnapshotRequest) TokenSecret() string {
+	return r.Token
+}
+
+func (r *SnapshotRequest) RequestSourceDatacenter() string {
+	return r.SourceDatacenter
+}
+
 // SnapshotResponse is used header for a snapshot RPC response. This will
 // precede any streaming data that's part of the request and is JSON-encoded on
 // the wire.
@@ -42,3 +67,18 @@ type SnapshotResponse struct {
 	// request. It is only filled in for a SnapshotSave.
 	QueryMeta
 }
+
+// SnapshotSummary describes the contents of a snapshot that was checked with
+// a SnapshotVerify operation. It is JSON-encoded as the streamed response.
+type SnapshotSummary struct {
+	// ID, Size, Index, Term and Version come from the Raft metadata of
+	// the snapshot.
+	ID      string
+	Size    int64
+	Index   uint64
+	Term    uint64
+	Version int
+
+	// Records is the number of records of each kind in the snapshot.
+	Records map[string]int
+}
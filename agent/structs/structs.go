@@ -3,7 +3,9 @@ package structs
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"math/rand"
+	"net"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -31,27 +33,35 @@ type RaftIndex struct {
 // These are serialized between Consul servers and stored in Consul snapshots,
 // so entries must only ever be added.
 const (
-	RegisterRequestType        MessageType = 0
-	DeregisterRequestType                  = 1
-	KVSRequestType                         = 2
-	SessionRequestType                     = 3
-	ACLRequestType                         = 4 // DEPRECATED (ACL-Legacy-Compat)
-	TombstoneRequestType                   = 5
-	CoordinateBatchUpdateType              = 6
-	PreparedQueryRequestType               = 7
-	TxnRequestType                         = 8
-	AutopilotRequestType                   = 9
-	AreaRequestType                        = 10
-	ACLBootstrapRequestType                = 11
-	IntentionRequestType                   = 12
-	ConnectCARequestType                   = 13
-	ConnectCAProviderStateType             = 14
-	ConnectCAConfigType                    = 15 // FSM snapshots only.
-	IndexRequestType                       = 16 // FSM snapshots only.
-	ACLTokenUpsertRequestType              = 17
-	ACLTokenDeleteRequestType              = 18
-	ACLPolicyUpsertRequestType             = 19
-	ACLPolicyDeleteRequestType             = 20
+	RegisterRequestType             MessageType = 0
+	DeregisterRequestType                       = 1
+	KVSRequestType                              = 2
+	SessionRequestType                          = 3
+	ACLRequestType                              = 4 // DEPRECATED (ACL-Legacy-Compat)
+	TombstoneRequestType                        = 5
+	CoordinateBatchUpdateType                   = 6
+	PreparedQueryRequestType                    = 7
+	TxnRequestType                              = 8
+	AutopilotRequestType                        = 9
+	AreaRequestType                             = 10
+	ACLBootstrapRequestType                     = 11
+	IntentionRequestType                        = 12
+	ConnectCARequestType                        = 13
+	ConnectCAProviderStateType                  = 14
+	ConnectCAConfigType                         = 15 // FSM snapshots only.
+	IndexRequestType                            = 16 // FSM snapshots only.
+	ACLTokenUpsertRequestType                   = 17
+	ACLTokenDeleteRequestType                   = 18
+	ACLPolicyUpsertRequestType                  = 19
+	ACLPolicyDeleteRequestType                  = 20
+	KVSRecycleBinRequestType                    = 21
+	UIConfigRequestType                         = 22
+	GossipKeyRotationRequestType                = 23
+	ACLAuthMethodUpsertRequestType              = 24
+	ACLAuthMethodDeleteRequestType              = 25
+	ACLBindingRuleUpsertRequestType             = 26
+	ACLBindingRuleDeleteRequestType             = 27
+	ServiceVirtualIPType                        = 28 // FSM snapshots only.
 )
 
 const (
@@ -68,6 +78,9 @@ const (
 	// ServiceMaintPrefix is the prefix for a service in maintenance mode.
 	ServiceMaintPrefix = "_service_maintenance:"
 
+	// ServiceWarmupPrefix is the prefix for a service which is warming up.
+	ServiceWarmupPrefix = "_service_warmup:"
+
 	// The meta key prefix reserved for Consul's internal use
 	metaKeyReservedPrefix = "consul-"
 
@@ -83,6 +96,16 @@ const (
 	// MetaSegmentKey is the node metadata key used to store the node's network segment
 	MetaSegmentKey = "consul-network-segment"
 
+	// MetaRetainServicesKey is the node metadata key an agent sets before it
+	// leaves gracefully to ask the servers to retain its services. The next
+	// sync of the node info removes it again.
+	MetaRetainServicesKey = "consul-retain-services"
+
+	// MetaExternalSource is the service metadata key used by the tools which
+	// sync services from external systems, such as Kubernetes or Terraform,
+	// to record where the service registration is managed.
+	MetaExternalSource = "external-source"
+
 	// MaxLockDelay provides a maximum LockDelay value for
 	// a session. Any value above this will not be respected.
 	MaxLockDelay = 60 * time.Second
@@ -101,6 +124,7 @@ type RPCInfo interface {
 	IsRead() bool
 	AllowStaleRead() bool
 	TokenSecret() string
+	RequestSourceDatacenter() string
 }
 
 // QueryOptions is used to specify various flags for read queries
@@ -160,6 +184,16 @@ type QueryOptions struct {
 	// ignored if the endpoint supports background refresh caching. See
 	// https://www.consul.io/api/index.html#agent-caching for more details.
 	StaleIfError time.Duration
+
+	// SourceDatacenter is the datacenter the request was forwarded from. It
+	// is set by the servers when forwarding the request, policies scoped to
+	// other datacenters don't apply to the request.
+	SourceDatacenter string
+
+	// MinAppliedIndex is the Raft index the server answering the read must
+	// have applied, e.g. the index of a preceding write of the client. Stale
+	// reads are forwarded to the leader if the local server is behind.
+	MinAppliedIndex uint64
 }
 
 // IsRead is always true for QueryOption.
@@ -178,6 +212,12 @@ func (q QueryOptions) ConsistencyLevel() string {
 	}
 }
 
+// IsBlockingQuery returns true if the query waits for changes after its
+// minimum index.
+func (q QueryOptions) IsBlockingQuery() bool {
+	return q.MinQueryIndex > 0
+}
+
 func (q QueryOptions) AllowStaleRead() bool {
 	return q.AllowStale
 }
@@ -186,10 +226,29 @@ func (q QueryOptions) TokenSecret() string {
 	return q.Token
 }
 
+func (q QueryOptions) RequestSourceDatacenter() string {
+	return q.SourceDatacenter
+}
+
+// RequestMinAppliedIndex returns the Raft index the server answering the
+// read must have applied.
+func (q QueryOptions) RequestMinAppliedIndex() uint64 {
+	return q.MinAppliedIndex
+}
+
+func (q *QueryOptions) SetSourceDatacenter(dc string) {
+	q.SourceDatacenter = dc
+}
+
 type WriteRequest struct {
 	// Token is the ACL token ID. If not provided, the 'anonymous'
 	// token is assumed for backwards compatibility.
 	Token string
+
+	// SourceDatacenter is the datacenter the request was forwarded from. It
+	// is set by the servers when forwarding the request, policies scoped to
+	// other datacenters don't apply to the request.
+	SourceDatacenter string
 }
 
 // WriteRequest only applies to writes, always false
@@ -205,6 +264,14 @@ func (w WriteRequest) TokenSecret() string {
 	return w.Token
 }
 
+func (w WriteRequest) RequestSourceDatacenter() string {
+	return w.SourceDatacenter
+}
+
+func (w *WriteRequest) SetSourceDatacenter(dc string) {
+	w.SourceDatacenter = dc
+}
+
 // QueryMeta allows a query response to include potentially
 // useful metadata about a query
 type QueryMeta struct {
@@ -223,6 +290,17 @@ type QueryMeta struct {
 	// Having `discovery_max_stale` on the agent can affect whether
 	// the request was served by a leader.
 	ConsistencyLevel string
+
+	// FilteredPolicies are the names of the policies of the token which
+	// didn't apply to the query because they are scoped to other
+	// datacenters. It is only set for queries forwarded from another
+	// datacenter, writes don't report it.
+	FilteredPolicies []string
+
+	// EffectiveWait is the time a blocking query was allowed to wait for a
+	// change, after the limits of the server were applied. It is zero for
+	// queries which don't block.
+	EffectiveWait time.Duration
 }
 
 // RegisterRequest is used for the Catalog.Register endpoint
@@ -311,6 +389,11 @@ type DCSpecificRequest struct {
 	Datacenter      string
 	NodeMetaFilters map[string]string
 	Source          QuerySource
+
+	// ExternalSource if set only returns the services registered with the
+	// given MetaExternalSource. It is only used by the service listings.
+	ExternalSource string
+
 	QueryOptions
 }
 
@@ -328,10 +411,13 @@ func (r *DCSpecificRequest) CacheInfo() cache.RequestInfo {
 		MustRevalidate: r.MustRevalidate,
 	}
 
-	// To calculate the cache key we only hash the node filters. The
-	// datacenter is handled by the cache framework. The other fields are
-	// not, but should not be used in any cache types.
-	v, err := hashstructure.Hash(r.NodeMetaFilters, nil)
+	// To calculate the cache key we only hash the node and external source
+	// filters. The datacenter is handled by the cache framework. The other
+	// fields are not, but should not be used in any cache types.
+	v, err := hashstructure.Hash([]interface{}{
+		r.NodeMetaFilters,
+		r.ExternalSource,
+	}, nil)
 	if err == nil {
 		// If there is an error, we don't set the key. A blank key forces
 		// no cache for this request so the request is forwarded directly
@@ -357,6 +443,10 @@ type ServiceSpecificRequest struct {
 	TagFilter       bool // Controls tag filtering
 	Source          QuerySource
 
+	// ExternalSource if set only returns the service instances registered
+	// with the given MetaExternalSource.
+	ExternalSource string
+
 	// Connect if true will only search for Connect-compatible services.
 	Connect bool
 
@@ -391,6 +481,7 @@ func (r *ServiceSpecificRequest) CacheInfo() cache.RequestInfo {
 		r.ServiceAddress,
 		r.TagFilter,
 		r.Connect,
+		r.ExternalSource,
 	}, nil)
 	if err == nil {
 		// If there is an error, we don't set the key. A blank key forces
@@ -417,6 +508,19 @@ func (r *NodeSpecificRequest) RequestDatacenter() string {
 	return r.Datacenter
 }
 
+// NodeSessionsRequest is used to request the sessions of a node, optionally
+// filtered by their metadata. It is wire compatible with NodeSpecificRequest.
+type NodeSessionsRequest struct {
+	Datacenter  string
+	Node        string
+	MetaFilters map[string]string
+	QueryOptions
+}
+
+func (r *NodeSessionsRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
 // ChecksInStateRequest is used to query for nodes in a state
 type ChecksInStateRequest struct {
 	Datacenter      string
@@ -645,6 +749,19 @@ type NodeService struct {
 	Weights           *Weights
 	EnableTagOverride bool
 
+	// DeregisterOnShutdown controls whether the service is removed from the
+	// catalog when the agent which registered it leaves the cluster
+	// gracefully. If it is nil the agent default is used. Like
+	// LocallyRegisteredAsSidecar it is only meaningful in the local agent
+	// state and is not translated to ServiceNode.
+	DeregisterOnShutdown *bool `json:",omitempty"`
+
+	// Warmup is the period after the service was registered during which
+	// the agent keeps it out of DNS and ?passing results, even if its checks
+	// pass. Like DeregisterOnShutdown it is only meaningful in the local
+	// agent state.
+	Warmup time.Duration `json:",omitempty"`
+
 	// ProxyDestination is DEPRECATED in favor of Proxy.DestinationServiceName.
 	// It's retained since this struct is used to parse input for
 	// /catalog/register but nothing else internal should use it - once
@@ -751,6 +868,28 @@ func (s *NodeService) Validate() error {
 			result = multierror.Append(result, fmt.Errorf(
 				"A Proxy cannot also be Connect Native, only typical services"))
 		}
+
+		if _, err := ProxyConfigProtocol(s.Proxy.Config); err != nil {
+			result = multierror.Append(result, fmt.Errorf("Proxy.Config: %v", err))
+		}
+		switch s.Proxy.Mode {
+		case ProxyModeDefault, ProxyModeDirect, ProxyModeTransparent:
+		default:
+			result = multierror.Append(result, fmt.Errorf(
+				"Proxy.Mode must be %q or %q, got %q", ProxyModeDirect,
+				ProxyModeTransparent, s.Proxy.Mode))
+		}
+		if port := s.Proxy.TransparentProxy.OutboundListenerPort; port < 0 || port > 65535 {
+			result = multierror.Append(result, fmt.Errorf(
+				"Proxy.TransparentProxy.OutboundListenerPort %d is invalid", port))
+		}
+
+		for _, u := range s.Proxy.Upstreams {
+			if _, err := ProxyConfigProtocol(u.Config); err != nil {
+				result = multierror.Append(result, fmt.Errorf(
+					"Upstream %q config: %v", u.DestinationName, err))
+			}
+		}
 	}
 
 	// Nested sidecar validation
@@ -887,6 +1026,12 @@ type HealthCheck struct {
 	ServiceName string        // optional service name
 	ServiceTags []string      // optional service tags
 
+	// PreviousStatus and PreviousOutput are the status and output of the
+	// check before its status last changed. They are maintained by the
+	// servers so that watchers can tell why a check changed.
+	PreviousStatus string `json:",omitempty"`
+	PreviousOutput string `json:",omitempty"`
+
 	Definition HealthCheckDefinition
 
 	RaftIndex
@@ -1043,6 +1188,172 @@ type IndexedNodeDump struct {
 	QueryMeta
 }
 
+// NodeDetail is used to return a node with its services, checks, sessions
+// and network coordinates in a single response. It is currently used for
+// the UI only.
+type NodeDetail struct {
+	*NodeInfo
+	Sessions    Sessions
+	Coordinates Coordinates
+}
+
+type IndexedNodeDetail struct {
+	// Detail is nil if the node doesn't exist.
+	Detail *NodeDetail
+	QueryMeta
+}
+
+const (
+	// ServiceInstanceRegistered is the event of a new service instance.
+	ServiceInstanceRegistered = "register"
+
+	// ServiceInstanceUpdated is the event of a change of the registration
+	// of a service instance.
+	ServiceInstanceUpdated = "update"
+
+	// ServiceInstanceDeregistered is the event of a service instance being
+	// deregistered on its own.
+	ServiceInstanceDeregistered = "deregister"
+
+	// ServiceInstanceNodeDeregistered is the event of a service instance
+	// being removed because its node was deregistered, for example when
+	// the leader reaps a node which left the cluster.
+	ServiceInstanceNodeDeregistered = "node-deregister"
+
+	// ServiceInstanceHealthChanged is the event of a check of a service
+	// instance changing its status.
+	ServiceInstanceHealthChanged = "health"
+)
+
+// ServiceInstanceEvent is an entry in the history of the instances of a
+// service.
+type ServiceInstanceEvent struct {
+	// Index is the Raft index of the change.
+	Index uint64
+
+	// Time is the time the change was applied on the server which answered
+	// the request.
+	Time time.Time
+
+	// Type is one of the ServiceInstance* event types.
+	Type string
+
+	// Node, ServiceID and ServiceName identify the service instance. The
+	// node is the agent which registered the instance.
+	Node        string
+	ServiceID   string
+	ServiceName string
+
+	// CheckID, Status, Output, PreviousStatus and PreviousOutput describe
+	// the change of a check of a health event.
+	CheckID        types.CheckID `json:",omitempty"`
+	Status         string        `json:",omitempty"`
+	Output         string        `json:",omitempty"`
+	PreviousStatus string        `json:",omitempty"`
+	PreviousOutput string        `json:",omitempty"`
+}
+
+type ServiceInstanceEvents []*ServiceInstanceEvent
+
+type IndexedServiceInstanceEvents struct {
+	Events ServiceInstanceEvents
+	QueryMeta
+}
+
+// ServiceVirtualIP is the virtual IP allocated to a service, which allows to
+// route to the service by destination IP. The IP is stored as an offset into
+// the virtual IP CIDR of the servers, see VirtualIP.
+type ServiceVirtualIP struct {
+	Service string
+	Offset  uint64
+
+	// FreedIndex is the Raft index at which the last instance of the
+	// service was deregistered, or zero while the service is registered.
+	// Freed offsets are kept for the service until they are reaped with
+	// the KV tombstones and are only reused after that.
+	FreedIndex uint64
+
+	RaftIndex
+}
+
+// ServiceVirtualIPResponse returns the virtual IP of a service. The IP is
+// empty if the service isn't registered.
+type ServiceVirtualIPResponse struct {
+	IP string
+	QueryMeta
+}
+
+// IndexedServiceVirtualIPs is the virtual IPs of the services, by service
+// name.
+type IndexedServiceVirtualIPs struct {
+	VirtualIPs map[string]string
+	QueryMeta
+}
+
+// VirtualIP returns the address at the offset into the CIDR. The first and
+// last addresses of the CIDR are never returned.
+func VirtualIP(cidr *net.IPNet, offset uint64) (net.IP, error) {
+	ip := cidr.IP.Mask(cidr.Mask)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	ones, bits := cidr.Mask.Size()
+	if hostBits := uint(bits - ones); offset == 0 || (hostBits < 64 && offset >= 1<<hostBits-1) {
+		return nil, fmt.Errorf("virtual IP offset %d is out of the range of %s", offset, cidr)
+	}
+
+	for i := len(ip) - 1; i >= 0 && offset > 0; i-- {
+		sum := uint64(ip[i]) + offset&0xff
+		ip[i] = byte(sum)
+		offset = offset>>8 + sum>>8
+	}
+	return ip, nil
+}
+
+// VirtualIPMaxOffset returns the highest offset into the CIDR which VirtualIP
+// accepts, or zero if the CIDR has no room for virtual IPs.
+func VirtualIPMaxOffset(cidr *net.IPNet) uint64 {
+	ones, bits := cidr.Mask.Size()
+	hostBits := uint(bits - ones)
+	switch {
+	case hostBits >= 64:
+		return math.MaxUint64
+	case hostBits < 2:
+		return 0
+	default:
+		return 1<<hostBits - 2
+	}
+}
+
+// NodeActivity is the time of the last registration of a node. It is used to
+// find nodes which aren't kept up to date by anything anymore.
+type NodeActivity struct {
+	Node    string
+	Address string
+	Meta    map[string]string
+
+	// LastRegistered is when the node or one of its services or checks was
+	// last registered according to the server which answered the request,
+	// even if the registration changed nothing. Servers only track the
+	// registrations since they started, so for nodes which weren't
+	// registered since then it is the start time of the server.
+	LastRegistered time.Time
+
+	// SerfMember is true if the node is a member of the LAN gossip pool of
+	// its datacenter, in which case its agent keeps it up to date.
+	SerfMember bool
+
+	RaftIndex
+}
+
+type NodeActivities []*NodeActivity
+
+type IndexedNodeActivities struct {
+	Nodes NodeActivities
+	QueryMeta
+}
+
 // DirEntry is used to represent a directory entry. This is
 // used for values in our Key-Value store.
 type DirEntry struct {
@@ -1077,6 +1388,12 @@ type KVSRequest struct {
 	Datacenter string
 	Op         api.KVOp // Which operation are we performing
 	DirEnt     DirEntry // Which directory entry
+
+	// RecycledAt is set by the leader on deletes when the KV recycle bin is
+	// enabled. The deleted entries are then moved to the recycle bin,
+	// recording this as their deletion time.
+	RecycledAt time.Time
+
 	WriteRequest
 }
 
@@ -1139,6 +1456,7 @@ type Session struct {
 	LockDelay time.Duration
 	Behavior  SessionBehavior // What to do when session is invalidated
 	TTL       string
+	Meta      map[string]string
 
 	RaftIndex
 }
@@ -1179,6 +1497,41 @@ type IndexedSessions struct {
 	QueryMeta
 }
 
+// KeyLock describes a KV key which is locked by a session, under a
+// lock-delay, or has sessions waiting to acquire it.
+type KeyLock struct {
+	Key string
+
+	// Session, SessionName and Node describe the holder of the lock. They
+	// are empty if the key is not locked.
+	Session     string
+	SessionName string
+	Node        string
+
+	// LockIndex is the number of times the lock was acquired.
+	LockIndex uint64
+
+	// AcquiredAt is the time the holder acquired the lock. It is the zero
+	// time if the key is not locked or if the lock was acquired before the
+	// server handling the request started tracking it.
+	AcquiredAt time.Time
+
+	// LockDelayUntil is the time until which the lock cannot be acquired
+	// because its previous holder was invalidated. It is the zero time if
+	// there is no lock-delay.
+	LockDelayUntil time.Time
+
+	// Waiters is the number of sessions which failed to acquire the lock
+	// since it was last acquired and still exist.
+	Waiters int
+}
+type KeyLocks []*KeyLock
+
+type IndexedKeyLocks struct {
+	Locks KeyLocks
+	QueryMeta
+}
+
 // Coordinate stores a node name with its associated network coordinate.
 type Coordinate struct {
 	Node    string
@@ -1249,6 +1602,55 @@ type EventFireResponse struct {
 	QueryMeta
 }
 
+// RecycledDirEntry is a KV entry which was deleted while the KV recycle
+// bin was enabled. It can be restored until it is purged.
+type RecycledDirEntry struct {
+	Key   string
+	Flags uint64
+	Value []byte
+
+	// DeletedAt is the time the entry was deleted according to the leader,
+	// and DeleteIndex the Raft index of the delete.
+	DeletedAt   time.Time
+	DeleteIndex uint64
+}
+
+type RecycledDirEntries []*RecycledDirEntry
+
+type IndexedRecycledDirEntries struct {
+	Entries RecycledDirEntries
+	QueryMeta
+}
+
+type KVSRecycleBinOp string
+
+const (
+	KVSRecycleBinRestore KVSRecycleBinOp = "restore"
+	KVSRecycleBinPurge   KVSRecycleBinOp = "purge"
+)
+
+// KVSRecycleBinRequest is used to restore or purge the entries of the KV
+// recycle bin.
+type KVSRecycleBinRequest struct {
+	Datacenter string
+	Op         KVSRecycleBinOp
+
+	// Key is the key of the entry, or the prefix of the entries if Recurse
+	// is set.
+	Key     string
+	Recurse bool
+
+	// DeletedBefore limits a purge to the entries deleted before the given
+	// time. It is ignored if zero.
+	DeletedBefore time.Time
+
+	WriteRequest
+}
+
+func (r *KVSRecycleBinRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
 type TombstoneOp string
 
 const (
@@ -1312,6 +1714,10 @@ type KeyringRequest struct {
 	Datacenter  string
 	Forwarded   bool
 	RelayFactor uint8
+
+	// Detailed requests the keys installed on every node and alerts about
+	// the state of the keyring when listing keys.
+	Detailed bool
 	QueryOptions
 }
 
@@ -1329,6 +1735,17 @@ type KeyringResponse struct {
 	Keys       map[string]int
 	NumNodes   int
 	Error      string `json:",omitempty"`
+
+	// PrimaryKeys is the number of nodes which use each key as their
+	// primary key.
+	PrimaryKeys map[string]int
+
+	// NodeKeys are the keys installed on every node, primary key first, and
+	// Alerts describe problems with the keyring, such as nodes which don't
+	// have the primary key installed. They are only set for detailed
+	// listings.
+	NodeKeys map[string][]string `json:",omitempty"`
+	Alerts   []string            `json:",omitempty"`
 }
 
 // KeyringResponses holds multiple responses to keyring queries. Each
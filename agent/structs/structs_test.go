@@ -2,6 +2,8 @@ package structs
 
 import (
 	"fmt"
+	"math"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
@@ -390,6 +392,26 @@ func TestStructs_NodeService_ValidateConnectProxy(t *testing.T) {
 			func(x *NodeService) { x.Connect.Native = true },
 			"cannot also be",
 		},
+
+		{
+			"connect-proxy: valid protocol",
+			func(x *NodeService) { x.Proxy.Config = map[string]interface{}{"protocol": "HTTP"} },
+			"",
+		},
+
+		{
+			"connect-proxy: unknown protocol",
+			func(x *NodeService) { x.Proxy.Config = map[string]interface{}{"protocol": "smtp"} },
+			"unknown protocol",
+		},
+
+		{
+			"connect-proxy: unknown upstream protocol",
+			func(x *NodeService) {
+				x.Proxy.Upstreams[0].Config = map[string]interface{}{"protocol": 80}
+			},
+			"protocol must be a string",
+		},
 	}
 
 	for _, tc := range cases {
@@ -899,3 +921,62 @@ func TestStructs_validateMetaPair(t *testing.T) {
 		}
 	}
 }
+
+func TestVirtualIPMaxOffset(t *testing.T) {
+	cases := map[string]uint64{
+		"240.0.0.0/4": 1<<28 - 2,
+		"10.1.2.0/24": 254,
+		"10.1.2.0/30": 2,
+		"10.1.2.0/31": 0,
+		"10.1.2.3/32": 0,
+		"fd00::/64":   math.MaxUint64,
+	}
+	for c, max := range cases {
+		_, cidr, err := net.ParseCIDR(c)
+		require.NoError(t, err)
+		require.Equal(t, max, VirtualIPMaxOffset(cidr), c)
+		if max > 0 && max < math.MaxUint64 {
+			_, err = VirtualIP(cidr, max)
+			require.NoError(t, err)
+			_, err = VirtualIP(cidr, max+1)
+			require.Error(t, err)
+		}
+	}
+}
+
+func TestVirtualIP(t *testing.T) {
+	cases := []struct {
+		CIDR   string
+		Offset uint64
+		IP     string
+		Error  string
+	}{
+		{"240.0.0.0/4", 1, "240.0.0.1", ""},
+		{"240.0.0.0/4", 256, "240.0.1.0", ""},
+		{"240.0.0.0/4", 1<<28 - 2, "255.255.255.254", ""},
+		{"240.0.0.0/4", 1<<28 - 1, "", "out of the range"},
+		{"240.0.0.0/4", 0, "", "out of the range"},
+		{"10.1.2.3/24", 255, "", "out of the range"},
+		{"10.1.2.3/24", 7, "10.1.2.7", ""},
+		{"fd00::/64", 1 << 32, "fd00::1:0:0", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("%s+%d", tc.CIDR, tc.Offset), func(t *testing.T) {
+			_, cidr, err := net.ParseCIDR(tc.CIDR)
+			require.NoError(t, err)
+
+			base := cidr.IP.String()
+			ip, err := VirtualIP(cidr, tc.Offset)
+			if tc.Error != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.Error)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.IP, ip.String())
+
+			require.Equal(t, base, cidr.IP.String())
+		})
+	}
+}
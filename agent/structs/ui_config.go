@@ -0,0 +1,114 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// UIConfigDashboardDefault is the key of the dashboard URL template
+	// used for services without their own template.
+	UIConfigDashboardDefault = "*"
+
+	UIBannerInfo     = "info"
+	UIBannerWarning  = "warning"
+	UIBannerCritical = "critical"
+)
+
+// uiConfigPlaceholder matches the placeholders of dashboard URL templates.
+var uiConfigPlaceholder = regexp.MustCompile(`{{\s*([^}]*?)\s*}}`)
+
+// UIConfig holds the settings of the web UI. They are stored by the servers
+// so that they apply to every agent without rebuilding the UI assets, and
+// are replicated from the primary datacenter.
+type UIConfig struct {
+	// DashboardURLTemplates maps service names to the URLs of the metrics
+	// dashboards the service views link to. The template with the "*" key
+	// is used for services without their own template. The placeholders
+	// {{Service}} and {{Datacenter}} are replaced with the name of the
+	// service and the datacenter.
+	DashboardURLTemplates map[string]string
+
+	// BannerMessage is shown at the top of every page of the UI if it is
+	// set. BannerLevel is one of "info", "warning" or "critical".
+	BannerMessage string
+	BannerLevel   string
+
+	RaftIndex
+}
+
+// Validate returns an error if the config is invalid. It sets the banner
+// level to "info" if a message is set without a level.
+func (c *UIConfig) Validate() error {
+	for service, tmpl := range c.DashboardURLTemplates {
+		if service == "" {
+			return fmt.Errorf("Dashboard URL template with empty service name")
+		}
+		if tmpl == "" {
+			return fmt.Errorf("Dashboard URL template of %q is empty", service)
+		}
+		for _, m := range uiConfigPlaceholder.FindAllStringSubmatch(tmpl, -1) {
+			if m[1] != "Service" && m[1] != "Datacenter" {
+				return fmt.Errorf("Dashboard URL template of %q has unknown placeholder %q, must be one of {{Service}} or {{Datacenter}}",
+					service, m[0])
+			}
+		}
+	}
+
+	if c.BannerMessage != "" && c.BannerLevel == "" {
+		c.BannerLevel = UIBannerInfo
+	}
+	switch c.BannerLevel {
+	case "", UIBannerInfo, UIBannerWarning, UIBannerCritical:
+	default:
+		return fmt.Errorf("Invalid banner level %q, must be one of %q, %q or %q",
+			c.BannerLevel, UIBannerInfo, UIBannerWarning, UIBannerCritical)
+	}
+	return nil
+}
+
+// DashboardURL returns the URL of the dashboard of the service, or an empty
+// string if there is no template for it.
+func (c *UIConfig) DashboardURL(service, datacenter string) string {
+	tmpl, ok := c.DashboardURLTemplates[service]
+	if !ok {
+		tmpl = c.DashboardURLTemplates[UIConfigDashboardDefault]
+	}
+	return uiConfigPlaceholder.ReplaceAllStringFunc(tmpl, func(p string) string {
+		switch strings.TrimSpace(strings.Trim(p, "{}")) {
+		case "Service":
+			return service
+		case "Datacenter":
+			return datacenter
+		}
+		return p
+	})
+}
+
+// UIConfigSetRequest is used to update the UI config.
+type UIConfigSetRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// Config is the new UI config.
+	Config UIConfig
+
+	// CAS controls whether to use check-and-set semantics for this request.
+	CAS bool
+
+	// WriteRequest holds the ACL token to go along with this request.
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *UIConfigSetRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// IndexedUIConfig is the UI config with the query metadata. Config is nil
+// if it was never set.
+type IndexedUIConfig struct {
+	Config *UIConfig
+	QueryMeta
+}
@@ -0,0 +1,74 @@
+package structs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUIConfig_Validate(t *testing.T) {
+	cases := map[string]struct {
+		config UIConfig
+		err    string
+	}{
+		"empty": {
+			UIConfig{},
+			"",
+		},
+		"valid": {
+			UIConfig{
+				DashboardURLTemplates: map[string]string{
+					"*":   "https://grafana/d/{{Service}}?dc={{ Datacenter }}",
+					"web": "https://grafana/d/web",
+				},
+				BannerMessage: "hello",
+				BannerLevel:   UIBannerWarning,
+			},
+			"",
+		},
+		"empty service": {
+			UIConfig{DashboardURLTemplates: map[string]string{"": "https://grafana"}},
+			"empty service name",
+		},
+		"empty template": {
+			UIConfig{DashboardURLTemplates: map[string]string{"web": ""}},
+			`template of "web" is empty`,
+		},
+		"unknown placeholder": {
+			UIConfig{DashboardURLTemplates: map[string]string{"web": "https://grafana/{{Node}}"}},
+			`unknown placeholder "{{Node}}"`,
+		},
+		"invalid level": {
+			UIConfig{BannerMessage: "hello", BannerLevel: "loud"},
+			`Invalid banner level "loud"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+			}
+		})
+	}
+
+	// The level defaults to info if a message is set.
+	c := UIConfig{BannerMessage: "hello"}
+	require.NoError(t, c.Validate())
+	require.Equal(t, UIBannerInfo, c.BannerLevel)
+}
+
+func TestUIConfig_DashboardURL(t *testing.T) {
+	c := UIConfig{
+		DashboardURLTemplates: map[string]string{
+			"*":   "https://grafana/d/{{Service}}?dc={{ Datacenter }}",
+			"web": "https://grafana/d/web-custom",
+		},
+	}
+	require.Equal(t, "https://grafana/d/api?dc=dc1", c.DashboardURL("api", "dc1"))
+	require.Equal(t, "https://grafana/d/web-custom", c.DashboardURL("web", "dc1"))
+	require.Equal(t, "", (&UIConfig{}).DashboardURL("api", "dc1"))
+}
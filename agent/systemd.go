@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/agent/systemd"
+)
+
+// readyPollInterval is the interval in which the agent checks for leader
+// contact before it reports startup completion to systemd.
+const readyPollInterval = time.Second
+
+// notify sends the given state to systemd. Errors are only logged since
+// the agent is usually not run under systemd.
+func (a *Agent) notify(state string) {
+	if a.systemdNotifier == nil {
+		return
+	}
+	err := a.systemdNotifier.Notify(state)
+	if err != nil && err != systemd.NotifyNoSocket {
+		a.logger.Printf("[DEBUG] agent: systemd notify %q failed: %v", state, err)
+	}
+}
+
+// notifyReady waits until the agent has contact with a leader and then
+// reports startup completion to systemd. This way units which depend on
+// Consul are not started before the agent can serve requests.
+func (a *Agent) notifyReady() {
+	for {
+		var leader string
+		if err := a.RPC("Status.Leader", struct{}{}, &leader); err == nil && leader != "" {
+			break
+		}
+		select {
+		case <-time.After(readyPollInterval):
+		case <-a.shutdownCh:
+			return
+		}
+	}
+
+	a.logger.Printf("[DEBUG] agent: Leader contact established, notifying systemd")
+	atomic.StoreInt32(&a.ready, 1)
+	a.notify(systemd.Ready)
+}
+
+// isReady returns true once startup completion has been reported.
+func (a *Agent) isReady() bool {
+	return atomic.LoadInt32(&a.ready) == 1
+}
+
+// startWatchdog starts sending keepalives to the systemd watchdog if it
+// is enabled for the agent.
+func (a *Agent) startWatchdog() {
+	interval, err := a.systemdWatchdogInterval()
+	if err != nil {
+		a.logger.Printf("[WARN] agent: Not starting systemd watchdog: %v", err)
+		return
+	}
+	if interval == 0 {
+		return
+	}
+	a.logger.Printf("[INFO] agent: Sending systemd watchdog keepalives every %s", interval/2)
+	go a.watchdog(interval / 2)
+}
+
+// watchdog sends a keepalive in every interval as long as the agent is
+// responsive. The local state is queried before every keepalive so that
+// systemd restarts the agent when it deadlocks.
+func (a *Agent) watchdog(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.State.Stats()
+			a.notify(systemd.Watchdog)
+		case <-a.shutdownCh:
+			return
+		}
+	}
+}
@@ -2,8 +2,11 @@ package systemd
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"os"
+	"strconv"
+	"time"
 )
 
 const (
@@ -13,6 +16,7 @@ const (
 	Ready     = "READY=1"
 	Reloading = "RELOADING=1"
 	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
 )
 
 var NotifyNoSocket = errors.New("No socket")
@@ -40,3 +44,32 @@ func (n *Notifier) Notify(state string) error {
 	_, err = conn.Write([]byte(state))
 	return err
 }
+
+// WatchdogInterval returns the timeout of the systemd watchdog for this
+// process. Keepalive messages should be sent in shorter intervals, e.g.
+// every half of the timeout. A zero duration is returned when the
+// watchdog is not enabled.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+
+	// The watchdog is meant for a different process when WATCHDOG_PID
+	// is set to another pid.
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" {
+		p, err := strconv.Atoi(pid)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WATCHDOG_PID %q: %v", pid, err)
+		}
+		if p != os.Getpid() {
+			return 0, nil
+		}
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
@@ -0,0 +1,72 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier_Notify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", path)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	n := &Notifier{}
+	require.NoError(t, n.Notify(Ready))
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	l, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, Ready, string(buf[:l]))
+
+	os.Unsetenv("NOTIFY_SOCKET")
+	require.Equal(t, NotifyNoSocket, n.Notify(Ready))
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	tests := []struct {
+		desc  string
+		usec  string
+		pid   string
+		want  time.Duration
+		isErr bool
+	}{
+		{desc: "disabled"},
+		{desc: "enabled", usec: "30000000", want: 30 * time.Second},
+		{desc: "own pid", usec: "1000", pid: strconv.Itoa(os.Getpid()), want: time.Millisecond},
+		{desc: "other pid", usec: "1000", pid: strconv.Itoa(os.Getpid() + 1)},
+		{desc: "invalid usec", usec: "x", isErr: true},
+		{desc: "invalid pid", usec: "1000", pid: "x", isErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			os.Setenv("WATCHDOG_USEC", tt.usec)
+			os.Setenv("WATCHDOG_PID", tt.pid)
+			got, err := WatchdogInterval()
+			if tt.isErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
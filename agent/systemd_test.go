@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/systemd"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// testNotifier records the states an agent reports to systemd.
+type testNotifier struct {
+	lock   sync.Mutex
+	states []string
+}
+
+func (n *testNotifier) Notify(state string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.states = append(n.states, state)
+	return nil
+}
+
+// reported returns the reported states without the watchdog keepalives and
+// the number of keepalives.
+func (n *testNotifier) reported() ([]string, int) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	var states []string
+	keepalives := 0
+	for _, s := range n.states {
+		if s == systemd.Watchdog {
+			keepalives++
+		} else {
+			states = append(states, s)
+		}
+	}
+	return states, keepalives
+}
+
+func TestAgent_SystemdNotify(t *testing.T) {
+	t.Parallel()
+	notifier := &testNotifier{}
+	a := &TestAgent{Name: t.Name(), beforeStart: func(a *Agent) {
+		a.systemdNotifier = notifier
+		a.systemdWatchdogInterval = func() (time.Duration, error) {
+			return 200 * time.Millisecond, nil
+		}
+	}}
+	a.Start()
+
+	retry.Run(t, func(r *retry.R) {
+		if states, _ := notifier.reported(); len(states) != 1 || states[0] != systemd.Ready {
+			r.Fatalf("got %v", states)
+		}
+	})
+	require.True(t, a.isReady())
+
+	require.NoError(t, a.ReloadConfig(a.Config))
+	states, _ := notifier.reported()
+	require.Equal(t, []string{systemd.Ready, systemd.Reloading, systemd.Ready}, states)
+
+	retry.Run(t, func(r *retry.R) {
+		if _, keepalives := notifier.reported(); keepalives == 0 {
+			r.Fatal("no watchdog keepalive")
+		}
+	})
+
+	a.Shutdown()
+	states, _ = notifier.reported()
+	require.Equal(t, systemd.Stopping, states[len(states)-1])
+}
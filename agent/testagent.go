@@ -85,6 +85,10 @@ type TestAgent struct {
 	// It is valid after Start().
 	srv *HTTPServer
 
+	// beforeStart is called with the agent before it is started, e.g. to
+	// replace its dependencies.
+	beforeStart func(*Agent)
+
 	// Agent is the embedded consul agent.
 	// It is valid after Start().
 	*Agent
@@ -157,6 +161,9 @@ func (a *TestAgent) Start() *TestAgent {
 		agent.LogWriter = a.LogWriter
 		agent.logger = log.New(logOutput, a.Name+" - ", log.LstdFlags|log.Lmicroseconds)
 		agent.MemSink = metrics.NewInmemSink(1*time.Second, time.Minute)
+		if a.beforeStart != nil {
+			a.beforeStart(agent)
+		}
 
 		// we need the err var in the next exit condition
 		if err := agent.Start(); err == nil {
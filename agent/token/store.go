@@ -90,6 +90,15 @@ func (t *Store) AgentToken() string {
 	return t.userToken
 }
 
+// HasAgentToken returns true if a token for internal agent operations was
+// set, instead of falling back to the user token.
+func (t *Store) HasAgentToken() bool {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	return t.agentToken != ""
+}
+
 // ACLReplicationToken returns the ACL replication token.
 func (t *Store) ACLReplicationToken() string {
 	t.l.RLock()
@@ -53,6 +53,9 @@ func TestStore_RegularTokens(t *testing.T) {
 			if got, want := s.AgentToken(), tt.want.agent; got != want {
 				t.Fatalf("got token %q want %q", got, want)
 			}
+			if got, want := s.HasAgentToken(), tt.set.agent != ""; got != want {
+				t.Fatalf("got %v want %v", got, want)
+			}
 			if got, want := s.ACLReplicationToken(), tt.want.repl; got != want {
 				t.Fatalf("got token %q want %q", got, want)
 			}
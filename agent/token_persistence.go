@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/hashicorp/consul/lib/file"
+)
+
+const (
+	// tokensFile is the name of the file in the data dir which contains
+	// the tokens set with the /v1/agent/token API.
+	tokensFile = "acl-tokens.json"
+
+	// tokensFileMode is the file mode of the tokens file. Since it
+	// contains secrets it must only be readable by the agent.
+	tokensFileMode = 0600
+)
+
+// updateToken sets the token with the given name as used by the
+// /v1/agent/token API. It returns false if the name is unknown.
+func (a *Agent) updateToken(name, token string) bool {
+	switch name {
+	case "acl_token":
+		a.tokens.UpdateUserToken(token)
+
+	case "acl_agent_token":
+		a.tokens.UpdateAgentToken(token)
+
+	case "acl_agent_master_token":
+		a.tokens.UpdateAgentMasterToken(token)
+
+	case "acl_replication_token":
+		a.tokens.UpdateACLReplicationToken(token)
+
+	case "connect_replication_token":
+		a.tokens.UpdateConnectReplicationToken(token)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// readPersistedTokens returns the tokens stored in the tokens file by
+// name. A missing file is not an error.
+func (a *Agent) readPersistedTokens() (map[string]string, error) {
+	path := filepath.Join(a.config.DataDir, tokensFile)
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := map[string]string{}
+	if err := json.Unmarshal(buf, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// persistToken stores a token which was set with the /v1/agent/token API
+// in the tokens file so that it survives a restart of the agent.
+func (a *Agent) persistToken(name, token string) error {
+	a.persistedTokensLock.Lock()
+	defer a.persistedTokensLock.Unlock()
+
+	tokens, err := a.readPersistedTokens()
+	if err != nil {
+		return err
+	}
+	tokens[name] = token
+
+	buf, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	return file.WriteAtomic(filepath.Join(a.config.DataDir, tokensFile), buf)
+}
+
+// loadPersistedTokens restores the tokens from the tokens file. They
+// replace the tokens from the configuration. Errors are logged since the
+// agent can still work with the configured tokens.
+func (a *Agent) loadPersistedTokens() {
+	if !a.config.ACLEnableTokenPersistence {
+		return
+	}
+
+	path := filepath.Join(a.config.DataDir, tokensFile)
+	if fi, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && fi.Mode().Perm() != tokensFileMode {
+		a.logger.Printf("[WARN] agent: Persisted ACL tokens file %q has mode %s, changing it to %s",
+			path, fi.Mode().Perm(), os.FileMode(tokensFileMode))
+		if err := os.Chmod(path, tokensFileMode); err != nil {
+			a.logger.Printf("[ERR] agent: Failed to change mode of persisted ACL tokens file: %v", err)
+			return
+		}
+	}
+
+	tokens, err := a.readPersistedTokens()
+	if err != nil {
+		a.logger.Printf("[WARN] agent: Failed to load persisted ACL tokens: %v", err)
+		return
+	}
+	for name, token := range tokens {
+		if !a.updateToken(name, token) {
+			a.logger.Printf("[WARN] agent: Ignoring unknown persisted ACL token %q", name)
+			continue
+		}
+		a.logger.Printf("[INFO] agent: Restored persisted ACL token %q", name)
+	}
+}
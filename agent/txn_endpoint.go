@@ -2,10 +2,12 @@ package agent
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 )
@@ -198,6 +200,7 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 		setLastContact(resp, reply.LastContact)
 		setKnownLeader(resp, reply.KnownLeader)
 
+		s.fixupTxnErrors(reply.Errors)
 		ret, conflict = reply, len(reply.Errors) > 0
 	} else {
 		args := structs.TxnRequest{Ops: ops}
@@ -208,6 +211,10 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 		if err := s.agent.RPC("Txn.Apply", &args, &reply); err != nil {
 			return nil, err
 		}
+		if len(reply.Errors) == 0 {
+			s.setWriteIndex(resp, args.Datacenter)
+		}
+		s.fixupTxnErrors(reply.Errors)
 		ret, conflict = reply, len(reply.Errors) > 0
 	}
 
@@ -230,3 +237,14 @@ func (s *HTTPServer) Txn(resp http.ResponseWriter, req *http.Request) (interface
 	// Otherwise, return the results of the successful transaction.
 	return ret, nil
 }
+
+// fixupTxnErrors removes the permissions named by the operations denied by
+// ACLs unless the agent is configured to disclose them.
+func (s *HTTPServer) fixupTxnErrors(errs structs.TxnErrors) {
+	if s.agent.config.ACLEnableDeniedPermissionDetails {
+		return
+	}
+	for _, e := range errs {
+		_, e.What = acl.DeniedPermission(errors.New(e.What))
+	}
+}
@@ -3,18 +3,18 @@ package agent
 import (
 	"fmt"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/lib"
 )
 
-// metaExternalSource is the key name for the service instance meta that
-// defines the external syncing source. This is used by the UI APIs below
-// to extract this.
-const metaExternalSource = "external-source"
-
 // ServiceSummary is used to summarize a service
 type ServiceSummary struct {
 	Kind              structs.ServiceKind `json:",omitempty"`
@@ -28,6 +28,39 @@ type ServiceSummary struct {
 	externalSourceSet map[string]struct{} // internal to track uniqueness
 }
 
+// CheckSummary counts checks by their status.
+type CheckSummary struct {
+	ChecksPassing  int
+	ChecksWarning  int
+	ChecksCritical int
+}
+
+// add counts a check with the given status.
+func (c *CheckSummary) add(status string) {
+	switch status {
+	case api.HealthPassing:
+		c.ChecksPassing++
+	case api.HealthWarning:
+		c.ChecksWarning++
+	case api.HealthCritical:
+		c.ChecksCritical++
+	}
+}
+
+// NodeDetail is used to show a node with its services, checks, sessions and
+// coordinates along with rollups of its checks.
+type NodeDetail struct {
+	*structs.NodeDetail
+
+	// CheckSummary counts all the checks of the node and NodeChecks only
+	// the node-level checks. ServiceChecks counts the checks of each service
+	// by service ID, including the node-level checks which also affect the
+	// health of the service.
+	CheckSummary
+	NodeChecks    CheckSummary
+	ServiceChecks map[string]CheckSummary
+}
+
 // UINodes is used to list the nodes in a given datacenter. We return a
 // NodeDump which provides overview information for all the nodes
 func (s *HTTPServer) UINodes(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -111,6 +144,79 @@ RPC:
 	return nil, nil
 }
 
+// UINodeDetail is used to get a node with its services, checks, sessions and
+// coordinates in a single request. It supports blocking queries.
+func (s *HTTPServer) UINodeDetail(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Parse arguments
+	args := structs.NodeSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	args.Node = strings.TrimPrefix(req.URL.Path, "/v1/internal/ui/node-detail/")
+	if args.Node == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(resp, "Missing node name")
+		return nil, nil
+	}
+
+	// Make the RPC request
+	var out structs.IndexedNodeDetail
+	defer setMeta(resp, &out.QueryMeta)
+RPC:
+	if err := s.agent.RPC("Internal.NodeDetail", &args, &out); err != nil {
+		// Retry the request allowing stale data if no leader
+		if strings.Contains(err.Error(), structs.ErrNoLeader.Error()) && !args.AllowStale {
+			args.AllowStale = true
+			goto RPC
+		}
+		return nil, err
+	}
+
+	if out.Detail == nil {
+		resp.WriteHeader(http.StatusNotFound)
+		return nil, nil
+	}
+	return summarizeNode(out.Detail), nil
+}
+
+// summarizeNode adds the rollups of the checks to the detail of a node.
+func summarizeNode(detail *structs.NodeDetail) *NodeDetail {
+	if detail.Services == nil {
+		detail.Services = make([]*structs.NodeService, 0)
+	}
+	if detail.Checks == nil {
+		detail.Checks = make([]*structs.HealthCheck, 0)
+	}
+	if detail.Sessions == nil {
+		detail.Sessions = make(structs.Sessions, 0)
+	}
+	if detail.Coordinates == nil {
+		detail.Coordinates = make(structs.Coordinates, 0)
+	}
+
+	out := &NodeDetail{
+		NodeDetail:    detail,
+		ServiceChecks: make(map[string]CheckSummary, len(detail.Services)),
+	}
+	for _, service := range detail.Services {
+		out.ServiceChecks[service.ID] = CheckSummary{}
+	}
+	for _, check := range detail.Checks {
+		out.CheckSummary.add(check.Status)
+		if check.ServiceID == "" {
+			out.NodeChecks.add(check.Status)
+		}
+		for id, sum := range out.ServiceChecks {
+			if check.ServiceID == "" || check.ServiceID == id {
+				sum.add(check.Status)
+				out.ServiceChecks[id] = sum
+			}
+		}
+	}
+	return out
+}
+
 // UIServices is used to list the services in a given datacenter. We return a
 // ServiceSummary which provides overview information for the service
 func (s *HTTPServer) UIServices(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -133,10 +239,45 @@ RPC:
 		return nil, err
 	}
 
+	// Only summarize the services of the requested external source.
+	if source := req.URL.Query().Get("external-source"); source != "" {
+		out.Dump = filterDumpByExternalSource(out.Dump, source)
+	}
+
 	// Generate the summary
 	return summarizeServices(out.Dump), nil
 }
 
+// filterDumpByExternalSource returns a copy of the dump with only the
+// services registered by the given external source, along with their checks
+// and the checks of their nodes. Nodes without such services are dropped.
+func filterDumpByExternalSource(dump structs.NodeDump, source string) structs.NodeDump {
+	var filtered structs.NodeDump
+	for _, node := range dump {
+		info := *node
+		info.Services = nil
+		info.Checks = nil
+
+		ids := make(map[string]struct{})
+		for _, service := range node.Services {
+			if service.Meta[structs.MetaExternalSource] == source {
+				ids[service.ID] = struct{}{}
+				info.Services = append(info.Services, service)
+			}
+		}
+		if len(info.Services) == 0 {
+			continue
+		}
+		for _, check := range node.Checks {
+			if _, ok := ids[check.ServiceID]; ok || check.ServiceID == "" {
+				info.Checks = append(info.Checks, check)
+			}
+		}
+		filtered = append(filtered, &info)
+	}
+	return filtered
+}
+
 func summarizeServices(dump structs.NodeDump) []*ServiceSummary {
 	// Collect the summary information
 	var services []string
@@ -164,8 +305,8 @@ func summarizeServices(dump structs.NodeDump) []*ServiceSummary {
 			// sources. We only want to add unique sources so there is extra
 			// accounting here with an unexported field to maintain the set
 			// of sources.
-			if len(service.Meta) > 0 && service.Meta[metaExternalSource] != "" {
-				source := service.Meta[metaExternalSource]
+			if len(service.Meta) > 0 && service.Meta[structs.MetaExternalSource] != "" {
+				source := service.Meta[structs.MetaExternalSource]
 				if sum.externalSourceSet == nil {
 					sum.externalSourceSet = make(map[string]struct{})
 				}
@@ -208,3 +349,61 @@ func summarizeServices(dump structs.NodeDump) []*ServiceSummary {
 	}
 	return output
 }
+
+// UIMetricsProxy forwards requests for the allowed paths of the Prometheus
+// API to the configured metrics backend, so that the UI can show the metrics
+// of services without exposing the backend to its users.
+func (s *HTTPServer) UIMetricsProxy(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	cfg := s.agent.config
+	if cfg.UIMetricsProxyBaseURL == "" {
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(resp, "Metrics proxy is disabled")
+		return nil, nil
+	}
+
+	// The metrics cover all services and nodes, so reading them requires
+	// read access to all of them.
+	var token string
+	s.parseToken(req, &token)
+	rule, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil && (!rule.ServiceRead("") || !rule.NodeRead("")) {
+		return nil, acl.ErrPermissionDenied
+	}
+
+	subpath := path.Clean("/" + strings.TrimPrefix(req.URL.Path, "/v1/internal/ui/metrics-proxy"))
+	if !lib.StrContains(cfg.UIMetricsProxyPathAllowlist, subpath) {
+		resp.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(resp, "Path %q is not allowed by the metrics proxy", subpath)
+		return nil, nil
+	}
+
+	target, err := url.Parse(cfg.UIMetricsProxyBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	target.Path = strings.TrimSuffix(target.Path, "/") + subpath
+
+	// Don't leak the ACL token to the backend.
+	query := req.URL.Query()
+	query.Del("token")
+	target.RawQuery = query.Encode()
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL = target
+			r.Host = target.Host
+			r.Header.Del("X-Consul-Token")
+			r.Header.Del("Authorization")
+			r.Header.Del("Cookie")
+			for k, v := range cfg.UIMetricsProxyAddHeaders {
+				r.Header.Set(k, v)
+			}
+		},
+		ErrorLog: s.agent.logger,
+	}
+	proxy.ServeHTTP(resp, req)
+	return nil, nil
+}
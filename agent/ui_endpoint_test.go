@@ -14,10 +14,13 @@ import (
 
 	"github.com/hashicorp/consul/testrpc"
 
+	"github.com/hashicorp/consul/acl"
 	"github.com/hashicorp/consul/agent/structs"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/types"
 	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUiIndex(t *testing.T) {
@@ -152,6 +155,83 @@ func TestUiNodeInfo(t *testing.T) {
 	}
 }
 
+func TestUiNodeDetail(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "test",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "web",
+			Service: "web",
+		},
+		Check: &structs.HealthCheck{
+			CheckID:   "web-check",
+			Name:      "web check",
+			Status:    api.HealthCritical,
+			ServiceID: "web",
+		},
+	}
+	var out struct{}
+	require.NoError(t, a.RPC("Catalog.Register", args, &out))
+
+	req, _ := http.NewRequest("GET", "/v1/internal/ui/node-detail/test", nil)
+	resp := httptest.NewRecorder()
+	obj, err := a.srv.UINodeDetail(resp, req)
+	require.NoError(t, err)
+	assertIndex(t, resp)
+
+	detail := obj.(*NodeDetail)
+	require.Equal(t, "test", detail.Node)
+	require.Len(t, detail.Services, 1)
+	require.Len(t, detail.Checks, 1)
+	require.NotNil(t, detail.Sessions)
+	require.NotNil(t, detail.Coordinates)
+	require.Equal(t, CheckSummary{ChecksCritical: 1}, detail.CheckSummary)
+	require.Equal(t, map[string]CheckSummary{"web": {ChecksCritical: 1}}, detail.ServiceChecks)
+
+	req, _ = http.NewRequest("GET", "/v1/internal/ui/node-detail/nope", nil)
+	resp = httptest.NewRecorder()
+	obj, err = a.srv.UINodeDetail(resp, req)
+	require.NoError(t, err)
+	require.Nil(t, obj)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestSummarizeNode(t *testing.T) {
+	t.Parallel()
+	detail := &structs.NodeDetail{
+		NodeInfo: &structs.NodeInfo{
+			Node: "foo",
+			Services: []*structs.NodeService{
+				{ID: "api", Service: "api"},
+				{ID: "web", Service: "web"},
+			},
+			Checks: structs.HealthChecks{
+				{CheckID: "serfHealth", Status: api.HealthPassing},
+				{CheckID: "disk", Status: api.HealthWarning},
+				{CheckID: "api", ServiceID: "api", Status: api.HealthCritical},
+				{CheckID: "web", ServiceID: "web", Status: api.HealthPassing},
+				{CheckID: "web2", ServiceID: "web", Status: api.HealthPassing},
+			},
+		},
+	}
+
+	out := summarizeNode(detail)
+	require.Equal(t, CheckSummary{ChecksPassing: 3, ChecksWarning: 1, ChecksCritical: 1}, out.CheckSummary)
+	require.Equal(t, CheckSummary{ChecksPassing: 1, ChecksWarning: 1}, out.NodeChecks)
+	require.Equal(t, map[string]CheckSummary{
+		"api": {ChecksPassing: 1, ChecksWarning: 1, ChecksCritical: 1},
+		"web": {ChecksPassing: 3, ChecksWarning: 1},
+	}, out.ServiceChecks)
+	require.NotNil(t, out.Sessions)
+	require.NotNil(t, out.Coordinates)
+}
+
 func TestSummarizeServices(t *testing.T) {
 	t.Parallel()
 	dump := structs.NodeDump{
@@ -168,7 +248,7 @@ func TestSummarizeServices(t *testing.T) {
 					Kind:    structs.ServiceKindConnectProxy,
 					Service: "web",
 					Tags:    []string{},
-					Meta:    map[string]string{metaExternalSource: "k8s"},
+					Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
 				},
 			},
 			Checks: []*structs.HealthCheck{
@@ -194,7 +274,7 @@ func TestSummarizeServices(t *testing.T) {
 					Kind:    structs.ServiceKindConnectProxy,
 					Service: "web",
 					Tags:    []string{},
-					Meta:    map[string]string{metaExternalSource: "k8s"},
+					Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
 				},
 			},
 			Checks: []*structs.HealthCheck{
@@ -262,3 +342,135 @@ func TestSummarizeServices(t *testing.T) {
 		t.Fatalf("bad: %v", summary[2])
 	}
 }
+
+func TestFilterDumpByExternalSource(t *testing.T) {
+	t.Parallel()
+	dump := structs.NodeDump{
+		&structs.NodeInfo{
+			Node: "foo",
+			Services: []*structs.NodeService{
+				&structs.NodeService{
+					ID:      "api",
+					Service: "api",
+				},
+				&structs.NodeService{
+					ID:      "web",
+					Service: "web",
+					Meta:    map[string]string{structs.MetaExternalSource: "k8s"},
+				},
+			},
+			Checks: []*structs.HealthCheck{
+				&structs.HealthCheck{
+					CheckID: "serfHealth",
+					Status:  api.HealthPassing,
+				},
+				&structs.HealthCheck{
+					CheckID:     "web",
+					Status:      api.HealthPassing,
+					ServiceID:   "web",
+					ServiceName: "web",
+				},
+				&structs.HealthCheck{
+					CheckID:     "api",
+					Status:      api.HealthWarning,
+					ServiceID:   "api",
+					ServiceName: "api",
+				},
+			},
+		},
+		&structs.NodeInfo{
+			Node: "bar",
+			Services: []*structs.NodeService{
+				&structs.NodeService{
+					ID:      "web",
+					Service: "web",
+					Meta:    map[string]string{structs.MetaExternalSource: "terraform"},
+				},
+			},
+		},
+	}
+
+	filtered := filterDumpByExternalSource(dump, "k8s")
+	require.Len(t, filtered, 1)
+	require.Equal(t, "foo", filtered[0].Node)
+	require.Len(t, filtered[0].Services, 1)
+	require.Equal(t, "web", filtered[0].Services[0].ID)
+	require.Len(t, filtered[0].Checks, 2)
+	require.Equal(t, types.CheckID("serfHealth"), filtered[0].Checks[0].CheckID)
+	require.Equal(t, types.CheckID("web"), filtered[0].Checks[1].CheckID)
+
+	// The original dump is untouched.
+	require.Len(t, dump[0].Services, 2)
+	require.Len(t, dump[0].Checks, 3)
+
+	summary := summarizeServices(filtered)
+	require.Len(t, summary, 1)
+	require.Equal(t, "web", summary[0].Name)
+	require.Equal(t, 2, summary[0].ChecksPassing)
+	require.Equal(t, []string{"k8s"}, summary[0].ExternalSources)
+}
+
+func TestUIMetricsProxy(t *testing.T) {
+	t.Parallel()
+
+	var got *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"success"}`)
+	}))
+	defer backend.Close()
+
+	a := NewTestAgent(t.Name(), TestACLConfig()+`
+		ui_metrics_proxy {
+			base_url = "`+backend.URL+`/prom/"
+			add_headers {
+				"X-Scope-OrgID" = "consul"
+			}
+		}
+	`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	t.Run("allowed path", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/internal/ui/metrics-proxy/api/v1/query?query=up&token=root", nil)
+		resp := httptest.NewRecorder()
+		_, err := a.srv.UIMetricsProxy(resp, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, `{"status":"success"}`, resp.Body.String())
+
+		require.NotNil(t, got)
+		require.Equal(t, "/prom/api/v1/query", got.URL.Path)
+		require.Equal(t, "query=up", got.URL.RawQuery)
+		require.Equal(t, "consul", got.Header.Get("X-Scope-OrgID"))
+	})
+
+	t.Run("path not allowed", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/internal/ui/metrics-proxy/api/v1/../v1/admin/tsdb/snapshot?token=root", nil)
+		resp := httptest.NewRecorder()
+		_, err := a.srv.UIMetricsProxy(resp, req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusForbidden, resp.Code)
+		require.Contains(t, resp.Body.String(), `"/api/v1/admin/tsdb/snapshot" is not allowed`)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/internal/ui/metrics-proxy/api/v1/query?query=up", nil)
+		resp := httptest.NewRecorder()
+		_, err := a.srv.UIMetricsProxy(resp, req)
+		require.True(t, acl.IsErrPermissionDenied(err), "err: %v", err)
+	})
+}
+
+func TestUIMetricsProxy_Disabled(t *testing.T) {
+	t.Parallel()
+	a := NewTestAgent(t.Name(), "")
+	defer a.Shutdown()
+
+	req, _ := http.NewRequest("GET", "/v1/internal/ui/metrics-proxy/api/v1/query?query=up", nil)
+	resp := httptest.NewRecorder()
+	_, err := a.srv.UIMetricsProxy(resp, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
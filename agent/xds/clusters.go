@@ -2,6 +2,7 @@ package xds
 
 import (
 	"errors"
+	"sort"
 	"time"
 
 	envoy "github.com/envoyproxy/go-control-plane/envoy/api/v2"
@@ -10,6 +11,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 
 	"github.com/hashicorp/consul/agent/proxycfg"
+	"github.com/hashicorp/consul/agent/structs"
 )
 
 // clustersFromSnapshot returns the xDS API representation of the "clusters"
@@ -27,9 +29,46 @@ func clustersFromSnapshot(cfgSnap *proxycfg.ConfigSnapshot, token string) ([]pro
 		clusters[idx+1] = makeUpstreamCluster(upstream.Identifier(), cfgSnap)
 	}
 
+	if cfgSnap.Proxy.Mode == structs.ProxyModeTransparent {
+		for _, service := range transparentServices(cfgSnap) {
+			clusters = append(clusters, makeUpstreamCluster(transparentClusterName(service), cfgSnap))
+		}
+		clusters = append(clusters, makePassthroughCluster())
+	}
+
 	return clusters, nil
 }
 
+// makePassthroughCluster returns the cluster which connects to the original
+// destination of the outbound connections of a proxy in the transparent mode
+// which aren't for a service of the mesh.
+func makePassthroughCluster() *envoy.Cluster {
+	return &envoy.Cluster{
+		Name:           PassthroughClusterName,
+		ConnectTimeout: 5 * time.Second,
+		Type:           envoy.Cluster_ORIGINAL_DST,
+		LbPolicy:       envoy.Cluster_ORIGINAL_DST_LB,
+	}
+}
+
+// transparentServices returns the sorted names of the services a proxy in the
+// transparent mode routes to by their virtual IP.
+func transparentServices(cfgSnap *proxycfg.ConfigSnapshot) []string {
+	services := make([]string, 0, len(cfgSnap.TransparentEndpoints))
+	for service := range cfgSnap.TransparentEndpoints {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// transparentClusterName returns the name of the cluster of a service a proxy
+// in the transparent mode routes to. It differs from the name of the cluster
+// of an explicit upstream for the same service.
+func transparentClusterName(service string) string {
+	return "transparent:" + service
+}
+
 func makeAppCluster(cfgSnap *proxycfg.ConfigSnapshot) *envoy.Cluster {
 	addr := cfgSnap.Proxy.LocalServiceAddress
 	if addr == "" {
@@ -25,6 +25,13 @@ func endpointsFromSnapshot(cfgSnap *proxycfg.ConfigSnapshot, token string) ([]pr
 		la := makeLoadAssignment(id, endpoints)
 		resources = append(resources, la)
 	}
+	for service, endpoints := range cfgSnap.TransparentEndpoints {
+		if len(endpoints) < 1 {
+			continue
+		}
+		la := makeLoadAssignment(transparentClusterName(service), endpoints)
+		resources = append(resources, la)
+	}
 	return resources, nil
 }
 
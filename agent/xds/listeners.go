@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 
 	envoy "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	envoyauth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
@@ -42,9 +43,63 @@ func listenersFromSnapshot(cfgSnap *proxycfg.ConfigSnapshot, token string) ([]pr
 			return nil, err
 		}
 	}
+
+	if cfgSnap.Proxy.Mode == structs.ProxyModeTransparent {
+		l, err := makeOutboundListener(cfgSnap)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, l)
+	}
 	return resources, nil
 }
 
+// makeOutboundListener returns the listener the outbound traffic of a proxy in
+// the transparent mode is redirected to. The connections are routed by their
+// original destination, the virtual IP of a service, and passed through to
+// their original destination if it isn't one.
+func makeOutboundListener(cfgSnap *proxycfg.ConfigSnapshot) (*envoy.Listener, error) {
+	l := makeListener(OutboundListenerName, "127.0.0.1", cfgSnap.Proxy.OutboundListenerPort())
+	l.ListenerFilters = []envoylistener.ListenerFilter{
+		{Name: "envoy.listener.original_dst"},
+	}
+
+	for _, service := range transparentServices(cfgSnap) {
+		ip := net.ParseIP(cfgSnap.VirtualIPs[service])
+		if ip == nil {
+			continue
+		}
+		prefixLen := uint32(32)
+		if ip.To4() == nil {
+			prefixLen = 128
+		}
+
+		name := transparentClusterName(service)
+		tcpProxy, err := makeTCPProxyFilter(name, name)
+		if err != nil {
+			return nil, err
+		}
+		l.FilterChains = append(l.FilterChains, envoylistener.FilterChain{
+			FilterChainMatch: &envoylistener.FilterChainMatch{
+				PrefixRanges: []*envoycore.CidrRange{{
+					AddressPrefix: ip.String(),
+					PrefixLen:     &types.UInt32Value{Value: prefixLen},
+				}},
+			},
+			Filters: []envoylistener.Filter{tcpProxy},
+		})
+	}
+
+	tcpProxy, err := makeTCPProxyFilter(PassthroughClusterName, PassthroughClusterName)
+	if err != nil {
+		return nil, err
+	}
+	l.FilterChains = append(l.FilterChains, envoylistener.FilterChain{
+		Filters: []envoylistener.Filter{tcpProxy},
+	})
+	return l, nil
+}
+
 // makeListener returns a listener with name and bind details set. Filters must
 // be added before it's useful.
 //
@@ -56,6 +56,16 @@ const (
 	// LocalAgentClusterName is the name we give the local agent "cluster" in
 	// Envoy config.
 	LocalAgentClusterName = "local_agent"
+
+	// OutboundListenerName is the name we give the listener the outbound
+	// traffic of proxies in the transparent mode is redirected to in Envoy
+	// config.
+	OutboundListenerName = "outbound_listener"
+
+	// PassthroughClusterName is the name we give the "cluster" in Envoy config
+	// which passes the outbound traffic of proxies in the transparent mode to
+	// destinations outside of the mesh through.
+	PassthroughClusterName = "original-destination"
 )
 
 // ACLResolverFunc is a shim to resolve ACLs. Since ACL enforcement is so far
@@ -392,7 +402,7 @@ func (s *Server) Check(ctx context.Context, r *envoyauthz.CheckRequest) (*envoya
 	token := tokenFromContext(ctx)
 	authed, reason, _, err := s.Authz.ConnectAuthorize(token, req)
 	if err != nil {
-		if err == acl.ErrPermissionDenied {
+		if acl.IsErrPermissionDenied(err) {
 			return nil, status.Error(codes.PermissionDenied, err.Error())
 		}
 		return nil, status.Error(codes.Internal, err.Error())
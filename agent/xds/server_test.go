@@ -909,6 +909,53 @@ func TestServer_ConfigOverrides(t *testing.T) {
 	}
 }
 
+func TestServer_TransparentProxy(t *testing.T) {
+	require := require.New(t)
+	snap := proxycfg.TestConfigSnapshotTransparent(t)
+
+	// The outbound listener routes the virtual IPs of the services with
+	// endpoints, everything else is passed through.
+	listeners, err := listenersFromSnapshot(snap, "my-token")
+	require.NoError(err)
+	require.Len(listeners, 2)
+	outbound := listeners[1].(*envoy.Listener)
+	require.Equal("outbound_listener:127.0.0.1:15001", outbound.Name)
+	require.Len(outbound.ListenerFilters, 1)
+	require.Equal("envoy.listener.original_dst", outbound.ListenerFilters[0].Name)
+	require.Len(outbound.FilterChains, 2)
+	match := outbound.FilterChains[0].FilterChainMatch
+	require.Len(match.PrefixRanges, 1)
+	require.Equal("240.0.0.2", match.PrefixRanges[0].AddressPrefix)
+	require.Equal(uint32(32), match.PrefixRanges[0].PrefixLen.Value)
+	require.Equal("transparent:db",
+		outbound.FilterChains[0].Filters[0].Config.Fields["cluster"].GetStringValue())
+	require.Nil(outbound.FilterChains[1].FilterChainMatch)
+	require.Equal(PassthroughClusterName,
+		outbound.FilterChains[1].Filters[0].Config.Fields["cluster"].GetStringValue())
+
+	clusters, err := clustersFromSnapshot(snap, "my-token")
+	require.NoError(err)
+	var names []string
+	for _, c := range clusters {
+		names = append(names, c.(*envoy.Cluster).Name)
+	}
+	require.Equal([]string{LocalAppClusterName, "transparent:db", PassthroughClusterName}, names)
+	require.Equal(envoy.Cluster_ORIGINAL_DST, clusters[2].(*envoy.Cluster).Type)
+
+	endpoints, err := endpointsFromSnapshot(snap, "my-token")
+	require.NoError(err)
+	require.Len(endpoints, 1)
+	la := endpoints[0].(*envoy.ClusterLoadAssignment)
+	require.Equal("transparent:db", la.ClusterName)
+	require.Len(la.Endpoints[0].LbEndpoints, len(snap.TransparentEndpoints["db"]))
+
+	// The outbound listener port can be configured.
+	snap.Proxy.TransparentProxy.OutboundListenerPort = 15201
+	listeners, err = listenersFromSnapshot(snap, "my-token")
+	require.NoError(err)
+	require.Equal("outbound_listener:127.0.0.1:15201", listeners[1].(*envoy.Listener).Name)
+}
+
 type customListenerJSONOptions struct {
 	Name          string
 	IncludeType   bool
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -19,17 +22,39 @@ type ACLTokenPolicyLink struct {
 	Name string
 }
 
+// ACLNodeIdentity represents a high-level grant of all necessary privileges
+// to assume the identity of the named Node in the Catalog and within the
+// associated datacenter.
+type ACLNodeIdentity struct {
+	NodeName   string
+	Datacenter string
+}
+
+// ACLServiceIdentity represents a high-level grant of all necessary privileges
+// to register the named service, and its sidecar proxy, in the Catalog within
+// the given datacenters, or all of them if none are given.
+type ACLServiceIdentity struct {
+	ServiceName string
+	Datacenters []string `json:",omitempty"`
+}
+
 // ACLToken represents an ACL Token
 type ACLToken struct {
-	CreateIndex uint64
-	ModifyIndex uint64
-	AccessorID  string
-	SecretID    string
-	Description string
-	Policies    []*ACLTokenPolicyLink
-	Local       bool
-	CreateTime  time.Time `json:",omitempty"`
-	Hash        []byte    `json:",omitempty"`
+	CreateIndex       uint64
+	ModifyIndex       uint64
+	AccessorID        string
+	SecretID          string
+	Description       string
+	Policies          []*ACLTokenPolicyLink
+	NodeIdentities    []*ACLNodeIdentity    `json:",omitempty"`
+	ServiceIdentities []*ACLServiceIdentity `json:",omitempty"`
+	Local             bool
+	AuthMethod        string        `json:",omitempty"`
+	IntroducedBy      string        `json:",omitempty"`
+	ExpirationTTL     time.Duration `json:",omitempty"`
+	ExpirationTime    *time.Time    `json:",omitempty"`
+	CreateTime        time.Time     `json:",omitempty"`
+	Hash              []byte        `json:",omitempty"`
 
 	// DEPRECATED (ACL-Legacy-Compat)
 	// Rules will only be present for legacy tokens returned via the new APIs
@@ -37,15 +62,19 @@ type ACLToken struct {
 }
 
 type ACLTokenListEntry struct {
-	CreateIndex uint64
-	ModifyIndex uint64
-	AccessorID  string
-	Description string
-	Policies    []*ACLTokenPolicyLink
-	Local       bool
-	CreateTime  time.Time
-	Hash        []byte
-	Legacy      bool
+	CreateIndex       uint64
+	ModifyIndex       uint64
+	AccessorID        string
+	Description       string
+	Policies          []*ACLTokenPolicyLink
+	NodeIdentities    []*ACLNodeIdentity
+	ServiceIdentities []*ACLServiceIdentity
+	Local             bool
+	AuthMethod        string     `json:",omitempty"`
+	ExpirationTime    *time.Time `json:",omitempty"`
+	CreateTime        time.Time
+	Hash              []byte
+	Legacy            bool
 }
 
 // ACLEntry is used to represent a legacy ACL token
@@ -91,6 +120,181 @@ type ACLPolicyListEntry struct {
 	ModifyIndex uint64
 }
 
+// ACLTokenImpact is the change of the access of a token with a proposed
+// change of a policy.
+type ACLTokenImpact struct {
+	AccessorID  string
+	Description string
+	Local       bool
+	Changes     []*ACLAccessChange
+}
+
+// ACLAccessChange is the change of the access of a token to a resource. The
+// access levels are "deny", "read" and "write".
+type ACLAccessChange struct {
+	Resource string
+	Segment  string `json:",omitempty"`
+	Before   string
+	After    string
+}
+
+// ACLAuthorizationRequest is a single check of a batch authorization request,
+// whether a token has the access to a resource. The access is "read", "write"
+// or "list" for keys.
+type ACLAuthorizationRequest struct {
+	Resource string
+	Segment  string `json:",omitempty"`
+	Access   string
+}
+
+// ACLAuthorizationResponse is the decision for a single check of a batch
+// authorization request.
+type ACLAuthorizationResponse struct {
+	ACLAuthorizationRequest
+	Allow bool
+}
+
+// ACLPolicyValidateResult is the result of checking the rules of a policy.
+// CanonicalRules is only set for valid rules when it was requested.
+type ACLPolicyValidateResult struct {
+	Valid          bool
+	Error          *ACLRulesError
+	CanonicalRules string
+}
+
+// ACLRulesError is an error in the rules of a policy. The line and column are
+// zero if the position of the error isn't known.
+type ACLRulesError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+// ACLAuthMethod represents an ACL Auth Method, with which workloads can
+// exchange the credentials of another system for an ACL Token.
+type ACLAuthMethod struct {
+	Name        string
+	Type        string
+	Description string
+	Policies    []*ACLTokenPolicyLink `json:",omitempty"`
+	MaxTokenTTL time.Duration         `json:",omitempty"`
+
+	// Configuration is arbitrary configuration for the auth method. This
+	// should only contain primitive values and containers (such as lists and
+	// maps).
+	Config map[string]interface{}
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+type ACLAuthMethodListEntry struct {
+	Name        string
+	Type        string
+	Description string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+type BindingRuleBindType string
+
+const (
+	// BindingRuleBindTypeService binds to a service identity with the given
+	// name.
+	BindingRuleBindTypeService BindingRuleBindType = "service"
+
+	// BindingRuleBindTypeNode binds to a node identity with the given name in
+	// the datacenter of the login.
+	BindingRuleBindTypeNode BindingRuleBindType = "node"
+
+	// BindingRuleBindTypePolicy binds to the policy with the given name.
+	BindingRuleBindTypePolicy BindingRuleBindType = "policy"
+)
+
+// ACLBindingRule maps the identity attributes of a login with an auth method
+// to the privileges granted to the token created for it.
+type ACLBindingRule struct {
+	ID          string
+	Description string
+	AuthMethod  string
+	Selector    string
+	BindType    BindingRuleBindType
+	BindName    string
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// KubernetesAuthMethodConfig is the config for the built-in Consul auth method
+// for Kubernetes.
+type KubernetesAuthMethodConfig struct {
+	Host              string `json:",omitempty"`
+	CACert            string `json:",omitempty"`
+	ServiceAccountJWT string `json:",omitempty"`
+}
+
+// RenderToConfig converts this into a map[string]interface{} suitable for use
+// in the ACLAuthMethod.Config field.
+func (c *KubernetesAuthMethodConfig) RenderToConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"Host":              c.Host,
+		"CACert":            c.CACert,
+		"ServiceAccountJWT": c.ServiceAccountJWT,
+	}
+}
+
+// JWTAuthMethodConfig is the config for the built-in Consul auth method for
+// JWTs issued by OIDC identity providers.
+type JWTAuthMethodConfig struct {
+	JWKSURL              string                 `json:",omitempty"`
+	OIDCDiscoveryURL     string                 `json:",omitempty"`
+	JWKSCACert           string                 `json:",omitempty"`
+	JWTValidationPubKeys []string               `json:",omitempty"`
+	BoundIssuer          string                 `json:",omitempty"`
+	BoundAudiences       []string               `json:",omitempty"`
+	BoundClaims          map[string]interface{} `json:",omitempty"`
+	ClaimMappings        map[string]string      `json:",omitempty"`
+	ClockSkewLeeway      time.Duration          `json:",omitempty"`
+}
+
+// RenderToConfig converts this into a map[string]interface{} suitable for use
+// in the ACLAuthMethod.Config field. Unset fields are left out.
+func (c *JWTAuthMethodConfig) RenderToConfig() map[string]interface{} {
+	config := make(map[string]interface{})
+	set := func(key, value string) {
+		if value != "" {
+			config[key] = value
+		}
+	}
+	set("JWKSURL", c.JWKSURL)
+	set("OIDCDiscoveryURL", c.OIDCDiscoveryURL)
+	set("JWKSCACert", c.JWKSCACert)
+	set("BoundIssuer", c.BoundIssuer)
+	if len(c.JWTValidationPubKeys) > 0 {
+		config["JWTValidationPubKeys"] = c.JWTValidationPubKeys
+	}
+	if len(c.BoundAudiences) > 0 {
+		config["BoundAudiences"] = c.BoundAudiences
+	}
+	if len(c.BoundClaims) > 0 {
+		config["BoundClaims"] = c.BoundClaims
+	}
+	if len(c.ClaimMappings) > 0 {
+		config["ClaimMappings"] = c.ClaimMappings
+	}
+	if c.ClockSkewLeeway != 0 {
+		config["ClockSkewLeeway"] = c.ClockSkewLeeway.String()
+	}
+	return config
+}
+
+// ACLLoginParams are the credentials presented to an auth method to log in.
+type ACLLoginParams struct {
+	AuthMethod  string
+	BearerToken string
+	Meta        map[string]string `json:",omitempty"`
+}
+
 // ACL can be used to query the ACL endpoints
 type ACL struct {
 	c *Client
@@ -305,7 +509,7 @@ func (a *ACL) TokenClone(tokenID string, description string, q *WriteOptions) (*
 		return nil, nil, fmt.Errorf("Must specify a tokenID for Token Cloning")
 	}
 
-	r := a.c.newRequest("PUT", "/v1/acl/token/clone/"+tokenID)
+	r := a.c.newRequest("PUT", "/v1/acl/token/"+tokenID+"/clone")
 	r.setWriteOptions(q)
 	r.obj = struct{ Description string }{description}
 	rtt, resp, err := requireOK(a.c.doRequest(r))
@@ -336,6 +540,30 @@ func (a *ACL) TokenDelete(tokenID string, q *WriteOptions) (*WriteMeta, error) {
 	return wm, nil
 }
 
+// TokenDeleteFilter deletes all the tokens matching the filter expression and
+// returns them. With dryRun the matching tokens are returned without deleting
+// them.
+func (a *ACL) TokenDeleteFilter(filter string, dryRun bool, q *WriteOptions) ([]*ACLTokenListEntry, *WriteMeta, error) {
+	r := a.c.newRequest("DELETE", "/v1/acl/tokens")
+	r.setWriteOptions(q)
+	r.params.Set("filter", filter)
+	if dryRun {
+		r.params.Set("dry-run", "")
+	}
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var entries []*ACLTokenListEntry
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, wm, nil
+}
+
 func (a *ACL) TokenRead(tokenID string, q *QueryOptions) (*ACLToken, *QueryMeta, error) {
 	r := a.c.newRequest("GET", "/v1/acl/token/"+tokenID)
 	r.setQueryOptions(q)
@@ -378,9 +606,54 @@ func (a *ACL) TokenReadSelf(q *QueryOptions) (*ACLToken, *QueryMeta, error) {
 	return &out, qm, nil
 }
 
+// TokenSelfWithContext is like TokenReadSelf but cancels the request when the
+// context is done. It is useful to check the token of a TokenSource.
+func (a *ACL) TokenSelfWithContext(ctx context.Context, q *QueryOptions) (*ACLToken, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{}
+	}
+	return a.TokenReadSelf(q.WithContext(ctx))
+}
+
+// RotateSecret replaces a token by a new token with the same policies, node
+// identities and description and deletes the old token. Both the AccessorID
+// and the SecretID of the new token differ from the old token, since the
+// secret of a token can't be changed.
+func (a *ACL) RotateSecret(tokenID string, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	var qo *QueryOptions
+	if q != nil {
+		qo = (&QueryOptions{Datacenter: q.Datacenter, Token: q.Token}).WithContext(q.Context())
+	}
+	old, _, err := a.TokenRead(tokenID, qo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, _, err := a.TokenClone(tokenID, old.Description, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wm, err := a.TokenDelete(tokenID, q)
+	if err != nil {
+		return token, nil, fmt.Errorf("Failed to delete the old token %q: %v", tokenID, err)
+	}
+	return token, wm, nil
+}
+
 func (a *ACL) TokenList(q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
+	return a.TokenListFiltered("", q)
+}
+
+// TokenListFiltered lists the tokens which match the filter expression, which
+// is evaluated by the servers. See the ACL guide for the fields of the tokens
+// which can be used in the expression.
+func (a *ACL) TokenListFiltered(filter string, q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, error) {
 	r := a.c.newRequest("GET", "/v1/acl/tokens")
 	r.setQueryOptions(q)
+	if filter != "" {
+		r.params.Set("filter", filter)
+	}
 	rtt, resp, err := requireOK(a.c.doRequest(r))
 	if err != nil {
 		return nil, nil, err
@@ -398,6 +671,62 @@ func (a *ACL) TokenList(q *QueryOptions) ([]*ACLTokenListEntry, *QueryMeta, erro
 	return entries, qm, nil
 }
 
+// ACLTokenListOptions selects a page of the tokens listed by TokenListPage.
+type ACLTokenListOptions struct {
+	// AccessorPrefix only lists the tokens whose accessor ID starts with it.
+	AccessorPrefix string
+
+	// Next is the accessor ID which starts the page, as returned by the
+	// previous call. It is empty for the first page.
+	Next string
+
+	// Limit is the maximum number of tokens in the page. Zero lists all the
+	// tokens.
+	Limit int
+
+	// Filter only lists the tokens which match the filter expression, see
+	// TokenListFiltered.
+	Filter string
+}
+
+// TokenListPage lists the tokens in the order of their accessor IDs, one page
+// at a time. It returns the accessor ID which starts the next page, which is
+// empty on the last page. A page may have fewer tokens than the limit if
+// some of them expired or don't match the filter.
+func (a *ACL) TokenListPage(opts *ACLTokenListOptions, q *QueryOptions) ([]*ACLTokenListEntry, string, *QueryMeta, error) {
+	r := a.c.newRequest("GET", "/v1/acl/tokens")
+	r.setQueryOptions(q)
+	if opts != nil {
+		if opts.AccessorPrefix != "" {
+			r.params.Set("prefix", opts.AccessorPrefix)
+		}
+		if opts.Next != "" {
+			r.params.Set("next", opts.Next)
+		}
+		if opts.Limit > 0 {
+			r.params.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		if opts.Filter != "" {
+			r.params.Set("filter", opts.Filter)
+		}
+	}
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*ACLTokenListEntry
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, "", nil, err
+	}
+	return entries, resp.Header.Get("X-Consul-Next"), qm, nil
+}
+
 // TokenUpgrade performs an almost identical operation as TokenUpdate. The only difference is
 // that not all parts of the token must be specified here and the server will patch the token
 // with the existing secret id, description etc.
@@ -504,8 +833,18 @@ func (a *ACL) PolicyRead(policyID string, q *QueryOptions) (*ACLPolicy, *QueryMe
 }
 
 func (a *ACL) PolicyList(q *QueryOptions) ([]*ACLPolicyListEntry, *QueryMeta, error) {
+	return a.PolicyListFiltered("", q)
+}
+
+// PolicyListFiltered lists the policies which match the filter expression,
+// which is evaluated by the servers. See the ACL guide for the fields of the
+// policies which can be used in the expression.
+func (a *ACL) PolicyListFiltered(filter string, q *QueryOptions) ([]*ACLPolicyListEntry, *QueryMeta, error) {
 	r := a.c.newRequest("GET", "/v1/acl/policies")
 	r.setQueryOptions(q)
+	if filter != "" {
+		r.params.Set("filter", filter)
+	}
 	rtt, resp, err := requireOK(a.c.doRequest(r))
 	if err != nil {
 		return nil, nil, err
@@ -523,6 +862,35 @@ func (a *ACL) PolicyList(q *QueryOptions) ([]*ACLPolicyListEntry, *QueryMeta, er
 	return entries, qm, nil
 }
 
+// PolicyImpact returns the tokens whose access would change if the policy
+// was updated with the given rules, or deleted if del is set. Nothing is
+// changed.
+func (a *ACL) PolicyImpact(policy *ACLPolicy, del bool, q *WriteOptions) ([]*ACLTokenImpact, *WriteMeta, error) {
+	if policy.ID == "" {
+		return nil, nil, fmt.Errorf("Must specify an ID in Policy Impact")
+	}
+
+	r := a.c.newRequest("PUT", "/v1/acl/policy/impact")
+	r.setWriteOptions(q)
+	if del {
+		r.params.Set("delete", "")
+	}
+	r.obj = policy
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out struct{ Tokens []*ACLTokenImpact }
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return out.Tokens, wm, nil
+}
+
 func (a *ACL) PolicyTranslate(rules string) (string, error) {
 	r := a.c.newRequest("POST", "/v1/acl/policy/translate")
 	r.obj = rules
@@ -543,3 +911,312 @@ func (a *ACL) PolicyTranslate(rules string) (string, error) {
 	return string(ruleBytes), nil
 
 }
+
+// AuthMethodCreate will create a new auth method.
+func (a *ACL) AuthMethodCreate(method *ACLAuthMethod, q *WriteOptions) (*ACLAuthMethod, *WriteMeta, error) {
+	if method.Name == "" {
+		return nil, nil, fmt.Errorf("Must specify a Name in Auth Method Creation")
+	}
+
+	r := a.c.newRequest("PUT", "/v1/acl/auth-method")
+	r.setWriteOptions(q)
+	r.obj = method
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLAuthMethod
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, wm, nil
+}
+
+// AuthMethodUpdate updates an auth method.
+func (a *ACL) AuthMethodUpdate(method *ACLAuthMethod, q *WriteOptions) (*ACLAuthMethod, *WriteMeta, error) {
+	if method.Name == "" {
+		return nil, nil, fmt.Errorf("Must specify a Name in Auth Method Update")
+	}
+
+	r := a.c.newRequest("PUT", "/v1/acl/auth-method/"+url.QueryEscape(method.Name))
+	r.setWriteOptions(q)
+	r.obj = method
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLAuthMethod
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, wm, nil
+}
+
+// AuthMethodDelete deletes an auth method given its Name, along with the
+// tokens created with it.
+func (a *ACL) AuthMethodDelete(methodName string, q *WriteOptions) (*WriteMeta, error) {
+	if methodName == "" {
+		return nil, fmt.Errorf("Must specify a Name in Auth Method Delete")
+	}
+
+	r := a.c.newRequest("DELETE", "/v1/acl/auth-method/"+url.QueryEscape(methodName))
+	r.setWriteOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	return wm, nil
+}
+
+// AuthMethodRead retrieves the auth method.
+func (a *ACL) AuthMethodRead(methodName string, q *QueryOptions) (*ACLAuthMethod, *QueryMeta, error) {
+	if methodName == "" {
+		return nil, nil, fmt.Errorf("Must specify a Name in Auth Method Read")
+	}
+
+	r := a.c.newRequest("GET", "/v1/acl/auth-method/"+url.QueryEscape(methodName))
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out ACLAuthMethod
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, qm, nil
+}
+
+// AuthMethodList retrieves a listing of all auth methods. The listing does not
+// include some metadata for the auth method as those should be retrieved by
+// subsequent calls to AuthMethodRead.
+func (a *ACL) AuthMethodList(q *QueryOptions) ([]*ACLAuthMethodListEntry, *QueryMeta, error) {
+	r := a.c.newRequest("GET", "/v1/acl/auth-methods")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*ACLAuthMethodListEntry
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
+// BindingRuleCreate will create a new binding rule. It is not allowed for the
+// binding rule parameter's ID field to be set as this will be generated by
+// Consul while processing the request.
+func (a *ACL) BindingRuleCreate(rule *ACLBindingRule, q *WriteOptions) (*ACLBindingRule, *WriteMeta, error) {
+	if rule.ID != "" {
+		return nil, nil, fmt.Errorf("Cannot specify an ID in Binding Rule Creation")
+	}
+
+	r := a.c.newRequest("PUT", "/v1/acl/binding-rule")
+	r.setWriteOptions(q)
+	r.obj = rule
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLBindingRule
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, wm, nil
+}
+
+// BindingRuleUpdate updates a binding rule. The ID field of the binding rule
+// parameter must be set to an existing binding rule ID.
+func (a *ACL) BindingRuleUpdate(rule *ACLBindingRule, q *WriteOptions) (*ACLBindingRule, *WriteMeta, error) {
+	if rule.ID == "" {
+		return nil, nil, fmt.Errorf("Must specify an ID in Binding Rule Update")
+	}
+
+	r := a.c.newRequest("PUT", "/v1/acl/binding-rule/"+rule.ID)
+	r.setWriteOptions(q)
+	r.obj = rule
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLBindingRule
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, wm, nil
+}
+
+// BindingRuleDelete deletes a binding rule given its ID.
+func (a *ACL) BindingRuleDelete(bindingRuleID string, q *WriteOptions) (*WriteMeta, error) {
+	r := a.c.newRequest("DELETE", "/v1/acl/binding-rule/"+bindingRuleID)
+	r.setWriteOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	return wm, nil
+}
+
+// BindingRuleRead retrieves the binding rule details.
+func (a *ACL) BindingRuleRead(bindingRuleID string, q *QueryOptions) (*ACLBindingRule, *QueryMeta, error) {
+	r := a.c.newRequest("GET", "/v1/acl/binding-rule/"+bindingRuleID)
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out ACLBindingRule
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+
+	return &out, qm, nil
+}
+
+// BindingRuleList retrieves a listing of all binding rules, or only those of
+// the given auth method if methodName is not empty.
+func (a *ACL) BindingRuleList(methodName string, q *QueryOptions) ([]*ACLBindingRule, *QueryMeta, error) {
+	r := a.c.newRequest("GET", "/v1/acl/binding-rules")
+	if methodName != "" {
+		r.params.Set("authmethod", methodName)
+	}
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*ACLBindingRule
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
+// Login is used to exchange auth method credentials for a newly-minted Consul Token.
+func (a *ACL) Login(auth *ACLLoginParams, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	r := a.c.newRequest("POST", "/v1/acl/login")
+	r.setWriteOptions(q)
+	r.obj = auth
+
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLToken
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// Logout is used to destroy a Consul Token created via Login().
+func (a *ACL) Logout(q *WriteOptions) (*WriteMeta, error) {
+	r := a.c.newRequest("POST", "/v1/acl/logout")
+	r.setWriteOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	return wm, nil
+}
+
+// Authorize is used to check whether the token of the request has the access
+// to each of the resources, without attempting any operation. The decisions
+// are returned in the order of the checks.
+func (a *ACL) Authorize(checks []ACLAuthorizationRequest, q *WriteOptions) ([]ACLAuthorizationResponse, *WriteMeta, error) {
+	r := a.c.newRequest("POST", "/v1/acl/authorize")
+	r.setWriteOptions(q)
+	r.obj = checks
+
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out []ACLAuthorizationResponse
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, wm, nil
+}
+
+// PolicyValidate checks the rules of the policy without storing it, and
+// returns the rules in the canonical form if canonical is set and they are
+// valid. Invalid rules are reported in the result and not as an error.
+func (a *ACL) PolicyValidate(policy *ACLPolicy, canonical bool, q *WriteOptions) (*ACLPolicyValidateResult, *WriteMeta, error) {
+	r := a.c.newRequest("PUT", "/v1/acl/policy/validate")
+	r.setWriteOptions(q)
+	if canonical {
+		r.params.Set("canonical", "")
+	}
+	r.obj = policy
+
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	var out ACLPolicyValidateResult
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"context"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPI_ACLBootstrap(t *testing.T) {
@@ -163,3 +166,48 @@ func TestAPI_ACLReplication(t *testing.T) {
 		t.Fatalf("bad: %v", qm)
 	}
 }
+
+func TestAPI_ACLToken_SelfWithContext(t *testing.T) {
+	t.Parallel()
+	c, s := makeACLClient(t)
+	defer s.Stop()
+
+	acl := c.ACL()
+
+	token, _, err := acl.TokenSelfWithContext(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, "root", token.SecretID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = acl.TokenSelfWithContext(ctx, nil)
+	require.Error(t, err)
+}
+
+func TestAPI_ACLToken_RotateSecret(t *testing.T) {
+	t.Parallel()
+	c, s := makeACLClient(t)
+	defer s.Stop()
+
+	acl := c.ACL()
+
+	policy, _, err := acl.PolicyCreate(&ACLPolicy{Name: "test", Rules: `node_prefix "" { policy = "read" }`}, nil)
+	require.NoError(t, err)
+	old, _, err := acl.TokenCreate(&ACLToken{
+		Description: "deploy",
+		Policies:    []*ACLTokenPolicyLink{{ID: policy.ID}},
+	}, nil)
+	require.NoError(t, err)
+
+	token, wm, err := acl.RotateSecret(old.AccessorID, nil)
+	require.NoError(t, err)
+	require.NotNil(t, wm)
+	require.NotEqual(t, old.AccessorID, token.AccessorID)
+	require.NotEqual(t, old.SecretID, token.SecretID)
+	require.Equal(t, "deploy", token.Description)
+	require.Len(t, token.Policies, 1)
+	require.Equal(t, policy.ID, token.Policies[0].ID)
+
+	_, _, err = acl.TokenRead(old.AccessorID, nil)
+	require.Error(t, err)
+}
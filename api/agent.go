@@ -3,6 +3,8 @@ package api
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"time"
 )
 
 // ServiceKind is the kind of service being registered.
@@ -50,6 +52,11 @@ const (
 	UpstreamDestTypePreparedQuery UpstreamDestType = "prepared_query"
 )
 
+// MetaExternalSource is the service metadata key used by the tools which
+// sync services from external systems, such as Kubernetes or Terraform, to
+// record where the service registration is managed.
+const MetaExternalSource = "external-source"
+
 // AgentCheck represents a check known to the agent
 type AgentCheck struct {
 	Node        string
@@ -71,18 +78,19 @@ type AgentWeights struct {
 
 // AgentService represents a service known to the agent
 type AgentService struct {
-	Kind              ServiceKind `json:",omitempty"`
-	ID                string
-	Service           string
-	Tags              []string
-	Meta              map[string]string
-	Port              int
-	Address           string
-	Weights           AgentWeights
-	EnableTagOverride bool
-	CreateIndex       uint64 `json:",omitempty"`
-	ModifyIndex       uint64 `json:",omitempty"`
-	ContentHash       string `json:",omitempty"`
+	Kind                 ServiceKind `json:",omitempty"`
+	ID                   string
+	Service              string
+	Tags                 []string
+	Meta                 map[string]string
+	Port                 int
+	Address              string
+	Weights              AgentWeights
+	EnableTagOverride    bool
+	DeregisterOnShutdown *bool  `json:",omitempty"`
+	CreateIndex          uint64 `json:",omitempty"`
+	ModifyIndex          uint64 `json:",omitempty"`
+	ContentHash          string `json:",omitempty"`
 	// DEPRECATED (ProxyDestination) - remove this field
 	ProxyDestination string                          `json:",omitempty"`
 	Proxy            *AgentServiceConnectProxyConfig `json:",omitempty"`
@@ -114,6 +122,38 @@ type AgentServiceConnectProxyConfig struct {
 	LocalServicePort       int                    `json:",omitempty"`
 	Config                 map[string]interface{} `json:",omitempty"`
 	Upstreams              []Upstream
+	Mode                   ProxyMode              `json:",omitempty"`
+	TransparentProxy       TransparentProxyConfig `json:",omitempty"`
+}
+
+// ProxyMode is how the application of a proxy reaches its upstreams.
+type ProxyMode string
+
+const (
+	// ProxyModeDefault is the mode when it isn't configured, which is the
+	// direct mode.
+	ProxyModeDefault ProxyMode = ""
+
+	// ProxyModeDirect is the mode in which the application connects to the
+	// local listeners of its explicit upstreams.
+	ProxyModeDirect ProxyMode = "direct"
+
+	// ProxyModeTransparent is the mode in which the outbound traffic of the
+	// application is redirected to the proxy, which routes it by the virtual
+	// IP of the destination service.
+	ProxyModeTransparent ProxyMode = "transparent"
+)
+
+// TransparentProxyConfig is the configuration of a proxy in the transparent
+// mode.
+type TransparentProxyConfig struct {
+	// OutboundListenerPort is the port the outbound traffic of the application
+	// is redirected to. It defaults to 15001.
+	OutboundListenerPort int `json:",omitempty"`
+
+	// AllowedServices are the services the application may reach through the
+	// proxy. If empty, they are the services the intentions allow.
+	AllowedServices []string `json:",omitempty"`
 }
 
 // AgentMember represents a cluster member known to the agent
@@ -144,19 +184,40 @@ type MembersOpts struct {
 	Segment string
 }
 
+// MonitorOpts is used to select the logs streamed by Monitor.
+type MonitorOpts struct {
+	// LogLevel is the minimum level of the logs. It defaults to INFO.
+	LogLevel string
+
+	// Include lists the subsystems whose logs are streamed, e.g. "raft" or
+	// "consul". Nested subsystems such as "consul.fsm" are included with
+	// their parent. All subsystems are streamed if it is empty.
+	Include []string
+
+	// Exclude lists the subsystems whose logs are dropped. It takes
+	// precedence over Include.
+	Exclude []string
+
+	// JSON streams each log line as a JSON object with the @timestamp,
+	// @level, @module and @message fields instead of plain text.
+	JSON bool
+}
+
 // AgentServiceRegistration is used to register a new service
 type AgentServiceRegistration struct {
-	Kind              ServiceKind       `json:",omitempty"`
-	ID                string            `json:",omitempty"`
-	Name              string            `json:",omitempty"`
-	Tags              []string          `json:",omitempty"`
-	Port              int               `json:",omitempty"`
-	Address           string            `json:",omitempty"`
-	EnableTagOverride bool              `json:",omitempty"`
-	Meta              map[string]string `json:",omitempty"`
-	Weights           *AgentWeights     `json:",omitempty"`
-	Check             *AgentServiceCheck
-	Checks            AgentServiceChecks
+	Kind                 ServiceKind       `json:",omitempty"`
+	ID                   string            `json:",omitempty"`
+	Name                 string            `json:",omitempty"`
+	Tags                 []string          `json:",omitempty"`
+	Port                 int               `json:",omitempty"`
+	Address              string            `json:",omitempty"`
+	EnableTagOverride    bool              `json:",omitempty"`
+	DeregisterOnShutdown *bool             `json:",omitempty"`
+	Warmup               string            `json:",omitempty"`
+	Meta                 map[string]string `json:",omitempty"`
+	Weights              *AgentWeights     `json:",omitempty"`
+	Check                *AgentServiceCheck
+	Checks               AgentServiceChecks
 	// DEPRECATED (ProxyDestination) - remove this field
 	ProxyDestination string                          `json:",omitempty"`
 	Proxy            *AgentServiceConnectProxyConfig `json:",omitempty"`
@@ -204,11 +265,43 @@ type AgentServiceCheck struct {
 }
 type AgentServiceChecks []*AgentServiceCheck
 
+// AgentCheckDefinitionUpdate is used to change the timing of a registered
+// check. Fields which are empty are left unchanged. The values use the same
+// Go time format as AgentServiceCheck.
+type AgentCheckDefinitionUpdate struct {
+	Interval                       string `json:",omitempty"`
+	Timeout                        string `json:",omitempty"`
+	TTL                            string `json:",omitempty"`
+	DeregisterCriticalServiceAfter string `json:",omitempty"`
+}
+
 // AgentToken is used when updating ACL tokens for an agent.
 type AgentToken struct {
 	Token string
 }
 
+// AgentReloadResult describes the effect of a configuration reload.
+type AgentReloadResult struct {
+	// Applied lists the changed settings which have been applied.
+	Applied []string
+
+	// Ignored lists the changed settings which only take effect after
+	// the agent has been restarted.
+	Ignored []string
+}
+
+// AgentRuntimeConfig is the complete runtime configuration of an agent.
+type AgentRuntimeConfig struct {
+	// Config is the runtime configuration with secrets redacted.
+	Config map[string]interface{}
+
+	// Sources maps the fields of Config like "HTTPPort" to the sources
+	// which set them, in the order they were applied. Files are listed
+	// by name, defaults as "default" and command line flags as "flags".
+	// Files which reference environment variables are followed by "env".
+	Sources map[string][]string
+}
+
 // Metrics info is used to store different types of metric values from the agent.
 type MetricsInfo struct {
 	Timestamp string
@@ -284,6 +377,71 @@ type Upstream struct {
 	Config               map[string]interface{} `json:",omitempty"`
 }
 
+// ConnectProxySnapshot is the fully resolved configuration of a local
+// Connect proxy, as it is pushed to the proxy.
+type ConnectProxySnapshot struct {
+	ProxyServiceID    string
+	TargetServiceID   string
+	TargetServiceName string
+	Address           string
+	Port              int
+	Config            map[string]interface{}
+	Upstreams         []ConnectProxySnapshotUpstream
+	Leaf              ConnectProxySnapshotLeaf
+	TrustDomain       string
+	Roots             []ConnectProxySnapshotRoot
+
+	// Intentions are the intentions with the target service as destination
+	// in the order they are evaluated. DefaultAllow is the decision if none
+	// of them matches a source.
+	Intentions   []ConnectProxySnapshotIntention
+	DefaultAllow bool
+}
+
+// ConnectProxySnapshotUpstream is an upstream of a proxy with the instances
+// it resolves to.
+type ConnectProxySnapshotUpstream struct {
+	Upstream
+	Endpoints []ConnectProxySnapshotEndpoint
+}
+
+// ConnectProxySnapshotEndpoint is an instance of an upstream.
+type ConnectProxySnapshotEndpoint struct {
+	Node      string
+	ServiceID string
+	Address   string
+	Port      int
+	Status    string
+}
+
+// ConnectProxySnapshotLeaf describes the leaf certificate of a proxy. The
+// certificate and its private key are not included.
+type ConnectProxySnapshotLeaf struct {
+	SerialNumber string
+	ServiceURI   string
+	ValidAfter   time.Time
+	ValidBefore  time.Time
+}
+
+// ConnectProxySnapshotRoot describes a trusted CA root.
+type ConnectProxySnapshotRoot struct {
+	ID           string
+	Name         string
+	SerialNumber uint64
+	NotBefore    time.Time
+	NotAfter     time.Time
+	Active       bool
+}
+
+// ConnectProxySnapshotIntention is an intention which applies to the target
+// service of a proxy.
+type ConnectProxySnapshotIntention struct {
+	ID         string
+	SourceName string
+	Action     IntentionAction
+	Precedence int
+}
+
 // Agent can be used to query the Agent endpoints
 type Agent struct {
 	c *Client
@@ -314,6 +472,24 @@ func (a *Agent) Self() (map[string]map[string]interface{}, error) {
 	return out, nil
 }
 
+// RuntimeConfig is used to retrieve the complete runtime configuration of
+// the agent together with the sources which set its fields.
+// Fields which may contain secrets are redacted. Requires agent:read.
+func (a *Agent) RuntimeConfig() (*AgentRuntimeConfig, error) {
+	r := a.c.newRequest("GET", "/v1/agent/config")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out AgentRuntimeConfig
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // Host is used to retrieve information about the host the
 // agent is running on such as CPU, memory, and disk. Requires
 // a operator:read ACL token.
@@ -360,6 +536,24 @@ func (a *Agent) Reload() error {
 	return nil
 }
 
+// ReloadWithResult triggers a configuration reload for the agent we are
+// connected to and returns which changed settings have been applied and
+// which require a restart of the agent.
+func (a *Agent) ReloadWithResult() (*AgentReloadResult, error) {
+	r := a.c.newRequest("PUT", "/v1/agent/reload")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out AgentReloadResult
+	if err := decodeBody(resp, &out); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &out, nil
+}
+
 // NodeName is used to get the node name of the agent
 func (a *Agent) NodeName() (string, error) {
 	if a.nodeName != "" {
@@ -614,6 +808,20 @@ func (a *Agent) CheckRegister(check *AgentCheckRegistration) error {
 	return nil
 }
 
+// CheckUpdateDefinition is used to change the interval, timeout, TTL or
+// critical service deregistration timeout of a check registered with the
+// local agent without re-registering it
+func (a *Agent) CheckUpdateDefinition(checkID string, update *AgentCheckDefinitionUpdate) error {
+	r := a.c.newRequest("PUT", "/v1/agent/check/update-definition/"+checkID)
+	r.obj = update
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
 // CheckDeregister is used to deregister a check with
 // the local agent
 func (a *Agent) CheckDeregister(checkID string) error {
@@ -748,6 +956,29 @@ func (a *Agent) ConnectProxyConfig(proxyServiceID string, q *QueryOptions) (*Con
 	return &out, qm, nil
 }
 
+// ConnectProxySnapshot returns the resolved configuration of a local
+// Connect proxy. The ID can be the ID of the proxy service or of the service
+// it is a proxy for.
+func (a *Agent) ConnectProxySnapshot(serviceID string, q *QueryOptions) (*ConnectProxySnapshot, *QueryMeta, error) {
+	r := a.c.newRequest("GET", "/v1/agent/connect/proxy-snapshot/"+serviceID)
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out ConnectProxySnapshot
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
 // EnableServiceMaintenance toggles service maintenance mode on
 // for the given service ID.
 func (a *Agent) EnableServiceMaintenance(serviceID, reason string) error {
@@ -802,15 +1033,214 @@ func (a *Agent) DisableNodeMaintenance() error {
 	return nil
 }
 
+// NodeMeta returns the metadata of the node of the agent we are
+// connected to.
+func (a *Agent) NodeMeta() (map[string]string, error) {
+	r := a.c.newRequest("GET", "/v1/agent/node-meta")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out map[string]string
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateNodeMeta sets the given metadata fields of the node of the agent we
+// are connected to. The fields are merged with the existing metadata and
+// take precedence over the configured ones. It returns the resulting
+// metadata.
+func (a *Agent) UpdateNodeMeta(meta map[string]string) (map[string]string, error) {
+	r := a.c.newRequest("PUT", "/v1/agent/node-meta")
+	r.obj = meta
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out map[string]string
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteNodeMeta removes the given metadata fields which were set with
+// UpdateNodeMeta. Fields which are also configured return to their
+// configured values. It returns the resulting metadata.
+func (a *Agent) DeleteNodeMeta(keys ...string) (map[string]string, error) {
+	r := a.c.newRequest("DELETE", "/v1/agent/node-meta")
+	for _, key := range keys {
+		r.params.Add("key", key)
+	}
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out map[string]string
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AgentFaultInjection configures the synthetic failures injected by an
+// agent with enable_fault_injection set.
+type AgentFaultInjection struct {
+	// RPCLatency is added to every RPC the agent makes to the servers.
+	RPCLatency ReadableDuration
+
+	// RPCDropPercent is the percentage of the RPCs which fail without
+	// being sent to the servers.
+	RPCDropPercent float64
+
+	// LeaderChurnInterval makes a server agent step down every interval
+	// while it is the leader.
+	LeaderChurnInterval ReadableDuration
+
+	// Seed seeds the selection of the dropped RPCs so that a run can be
+	// repeated. Zero uses a random seed.
+	Seed int64
+}
+
+// FaultInjection returns the failures injected by the agent we are
+// connected to.
+func (a *Agent) FaultInjection() (*AgentFaultInjection, error) {
+	r := a.c.newRequest("GET", "/v1/agent/fault-injection")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out AgentFaultInjection
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetFaultInjection replaces the failures injected by the agent we are
+// connected to.
+func (a *Agent) SetFaultInjection(faults *AgentFaultInjection) error {
+	r := a.c.newRequest("PUT", "/v1/agent/fault-injection")
+	r.obj = faults
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ClearFaultInjection stops injecting failures on the agent we are
+// connected to.
+func (a *Agent) ClearFaultInjection() error {
+	r := a.c.newRequest("DELETE", "/v1/agent/fault-injection")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// AgentCacheTypeStats are the statistics of a type of the agent cache.
+type AgentCacheTypeStats struct {
+	Type            string
+	Entries         int
+	FetchingEntries int
+	ErrorEntries    int
+	StaleEntries    int
+	Hits            uint64
+	MissesNew       uint64
+	MissesBlock     uint64
+	Bypass          uint64
+	HitRate         float64
+	FetchSuccess    uint64
+	FetchErrors     uint64
+	Evictions       uint64
+	Invalidations   uint64
+	OldestEntryAge  time.Duration
+	MaxStaleAge     time.Duration
+}
+
+// AgentCacheInvalidation is the result of invalidating the agent cache.
+type AgentCacheInvalidation struct {
+	// Invalidated is the number of entries removed from the cache.
+	Invalidated int
+}
+
+// CacheStats returns the statistics of the cache of the agent we are
+// connected to, per type.
+func (a *Agent) CacheStats() ([]*AgentCacheTypeStats, error) {
+	r := a.c.newRequest("GET", "/v1/agent/cache")
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []*AgentCacheTypeStats
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InvalidateCache removes the entries of the given type from the cache of
+// the agent we are connected to, or all its entries if the type is empty, so
+// that they are fetched again from the servers. It returns the number of
+// entries removed.
+func (a *Agent) InvalidateCache(cacheType string) (int, error) {
+	r := a.c.newRequest("DELETE", "/v1/agent/cache")
+	if cacheType != "" {
+		r.params.Set("type", cacheType)
+	}
+	_, resp, err := requireOK(a.c.doRequest(r))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var out AgentCacheInvalidation
+	if err := decodeBody(resp, &out); err != nil {
+		return 0, err
+	}
+	return out.Invalidated, nil
+}
+
 // Monitor returns a channel which will receive streaming logs from the agent
 // Providing a non-nil stopCh can be used to close the connection and stop the
 // log stream. An empty string will be sent down the given channel when there's
 // nothing left to stream, after which the caller should close the stopCh.
 func (a *Agent) Monitor(loglevel string, stopCh <-chan struct{}, q *QueryOptions) (chan string, error) {
+	return a.MonitorOpts(MonitorOpts{LogLevel: loglevel}, stopCh, q)
+}
+
+// MonitorOpts is like Monitor but allows to filter the logs by subsystem
+// and to stream them as JSON.
+func (a *Agent) MonitorOpts(opts MonitorOpts, stopCh <-chan struct{}, q *QueryOptions) (chan string, error) {
 	r := a.c.newRequest("GET", "/v1/agent/monitor")
 	r.setQueryOptions(q)
-	if loglevel != "" {
-		r.params.Add("loglevel", loglevel)
+	if opts.LogLevel != "" {
+		r.params.Add("loglevel", opts.LogLevel)
+	}
+	for _, name := range opts.Include {
+		r.params.Add("include", name)
+	}
+	for _, name := range opts.Exclude {
+		r.params.Add("exclude", name)
+	}
+	if opts.JSON {
+		r.params.Set("logjson", "true")
 	}
 	_, resp, err := requireOK(a.c.doRequest(r))
 	if err != nil {
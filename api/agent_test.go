@@ -32,6 +32,25 @@ func TestAPI_AgentSelf(t *testing.T) {
 	}
 }
 
+func TestAPI_AgentRuntimeConfig(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+
+	info, err := agent.RuntimeConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if name, ok := info.Config["NodeName"].(string); !ok || name == "" {
+		t.Fatalf("bad: %v", info)
+	}
+	if info.Sources == nil {
+		t.Fatalf("bad: %v", info)
+	}
+}
+
 func TestAPI_AgentMetrics(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -119,6 +138,20 @@ func TestAPI_AgentReload(t *testing.T) {
 	if service.Meta["some"] != "meta" {
 		t.Fatalf("Missing metadata some:=meta in %v", service)
 	}
+
+	// Change a reloadable and a non-reloadable setting
+	config = `{"service":{"name":"redis", "port":1234}, "node_meta": {"env": "test"}, "disable_update_check": false}`
+	err = ioutil.WriteFile(configFile.Name(), []byte(config), 0644)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	result, err := agent.ReloadWithResult()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	require.Equal(t, []string{"NodeMeta", "Services"}, result.Applied)
+	require.Equal(t, []string{"DisableUpdateCheck"}, result.Ignored)
 }
 
 func TestAPI_AgentMembersOpts(t *testing.T) {
@@ -1368,3 +1401,26 @@ func TestAPI_AgentConnectProxyConfig(t *testing.T) {
 	require.Equal(t, expectConfig, config)
 	require.Equal(t, expectConfig.ContentHash, qm.LastContentHash)
 }
+
+func TestAPI_AgentNodeMeta(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, nil, func(conf *testutil.TestServerConfig) {
+		conf.NodeMeta = map[string]string{"rack": "r1"}
+	})
+	defer s.Stop()
+
+	agent := c.Agent()
+	meta, err := agent.UpdateNodeMeta(map[string]string{"rack": "r2", "maintenance-window": "true"})
+	require.NoError(t, err)
+	require.Equal(t, "r2", meta["rack"])
+	require.Equal(t, "true", meta["maintenance-window"])
+
+	meta, err = agent.NodeMeta()
+	require.NoError(t, err)
+	require.Equal(t, "true", meta["maintenance-window"])
+
+	meta, err = agent.DeleteNodeMeta("rack", "maintenance-window")
+	require.NoError(t, err)
+	require.Equal(t, "r1", meta["rack"])
+	require.NotContains(t, meta, "maintenance-window")
+}
@@ -134,6 +134,11 @@ type QueryOptions struct {
 	// be provided for filtering.
 	NodeMeta map[string]string
 
+	// ExternalSource is used to filter the service listings and service
+	// instances by the source which registered them, as recorded in their
+	// MetaExternalSource metadata.
+	ExternalSource string
+
 	// RelayFactor is used in keyring operations to cause responses to be
 	// relayed back to the sender through N other random nodes. Must be
 	// a value from 0 to 5 (inclusive).
@@ -143,6 +148,12 @@ type QueryOptions struct {
 	// services. This currently affects prepared query execution.
 	Connect bool
 
+	// MinAppliedIndex is the Raft index the server answering the query must
+	// have applied, usually the WriteIndex of a preceding write. Servers
+	// which are behind forward the query to the leader, so it sees the
+	// write even when AllowStale is set.
+	MinAppliedIndex uint64
+
 	// ctx is an optional context pass through to the underlying HTTP
 	// request layer. Use Context() and WithContext() to manage this.
 	ctx context.Context
@@ -179,6 +190,11 @@ type WriteOptions struct {
 	// a value from 0 to 5 (inclusive).
 	RelayFactor uint8
 
+	// IdempotencyKey makes the write safe to retry. The agent replays the
+	// response of the first request made with the same key and token for
+	// a short time instead of applying the write again.
+	IdempotencyKey string
+
 	// ctx is an optional context pass through to the underlying HTTP
 	// request layer. Use Context() and WithContext() to manage this.
 	ctx context.Context
@@ -230,12 +246,21 @@ type QueryMeta struct {
 	// CacheAge is set if request was ?cached and indicates how stale the cached
 	// response is.
 	CacheAge time.Duration
+
+	// EffectiveWait is the time a blocking query was allowed to wait by the
+	// servers, which can be shorter than the requested WaitTime.
+	EffectiveWait time.Duration
 }
 
 // WriteMeta is used to return meta data about a write
 type WriteMeta struct {
 	// How long did the request take
 	RequestTime time.Duration
+
+	// WriteIndex is the Raft index which includes the write, if the
+	// endpoint returns one. Setting it as the MinAppliedIndex of a
+	// subsequent query ensures the query sees the write.
+	WriteIndex uint64
 }
 
 // HttpBasicAuth is used to authenticate http client with HTTP Basic Authentication
@@ -276,9 +301,30 @@ type Config struct {
 	// which overrides the agent's default token.
 	Token string
 
+	// TokenSource provides the ACL token instead of Token. It is asked
+	// for the token before every request which doesn't set its own token,
+	// so that it can replace expiring tokens.
+	TokenSource TokenSource
+
 	TLSConfig TLSConfig
 }
 
+// TokenSource provides the ACL tokens used by a client.
+type TokenSource interface {
+	// Token returns the token for the next request. Implementations must
+	// be safe for concurrent use and should cache the token until it
+	// needs to be refreshed.
+	Token() (string, error)
+}
+
+// TokenSourceFunc adapts a function to the TokenSource interface.
+type TokenSourceFunc func() (string, error)
+
+// Token implements TokenSource.
+func (f TokenSourceFunc) Token() (string, error) {
+	return f()
+}
+
 // TLSConfig is used to generate a TLSClientConfig that's useful for talking to
 // Consul using TLS.
 type TLSConfig struct {
@@ -619,12 +665,18 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 			r.params.Add("node-meta", key+":"+value)
 		}
 	}
+	if q.ExternalSource != "" {
+		r.params.Set("external-source", q.ExternalSource)
+	}
 	if q.RelayFactor != 0 {
 		r.params.Set("relay-factor", strconv.Itoa(int(q.RelayFactor)))
 	}
 	if q.Connect {
 		r.params.Set("connect", "true")
 	}
+	if q.MinAppliedIndex != 0 {
+		r.header.Set("X-Consul-MinIndex", strconv.FormatUint(q.MinAppliedIndex, 10))
+	}
 	if q.UseCache && !q.RequireConsistent {
 		r.params.Set("cached", "")
 
@@ -691,6 +743,9 @@ func (r *request) setWriteOptions(q *WriteOptions) {
 	if q.RelayFactor != 0 {
 		r.params.Set("relay-factor", strconv.Itoa(int(q.RelayFactor)))
 	}
+	if q.IdempotencyKey != "" {
+		r.header.Set("Idempotency-Key", q.IdempotencyKey)
+	}
 	r.ctx = q.ctx
 }
 
@@ -749,7 +804,7 @@ func (c *Client) newRequest(method, path string) *request {
 	if c.config.WaitTime != 0 {
 		r.params.Set("wait", durToMsec(r.config.WaitTime))
 	}
-	if c.config.Token != "" {
+	if c.config.Token != "" && c.config.TokenSource == nil {
 		r.header.Set("X-Consul-Token", r.config.Token)
 	}
 	return r
@@ -757,6 +812,15 @@ func (c *Client) newRequest(method, path string) *request {
 
 // doRequest runs a request with our client
 func (c *Client) doRequest(r *request) (time.Duration, *http.Response, error) {
+	if c.config.TokenSource != nil && r.header.Get("X-Consul-Token") == "" {
+		token, err := c.config.TokenSource.Token()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get token: %v", err)
+		}
+		if token != "" {
+			r.header.Set("X-Consul-Token", token)
+		}
+	}
 	req, err := r.toHTTP()
 	if err != nil {
 		return 0, nil, err
@@ -802,6 +866,9 @@ func (c *Client) write(endpoint string, in, out interface{}, q *WriteOptions) (*
 	defer resp.Body.Close()
 
 	wm := &WriteMeta{RequestTime: rtt}
+	if err := parseWriteMeta(resp, wm); err != nil {
+		return nil, err
+	}
 	if out != nil {
 		if err := decodeBody(resp, &out); err != nil {
 			return nil, err
@@ -850,6 +917,15 @@ func parseQueryMeta(resp *http.Response, q *QueryMeta) error {
 		q.AddressTranslationEnabled = false
 	}
 
+	// Parse X-Consul-Effective-Wait
+	if waitStr := header.Get("X-Consul-Effective-Wait"); waitStr != "" {
+		wait, err := time.ParseDuration(waitStr)
+		if err != nil {
+			return fmt.Errorf("Failed to parse X-Consul-Effective-Wait: %v", err)
+		}
+		q.EffectiveWait = wait
+	}
+
 	// Parse Cache info
 	if cacheStr := header.Get("X-Cache"); cacheStr != "" {
 		q.CacheHit = strings.EqualFold(cacheStr, "HIT")
@@ -865,6 +941,18 @@ func parseQueryMeta(resp *http.Response, q *QueryMeta) error {
 	return nil
 }
 
+// parseWriteMeta is used to help parse write meta-data
+func parseWriteMeta(resp *http.Response, w *WriteMeta) error {
+	if indexStr := resp.Header.Get("X-Consul-Write-Index"); indexStr != "" {
+		index, err := strconv.ParseUint(indexStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Failed to parse X-Consul-Write-Index: %v", err)
+		}
+		w.WriteIndex = index
+	}
+	return nil
+}
+
 // decodeBody is used to JSON decode a body
 func decodeBody(resp *http.Response, out interface{}) error {
 	dec := json.NewDecoder(resp.Body)
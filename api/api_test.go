@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -394,6 +395,7 @@ func TestAPI_SetQueryOptions(t *testing.T) {
 		WaitTime:          100 * time.Second,
 		Token:             "12345",
 		Near:              "nodex",
+		ExternalSource:    "k8s",
 	}
 	r.setQueryOptions(q)
 
@@ -418,6 +420,9 @@ func TestAPI_SetQueryOptions(t *testing.T) {
 	if r.params.Get("near") != "nodex" {
 		t.Fatalf("bad: %v", r.params)
 	}
+	if r.params.Get("external-source") != "k8s" {
+		t.Fatalf("bad: %v", r.params)
+	}
 	assert.Equal("", r.header.Get("Cache-Control"))
 
 	r = c.newRequest("GET", "/v1/kv/foo")
@@ -440,8 +445,9 @@ func TestAPI_SetWriteOptions(t *testing.T) {
 
 	r := c.newRequest("GET", "/v1/kv/foo")
 	q := &WriteOptions{
-		Datacenter: "foo",
-		Token:      "23456",
+		Datacenter:     "foo",
+		Token:          "23456",
+		IdempotencyKey: "retry-1",
 	}
 	r.setWriteOptions(q)
 
@@ -451,6 +457,44 @@ func TestAPI_SetWriteOptions(t *testing.T) {
 	if r.header.Get("X-Consul-Token") != "23456" {
 		t.Fatalf("bad: %v", r.header)
 	}
+	if r.header.Get("Idempotency-Key") != "retry-1" {
+		t.Fatalf("bad: %v", r.header)
+	}
+}
+
+func TestAPI_TokenSource(t *testing.T) {
+	t.Parallel()
+
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("X-Consul-Token"))
+		w.Write([]byte("null"))
+	}))
+	defer srv.Close()
+
+	var sourceErr error
+	c, err := NewClient(&Config{
+		Address: srv.Listener.Addr().String(),
+		Token:   "static",
+		TokenSource: TokenSourceFunc(func() (string, error) {
+			return fmt.Sprintf("source-%d", len(tokens)), sourceErr
+		}),
+	})
+	require.NoError(t, err)
+
+	// The source is asked for every request and overrides the static token,
+	// tokens of the request override the source.
+	_, err = c.Catalog().Datacenters()
+	require.NoError(t, err)
+	_, err = c.Catalog().Datacenters()
+	require.NoError(t, err)
+	_, _, err = c.Catalog().Nodes(&QueryOptions{Token: "request"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"source-0", "source-1", "request"}, tokens)
+
+	sourceErr = fmt.Errorf("expired")
+	_, err = c.Catalog().Datacenters()
+	require.EqualError(t, err, "failed to get token: expired")
 }
 
 func TestAPI_RequestToHTTP(t *testing.T) {
@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 type Weights struct {
 	Passing int
 	Warning int
@@ -16,6 +18,19 @@ type Node struct {
 	ModifyIndex     uint64
 }
 
+// NodeActivity is the time of the last registration of a node. Servers only
+// track the registrations since they started, so for nodes which weren't
+// registered since then LastRegistered is the start time of the server.
+type NodeActivity struct {
+	Node           string
+	Address        string
+	Meta           map[string]string
+	LastRegistered time.Time
+	SerfMember     bool
+	CreateIndex    uint64
+	ModifyIndex    uint64
+}
+
 type CatalogService struct {
 	ID                       string
 	Node                     string
@@ -43,6 +58,23 @@ type CatalogNode struct {
 	Services map[string]*AgentService
 }
 
+// CatalogServiceEvent is an entry in the history of the instances of a
+// service. Type is one of "register", "update", "deregister",
+// "node-deregister" and "health".
+type CatalogServiceEvent struct {
+	Index          uint64
+	Time           time.Time
+	Type           string
+	Node           string
+	ServiceID      string
+	ServiceName    string
+	CheckID        string
+	Status         string
+	Output         string
+	PreviousStatus string
+	PreviousOutput string
+}
+
 type CatalogRegistration struct {
 	ID              string
 	Node            string
@@ -85,6 +117,9 @@ func (c *Catalog) Register(reg *CatalogRegistration, q *WriteOptions) (*WriteMet
 
 	wm := &WriteMeta{}
 	wm.RequestTime = rtt
+	if err := parseWriteMeta(resp, wm); err != nil {
+		return nil, err
+	}
 
 	return wm, nil
 }
@@ -101,6 +136,9 @@ func (c *Catalog) Deregister(dereg *CatalogDeregistration, q *WriteOptions) (*Wr
 
 	wm := &WriteMeta{}
 	wm.RequestTime = rtt
+	if err := parseWriteMeta(resp, wm); err != nil {
+		return nil, err
+	}
 
 	return wm, nil
 }
@@ -142,6 +180,50 @@ func (c *Catalog) Nodes(q *QueryOptions) ([]*Node, *QueryMeta, error) {
 	return out, qm, nil
 }
 
+// NodeActivity is used to query the times of the last registrations of the
+// nodes, in order to find nodes which aren't kept up to date anymore.
+func (c *Catalog) NodeActivity(q *QueryOptions) ([]*NodeActivity, *QueryMeta, error) {
+	r := c.c.newRequest("GET", "/v1/catalog/node-activity")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out []*NodeActivity
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
+
+// VirtualIPs is used to query for the virtual IPs of the services, by service
+// name
+func (c *Catalog) VirtualIPs(q *QueryOptions) (map[string]string, *QueryMeta, error) {
+	r := c.c.newRequest("GET", "/v1/catalog/virtual-ips")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out map[string]string
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
+
 // Services is used to query for all known services
 func (c *Catalog) Services(q *QueryOptions) (map[string][]string, *QueryMeta, error) {
 	r := c.c.newRequest("GET", "/v1/catalog/services")
@@ -240,3 +322,25 @@ func (c *Catalog) Node(node string, q *QueryOptions) (*CatalogNode, *QueryMeta,
 	}
 	return out, qm, nil
 }
+
+// ServiceHistory is used to query the recent registrations, deregistrations
+// and health changes of the instances of a service.
+func (c *Catalog) ServiceHistory(service string, q *QueryOptions) ([]*CatalogServiceEvent, *QueryMeta, error) {
+	r := c.c.newRequest("GET", "/v1/catalog/service/"+service+"/history")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out []*CatalogServiceEvent
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
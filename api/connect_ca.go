@@ -77,6 +77,11 @@ type CARoot struct {
 	// RootCertPEM is the PEM-encoded public certificate.
 	RootCertPEM string `json:"RootCert"`
 
+	// IntermediateCerts are the PEM-encoded intermediates which are needed
+	// to chain leaf certificates of this root to an older root, such as the
+	// cross-signed certificate created during a root rotation.
+	IntermediateCerts []string
+
 	// Active is true if this is the current active CA. This must only
 	// be true for exactly one CA. For any method that modifies roots in the
 	// state store, tests should be written to verify that multiple roots
@@ -113,6 +118,34 @@ type LeafCert struct {
 	ModifyIndex uint64
 }
 
+// CALeafList is the structure for the results of listing the leaf
+// certificates signed by the leader.
+type CALeafList struct {
+	ActiveRootID string
+	Leaves       []*CALeaf
+}
+
+// CALeaf describes the most recent leaf certificate signed for a service on
+// a node.
+type CALeaf struct {
+	// Node and Service identify the agent and the service the certificate
+	// was issued to.
+	Node    string
+	Service string
+
+	// RootID is the ID of the root which was active when the certificate was
+	// signed.
+	RootID string
+
+	// SerialNumber is the unique serial number for this certificate.
+	SerialNumber string
+
+	// ValidAfter and ValidBefore are the validity periods for the
+	// certificate.
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
 // CARoots queries the list of available roots.
 func (h *Connect) CARoots(q *QueryOptions) (*CARootList, *QueryMeta, error) {
 	r := h.c.newRequest("GET", "/v1/connect/ca/roots")
@@ -134,6 +167,29 @@ func (h *Connect) CARoots(q *QueryOptions) (*CARootList, *QueryMeta, error) {
 	return &out, qm, nil
 }
 
+// CALeaves queries the leaf certificates which the leader signed and which
+// are still valid. Only certificates signed since the current leader was
+// elected are returned.
+func (h *Connect) CALeaves(q *QueryOptions) (*CALeafList, *QueryMeta, error) {
+	r := h.c.newRequest("GET", "/v1/connect/ca/leaves")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(h.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out CALeafList
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
 // CAGetConfig returns the current CA configuration.
 func (h *Connect) CAGetConfig(q *QueryOptions) (*CAConfig, *QueryMeta, error) {
 	r := h.c.newRequest("GET", "/v1/connect/ca/configuration")
@@ -54,6 +54,34 @@ func TestAPI_ConnectCARoots_list(t *testing.T) {
 
 }
 
+func TestAPI_ConnectCALeaves(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+	reg := &AgentServiceRegistration{
+		Name: "foo",
+		Port: 8000,
+	}
+	require.NoError(agent.ServiceRegister(reg))
+
+	leaf, _, err := agent.ConnectCALeaf("foo", nil)
+	require.NoError(err)
+
+	connect := c.Connect()
+	list, _, err := connect.CALeaves(nil)
+	require.NoError(err)
+	require.NotEmpty(list.ActiveRootID)
+	require.Len(list.Leaves, 1)
+	require.Equal(s.Config.NodeName, list.Leaves[0].Node)
+	require.Equal("foo", list.Leaves[0].Service)
+	require.Equal(list.ActiveRootID, list.Leaves[0].RootID)
+	require.Equal(leaf.SerialNumber, list.Leaves[0].SerialNumber)
+}
+
 func TestAPI_ConnectCAConfig_get_set(t *testing.T) {
 	t.Parallel()
 
@@ -136,6 +136,33 @@ type IntentionCheck struct {
 	SourceType IntentionSourceType
 }
 
+// IntentionImpact lists the connections between the services of the mesh
+// whose authorization would change with a proposed intention change.
+type IntentionImpact struct {
+	// Allowed are the connections which are denied now and would be allowed.
+	Allowed []*IntentionConnection
+
+	// Denied are the connections which are allowed now and would be denied.
+	Denied []*IntentionConnection
+}
+
+// IntentionConnection is a connection from a source to a destination service.
+type IntentionConnection struct {
+	SourceName      string
+	DestinationName string
+}
+
+// IntentionReplicationStatus provides information about the health of the
+// replication of the intentions from the primary datacenter.
+type IntentionReplicationStatus struct {
+	Enabled          bool
+	Running          bool
+	SourceDatacenter string
+	ReplicatedIndex  uint64
+	LastSuccess      time.Time
+	LastError        time.Time
+}
+
 // Intentions returns the list of intentions.
 func (h *Connect) Intentions(q *QueryOptions) ([]*Intention, *QueryMeta, error) {
 	r := h.c.newRequest("GET", "/v1/connect/intentions")
@@ -300,3 +327,51 @@ func (c *Connect) IntentionUpdate(ixn *Intention, q *WriteOptions) (*WriteMeta,
 	wm.RequestTime = rtt
 	return wm, nil
 }
+
+// IntentionImpact returns the connections whose authorization would change
+// if the intention was created, or updated when it has an ID, or deleted if
+// del is set. Nothing is changed.
+func (c *Connect) IntentionImpact(ixn *Intention, del bool, q *WriteOptions) (*IntentionImpact, *WriteMeta, error) {
+	r := c.c.newRequest("PUT", "/v1/connect/intentions/impact")
+	r.setWriteOptions(q)
+	if del {
+		r.params.Set("delete", "")
+	}
+	r.obj = ixn
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{}
+	wm.RequestTime = rtt
+
+	var out IntentionImpact
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// IntentionReplication returns the status of the replication of the
+// intentions from the primary datacenter.
+func (c *Connect) IntentionReplication(q *QueryOptions) (*IntentionReplicationStatus, *QueryMeta, error) {
+	r := c.c.newRequest("GET", "/v1/connect/intentions/replication")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(c.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out IntentionReplicationStatus
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
@@ -185,3 +185,19 @@ func testIntention() *Intention {
 		Meta:            map[string]string{},
 	}
 }
+
+func TestAPI_ConnectIntentionReplication(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	connect := c.Connect()
+
+	// The intentions of the primary datacenter aren't replicated.
+	status, _, err := connect.IntentionReplication(nil)
+	require.NoError(err)
+	require.False(status.Enabled)
+	require.False(status.Running)
+}
@@ -0,0 +1,244 @@
+// Package grpcresolver provides a gRPC name resolver which resolves the
+// names of Consul services to the addresses of their instances. The
+// resolver watches the health endpoint of the service with blocking queries,
+// so gRPC clients are updated as soon as instances come and go.
+//
+// The resolver is registered for the "consul" scheme:
+//
+//	grpcresolver.Register(api.DefaultConfig())
+//	conn, err := grpc.Dial("consul:///web?tag=v2",
+//		grpc.WithBalancerName(roundrobin.Name), grpc.WithInsecure())
+//
+// The target names the service after the optional address of the agent to
+// query, which defaults to the address of the configuration. The following
+// query parameters are supported:
+//
+//	dc              - the datacenter of the service
+//	tag             - a tag the instances must have, can be given multiple times
+//	passing         - whether only the passing instances are used, defaults to true
+//	near            - a node name to sort the instances by round trip time
+//	external-source - the external source which registered the instances
+//
+// Without a load balancing policy gRPC uses the first address only, so the
+// round robin balancer of grpc-go should be used to spread the requests over
+// all the instances.
+package grpcresolver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme is the scheme of the targets resolved by the resolver.
+const Scheme = "consul"
+
+const (
+	// retryInterval is the base retry value after a failed query.
+	retryInterval = time.Second
+
+	// maxBackoffTime is the maximum time between the retries of failed
+	// queries.
+	maxBackoffTime = time.Minute
+)
+
+// Builder builds the resolvers of the targets with the consul scheme.
+type Builder struct {
+	config api.Config
+}
+
+// NewBuilder returns a builder whose resolvers query Consul with the given
+// configuration, or with the default configuration if it is nil.
+func NewBuilder(config *api.Config) *Builder {
+	if config == nil {
+		config = api.DefaultConfig()
+	}
+	return &Builder{config: *config}
+}
+
+// Register registers a builder with the given configuration for the consul
+// scheme. It should only be called during initialization since the gRPC
+// resolver registry is not thread-safe.
+func Register(config *api.Config) {
+	resolver.Register(NewBuilder(config))
+}
+
+// Scheme returns the scheme of the targets resolved by the resolvers.
+func (b *Builder) Scheme() string {
+	return Scheme
+}
+
+// Build returns a resolver which watches the instances of the service of the
+// target and sends their addresses to the client connection.
+func (b *Builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOption) (resolver.Resolver, error) {
+	q, err := parseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	config := b.config
+	if target.Authority != "" {
+		config.Address = target.Authority
+	}
+	client, err := api.NewClient(&config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the Consul client: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &consulResolver{
+		client: client,
+		cc:     cc,
+		query:  q,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	r.wg.Add(1)
+	go r.watch()
+	return r, nil
+}
+
+// query is the health query of a target.
+type query struct {
+	service     string
+	tags        []string
+	passingOnly bool
+	opts        api.QueryOptions
+}
+
+// parseTarget returns the health query of the endpoint of a target.
+func parseTarget(target resolver.Target) (*query, error) {
+	u, err := url.Parse(target.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid target %q: %v", target.Endpoint, err)
+	}
+	if u.Path == "" {
+		return nil, fmt.Errorf("Invalid target %q: missing service name", target.Endpoint)
+	}
+
+	q := &query{
+		service:     u.Path,
+		passingOnly: true,
+	}
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "dc":
+			q.opts.Datacenter = value
+		case "tag":
+			q.tags = values
+		case "passing":
+			passing, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value %q for passing: %v", value, err)
+			}
+			q.passingOnly = passing
+		case "near":
+			q.opts.Near = value
+		case "external-source":
+			q.opts.ExternalSource = value
+		default:
+			return nil, fmt.Errorf("Invalid target %q: unknown parameter %q", target.Endpoint, key)
+		}
+	}
+	return q, nil
+}
+
+// consulResolver watches the instances of a service.
+type consulResolver struct {
+	client *api.Client
+	cc     resolver.ClientConn
+	query  *query
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ResolveNow is a no-op since the instances are watched with blocking
+// queries, which return as soon as they change.
+func (r *consulResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+// Close stops watching the instances.
+func (r *consulResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// watch runs the blocking queries until the resolver is closed and sends the
+// addresses to the client connection whenever they change. Failed queries
+// are retried with an exponential backoff while the last addresses are kept.
+func (r *consulResolver) watch() {
+	defer r.wg.Done()
+
+	var index uint64
+	var last []resolver.Address
+	sent := false
+	failures := 0
+	for {
+		opts := r.query.opts
+		opts.WaitIndex = index
+		entries, meta, err := r.client.Health().ServiceMultipleTags(r.query.service,
+			r.query.tags, r.query.passingOnly, opts.WithContext(r.ctx))
+		if r.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			failures++
+			retry := retryInterval * time.Duration(failures*failures)
+			if retry > maxBackoffTime {
+				retry = maxBackoffTime
+			}
+			select {
+			case <-time.After(retry):
+				continue
+			case <-r.ctx.Done():
+				return
+			}
+		}
+		failures = 0
+
+		// Start over if the index went backwards, e.g. after a snapshot
+		// restore, instead of blocking until it catches up.
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+
+		addrs := serviceAddresses(entries)
+		if sent && reflect.DeepEqual(addrs, last) {
+			continue
+		}
+		r.cc.NewAddress(addrs)
+		last, sent = addrs, true
+	}
+}
+
+// serviceAddresses returns the sorted addresses of the service instances.
+// The address of the node is used for the instances without an address.
+func serviceAddresses(entries []*api.ServiceEntry) []resolver.Address {
+	addrs := make([]resolver.Address, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		addrs = append(addrs, resolver.Address{
+			Addr: net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)),
+		})
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].Addr < addrs[j].Addr
+	})
+	return addrs
+}
@@ -0,0 +1,214 @@
+package grpcresolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+)
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		endpoint string
+		expected *query
+		err      string
+	}{
+		{
+			endpoint: "web",
+			expected: &query{service: "web", passingOnly: true},
+		},
+		{
+			endpoint: "web?dc=dc2&tag=a&tag=b&passing=false&near=_agent&external-source=k8s",
+			expected: &query{
+				service: "web",
+				tags:    []string{"a", "b"},
+				opts: api.QueryOptions{
+					Datacenter:     "dc2",
+					Near:           "_agent",
+					ExternalSource: "k8s",
+				},
+			},
+		},
+		{
+			endpoint: "?dc=dc2",
+			err:      "missing service name",
+		},
+		{
+			endpoint: "web?passing=maybe",
+			err:      "Invalid value \"maybe\" for passing",
+		},
+		{
+			endpoint: "web?foo=bar",
+			err:      "unknown parameter \"foo\"",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			q, err := parseTarget(resolver.Target{Scheme: Scheme, Endpoint: tc.endpoint})
+			if tc.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func TestServiceAddresses(t *testing.T) {
+	t.Parallel()
+	entries := []*api.ServiceEntry{
+		{
+			Node:    &api.Node{Address: "10.0.0.2"},
+			Service: &api.AgentService{Port: 8080},
+		},
+		{
+			Node:    &api.Node{Address: "10.0.0.3"},
+			Service: &api.AgentService{Address: "10.0.1.1", Port: 9090},
+		},
+		{
+			Node:    &api.Node{Address: "::1"},
+			Service: &api.AgentService{Port: 8080},
+		},
+	}
+	require.Equal(t, []resolver.Address{
+		{Addr: "10.0.0.2:8080"},
+		{Addr: "10.0.1.1:9090"},
+		{Addr: "[::1]:8080"},
+	}, serviceAddresses(entries))
+	require.Equal(t, []resolver.Address{}, serviceAddresses(nil))
+}
+
+// testClientConn records the addresses sent by a resolver.
+type testClientConn struct {
+	resolver.ClientConn
+	addrsCh chan []resolver.Address
+}
+
+func (cc *testClientConn) NewAddress(addrs []resolver.Address) {
+	cc.addrsCh <- addrs
+}
+
+// testHealthServer serves the health endpoint of the "web" service. Queries
+// with the current index block until the instances change or a short
+// timeout.
+type testHealthServer struct {
+	lock    sync.Mutex
+	index   uint64
+	entries []*api.ServiceEntry
+	queries []string
+	changed chan struct{}
+}
+
+func (s *testHealthServer) set(entries ...*api.ServiceEntry) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.index++
+	s.entries = entries
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *testHealthServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v1/health/service/web" {
+		resp.WriteHeader(http.StatusNotFound)
+		return
+	}
+	index, _ := strconv.ParseUint(req.URL.Query().Get("index"), 10, 64)
+
+	s.lock.Lock()
+	s.queries = append(s.queries, req.URL.RawQuery)
+	changed := s.changed
+	current := s.index
+	s.lock.Unlock()
+	if index == current {
+		select {
+		case <-changed:
+		case <-time.After(50 * time.Millisecond):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	resp.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+	json.NewEncoder(resp).Encode(s.entries)
+}
+
+func testEntry(addr string, port int) *api.ServiceEntry {
+	return &api.ServiceEntry{
+		Node:    &api.Node{Address: addr},
+		Service: &api.AgentService{Service: "web", Port: port},
+	}
+}
+
+func TestResolver_Watch(t *testing.T) {
+	t.Parallel()
+	hs := &testHealthServer{index: 1, changed: make(chan struct{})}
+	hs.entries = []*api.ServiceEntry{testEntry("10.0.0.1", 8080)}
+	srv := httptest.NewServer(hs)
+	defer srv.Close()
+
+	// The authority of the target overrides the address of the config.
+	b := NewBuilder(&api.Config{Address: "127.0.0.1:1"})
+	cc := &testClientConn{addrsCh: make(chan []resolver.Address, 10)}
+	target := resolver.Target{
+		Scheme:    Scheme,
+		Authority: strings.TrimPrefix(srv.URL, "http://"),
+		Endpoint:  "web?tag=v2",
+	}
+	r, err := b.Build(target, cc, resolver.BuildOption{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	recv := func() []resolver.Address {
+		select {
+		case addrs := <-cc.addrsCh:
+			return addrs
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for addresses")
+		}
+		return nil
+	}
+	require.Equal(t, []resolver.Address{{Addr: "10.0.0.1:8080"}}, recv())
+
+	hs.set(testEntry("10.0.0.2", 8080), testEntry("10.0.0.1", 8080))
+	require.Equal(t, []resolver.Address{{Addr: "10.0.0.1:8080"}, {Addr: "10.0.0.2:8080"}}, recv())
+
+	hs.set()
+	require.Equal(t, []resolver.Address{}, recv())
+
+	// Unchanged addresses are not sent again.
+	hs.set()
+	select {
+	case addrs := <-cc.addrsCh:
+		t.Fatalf("unexpected addresses: %v", addrs)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	r.Close()
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	require.Contains(t, hs.queries[0], "tag=v2")
+	require.Contains(t, hs.queries[0], "passing=1")
+}
+
+func TestBuilder_InvalidTarget(t *testing.T) {
+	t.Parallel()
+	b := NewBuilder(nil)
+	require.Equal(t, Scheme, b.Scheme())
+	cc := &testClientConn{addrsCh: make(chan []resolver.Address, 1)}
+	_, err := b.Build(resolver.Target{Scheme: Scheme, Endpoint: "web?foo=bar"}, cc, resolver.BuildOption{})
+	require.Error(t, err)
+}
@@ -35,6 +35,11 @@ type HealthCheck struct {
 	ServiceName string
 	ServiceTags []string
 
+	// PreviousStatus and PreviousOutput are the status and output of the
+	// check before its status last changed.
+	PreviousStatus string
+	PreviousOutput string
+
 	Definition HealthCheckDefinition
 }
 
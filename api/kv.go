@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // KVPair is used to represent a single K/V entry
@@ -247,6 +248,9 @@ func (k *KV) put(key string, params map[string]string, body []byte, q *WriteOpti
 
 	qm := &WriteMeta{}
 	qm.RequestTime = rtt
+	if err := parseWriteMeta(resp, qm); err != nil {
+		return false, nil, err
+	}
 
 	var buf bytes.Buffer
 	if _, err := io.Copy(&buf, resp.Body); err != nil {
@@ -291,6 +295,9 @@ func (k *KV) deleteInternal(key string, params map[string]string, q *WriteOption
 
 	qm := &WriteMeta{}
 	qm.RequestTime = rtt
+	if err := parseWriteMeta(resp, qm); err != nil {
+		return false, nil, err
+	}
 
 	var buf bytes.Buffer
 	if _, err := io.Copy(&buf, resp.Body); err != nil {
@@ -300,6 +307,95 @@ func (k *KV) deleteInternal(key string, params map[string]string, q *WriteOption
 	return res, qm, nil
 }
 
+// KVRecycledPair is a K/V entry which was deleted while the recycle bin was
+// enabled, and which can be restored until it is purged.
+type KVRecycledPair struct {
+	// Key is the name of the deleted key.
+	Key string
+
+	// Flags are the user-defined flags the key had when it was deleted.
+	Flags uint64
+
+	// Value is the value the key had when it was deleted.
+	Value []byte
+
+	// DeletedAt is the time the key was deleted. The entry is purged once
+	// it is older than the recycle bin retention of the servers.
+	DeletedAt time.Time
+
+	// DeleteIndex holds the index corresponding to the deletion of the key.
+	DeleteIndex uint64
+}
+
+// Recycled is used to list the entries of the recycle bin with a given
+// prefix.
+func (k *KV) Recycled(prefix string, q *QueryOptions) ([]*KVRecycledPair, *QueryMeta, error) {
+	r := k.c.newRequest("GET", "/v1/kv-restore/"+strings.TrimPrefix(prefix, "/"))
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(k.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*KVRecycledPair
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
+// Restore is used to restore a single key from the recycle bin. It returns
+// the number of restored keys, which is zero if the key doesn't exist in the
+// recycle bin or was created again since it was deleted.
+func (k *KV) Restore(key string, w *WriteOptions) (int, *WriteMeta, error) {
+	return k.recycleBinInternal("PUT", key, nil, w)
+}
+
+// RestoreTree is used to restore all the keys under a prefix from the
+// recycle bin. Keys which were created again since they were deleted are
+// not restored.
+func (k *KV) RestoreTree(prefix string, w *WriteOptions) (int, *WriteMeta, error) {
+	return k.recycleBinInternal("PUT", prefix, map[string]string{"recurse": ""}, w)
+}
+
+// Purge is used to remove a single key from the recycle bin.
+func (k *KV) Purge(key string, w *WriteOptions) (int, *WriteMeta, error) {
+	return k.recycleBinInternal("DELETE", key, nil, w)
+}
+
+// PurgeTree is used to remove all the keys under a prefix from the recycle
+// bin.
+func (k *KV) PurgeTree(prefix string, w *WriteOptions) (int, *WriteMeta, error) {
+	return k.recycleBinInternal("DELETE", prefix, map[string]string{"recurse": ""}, w)
+}
+
+func (k *KV) recycleBinInternal(method, key string, params map[string]string, q *WriteOptions) (int, *WriteMeta, error) {
+	r := k.c.newRequest(method, "/v1/kv-restore/"+strings.TrimPrefix(key, "/"))
+	r.setWriteOptions(q)
+	for param, val := range params {
+		r.params.Set(param, val)
+	}
+	rtt, resp, err := requireOK(k.c.doRequest(r))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	wm := &WriteMeta{}
+	wm.RequestTime = rtt
+
+	var n int
+	if err := decodeBody(resp, &n); err != nil {
+		return 0, nil, err
+	}
+	return n, wm, nil
+}
+
 // TxnOp is the internal format we send to Consul. It's not specific to KV,
 // though currently only KV operations are supported.
 type TxnOp struct {
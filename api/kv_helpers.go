@@ -0,0 +1,181 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const (
+	// DefaultKVWatchRetryTime is how long WatchPrefix waits after a
+	// retryable error before querying the prefix again.
+	DefaultKVWatchRetryTime = 2 * time.Second
+
+	// DefaultKVModifyAttempts is how many times Modify and ModifyJSON try
+	// to write a key before giving up because of concurrent updates.
+	DefaultKVModifyAttempts = 10
+)
+
+var (
+	// ErrKVModifyConflict is returned by Modify and ModifyJSON when the key
+	// kept being updated concurrently by someone else.
+	ErrKVModifyConflict = fmt.Errorf("Key was modified concurrently too many times")
+)
+
+// KVWatchHandler is called by WatchPrefix with the index and the entries of
+// the prefix every time the prefix changes. Returning an error stops the
+// watch.
+type KVWatchHandler func(index uint64, pairs KVPairs) error
+
+// GetJSON is used to lookup a single key and decode its JSON value into v.
+// The returned pair is nil and v is left untouched if the key does not
+// exist. The ModifyIndex of the pair can be passed to CASJSON.
+func (k *KV) GetJSON(key string, v interface{}, q *QueryOptions) (*KVPair, *QueryMeta, error) {
+	pair, qm, err := k.Get(key, q)
+	if err != nil || pair == nil {
+		return nil, qm, err
+	}
+	if err := json.Unmarshal(pair.Value, v); err != nil {
+		return nil, nil, fmt.Errorf("Failed to decode the value of %q: %v", key, err)
+	}
+	return pair, qm, nil
+}
+
+// PutJSON is used to write the JSON encoding of v to a key.
+func (k *KV) PutJSON(key string, v interface{}, q *WriteOptions) (*WriteMeta, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode the value of %q: %v", key, err)
+	}
+	return k.Put(&KVPair{Key: key, Value: value}, q)
+}
+
+// CASJSON is used for a Check-And-Set operation writing the JSON encoding
+// of v to a key. An index of 0 only writes the key if it does not exist.
+// Returns true on success or false on failures.
+func (k *KV) CASJSON(key string, v interface{}, index uint64, q *WriteOptions) (bool, *WriteMeta, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return false, nil, fmt.Errorf("Failed to encode the value of %q: %v", key, err)
+	}
+	return k.CAS(&KVPair{Key: key, Value: value, ModifyIndex: index}, q)
+}
+
+// Modify is used for an atomic read-modify-write of a single key. The
+// function is given the current entry, or nil if the key does not exist,
+// and returns the new value. The write is a Check-And-Set, so the function
+// is called again with the new entry if the key was modified in the
+// meantime, up to DefaultKVModifyAttempts times. An error returned by the
+// function aborts the operation and is returned as is.
+func (k *KV) Modify(key string, fn func(pair *KVPair) ([]byte, error), q *WriteOptions) (*WriteMeta, error) {
+	qo := kvReadOptions(q)
+	for i := 0; i < DefaultKVModifyAttempts; i++ {
+		pair, _, err := k.Get(key, qo)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := fn(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		update := &KVPair{Key: key, Value: value}
+		if pair != nil {
+			update.Flags = pair.Flags
+			update.ModifyIndex = pair.ModifyIndex
+		}
+		ok, wm, err := k.CAS(update, q)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return wm, nil
+		}
+	}
+	return nil, ErrKVModifyConflict
+}
+
+// ModifyJSON is like Modify for keys with JSON values. Before every call of
+// the function v is reset to its zero value and the current value of the
+// key, if any, is decoded into it. The function updates v, which is then
+// encoded and written back. The function is told whether the key exists.
+func (k *KV) ModifyJSON(key string, v interface{}, fn func(exists bool) error, q *WriteOptions) (*WriteMeta, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, fmt.Errorf("ModifyJSON requires a non-nil pointer, got %T", v)
+	}
+
+	return k.Modify(key, func(pair *KVPair) ([]byte, error) {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+		if pair != nil {
+			if err := json.Unmarshal(pair.Value, v); err != nil {
+				return nil, fmt.Errorf("Failed to decode the value of %q: %v", key, err)
+			}
+		}
+		if err := fn(pair != nil); err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode the value of %q: %v", key, err)
+		}
+		return value, nil
+	}, q)
+}
+
+// WatchPrefix is used to watch all the keys under a prefix with blocking
+// queries. The handler is called with the current entries right away and
+// then every time the prefix changes. Retryable errors are ridden out by
+// querying again after DefaultKVWatchRetryTime. The watch runs until the
+// handler returns an error, a non-retryable error occurs or the context of
+// the query options is done, and returns that error.
+func (k *KV) WatchPrefix(prefix string, q *QueryOptions, handler KVWatchHandler) error {
+	opts := &QueryOptions{}
+	if q != nil {
+		*opts = *q
+	}
+	ctx := opts.Context()
+
+	var index uint64
+	for {
+		opts.WaitIndex = index
+		pairs, qm, err := k.List(prefix, opts)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if !IsRetryableError(err) {
+				return err
+			}
+			select {
+			case <-time.After(DefaultKVWatchRetryTime):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		// The index stays the same when the query timed out without any
+		// change. If it goes backwards, e.g. after a snapshot restore, we
+		// start over with the new index.
+		if index != 0 && qm.LastIndex == index {
+			continue
+		}
+		index = qm.LastIndex
+		if err := handler(index, pairs); err != nil {
+			return err
+		}
+	}
+}
+
+// kvReadOptions returns the query options for the reads which are part of a
+// write operation.
+func kvReadOptions(q *WriteOptions) *QueryOptions {
+	if q == nil {
+		return nil
+	}
+	qo := &QueryOptions{Datacenter: q.Datacenter, Token: q.Token}
+	return qo.WithContext(q.Context())
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type kvTestConfig struct {
+	Name    string
+	Count   int
+	Enabled bool `json:",omitempty"`
+}
+
+func TestAPI_KVGetPutJSON(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+	key := testKey()
+
+	var out kvTestConfig
+	pair, _, err := kv.GetJSON(key, &out, nil)
+	require.NoError(t, err)
+	require.Nil(t, pair)
+
+	_, err = kv.PutJSON(key, &kvTestConfig{Name: "web", Count: 1}, nil)
+	require.NoError(t, err)
+
+	pair, _, err = kv.GetJSON(key, &out, nil)
+	require.NoError(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, kvTestConfig{Name: "web", Count: 1}, out)
+
+	// CAS with a stale index fails, with the current one it works.
+	ok, _, err := kv.CASJSON(key, &kvTestConfig{Name: "web", Count: 2}, pair.ModifyIndex-1, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, _, err = kv.CASJSON(key, &kvTestConfig{Name: "web", Count: 2}, pair.ModifyIndex, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Values which aren't JSON are reported.
+	_, err = kv.Put(&KVPair{Key: key, Value: []byte("nope")}, nil)
+	require.NoError(t, err)
+	_, _, err = kv.GetJSON(key, &out, nil)
+	require.Error(t, err)
+}
+
+func TestAPI_KVModify(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+	key := testKey()
+
+	// Concurrent increments must not get lost.
+	errCh := make(chan error, 5)
+	for i := 0; i < cap(errCh); i++ {
+		go func() {
+			_, err := kv.Modify(key, func(pair *KVPair) ([]byte, error) {
+				n := 0
+				if pair != nil {
+					fmt.Sscanf(string(pair.Value), "%d", &n)
+				}
+				return []byte(fmt.Sprintf("%d", n+1)), nil
+			}, nil)
+			errCh <- err
+		}()
+	}
+	for i := 0; i < cap(errCh); i++ {
+		require.NoError(t, <-errCh)
+	}
+
+	pair, _, err := kv.Get(key, nil)
+	require.NoError(t, err)
+	require.Equal(t, "5", string(pair.Value))
+
+	// Errors of the function abort the write.
+	_, err = kv.Modify(key, func(pair *KVPair) ([]byte, error) {
+		return nil, fmt.Errorf("nope")
+	}, nil)
+	require.EqualError(t, err, "nope")
+	pair, _, err = kv.Get(key, nil)
+	require.NoError(t, err)
+	require.Equal(t, "5", string(pair.Value))
+}
+
+func TestAPI_KVModifyJSON(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+	key := testKey()
+
+	var conf kvTestConfig
+	_, err := kv.ModifyJSON(key, &conf, func(exists bool) error {
+		require.False(t, exists)
+		conf.Name = "web"
+		conf.Enabled = true
+		return nil
+	}, nil)
+	require.NoError(t, err)
+
+	// Fields which aren't stored are reset before decoding.
+	_, err = kv.PutJSON(key, &kvTestConfig{Name: "web", Count: 3}, nil)
+	require.NoError(t, err)
+	_, err = kv.ModifyJSON(key, &conf, func(exists bool) error {
+		require.True(t, exists)
+		require.False(t, conf.Enabled)
+		conf.Count++
+		return nil
+	}, nil)
+	require.NoError(t, err)
+
+	var out kvTestConfig
+	_, _, err = kv.GetJSON(key, &out, nil)
+	require.NoError(t, err)
+	require.Equal(t, kvTestConfig{Name: "web", Count: 4}, out)
+
+	_, err = kv.ModifyJSON(key, conf, func(bool) error { return nil }, nil)
+	require.Error(t, err)
+}
+
+func TestAPI_KVWatchPrefix(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+	prefix := testKey()
+
+	_, err := kv.Put(&KVPair{Key: prefix + "/a", Value: []byte("1")}, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updateCh := make(chan KVPairs, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- kv.WatchPrefix(prefix+"/", (&QueryOptions{WaitTime: time.Second}).WithContext(ctx),
+			func(index uint64, pairs KVPairs) error {
+				updateCh <- pairs
+				return nil
+			})
+	}()
+
+	select {
+	case pairs := <-updateCh:
+		require.Len(t, pairs, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no initial update")
+	}
+
+	_, err = kv.Put(&KVPair{Key: prefix + "/b", Value: []byte("2")}, nil)
+	require.NoError(t, err)
+
+	select {
+	case pairs := <-updateCh:
+		require.Len(t, pairs, 2)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no update after put")
+	}
+
+	// Writes outside of the prefix don't trigger the handler, even if the
+	// blocking queries time out in the meantime.
+	_, err = kv.Put(&KVPair{Key: testKey(), Value: []byte("3")}, nil)
+	require.NoError(t, err)
+	select {
+	case pairs := <-updateCh:
+		t.Fatalf("unexpected update: %v", pairs)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		require.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch didn't stop")
+	}
+}
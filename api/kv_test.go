@@ -8,6 +8,32 @@ import (
 	"time"
 )
 
+func TestAPI_ClientPut_WriteIndex(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	kv := c.KV()
+
+	key := testKey()
+	wm, err := kv.Put(&KVPair{Key: key, Value: []byte("test")}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if wm.WriteIndex == 0 {
+		t.Fatalf("unexpected value: %#v", wm)
+	}
+
+	// A stale read which requires the write index sees the write
+	pair, _, err := kv.Get(key, &QueryOptions{AllowStale: true, MinAppliedIndex: wm.WriteIndex})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair == nil || pair.ModifyIndex > wm.WriteIndex {
+		t.Fatalf("unexpected value: %#v", pair)
+	}
+}
+
 func TestAPI_ClientPutGetDelete(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -201,14 +202,24 @@ func (op *Operator) AutopilotCASConfiguration(conf *AutopilotConfiguration, q *W
 	return res, nil
 }
 
-// AutopilotServerHealth
+// AutopilotServerHealth returns the health of the servers. The health is
+// returned along with a nil error if the cluster is unhealthy, in which case
+// the HTTP API responds with status 429.
 func (op *Operator) AutopilotServerHealth(q *QueryOptions) (*OperatorHealthReply, error) {
 	r := op.c.newRequest("GET", "/v1/operator/autopilot/health")
 	r.setQueryOptions(q)
-	_, resp, err := requireOK(op.c.doRequest(r))
+	_, resp, err := op.c.doRequest(r)
 	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		if _, resp, err = requireOK(0, resp, nil); err != nil {
+			return nil, err
+		}
+	}
 	defer resp.Body.Close()
 
 	var out OperatorHealthReply
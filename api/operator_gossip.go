@@ -0,0 +1,79 @@
+package api
+
+// GossipPool holds the gossip settings of one of the gossip pools of an
+// agent together with estimates of how the pool behaves with them.
+type GossipPool struct {
+	// Pool is either "lan" or "wan". Only servers are members of the WAN
+	// pool.
+	Pool string
+
+	// Profile is the name of the gossip profile the pool runs with.
+	// PendingProfile is the profile selected through the operator API
+	// which takes effect when the agent is restarted.
+	Profile        string
+	PendingProfile string
+
+	// Members is the number of members of the pool and HealthScore the
+	// health score of the agent in the pool, 0 means healthy.
+	Members     int
+	HealthScore int
+
+	GossipNodes    int
+	GossipInterval ReadableDuration
+	ProbeInterval  ReadableDuration
+	ProbeTimeout   ReadableDuration
+	SuspicionMult  int
+	RetransmitMult int
+
+	// RetransmitLimit is the number of times a message is retransmitted,
+	// ConvergenceTime the estimated time for a message to reach all
+	// members and SuspicionTimeout the time after which a suspected member
+	// is declared dead.
+	RetransmitLimit  int
+	ConvergenceTime  ReadableDuration
+	SuspicionTimeout ReadableDuration
+}
+
+// GossipProfileRequest is used to select the gossip profile of a pool.
+type GossipProfileRequest struct {
+	Pool    string
+	Profile string
+}
+
+// GossipPools is used to retrieve the gossip settings of the pools of the
+// agent.
+func (op *Operator) GossipPools(q *QueryOptions) ([]*GossipPool, error) {
+	r := op.c.newRequest("GET", "/v1/operator/gossip")
+	r.setQueryOptions(q)
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []*GossipPool
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GossipProfileSet is used to select the gossip profile of a pool of the
+// agent. The profile takes effect when the agent is restarted, an empty
+// profile returns to the configured one.
+func (op *Operator) GossipProfileSet(pool, profile string, q *WriteOptions) (*GossipPool, error) {
+	r := op.c.newRequest("PUT", "/v1/operator/gossip")
+	r.setWriteOptions(q)
+	r.obj = &GossipProfileRequest{Pool: pool, Profile: profile}
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out GossipPool
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
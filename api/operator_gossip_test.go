@@ -0,0 +1,27 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_OperatorGossip(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	operator := c.Operator()
+	pools, err := operator.GossipPools(nil)
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+	require.Equal(t, "lan", pools[0].Pool)
+	require.Equal(t, "wan", pools[1].Pool)
+
+	pool, err := operator.GossipProfileSet("wan", "large-cluster", nil)
+	require.NoError(t, err)
+	require.Equal(t, "large-cluster", pool.PendingProfile)
+
+	_, err = operator.GossipProfileSet("wan", "huge", nil)
+	require.Error(t, err)
+}
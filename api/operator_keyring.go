@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // keyringRequest is used for performing Keyring operations
 type keyringRequest struct {
 	Key string
@@ -19,8 +23,41 @@ type KeyringResponse struct {
 	// A map of the encryption keys to the number of nodes they're installed on
 	Keys map[string]int
 
+	// A map of the encryption keys to the number of nodes using them as
+	// their primary key
+	PrimaryKeys map[string]int
+
 	// The total number of nodes in this ring
 	NumNodes int
+
+	// The keys installed on every node, primary key first. Only returned by
+	// KeyringListDetailed.
+	NodeKeys map[string][]string
+
+	// Problems with the keyring, such as nodes which don't have the primary
+	// key installed. Only returned by KeyringListDetailed.
+	Alerts []string
+}
+
+// KeyringRotationStatus provides information about the automatic rotation
+// of the gossip encryption keys.
+type KeyringRotationStatus struct {
+	// Enabled is true if the keys are rotated automatically by the servers
+	// of the primary datacenter.
+	Enabled     bool
+	Interval    time.Duration
+	RetireAfter time.Duration
+
+	// Phase is "idle" between rotations, "distributing" while the new key
+	// is installed on all nodes and "promoted" while the previous primary
+	// key waits to be removed.
+	Phase        string
+	PhaseStarted time.Time
+	LastRotation time.Time
+	NextRotation time.Time
+
+	LastError     string
+	LastErrorTime time.Time
 }
 
 // KeyringInstall is used to install a new gossip encryption key into the cluster
@@ -55,6 +92,26 @@ func (op *Operator) KeyringList(q *QueryOptions) ([]*KeyringResponse, error) {
 	return out, nil
 }
 
+// KeyringListDetailed is used to list the gossip keys installed in the
+// cluster along with the keys installed on every node and alerts about the
+// state of the keyrings.
+func (op *Operator) KeyringListDetailed(q *QueryOptions) ([]*KeyringResponse, error) {
+	r := op.c.newRequest("GET", "/v1/operator/keyring")
+	r.setQueryOptions(q)
+	r.params.Set("detailed", "true")
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out []*KeyringResponse
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // KeyringRemove is used to remove a gossip encryption key from the cluster
 func (op *Operator) KeyringRemove(key string, q *WriteOptions) error {
 	r := op.c.newRequest("DELETE", "/v1/operator/keyring")
@@ -84,3 +141,21 @@ func (op *Operator) KeyringUse(key string, q *WriteOptions) error {
 	resp.Body.Close()
 	return nil
 }
+
+// KeyringRotationStatus is used to retrieve the status of the automatic
+// gossip key rotation.
+func (op *Operator) KeyringRotationStatus(q *QueryOptions) (*KeyringRotationStatus, error) {
+	r := op.c.newRequest("GET", "/v1/operator/keyring/rotation")
+	r.setQueryOptions(q)
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out KeyringRotationStatus
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -71,3 +71,56 @@ func TestAPI_OperatorKeyringInstallListPutRemove(t *testing.T) {
 		}
 	}
 }
+
+func TestAPI_OperatorKeyringListDetailed(t *testing.T) {
+	t.Parallel()
+	oldKey := "d8wu8CSUrqgtjVsvcBPmhQ=="
+	newKey := "qxycTi/SsePj/TZzCBmNXw=="
+	c, s := makeClientWithConfig(t, nil, func(c *testutil.TestServerConfig) {
+		c.Encrypt = oldKey
+	})
+	defer s.Stop()
+
+	operator := c.Operator()
+	if err := operator.KeyringInstall(newKey, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	listResponses, err := operator.KeyringListDetailed(nil)
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	if len(listResponses) != 2 {
+		t.Fatalf("bad: %v", len(listResponses))
+	}
+	for _, response := range listResponses {
+		if response.PrimaryKeys[oldKey] != 1 || len(response.PrimaryKeys) != 1 {
+			t.Fatalf("bad: %v", response.PrimaryKeys)
+		}
+		if len(response.NodeKeys) != 1 {
+			t.Fatalf("bad: %v", response.NodeKeys)
+		}
+		for _, keys := range response.NodeKeys {
+			if len(keys) != 2 || keys[0] != oldKey {
+				t.Fatalf("bad: %v", keys)
+			}
+		}
+		if len(response.Alerts) != 0 {
+			t.Fatalf("bad: %v", response.Alerts)
+		}
+	}
+}
+
+func TestAPI_OperatorKeyringRotationStatus(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	status, err := c.Operator().KeyringRotationStatus(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status.Enabled {
+		t.Fatalf("bad: %#v", status)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"time"
+)
+
+// KVReplicationStatus provides information about the replication of KV
+// prefixes from the primary datacenter.
+type KVReplicationStatus struct {
+	Enabled          bool
+	SourceDatacenter string
+
+	// ConflictPolicy is either "overwrite" or "keep-local" and decides
+	// whether local keys which don't exist in the primary datacenter are
+	// deleted.
+	ConflictPolicy string
+
+	Prefixes []*KVReplicationPrefixStatus
+}
+
+// KVReplicationPrefixStatus provides information about the replication of
+// a single KV prefix.
+type KVReplicationPrefixStatus struct {
+	Prefix          string
+	Running         bool
+	ReplicatedIndex uint64
+
+	// Keys is the number of replicated keys and Conflicts the number of
+	// local keys which don't exist in the primary datacenter and were
+	// kept.
+	Keys      int
+	Conflicts int
+
+	LastSuccess time.Time
+	LastError   time.Time
+
+	// Lag is the time since the prefix was last known to match the primary
+	// datacenter.
+	Lag time.Duration
+}
+
+// KVReplicationStatus is used to retrieve the status of the KV replication
+// of a datacenter.
+func (op *Operator) KVReplicationStatus(q *QueryOptions) (*KVReplicationStatus, error) {
+	r := op.c.newRequest("GET", "/v1/operator/kv-replication")
+	r.setQueryOptions(q)
+	_, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out KVReplicationStatus
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
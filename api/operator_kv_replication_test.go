@@ -0,0 +1,19 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_OperatorKVReplicationStatus(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	status, err := c.Operator().KVReplicationStatus(nil)
+	require.NoError(t, err)
+	require.False(t, status.Enabled)
+	require.Equal(t, "overwrite", status.ConflictPolicy)
+	require.Empty(t, status.Prefixes)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UIConfigDashboardDefault is the key of the dashboard URL template
+	// used for services without their own template.
+	UIConfigDashboardDefault = "*"
+
+	UIBannerInfo     = "info"
+	UIBannerWarning  = "warning"
+	UIBannerCritical = "critical"
+)
+
+// UIConfig holds the settings of the web UI. They are stored by the servers
+// and replicated from the primary datacenter.
+type UIConfig struct {
+	// DashboardURLTemplates maps service names to the URLs of the metrics
+	// dashboards the service views link to. The template with the "*" key
+	// is used for services without their own template. The placeholders
+	// {{Service}} and {{Datacenter}} are replaced with the name of the
+	// service and the datacenter.
+	DashboardURLTemplates map[string]string
+
+	// BannerMessage is shown at the top of every page of the UI if it is
+	// set. BannerLevel is one of "info", "warning" or "critical".
+	BannerMessage string
+	BannerLevel   string
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// UIConfigGet is used to retrieve the settings of the web UI.
+func (op *Operator) UIConfigGet(q *QueryOptions) (*UIConfig, *QueryMeta, error) {
+	r := op.c.newRequest("GET", "/v1/operator/ui-config")
+	r.setQueryOptions(q)
+	rtt, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var out UIConfig
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out, qm, nil
+}
+
+// UIConfigSet is used to set the settings of the web UI.
+func (op *Operator) UIConfigSet(conf *UIConfig, q *WriteOptions) (*WriteMeta, error) {
+	r := op.c.newRequest("PUT", "/v1/operator/ui-config")
+	r.setWriteOptions(q)
+	r.obj = conf
+	rtt, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	wm := &WriteMeta{RequestTime: rtt}
+	return wm, nil
+}
+
+// UIConfigCAS is used to perform a Check-And-Set update on the settings of
+// the web UI. The ModifyIndex value will be respected, an index of 0 only
+// sets the settings if they were never set. Returns true on success or
+// false on failures.
+func (op *Operator) UIConfigCAS(conf *UIConfig, q *WriteOptions) (bool, *WriteMeta, error) {
+	r := op.c.newRequest("PUT", "/v1/operator/ui-config")
+	r.setWriteOptions(q)
+	r.params.Set("cas", strconv.FormatUint(conf.ModifyIndex, 10))
+	r.obj = conf
+	rtt, resp, err := requireOK(op.c.doRequest(r))
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, resp.Body); err != nil {
+		return false, nil, fmt.Errorf("Failed to read response: %v", err)
+	}
+	res := strings.Contains(buf.String(), "true")
+
+	wm := &WriteMeta{RequestTime: rtt}
+	return res, wm, nil
+}
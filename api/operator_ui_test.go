@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_OperatorUIConfig(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	operator := c.Operator()
+	config, _, err := operator.UIConfigGet(nil)
+	require.NoError(t, err)
+	require.Empty(t, config.DashboardURLTemplates)
+
+	_, err = operator.UIConfigSet(&UIConfig{
+		DashboardURLTemplates: map[string]string{
+			UIConfigDashboardDefault: "https://grafana/d/{{Service}}",
+		},
+		BannerMessage: "hello",
+	}, nil)
+	require.NoError(t, err)
+
+	config, meta, err := operator.UIConfigGet(nil)
+	require.NoError(t, err)
+	require.Equal(t, "hello", config.BannerMessage)
+	require.Equal(t, UIBannerInfo, config.BannerLevel)
+	require.Equal(t, meta.LastIndex, config.ModifyIndex)
+
+	// A CAS with an old index fails.
+	config.BannerMessage = "world"
+	config.ModifyIndex--
+	ok, _, err := operator.UIConfigCAS(config, nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	config.ModifyIndex++
+	ok, _, err = operator.UIConfigCAS(config, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -29,6 +29,7 @@ type SessionEntry struct {
 	LockDelay   time.Duration
 	Behavior    string
 	TTL         string
+	Meta        map[string]string
 }
 
 // Session can be used to query the Session endpoints
@@ -62,6 +63,9 @@ func (s *Session) CreateNoChecks(se *SessionEntry, q *WriteOptions) (string, *Wr
 		if se.TTL != "" {
 			body["TTL"] = se.TTL
 		}
+		if len(se.Meta) > 0 {
+			body["Meta"] = se.Meta
+		}
 	}
 	return s.create(body, q)
 
@@ -92,6 +96,9 @@ func (s *Session) Create(se *SessionEntry, q *WriteOptions) (string, *WriteMeta,
 		if se.TTL != "" {
 			body["TTL"] = se.TTL
 		}
+		if len(se.Meta) > 0 {
+			body["Meta"] = se.Meta
+		}
 	}
 	return s.create(obj, q)
 }
@@ -213,6 +220,31 @@ func (s *Session) Node(node string, q *QueryOptions) ([]*SessionEntry, *QueryMet
 	return entries, qm, nil
 }
 
+// NodeWithMeta gets the active sessions of a node which have all of the
+// given metadata key/value pairs
+func (s *Session) NodeWithMeta(node string, meta map[string]string, q *QueryOptions) ([]*SessionEntry, *QueryMeta, error) {
+	r := s.c.newRequest("GET", "/v1/session/node/"+node)
+	r.setQueryOptions(q)
+	for key, value := range meta {
+		r.params.Add("meta", key+":"+value)
+	}
+	rtt, resp, err := requireOK(s.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*SessionEntry
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
 // List gets all active sessions
 func (s *Session) List(q *QueryOptions) ([]*SessionEntry, *QueryMeta, error) {
 	var entries []*SessionEntry
@@ -45,3 +45,36 @@ func (s *Snapshot) Restore(q *WriteOptions, in io.Reader) error {
 	}
 	return nil
 }
+
+// SnapshotSummary describes the contents of a snapshot checked by Verify.
+type SnapshotSummary struct {
+	ID      string
+	Size    int64
+	Index   uint64
+	Term    uint64
+	Version int
+
+	// Records is the number of records of each kind in the snapshot.
+	Records map[string]int
+}
+
+// Verify streams in an existing snapshot and checks that it could be
+// restored, without applying it. A summary of the snapshot's contents is
+// returned.
+func (s *Snapshot) Verify(q *WriteOptions, in io.Reader) (*SnapshotSummary, error) {
+	r := s.c.newRequest("PUT", "/v1/snapshot")
+	r.body = in
+	r.params.Set("verify", "")
+	r.setWriteOptions(q)
+	_, resp, err := requireOK(s.c.doRequest(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out SnapshotSummary
+	if err := decodeBody(resp, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
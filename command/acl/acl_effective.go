@@ -0,0 +1,299 @@
+package acl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/acl"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+)
+
+// EffectiveRule is one rule of the effective rules of a token, which are the
+// rules of all its policies and identities merged together.
+type EffectiveRule struct {
+	// Resource is the kind of the rule, such as "key_prefix" or "operator".
+	Resource string
+
+	// Segment is the name or prefix the rule applies to. It is empty for the
+	// resources without segments, such as "acl" or "operator".
+	Segment    string `json:",omitempty"`
+	Policy     string
+	Intentions string `json:",omitempty"`
+
+	// Sources are the policies and identities which have a rule for the same
+	// resource and segment, including the ones which were overridden.
+	Sources []*EffectiveRuleSource
+}
+
+// EffectiveRuleSource is the rule of a single policy or identity which was
+// merged into an effective rule.
+type EffectiveRuleSource struct {
+	Source     string
+	Policy     string
+	Intentions string `json:",omitempty"`
+}
+
+// EffectiveRules holds the effective rules of a token in the given datacenter.
+type EffectiveRules struct {
+	Datacenter string
+	Rules      []*EffectiveRule
+
+	// Ignored lists the sources which aren't valid in the datacenter.
+	Ignored []string `json:",omitempty"`
+}
+
+// ruleSource is a parsed policy or identity linked to a token.
+type ruleSource struct {
+	name   string
+	policy *acl.Policy
+}
+
+// GetEffectiveRules fetches the policies linked to the token and merges their
+// rules, and those of its identities and legacy rules, the way the servers do
+// in the given datacenter.
+func GetEffectiveRules(client *api.Client, token *api.ACLToken, datacenter string) (*EffectiveRules, error) {
+	result := &EffectiveRules{Datacenter: datacenter}
+
+	var sources []*ruleSource
+	add := func(name, rules string, syntax acl.SyntaxVersion) error {
+		policy, err := acl.NewPolicyFromSource("", 0, rules, syntax, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to parse the rules of %s: %v", name, err)
+		}
+		sources = append(sources, &ruleSource{name: name, policy: policy})
+		return nil
+	}
+
+	for _, link := range token.Policies {
+		policy, _, err := client.ACL().PolicyRead(link.ID, &api.QueryOptions{Datacenter: datacenter})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read policy %q: %v", link.ID, err)
+		}
+		name := fmt.Sprintf("policy %q", policy.Name)
+		if !validInDatacenter(policy.Datacenters, datacenter) {
+			result.Ignored = append(result.Ignored, name)
+			continue
+		}
+		if err := add(name, policy.Rules, acl.SyntaxCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ident := range token.NodeIdentities {
+		name := fmt.Sprintf("node identity %q (%s)", ident.NodeName, ident.Datacenter)
+		if ident.Datacenter != datacenter {
+			result.Ignored = append(result.Ignored, name)
+			continue
+		}
+		synthetic := (&structs.ACLNodeIdentity{
+			NodeName:   ident.NodeName,
+			Datacenter: ident.Datacenter,
+		}).SyntheticPolicy()
+		if err := add(name, synthetic.Rules, acl.SyntaxCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ident := range token.ServiceIdentities {
+		name := fmt.Sprintf("service identity %q", ident.ServiceName)
+		if len(ident.Datacenters) > 0 {
+			name += fmt.Sprintf(" (%s)", strings.Join(ident.Datacenters, ", "))
+		}
+		if !validInDatacenter(ident.Datacenters, datacenter) {
+			result.Ignored = append(result.Ignored, name)
+			continue
+		}
+		synthetic := (&structs.ACLServiceIdentity{
+			ServiceName: ident.ServiceName,
+			Datacenters: ident.Datacenters,
+		}).SyntheticPolicy()
+		if err := add(name, synthetic.Rules, acl.SyntaxCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	if token.Rules != "" {
+		if err := add("legacy rules", token.Rules, acl.SyntaxLegacy); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(sources) == 0 {
+		return result, nil
+	}
+
+	// The rules of the sources are flattened before merging, as merging
+	// modifies the service rules of the policies in place.
+	policies := make([]*acl.Policy, 0, len(sources))
+	flattened := make([][]*EffectiveRule, 0, len(sources))
+	for _, source := range sources {
+		policies = append(policies, source.policy)
+		flattened = append(flattened, flattenPolicy(source.policy))
+	}
+
+	// The rules are merged with the same precedence as the servers use, and
+	// every source with a rule for the same resource is recorded.
+	rulesByKey := make(map[string]*EffectiveRule)
+	for _, rule := range flattenPolicy(acl.MergePolicies(policies)) {
+		rulesByKey[rule.Resource+"\x00"+rule.Segment] = rule
+		result.Rules = append(result.Rules, rule)
+	}
+	for i, source := range sources {
+		for _, rule := range flattened[i] {
+			effective, ok := rulesByKey[rule.Resource+"\x00"+rule.Segment]
+			if !ok {
+				continue
+			}
+			effective.Sources = append(effective.Sources, &EffectiveRuleSource{
+				Source:     source.name,
+				Policy:     rule.Policy,
+				Intentions: rule.Intentions,
+			})
+		}
+	}
+
+	order := make(map[string]int, len(resourceOrder))
+	for i, resource := range resourceOrder {
+		order[resource] = i
+	}
+	sort.Slice(result.Rules, func(i, j int) bool {
+		a, b := result.Rules[i], result.Rules[j]
+		if a.Resource != b.Resource {
+			return order[a.Resource] < order[b.Resource]
+		}
+		return a.Segment < b.Segment
+	})
+	return result, nil
+}
+
+func validInDatacenter(datacenters []string, datacenter string) bool {
+	if len(datacenters) == 0 {
+		return true
+	}
+	for _, dc := range datacenters {
+		if dc == datacenter {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceOrder is the order in which the resources of the effective rules
+// are printed.
+var resourceOrder = []string{
+	"acl", "agent", "agent_prefix", "event", "event_prefix", "key", "key_prefix",
+	"keyring", "node", "node_prefix", "operator", "query", "query_prefix",
+	"service", "service_prefix", "session", "session_prefix",
+}
+
+// flattenPolicy returns the rules of a policy as a list.
+func flattenPolicy(p *acl.Policy) []*EffectiveRule {
+	var rules []*EffectiveRule
+	add := func(resource, segment, policy, intentions string) {
+		rules = append(rules, &EffectiveRule{
+			Resource:   resource,
+			Segment:    segment,
+			Policy:     policy,
+			Intentions: intentions,
+		})
+	}
+
+	if p.ACL != "" {
+		add("acl", "", p.ACL, "")
+	}
+	if p.Keyring != "" {
+		add("keyring", "", p.Keyring, "")
+	}
+	if p.Operator != "" {
+		add("operator", "", p.Operator, "")
+	}
+	for _, r := range p.Agents {
+		add("agent", r.Node, r.Policy, "")
+	}
+	for _, r := range p.AgentPrefixes {
+		add("agent_prefix", r.Node, r.Policy, "")
+	}
+	for _, r := range p.Events {
+		add("event", r.Event, r.Policy, "")
+	}
+	for _, r := range p.EventPrefixes {
+		add("event_prefix", r.Event, r.Policy, "")
+	}
+	for _, r := range p.Keys {
+		add("key", r.Prefix, r.Policy, "")
+	}
+	for _, r := range p.KeyPrefixes {
+		add("key_prefix", r.Prefix, r.Policy, "")
+	}
+	for _, r := range p.Nodes {
+		add("node", r.Name, r.Policy, "")
+	}
+	for _, r := range p.NodePrefixes {
+		add("node_prefix", r.Name, r.Policy, "")
+	}
+	for _, r := range p.PreparedQueries {
+		add("query", r.Prefix, r.Policy, "")
+	}
+	for _, r := range p.PreparedQueryPrefixes {
+		add("query_prefix", r.Prefix, r.Policy, "")
+	}
+	for _, r := range p.Services {
+		add("service", r.Name, r.Policy, r.Intentions)
+	}
+	for _, r := range p.ServicePrefixes {
+		add("service_prefix", r.Name, r.Policy, r.Intentions)
+	}
+	for _, r := range p.Sessions {
+		add("session", r.Node, r.Policy, "")
+	}
+	for _, r := range p.SessionPrefixes {
+		add("session_prefix", r.Node, r.Policy, "")
+	}
+	return rules
+}
+
+// PrintEffectiveRules prints the effective rules in the rule syntax, with a
+// comment above each rule naming its sources.
+func PrintEffectiveRules(rules *EffectiveRules, ui cli.Ui) {
+	ui.Info(fmt.Sprintf("Effective Rules (Datacenter: %s):", rules.Datacenter))
+	for _, rule := range rules.Rules {
+		var sources []string
+		for _, source := range rule.Sources {
+			s := fmt.Sprintf("%s = %s", source.Source, source.Policy)
+			if source.Intentions != "" {
+				s += fmt.Sprintf(" (intentions %s)", source.Intentions)
+			}
+			sources = append(sources, s)
+		}
+		ui.Info(fmt.Sprintf("   # from %s", strings.Join(sources, ", ")))
+
+		if isSegmentless(rule.Resource) {
+			ui.Info(fmt.Sprintf("   %s = %q", rule.Resource, rule.Policy))
+			continue
+		}
+		ui.Info(fmt.Sprintf("   %s %q {", rule.Resource, rule.Segment))
+		ui.Info(fmt.Sprintf("      policy = %q", rule.Policy))
+		if rule.Intentions != "" {
+			ui.Info(fmt.Sprintf("      intentions = %q", rule.Intentions))
+		}
+		ui.Info("   }")
+	}
+	if len(rules.Ignored) > 0 {
+		ui.Info(fmt.Sprintf("Not valid in this datacenter:"))
+		for _, name := range rules.Ignored {
+			ui.Info(fmt.Sprintf("   %s", name))
+		}
+	}
+}
+
+func isSegmentless(resource string) bool {
+	switch resource {
+	case "acl", "keyring", "operator":
+		return true
+	default:
+		return false
+	}
+}
@@ -14,6 +14,9 @@ func PrintToken(token *api.ACLToken, ui cli.Ui, showMeta bool) {
 	ui.Info(fmt.Sprintf("Description:  %s", token.Description))
 	ui.Info(fmt.Sprintf("Local:        %t", token.Local))
 	ui.Info(fmt.Sprintf("Create Time:  %v", token.CreateTime))
+	if token.ExpirationTime != nil && !token.ExpirationTime.IsZero() {
+		ui.Info(fmt.Sprintf("Expiration Time:  %v", *token.ExpirationTime))
+	}
 	if showMeta {
 		ui.Info(fmt.Sprintf("Hash:         %x", token.Hash))
 		ui.Info(fmt.Sprintf("Create Index: %d", token.CreateIndex))
@@ -23,6 +26,22 @@ func PrintToken(token *api.ACLToken, ui cli.Ui, showMeta bool) {
 	for _, policy := range token.Policies {
 		ui.Info(fmt.Sprintf("   %s - %s", policy.ID, policy.Name))
 	}
+	if len(token.NodeIdentities) > 0 {
+		ui.Info(fmt.Sprintf("Node Identities:"))
+		for _, nodeIdent := range token.NodeIdentities {
+			ui.Info(fmt.Sprintf("   %s (Datacenter: %s)", nodeIdent.NodeName, nodeIdent.Datacenter))
+		}
+	}
+	if len(token.ServiceIdentities) > 0 {
+		ui.Info(fmt.Sprintf("Service Identities:"))
+		for _, svcIdent := range token.ServiceIdentities {
+			if len(svcIdent.Datacenters) > 0 {
+				ui.Info(fmt.Sprintf("   %s (Datacenters: %s)", svcIdent.ServiceName, strings.Join(svcIdent.Datacenters, ", ")))
+			} else {
+				ui.Info(fmt.Sprintf("   %s (Datacenters: all)", svcIdent.ServiceName))
+			}
+		}
+	}
 	if token.Rules != "" {
 		ui.Info(fmt.Sprintf("Rules:"))
 		ui.Info(token.Rules)
@@ -34,6 +53,9 @@ func PrintTokenListEntry(token *api.ACLTokenListEntry, ui cli.Ui, showMeta bool)
 	ui.Info(fmt.Sprintf("Description:  %s", token.Description))
 	ui.Info(fmt.Sprintf("Local:        %t", token.Local))
 	ui.Info(fmt.Sprintf("Create Time:  %v", token.CreateTime))
+	if token.ExpirationTime != nil && !token.ExpirationTime.IsZero() {
+		ui.Info(fmt.Sprintf("Expiration Time:  %v", *token.ExpirationTime))
+	}
 	ui.Info(fmt.Sprintf("Legacy:       %t", token.Legacy))
 	if showMeta {
 		ui.Info(fmt.Sprintf("Hash:         %x", token.Hash))
@@ -44,6 +66,22 @@ func PrintTokenListEntry(token *api.ACLTokenListEntry, ui cli.Ui, showMeta bool)
 	for _, policy := range token.Policies {
 		ui.Info(fmt.Sprintf("   %s - %s", policy.ID, policy.Name))
 	}
+	if len(token.NodeIdentities) > 0 {
+		ui.Info(fmt.Sprintf("Node Identities:"))
+		for _, nodeIdent := range token.NodeIdentities {
+			ui.Info(fmt.Sprintf("   %s (Datacenter: %s)", nodeIdent.NodeName, nodeIdent.Datacenter))
+		}
+	}
+	if len(token.ServiceIdentities) > 0 {
+		ui.Info(fmt.Sprintf("Service Identities:"))
+		for _, svcIdent := range token.ServiceIdentities {
+			if len(svcIdent.Datacenters) > 0 {
+				ui.Info(fmt.Sprintf("   %s (Datacenters: %s)", svcIdent.ServiceName, strings.Join(svcIdent.Datacenters, ", ")))
+			} else {
+				ui.Info(fmt.Sprintf("   %s (Datacenters: all)", svcIdent.ServiceName))
+			}
+		}
+	}
 }
 
 func PrintPolicy(policy *api.ACLPolicy, ui cli.Ui, showMeta bool) {
@@ -72,6 +110,32 @@ func PrintPolicyListEntry(policy *api.ACLPolicyListEntry, ui cli.Ui, showMeta bo
 	}
 }
 
+func PrintBindingRule(rule *api.ACLBindingRule, ui cli.Ui, showMeta bool) {
+	ui.Info(fmt.Sprintf("ID:           %s", rule.ID))
+	ui.Info(fmt.Sprintf("AuthMethod:   %s", rule.AuthMethod))
+	ui.Info(fmt.Sprintf("Description:  %s", rule.Description))
+	ui.Info(fmt.Sprintf("BindType:     %s", rule.BindType))
+	ui.Info(fmt.Sprintf("BindName:     %s", rule.BindName))
+	ui.Info(fmt.Sprintf("Selector:     %s", rule.Selector))
+	if showMeta {
+		ui.Info(fmt.Sprintf("Create Index: %d", rule.CreateIndex))
+		ui.Info(fmt.Sprintf("Modify Index: %d", rule.ModifyIndex))
+	}
+}
+
+func PrintBindingRuleListEntry(rule *api.ACLBindingRule, ui cli.Ui, showMeta bool) {
+	ui.Info(fmt.Sprintf("%s:", rule.ID))
+	ui.Info(fmt.Sprintf("   AuthMethod:   %s", rule.AuthMethod))
+	ui.Info(fmt.Sprintf("   Description:  %s", rule.Description))
+	ui.Info(fmt.Sprintf("   BindType:     %s", rule.BindType))
+	ui.Info(fmt.Sprintf("   BindName:     %s", rule.BindName))
+	ui.Info(fmt.Sprintf("   Selector:     %s", rule.Selector))
+	if showMeta {
+		ui.Info(fmt.Sprintf("   Create Index: %d", rule.CreateIndex))
+		ui.Info(fmt.Sprintf("   Modify Index: %d", rule.ModifyIndex))
+	}
+}
+
 func GetTokenIDFromPartial(client *api.Client, partialID string) (string, error) {
 	// the full UUID string was given
 	if len(partialID) == 36 {
@@ -128,6 +192,34 @@ func GetPolicyIDFromPartial(client *api.Client, partialID string) (string, error
 	return policyID, nil
 }
 
+func GetBindingRuleIDFromPartial(client *api.Client, partialID string) (string, error) {
+	// The full UUID string was given
+	if len(partialID) == 36 {
+		return partialID, nil
+	}
+
+	rules, _, err := client.ACL().BindingRuleList("", nil)
+	if err != nil {
+		return "", err
+	}
+
+	ruleID := ""
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.ID, partialID) {
+			if ruleID != "" {
+				return "", fmt.Errorf("Partial binding rule ID is not unique")
+			}
+			ruleID = rule.ID
+		}
+	}
+
+	if ruleID == "" {
+		return "", fmt.Errorf("No such binding rule ID with prefix: %s", partialID)
+	}
+
+	return ruleID, nil
+}
+
 func GetPolicyIDByName(client *api.Client, name string) (string, error) {
 	if name == "" {
 		return "", fmt.Errorf("No name specified")
@@ -147,6 +239,46 @@ func GetPolicyIDByName(client *api.Client, name string) (string, error) {
 	return "", fmt.Errorf("No such policy with name %s", name)
 }
 
+// ExtractNodeIdentities parses the node identities given on the command line
+// in the form of "<node name>:<datacenter>".
+func ExtractNodeIdentities(nodeIdents []string) ([]*api.ACLNodeIdentity, error) {
+	var out []*api.ACLNodeIdentity
+	for _, nodeIdent := range nodeIdents {
+		parts := strings.Split(nodeIdent, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Malformed -node-identity argument: %q", nodeIdent)
+		}
+		out = append(out, &api.ACLNodeIdentity{
+			NodeName:   parts[0],
+			Datacenter: parts[1],
+		})
+	}
+	return out, nil
+}
+
+// ExtractServiceIdentities parses the service identities given on the command
+// line in the form of "<service name>" or "<service name>:<dc1>,<dc2>".
+func ExtractServiceIdentities(serviceIdents []string) ([]*api.ACLServiceIdentity, error) {
+	var out []*api.ACLServiceIdentity
+	for _, svcIdent := range serviceIdents {
+		parts := strings.Split(svcIdent, ":")
+		if len(parts) > 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Malformed -service-identity argument: %q", svcIdent)
+		}
+		ident := &api.ACLServiceIdentity{ServiceName: parts[0]}
+		if len(parts) == 2 {
+			for _, dc := range strings.Split(parts[1], ",") {
+				if dc == "" {
+					return nil, fmt.Errorf("Malformed -service-identity argument: %q", svcIdent)
+				}
+				ident.Datacenters = append(ident.Datacenters, dc)
+			}
+		}
+		out = append(out, ident)
+	}
+	return out, nil
+}
+
 func GetRulesFromLegacyToken(client *api.Client, tokenID string, isSecret bool) (string, error) {
 	var token *api.ACLToken
 	var err error
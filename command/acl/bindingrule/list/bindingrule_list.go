@@ -0,0 +1,104 @@
+package bindingrulelist
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/command/acl"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
+
+	authMethodName string
+	showMeta       bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.authMethodName, "method", "", "Only show rules linked to the auth "+
+		"method with the given name.")
+	c.flags.BoolVar(&c.showMeta, "meta", false, "Indicates that binding rule metadata such "+
+		"as the raft indices should be shown for each entry")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	rules, _, err := client.ACL().BindingRuleList(c.authMethodName, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to retrieve the binding rule list: %v", err))
+		return 1
+	}
+
+	ids := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+
+	err = c.output.Print(c.UI, rules, ids, func() {
+		for _, rule := range rules {
+			acl.PrintBindingRuleListEntry(rule, c.UI, c.showMeta)
+		}
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "List ACL Binding Rules"
+const help = `
+Usage: consul acl binding-rule list [options]
+
+    Lists all the ACL binding rules
+
+          $ consul acl binding-rule list
+
+    Show all binding rules associated with a specific auth method:
+
+          $ consul acl binding-rule list -method=my-method
+`
@@ -0,0 +1,176 @@
+package bindingruleupdate
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	ruleID         string
+	descriptionSet bool
+	description    string
+	selectorSet    bool
+	selector       string
+	bindTypeSet    bool
+	bindType       string
+	bindNameSet    bool
+	bindName       string
+	noMerge        bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.ruleID, "id", "", "The ID of the binding rule to update. "+
+		"It may be specified as a unique ID prefix but will error if the prefix "+
+		"matches multiple binding rule IDs")
+	c.flags.StringVar(&c.description, "description", "", "A description of the binding rule")
+	c.flags.StringVar(&c.selector, "selector", "", "Selector is an expression that matches "+
+		"against verified identity attributes returned from the auth method during login.")
+	c.flags.StringVar(&c.bindType, "bind-type", string(api.BindingRuleBindTypeService),
+		"Type of binding to perform (\"service\", \"node\" or \"policy\").")
+	c.flags.StringVar(&c.bindName, "bind-name", "", "Name to bind on match. Can use "+
+		"${var} interpolation.")
+	c.flags.BoolVar(&c.noMerge, "no-merge", false, "Do not merge the current binding rule "+
+		"information with what is provided to the command. Instead overwrite all fields "+
+		"with the exception of the binding rule ID and auth method which are immutable.")
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) checkSet(f *flag.Flag) {
+	switch f.Name {
+	case "description":
+		c.descriptionSet = true
+	case "selector":
+		c.selectorSet = true
+	case "bind-type":
+		c.bindTypeSet = true
+	case "bind-name":
+		c.bindNameSet = true
+	}
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	c.flags.Visit(c.checkSet)
+
+	if c.ruleID == "" {
+		c.UI.Error(fmt.Sprintf("Must specify the -id parameter"))
+		return 1
+	}
+
+	if c.noMerge && c.bindName == "" {
+		c.UI.Error(fmt.Sprintf("Missing required '-bind-name' flag"))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	ruleID, err := acl.GetBindingRuleIDFromPartial(client, c.ruleID)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error determining binding rule ID: %v", err))
+		return 1
+	}
+
+	rule, _, err := client.ACL().BindingRuleRead(ruleID, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading binding rule %q: %v", ruleID, err))
+		return 1
+	}
+
+	var updated *api.ACLBindingRule
+	if c.noMerge {
+		updated = &api.ACLBindingRule{
+			ID:          ruleID,
+			AuthMethod:  rule.AuthMethod,
+			Description: c.description,
+			Selector:    c.selector,
+			BindType:    api.BindingRuleBindType(c.bindType),
+			BindName:    c.bindName,
+		}
+	} else {
+		updated = &api.ACLBindingRule{
+			ID:          ruleID,
+			AuthMethod:  rule.AuthMethod,
+			Description: rule.Description,
+			Selector:    rule.Selector,
+			BindType:    rule.BindType,
+			BindName:    rule.BindName,
+		}
+
+		if c.descriptionSet {
+			updated.Description = c.description
+		}
+		if c.selectorSet {
+			updated.Selector = c.selector
+		}
+		if c.bindTypeSet {
+			updated.BindType = api.BindingRuleBindType(c.bindType)
+		}
+		if c.bindNameSet {
+			updated.BindName = c.bindName
+		}
+	}
+
+	rule, _, err = client.ACL().BindingRuleUpdate(updated, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error updating binding rule %q: %v", ruleID, err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Binding rule updated successfully"))
+	acl.PrintBindingRule(rule, c.UI, true)
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Update an ACL Binding Rule"
+const help = `
+Usage: consul acl binding-rule update -id ID [options]
+
+    Updates a binding rule. By default it will merge the binding rule
+    information with its current state so that you do not have to provide
+    all parameters. This behavior can be disabled by passing -no-merge.
+
+    Update all editable fields of the binding rule:
+
+          $ consul acl binding-rule update \
+                   -id=43cb72df-9c6f-4315-ac8a-01a9d98155ef \
+                   -description="new description" \
+                   -bind-type=service \
+                   -bind-name='k8s-${serviceaccount.name}' \
+                   -selector='serviceaccount.namespace==default and serviceaccount.name!=vault'
+`
@@ -0,0 +1,120 @@
+package bindingruleupdate
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/consul/authmethod/testauth"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindingRuleUpdateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestBindingRuleUpdateCommand(t *testing.T) {
+	t.Parallel()
+
+	testDir := testutil.TempDir(t, "acl")
+	defer os.RemoveAll(testDir)
+
+	a := agent.NewTestAgent(t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+
+	testSessionID := testauth.StartSession()
+	defer testauth.ResetSession(testSessionID)
+
+	// Create an auth method
+	_, _, err := client.ACL().AuthMethodCreate(
+		&api.ACLAuthMethod{
+			Name: "test",
+			Type: "testing",
+			Config: map[string]interface{}{
+				"SessionID": testSessionID,
+			},
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(t, err)
+
+	// Create a binding rule
+	rule, _, err := client.ACL().BindingRuleCreate(
+		&api.ACLBindingRule{
+			AuthMethod: "test",
+			Selector:   "serviceaccount.namespace == default",
+			BindType:   api.BindingRuleBindTypeService,
+			BindName:   "${serviceaccount.name}",
+		},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(t, err)
+
+	t.Run("merge", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + rule.ID,
+			"-description=updated",
+		})
+		require.Equal(t, 0, code)
+		require.Empty(t, ui.ErrorWriter.String())
+
+		updated, _, err := client.ACL().BindingRuleRead(rule.ID, &api.QueryOptions{Token: "root"})
+		require.NoError(t, err)
+		require.Equal(t, "updated", updated.Description)
+		require.Equal(t, rule.Selector, updated.Selector)
+		require.Equal(t, rule.BindType, updated.BindType)
+		require.Equal(t, rule.BindName, updated.BindName)
+	})
+
+	t.Run("no merge", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + rule.ID,
+			"-no-merge",
+			"-bind-type=policy",
+			"-bind-name=monitoring",
+		})
+		require.Equal(t, 0, code)
+		require.Empty(t, ui.ErrorWriter.String())
+
+		updated, _, err := client.ACL().BindingRuleRead(rule.ID, &api.QueryOptions{Token: "root"})
+		require.NoError(t, err)
+		require.Equal(t, "test", updated.AuthMethod)
+		require.Empty(t, updated.Description)
+		require.Empty(t, updated.Selector)
+		require.Equal(t, api.BindingRuleBindTypePolicy, updated.BindType)
+		require.Equal(t, "monitoring", updated.BindName)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -81,6 +82,17 @@ func (c *cmd) Help() string {
 	return flags.Usage(c.help, nil)
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-id":   c.http.PredictPolicyIDs(),
+		"-name": c.http.PredictPolicyNames(),
+	})
+}
+
 const synopsis = "Delete an ACL Policy"
 const help = `
 Usage: consul acl policy delete [options] -id POLICY
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 )
 
@@ -16,22 +17,28 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	showMeta bool
+	filter   string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.BoolVar(&c.showMeta, "meta", false, "Indicates that policy metadata such "+
 		"as the content hash and raft indices should be show for each entry")
+	c.flags.StringVar(&c.filter, "filter", "", "Only list the policies matching this "+
+		"filter expression")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -40,20 +47,36 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
 		return 1
 	}
 
-	policies, _, err := client.ACL().PolicyList(nil)
+	policies, _, err := client.ACL().PolicyListFiltered(c.filter, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to retrieve the policy list: %v", err))
 		return 1
 	}
 
+	ids := make([]string, 0, len(policies))
 	for _, policy := range policies {
-		acl.PrintPolicyListEntry(policy, c.UI, c.showMeta)
+		ids = append(ids, policy.ID)
+	}
+
+	err = c.output.Print(c.UI, policies, ids, func() {
+		for _, policy := range policies {
+			acl.PrintPolicyListEntry(policy, c.UI, c.showMeta)
+		}
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
 	return 0
@@ -74,4 +97,8 @@ Usage: consul acl policy list [options]
     Lists all the ACL policies
 
           $ consul acl policy list
+
+    List the policies which grant operator access:
+
+          $ consul acl policy list -filter 'Rules contains "operator"'
 `
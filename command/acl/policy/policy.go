@@ -46,6 +46,10 @@ Usage: consul acl policy <subcommand> [options] [args]
 
       $ consul acl policy update “other-policy” -datacenter “dc1”
 
+  Validate the rules of a policy:
+
+      $ consul acl policy validate -rules @rules.hcl
+
   Read a policy:
 
     $ consul acl policy read 0479e93e-091c-4475-9b06-79a004765c24
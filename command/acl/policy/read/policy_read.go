@@ -6,7 +6,9 @@ import (
 
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -16,10 +18,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	policyID   string
 	policyName string
@@ -34,6 +37,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -42,6 +47,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if c.policyID == "" && c.policyName == "" {
 		c.UI.Error(fmt.Sprintf("Must specify either the -id or -name parameters"))
 		return 1
@@ -69,7 +79,13 @@ func (c *cmd) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error reading policy %q: %v", policyID, err))
 		return 1
 	}
-	acl.PrintPolicy(policy, c.UI, true)
+	err = c.output.Print(c.UI, policy, []string{policy.ID}, func() {
+		acl.PrintPolicy(policy, c.UI, true)
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	return 0
 }
 
@@ -81,6 +97,17 @@ func (c *cmd) Help() string {
 	return flags.Usage(c.help, nil)
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-id":   c.http.PredictPolicyIDs(),
+		"-name": c.http.PredictPolicyNames(),
+	})
+}
+
 const synopsis = "Read an ACL Policy"
 const help = `
 Usage: consul acl policy read [options] POLICY
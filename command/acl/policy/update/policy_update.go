@@ -4,12 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/hashicorp/consul/command/helpers"
 	"github.com/mitchellh/cli"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -32,7 +35,9 @@ type cmd struct {
 	datacenters    []string
 	rulesSet       bool
 	rules          string
+	rulesFile      string
 	noMerge        bool
+	yes            bool
 
 	testStdin io.Reader
 }
@@ -49,6 +54,11 @@ func (c *cmd) init() {
 	c.flags.StringVar(&c.rules, "rules", "", "The policy rules. May be prefixed with '@' "+
 		"to indicate that the value is a file path to load the rules from. '-' may also be "+
 		"given to indicate that the rules are available on stdin")
+	c.flags.StringVar(&c.rulesFile, "file", "", "Path of a file to load the policy rules "+
+		"from. '-' may be given to read the rules from stdin. Cannot be used with -rules")
+	c.flags.BoolVar(&c.yes, "yes", false, "Do not ask for confirmation before "+
+		"changing the rules of the policy. Required when the rules are read from stdin")
+	c.flags.BoolVar(&c.yes, "no-confirm", false, "Alias of -yes")
 	c.flags.BoolVar(&c.noMerge, "no-merge", false, "Do not merge the current policy "+
 		"information with what is provided to the command. Instead overwrite all fields "+
 		"with the exception of the policy ID which is immutable.")
@@ -64,7 +74,7 @@ func (c *cmd) checkSet(f *flag.Flag) {
 		c.nameSet = true
 	case "description":
 		c.descriptionSet = true
-	case "rules":
+	case "rules", "file":
 		c.rulesSet = true
 	}
 }
@@ -81,6 +91,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.rules != "" && c.rulesFile != "" {
+		c.UI.Error(fmt.Sprintf("Cannot specify both the -rules and -file parameters"))
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
@@ -98,7 +113,23 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	rules, err := helpers.LoadDataSource(c.rules, c.testStdin)
+	rulesSource := c.rules
+	if c.rulesFile == "-" {
+		rulesSource = "-"
+	} else if c.rulesFile != "" {
+		rulesSource = "@" + c.rulesFile
+	}
+	rules, err := helpers.LoadDataSource(rulesSource, c.testStdin)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading the policy rules: %v", err))
+		return 1
+	}
+
+	policy, _, err := client.ACL().PolicyRead(policyID, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading policy %q: %v", policyID, err))
+		return 1
+	}
 
 	var updated *api.ACLPolicy
 	if c.noMerge {
@@ -110,12 +141,6 @@ func (c *cmd) Run(args []string) int {
 			Rules:       rules,
 		}
 	} else {
-		policy, _, err := client.ACL().PolicyRead(policyID, nil)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Error reading policy %q: %v", policyID, err))
-			return 1
-		}
-
 		updated = &api.ACLPolicy{
 			ID:          policyID,
 			Name:        policy.Name,
@@ -138,7 +163,11 @@ func (c *cmd) Run(args []string) int {
 		}
 	}
 
-	policy, _, err := client.ACL().PolicyUpdate(updated, nil)
+	if updated.Rules != policy.Rules && !c.confirmRules(policy, updated.Rules, rulesSource == "-") {
+		return 1
+	}
+
+	policy, _, err = client.ACL().PolicyUpdate(updated, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error updating policy %q: %v", policyID, err))
 		return 1
@@ -149,6 +178,45 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// confirmRules shows the changes of the rules of the policy as a unified diff
+// and asks for confirmation to apply them unless -yes is set. Rules read from
+// stdin can't be confirmed interactively and require -yes.
+func (c *cmd) confirmRules(policy *api.ACLPolicy, rules string, fromStdin bool) bool {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(policy.Rules),
+		B:        difflib.SplitLines(rules),
+		FromFile: "current",
+		ToFile:   "updated",
+		Context:  3,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error computing the rules diff: %v", err))
+		return false
+	}
+
+	c.UI.Info(fmt.Sprintf("The rules of policy %q will be changed:", policy.Name))
+	c.UI.Output(diff)
+
+	if c.yes {
+		return true
+	}
+	if fromStdin {
+		c.UI.Error("The -yes parameter is required to change the rules when they are read from stdin")
+		return false
+	}
+
+	answer, err := c.UI.Ask("Are you sure you want to apply these rules? Only 'yes' will be accepted:")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading the confirmation: %v", err))
+		return false
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		c.UI.Info("Update cancelled")
+		return false
+	}
+	return true
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -157,6 +225,16 @@ func (c *cmd) Help() string {
 	return flags.Usage(help, nil)
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-id": c.http.PredictPolicyIDs(),
+	})
+}
+
 const synopsis = "Update an ACL Policy"
 const help = `
 Usage: consul acl policy update [options]
@@ -174,4 +252,10 @@ Usage: consul acl policy update [options]
           # this will remove any datacenter scope if provided and will remove
           # the description
           $consul acl policy update -id abcd -name "better-name" -rules @rules.hcl
+
+  When the rules change, a diff of the current and the updated rules is shown
+  and the change must be confirmed. Pass -yes to skip the confirmation, which
+  is required when the rules are read from stdin:
+
+          $ cat rules.hcl | consul acl policy update -id abcd -file - -yes
 `
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/consul/testutil"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPolicyUpdateCommand_noTabs(t *testing.T) {
@@ -66,9 +67,127 @@ func TestPolicyUpdateCommand(t *testing.T) {
 		"-id=" + policy.ID,
 		"-name=new-name",
 		"-rules=@" + testDir + "/rules.hcl",
+		"-yes",
 	}
 
 	code := cmd.Run(args)
 	assert.Equal(code, 0)
 	assert.Empty(ui.ErrorWriter.String())
 }
+
+func TestPolicyUpdateCommand_confirmRules(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	policy, _, err := client.ACL().PolicyCreate(
+		&api.ACLPolicy{Name: "test-policy", Rules: "service \"\" { policy = \"read\" }\n"},
+		&api.WriteOptions{Token: "root"},
+	)
+	require.NoError(err)
+
+	newRules := "service \"\" { policy = \"write\" }\n"
+	currentRules := func() string {
+		p, _, err := client.ACL().PolicyRead(policy.ID, &api.QueryOptions{Token: "root"})
+		require.NoError(err)
+		return p.Rules
+	}
+
+	t.Run("diff is shown and the change can be cancelled", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		ui.InputReader = strings.NewReader("no\n")
+		cmd := New(ui)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + policy.ID,
+			"-rules=" + newRules,
+		})
+		require.Equal(1, code)
+		output := ui.OutputWriter.String()
+		require.Contains(output, "--- current")
+		require.Contains(output, "+++ updated")
+		require.Contains(output, `-service "" { policy = "read" }`)
+		require.Contains(output, `+service "" { policy = "write" }`)
+		require.Contains(output, "Update cancelled")
+		require.Equal(policy.Rules, currentRules())
+	})
+
+	t.Run("rules from stdin require -yes", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		cmd.testStdin = strings.NewReader(newRules)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + policy.ID,
+			"-file=-",
+		})
+		require.Equal(1, code)
+		require.Contains(ui.ErrorWriter.String(), "-yes parameter is required")
+		require.Equal(policy.Rules, currentRules())
+	})
+
+	t.Run("rules from stdin with -yes", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		cmd.testStdin = strings.NewReader(newRules)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + policy.ID,
+			"-file=-",
+			"-yes",
+		})
+		require.Equal(0, code)
+		require.Empty(ui.ErrorWriter.String())
+		require.Equal(newRules, currentRules())
+	})
+
+	t.Run("unchanged rules need no confirmation", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + policy.ID,
+			"-description=still writing",
+		})
+		require.Equal(0, code)
+		require.Empty(ui.ErrorWriter.String())
+		require.NotContains(ui.OutputWriter.String(), "will be changed")
+	})
+
+	t.Run("-rules and -file are exclusive", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + policy.ID,
+			"-rules=" + newRules,
+			"-file=rules.hcl",
+		})
+		require.Equal(1, code)
+		require.Contains(ui.ErrorWriter.String(), "Cannot specify both")
+	})
+}
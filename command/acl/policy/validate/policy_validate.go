@@ -0,0 +1,119 @@
+package policyvalidate
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/helpers"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	rules     string
+	canonical bool
+
+	testStdin io.Reader
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.rules, "rules", "", "The policy rules to validate. May be prefixed "+
+		"with '@' to indicate that the value is a file path to load the rules from. '-' may "+
+		"also be given to indicate that the rules are available on stdin. This flag is required.")
+	c.flags.BoolVar(&c.canonical, "canonical", false, "Print the rules in the canonical "+
+		"form if they are valid")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if c.rules == "" {
+		c.UI.Error(fmt.Sprintf("Missing require '-rules' flag"))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	rules, err := helpers.LoadDataSource(c.rules, c.testStdin)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading rules: %v", err))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	result, _, err := client.ACL().PolicyValidate(&api.ACLPolicy{Rules: rules}, c.canonical, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to validate the policy rules: %v", err))
+		return 1
+	}
+
+	if !result.Valid {
+		if result.Error.Line != 0 {
+			c.UI.Error(fmt.Sprintf("Invalid rules at line %d, column %d: %s",
+				result.Error.Line, result.Error.Column, result.Error.Message))
+		} else {
+			c.UI.Error(fmt.Sprintf("Invalid rules: %s", result.Error.Message))
+		}
+		return 1
+	}
+
+	if c.canonical {
+		c.UI.Output(strings.TrimSuffix(result.CanonicalRules, "\n"))
+	} else {
+		c.UI.Info("The rules are valid")
+	}
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(c.help, nil)
+}
+
+const synopsis = "Validate the rules of an ACL Policy"
+const help = `
+Usage: consul acl policy validate -rules RULES [options]
+
+  Checks the rules of a policy without creating or updating a policy. The
+  position of the first error in the rules is reported, and the command
+  exits with a non-zero status if the rules are invalid. The -rules value
+  may be loaded from stdin, a file or the raw value like for the create
+  command.
+
+  Validate the rules in a file:
+
+      $ consul acl policy validate -rules @rules.hcl
+
+  Print the rules in the canonical form, with the rules grouped by resource:
+
+      $ consul acl policy validate -canonical -rules @rules.hcl
+`
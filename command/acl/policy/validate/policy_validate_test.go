@@ -0,0 +1,82 @@
+package policyvalidate
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyValidateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestPolicyValidateCommand(t *testing.T) {
+	t.Parallel()
+
+	testDir := testutil.TempDir(t, "acl")
+	defer os.RemoveAll(testDir)
+
+	a := agent.NewTestAgent(t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	rules := []byte("service \"\" { policy = \"write\" }")
+	require.NoError(t, ioutil.WriteFile(testDir+"/rules.hcl", rules, 0644))
+
+	t.Run("valid", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-rules=@" + testDir + "/rules.hcl",
+		})
+		require.Equal(t, 0, code)
+		require.Empty(t, ui.ErrorWriter.String())
+		require.Contains(t, ui.OutputWriter.String(), "The rules are valid")
+	})
+
+	t.Run("canonical", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-canonical",
+			"-rules=@" + testDir + "/rules.hcl",
+		})
+		require.Equal(t, 0, code)
+		require.Equal(t, "service \"\" {\n  policy = \"write\"\n}\n", ui.OutputWriter.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		code := cmd.Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-rules=key_prefix \"\" {\n  policy = \"admin\"\n}",
+		})
+		require.Equal(t, 1, code)
+		require.Contains(t, ui.ErrorWriter.String(), "Invalid rules at line 1, column 1: Invalid key_prefix policy")
+	})
+}
@@ -3,11 +3,13 @@ package tokencreate
 import (
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -22,10 +24,13 @@ type cmd struct {
 	http  *flags.HTTPFlags
 	help  string
 
-	policyIDs   []string
-	policyNames []string
-	description string
-	local       bool
+	policyIDs         []string
+	policyNames       []string
+	nodeIdentities    []string
+	serviceIdentities []string
+	description       string
+	local             bool
+	expirationTTL     time.Duration
 }
 
 func (c *cmd) init() {
@@ -36,6 +41,14 @@ func (c *cmd) init() {
 		"policy to use for this token. May be specified multiple times")
 	c.flags.Var((*flags.AppendSliceValue)(&c.policyNames), "policy-name", "Name of a "+
 		"policy to use for this token. May be specified multiple times")
+	c.flags.Var((*flags.AppendSliceValue)(&c.nodeIdentities), "node-identity", "Name of a "+
+		"node identity to use for this token in the format of <node name>:<datacenter>. "+
+		"May be specified multiple times")
+	c.flags.Var((*flags.AppendSliceValue)(&c.serviceIdentities), "service-identity", "Name of a "+
+		"service identity to use for this token in the format of <service name> or "+
+		"<service name>:<dc1>,<dc2>. May be specified multiple times")
+	c.flags.DurationVar(&c.expirationTTL, "expires-ttl", 0, "Duration of time this "+
+		"token should be valid for, after which it is revoked and deleted")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -47,8 +60,20 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	if len(c.policyNames) == 0 && len(c.policyIDs) == 0 {
-		c.UI.Error(fmt.Sprintf("Cannot create a token without specifying -policy-name or -policy-id at least once"))
+	if len(c.policyNames) == 0 && len(c.policyIDs) == 0 && len(c.nodeIdentities) == 0 && len(c.serviceIdentities) == 0 {
+		c.UI.Error(fmt.Sprintf("Cannot create a token without specifying -policy-name, -policy-id, -node-identity or -service-identity at least once"))
+		return 1
+	}
+
+	nodeIdentities, err := acl.ExtractNodeIdentities(c.nodeIdentities)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	serviceIdentities, err := acl.ExtractServiceIdentities(c.serviceIdentities)
+	if err != nil {
+		c.UI.Error(err.Error())
 		return 1
 	}
 
@@ -59,8 +84,13 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	newToken := &api.ACLToken{
-		Description: c.description,
-		Local:       c.local,
+		Description:       c.description,
+		Local:             c.local,
+		NodeIdentities:    nodeIdentities,
+		ServiceIdentities: serviceIdentities,
+	}
+	if c.expirationTTL > 0 {
+		newToken.ExpirationTTL = c.expirationTTL
 	}
 
 	for _, policyName := range c.policyNames {
@@ -96,6 +126,17 @@ func (c *cmd) Help() string {
 	return flags.Usage(c.help, nil)
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-policy-id":   c.http.PredictPolicyIDs(),
+		"-policy-name": c.http.PredictPolicyNames(),
+	})
+}
+
 const synopsis = "Create an ACL Token"
 const help = `
 Usage: consul acl token create [options]
@@ -104,9 +145,33 @@ Usage: consul acl token create [options]
   or the -policy-name options. When specifying policies by IDs you may use a
   unique prefix of the UUID as a shortcut for specifying the entire UUID.
 
+  Node identities may be added with the -node-identity option to grant the
+  token write access to a node in a datacenter, without writing a policy for
+  every node.
+
+  Service identities may be added with the -service-identity option to grant
+  the token write access to a service and its sidecar proxy, and read access
+  to all other services and nodes, without writing a policy for every service.
+
   Create a new token:
 
           $ consul acl token create -description "Replication token"
                                             -policy-id b52fc3de-5
                                             -policy-name "acl-replication"
+
+  Create a new agent token for a node:
+
+          $ consul acl token create -description "Agent token for web-1"
+                                            -node-identity "web-1:dc1"
+
+  Create a new token for the web service in dc1 and dc2:
+
+          $ consul acl token create -description "Token for web"
+                                            -service-identity "web:dc1,dc2"
+
+  Create a new token which expires after an hour:
+
+          $ consul acl token create -description "Temporary token"
+                                            -policy-name "read-only"
+                                            -expires-ttl 1h
 `
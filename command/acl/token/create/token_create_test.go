@@ -82,4 +82,84 @@ func TestTokenCreateCommand(t *testing.T) {
 		assert.Equal(code, 0)
 		assert.Empty(ui.ErrorWriter.String())
 	}
+	// create with node identity
+	{
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-node-identity=web-1:dc1",
+			"-description=test token",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+		assert.Contains(ui.OutputWriter.String(), "web-1 (Datacenter: dc1)")
+	}
+
+	// create with service identities
+	{
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-service-identity=web:dc1,dc2",
+			"-service-identity=db",
+			"-description=test token",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+		assert.Contains(ui.OutputWriter.String(), "web (Datacenters: dc1, dc2)")
+		assert.Contains(ui.OutputWriter.String(), "db (Datacenters: all)")
+	}
+
+	// create with an expiration TTL
+	{
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-policy-name=" + policy.Name,
+			"-expires-ttl=1h",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+		assert.Contains(ui.OutputWriter.String(), "Expiration Time:")
+	}
+
+	// create with malformed node identity
+	{
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-node-identity=web-1",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 1)
+		assert.Contains(ui.ErrorWriter.String(), "Malformed -node-identity argument")
+	}
+
+	// create with malformed service identity
+	{
+		ui := cli.NewMockUi()
+		cmd := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-service-identity=web:",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 1)
+		assert.Contains(ui.ErrorWriter.String(), "Malformed -service-identity argument")
+	}
 }
@@ -3,7 +3,9 @@ package tokendelete
 import (
 	"flag"
 	"fmt"
+	"strings"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
@@ -22,6 +24,8 @@ type cmd struct {
 	help  string
 
 	tokenID string
+	filter  string
+	force   bool
 }
 
 func (c *cmd) init() {
@@ -29,7 +33,10 @@ func (c *cmd) init() {
 	c.flags.StringVar(&c.tokenID, "id", "", "The Accessor ID of the token to delete. "+
 		"It may be specified as a unique ID prefix but will error if the prefix "+
 		"matches multiple token Accessor IDs")
-	c.http = &flags.HTTPFlags{}
+	c.flags.StringVar(&c.filter, "filter", "", "Delete all the tokens matching this "+
+		"filter expression instead of a single token")
+	c.flags.BoolVar(&c.force, "force", false, "Do not ask for confirmation before "+
+		"deleting the tokens matching the -filter expression")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -41,8 +48,12 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	if c.tokenID == "" {
-		c.UI.Error(fmt.Sprintf("Must specify the -id paramter"))
+	if c.tokenID == "" && c.filter == "" {
+		c.UI.Error(fmt.Sprintf("Must specify the -id or -filter paramter"))
+		return 1
+	}
+	if c.tokenID != "" && c.filter != "" {
+		c.UI.Error(fmt.Sprintf("Cannot specify both the -id and -filter parameters"))
 		return 1
 	}
 
@@ -52,6 +63,10 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if c.filter != "" {
+		return c.deleteFilter(client)
+	}
+
 	tokenID, err := acl.GetTokenIDFromPartial(client, c.tokenID)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error determining token ID: %v", err))
@@ -67,6 +82,48 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// deleteFilter deletes the tokens matching the filter expression after
+// asking for confirmation unless -force is set.
+func (c *cmd) deleteFilter(client *api.Client) int {
+	if !c.force {
+		tokens, _, err := client.ACL().TokenDeleteFilter(c.filter, true, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error listing the tokens matching the filter: %v", err))
+			return 1
+		}
+		if len(tokens) == 0 {
+			c.UI.Info("No tokens match the filter")
+			return 0
+		}
+
+		c.UI.Info(fmt.Sprintf("The following %d tokens match the filter:", len(tokens)))
+		for _, token := range tokens {
+			c.UI.Info(fmt.Sprintf("   %s - %s", token.AccessorID, token.Description))
+		}
+		answer, err := c.UI.Ask(fmt.Sprintf("Are you sure you want to delete %d tokens? Only 'yes' will be accepted:", len(tokens)))
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading the confirmation: %v", err))
+			return 1
+		}
+		if strings.TrimSpace(answer) != "yes" {
+			c.UI.Info("Deletion cancelled")
+			return 1
+		}
+	}
+
+	tokens, _, err := client.ACL().TokenDeleteFilter(c.filter, false, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error deleting the tokens matching the filter: %v", err))
+		return 1
+	}
+
+	for _, token := range tokens {
+		c.UI.Info(fmt.Sprintf("Token %q deleted successfully", token.AccessorID))
+	}
+	c.UI.Info(fmt.Sprintf("Deleted %d tokens", len(tokens)))
+	return 0
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -79,7 +136,8 @@ const synopsis = "Delete an ACL Token"
 const help = `
 Usage: consul acl token delete [options] -id TOKEN
 
-  Deletes an ACL token by providing either the ID or a unique ID prefix.
+  Deletes an ACL token by providing either the ID or a unique ID prefix, or
+  all the tokens matching a filter expression.
 
       Delete by prefix:
 
@@ -88,4 +146,9 @@ Usage: consul acl token delete [options] -id TOKEN
       Delete by full ID:
 
           $ consul acl token delete -id b6b856da-5193-4e78-845a-7d61ca8371ba
+
+      Delete all the tokens whose description contains "ci-" without
+      confirmation:
+
+          $ consul acl token delete -filter 'Description contains "ci-"' -force
 `
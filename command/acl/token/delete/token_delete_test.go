@@ -76,3 +76,95 @@ func TestTokenDeleteCommand(t *testing.T) {
 	)
 	assert.EqualError(err, "Unexpected response code: 403 (ACL not found)")
 }
+
+func TestTokenDeleteCommand_Filter(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	a := agent.NewTestAgent(t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+
+	a.Agent.LogWriter = logger.NewLogWriter(512)
+
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+
+	var ciTokens []string
+	for _, desc := range []string{"ci-1", "ci-2", "deploy"} {
+		token, _, err := client.ACL().TokenCreate(
+			&api.ACLToken{Description: desc},
+			&api.WriteOptions{Token: "root"},
+		)
+		assert.NoError(err)
+		if desc != "deploy" {
+			ciTokens = append(ciTokens, token.AccessorID)
+		}
+	}
+
+	exists := func(id string) bool {
+		_, _, err := client.ACL().TokenRead(id, &api.QueryOptions{Token: "root"})
+		return err == nil
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-token=root",
+		"-filter=Description contains \"ci-\"",
+	}
+
+	// declining the confirmation keeps the tokens
+	{
+		ui := cli.NewMockUi()
+		ui.InputReader = strings.NewReader("no\n")
+		code := New(ui).Run(args)
+		assert.Equal(1, code)
+		output := ui.OutputWriter.String()
+		assert.Contains(output, "The following 2 tokens match the filter")
+		assert.Contains(output, "Deletion cancelled")
+		for _, id := range ciTokens {
+			assert.True(exists(id))
+		}
+	}
+
+	// confirming deletes the matching tokens only
+	{
+		ui := cli.NewMockUi()
+		ui.InputReader = strings.NewReader("yes\n")
+		code := New(ui).Run(args)
+		assert.Equal(0, code)
+		assert.Empty(ui.ErrorWriter.String())
+		assert.Contains(ui.OutputWriter.String(), "Deleted 2 tokens")
+		for _, id := range ciTokens {
+			assert.False(exists(id))
+		}
+	}
+
+	// -force skips the confirmation
+	{
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-filter=Description == deploy",
+			"-force",
+		})
+		assert.Equal(0, code)
+		assert.Contains(ui.OutputWriter.String(), "Deleted 1 tokens")
+	}
+
+	// -id and -filter are exclusive
+	{
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{"-id=abcd", "-filter=Local == true"})
+		assert.Equal(1, code)
+		assert.Contains(ui.ErrorWriter.String(), "Cannot specify both")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 )
 
@@ -16,21 +17,27 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	showMeta bool
+	filter   string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.BoolVar(&c.showMeta, "meta", false, "Indicates that token metadata such "+
 		"as the content hash and raft indices should be show for each entry")
+	c.flags.StringVar(&c.filter, "filter", "", "Only list the tokens matching this "+
+		"filter expression")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -39,26 +46,42 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
 		return 1
 	}
 
-	tokens, _, err := client.ACL().TokenList(nil)
+	tokens, _, err := client.ACL().TokenListFiltered(c.filter, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to retrieve the token list: %v", err))
 		return 1
 	}
 
-	first := true
+	ids := make([]string, 0, len(tokens))
 	for _, token := range tokens {
-		if first {
-			first = false
-		} else {
-			c.UI.Info("")
+		ids = append(ids, token.AccessorID)
+	}
+
+	err = c.output.Print(c.UI, tokens, ids, func() {
+		first := true
+		for _, token := range tokens {
+			if first {
+				first = false
+			} else {
+				c.UI.Info("")
+			}
+			acl.PrintTokenListEntry(token, c.UI, c.showMeta)
 		}
-		acl.PrintTokenListEntry(token, c.UI, c.showMeta)
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
 	return 0
@@ -79,4 +102,8 @@ Usage: consul acl token list [options]
   List all the ALC tokens
 
           $ consul acl token list
+
+  List the tokens created by CI jobs in the last month:
+
+          $ consul acl token list -filter 'Description contains "ci-" and CreateTime > 2019-05-01'
 `
@@ -1,6 +1,7 @@
 package tokenlist
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -77,4 +78,36 @@ func TestTokenListCommand(t *testing.T) {
 		assert.Contains(output, fmt.Sprintf("test token %d", i))
 		assert.Contains(output, v)
 	}
+
+	// Only the accessor IDs are printed in quiet mode
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run(append(args, "-quiet"))
+	assert.Equal(code, 0)
+	assert.Empty(ui.ErrorWriter.String())
+	ids := strings.Fields(ui.OutputWriter.String())
+	assert.Subset(ids, tokenIds)
+	assert.NotContains(ui.OutputWriter.String(), "test token")
+
+	// The JSON output decodes into the API structs
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run(append(args, "-format=json"))
+	assert.Equal(code, 0)
+	var entries []*api.ACLTokenListEntry
+	assert.NoError(json.Unmarshal(ui.OutputWriter.Bytes(), &entries))
+	assert.Len(entries, len(ids))
+
+	// The servers only return the tokens matching the filter
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run(append(args, "-quiet", `-filter=Description == "test token 3"`))
+	assert.Equal(code, 0)
+	assert.Equal(tokenIds[3:4], strings.Fields(ui.OutputWriter.String()))
+
+	ui = cli.NewMockUi()
+	cmd = New(ui)
+	code = cmd.Run(append(args, "-filter=Unknown == foo"))
+	assert.Equal(code, 1)
+	assert.Contains(ui.ErrorWriter.String(), "Unknown selector")
 }
@@ -4,8 +4,10 @@ import (
 	"flag"
 	"fmt"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 )
 
@@ -16,12 +18,14 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	tokenID string
+	expand  bool
 }
 
 func (c *cmd) init() {
@@ -29,9 +33,16 @@ func (c *cmd) init() {
 	c.flags.StringVar(&c.tokenID, "id", "", "The Accessor ID of the token to read. "+
 		"It may be specified as a unique ID prefix but will error if the prefix "+
 		"matches multiple token Accessor IDs")
+	c.flags.BoolVar(&c.expand, "expand", false, "Fetch the policies linked "+
+		"to the token and print its effective rules, which are the rules of "+
+		"its policies, node identities and legacy rules merged together, with "+
+		"the sources of every rule. With -format=json the token is printed "+
+		"under \"Token\" and the rules under \"EffectiveRules\".")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -40,6 +51,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	if c.tokenID == "" {
 		c.UI.Error(fmt.Sprintf("Must specify the -id parameter"))
 		return 1
@@ -63,7 +79,45 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	acl.PrintToken(token, c.UI, true)
+	if !c.expand {
+		err = c.output.Print(c.UI, token, []string{token.AccessorID}, func() {
+			acl.PrintToken(token, c.UI, true)
+		})
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	datacenter := c.http.Datacenter()
+	if datacenter == "" {
+		self, err := client.Agent().Self()
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying the agent's datacenter: %v", err))
+			return 1
+		}
+		datacenter, _ = self["Config"]["Datacenter"].(string)
+	}
+
+	rules, err := acl.GetEffectiveRules(client, token, datacenter)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error determining the effective rules of token %q: %v", tokenID, err))
+		return 1
+	}
+
+	expanded := struct {
+		Token          *api.ACLToken
+		EffectiveRules *acl.EffectiveRules
+	}{token, rules}
+	err = c.output.Print(c.UI, expanded, []string{token.AccessorID}, func() {
+		acl.PrintToken(token, c.UI, true)
+		acl.PrintEffectiveRules(rules, c.UI)
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	return 0
 }
 
@@ -89,4 +143,9 @@ Usage: consul acl token read [options] -id TOKENID
   Using the full ID:
 
           $ consul acl token read -id 4be56c77-8244-4c7d-b08c-667b8c71baed
+
+  Showing the effective rules of the token, merged from all its policies
+  and identities, with the sources of every rule:
+
+          $ consul acl token read -id 4be56c77-82 -expand
 `
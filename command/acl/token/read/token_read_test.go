@@ -1,6 +1,7 @@
 package tokenread
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -8,11 +9,13 @@ import (
 
 	"github.com/hashicorp/consul/agent"
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/logger"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTokenReadCommand_noTabs(t *testing.T) {
@@ -71,3 +74,101 @@ func TestTokenReadCommand(t *testing.T) {
 	assert.Contains(output, token.AccessorID)
 	assert.Contains(output, token.SecretID)
 }
+
+func TestTokenReadCommand_expand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+
+	a := agent.NewTestAgent(t.Name(), `
+	primary_datacenter = "dc1"
+	acl {
+		enabled = true
+		tokens {
+			master = "root"
+		}
+	}`)
+	defer a.Shutdown()
+	testrpc.WaitForLeader(t, a.RPC, "dc1")
+
+	client := a.Client()
+	writeOpts := &api.WriteOptions{Token: "root"}
+
+	readOnly, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:  "app-read",
+		Rules: `key_prefix "app/" { policy = "read" } operator = "read"`,
+	}, writeOpts)
+	require.NoError(err)
+	readWrite, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:  "app-write",
+		Rules: `key_prefix "app/" { policy = "write" }`,
+	}, writeOpts)
+	require.NoError(err)
+	otherDC, _, err := client.ACL().PolicyCreate(&api.ACLPolicy{
+		Name:        "dc2-only",
+		Rules:       `key_prefix "" { policy = "deny" }`,
+		Datacenters: []string{"dc2"},
+	}, writeOpts)
+	require.NoError(err)
+
+	token, _, err := client.ACL().TokenCreate(&api.ACLToken{
+		Description: "test",
+		Policies: []*api.ACLTokenPolicyLink{
+			{ID: readOnly.ID},
+			{ID: readWrite.ID},
+			{ID: otherDC.ID},
+		},
+		NodeIdentities: []*api.ACLNodeIdentity{
+			{NodeName: "web-1", Datacenter: "dc1"},
+		},
+	}, writeOpts)
+	require.NoError(err)
+
+	t.Run("table", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + token.AccessorID,
+			"-expand",
+		})
+		require.Equal(0, code, ui.ErrorWriter.String())
+
+		output := ui.OutputWriter.String()
+		require.Contains(output, "Effective Rules (Datacenter: dc1):")
+		require.Contains(output, `# from policy "app-read" = read, policy "app-write" = write`)
+		require.Contains(output, `key_prefix "app/" {`+"\n"+`      policy = "write"`)
+		require.Contains(output, `operator = "read"`)
+		require.Contains(output, `node "web-1" {`)
+		require.Contains(output, "Not valid in this datacenter:\n   policy \"dc2-only\"")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		code := New(ui).Run([]string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-token=root",
+			"-id=" + token.AccessorID,
+			"-expand",
+			"-format=json",
+		})
+		require.Equal(0, code, ui.ErrorWriter.String())
+
+		var out struct {
+			Token          *api.ACLToken
+			EffectiveRules *acl.EffectiveRules
+		}
+		require.NoError(json.Unmarshal(ui.OutputWriter.Bytes(), &out))
+		require.Equal(token.AccessorID, out.Token.AccessorID)
+		require.Equal([]string{`policy "dc2-only"`}, out.EffectiveRules.Ignored)
+
+		var keyRule *acl.EffectiveRule
+		for _, rule := range out.EffectiveRules.Rules {
+			if rule.Resource == "key_prefix" && rule.Segment == "app/" {
+				keyRule = rule
+			}
+		}
+		require.NotNil(keyRule)
+		require.Equal("write", keyRule.Policy)
+		require.Len(keyRule.Sources, 2)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/consul/command/acl"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -22,18 +23,26 @@ type cmd struct {
 	http  *flags.HTTPFlags
 	help  string
 
-	tokenID     string
-	policyIDs   []string
-	policyNames []string
-	description string
+	tokenID           string
+	policyIDs         []string
+	policyNames       []string
+	nodeIdentities    []string
+	serviceIdentities []string
+	description       string
 
-	mergePolicies bool
+	mergePolicies          bool
+	mergeNodeIdentities    bool
+	mergeServiceIdentities bool
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.BoolVar(&c.mergePolicies, "merge-policies", false, "Merge the new policies "+
 		"with the existing policies")
+	c.flags.BoolVar(&c.mergeNodeIdentities, "merge-node-identities", false, "Merge the new "+
+		"node identities with the existing node identities")
+	c.flags.BoolVar(&c.mergeServiceIdentities, "merge-service-identities", false, "Merge the new "+
+		"service identities with the existing service identities")
 	c.flags.StringVar(&c.tokenID, "id", "", "The Accessor ID of the token to read. "+
 		"It may be specified as a unique ID prefix but will error if the prefix "+
 		"matches multiple token Accessor IDs")
@@ -42,6 +51,12 @@ func (c *cmd) init() {
 		"policy to use for this token. May be specified multiple times")
 	c.flags.Var((*flags.AppendSliceValue)(&c.policyNames), "policy-name", "Name of a "+
 		"policy to use for this token. May be specified multiple times")
+	c.flags.Var((*flags.AppendSliceValue)(&c.nodeIdentities), "node-identity", "Name of a "+
+		"node identity to use for this token in the format of <node name>:<datacenter>. "+
+		"May be specified multiple times")
+	c.flags.Var((*flags.AppendSliceValue)(&c.serviceIdentities), "service-identity", "Name of a "+
+		"service identity to use for this token in the format of <service name> or "+
+		"<service name>:<dc1>,<dc2>. May be specified multiple times")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -58,6 +73,18 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	nodeIdentities, err := acl.ExtractNodeIdentities(c.nodeIdentities)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	serviceIdentities, err := acl.ExtractServiceIdentities(c.serviceIdentities)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	client, err := c.http.APIClient()
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
@@ -133,6 +160,45 @@ func (c *cmd) Run(args []string) int {
 		}
 	}
 
+	if c.mergeNodeIdentities {
+		for _, nodeIdent := range nodeIdentities {
+			found := false
+			for _, existing := range token.NodeIdentities {
+				if existing.NodeName == nodeIdent.NodeName && existing.Datacenter == nodeIdent.Datacenter {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				token.NodeIdentities = append(token.NodeIdentities, nodeIdent)
+			}
+		}
+	} else {
+		token.NodeIdentities = nodeIdentities
+	}
+
+	if c.mergeServiceIdentities {
+		for _, svcIdent := range serviceIdentities {
+			found := false
+			for i, existing := range token.ServiceIdentities {
+				if existing.ServiceName == svcIdent.ServiceName {
+					// The datacenters of the new identity replace the
+					// existing ones.
+					token.ServiceIdentities[i] = svcIdent
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				token.ServiceIdentities = append(token.ServiceIdentities, svcIdent)
+			}
+		}
+	} else {
+		token.ServiceIdentities = serviceIdentities
+	}
+
 	token, _, err = client.ACL().TokenUpdate(token, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to update token %s: %v", tokenID, err))
@@ -152,17 +218,36 @@ func (c *cmd) Help() string {
 	return flags.Usage(c.help, nil)
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-policy-id":   c.http.PredictPolicyIDs(),
+		"-policy-name": c.http.PredictPolicyNames(),
+	})
+}
+
 const synopsis = "Update an ACL Token"
 const help = `
 Usage: consul acl token update [options]
 
     This command will update a token. Some parts such as marking the token local
-    cannot be changed.
+    or its expiration time cannot be changed.
 
     Update a token description and take the policies from the existing token:
 
         $ consul acl token update -id abcd -description "replication" -merge-policies
 
+    Add a node identity to a token and keep the existing ones:
+
+        $ consul acl token update -id abcd -node-identity "web-1:dc1" -merge-node-identities
+
+    Add a service identity to a token and keep the existing ones:
+
+        $ consul acl token update -id abcd -service-identity "web" -merge-service-identities
+
       Update all editable fields of the token:
 
           $ consul acl token update -id abcd -description "replication" -policy-name "token-replication"
@@ -105,4 +105,81 @@ func TestTokenUpdateCommand(t *testing.T) {
 		assert.NoError(err)
 		assert.NotNil(token)
 	}
+	// update with node identities
+	{
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-id=" + token.AccessorID,
+			"-token=root",
+			"-node-identity=web-1:dc1",
+			"-description=test token",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+
+		args = []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-id=" + token.AccessorID,
+			"-token=root",
+			"-node-identity=web-2:dc1",
+			"-merge-node-identities",
+			"-description=test token",
+		}
+
+		code = cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+
+		token, _, err := client.ACL().TokenRead(
+			token.AccessorID,
+			&api.QueryOptions{Token: "root"},
+		)
+		assert.NoError(err)
+		assert.Equal([]*api.ACLNodeIdentity{
+			&api.ACLNodeIdentity{NodeName: "web-1", Datacenter: "dc1"},
+			&api.ACLNodeIdentity{NodeName: "web-2", Datacenter: "dc1"},
+		}, token.NodeIdentities)
+	}
+	// update with service identities
+	{
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-id=" + token.AccessorID,
+			"-token=root",
+			"-service-identity=web",
+			"-service-identity=db:dc1",
+			"-description=test token",
+		}
+
+		code := cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+
+		args = []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-id=" + token.AccessorID,
+			"-token=root",
+			"-service-identity=db:dc1,dc2",
+			"-service-identity=api",
+			"-merge-service-identities",
+			"-description=test token",
+		}
+
+		code = cmd.Run(args)
+		assert.Equal(code, 0)
+		assert.Empty(ui.ErrorWriter.String())
+
+		token, _, err := client.ACL().TokenRead(
+			token.AccessorID,
+			&api.QueryOptions{Token: "root"},
+		)
+		assert.NoError(err)
+		assert.Equal([]*api.ACLServiceIdentity{
+			&api.ACLServiceIdentity{ServiceName: "web"},
+			&api.ACLServiceIdentity{ServiceName: "db", Datacenters: []string{"dc1", "dc2"}},
+			&api.ACLServiceIdentity{ServiceName: "api"},
+		}, token.ServiceIdentities)
+	}
 }
@@ -63,11 +63,25 @@ type cmd struct {
 	logFilter         *logutils.LevelFilter
 	logOutput         io.Writer
 	logger            *log.Logger
+	configSources     func() (map[string][]string, error)
+
+	// flags for managing the Windows service
+	registerService   bool
+	unregisterService bool
+	serviceName       string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	config.AddFlags(c.flags, &c.flagArgs)
+	c.flags.BoolVar(&c.registerService, "register-windows-service", false,
+		"Registers the agent with the given options as a Windows service and exits. "+
+			"Paths in the options should be absolute since services are started "+
+			"in the system directory.")
+	c.flags.BoolVar(&c.unregisterService, "unregister-windows-service", false,
+		"Removes the Windows service registered with -register-windows-service and exits.")
+	c.flags.StringVar(&c.serviceName, "windows-service-name", "Consul",
+		"Name of the Windows service to register or unregister.")
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -95,6 +109,7 @@ func (c *cmd) readConfig() *config.RuntimeConfig {
 	for _, w := range b.Warnings {
 		c.UI.Warn(w)
 	}
+	c.configSources = b.FieldSources
 	return &cfg
 }
 
@@ -181,16 +196,31 @@ func (c *cmd) run(args []string) int {
 		return 1
 	}
 	c.flagArgs.Args = c.flags.Args()
+
+	if c.unregisterService {
+		if err := service_os.Uninstall(c.serviceName); err != nil {
+			c.UI.Error(fmt.Sprintf("Error removing Windows service: %s", err))
+			return 1
+		}
+		c.UI.Output(fmt.Sprintf("Removed Windows service %q", c.serviceName))
+		return 0
+	}
+
 	config := c.readConfig()
 	if config == nil {
 		return 1
 	}
 
+	if c.registerService {
+		return c.installService(args)
+	}
+
 	// Setup the log outputs
 	logConfig := &logger.Config{
 		LogLevel:          config.LogLevel,
 		EnableSyslog:      config.EnableSyslog,
 		SyslogFacility:    config.SyslogFacility,
+		EnableEventLog:    config.EnableWindowsEventLog,
 		LogFilePath:       config.LogFile,
 		LogRotateDuration: config.LogRotateDuration,
 		LogRotateBytes:    config.LogRotateBytes,
@@ -222,6 +252,7 @@ func (c *cmd) run(args []string) int {
 	agent.LogOutput = logOutput
 	agent.LogWriter = logWriter
 	agent.MemSink = memSink
+	agent.SetConfigSources(c.configSources)
 
 	if err := agent.Start(); err != nil {
 		c.UI.Error(fmt.Sprintf("Error starting agent: %s", err))
@@ -276,15 +307,19 @@ func (c *cmd) run(args []string) int {
 	signalCh := make(chan os.Signal, 10)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGPIPE)
 
+	// startConfig is the configuration the agent was started with. Settings
+	// which cannot be reloaded are compared against it.
+	startConfig := config
+
 	for {
 		var sig os.Signal
-		var reloadErrCh chan error
+		var reloadRespCh reloadResponseCh
 		select {
 		case s := <-signalCh:
 			sig = s
 		case ch := <-agent.ReloadCh():
 			sig = syscall.SIGHUP
-			reloadErrCh = ch
+			reloadRespCh = ch
 		case <-service_os.Shutdown_Channel():
 			sig = os.Interrupt
 		case <-c.shutdownCh:
@@ -304,7 +339,7 @@ func (c *cmd) run(args []string) int {
 		case syscall.SIGHUP:
 			c.logger.Println("[INFO] agent: Caught signal: ", sig)
 
-			conf, err := c.handleReload(agent, config)
+			conf, result, err := c.handleReload(agent, startConfig, config)
 			if conf != nil {
 				config = conf
 			}
@@ -312,8 +347,8 @@ func (c *cmd) run(args []string) int {
 				c.logger.Println("[ERR] agent: Reload config failed: ", err)
 			}
 			// Send result back if reload was called via HTTP
-			if reloadErrCh != nil {
-				reloadErrCh <- err
+			if reloadRespCh != nil {
+				reloadRespCh.send(result, err)
 			}
 
 		default:
@@ -351,14 +386,24 @@ func (c *cmd) run(args []string) int {
 	}
 }
 
-// handleReload is invoked when we should reload our configs, e.g. SIGHUP
-func (c *cmd) handleReload(agent *agent.Agent, cfg *config.RuntimeConfig) (*config.RuntimeConfig, error) {
+// reloadResponseCh is the channel on which the result of a reload
+// requested through the agent is sent back.
+type reloadResponseCh chan agent.ReloadResponse
+
+func (ch reloadResponseCh) send(result *agent.ReloadResult, err error) {
+	ch <- agent.ReloadResponse{Result: result, Err: err}
+}
+
+// handleReload is invoked when we should reload our configs, e.g. SIGHUP.
+// startCfg is the configuration the agent was started with and cfg the
+// one from the last reload.
+func (c *cmd) handleReload(a *agent.Agent, startCfg, cfg *config.RuntimeConfig) (*config.RuntimeConfig, *agent.ReloadResult, error) {
 	c.logger.Println("[INFO] agent: Reloading configuration...")
 	var errs error
 	newCfg := c.readConfig()
 	if newCfg == nil {
 		errs = multierror.Append(errs, fmt.Errorf("Failed to reload configs"))
-		return cfg, errs
+		return cfg, nil, errs
 	}
 
 	// Change the log level
@@ -374,12 +419,22 @@ func (c *cmd) handleReload(agent *agent.Agent, cfg *config.RuntimeConfig) (*conf
 		newCfg.LogLevel = cfg.LogLevel
 	}
 
-	if err := agent.ReloadConfig(newCfg); err != nil {
+	if err := a.ReloadConfig(newCfg); err != nil {
 		errs = multierror.Append(fmt.Errorf(
 			"Failed to reload configs: %v", err))
+	} else {
+		a.SetConfigSources(c.configSources)
 	}
 
-	return newCfg, errs
+	result := agent.NewReloadResult(startCfg, cfg, newCfg)
+	if len(result.Applied) > 0 {
+		c.logger.Printf("[INFO] agent: Reload applied changes to: %s", strings.Join(result.Applied, ", "))
+	}
+	if len(result.Ignored) > 0 {
+		c.logger.Printf("[WARN] agent: Changes to the following settings require a restart: %s", strings.Join(result.Ignored, ", "))
+	}
+
+	return newCfg, result, errs
 }
 
 func (c *cmd) Synopsis() string {
@@ -390,6 +445,57 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+// installService registers the agent as a Windows service which is started
+// with the same options as the current invocation.
+func (c *cmd) installService(args []string) int {
+	exe, err := os.Executable()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error determining the path to consul: %s", err))
+		return 1
+	}
+	svcArgs := append([]string{"agent"}, serviceArgs(args)...)
+	err = service_os.Install(c.serviceName, "Consul Agent",
+		"Consul agent for service discovery and configuration.", exe, svcArgs)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error registering Windows service: %s", err))
+		return 1
+	}
+	c.UI.Output(fmt.Sprintf("Registered Windows service %q", c.serviceName))
+	return 0
+}
+
+// serviceArgs returns the command line arguments for the Windows service
+// by removing the flags which only control the registration from args.
+func serviceArgs(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if !strings.HasPrefix(arg, "-") {
+			out = append(out, arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, hasValue = name[:j], true
+		}
+		switch name {
+		case "register-windows-service", "unregister-windows-service":
+			continue
+		case "windows-service-name":
+			if !hasValue {
+				i++
+			}
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
 const synopsis = "Runs a Consul agent"
 const help = `
 Usage: consul agent [options]
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -232,3 +233,37 @@ func TestBadDataDirPermissions(t *testing.T) {
 		t.Fatalf("expected permission denied error, got: %s", out)
 	}
 }
+
+func TestServiceArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, nil},
+		{
+			[]string{"-register-windows-service", "-data-dir", `C:\consul`},
+			[]string{"-data-dir", `C:\consul`},
+		},
+		{
+			[]string{"--register-windows-service=true", "-windows-service-name", "consul-dev", "-dev"},
+			[]string{"-dev"},
+		},
+		{
+			[]string{"-windows-service-name=consul-dev", "-config-dir", `C:\consul.d`, "-register-windows-service"},
+			[]string{"-config-dir", `C:\consul.d`},
+		},
+		{
+			[]string{"-register-windows-service", "--", "-register-windows-service"},
+			[]string{"--", "-register-windows-service"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			got := serviceArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -46,5 +46,9 @@ Usage: consul catalog <subcommand> [options] [args]
 
       $ consul catalog services
 
+  Deregister external nodes which weren't registered within three days:
+
+      $ consul catalog cleanup -older-than 72h -node-meta external=true
+
   For more examples, ask for subcommand help or view the documentation.
 `
@@ -0,0 +1,181 @@
+package cleanup
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	olderThan time.Duration
+	nodeMeta  map[string]string
+	dryRun    bool
+	batchSize int
+	batchWait time.Duration
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.DurationVar(&c.olderThan, "older-than", 0,
+		"Deregister nodes which weren't registered within this `duration`, "+
+			"e.g. 72h. This is required.")
+	c.flags.Var((*flags.FlagMapValue)(&c.nodeMeta), "node-meta", "Metadata to "+
+		"filter nodes with the given `key=value` pairs. This flag may be "+
+		"specified multiple times to filter on multiple sources of metadata.")
+	c.flags.BoolVar(&c.dryRun, "dry-run", false,
+		"List the nodes which would be deregistered without deregistering "+
+			"them. The default value is false.")
+	c.flags.IntVar(&c.batchSize, "batch-size", 64,
+		"Number of nodes to deregister before waiting for -batch-wait.")
+	c.flags.DurationVar(&c.batchWait, "batch-wait", time.Second,
+		"Time to wait between batches of deregistrations to limit the load "+
+			"on the servers.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+	c.now = time.Now
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if l := len(c.flags.Args()); l > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", l))
+		return 1
+	}
+	if c.olderThan <= 0 {
+		c.UI.Error("Must specify a positive -older-than duration")
+		return 1
+	}
+	if c.batchSize <= 0 {
+		c.UI.Error("Must specify a positive -batch-size")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	nodes, _, err := client.Catalog().NodeActivity(&api.QueryOptions{
+		NodeMeta: c.nodeMeta,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error querying node activity: %s", err))
+		return 1
+	}
+
+	// Nodes which are members of the cluster are kept up to date by their
+	// agents, even if nothing about them changes.
+	cutoff := c.now().Add(-c.olderThan)
+	var stale []*api.NodeActivity
+	for _, n := range nodes {
+		if !n.SerfMember && n.LastRegistered.Before(cutoff) {
+			stale = append(stale, n)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Node < stale[j].Node })
+
+	if len(stale) == 0 {
+		c.UI.Info(fmt.Sprintf("No nodes were registered more than %s ago", c.olderThan))
+		return 0
+	}
+
+	if c.dryRun {
+		c.UI.Info(printNodes(stale))
+		c.UI.Info(fmt.Sprintf("Dry run! Would deregister %d nodes", len(stale)))
+		return 0
+	}
+
+	for i, n := range stale {
+		if i > 0 && i%c.batchSize == 0 {
+			c.UI.Info(fmt.Sprintf("Deregistered %d of %d nodes", i, len(stale)))
+			time.Sleep(c.batchWait)
+		}
+
+		_, err := client.Catalog().Deregister(&api.CatalogDeregistration{
+			Node:       n.Node,
+			Datacenter: c.http.Datacenter(),
+		}, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error deregistering node %q: %s", n.Node, err))
+			return 1
+		}
+	}
+
+	c.UI.Info(fmt.Sprintf("Deregistered %d nodes", len(stale)))
+	return 0
+}
+
+// printNodes returns the nodes and the times of their last registrations as
+// a table.
+func printNodes(nodes []*api.NodeActivity) string {
+	result := make([]string, 0, len(nodes)+1)
+	result = append(result, "Node|Address|Last Registered")
+	for _, n := range nodes {
+		result = append(result, fmt.Sprintf("%s|%s|%s",
+			n.Node, n.Address, n.LastRegistered.Format(time.RFC3339)))
+	}
+	return columnize.SimpleFormat(result)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Deregister nodes which aren't kept up to date anymore"
+const help = `
+Usage: consul catalog cleanup [options] -older-than <duration>
+
+  Deregisters the nodes, with their services and checks, which are not
+  members of the cluster and weren't registered within the given duration.
+  This removes registrations of external nodes whose registering process is
+  gone. Any registration of the node, its services or its checks counts,
+  even if it didn't change anything.
+
+  The servers only track the registrations since they started, so a node
+  is only considered stale once the server answering the request ran for
+  longer than the given duration.
+
+  List the external nodes which weren't registered within three days:
+
+      $ consul catalog cleanup -older-than 72h -node-meta external=true -dry-run
+
+  Deregister them:
+
+      $ consul catalog cleanup -older-than 72h -node-meta external=true
+
+  For a full list of options and examples, please see the Consul
+  documentation.
+`
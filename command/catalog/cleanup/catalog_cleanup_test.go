@@ -0,0 +1,113 @@
+package cleanup
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogCleanupCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCatalogCleanupCommand_Validation(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"too many args": {
+			[]string{"-older-than=1h", "foo"},
+			"Too many arguments",
+		},
+		"no older-than": {
+			[]string{},
+			"Must specify a positive -older-than duration",
+		},
+		"invalid batch size": {
+			[]string{"-older-than=1h", "-batch-size=0"},
+			"Must specify a positive -batch-size",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			require.Equal(t, 1, c.Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+func TestCatalogCleanupCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	client := a.Client()
+
+	for _, node := range []string{"ext1", "ext2", "other"} {
+		meta := map[string]string{"external": "true"}
+		if node == "other" {
+			meta = nil
+		}
+		_, err := client.Catalog().Register(&api.CatalogRegistration{
+			Node:     node,
+			Address:  "127.0.0.2",
+			NodeMeta: meta,
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	run := func(args ...string) (string, int) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		// Pretend the nodes were registered two hours ago.
+		c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		code := c.Run(append([]string{"-http-addr=" + a.HTTPAddr()}, args...))
+		return ui.OutputWriter.String() + ui.ErrorWriter.String(), code
+	}
+
+	t.Run("nothing stale", func(t *testing.T) {
+		out, code := run("-older-than=3h")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "No nodes were registered more than 3h0m0s ago")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		out, code := run("-older-than=1h", "-node-meta=external=true", "-dry-run")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "ext1")
+		require.Contains(t, out, "ext2")
+		require.NotContains(t, out, "other")
+		require.NotContains(t, out, a.Config.NodeName)
+		require.Contains(t, out, "Dry run! Would deregister 2 nodes")
+
+		nodes, _, err := client.Catalog().Nodes(nil)
+		require.NoError(t, err)
+		require.Len(t, nodes, 4)
+	})
+
+	t.Run("deregister", func(t *testing.T) {
+		out, code := run("-older-than=1h", "-node-meta=external=true", "-batch-size=1", "-batch-wait=1ms")
+		require.Equal(t, 0, code, out)
+		require.Contains(t, out, "Deregistered 1 of 2 nodes")
+		require.Contains(t, out, "Deregistered 2 nodes")
+
+		nodes, _, err := client.Catalog().Nodes(nil)
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+		for _, n := range nodes {
+			require.NotContains(t, []string{"ext1", "ext2"}, n.Node)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 )
 
@@ -15,10 +16,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 }
 
 func (c *cmd) init() {
@@ -26,6 +28,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -39,6 +43,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -52,8 +61,14 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	for _, dc := range dcs {
-		c.UI.Info(dc)
+	err = c.output.Print(c.UI, dcs, dcs, func() {
+		for _, dc := range dcs {
+			c.UI.Info(dc)
+		}
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 
 	return 0
@@ -8,7 +8,9 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 	"github.com/ryanuber/columnize"
 )
 
@@ -19,10 +21,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	// flags
 	detailed bool
@@ -48,6 +51,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -61,6 +66,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -104,25 +114,30 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	// Handle the edge case where there are no nodes that match the query.
-	if len(nodes) == 0 {
+	if len(nodes) == 0 && c.output.Table() {
 		c.UI.Error("No nodes match the given query - try expanding your search.")
 		return 0
 	}
 
-	output, err := printNodes(nodes, c.detailed)
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Node)
+	}
+
+	err = c.output.Print(c.UI, nodes, names, func() {
+		c.UI.Info(printNodes(nodes, c.detailed))
+	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error printing nodes: %s", err))
 		return 1
 	}
 
-	c.UI.Info(output)
-
 	return 0
 }
 
 // printNodes accepts a list of nodes and prints information in a tabular
 // format about the nodes.
-func printNodes(nodes []*api.Node, detailed bool) (string, error) {
+func printNodes(nodes []*api.Node, detailed bool) string {
 	var result []string
 	if detailed {
 		result = detailedNodes(nodes)
@@ -130,7 +145,7 @@ func printNodes(nodes []*api.Node, detailed bool) (string, error) {
 		result = simpleNodes(nodes)
 	}
 
-	return columnize.SimpleFormat(result), nil
+	return columnize.SimpleFormat(result)
 }
 
 func detailedNodes(nodes []*api.Node) []string {
@@ -190,6 +205,17 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-near":    c.http.PredictNodes(),
+		"-service": c.http.PredictServices(),
+	})
+}
+
 const synopsis = "Lists all nodes in the given datacenter"
 const help = `
 Usage: consul catalog nodes [options]
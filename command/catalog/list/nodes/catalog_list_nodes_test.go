@@ -1,12 +1,15 @@
 package nodes
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func TestCatalogListNodesCommand_noTabs(t *testing.T) {
@@ -145,4 +148,74 @@ func TestCatalogListNodesCommand(t *testing.T) {
 			t.Errorf("expected %q to contain %q", output, expected)
 		}
 	})
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-format=json",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad exit code %d: %s", code, ui.ErrorWriter.String())
+		}
+		var nodes []*api.Node
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &nodes); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(nodes) != 1 || nodes[0].Node != a.Config.NodeName {
+			t.Errorf("bad: %#v", nodes)
+		}
+	})
+
+	t.Run("json_no_match", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-node-meta", "foo=bar",
+			"-format=json",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad exit code %d: %s", code, ui.ErrorWriter.String())
+		}
+		if output := strings.TrimSpace(ui.OutputWriter.String()); output != "[]" {
+			t.Errorf("bad: %q", output)
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-quiet",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad exit code %d: %s", code, ui.ErrorWriter.String())
+		}
+		if output, expected := ui.OutputWriter.String(), a.Config.NodeName+"\n"; output != expected {
+			t.Errorf("expected %q to be %q", output, expected)
+		}
+	})
+
+	t.Run("autocomplete", func(t *testing.T) {
+		c := New(cli.NewMockUi())
+		if err := c.flags.Parse([]string{"-http-addr=" + a.HTTPAddr()}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		predictors := c.AutocompleteFlags()
+		if got := predictors["-service"].Predict(complete.Args{}); len(got) != 1 || got[0] != "consul" {
+			t.Errorf("bad: %v", got)
+		}
+		if got := predictors["-near"].Predict(complete.Args{}); len(got) != 1 || got[0] != a.Config.NodeName {
+			t.Errorf("bad: %v", got)
+		}
+		if _, ok := predictors["-detailed"]; !ok {
+			t.Errorf("missing -detailed in %v", predictors)
+		}
+	})
 }
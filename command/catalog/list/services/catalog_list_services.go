@@ -10,7 +10,9 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -20,10 +22,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 
 	// flags
 	node     string
@@ -46,6 +49,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -59,6 +64,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -92,7 +102,7 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	// Handle the edge case where there are no services that match the query.
-	if len(services) == 0 {
+	if len(services) == 0 && c.output.Table() {
 		c.UI.Error("No services match the given query - try expanding your search.")
 		return 0
 	}
@@ -101,14 +111,25 @@ func (c *cmd) Run(args []string) int {
 	order := make([]string, 0, len(services))
 	for k := range services {
 		order = append(order, k)
+		sort.Strings(services[k])
 	}
 	sort.Strings(order)
 
+	if !c.output.Table() {
+		if services == nil {
+			services = make(map[string][]string)
+		}
+		if err := c.output.Print(c.UI, services, order, nil); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		return 0
+	}
+
 	if c.tags {
 		var b bytes.Buffer
 		tw := tabwriter.NewWriter(&b, 0, 2, 6, ' ', 0)
 		for _, s := range order {
-			sort.Strings(services[s])
 			fmt.Fprintf(tw, "%s\t%s\n", s, strings.Join(services[s], ","))
 		}
 		if err := tw.Flush(); err != nil {
@@ -133,6 +154,16 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-node": c.http.PredictNodes(),
+	})
+}
+
 const synopsis = "Lists all registered services in a datacenter"
 const help = `
 Usage: consul catalog services [options]
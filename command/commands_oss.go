@@ -3,6 +3,12 @@ package command
 import (
 	"github.com/hashicorp/consul/command/acl"
 	aclagent "github.com/hashicorp/consul/command/acl/agenttokens"
+	aclbrule "github.com/hashicorp/consul/command/acl/bindingrule"
+	aclbrcreate "github.com/hashicorp/consul/command/acl/bindingrule/create"
+	aclbrdelete "github.com/hashicorp/consul/command/acl/bindingrule/delete"
+	aclbrlist "github.com/hashicorp/consul/command/acl/bindingrule/list"
+	aclbrread "github.com/hashicorp/consul/command/acl/bindingrule/read"
+	aclbrupdate "github.com/hashicorp/consul/command/acl/bindingrule/update"
 	aclbootstrap "github.com/hashicorp/consul/command/acl/bootstrap"
 	aclpolicy "github.com/hashicorp/consul/command/acl/policy"
 	aclpcreate "github.com/hashicorp/consul/command/acl/policy/create"
@@ -10,6 +16,7 @@ import (
 	aclplist "github.com/hashicorp/consul/command/acl/policy/list"
 	aclpread "github.com/hashicorp/consul/command/acl/policy/read"
 	aclpupdate "github.com/hashicorp/consul/command/acl/policy/update"
+	aclpvalidate "github.com/hashicorp/consul/command/acl/policy/validate"
 	aclrules "github.com/hashicorp/consul/command/acl/rules"
 	acltoken "github.com/hashicorp/consul/command/acl/token"
 	acltcreate "github.com/hashicorp/consul/command/acl/token/create"
@@ -19,15 +26,22 @@ import (
 	acltupdate "github.com/hashicorp/consul/command/acl/token/update"
 	"github.com/hashicorp/consul/command/agent"
 	"github.com/hashicorp/consul/command/catalog"
+	catcleanup "github.com/hashicorp/consul/command/catalog/cleanup"
 	catlistdc "github.com/hashicorp/consul/command/catalog/list/dc"
 	catlistnodes "github.com/hashicorp/consul/command/catalog/list/nodes"
 	catlistsvc "github.com/hashicorp/consul/command/catalog/list/services"
+	"github.com/hashicorp/consul/command/completion"
+	"github.com/hashicorp/consul/command/config"
+	configrender "github.com/hashicorp/consul/command/config/render"
 	"github.com/hashicorp/consul/command/connect"
 	"github.com/hashicorp/consul/command/connect/ca"
 	caget "github.com/hashicorp/consul/command/connect/ca/get"
+	carotate "github.com/hashicorp/consul/command/connect/ca/rotate"
 	caset "github.com/hashicorp/consul/command/connect/ca/set"
 	"github.com/hashicorp/consul/command/connect/envoy"
 	"github.com/hashicorp/consul/command/connect/proxy"
+	"github.com/hashicorp/consul/command/connect/proxyconfig"
+	"github.com/hashicorp/consul/command/connect/redirecttraffic"
 	"github.com/hashicorp/consul/command/debug"
 	"github.com/hashicorp/consul/command/event"
 	"github.com/hashicorp/consul/command/exec"
@@ -47,7 +61,9 @@ import (
 	kvexp "github.com/hashicorp/consul/command/kv/exp"
 	kvget "github.com/hashicorp/consul/command/kv/get"
 	kvimp "github.com/hashicorp/consul/command/kv/imp"
+	kvpurge "github.com/hashicorp/consul/command/kv/purge"
 	kvput "github.com/hashicorp/consul/command/kv/put"
+	kvrestore "github.com/hashicorp/consul/command/kv/restore"
 	"github.com/hashicorp/consul/command/leave"
 	"github.com/hashicorp/consul/command/lock"
 	"github.com/hashicorp/consul/command/maint"
@@ -59,8 +75,10 @@ import (
 	operautoset "github.com/hashicorp/consul/command/operator/autopilot/set"
 	operraft "github.com/hashicorp/consul/command/operator/raft"
 	operraftlist "github.com/hashicorp/consul/command/operator/raft/listpeers"
+	operraftrecover "github.com/hashicorp/consul/command/operator/raft/recover"
 	operraftremove "github.com/hashicorp/consul/command/operator/raft/removepeer"
 	"github.com/hashicorp/consul/command/reload"
+	"github.com/hashicorp/consul/command/render"
 	"github.com/hashicorp/consul/command/rtt"
 	"github.com/hashicorp/consul/command/services"
 	svcsderegister "github.com/hashicorp/consul/command/services/deregister"
@@ -69,6 +87,13 @@ import (
 	snapinspect "github.com/hashicorp/consul/command/snapshot/inspect"
 	snaprestore "github.com/hashicorp/consul/command/snapshot/restore"
 	snapsave "github.com/hashicorp/consul/command/snapshot/save"
+	"github.com/hashicorp/consul/command/tls"
+	tlsca "github.com/hashicorp/consul/command/tls/ca"
+	tlscacreate "github.com/hashicorp/consul/command/tls/ca/create"
+	tlscert "github.com/hashicorp/consul/command/tls/cert"
+	tlscertcreate "github.com/hashicorp/consul/command/tls/cert/create"
+	"github.com/hashicorp/consul/command/txn"
+	txnapply "github.com/hashicorp/consul/command/txn/apply"
 	"github.com/hashicorp/consul/command/validate"
 	"github.com/hashicorp/consul/command/version"
 	"github.com/hashicorp/consul/command/watch"
@@ -91,6 +116,7 @@ func init() {
 	Register("acl policy read", func(ui cli.Ui) (cli.Command, error) { return aclpread.New(ui), nil })
 	Register("acl policy update", func(ui cli.Ui) (cli.Command, error) { return aclpupdate.New(ui), nil })
 	Register("acl policy delete", func(ui cli.Ui) (cli.Command, error) { return aclpdelete.New(ui), nil })
+	Register("acl policy validate", func(ui cli.Ui) (cli.Command, error) { return aclpvalidate.New(ui), nil })
 	Register("acl translate-rules", func(ui cli.Ui) (cli.Command, error) { return aclrules.New(ui), nil })
 	Register("acl set-agent-token", func(ui cli.Ui) (cli.Command, error) { return aclagent.New(ui), nil })
 	Register("acl token", func(cli.Ui) (cli.Command, error) { return acltoken.New(), nil })
@@ -99,19 +125,32 @@ func init() {
 	Register("acl token read", func(ui cli.Ui) (cli.Command, error) { return acltread.New(ui), nil })
 	Register("acl token update", func(ui cli.Ui) (cli.Command, error) { return acltupdate.New(ui), nil })
 	Register("acl token delete", func(ui cli.Ui) (cli.Command, error) { return acltdelete.New(ui), nil })
+	Register("acl binding-rule", func(cli.Ui) (cli.Command, error) { return aclbrule.New(), nil })
+	Register("acl binding-rule create", func(ui cli.Ui) (cli.Command, error) { return aclbrcreate.New(ui), nil })
+	Register("acl binding-rule list", func(ui cli.Ui) (cli.Command, error) { return aclbrlist.New(ui), nil })
+	Register("acl binding-rule read", func(ui cli.Ui) (cli.Command, error) { return aclbrread.New(ui), nil })
+	Register("acl binding-rule update", func(ui cli.Ui) (cli.Command, error) { return aclbrupdate.New(ui), nil })
+	Register("acl binding-rule delete", func(ui cli.Ui) (cli.Command, error) { return aclbrdelete.New(ui), nil })
 	Register("agent", func(ui cli.Ui) (cli.Command, error) {
 		return agent.New(ui, rev, ver, verPre, verHuman, make(chan struct{})), nil
 	})
 	Register("catalog", func(cli.Ui) (cli.Command, error) { return catalog.New(), nil })
+	Register("catalog cleanup", func(ui cli.Ui) (cli.Command, error) { return catcleanup.New(ui), nil })
 	Register("catalog datacenters", func(ui cli.Ui) (cli.Command, error) { return catlistdc.New(ui), nil })
 	Register("catalog nodes", func(ui cli.Ui) (cli.Command, error) { return catlistnodes.New(ui), nil })
 	Register("catalog services", func(ui cli.Ui) (cli.Command, error) { return catlistsvc.New(ui), nil })
+	Register("completion", func(ui cli.Ui) (cli.Command, error) { return completion.New(ui), nil })
+	Register("config", func(cli.Ui) (cli.Command, error) { return config.New(), nil })
+	Register("config render", func(ui cli.Ui) (cli.Command, error) { return configrender.New(ui), nil })
 	Register("connect", func(ui cli.Ui) (cli.Command, error) { return connect.New(), nil })
 	Register("connect ca", func(ui cli.Ui) (cli.Command, error) { return ca.New(), nil })
 	Register("connect ca get-config", func(ui cli.Ui) (cli.Command, error) { return caget.New(ui), nil })
+	Register("connect ca rotate", func(ui cli.Ui) (cli.Command, error) { return carotate.New(ui), nil })
 	Register("connect ca set-config", func(ui cli.Ui) (cli.Command, error) { return caset.New(ui), nil })
 	Register("connect proxy", func(ui cli.Ui) (cli.Command, error) { return proxy.New(ui, MakeShutdownCh()), nil })
 	Register("connect envoy", func(ui cli.Ui) (cli.Command, error) { return envoy.New(ui), nil })
+	Register("connect proxy-config", func(ui cli.Ui) (cli.Command, error) { return proxyconfig.New(ui), nil })
+	Register("connect redirect-traffic", func(ui cli.Ui) (cli.Command, error) { return redirecttraffic.New(ui), nil })
 	Register("debug", func(ui cli.Ui) (cli.Command, error) { return debug.New(ui, MakeShutdownCh()), nil })
 	Register("event", func(ui cli.Ui) (cli.Command, error) { return event.New(ui), nil })
 	Register("exec", func(ui cli.Ui) (cli.Command, error) { return exec.New(ui, MakeShutdownCh()), nil })
@@ -131,7 +170,9 @@ func init() {
 	Register("kv export", func(ui cli.Ui) (cli.Command, error) { return kvexp.New(ui), nil })
 	Register("kv get", func(ui cli.Ui) (cli.Command, error) { return kvget.New(ui), nil })
 	Register("kv import", func(ui cli.Ui) (cli.Command, error) { return kvimp.New(ui), nil })
+	Register("kv purge", func(ui cli.Ui) (cli.Command, error) { return kvpurge.New(ui), nil })
 	Register("kv put", func(ui cli.Ui) (cli.Command, error) { return kvput.New(ui), nil })
+	Register("kv restore", func(ui cli.Ui) (cli.Command, error) { return kvrestore.New(ui), nil })
 	Register("leave", func(ui cli.Ui) (cli.Command, error) { return leave.New(ui), nil })
 	Register("lock", func(ui cli.Ui) (cli.Command, error) { return lock.New(ui), nil })
 	Register("maint", func(ui cli.Ui) (cli.Command, error) { return maint.New(ui), nil })
@@ -143,8 +184,10 @@ func init() {
 	Register("operator autopilot set-config", func(ui cli.Ui) (cli.Command, error) { return operautoset.New(ui), nil })
 	Register("operator raft", func(cli.Ui) (cli.Command, error) { return operraft.New(), nil })
 	Register("operator raft list-peers", func(ui cli.Ui) (cli.Command, error) { return operraftlist.New(ui), nil })
+	Register("operator raft recover", func(ui cli.Ui) (cli.Command, error) { return operraftrecover.New(ui), nil })
 	Register("operator raft remove-peer", func(ui cli.Ui) (cli.Command, error) { return operraftremove.New(ui), nil })
 	Register("reload", func(ui cli.Ui) (cli.Command, error) { return reload.New(ui), nil })
+	Register("render", func(ui cli.Ui) (cli.Command, error) { return render.New(ui, MakeShutdownCh()), nil })
 	Register("rtt", func(ui cli.Ui) (cli.Command, error) { return rtt.New(ui), nil })
 	Register("services", func(cli.Ui) (cli.Command, error) { return services.New(), nil })
 	Register("services register", func(ui cli.Ui) (cli.Command, error) { return svcsregister.New(ui), nil })
@@ -153,6 +196,13 @@ func init() {
 	Register("snapshot inspect", func(ui cli.Ui) (cli.Command, error) { return snapinspect.New(ui), nil })
 	Register("snapshot restore", func(ui cli.Ui) (cli.Command, error) { return snaprestore.New(ui), nil })
 	Register("snapshot save", func(ui cli.Ui) (cli.Command, error) { return snapsave.New(ui), nil })
+	Register("tls", func(ui cli.Ui) (cli.Command, error) { return tls.New(), nil })
+	Register("tls ca", func(ui cli.Ui) (cli.Command, error) { return tlsca.New(), nil })
+	Register("tls ca create", func(ui cli.Ui) (cli.Command, error) { return tlscacreate.New(ui), nil })
+	Register("tls cert", func(ui cli.Ui) (cli.Command, error) { return tlscert.New(), nil })
+	Register("tls cert create", func(ui cli.Ui) (cli.Command, error) { return tlscertcreate.New(ui), nil })
+	Register("txn", func(cli.Ui) (cli.Command, error) { return txn.New(), nil })
+	Register("txn apply", func(ui cli.Ui) (cli.Command, error) { return txnapply.New(ui), nil })
 	Register("validate", func(ui cli.Ui) (cli.Command, error) { return validate.New(ui), nil })
 	Register("version", func(ui cli.Ui) (cli.Command, error) { return version.New(ui, verHuman), nil })
 	Register("watch", func(ui cli.Ui) (cli.Command, error) { return watch.New(ui, MakeShutdownCh()), nil })
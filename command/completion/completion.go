@@ -0,0 +1,132 @@
+package completion
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	help  string
+
+	// executable returns the path of the consul binary which is called by
+	// the shell to complete the command line. It is replaced in tests.
+	executable func() (string, error)
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.help = flags.Usage(help, c.flags)
+	c.executable = executable
+}
+
+// scripts are the completion scripts for the supported shells. The shell
+// calls the consul binary with the command line in the COMP_LINE
+// environment variable and the binary prints the suggestions, so the
+// scripts don't have to change when commands are added.
+var scripts = map[string]string{
+	"bash": `complete -o default -C %[1]s consul
+`,
+	"zsh": `autoload -U +X bashcompinit && bashcompinit
+complete -o default -C %[1]s consul
+`,
+	"fish": `function __consul_complete
+    set -lx COMP_LINE (commandline -cp)
+    test -z (commandline -ct)
+    and set COMP_LINE "$COMP_LINE "
+    %[1]s
+end
+complete -f -c consul -a "(__consul_complete)"
+`,
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = c.flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(fmt.Sprintf("Expected exactly one argument, the shell, got %d", len(args)))
+		return 1
+	}
+
+	script, ok := scripts[args[0]]
+	if !ok {
+		c.UI.Error(fmt.Sprintf("Unsupported shell %q, must be one of bash, zsh or fish", args[0]))
+		return 1
+	}
+
+	bin, err := c.executable()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error determining the path of the consul binary: %s", err))
+		return 1
+	}
+
+	c.UI.Output(strings.TrimSuffix(fmt.Sprintf(script, quote(bin)), "\n"))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("bash", "zsh", "fish")
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, nil)
+}
+
+// executable returns the absolute path of the running binary.
+func executable() (string, error) {
+	bin, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(bin)
+}
+
+// quote quotes s as a single word for the supported shells.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+const synopsis = "Generates shell completion scripts"
+const help = `
+Usage: consul completion <bash|zsh|fish>
+
+  Prints a script which enables the completion of consul commands, flags and
+  arguments in the given shell. Besides commands and flags, the names of
+  services, nodes, ACL policies and KV keys are suggested when the agent at
+  CONSUL_HTTP_ADDR can be reached.
+
+  To enable completion in the current bash or zsh shell:
+
+      $ source <(consul completion bash)
+
+  To enable completion for every new shell, add the script to the shell's
+  completion directory or startup file, for example:
+
+      $ consul completion bash > /etc/bash_completion.d/consul
+      $ consul completion fish > ~/.config/fish/completions/consul.fish
+`
@@ -0,0 +1,70 @@
+package completion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestCompletionCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestCompletionCommand_Validation(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no shell": {
+			nil,
+			"Expected exactly one argument",
+		},
+		"too many shells": {
+			[]string{"bash", "zsh"},
+			"Expected exactly one argument",
+		},
+		"unsupported shell": {
+			[]string{"tcsh"},
+			`Unsupported shell "tcsh"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			if code := c.Run(tc.args); code != 1 {
+				t.Fatalf("bad: %d", code)
+			}
+			if got := ui.ErrorWriter.String(); !strings.Contains(got, tc.output) {
+				t.Fatalf("expected %q to contain %q", got, tc.output)
+			}
+		})
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"bash": "complete -o default -C '/opt/it'\"'\"'s/consul' consul\n",
+		"zsh":  "autoload -U +X bashcompinit && bashcompinit\ncomplete -o default -C '/opt/it'\"'\"'s/consul' consul\n",
+		"fish": "    '/opt/it'\"'\"'s/consul'\nend\ncomplete -f -c consul -a \"(__consul_complete)\"\n",
+	}
+	for shell, expected := range cases {
+		t.Run(shell, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			c.executable = func() (string, error) { return "/opt/it's/consul", nil }
+			if code := c.Run([]string{shell}); code != 0 {
+				t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+			}
+			if got := ui.OutputWriter.String(); !strings.HasSuffix(got, expected) {
+				t.Fatalf("expected %q to end with %q", got, expected)
+			}
+		})
+	}
+}
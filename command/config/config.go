@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+const synopsis = "Interact with the agent configuration"
+const help = `
+Usage: consul config <subcommand> [options] [args]
+
+  This command has subcommands for inspecting the configuration of a Consul
+  agent without starting it.
+
+  Print the merged runtime configuration of a config directory:
+
+      $ consul config render -config-dir=/etc/consul.d
+
+  For more examples, ask for subcommand help or view the documentation.
+`
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/agent/config"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI       cli.Ui
+	flags    *flag.FlagSet
+	flagArgs config.Flags
+	help     string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	config.AddFlags(c.flags, &c.flagArgs)
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error("Too many arguments (expected 0)")
+		return 1
+	}
+
+	b, err := config.NewBuilder(c.flagArgs)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration: %s", err))
+		return 1
+	}
+	rt, err := b.BuildAndValidate()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading configuration: %s", err))
+		return 1
+	}
+	for _, w := range b.Warnings {
+		c.UI.Warn(w)
+	}
+
+	out, err := json.MarshalIndent(rt.Sanitized(), "", "    ")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error encoding configuration: %s", err))
+		return 1
+	}
+	c.UI.Output(string(out))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Print the merged runtime configuration"
+const help = `
+Usage: consul config render [options]
+
+  Loads the configuration exactly as "consul agent" would when given the same
+  options, including the built-in defaults, and prints the resulting runtime
+  configuration as JSON. The agent is not started.
+
+  This is useful to answer why a setting does not have the expected value
+  when the configuration is spread across multiple files and flags. Fields
+  which may contain secrets like tokens and keys are replaced with "hidden".
+
+  Render the configuration of a config directory:
+
+      $ consul config render -config-dir=/etc/consul.d
+
+  Render a server configuration including command line overrides:
+
+      $ consul config render -config-dir=/etc/consul.d -server -bootstrap-expect=3
+`
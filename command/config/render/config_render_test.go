@@ -0,0 +1,78 @@
+package render
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRenderCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestConfigRenderCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	code := c.Run([]string{"foo"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "Too many arguments")
+}
+
+func TestConfigRenderCommand(t *testing.T) {
+	t.Parallel()
+	td := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(td)
+
+	err := ioutil.WriteFile(filepath.Join(td, "a.json"), []byte(`{
+		"bind_addr": "10.0.0.1",
+		"data_dir": "`+td+`",
+		"node_name": "foo",
+		"acl": {"tokens": {"agent": "secret-agent-token"}}
+	}`), 0644)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(td, "b.hcl"), []byte(`log_level = "debug"`), 0644)
+	require.NoError(t, err)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-config-dir", td, "-datacenter", "dc9"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(ui.OutputWriter.Bytes(), &out))
+	require.Equal(t, "foo", out["NodeName"])
+	require.Equal(t, "debug", out["LogLevel"])
+	require.Equal(t, "dc9", out["Datacenter"])
+	require.Equal(t, "hidden", out["ACLAgentToken"])
+
+	// defaults are applied
+	require.Equal(t, float64(8500), out["HTTPPort"])
+	require.NotContains(t, ui.OutputWriter.String(), "secret-agent-token")
+}
+
+func TestConfigRenderCommand_InvalidConfig(t *testing.T) {
+	t.Parallel()
+	td := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(td)
+
+	err := ioutil.WriteFile(filepath.Join(td, "a.json"), []byte(`{"bind_addr": "10.0.0.1", "data_dir": "`+td+`", "bogus": 1}`), 0644)
+	require.NoError(t, err)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-config-dir", td})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "bogus")
+}
@@ -41,5 +41,9 @@ Usage: consul connect ca <subcommand> [options] [args]
 
       $ consul connect ca set-config -config-file ca.json
 
+  Rotate the root and wait for all leaf certificates to be replaced:
+
+      $ consul connect ca rotate -provider vault -config vault.json
+
   For more examples, ask for subcommand help or view the documentation.
 `
@@ -0,0 +1,214 @@
+package rotate
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	provider     string
+	configFile   string
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.provider, "provider", "",
+		"The name of the CA provider to rotate to, such as \"consul\" or \"vault\".")
+	c.flags.StringVar(&c.configFile, "config", "",
+		"The path to a JSON file with the configuration of the provider. "+
+			"If omitted, the provider is configured with its defaults.")
+	c.flags.DurationVar(&c.timeout, "timeout", 10*time.Minute,
+		"How long to wait for all leaf certificates to be signed by the new root.")
+	c.flags.DurationVar(&c.pollInterval, "poll-interval", 5*time.Second,
+		"How often to check the progress of the leaf certificate rotation.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error("Should have no non-flag arguments.")
+		return 1
+	}
+	if c.provider == "" {
+		c.UI.Error("The -provider flag is required")
+		return 1
+	}
+	if c.pollInterval <= 0 {
+		c.UI.Error("The -poll-interval flag must be positive")
+		return 1
+	}
+
+	providerConfig := map[string]interface{}{}
+	if c.configFile != "" {
+		data, err := ioutil.ReadFile(c.configFile)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading config file: %s", err))
+			return 1
+		}
+		if err := json.Unmarshal(data, &providerConfig); err != nil {
+			c.UI.Error(fmt.Sprintf("Error parsing config file: %s", err))
+			return 1
+		}
+	}
+
+	// Set up a client.
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	connect := client.Connect()
+
+	oldRoots, _, err := connect.CARoots(nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading CA roots: %s", err))
+		return 1
+	}
+
+	// Setting the configuration makes the leader cross-sign the new root with
+	// the old one, so that leaves of both roots are trusted while the agents
+	// replace their certificates.
+	config := &api.CAConfig{
+		Provider: c.provider,
+		Config:   providerConfig,
+	}
+	if _, err := connect.CASetConfig(config, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting CA configuration: %s", err))
+		return 1
+	}
+
+	roots, _, err := connect.CARoots(nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading CA roots: %s", err))
+		return 1
+	}
+	if roots.ActiveRootID == oldRoots.ActiveRootID {
+		c.UI.Output("The configuration was updated but the CA root didn't change, nothing to rotate.")
+		return 0
+	}
+	c.UI.Output(fmt.Sprintf("==> Rotated CA root from %s to %s", oldRoots.ActiveRootID, roots.ActiveRootID))
+
+	if oldRoots.ActiveRootID != "" {
+		crossSigned := false
+		for _, root := range roots.Roots {
+			if root.ID == roots.ActiveRootID && len(root.IntermediateCerts) > 0 {
+				crossSigned = true
+			}
+		}
+		if crossSigned {
+			c.UI.Output("==> New root is cross-signed by the old root")
+		} else {
+			c.UI.Warn("Warning: the new root isn't cross-signed by the old root. Services " +
+				"with leaf certificates of different roots may fail to connect until " +
+				"all leaf certificates are rotated.")
+		}
+	}
+
+	c.UI.Output("==> Waiting for leaf certificates to be signed by the new root...")
+	deadline := time.Now().Add(c.timeout)
+	lastBlocked := -1
+	for {
+		leaves, _, err := connect.CALeaves(nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading leaf certificates: %s", err))
+			return 1
+		}
+
+		var blocked []*api.CALeaf
+		for _, leaf := range leaves.Leaves {
+			if leaf.RootID != roots.ActiveRootID {
+				blocked = append(blocked, leaf)
+			}
+		}
+		if len(blocked) == 0 {
+			c.UI.Output(fmt.Sprintf("==> All %d leaf certificates are signed by the new root", len(leaves.Leaves)))
+			return 0
+		}
+		if len(blocked) != lastBlocked {
+			c.UI.Output(fmt.Sprintf("    %d of %d leaf certificates are signed by an old root",
+				len(blocked), len(leaves.Leaves)))
+			lastBlocked = len(blocked)
+		}
+
+		if !time.Now().Add(c.pollInterval).Before(deadline) {
+			c.UI.Error(fmt.Sprintf("Timed out waiting for %d leaf certificates to be rotated. "+
+				"The following agents still use certificates of an old root:", len(blocked)))
+			c.UI.Output(formatLeaves(blocked))
+			return 1
+		}
+		time.Sleep(c.pollInterval)
+	}
+}
+
+// formatLeaves formats the leaf certificates as a table.
+func formatLeaves(leaves []*api.CALeaf) string {
+	result := []string{"Node|Service|Root ID|Valid Before"}
+	for _, leaf := range leaves {
+		node := leaf.Node
+		if node == "" {
+			node = "(unknown)"
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s",
+			node, leaf.Service, leaf.RootID, leaf.ValidBefore.Format(time.RFC3339)))
+	}
+	return columnize.SimpleFormat(result)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Rotate the Connect CA root and wait for all leaf certificates"
+const help = `
+Usage: consul connect ca rotate [options] -provider <name>
+
+  Rotates the Connect Certificate Authority (CA) root by switching to the given
+  provider configuration. The leader cross-signs the new root with the old one
+  so that services keep trusting each other while the agents request new leaf
+  certificates. The command then waits until every leaf certificate known to
+  the leader is signed by the new root, and lists the agents which still use
+  a certificate of an old root if that doesn't happen within -timeout.
+
+  Migrate from the built-in CA to Vault:
+
+      $ consul connect ca rotate -provider vault -config vault.json
+
+  Only certificates signed since the current leader was elected are tracked.
+`
@@ -0,0 +1,149 @@
+package rotate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectCARotateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestConnectCARotateCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no provider": {
+			[]string{},
+			"-provider flag is required",
+		},
+		"bad poll interval": {
+			[]string{"-provider=consul", "-poll-interval=0s"},
+			"-poll-interval flag must be positive",
+		},
+		"missing config": {
+			[]string{"-provider=consul", "-config=missing.json"},
+			"Error reading config file",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			require.Equal(t, 1, c.Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+// testConfigFile writes the configuration of the built-in provider with a new
+// private key, which makes it generate a new root. It returns the path of the
+// file along with a function to remove it.
+func testConfigFile(t *testing.T) (string, func()) {
+	_, key, err := connect.GeneratePrivateKey()
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]interface{}{
+		"PrivateKey":     key,
+		"RootCert":       "",
+		"RotationPeriod": "24h",
+	})
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "consul")
+	require.NoError(t, err)
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name(), func() { os.Remove(f.Name()) }
+}
+
+func TestConnectCARotateCommand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	req := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var oldRoots structs.IndexedCARoots
+	require.NoError(a.RPC("ConnectCA.Roots", &req, &oldRoots))
+
+	configFile, remove := testConfigFile(t)
+	defer remove()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-provider=consul",
+		"-config=" + configFile,
+	}
+	code := c.Run(args)
+	require.Equal(0, code, ui.ErrorWriter.String())
+
+	var roots structs.IndexedCARoots
+	require.NoError(a.RPC("ConnectCA.Roots", &req, &roots))
+	require.NotEqual(oldRoots.ActiveRootID, roots.ActiveRootID)
+
+	output := ui.OutputWriter.String()
+	require.Contains(output, "New root is cross-signed by the old root")
+	require.Contains(output, "All 0 leaf certificates are signed by the new root")
+}
+
+func TestConnectCARotateCommand_Blocked(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	// Sign a leaf certificate of the current root which no agent is going to
+	// replace.
+	csr, _ := connect.TestCSR(t, connect.TestSpiffeIDService(t, "web"))
+	sign := structs.CASignRequest{
+		Datacenter: "dc1",
+		CSR:        csr,
+		Node:       "node1",
+	}
+	var cert structs.IssuedCert
+	require.NoError(a.RPC("ConnectCA.Sign", &sign, &cert))
+
+	configFile, remove := testConfigFile(t)
+	defer remove()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-provider=consul",
+		"-config=" + configFile,
+		"-timeout=200ms",
+		"-poll-interval=50ms",
+	}
+	code := c.Run(args)
+	require.Equal(1, code)
+	require.Contains(ui.ErrorWriter.String(), "Timed out waiting for 1 leaf certificates")
+
+	output := ui.OutputWriter.String()
+	require.Contains(output, "1 of 1 leaf certificates are signed by an old root")
+	require.Regexp(`node1\s+web`, output)
+}
@@ -0,0 +1,277 @@
+package envoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// BootstrapConfig is the set of keys in the opaque Config of a proxy
+// registration which customize the Envoy bootstrap config. They configure the
+// telemetry of the proxy, so that it is the same for every instance which
+// shares the registration instead of being templated per deployment.
+type BootstrapConfig struct {
+	// StatsdURL is the URL of a statsd server to which Envoy sends its stats,
+	// e.g. udp://127.0.0.1:8125. Environment variables in the URL are expanded
+	// when the bootstrap config is generated, e.g. udp://${HOST_IP}:8125, which
+	// allows to send to the statsd server of the host in Kubernetes. The host
+	// must be an IP address.
+	StatsdURL string `mapstructure:"envoy_statsd_url"`
+
+	// DogstatsdURL is like StatsdURL but for a DogStatsD server, which accepts
+	// the tags of the stats. It may also be a unix socket, e.g.
+	// unix:///var/run/dogstatsd.sock.
+	DogstatsdURL string `mapstructure:"envoy_dogstatsd_url"`
+
+	// StatsTags are tags added to all the stats, in the form "name=value".
+	// Envoy also extracts its default tags, such as the cluster name, from the
+	// names of the stats.
+	StatsTags []string `mapstructure:"envoy_stats_tags"`
+
+	// PrometheusBindAddr is the ip:port of a listener which serves the stats of
+	// Envoy in the Prometheus format on /metrics. It is proxied to the admin
+	// API of Envoy, which shouldn't be exposed itself.
+	PrometheusBindAddr string `mapstructure:"envoy_prometheus_bind_addr"`
+
+	// StatsFlushInterval is how often the stats are flushed to the sinks, e.g.
+	// "10s". Envoy defaults to 5 seconds.
+	StatsFlushInterval string `mapstructure:"envoy_stats_flush_interval"`
+}
+
+// ParseBootstrapConfig decodes the bootstrap config from the Config of a
+// proxy. Unrelated keys are ignored.
+func ParseBootstrapConfig(cfg map[string]interface{}) (BootstrapConfig, error) {
+	var c BootstrapConfig
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &c,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return c, err
+	}
+	if err := decoder.Decode(cfg); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Template sets the fields of the template args which render the telemetry
+// config of Envoy.
+func (c *BootstrapConfig) Template(args *templateArgs) error {
+	var sinks []interface{}
+	if c.StatsdURL != "" {
+		sink, err := statsdSink("envoy.statsd", c.StatsdURL, false)
+		if err != nil {
+			return fmt.Errorf("invalid envoy_statsd_url: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if c.DogstatsdURL != "" {
+		sink, err := statsdSink("envoy.dog_statsd", c.DogstatsdURL, true)
+		if err != nil {
+			return fmt.Errorf("invalid envoy_dogstatsd_url: %v", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) > 0 {
+		out, err := renderJSON(sinks, "  ")
+		if err != nil {
+			return err
+		}
+		args.StatsSinksJSON = out
+	}
+
+	if len(c.StatsTags) > 0 {
+		var tags []interface{}
+		for _, tag := range c.StatsTags {
+			parts := strings.SplitN(tag, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return fmt.Errorf("invalid envoy_stats_tags: %q is not in the form name=value", tag)
+			}
+			tags = append(tags, map[string]interface{}{
+				"tag_name":    parts[0],
+				"fixed_value": parts[1],
+			})
+		}
+		out, err := renderJSON(map[string]interface{}{
+			"use_all_default_tags": true,
+			"stats_tags":           tags,
+		}, "  ")
+		if err != nil {
+			return err
+		}
+		args.StatsConfigJSON = out
+	}
+
+	if c.PrometheusBindAddr != "" {
+		if err := c.prometheusTemplate(args); err != nil {
+			return err
+		}
+	}
+
+	if c.StatsFlushInterval != "" {
+		d, err := time.ParseDuration(c.StatsFlushInterval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid envoy_stats_flush_interval: %q", c.StatsFlushInterval)
+		}
+		args.StatsFlushInterval = d.String()
+	}
+
+	return nil
+}
+
+// prometheusTemplate adds a listener which serves the Prometheus stats of the
+// admin API on /metrics, and the cluster of the admin API it proxies to.
+func (c *BootstrapConfig) prometheusTemplate(args *templateArgs) error {
+	host, port, err := splitIPPort(c.PrometheusBindAddr)
+	if err != nil {
+		return fmt.Errorf("invalid envoy_prometheus_bind_addr: %v", err)
+	}
+
+	adminPort, err := strconv.Atoi(args.AdminBindPort)
+	if err != nil {
+		return fmt.Errorf("invalid admin bind port: %v", err)
+	}
+
+	cluster := map[string]interface{}{
+		"name":                  selfAdminClusterName,
+		"connect_timeout":       "5s",
+		"type":                  "STATIC",
+		"http_protocol_options": map[string]interface{}{},
+		"hosts": []interface{}{
+			socketAddress(args.AdminBindAddress, adminPort),
+		},
+	}
+	listener := map[string]interface{}{
+		"name":    "envoy_prometheus_metrics_listener",
+		"address": socketAddress(host, port),
+		"filter_chains": []interface{}{
+			map[string]interface{}{
+				"filters": []interface{}{
+					map[string]interface{}{
+						"name": "envoy.http_connection_manager",
+						"config": map[string]interface{}{
+							"stat_prefix": "envoy_prometheus_metrics",
+							"codec_type":  "HTTP1",
+							"route_config": map[string]interface{}{
+								"name": "self_admin_route",
+								"virtual_hosts": []interface{}{
+									map[string]interface{}{
+										"name":    selfAdminClusterName,
+										"domains": []string{"*"},
+										"routes": []interface{}{
+											map[string]interface{}{
+												"match": map[string]interface{}{"path": "/metrics"},
+												"route": map[string]interface{}{
+													"cluster":        selfAdminClusterName,
+													"prefix_rewrite": "/stats/prometheus",
+												},
+											},
+											map[string]interface{}{
+												"match":           map[string]interface{}{"prefix": "/"},
+												"direct_response": map[string]interface{}{"status": 404},
+											},
+										},
+									},
+								},
+							},
+							"http_filters": []interface{}{
+								map[string]interface{}{"name": "envoy.router"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if args.StaticClustersJSON, err = renderJSON(cluster, "      "); err != nil {
+		return err
+	}
+	if args.StaticListenersJSON, err = renderJSON(listener, "      "); err != nil {
+		return err
+	}
+	return nil
+}
+
+// selfAdminClusterName is the name of the cluster of the admin API of Envoy
+// itself.
+const selfAdminClusterName = "self_admin"
+
+// statsdSink returns the stats sink with the given name which sends to the
+// statsd server at the URL. Only DogStatsD accepts unix sockets.
+func statsdSink(name, rawURL string, allowUnix bool) (interface{}, error) {
+	u, err := url.Parse(os.ExpandEnv(rawURL))
+	if err != nil {
+		return nil, err
+	}
+
+	var address interface{}
+	switch {
+	case u.Scheme == "udp":
+		host, port, err := splitIPPort(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		address = socketAddress(host, port)
+	case u.Scheme == "unix" && allowUnix:
+		if u.Path == "" {
+			return nil, fmt.Errorf("missing socket path in %q", rawURL)
+		}
+		address = map[string]interface{}{
+			"pipe": map[string]interface{}{"path": u.Path},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q in %q", u.Scheme, rawURL)
+	}
+
+	return map[string]interface{}{
+		"name": name,
+		"config": map[string]interface{}{
+			"address": address,
+		},
+	}, nil
+}
+
+// splitIPPort splits an ip:port address. Envoy only binds and sends to
+// static addresses so the host must be an IP.
+func splitIPPort(addr string) (string, int, error) {
+	host, portRaw, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	if net.ParseIP(host) == nil {
+		return "", 0, fmt.Errorf("%q is not an IP address", host)
+	}
+	port, err := strconv.Atoi(portRaw)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portRaw)
+	}
+	return host, port, nil
+}
+
+func socketAddress(host string, port int) map[string]interface{} {
+	return map[string]interface{}{
+		"socket_address": map[string]interface{}{
+			"address":    host,
+			"port_value": port,
+		},
+	}
+}
+
+// renderJSON renders v as indented JSON to be embedded in the bootstrap
+// template at the given indentation.
+func renderJSON(v interface{}, indent string) (string, error) {
+	out, err := json.MarshalIndent(v, indent, "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -10,6 +10,14 @@ type templateArgs struct {
 	AdminBindPort         string
 	LocalAgentClusterName string
 	Token                 string
+
+	// The fields below are rendered from the BootstrapConfig of the proxy and
+	// are left empty when it doesn't set them.
+	StatsConfigJSON     string
+	StatsSinksJSON      string
+	StatsFlushInterval  string
+	StaticClustersJSON  string
+	StaticListenersJSON string
 }
 
 const bootstrapTemplate = `{
@@ -26,6 +34,15 @@ const bootstrapTemplate = `{
     "cluster": "{{ .ProxyCluster }}",
     "id": "{{ .ProxyID }}"
   },
+  {{- if .StatsConfigJSON }}
+  "stats_config": {{ .StatsConfigJSON }},
+  {{- end }}
+  {{- if .StatsSinksJSON }}
+  "stats_sinks": {{ .StatsSinksJSON }},
+  {{- end }}
+  {{- if .StatsFlushInterval }}
+  "stats_flush_interval": "{{ .StatsFlushInterval }}",
+  {{- end }}
   "static_resources": {
     "clusters": [
       {
@@ -53,7 +70,17 @@ const bootstrapTemplate = `{
           }
         ]
       }
+      {{- if .StaticClustersJSON -}}
+      ,
+      {{ .StaticClustersJSON }}
+      {{- end }}
+    ]
+    {{- if .StaticListenersJSON -}}
+    ,
+    "listeners": [
+      {{ .StaticListenersJSON }}
     ]
+    {{- end }}
   },
   "dynamic_resources": {
     "lds_config": { "ads": {} },
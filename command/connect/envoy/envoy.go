@@ -5,12 +5,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"html/template"
 	"net"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"text/template"
 
 	proxyAgent "github.com/hashicorp/consul/agent/proxyprocess"
 	"github.com/hashicorp/consul/agent/xds"
@@ -41,6 +41,10 @@ type cmd struct {
 	help   string
 	client *api.Client
 
+	// proxyConfig is the opaque Config of the proxy registration, which holds
+	// the BootstrapConfig.
+	proxyConfig map[string]interface{}
+
 	// flags
 	proxyID    string
 	sidecarFor string
@@ -135,6 +139,17 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	// Fetch the proxy registration for the keys of its Config which customize
+	// the bootstrap config.
+	svc, _, err := c.client.Agent().Service(c.proxyID, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to fetch the proxy config from the local agent: %s", err))
+		return 1
+	}
+	if svc.Proxy != nil {
+		c.proxyConfig = svc.Proxy.Config
+	}
+
 	// Generate config
 	bootstrapJson, err := c.generateConfig()
 	if err != nil {
@@ -235,7 +250,7 @@ func (c *cmd) templateArgs() (*templateArgs, error) {
 		return nil, fmt.Errorf("Failed to resolve admin bind address: %s", err)
 	}
 
-	return &templateArgs{
+	args := &templateArgs{
 		ProxyCluster:          c.proxyID,
 		ProxyID:               c.proxyID,
 		AgentAddress:          agentIP.String(),
@@ -246,7 +261,17 @@ func (c *cmd) templateArgs() (*templateArgs, error) {
 		AdminBindPort:         adminPort,
 		Token:                 httpCfg.Token,
 		LocalAgentClusterName: xds.LocalAgentClusterName,
-	}, nil
+	}
+
+	bsCfg, err := ParseBootstrapConfig(c.proxyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the bootstrap config of the proxy: %s", err)
+	}
+	if err := bsCfg.Template(args); err != nil {
+		return nil, fmt.Errorf("Invalid bootstrap config of the proxy: %s", err)
+	}
+
+	return args, nil
 }
 
 func (c *cmd) generateConfig() ([]byte, error) {
@@ -293,6 +318,10 @@ Usage: consul connect envoy [options]
   The token may be passed via the CLI or the CONSUL_TOKEN environment
   variable.
 
+  The telemetry of Envoy is configured with the envoy_statsd_url,
+  envoy_dogstatsd_url, envoy_stats_tags, envoy_prometheus_bind_addr and
+  envoy_stats_flush_interval keys of the Config of the proxy registration.
+
   The example below shows how to start a local proxy as a sidecar to a "web"
   service instance. It assumes that the proxy was already registered with it's
   Config for example via a sidecar_service block.
@@ -1,14 +1,18 @@
 package envoy
 
 import (
+	"encoding/json"
 	"flag"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent/xds"
+	"github.com/hashicorp/consul/api"
 	"github.com/mitchellh/cli"
 	"github.com/stretchr/testify/require"
 )
@@ -56,11 +60,12 @@ func testSetAndResetEnv(t *testing.T, env []string) func() {
 // pass the test of having their template args generated as expected.
 func TestGenerateConfig(t *testing.T) {
 	cases := []struct {
-		Name     string
-		Flags    []string
-		Env      []string
-		WantArgs templateArgs
-		WantErr  string
+		Name        string
+		Flags       []string
+		Env         []string
+		ProxyConfig map[string]interface{}
+		WantArgs    templateArgs
+		WantErr     string
 	}{
 		{
 			Name:    "no-args",
@@ -119,6 +124,168 @@ func TestGenerateConfig(t *testing.T) {
 				LocalAgentClusterName: xds.LocalAgentClusterName,
 			},
 		},
+		{
+			Name:  "stats-sinks",
+			Flags: []string{"-proxy-id", "test-proxy"},
+			Env: []string{
+				"HOST_IP=10.0.0.1",
+			},
+			ProxyConfig: map[string]interface{}{
+				"envoy_statsd_url":           "udp://${HOST_IP}:8125",
+				"envoy_dogstatsd_url":        "unix:///var/run/dogstatsd.sock",
+				"envoy_stats_tags":           []interface{}{"env=prod", "team=web"},
+				"envoy_stats_flush_interval": "10s",
+			},
+			WantArgs: templateArgs{
+				ProxyCluster:          "test-proxy",
+				ProxyID:               "test-proxy",
+				AgentAddress:          "127.0.0.1",
+				AgentPort:             "8502",
+				AdminBindAddress:      "127.0.0.1",
+				AdminBindPort:         "19000",
+				LocalAgentClusterName: xds.LocalAgentClusterName,
+				StatsConfigJSON: `{
+    "stats_tags": [
+      {
+        "fixed_value": "prod",
+        "tag_name": "env"
+      },
+      {
+        "fixed_value": "web",
+        "tag_name": "team"
+      }
+    ],
+    "use_all_default_tags": true
+  }`,
+				StatsSinksJSON: `[
+    {
+      "config": {
+        "address": {
+          "socket_address": {
+            "address": "10.0.0.1",
+            "port_value": 8125
+          }
+        }
+      },
+      "name": "envoy.statsd"
+    },
+    {
+      "config": {
+        "address": {
+          "pipe": {
+            "path": "/var/run/dogstatsd.sock"
+          }
+        }
+      },
+      "name": "envoy.dog_statsd"
+    }
+  ]`,
+				StatsFlushInterval: "10s",
+			},
+		},
+		{
+			Name:  "prometheus",
+			Flags: []string{"-proxy-id", "test-proxy"},
+			ProxyConfig: map[string]interface{}{
+				"envoy_prometheus_bind_addr": "0.0.0.0:9102",
+			},
+			WantArgs: templateArgs{
+				ProxyCluster:          "test-proxy",
+				ProxyID:               "test-proxy",
+				AgentAddress:          "127.0.0.1",
+				AgentPort:             "8502",
+				AdminBindAddress:      "127.0.0.1",
+				AdminBindPort:         "19000",
+				LocalAgentClusterName: xds.LocalAgentClusterName,
+				StaticClustersJSON: `{
+        "connect_timeout": "5s",
+        "hosts": [
+          {
+            "socket_address": {
+              "address": "127.0.0.1",
+              "port_value": 19000
+            }
+          }
+        ],
+        "http_protocol_options": {},
+        "name": "self_admin",
+        "type": "STATIC"
+      }`,
+				StaticListenersJSON: `{
+        "address": {
+          "socket_address": {
+            "address": "0.0.0.0",
+            "port_value": 9102
+          }
+        },
+        "filter_chains": [
+          {
+            "filters": [
+              {
+                "config": {
+                  "codec_type": "HTTP1",
+                  "http_filters": [
+                    {
+                      "name": "envoy.router"
+                    }
+                  ],
+                  "route_config": {
+                    "name": "self_admin_route",
+                    "virtual_hosts": [
+                      {
+                        "domains": [
+                          "*"
+                        ],
+                        "name": "self_admin",
+                        "routes": [
+                          {
+                            "match": {
+                              "path": "/metrics"
+                            },
+                            "route": {
+                              "cluster": "self_admin",
+                              "prefix_rewrite": "/stats/prometheus"
+                            }
+                          },
+                          {
+                            "direct_response": {
+                              "status": 404
+                            },
+                            "match": {
+                              "prefix": "/"
+                            }
+                          }
+                        ]
+                      }
+                    ]
+                  },
+                  "stat_prefix": "envoy_prometheus_metrics"
+                },
+                "name": "envoy.http_connection_manager"
+              }
+            ]
+          }
+        ],
+        "name": "envoy_prometheus_metrics_listener"
+      }`,
+			},
+		},
+		{
+			Name:  "invalid-statsd-url",
+			Flags: []string{"-proxy-id", "test-proxy"},
+			ProxyConfig: map[string]interface{}{
+				"envoy_statsd_url": "udp://statsd.example.com:8125",
+			},
+			WantErr: "invalid envoy_statsd_url",
+		},
+		{
+			Name:  "invalid-stats-tag",
+			Flags: []string{"-proxy-id", "test-proxy"},
+			ProxyConfig: map[string]interface{}{
+				"envoy_stats_tags": []interface{}{"prod"},
+			},
+			WantErr: "invalid envoy_stats_tags",
+		},
 		// TODO(banks): all the flags/env manipulation cases
 	}
 
@@ -126,13 +293,17 @@ func TestGenerateConfig(t *testing.T) {
 		t.Run(tc.Name, func(t *testing.T) {
 			require := require.New(t)
 
+			// Mock the agent which serves the proxy registration.
+			srv := httptest.NewServer(testMockAgentProxyConfig(tc.ProxyConfig))
+			defer srv.Close()
+
 			ui := cli.NewMockUi()
 			c := New(ui)
 
 			defer testSetAndResetEnv(t, tc.Env)()
 
 			// Run the command
-			args := append([]string{"-bootstrap"}, tc.Flags...)
+			args := append([]string{"-bootstrap", "-http-addr=" + srv.URL}, tc.Flags...)
 			code := c.Run(args)
 			if tc.WantErr == "" {
 				require.Equal(0, code, ui.ErrorWriter.String())
@@ -165,3 +336,27 @@ func TestGenerateConfig(t *testing.T) {
 		})
 	}
 }
+
+// testMockAgentProxyConfig returns a handler which serves a proxy
+// registration with the given Config for every proxy ID.
+func testMockAgentProxyConfig(cfg map[string]interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/agent/service/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		svc := api.AgentService{
+			Kind:    api.ServiceKindConnectProxy,
+			ID:      strings.TrimPrefix(r.URL.Path, "/v1/agent/service/"),
+			Service: "web-sidecar-proxy",
+			Port:    8888,
+			Proxy: &api.AgentServiceConnectProxyConfig{
+				DestinationServiceName: "web",
+				DestinationServiceID:   "web",
+				Config:                 cfg,
+			},
+		}
+		json.NewEncoder(w).Encode(svc)
+	}
+}
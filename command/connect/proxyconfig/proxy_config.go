@@ -0,0 +1,159 @@
+package proxyconfig
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = c.flags.Args()
+	if len(args) != 1 {
+		c.UI.Error(fmt.Sprintf("Expected exactly one argument, the service ID (got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	snap, _, err := client.Agent().ConnectProxySnapshot(args[0], nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading proxy config: %s", err))
+		return 1
+	}
+
+	err = c.output.Print(c.UI, snap, []string{snap.ProxyServiceID}, func() {
+		c.UI.Output(formatSnapshot(snap))
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// formatSnapshot returns the human readable output of a proxy config.
+func formatSnapshot(snap *api.ConnectProxySnapshot) string {
+	var b bytes.Buffer
+	roots := make([]string, 0, len(snap.Roots))
+	for _, root := range snap.Roots {
+		name := root.Name
+		if root.Active {
+			name += " (active)"
+		}
+		roots = append(roots, name)
+	}
+	b.WriteString(columnize.SimpleFormat([]string{
+		fmt.Sprintf("Proxy|%s (%s:%d)", snap.ProxyServiceID, snap.Address, snap.Port),
+		fmt.Sprintf("Service|%s (%s)", snap.TargetServiceName, snap.TargetServiceID),
+		fmt.Sprintf("Certificate|%s", snap.Leaf.ServiceURI),
+		fmt.Sprintf("Serial|%s", snap.Leaf.SerialNumber),
+		fmt.Sprintf("Valid|%s - %s", snap.Leaf.ValidAfter, snap.Leaf.ValidBefore),
+		fmt.Sprintf("Trust Domain|%s", snap.TrustDomain),
+		fmt.Sprintf("Roots|%s", strings.Join(roots, ", ")),
+		fmt.Sprintf("Default Allow|%t", snap.DefaultAllow),
+	}))
+
+	b.WriteString("\n\nIntentions:\n")
+	if len(snap.Intentions) == 0 {
+		b.WriteString("(none)")
+	} else {
+		rows := []string{"Precedence|Source|Action|ID"}
+		for _, ixn := range snap.Intentions {
+			rows = append(rows, fmt.Sprintf("%d|%s|%s|%s",
+				ixn.Precedence, ixn.SourceName, ixn.Action, ixn.ID))
+		}
+		b.WriteString(columnize.SimpleFormat(rows))
+	}
+
+	b.WriteString("\n\nUpstreams:\n")
+	if len(snap.Upstreams) == 0 {
+		b.WriteString("(none)")
+	} else {
+		rows := []string{"Upstream|Local Bind|Node|Endpoint|Status"}
+		for _, u := range snap.Upstreams {
+			name := string(u.DestinationType) + ":" + u.DestinationName
+			bind := fmt.Sprintf("%s:%d", u.LocalBindAddress, u.LocalBindPort)
+			if len(u.Endpoints) == 0 {
+				rows = append(rows, fmt.Sprintf("%s|%s|-|-|-", name, bind))
+			}
+			for _, ep := range u.Endpoints {
+				rows = append(rows, fmt.Sprintf("%s|%s|%s|%s:%d|%s",
+					name, bind, ep.Node, ep.Address, ep.Port, ep.Status))
+			}
+		}
+		b.WriteString(columnize.SimpleFormat(rows))
+	}
+	return b.String()
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Show the resolved configuration of a local Connect proxy"
+const help = `
+Usage: consul connect proxy-config [options] <service-id>
+
+  Shows the configuration which the local agent pushes to a Connect proxy:
+  the upstreams with the instances they resolve to, the metadata of the
+  leaf certificate and the CA roots, and the intentions which apply to the
+  proxied service in the order they are evaluated. The ID may be the ID of
+  the proxy or of the service it proxies. This is meant for debugging
+  routing between services without capturing traffic.
+
+  Show the configuration of the sidecar proxy of the service "web":
+
+      $ consul connect proxy-config web
+
+  Print the configuration as JSON:
+
+      $ consul connect proxy-config -format=json web
+`
@@ -0,0 +1,84 @@
+package proxyconfig
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectProxyConfigCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestConnectProxyConfigCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+	require.Equal(t, 1, c.Run(nil))
+	require.Contains(t, ui.ErrorWriter.String(), "Expected exactly one argument")
+}
+
+func TestConnectProxyConfigCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	require.NoError(t, a.Client().Agent().ServiceRegister(&api.AgentServiceRegistration{
+		Name: "web",
+		Port: 8080,
+		Connect: &api.AgentServiceConnect{
+			SidecarService: &api.AgentServiceRegistration{
+				Proxy: &api.AgentServiceConnectProxyConfig{
+					Upstreams: []api.Upstream{
+						{
+							DestinationName: "db",
+							LocalBindPort:   9191,
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	// The config is available once the leaf certificate has been issued.
+	retry.Run(t, func(r *retry.R) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		if code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-format=json", "web"}); code != 0 {
+			r.Fatalf("bad: %d. %s", code, ui.ErrorWriter.String())
+		}
+		var snap api.ConnectProxySnapshot
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &snap); err != nil {
+			r.Fatal(err)
+		}
+		if snap.ProxyServiceID != "web-sidecar-proxy" || len(snap.Upstreams) != 1 {
+			r.Fatalf("bad: %#v", snap)
+		}
+	})
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "web"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	out := ui.OutputWriter.String()
+	require.Contains(t, out, "web-sidecar-proxy")
+	require.Contains(t, out, "service:db")
+	require.Contains(t, out, "/svc/web")
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-quiet", "web"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Equal(t, "web-sidecar-proxy\n", ui.OutputWriter.String())
+}
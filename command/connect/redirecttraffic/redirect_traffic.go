@@ -0,0 +1,214 @@
+package redirecttraffic
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/hashicorp/consul/agent/structs"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// inboundChain and outboundChain are the chains traffic enters from the
+	// PREROUTING and OUTPUT chains of the nat table, the redirect chains do
+	// the actual redirection to the proxy.
+	inboundChain          = "CONSUL_PROXY_INBOUND"
+	inboundRedirectChain  = "CONSUL_PROXY_IN_REDIRECT"
+	outboundChain         = "CONSUL_PROXY_OUTPUT"
+	outboundRedirectChain = "CONSUL_PROXY_REDIRECT"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	// flags
+	proxyID             string
+	proxyUID            string
+	proxyInboundPort    int
+	proxyOutboundPort   int
+	excludeInboundPorts flags.AppendSliceValue
+	excludeOutboundCIDR flags.AppendSliceValue
+	excludeUIDs         flags.AppendSliceValue
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	c.flags.StringVar(&c.proxyID, "proxy-id", "",
+		"The ID of a proxy registered with the local agent in the transparent "+
+			"mode. The ports the traffic is redirected to are read from its "+
+			"registration.")
+	c.flags.StringVar(&c.proxyUID, "proxy-uid", "",
+		"The ID of the user the proxy runs as. Its traffic isn't redirected. "+
+			"This is required.")
+	c.flags.IntVar(&c.proxyInboundPort, "proxy-inbound-port", 0,
+		"The port of the public listener of the proxy, which inbound traffic "+
+			"is redirected to. Defaults to the port of the proxy registration.")
+	c.flags.IntVar(&c.proxyOutboundPort, "proxy-outbound-port", 0,
+		"The port of the outbound listener of the proxy, which outbound "+
+			"traffic is redirected to. Defaults to the outbound listener port of "+
+			"the proxy registration, or "+
+			strconv.Itoa(structs.DefaultOutboundListenerPort)+" without -proxy-id.")
+	c.flags.Var(&c.excludeInboundPorts, "exclude-inbound-port",
+		"An inbound port which isn't redirected to the proxy. This may be "+
+			"specified multiple times.")
+	c.flags.Var(&c.excludeOutboundCIDR, "exclude-outbound-cidr",
+		"A destination address or CIDR block which outbound traffic isn't "+
+			"redirected for. This may be specified multiple times.")
+	c.flags.Var(&c.excludeUIDs, "exclude-uid",
+		"The ID of another user whose outbound traffic isn't redirected, such "+
+			"as the user of the Consul agent. This may be specified multiple times.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if c.proxyUID == "" {
+		c.UI.Error("The -proxy-uid flag is required")
+		return 1
+	}
+
+	if c.proxyID != "" {
+		client, err := c.http.APIClient()
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+			return 1
+		}
+		if err := c.readProxy(client); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+	if c.proxyInboundPort == 0 {
+		c.UI.Error("Either -proxy-id or -proxy-inbound-port must be specified")
+		return 1
+	}
+	if c.proxyOutboundPort == 0 {
+		c.proxyOutboundPort = structs.DefaultOutboundListenerPort
+	}
+
+	rules, err := c.rules()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output(rules)
+	return 0
+}
+
+// readProxy fills in the ports which weren't given as flags from the
+// registration of the proxy.
+func (c *cmd) readProxy(client *api.Client) error {
+	svc, _, err := client.Agent().Service(c.proxyID, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to look up proxy service %q: %s", c.proxyID, err)
+	}
+	if svc.Kind != api.ServiceKindConnectProxy || svc.Proxy == nil {
+		return fmt.Errorf("Service %q is not a Connect proxy", c.proxyID)
+	}
+	if svc.Proxy.Mode != api.ProxyModeTransparent {
+		return fmt.Errorf("Proxy %q is not in the transparent mode", c.proxyID)
+	}
+
+	if c.proxyInboundPort == 0 {
+		c.proxyInboundPort = svc.Port
+	}
+	if c.proxyOutboundPort == 0 {
+		c.proxyOutboundPort = svc.Proxy.TransparentProxy.OutboundListenerPort
+	}
+	return nil
+}
+
+// rules returns the rules of the nat table in the format of iptables-restore.
+func (c *cmd) rules() (string, error) {
+	var b bytes.Buffer
+	b.WriteString("*nat\n")
+	for _, chain := range []string{inboundChain, inboundRedirectChain, outboundChain, outboundRedirectChain} {
+		fmt.Fprintf(&b, ":%s - [0:0]\n", chain)
+	}
+
+	// Inbound traffic goes to the public listener of the proxy unless the
+	// port is excluded.
+	fmt.Fprintf(&b, "-A PREROUTING -p tcp -j %s\n", inboundChain)
+	fmt.Fprintf(&b, "-A %s -p tcp -j REDIRECT --to-ports %d\n", inboundRedirectChain, c.proxyInboundPort)
+	for _, port := range c.excludeInboundPorts {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", fmt.Errorf("Invalid inbound port %q", port)
+		}
+		fmt.Fprintf(&b, "-A %s -p tcp --dport %s -j RETURN\n", inboundChain, port)
+	}
+	fmt.Fprintf(&b, "-A %s -p tcp -j %s\n", inboundChain, inboundRedirectChain)
+
+	// Outbound traffic goes to the outbound listener of the proxy, except for
+	// the traffic of the proxy itself, which would otherwise loop, and traffic
+	// to the loopback interface, which is where the proxy reaches the
+	// application.
+	fmt.Fprintf(&b, "-A OUTPUT -p tcp -j %s\n", outboundChain)
+	fmt.Fprintf(&b, "-A %s -p tcp -j REDIRECT --to-ports %d\n", outboundRedirectChain, c.proxyOutboundPort)
+	for _, uid := range append([]string{c.proxyUID}, c.excludeUIDs...) {
+		if _, err := strconv.ParseUint(uid, 10, 32); err != nil {
+			return "", fmt.Errorf("Invalid user ID %q", uid)
+		}
+		fmt.Fprintf(&b, "-A %s -m owner --uid-owner %s -j RETURN\n", outboundChain, uid)
+	}
+	fmt.Fprintf(&b, "-A %s -d 127.0.0.1/32 -j RETURN\n", outboundChain)
+	for _, cidr := range c.excludeOutboundCIDR {
+		if net.ParseIP(cidr) == nil {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return "", fmt.Errorf("Invalid outbound CIDR %q", cidr)
+			}
+		}
+		fmt.Fprintf(&b, "-A %s -d %s -j RETURN\n", outboundChain, cidr)
+	}
+	fmt.Fprintf(&b, "-A %s -p tcp -j %s\n", outboundChain, outboundRedirectChain)
+
+	b.WriteString("COMMIT")
+	return b.String(), nil
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Generate iptables rules for a proxy in the transparent mode"
+const help = `
+Usage: consul connect redirect-traffic [options]
+
+  Generates the iptables rules which redirect the traffic of an application
+  to its Connect proxy in the transparent mode. Inbound traffic goes to the
+  public listener of the proxy, outbound traffic to its outbound listener,
+  which forwards it to the upstream the virtual IP of the destination
+  belongs to. The rules are printed in the format of iptables-restore and
+  must be applied in the network namespace of the application.
+
+  Redirect the traffic of the application of the proxy "web-sidecar-proxy",
+  which runs as the user with the ID 1234:
+
+      $ consul connect redirect-traffic -proxy-id=web-sidecar-proxy \
+          -proxy-uid=1234 | iptables-restore --noflush
+`
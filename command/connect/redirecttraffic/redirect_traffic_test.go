@@ -0,0 +1,130 @@
+package redirecttraffic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectRedirectTrafficCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestConnectRedirectTrafficCommand_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args []string
+		err  string
+	}{
+		"no uid":          {nil, "-proxy-uid flag is required"},
+		"no inbound port": {[]string{"-proxy-uid=1234"}, "Either -proxy-id or -proxy-inbound-port"},
+		"bad uid":         {[]string{"-proxy-uid=envoy", "-proxy-inbound-port=21000"}, `Invalid user ID "envoy"`},
+		"bad port": {
+			[]string{"-proxy-uid=1234", "-proxy-inbound-port=21000", "-exclude-inbound-port=ssh"},
+			`Invalid inbound port "ssh"`,
+		},
+		"bad cidr": {
+			[]string{"-proxy-uid=1234", "-proxy-inbound-port=21000", "-exclude-outbound-cidr=10.0.0.0/33"},
+			`Invalid outbound CIDR "10.0.0.0/33"`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui)
+			require.Equal(t, 1, c.Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.err)
+		})
+	}
+}
+
+func TestConnectRedirectTrafficCommand_Flags(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{
+		"-proxy-uid=1234",
+		"-proxy-inbound-port=21000",
+		"-exclude-inbound-port=22",
+		"-exclude-outbound-cidr=10.0.0.0/8",
+		"-exclude-uid=100",
+	})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	expected := `*nat
+:CONSUL_PROXY_INBOUND - [0:0]
+:CONSUL_PROXY_IN_REDIRECT - [0:0]
+:CONSUL_PROXY_OUTPUT - [0:0]
+:CONSUL_PROXY_REDIRECT - [0:0]
+-A PREROUTING -p tcp -j CONSUL_PROXY_INBOUND
+-A CONSUL_PROXY_IN_REDIRECT -p tcp -j REDIRECT --to-ports 21000
+-A CONSUL_PROXY_INBOUND -p tcp --dport 22 -j RETURN
+-A CONSUL_PROXY_INBOUND -p tcp -j CONSUL_PROXY_IN_REDIRECT
+-A OUTPUT -p tcp -j CONSUL_PROXY_OUTPUT
+-A CONSUL_PROXY_REDIRECT -p tcp -j REDIRECT --to-ports 15001
+-A CONSUL_PROXY_OUTPUT -m owner --uid-owner 1234 -j RETURN
+-A CONSUL_PROXY_OUTPUT -m owner --uid-owner 100 -j RETURN
+-A CONSUL_PROXY_OUTPUT -d 127.0.0.1/32 -j RETURN
+-A CONSUL_PROXY_OUTPUT -d 10.0.0.0/8 -j RETURN
+-A CONSUL_PROXY_OUTPUT -p tcp -j CONSUL_PROXY_REDIRECT
+COMMIT
+`
+	require.Equal(t, expected, ui.OutputWriter.String())
+}
+
+func TestConnectRedirectTrafficCommand_ProxyID(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	register := func(id string, mode api.ProxyMode) {
+		require.NoError(t, a.Client().Agent().ServiceRegister(&api.AgentServiceRegistration{
+			Kind: api.ServiceKindConnectProxy,
+			ID:   id,
+			Name: "web-proxy",
+			Port: 21000,
+			Proxy: &api.AgentServiceConnectProxyConfig{
+				DestinationServiceName: "web",
+				Mode:                   mode,
+				TransparentProxy: api.TransparentProxyConfig{
+					OutboundListenerPort: 15201,
+				},
+			},
+		}))
+	}
+	register("transparent-proxy", api.ProxyModeTransparent)
+	register("direct-proxy", api.ProxyModeDirect)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	code := c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-proxy-id=transparent-proxy", "-proxy-uid=1234"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	out := ui.OutputWriter.String()
+	require.Contains(t, out, "-A CONSUL_PROXY_IN_REDIRECT -p tcp -j REDIRECT --to-ports 21000\n")
+	require.Contains(t, out, "-A CONSUL_PROXY_REDIRECT -p tcp -j REDIRECT --to-ports 15201\n")
+
+	// Flags take precedence over the registration.
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-proxy-id=transparent-proxy",
+		"-proxy-uid=1234", "-proxy-outbound-port=15001"})
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "REDIRECT --to-ports 15001\n")
+
+	ui = cli.NewMockUi()
+	c = New(ui)
+	code = c.Run([]string{"-http-addr=" + a.HTTPAddr(), "-proxy-id=direct-proxy", "-proxy-uid=1234"})
+	require.Equal(t, 1, code)
+	require.Contains(t, ui.ErrorWriter.String(), "not in the transparent mode")
+}
@@ -0,0 +1,156 @@
+package flags
+
+import (
+	"flag"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/posener/complete"
+)
+
+// predictTimeout bounds the time spent querying the agent for dynamic
+// completions so that an unreachable agent doesn't block the shell.
+const predictTimeout = 2 * time.Second
+
+// Predictors returns the autocomplete predictors for all the flags of the
+// flag set. Boolean flags don't take a value, the values of the other flags
+// are predicted by the predictor with the same name in values, e.g.
+// "-service", or not at all.
+func Predictors(fs *flag.FlagSet, values complete.Flags) complete.Flags {
+	out := make(complete.Flags)
+	fs.VisitAll(func(f *flag.Flag) {
+		name := "-" + f.Name
+		if p, ok := values[name]; ok {
+			out[name] = p
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			out[name] = complete.PredictNothing
+			return
+		}
+		out[name] = complete.PredictAnything
+	})
+	return out
+}
+
+// predict returns a predictor which suggests the values returned by fn. The
+// agent is queried with the address and token from the environment since
+// the flags are not parsed when completing. Nothing is suggested if the
+// agent can't be reached.
+func (f *HTTPFlags) predict(fn func(client *api.Client, a complete.Args, q *api.QueryOptions) ([]string, error)) complete.Predictor {
+	return complete.PredictFunc(func(a complete.Args) []string {
+		client, err := f.predictClient()
+		if err != nil {
+			return nil
+		}
+
+		values, err := fn(client, a, &api.QueryOptions{AllowStale: true})
+		if err != nil {
+			return nil
+		}
+		sort.Strings(values)
+		return values
+	})
+}
+
+// predictClient returns an API client whose requests time out after
+// predictTimeout.
+func (f *HTTPFlags) predictClient() (*api.Client, error) {
+	c := api.DefaultConfig()
+	f.MergeOntoConfig(c)
+
+	httpClient, err := api.NewHttpClient(c.Transport, c.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = predictTimeout
+	c.HttpClient = httpClient
+
+	return api.NewClient(c)
+}
+
+// PredictServices suggests the names of the services in the catalog.
+func (f *HTTPFlags) PredictServices() complete.Predictor {
+	return f.predict(func(client *api.Client, _ complete.Args, q *api.QueryOptions) ([]string, error) {
+		services, _, err := client.Catalog().Services(q)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		return names, nil
+	})
+}
+
+// PredictAgentServices suggests the IDs of the services registered with the
+// local agent.
+func (f *HTTPFlags) PredictAgentServices() complete.Predictor {
+	return f.predict(func(client *api.Client, _ complete.Args, _ *api.QueryOptions) ([]string, error) {
+		services, err := client.Agent().Services()
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(services))
+		for id := range services {
+			ids = append(ids, id)
+		}
+		return ids, nil
+	})
+}
+
+// PredictNodes suggests the names of the nodes in the catalog.
+func (f *HTTPFlags) PredictNodes() complete.Predictor {
+	return f.predict(func(client *api.Client, _ complete.Args, q *api.QueryOptions) ([]string, error) {
+		nodes, _, err := client.Catalog().Nodes(q)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			names = append(names, node.Node)
+		}
+		return names, nil
+	})
+}
+
+// PredictPolicyNames suggests the names of the ACL policies.
+func (f *HTTPFlags) PredictPolicyNames() complete.Predictor {
+	return f.predict(func(client *api.Client, _ complete.Args, q *api.QueryOptions) ([]string, error) {
+		policies, _, err := client.ACL().PolicyList(q)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(policies))
+		for _, policy := range policies {
+			names = append(names, policy.Name)
+		}
+		return names, nil
+	})
+}
+
+// PredictPolicyIDs suggests the IDs of the ACL policies.
+func (f *HTTPFlags) PredictPolicyIDs() complete.Predictor {
+	return f.predict(func(client *api.Client, _ complete.Args, q *api.QueryOptions) ([]string, error) {
+		policies, _, err := client.ACL().PolicyList(q)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(policies))
+		for _, policy := range policies {
+			ids = append(ids, policy.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictKeys suggests the keys and folders of the KV store which start
+// with the argument being completed.
+func (f *HTTPFlags) PredictKeys() complete.Predictor {
+	return f.predict(func(client *api.Client, a complete.Args, q *api.QueryOptions) ([]string, error) {
+		keys, _, err := client.KV().Keys(a.Last, "/", q)
+		return keys, err
+	})
+}
@@ -0,0 +1,32 @@
+package flags
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/posener/complete"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictors(t *testing.T) {
+	var s string
+	var b bool
+	var v BoolValue
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&s, "name", "", "")
+	fs.StringVar(&s, "service", "", "")
+	fs.BoolVar(&b, "detailed", false, "")
+	fs.Var(&v, "stale", "")
+
+	services := complete.PredictSet("web", "db")
+	p := Predictors(fs, complete.Flags{"-service": services})
+
+	require.Len(t, p, 4)
+	require.NotNil(t, p["-name"])
+	require.Empty(t, p["-name"].Predict(complete.Args{}))
+	require.Equal(t, []string{"web", "db"}, p["-service"].Predict(complete.Args{}))
+	require.Nil(t, p["-detailed"])
+	require.Nil(t, p["-stale"])
+	require.Contains(t, p, "-detailed")
+	require.Contains(t, p, "-stale")
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 )
 
@@ -16,16 +17,19 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.http = &flags.HTTPFlags{}
+	c.output = &output.Flags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -33,6 +37,10 @@ func (c *cmd) Run(args []string) int {
 	if err := c.flags.Parse(args); err != nil {
 		return 1
 	}
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -54,31 +62,37 @@ func (c *cmd) Run(args []string) int {
 	// Get the keys in sorted order
 	keys := make([]string, 0, len(stats))
 	for key := range stats {
+		if _, ok := stats[key].(map[string]interface{}); !ok {
+			c.UI.Error(fmt.Sprintf("Got invalid subkey in stats: %v", stats[key]))
+			return 1
+		}
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
 
-	// Iterate over each top-level key
-	for _, key := range keys {
-		c.UI.Output(key + ":")
-
-		// Sort the sub-keys
-		subvals, ok := stats[key].(map[string]interface{})
-		if !ok {
-			c.UI.Error(fmt.Sprintf("Got invalid subkey in stats: %v", subvals))
-			return 1
-		}
-		subkeys := make([]string, 0, len(subvals))
-		for k := range subvals {
-			subkeys = append(subkeys, k)
-		}
-		sort.Strings(subkeys)
-
-		// Iterate over the subkeys
-		for _, subkey := range subkeys {
-			val := subvals[subkey]
-			c.UI.Output(fmt.Sprintf("\t%s = %s", subkey, val))
+	err = c.output.Print(c.UI, stats, keys, func() {
+		// Iterate over each top-level key
+		for _, key := range keys {
+			c.UI.Output(key + ":")
+
+			// Sort the sub-keys
+			subvals := stats[key].(map[string]interface{})
+			subkeys := make([]string, 0, len(subvals))
+			for k := range subvals {
+				subkeys = append(subkeys, k)
+			}
+			sort.Strings(subkeys)
+
+			// Iterate over the subkeys
+			for _, subkey := range subkeys {
+				val := subvals[subkey]
+				c.UI.Output(fmt.Sprintf("\t%s = %s", subkey, val))
+			}
 		}
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
 	return 0
 }
@@ -95,5 +109,11 @@ const synopsis = "Provides debugging information for operators."
 const help = `
 Usage: consul info [options]
 
-  Provides debugging information for operators.
+  Provides debugging information for operators. The information is grouped
+  into sections like raft, serf_lan or autopilot, and the set of sections
+  and values depends on whether the agent is a server or the leader.
+
+  Print the information as JSON for monitoring tools:
+
+      $ consul info -format=json
 `
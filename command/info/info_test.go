@@ -1,11 +1,13 @@
 package info
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent"
 	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInfoCommand_noTabs(t *testing.T) {
@@ -33,3 +35,21 @@ func TestInfoCommand(t *testing.T) {
 		t.Fatalf("bad: %#v", ui.OutputWriter.String())
 	}
 }
+
+func TestInfoCommand_JSON(t *testing.T) {
+	t.Parallel()
+	a1 := agent.NewTestAgent(t.Name(), ``)
+	defer a1.Shutdown()
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	args := []string{"-http-addr=" + a1.HTTPAddr(), "-format=json"}
+
+	code := cmd.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	var stats map[string]map[string]string
+	require.NoError(t, json.Unmarshal(ui.OutputWriter.Bytes(), &stats))
+	require.Equal(t, "true", stats["consul"]["server"])
+	require.Contains(t, stats, "raft")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -16,13 +17,15 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI          cli.Ui
-	flags       *flag.FlagSet
-	http        *flags.HTTPFlags
-	help        string
-	cas         bool
-	modifyIndex uint64
-	recurse     bool
+	UI            cli.Ui
+	flags         *flag.FlagSet
+	http          *flags.HTTPFlags
+	help          string
+	cas           bool
+	modifyIndex   uint64
+	recurse       bool
+	dryRun        bool
+	expectedCount int
 }
 
 func (c *cmd) init() {
@@ -35,6 +38,14 @@ func (c *cmd) init() {
 			"used in combination with the -cas flag.")
 	c.flags.BoolVar(&c.recurse, "recurse", false,
 		"Recursively delete all keys with the path. The default value is false.")
+	c.flags.BoolVar(&c.dryRun, "dry-run", false,
+		"List the keys which would be deleted by a recursive delete without "+
+			"deleting them. This requires the -recurse flag to be set. The "+
+			"default value is false.")
+	c.flags.IntVar(&c.expectedCount, "expected-count", 0,
+		"Abort a recursive delete if it would delete more than this number of "+
+			"keys. This requires the -recurse flag to be set. The default value "+
+			"is 0, which doesn't limit the number of keys.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -93,6 +104,20 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	// The safety checks only apply to recursive deletes
+	if c.dryRun && !c.recurse {
+		c.UI.Error("Cannot specify -dry-run without -recurse!")
+		return 1
+	}
+	if c.expectedCount != 0 && !c.recurse {
+		c.UI.Error("Cannot specify -expected-count without -recurse!")
+		return 1
+	}
+	if c.expectedCount < 0 {
+		c.UI.Error("Must specify a positive -expected-count!")
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -102,6 +127,28 @@ func (c *cmd) Run(args []string) int {
 
 	switch {
 	case c.recurse:
+		if c.dryRun || c.expectedCount > 0 {
+			keys, _, err := client.KV().Keys(key, "", nil)
+			if err != nil {
+				c.UI.Error(fmt.Sprintf("Error querying keys with prefix %s: %s", key, err))
+				return 1
+			}
+
+			if c.dryRun {
+				for _, k := range keys {
+					c.UI.Info(k)
+				}
+				c.UI.Info(fmt.Sprintf("Dry run! Would delete %d keys with prefix: %s", len(keys), key))
+				return 0
+			}
+
+			if len(keys) > c.expectedCount {
+				c.UI.Error(fmt.Sprintf("Error! Did not delete prefix %s: found %d keys, expected at most %d",
+					key, len(keys), c.expectedCount))
+				return 1
+			}
+		}
+
 		if _, err := client.KV().DeleteTree(key, nil); err != nil {
 			c.UI.Error(fmt.Sprintf("Error! Did not delete prefix %s: %s", key, err))
 			return 1
@@ -146,6 +193,14 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return c.http.PredictKeys()
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, nil)
+}
+
 const synopsis = "Removes data from the KV store"
 const help = `
 Usage: consul kv delete [options] KEY_OR_PREFIX
@@ -163,4 +218,14 @@ Usage: consul kv delete [options] KEY_OR_PREFIX
 
   This will delete the keys named "foo", "food", and "foo/bar/zip" if they
   existed.
+
+  To list the keys a recursive delete would remove, specify the -dry-run
+  option:
+
+      $ consul kv delete -recurse -dry-run foo/
+
+  To abort a recursive delete if it would remove more keys than expected,
+  specify the -expected-count option:
+
+      $ consul kv delete -recurse -expected-count=10 foo/
 `
@@ -38,6 +38,18 @@ func TestKVDeleteCommand_Validation(t *testing.T) {
 			[]string{"-modify-index", "2", "foo"},
 			"Cannot specify -modify-index without",
 		},
+		"-dry-run no -recurse": {
+			[]string{"-dry-run", "foo"},
+			"Cannot specify -dry-run without",
+		},
+		"-expected-count no -recurse": {
+			[]string{"-expected-count", "2", "foo"},
+			"Cannot specify -expected-count without",
+		},
+		"negative -expected-count": {
+			[]string{"-recurse", "-expected-count", "-1", "foo"},
+			"Must specify a positive -expected-count",
+		},
 		"no key": {
 			[]string{},
 			"Missing KEY argument",
@@ -210,3 +222,125 @@ func TestKVDeleteCommand_CAS(t *testing.T) {
 		t.Fatalf("bad: %#v", data)
 	}
 }
+
+func TestKVDeleteCommand_DryRun(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	keys := []string{"foo/a", "foo/b", "food"}
+
+	for _, k := range keys {
+		pair := &api.KVPair{
+			Key:   k,
+			Value: []byte("bar"),
+		}
+		_, err := client.KV().Put(pair, nil)
+		if err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-recurse",
+		"-dry-run",
+		"foo/",
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "foo/a\nfoo/b\n") || strings.Contains(output, "food") ||
+		!strings.Contains(output, "Would delete 2 keys") {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	for _, k := range keys {
+		pair, _, err := client.KV().Get(k, nil)
+		if err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+		if pair == nil {
+			t.Fatalf("missing key: %s", k)
+		}
+	}
+}
+
+func TestKVDeleteCommand_ExpectedCount(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	keys := []string{"foo/a", "foo/b", "foo/c"}
+
+	for _, k := range keys {
+		pair := &api.KVPair{
+			Key:   k,
+			Value: []byte("bar"),
+		}
+		_, err := client.KV().Put(pair, nil)
+		if err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-recurse",
+		"-expected-count=2",
+		"foo/",
+	}
+
+	code := c.Run(args)
+	if code == 0 {
+		t.Fatalf("bad: expected error")
+	}
+	if output := ui.ErrorWriter.String(); !strings.Contains(output, "found 3 keys, expected at most 2") {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	existing, _, err := client.KV().Keys("foo/", "", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if len(existing) != 3 {
+		t.Fatalf("bad: %#v", existing)
+	}
+
+	// Reset buffers and flags
+	ui.OutputWriter.Reset()
+	ui.ErrorWriter.Reset()
+	c.init()
+
+	args = []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-recurse",
+		"-expected-count=3",
+		"foo/",
+	}
+
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	existing, _, err = client.KV().Keys("foo/", "", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if len(existing) != 0 {
+		t.Fatalf("bad: %#v", existing)
+	}
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -23,6 +25,7 @@ type cmd struct {
 	UI           cli.Ui
 	flags        *flag.FlagSet
 	http         *flags.HTTPFlags
+	output       *output.Flags
 	help         string
 	base64encode bool
 	detailed     bool
@@ -54,6 +57,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -62,6 +67,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	key := ""
 
 	// Check for arg validation
@@ -107,8 +117,14 @@ func (c *cmd) Run(args []string) int {
 			return 1
 		}
 
-		for _, k := range keys {
-			c.UI.Info(string(k))
+		err = c.output.Print(c.UI, keys, keys, func() {
+			for _, k := range keys {
+				c.UI.Info(string(k))
+			}
+		})
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
 		}
 
 		return 0
@@ -121,6 +137,18 @@ func (c *cmd) Run(args []string) int {
 			return 1
 		}
 
+		if !c.output.Table() {
+			keys := make([]string, 0, len(pairs))
+			for _, pair := range pairs {
+				keys = append(keys, pair.Key)
+			}
+			if err := c.output.Print(c.UI, pairs, keys, nil); err != nil {
+				c.UI.Error(err.Error())
+				return 1
+			}
+			return 0
+		}
+
 		for i, pair := range pairs {
 			if c.detailed {
 				var b bytes.Buffer
@@ -158,6 +186,14 @@ func (c *cmd) Run(args []string) int {
 			return 1
 		}
 
+		if !c.output.Table() {
+			if err := c.output.Print(c.UI, pair, []string{pair.Key}, nil); err != nil {
+				c.UI.Error(err.Error())
+				return 1
+			}
+			return 0
+		}
+
 		if c.detailed {
 			var b bytes.Buffer
 			if err := prettyKVPair(&b, pair, c.base64encode); err != nil {
@@ -186,6 +222,14 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return c.http.PredictKeys()
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, nil)
+}
+
 func prettyKVPair(w io.Writer, pair *api.KVPair, base64EncodeValue bool) error {
 	tw := tabwriter.NewWriter(w, 0, 2, 6, ' ', 0)
 	fmt.Fprintf(tw, "CreateIndex\t%d\n", pair.CreateIndex)
@@ -236,5 +280,10 @@ Usage: consul kv get [options] [KEY_OR_PREFIX]
 
       $ consul kv get -keys foo
 
+  To retrieve the key-value pairs as JSON, including their metadata and with
+  the values base64 encoded, specify "-format=json":
+
+      $ consul kv get -recurse -format=json foo
+
   For a full list of options and examples, please see the Consul documentation.
 `
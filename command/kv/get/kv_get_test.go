@@ -2,6 +2,7 @@ package get
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"strings"
 	"testing"
 
@@ -293,6 +294,70 @@ func TestKVGetCommand_Recurse(t *testing.T) {
 	}
 }
 
+func TestKVGetCommand_RecurseFormat(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	keys := map[string]string{
+		"foo/a": "a",
+		"foo/b": "b",
+	}
+	for k, v := range keys {
+		pair := &api.KVPair{Key: k, Value: []byte(v)}
+		if _, err := client.KV().Put(pair, nil); err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-recurse",
+			"-format=json",
+			"foo",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+
+		var pairs api.KVPairs
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &pairs); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(pairs) != len(keys) {
+			t.Fatalf("bad: %#v", pairs)
+		}
+		for _, pair := range pairs {
+			if string(pair.Value) != keys[pair.Key] || pair.ModifyIndex == 0 {
+				t.Fatalf("bad: %#v", pair)
+			}
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		args := []string{
+			"-http-addr=" + a.HTTPAddr(),
+			"-recurse",
+			"-quiet",
+			"foo",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+		if output, expected := ui.OutputWriter.String(), "foo/a\nfoo/b\n"; output != expected {
+			t.Fatalf("bad: %q, expected %q", output, expected)
+		}
+	})
+}
+
 func TestKVGetCommand_RecurseBase64(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t.Name(), ``)
@@ -0,0 +1,121 @@
+package purge
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI      cli.Ui
+	flags   *flag.FlagSet
+	http    *flags.HTTPFlags
+	help    string
+	recurse bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.recurse, "recurse", false,
+		"Recursively purge all deleted keys with the path. The default value "+
+			"is false.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	key := ""
+
+	// Check for arg validation
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		key = ""
+	case 1:
+		key = args[0]
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// This is just a "nice" thing to do. Since pairs cannot start with a /, but
+	// users will likely put "/" or "/foo", lets go ahead and strip that for them
+	// here.
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	// If the key is empty and we are not doing a recursive purge, this is an
+	// error.
+	if key == "" && !c.recurse {
+		c.UI.Error("Error! Missing KEY argument")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	if c.recurse {
+		n, _, err := client.KV().PurgeTree(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Did not purge prefix %s: %s", key, err))
+			return 1
+		}
+
+		c.UI.Info(fmt.Sprintf("Success! Purged %d keys with prefix: %s", n, key))
+		return 0
+	}
+
+	if _, _, err := client.KV().Purge(key, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error purging key %s: %s", key, err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Success! Purged key: %s", key))
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Removes deleted data from the KV recycle bin"
+const help = `
+Usage: consul kv purge [options] KEY_OR_PREFIX
+
+  Permanently removes deleted keys from the recycle bin of Consul's key-value
+  store, so they can no longer be restored. If the key isn't in the recycle
+  bin, no action is taken.
+
+  To purge the deleted key named "foo":
+
+      $ consul kv purge foo
+
+  To purge all deleted keys which start with "foo", specify the -recurse
+  option:
+
+      $ consul kv purge -recurse foo
+`
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/hashicorp/consul/command/helpers"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func New(ui cli.Ui) *cmd {
@@ -199,6 +200,14 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return c.http.PredictKeys()
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, nil)
+}
+
 const synopsis = "Sets or updates data in the KV store"
 const help = `
 Usage: consul kv put [options] KEY [DATA]
@@ -0,0 +1,161 @@
+package restore
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI      cli.Ui
+	flags   *flag.FlagSet
+	http    *flags.HTTPFlags
+	help    string
+	list    bool
+	recurse bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.list, "list", false,
+		"List the deleted keys with the prefix which can be restored instead "+
+			"of restoring them. The default value is false.")
+	c.flags.BoolVar(&c.recurse, "recurse", false,
+		"Recursively restore all deleted keys with the path. The default value "+
+			"is false.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	key := ""
+
+	// Check for arg validation
+	args = c.flags.Args()
+	switch len(args) {
+	case 0:
+		key = ""
+	case 1:
+		key = args[0]
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// This is just a "nice" thing to do. Since pairs cannot start with a /, but
+	// users will likely put "/" or "/foo", lets go ahead and strip that for them
+	// here.
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	// It is not valid to list and restore in the same call
+	if c.list && c.recurse {
+		c.UI.Error("Cannot specify both -list and -recurse!")
+		return 1
+	}
+
+	// If the key is empty and we are not doing a recursive restore or a
+	// listing, this is an error.
+	if key == "" && !c.recurse && !c.list {
+		c.UI.Error("Error! Missing KEY argument")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	switch {
+	case c.list:
+		entries, _, err := client.KV().Recycled(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error querying deleted keys with prefix %s: %s", key, err))
+			return 1
+		}
+		if len(entries) == 0 {
+			return 0
+		}
+
+		result := []string{"Key\x1fDeleted At\x1fDelete Index"}
+		for _, e := range entries {
+			result = append(result, fmt.Sprintf("%s\x1f%s\x1f%d",
+				e.Key, e.DeletedAt.Format(time.RFC3339), e.DeleteIndex))
+		}
+		c.UI.Output(columnize.Format(result, &columnize.Config{Delim: string([]byte{0x1f})}))
+		return 0
+	case c.recurse:
+		n, _, err := client.KV().RestoreTree(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error! Did not restore prefix %s: %s", key, err))
+			return 1
+		}
+
+		c.UI.Info(fmt.Sprintf("Success! Restored %d keys with prefix: %s", n, key))
+		return 0
+	default:
+		n, _, err := client.KV().Restore(key, nil)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error restoring key %s: %s", key, err))
+			return 1
+		}
+		if n == 0 {
+			c.UI.Error(fmt.Sprintf("Error! Did not restore key %s: not in the recycle bin or created again", key))
+			return 1
+		}
+
+		c.UI.Info(fmt.Sprintf("Success! Restored key: %s", key))
+		return 0
+	}
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Restores deleted data from the KV recycle bin"
+const help = `
+Usage: consul kv restore [options] KEY_OR_PREFIX
+
+  Restores deleted keys from the recycle bin of Consul's key-value store.
+  Keys are only moved to the recycle bin when the servers are configured
+  with a kv_recycle_bin_retention, and are kept there for that long. Keys
+  which were created again since they were deleted are not restored.
+
+  To restore the key named "foo" in the key-value store:
+
+      $ consul kv restore foo
+
+  To restore all deleted keys which start with "foo", specify the -recurse
+  option:
+
+      $ consul kv restore -recurse foo
+
+  To list the deleted keys which start with "foo", specify the -list option:
+
+      $ consul kv restore -list foo
+`
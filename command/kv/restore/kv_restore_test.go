@@ -0,0 +1,171 @@
+package restore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestKVRestoreCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestKVRestoreCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"-list and -recurse": {
+			[]string{"-list", "-recurse", "foo"},
+			"Cannot specify both",
+		},
+		"no key": {
+			[]string{},
+			"Missing KEY argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar", "baz"},
+			"Too many arguments",
+		},
+	}
+
+	for name, tc := range cases {
+		c.init()
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestKVRestoreCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), `kv_recycle_bin_retention = "1h"`)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	pair := &api.KVPair{
+		Key:   "foo",
+		Value: []byte("bar"),
+	}
+	if _, err := client.KV().Put(pair, nil); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if _, err := client.KV().Delete("foo", nil); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-list",
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if output := ui.OutputWriter.String(); !strings.Contains(output, "foo") {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	// Reset buffers and flags
+	ui.OutputWriter.Reset()
+	ui.ErrorWriter.Reset()
+	c.init()
+
+	args = []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"foo",
+	}
+
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	pair, _, err := client.KV().Get("foo", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if pair == nil || string(pair.Value) != "bar" {
+		t.Fatalf("bad: %#v", pair)
+	}
+
+	// The key is no longer in the recycle bin.
+	code = c.Run(args)
+	if code == 0 {
+		t.Fatalf("bad: expected error")
+	}
+}
+
+func TestKVRestoreCommand_Recurse(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), `kv_recycle_bin_retention = "1h"`)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	keys := []string{"foo/a", "foo/b", "food"}
+
+	for _, k := range keys {
+		pair := &api.KVPair{
+			Key:   k,
+			Value: []byte("bar"),
+		}
+		if _, err := client.KV().Put(pair, nil); err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+	}
+	if _, err := client.KV().DeleteTree("foo", nil); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-recurse",
+		"foo",
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	for _, k := range keys {
+		pair, _, err := client.KV().Get(k, nil)
+		if err != nil {
+			t.Fatalf("err: %#v", err)
+		}
+		if pair == nil {
+			t.Fatalf("missing key: %s", k)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 // cmd is a Command implementation that enables or disables
@@ -154,6 +155,16 @@ func (c *cmd) Help() string {
 	return c.help
 }
 
+func (c *cmd) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *cmd) AutocompleteFlags() complete.Flags {
+	return flags.Predictors(c.flags, complete.Flags{
+		"-service": c.http.PredictAgentServices(),
+	})
+}
+
 const synopsis = "Controls node or service maintenance mode"
 const help = `
 Usage: consul maint [options]
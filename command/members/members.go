@@ -10,6 +10,7 @@ import (
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/hashicorp/serf/serf"
 	"github.com/mitchellh/cli"
 	"github.com/ryanuber/columnize"
@@ -18,10 +19,11 @@ import (
 // cmd is a Command implementation that queries a running
 // Consul agent what members are part of the cluster currently.
 type cmd struct {
-	UI    cli.Ui
-	help  string
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
+	UI     cli.Ui
+	help   string
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
 
 	// flags
 	detailed     bool
@@ -52,6 +54,8 @@ func (c *cmd) init() {
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -59,6 +63,10 @@ func (c *cmd) Run(args []string) int {
 	if err := c.flags.Parse(args); err != nil {
 		return 1
 	}
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 
 	// Compile the regexp
 	statusRe, err := regexp.Compile(c.statusFilter)
@@ -111,17 +119,27 @@ func (c *cmd) Run(args []string) int {
 
 	sort.Sort(ByMemberNameAndSegment(members))
 
-	// Generate the output
-	var result []string
-	if c.detailed {
-		result = c.detailedOutput(members)
-	} else {
-		result = c.standardOutput(members)
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.Name)
 	}
 
-	// Generate the columnized version
-	output := columnize.SimpleFormat(result)
-	c.UI.Output(output)
+	err = c.output.Print(c.UI, members, names, func() {
+		// Generate the output
+		var result []string
+		if c.detailed {
+			result = c.detailedOutput(members)
+		} else {
+			result = c.standardOutput(members)
+		}
+
+		// Generate the columnized version
+		c.UI.Output(columnize.SimpleFormat(result))
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 
 	return 0
 }
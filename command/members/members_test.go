@@ -1,11 +1,13 @@
 package members
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/agent"
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/mitchellh/cli"
 )
 
@@ -69,6 +71,59 @@ func TestMembersCommand_WAN(t *testing.T) {
 	}
 }
 
+func TestMembersCommand_format(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+
+	t.Run("json", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		c.flags.SetOutput(ui.ErrorWriter)
+
+		args := []string{"-http-addr=" + a.HTTPAddr(), "-format=json"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+
+		var members []*consulapi.AgentMember
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &members); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(members) != 1 || members[0].Name != a.Config.NodeName {
+			t.Fatalf("bad: %#v", members)
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		c.flags.SetOutput(ui.ErrorWriter)
+
+		args := []string{"-http-addr=" + a.HTTPAddr(), "-quiet"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+		if got, want := ui.OutputWriter.String(), a.Config.NodeName+"\n"; got != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ui := cli.NewMockUi()
+		c := New(ui)
+		c.flags.SetOutput(ui.ErrorWriter)
+
+		args := []string{"-http-addr=" + a.HTTPAddr(), "-format=yaml"}
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("bad: %d", code)
+		}
+		if !strings.Contains(ui.ErrorWriter.String(), "Invalid -format") {
+			t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+		}
+	})
+}
+
 func TestMembersCommand_statusFilter(t *testing.T) {
 	t.Parallel()
 	a := agent.NewTestAgent(t.Name(), ``)
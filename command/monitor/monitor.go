@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
 )
@@ -24,6 +25,9 @@ type cmd struct {
 
 	// flags
 	logLevel string
+	include  []string
+	exclude  []string
+	logJSON  bool
 }
 
 func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
@@ -36,6 +40,17 @@ func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.StringVar(&c.logLevel, "log-level", "INFO",
 		"Log level of the agent.")
+	c.flags.Var((*flags.AppendSliceValue)(&c.include), "include",
+		"Only stream the logs of the given subsystem, e.g. \"raft\" or "+
+			"\"consul\". Nested subsystems such as \"consul.fsm\" are "+
+			"included with their parent. This flag may be specified multiple times.")
+	c.flags.Var((*flags.AppendSliceValue)(&c.exclude), "exclude",
+		"Drop the logs of the given subsystem and the subsystems nested under "+
+			"it. Takes precedence over -include. This flag may be specified "+
+			"multiple times.")
+	c.flags.BoolVar(&c.logJSON, "log-json", false,
+		"Stream the logs as JSON lines with the @timestamp, @level, @module "+
+			"and @message fields instead of plain text.")
 
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
@@ -54,7 +69,13 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	eventDoneCh := make(chan struct{})
-	logCh, err := client.Agent().Monitor(c.logLevel, eventDoneCh, nil)
+	opts := api.MonitorOpts{
+		LogLevel: c.logLevel,
+		Include:  c.include,
+		Exclude:  c.exclude,
+		JSON:     c.logJSON,
+	}
+	logCh, err := client.Agent().MonitorOpts(opts, eventDoneCh, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error starting monitor: %s", err))
 		return 1
@@ -111,4 +132,8 @@ Usage: consul monitor [options]
   listen for log levels that may be filtered out of the Consul agent. For
   example your agent may only be logging at INFO level, but with the monitor
   you can see the DEBUG level logs.
+
+  To only see the logs of the Raft and RPC subsystems as JSON lines:
+
+      $ consul monitor -include=raft -include=consul -log-json
 `
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/command/output"
 	"github.com/mitchellh/cli"
 	"github.com/ryanuber/columnize"
 )
@@ -17,10 +18,11 @@ func New(ui cli.Ui) *cmd {
 }
 
 type cmd struct {
-	UI    cli.Ui
-	flags *flag.FlagSet
-	http  *flags.HTTPFlags
-	help  string
+	UI     cli.Ui
+	flags  *flag.FlagSet
+	http   *flags.HTTPFlags
+	output *output.Flags
+	help   string
 }
 
 func (c *cmd) init() {
@@ -28,6 +30,8 @@ func (c *cmd) init() {
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
+	c.output = &output.Flags{}
+	flags.Merge(c.flags, c.output.Flags())
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -40,6 +44,11 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.output.Validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	// Set up a client.
 	client, err := c.http.APIClient()
 	if err != nil {
@@ -48,28 +57,92 @@ func (c *cmd) Run(args []string) int {
 	}
 
 	// Fetch the current configuration.
-	result, err := raftListPeers(client, c.http.Stale())
+	reply, err := raftGetConfiguration(client, c.http.Stale())
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error getting peers: %v", err))
 		return 1
 	}
 
-	c.UI.Output(result)
+	// The health is only known to the leader, so it can't be retrieved
+	// during an outage. The peers are still listed in this case.
+	health, err := client.Operator().AutopilotServerHealth(nil)
+	if err != nil {
+		c.UI.Warn(fmt.Sprintf("Failed to retrieve server health: %v", err))
+		health = nil
+	}
+	peers := newPeers(reply, health)
+
+	ids := make([]string, 0, len(reply.Servers))
+	for _, s := range reply.Servers {
+		ids = append(ids, s.ID)
+	}
+
+	err = c.output.Print(c.UI, peers, ids, func() {
+		c.UI.Output(formatPeers(peers))
+	})
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
 	return 0
 }
 
-func raftListPeers(client *api.Client, stale bool) (string, error) {
+func raftGetConfiguration(client *api.Client, stale bool) (*api.RaftConfiguration, error) {
 	q := &api.QueryOptions{
 		AllowStale: stale,
 	}
 	reply, err := client.Operator().RaftGetConfiguration(q)
 	if err != nil {
-		return "", fmt.Errorf("Failed to retrieve raft configuration: %v", err)
+		return nil, fmt.Errorf("Failed to retrieve raft configuration: %v", err)
 	}
+	return reply, nil
+}
 
-	// Format it as a nice table.
-	result := []string{"Node|ID|Address|State|Voter|RaftProtocol"}
+// peer is a server of the Raft configuration along with its health. The
+// fields of the RaftServer are inlined so that the JSON output is a superset
+// of the one of the HTTP API.
+type peer struct {
+	*api.RaftServer
+
+	// Health is the health of the server as tracked by the leader. It is
+	// nil if it couldn't be retrieved.
+	Health *api.ServerHealth `json:",omitempty"`
+
+	// IndexLag is the number of Raft log entries the server is behind the
+	// leader. It is only meaningful if Health is set.
+	IndexLag uint64
+}
+
+// newPeers joins the Raft configuration with the health of the servers,
+// which may be nil.
+func newPeers(reply *api.RaftConfiguration, health *api.OperatorHealthReply) []*peer {
+	byID := make(map[string]*api.ServerHealth)
+	var leaderIndex uint64
+	if health != nil {
+		for i := range health.Servers {
+			h := &health.Servers[i]
+			byID[h.ID] = h
+			if h.Leader {
+				leaderIndex = h.LastIndex
+			}
+		}
+	}
+
+	peers := make([]*peer, 0, len(reply.Servers))
 	for _, s := range reply.Servers {
+		p := &peer{RaftServer: s, Health: byID[s.ID]}
+		if p.Health != nil && leaderIndex > p.Health.LastIndex {
+			p.IndexLag = leaderIndex - p.Health.LastIndex
+		}
+		peers = append(peers, p)
+	}
+	return peers
+}
+
+// formatPeers formats the raft configuration as a nice table.
+func formatPeers(peers []*peer) string {
+	result := []string{"Node|ID|Address|State|Voter|RaftProtocol|LastContact|IndexLag"}
+	for _, s := range peers {
 		raftProtocol := s.ProtocolVersion
 
 		if raftProtocol == "" {
@@ -79,11 +152,18 @@ func raftListPeers(client *api.Client, stale bool) (string, error) {
 		if s.Leader {
 			state = "leader"
 		}
-		result = append(result, fmt.Sprintf("%s|%s|%s|%s|%v|%s",
-			s.Node, s.ID, s.Address, state, s.Voter, raftProtocol))
+		lastContact, indexLag := "unknown", "unknown"
+		if s.Health != nil {
+			if s.Health.LastContact != nil {
+				lastContact = s.Health.LastContact.String()
+			}
+			indexLag = fmt.Sprintf("%d", s.IndexLag)
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s|%v|%s|%s|%s",
+			s.Node, s.ID, s.Address, state, s.Voter, raftProtocol, lastContact, indexLag))
 	}
 
-	return columnize.SimpleFormat(result), nil
+	return columnize.SimpleFormat(result)
 }
 
 func (c *cmd) Synopsis() string {
@@ -98,5 +178,8 @@ const synopsis = "Display the current Raft peer configuration"
 const help = `
 Usage: consul operator raft list-peers [options]
 
-  Displays the current Raft peer configuration.
+  Displays the current Raft peer configuration along with the health of the
+  servers as tracked by the leader: the time since their last contact with
+  the leader and the number of Raft log entries they are behind it. The
+  health is "unknown" if there is no leader.
 `
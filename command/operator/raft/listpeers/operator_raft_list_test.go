@@ -1,11 +1,15 @@
 package listpeers
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/mitchellh/cli"
 )
 
@@ -38,3 +42,75 @@ func TestOperatorRaftListPeersCommand(t *testing.T) {
 		t.Fatalf("bad: %q, %q", output, expected)
 	}
 }
+
+func TestOperatorRaftListPeersCommand_JSON(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{"-http-addr=" + a.HTTPAddr(), "-format=json"}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	var servers []*api.RaftServer
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &servers); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(servers) != 1 || servers[0].ID != string(a.Config.NodeID) || !servers[0].Leader {
+		t.Fatalf("bad: %#v", servers)
+	}
+
+	// The health is added to the fields of the HTTP API once the leader
+	// tracks it.
+	retry.Run(t, func(r *retry.R) {
+		ui := cli.NewMockUi()
+		if code := New(ui).Run(args); code != 0 {
+			r.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+		var peers []*peer
+		if err := json.Unmarshal(ui.OutputWriter.Bytes(), &peers); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(peers) != 1 || peers[0].Health == nil || !peers[0].Health.Leader || peers[0].IndexLag != 0 {
+			r.Fatalf("bad: %#v", peers)
+		}
+	})
+}
+
+func TestOperatorRaftListPeersCommand_formatPeers(t *testing.T) {
+	t.Parallel()
+	reply := &api.RaftConfiguration{
+		Servers: []*api.RaftServer{
+			{ID: "a", Node: "alice", Address: "127.0.0.1:8300", Voter: true, ProtocolVersion: "3"},
+			{ID: "b", Node: "bob", Address: "127.0.0.2:8300", Leader: true, Voter: true, ProtocolVersion: "3"},
+			{ID: "c", Node: "carol", Address: "127.0.0.3:8300", ProtocolVersion: "3"},
+		},
+	}
+	health := &api.OperatorHealthReply{
+		Servers: []api.ServerHealth{
+			{ID: "a", LastIndex: 90, LastContact: api.NewReadableDuration(20 * time.Millisecond)},
+			{ID: "b", LastIndex: 100, Leader: true, LastContact: api.NewReadableDuration(0)},
+		},
+	}
+
+	output := formatPeers(newPeers(reply, health))
+	for _, expected := range []string{
+		"alice  a   127.0.0.1:8300  follower  true   3             20ms         10",
+		"bob    b   127.0.0.2:8300  leader    true   3             0s           0",
+		"carol  c   127.0.0.3:8300  follower  false  3             unknown      unknown",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Fatalf("bad: %q, %q", output, expected)
+		}
+	}
+
+	output = formatPeers(newPeers(reply, nil))
+	if strings.Count(output, "unknown") != 6 {
+		t.Fatalf("bad: %q", output)
+	}
+}
@@ -29,5 +29,5 @@ Usage: consul operator raft <subcommand> [options]
 
 The Raft operator command is used to interact with Consul's Raft subsystem. The
 command can be used to verify Raft peers or in rare cases to recover quorum by
-removing invalid peers.
+removing invalid peers or generating a peers.json recovery file.
 `
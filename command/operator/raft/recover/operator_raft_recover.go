@@ -0,0 +1,278 @@
+package recover
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/raft"
+	"github.com/mitchellh/cli"
+)
+
+// defaultServerPort is the server RPC port used when an address in -servers
+// doesn't give one.
+const defaultServerPort = "8300"
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	help  string
+
+	// flags
+	dataDir      string
+	servers      string
+	raftProtocol int
+	dryRun       bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.dataDir, "data-dir", "",
+		"Path to the data directory of the stopped Consul server to recover. "+
+			"This is required.")
+	c.flags.StringVar(&c.servers, "servers", "",
+		"Comma-separated list of the remaining servers. With Raft protocol 3 "+
+			"each entry is <node-id>@<ip:port>, with earlier versions it is "+
+			"<ip:port>. The port defaults to 8300. This is required.")
+	c.flags.IntVar(&c.raftProtocol, "raft-protocol", 3,
+		"The Raft protocol version the servers are configured with.")
+	c.flags.BoolVar(&c.dryRun, "dry-run", false,
+		"Verify the data directory and print the peers.json file without "+
+			"writing it.")
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+
+	if c.dataDir == "" {
+		c.UI.Error("Missing -data-dir flag")
+		return 1
+	}
+	if c.servers == "" {
+		c.UI.Error("Missing -servers flag")
+		return 1
+	}
+	if c.raftProtocol < 1 || c.raftProtocol > 3 {
+		c.UI.Error(fmt.Sprintf("Unsupported Raft protocol version %d", c.raftProtocol))
+		return 1
+	}
+
+	raftDir, err := verifyDataDir(c.dataDir)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error verifying data directory: %v", err))
+		return 1
+	}
+
+	content, err := peersJSON(c.servers, c.raftProtocol)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error parsing servers: %v", err))
+		return 1
+	}
+
+	// Write the file to a scratch location first and read it back the same
+	// way the server does, so we never leave behind a file that won't be
+	// ingested.
+	tmp, err := ioutil.TempFile(raftDir, "peers.json")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing peers.json: %v", err))
+		return 1
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		c.UI.Error(fmt.Sprintf("Error writing peers.json: %v", err))
+		return 1
+	}
+	if err := tmp.Close(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing peers.json: %v", err))
+		return 1
+	}
+	configuration, err := readPeersJSON(tmp.Name(), c.raftProtocol)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error verifying peers.json: %v", err))
+		return 1
+	}
+
+	// With Raft protocol 3 servers are identified by their node ID, so
+	// this server must be in the configuration or it won't be able to
+	// take part in the recovered cluster.
+	if c.raftProtocol >= 3 {
+		nodeID, err := ioutil.ReadFile(filepath.Join(c.dataDir, "node-id"))
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading node ID: %v", err))
+			return 1
+		}
+		id := raft.ServerID(strings.TrimSpace(string(nodeID)))
+		found := false
+		for _, server := range configuration.Servers {
+			if server.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.UI.Error(fmt.Sprintf("This server's node ID %q is not in -servers", id))
+			return 1
+		}
+	}
+
+	if c.dryRun {
+		c.UI.Output(string(content))
+		return 0
+	}
+
+	peersFile := filepath.Join(raftDir, "peers.json")
+	if err := os.Rename(tmp.Name(), peersFile); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing peers.json: %v", err))
+		return 1
+	}
+	c.UI.Output(fmt.Sprintf("Wrote %s with %d servers", peersFile, len(configuration.Servers)))
+	c.UI.Output("Write the same file on all remaining servers, then start them to recover the cluster")
+	return 0
+}
+
+// verifyDataDir checks that dir is the data directory of a Consul server that
+// has run before, and returns the path of its Raft directory.
+func verifyDataDir(dir string) (string, error) {
+	raftDir := filepath.Join(dir, "raft")
+	if _, err := os.Stat(filepath.Join(raftDir, "raft.db")); err != nil {
+		return "", fmt.Errorf("%q is not the data directory of a Consul server: %v", dir, err)
+	}
+
+	// Without the peers.info sentinel the server deletes peers.json on
+	// startup instead of ingesting it.
+	if _, err := os.Stat(filepath.Join(raftDir, "peers.info")); err != nil {
+		return "", fmt.Errorf("missing peers.info, the server would ignore peers.json: %v", err)
+	}
+	return raftDir, nil
+}
+
+// peersJSON builds the contents of a peers.json file from the -servers list
+// in the format used by the given Raft protocol version.
+func peersJSON(servers string, protocol int) ([]byte, error) {
+	type configEntry struct {
+		ID       string `json:"id"`
+		Address  string `json:"address"`
+		NonVoter bool   `json:"non_voter"`
+	}
+
+	var addrs []string
+	var entries []configEntry
+	seenIDs := make(map[string]bool)
+	seenAddrs := make(map[string]bool)
+	for _, server := range strings.Split(servers, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+
+		var id string
+		addr := server
+		if protocol >= 3 {
+			parts := strings.SplitN(server, "@", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("server %q must be given as <node-id>@<ip:port>", server)
+			}
+			id, addr = parts[0], parts[1]
+			if _, err := uuid.ParseUUID(id); err != nil {
+				return nil, fmt.Errorf("invalid node ID %q: %v", id, err)
+			}
+			if seenIDs[id] {
+				return nil, fmt.Errorf("duplicate node ID %q", id)
+			}
+			seenIDs[id] = true
+		}
+
+		addr, err := serverAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		if seenAddrs[addr] {
+			return nil, fmt.Errorf("duplicate address %q", addr)
+		}
+		seenAddrs[addr] = true
+
+		addrs = append(addrs, addr)
+		entries = append(entries, configEntry{ID: id, Address: addr})
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no servers given")
+	}
+
+	if protocol < 3 {
+		return json.MarshalIndent(addrs, "", "  ")
+	}
+	return json.MarshalIndent(entries, "", "  ")
+}
+
+// serverAddr validates an address from -servers, adding the default server
+// port if it doesn't have one.
+func serverAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, defaultServerPort
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("address %q must be an IP address", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// readPeersJSON reads back a peers.json file the same way the server does
+// when it recovers.
+func readPeersJSON(path string, protocol int) (raft.Configuration, error) {
+	if protocol < 3 {
+		return raft.ReadPeersJSON(path)
+	}
+	return raft.ReadConfigJSON(path)
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Generate a peers.json file to recover a Consul server"
+const help = `
+Usage: consul operator raft recover [options]
+
+  Writes the raft/peers.json recovery file into the data directory of a
+  stopped Consul server, for manual recovery after an outage that lost quorum.
+
+  The file is generated in the format used by the given Raft protocol version
+  and read back the way the server will read it. The data directory is checked
+  to belong to a server, and with Raft protocol 3 that the server's own node ID
+  is one of the given servers.
+
+  Run this on each remaining server with the same -servers list while they are
+  stopped, then start them. Servers left out must have failed and must not
+  rejoin the cluster later.
+
+    $ consul operator raft recover -data-dir=/opt/consul \
+        -servers=adf4238a-882b-9ddc-4a9d-5b6758e4159e@10.1.0.1:8300,8b6dda82-3103-11e7-93ae-92361f002671@10.1.0.2:8300
+
+  For a full list of options and examples, please see the Consul documentation.
+`
@@ -0,0 +1,204 @@
+package recover
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/raft"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testNodeID  = "adf4238a-882b-9ddc-4a9d-5b6758e4159e"
+	testOtherID = "8b6dda82-3103-11e7-93ae-92361f002671"
+)
+
+// testDataDir makes a data directory that looks like it belongs to a server
+// which has run before.
+func testDataDir(t *testing.T) string {
+	dir := testutil.TempDir(t, "recover")
+	raftDir := filepath.Join(dir, "raft")
+	require.NoError(t, os.MkdirAll(raftDir, 0700))
+	for _, name := range []string{"raft.db", "peers.info"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(raftDir, name), nil, 0600))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "node-id"), []byte(testNodeID), 0600))
+	return dir
+}
+
+func TestOperatorRaftRecoverCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestOperatorRaftRecoverCommand(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	dir := testDataDir(t)
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-data-dir=" + dir,
+		"-servers=" + testNodeID + "@10.1.0.1:8300, " + testOtherID + "@10.1.0.2",
+	}
+	code := c.Run(args)
+	require.Equal(0, code, ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(), "with 2 servers")
+
+	// The file must be readable the way the server reads it.
+	configuration, err := raft.ReadConfigJSON(filepath.Join(dir, "raft", "peers.json"))
+	require.NoError(err)
+	require.Equal([]raft.Server{
+		{Suffrage: raft.Voter, ID: testNodeID, Address: "10.1.0.1:8300"},
+		{Suffrage: raft.Voter, ID: testOtherID, Address: "10.1.0.2:8300"},
+	}, configuration.Servers)
+
+	// No scratch files should be left behind.
+	files, err := ioutil.ReadDir(filepath.Join(dir, "raft"))
+	require.NoError(err)
+	require.Len(files, 3)
+}
+
+func TestOperatorRaftRecoverCommand_Protocol2(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	dir := testDataDir(t)
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-data-dir=" + dir,
+		"-raft-protocol=2",
+		"-servers=10.1.0.1:8300,10.1.0.2:8300",
+	}
+	code := c.Run(args)
+	require.Equal(0, code, ui.ErrorWriter.String())
+
+	configuration, err := raft.ReadPeersJSON(filepath.Join(dir, "raft", "peers.json"))
+	require.NoError(err)
+	require.Equal([]raft.Server{
+		{Suffrage: raft.Voter, ID: "10.1.0.1:8300", Address: "10.1.0.1:8300"},
+		{Suffrage: raft.Voter, ID: "10.1.0.2:8300", Address: "10.1.0.2:8300"},
+	}, configuration.Servers)
+}
+
+func TestOperatorRaftRecoverCommand_DryRun(t *testing.T) {
+	t.Parallel()
+	require := require.New(t)
+	dir := testDataDir(t)
+	defer os.RemoveAll(dir)
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+	args := []string{
+		"-data-dir=" + dir,
+		"-dry-run",
+		"-servers=" + testNodeID + "@10.1.0.1:8300",
+	}
+	code := c.Run(args)
+	require.Equal(0, code, ui.ErrorWriter.String())
+	require.Contains(ui.OutputWriter.String(), `"id": "`+testNodeID+`"`)
+
+	_, err := os.Stat(filepath.Join(dir, "raft", "peers.json"))
+	require.True(os.IsNotExist(err))
+}
+
+func TestOperatorRaftRecoverCommand_Errors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		setup   func(dir string)
+		args    []string
+		wantErr string
+	}{
+		{
+			"missing data dir",
+			nil,
+			[]string{"-servers=" + testNodeID + "@10.1.0.1:8300"},
+			"Missing -data-dir",
+		},
+		{
+			"missing servers",
+			nil,
+			[]string{},
+			"Missing -servers",
+		},
+		{
+			"not a server",
+			func(dir string) { os.Remove(filepath.Join(dir, "raft", "raft.db")) },
+			[]string{"-servers=" + testNodeID + "@10.1.0.1:8300"},
+			"is not the data directory of a Consul server",
+		},
+		{
+			"missing peers.info",
+			func(dir string) { os.Remove(filepath.Join(dir, "raft", "peers.info")) },
+			[]string{"-servers=" + testNodeID + "@10.1.0.1:8300"},
+			"missing peers.info",
+		},
+		{
+			"missing node ID",
+			nil,
+			[]string{"-servers=10.1.0.1:8300"},
+			"must be given as <node-id>@<ip:port>",
+		},
+		{
+			"bad node ID",
+			nil,
+			[]string{"-servers=nope@10.1.0.1:8300"},
+			"invalid node ID",
+		},
+		{
+			"hostname",
+			nil,
+			[]string{"-servers=" + testNodeID + "@server1:8300"},
+			"must be an IP address",
+		},
+		{
+			"duplicate address",
+			nil,
+			[]string{"-servers=" + testNodeID + "@10.1.0.1," + testOtherID + "@10.1.0.1:8300"},
+			"duplicate address",
+		},
+		{
+			"local server not included",
+			nil,
+			[]string{"-servers=" + testOtherID + "@10.1.0.2:8300"},
+			"is not in -servers",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dir := testDataDir(t)
+			defer os.RemoveAll(dir)
+			if tc.setup != nil {
+				tc.setup(dir)
+			}
+
+			ui := cli.NewMockUi()
+			c := New(ui)
+			args := tc.args
+			if tc.name != "missing data dir" {
+				args = append([]string{"-data-dir=" + dir}, args...)
+			}
+			code := c.Run(args)
+			require.Equal(t, 1, code)
+			require.Contains(t, ui.ErrorWriter.String(), tc.wantErr)
+
+			_, err := os.Stat(filepath.Join(dir, "raft", "peers.json"))
+			require.True(t, os.IsNotExist(err))
+		})
+	}
+}
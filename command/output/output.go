@@ -0,0 +1,103 @@
+// Package output implements the -format and -quiet flags which are shared by
+// the commands that print objects. The JSON format and the quiet mode are
+// meant for scripts and are kept stable between releases, whereas the table
+// format is meant for humans and may change at any time.
+package output
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// FormatTable is the human readable format and the default.
+	FormatTable = "table"
+
+	// FormatJSON prints the objects returned by the HTTP API as JSON.
+	FormatJSON = "json"
+)
+
+// Flags holds the output flags of a command. They are added to the flags of
+// a command with flags.Merge(c.flags, c.output.Flags()).
+type Flags struct {
+	format string
+	quiet  bool
+}
+
+// Flags returns the flag set with the -format and -quiet flags.
+func (f *Flags) Flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&f.format, "format", FormatTable,
+		"Output format. Must be one of \"table\" or \"json\". The JSON output "+
+			"is stable between releases and is the one which should be used "+
+			"by scripts. The default value is \"table\".")
+	fs.BoolVar(&f.quiet, "quiet", false,
+		"Only print the identifiers of the results, one per line. This takes "+
+			"precedence over -format. The default value is false.")
+	return fs
+}
+
+// Validate returns an error if the flags have invalid values. It must be
+// called after the flags have been parsed.
+func (f *Flags) Validate() error {
+	switch f.format {
+	case FormatTable, FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("Invalid -format %q, must be one of %q or %q",
+			f.format, FormatTable, FormatJSON)
+	}
+}
+
+// Format returns the selected output format.
+func (f *Flags) Format() string {
+	if f.format == "" {
+		return FormatTable
+	}
+	return f.format
+}
+
+// Quiet returns true if only the identifiers of the results should be
+// printed.
+func (f *Flags) Quiet() bool {
+	return f.quiet
+}
+
+// Table returns true if the output is meant for humans, that is when
+// neither -quiet nor -format=json were given.
+func (f *Flags) Table() bool {
+	return !f.quiet && f.Format() == FormatTable
+}
+
+// Print prints the result of a command. In quiet mode the identifiers in ids
+// are printed one per line, with -format=json v is printed as indented JSON
+// and otherwise table is called to print the human readable output.
+func (f *Flags) Print(ui cli.Ui, v interface{}, ids []string, table func()) error {
+	switch {
+	case f.quiet:
+		for _, id := range ids {
+			ui.Output(id)
+		}
+	case f.Format() == FormatJSON:
+		out, err := JSON(v)
+		if err != nil {
+			return err
+		}
+		ui.Output(out)
+	default:
+		table()
+	}
+	return nil
+}
+
+// JSON returns v encoded as indented JSON.
+func JSON(v interface{}) (string, error) {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode output as JSON: %v", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, args ...string) *Flags {
+	t.Helper()
+	f := &Flags{}
+	require.NoError(t, f.Flags().Parse(args))
+	return f
+}
+
+func TestFlags_Validate(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, parse(t).Validate())
+	require.NoError(t, parse(t, "-format=table").Validate())
+	require.NoError(t, parse(t, "-format=json").Validate())
+
+	err := parse(t, "-format=yaml").Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Invalid -format "yaml"`)
+}
+
+func TestFlags_Print(t *testing.T) {
+	t.Parallel()
+	v := []map[string]string{{"Name": "foo"}, {"Name": "bar"}}
+	ids := []string{"foo", "bar"}
+
+	cases := []struct {
+		args     []string
+		expected string
+		table    bool
+	}{
+		{nil, "table\n", true},
+		{[]string{"-format=json"}, "[\n    {\n        \"Name\": \"foo\"\n    },\n    {\n        \"Name\": \"bar\"\n    }\n]\n", false},
+		{[]string{"-quiet"}, "foo\nbar\n", false},
+		{[]string{"-quiet", "-format=json"}, "foo\nbar\n", false},
+	}
+	for _, tc := range cases {
+		f := parse(t, tc.args...)
+		require.Equal(t, tc.table, f.Table())
+
+		ui := cli.NewMockUi()
+		err := f.Print(ui, v, ids, func() { ui.Output("table") })
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, ui.OutputWriter.String())
+	}
+}
@@ -3,6 +3,7 @@ package reload
 import (
 	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
@@ -39,12 +40,19 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	if err := client.Agent().Reload(); err != nil {
+	result, err := client.Agent().ReloadWithResult()
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error reloading: %s", err))
 		return 1
 	}
 
 	c.UI.Output("Configuration reload triggered")
+	if len(result.Applied) > 0 {
+		c.UI.Output(fmt.Sprintf("Applied changes to: %s", strings.Join(result.Applied, ", ")))
+	}
+	if len(result.Ignored) > 0 {
+		c.UI.Warn(fmt.Sprintf("Changes to the following settings require a restart: %s", strings.Join(result.Ignored, ", ")))
+	}
 	return 0
 }
 
@@ -61,5 +69,6 @@ const help = `
 Usage: consul reload
 
   Causes the agent to reload configurations. This can be used instead
-  of sending the SIGHUP signal to the agent.
+  of sending the SIGHUP signal to the agent. The changed settings are
+  listed along with the ones which only take effect after a restart.
 `
@@ -20,10 +20,15 @@ func TestReloadCommand(t *testing.T) {
 	a := agent.NewTestAgent(t.Name(), ``)
 	defer a.Shutdown()
 
-	// Setup a dummy response to errCh to simulate a successful reload
+	// Setup a dummy response to simulate a successful reload
 	go func() {
-		errCh := <-a.ReloadCh()
-		errCh <- nil
+		respCh := <-a.ReloadCh()
+		respCh <- agent.ReloadResponse{
+			Result: &agent.ReloadResult{
+				Applied: []string{"LogLevel"},
+				Ignored: []string{"NodeName"},
+			},
+		}
 	}()
 
 	ui := cli.NewMockUi()
@@ -38,4 +43,10 @@ func TestReloadCommand(t *testing.T) {
 	if !strings.Contains(ui.OutputWriter.String(), "reload triggered") {
 		t.Fatalf("bad: %#v", ui.OutputWriter.String())
 	}
+	if !strings.Contains(ui.OutputWriter.String(), "Applied changes to: LogLevel") {
+		t.Fatalf("bad: %#v", ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "require a restart: NodeName") {
+		t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+	}
 }
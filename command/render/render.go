@@ -0,0 +1,149 @@
+package render
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/consul/command/flags"
+	consulrender "github.com/hashicorp/consul/render"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui, shutdownCh <-chan struct{}) *cmd {
+	c := &cmd{UI: ui, shutdownCh: shutdownCh}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+
+	shutdownCh <-chan struct{}
+
+	// flags
+	template    string
+	out         string
+	perms       string
+	exec        string
+	execTimeout time.Duration
+	once        bool
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.template, "template", "",
+		"Path of the template to render. This is required.")
+	c.flags.StringVar(&c.out, "out", "",
+		"Path of the rendered file. The file is replaced atomically. This is "+
+			"required.")
+	c.flags.StringVar(&c.perms, "perms", "0644",
+		"Permissions of the rendered file in octal notation.")
+	c.flags.StringVar(&c.exec, "exec", "",
+		"Command to run with a shell whenever the rendered file changes, e.g. "+
+			"to reload a service.")
+	c.flags.DurationVar(&c.execTimeout, "exec-timeout", 30*time.Second,
+		"How long the command may run before it is killed.")
+	c.flags.BoolVar(&c.once, "once", false,
+		"Render the template once and exit instead of rerendering it "+
+			"whenever the data it uses changes.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if c.template == "" || c.out == "" {
+		c.UI.Error("Both -template and -out must be specified")
+		c.UI.Error("")
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	perms, err := strconv.ParseUint(c.perms, 8, 32)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid -perms %q: %s", c.perms, err))
+		return 1
+	}
+
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	logger := log.New(&cli.UiWriter{Ui: c.UI}, "", log.LstdFlags)
+	r, err := consulrender.New(client, &consulrender.Template{
+		Source:         c.template,
+		Destination:    c.out,
+		Perms:          os.FileMode(perms),
+		Command:        c.exec,
+		CommandTimeout: c.execTimeout,
+	}, logger)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if c.once {
+		if err := r.Once(); err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	go func() {
+		<-c.shutdownCh
+		r.Stop()
+	}()
+	r.Run()
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Render a template with data from Consul"
+const help = `
+Usage: consul render [options] -template <path> -out <path>
+
+  Renders a template with data from the KV store and the catalog and
+  rerenders it whenever that data changes. The rendered file is replaced
+  atomically and the command given with -exec is run after every change.
+
+  The template uses the Go text/template syntax with the key, keyOrDefault,
+  ls, service, services, env and toJSON functions:
+
+      upstream web {
+      {{- range service "web" }}
+        server {{ .Address }}:{{ .Port }};
+      {{- end }}
+      }
+
+  Render the template and reload nginx whenever it changes:
+
+      $ consul render -template nginx.conf.tpl -out /etc/nginx/nginx.conf \
+          -exec "nginx -s reload"
+
+  Render the template once and exit:
+
+      $ consul render -template app.conf.tpl -out app.conf -once
+`
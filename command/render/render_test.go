@@ -0,0 +1,83 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi(), nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestRenderCommand_Validation(t *testing.T) {
+	t.Parallel()
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no out": {
+			[]string{"-template=in.tpl"},
+			"Both -template and -out must be specified",
+		},
+		"invalid perms": {
+			[]string{"-template=in.tpl", "-out=out", "-perms=rw"},
+			`Invalid -perms "rw"`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			c := New(ui, nil)
+			require.Equal(t, 1, c.Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.output)
+		})
+	}
+}
+
+func TestRenderCommand_Once(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+
+	_, err := a.Client().KV().Put(&api.KVPair{Key: "app/port", Value: []byte("8080")}, nil)
+	require.NoError(t, err)
+
+	dir := testutil.TempDir(t, "render")
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "in.tpl")
+	require.NoError(t, ioutil.WriteFile(src, []byte(`port = {{ key "app/port" }}`), 0644))
+	dest := filepath.Join(dir, "app.conf")
+	marker := filepath.Join(dir, "reloaded")
+
+	ui := cli.NewMockUi()
+	c := New(ui, nil)
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-template=" + src,
+		"-out=" + dest,
+		"-exec=touch " + marker,
+		"-once",
+	}
+	code := c.Run(args)
+	require.Equal(t, 0, code, ui.ErrorWriter.String())
+
+	out, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "port = 8080", string(out))
+	_, err = os.Stat(marker)
+	require.NoError(t, err)
+}
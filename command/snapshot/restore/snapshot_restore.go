@@ -1,10 +1,14 @@
 package restore
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"text/tabwriter"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/flags"
 	"github.com/mitchellh/cli"
 )
@@ -20,10 +24,16 @@ type cmd struct {
 	flags *flag.FlagSet
 	http  *flags.HTTPFlags
 	help  string
+
+	// flags
+	verifyOnly bool
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.BoolVar(&c.verifyOnly, "verify-only", false,
+		"Check that the snapshot could be restored and print a summary of its "+
+			"contents, without applying it.")
 	c.http = &flags.HTTPFlags{}
 	flags.Merge(c.flags, c.http.ClientFlags())
 	flags.Merge(c.flags, c.http.ServerFlags())
@@ -64,6 +74,10 @@ func (c *cmd) Run(args []string) int {
 	}
 	defer f.Close()
 
+	if c.verifyOnly {
+		return c.verify(client.Snapshot(), f)
+	}
+
 	// Restore the snapshot.
 	err = client.Snapshot().Restore(nil, f)
 	if err != nil {
@@ -75,6 +89,41 @@ func (c *cmd) Run(args []string) int {
 	return 0
 }
 
+// verify checks the snapshot with the servers and prints a summary of its
+// contents.
+func (c *cmd) verify(snap *api.Snapshot, f *os.File) int {
+	summary, err := snap.Verify(nil, f)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error verifying snapshot: %s", err))
+		return 1
+	}
+
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 2, 6, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", summary.ID)
+	fmt.Fprintf(tw, "Size\t%d\n", summary.Size)
+	fmt.Fprintf(tw, "Index\t%d\n", summary.Index)
+	fmt.Fprintf(tw, "Term\t%d\n", summary.Term)
+	fmt.Fprintf(tw, "Version\t%d\n", summary.Version)
+
+	names := make([]string, 0, len(summary.Records))
+	for name := range summary.Records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\n", name, summary.Records[name])
+	}
+	if err := tw.Flush(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error rendering snapshot info: %s", err))
+		return 1
+	}
+
+	c.UI.Info(b.String())
+	c.UI.Info("Snapshot verified, it was not restored")
+	return 0
+}
+
 func (c *cmd) Synopsis() string {
 	return synopsis
 }
@@ -103,5 +152,9 @@ Usage: consul snapshot restore [options] FILE
 
     $ consul snapshot restore backup.snap
 
+  To check that "backup.snap" could be restored without restoring it:
+
+    $ consul snapshot restore -verify-only backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 `
@@ -0,0 +1,184 @@
+package create
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/lib/file"
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI                    cli.Ui
+	flags                 *flag.FlagSet
+	help                  string
+	days                  int
+	domain                string
+	constraint            bool
+	additionalConstraints flags.AppendSliceValue
+	intermediate          bool
+	signingCA             string
+	signingKey            string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.IntVar(&c.days, "days", 1825, "Provide number of days the CA is valid for from now on. Defaults to 5 years.")
+	c.flags.BoolVar(&c.constraint, "name-constraint", false, "Add name constraints for the CA. Results in rejecting "+
+		"certificates for other DNS than specified. If turned on localhost and -domain will be added to the allowed "+
+		"DNS. If the UI is going to be served over HTTPS its DNS has to be added with -additional-name-constraint. It is not "+
+		"possible to add that after the fact! Defaults to false.")
+	c.flags.StringVar(&c.domain, "domain", "consul", "Domain of consul cluster. Only used in combination with -name-constraint. Defaults to consul.")
+	c.flags.Var(&c.additionalConstraints, "additional-name-constraint", "Add name constraints for the CA. Results in rejecting certificates "+
+		"for other DNS than specified. Can be used multiple times. Only used in combination with -name-constraint.")
+	c.flags.BoolVar(&c.intermediate, "intermediate", false, "Create an intermediate CA which is signed by the CA "+
+		"given with -signing-ca and -signing-key instead of a self-signed root CA. Certificates are then signed by "+
+		"the intermediate, so that the key of the root CA can be kept offline.")
+	c.flags.StringVar(&c.signingCA, "signing-ca", "#DOMAIN#-agent-ca.pem", "Provide path to the CA which signs the "+
+		"intermediate CA. Only used in combination with -intermediate. Defaults to #DOMAIN#-agent-ca.pem.")
+	c.flags.StringVar(&c.signingKey, "signing-key", "#DOMAIN#-agent-ca-key.pem", "Provide path to the key of the CA "+
+		"which signs the intermediate CA. Only used in combination with -intermediate. Defaults to "+
+		"#DOMAIN#-agent-ca-key.pem.")
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error("Should have no non-flag arguments.")
+		return 1
+	}
+	if c.days <= 0 {
+		c.UI.Error("-days must be greater than zero")
+		return 1
+	}
+
+	prefix := fmt.Sprintf("%s-agent-ca", c.domain)
+	if c.intermediate {
+		prefix = fmt.Sprintf("%s-agent-intermediate-ca", c.domain)
+	}
+	certFileName := prefix + ".pem"
+	pkFileName := prefix + "-key.pem"
+
+	if _, err := os.Stat(certFileName); !os.IsNotExist(err) {
+		c.UI.Error(certFileName + " already exists.")
+		return 1
+	}
+	if _, err := os.Stat(pkFileName); !os.IsNotExist(err) {
+		c.UI.Error(pkFileName + " already exists.")
+		return 1
+	}
+
+	sn, err := tlsutil.GenerateSerialNumber()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	s, pk, err := tlsutil.GeneratePrivateKey()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	var constraints []string
+	if c.constraint {
+		constraints = append([]string{c.domain, "localhost"}, c.additionalConstraints...)
+	}
+
+	var ca string
+	if c.intermediate {
+		signingCAFile := strings.Replace(c.signingCA, "#DOMAIN#", c.domain, 1)
+		signingKeyFile := strings.Replace(c.signingKey, "#DOMAIN#", c.domain, 1)
+		signingCA, err := ioutil.ReadFile(signingCAFile)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading the signing CA: %s", err))
+			return 1
+		}
+		signingKey, err := ioutil.ReadFile(signingKeyFile)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error reading the signing CA key: %s", err))
+			return 1
+		}
+
+		ca, err = tlsutil.GenerateIntermediateCA(s, sn, c.days, constraints, string(signingCA), string(signingKey))
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+
+		// If the signing CA is an intermediate itself, its chain has to
+		// follow the new intermediate so that clients can verify it.
+		chain, err := tlsutil.CertChain(string(signingCA))
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error parsing the signing CA: %s", err))
+			return 1
+		}
+		ca += chain
+	} else {
+		ca, err = tlsutil.GenerateCA(s, sn, c.days, constraints)
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 1
+		}
+	}
+
+	if err := file.WriteAtomicWithPerms(certFileName, []byte(ca), 0755, 0644); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output("==> Saved " + certFileName)
+
+	if err := file.WriteAtomicWithPerms(pkFileName, []byte(pk), 0755, 0600); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output("==> Saved " + pkFileName)
+
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Create a new consul CA"
+const help = `
+Usage: consul tls ca create [options]
+
+  Create a new consul CA:
+
+  $ consul tls ca create
+  ==> Saved consul-agent-ca.pem
+  ==> Saved consul-agent-ca-key.pem
+
+  Create an intermediate CA signed by the CA, so that the key of the CA can
+  be kept offline:
+
+  $ consul tls ca create -intermediate
+  ==> Saved consul-agent-intermediate-ca.pem
+  ==> Saved consul-agent-intermediate-ca-key.pem
+
+  The intermediate CA is then used to sign certificates with
+  'consul tls cert create -ca consul-agent-intermediate-ca.pem
+  -key consul-agent-intermediate-ca-key.pem'.
+`
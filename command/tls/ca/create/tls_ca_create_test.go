@@ -0,0 +1,96 @@
+package create
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSCACreateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+// testChdir changes into a new temporary directory and returns a func which
+// changes back and removes it.
+func testChdir(t *testing.T) func() {
+	t.Helper()
+	dir := testutil.TempDir(t, "tls")
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestTLSCACreateCommand(t *testing.T) {
+	defer testChdir(t)()
+
+	ui := cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-name-constraint"}), ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "==> Saved consul-agent-ca.pem")
+
+	ca, err := ioutil.ReadFile("consul-agent-ca.pem")
+	require.NoError(t, err)
+	cert, err := connect.ParseCert(string(ca))
+	require.NoError(t, err)
+	require.True(t, cert.IsCA)
+	require.Equal(t, []string{"consul", "localhost"}, cert.PermittedDNSDomains)
+
+	info, err := os.Stat("consul-agent-ca-key.pem")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// An existing CA is never overwritten.
+	ui = cli.NewMockUi()
+	require.Equal(t, 1, New(ui).Run(nil))
+	require.Contains(t, ui.ErrorWriter.String(), "consul-agent-ca.pem already exists.")
+}
+
+func TestTLSCACreateCommand_Intermediate(t *testing.T) {
+	defer testChdir(t)()
+
+	ui := cli.NewMockUi()
+	require.Equal(t, 1, New(ui).Run([]string{"-intermediate"}))
+	require.Contains(t, ui.ErrorWriter.String(), "Error reading the signing CA")
+
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run(nil), ui.ErrorWriter.String())
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-intermediate"}), ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "==> Saved consul-agent-intermediate-ca.pem")
+	require.Contains(t, ui.OutputWriter.String(), "==> Saved consul-agent-intermediate-ca-key.pem")
+
+	root, err := ioutil.ReadFile("consul-agent-ca.pem")
+	require.NoError(t, err)
+	inter, err := ioutil.ReadFile("consul-agent-intermediate-ca.pem")
+	require.NoError(t, err)
+
+	rootCert, err := connect.ParseCert(string(root))
+	require.NoError(t, err)
+	interCert, err := connect.ParseCert(string(inter))
+	require.NoError(t, err)
+	require.True(t, interCert.IsCA)
+	require.NoError(t, interCert.CheckSignatureFrom(rootCert))
+	require.Equal(t, 1, strings.Count(string(inter), "BEGIN CERTIFICATE"))
+
+	// An intermediate signed by an intermediate contains the chain.
+	require.NoError(t, os.Rename("consul-agent-intermediate-ca.pem", "inter.pem"))
+	require.NoError(t, os.Rename("consul-agent-intermediate-ca-key.pem", "inter-key.pem"))
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-intermediate", "-signing-ca", "inter.pem", "-signing-key", "inter-key.pem"}), ui.ErrorWriter.String())
+	inter2, err := ioutil.ReadFile("consul-agent-intermediate-ca.pem")
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(inter2), "BEGIN CERTIFICATE"))
+	require.True(t, strings.HasSuffix(string(inter2), string(inter)))
+}
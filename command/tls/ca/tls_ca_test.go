@@ -0,0 +1,13 @@
+package ca
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLSCACommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New().Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
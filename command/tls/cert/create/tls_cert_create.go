@@ -0,0 +1,266 @@
+package create
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/hashicorp/consul/lib/file"
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/mitchellh/cli"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI          cli.Ui
+	flags       *flag.FlagSet
+	ca          string
+	key         string
+	server      bool
+	client      bool
+	cli         bool
+	dc          string
+	days        int
+	domain      string
+	help        string
+	dnsnames    flags.AppendSliceValue
+	ipaddresses flags.AppendSliceValue
+	trustDomain string
+	node        string
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.ca, "ca", "#DOMAIN#-agent-ca.pem", "Provide path to the ca. If the file contains an "+
+		"intermediate CA followed by its chain, the chain is appended to the certificate. Defaults to #DOMAIN#-agent-ca.pem.")
+	c.flags.StringVar(&c.key, "key", "#DOMAIN#-agent-ca-key.pem", "Provide path to the key. Defaults to #DOMAIN#-agent-ca-key.pem.")
+	c.flags.BoolVar(&c.server, "server", false, "Generate server certificate.")
+	c.flags.BoolVar(&c.client, "client", false, "Generate client certificate.")
+	c.flags.BoolVar(&c.cli, "cli", false, "Generate cli certificate.")
+	c.flags.IntVar(&c.days, "days", 365, "Provide number of days the certificate is valid for from now on. Defaults to 1 year.")
+	c.flags.StringVar(&c.dc, "dc", "dc1", "Provide the datacenter, which is part of the name of the certificate. Defaults to dc1.")
+	c.flags.StringVar(&c.domain, "domain", "consul", "Provide the domain, which is part of the name of the certificate. Defaults to consul.")
+	c.flags.Var(&c.dnsnames, "additional-dnsname", "Provide an additional dnsname for Subject Alternative Names. "+
+		"localhost is always included. This flag may be provided multiple times.")
+	c.flags.Var(&c.ipaddresses, "additional-ipaddress", "Provide an additional ipaddress for Subject Alternative Names. "+
+		"127.0.0.1 and ::1 are always included. This flag may be provided multiple times.")
+	c.flags.StringVar(&c.trustDomain, "spiffe-trust-domain", "", "Provide the SPIFFE trust domain to include "+
+		"the SPIFFE ID of the agent, spiffe://<trust-domain>/agent/<role>/<dc>/<node>, in the Subject Alternative Names. "+
+		"The trust domain of Connect is shown by the CA roots endpoint. Requires -node.")
+	c.flags.StringVar(&c.node, "node", "", "Provide the name of the node, which is part of the SPIFFE ID "+
+		"of the agent.")
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		c.UI.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+	if len(c.flags.Args()) > 0 {
+		c.UI.Error("Should have no non-flag arguments.")
+		return 1
+	}
+	if c.ca == "" {
+		c.UI.Error("Please provide the ca")
+		return 1
+	}
+	if c.key == "" {
+		c.UI.Error("Please provide the key")
+		return 1
+	}
+	if c.days <= 0 {
+		c.UI.Error("-days must be greater than zero")
+		return 1
+	}
+
+	var kinds int
+	for _, b := range []bool{c.server, c.client, c.cli} {
+		if b {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		c.UI.Error("Please provide exactly one of -server, -client or -cli")
+		return 1
+	}
+
+	if c.node != "" && c.trustDomain == "" {
+		c.UI.Error("-node requires -spiffe-trust-domain")
+		return 1
+	}
+	if c.trustDomain != "" && c.node == "" {
+		c.UI.Error("-spiffe-trust-domain requires -node")
+		return 1
+	}
+
+	var dnsNames []string
+	var ipAddresses []net.IP
+	var uris []*url.URL
+	var extKeyUsage []x509.ExtKeyUsage
+	var name, kind string
+
+	for _, d := range c.dnsnames {
+		if len(d) > 0 {
+			dnsNames = append(dnsNames, strings.TrimSpace(d))
+		}
+	}
+	for _, i := range c.ipaddresses {
+		if len(i) > 0 {
+			ip := net.ParseIP(strings.TrimSpace(i))
+			if ip == nil {
+				c.UI.Error(fmt.Sprintf("%q is not a valid IP address", i))
+				return 1
+			}
+			ipAddresses = append(ipAddresses, ip)
+		}
+	}
+
+	switch {
+	case c.server:
+		kind = "server"
+		name = fmt.Sprintf("server.%s.%s", c.dc, c.domain)
+		dnsNames = append([]string{name, "localhost"}, dnsNames...)
+		ipAddresses = append([]net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback}, ipAddresses...)
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	case c.client:
+		kind = "client"
+		name = fmt.Sprintf("client.%s.%s", c.dc, c.domain)
+		dnsNames = append([]string{name, "localhost"}, dnsNames...)
+		ipAddresses = append([]net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback}, ipAddresses...)
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	case c.cli:
+		kind = "cli"
+		name = fmt.Sprintf("cli.%s.%s", c.dc, c.domain)
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	if c.trustDomain != "" {
+		id := &connect.SpiffeIDAgent{
+			Host:       c.trustDomain,
+			Role:       kind,
+			Datacenter: c.dc,
+			Node:       c.node,
+		}
+		// Values which don't survive the round trip, like a trust domain with
+		// a path or a node with a slash, would make an unusable ID.
+		parsed, err := connect.ParseCertURIFromString(id.URI().String())
+		if agentID, ok := parsed.(*connect.SpiffeIDAgent); err != nil || !ok || *agentID != *id {
+			c.UI.Error(fmt.Sprintf("%q is not a valid SPIFFE ID", id.URI()))
+			return 1
+		}
+		uris = append(uris, id.URI())
+	}
+
+	prefix := fmt.Sprintf("%s-%s-%s", c.dc, kind, c.domain)
+	var certFileName, pkFileName string
+	for i := 0; ; i++ {
+		certFileName = fmt.Sprintf("%s-%d.pem", prefix, i)
+		pkFileName = fmt.Sprintf("%s-%d-key.pem", prefix, i)
+		if _, err := os.Stat(certFileName); os.IsNotExist(err) {
+			if _, err := os.Stat(pkFileName); os.IsNotExist(err) {
+				break
+			}
+		}
+	}
+
+	caFile := strings.Replace(c.ca, "#DOMAIN#", c.domain, 1)
+	keyFile := strings.Replace(c.key, "#DOMAIN#", c.domain, 1)
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading CA: %s", err))
+		return 1
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading CA key: %s", err))
+		return 1
+	}
+
+	sn, err := tlsutil.GenerateSerialNumber()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	pub, priv, err := tlsutil.GenerateCert(string(ca), string(key), sn, name, c.days, dnsNames, ipAddresses, uris, extKeyUsage)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	// If the certificate is signed by an intermediate CA, the intermediates
+	// are appended so that the other agents can verify it with the root CA.
+	chain, err := tlsutil.CertChain(string(ca))
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error parsing CA: %s", err))
+		return 1
+	}
+	pub += chain
+
+	if err := file.WriteAtomicWithPerms(certFileName, []byte(pub), 0755, 0644); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output("==> Saved " + certFileName)
+
+	if err := file.WriteAtomicWithPerms(pkFileName, []byte(priv), 0755, 0600); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	c.UI.Output("==> Saved " + pkFileName)
+
+	return 0
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Create a new certificate"
+const help = `
+Usage: consul tls cert create [options]
+
+  Create a new certificate
+
+  $ consul tls cert create -server
+  ==> Saved dc1-server-consul-0.pem
+  ==> Saved dc1-server-consul-0-key.pem
+  $ consul tls cert create -client
+  ==> Saved dc1-client-consul-0.pem
+  ==> Saved dc1-client-consul-0-key.pem
+
+  Certificates signed by an intermediate CA contain the chain up to the
+  root CA:
+
+  $ consul tls cert create -server -ca consul-agent-intermediate-ca.pem \
+      -key consul-agent-intermediate-ca-key.pem
+  ==> Saved dc1-server-consul-1.pem
+  ==> Saved dc1-server-consul-1-key.pem
+
+  Certificates can identify the agent with a SPIFFE ID in the trust domain
+  of Connect, for systems which authenticate SPIFFE IDs:
+
+  $ consul tls cert create -client -node web-1 \
+      -spiffe-trust-domain 7b1e8b5c-5f48-4c2a-a3c6-2a3ef5ac1e35.consul
+  ==> Saved dc1-client-consul-0.pem
+  ==> Saved dc1-client-consul-0-key.pem
+`
@@ -0,0 +1,185 @@
+package create
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/tlsutil"
+	"github.com/mitchellh/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSCertCreateCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(cli.NewMockUi()).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+// testChdir changes into a new temporary directory and returns a func which
+// changes back and removes it.
+func testChdir(t *testing.T) func() {
+	t.Helper()
+	dir := testutil.TempDir(t, "tls")
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+// writeCA writes a root CA and an intermediate CA signed by it and returns
+// the PEM of the root.
+func writeCA(t *testing.T) string {
+	t.Helper()
+	s, pk, err := tlsutil.GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err := tlsutil.GenerateSerialNumber()
+	require.NoError(t, err)
+	ca, err := tlsutil.GenerateCA(s, sn, 365, nil)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile("consul-agent-ca.pem", []byte(ca), 0644))
+	require.NoError(t, ioutil.WriteFile("consul-agent-ca-key.pem", []byte(pk), 0600))
+
+	s, ipk, err := tlsutil.GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err = tlsutil.GenerateSerialNumber()
+	require.NoError(t, err)
+	inter, err := tlsutil.GenerateIntermediateCA(s, sn, 365, nil, ca, pk)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile("inter.pem", []byte(inter), 0644))
+	require.NoError(t, ioutil.WriteFile("inter-key.pem", []byte(ipk), 0600))
+	return ca
+}
+
+func verify(t *testing.T, root, file, name string, usage x509.ExtKeyUsage) {
+	t.Helper()
+	pem, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	cert, err := connect.ParseCert(string(pem))
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM([]byte(root)))
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM(pem)
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	require.NoError(t, err)
+}
+
+func TestTLSCertCreateCommand_InvalidArgs(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args []string
+		err  string
+	}{
+		"no kind":        {[]string{}, "Please provide exactly one of -server, -client or -cli"},
+		"multiple kinds": {[]string{"-server", "-cli"}, "Please provide exactly one of -server, -client or -cli"},
+		"no ca":          {[]string{"-server", "-ca", ""}, "Please provide the ca"},
+		"no key":         {[]string{"-server", "-key", ""}, "Please provide the key"},
+		"invalid days":   {[]string{"-server", "-days", "0"}, "-days must be greater than zero"},
+		"invalid ip":     {[]string{"-server", "-additional-ipaddress", "foo"}, `"foo" is not a valid IP address`},
+		"no node":        {[]string{"-server", "-spiffe-trust-domain", "1234.consul"}, "-spiffe-trust-domain requires -node"},
+		"no domain":      {[]string{"-server", "-node", "node1"}, "-node requires -spiffe-trust-domain"},
+		"invalid spiffe id": {
+			[]string{"-server", "-spiffe-trust-domain", "1234.consul", "-node", "node/1"},
+			`"spiffe://1234.consul/agent/server/dc1/node/1" is not a valid SPIFFE ID`,
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			ui := cli.NewMockUi()
+			require.Equal(t, 1, New(ui).Run(tc.args))
+			require.Contains(t, ui.ErrorWriter.String(), tc.err)
+		})
+	}
+}
+
+func TestTLSCertCreateCommand(t *testing.T) {
+	defer testChdir(t)()
+	root := writeCA(t)
+
+	ui := cli.NewMockUi()
+	args := []string{"-server", "-additional-dnsname", "consul.example.com", "-additional-ipaddress", "10.0.0.1"}
+	require.Equal(t, 0, New(ui).Run(args), ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "==> Saved dc1-server-consul-0.pem")
+	verify(t, root, "dc1-server-consul-0.pem", "server.dc1.consul", x509.ExtKeyUsageServerAuth)
+	verify(t, root, "dc1-server-consul-0.pem", "consul.example.com", x509.ExtKeyUsageClientAuth)
+
+	pem, err := ioutil.ReadFile("dc1-server-consul-0.pem")
+	require.NoError(t, err)
+	cert, err := connect.ParseCert(string(pem))
+	require.NoError(t, err)
+	require.Len(t, cert.IPAddresses, 3)
+	require.Equal(t, "127.0.0.1", cert.IPAddresses[0].String())
+	require.Equal(t, "::1", cert.IPAddresses[1].String())
+	require.Equal(t, "10.0.0.1", cert.IPAddresses[2].String())
+
+	info, err := os.Stat("dc1-server-consul-0-key.pem")
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The next certificate gets a new file.
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-server"}), ui.ErrorWriter.String())
+	require.Contains(t, ui.OutputWriter.String(), "==> Saved dc1-server-consul-1.pem")
+
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-cli", "-dc", "dc2"}), ui.ErrorWriter.String())
+	verify(t, root, "dc2-cli-consul-0.pem", "", x509.ExtKeyUsageClientAuth)
+}
+
+func TestTLSCertCreateCommand_Intermediate(t *testing.T) {
+	defer testChdir(t)()
+	root := writeCA(t)
+
+	ui := cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-client", "-ca", "inter.pem", "-key", "inter-key.pem"}), ui.ErrorWriter.String())
+
+	// The certificate contains the intermediate, so it can be verified with
+	// the root CA only.
+	cert, err := ioutil.ReadFile("dc1-client-consul-0.pem")
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(cert), "BEGIN CERTIFICATE"))
+	verify(t, root, "dc1-client-consul-0.pem", "client.dc1.consul", x509.ExtKeyUsageServerAuth)
+}
+
+func TestTLSCertCreateCommand_SpiffeID(t *testing.T) {
+	defer testChdir(t)()
+	root := writeCA(t)
+
+	ui := cli.NewMockUi()
+	args := []string{"-client", "-dc", "dc2", "-node", "node1", "-spiffe-trust-domain", "1234.consul"}
+	require.Equal(t, 0, New(ui).Run(args), ui.ErrorWriter.String())
+	verify(t, root, "dc2-client-consul-0.pem", "client.dc2.consul", x509.ExtKeyUsageClientAuth)
+
+	pem, err := ioutil.ReadFile("dc2-client-consul-0.pem")
+	require.NoError(t, err)
+	cert, err := connect.ParseCert(string(pem))
+	require.NoError(t, err)
+	require.Len(t, cert.URIs, 1)
+	require.Equal(t, "spiffe://1234.consul/agent/client/dc2/node1", cert.URIs[0].String())
+
+	// Certificates have no URI without the flag.
+	ui = cli.NewMockUi()
+	require.Equal(t, 0, New(ui).Run([]string{"-client", "-dc", "dc2"}), ui.ErrorWriter.String())
+	pem, err = ioutil.ReadFile("dc2-client-consul-1.pem")
+	require.NoError(t, err)
+	cert, err = connect.ParseCert(string(pem))
+	require.NoError(t, err)
+	require.Empty(t, cert.URIs)
+}
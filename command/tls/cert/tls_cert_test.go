@@ -0,0 +1,13 @@
+package cert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLSCertCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New().Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
@@ -0,0 +1,13 @@
+package tls
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTLSCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New().Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
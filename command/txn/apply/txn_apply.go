@@ -0,0 +1,195 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
+)
+
+func New(ui cli.Ui) *cmd {
+	c := &cmd{UI: ui}
+	c.init()
+	return c
+}
+
+type cmd struct {
+	UI    cli.Ui
+	flags *flag.FlagSet
+	http  *flags.HTTPFlags
+	help  string
+	file  string
+
+	// testStdin is the input for testing.
+	testStdin io.Reader
+}
+
+func (c *cmd) init() {
+	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
+	c.flags.StringVar(&c.file, "file", "",
+		"Path to a JSON file with the operations of the transaction, in the "+
+			"format of the /v1/txn endpoint. If this is \"-\", the operations "+
+			"are read from stdin.")
+
+	c.http = &flags.HTTPFlags{}
+	flags.Merge(c.flags, c.http.ClientFlags())
+	flags.Merge(c.flags, c.http.ServerFlags())
+	c.help = flags.Usage(help, c.flags)
+}
+
+func (c *cmd) Run(args []string) int {
+	if err := c.flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	if args := c.flags.Args(); len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+	if c.file == "" {
+		c.UI.Error("Error! Missing -file argument")
+		return 1
+	}
+
+	ops, err := c.readOps()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error! %s", err))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.http.APIClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	ok, resp, _, err := client.KV().Txn(ops, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error applying transaction: %s", err))
+		return 1
+	}
+
+	if !ok {
+		c.UI.Error(fmt.Sprintf("Error! Transaction rolled back, none of the %d operations were applied:", len(ops)))
+		for _, e := range resp.Errors {
+			op := "unknown operation"
+			if e.OpIndex >= 0 && e.OpIndex < len(ops) {
+				op = fmt.Sprintf("%s %q", ops[e.OpIndex].Verb, ops[e.OpIndex].Key)
+			}
+			c.UI.Error(fmt.Sprintf("  Operation %d (%s): %s", e.OpIndex, op, e.What))
+		}
+		return 1
+	}
+
+	if len(resp.Results) > 0 {
+		result := []string{"Key\x1fCreateIndex\x1fModifyIndex\x1fValue"}
+		for _, kv := range resp.Results {
+			if kv == nil {
+				continue
+			}
+			result = append(result, fmt.Sprintf("%s\x1f%d\x1f%d\x1f%s",
+				kv.Key, kv.CreateIndex, kv.ModifyIndex, kv.Value))
+		}
+		c.UI.Output(columnize.Format(result, &columnize.Config{Delim: string([]byte{0x1f})}))
+	}
+	c.UI.Info(fmt.Sprintf("Success! Transaction applied with %d operations", len(ops)))
+	return 0
+}
+
+// readOps reads the operations of the transaction from the file, or from
+// stdin. Only KV operations are supported.
+func (c *cmd) readOps() (api.KVTxnOps, error) {
+	var data []byte
+	var err error
+	if c.file == "-" {
+		var stdin io.Reader = os.Stdin
+		if c.testStdin != nil {
+			stdin = c.testStdin
+		}
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, stdin); err != nil {
+			return nil, fmt.Errorf("Failed to read stdin: %s", err)
+		}
+		data = b.Bytes()
+	} else {
+		data, err = ioutil.ReadFile(c.file)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read file: %s", err)
+		}
+	}
+
+	var txnOps api.TxnOps
+	if err := json.Unmarshal(data, &txnOps); err != nil {
+		return nil, fmt.Errorf("Failed to decode operations: %s", err)
+	}
+	if len(txnOps) == 0 {
+		return nil, fmt.Errorf("No operations to apply")
+	}
+
+	ops := make(api.KVTxnOps, 0, len(txnOps))
+	for i, op := range txnOps {
+		if op == nil || op.KV == nil {
+			return nil, fmt.Errorf("Operation %d is not a KV operation", i)
+		}
+		ops = append(ops, op.KV)
+	}
+	return ops, nil
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return c.help
+}
+
+const synopsis = "Applies the operations of a file in an atomic transaction"
+const help = `
+Usage: consul txn apply [options] -file=<path>
+
+  Applies a list of KV operations in a single, atomic transaction. The
+  operations are read from a JSON file in the format of the /v1/txn endpoint,
+  where values are base64 encoded:
+
+      [
+        {
+          "KV": {
+            "Verb": "cas",
+            "Key": "redis/config/connections",
+            "Value": "NQ==",
+            "Index": 12
+          }
+        },
+        {
+          "KV": {
+            "Verb": "delete",
+            "Key": "redis/config/timeout"
+          }
+        }
+      ]
+
+  To apply the operations of the file "ops.json":
+
+      $ consul txn apply -file ops.json
+
+  Or to read them from stdin:
+
+      $ cat ops.json | consul txn apply -file -
+
+  If any operation fails, none of the operations are applied and the failed
+  operations are reported. The command then exits with a non-zero status.
+  Otherwise the keys returned by the operations are listed.
+
+  For a full list of options and examples, please see the Consul documentation.
+`
@@ -0,0 +1,175 @@
+package apply
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/agent"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func TestTxnApplyCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New(nil).Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
+
+func TestTxnApplyCommand_Validation(t *testing.T) {
+	t.Parallel()
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	dir := testutil.TempDir(t, "txn")
+	defer os.RemoveAll(dir)
+	notKV := filepath.Join(dir, "not-kv.json")
+	if err := ioutil.WriteFile(notKV, []byte(`[{"KV": {"Verb": "get", "Key": "foo"}}, {}]`), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	empty := filepath.Join(dir, "empty.json")
+	if err := ioutil.WriteFile(empty, []byte(`[]`), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no file": {
+			[]string{},
+			"Missing -file argument",
+		},
+		"extra args": {
+			[]string{"-file", notKV, "foo"},
+			"Too many arguments",
+		},
+		"missing file": {
+			[]string{"-file", filepath.Join(dir, "nope.json")},
+			"Failed to read file",
+		},
+		"not kv": {
+			[]string{"-file", notKV},
+			"Operation 1 is not a KV operation",
+		},
+		"no operations": {
+			[]string{"-file", empty},
+			"No operations to apply",
+		},
+	}
+
+	for name, tc := range cases {
+		c.init()
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestTxnApplyCommand(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	if _, err := client.KV().Put(&api.KVPair{Key: "zip", Value: []byte("zap")}, nil); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+
+	// "YmFy" is "bar" base64 encoded.
+	c.testStdin = strings.NewReader(`[
+		{"KV": {"Verb": "set", "Key": "foo", "Value": "YmFy"}},
+		{"KV": {"Verb": "delete", "Key": "zip"}},
+		{"KV": {"Verb": "get", "Key": "foo"}}
+	]`)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-file", "-",
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "bar") || !strings.Contains(output, "applied with 3 operations") {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	pair, _, err := client.KV().Get("foo", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if pair == nil || string(pair.Value) != "bar" {
+		t.Fatalf("bad: %#v", pair)
+	}
+	pair, _, err = client.KV().Get("zip", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if pair != nil {
+		t.Fatalf("bad: %#v", pair)
+	}
+}
+
+func TestTxnApplyCommand_Rollback(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	client := a.Client()
+
+	ui := cli.NewMockUi()
+	c := New(ui)
+
+	c.testStdin = strings.NewReader(`[
+		{"KV": {"Verb": "set", "Key": "foo", "Value": "YmFy"}},
+		{"KV": {"Verb": "get", "Key": "missing"}}
+	]`)
+
+	args := []string{
+		"-http-addr=" + a.HTTPAddr(),
+		"-file", "-",
+	}
+
+	code := c.Run(args)
+	if code == 0 {
+		t.Fatalf("bad: expected error")
+	}
+
+	output := ui.ErrorWriter.String()
+	if !strings.Contains(output, "rolled back") ||
+		!strings.Contains(output, `Operation 1 (get "missing"): key "missing" doesn't exist`) {
+		t.Fatalf("bad: %#v", output)
+	}
+
+	pair, _, err := client.KV().Get("foo", nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if pair != nil {
+		t.Fatalf("bad: %#v", pair)
+	}
+}
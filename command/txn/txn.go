@@ -0,0 +1,39 @@
+package txn
+
+import (
+	"github.com/hashicorp/consul/command/flags"
+	"github.com/mitchellh/cli"
+)
+
+func New() *cmd {
+	return &cmd{}
+}
+
+type cmd struct{}
+
+func (c *cmd) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *cmd) Synopsis() string {
+	return synopsis
+}
+
+func (c *cmd) Help() string {
+	return flags.Usage(help, nil)
+}
+
+const synopsis = "Applies atomic transactions to the KV store"
+const help = `
+Usage: consul txn <subcommand> [options] [args]
+
+  This command has subcommands for applying multiple operations on Consul's
+  key-value store in a single, atomic transaction. Either all the operations
+  are applied or, if any of them fails, none are.
+
+  Apply the operations of a file:
+
+      $ consul txn apply -file ops.json
+
+  For more examples, ask for subcommand help or view the documentation.
+`
@@ -0,0 +1,13 @@
+package txn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTxnCommand_noTabs(t *testing.T) {
+	t.Parallel()
+	if strings.ContainsRune(New().Help(), '\t') {
+		t.Fatal("help has tabs")
+	}
+}
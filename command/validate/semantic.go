@@ -0,0 +1,263 @@
+package validate
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/agent/config"
+)
+
+// deprecatedFields maps the deprecated top-level config keys to the keys
+// which replace them. An empty replacement means the key has no successor.
+// acl_datacenter is not listed since the builder already warns about it.
+var deprecatedFields = []struct {
+	name        string
+	replacement string
+	set         func(c config.Config) bool
+}{
+	{"acl_agent_master_token", "acl.tokens.agent_master", func(c config.Config) bool { return c.ACLAgentMasterToken != nil }},
+	{"acl_agent_token", "acl.tokens.agent", func(c config.Config) bool { return c.ACLAgentToken != nil }},
+	{"acl_default_policy", "acl.default_policy", func(c config.Config) bool { return c.ACLDefaultPolicy != nil }},
+	{"acl_down_policy", "acl.down_policy", func(c config.Config) bool { return c.ACLDownPolicy != nil }},
+	{"acl_enable_key_list_policy", "acl.enable_key_list_policy", func(c config.Config) bool { return c.ACLEnableKeyListPolicy != nil }},
+	{"acl_enforce_version_8", "", func(c config.Config) bool { return c.ACLEnforceVersion8 != nil }},
+	{"acl_master_token", "acl.tokens.master", func(c config.Config) bool { return c.ACLMasterToken != nil }},
+	{"acl_replication_token", "acl.tokens.replication", func(c config.Config) bool { return c.ACLReplicationToken != nil }},
+	{"acl_ttl", "acl.token_ttl", func(c config.Config) bool { return c.ACLTTL != nil }},
+	{"acl_token", "acl.tokens.default", func(c config.Config) bool { return c.ACLToken != nil }},
+}
+
+// tokenFields lists the config keys which hold ACL token secrets.
+var tokenFields = []struct {
+	name  string
+	value func(c config.Config) *string
+}{
+	{"acl.tokens.master", func(c config.Config) *string { return c.ACL.Tokens.Master }},
+	{"acl.tokens.replication", func(c config.Config) *string { return c.ACL.Tokens.Replication }},
+	{"acl.tokens.agent_master", func(c config.Config) *string { return c.ACL.Tokens.AgentMaster }},
+	{"acl.tokens.default", func(c config.Config) *string { return c.ACL.Tokens.Default }},
+	{"acl.tokens.agent", func(c config.Config) *string { return c.ACL.Tokens.Agent }},
+	{"acl_agent_master_token", func(c config.Config) *string { return c.ACLAgentMasterToken }},
+	{"acl_agent_token", func(c config.Config) *string { return c.ACLAgentToken }},
+	{"acl_master_token", func(c config.Config) *string { return c.ACLMasterToken }},
+	{"acl_replication_token", func(c config.Config) *string { return c.ACLReplicationToken }},
+	{"acl_token", func(c config.Config) *string { return c.ACLToken }},
+}
+
+// semanticChecker performs checks on a configuration which are not
+// fatal for the agent but usually point to a mistake. Every check
+// returns a list of human readable warnings.
+type semanticChecker struct {
+	// lookupHost resolves host names for the retry_join check. It is
+	// a field so that tests can avoid doing real DNS lookups.
+	lookupHost func(host string) ([]string, error)
+}
+
+func (s *semanticChecker) Check(rt config.RuntimeConfig, srcs []config.Source) []string {
+	var warnings []string
+	warnings = append(warnings, s.checkSources(srcs)...)
+	warnings = append(warnings, s.checkPorts(rt)...)
+	warnings = append(warnings, s.checkServiceIDs(rt)...)
+	warnings = append(warnings, s.checkRetryJoin("retry_join", rt.RetryJoinLAN)...)
+	warnings = append(warnings, s.checkRetryJoin("retry_join_wan", rt.RetryJoinWAN)...)
+	warnings = append(warnings, s.checkACL(rt)...)
+	return warnings
+}
+
+// checkSources re-parses the individual config sources for the checks
+// which have to be done on the sources since the runtime config doesn't
+// tell where a value came from or whether it was set at all. The sources
+// must be the ones returned by config.Builder.ConfigSources so that
+// environment variables have been interpolated.
+func (s *semanticChecker) checkSources(srcs []config.Source) []string {
+	var warnings []string
+	for _, src := range srcs {
+		if src.Data == "" || src.Format == "" {
+			continue
+		}
+		c, err := config.Parse(src.Data, src.Format)
+		if err != nil {
+			// parse errors have already been reported by the builder
+			continue
+		}
+		warnings = append(warnings, s.checkDeprecatedFields(src.Name, c)...)
+		warnings = append(warnings, s.checkTokenSecrets(src.Name, c)...)
+	}
+	return warnings
+}
+
+// checkDeprecatedFields finds fields which are still accepted but have
+// been deprecated. The deprecated fields are folded into their
+// replacements when the runtime config is built.
+func (s *semanticChecker) checkDeprecatedFields(name string, c config.Config) []string {
+	var warnings []string
+	for _, f := range deprecatedFields {
+		if !f.set(c) {
+			continue
+		}
+		if f.replacement == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: the '%s' field is deprecated and will be removed", name, f.name))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s: the '%s' field is deprecated. Use the '%s' field instead.", name, f.name, f.replacement))
+		}
+	}
+
+	services := c.Services
+	if c.Service != nil {
+		services = append(services, *c.Service)
+	}
+	for _, svc := range services {
+		if svc.ProxyDestination != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: service %q: the 'proxy_destination' field is deprecated. Use the 'proxy.destination_service_name' field instead.", name, stringVal(svc.Name)))
+		}
+	}
+	return warnings
+}
+
+// checkTokenSecrets finds ACL tokens which are configured without a
+// secret, e.g. from an environment variable which is set but empty. An
+// empty token is treated like no token at all, so requests fall back to
+// the anonymous token.
+func (s *semanticChecker) checkTokenSecrets(name string, c config.Config) []string {
+	var warnings []string
+	for _, f := range tokenFields {
+		if v := f.value(c); v != nil && strings.TrimSpace(*v) == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: the '%s' token is configured without a secret", name, f.name))
+		}
+	}
+	return warnings
+}
+
+// checkPorts detects listeners which would try to bind the same TCP port on
+// overlapping addresses. Identical addresses for DNS, HTTP and HTTPS are
+// already rejected by the builder but a wildcard bind address conflicting
+// with a specific one is only detected when the agent starts.
+func (s *semanticChecker) checkPorts(rt config.RuntimeConfig) []string {
+	type listener struct {
+		name string
+		addr *net.TCPAddr
+	}
+	var listeners []listener
+	add := func(name string, addrs ...net.Addr) {
+		for _, a := range addrs {
+			if tcp, ok := a.(*net.TCPAddr); ok && tcp != nil {
+				listeners = append(listeners, listener{name, tcp})
+			}
+		}
+	}
+	add("DNS", rt.DNSAddrs...)
+	add("HTTP", rt.HTTPAddrs...)
+	add("HTTPS", rt.HTTPSAddrs...)
+	add("gRPC", rt.GRPCAddrs...)
+	if rt.SerfBindAddrLAN != nil {
+		add("Serf LAN", rt.SerfBindAddrLAN)
+	}
+	if rt.ServerMode {
+		if rt.SerfBindAddrWAN != nil {
+			add("Serf WAN", rt.SerfBindAddrWAN)
+		}
+		if rt.RPCBindAddr != nil {
+			add("Server RPC", rt.RPCBindAddr)
+		}
+	}
+
+	var warnings []string
+	for i := 0; i < len(listeners); i++ {
+		for j := i + 1; j < len(listeners); j++ {
+			a, b := listeners[i], listeners[j]
+			if a.name == b.name || a.addr.Port != b.addr.Port {
+				continue
+			}
+			if a.addr.IP.Equal(b.addr.IP) || a.addr.IP.IsUnspecified() || b.addr.IP.IsUnspecified() {
+				warnings = append(warnings, fmt.Sprintf("port %d is configured for both %s (%s) and %s (%s)",
+					a.addr.Port, a.name, a.addr, b.name, b.addr))
+			}
+		}
+	}
+	return warnings
+}
+
+// checkServiceIDs reports services which were defined more than once with
+// the same ID. Only the last definition survives when the agent registers
+// them so the other ones are silently dropped.
+func (s *semanticChecker) checkServiceIDs(rt config.RuntimeConfig) []string {
+	seen := make(map[string]int)
+	for _, svc := range rt.Services {
+		id := svc.ID
+		if id == "" {
+			id = svc.Name
+		}
+		seen[id]++
+	}
+
+	var ids []string
+	for id, n := range seen {
+		if n > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var warnings []string
+	for _, id := range ids {
+		warnings = append(warnings, fmt.Sprintf("service ID %q is defined %d times; only the last definition will be registered", id, seen[id]))
+	}
+	return warnings
+}
+
+// checkRetryJoin makes sure that the host names used for joining can be
+// resolved. Cloud auto-join expressions are resolved by go-discover at
+// runtime and are skipped.
+func (s *semanticChecker) checkRetryJoin(name string, addrs []string) []string {
+	var warnings []string
+	for _, addr := range addrs {
+		if strings.Contains(addr, "provider=") {
+			continue
+		}
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		if _, err := s.lookupHost(host); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: cannot resolve %q: %s", name, addr, err))
+		}
+	}
+	return warnings
+}
+
+// checkACL looks for token configurations which will not work as intended.
+func (s *semanticChecker) checkACL(rt config.RuntimeConfig) []string {
+	var warnings []string
+	if !rt.ACLsEnabled {
+		if rt.ACLToken != "" || rt.ACLAgentToken != "" || rt.ACLAgentMasterToken != "" ||
+			rt.ACLMasterToken != "" || rt.ACLReplicationToken != "" {
+			warnings = append(warnings, "acl: tokens are configured but ACLs are not enabled")
+		}
+		return warnings
+	}
+
+	if rt.ACLDefaultPolicy == "deny" && rt.ACLToken == "" && rt.ACLAgentToken == "" {
+		warnings = append(warnings, "acl: default_policy is \"deny\" but neither acl.tokens.agent nor acl.tokens.default is set; the agent will not be able to register itself")
+	}
+	if rt.ServerMode && rt.PrimaryDatacenter != "" && rt.Datacenter != rt.PrimaryDatacenter &&
+		rt.ACLTokenReplication && rt.ACLReplicationToken == "" {
+		warnings = append(warnings, "acl: enable_token_replication is set but acl.tokens.replication is empty")
+	}
+	if rt.ServerMode && rt.Datacenter == rt.PrimaryDatacenter && rt.ACLReplicationToken != "" {
+		warnings = append(warnings, "acl: acl.tokens.replication is only used by servers outside of the primary datacenter")
+	}
+	return warnings
+}
+
+func stringVal(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -3,6 +3,8 @@ package validate
 import (
 	"flag"
 	"fmt"
+	"net"
+	"os"
 
 	"github.com/hashicorp/consul/agent/config"
 	"github.com/hashicorp/consul/command/flags"
@@ -21,16 +23,33 @@ type cmd struct {
 	// configFormat forces all config files to be interpreted as this
 	// format independent of their extension.
 	configFormat string
-	quiet        bool
-	help         string
+	// interpolateEnv enables the replacement of ${NAME} references to
+	// environment variables in the config files.
+	interpolateEnv bool
+	quiet          bool
+	strict         bool
+	help           string
+
+	// lookupHost is used to resolve retry_join addresses. It defaults to
+	// net.LookupHost and is only replaced in tests.
+	lookupHost func(host string) ([]string, error)
+	// lookupEnv is used to interpolate environment variables. It
+	// defaults to os.LookupEnv and is only replaced in tests.
+	lookupEnv func(key string) (string, bool)
 }
 
 func (c *cmd) init() {
 	c.flags = flag.NewFlagSet("", flag.ContinueOnError)
 	c.flags.StringVar(&c.configFormat, "config-format", "",
 		"Config files are in this format irrespective of their extension. Must be 'hcl' or 'json'")
+	c.flags.BoolVar(&c.interpolateEnv, "config-interpolate-env", false,
+		"Replaces ${NAME} and ${NAME:-default} references to environment variables in config files with their values.")
 	c.flags.BoolVar(&c.quiet, "quiet", false,
 		"When given, a successful run will produce no output.")
+	c.flags.BoolVar(&c.strict, "strict", false,
+		"When given, warnings are treated as errors and cause a non-zero exit code.")
+	c.lookupHost = net.LookupHost
+	c.lookupEnv = os.LookupEnv
 	c.help = flags.Usage(help, c.flags)
 }
 
@@ -51,15 +70,36 @@ func (c *cmd) Run(args []string) int {
 		return 1
 	}
 
-	b, err := config.NewBuilder(config.Flags{ConfigFiles: configFiles, ConfigFormat: &c.configFormat})
+	b, err := config.NewBuilder(config.Flags{
+		ConfigFiles:          configFiles,
+		ConfigFormat:         &c.configFormat,
+		ConfigInterpolateEnv: &c.interpolateEnv,
+	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Config validation failed: %v", err.Error()))
 		return 1
 	}
-	if _, err := b.BuildAndValidate(); err != nil {
+	b.LookupEnv = c.lookupEnv
+	rt, err := b.BuildAndValidate()
+	if err != nil {
 		c.UI.Error(fmt.Sprintf("Config validation failed: %v", err.Error()))
 		return 1
 	}
+
+	srcs, err := b.ConfigSources()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Config validation failed: %v", err.Error()))
+		return 1
+	}
+	checker := &semanticChecker{lookupHost: c.lookupHost}
+	warnings := append(b.Warnings, checker.Check(rt, srcs)...)
+	for _, w := range warnings {
+		c.UI.Warn(w)
+	}
+	if c.strict && len(warnings) > 0 {
+		c.UI.Error(fmt.Sprintf("Config validation failed: %d warning(s) in strict mode", len(warnings)))
+		return 1
+	}
 	if !c.quiet {
 		c.UI.Output("Configuration is valid!")
 	}
@@ -88,5 +128,13 @@ Usage: consul validate [options] FILE_OR_DIRECTORY...
   to be loaded by the agent. This command cannot operate on partial
   configuration fragments since those won't pass the full agent validation.
 
+  In addition to the checks the agent performs at startup, the configuration
+  is checked for problems which do not prevent the agent from starting but
+  usually indicate a mistake: ports used by more than one listener, services
+  defined more than once with the same ID, retry_join addresses that cannot
+  be resolved, deprecated fields and ACL tokens that cannot take effect.
+  These are reported as warnings. With -strict any warning causes the
+  command to fail, which is useful for CI pipelines.
+
   Returns 0 if the configuration is valid, or 1 if there are problems.
 `
@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -143,3 +144,113 @@ func TestValidateCommand_Quiet(t *testing.T) {
 	require.Equalf(t, 0, code, "return code - expected: 0, bad: %d, %s", code, ui.ErrorWriter.String())
 	require.Equal(t, "", ui.OutputWriter.String())
 }
+
+func TestValidateCommand_Warnings(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		config string
+		warn   string
+	}{
+		"deprecated field": {
+			`{"bind_addr":"10.0.0.1", "acl_token":"foo", "acl":{"enabled":true}}`,
+			"the 'acl_token' field is deprecated. Use the 'acl.tokens.default' field instead.",
+		},
+		"port conflict": {
+			`{"bind_addr":"10.0.0.1", "client_addr":"0.0.0.0", "ports":{"http":8301}}`,
+			"port 8301 is configured for both HTTP (0.0.0.0:8301) and Serf LAN (10.0.0.1:8301)",
+		},
+		"duplicate service ID": {
+			`{"bind_addr":"10.0.0.1", "services":[{"name":"web"},{"name":"web"}]}`,
+			`service ID "web" is defined 2 times`,
+		},
+		"unresolvable retry_join": {
+			`{"bind_addr":"10.0.0.1", "retry_join":["consul.invalid:8301", "10.0.0.2", "provider=aws tag_key=x tag_value=y"]}`,
+			`retry_join: cannot resolve "consul.invalid:8301": no such host`,
+		},
+		"tokens without ACLs": {
+			`{"bind_addr":"10.0.0.1", "acl":{"tokens":{"agent":"foo"}}}`,
+			"acl: tokens are configured but ACLs are not enabled",
+		},
+		"token without secret": {
+			`{"bind_addr":"10.0.0.1", "acl":{"enabled":true, "tokens":{"agent":"foo", "default":" "}}}`,
+			"the 'acl.tokens.default' token is configured without a secret",
+		},
+		"deny without agent token": {
+			`{"bind_addr":"10.0.0.1", "acl":{"enabled":true, "default_policy":"deny"}}`,
+			"acl: default_policy is \"deny\" but neither acl.tokens.agent nor acl.tokens.default is set",
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			td := testutil.TempDir(t, "consul")
+			defer os.RemoveAll(td)
+
+			fp := filepath.Join(td, "config.json")
+			data := strings.Replace(tc.config, "{", `{"data_dir":"`+td+`", `, 1)
+			require.NoError(t, ioutil.WriteFile(fp, []byte(data), 0644))
+
+			lookup := func(host string) ([]string, error) {
+				return nil, fmt.Errorf("no such host")
+			}
+
+			ui := cli.NewMockUi()
+			cmd := New(ui)
+			cmd.lookupHost = lookup
+			require.Equal(t, 0, cmd.Run([]string{fp}), ui.ErrorWriter.String())
+			require.Contains(t, ui.ErrorWriter.String(), tc.warn)
+			require.Contains(t, ui.OutputWriter.String(), "Configuration is valid!")
+
+			ui = cli.NewMockUi()
+			cmd = New(ui)
+			cmd.lookupHost = lookup
+			require.Equal(t, 1, cmd.Run([]string{"-strict", fp}))
+			require.Contains(t, ui.ErrorWriter.String(), tc.warn)
+			require.Contains(t, ui.ErrorWriter.String(), "strict mode")
+		})
+	}
+}
+
+func TestValidateCommand_WarningsInterpolateEnv(t *testing.T) {
+	t.Parallel()
+	td := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(td)
+
+	// The port is not quoted, so the file can only be parsed after the
+	// environment variables have been interpolated.
+	fp := filepath.Join(td, "config.json")
+	err := ioutil.WriteFile(fp, []byte(`{"bind_addr":"10.0.0.1", "data_dir":"`+td+`",
+		"ports":{"http":${HTTP_PORT}}, "acl_token":"${ACL_TOKEN}",
+		"acl":{"enabled":true, "tokens":{"agent":"${AGENT_TOKEN}"}}}`), 0644)
+	require.NoError(t, err)
+
+	env := map[string]string{"HTTP_PORT": "8080", "ACL_TOKEN": "foo", "AGENT_TOKEN": ""}
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	cmd.lookupEnv = func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	code := cmd.Run([]string{"-config-interpolate-env", fp})
+	require.Equalf(t, 0, code, "bad: %s", ui.ErrorWriter.String())
+	require.Contains(t, ui.ErrorWriter.String(), "the 'acl_token' field is deprecated. Use the 'acl.tokens.default' field instead.")
+	require.Contains(t, ui.ErrorWriter.String(), "the 'acl.tokens.agent' token is configured without a secret")
+}
+
+func TestValidateCommand_StrictNoWarnings(t *testing.T) {
+	t.Parallel()
+	td := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(td)
+
+	fp := filepath.Join(td, "config.json")
+	err := ioutil.WriteFile(fp, []byte(`{"bind_addr":"10.0.0.1", "data_dir":"`+td+`"}`), 0644)
+	require.NoError(t, err)
+
+	ui := cli.NewMockUi()
+	cmd := New(ui)
+	code := cmd.Run([]string{"-strict", fp})
+	require.Equalf(t, 0, code, "bad: %s", ui.ErrorWriter.String())
+	require.Equal(t, "", ui.ErrorWriter.String())
+}
@@ -0,0 +1,176 @@
+// Package cgroup reads the CPU and memory limits that a Linux control group
+// imposes on the current process, for example when it runs in a container.
+// Both cgroup v1 and the unified cgroup v2 hierarchy are supported.
+package cgroup
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Limits are the resource limits of the cgroups of a process.
+type Limits struct {
+	// CPUQuota is the number of CPUs the process may use per scheduling
+	// period, which can be fractional. It is 0 if the CPU is not limited.
+	CPUQuota float64
+
+	// MemoryLimit is the maximum memory in bytes. It is 0 if the memory is
+	// not limited.
+	MemoryLimit uint64
+}
+
+// unlimitedMemory is the smallest memory limit which is considered to be no
+// limit. cgroup v1 reports a huge page aligned number close to the maximum
+// int64 value if the memory is not limited.
+const unlimitedMemory = 1 << 62
+
+// readLimits reads the limits of the process whose /proc/<pid>/cgroup file
+// is at procPath from the cgroup file system mounted at mountPath.
+func readLimits(procPath, mountPath string) (*Limits, error) {
+	paths, err := parseProcCgroup(procPath)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &Limits{}
+	if path, ok := paths[""]; ok && exists(filepath.Join(mountPath, "cgroup.controllers")) {
+		dir := cgroupDir(mountPath, path)
+		if s, err := readFirstLine(filepath.Join(dir, "cpu.max")); err == nil {
+			if limits.CPUQuota, err = parseCPUMax(s); err != nil {
+				return nil, err
+			}
+		}
+		if s, err := readFirstLine(filepath.Join(dir, "memory.max")); err == nil {
+			if limits.MemoryLimit, err = parseMemory(s); err != nil {
+				return nil, err
+			}
+		}
+		return limits, nil
+	}
+
+	for _, controller := range []string{"cpu", "cpu,cpuacct", "cpuacct,cpu"} {
+		path, ok := paths[controller]
+		if !ok {
+			continue
+		}
+		dir := cgroupDir(filepath.Join(mountPath, controller), path)
+		quota, err := readFirstLine(filepath.Join(dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := readFirstLine(filepath.Join(dir, "cpu.cfs_period_us"))
+		if err != nil {
+			continue
+		}
+		if limits.CPUQuota, err = parseCFS(quota, period); err != nil {
+			return nil, err
+		}
+		break
+	}
+	if path, ok := paths["memory"]; ok {
+		dir := cgroupDir(filepath.Join(mountPath, "memory"), path)
+		if s, err := readFirstLine(filepath.Join(dir, "memory.limit_in_bytes")); err == nil {
+			if limits.MemoryLimit, err = parseMemory(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return limits, nil
+}
+
+// parseProcCgroup returns the cgroup path of each controller list in the
+// given /proc/<pid>/cgroup file. The path of the cgroup v2 hierarchy has an
+// empty controller list.
+func parseProcCgroup(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		paths[parts[1]] = parts[2]
+	}
+	return paths, scanner.Err()
+}
+
+// cgroupDir returns the directory of the cgroup with the given path below
+// the mount point of its hierarchy. Inside a container the cgroup namespace
+// or the bind mount usually makes the own cgroup the root of the mount, so
+// the root is used if the path doesn't exist.
+func cgroupDir(mount, path string) string {
+	dir := filepath.Join(mount, path)
+	if exists(dir) {
+		return dir
+	}
+	return mount
+}
+
+// parseCPUMax parses the "$MAX $PERIOD" format of cgroup v2 cpu.max.
+func parseCPUMax(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid cpu.max %q", s)
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	return parseCFS(fields[0], fields[1])
+}
+
+// parseCFS returns the number of CPUs for the given CFS quota and period. A
+// negative quota means that the CPU is not limited.
+func parseCFS(quota, period string) (float64, error) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU quota %q: %v", quota, err)
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU period %q: %v", period, err)
+	}
+	if q <= 0 || p <= 0 {
+		return 0, nil
+	}
+	return float64(q) / float64(p), nil
+}
+
+// parseMemory parses a memory limit in bytes, which is "max" in cgroup v2
+// if the memory is not limited.
+func parseMemory(s string) (uint64, error) {
+	if s == "max" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory limit %q: %v", s, err)
+	}
+	if v >= unlimitedMemory {
+		return 0, nil
+	}
+	return v, nil
+}
+
+func readFirstLine(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0]), nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,6 @@
+package cgroup
+
+// Detect returns the limits of the cgroups of the current process.
+func Detect() (*Limits, error) {
+	return readLimits("/proc/self/cgroup", "/sys/fs/cgroup")
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package cgroup
+
+// Detect returns no limits since cgroups only exist on Linux.
+func Detect() (*Limits, error) {
+	return &Limits{}, nil
+}
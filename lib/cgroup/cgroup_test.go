@@ -0,0 +1,117 @@
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the files with the given contents below dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, contents := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  Limits
+	}{
+		{
+			"v2 limited",
+			map[string]string{
+				"proc":                   "0::/consul\n",
+				"mnt/cgroup.controllers": "cpu memory\n",
+				"mnt/consul/cpu.max":     "150000 100000\n",
+				"mnt/consul/memory.max":  "536870912\n",
+			},
+			Limits{CPUQuota: 1.5, MemoryLimit: 512 << 20},
+		},
+		{
+			"v2 unlimited",
+			map[string]string{
+				"proc":                   "0::/\n",
+				"mnt/cgroup.controllers": "cpu memory\n",
+				"mnt/cpu.max":            "max 100000\n",
+				"mnt/memory.max":         "max\n",
+			},
+			Limits{},
+		},
+		{
+			"v2 namespaced",
+			map[string]string{
+				"proc":                   "0::/kubepods/pod1/container\n",
+				"mnt/cgroup.controllers": "cpu memory\n",
+				"mnt/cpu.max":            "200000 100000\n",
+				"mnt/memory.max":         "1073741824\n",
+			},
+			Limits{CPUQuota: 2, MemoryLimit: 1 << 30},
+		},
+		{
+			"v1 limited",
+			map[string]string{
+				"proc": "4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n0::/\n",
+				"mnt/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  "50000\n",
+				"mnt/cpu,cpuacct/docker/abc/cpu.cfs_period_us": "100000\n",
+				"mnt/memory/docker/abc/memory.limit_in_bytes":  "268435456\n",
+			},
+			Limits{CPUQuota: 0.5, MemoryLimit: 256 << 20},
+		},
+		{
+			"v1 unlimited",
+			map[string]string{
+				"proc":                             "4:memory:/\n3:cpu:/\n",
+				"mnt/cpu/cpu.cfs_quota_us":         "-1\n",
+				"mnt/cpu/cpu.cfs_period_us":        "100000\n",
+				"mnt/memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			Limits{},
+		},
+		{
+			"no cgroup files",
+			map[string]string{
+				"proc": "4:memory:/\n3:cpu:/\n",
+			},
+			Limits{},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cgroup")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			writeFiles(t, dir, tc.files)
+			limits, err := readLimits(filepath.Join(dir, "proc"), filepath.Join(dir, "mnt"))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, *limits)
+		})
+	}
+}
+
+func TestReadLimits_invalid(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"proc":                   "0::/\n",
+		"mnt/cgroup.controllers": "cpu memory\n",
+		"mnt/cpu.max":            "lots\n",
+	})
+	_, err = readLimits(filepath.Join(dir, "proc"), filepath.Join(dir, "mnt"))
+	require.Error(t, err)
+}
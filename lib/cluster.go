@@ -45,6 +45,40 @@ func RandomStagger(intv time.Duration) time.Duration {
 	return time.Duration(uint64(rand.Int63()) % uint64(intv))
 }
 
+// Backoff describes an exponential backoff with jitter for retries.
+type Backoff struct {
+	// Min is the interval before the first retry. It doubles with every
+	// further retry.
+	Min time.Duration
+
+	// Max caps the interval before the jitter is added.
+	Max time.Duration
+
+	// Jitter is the maximum random delay added to the interval, as a
+	// fraction of the interval. Randomizing the retries keeps the clients
+	// of a restarted server from retrying in sync.
+	Jitter float64
+}
+
+// Interval returns the interval before the given retry without jitter.
+// Retries are counted from 0.
+func (b Backoff) Interval(retry int) time.Duration {
+	d := b.Min
+	for i := 0; i < retry && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// Wait returns the interval before the given retry plus a random jitter.
+func (b Backoff) Wait(retry int) time.Duration {
+	d := b.Interval(retry)
+	return d + RandomStagger(time.Duration(float64(d)*b.Jitter))
+}
+
 // RateScaledInterval is used to choose an interval to perform an action in
 // order to target an aggregate number of actions per second across the whole
 // cluster.
@@ -137,6 +137,37 @@ func TestRandomStagger(t *testing.T) {
 	}
 }
 
+func TestBackoff(t *testing.T) {
+	b := Backoff{Min: 100 * time.Millisecond, Max: time.Second, Jitter: 0.5}
+	tests := []struct {
+		Retry    int
+		Interval time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{100, time.Second},
+	}
+	for _, test := range tests {
+		if got := b.Interval(test.Retry); got != test.Interval {
+			t.Fatalf("retry %d: got interval %v want %v", test.Retry, got, test.Interval)
+		}
+		for i := 0; i < 10; i++ {
+			wait := b.Wait(test.Retry)
+			if wait < test.Interval || wait >= test.Interval+test.Interval/2 {
+				t.Fatalf("retry %d: bad wait %v", test.Retry, wait)
+			}
+		}
+	}
+
+	b.Jitter = 0
+	if wait := b.Wait(1); wait != 200*time.Millisecond {
+		t.Fatalf("bad wait without jitter: %v", wait)
+	}
+}
+
 func TestRateScaledInterval(t *testing.T) {
 	const min = 1 * time.Second
 	rate := 200.0
@@ -30,3 +30,17 @@ func TestWriteAtomic(t *testing.T) {
 	require.NoError(err)
 	require.Equal(expected, actual)
 }
+
+func TestWriteAtomicWithPerms(t *testing.T) {
+	require := require.New(t)
+	td, err := ioutil.TempDir("", "lib-file")
+	require.NoError(err)
+	defer os.RemoveAll(td)
+
+	path := filepath.Join(td, "subdir", "file")
+	require.NoError(WriteAtomicWithPerms(path, []byte("hello"), 0755, 0644))
+
+	fi, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0644), fi.Mode().Perm())
+}
@@ -0,0 +1,472 @@
+// Package filter implements the boolean expressions which are used to select
+// objects by the values of their fields, e.g.
+//
+//	Description contains "ci-" and not Local == true
+//
+// An expression is made of matches which are combined with "and", "or", "not"
+// and parentheses. A match compares the values of the field named by a
+// selector with a value:
+//
+//	<Selector> == <Value>
+//	<Selector> != <Value>
+//	<Selector> contains <Value>
+//	<Selector> not contains <Value>
+//	<Selector> matches <Regexp>
+//	<Selector> not matches <Regexp>
+//	<Selector> is empty
+//	<Selector> is not empty
+//	<Selector> < <Value>
+//	<Selector> <= <Value>
+//	<Selector> > <Value>
+//	<Selector> >= <Value>
+//
+// The ordering operators compare the values as numbers if both are numbers
+// and as strings otherwise, which orders times formatted as RFC 3339 in UTC,
+// e.g. CreateTime < 2019-06-01.
+//
+// Selectors are names separated by dots. Values are double quoted strings or
+// bare words made of letters, digits and the characters "-", "_", "." and
+// ":". A field may have several values, e.g. the names of the policies of a
+// token, in which case a match is true if any of the values matches.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Fields is implemented by the objects which can be filtered.
+type Fields interface {
+	// FilterValues returns the values of the field with the given selector.
+	// The second return value is false if the selector is unknown.
+	FilterValues(selector string) ([]string, bool)
+}
+
+// Expression is a parsed filter expression.
+type Expression struct {
+	raw  string
+	root node
+}
+
+// Parse parses a filter expression.
+func Parse(expression string) (*Expression, error) {
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Filter expression is empty")
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("Unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return &Expression{raw: expression, root: root}, nil
+}
+
+// String returns the expression as it was parsed.
+func (e *Expression) String() string {
+	return e.raw
+}
+
+// Selectors returns the selectors used in the expression.
+func (e *Expression) Selectors() []string {
+	var out []string
+	seen := make(map[string]bool)
+	e.root.walk(func(m *match) {
+		if !seen[m.selector] {
+			seen[m.selector] = true
+			out = append(out, m.selector)
+		}
+	})
+	return out
+}
+
+// Validate returns an error if the expression uses a selector which is
+// unknown to the given fields.
+func (e *Expression) Validate(fields Fields) error {
+	for _, selector := range e.Selectors() {
+		if _, ok := fields.FilterValues(selector); !ok {
+			return fmt.Errorf("Unknown selector %q", selector)
+		}
+	}
+	return nil
+}
+
+// Match returns true if the fields match the expression. Unknown selectors
+// have no values.
+func (e *Expression) Match(fields Fields) bool {
+	return e.root.eval(fields)
+}
+
+type node interface {
+	eval(fields Fields) bool
+	walk(fn func(m *match))
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) eval(fields Fields) bool { return n.left.eval(fields) && n.right.eval(fields) }
+func (n *andNode) walk(fn func(m *match))  { n.left.walk(fn); n.right.walk(fn) }
+
+type orNode struct{ left, right node }
+
+func (n *orNode) eval(fields Fields) bool { return n.left.eval(fields) || n.right.eval(fields) }
+func (n *orNode) walk(fn func(m *match))  { n.left.walk(fn); n.right.walk(fn) }
+
+type notNode struct{ inner node }
+
+func (n *notNode) eval(fields Fields) bool { return !n.inner.eval(fields) }
+func (n *notNode) walk(fn func(m *match))  { n.inner.walk(fn) }
+
+type operator int
+
+const (
+	opEqual operator = iota
+	opContains
+	opMatches
+	opEmpty
+	opLess
+	opLessEqual
+	opGreater
+	opGreaterEqual
+)
+
+// match compares the values of a field with a value. Negated operators
+// such as != are represented by setting negate.
+type match struct {
+	selector string
+	op       operator
+	negate   bool
+	value    string
+	re       *regexp.Regexp
+}
+
+func (m *match) walk(fn func(m *match)) { fn(m) }
+
+func (m *match) eval(fields Fields) bool {
+	values, _ := fields.FilterValues(m.selector)
+
+	var result bool
+	switch m.op {
+	case opEmpty:
+		result = true
+		for _, v := range values {
+			if v != "" {
+				result = false
+				break
+			}
+		}
+	default:
+		for _, v := range values {
+			if m.matchValue(v) {
+				result = true
+				break
+			}
+		}
+	}
+
+	if m.negate {
+		return !result
+	}
+	return result
+}
+
+func (m *match) matchValue(v string) bool {
+	switch m.op {
+	case opEqual:
+		return v == m.value
+	case opContains:
+		return strings.Contains(v, m.value)
+	case opMatches:
+		return m.re.MatchString(v)
+	case opLess:
+		return compare(v, m.value) < 0
+	case opLessEqual:
+		return compare(v, m.value) <= 0
+	case opGreater:
+		return compare(v, m.value) > 0
+	case opGreaterEqual:
+		return compare(v, m.value) >= 0
+	}
+	return false
+}
+
+// compare compares two values as numbers if both are numbers and as strings
+// otherwise.
+func compare(a, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenEqual
+	tokenNotEqual
+	tokenOrder
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.:", r)
+}
+
+// lex splits an expression into its tokens.
+func lex(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case r == '=' || r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, fmt.Errorf("Unexpected %q at position %d", string(r), i)
+			}
+			kind := tokenEqual
+			if r == '!' {
+				kind = tokenNotEqual
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[i : i+2]), pos: i})
+			i += 2
+
+		case r == '<' || r == '>':
+			end := i + 1
+			if end < len(runes) && runes[end] == '=' {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenOrder, text: string(runes[i:end]), pos: i})
+			i = end
+
+		case r == '"':
+			end := i + 1
+			for ; end < len(runes); end++ {
+				if runes[end] == '\\' {
+					end++
+					continue
+				}
+				if runes[end] == '"' {
+					break
+				}
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("Unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return nil, fmt.Errorf("Invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end + 1
+
+		case isWordChar(r):
+			end := i
+			for end < len(runes) && isWordChar(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[i:end]), pos: i})
+			i = end
+
+		default:
+			return nil, fmt.Errorf("Unexpected %q at position %d", string(r), i)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser for the grammar
+//
+//	or    = and { "or" and }
+//	and   = unary { "and" unary }
+//	unary = "not" unary | "(" or ")" | match
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// accept consumes the next token if it is the given keyword.
+func (p *parser) accept(keyword string) bool {
+	if !p.done() && p.peek().kind == tokenWord && p.peek().text == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("Unexpected end of filter expression")
+	}
+	if p.accept("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner: inner}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenRParen {
+			return nil, fmt.Errorf("Missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return p.parseMatch()
+}
+
+func (p *parser) parseMatch() (node, error) {
+	t := p.peek()
+	if t.kind != tokenWord || isKeyword(t.text) {
+		return nil, fmt.Errorf("Expected a selector at position %d but found %q", t.pos, t.text)
+	}
+	p.pos++
+	m := &match{selector: t.text}
+
+	if p.done() {
+		return nil, fmt.Errorf("Missing operator after selector %q", m.selector)
+	}
+	op := p.peek()
+	switch {
+	case op.kind == tokenEqual:
+		p.pos++
+		m.op = opEqual
+	case op.kind == tokenNotEqual:
+		p.pos++
+		m.op, m.negate = opEqual, true
+	case op.kind == tokenOrder:
+		p.pos++
+		m.op = orderOperators[op.text]
+	case p.accept("is"):
+		m.negate = p.accept("not")
+		if !p.accept("empty") {
+			return nil, fmt.Errorf("Expected \"empty\" after \"is\" for selector %q", m.selector)
+		}
+		m.op = opEmpty
+		return m, nil
+	default:
+		m.negate = p.accept("not")
+		switch {
+		case p.accept("contains"):
+			m.op = opContains
+		case p.accept("matches"):
+			m.op = opMatches
+		default:
+			return nil, fmt.Errorf("Invalid operator %q for selector %q", op.text, m.selector)
+		}
+	}
+
+	if p.done() {
+		return nil, fmt.Errorf("Missing value for selector %q", m.selector)
+	}
+	value := p.peek()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, fmt.Errorf("Expected a value at position %d but found %q", value.pos, value.text)
+	}
+	p.pos++
+	m.value = value.text
+
+	if m.op == opMatches {
+		re, err := regexp.Compile(m.value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regular expression %q: %v", m.value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+var orderOperators = map[string]operator{
+	"<":  opLess,
+	"<=": opLessEqual,
+	">":  opGreater,
+	">=": opGreaterEqual,
+}
+
+func isKeyword(word string) bool {
+	switch word {
+	case "and", "or", "not", "is", "empty", "contains", "matches":
+		return true
+	}
+	return false
+}
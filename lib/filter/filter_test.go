@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testFields map[string][]string
+
+func (f testFields) FilterValues(selector string) ([]string, bool) {
+	values, ok := f[selector]
+	return values, ok
+}
+
+func TestParse_Errors(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"":                              "empty",
+		"Description":                   "Missing operator",
+		"Description ==":                "Missing value",
+		"Description = foo":             "Unexpected",
+		"Description like foo":          "Invalid operator",
+		"Description is foo":            "Expected \"empty\"",
+		"Description contains \"foo":    "Unterminated string",
+		"(Description == foo":           "Missing closing parenthesis",
+		"Description == foo)":           "Unexpected \")\"",
+		"Description == foo and":        "Unexpected end",
+		"and == foo":                    "Expected a selector",
+		"Description matches \"[\"":     "Invalid regular expression",
+		"Description == foo Local == a": "Unexpected \"Local\"",
+		"Description < ":                "Missing value",
+		"Description <> foo":            "Expected a value",
+	}
+	for expr, errText := range cases {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), errText)
+		})
+	}
+}
+
+func TestExpression_Match(t *testing.T) {
+	t.Parallel()
+	fields := testFields{
+		"Description":   {"ci-build 1234"},
+		"Local":         {"true"},
+		"Policies.Name": {"web", "db-read"},
+		"Empty":         {""},
+		"None":          nil,
+		"CreateTime":    {"2019-05-20T10:00:00Z"},
+		"Count":         {"9"},
+	}
+	cases := map[string]bool{
+		`Description == "ci-build 1234"`:              true,
+		`Description != "ci-build 1234"`:              false,
+		`Description contains "ci-"`:                  true,
+		`Description contains ci-`:                    true,
+		`Description not contains "ci-"`:              false,
+		`Description matches "^ci-[a-z]+ [0-9]+$"`:    true,
+		`Description not matches "^ci-"`:              false,
+		`Local == true`:                               true,
+		`Policies.Name == web`:                        true,
+		`Policies.Name == db`:                         false,
+		`Policies.Name contains db`:                   true,
+		`Policies.Name != web`:                        false,
+		`Empty is empty`:                              true,
+		`None is empty`:                               true,
+		`Description is empty`:                        false,
+		`Description is not empty`:                    true,
+		`Unknown == foo`:                              false,
+		`Unknown is empty`:                            true,
+		`Local == true and Description contains ci-`:  true,
+		`Local == false and Description contains ci-`: false,
+		`Local == false or Description contains ci-`:  true,
+		`not Local == false`:                          true,
+		`not (Local == true or Empty is empty)`:       false,
+		`CreateTime < 2019-06-01`:                     true,
+		`CreateTime > "2019-05-20T10:00:00Z"`:         false,
+		`CreateTime >= "2019-05-20T10:00:00Z"`:        true,
+		`CreateTime<=2019-05-20`:                      false,
+		`Count < 10`:                                  true,
+		`Count > 10`:                                  false,
+		`Count <= 9.0`:                                true,
+		`None < z`:                                    false,
+		// and binds stronger than or
+		`Local == true or Local == false and Empty is not empty`:   true,
+		`(Local == true or Local == false) and Empty is not empty`: false,
+	}
+	for expr, expected := range cases {
+		t.Run(expr, func(t *testing.T) {
+			e, err := Parse(expr)
+			require.NoError(t, err)
+			require.Equal(t, expected, e.Match(fields))
+		})
+	}
+}
+
+func TestExpression_Validate(t *testing.T) {
+	t.Parallel()
+	fields := testFields{"Description": nil, "Local": nil}
+
+	e, err := Parse(`Description contains ci- and (Local == true or Description == foo)`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Description", "Local"}, e.Selectors())
+	require.NoError(t, e.Validate(fields))
+
+	e, err = Parse(`Description contains ci- or Policy == web`)
+	require.NoError(t, err)
+	err = e.Validate(fields)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `Unknown selector "Policy"`)
+}
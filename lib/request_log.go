@@ -0,0 +1,42 @@
+package lib
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RequestLogConfig configures which HTTP and RPC requests are logged
+// without enabling debug logging.
+type RequestLogConfig struct {
+	// SampleRate is the fraction of requests which are logged, between 0
+	// and 1.
+	SampleRate float64
+
+	// SlowReadThreshold and SlowWriteThreshold are the latencies above
+	// which reads and writes are logged as slow. Zero disables the slow
+	// request log for the class. Blocking queries wait for changes by
+	// design and are never logged as slow.
+	SlowReadThreshold  time.Duration
+	SlowWriteThreshold time.Duration
+}
+
+// Enabled returns true if any requests are logged.
+func (c *RequestLogConfig) Enabled() bool {
+	return c.SampleRate > 0 || c.SlowReadThreshold > 0 || c.SlowWriteThreshold > 0
+}
+
+// Check returns whether a request of the given class which took the given
+// time is logged because it was slow, or because it was sampled.
+func (c *RequestLogConfig) Check(read, blocking bool, elapsed time.Duration) (slow, sampled bool) {
+	threshold := c.SlowWriteThreshold
+	if read {
+		threshold = c.SlowReadThreshold
+	}
+	if !blocking && threshold > 0 && elapsed >= threshold {
+		return true, false
+	}
+	if c.SampleRate > 0 && rand.Float64() < c.SampleRate {
+		return false, true
+	}
+	return false, false
+}
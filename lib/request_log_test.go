@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestLogConfig_Check(t *testing.T) {
+	c := RequestLogConfig{
+		SlowReadThreshold:  time.Second,
+		SlowWriteThreshold: 2 * time.Second,
+	}
+	if !c.Enabled() {
+		t.Fatalf("should be enabled")
+	}
+
+	cases := []struct {
+		read, blocking bool
+		elapsed        time.Duration
+		slow           bool
+	}{
+		{true, false, 500 * time.Millisecond, false},
+		{true, false, time.Second, true},
+		{true, true, time.Minute, false},
+		{false, false, time.Second, false},
+		{false, false, 2 * time.Second, true},
+	}
+	for _, tc := range cases {
+		slow, sampled := c.Check(tc.read, tc.blocking, tc.elapsed)
+		if slow != tc.slow || sampled {
+			t.Fatalf("bad: %#v slow=%v sampled=%v", tc, slow, sampled)
+		}
+	}
+
+	// All requests are sampled with a rate of 1.
+	c = RequestLogConfig{SampleRate: 1}
+	if slow, sampled := c.Check(true, true, time.Minute); slow || !sampled {
+		t.Fatalf("bad: slow=%v sampled=%v", slow, sampled)
+	}
+
+	c = RequestLogConfig{}
+	if c.Enabled() {
+		t.Fatalf("should be disabled")
+	}
+	if slow, sampled := c.Check(false, false, time.Hour); slow || sampled {
+		t.Fatalf("bad: slow=%v sampled=%v", slow, sampled)
+	}
+}
@@ -2,6 +2,8 @@ package lib
 
 import (
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	metrics "github.com/armon/go-metrics"
@@ -323,6 +325,114 @@ func circonusSink(cfg TelemetryConfig, hostname string) (metrics.MetricSink, err
 	return sink, nil
 }
 
+// networkSinks holds the statsite, statsd and dogstatsd sinks. They are
+// wrapped in a single sink which is registered with go-metrics so that
+// they can be replaced when the configuration is reloaded.
+var networkSinks = &reloadableSink{}
+
+// reloadableSink forwards metrics to a set of sinks which can be
+// replaced at runtime.
+type reloadableSink struct {
+	// l serializes reloads
+	l sync.Mutex
+
+	// cfg is the configuration the current sinks were created from
+	cfg TelemetryConfig
+
+	// sinks always contains a metrics.FanoutSink
+	sinks atomic.Value
+}
+
+func (s *reloadableSink) fanout() metrics.FanoutSink {
+	fs, _ := s.sinks.Load().(metrics.FanoutSink)
+	return fs
+}
+
+func (s *reloadableSink) SetGauge(key []string, val float32) {
+	s.fanout().SetGauge(key, val)
+}
+
+func (s *reloadableSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.fanout().SetGaugeWithLabels(key, val, labels)
+}
+
+func (s *reloadableSink) EmitKey(key []string, val float32) {
+	s.fanout().EmitKey(key, val)
+}
+
+func (s *reloadableSink) IncrCounter(key []string, val float32) {
+	s.fanout().IncrCounter(key, val)
+}
+
+func (s *reloadableSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.fanout().IncrCounterWithLabels(key, val, labels)
+}
+
+func (s *reloadableSink) AddSample(key []string, val float32) {
+	s.fanout().AddSample(key, val)
+}
+
+func (s *reloadableSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.fanout().AddSampleWithLabels(key, val, labels)
+}
+
+// networkSinkConfigEqual returns true if a and b configure the same
+// statsite, statsd and dogstatsd sinks.
+func networkSinkConfigEqual(a, b TelemetryConfig) bool {
+	return a.StatsiteAddr == b.StatsiteAddr &&
+		a.StatsdAddr == b.StatsdAddr &&
+		a.DogstatsdAddr == b.DogstatsdAddr &&
+		reflect.DeepEqual(a.DogstatsdTags, b.DogstatsdTags)
+}
+
+// reload replaces the sinks with ones created from cfg. The previous
+// sinks are shut down if they support it.
+func (s *reloadableSink) reload(cfg TelemetryConfig, hostname string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.fanout() != nil && networkSinkConfigEqual(s.cfg, cfg) {
+		return nil
+	}
+
+	var sinks metrics.FanoutSink
+	for _, fn := range []func(TelemetryConfig, string) (metrics.MetricSink, error){
+		statsiteSink, statsdSink, dogstatdSink,
+	} {
+		sink, err := fn(cfg, hostname)
+		if err != nil {
+			return err
+		}
+		if sink != nil {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	old := s.fanout()
+	s.sinks.Store(sinks)
+	s.cfg = cfg
+
+	for _, sink := range old {
+		if x, ok := sink.(interface{ Shutdown() }); ok {
+			x.Shutdown()
+		}
+	}
+	return nil
+}
+
+// ReloadTelemetry applies the telemetry settings which can be changed at
+// runtime. These are the statsite, statsd and dogstatsd sinks and the
+// allowed and blocked metric prefixes. All other settings require a
+// restart.
+func ReloadTelemetry(cfg TelemetryConfig) error {
+	hostname := metrics.DefaultConfig(cfg.MetricsPrefix).HostName
+	if err := networkSinks.reload(cfg, hostname); err != nil {
+		return err
+	}
+	metrics.UpdateFilter(cfg.AllowedPrefixes, cfg.BlockedPrefixes)
+	return nil
+}
+
 // InitTelemetry configures go-metrics based on map of telemetry config
 // values as returned by Runtimecfg.Config().
 func InitTelemetry(cfg TelemetryConfig) (*metrics.InmemSink, error) {
@@ -349,13 +459,7 @@ func InitTelemetry(cfg TelemetryConfig) (*metrics.InmemSink, error) {
 		return nil
 	}
 
-	if err := addSink("statsite", statsiteSink); err != nil {
-		return nil, err
-	}
-	if err := addSink("statsd", statsdSink); err != nil {
-		return nil, err
-	}
-	if err := addSink("dogstatd", dogstatdSink); err != nil {
+	if err := networkSinks.reload(cfg, metricsConf.HostName); err != nil {
 		return nil, err
 	}
 	if err := addSink("circonus", circonusSink); err != nil {
@@ -365,12 +469,13 @@ func InitTelemetry(cfg TelemetryConfig) (*metrics.InmemSink, error) {
 		return nil, err
 	}
 
-	if len(sinks) > 0 {
-		sinks = append(sinks, memSink)
-		metrics.NewGlobal(metricsConf, sinks)
-	} else {
+	if len(sinks) == 0 && len(networkSinks.fanout()) == 0 {
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, memSink)
 	}
+
+	// The network sinks are always registered so that they can be
+	// enabled on reload.
+	sinks = append(sinks, networkSinks, memSink)
+	metrics.NewGlobal(metricsConf, sinks)
 	return memSink, nil
 }
@@ -88,3 +88,24 @@ func TestTelemetryConfig_MergeDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestReloadTelemetry(t *testing.T) {
+	cfg := TelemetryConfig{StatsdAddr: "127.0.0.1:8125"}
+	require.NoError(t, ReloadTelemetry(cfg))
+	sinks := networkSinks.fanout()
+	require.Len(t, sinks, 1)
+
+	// an unchanged configuration keeps the sinks
+	cfg.AllowedPrefixes = []string{"consul.rpc"}
+	require.NoError(t, ReloadTelemetry(cfg))
+	require.True(t, sinks[0] == networkSinks.fanout()[0])
+
+	cfg.StatsdAddr = ""
+	cfg.StatsiteAddr = "127.0.0.1:8125"
+	require.NoError(t, ReloadTelemetry(cfg))
+	require.Len(t, networkSinks.fanout(), 1)
+	require.False(t, sinks[0] == networkSinks.fanout()[0])
+
+	require.NoError(t, ReloadTelemetry(TelemetryConfig{}))
+	require.Len(t, networkSinks.fanout(), 0)
+}
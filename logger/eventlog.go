@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+
+	"github.com/hashicorp/logutils"
+)
+
+// eventLogger is the subset of the Windows event log API used to
+// forward log messages.
+type eventLogger interface {
+	Info(msg string) error
+	Warning(msg string) error
+	Error(msg string) error
+}
+
+// EventLogWrapper is used to cleanup log messages before
+// writing them to the Windows event log. Implements the
+// io.Writer interface.
+type EventLogWrapper struct {
+	l    eventLogger
+	filt *logutils.LevelFilter
+}
+
+// Write is used to implement io.Writer
+func (e *EventLogWrapper) Write(p []byte) (int, error) {
+	// Skip the event log if the log level doesn't apply
+	if !e.filt.Check(p) {
+		return 0, nil
+	}
+
+	// Extract log level
+	var level string
+	afterLevel := p
+	x := bytes.IndexByte(p, '[')
+	if x >= 0 {
+		y := bytes.IndexByte(p[x:], ']')
+		if y >= 0 {
+			level = string(p[x+1 : x+y])
+			afterLevel = p[x+y+2:]
+		}
+	}
+	msg := string(bytes.TrimRight(afterLevel, "\n"))
+
+	// The event log only knows three severities
+	var err error
+	switch level {
+	case "ERR", "CRIT":
+		err = e.l.Error(msg)
+	case "WARN":
+		err = e.l.Warning(msg)
+	default:
+		err = e.l.Info(msg)
+	}
+	return len(p), err
+}
@@ -0,0 +1,11 @@
+// +build !windows
+
+package logger
+
+import (
+	"fmt"
+)
+
+func newEventLogger(source string) (eventLogger, error) {
+	return nil, fmt.Errorf("The Windows event log is not supported on this platform")
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/hashicorp/logutils"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEventLog struct {
+	events []string
+}
+
+func (m *mockEventLog) Info(msg string) error {
+	m.events = append(m.events, "info: "+msg)
+	return nil
+}
+
+func (m *mockEventLog) Warning(msg string) error {
+	m.events = append(m.events, "warning: "+msg)
+	return nil
+}
+
+func (m *mockEventLog) Error(msg string) error {
+	m.events = append(m.events, "error: "+msg)
+	return nil
+}
+
+func TestEventLogFilter(t *testing.T) {
+	l := &mockEventLog{}
+	filt := LevelFilter()
+	filt.MinLevel = logutils.LogLevel("INFO")
+
+	e := &EventLogWrapper{l, filt}
+	for _, line := range []string{
+		"[DEBUG] skipped\n",
+		"[INFO] info\n",
+		"[WARN] warn\n",
+		"[ERR] err\n",
+		"[CRIT] crit\n",
+	} {
+		if _, err := e.Write([]byte(line)); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	require.Equal(t, []string{
+		"info: info",
+		"warning: warn",
+		"error: err",
+		"error: crit",
+	}, l.events)
+}
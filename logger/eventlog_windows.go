@@ -0,0 +1,50 @@
+// +build windows
+
+package logger
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// eventID is the id used for all events written by the agent.
+const eventID = 1
+
+// windowsEventLog writes messages to the Windows application event log.
+type windowsEventLog struct {
+	handle windows.Handle
+}
+
+// newEventLogger registers source as an event source and returns a
+// logger for it.
+func newEventLogger(source string) (eventLogger, error) {
+	name, err := windows.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.RegisterEventSource(nil, name)
+	if err != nil {
+		return nil, err
+	}
+	return &windowsEventLog{handle: h}, nil
+}
+
+func (l *windowsEventLog) report(etype uint16, msg string) error {
+	s, err := windows.UTF16PtrFromString(msg)
+	if err != nil {
+		return err
+	}
+	ss := []*uint16{s}
+	return windows.ReportEvent(l.handle, etype, 0, eventID, 0, 1, 0, &ss[0], nil)
+}
+
+func (l *windowsEventLog) Info(msg string) error {
+	return l.report(windows.EVENTLOG_INFORMATION_TYPE, msg)
+}
+
+func (l *windowsEventLog) Warning(msg string) error {
+	return l.report(windows.EVENTLOG_WARNING_TYPE, msg)
+}
+
+func (l *windowsEventLog) Error(msg string) error {
+	return l.report(windows.EVENTLOG_ERROR_TYPE, msg)
+}
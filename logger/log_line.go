@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// lineRe matches the log lines written by the agent's standard logger, e.g.
+//
+//	2019/01/02 15:04:05 [INFO] consul.fsm: message
+//
+// The subsystem is optional. A logger prefix before the timestamp and
+// fractional seconds, as written by the test agents, are allowed.
+var lineRe = regexp.MustCompile(`^.*?(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) \[([A-Z]+)\] (?:([A-Za-z0-9_.\-]+): )?(.*)$`)
+
+// lineTimeFormat is the format of the timestamps written by log.LstdFlags.
+// Fractional seconds are accepted when parsing.
+const lineTimeFormat = "2006/01/02 15:04:05"
+
+// Line is a log line split into its fields. The JSON field names are the
+// ones commonly used by structured loggers so that log pipelines can
+// consume them without further configuration.
+type Line struct {
+	Timestamp time.Time `json:"@timestamp"`
+	Level     string    `json:"@level"`
+	Subsystem string    `json:"@module,omitempty"`
+	Message   string    `json:"@message"`
+}
+
+// ParseLine splits a log line into its fields. Lines which are not in the
+// format of the agent's logger, e.g. the continuation of a multi-line
+// message, are returned as the message of an INFO line logged now.
+func ParseLine(line string) Line {
+	m := lineRe.FindStringSubmatch(line)
+	if m == nil {
+		return Line{Timestamp: time.Now(), Level: "INFO", Message: line}
+	}
+
+	ts, err := time.ParseInLocation(lineTimeFormat, m[1], time.Local)
+	if err != nil {
+		ts = time.Now()
+	}
+	return Line{
+		Timestamp: ts,
+		Level:     m[2],
+		Subsystem: m[3],
+		Message:   m[4],
+	}
+}
+
+// SubsystemFilter selects log lines by the subsystem which logged them. A
+// subsystem matches a name if it is equal to it or nested under it, e.g.
+// "consul" matches both "consul" and "consul.fsm".
+type SubsystemFilter struct {
+	// Include lists the subsystems to keep. All subsystems are kept if it
+	// is empty.
+	Include []string
+
+	// Exclude lists the subsystems to drop. It takes precedence over
+	// Include.
+	Exclude []string
+}
+
+// Empty returns true if the filter keeps all lines.
+func (f *SubsystemFilter) Empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Check returns true if a line logged by the given subsystem should be
+// kept.
+func (f *SubsystemFilter) Check(subsystem string) bool {
+	for _, name := range f.Exclude {
+		if subsystemMatches(subsystem, name) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, name := range f.Include {
+		if subsystemMatches(subsystem, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func subsystemMatches(subsystem, name string) bool {
+	return subsystem == name || strings.HasPrefix(subsystem, name+".")
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2019, 1, 2, 15, 4, 5, 0, time.Local)
+
+	cases := map[string]Line{
+		"2019/01/02 15:04:05 [INFO] consul.fsm: snapshot created": {
+			Timestamp: ts, Level: "INFO", Subsystem: "consul.fsm", Message: "snapshot created",
+		},
+		"2019/01/02 15:04:05 [WARN] agent: Node name \"a b\": invalid": {
+			Timestamp: ts, Level: "WARN", Subsystem: "agent", Message: "Node name \"a b\": invalid",
+		},
+		"2019/01/02 15:04:05 [DEBUG] no subsystem here": {
+			Timestamp: ts, Level: "DEBUG", Message: "no subsystem here",
+		},
+		"node1 - 2019/01/02 15:04:05.250000 [ERR] raft: failed": {
+			Timestamp: ts.Add(250 * time.Millisecond), Level: "ERR", Subsystem: "raft", Message: "failed",
+		},
+	}
+	for line, expected := range cases {
+		require.Equal(t, expected, ParseLine(line), line)
+	}
+
+	l := ParseLine("    continuation")
+	require.Equal(t, "INFO", l.Level)
+	require.Equal(t, "    continuation", l.Message)
+	require.False(t, l.Timestamp.IsZero())
+}
+
+func TestLine_JSON(t *testing.T) {
+	t.Parallel()
+	l := Line{
+		Timestamp: time.Date(2019, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:     "INFO",
+		Subsystem: "raft",
+		Message:   "Initial configuration",
+	}
+	b, err := json.Marshal(l)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"@timestamp": "2019-01-02T15:04:05Z",
+		"@level": "INFO",
+		"@module": "raft",
+		"@message": "Initial configuration"
+	}`, string(b))
+}
+
+func TestSubsystemFilter(t *testing.T) {
+	t.Parallel()
+	f := &SubsystemFilter{}
+	require.True(t, f.Empty())
+	require.True(t, f.Check("raft"))
+	require.True(t, f.Check(""))
+
+	f = &SubsystemFilter{Include: []string{"consul", "raft"}, Exclude: []string{"consul.fsm"}}
+	require.False(t, f.Empty())
+	require.True(t, f.Check("consul"))
+	require.True(t, f.Check("consul.leader"))
+	require.True(t, f.Check("raft"))
+	require.False(t, f.Check("consul.fsm"))
+	require.False(t, f.Check("consulx"))
+	require.False(t, f.Check("agent"))
+	require.False(t, f.Check(""))
+
+	f = &SubsystemFilter{Exclude: []string{"memberlist"}}
+	require.True(t, f.Check("agent"))
+	require.True(t, f.Check(""))
+	require.False(t, f.Check("memberlist"))
+}
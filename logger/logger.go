@@ -23,6 +23,9 @@ type Config struct {
 	// SyslogFacility is the destination for syslog forwarding.
 	SyslogFacility string
 
+	// EnableEventLog controls forwarding to the Windows event log.
+	EnableEventLog bool
+
 	//LogFilePath is the path to write the logs to the user specified file.
 	LogFilePath string
 
@@ -96,6 +99,18 @@ func Setup(config *Config, ui cli.Ui) (*logutils.LevelFilter, *GatedWriter, *Log
 			time.Sleep(delay)
 		}
 	}
+
+	// Set up the Windows event log if it's enabled.
+	var eventLog io.Writer
+	if config.EnableEventLog {
+		l, err := newEventLogger("consul")
+		if err != nil {
+			ui.Error(fmt.Sprintf("Event log setup error: %v", err))
+			return nil, nil, nil, nil, false
+		}
+		eventLog = &EventLogWrapper{l, logFilter}
+	}
+
 	// Create a log writer, and wrap a logOutput around it
 	logWriter := NewLogWriter(512)
 	writers := []io.Writer{logFilter, logWriter}
@@ -104,6 +119,9 @@ func Setup(config *Config, ui cli.Ui) (*logutils.LevelFilter, *GatedWriter, *Log
 	if syslog != nil {
 		writers = append(writers, syslog)
 	}
+	if eventLog != nil {
+		writers = append(writers, eventLog)
+	}
 
 	// Create a file logger if the user has specified the path to the log file
 	if config.LogFilePath != "" {
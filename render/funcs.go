@@ -0,0 +1,166 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// KeyPair is a key and its value as returned by the ls function. The key is
+// relative to the listed prefix.
+type KeyPair struct {
+	Key   string
+	Value string
+}
+
+// ServiceInstance is a healthy instance of a service as returned by the
+// service function.
+type ServiceInstance struct {
+	ID      string
+	Name    string
+	Node    string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+}
+
+// Service is a service of the catalog as returned by the services function.
+type Service struct {
+	Name string
+	Tags []string
+}
+
+// funcs returns the template functions. The functions which read from
+// Consul register the data they read as dependencies of the template.
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"key":          r.key,
+		"keyOrDefault": r.keyOrDefault,
+		"ls":           r.ls,
+		"service":      r.service,
+		"services":     r.services,
+		"env":          os.Getenv,
+		"toJSON":       toJSON,
+	}
+}
+
+// key returns the value of the key or an empty string if it doesn't exist.
+func (r *Renderer) key(key string) (string, error) {
+	return r.keyOrDefault(key, "")
+}
+
+// keyOrDefault returns the value of the key or the default if it doesn't
+// exist.
+func (r *Renderer) keyOrDefault(key, def string) (string, error) {
+	v, err := r.get("key:"+key, func(q *consulapi.QueryOptions) (interface{}, *consulapi.QueryMeta, error) {
+		return r.client.KV().Get(key, q)
+	})
+	if err != nil {
+		return "", err
+	}
+	pair := v.(*consulapi.KVPair)
+	if pair == nil {
+		return def, nil
+	}
+	return string(pair.Value), nil
+}
+
+// ls returns the keys under the prefix, sorted by key. Folders, i.e. keys
+// ending in "/", are skipped.
+func (r *Renderer) ls(prefix string) ([]KeyPair, error) {
+	v, err := r.get("ls:"+prefix, func(q *consulapi.QueryOptions) (interface{}, *consulapi.QueryMeta, error) {
+		return r.client.KV().List(prefix, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]KeyPair, 0)
+	for _, pair := range v.(consulapi.KVPairs) {
+		if strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		pairs = append(pairs, KeyPair{
+			Key:   strings.TrimPrefix(strings.TrimPrefix(pair.Key, prefix), "/"),
+			Value: string(pair.Value),
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+// service returns the instances of the service which pass their health
+// checks, optionally filtered by a tag, sorted by node and ID.
+func (r *Renderer) service(name string, tag ...string) ([]ServiceInstance, error) {
+	if len(tag) > 1 {
+		return nil, fmt.Errorf("service: expected at most one tag, got %d", len(tag))
+	}
+	var t string
+	if len(tag) == 1 {
+		t = tag[0]
+	}
+
+	v, err := r.get("service:"+name+":"+t, func(q *consulapi.QueryOptions) (interface{}, *consulapi.QueryMeta, error) {
+		return r.client.Health().Service(name, t, true, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]ServiceInstance, 0)
+	for _, entry := range v.([]*consulapi.ServiceEntry) {
+		addr := entry.Service.Address
+		if addr == "" {
+			addr = entry.Node.Address
+		}
+		instances = append(instances, ServiceInstance{
+			ID:      entry.Service.ID,
+			Name:    entry.Service.Service,
+			Node:    entry.Node.Node,
+			Address: addr,
+			Port:    entry.Service.Port,
+			Tags:    entry.Service.Tags,
+			Meta:    entry.Service.Meta,
+		})
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Node != instances[j].Node {
+			return instances[i].Node < instances[j].Node
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
+
+// services returns the services of the catalog sorted by name.
+func (r *Renderer) services() ([]Service, error) {
+	v, err := r.get("services", func(q *consulapi.QueryOptions) (interface{}, *consulapi.QueryMeta, error) {
+		return r.client.Catalog().Services(q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]Service, 0)
+	for name, tags := range v.(map[string][]string) {
+		sort.Strings(tags)
+		services = append(services, Service{Name: name, Tags: tags})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// toJSON returns v encoded as JSON.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,347 @@
+// Package render renders templates with data read from Consul and keeps the
+// rendered files up to date. It covers the simple use cases of
+// consul-template without deploying a separate binary.
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/armon/circbuf"
+	"github.com/hashicorp/consul/agent/exec"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/lib/file"
+)
+
+const (
+	// retryInterval is the base retry interval after an error. It is
+	// increased exponentially up to maxBackoffTime.
+	retryInterval  = 5 * time.Second
+	maxBackoffTime = 180 * time.Second
+
+	// defaultCommandTimeout is how long the command may run if the
+	// template has no timeout.
+	defaultCommandTimeout = 30 * time.Second
+
+	// outputBufSize limits the captured output of the command.
+	outputBufSize = 4 * 1024 // 4KB
+)
+
+// Template configures a template and what to do when the rendered file
+// changes.
+type Template struct {
+	// Source is the path of the template. The template uses the syntax of
+	// text/template.
+	Source string
+
+	// Destination is the path of the rendered file. It is replaced
+	// atomically.
+	Destination string
+
+	// Perms are the permissions of the rendered file. The default is 0644.
+	Perms os.FileMode
+
+	// Command is run with a shell after the rendered file changed. Args is
+	// run without a shell. Only one of them may be set.
+	Command string
+	Args    []string
+
+	// CommandTimeout is how long the command may run. The default is 30s.
+	CommandTimeout time.Duration
+}
+
+// Renderer renders a template whenever the data it reads from Consul
+// changes.
+type Renderer struct {
+	client *consulapi.Client
+	config *Template
+	tmpl   *template.Template
+	logger *log.Logger
+
+	// deps are the results of the queries made by the last render. They
+	// are keyed by the query.
+	deps map[string]*dependency
+
+	// used tracks the queries made during a render.
+	used map[string]bool
+
+	stop     bool
+	stopCh   chan struct{}
+	stopLock sync.Mutex
+}
+
+// fetchFunc runs a query with the given options.
+type fetchFunc func(q *consulapi.QueryOptions) (interface{}, *consulapi.QueryMeta, error)
+
+// dependency is the last result of a query.
+type dependency struct {
+	fetch fetchFunc
+	value interface{}
+	index uint64
+}
+
+// New returns a renderer for the template which reads the data with the
+// client.
+func New(client *consulapi.Client, t *Template, logger *log.Logger) (*Renderer, error) {
+	if t.Source == "" {
+		return nil, fmt.Errorf("Missing template source")
+	}
+	if t.Destination == "" {
+		return nil, fmt.Errorf("Missing template destination")
+	}
+	if t.Command != "" && len(t.Args) > 0 {
+		return nil, fmt.Errorf("Only one of command or args can be set")
+	}
+
+	r := &Renderer{
+		client: client,
+		config: t,
+		logger: logger,
+		deps:   make(map[string]*dependency),
+		stopCh: make(chan struct{}),
+	}
+
+	src, err := ioutil.ReadFile(t.Source)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read template: %v", err)
+	}
+	tmpl, err := template.New(filepath.Base(t.Source)).Funcs(r.funcs()).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse template: %v", err)
+	}
+	r.tmpl = tmpl
+	return r, nil
+}
+
+// get returns the result of the query with the given key. The cached result
+// is returned if the query was made before, otherwise the query is run.
+func (r *Renderer) get(key string, fetch fetchFunc) (interface{}, error) {
+	r.used[key] = true
+	if dep, ok := r.deps[key]; ok {
+		return dep.value, nil
+	}
+
+	value, meta, err := fetch(&consulapi.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	r.deps[key] = &dependency{fetch: fetch, value: value, index: meta.LastIndex}
+	return value, nil
+}
+
+// Render renders the template and writes the rendered file if its contents
+// changed. It returns true if the file was written.
+func (r *Renderer) Render() (bool, error) {
+	r.used = make(map[string]bool)
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, nil); err != nil {
+		return false, fmt.Errorf("Failed to render template: %v", err)
+	}
+
+	// Forget the queries which aren't used anymore, e.g. because they
+	// were in a branch of the template which wasn't taken.
+	for key := range r.deps {
+		if !r.used[key] {
+			delete(r.deps, key)
+		}
+	}
+
+	existing, err := ioutil.ReadFile(r.config.Destination)
+	if err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return false, nil
+	}
+
+	perms := r.config.Perms
+	if perms == 0 {
+		perms = 0644
+	}
+	if err := file.WriteAtomicWithPerms(r.config.Destination, buf.Bytes(), 0755, perms); err != nil {
+		return false, fmt.Errorf("Failed to write %q: %v", r.config.Destination, err)
+	}
+	return true, nil
+}
+
+// Once renders the template and runs the command if the rendered file
+// changed.
+func (r *Renderer) Once() error {
+	changed, err := r.Render()
+	if err != nil {
+		return err
+	}
+	if changed {
+		r.logger.Printf("[INFO] render: Rendered %q", r.config.Destination)
+		return r.runCommand()
+	}
+	return nil
+}
+
+// Run renders the template and rerenders it whenever the data it uses
+// changes until the renderer is stopped. Errors are logged and retried
+// with a backoff.
+func (r *Renderer) Run() {
+	failures := 0
+	for !r.shouldStop() {
+		changed, err := r.Render()
+		if err == nil && changed {
+			r.logger.Printf("[INFO] render: Rendered %q", r.config.Destination)
+			if err := r.runCommand(); err != nil {
+				r.logger.Printf("[ERR] render: Template %q: %v", r.config.Source, err)
+			}
+		}
+		if err == nil {
+			err = r.wait()
+		}
+		if r.shouldStop() {
+			return
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		// Perform an exponential backoff
+		failures++
+		retry := retryInterval * time.Duration(failures*failures)
+		if retry > maxBackoffTime {
+			retry = maxBackoffTime
+		}
+		r.logger.Printf("[ERR] render: Template %q errored: %v, retry in %v",
+			r.config.Source, err, retry)
+
+		// Query everything again after an error.
+		r.deps = make(map[string]*dependency)
+		select {
+		case <-time.After(retry):
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// Stop stops a running renderer.
+func (r *Renderer) Stop() {
+	r.stopLock.Lock()
+	defer r.stopLock.Unlock()
+	if r.stop {
+		return
+	}
+	r.stop = true
+	close(r.stopCh)
+}
+
+func (r *Renderer) shouldStop() bool {
+	r.stopLock.Lock()
+	defer r.stopLock.Unlock()
+	return r.stop
+}
+
+// wait blocks until the result of one of the queries of the last render
+// changes or the renderer is stopped.
+func (r *Renderer) wait() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type result struct {
+		dep   *dependency
+		value interface{}
+		index uint64
+		err   error
+	}
+	resultCh := make(chan result, len(r.deps))
+	for _, dep := range r.deps {
+		go func(dep *dependency) {
+			index := dep.index
+			for {
+				q := (&consulapi.QueryOptions{WaitIndex: index}).WithContext(ctx)
+				value, meta, err := dep.fetch(q)
+				if err != nil {
+					resultCh <- result{dep: dep, err: err}
+					return
+				}
+
+				// The index may also go backwards, e.g. after the
+				// state was restored from a snapshot.
+				if meta.LastIndex != index {
+					resultCh <- result{dep: dep, value: value, index: meta.LastIndex}
+					return
+				}
+			}
+		}(dep)
+	}
+
+	select {
+	case res := <-resultCh:
+		if res.err != nil {
+			return res.err
+		}
+		res.dep.value = res.value
+		res.dep.index = res.index
+		return nil
+	case <-r.stopCh:
+		return nil
+	}
+}
+
+// runCommand runs the command of the template and logs its output.
+func (r *Renderer) runCommand() error {
+	var cmd *osexec.Cmd
+	var err error
+	switch {
+	case len(r.config.Args) > 0:
+		cmd, err = exec.Subprocess(r.config.Args)
+	case r.config.Command != "":
+		cmd, err = exec.Script(r.config.Command)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to setup command: %v", err)
+	}
+
+	output, _ := circbuf.NewBuffer(outputBufSize)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	exec.SetSysProcAttr(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Failed to run command: %v", err)
+	}
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	timeout := r.config.CommandTimeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	select {
+	case <-time.After(timeout):
+		if err := exec.KillCommandSubtree(cmd); err != nil {
+			r.logger.Printf("[WARN] render: Failed to kill command after timeout: %v", err)
+		}
+		<-waitCh
+		err = fmt.Errorf("timed out after %s", timeout)
+	case err = <-waitCh:
+	}
+
+	outputStr := string(output.Bytes())
+	if output.TotalWritten() > output.Size() {
+		outputStr = fmt.Sprintf("Captured %d of %d bytes\n...\n%s",
+			output.Size(), output.TotalWritten(), outputStr)
+	}
+	if err != nil {
+		return fmt.Errorf("Command failed: %v, output: %s", err, outputStr)
+	}
+	r.logger.Printf("[DEBUG] render: Command output: %s", outputStr)
+	return nil
+}
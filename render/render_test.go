@@ -0,0 +1,173 @@
+package render_test
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/render"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplate(t *testing.T, dir, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, "in.tpl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestRenderer_Render(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "render")
+	defer os.RemoveAll(dir)
+
+	_, err := client.KV().Put(&consulapi.KVPair{Key: "app/name", Value: []byte("web")}, nil)
+	require.NoError(t, err)
+	_, err = client.KV().Put(&consulapi.KVPair{Key: "app/config/b", Value: []byte("2")}, nil)
+	require.NoError(t, err)
+	_, err = client.KV().Put(&consulapi.KVPair{Key: "app/config/a", Value: []byte("1")}, nil)
+	require.NoError(t, err)
+	require.NoError(t, client.Agent().ServiceRegister(&consulapi.AgentServiceRegistration{
+		Name:    "web",
+		ID:      "web1",
+		Tags:    []string{"v1"},
+		Address: "10.0.0.1",
+		Port:    8080,
+	}))
+
+	src := writeTemplate(t, dir, `name={{ key "app/name" }}
+missing={{ keyOrDefault "app/missing" "default" }}
+{{ range ls "app/config" }}{{ .Key }}={{ .Value }}
+{{ end }}{{ range service "web" "v1" }}server {{ .Address }}:{{ .Port }}
+{{ end }}{{ range services }}{{ .Name }} {{ toJSON .Tags }}
+{{ end }}`)
+	dest := filepath.Join(dir, "out", "app.conf")
+
+	r, err := render.New(client, &render.Template{
+		Source:      src,
+		Destination: dest,
+		Perms:       0600,
+	}, logger())
+	require.NoError(t, err)
+
+	changed, err := r.Render()
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	out, err := ioutil.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, `name=web
+missing=default
+a=1
+b=2
+server 10.0.0.1:8080
+consul []
+web ["v1"]
+`, string(out))
+
+	fi, err := os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// Rendering again doesn't change the file.
+	changed, err = r.Render()
+	require.NoError(t, err)
+	require.False(t, changed)
+}
+
+func TestRenderer_Run(t *testing.T) {
+	t.Parallel()
+	a := agent.NewTestAgent(t.Name(), ``)
+	defer a.Shutdown()
+	testrpc.WaitForTestAgent(t, a.RPC, "dc1")
+	client := a.Client()
+
+	dir := testutil.TempDir(t, "render")
+	defer os.RemoveAll(dir)
+
+	src := writeTemplate(t, dir, `{{ keyOrDefault "app/version" "none" }}`)
+	dest := filepath.Join(dir, "app.conf")
+	cmdLog := filepath.Join(dir, "commands")
+
+	r, err := render.New(client, &render.Template{
+		Source:      src,
+		Destination: dest,
+		Args:        []string{"sh", "-c", "cat " + dest + " >> " + cmdLog + " && echo >> " + cmdLog},
+	}, logger())
+	require.NoError(t, err)
+	go r.Run()
+	defer r.Stop()
+
+	retry.Run(t, func(r *retry.R) {
+		out, err := ioutil.ReadFile(cmdLog)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if string(out) != "none\n" {
+			r.Fatalf("bad: %q", out)
+		}
+	})
+
+	_, err = client.KV().Put(&consulapi.KVPair{Key: "app/version", Value: []byte("2")}, nil)
+	require.NoError(t, err)
+
+	retry.Run(t, func(r *retry.R) {
+		out, err := ioutil.ReadFile(cmdLog)
+		if err != nil {
+			r.Fatal(err)
+		}
+		if string(out) != "none\n2\n" {
+			r.Fatalf("bad: %q", out)
+		}
+	})
+
+	// A change which doesn't change the rendered file doesn't run the
+	// command.
+	_, err = client.KV().Put(&consulapi.KVPair{Key: "app/other", Value: []byte("x")}, nil)
+	require.NoError(t, err)
+	_, err = client.KV().Put(&consulapi.KVPair{Key: "app/version", Value: []byte("2")}, nil)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	out, err := ioutil.ReadFile(cmdLog)
+	require.NoError(t, err)
+	require.Equal(t, "none\n2\n", string(out))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	t.Parallel()
+	dir := testutil.TempDir(t, "render")
+	defer os.RemoveAll(dir)
+
+	_, err := render.New(nil, &render.Template{Source: "in.tpl"}, logger())
+	require.EqualError(t, err, "Missing template destination")
+
+	_, err = render.New(nil, &render.Template{
+		Source:      "in.tpl",
+		Destination: "out",
+		Command:     "true",
+		Args:        []string{"true"},
+	}, logger())
+	require.EqualError(t, err, "Only one of command or args can be set")
+
+	src := writeTemplate(t, dir, `{{ key "a" `)
+	_, err = render.New(nil, &render.Template{Source: src, Destination: "out"}, logger())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Failed to parse template")
+}
+
+func logger() *log.Logger {
+	return log.New(os.Stderr, "", log.LstdFlags)
+}
@@ -0,0 +1,21 @@
+// +build !windows
+
+package service_os
+
+import (
+	"errors"
+)
+
+// ErrNotSupported is returned when registering a service on a platform
+// other than Windows.
+var ErrNotSupported = errors.New("Windows services are only supported on Windows")
+
+// Install is not supported on this platform.
+func Install(name, displayName, description, exePath string, args []string) error {
+	return ErrNotSupported
+}
+
+// Uninstall is not supported on this platform.
+func Uninstall(name string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,82 @@
+// +build windows
+
+package service_os
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Error codes returned by the service manager which are not defined in
+// golang.org/x/sys/windows.
+const (
+	errServiceDoesNotExist = syscall.Errno(1060)
+	errServiceExists       = syscall.Errno(1073)
+)
+
+// Install registers the executable at exePath with the given arguments as
+// an automatically started Windows service.
+func Install(name, displayName, description, exePath string, args []string) error {
+	m, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_ALL_ACCESS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %v", err)
+	}
+	defer windows.CloseServiceHandle(m)
+
+	cmdline := []string{windows.EscapeArg(exePath)}
+	for _, arg := range args {
+		cmdline = append(cmdline, windows.EscapeArg(arg))
+	}
+
+	s, err := windows.CreateService(m, toPtr(name), toPtr(displayName),
+		windows.SERVICE_ALL_ACCESS, windows.SERVICE_WIN32_OWN_PROCESS,
+		windows.SERVICE_AUTO_START, windows.SERVICE_ERROR_NORMAL,
+		toPtr(strings.Join(cmdline, " ")), nil, nil, nil, nil, nil)
+	if err != nil {
+		if err == errServiceExists {
+			return fmt.Errorf("service %q already exists", name)
+		}
+		return fmt.Errorf("failed to create service %q: %v", name, err)
+	}
+	defer windows.CloseServiceHandle(s)
+
+	desc := windows.SERVICE_DESCRIPTION{Description: toPtr(description)}
+	err = windows.ChangeServiceConfig2(s, windows.SERVICE_CONFIG_DESCRIPTION, (*byte)(unsafe.Pointer(&desc)))
+	if err != nil {
+		windows.DeleteService(s)
+		return fmt.Errorf("failed to set description of service %q: %v", name, err)
+	}
+	return nil
+}
+
+// Uninstall removes the Windows service with the given name. A running
+// service is removed once it has stopped.
+func Uninstall(name string) error {
+	m, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_ALL_ACCESS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %v", err)
+	}
+	defer windows.CloseServiceHandle(m)
+
+	s, err := windows.OpenService(m, toPtr(name), windows.SERVICE_ALL_ACCESS)
+	if err != nil {
+		if err == errServiceDoesNotExist {
+			return fmt.Errorf("service %q is not installed", name)
+		}
+		return fmt.Errorf("failed to open service %q: %v", name, err)
+	}
+	defer windows.CloseServiceHandle(s)
+
+	if err := windows.DeleteService(s); err != nil {
+		return fmt.Errorf("failed to delete service %q: %v", name, err)
+	}
+	return nil
+}
+
+func toPtr(s string) *uint16 {
+	return windows.StringToUTF16Ptr(s)
+}
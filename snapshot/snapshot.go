@@ -190,3 +190,56 @@ func Restore(logger *log.Logger, in io.Reader, r *raft.Raft) error {
 
 	return nil
 }
+
+// VerifyState takes the snapshot from the reader, verifies its contents and
+// passes its metadata and state to the given function without applying it to
+// Raft. This lets the state be checked against the running version before a
+// restore is attempted.
+func VerifyState(logger *log.Logger, in io.Reader, fn func(*raft.SnapshotMeta, io.Reader) error) error {
+	// Wrap the reader in a gzip decompressor.
+	decomp, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to decompress snapshot: %v", err)
+	}
+	defer func() {
+		if err := decomp.Close(); err != nil {
+			logger.Printf("[ERR] snapshot: Failed to close snapshot decompressor: %v", err)
+		}
+	}()
+
+	// Make a scratch file to receive the contents of the snapshot data so
+	// the checksums are verified before any of it is decoded.
+	snap, err := ioutil.TempFile("", "snapshot")
+	if err != nil {
+		return fmt.Errorf("failed to create temp snapshot file: %v", err)
+	}
+	defer func() {
+		if err := snap.Close(); err != nil {
+			logger.Printf("[ERR] snapshot: Failed to close temp snapshot: %v", err)
+		}
+		if err := os.Remove(snap.Name()); err != nil {
+			logger.Printf("[ERR] snapshot: Failed to clean up temp snapshot: %v", err)
+		}
+	}()
+
+	// Read the archive.
+	var metadata raft.SnapshotMeta
+	if err := read(decomp, &metadata, snap); err != nil {
+		return fmt.Errorf("failed to read snapshot file: %v", err)
+	}
+
+	// Make sure Raft is able to restore this version of snapshot.
+	if metadata.Version < raft.SnapshotVersionMin || metadata.Version > raft.SnapshotVersionMax {
+		return fmt.Errorf("unsupported snapshot version %d", metadata.Version)
+	}
+
+	// Sync and rewind the file so it's ready to be read again.
+	if err := snap.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp snapshot: %v", err)
+	}
+	if _, err := snap.Seek(0, 0); err != nil {
+		return fmt.Errorf("failed to rewind temp snapshot: %v", err)
+	}
+
+	return fn(&metadata, snap)
+}
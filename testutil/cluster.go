@@ -0,0 +1,217 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/go-uuid"
+	"github.com/pkg/errors"
+)
+
+// TestClusterConfig configures a cluster of test servers.
+type TestClusterConfig struct {
+	// Servers is the number of servers, which defaults to 3.
+	Servers int
+
+	// Datacenter is the datacenter of the servers, which defaults to dc1.
+	Datacenter string
+
+	// ACLs enables the ACLs with a default policy of deny. The master
+	// token is available as the MasterToken of the cluster.
+	ACLs bool
+
+	// TLS generates a CA and a server certificate and enables verified
+	// TLS for the RPC between the servers. The files are available as the
+	// TLS of the cluster, so clients can use them for the HTTPS API.
+	TLS bool
+
+	// ServerConfig is an optional callback to modify the configuration of
+	// every server.
+	ServerConfig ServerConfigCallback
+}
+
+// TestCluster is a cluster of test Consul servers in a single datacenter.
+// Servers can be killed and restarted to test failover.
+type TestCluster struct {
+	Servers     []*TestServer
+	MasterToken string
+	TLS         *TestTLSFiles
+
+	tmpdir string
+}
+
+// NewTestCluster starts a cluster of test servers and waits until they
+// elected a leader. If there is an error starting the cluster none of the
+// servers will be running when the function returns.
+func NewTestCluster(t *testing.T, conf TestClusterConfig) (*TestCluster, error) {
+	if conf.Servers <= 0 {
+		conf.Servers = 3
+	}
+	if conf.Datacenter == "" {
+		conf.Datacenter = "dc1"
+	}
+
+	c := &TestCluster{tmpdir: TempDir(t, "cluster")}
+	if conf.ACLs {
+		token, err := uuid.GenerateUUID()
+		if err != nil {
+			os.RemoveAll(c.tmpdir)
+			return nil, errors.Wrap(err, "failed generating master token")
+		}
+		c.MasterToken = token
+	}
+	if conf.TLS {
+		files, err := generateTestTLS(c.tmpdir, conf.Datacenter)
+		if err != nil {
+			os.RemoveAll(c.tmpdir)
+			return nil, err
+		}
+		c.TLS = files
+	}
+
+	for i := 0; i < conf.Servers; i++ {
+		var join []string
+		if i > 0 {
+			join = []string{c.Servers[0].LANAddr}
+		}
+		s, err := NewTestServerConfigT(t, func(sc *TestServerConfig) {
+			sc.Datacenter = conf.Datacenter
+			sc.Bootstrap = false
+			sc.BootstrapExpect = conf.Servers
+			sc.RetryJoin = join
+			if conf.ACLs {
+				sc.PrimaryDatacenter = conf.Datacenter
+				sc.ACLMasterToken = c.MasterToken
+				sc.ACL.Enabled = true
+				sc.ACLDefaultPolicy = "deny"
+			}
+			if conf.TLS {
+				sc.CAFile = c.TLS.CAFile
+				sc.CertFile = c.TLS.CertFile
+				sc.KeyFile = c.TLS.KeyFile
+				sc.VerifyIncomingRPC = true
+				sc.VerifyOutgoing = true
+				sc.Args = append(sc.Args, "-hcl", "verify_server_hostname = true")
+			}
+			if conf.ServerConfig != nil {
+				conf.ServerConfig(sc)
+			}
+		})
+		if err != nil {
+			c.Stop()
+			return nil, errors.Wrapf(err, "failed starting server %d", i)
+		}
+		c.Servers = append(c.Servers, s)
+	}
+
+	for _, s := range c.Servers {
+		if err := s.waitForLeader(); err != nil {
+			c.Stop()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Stop stops all the servers of the cluster and removes their data.
+func (c *TestCluster) Stop() error {
+	defer os.RemoveAll(c.tmpdir)
+
+	var result error
+	for _, s := range c.Servers {
+		if err := s.Stop(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// Kill crashes the server with the given index, see TestServer.Kill.
+func (c *TestCluster) Kill(i int) error {
+	return c.Servers[i].Kill()
+}
+
+// Restart starts the killed server with the given index again. The server
+// rejoins the other servers of the cluster.
+func (c *TestCluster) Restart(i int) error {
+	s := c.Servers[i]
+	s.Config.RetryJoin = nil
+	for j, other := range c.Servers {
+		if j != i {
+			s.Config.RetryJoin = append(s.Config.RetryJoin, other.LANAddr)
+		}
+	}
+	return s.Restart()
+}
+
+// Running returns the servers which haven't been killed.
+func (c *TestCluster) Running() []*TestServer {
+	var running []*TestServer
+	for _, s := range c.Servers {
+		if s.cmd != nil {
+			running = append(running, s)
+		}
+	}
+	return running
+}
+
+// Leader returns the server which is the current leader according to all
+// the running servers.
+func (c *TestCluster) Leader() (*TestServer, error) {
+	var leader string
+	for _, s := range c.Running() {
+		addr, err := s.leaderAddr()
+		if err != nil {
+			return nil, err
+		}
+		if addr == "" {
+			return nil, fmt.Errorf("no leader known to %s", s.Config.NodeName)
+		}
+		if leader != "" && addr != leader {
+			return nil, fmt.Errorf("servers disagree about the leader: %s and %s", leader, addr)
+		}
+		leader = addr
+	}
+
+	for _, s := range c.Running() {
+		if leader == fmt.Sprintf("127.0.0.1:%d", s.Config.Ports.Server) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("leader %q is not a running server", leader)
+}
+
+// WaitForLeader waits until the running servers agree on a leader and
+// returns it.
+func (c *TestCluster) WaitForLeader(t *testing.T) *TestServer {
+	var leader *TestServer
+	retry.Run(t, func(r *retry.R) {
+		var err error
+		if leader, err = c.Leader(); err != nil {
+			r.Fatal(err)
+		}
+	})
+	return leader
+}
+
+// leaderAddr returns the RPC address of the leader known to the server,
+// which is empty if there is no leader.
+func (s *TestServer) leaderAddr() (string, error) {
+	resp, err := s.HTTPClient.Get(s.url("/v1/status/leader"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed http get")
+	}
+	defer resp.Body.Close()
+	if err := s.requireOK(resp); err != nil {
+		return "", err
+	}
+
+	var leader string
+	if err := json.NewDecoder(resp.Body).Decode(&leader); err != nil {
+		return "", errors.Wrap(err, "failed decoding leader")
+	}
+	return leader, nil
+}
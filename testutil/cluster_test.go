@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/testutil/retry"
+)
+
+func TestCluster_Failover(t *testing.T) {
+	t.Parallel()
+	c, err := NewTestCluster(t, TestClusterConfig{ACLs: true, TLS: true})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer c.Stop()
+
+	if c.MasterToken == "" || c.TLS == nil {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	// Kill the leader and wait for another server to take over.
+	leader := c.WaitForLeader(t)
+	var killed int
+	for i, s := range c.Servers {
+		if s == leader {
+			killed = i
+		}
+	}
+	if err := c.Kill(killed); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := len(c.Running()); n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+	if newLeader := c.WaitForLeader(t); newLeader == leader {
+		t.Fatalf("leader didn't change")
+	}
+
+	// The restarted server rejoins and follows the new leader.
+	if err := c.Restart(killed); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	retry.Run(t, func(r *retry.R) {
+		if n := len(c.Running()); n != 3 {
+			r.Fatalf("bad: %d", n)
+		}
+		if _, err := c.Leader(); err != nil {
+			r.Fatal(err)
+		}
+	})
+}
@@ -74,6 +74,7 @@ type TestServerConfig struct {
 	NodeMeta            map[string]string      `json:"node_meta,omitempty"`
 	Performance         *TestPerformanceConfig `json:"performance,omitempty"`
 	Bootstrap           bool                   `json:"bootstrap,omitempty"`
+	BootstrapExpect     int                    `json:"bootstrap_expect,omitempty"`
 	Server              bool                   `json:"server,omitempty"`
 	DataDir             string                 `json:"data_dir,omitempty"`
 	Datacenter          string                 `json:"datacenter,omitempty"`
@@ -81,6 +82,7 @@ type TestServerConfig struct {
 	DisableCheckpoint   bool                   `json:"disable_update_check"`
 	LogLevel            string                 `json:"log_level,omitempty"`
 	Bind                string                 `json:"bind_addr,omitempty"`
+	RetryJoin           []string               `json:"retry_join,omitempty"`
 	Addresses           *TestAddressConfig     `json:"addresses,omitempty"`
 	Ports               *TestPortConfig        `json:"ports,omitempty"`
 	RaftProtocol        int                    `json:"raft_protocol,omitempty"`
@@ -207,7 +209,8 @@ type TestServer struct {
 
 	HTTPClient *http.Client
 
-	tmpdir string
+	tmpdir     string
+	configFile string
 }
 
 // NewTestServer is an easy helper method to create a new Consul
@@ -243,37 +246,6 @@ func newTestServerConfigT(t *testing.T, cb ServerConfigCallback) (*TestServer, e
 		cb(cfg)
 	}
 
-	b, err := json.Marshal(cfg)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed marshaling json")
-	}
-
-	log.Printf("CONFIG JSON: %s", string(b))
-	configFile := filepath.Join(tmpdir, "config.json")
-	if err := ioutil.WriteFile(configFile, b, 0644); err != nil {
-		defer os.RemoveAll(tmpdir)
-		return nil, errors.Wrap(err, "failed writing config content")
-	}
-
-	stdout := io.Writer(os.Stdout)
-	if cfg.Stdout != nil {
-		stdout = cfg.Stdout
-	}
-	stderr := io.Writer(os.Stderr)
-	if cfg.Stderr != nil {
-		stderr = cfg.Stderr
-	}
-
-	// Start the server
-	args := []string{"agent", "-config-file", configFile}
-	args = append(args, cfg.Args...)
-	cmd := exec.Command("consul", args...)
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-	if err := cmd.Start(); err != nil {
-		return nil, errors.Wrap(err, "failed starting command")
-	}
-
 	httpAddr := fmt.Sprintf("127.0.0.1:%d", cfg.Ports.HTTP)
 	client := cleanhttp.DefaultClient()
 	if strings.HasPrefix(cfg.Addresses.HTTP, "unix://") {
@@ -287,7 +259,6 @@ func newTestServerConfigT(t *testing.T, cb ServerConfigCallback) (*TestServer, e
 
 	server := &TestServer{
 		Config: cfg,
-		cmd:    cmd,
 
 		HTTPAddr:  httpAddr,
 		HTTPSAddr: fmt.Sprintf("127.0.0.1:%d", cfg.Ports.HTTPS),
@@ -296,7 +267,14 @@ func newTestServerConfigT(t *testing.T, cb ServerConfigCallback) (*TestServer, e
 
 		HTTPClient: client,
 
-		tmpdir: tmpdir,
+		tmpdir:     tmpdir,
+		configFile: filepath.Join(tmpdir, "config.json"),
+	}
+
+	// Start the server
+	if err := server.start(); err != nil {
+		defer os.RemoveAll(tmpdir)
+		return nil, err
 	}
 
 	// Wait for the server to be ready
@@ -312,6 +290,39 @@ func newTestServerConfigT(t *testing.T, cb ServerConfigCallback) (*TestServer, e
 	return server, nil
 }
 
+// start writes the configuration file and starts the Consul agent.
+func (s *TestServer) start() error {
+	b, err := json.Marshal(s.Config)
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling json")
+	}
+
+	log.Printf("CONFIG JSON: %s", string(b))
+	if err := ioutil.WriteFile(s.configFile, b, 0644); err != nil {
+		return errors.Wrap(err, "failed writing config content")
+	}
+
+	stdout := io.Writer(os.Stdout)
+	if s.Config.Stdout != nil {
+		stdout = s.Config.Stdout
+	}
+	stderr := io.Writer(os.Stderr)
+	if s.Config.Stderr != nil {
+		stderr = s.Config.Stderr
+	}
+
+	args := []string{"agent", "-config-file", s.configFile}
+	args = append(args, s.Config.Args...)
+	cmd := exec.Command("consul", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed starting command")
+	}
+	s.cmd = cmd
+	return nil
+}
+
 // Stop stops the test Consul server, and removes the Consul data
 // directory once we are done.
 func (s *TestServer) Stop() error {
@@ -333,6 +344,44 @@ func (s *TestServer) Stop() error {
 	return s.cmd.Wait()
 }
 
+// Kill terminates the test Consul server abruptly, without leaving the
+// cluster, which simulates a crash. Unlike Stop, the data directory is
+// kept so that the server can be started again with Restart.
+func (s *TestServer) Kill() error {
+	if s.cmd == nil {
+		return nil
+	}
+	if s.cmd.Process != nil {
+		if err := s.cmd.Process.Kill(); err != nil {
+			return errors.Wrap(err, "failed to kill consul server")
+		}
+	}
+
+	// The exit status of a killed process is an error we expect.
+	err := s.cmd.Wait()
+	s.cmd = nil
+	if _, ok := err.(*exec.ExitError); ok {
+		return nil
+	}
+	return err
+}
+
+// Restart starts a killed test Consul server again with the same
+// configuration and data directory, and waits for its HTTP API to respond.
+func (s *TestServer) Restart() error {
+	if s.cmd != nil {
+		return errors.New("consul server is still running")
+	}
+	if err := s.start(); err != nil {
+		return err
+	}
+	if err := s.waitForAPI(); err != nil {
+		defer s.Kill()
+		return errors.Wrap(err, "failed waiting for server to restart")
+	}
+	return nil
+}
+
 type failer struct {
 	failed bool
 }
@@ -49,6 +49,12 @@ type Config struct {
 	// existing clients.
 	VerifyServerHostname bool
 
+	// VerifyServerHostnamePolicies refine the hostname verification of the
+	// servers of some datacenters when VerifyServerHostname is set. The
+	// servers of datacenters without a policy have to present a certificate
+	// valid for server.<datacenter>.<domain>.
+	VerifyServerHostnamePolicies []ServerHostnamePolicy
+
 	// UseTLS is used to enable outgoing TLS connections to Consul servers.
 	UseTLS bool
 
@@ -87,6 +93,28 @@ type Config struct {
 	// PreferServerCipherSuites specifies whether to prefer the server's ciphersuite
 	// over the client ciphersuites.
 	PreferServerCipherSuites bool
+
+	// Revocation checks the certificates of peers against CRLs and OCSP
+	// and provides the OCSP response which is stapled for CertFile. It is
+	// optional.
+	Revocation *Revocation
+}
+
+// ServerHostnamePolicy configures which certificates are accepted from the
+// servers of a datacenter when VerifyServerHostname is set.
+type ServerHostnamePolicy struct {
+	// Datacenter is the datacenter the policy applies to. The "*" policy
+	// applies to all datacenters without their own policy.
+	Datacenter string
+
+	// Names are the accepted server names. A leading "*." matches any single
+	// label, e.g. "*.dc2.example.com". Defaults to server.<datacenter>.<domain>.
+	Names []string
+
+	// Exempt disables the hostname verification for the servers of the
+	// datacenter while they are migrated to certificates with the expected
+	// names. Their certificates still have to be signed by the CA.
+	Exempt bool
 }
 
 // AppendCA opens and parses the CA file and adds the certificates to
@@ -217,6 +245,24 @@ func (c *Config) OutgoingTLSWrapper() (DCWrapper, error) {
 			return WrapTLSClient(conn, conf)
 		}
 	}
+	if c.VerifyServerHostname && len(c.VerifyServerHostnamePolicies) > 0 {
+		wrapper = func(dc string, conn net.Conn) (net.Conn, error) {
+			conf := tlsConfig.Clone()
+			conf.ServerName = "server." + dc + "." + domain
+			conf.InsecureSkipVerify = true
+			return c.wrapServerConn(dc, conn, conf)
+		}
+	}
+	if c.Revocation != nil {
+		wrap := wrapper
+		wrapper = func(dc string, conn net.Conn) (net.Conn, error) {
+			wrapped, err := wrap(dc, conn)
+			if err != nil {
+				return nil, err
+			}
+			return verifyRevocation(wrapped.(*tls.Conn), c.Revocation)
+		}
+	}
 
 	return wrapper, nil
 }
@@ -285,6 +331,152 @@ func WrapTLSClient(conn net.Conn, tlsConfig *tls.Config) (net.Conn, error) {
 	return tlsConn, err
 }
 
+// verifyRevocation completes the handshake of the connection and checks that
+// the certificate of the server wasn't revoked.
+func verifyRevocation(tlsConn *tls.Conn, revocation *Revocation) (net.Conn, error) {
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	if err := revocation.VerifyConnection(tlsConn.ConnectionState()); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// wrapServerConn wraps a connection to a server of the given datacenter and
+// verifies the certificate of the server according to the hostname policy
+// of the datacenter.
+func (c *Config) wrapServerConn(dc string, conn net.Conn, tlsConfig *tls.Config) (net.Conn, error) {
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if err := c.verifyServerCertificate(dc, certs, tlsConfig.RootCAs); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// serverHostnamePolicy returns the hostname policy for the servers of the
+// given datacenter, with the default names filled in.
+func (c *Config) serverHostnamePolicy(dc string) ServerHostnamePolicy {
+	policy := ServerHostnamePolicy{Datacenter: dc}
+	for _, p := range c.VerifyServerHostnamePolicies {
+		if p.Datacenter == dc {
+			policy = p
+			break
+		}
+		if p.Datacenter == "*" {
+			policy = p
+		}
+	}
+	if len(policy.Names) == 0 {
+		policy.Names = []string{"server." + dc + "." + strings.TrimSuffix(c.Domain, ".")}
+	}
+	return policy
+}
+
+// verifyServerCertificate checks that the certificate chain presented by a
+// server of the given datacenter is signed by the CA and valid for one of
+// the names accepted by the hostname policy of the datacenter. The errors
+// describe the certificate and the expectation it failed.
+func (c *Config) verifyServerCertificate(dc string, certs []*x509.Certificate, roots *x509.CertPool) error {
+	if len(certs) == 0 {
+		return fmt.Errorf("server in %s didn't present a certificate", dc)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		CurrentTime:   time.Now(),
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return fmt.Errorf("certificate %s of server in %s isn't signed by the CA: %v",
+			describeCertificate(certs[0]), dc, err)
+	}
+
+	policy := c.serverHostnamePolicy(dc)
+	if policy.Exempt {
+		return nil
+	}
+	names := certs[0].DNSNames
+	if len(names) == 0 && certs[0].Subject.CommonName != "" {
+		names = []string{certs[0].Subject.CommonName}
+	}
+	for _, pattern := range policy.Names {
+		for _, name := range names {
+			if matchServerName(pattern, name) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("certificate %s of server in %s isn't valid for any of the expected names %s",
+		describeCertificate(certs[0]), dc, strings.Join(policy.Names, ", "))
+}
+
+// VerifyServerCertificate checks that the certificate of this server is
+// signed by the CA and valid for the hostname policy of its datacenter, so
+// that the other servers accept it. It does nothing unless
+// VerifyServerHostname is set and a certificate is configured.
+func (c *Config) VerifyServerCertificate(dc string) error {
+	if !c.VerifyServerHostname {
+		return nil
+	}
+	cert, err := c.KeyPair()
+	if err != nil || cert == nil {
+		return err
+	}
+
+	var certs []*x509.Certificate
+	for _, der := range cert.Certificate {
+		parsed, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("Failed to parse certificate %s: %v", c.CertFile, err)
+		}
+		certs = append(certs, parsed)
+	}
+
+	tlsConfig := &tls.Config{}
+	rootConfig := &rootcerts.Config{
+		CAFile: c.CAFile,
+		CAPath: c.CAPath,
+	}
+	if err := rootcerts.ConfigureTLS(tlsConfig, rootConfig); err != nil {
+		return err
+	}
+	if err := c.verifyServerCertificate(dc, certs, tlsConfig.RootCAs); err != nil {
+		return fmt.Errorf("Invalid server certificate %s: %v", c.CertFile, err)
+	}
+	return nil
+}
+
+// matchServerName returns true if the name matches the pattern of a server
+// name. A leading "*." in the pattern matches any single label.
+func matchServerName(pattern, name string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if strings.HasPrefix(pattern, "*.") {
+		i := strings.Index(name, ".")
+		return i > 0 && name[i:] == pattern[1:]
+	}
+	return pattern == name
+}
+
+// describeCertificate returns the common name and DNS names of a certificate
+// for errors.
+func describeCertificate(cert *x509.Certificate) string {
+	return fmt.Sprintf("%q (DNS names: [%s])", cert.Subject.CommonName, strings.Join(cert.DNSNames, ", "))
+}
+
 // IncomingTLSConfig generates a TLS configuration for incoming requests
 func (c *Config) IncomingTLSConfig() (*tls.Config, error) {
 	// Create the tlsConfig
@@ -328,6 +520,23 @@ func (c *Config) IncomingTLSConfig() (*tls.Config, error) {
 		tlsConfig.Certificates = []tls.Certificate{*cert}
 	}
 
+	// Reject revoked client certificates and staple the OCSP response for
+	// the certificate if there is one.
+	if c.Revocation != nil {
+		tlsConfig.VerifyPeerCertificate = c.Revocation.VerifyPeerCertificate
+		if cert != nil && c.Revocation.config.OCSPStapling {
+			// The certificate is only returned by GetCertificate, since
+			// it isn't called for handshakes without SNI otherwise.
+			revocation := c.Revocation
+			tlsConfig.Certificates = nil
+			tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				stapled := *cert
+				stapled.OCSPStaple = revocation.Staple()
+				return &stapled, nil
+			}
+		}
+	}
+
 	// Check if we require verification
 	if c.VerifyIncoming {
 		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
@@ -355,6 +355,142 @@ func TestConfig_outgoingWrapper_BadCert(t *testing.T) {
 	<-errc
 }
 
+func TestConfig_outgoingWrapper_HostnamePolicies(t *testing.T) {
+	cases := []struct {
+		name     string
+		dc       string
+		policies []ServerHostnamePolicy
+		err      string
+	}{
+		{"wildcard", "dc2", []ServerHostnamePolicy{{Datacenter: "dc2", Names: []string{"*.dc1.consul"}}}, ""},
+		{"default policy", "dc2", []ServerHostnamePolicy{{Datacenter: "*", Names: []string{"server.dc1.consul"}}}, ""},
+		{"exempt", "dc2", []ServerHostnamePolicy{{Datacenter: "dc2", Exempt: true}}, ""},
+		{"other datacenter", "dc1", []ServerHostnamePolicy{{Datacenter: "dc2", Exempt: true}}, ""},
+		{"mismatch", "dc2", []ServerHostnamePolicy{{Datacenter: "dc2", Names: []string{"*.dc2.consul"}}},
+			`certificate "Alice" (DNS names: [server.dc1.consul]) of server in dc2 isn't valid for any of the expected names *.dc2.consul`},
+		{"default names", "dc3", []ServerHostnamePolicy{{Datacenter: "dc2", Exempt: true}},
+			`certificate "Alice" (DNS names: [server.dc1.consul]) of server in dc3 isn't valid for any of the expected names server.dc3.consul`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{
+				CAFile:                       "../test/hostname/CertAuth.crt",
+				CertFile:                     "../test/hostname/Alice.crt",
+				KeyFile:                      "../test/hostname/Alice.key",
+				VerifyServerHostname:         true,
+				VerifyServerHostnamePolicies: tc.policies,
+				Domain:                       "consul",
+			}
+
+			client, errc := startTLSServer(config)
+			if client == nil {
+				t.Fatalf("startTLSServer err: %v", <-errc)
+			}
+
+			wrap, err := config.OutgoingTLSWrapper()
+			if err != nil {
+				t.Fatalf("OutgoingTLSWrapper err: %v", err)
+			}
+
+			tlsClient, err := wrap(tc.dc, client)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("wrapTLS err: %v", err)
+				}
+				tlsClient.Close()
+			} else if err == nil || err.Error() != tc.err {
+				t.Fatalf("got error %v want %q", err, tc.err)
+			}
+
+			<-errc
+		})
+	}
+}
+
+func TestConfig_outgoingWrapper_HostnamePolicies_BadCA(t *testing.T) {
+	config := &Config{
+		CAFile:                       "../test/ca/root.cer",
+		CertFile:                     "../test/hostname/Alice.crt",
+		KeyFile:                      "../test/hostname/Alice.key",
+		VerifyServerHostname:         true,
+		VerifyServerHostnamePolicies: []ServerHostnamePolicy{{Datacenter: "dc1", Exempt: true}},
+		Domain:                       "consul",
+	}
+
+	client, errc := startTLSServer(config)
+	if client == nil {
+		t.Fatalf("startTLSServer err: %v", <-errc)
+	}
+
+	wrap, err := config.OutgoingTLSWrapper()
+	if err != nil {
+		t.Fatalf("OutgoingTLSWrapper err: %v", err)
+	}
+
+	tlsClient, err := wrap("dc1", client)
+	if err == nil || !strings.Contains(err.Error(), "isn't signed by the CA") {
+		t.Fatalf("should get CA err: %v", err)
+	}
+	if tlsClient != nil {
+		t.Fatalf("returned a client")
+	}
+
+	<-errc
+}
+
+func TestConfig_VerifyServerCertificate(t *testing.T) {
+	config := &Config{
+		CAFile:               "../test/hostname/CertAuth.crt",
+		CertFile:             "../test/hostname/Alice.crt",
+		KeyFile:              "../test/hostname/Alice.key",
+		VerifyServerHostname: true,
+		Domain:               "consul.",
+	}
+	if err := config.VerifyServerCertificate("dc1"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := config.VerifyServerCertificate("dc2")
+	want := `Invalid server certificate ../test/hostname/Alice.crt: certificate "Alice" (DNS names: [server.dc1.consul]) of server in dc2 isn't valid for any of the expected names server.dc2.consul`
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v want %q", err, want)
+	}
+
+	config.VerifyServerHostnamePolicies = []ServerHostnamePolicy{{Datacenter: "dc2", Exempt: true}}
+	if err := config.VerifyServerCertificate("dc2"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The certificate isn't checked without hostname verification.
+	config.VerifyServerHostname = false
+	config.CAFile = "../test/ca/root.cer"
+	config.VerifyServerHostnamePolicies = nil
+	if err := config.VerifyServerCertificate("dc2"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestConfig_matchServerName(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"server.dc1.consul", "server.dc1.consul", true},
+		{"server.dc1.consul.", "SERVER.dc1.consul", true},
+		{"server.dc1.consul", "server.dc2.consul", false},
+		{"*.dc1.consul", "server.dc1.consul", true},
+		{"*.dc1.consul", "a.server.dc1.consul", false},
+		{"*.dc1.consul", "dc1.consul", false},
+		{"*.dc1.consul", ".dc1.consul", false},
+	}
+	for _, tc := range cases {
+		if got := matchServerName(tc.pattern, tc.name); got != tc.match {
+			t.Fatalf("%q %q: got %v want %v", tc.pattern, tc.name, got, tc.match)
+		}
+	}
+}
+
 func TestConfig_wrapTLS_OK(t *testing.T) {
 	config := &Config{
 		CAFile:         "../test/ca/root.cer",
@@ -0,0 +1,201 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/consul/agent/connect"
+)
+
+// GenerateSerialNumber returns a random serial number for a certificate.
+func GenerateSerialNumber() (*big.Int, error) {
+	l := new(big.Int).Lsh(big.NewInt(1), 128)
+	sn, err := rand.Int(rand.Reader, l)
+	if err != nil {
+		return nil, err
+	}
+	return sn, nil
+}
+
+// GeneratePrivateKey generates a new ECDSA private key and returns it along
+// with its PEM encoding.
+func GeneratePrivateKey() (crypto.Signer, string, error) {
+	return connect.GeneratePrivateKey()
+}
+
+// GenerateCA generates a self-signed root CA certificate for the given key
+// and returns it PEM encoded. If constraints are given, the CA can only sign
+// certificates for DNS names in these domains.
+func GenerateCA(signer crypto.Signer, sn *big.Int, days int, constraints []string) (string, error) {
+	id, err := keyID(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	template := caTemplate(sn, days, constraints)
+	template.Subject.CommonName = fmt.Sprintf("Consul Agent CA %d", sn)
+	template.SubjectKeyId = id
+	template.AuthorityKeyId = id
+	return createCertificate(template, template, signer.Public(), signer)
+}
+
+// GenerateIntermediateCA generates an intermediate CA certificate for the
+// given key, which is signed by the CA certificate and key given as PEM.
+// Leaf certificates are then signed by the intermediate, so that the key of
+// the root CA can be kept offline.
+func GenerateIntermediateCA(signer crypto.Signer, sn *big.Int, days int, constraints []string, ca, caKey string) (string, error) {
+	parent, err := connect.ParseCert(ca)
+	if err != nil {
+		return "", fmt.Errorf("error parsing the signing CA: %s", err)
+	}
+	if !parent.IsCA {
+		return "", fmt.Errorf("the signing certificate is not a CA")
+	}
+	parentSigner, err := connect.ParseSigner(caKey)
+	if err != nil {
+		return "", fmt.Errorf("error parsing the signing CA key: %s", err)
+	}
+
+	id, err := keyID(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	template := caTemplate(sn, days, constraints)
+	template.Subject.CommonName = fmt.Sprintf("Consul Agent Intermediate CA %d", sn)
+	template.SubjectKeyId = id
+	template.AuthorityKeyId = parent.SubjectKeyId
+	if template.NotAfter.After(parent.NotAfter) {
+		template.NotAfter = parent.NotAfter
+	}
+	return createCertificate(template, parent, signer.Public(), parentSigner)
+}
+
+// GenerateCert generates a new key and a leaf certificate for it, which is
+// signed by the CA certificate and key given as PEM. It returns the PEM
+// encoded certificate and key. The URIs are added to the subject alternative
+// names like the DNS names and IP addresses, e.g. for SPIFFE IDs.
+func GenerateCert(ca, caKey string, sn *big.Int, name string, days int, dnsNames []string, ipAddresses []net.IP, uris []*url.URL, extKeyUsage []x509.ExtKeyUsage) (string, string, error) {
+	parent, err := connect.ParseCert(ca)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing the CA: %s", err)
+	}
+	parentSigner, err := connect.ParseSigner(caKey)
+	if err != nil {
+		return "", "", fmt.Errorf("error parsing the CA key: %s", err)
+	}
+
+	signer, pk, err := GeneratePrivateKey()
+	if err != nil {
+		return "", "", err
+	}
+	id, err := keyID(signer.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{CommonName: name},
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           extKeyUsage,
+		IsCA:                  false,
+		NotAfter:              time.Now().AddDate(0, 0, days),
+		NotBefore:             time.Now(),
+		SubjectKeyId:          id,
+		AuthorityKeyId:        parent.SubjectKeyId,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		URIs:                  uris,
+	}
+	if template.NotAfter.After(parent.NotAfter) {
+		template.NotAfter = parent.NotAfter
+	}
+	cert, err := createCertificate(template, parent, signer.Public(), parentSigner)
+	if err != nil {
+		return "", "", err
+	}
+	return cert, pk, nil
+}
+
+// CertChain returns the PEM encoded intermediate certificates in the given
+// PEM, which are all certificates that are not self-signed. They complete
+// the chain of a certificate signed by the first of them up to the root CA.
+func CertChain(pemValue string) (string, error) {
+	var chain bytes.Buffer
+	rest := []byte(pemValue)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", err
+		}
+		if isSelfSigned(cert) {
+			continue
+		}
+		if err := pem.Encode(&chain, block); err != nil {
+			return "", err
+		}
+	}
+	return chain.String(), nil
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func caTemplate(sn *big.Int, days int, constraints []string) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{},
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		IsCA:                  true,
+		NotAfter:              time.Now().AddDate(0, 0, days),
+		NotBefore:             time.Now(),
+	}
+	if len(constraints) > 0 {
+		template.PermittedDNSDomainsCritical = true
+		template.PermittedDNSDomains = constraints
+	}
+	return template
+}
+
+func createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (string, error) {
+	bs, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return "", fmt.Errorf("error generating certificate: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: bs}); err != nil {
+		return "", fmt.Errorf("error encoding certificate: %s", err)
+	}
+	return buf.String(), nil
+}
+
+// keyID returns the ID of a public key, which links the certificates of a
+// chain through their subject and authority key IDs.
+func keyID(pub crypto.PublicKey) ([]byte, error) {
+	bs, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(bs)
+	return id[:], nil
+}
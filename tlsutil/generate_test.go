@@ -0,0 +1,160 @@
+package tlsutil
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/stretchr/testify/require"
+)
+
+func testCA(t *testing.T, constraints []string) (string, string) {
+	t.Helper()
+	signer, pk, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	ca, err := GenerateCA(signer, sn, 365, constraints)
+	require.NoError(t, err)
+	return ca, pk
+}
+
+func testIntermediateCA(t *testing.T, ca, caKey string, constraints []string) (string, string) {
+	t.Helper()
+	signer, pk, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	inter, err := GenerateIntermediateCA(signer, sn, 365, constraints, ca, caKey)
+	require.NoError(t, err)
+	return inter, pk
+}
+
+func verifyCert(t *testing.T, root, cert, chain string, name string) error {
+	t.Helper()
+	leaf, err := connect.ParseCert(cert)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM([]byte(root)))
+	intermediates := x509.NewCertPool()
+	if chain != "" {
+		require.True(t, intermediates.AppendCertsFromPEM([]byte(chain)))
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
+
+func TestGenerateCA(t *testing.T) {
+	ca, pk := testCA(t, []string{"consul", "localhost"})
+	cert, err := connect.ParseCert(ca)
+	require.NoError(t, err)
+	require.True(t, cert.IsCA)
+	require.True(t, isSelfSigned(cert))
+	require.Equal(t, cert.SubjectKeyId, cert.AuthorityKeyId)
+	require.Equal(t, []string{"consul", "localhost"}, cert.PermittedDNSDomains)
+
+	_, err = connect.ParseSigner(pk)
+	require.NoError(t, err)
+}
+
+func TestGenerateCert(t *testing.T) {
+	ca, caKey := testCA(t, nil)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+
+	uri, err := url.Parse("spiffe://11111111-2222-3333-4444-555555555555.consul/agent/server/dc1/node1")
+	require.NoError(t, err)
+
+	cert, pk, err := GenerateCert(ca, caKey, sn, "server.dc1.consul", 30,
+		[]string{"server.dc1.consul", "localhost"}, []net.IP{net.ParseIP("127.0.0.1")}, []*url.URL{uri},
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth})
+	require.NoError(t, err)
+	require.NotEmpty(t, pk)
+
+	parsed, err := connect.ParseCert(cert)
+	require.NoError(t, err)
+	require.False(t, parsed.IsCA)
+	require.Equal(t, "server.dc1.consul", parsed.Subject.CommonName)
+	require.Equal(t, []string{"server.dc1.consul", "localhost"}, parsed.DNSNames)
+	require.Equal(t, []*url.URL{uri}, parsed.URIs)
+	require.NoError(t, verifyCert(t, ca, cert, "", "server.dc1.consul"))
+}
+
+func TestGenerateIntermediateCA(t *testing.T) {
+	root, rootKey := testCA(t, []string{"consul", "localhost"})
+	inter, interKey := testIntermediateCA(t, root, rootKey, []string{"consul", "localhost"})
+
+	parsed, err := connect.ParseCert(inter)
+	require.NoError(t, err)
+	require.True(t, parsed.IsCA)
+	require.False(t, isSelfSigned(parsed))
+
+	rootCert, err := connect.ParseCert(root)
+	require.NoError(t, err)
+	require.Equal(t, rootCert.SubjectKeyId, parsed.AuthorityKeyId)
+	require.False(t, parsed.NotAfter.After(rootCert.NotAfter))
+
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	cert, _, err := GenerateCert(inter, interKey, sn, "server.dc1.consul", 30,
+		[]string{"server.dc1.consul"}, nil, nil,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	require.NoError(t, err)
+
+	// The leaf can only be verified with the intermediate.
+	require.Error(t, verifyCert(t, root, cert, "", "server.dc1.consul"))
+	require.NoError(t, verifyCert(t, root, cert, inter, "server.dc1.consul"))
+
+	// A second level intermediate chains up through the first one.
+	inter2, inter2Key := testIntermediateCA(t, inter, interKey, nil)
+	sn, err = GenerateSerialNumber()
+	require.NoError(t, err)
+	cert, _, err = GenerateCert(inter2, inter2Key, sn, "server.dc1.consul", 30,
+		[]string{"server.dc1.consul"}, nil, nil,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	require.NoError(t, err)
+	require.NoError(t, verifyCert(t, root, cert, inter2+inter, "server.dc1.consul"))
+
+	// The name constraints of the root still apply.
+	sn, err = GenerateSerialNumber()
+	require.NoError(t, err)
+	cert, _, err = GenerateCert(inter, interKey, sn, "example.com", 30,
+		[]string{"example.com"}, nil, nil,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	require.NoError(t, err)
+	require.Error(t, verifyCert(t, root, cert, inter, "example.com"))
+}
+
+func TestGenerateIntermediateCA_NotCA(t *testing.T) {
+	ca, caKey := testCA(t, nil)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	cert, pk, err := GenerateCert(ca, caKey, sn, "server.dc1.consul", 30, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	signer, _, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	_, err = GenerateIntermediateCA(signer, sn, 365, nil, cert, pk)
+	require.EqualError(t, err, "the signing certificate is not a CA")
+}
+
+func TestCertChain(t *testing.T) {
+	root, rootKey := testCA(t, nil)
+	inter, interKey := testIntermediateCA(t, root, rootKey, nil)
+	inter2, _ := testIntermediateCA(t, inter, interKey, nil)
+
+	chain, err := CertChain(root)
+	require.NoError(t, err)
+	require.Empty(t, chain)
+
+	chain, err = CertChain(inter2 + inter + root)
+	require.NoError(t, err)
+	require.Equal(t, inter2+inter, chain)
+}
@@ -0,0 +1,96 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// This file checks the status of certificates with the Online Certificate
+// Status Protocol (RFC 6960): requesting it from the responder of a
+// certificate, and verifying the response of the responder or a stapled
+// response.
+
+// ocspMaxResponseSize limits the size of the responses which are read from
+// a responder.
+const ocspMaxResponseSize = 1024 * 1024
+
+// ocspClockSkew is how far the thisUpdate time of a response may be in the
+// future, to allow for the clock of the responder being ahead.
+const ocspClockSkew = 5 * time.Minute
+
+// parseOCSPResponse parses a DER encoded OCSP response for the certificate,
+// verifies that it is signed by its issuer or a responder the issuer
+// delegated to, and that it was produced before now.
+func parseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	resp, err := ocsp.ParseResponseForCert(der, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response: %v", err)
+	}
+
+	now := time.Now()
+	if responder := resp.Certificate; responder != nil {
+		// ParseResponseForCert only checks that the issuer signed the
+		// certificate of a delegated responder.
+		if !hasExtKeyUsage(responder, x509.ExtKeyUsageOCSPSigning) {
+			return nil, fmt.Errorf("OCSP responder certificate %s isn't authorized to sign OCSP responses",
+				describeCertificate(responder))
+		}
+		if now.Before(responder.NotBefore) || now.After(responder.NotAfter) {
+			return nil, fmt.Errorf("OCSP responder certificate %s is only valid from %s to %s",
+				describeCertificate(responder), responder.NotBefore, responder.NotAfter)
+		}
+	}
+	if resp.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return nil, fmt.Errorf("OCSP response for certificate %s is only valid from %s",
+			describeCertificate(cert), resp.ThisUpdate)
+	}
+	return resp, nil
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, usage x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == usage {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchOCSPResponse requests the status of the certificate from the first
+// responder it lists. It returns the DER encoded response along with the
+// parsed one.
+func fetchOCSPResponse(client *http.Client, cert, issuer *x509.Certificate) ([]byte, *ocsp.Response, error) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, nil, fmt.Errorf("certificate %s has no OCSP responder", describeCertificate(cert))
+	}
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server := cert.OCSPServer[0]
+	httpResp, err := client.Post(server, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("OCSP responder %s returned %s", server, httpResp.Status)
+	}
+	der, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, ocspMaxResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := parseOCSPResponse(der, cert, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, resp, nil
+}
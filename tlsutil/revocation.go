@@ -0,0 +1,546 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// DefaultCRLRefreshInterval is how often the CRLs are reloaded by
+	// default.
+	DefaultCRLRefreshInterval = time.Hour
+
+	// ocspDefaultValidity is how long OCSP responses without a next update
+	// time are used.
+	ocspDefaultValidity = time.Hour
+
+	// ocspRetryInterval is how long to wait before a failed request for the
+	// OCSP staple is retried.
+	ocspRetryInterval = time.Minute
+
+	// revocationFetchTimeout limits the requests for CRLs and OCSP
+	// responses.
+	revocationFetchTimeout = 10 * time.Second
+
+	// ocspHandshakeWait is how long a handshake waits for the OCSP status
+	// of a certificate which isn't cached. The request continues in the
+	// background, so that later handshakes use its response.
+	ocspHandshakeWait = 2 * time.Second
+
+	// crlMaxSize limits the size of the CRLs which are downloaded.
+	crlMaxSize = 64 * 1024 * 1024
+)
+
+// RevocationConfig configures the checks whether the certificates of the
+// peers of TLS connections were revoked.
+type RevocationConfig struct {
+	// CRLFile is a path to a PEM or DER encoded certificate revocation list.
+	CRLFile string
+
+	// CRLURL is a HTTP(S) URL the certificate revocation list is downloaded
+	// from.
+	CRLURL string
+
+	// CRLRefreshInterval is how often the CRL is reloaded from CRLFile and
+	// CRLURL. Defaults to DefaultCRLRefreshInterval.
+	CRLRefreshInterval time.Duration
+
+	// VerifyOCSP enables checking the status of peer certificates with
+	// their OCSP responder, or the response stapled by a server. Only
+	// certificates the responder reports as revoked are rejected, so that
+	// an unavailable responder doesn't break the cluster.
+	VerifyOCSP bool
+
+	// OCSPStapling enables fetching the OCSP response for CertFile and
+	// stapling it to the handshakes of incoming connections.
+	OCSPStapling bool
+
+	// CAFile and CAPath contain the CA certificates which CRLs have to be
+	// signed by and which issue the certificates that are checked.
+	CAFile string
+	CAPath string
+
+	// CertFile is the certificate of the agent, which the OCSP response is
+	// stapled for. Intermediates following it are trusted to sign CRLs as
+	// well.
+	CertFile string
+}
+
+// Enabled returns true if any revocation check is configured.
+func (c RevocationConfig) Enabled() bool {
+	return c.CRLFile != "" || c.CRLURL != "" || c.VerifyOCSP || c.OCSPStapling
+}
+
+// Revocation checks the certificates of the peers of TLS connections
+// against the configured CRLs and their OCSP responders, and maintains the
+// OCSP response which is stapled for the certificate of the agent. It is
+// shared by the TLS configurations of the agent and refreshed by Run.
+type Revocation struct {
+	config RevocationConfig
+	logger *log.Logger
+	client *http.Client
+
+	// cas are the CA and intermediate certificates which can issue the
+	// checked certificates and sign CRLs.
+	cas []*x509.Certificate
+
+	// leaf and issuer are the certificate of the agent and its issuer,
+	// which the OCSP response is stapled for.
+	leaf   *x509.Certificate
+	issuer *x509.Certificate
+
+	l           sync.RWMutex
+	crls        []*pkix.CertificateList
+	ocspCache   map[string]*ocsp.Response
+	ocspFetches map[string]*ocspFetch
+	staple      []byte
+}
+
+// ocspFetch is a request for the OCSP status of a certificate. Concurrent
+// checks of the certificate share the request, and resp is set once done is
+// closed.
+type ocspFetch struct {
+	done chan struct{}
+	resp *ocsp.Response
+}
+
+// NewRevocation returns a Revocation for the configuration. The CRLs are
+// loaded right away, so that an invalid configuration is reported on
+// startup, while the OCSP staple is only fetched by Run.
+func NewRevocation(config RevocationConfig, logger *log.Logger) (*Revocation, error) {
+	if config.CRLRefreshInterval <= 0 {
+		config.CRLRefreshInterval = DefaultCRLRefreshInterval
+	}
+	r := &Revocation{
+		config:      config,
+		logger:      logger,
+		client:      &http.Client{Timeout: revocationFetchTimeout},
+		ocspCache:   make(map[string]*ocsp.Response),
+		ocspFetches: make(map[string]*ocspFetch),
+	}
+
+	cas, err := loadCertificates(config.CAFile, config.CAPath)
+	if err != nil {
+		return nil, err
+	}
+	r.cas = cas
+
+	if config.CertFile != "" {
+		data, err := ioutil.ReadFile(config.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read certificate: %v", err)
+		}
+		chain, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse certificate %s: %v", config.CertFile, err)
+		}
+		if len(chain) > 0 {
+			r.leaf = chain[0]
+			r.cas = append(r.cas, chain[1:]...)
+		}
+	}
+
+	if config.OCSPStapling {
+		if r.leaf == nil {
+			return nil, fmt.Errorf("OCSP stapling requires a certificate")
+		}
+		if len(r.leaf.OCSPServer) == 0 {
+			return nil, fmt.Errorf("OCSP stapling requires a certificate with an OCSP responder")
+		}
+		r.issuer = r.findIssuer(r.leaf, nil)
+		if r.issuer == nil {
+			return nil, fmt.Errorf("OCSP stapling requires the issuer of the certificate in the CA certificates or the certificate file")
+		}
+	}
+
+	if err := r.loadCRLs(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Run reloads the CRLs and refreshes the OCSP staple until stopCh is
+// closed.
+func (r *Revocation) Run(stopCh <-chan struct{}) {
+	var crlCh <-chan time.Time
+	if r.config.CRLFile != "" || r.config.CRLURL != "" {
+		ticker := time.NewTicker(r.config.CRLRefreshInterval)
+		defer ticker.Stop()
+		crlCh = ticker.C
+	}
+
+	var stapleCh <-chan time.Time
+	if r.config.OCSPStapling {
+		stapleCh = time.After(0)
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-crlCh:
+			if err := r.loadCRLs(); err != nil {
+				r.logger.Printf("[ERR] tlsutil: Failed to reload CRL, keeping the previous one: %v", err)
+			}
+		case <-stapleCh:
+			stapleCh = time.After(r.refreshStaple())
+		}
+	}
+}
+
+// Staple returns the current OCSP response for the certificate of the
+// agent, or nil if there is none.
+func (r *Revocation) Staple() []byte {
+	r.l.RLock()
+	defer r.l.RUnlock()
+	return r.staple
+}
+
+// refreshStaple fetches the OCSP response for the certificate of the agent
+// and returns when it has to be refreshed.
+func (r *Revocation) refreshStaple() time.Duration {
+	der, resp, err := fetchOCSPResponse(r.client, r.leaf, r.issuer)
+	if err != nil {
+		r.logger.Printf("[WARN] tlsutil: Failed to fetch OCSP response for stapling: %v", err)
+		return ocspRetryInterval
+	}
+	if resp.Status == ocsp.Revoked {
+		r.logger.Printf("[ERR] tlsutil: Certificate %s was revoked at %s",
+			describeCertificate(r.leaf), resp.RevokedAt)
+	}
+
+	r.l.Lock()
+	r.staple = der
+	r.l.Unlock()
+
+	// Refresh when half of the validity of the response is over.
+	if resp.NextUpdate.IsZero() {
+		return ocspDefaultValidity
+	}
+	wait := time.Until(resp.NextUpdate) / 2
+	if wait < ocspRetryInterval {
+		wait = ocspRetryInterval
+	}
+	return wait
+}
+
+// VerifyPeerCertificate checks that none of the certificates presented by a
+// peer was revoked. It has the signature of tls.Config.VerifyPeerCertificate
+// and is used to check the client certificates of incoming connections.
+func (r *Revocation) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	return r.Check(certs, nil)
+}
+
+// VerifyConnection checks that none of the certificates presented by the
+// server of an outgoing connection was revoked, using the OCSP response it
+// stapled if there is one.
+func (r *Revocation) VerifyConnection(state tls.ConnectionState) error {
+	return r.Check(state.PeerCertificates, state.OCSPResponse)
+}
+
+// Check returns an error if any of the certificates of a chain is listed in
+// a CRL, or if the leaf certificate was revoked according to the stapled
+// OCSP response or its OCSP responder.
+func (r *Revocation) Check(certs []*x509.Certificate, staple []byte) error {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	r.l.RLock()
+	crls := r.crls
+	r.l.RUnlock()
+	for _, cert := range certs {
+		if crlRevoked(crls, cert) {
+			return fmt.Errorf("certificate %s was revoked", describeCertificate(cert))
+		}
+	}
+
+	if !r.config.VerifyOCSP {
+		return nil
+	}
+	leaf := certs[0]
+	issuer := r.findIssuer(leaf, certs[1:])
+	if issuer == nil {
+		// The chain is verified separately, so an unknown issuer fails
+		// the handshake anyway.
+		return nil
+	}
+	resp := r.ocspStatus(leaf, issuer, staple)
+	if resp != nil && resp.Status == ocsp.Revoked {
+		return fmt.Errorf("certificate %s was revoked at %s", describeCertificate(leaf), resp.RevokedAt)
+	}
+	return nil
+}
+
+// ocspStatus returns the OCSP status of the certificate from the staple, the
+// cache or its responder. Cached responses are refreshed in the background
+// once half of their validity is over. Failures are logged and result in
+// nil.
+func (r *Revocation) ocspStatus(cert, issuer *x509.Certificate, staple []byte) *ocsp.Response {
+	if len(staple) > 0 {
+		resp, err := parseOCSPResponse(staple, cert, issuer)
+		if err == nil && !ocspExpired(resp) {
+			return resp
+		}
+		if err != nil {
+			r.logger.Printf("[WARN] tlsutil: Invalid stapled OCSP response for certificate %s: %v",
+				describeCertificate(cert), err)
+		}
+	}
+
+	if len(cert.OCSPServer) == 0 {
+		return nil
+	}
+	key := string(issuer.RawSubject) + cert.SerialNumber.String()
+	r.l.RLock()
+	resp, ok := r.ocspCache[key]
+	r.l.RUnlock()
+	if ok && !ocspExpired(resp) {
+		if time.Now().After(ocspRefreshAt(resp)) {
+			r.fetchOCSP(key, cert, issuer)
+		}
+		return resp
+	}
+
+	fetch := r.fetchOCSP(key, cert, issuer)
+	select {
+	case <-fetch.done:
+		return fetch.resp
+	case <-time.After(ocspHandshakeWait):
+		r.logger.Printf("[WARN] tlsutil: Timed out waiting for OCSP status of certificate %s",
+			describeCertificate(cert))
+		return nil
+	}
+}
+
+// fetchOCSP requests the OCSP status of the certificate in the background
+// and caches the response, unless it is already being requested. It returns
+// the request in flight.
+func (r *Revocation) fetchOCSP(key string, cert, issuer *x509.Certificate) *ocspFetch {
+	r.l.Lock()
+	defer r.l.Unlock()
+	if fetch, ok := r.ocspFetches[key]; ok {
+		return fetch
+	}
+	fetch := &ocspFetch{done: make(chan struct{})}
+	r.ocspFetches[key] = fetch
+
+	go func() {
+		_, resp, err := fetchOCSPResponse(r.client, cert, issuer)
+		if err != nil {
+			r.logger.Printf("[WARN] tlsutil: Failed to check OCSP status of certificate %s: %v",
+				describeCertificate(cert), err)
+		}
+
+		r.l.Lock()
+		delete(r.ocspFetches, key)
+		if resp != nil {
+			for k, v := range r.ocspCache {
+				if ocspExpired(v) {
+					delete(r.ocspCache, k)
+				}
+			}
+			r.ocspCache[key] = resp
+		}
+		fetch.resp = resp
+		r.l.Unlock()
+		close(fetch.done)
+	}()
+	return fetch
+}
+
+// findIssuer returns the certificate which signed cert from the
+// intermediates presented with it or the CA certificates.
+func (r *Revocation) findIssuer(cert *x509.Certificate, intermediates []*x509.Certificate) *x509.Certificate {
+	for _, candidates := range [][]*x509.Certificate{intermediates, r.cas} {
+		for _, c := range candidates {
+			if bytes.Equal(c.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(c) == nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// loadCRLs loads the CRLs from the file and the URL and replaces the current
+// ones if they are all valid.
+func (r *Revocation) loadCRLs() error {
+	var crls []*pkix.CertificateList
+	if r.config.CRLFile != "" {
+		data, err := ioutil.ReadFile(r.config.CRLFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read CRL file: %v", err)
+		}
+		crl, err := r.parseCRL(data)
+		if err != nil {
+			return fmt.Errorf("Invalid CRL %s: %v", r.config.CRLFile, err)
+		}
+		crls = append(crls, crl)
+	}
+	if r.config.CRLURL != "" {
+		data, err := r.fetchCRL(r.config.CRLURL)
+		if err != nil {
+			return fmt.Errorf("Failed to download CRL: %v", err)
+		}
+		crl, err := r.parseCRL(data)
+		if err != nil {
+			return fmt.Errorf("Invalid CRL %s: %v", r.config.CRLURL, err)
+		}
+		crls = append(crls, crl)
+	}
+
+	for _, crl := range crls {
+		if crl.HasExpired(time.Now()) {
+			r.logger.Printf("[WARN] tlsutil: CRL of %s expired at %s",
+				crl.TBSCertList.Issuer.String(), crl.TBSCertList.NextUpdate)
+		}
+	}
+
+	r.l.Lock()
+	r.crls = crls
+	r.l.Unlock()
+	return nil
+}
+
+func (r *Revocation) fetchCRL(url string) ([]byte, error) {
+	resp, err := r.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, crlMaxSize))
+}
+
+// parseCRL parses a PEM or DER encoded CRL and checks that it is signed by
+// one of the CA certificates.
+func (r *Revocation) parseCRL(data []byte) (*pkix.CertificateList, error) {
+	crl, err := x509.ParseCRL(data)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := asn1.Marshal(crl.TBSCertList.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	for _, ca := range r.cas {
+		if bytes.Equal(ca.RawSubject, issuer) && ca.CheckCRLSignature(crl) == nil {
+			return crl, nil
+		}
+	}
+	return nil, fmt.Errorf("CRL of %s isn't signed by any of the CA certificates", crl.TBSCertList.Issuer.String())
+}
+
+// crlRevoked returns true if the certificate is listed in the CRL of its
+// issuer.
+func crlRevoked(crls []*pkix.CertificateList, cert *x509.Certificate) bool {
+	for _, crl := range crls {
+		issuer, err := asn1.Marshal(crl.TBSCertList.Issuer)
+		if err != nil || !bytes.Equal(issuer, cert.RawIssuer) {
+			continue
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ocspExpired(resp *ocsp.Response) bool {
+	if resp.NextUpdate.IsZero() {
+		return time.Since(resp.ThisUpdate) > ocspDefaultValidity
+	}
+	return time.Now().After(resp.NextUpdate)
+}
+
+// ocspRefreshAt returns when half of the validity of the response is over.
+func ocspRefreshAt(resp *ocsp.Response) time.Time {
+	if resp.NextUpdate.IsZero() {
+		return resp.ThisUpdate.Add(ocspDefaultValidity / 2)
+	}
+	return resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2)
+}
+
+// loadCertificates returns the certificates in the CA file and the files in
+// the CA path.
+func loadCertificates(caFile, caPath string) ([]*x509.Certificate, error) {
+	var files []string
+	if caFile != "" {
+		files = append(files, caFile)
+	}
+	if caPath != "" {
+		entries, err := ioutil.ReadDir(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CA path: %v", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, filepath.Join(caPath, e.Name()))
+			}
+		}
+	}
+
+	var certs []*x509.Certificate
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("Failed to read CA file: %v", err)
+		}
+		parsed, err := parseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse CA file %s: %v", f, err)
+		}
+		certs = append(certs, parsed...)
+	}
+	return certs, nil
+}
+
+// parseCertificates returns the certificates in the PEM data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
@@ -0,0 +1,491 @@
+package tlsutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/agent/connect"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testPKI is a CA with certificates signed by it, which are written to a
+// temporary directory.
+type testPKI struct {
+	dir    string
+	ca     *x509.Certificate
+	caKey  crypto.Signer
+	caFile string
+}
+
+func newTestPKI(t *testing.T) (*testPKI, func()) {
+	t.Helper()
+	dir := testutil.TempDir(t, "revocation")
+	ca, caKey := testCA(t, nil)
+	p := &testPKI{dir: dir, caFile: filepath.Join(dir, "ca.pem")}
+	require.NoError(t, ioutil.WriteFile(p.caFile, []byte(ca), 0644))
+
+	var err error
+	p.ca, err = connect.ParseCert(ca)
+	require.NoError(t, err)
+	p.caKey, err = connect.ParseSigner(caKey)
+	require.NoError(t, err)
+	return p, func() { os.RemoveAll(dir) }
+}
+
+// cert creates a certificate for server.dc1.consul with the given OCSP
+// responder and writes it and its key to name.pem and name-key.pem.
+func (p *testPKI) cert(t *testing.T, name, ocspServer string) (*x509.Certificate, string, string) {
+	t.Helper()
+	signer, pk, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: sn,
+		Subject:      pkix.Name{CommonName: "server.dc1.consul"},
+		DNSNames:     []string{"server.dc1.consul"},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, signer.Public(), p.caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(p.dir, name+".pem")
+	keyFile := filepath.Join(p.dir, name+"-key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0644))
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(pk), 0600))
+	return cert, certFile, keyFile
+}
+
+// crl returns a PEM encoded CRL of the CA which revokes the certificates.
+func (p *testPKI) crl(t *testing.T, revoked ...*x509.Certificate) []byte {
+	t.Helper()
+	var list []pkix.RevokedCertificate
+	for _, c := range revoked {
+		list = append(list, pkix.RevokedCertificate{SerialNumber: c.SerialNumber, RevocationTime: time.Now()})
+	}
+	der, err := p.ca.CreateCRL(rand.Reader, p.caKey, list, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+// ocspResponse returns a DER encoded OCSP response of the CA for the
+// certificate.
+func (p *testPKI) ocspResponse(t *testing.T, cert *x509.Certificate, status int) []byte {
+	t.Helper()
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		template.RevokedAt = time.Now().Add(-time.Minute)
+	}
+	der, err := ocsp.CreateResponse(p.ca, p.ca, template, p.caKey)
+	require.NoError(t, err)
+	return der
+}
+
+// responder creates a certificate of a responder the CA delegates to, which
+// is valid until notAfter.
+func (p *testPKI) responder(t *testing.T, usage []x509.ExtKeyUsage, notAfter time.Time) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	signer, _, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sn, err := GenerateSerialNumber()
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: sn,
+		Subject:      pkix.Name{CommonName: "ocsp.dc1.consul"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usage,
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, signer.Public(), p.caKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, signer
+}
+
+// testResponder is an OCSP responder which answers with the configured
+// status of a certificate, or good.
+type testResponder struct {
+	sync.Mutex
+	t        *testing.T
+	pki      *testPKI
+	certs    map[string]*x509.Certificate
+	status   map[string]int
+	delay    time.Duration
+	requests int
+}
+
+func (r *testResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	r.requests++
+	delay := r.delay
+	r.Unlock()
+	time.Sleep(delay)
+
+	body, _ := ioutil.ReadAll(req.Body)
+	ocspReq, err := ocsp.ParseRequest(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	sn := ocspReq.SerialNumber.String()
+	w.Write(r.pki.ocspResponse(r.t, r.certs[sn], r.status[sn]))
+}
+
+func (r *testResponder) add(cert *x509.Certificate, status int) {
+	r.Lock()
+	defer r.Unlock()
+	r.certs[cert.SerialNumber.String()] = cert
+	r.status[cert.SerialNumber.String()] = status
+}
+
+func (r *testResponder) numRequests() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.requests
+}
+
+func newTestResponder(t *testing.T, p *testPKI) (*testResponder, *httptest.Server) {
+	r := &testResponder{
+		t:      t,
+		pki:    p,
+		certs:  make(map[string]*x509.Certificate),
+		status: make(map[string]int),
+	}
+	return r, httptest.NewServer(r)
+}
+
+func testLogger() *log.Logger {
+	return log.New(os.Stderr, "", log.LstdFlags)
+}
+
+func TestOCSP_Response(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	cert, _, _ := p.cert(t, "server", "")
+
+	for _, status := range []int{ocsp.Good, ocsp.Revoked, ocsp.Unknown} {
+		resp, err := parseOCSPResponse(p.ocspResponse(t, cert, status), cert, p.ca)
+		require.NoError(t, err)
+		require.Equal(t, status, resp.Status)
+		require.False(t, ocspExpired(resp))
+	}
+
+	// Responses of another CA are rejected.
+	other, cleanupOther := newTestPKI(t)
+	defer cleanupOther()
+	_, err := parseOCSPResponse(p.ocspResponse(t, cert, ocsp.Good), cert, other.ca)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad OCSP signature")
+
+	// So are responses for other certificates.
+	cert2, _, _ := p.cert(t, "server2", "")
+	_, err = parseOCSPResponse(p.ocspResponse(t, cert2, ocsp.Good), cert, p.ca)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no response matching the supplied certificate")
+
+	// And responses which aren't valid yet.
+	future, err := ocsp.CreateResponse(p.ca, p.ca, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   time.Now().Add(time.Hour),
+		NextUpdate:   time.Now().Add(2 * time.Hour),
+	}, p.caKey)
+	require.NoError(t, err)
+	_, err = parseOCSPResponse(future, cert, p.ca)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is only valid from")
+
+	delegated := func(responder *x509.Certificate, key crypto.Signer) []byte {
+		der, err := ocsp.CreateResponse(p.ca, responder, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: cert.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			Certificate:  responder,
+		}, key)
+		require.NoError(t, err)
+		return der
+	}
+	ocspSigning := []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+
+	// A responder the CA delegated to can sign responses.
+	responder, key := p.responder(t, ocspSigning, time.Now().Add(time.Hour))
+	_, err = parseOCSPResponse(delegated(responder, key), cert, p.ca)
+	require.NoError(t, err)
+
+	// Unless it isn't authorized for OCSP signing.
+	responder, key = p.responder(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, time.Now().Add(time.Hour))
+	_, err = parseOCSPResponse(delegated(responder, key), cert, p.ca)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "isn't authorized to sign OCSP responses")
+
+	// Or its certificate expired.
+	responder, key = p.responder(t, ocspSigning, time.Now().Add(-time.Hour))
+	_, err = parseOCSPResponse(delegated(responder, key), cert, p.ca)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is only valid from")
+}
+
+func TestRevocation_CRL(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	good, _, _ := p.cert(t, "good", "")
+	revoked, _, _ := p.cert(t, "revoked", "")
+
+	crlFile := filepath.Join(p.dir, "crl.pem")
+	require.NoError(t, ioutil.WriteFile(crlFile, p.crl(t, revoked), 0644))
+
+	r, err := NewRevocation(RevocationConfig{CRLFile: crlFile, CAFile: p.caFile}, testLogger())
+	require.NoError(t, err)
+	require.NoError(t, r.Check([]*x509.Certificate{good}, nil))
+	require.Error(t, r.Check([]*x509.Certificate{revoked}, nil))
+	require.NoError(t, r.VerifyPeerCertificate([][]byte{good.Raw}, nil))
+	require.Error(t, r.VerifyPeerCertificate([][]byte{revoked.Raw}, nil))
+
+	// Reloading picks up newly revoked certificates.
+	require.NoError(t, ioutil.WriteFile(crlFile, p.crl(t, revoked, good), 0644))
+	require.NoError(t, r.loadCRLs())
+	require.Error(t, r.Check([]*x509.Certificate{good}, nil))
+
+	// A CRL which isn't signed by the CA is rejected.
+	other, cleanupOther := newTestPKI(t)
+	defer cleanupOther()
+	require.NoError(t, ioutil.WriteFile(crlFile, other.crl(t, good), 0644))
+	_, err = NewRevocation(RevocationConfig{CRLFile: crlFile, CAFile: p.caFile}, testLogger())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "isn't signed by any of the CA certificates")
+	require.Error(t, r.loadCRLs())
+	require.Error(t, r.Check([]*x509.Certificate{good}, nil), "previous CRL was kept")
+}
+
+func TestRevocation_CRLURL(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	revoked, _, _ := p.cert(t, "revoked", "")
+	crl := p.crl(t, revoked)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(crl)
+	}))
+	defer srv.Close()
+
+	r, err := NewRevocation(RevocationConfig{CRLURL: srv.URL, CAFile: p.caFile}, testLogger())
+	require.NoError(t, err)
+	require.Error(t, r.Check([]*x509.Certificate{revoked}, nil))
+
+	srv.Close()
+	_, err = NewRevocation(RevocationConfig{CRLURL: srv.URL, CAFile: p.caFile}, testLogger())
+	require.Error(t, err)
+}
+
+func TestRevocation_OCSP(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	responder, srv := newTestResponder(t, p)
+	defer srv.Close()
+
+	good, _, _ := p.cert(t, "good", srv.URL)
+	revoked, _, _ := p.cert(t, "revoked", srv.URL)
+	responder.add(good, ocsp.Good)
+	responder.add(revoked, ocsp.Revoked)
+
+	r, err := NewRevocation(RevocationConfig{VerifyOCSP: true, CAFile: p.caFile}, testLogger())
+	require.NoError(t, err)
+	require.NoError(t, r.Check([]*x509.Certificate{good}, nil))
+	err = r.Check([]*x509.Certificate{revoked}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "was revoked at")
+	require.Equal(t, 2, responder.numRequests())
+
+	// The responses are cached.
+	require.NoError(t, r.Check([]*x509.Certificate{good}, nil))
+	require.Equal(t, 2, responder.numRequests())
+
+	// A stapled response is used instead of the responder.
+	unknown, _, _ := p.cert(t, "stapled", srv.URL)
+	require.Error(t, r.Check([]*x509.Certificate{unknown}, p.ocspResponse(t, unknown, ocsp.Revoked)))
+	require.Equal(t, 2, responder.numRequests())
+
+	// An unavailable responder doesn't reject certificates.
+	srv.Close()
+	require.NoError(t, r.Check([]*x509.Certificate{unknown}, nil))
+}
+
+func TestRevocation_OCSP_Concurrent(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	responder, srv := newTestResponder(t, p)
+	defer srv.Close()
+
+	revoked, _, _ := p.cert(t, "revoked", srv.URL)
+	responder.add(revoked, ocsp.Revoked)
+	responder.delay = 200 * time.Millisecond
+
+	r, err := NewRevocation(RevocationConfig{VerifyOCSP: true, CAFile: p.caFile}, testLogger())
+	require.NoError(t, err)
+
+	// Concurrent handshakes share the request for the status.
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- r.Check([]*x509.Certificate{revoked}, nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Error(t, err)
+	}
+	require.Equal(t, 1, responder.numRequests())
+
+	// A response past half of its validity is still used, while it is
+	// refreshed in the background.
+	key := string(p.ca.RawSubject) + revoked.SerialNumber.String()
+	r.l.Lock()
+	r.ocspCache[key].ThisUpdate = time.Now().Add(-2 * time.Hour)
+	r.l.Unlock()
+	require.Error(t, r.Check([]*x509.Certificate{revoked}, nil))
+	retry.Run(t, func(r *retry.R) {
+		if n := responder.numRequests(); n != 2 {
+			r.Fatalf("got %d requests", n)
+		}
+	})
+}
+
+func TestRevocation_Stapling(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	responder, srv := newTestResponder(t, p)
+	defer srv.Close()
+
+	cert, certFile, keyFile := p.cert(t, "server", srv.URL)
+	responder.add(cert, ocsp.Good)
+
+	_, err := NewRevocation(RevocationConfig{OCSPStapling: true, CAFile: p.caFile}, testLogger())
+	require.EqualError(t, err, "OCSP stapling requires a certificate")
+
+	r, err := NewRevocation(RevocationConfig{
+		OCSPStapling: true,
+		VerifyOCSP:   true,
+		CAFile:       p.caFile,
+		CertFile:     certFile,
+	}, testLogger())
+	require.NoError(t, err)
+	require.Nil(t, r.Staple())
+	require.True(t, r.refreshStaple() > ocspRetryInterval)
+	require.NotEmpty(t, r.Staple())
+
+	config := &Config{
+		CAFile:         p.caFile,
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		VerifyOutgoing: true,
+		Revocation:     r,
+	}
+	client, errc := startTLSServer(config)
+	require.NotNil(t, client)
+	wrap, err := config.OutgoingTLSWrapper()
+	require.NoError(t, err)
+	conn, err := wrap("dc1", client)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, <-errc)
+	require.NotEmpty(t, conn.(*tls.Conn).ConnectionState().OCSPResponse)
+
+	// Once revoked, the stapled response rejects the server without asking
+	// the responder.
+	responder.add(cert, ocsp.Revoked)
+	r.refreshStaple()
+	requests := responder.numRequests()
+
+	client, errc = startTLSServer(config)
+	require.NotNil(t, client)
+	_, err = wrap("dc1", client)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "was revoked at")
+	require.Equal(t, requests, responder.numRequests())
+}
+
+func TestRevocation_IncomingTLS(t *testing.T) {
+	p, cleanup := newTestPKI(t)
+	defer cleanup()
+	_, serverCert, serverKey := p.cert(t, "server", "")
+	revoked, clientCert, clientKey := p.cert(t, "client", "")
+
+	crlFile := filepath.Join(p.dir, "crl.pem")
+	require.NoError(t, ioutil.WriteFile(crlFile, p.crl(t, revoked), 0644))
+	r, err := NewRevocation(RevocationConfig{CRLFile: crlFile, CAFile: p.caFile}, testLogger())
+	require.NoError(t, err)
+
+	server := &Config{
+		CAFile:         p.caFile,
+		CertFile:       serverCert,
+		KeyFile:        serverKey,
+		VerifyIncoming: true,
+		Revocation:     r,
+	}
+	client := &Config{
+		CAFile:         p.caFile,
+		CertFile:       clientCert,
+		KeyFile:        clientKey,
+		VerifyOutgoing: true,
+	}
+
+	conn, errc := startTLSServer(server)
+	require.NotNil(t, conn)
+	wrap, err := client.OutgoingTLSWrapper()
+	require.NoError(t, err)
+	tlsConn, err := wrap("dc1", conn)
+	require.NoError(t, err)
+	defer tlsConn.Close()
+	tlsConn.(*tls.Conn).Handshake()
+
+	err = <-errc
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "was revoked")
+}
+
+func TestRevocationConfig_Enabled(t *testing.T) {
+	require.False(t, RevocationConfig{CAFile: "ca.pem", CRLRefreshInterval: time.Hour}.Enabled())
+	require.True(t, RevocationConfig{CRLFile: "crl.pem"}.Enabled())
+	require.True(t, RevocationConfig{CRLURL: "http://example.com/crl"}.Enabled())
+	require.True(t, RevocationConfig{VerifyOCSP: true}.Enabled())
+	require.True(t, RevocationConfig{OCSPStapling: true}.Enabled())
+}